@@ -152,6 +152,10 @@ func init() {
 	registerBooleanParameter(constants.RepairEnabled, true, "Whether to enable race condition repair or not")
 	registerBooleanParameter(constants.RepairDeletePods, false, "Controller will delete pods when detecting pod broken by race condition")
 	registerBooleanParameter(constants.RepairLabelPods, false, "Controller will label pods when detecting pod broken by race condition")
+	registerBooleanParameter(constants.RepairEvictPods, false,
+		"Controller will evict pods when detecting pod broken by race condition, respecting their PodDisruptionBudgets")
+	registerBooleanParameter(constants.RepairFailReadiness, false,
+		"Controller will set the "+string(repair.ReadinessConditionType)+" condition of pods to false when detecting pod broken by race condition")
 	registerBooleanParameter(constants.RepairRunAsDaemon, false, "Controller will run in a loop")
 	registerStringParameter(constants.RepairLabelKey, "cni.istio.io/uninitialized",
 		"The key portion of the label which will be set by the ace repair if label pods is true")
@@ -250,6 +254,8 @@ func constructConfig() (*config.Config, error) {
 		Enabled:            viper.GetBool(constants.RepairEnabled),
 		DeletePods:         viper.GetBool(constants.RepairDeletePods),
 		LabelPods:          viper.GetBool(constants.RepairLabelPods),
+		EvictPods:          viper.GetBool(constants.RepairEvictPods),
+		FailReadiness:      viper.GetBool(constants.RepairFailReadiness),
 		RunAsDaemon:        viper.GetBool(constants.RepairRunAsDaemon),
 		LabelKey:           viper.GetString(constants.RepairLabelKey),
 		LabelValue:         viper.GetString(constants.RepairLabelValue),
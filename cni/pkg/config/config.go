@@ -106,6 +106,13 @@ type RepairConfig struct {
 	// Whether to label broken pods
 	LabelPods bool
 
+	// Whether to fix race condition by evicting broken pods, which respects their PodDisruptionBudgets
+	EvictPods bool
+
+	// Whether to mark broken pods as not ready by setting their cni.istio.io/ready condition to false.
+	// This only affects pods with a readiness gate on the condition.
+	FailReadiness bool
+
 	// Filters for race repair, including name of sidecar annotation, name of init container,
 	// init container termination message and exit code.
 	SidecarAnnotation  string
@@ -157,6 +164,8 @@ func (c RepairConfig) String() string {
 	b.WriteString("LabelValue: " + c.LabelValue + "\n")
 	b.WriteString("DeletePods: " + fmt.Sprint(c.DeletePods) + "\n")
 	b.WriteString("LabelPods: " + fmt.Sprint(c.LabelPods) + "\n")
+	b.WriteString("EvictPods: " + fmt.Sprint(c.EvictPods) + "\n")
+	b.WriteString("FailReadiness: " + fmt.Sprint(c.FailReadiness) + "\n")
 	b.WriteString("SidecarAnnotation: " + c.SidecarAnnotation + "\n")
 	b.WriteString("InitContainerName: " + c.InitContainerName + "\n")
 	b.WriteString("InitTerminationMsg: " + c.InitTerminationMsg + "\n")
@@ -39,6 +39,8 @@ const (
 	RepairEnabled            = "repair-enabled"
 	RepairDeletePods         = "repair-delete-pods"
 	RepairLabelPods          = "repair-label-pods"
+	RepairEvictPods          = "repair-evict-pods"
+	RepairFailReadiness      = "repair-fail-readiness"
 	RepairRunAsDaemon        = "repair-run-as-daemon"
 	RepairLabelKey           = "repair-broken-pod-label-key"
 	RepairLabelValue         = "repair-broken-pod-label-value"
@@ -59,6 +61,7 @@ var (
 	ServiceAccountPath    = "/var/run/secrets/kubernetes.io/serviceaccount"
 	DefaultKubeconfigMode = 0o600
 	UDSLogPath            = "/log"
+	UDSDriftPath          = "/drift"
 
 	// K8s liveness and readiness endpoints
 	LivenessEndpoint  = "/healthz"
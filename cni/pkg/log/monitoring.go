@@ -0,0 +1,26 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import "istio.io/pkg/monitoring"
+
+var iptablesDrift = monitoring.NewSum(
+	"istio_cni_iptables_drift_total",
+	"Total number of CNI plugin checks which found that the iptables rules of a pod had drifted",
+)
+
+func init() {
+	monitoring.MustRegister(iptablesDrift)
+}
@@ -39,10 +39,18 @@ type cniLog struct {
 	Msg   string `json:"msg"`
 }
 
+// Drift is the drift of the iptables rules of a pod, reported by the CNI network plugin when it is checked.
+type Drift struct {
+	Pod       string   `json:"pod"`
+	Namespace string   `json:"namespace"`
+	Rules     []string `json:"rules"`
+}
+
 func NewUDSLogger() *UDSLogger {
 	l := &UDSLogger{}
 	mux := http.NewServeMux()
 	mux.HandleFunc(constants.UDSLogPath, l.handleLog)
+	mux.HandleFunc(constants.UDSDriftPath, l.handleDrift)
 	loggingServer := &http.Server{
 		Handler: mux,
 	}
@@ -127,3 +135,17 @@ func (l *UDSLogger) processLog(body []byte) {
 		}
 	}
 }
+
+func (l *UDSLogger) handleDrift(w http.ResponseWriter, req *http.Request) {
+	if req.Body == nil {
+		return
+	}
+	defer req.Body.Close()
+	var drift Drift
+	if err := json.NewDecoder(req.Body).Decode(&drift); err != nil {
+		log.Errorf("Failed to unmarshal iptables drift report from cni plugin: %v", err)
+		return
+	}
+	iptablesDrift.Increment()
+	pluginLog.Warnf("iptables rules of pod %s/%s have drifted:\n%s", drift.Namespace, drift.Pod, strings.Join(drift.Rules, "\n"))
+}
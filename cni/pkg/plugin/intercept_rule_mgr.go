@@ -22,6 +22,8 @@ const (
 // redirecting traffic to an Istio proxy.
 type InterceptRuleMgr interface {
 	Program(podName, netns string, redirect *Redirect) error
+	// Check verifies that the rules programmed for the redirect were not modified, and returns the drift found.
+	Check(podName, netns string, redirect *Redirect) ([]string, error)
 }
 
 type InterceptRuleMgrCtor func() InterceptRuleMgr
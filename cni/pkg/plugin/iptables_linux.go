@@ -30,9 +30,8 @@ import (
 // getNs is a unit test override variable for interface create.
 var getNs = ns.GetNS
 
-// Program defines a method which programs iptables based on the parameters
-// provided in Redirect.
-func (ipt *iptables) Program(podName, netns string, rdrct *Redirect) error {
+// setRedirect configures istio-iptables, through viper, to program the iptables rules of the Redirect in netns.
+func setRedirect(netns string, rdrct *Redirect) {
 	viper.Set(constants.CNIMode, true)
 	viper.Set(constants.HostNSEnterExec, rdrct.hostNSEnterExec)
 	viper.Set(constants.NetworkNamespace, netns)
@@ -47,12 +46,20 @@ func (ipt *iptables) Program(podName, netns string, rdrct *Redirect) error {
 	viper.Set(constants.OutboundPorts, rdrct.includeOutboundPorts)
 	viper.Set(constants.ServiceExcludeCidr, rdrct.excludeIPCidrs)
 	viper.Set(constants.KubeVirtInterfaces, rdrct.kubevirtInterfaces)
+	viper.Set(constants.OutboundOwnerUIDsExclude, rdrct.excludeOutboundUIDs)
+	viper.Set(constants.OwnerGroupsExclude.Name, rdrct.excludeOwnerGroups)
 	drf := dryRunFilePath.Get()
 	viper.Set(constants.DryRun, drf != "")
 	viper.Set(constants.OutputPath, drf)
 	viper.Set(constants.RedirectDNS, rdrct.dnsRedirect)
 	viper.Set(constants.CaptureAllDNS, rdrct.dnsRedirect)
 	viper.Set(constants.DropInvalid, rdrct.invalidDrop)
+}
+
+// Program defines a method which programs iptables based on the parameters
+// provided in Redirect.
+func (ipt *iptables) Program(podName, netns string, rdrct *Redirect) error {
+	setRedirect(netns, rdrct)
 
 	netNs, err := getNs(netns)
 	if err != nil {
@@ -75,3 +82,24 @@ func (ipt *iptables) Program(podName, netns string, rdrct *Redirect) error {
 
 	return nil
 }
+
+// Check verifies, without modifying them, that the iptables rules and routes programmed for the Redirect are still
+// applied, and returns the drift found.
+func (ipt *iptables) Check(podName, netns string, rdrct *Redirect) ([]string, error) {
+	setRedirect(netns, rdrct)
+
+	netNs, err := getNs(netns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open netns %q: %s", netns, err)
+	}
+	defer netNs.Close()
+
+	var drift []string
+	err = netNs.Do(func(_ ns.NetNS) error {
+		log.Debugf("Checking iptables configuration for %v", podName)
+		var checkErr error
+		drift, checkErr = cmd.CheckRules()
+		return checkErr
+	})
+	return drift, err
+}
@@ -29,3 +29,9 @@ var ErrNotImplemented = errors.New("not implemented")
 func (ipt *iptables) Program(podName, netns string, rdrct *Redirect) error {
 	return ErrNotImplemented
 }
+
+// Check verifies, without modifying them, that the iptables rules programmed for the Redirect are still applied,
+// and returns the drift found.
+func (ipt *iptables) Check(podName, netns string, rdrct *Redirect) ([]string, error) {
+	return nil, ErrNotImplemented
+}
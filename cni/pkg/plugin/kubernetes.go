@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"strings"
 
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
@@ -64,21 +65,37 @@ func getK8sPodInfo(client *kubernetes.Clientset, podName, podNamespace string) (
 		return nil, err
 	}
 
+	pi := newPodInfo(pod)
+	log.Debugf("Pod %v/%v info: \n%+v", podNamespace, podName, pi)
+
+	return pi, nil
+}
+
+// newPodInfo returns the information of a POD relevant to the plugin. A proxy injected as a native sidecar is an
+// init container of the pod, but it is reported in the containers too, as the sidecar of the app containers.
+func newPodInfo(pod *v1.Pod) *PodInfo {
 	pi := &PodInfo{
 		InitContainers:    make(map[string]struct{}),
-		Containers:        make([]string, len(pod.Spec.Containers)),
+		Containers:        make([]string, 0, len(pod.Spec.Containers)+1),
 		Labels:            pod.Labels,
 		Annotations:       pod.Annotations,
 		ProxyEnvironments: make(map[string]string),
 	}
 	for _, initContainer := range pod.Spec.InitContainers {
 		pi.InitContainers[initContainer.Name] = struct{}{}
+		if initContainer.Name == ISTIOPROXY {
+			log.Debugf("Inspecting pod %v/%v native sidecar %v", pod.Namespace, pod.Name, initContainer.Name)
+			pi.Containers = append(pi.Containers, initContainer.Name)
+			for _, e := range initContainer.Env {
+				pi.ProxyEnvironments[e.Name] = e.Value
+			}
+		}
 	}
-	for containerIdx, container := range pod.Spec.Containers {
-		log.Debugf("Inspecting pod %v/%v container %v", podNamespace, podName, container.Name)
-		pi.Containers[containerIdx] = container.Name
+	for _, container := range pod.Spec.Containers {
+		log.Debugf("Inspecting pod %v/%v container %v", pod.Namespace, pod.Name, container.Name)
+		pi.Containers = append(pi.Containers, container.Name)
 
-		if container.Name == "istio-proxy" {
+		if container.Name == ISTIOPROXY {
 			// don't include ports from istio-proxy in the redirect ports
 			// Get proxy container env variable, and extract out ProxyConfig from it.
 			for _, e := range container.Env {
@@ -87,9 +104,7 @@ func getK8sPodInfo(client *kubernetes.Clientset, podName, podNamespace string) (
 			continue
 		}
 	}
-	log.Debugf("Pod %v/%v info: \n%+v", podNamespace, podName, pi)
-
-	return pi, nil
+	return pi
 }
 
 func (pi PodInfo) String() string {
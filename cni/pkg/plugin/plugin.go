@@ -17,11 +17,15 @@
 package plugin
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
+	"net/http"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/containernetworking/cni/pkg/skel"
@@ -32,6 +36,7 @@ import (
 	"istio.io/api/annotation"
 	"istio.io/api/label"
 	"istio.io/istio/cni/pkg/constants"
+	udsLog "istio.io/istio/cni/pkg/log"
 	"istio.io/pkg/log"
 )
 
@@ -44,7 +49,10 @@ var (
 	podRetrievalInterval   = 1 * time.Second
 )
 
-const ISTIOINIT = "istio-init"
+const (
+	ISTIOINIT  = "istio-init"
+	ISTIOPROXY = "istio-proxy"
+)
 
 // Kubernetes a K8s specific struct to hold config
 type Kubernetes struct {
@@ -199,94 +207,25 @@ func CmdAdd(args *skel.CmdArgs) (err error) {
 	}
 
 	// Check if the workload is running under Kubernetes.
-	// TODO(bianpengyuan): refactor the following code to make it less nested.
 	podNamespace := string(k8sArgs.K8S_POD_NAMESPACE)
 	podName := string(k8sArgs.K8S_POD_NAME)
 	if podNamespace != "" && podName != "" {
-		excludePod := false
-		for _, excludeNs := range conf.Kubernetes.ExcludeNamespaces {
-			if podNamespace == excludeNs {
-				excludePod = true
-				break
-			}
+		redirect, err := podRedirect(conf, podName, podNamespace, podRetrievalMaxRetries)
+		if err != nil {
+			return err
 		}
-		if !excludePod {
-			client, err := newKubeClient(*conf)
-			if err != nil {
-				return err
-			}
-			pi := &PodInfo{}
-			var k8sErr error
-			for attempt := 1; attempt <= podRetrievalMaxRetries; attempt++ {
-				pi, k8sErr = getKubePodInfo(client, podName, podNamespace)
-				if k8sErr == nil {
-					break
-				}
-				log.Debugf("Failed to get %s/%s pod info: %v", podNamespace, podName, k8sErr)
-				time.Sleep(podRetrievalInterval)
-			}
-			if k8sErr != nil {
-				log.Errorf("Failed to get %s/%s pod info: %v", podNamespace, podName, k8sErr)
-				return k8sErr
-			}
-
-			// Check if istio-init container is present; in that case exclude pod
-			if _, present := pi.InitContainers[ISTIOINIT]; present {
-				log.Infof("Pod %s/%s excluded due to being already injected with istio-init container", podNamespace, podName)
-				excludePod = true
-			}
-
-			if val, ok := pi.ProxyEnvironments["DISABLE_ENVOY"]; ok {
-				if val, err := strconv.ParseBool(val); err == nil && val {
-					log.Infof("Pod %s/%s excluded due to DISABLE_ENVOY on istio-proxy", podNamespace, podName)
-					excludePod = true
-				}
-			}
-
-			if len(pi.Containers) > 1 {
-				log.Debugf("Checking pod %s/%s annotations prior to redirect for Istio proxy", podNamespace, podName)
-				val := pi.Annotations[injectAnnotationKey]
-				if lbl, labelPresent := pi.Labels[label.SidecarInject.Name]; labelPresent {
-					// The label is the new API; if both are present we prefer the label
-					val = lbl
-				}
-				if val != "" {
-					log.Debugf("Pod %s/%s contains inject annotation: %s", podNamespace, podName, val)
-					if injectEnabled, err := strconv.ParseBool(val); err == nil {
-						if !injectEnabled {
-							log.Infof("Pod %s/%s excluded due to inject-disabled annotation", podNamespace, podName)
-							excludePod = true
-						}
-					}
-				}
-				if _, ok := pi.Annotations[sidecarStatusKey]; !ok {
-					log.Infof("Pod %s/%s excluded due to not containing sidecar annotation", podNamespace, podName)
-					excludePod = true
-				}
-				if !excludePod {
-					log.Debugf("Setting up redirect for pod %v/%v", podNamespace, podName)
-					if redirect, redirErr := NewRedirect(pi); redirErr != nil {
-						log.Errorf("Pod %s/%s redirect failed due to bad params: %v", podNamespace, podName, redirErr)
-					} else {
-						// Get the constructor for the configured type of InterceptRuleMgr
-						interceptMgrCtor := GetInterceptRuleMgrCtor(interceptRuleMgrType)
-						if interceptMgrCtor == nil {
-							log.Errorf("Pod redirect failed due to unavailable InterceptRuleMgr of type %s",
-								interceptRuleMgrType)
-						} else {
-							redirect.hostNSEnterExec = conf.HostNSEnterExec
-							rulesMgr := interceptMgrCtor()
-							if err := rulesMgr.Program(podName, args.Netns, redirect); err != nil {
-								return err
-							}
-						}
-					}
-				}
+		if redirect != nil {
+			// Get the constructor for the configured type of InterceptRuleMgr
+			interceptMgrCtor := GetInterceptRuleMgrCtor(interceptRuleMgrType)
+			if interceptMgrCtor == nil {
+				log.Errorf("Pod redirect failed due to unavailable InterceptRuleMgr of type %s",
+					interceptRuleMgrType)
 			} else {
-				log.Infof("Pod %s/%s excluded because it only has %d containers", podNamespace, podName, len(pi.Containers))
+				rulesMgr := interceptMgrCtor()
+				if err := rulesMgr.Program(podName, args.Netns, redirect); err != nil {
+					return err
+				}
 			}
-		} else {
-			log.Infof("Pod %s/%s excluded", podNamespace, podName)
 		}
 	} else {
 		log.Debugf("Not a kubernetes pod")
@@ -304,8 +243,156 @@ func CmdAdd(args *skel.CmdArgs) (err error) {
 	return types.PrintResult(result, conf.CNIVersion)
 }
 
+// podRedirect returns the redirect of the pod, or nil if the traffic of the pod is not redirected.
+// The pod is retrieved up to retries times, as it may not be visible to the API server yet.
+func podRedirect(conf *Config, podName, podNamespace string, retries int) (*Redirect, error) {
+	for _, excludeNs := range conf.Kubernetes.ExcludeNamespaces {
+		if podNamespace == excludeNs {
+			log.Infof("Pod %s/%s excluded", podNamespace, podName)
+			return nil, nil
+		}
+	}
+	client, err := newKubeClient(*conf)
+	if err != nil {
+		return nil, err
+	}
+	pi := &PodInfo{}
+	var k8sErr error
+	for attempt := 1; attempt <= retries; attempt++ {
+		pi, k8sErr = getKubePodInfo(client, podName, podNamespace)
+		if k8sErr == nil {
+			break
+		}
+		log.Debugf("Failed to get %s/%s pod info: %v", podNamespace, podName, k8sErr)
+		time.Sleep(podRetrievalInterval)
+	}
+	if k8sErr != nil {
+		log.Errorf("Failed to get %s/%s pod info: %v", podNamespace, podName, k8sErr)
+		return nil, k8sErr
+	}
+
+	excludePod := false
+	// Check if istio-init container is present; in that case exclude pod
+	if _, present := pi.InitContainers[ISTIOINIT]; present {
+		log.Infof("Pod %s/%s excluded due to being already injected with istio-init container", podNamespace, podName)
+		excludePod = true
+	}
+
+	if val, ok := pi.ProxyEnvironments["DISABLE_ENVOY"]; ok {
+		if val, err := strconv.ParseBool(val); err == nil && val {
+			log.Infof("Pod %s/%s excluded due to DISABLE_ENVOY on istio-proxy", podNamespace, podName)
+			excludePod = true
+		}
+	}
+
+	if len(pi.Containers) <= 1 {
+		log.Infof("Pod %s/%s excluded because it only has %d containers", podNamespace, podName, len(pi.Containers))
+		return nil, nil
+	}
+	log.Debugf("Checking pod %s/%s annotations prior to redirect for Istio proxy", podNamespace, podName)
+	val := pi.Annotations[injectAnnotationKey]
+	if lbl, labelPresent := pi.Labels[label.SidecarInject.Name]; labelPresent {
+		// The label is the new API; if both are present we prefer the label
+		val = lbl
+	}
+	if val != "" {
+		log.Debugf("Pod %s/%s contains inject annotation: %s", podNamespace, podName, val)
+		if injectEnabled, err := strconv.ParseBool(val); err == nil {
+			if !injectEnabled {
+				log.Infof("Pod %s/%s excluded due to inject-disabled annotation", podNamespace, podName)
+				excludePod = true
+			}
+		}
+	}
+	if _, ok := pi.Annotations[sidecarStatusKey]; !ok {
+		log.Infof("Pod %s/%s excluded due to not containing sidecar annotation", podNamespace, podName)
+		excludePod = true
+	}
+	if excludePod {
+		return nil, nil
+	}
+
+	log.Debugf("Setting up redirect for pod %v/%v", podNamespace, podName)
+	redirect, redirErr := NewRedirect(pi)
+	if redirErr != nil {
+		log.Errorf("Pod %s/%s redirect failed due to bad params: %v", podNamespace, podName, redirErr)
+		return nil, nil
+	}
+	redirect.hostNSEnterExec = conf.HostNSEnterExec
+	return redirect, nil
+}
+
+// CmdCheck is called for CHECK requests. It verifies that the iptables rules and routes programmed for the pod were
+// not modified since they were added, and reports the drift to the CNI node agent, which exports it as a metric.
 func CmdCheck(args *skel.CmdArgs) (err error) {
-	return nil
+	conf, err := parseConfig(args.StdinData)
+	if err != nil {
+		log.Errorf("istio-cni cmdCheck failed to parse config %v %v", string(args.StdinData), err)
+		return err
+	}
+	if conf.LogUDSAddress != "" {
+		if err := log.Configure(GetLoggingOptions(conf.LogUDSAddress)); err != nil {
+			log.Error("Failed to configure istio-cni with UDS log")
+		}
+	}
+	log.FindScope("default").SetOutputLevel(getLogLevel(conf.LogLevel))
+
+	k8sArgs := K8sArgs{}
+	if err := types.LoadArgs(args.Args, &k8sArgs); err != nil {
+		return err
+	}
+	podNamespace := string(k8sArgs.K8S_POD_NAMESPACE)
+	podName := string(k8sArgs.K8S_POD_NAME)
+	if podNamespace == "" || podName == "" {
+		return nil
+	}
+	if conf.Kubernetes.InterceptRuleMgrType != "" {
+		interceptRuleMgrType = conf.Kubernetes.InterceptRuleMgrType
+	}
+	interceptMgrCtor := GetInterceptRuleMgrCtor(interceptRuleMgrType)
+	if interceptMgrCtor == nil {
+		return fmt.Errorf("unavailable InterceptRuleMgr of type %s", interceptRuleMgrType)
+	}
+	// The pod exists once it is running, so it is retrieved only once.
+	redirect, err := podRedirect(conf, podName, podNamespace, 1)
+	if err != nil || redirect == nil {
+		return err
+	}
+	drift, err := interceptMgrCtor().Check(podName, args.Netns, redirect)
+	if err != nil {
+		return err
+	}
+	if len(drift) == 0 {
+		return nil
+	}
+	if conf.LogUDSAddress != "" {
+		if err := reportDrift(conf.LogUDSAddress, udsLog.Drift{Pod: podName, Namespace: podNamespace, Rules: drift}); err != nil {
+			log.Warnf("Failed to report the iptables drift of pod %s/%s: %v", podNamespace, podName, err)
+		}
+	}
+	return fmt.Errorf("iptables rules or routes of pod %s/%s have drifted:\n%s", podNamespace, podName, strings.Join(drift, "\n"))
+}
+
+// reportDrift reports the drift of the iptables rules of a pod to the UDS server of the CNI node agent.
+func reportDrift(udsAddress string, drift udsLog.Drift) error {
+	body, err := json.Marshal(drift)
+	if err != nil {
+		return err
+	}
+	client := http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", udsAddress)
+			},
+		},
+		Timeout: time.Second,
+	}
+	resp, err := client.Post("http://unix"+constants.UDSDriftPath, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
 }
 
 func CmdDelete(args *skel.CmdArgs) (err error) {
@@ -37,6 +37,7 @@ import (
 	diff "istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/test/env"
 	"istio.io/istio/tools/istio-iptables/pkg/cmd"
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
 )
 
 type k8sPodInfoFunc func(*kubernetes.Clientset, string, string) (*PodInfo, error)
@@ -128,6 +129,21 @@ func TestIPTablesRuleGeneration(t *testing.T) {
 			},
 			golden: filepath.Join(env.IstioSrc, "cni/pkg/plugin/testdata/include-exclude-ports.txt.golden"),
 		},
+		{
+			name: "exclude-owners",
+			input: &PodInfo{
+				Containers:     []string{"test", "istio-proxy"},
+				InitContainers: map[string]struct{}{"istio-validate": {}},
+				Annotations: map[string]string{
+					annotation.SidecarStatus.Name: "true",
+					excludeOutboundUIDsKey:        "1000-1999",
+				},
+				ProxyEnvironments: map[string]string{
+					constants.OwnerGroupsExclude.Name: "2000",
+				},
+			},
+			golden: filepath.Join(env.IstioSrc, "cni/pkg/plugin/testdata/exclude-owners.txt.golden"),
+		},
 		{
 			name: "tproxy",
 			input: &PodInfo{
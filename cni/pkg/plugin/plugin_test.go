@@ -17,6 +17,7 @@ package plugin
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -25,9 +26,12 @@ import (
 	"github.com/containernetworking/cni/pkg/types"
 	cniv1 "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/testutils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
 	"istio.io/api/label"
+	udsLog "istio.io/istio/cni/pkg/log"
 )
 
 var (
@@ -99,6 +103,7 @@ var conf = `{
 
 type mockInterceptRuleMgr struct {
 	lastRedirect []*Redirect
+	drift        []string
 }
 
 func init() {
@@ -112,6 +117,10 @@ func (mrdir *mockInterceptRuleMgr) Program(podName, netns string, redirect *Redi
 	return nil
 }
 
+func (mrdir *mockInterceptRuleMgr) Check(podName, netns string, redirect *Redirect) ([]string, error) {
+	return mrdir.drift, nil
+}
+
 func NewMockInterceptRuleMgr() InterceptRuleMgr {
 	return singletonMockInterceptRuleMgr
 }
@@ -264,6 +273,38 @@ func TestCmdAddTwoContainers(t *testing.T) {
 	}
 }
 
+func TestCmdAddNativeSidecar(t *testing.T) {
+	defer resetGlobalTestVariables()
+
+	pi := newPodInfo(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{sidecarStatusKey: "true"}},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{
+				{Name: "istio-validation"},
+				{Name: ISTIOPROXY, Env: []corev1.EnvVar{{Name: "NATIVE_SIDECAR", Value: "true"}}},
+				{Name: "app-init"},
+			},
+			Containers: []corev1.Container{{Name: "app"}},
+		},
+	})
+	if !reflect.DeepEqual(pi.Containers, []string{ISTIOPROXY, "app"}) {
+		t.Fatalf("expected the native sidecar in the containers, got %v", pi.Containers)
+	}
+	if pi.ProxyEnvironments["NATIVE_SIDECAR"] != "true" {
+		t.Fatalf("expected the env of the native sidecar, got %v", pi.ProxyEnvironments)
+	}
+	testContainers = pi.Containers
+	testInitContainers = pi.InitContainers
+	testAnnotations = pi.Annotations
+	testProxyEnv = pi.ProxyEnvironments
+
+	testCmdAdd(t)
+
+	if !nsenterFuncCalled {
+		t.Fatalf("expected nsenterFunc to be called")
+	}
+}
+
 func TestCmdAddTwoContainersWithStarInboundPort(t *testing.T) {
 	defer resetGlobalTestVariables()
 	testAnnotations[includeInboundPortsKey] = "*"
@@ -488,6 +529,38 @@ func TestCmdAddNoPrevResult(t *testing.T) {
 	testCmdAddWithStdinData(t, confNoPrevResult)
 }
 
+func TestCmdCheck(t *testing.T) {
+	defer resetGlobalTestVariables()
+	newKubeClient = mocknewK8sClient
+	getKubePodInfo = mockgetK8sPodInfo
+	testContainers = []string{"mockContainer", "mockContainer2"}
+	args := testSetArgs(fmt.Sprintf(conf, currentVersion, currentVersion, ifname, sandboxDirectory, "mock"))
+
+	if err := CmdCheck(args); err != nil {
+		t.Fatalf("expected no drift, got: %v", err)
+	}
+
+	drifted := "missing or modified rule: iptables -t nat -C PREROUTING -p tcp -j ISTIO_INBOUND"
+	singletonMockInterceptRuleMgr.drift = []string{drifted}
+	defer func() { singletonMockInterceptRuleMgr.drift = nil }()
+	if err := CmdCheck(args); err == nil || !strings.Contains(err.Error(), drifted) {
+		t.Fatalf("expected the drift %q to be reported, got: %v", drifted, err)
+	}
+}
+
+func TestReportDrift(t *testing.T) {
+	udsSock := filepath.Join(t.TempDir(), "cni.sock")
+	stop := make(chan struct{})
+	defer close(stop)
+	if err := udsLog.NewUDSLogger().StartUDSLogServer(udsSock, stop); err != nil {
+		t.Fatal(err)
+	}
+	drift := udsLog.Drift{Pod: "testPodName", Namespace: "istio-system", Rules: []string{"chain ISTIO_INBOUND of table nat differs"}}
+	if err := reportDrift(udsSock, drift); err != nil {
+		t.Fatalf("failed to report the drift: %v", err)
+	}
+}
+
 func MockInterceptRuleMgrCtor() InterceptRuleMgr {
 	return NewMockInterceptRuleMgr()
 }
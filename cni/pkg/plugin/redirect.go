@@ -23,6 +23,8 @@ import (
 
 	"istio.io/api/annotation"
 	"istio.io/istio/tools/istio-iptables/pkg/cmd"
+	iptablesconfig "istio.io/istio/tools/istio-iptables/pkg/config"
+	"istio.io/istio/tools/istio-iptables/pkg/constants"
 	"istio.io/pkg/log"
 )
 
@@ -40,6 +42,7 @@ const (
 	defaultIncludeInboundPorts   = "*"
 	defaultIncludeOutboundPorts  = ""
 	defaultExcludeInterfaces     = ""
+	defaultExcludeOutboundOwners = ""
 )
 
 var (
@@ -50,6 +53,8 @@ var (
 	excludeOutboundPortsKey = annotation.SidecarTrafficExcludeOutboundPorts.Name
 	includeOutboundPortsKey = annotation.SidecarTrafficIncludeOutboundPorts.Name
 	excludeInterfacesKey    = annotation.SidecarTrafficExcludeInterfaces.Name
+	// The key of the excludeOutboundUIDs annotation of the injector.
+	excludeOutboundUIDsKey = "traffic.sidecar.istio.io/excludeOutboundUIDs"
 
 	sidecarInterceptModeKey = annotation.SidecarInterceptionMode.Name
 	sidecarPortListKey      = annotation.SidecarStatusPort.Name
@@ -69,6 +74,7 @@ var (
 		"includeOutboundPorts": {includeOutboundPortsKey, defaultIncludeOutboundPorts, validatePortListWithWildcard},
 		"kubevirtInterfaces":   {kubevirtInterfacesKey, defaultKubevirtInterfaces, alwaysValidFunc},
 		"excludeInterfaces":    {excludeInterfacesKey, defaultExcludeInterfaces, alwaysValidFunc},
+		"excludeOutboundUIDs":  {excludeOutboundUIDsKey, defaultExcludeOutboundOwners, iptablesconfig.ValidateOwnerIDs},
 	}
 )
 
@@ -85,6 +91,8 @@ type Redirect struct {
 	includeOutboundPorts string
 	kubevirtInterfaces   string
 	excludeInterfaces    string
+	excludeOutboundUIDs  string
+	excludeOwnerGroups   string
 	dnsRedirect          bool
 	invalidDrop          bool
 	hostNSEnterExec      bool
@@ -264,6 +272,15 @@ func NewRedirect(pi *PodInfo) (*Redirect, error) {
 		return nil, fmt.Errorf("annotation value error for value %s; annotationFound = %t: %v",
 			"kubevirtInterfaces", isFound, valErr)
 	}
+	isFound, redir.excludeOutboundUIDs, valErr = getAnnotationOrDefault("excludeOutboundUIDs", pi.Annotations)
+	if valErr != nil {
+		return nil, fmt.Errorf("annotation value error for value %s; annotationFound = %t: %v",
+			"excludeOutboundUIDs", isFound, valErr)
+	}
+	// The groups excluded from redirection are set through the proxy environment, as for istio-iptables.
+	if v, found := pi.ProxyEnvironments[constants.OwnerGroupsExclude.Name]; found {
+		redir.excludeOwnerGroups = v
+	}
 	if v, found := pi.ProxyEnvironments["ISTIO_META_DNS_CAPTURE"]; found {
 		// parse and set the bool value of dnsRedirect
 		redir.dnsRedirect, valErr = strconv.ParseBool(v)
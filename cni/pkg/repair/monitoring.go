@@ -19,9 +19,11 @@ import (
 )
 
 var (
-	typeLabel  = monitoring.MustCreateLabel("type")
-	deleteType = "delete"
-	labelType  = "label"
+	typeLabel     = monitoring.MustCreateLabel("type")
+	deleteType    = "delete"
+	labelType     = "label"
+	evictType     = "evict"
+	readinessType = "readiness"
 
 	resultLabel   = monitoring.MustCreateLabel("result")
 	resultSuccess = "success"
@@ -33,8 +35,13 @@ var (
 		"Total number of pods repaired by repair controller",
 		monitoring.WithLabels(typeLabel, resultLabel),
 	)
+
+	podsDetected = monitoring.NewSum(
+		"istio_cni_repair_pods_detected_total",
+		"Total number of broken pods detected by repair controller",
+	)
 )
 
 func init() {
-	monitoring.MustRegister(podsRepaired)
+	monitoring.MustRegister(podsRepaired, podsDetected)
 }
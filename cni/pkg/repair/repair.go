@@ -16,13 +16,19 @@ package repair
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"go.uber.org/multierr"
 	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	client "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 
 	"istio.io/istio/cni/pkg/config"
 	"istio.io/istio/pkg/kube"
@@ -31,10 +37,34 @@ import (
 
 var repairLog = log.RegisterScope("repair", "CNI race condition repair", 0)
 
+// ReadinessConditionType is the pod condition set to false on broken pods when FailReadiness is enabled.
+// Pods with a readiness gate on this condition are not ready until they are recreated.
+const ReadinessConditionType v1.PodConditionType = "cni.istio.io/ready"
+
+// eventSourceName is the component of the events recorded on broken pods.
+const eventSourceName = "istio-cni-repair"
+
+// proxyContainerName is the name of the proxy, which is an init container of the pods injected with a native sidecar.
+const proxyContainerName = "istio-proxy"
+
+// Reasons of the events recorded on broken pods.
+const (
+	reasonDetected     = "BrokenPodDetected"
+	reasonLabeled      = "BrokenPodLabeled"
+	reasonDeleted      = "BrokenPodDeleted"
+	reasonEvicted      = "BrokenPodEvicted"
+	reasonNotReady     = "BrokenPodNotReady"
+	reasonRepairFailed = "BrokenPodRepairFailed"
+)
+
+const brokenPodMessage = "Pod started before the Istio CNI plugin was ready, its traffic is not captured by the mesh"
+
 // The pod reconciler struct. Contains state used to reconcile broken pods.
 type brokenPodReconciler struct {
 	client client.Interface
 	cfg    *config.RepairConfig
+	// recorder records the events of the broken pods, if set.
+	recorder record.EventRecorder
 }
 
 // Constructs a new brokenPodReconciler struct.
@@ -48,14 +78,76 @@ func newBrokenPodReconciler(client client.Interface, cfg *config.RepairConfig) b
 func (bpr brokenPodReconciler) ReconcilePod(pod v1.Pod) (err error) {
 	repairLog.Debugf("Reconciling pod %s", pod.Name)
 
-	if bpr.cfg.DeletePods {
+	// The pods are reconciled on every update, so a broken pod is only reported when it is repaired.
+	if bpr.detectPod(pod) && bpr.needsRepair(pod) {
+		podsDetected.Increment()
+		bpr.event(pod, v1.EventTypeWarning, reasonDetected, brokenPodMessage)
+	}
+	switch {
+	case bpr.cfg.DeletePods:
 		err = multierr.Append(err, bpr.deleteBrokenPod(pod))
-	} else if bpr.cfg.LabelPods {
-		err = multierr.Append(err, bpr.labelBrokenPod(pod))
+	case bpr.cfg.EvictPods:
+		err = multierr.Append(err, bpr.evictBrokenPod(pod))
+	default:
+		// Labeling and failing readiness do not remove the pod, so both apply. The readiness condition is
+		// patched, so it does not conflict with the update of the labels.
+		if bpr.cfg.LabelPods {
+			err = multierr.Append(err, bpr.labelBrokenPod(pod))
+		}
+		if bpr.cfg.FailReadiness {
+			err = multierr.Append(err, bpr.failBrokenPodReadiness(pod))
+		}
+	}
+	return err
+}
+
+// needsRepair returns true if the configured repair of a broken pod is not done yet.
+func (bpr brokenPodReconciler) needsRepair(pod v1.Pod) bool {
+	if bpr.cfg.DeletePods || bpr.cfg.EvictPods {
+		return pod.DeletionTimestamp == nil
+	}
+	if bpr.cfg.LabelPods {
+		if _, ok := pod.Labels[bpr.cfg.LabelKey]; !ok {
+			return true
+		}
+	}
+	return bpr.cfg.FailReadiness && !readinessFailed(pod)
+}
+
+// readinessFailed returns true if the readiness condition of the broken pods is already false.
+func readinessFailed(pod v1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == ReadinessConditionType && c.Status == v1.ConditionFalse {
+			return true
+		}
+	}
+	return false
+}
+
+// RepairBrokenPods reconciles all pods detected as broken by ListPods
+func (bpr brokenPodReconciler) RepairBrokenPods() (err error) {
+	podList, err := bpr.ListBrokenPods()
+	if err != nil {
+		return err
+	}
+	for _, pod := range podList.Items {
+		err = multierr.Append(err, bpr.ReconcilePod(pod))
 	}
 	return err
 }
 
+// event records an event on the pod, if the reconciler has a recorder.
+func (bpr brokenPodReconciler) event(pod v1.Pod, eventType, reason, message string) {
+	if bpr.recorder != nil {
+		bpr.recorder.Event(&pod, eventType, reason, message)
+	}
+}
+
+// repairFailed records the failure to repair the pod.
+func (bpr brokenPodReconciler) repairFailed(pod v1.Pod, action string, err error) {
+	bpr.event(pod, v1.EventTypeWarning, reasonRepairFailed, fmt.Sprintf("Failed to %s broken pod: %v", action, err))
+}
+
 // Label all pods detected as broken by ListPods with a customizable label
 func (bpr brokenPodReconciler) LabelBrokenPods() (err error) {
 	// Get a list of all broken pods
@@ -97,12 +189,78 @@ func (bpr brokenPodReconciler) labelBrokenPod(pod v1.Pod) (err error) {
 	if _, err = bpr.client.CoreV1().Pods(pod.Namespace).Update(context.TODO(), &pod, metav1.UpdateOptions{}); err != nil {
 		repairLog.Errorf("Failed to update pod: %s", err)
 		m.With(resultLabel.Value(resultFail)).Increment()
+		bpr.repairFailed(pod, "label", err)
 		return
 	}
 	m.With(resultLabel.Value(resultSuccess)).Increment()
+	bpr.event(pod, v1.EventTypeNormal, reasonLabeled,
+		fmt.Sprintf("Labeled pod with %s=%s", bpr.cfg.LabelKey, bpr.cfg.LabelValue))
 	return
 }
 
+func (bpr brokenPodReconciler) evictBrokenPod(pod v1.Pod) error {
+	m := podsRepaired.With(typeLabel.Value(evictType))
+	// Added for safety, to make sure no healthy pods get evicted.
+	if !bpr.detectPod(pod) {
+		m.With(resultLabel.Value(resultSkip)).Increment()
+		return nil
+	}
+	repairLog.Infof("Pod detected as broken, evicting: %s/%s", pod.Namespace, pod.Name)
+	err := bpr.client.CoreV1().Pods(pod.Namespace).EvictV1(context.TODO(), &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	})
+	if err != nil {
+		// The eviction is rejected while it would violate a PodDisruptionBudget, and retried.
+		m.With(resultLabel.Value(resultFail)).Increment()
+		bpr.repairFailed(pod, "evict", err)
+		return err
+	}
+	m.With(resultLabel.Value(resultSuccess)).Increment()
+	bpr.event(pod, v1.EventTypeNormal, reasonEvicted, "Evicted pod")
+	return nil
+}
+
+func (bpr brokenPodReconciler) failBrokenPodReadiness(pod v1.Pod) error {
+	m := podsRepaired.With(typeLabel.Value(readinessType))
+	// Added for safety, to make sure no healthy pods are marked as not ready.
+	if !bpr.detectPod(pod) {
+		m.With(resultLabel.Value(resultSkip)).Increment()
+		return nil
+	}
+	if readinessFailed(pod) {
+		m.With(resultLabel.Value(resultSkip)).Increment()
+		repairLog.Infof("Pod %s/%s already has condition %s set to false, skipping", pod.Namespace, pod.Name, ReadinessConditionType)
+		return nil
+	}
+
+	// Conditions are merged by type, leaving the other conditions of the pod untouched.
+	patch, err := json.Marshal(map[string]any{
+		"status": map[string]any{
+			"conditions": []v1.PodCondition{{
+				Type:               ReadinessConditionType,
+				Status:             v1.ConditionFalse,
+				LastTransitionTime: metav1.Now(),
+				Reason:             reasonDetected,
+				Message:            brokenPodMessage,
+			}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	repairLog.Infof("Setting condition %s of pod %s/%s to false", ReadinessConditionType, pod.Namespace, pod.Name)
+	if _, err := bpr.client.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.StrategicMergePatchType,
+		patch, metav1.PatchOptions{}, "status"); err != nil {
+		repairLog.Errorf("Failed to patch pod status: %s", err)
+		m.With(resultLabel.Value(resultFail)).Increment()
+		bpr.repairFailed(pod, "fail readiness of", err)
+		return err
+	}
+	m.With(resultLabel.Value(resultSuccess)).Increment()
+	bpr.event(pod, v1.EventTypeNormal, reasonNotReady, fmt.Sprintf("Set condition %s to false", ReadinessConditionType))
+	return nil
+}
+
 // Delete all pods detected as broken by ListPods
 func (bpr brokenPodReconciler) DeleteBrokenPods() error {
 	// Get a list of all broken pods
@@ -139,9 +297,11 @@ func (bpr brokenPodReconciler) deleteBrokenPod(pod v1.Pod) error {
 	err := bpr.client.CoreV1().Pods(pod.Namespace).Delete(context.TODO(), pod.Name, metav1.DeleteOptions{})
 	if err != nil {
 		m.With(resultLabel.Value(resultFail)).Increment()
+		bpr.repairFailed(pod, "delete", err)
 		return err
 	}
 	m.With(resultLabel.Value(resultSuccess)).Increment()
+	bpr.event(pod, v1.EventTypeNormal, reasonDeleted, "Deleted pod")
 	return nil
 }
 
@@ -199,6 +359,11 @@ func (bpr brokenPodReconciler) detectPod(pod v1.Pod) bool {
 		if bpr.cfg.InitContainerName != "" && container.Name != bpr.cfg.InitContainerName {
 			continue
 		}
+		// A proxy injected as a native sidecar is restarted like an init container, but it does not fail on a broken
+		// CNI configuration.
+		if container.Name == proxyContainerName {
+			continue
+		}
 
 		// For safety, check the containers *current* status. If the container
 		// successfully exited, we NEVER want to identify this pod as broken.
@@ -236,6 +401,7 @@ func StartRepair(ctx context.Context, cfg *config.RepairConfig) {
 	}
 
 	podFixer := newBrokenPodReconciler(clientSet, cfg)
+	podFixer.recorder = newEventRecorder(clientSet, cfg.NodeName)
 
 	if cfg.RunAsDaemon {
 		rc, err := NewRepairController(podFixer)
@@ -244,19 +410,19 @@ func StartRepair(ctx context.Context, cfg *config.RepairConfig) {
 		}
 		rc.Run(ctx.Done())
 	} else {
-		err = nil
-		if podFixer.cfg.LabelPods {
-			err = multierr.Append(err, podFixer.LabelBrokenPods())
-		}
-		if podFixer.cfg.DeletePods {
-			err = multierr.Append(err, podFixer.DeleteBrokenPods())
-		}
-		if err != nil {
+		if err := podFixer.RepairBrokenPods(); err != nil {
 			repairLog.Fatalf(err.Error())
 		}
 	}
 }
 
+// newEventRecorder returns a recorder of the events of the broken pods, sent to the API server.
+func newEventRecorder(client client.Interface, nodeName string) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: client.CoreV1().Events("")})
+	return broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventSourceName, Host: nodeName})
+}
+
 // Set up Kubernetes client using kubeconfig (or in-cluster config if no file provided)
 func clientSetup() (clientset *client.Clientset, err error) {
 	config, err := kube.DefaultRestConfig("", "")
@@ -30,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 
 	"istio.io/istio/cni/pkg/config"
@@ -204,6 +205,26 @@ func TestBrokenPodReconciler_detectPod(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"Check native sidecar proxy is ignored",
+			config.RepairConfig{
+				SidecarAnnotation: "sidecar.istio.io/status",
+				InitExitCode:      126,
+			},
+			args{
+				pod: *makePod(makePodArgs{
+					PodName:     "NativeSidecar",
+					Annotations: map[string]string{"sidecar.istio.io/status": "something"},
+					InitContainerStatus: &v1.ContainerStatus{
+						Name: proxyContainerName,
+						LastTerminationState: v1.ContainerState{
+							Terminated: &v1.ContainerStateTerminated{ExitCode: 126},
+						},
+					},
+				}),
+			},
+			false,
+		},
 		{
 			"Check badly formatted pod",
 			config.RepairConfig{
@@ -541,6 +562,134 @@ func TestBrokenPodReconciler_deleteBrokenPods(t *testing.T) {
 	}
 }
 
+func TestBrokenPodReconciler_ReconcilePod(t *testing.T) {
+	repairConfig := config.RepairConfig{
+		InitContainerName:  constants.ValidationContainerName,
+		InitExitCode:       126,
+		InitTerminationMsg: "Died for some reason",
+	}
+	repairedPod := *brokenPodWaiting.DeepCopy()
+	repairedPod.Labels = map[string]string{"testkey": "testval"}
+	repairedPod.Status.Conditions = append(repairedPod.Status.Conditions, v1.PodCondition{
+		Type:   ReadinessConditionType,
+		Status: v1.ConditionFalse,
+	})
+	tests := []struct {
+		name        string
+		configure   func(cfg *config.RepairConfig)
+		pod         v1.Pod
+		wantActions []string
+		wantEvents  []string
+		check       func(t *testing.T, client kubernetes.Interface)
+	}{
+		{
+			name:        "working pod",
+			configure:   func(cfg *config.RepairConfig) { cfg.EvictPods = true },
+			pod:         workingPod,
+			wantActions: []string{},
+			wantEvents:  []string{},
+		},
+		{
+			name:        "evict broken pod",
+			configure:   func(cfg *config.RepairConfig) { cfg.EvictPods = true },
+			pod:         brokenPodWaiting,
+			wantActions: []string{"create pods/eviction"},
+			wantEvents:  []string{"Warning " + reasonDetected, "Normal " + reasonEvicted},
+		},
+		{
+			name: "delete takes precedence over evict",
+			configure: func(cfg *config.RepairConfig) {
+				cfg.DeletePods = true
+				cfg.EvictPods = true
+			},
+			pod:         brokenPodWaiting,
+			wantActions: []string{"delete pods/"},
+			wantEvents:  []string{"Warning " + reasonDetected, "Normal " + reasonDeleted},
+		},
+		{
+			name: "fail readiness and label broken pod",
+			configure: func(cfg *config.RepairConfig) {
+				cfg.FailReadiness = true
+				cfg.LabelPods = true
+				cfg.LabelKey = "testkey"
+				cfg.LabelValue = "testval"
+			},
+			pod:         brokenPodWaiting,
+			wantActions: []string{"update pods/", "patch pods/status"},
+			wantEvents:  []string{"Warning " + reasonDetected, "Normal " + reasonLabeled, "Normal " + reasonNotReady},
+			check: func(t *testing.T, client kubernetes.Interface) {
+				pod, err := client.CoreV1().Pods(brokenPodWaiting.Namespace).Get(context.TODO(), brokenPodWaiting.Name, metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				found := false
+				for _, c := range pod.Status.Conditions {
+					if c.Type == ReadinessConditionType && c.Status == v1.ConditionFalse {
+						found = true
+					}
+				}
+				if !found {
+					t.Errorf("expected condition %s to be false, got %v", ReadinessConditionType, pod.Status.Conditions)
+				}
+				if pod.Labels["testkey"] != "testval" {
+					t.Errorf("expected label testkey=testval, got %v", pod.Labels)
+				}
+			},
+		},
+		{
+			name: "repaired pod is not reported again",
+			configure: func(cfg *config.RepairConfig) {
+				cfg.FailReadiness = true
+				cfg.LabelPods = true
+				cfg.LabelKey = "testkey"
+				cfg.LabelValue = "testval"
+			},
+			pod:         repairedPod,
+			wantActions: []string{},
+			wantEvents:  []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := repairConfig
+			tt.configure(&cfg)
+			client := fake.NewSimpleClientset(tt.pod.DeepCopy())
+			client.ClearActions()
+			recorder := record.NewFakeRecorder(10)
+			bpr := brokenPodReconciler{
+				client:   client,
+				cfg:      &cfg,
+				recorder: recorder,
+			}
+			if err := bpr.ReconcilePod(tt.pod); err != nil {
+				t.Fatalf("ReconcilePod() error = %v", err)
+			}
+
+			actions := []string{}
+			for _, a := range client.Actions() {
+				actions = append(actions, a.GetVerb()+" "+a.GetResource().Resource+"/"+a.GetSubresource())
+			}
+			if !reflect.DeepEqual(actions, tt.wantActions) {
+				t.Errorf("ReconcilePod() actions = %v, want %v", actions, tt.wantActions)
+			}
+
+			close(recorder.Events)
+			events := []string{}
+			for e := range recorder.Events {
+				// Events are formatted as "<type> <reason> <message>".
+				parts := strings.SplitN(e, " ", 3)
+				events = append(events, parts[0]+" "+parts[1])
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("ReconcilePod() events = %v, want %v", events, tt.wantEvents)
+			}
+			if tt.check != nil {
+				tt.check(t, client)
+			}
+		})
+	}
+}
+
 type testExporter struct {
 	sync.Mutex
 
@@ -0,0 +1,427 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/tag"
+	"istio.io/istio/operator/cmd/mesh"
+	analyzer_util "istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/kube"
+)
+
+// canaryOptions are the options of a canary upgrade.
+type canaryOptions struct {
+	// revision is the new control plane revision.
+	revision string
+	// tag is the revision tag pointed to the new revision, to which the namespaces are shifted.
+	tag string
+	// from is the revision or revision tag of the namespaces to shift.
+	from       string
+	percentage int
+	restart    bool
+
+	// baseline is the duration of the metrics the canary is compared to, before the namespaces are shifted.
+	baseline time.Duration
+	// duration is the duration of the analysis after the namespaces are shifted.
+	duration time.Duration
+	interval time.Duration
+	// maxErrorRateIncrease is the increase of the error rate, in percentage points, considered a regression.
+	maxErrorRateIncrease float64
+	// maxLatencyIncrease is the increase of the p99 latency, in percent, considered a regression.
+	maxLatencyIncrease float64
+	// controlPlaneSelector selects the metrics of the new control plane revision.
+	controlPlaneSelector string
+}
+
+func upgradeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: "Command group used to upgrade the data plane to a new control plane revision",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("unknown subcommand %q", args[0])
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.HelpFunc()(cmd, args)
+			return nil
+		},
+	}
+	cmd.AddCommand(canaryCmd())
+	return cmd
+}
+
+func canaryCmd() *cobra.Command {
+	opts := canaryOptions{}
+	cmd := &cobra.Command{
+		Use:   "canary",
+		Short: "Shift a percentage of namespaces to a new revision and roll back on regression",
+		Long: `Shifts a percentage of the namespaces injected by a revision or revision tag to a new control plane revision,
+then compares the metrics of the shifted namespaces to their metrics before the shift, and rolls back on regression.
+
+The new revision is referenced by a revision tag, "canary" by default, and the shifted namespaces are labeled with
+"istio.io/rev=<tag>". Their deployments are restarted, unless --restart=false, so that their sidecars are injected by
+the new revision. The namespaces are selected in alphabetical order.
+
+During the analysis, the following are checked every interval:
+  * the rate of 5xx responses of the workloads of the shifted namespaces, reported by the destination,
+  * the p99 request latency of the workloads of the shifted namespaces,
+  * the rate of configuration rejected by the proxies connected to the new control plane revision.
+
+If the error rate or the latency increase more than allowed compared to the baseline, if the configuration of the
+new revision is rejected, or if the command is interrupted, the namespaces are relabeled with their previous labels,
+the revision tag is restored or removed, and the deployments are restarted again.
+
+The metrics are queried from Prometheus, or from any server implementing the Prometheus query API, as with
+"istioctl experimental metrics".`,
+		Example: `  # Shift 20% of the namespaces of the default revision to revision 1-17-0, analyzing them for 10 minutes
+  istioctl experimental upgrade canary --revision 1-17-0 --percentage 20
+
+  # Shift half of the namespaces using the "prod" revision tag, with a Thanos query endpoint
+  istioctl experimental upgrade canary --revision 1-17-0 --from prod --percentage 50 \
+    --prometheus-address https://thanos.example.com --prometheus-bearer-token-file /var/run/secrets/token`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("canary does not accept arguments")
+			}
+			if opts.percentage <= 0 || opts.percentage > 100 {
+				return fmt.Errorf("--percentage must be between 1 and 100, got %d", opts.percentage)
+			}
+			if opts.tag == opts.from {
+				return fmt.Errorf("the namespaces of %q cannot be shifted to the same tag", opts.from)
+			}
+			if opts.interval <= 0 || opts.duration < opts.interval {
+				return fmt.Errorf("--duration must be at least one --interval")
+			}
+			return validatePrometheusFlags()
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create Kubernetes client: %v", err)
+			}
+			promAPI, closer, err := prometheusQueryAPI()
+			if err != nil {
+				return err
+			}
+			defer closer()
+			if opts.controlPlaneSelector == "" {
+				opts.controlPlaneSelector = fmt.Sprintf(`app="istiod",istio_io_rev=%q`, opts.revision)
+			}
+			ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer cancel()
+			return canaryUpgrade(ctx, kubeClient, promAPI, opts, c.OutOrStdout())
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&opts.revision, "revision", "r", "", "The new control plane revision")
+	cmd.PersistentFlags().StringVar(&opts.tag, "tag", "canary", "The revision tag referencing the new revision, to which the namespaces are shifted")
+	cmd.PersistentFlags().StringVar(&opts.from, "from", tag.DefaultRevisionName,
+		"The revision or revision tag of the namespaces to shift. The default revision includes the namespaces labeled istio-injection=enabled")
+	cmd.PersistentFlags().IntVar(&opts.percentage, "percentage", 10, "The percentage of the namespaces to shift")
+	cmd.PersistentFlags().BoolVar(&opts.restart, "restart", true, "Restart the deployments of the shifted namespaces")
+	cmd.PersistentFlags().DurationVar(&opts.baseline, "baseline", 10*time.Minute,
+		"The duration of the metrics before the shift to which the canary is compared")
+	cmd.PersistentFlags().DurationVarP(&opts.duration, "duration", "d", 10*time.Minute, "The duration of the analysis")
+	cmd.PersistentFlags().DurationVar(&opts.interval, "interval", time.Minute, "The interval at which the metrics are analyzed")
+	cmd.PersistentFlags().Float64Var(&opts.maxErrorRateIncrease, "max-error-rate-increase", 1,
+		"The increase of the 5xx error rate, in percentage points, considered a regression")
+	cmd.PersistentFlags().Float64Var(&opts.maxLatencyIncrease, "max-latency-increase", 20,
+		"The increase of the p99 latency, in percent, considered a regression")
+	cmd.PersistentFlags().StringVar(&opts.controlPlaneSelector, "control-plane-selector", "",
+		`The label matchers selecting the metrics of the new control plane revision. Defaults to app="istiod",istio_io_rev="<revision>"`)
+	cmd.PersistentFlags().StringVar(&manifestsPath, "manifests", "", mesh.ManifestsFlagHelpStr)
+	addPrometheusFlags(cmd.PersistentFlags())
+	_ = cmd.MarkPersistentFlagRequired("revision")
+	return cmd
+}
+
+// namespaceShift is a namespace shifted to the canary tag, with its previous injection labels. Empty values are
+// labels that were not set.
+type namespaceShift struct {
+	name      string
+	revision  string
+	injection string
+}
+
+// canaryMetrics are the metrics analyzed during a canary upgrade.
+type canaryMetrics struct {
+	errorRate  float64
+	p99Latency time.Duration
+	xdsRejects float64
+}
+
+func canaryUpgrade(ctx context.Context, client kube.CLIClient, promAPI promv1.API, opts canaryOptions, w io.Writer) error {
+	namespaces, err := canaryNamespaces(ctx, client.Kube(), opts.from, opts.percentage)
+	if err != nil {
+		return err
+	}
+	if len(namespaces) == 0 {
+		return fmt.Errorf("no namespaces are injected by %q", opts.from)
+	}
+	fmt.Fprintf(w, "Shifting namespaces %s to revision %q\n", strings.Join(namespaces, ","), opts.revision)
+
+	baseline, err := queryCanaryMetrics(promAPI, namespaces, opts.baseline, opts.controlPlaneSelector)
+	if err != nil {
+		return fmt.Errorf("failed to query baseline metrics: %v", err)
+	}
+	fmt.Fprintf(w, "Baseline: %s\n", baseline)
+
+	previousRevision, err := tagRevision(ctx, client.Kube(), opts.tag)
+	if err != nil {
+		return err
+	}
+	if err := applyTag(ctx, client, opts.tag, opts.revision); err != nil {
+		return err
+	}
+	shifts, err := shiftNamespaces(ctx, client.Kube(), namespaces, opts.tag)
+	rollback := func(reason string) error {
+		fmt.Fprintf(w, "Rolling back: %s\n", reason)
+		// The analysis context may be canceled, the rollback must not be.
+		ctx := context.Background()
+		if err := restoreNamespaces(ctx, client.Kube(), shifts); err != nil {
+			return fmt.Errorf("failed to restore the labels of the namespaces: %v", err)
+		}
+		if previousRevision != "" {
+			if err := applyTag(ctx, client, opts.tag, previousRevision); err != nil {
+				return fmt.Errorf("failed to restore revision tag %q: %v", opts.tag, err)
+			}
+		} else if err := tag.DeleteTagWebhooks(ctx, client.Kube(), opts.tag); err != nil {
+			return fmt.Errorf("failed to remove revision tag %q: %v", opts.tag, err)
+		}
+		if opts.restart {
+			if err := restartNamespaces(ctx, client.Kube(), namespaces); err != nil {
+				return err
+			}
+		}
+		return fmt.Errorf("canary upgrade to revision %q rolled back: %s", opts.revision, reason)
+	}
+	if err != nil {
+		return rollback(err.Error())
+	}
+	if opts.restart {
+		if err := restartNamespaces(ctx, client.Kube(), namespaces); err != nil {
+			return rollback(err.Error())
+		}
+	}
+
+	t := time.NewTicker(opts.interval)
+	defer t.Stop()
+	deadline := time.Now().Add(opts.duration)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return rollback("interrupted")
+		case <-t.C:
+		}
+		current, err := queryCanaryMetrics(promAPI, namespaces, opts.interval, opts.controlPlaneSelector)
+		if err != nil {
+			return rollback(fmt.Sprintf("failed to query metrics: %v", err))
+		}
+		fmt.Fprintf(w, "Canary: %s\n", current)
+		if reason := canaryRegression(baseline, current, opts); reason != "" {
+			return rollback(reason)
+		}
+	}
+	fmt.Fprintf(w, "Canary analysis of revision %q passed. To shift the remaining namespaces, run the command again with --from %s "+
+		"--percentage 100, or point their revision tag to the new revision with 'istioctl tag set'.\n", opts.revision, opts.from)
+	return nil
+}
+
+// applyTag points the revision tag to the revision, creating it if needed.
+func applyTag(ctx context.Context, client kube.CLIClient, tagName, revision string) error {
+	tagWhYAML, err := tag.Generate(ctx, client, &tag.GenerateOptions{
+		Tag:           tagName,
+		Revision:      revision,
+		ManifestsPath: manifestsPath,
+		Overwrite:     true,
+	}, istioNamespace)
+	if err != nil {
+		return err
+	}
+	if err := tag.Create(client, tagWhYAML); err != nil {
+		return fmt.Errorf("failed to apply tag webhook MutatingWebhookConfiguration to cluster: %v", err)
+	}
+	return nil
+}
+
+// canaryNamespaces returns the given percentage of the namespaces injected by the revision or tag, rounded up,
+// in alphabetical order.
+func canaryNamespaces(ctx context.Context, client kubernetes.Interface, from string, percentage int) ([]string, error) {
+	nsList, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %v", err)
+	}
+	var out []string
+	for _, ns := range nsList.Items {
+		rev, f := ns.Labels[label.IoIstioRev.Name]
+		if rev == from || (!f && from == tag.DefaultRevisionName && ns.Labels[analyzer_util.InjectionLabelName] == analyzer_util.InjectionLabelEnableValue) {
+			out = append(out, ns.Name)
+		}
+	}
+	sort.Strings(out)
+	n := int(math.Ceil(float64(len(out)*percentage) / 100))
+	return out[:n], nil
+}
+
+// tagRevision returns the revision referenced by the revision tag, or an empty string if the tag does not exist.
+func tagRevision(ctx context.Context, client kubernetes.Interface, tagName string) (string, error) {
+	webhooks, err := tag.GetWebhooksWithTag(ctx, client, tagName)
+	if err != nil {
+		return "", fmt.Errorf("failed to retrieve revision tag %q: %v", tagName, err)
+	}
+	if len(webhooks) == 0 {
+		return "", nil
+	}
+	return tag.GetWebhookRevision(webhooks[0])
+}
+
+// shiftNamespaces labels the namespaces with the revision tag, and returns their previous injection labels. The
+// namespaces shifted before an error are returned with it.
+func shiftNamespaces(ctx context.Context, client kubernetes.Interface, namespaces []string, tagName string) ([]namespaceShift, error) {
+	shifts := make([]namespaceShift, 0, len(namespaces))
+	for _, name := range namespaces {
+		ns, err := client.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return shifts, fmt.Errorf("failed to get namespace %s: %v", name, err)
+		}
+		shift := namespaceShift{
+			name:      name,
+			revision:  ns.Labels[label.IoIstioRev.Name],
+			injection: ns.Labels[analyzer_util.InjectionLabelName],
+		}
+		if err := patchInjectionLabels(ctx, client, name, tagName, ""); err != nil {
+			return shifts, err
+		}
+		shifts = append(shifts, shift)
+	}
+	return shifts, nil
+}
+
+// restoreNamespaces restores the injection labels of the shifted namespaces.
+func restoreNamespaces(ctx context.Context, client kubernetes.Interface, shifts []namespaceShift) error {
+	for _, shift := range shifts {
+		if err := patchInjectionLabels(ctx, client, shift.name, shift.revision, shift.injection); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchInjectionLabels sets the istio.io/rev and istio-injection labels of the namespace, removing the empty ones.
+func patchInjectionLabels(ctx context.Context, client kubernetes.Interface, namespace, revision, injection string) error {
+	value := func(v string) any {
+		if v == "" {
+			return nil
+		}
+		return v
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"labels": map[string]any{
+				label.IoIstioRev.Name:            value(revision),
+				analyzer_util.InjectionLabelName: value(injection),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := client.CoreV1().Namespaces().Patch(ctx, namespace, types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to label namespace %s: %v", namespace, err)
+	}
+	return nil
+}
+
+// restartNamespaces restarts the deployments of the namespaces, as kubectl rollout restart does.
+func restartNamespaces(ctx context.Context, client kubernetes.Interface, namespaces []string) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{"kubectl.kubernetes.io/restartedAt":%q}}}}}`,
+		time.Now().Format(time.RFC3339)))
+	for _, ns := range namespaces {
+		deployments, err := client.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to list the deployments of namespace %s: %v", ns, err)
+		}
+		for _, d := range deployments.Items {
+			if _, err := client.AppsV1().Deployments(ns).Patch(ctx, d.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+				return fmt.Errorf("failed to restart deployment %s.%s: %v", d.Name, ns, err)
+			}
+		}
+	}
+	return nil
+}
+
+func queryCanaryMetrics(promAPI promv1.API, namespaces []string, window time.Duration, controlPlaneSelector string) (canaryMetrics, error) {
+	selector := fmt.Sprintf(`%s=~"%s",reporter="destination"`, destWorkloadNamespaceLabel, strings.Join(namespaces, "|"))
+	value := func(query string) (float64, error) {
+		v, err := vectorValues(promAPI, query, nil)
+		return v[""], err
+	}
+	var out canaryMetrics
+	total, err := value(fmt.Sprintf("sum(rate(%s{%s}[%s]))", reqTot, selector, window))
+	if err != nil {
+		return out, err
+	}
+	failed, err := value(fmt.Sprintf(`sum(rate(%s{%s,response_code=~"5.."}[%s]))`, reqTot, selector, window))
+	if err != nil {
+		return out, err
+	}
+	if total > 0 {
+		out.errorRate = failed / total
+	}
+	latency, err := value(fmt.Sprintf("histogram_quantile(0.99, sum(rate(%s_bucket{%s}[%s])) by (le))", reqDur, selector, window))
+	if err != nil {
+		return out, err
+	}
+	out.p99Latency = convertLatencyToDuration(latency)
+	out.xdsRejects, err = value(fmt.Sprintf("sum(rate(pilot_total_xds_rejects{%s}[%s]))", controlPlaneSelector, window))
+	return out, err
+}
+
+// canaryRegression returns the reason the canary metrics are a regression from the baseline, if any.
+func canaryRegression(baseline, current canaryMetrics, opts canaryOptions) string {
+	if current.errorRate-baseline.errorRate > opts.maxErrorRateIncrease/100 {
+		return fmt.Sprintf("the error rate increased from %.2f%% to %.2f%%", 100*baseline.errorRate, 100*current.errorRate)
+	}
+	if baseline.p99Latency > 0 && float64(current.p99Latency) > float64(baseline.p99Latency)*(1+opts.maxLatencyIncrease/100) {
+		return fmt.Sprintf("the p99 latency increased from %s to %s", baseline.p99Latency, current.p99Latency)
+	}
+	if current.xdsRejects > 0 {
+		return fmt.Sprintf("proxies rejected the configuration of revision %q", opts.revision)
+	}
+	return ""
+}
+
+func (m canaryMetrics) String() string {
+	return fmt.Sprintf("error rate %.2f%%, p99 latency %s, rejected configurations %.3f/s", 100*m.errorRate, m.p99Latency, m.xdsRejects)
+}
@@ -0,0 +1,178 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	prometheus_model "github.com/prometheus/common/model"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func namespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestCanaryNamespaces(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		namespace("a", map[string]string{"istio-injection": "enabled"}),
+		namespace("b", map[string]string{"istio.io/rev": "default"}),
+		namespace("c", map[string]string{"istio-injection": "enabled"}),
+		namespace("d", map[string]string{"istio.io/rev": "prod"}),
+		namespace("e", map[string]string{"istio.io/rev": "prod", "istio-injection": "enabled"}),
+		namespace("f", nil),
+	)
+	cases := []struct {
+		from       string
+		percentage int
+		want       []string
+	}{
+		{from: "default", percentage: 100, want: []string{"a", "b", "c"}},
+		{from: "default", percentage: 50, want: []string{"a", "b"}},
+		{from: "default", percentage: 1, want: []string{"a"}},
+		{from: "prod", percentage: 100, want: []string{"d", "e"}},
+		{from: "canary", percentage: 100, want: nil},
+	}
+	for _, c := range cases {
+		got, err := canaryNamespaces(context.Background(), client, c.from, c.percentage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("canaryNamespaces(%s, %d): got %v, want %v", c.from, c.percentage, got, c.want)
+		}
+	}
+}
+
+func TestShiftNamespaces(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		namespace("a", map[string]string{"istio-injection": "enabled", "team": "a"}),
+		namespace("b", map[string]string{"istio.io/rev": "prod"}),
+	)
+	labels := func(name string) map[string]string {
+		ns, err := client.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ns.Labels
+	}
+
+	shifts, err := shiftNamespaces(ctx, client, []string{"a", "b"}, "canary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := labels("a"); !reflect.DeepEqual(got, map[string]string{"istio.io/rev": "canary", "team": "a"}) {
+		t.Errorf("unexpected labels of shifted namespace a: %v", got)
+	}
+	if got := labels("b"); !reflect.DeepEqual(got, map[string]string{"istio.io/rev": "canary"}) {
+		t.Errorf("unexpected labels of shifted namespace b: %v", got)
+	}
+
+	if err := restoreNamespaces(ctx, client, shifts); err != nil {
+		t.Fatal(err)
+	}
+	if got := labels("a"); !reflect.DeepEqual(got, map[string]string{"istio-injection": "enabled", "team": "a"}) {
+		t.Errorf("unexpected labels of restored namespace a: %v", got)
+	}
+	if got := labels("b"); !reflect.DeepEqual(got, map[string]string{"istio.io/rev": "prod"}) {
+		t.Errorf("unexpected labels of restored namespace b: %v", got)
+	}
+}
+
+func TestRestartNamespaces(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "a"}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "ratings", Namespace: "b"}},
+	)
+	if err := restartNamespaces(ctx, client, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	restarted := func(name, ns string) bool {
+		d, err := client.AppsV1().Deployments(ns).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, f := d.Spec.Template.Annotations["kubectl.kubernetes.io/restartedAt"]
+		return f
+	}
+	if !restarted("reviews", "a") || restarted("ratings", "b") {
+		t.Errorf("expected only the deployments of namespace a to be restarted")
+	}
+}
+
+func TestQueryCanaryMetrics(t *testing.T) {
+	sample := func(v float64) prometheus_model.Vector {
+		return prometheus_model.Vector{&prometheus_model.Sample{Value: prometheus_model.SampleValue(v)}}
+	}
+	mockProm := mockPromAPI{
+		cannedResponse: map[string]prometheus_model.Value{
+			`sum(rate(istio_requests_total{destination_workload_namespace=~"a|b",reporter="destination"}[1m0s]))`:                                                         sample(10),  // nolint: lll
+			`sum(rate(istio_requests_total{destination_workload_namespace=~"a|b",reporter="destination",response_code=~"5.."}[1m0s]))`:                                    sample(0.5), // nolint: lll
+			`histogram_quantile(0.99, sum(rate(istio_request_duration_milliseconds_bucket{destination_workload_namespace=~"a|b",reporter="destination"}[1m0s])) by (le))`: sample(12),  // nolint: lll
+			`sum(rate(pilot_total_xds_rejects{app="istiod",istio_io_rev="1-17-0"}[1m0s]))`:                                                                                sample(0.1), // nolint: lll
+		},
+	}
+	got, err := queryCanaryMetrics(mockProm, []string{"a", "b"}, time.Minute, `app="istiod",istio_io_rev="1-17-0"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := canaryMetrics{errorRate: 0.05, p99Latency: 12 * time.Millisecond, xdsRejects: 0.1}
+	if got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestCanaryRegression(t *testing.T) {
+	opts := canaryOptions{revision: "1-17-0", maxErrorRateIncrease: 1, maxLatencyIncrease: 20}
+	baseline := canaryMetrics{errorRate: 0.01, p99Latency: 100 * time.Millisecond}
+	cases := []struct {
+		name    string
+		current canaryMetrics
+		want    string
+	}{
+		{name: "healthy", current: canaryMetrics{errorRate: 0.015, p99Latency: 110 * time.Millisecond}},
+		{name: "improved", current: canaryMetrics{p99Latency: 50 * time.Millisecond}},
+		{
+			name:    "errors",
+			current: canaryMetrics{errorRate: 0.03, p99Latency: 100 * time.Millisecond},
+			want:    "the error rate increased from 1.00% to 3.00%",
+		},
+		{
+			name:    "latency",
+			current: canaryMetrics{errorRate: 0.01, p99Latency: 130 * time.Millisecond},
+			want:    "the p99 latency increased from 100ms to 130ms",
+		},
+		{
+			name:    "rejects",
+			current: canaryMetrics{errorRate: 0.01, p99Latency: 100 * time.Millisecond, xdsRejects: 0.2},
+			want:    `proxies rejected the configuration of revision "1-17-0"`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := canaryRegression(baseline, c.current, opts); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/pilot/cmd/pilot-agent/status/util"
+)
+
+// connectionPoolPressureThreshold is the utilization of a connection pool limit above which tuning is suggested.
+const connectionPoolPressureThreshold = 0.8
+
+// connectionPoolSettings maps connection pool resources to the DestinationRule setting limiting them.
+var connectionPoolSettings = map[string]string{
+	util.ResourceConnections:     "connectionPool.tcp.maxConnections",
+	util.ResourcePendingRequests: "connectionPool.http.http1MaxPendingRequests",
+	util.ResourceRequests:        "connectionPool.http.http2MaxRequests",
+	util.ResourceRetries:         "connectionPool.http.maxRetries",
+}
+
+var connectionPoolResources = []string{
+	util.ResourceConnections,
+	util.ResourcePendingRequests,
+	util.ResourceRequests,
+	util.ResourceRetries,
+}
+
+func connectionPoolCmd() *cobra.Command {
+	var all bool
+	cmd := &cobra.Command{
+		Use:   "connection-pool [<type>/]<name>[.<namespace>]",
+		Short: "Analyze the connection pool pressure of the upstream services of a pod",
+		Long: `Compares the connections and requests in use by the Envoy instance in the specified pod against the
+connection pool limits configured for each upstream service by DestinationRules, and suggests DestinationRule
+settings to tune when a limit is close to being reached or was hit.
+
+The usage and overflow stats of the connection pools are only available if the proxy was started with
+ENABLE_CONNECTION_POOL_METRICS set, for example through the proxyMetadata of the mesh or of the workload.`,
+		Example: `  # Analyze the connection pools of a pod
+  istioctl experimental connection-pool productpage-v1-7d79b4c9f-4zr2k.default
+
+  # Also show upstream services which are not under pressure
+  istioctl experimental connection-pool deployment/productpage-v1 --all`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("connection-pool requires a pod name")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			podName, podNamespace, err := getPodName(args[0])
+			if err != nil {
+				return err
+			}
+			kubeClient, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create Kubernetes client: %v", err)
+			}
+			ctx := context.Background()
+			clusters, err := kubeClient.EnvoyDo(ctx, podName, podNamespace, "GET", "clusters?format=json")
+			if err != nil {
+				return fmt.Errorf("failed to retrieve clusters of %s.%s: %v", podName, podNamespace, err)
+			}
+			stats, err := kubeClient.EnvoyDo(ctx, podName, podNamespace, "GET",
+				"stats?filter="+url.QueryEscape(util.ConnectionPoolStatsRegex))
+			if err != nil {
+				return fmt.Errorf("failed to retrieve connection pool stats of %s.%s: %v", podName, podNamespace, err)
+			}
+			usages, err := analyzeConnectionPools(clusters, stats)
+			if err != nil {
+				return err
+			}
+			if len(usages) == 0 {
+				return fmt.Errorf("no connection pool stats found for %s.%s; set ENABLE_CONNECTION_POOL_METRICS "+
+					"in the proxyMetadata of the workload and restart it", podName, podNamespace)
+			}
+			printConnectionPools(c.OutOrStdout(), usages, all)
+			return nil
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+	cmd.PersistentFlags().BoolVar(&all, "all", false,
+		"Show all upstream services, not only those whose connection pool is under pressure")
+	return cmd
+}
+
+// connectionPoolUsage is the usage of one connection pool limit of an upstream service.
+type connectionPoolUsage struct {
+	service  string
+	port     string
+	subset   string
+	resource string
+	limit    uint64
+	inUse    uint64
+	overflow uint64
+}
+
+// unlimited reports whether the limit is the default one, set when the DestinationRule does not configure it.
+func (u connectionPoolUsage) unlimited() bool {
+	return u.limit >= math.MaxUint32
+}
+
+func (u connectionPoolUsage) utilization() float64 {
+	if u.limit == 0 || u.unlimited() {
+		return 0
+	}
+	return float64(u.inUse) / float64(u.limit)
+}
+
+func (u connectionPoolUsage) underPressure() bool {
+	return u.overflow > 0 || u.utilization() >= connectionPoolPressureThreshold
+}
+
+// suggestion returns the recommended DestinationRule change, or an empty string if no change is needed.
+func (u connectionPoolUsage) suggestion() string {
+	if !u.underPressure() {
+		return ""
+	}
+	setting := connectionPoolSettings[u.resource]
+	if u.unlimited() {
+		return fmt.Sprintf("%d %s overflowed; check the %s of other DestinationRules for this host",
+			u.overflow, u.resource, setting)
+	}
+	// Growing the limit by the observed overflow is a lower bound, round it up to leave some headroom.
+	suggested := uint64(math.Ceil(float64(u.limit+u.overflow) * 1.5))
+	if u.overflow == 0 {
+		return fmt.Sprintf("%.0f%% of the %s limit is in use; consider increasing %s to at least %d",
+			u.utilization()*100, u.resource, setting, suggested)
+	}
+	return fmt.Sprintf("%d %s overflowed the limit of %d; consider increasing %s to at least %d",
+		u.overflow, u.resource, u.limit, setting, suggested)
+}
+
+// analyzeConnectionPools joins the circuit breaker thresholds from the Envoy clusters endpoint with the
+// connection pool stats of each outbound service cluster.
+func analyzeConnectionPools(clusters, stats []byte) ([]connectionPoolUsage, error) {
+	var resp struct {
+		ClusterStatuses []struct {
+			Name            string `json:"name"`
+			CircuitBreakers struct {
+				Thresholds []struct {
+					Priority           string `json:"priority"`
+					MaxConnections     uint64 `json:"max_connections"`
+					MaxPendingRequests uint64 `json:"max_pending_requests"`
+					MaxRequests        uint64 `json:"max_requests"`
+					MaxRetries         uint64 `json:"max_retries"`
+				} `json:"thresholds"`
+			} `json:"circuit_breakers"`
+		} `json:"cluster_statuses"`
+	}
+	if err := json.Unmarshal(clusters, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse clusters: %v", err)
+	}
+	limits := map[string]map[string]uint64{}
+	for _, c := range resp.ClusterStatuses {
+		for _, t := range c.CircuitBreakers.Thresholds {
+			// The priority is omitted for the default priority.
+			if t.Priority != "" && t.Priority != "DEFAULT" {
+				continue
+			}
+			limits[c.Name] = map[string]uint64{
+				util.ResourceConnections:     t.MaxConnections,
+				util.ResourcePendingRequests: t.MaxPendingRequests,
+				util.ResourceRequests:        t.MaxRequests,
+				util.ResourceRetries:         t.MaxRetries,
+			}
+		}
+	}
+
+	poolStats, err := util.ParseConnectionPoolStats(bytes.NewBuffer(stats))
+	if err != nil {
+		return nil, err
+	}
+	var usages []connectionPoolUsage
+	for _, s := range poolStats {
+		l, f := limits[s.Cluster]
+		if !f {
+			continue
+		}
+		for _, resource := range connectionPoolResources {
+			remaining, f := s.Remaining[resource]
+			if !f {
+				continue
+			}
+			u := connectionPoolUsage{
+				service:  s.Service,
+				port:     s.Port,
+				subset:   s.Subset,
+				resource: resource,
+				limit:    l[resource],
+				overflow: s.Overflow[resource],
+			}
+			if remaining < u.limit {
+				u.inUse = u.limit - remaining
+			}
+			usages = append(usages, u)
+		}
+	}
+	return usages, nil
+}
+
+func printConnectionPools(writer io.Writer, usages []connectionPoolUsage, all bool) {
+	w := tabwriter.NewWriter(writer, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SERVICE\tPORT\tSUBSET\tRESOURCE\tLIMIT\tIN USE\tOVERFLOW")
+	var suggestions []string
+	for _, u := range usages {
+		if !all && !u.underPressure() {
+			continue
+		}
+		limit := fmt.Sprint(u.limit)
+		if u.unlimited() {
+			limit = "unlimited"
+		}
+		subset := u.subset
+		if subset == "" {
+			subset = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%d\n", u.service, u.port, subset, u.resource, limit, u.inUse, u.overflow)
+		if s := u.suggestion(); s != "" {
+			target := u.service + ":" + u.port
+			if u.subset != "" {
+				target += " subset " + u.subset
+			}
+			suggestions = append(suggestions, fmt.Sprintf("%s: %s", target, s))
+		}
+	}
+	_ = w.Flush()
+	if len(suggestions) == 0 {
+		_, _ = fmt.Fprintln(writer, "\nNo connection pool is under pressure.")
+		return
+	}
+	_, _ = fmt.Fprintln(writer, "\nSuggested DestinationRule tuning:")
+	for _, s := range suggestions {
+		_, _ = fmt.Fprintf(writer, "  %s\n", s)
+	}
+}
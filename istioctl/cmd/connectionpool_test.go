@@ -0,0 +1,82 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAnalyzeConnectionPools(t *testing.T) {
+	clusters := []byte(`{"cluster_statuses":[
+		{"name":"outbound|9080|v1|reviews.default.svc.cluster.local","circuit_breakers":{"thresholds":[
+			{"max_connections":100,"max_pending_requests":10,"max_requests":4294967295,"max_retries":4294967295},
+			{"priority":"HIGH","max_connections":1024,"max_pending_requests":1024,"max_requests":1024,"max_retries":3}
+		]}},
+		{"name":"outbound|9080||ratings.default.svc.cluster.local","circuit_breakers":{"thresholds":[
+			{"max_connections":4294967295,"max_pending_requests":4294967295,"max_requests":4294967295,"max_retries":4294967295}
+		]}}
+	]}`)
+	stats := []byte(`cluster.outbound|9080|v1|reviews.default.svc.cluster.local.circuit_breakers.default.remaining_cx: 10
+cluster.outbound|9080|v1|reviews.default.svc.cluster.local.circuit_breakers.default.remaining_pending: 10
+cluster.outbound|9080|v1|reviews.default.svc.cluster.local.upstream_cx_overflow: 0
+cluster.outbound|9080|v1|reviews.default.svc.cluster.local.upstream_rq_pending_overflow: 5
+cluster.outbound|9080||ratings.default.svc.cluster.local.circuit_breakers.default.remaining_cx: 4294967290
+cluster.outbound|9080||ratings.default.svc.cluster.local.upstream_cx_overflow: 0
+`)
+	usages, err := analyzeConnectionPools(clusters, stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []connectionPoolUsage{
+		{service: "reviews.default.svc.cluster.local", port: "9080", subset: "v1", resource: "connections", limit: 100, inUse: 90},
+		{service: "reviews.default.svc.cluster.local", port: "9080", subset: "v1", resource: "pending_requests", limit: 10, overflow: 5},
+		{service: "ratings.default.svc.cluster.local", port: "9080", resource: "connections", limit: 4294967295, inUse: 5},
+	}
+	if len(usages) != len(want) {
+		t.Fatalf("got %d usages, want %d: %+v", len(usages), len(want), usages)
+	}
+	for i := range want {
+		if usages[i] != want[i] {
+			t.Errorf("usage %d: got %+v, want %+v", i, usages[i], want[i])
+		}
+	}
+
+	if usages[2].underPressure() {
+		t.Errorf("unlimited connection pool should not be under pressure")
+	}
+	if got := usages[0].suggestion(); !strings.Contains(got, "90% of the connections limit") ||
+		!strings.Contains(got, "connectionPool.tcp.maxConnections to at least 150") {
+		t.Errorf("unexpected suggestion: %q", got)
+	}
+	if got := usages[1].suggestion(); !strings.Contains(got, "5 pending_requests overflowed the limit of 10") ||
+		!strings.Contains(got, "connectionPool.http.http1MaxPendingRequests to at least 23") {
+		t.Errorf("unexpected suggestion: %q", got)
+	}
+
+	var out bytes.Buffer
+	printConnectionPools(&out, usages, false)
+	if strings.Contains(out.String(), "ratings") {
+		t.Errorf("services which are not under pressure should be hidden:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "reviews.default.svc.cluster.local:9080 subset v1: ") {
+		t.Errorf("missing suggestion for reviews:\n%s", out.String())
+	}
+
+	if _, err := analyzeConnectionPools([]byte("not json"), stats); err == nil {
+		t.Errorf("expected error for invalid clusters")
+	}
+}
@@ -516,6 +516,7 @@ func dashboard() *cobra.Command {
 	dashboardCmd.AddCommand(jaegerDashCmd())
 	dashboardCmd.AddCommand(zipkinDashCmd())
 	dashboardCmd.AddCommand(skywalkingDashCmd())
+	dashboardCmd.AddCommand(meshDashCmd())
 
 	envoy := envoyDashCmd()
 	envoy.PersistentFlags().StringVarP(&labelSelector, "selector", "l", "", "Label selector")
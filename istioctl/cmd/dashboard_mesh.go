@@ -0,0 +1,330 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/expfmt"
+	"github.com/spf13/cobra"
+
+	"istio.io/api/label"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/kube"
+	"istio.io/pkg/log"
+)
+
+//go:embed dashboard_mesh.html
+var meshDashboardHTML []byte
+
+// meshDashboardPort is the default local port of the mesh dashboard.
+const meshDashboardPort = 15200
+
+// meshGraph is the service graph rendered by the mesh dashboard.
+type meshGraph struct {
+	Nodes []*meshGraphNode `json:"nodes"`
+	Edges []*meshGraphEdge `json:"edges"`
+}
+
+// meshGraphNode is a service of the registry of istiod, or a workload calling services.
+type meshGraphNode struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Policies are the security policies applying to the namespace of the node, mesh-wide policies included.
+	Policies []string `json:"policies,omitempty"`
+}
+
+// meshGraphEdge is the traffic from a workload to a service, as reported by the proxies of the service.
+type meshGraphEdge struct {
+	Source      string  `json:"source"`
+	Destination string  `json:"destination"`
+	Protocol    string  `json:"protocol"`
+	Requests    float64 `json:"requests"`
+	Errors      float64 `json:"errors"`
+	// MTLS is "mutual_tls" or "none" if all the traffic of the edge is or is not mutual TLS, "mixed" otherwise.
+	MTLS string `json:"mtls"`
+}
+
+const (
+	meshGraphService  = "service"
+	meshGraphWorkload = "workload"
+)
+
+// meshPolicyKinds are the kinds of the configs listed as the active policies of the nodes.
+var meshPolicyKinds = map[string]bool{
+	"AuthorizationPolicy":   true,
+	"PeerAuthentication":    true,
+	"RequestAuthentication": true,
+}
+
+func meshDashCmd() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	cmd := &cobra.Command{
+		Use:   "mesh",
+		Short: "Open the mesh topology web UI",
+		Long: `Serves a local web UI rendering the service graph of the mesh, without any addon installed in the cluster.
+
+The services are read from the registry of istiod, and the traffic between the workloads and the services from the
+metrics of the sidecars. Edges show the protocol, the number of requests and errors, and whether the traffic is
+mutual TLS; nodes show the authorization and authentication policies applying to their namespace. The graph is
+refreshed on each page load.`,
+		Example: `  istioctl dashboard mesh
+
+  # with short syntax
+  istioctl dash mesh
+  istioctl d mesh`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			client, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			port := meshDashboardPort
+			if listenPort != 0 {
+				port = listenPort
+			}
+			l, err := net.Listen("tcp", net.JoinHostPort(bindAddress, strconv.Itoa(port)))
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s:%d: %v", bindAddress, port, err)
+			}
+			closeListenerOnInterrupt(l)
+			openBrowser("http://"+l.Addr().String(), c.OutOrStdout(), browser)
+			err = http.Serve(l, meshDashboardHandler(client))
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	return cmd
+}
+
+func closeListenerOnInterrupt(l net.Listener) {
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt)
+		defer signal.Stop(signals)
+		<-signals
+		_ = l.Close()
+	}()
+}
+
+func meshDashboardHandler(client kube.CLIClient) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(meshDashboardHTML)
+	})
+	mux.HandleFunc("/api/graph", func(w http.ResponseWriter, req *http.Request) {
+		graph, err := fetchMeshGraph(req.Context(), client)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(graph)
+	})
+	return mux
+}
+
+// fetchMeshGraph reads the services and policies from istiod, and the metrics of the sidecars.
+func fetchMeshGraph(ctx context.Context, client kube.CLIClient) (*meshGraph, error) {
+	var services []snapshotService
+	if err := readFromIstiod(ctx, client, "/debug/registryz", &services); err != nil {
+		return nil, err
+	}
+	var configs []map[string]any
+	if err := readFromIstiod(ctx, client, "/debug/configz", &configs); err != nil {
+		return nil, err
+	}
+
+	pods, err := client.PodsForSelector(ctx, "", label.SecurityTlsMode.Name+"="+model.IstioMutualTLSModeLabel)
+	if err != nil {
+		return nil, err
+	}
+	stats := map[string][]byte{}
+	for _, pod := range pods.Items {
+		out, err := client.EnvoyDo(ctx, pod.Name, pod.Namespace, "GET", "stats/prometheus")
+		if err != nil {
+			log.Warnf("failed to read the metrics of %s/%s: %v", pod.Namespace, pod.Name, err)
+			continue
+		}
+		stats[pod.Namespace+"/"+pod.Name] = out
+	}
+	return buildMeshGraph(istioNamespace, services, configs, stats), nil
+}
+
+// readFromIstiod decodes the response of the debug endpoint of the first istiod instance answering.
+func readFromIstiod(ctx context.Context, client kube.CLIClient, path string, out any) error {
+	res, err := client.AllDiscoveryDo(ctx, istioNamespace, path)
+	if err != nil {
+		return err
+	}
+	istiods := make([]string, 0, len(res))
+	for istiod := range res {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	for _, istiod := range istiods {
+		if err := json.Unmarshal(res[istiod], out); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("no istiod instance returned %s", path)
+}
+
+// buildMeshGraph builds the service graph from the services of the registry, the Istio configs, and the metrics
+// of the sidecars in the Prometheus text format. The policies of the root namespace apply to all the nodes.
+func buildMeshGraph(rootNamespace string, services []snapshotService, configs []map[string]any, stats map[string][]byte) *meshGraph {
+	policies := map[string][]string{}
+	for _, c := range configs {
+		kind, _ := c["kind"].(string)
+		if !meshPolicyKinds[kind] {
+			continue
+		}
+		meta, _ := c["metadata"].(map[string]any)
+		name, _ := meta["name"].(string)
+		ns, _ := meta["namespace"].(string)
+		policies[ns] = append(policies[ns], kind+"/"+ns+"/"+name)
+	}
+	policiesOf := func(ns string) []string {
+		out := append([]string{}, policies[rootNamespace]...)
+		if ns != rootNamespace {
+			out = append(out, policies[ns]...)
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	nodes := map[string]*meshGraphNode{}
+	for _, svc := range services {
+		nodes[svc.Hostname] = &meshGraphNode{
+			ID:        svc.Hostname,
+			Kind:      meshGraphService,
+			Name:      svc.Attributes.Name,
+			Namespace: svc.Attributes.Namespace,
+			Policies:  policiesOf(svc.Attributes.Namespace),
+		}
+	}
+
+	edges := map[string]*meshGraphEdge{}
+	mtls := map[string]map[string]bool{}
+	for _, proxy := range sortedKeys(stats) {
+		families, err := new(expfmt.TextParser).TextToMetricFamilies(bytes.NewReader(stats[proxy]))
+		if err != nil {
+			log.Warnf("failed to parse the metrics of %s: %v", proxy, err)
+			continue
+		}
+		for name, familyProtocol := range map[string]string{
+			"istio_requests_total":               "http",
+			"istio_tcp_connections_opened_total": "tcp",
+		} {
+			family, f := families[name]
+			if !f {
+				continue
+			}
+			for _, m := range family.GetMetric() {
+				labels := map[string]string{}
+				for _, l := range m.GetLabel() {
+					labels[l.GetName()] = l.GetValue()
+				}
+				// The destination proxies report the traffic they receive, each request is counted once.
+				if labels["reporter"] != "destination" {
+					continue
+				}
+				source := labels["source_workload"] + "." + labels["source_workload_namespace"]
+				if _, f := nodes[source]; !f {
+					nodes[source] = &meshGraphNode{
+						ID:        source,
+						Kind:      meshGraphWorkload,
+						Name:      labels["source_workload"],
+						Namespace: labels["source_workload_namespace"],
+						Policies:  policiesOf(labels["source_workload_namespace"]),
+					}
+				}
+				dest := labels["destination_service"]
+				if _, f := nodes[dest]; !f {
+					nodes[dest] = &meshGraphNode{
+						ID:        dest,
+						Kind:      meshGraphService,
+						Name:      labels["destination_service_name"],
+						Namespace: labels["destination_service_namespace"],
+						Policies:  policiesOf(labels["destination_service_namespace"]),
+					}
+				}
+				protocol := familyProtocol
+				if protocol == "http" && labels["request_protocol"] == "grpc" {
+					protocol = "grpc"
+				}
+				key := source + "|" + dest + "|" + protocol
+				e, f := edges[key]
+				if !f {
+					e = &meshGraphEdge{Source: source, Destination: dest, Protocol: protocol}
+					edges[key] = e
+					mtls[key] = map[string]bool{}
+				}
+				value := m.GetCounter().GetValue()
+				e.Requests += value
+				if strings.HasPrefix(labels["response_code"], "5") {
+					e.Errors += value
+				}
+				mtls[key][labels["connection_security_policy"]] = true
+			}
+		}
+	}
+
+	graph := &meshGraph{}
+	for _, id := range sortedKeys(nodes) {
+		graph.Nodes = append(graph.Nodes, nodes[id])
+	}
+	for _, key := range sortedKeys(edges) {
+		e := edges[key]
+		switch {
+		case len(mtls[key]) > 1:
+			e.MTLS = "mixed"
+		case mtls[key]["mutual_tls"]:
+			e.MTLS = "mutual_tls"
+		default:
+			e.MTLS = "none"
+		}
+		graph.Edges = append(graph.Edges, e)
+	}
+	return graph
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
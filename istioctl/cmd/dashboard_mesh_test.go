@@ -0,0 +1,62 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestBuildMeshGraph(t *testing.T) {
+	reviews := snapshotService{Hostname: "reviews.default.svc.cluster.local"}
+	reviews.Attributes.Name = "reviews"
+	reviews.Attributes.Namespace = "default"
+	configs := []map[string]any{
+		{"kind": "PeerAuthentication", "metadata": map[string]any{"name": "default", "namespace": "istio-system"}},
+		{"kind": "AuthorizationPolicy", "metadata": map[string]any{"name": "allow", "namespace": "default"}},
+		{"kind": "VirtualService", "metadata": map[string]any{"name": "reviews", "namespace": "default"}},
+	}
+	stats := map[string][]byte{
+		"default/reviews-v1": []byte(`# TYPE istio_requests_total counter
+istio_requests_total{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_service="reviews.default.svc.cluster.local",destination_service_name="reviews",destination_service_namespace="default",request_protocol="http",response_code="200",connection_security_policy="mutual_tls"} 10
+istio_requests_total{reporter="destination",source_workload="productpage-v1",source_workload_namespace="default",destination_service="reviews.default.svc.cluster.local",destination_service_name="reviews",destination_service_namespace="default",request_protocol="http",response_code="503",connection_security_policy="mutual_tls"} 2
+istio_requests_total{reporter="source",source_workload="reviews-v1",source_workload_namespace="default",destination_service="ratings.default.svc.cluster.local",destination_service_name="ratings",destination_service_namespace="default",request_protocol="http",response_code="200",connection_security_policy="unknown"} 5
+`),
+		"default/mysql-v1": []byte(`# TYPE istio_tcp_connections_opened_total counter
+istio_tcp_connections_opened_total{reporter="destination",source_workload="ratings-v2",source_workload_namespace="default",destination_service="mysql.default.svc.cluster.local",destination_service_name="mysql",destination_service_namespace="default",connection_security_policy="mutual_tls"} 1
+istio_tcp_connections_opened_total{reporter="destination",source_workload="ratings-v2",source_workload_namespace="default",destination_service="mysql.default.svc.cluster.local",destination_service_name="mysql",destination_service_namespace="default",connection_security_policy="none"} 3
+`),
+	}
+
+	graph := buildMeshGraph("istio-system", []snapshotService{reviews}, configs, stats)
+	policies := []string{"AuthorizationPolicy/default/allow", "PeerAuthentication/istio-system/default"}
+	assert.Equal(t, graph.Nodes, []*meshGraphNode{
+		{ID: "mysql.default.svc.cluster.local", Kind: meshGraphService, Name: "mysql", Namespace: "default", Policies: policies},
+		{ID: "productpage-v1.default", Kind: meshGraphWorkload, Name: "productpage-v1", Namespace: "default", Policies: policies},
+		{ID: "ratings-v2.default", Kind: meshGraphWorkload, Name: "ratings-v2", Namespace: "default", Policies: policies},
+		{ID: "reviews.default.svc.cluster.local", Kind: meshGraphService, Name: "reviews", Namespace: "default", Policies: policies},
+	})
+	assert.Equal(t, graph.Edges, []*meshGraphEdge{
+		{
+			Source: "productpage-v1.default", Destination: "reviews.default.svc.cluster.local", Protocol: "http",
+			Requests: 12, Errors: 2, MTLS: "mutual_tls",
+		},
+		{
+			Source: "ratings-v2.default", Destination: "mysql.default.svc.cluster.local", Protocol: "tcp",
+			Requests: 4, MTLS: "mixed",
+		},
+	})
+}
@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	"istio.io/istio/pilot/pkg/config/kube/crdclient"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/validation"
+)
+
+func deprecationsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deprecations",
+		Short: "Report the deprecated fields used by the configuration of the cluster",
+		Long: `Reports, for each deprecated field of the Istio configuration APIs, the resources of the cluster using it.
+
+The deprecated fields must be replaced before upgrading to the release they are removed in. Without --namespace,
+the resources of all the namespaces are reported.`,
+		Example: `  # Report the deprecated fields used in the cluster
+  istioctl experimental deprecations
+
+  # Report the deprecated fields used in the bookinfo namespace
+  istioctl experimental deprecations -n bookinfo`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			configClient, err := configStoreFactory()
+			if err != nil {
+				return err
+			}
+			cfgs, err := fetchDeprecationCandidates(configClient, namespace)
+			if err != nil {
+				return err
+			}
+			printDeprecationUsages(c.OutOrStdout(), deprecationUsages(cfgs))
+			return nil
+		},
+	}
+	return cmd
+}
+
+// fetchDeprecationCandidates returns the configs of the kinds with deprecated fields in the namespace, or in all the
+// namespaces if it is empty.
+func fetchDeprecationCandidates(configClient istioclient.Interface, ns string) ([]config.Config, error) {
+	ctx := context.Background()
+	var cfgs []config.Config
+	for _, kind := range deprecationKinds() {
+		switch kind {
+		case gvk.VirtualService:
+			vss, err := configClient.NetworkingV1alpha3().VirtualServices(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch VirtualServices: %v", err)
+			}
+			for _, vs := range vss.Items {
+				cfgs = append(cfgs, crdclient.TranslateObject(vs, kind, ""))
+			}
+		case gvk.DestinationRule:
+			drs, err := configClient.NetworkingV1alpha3().DestinationRules(ns).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch DestinationRules: %v", err)
+			}
+			for _, dr := range drs.Items {
+				cfgs = append(cfgs, crdclient.TranslateObject(dr, kind, ""))
+			}
+		default:
+			return nil, fmt.Errorf("deprecated fields of %s resources cannot be reported", kind.Kind)
+		}
+	}
+	return cfgs, nil
+}
+
+// deprecationKinds returns the kinds with deprecated fields, in the order of the deprecations.
+func deprecationKinds() []config.GroupVersionKind {
+	var kinds []config.GroupVersionKind
+	for _, d := range validation.Deprecations {
+		if !containsKind(kinds, d.Kind) {
+			kinds = append(kinds, d.Kind)
+		}
+	}
+	return kinds
+}
+
+func containsKind(kinds []config.GroupVersionKind, kind config.GroupVersionKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// deprecationUsage is a deprecated field, and the resources using it.
+type deprecationUsage struct {
+	deprecation *validation.Deprecation
+	resources   []string
+}
+
+// deprecationUsages returns the deprecated fields used by the configs, in the order of the deprecations. The
+// deprecated fields are the deprecations in the warnings of the validation of the configs.
+func deprecationUsages(cfgs []config.Config) []deprecationUsage {
+	used := map[*validation.Deprecation][]string{}
+	for _, cfg := range cfgs {
+		s, f := collections.Pilot.FindByGroupVersionKind(cfg.GroupVersionKind)
+		if !f {
+			continue
+		}
+		warnings, _ := s.Resource().ValidateConfig(cfg)
+		for _, d := range validation.UsedDeprecations(warnings) {
+			used[d] = append(used[d], cfg.Namespace+"/"+cfg.Name)
+		}
+	}
+	var usages []deprecationUsage
+	for _, d := range validation.Deprecations {
+		if resources := used[d]; len(resources) > 0 {
+			sort.Strings(resources)
+			usages = append(usages, deprecationUsage{deprecation: d, resources: resources})
+		}
+	}
+	return usages
+}
+
+func printDeprecationUsages(writer io.Writer, usages []deprecationUsage) {
+	if len(usages) == 0 {
+		_, _ = fmt.Fprintln(writer, "No deprecated fields are used.")
+		return
+	}
+	w := tabwriter.NewWriter(writer, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KIND\tDEPRECATED FIELD\tREPLACEMENT\tREMOVED IN\tRESOURCES")
+	for _, u := range usages {
+		d := u.deprecation
+		replacement, removedIn := d.Replacement, d.RemovedIn
+		if replacement == "" {
+			replacement = "-"
+		}
+		if removedIn == "" {
+			removedIn = "not planned"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Kind.Kind, d.Field, replacement, removedIn, strings.Join(u.resources, ","))
+	}
+	_ = w.Flush()
+}
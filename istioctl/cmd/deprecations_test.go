@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
+)
+
+func TestDeprecationUsages(t *testing.T) {
+	client := istiofake.NewSimpleClientset(
+		&clientnetworking.VirtualService{
+			ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "bookinfo"},
+			Spec: networking.VirtualService{
+				Hosts: []string{"reviews"},
+				Http: []*networking.HTTPRoute{{
+					MirrorPercent: &wrapperspb.UInt32Value{Value: 5},
+					Route:         []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews"}}},
+				}},
+			},
+		},
+		&clientnetworking.DestinationRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "bookinfo"},
+			Spec: networking.DestinationRule{
+				Host:          "reviews",
+				TrafficPolicy: &networking.TrafficPolicy{OutlierDetection: &networking.OutlierDetection{ConsecutiveErrors: 5}},
+			},
+		},
+		&clientnetworking.DestinationRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "ratings", Namespace: "default"},
+			Spec: networking.DestinationRule{
+				Host:          "ratings",
+				TrafficPolicy: &networking.TrafficPolicy{OutlierDetection: &networking.OutlierDetection{ConsecutiveErrors: 5}},
+			},
+		},
+		&clientnetworking.DestinationRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "details", Namespace: "default"},
+			Spec: networking.DestinationRule{
+				Host:          "details",
+				TrafficPolicy: &networking.TrafficPolicy{OutlierDetection: &networking.OutlierDetection{Consecutive_5XxErrors: &wrapperspb.UInt32Value{Value: 5}}},
+			},
+		},
+	)
+
+	cfgs, err := fetchDeprecationCandidates(client, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	usages := deprecationUsages(cfgs)
+	if len(usages) != 2 {
+		t.Fatalf("expected 2 deprecated fields to be used, got %v", usages)
+	}
+	if got := strings.Join(usages[1].resources, ","); got != "bookinfo/reviews,default/ratings" {
+		t.Fatalf("unexpected resources using consecutiveErrors: %v", got)
+	}
+	var out bytes.Buffer
+	printDeprecationUsages(&out, usages)
+	for _, s := range []string{"spec.http.mirrorPercent", "spec.trafficPolicy.outlierDetection.consecutiveErrors"} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %q in output:\n%s", s, out.String())
+		}
+	}
+
+	cfgs, err = fetchDeprecationCandidates(client, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usages := deprecationUsages(cfgs); len(usages) != 1 || len(usages[0].resources) != 1 {
+		t.Fatalf("expected only default/ratings to be reported, got %v", usages)
+	}
+}
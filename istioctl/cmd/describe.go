@@ -84,13 +84,20 @@ var (
 
 func podDescribeCmd() *cobra.Command {
 	var opts clioptions.ControlPlaneOptions
+	var fromPod string
 	cmd := &cobra.Command{
 		Use:     "pod <pod>",
 		Aliases: []string{"po"},
 		Short:   "Describe pods and their Istio configuration [kube-only]",
 		Long: `Analyzes pod, its Services, DestinationRules, and VirtualServices and reports
-the configuration objects that affect that pod.`,
-		Example: `  istioctl experimental describe pod productpage-v1-c7765c886-7zzd4`,
+the configuration objects that affect that pod.
+
+With --from, it also explains step by step how the Sidecar scope and DestinationRules of the source pod, and the
+PeerAuthentications and AuthorizationPolicies of the pod allow or deny the traffic from the source pod.`,
+		Example: `  istioctl experimental describe pod productpage-v1-c7765c886-7zzd4
+
+  # Explain why the traffic from a ratings pod to the productpage pod is denied
+  istioctl experimental describe pod productpage-v1-c7765c886-7zzd4 --from ratings-v1-b6994bb9-kc6mv.bookinfo`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return fmt.Errorf("expecting pod name")
@@ -161,6 +168,18 @@ the configuration objects that affect that pod.`,
 				return err
 			}
 
+			if fromPod != "" {
+				srcName, srcNamespace := handlers.InferPodInfo(fromPod, handlers.HandleNamespace(namespace, defaultNamespace))
+				src, err := client.CoreV1().Pods(srcNamespace).Get(context.TODO(), srcName, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(writer, "--------------------\n")
+				if err := describeTrafficFrom(writer, kubeClient, configClient, src, pod, matchingServices); err != nil {
+					return err
+				}
+			}
+
 			// TODO find sidecar configs that select this workload and render them
 
 			// Now look for ingress gateways
@@ -171,6 +190,8 @@ the configuration objects that affect that pod.`,
 
 	cmd.PersistentFlags().BoolVar(&ignoreUnmeshed, "ignoreUnmeshed", false,
 		"Suppress warnings for unmeshed pods")
+	cmd.PersistentFlags().StringVar(&fromPod, "from", "",
+		"Source pod <pod-name>[.<namespace>] of the traffic to explain")
 	cmd.Long += "\n\n" + ExperimentalMsg
 	return cmd
 }
@@ -0,0 +1,633 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	apiannotation "istio.io/api/annotation"
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/api/security/v1beta1"
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	"istio.io/istio/pilot/pkg/config/kube/crdclient"
+	authnv1beta1 "istio.io/istio/pilot/pkg/security/authn/v1beta1"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/kube"
+)
+
+// trafficWorkload is the source or the destination workload of the traffic explained by describe --from.
+type trafficWorkload struct {
+	name      string
+	namespace string
+	labels    klabels.Set
+	principal string
+	ip        string
+	meshed    bool
+}
+
+func newTrafficWorkload(pod *v1.Pod, trustDomain string) trafficWorkload {
+	sa := pod.Spec.ServiceAccountName
+	if sa == "" {
+		sa = "default"
+	}
+	return trafficWorkload{
+		name:      kname(pod.ObjectMeta),
+		namespace: pod.Namespace,
+		labels:    klabels.Set(pod.Labels),
+		principal: fmt.Sprintf("%s/ns/%s/sa/%s", trustDomain, pod.Namespace, sa),
+		ip:        pod.Status.PodIP,
+		meshed:    isMeshed(pod),
+	}
+}
+
+// trafficConfigs are the configs of the source and destination namespaces, and of the root namespace.
+type trafficConfigs struct {
+	rootNamespace         string
+	registryOnly          bool
+	sidecars              []*config.Config
+	destinationRules      []*config.Config
+	peerAuthentications   []*config.Config
+	authorizationPolicies []*config.Config
+}
+
+// trafficExplanation is the step by step explanation of whether the traffic between two workloads is allowed.
+type trafficExplanation struct {
+	steps  []string
+	denied bool
+	// conditional is set when the traffic is only allowed for the requests matching some conditions.
+	conditional bool
+}
+
+func (e *trafficExplanation) step(format string, a ...any) {
+	e.steps = append(e.steps, fmt.Sprintf(format, a...))
+}
+
+func (e *trafficExplanation) deny(format string, a ...any) {
+	e.step(format, a...)
+	e.denied = true
+}
+
+func (e *trafficExplanation) print(writer io.Writer, title string) {
+	fmt.Fprintf(writer, "%s:\n", title)
+	for i, s := range e.steps {
+		fmt.Fprintf(writer, "   %d. %s\n", i+1, s)
+	}
+	switch {
+	case e.denied:
+		fmt.Fprintf(writer, "   Result: DENIED\n")
+	case e.conditional:
+		fmt.Fprintf(writer, "   Result: ALLOWED for the requests matching the conditions above\n")
+	default:
+		fmt.Fprintf(writer, "   Result: ALLOWED\n")
+	}
+}
+
+// describeTrafficFrom explains whether the traffic from the source pod to the destination pod, through each of
+// the services of the destination, is allowed.
+func describeTrafficFrom(writer io.Writer, kubeClient kube.CLIClient, configClient istioclient.Interface,
+	src, dst *v1.Pod, matchingServices []v1.Service,
+) error {
+	meshCfg, err := getMeshConfig(kubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to fetch mesh config: %v", err)
+	}
+	cfgs, err := fetchTrafficConfigs(configClient, meshCfg, src.Namespace, dst.Namespace)
+	if err != nil {
+		return err
+	}
+	source := newTrafficWorkload(src, meshCfg.GetTrustDomain())
+	destination := newTrafficWorkload(dst, meshCfg.GetTrustDomain())
+	if len(matchingServices) == 0 {
+		explainTraffic(source, destination, nil, cfgs).print(writer,
+			fmt.Sprintf("Traffic from %s to %s", source.name, destination.name))
+		return nil
+	}
+	for i := range matchingServices {
+		svc := &matchingServices[i]
+		explainTraffic(source, destination, svc, cfgs).print(writer,
+			fmt.Sprintf("Traffic from %s to %s through Service %s", source.name, destination.name, kname(svc.ObjectMeta)))
+	}
+	return nil
+}
+
+func fetchTrafficConfigs(configClient istioclient.Interface, meshCfg *meshconfig.MeshConfig, srcNamespace, dstNamespace string) (trafficConfigs, error) {
+	root := meshCfg.GetRootNamespace()
+	cfgs := trafficConfigs{
+		rootNamespace: root,
+		registryOnly:  meshCfg.GetOutboundTrafficPolicy().GetMode() == meshconfig.MeshConfig_OutboundTrafficPolicy_REGISTRY_ONLY,
+	}
+	ctx := context.Background()
+	for _, ns := range uniqueNamespaces(srcNamespace, root) {
+		sidecars, err := configClient.NetworkingV1alpha3().Sidecars(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return cfgs, fmt.Errorf("failed to fetch Sidecars of namespace %s: %v", ns, err)
+		}
+		for _, sc := range sidecars.Items {
+			cfg := crdclient.TranslateObject(sc, config.GroupVersionKind(sc.GroupVersionKind()), "")
+			cfgs.sidecars = append(cfgs.sidecars, &cfg)
+		}
+	}
+	for _, ns := range uniqueNamespaces(srcNamespace, dstNamespace, root) {
+		drs, err := configClient.NetworkingV1alpha3().DestinationRules(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return cfgs, fmt.Errorf("failed to fetch DestinationRules of namespace %s: %v", ns, err)
+		}
+		for _, dr := range drs.Items {
+			cfg := crdclient.TranslateObject(dr, config.GroupVersionKind(dr.GroupVersionKind()), "")
+			cfgs.destinationRules = append(cfgs.destinationRules, &cfg)
+		}
+	}
+	for _, ns := range uniqueNamespaces(root, dstNamespace) {
+		pas, err := configClient.SecurityV1beta1().PeerAuthentications(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return cfgs, fmt.Errorf("failed to fetch PeerAuthentications of namespace %s: %v", ns, err)
+		}
+		for _, pa := range pas.Items {
+			cfg := crdclient.TranslateObject(pa, config.GroupVersionKind(pa.GroupVersionKind()), "")
+			cfgs.peerAuthentications = append(cfgs.peerAuthentications, &cfg)
+		}
+		aps, err := configClient.SecurityV1beta1().AuthorizationPolicies(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return cfgs, fmt.Errorf("failed to fetch AuthorizationPolicies of namespace %s: %v", ns, err)
+		}
+		for _, ap := range aps.Items {
+			cfg := crdclient.TranslateObject(ap, config.GroupVersionKind(ap.GroupVersionKind()), "")
+			cfgs.authorizationPolicies = append(cfgs.authorizationPolicies, &cfg)
+		}
+	}
+	return cfgs, nil
+}
+
+func uniqueNamespaces(namespaces ...string) []string {
+	var res []string
+	for _, ns := range namespaces {
+		if ns != "" && !contains(res, ns) {
+			res = append(res, ns)
+		}
+	}
+	return res
+}
+
+// explainTraffic explains, in the order they are applied, how the Sidecar scope and DestinationRules of the
+// source, and the PeerAuthentications and AuthorizationPolicies of the destination apply to the traffic.
+// The service is nil if the traffic is sent to the IP of the destination pod.
+func explainTraffic(src, dst trafficWorkload, svc *v1.Service, cfgs trafficConfigs) *trafficExplanation {
+	e := &trafficExplanation{}
+	if !dst.meshed {
+		e.step("the destination has no sidecar: PeerAuthentications and AuthorizationPolicies are not enforced")
+		return e
+	}
+
+	svcHost := ""
+	inScope := false
+	switch {
+	case !src.meshed:
+		e.step("the source has no sidecar: it sends plaintext traffic")
+	case svc == nil:
+		e.step("no Service selects the destination: the source sends the traffic to the pod IP as-is")
+	default:
+		svcHost = svc.Name + "." + svc.Namespace + k8sSuffix
+		inScope = explainSidecarScope(e, src, svc, svcHost, cfgs)
+		if e.denied {
+			return e
+		}
+	}
+
+	tlsMode := plaintext
+	if inScope {
+		tlsMode = explainDestinationRule(e, src, svc, svcHost, cfgs)
+	}
+	mtls := explainPeerAuthentication(e, dst, tlsMode, cfgs)
+	if e.denied {
+		return e
+	}
+	explainAuthorizationPolicies(e, src, dst, mtls, cfgs)
+	return e
+}
+
+// explainSidecarScope returns true if the service is in the Sidecar scope of the source.
+func explainSidecarScope(e *trafficExplanation, src trafficWorkload, svc *v1.Service, svcHost string, cfgs trafficConfigs) bool {
+	registryOnly := cfgs.registryOnly
+	inScope := true
+	var reason string
+	if exportTo := svc.Annotations[apiannotation.NetworkingExportTo.Name]; exportTo != "" && !exportedTo(exportTo, svc.Namespace, src.namespace) {
+		inScope = false
+		reason = fmt.Sprintf("Service %s is not exported to namespace %s (%s=%s)", kname(svc.ObjectMeta), src.namespace,
+			apiannotation.NetworkingExportTo.Name, exportTo)
+	}
+	if sc := selectSidecar(src, cfgs); sc == nil {
+		if inScope {
+			reason = fmt.Sprintf("no Sidecar applies to the source: %s is visible to it", svcHost)
+		}
+	} else {
+		spec := sc.Spec.(*v1alpha3.Sidecar)
+		if spec.GetOutboundTrafficPolicy() != nil {
+			registryOnly = spec.GetOutboundTrafficPolicy().GetMode() == v1alpha3.OutboundTrafficPolicy_REGISTRY_ONLY
+		}
+		if inScope {
+			if hosts := egressHosts(spec); sidecarHostsMatch(hosts, src.namespace, svc.Namespace, svcHost) {
+				reason = fmt.Sprintf("Sidecar %s.%s makes %s visible to the source", sc.Name, sc.Namespace, svcHost)
+			} else {
+				inScope = false
+				reason = fmt.Sprintf("Sidecar %s.%s does not make %s visible to the source (egress hosts %s)",
+					sc.Name, sc.Namespace, svcHost, strings.Join(hosts, ", "))
+			}
+		}
+	}
+	switch {
+	case inScope:
+		e.step("%s", reason)
+	case registryOnly:
+		e.deny("%s, and the outbound traffic policy is REGISTRY_ONLY: the traffic is sent to the BlackHoleCluster", reason)
+	default:
+		e.step("%s: the traffic is sent as-is through the PassthroughCluster", reason)
+	}
+	return inScope
+}
+
+// selectSidecar returns the Sidecar of the source: the one selecting it in its namespace, or the one without
+// selector of its namespace, or of the root namespace.
+func selectSidecar(src trafficWorkload, cfgs trafficConfigs) *config.Config {
+	var namespaceSidecar, rootSidecar *config.Config
+	for _, sc := range cfgs.sidecars {
+		selector := sc.Spec.(*v1alpha3.Sidecar).GetWorkloadSelector().GetLabels()
+		switch {
+		case sc.Namespace == src.namespace && len(selector) > 0:
+			if klabels.SelectorFromSet(selector).Matches(src.labels) {
+				return sc
+			}
+		case sc.Namespace == src.namespace && namespaceSidecar == nil:
+			namespaceSidecar = sc
+		case sc.Namespace == cfgs.rootNamespace && len(selector) == 0 && rootSidecar == nil:
+			rootSidecar = sc
+		}
+	}
+	if namespaceSidecar != nil {
+		return namespaceSidecar
+	}
+	return rootSidecar
+}
+
+func egressHosts(sc *v1alpha3.Sidecar) []string {
+	if len(sc.GetEgress()) == 0 {
+		// Sidecars without egress listener import all the services.
+		return []string{"*/*"}
+	}
+	var hosts []string
+	for _, egress := range sc.GetEgress() {
+		hosts = append(hosts, egress.GetHosts()...)
+	}
+	return hosts
+}
+
+// sidecarHostsMatch returns true if one of the <namespace>/<host> egress hosts of a Sidecar applied to the source
+// namespace matches the service.
+func sidecarHostsMatch(hosts []string, srcNamespace, svcNamespace, svcHost string) bool {
+	for _, h := range hosts {
+		ns, hostname, found := strings.Cut(h, "/")
+		if !found {
+			continue
+		}
+		switch ns {
+		case "*":
+		case ".":
+			if svcNamespace != srcNamespace {
+				continue
+			}
+		default:
+			if ns != svcNamespace {
+				continue
+			}
+		}
+		if host.Name(svcHost).SubsetOf(host.Name(hostname)) {
+			return true
+		}
+	}
+	return false
+}
+
+func exportedTo(exportTo, svcNamespace, namespace string) bool {
+	for _, e := range strings.Split(exportTo, ",") {
+		switch e = strings.TrimSpace(e); e {
+		case "*", namespace:
+			return true
+		case ".":
+			if svcNamespace == namespace {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sourceTLSMode is how the source sends the traffic to the destination.
+type sourceTLSMode int
+
+const (
+	plaintext sourceTLSMode = iota
+	// autoMTLS sends Istio mTLS, unless the destination disables mTLS.
+	autoMTLS
+	istioMTLS
+	// otherTLS is TLS originated by the source, with certificates not issued by Istio.
+	otherTLS
+)
+
+func explainDestinationRule(e *trafficExplanation, src trafficWorkload, svc *v1.Service, svcHost string, cfgs trafficConfigs) sourceTLSMode {
+	dr := findDestinationRule(cfgs.destinationRules, svcHost, src.namespace, svc.Namespace, cfgs.rootNamespace)
+	tls := dr.GetTrafficPolicy().GetTls()
+	if tls == nil {
+		if dr == nil {
+			e.step("no DestinationRule sets the TLS mode of %s: the source uses auto mTLS", svcHost)
+		} else {
+			e.step("DestinationRule %s does not set the TLS mode: the source uses auto mTLS", dr.name)
+		}
+		return autoMTLS
+	}
+	mode := tls.GetMode()
+	switch mode {
+	case v1alpha3.ClientTLSSettings_ISTIO_MUTUAL:
+		e.step("DestinationRule %s sets the TLS mode %s: the source sends Istio mTLS", dr.name, mode)
+		return istioMTLS
+	case v1alpha3.ClientTLSSettings_DISABLE:
+		e.step("DestinationRule %s sets the TLS mode %s: the source sends plaintext traffic", dr.name, mode)
+		return plaintext
+	default:
+		e.step("DestinationRule %s sets the TLS mode %s: the source does not send Istio mTLS", dr.name, mode)
+		return otherTLS
+	}
+}
+
+type destinationRule struct {
+	*v1alpha3.DestinationRule
+	name string
+}
+
+// findDestinationRule finds the DestinationRule of the host in the source namespace, the service namespace, and
+// the root namespace, in that order.
+func findDestinationRule(drs []*config.Config, svcHost string, namespaces ...string) *destinationRule {
+	for _, ns := range namespaces {
+		for _, cfg := range drs {
+			if cfg.Namespace != ns {
+				continue
+			}
+			spec := cfg.Spec.(*v1alpha3.DestinationRule)
+			h := spec.GetHost()
+			if !strings.Contains(h, ".") && h != "*" {
+				h = h + "." + cfg.Namespace + k8sSuffix
+			}
+			if host.Name(svcHost).SubsetOf(host.Name(h)) {
+				return &destinationRule{DestinationRule: spec, name: cfg.Name + "." + cfg.Namespace}
+			}
+		}
+	}
+	return nil
+}
+
+func (dr *destinationRule) GetTrafficPolicy() *v1alpha3.TrafficPolicy {
+	if dr == nil {
+		return nil
+	}
+	return dr.DestinationRule.GetTrafficPolicy()
+}
+
+// explainPeerAuthentication returns true if the traffic is received with Istio mTLS.
+func explainPeerAuthentication(e *trafficExplanation, dst trafficWorkload, tlsMode sourceTLSMode, cfgs trafficConfigs) bool {
+	var configs []*config.Config
+	for _, pa := range cfgs.peerAuthentications {
+		if pa.Namespace == dst.namespace || pa.Namespace == cfgs.rootNamespace {
+			configs = append(configs, pa)
+		}
+	}
+	matched := findMatchedConfigs(dst.labels, configs)
+	mode := authnv1beta1.ComposePeerAuthentication(cfgs.rootNamespace, matched).GetMtls().GetMode()
+	from := "by default"
+	if len(matched) > 0 {
+		names := make([]string, 0, len(matched))
+		for _, pa := range matched {
+			names = append(names, pa.Name+"."+pa.Namespace)
+		}
+		from = "from PeerAuthentication " + strings.Join(names, ", ")
+	}
+	mtls := tlsMode == autoMTLS || tlsMode == istioMTLS
+	switch mode {
+	case v1beta1.PeerAuthentication_MutualTLS_STRICT:
+		if !mtls {
+			e.deny("the destination requires mTLS (%s %s): the traffic is rejected", mode, from)
+			return false
+		}
+	case v1beta1.PeerAuthentication_MutualTLS_DISABLE:
+		switch tlsMode {
+		case istioMTLS:
+			e.deny("the destination disables mTLS (%s %s), but the source sends Istio mTLS: the traffic is rejected", mode, from)
+			return false
+		case autoMTLS:
+			e.step("the destination disables mTLS (%s %s): auto mTLS sends plaintext traffic", mode, from)
+			return false
+		}
+	}
+	if mtls {
+		e.step("the destination accepts the mTLS traffic (%s %s)", mode, from)
+	} else {
+		e.step("the destination accepts the traffic without mTLS (%s %s)", mode, from)
+	}
+	return mtls
+}
+
+// ruleMatch is whether an AuthorizationPolicy rule matches the traffic of the source.
+type ruleMatch int
+
+const (
+	noMatch ruleMatch = iota
+	// conditionalMatch is a rule matching the source, but only for the requests matching its conditions.
+	conditionalMatch
+	fullMatch
+)
+
+func explainAuthorizationPolicies(e *trafficExplanation, src, dst trafficWorkload, mtls bool, cfgs trafficConfigs) {
+	var configs []*config.Config
+	for _, ap := range cfgs.authorizationPolicies {
+		if ap.Namespace == dst.namespace || ap.Namespace == cfgs.rootNamespace {
+			configs = append(configs, ap)
+		}
+	}
+	matched := findMatchedConfigs(dst.labels, configs)
+
+	var allow []*config.Config
+	for _, action := range []v1beta1.AuthorizationPolicy_Action{v1beta1.AuthorizationPolicy_CUSTOM, v1beta1.AuthorizationPolicy_DENY} {
+		for _, ap := range matched {
+			spec := ap.Spec.(*v1beta1.AuthorizationPolicy)
+			if spec.GetAction() != action {
+				continue
+			}
+			match, rule := policyMatch(spec, src, mtls)
+			switch {
+			case match == noMatch:
+				e.step("%s AuthorizationPolicy %s.%s does not match the source", action, ap.Name, ap.Namespace)
+			case action == v1beta1.AuthorizationPolicy_CUSTOM:
+				e.step("%s AuthorizationPolicy %s.%s delegates the requests matching rules[%d] to the %s extension provider",
+					action, ap.Name, ap.Namespace, rule, spec.GetProvider().GetName())
+			case match == conditionalMatch:
+				e.step("%s AuthorizationPolicy %s.%s denies the requests matching rules[%d]", action, ap.Name, ap.Namespace, rule)
+			default:
+				e.deny("%s AuthorizationPolicy %s.%s matches the source with rules[%d]: the traffic is denied", action, ap.Name, ap.Namespace, rule)
+				return
+			}
+		}
+	}
+	for _, ap := range matched {
+		if ap.Spec.(*v1beta1.AuthorizationPolicy).GetAction() == v1beta1.AuthorizationPolicy_ALLOW {
+			allow = append(allow, ap)
+		}
+	}
+	if len(allow) == 0 {
+		e.step("no ALLOW AuthorizationPolicy applies to the destination: the traffic is allowed")
+		return
+	}
+
+	var conditional *config.Config
+	conditionalRule := 0
+	names := make([]string, 0, len(allow))
+	for _, ap := range allow {
+		names = append(names, ap.Name+"."+ap.Namespace)
+		switch match, rule := policyMatch(ap.Spec.(*v1beta1.AuthorizationPolicy), src, mtls); match {
+		case fullMatch:
+			e.step("ALLOW AuthorizationPolicy %s.%s matches the source with rules[%d]: the traffic is allowed", ap.Name, ap.Namespace, rule)
+			return
+		case conditionalMatch:
+			if conditional == nil {
+				conditional, conditionalRule = ap, rule
+			}
+		}
+	}
+	if conditional != nil {
+		e.step("ALLOW AuthorizationPolicy %s.%s only allows the requests matching rules[%d]", conditional.Name, conditional.Namespace, conditionalRule)
+		e.conditional = true
+		return
+	}
+	reason := "none of the ALLOW AuthorizationPolicies " + strings.Join(names, ", ") + " matches the source"
+	if !mtls {
+		reason += ", whose principal and namespace are unknown without mTLS"
+	}
+	e.deny("%s: the traffic is denied", reason)
+}
+
+// policyMatch returns the best match of the rules of the policy, and the index of the matching rule.
+func policyMatch(ap *v1beta1.AuthorizationPolicy, src trafficWorkload, mtls bool) (ruleMatch, int) {
+	best, bestRule := noMatch, 0
+	for i, rule := range ap.GetRules() {
+		if m := rulePolicyMatch(rule, src, mtls); m > best {
+			best, bestRule = m, i
+		}
+	}
+	return best, bestRule
+}
+
+func rulePolicyMatch(rule *v1beta1.Rule, src trafficWorkload, mtls bool) ruleMatch {
+	match := fullMatch
+	if len(rule.GetFrom()) > 0 {
+		match = noMatch
+		for _, from := range rule.GetFrom() {
+			if m := sourceMatch(from.GetSource(), src, mtls); m > match {
+				match = m
+			}
+		}
+	}
+	if match == fullMatch && (len(rule.GetTo()) > 0 || len(rule.GetWhen()) > 0) {
+		// The operations and conditions depend on the requests.
+		match = conditionalMatch
+	}
+	return match
+}
+
+func sourceMatch(s *v1beta1.Source, src trafficWorkload, mtls bool) ruleMatch {
+	if len(s.GetPrincipals()) > 0 && (!mtls || !matchesAnyString(s.GetPrincipals(), src.principal)) {
+		return noMatch
+	}
+	if mtls && matchesAnyString(s.GetNotPrincipals(), src.principal) {
+		return noMatch
+	}
+	if len(s.GetNamespaces()) > 0 && (!mtls || !matchesAnyString(s.GetNamespaces(), src.namespace)) {
+		return noMatch
+	}
+	if mtls && matchesAnyString(s.GetNotNamespaces(), src.namespace) {
+		return noMatch
+	}
+	for _, blocks := range [][]string{s.GetIpBlocks(), s.GetRemoteIpBlocks()} {
+		if len(blocks) > 0 && !matchesAnyIPBlock(blocks, src.ip) {
+			return noMatch
+		}
+	}
+	for _, blocks := range [][]string{s.GetNotIpBlocks(), s.GetNotRemoteIpBlocks()} {
+		if matchesAnyIPBlock(blocks, src.ip) {
+			return noMatch
+		}
+	}
+	if len(s.GetRequestPrincipals()) > 0 || len(s.GetNotRequestPrincipals()) > 0 {
+		// The request principals depend on the JWT of the requests.
+		return conditionalMatch
+	}
+	return fullMatch
+}
+
+// matchesAnyString matches the value with the exact, prefix ("abc*"), suffix ("*abc") and presence ("*")
+// patterns of AuthorizationPolicies.
+func matchesAnyString(patterns []string, value string) bool {
+	for _, p := range patterns {
+		switch {
+		case p == "*":
+			if value != "" {
+				return true
+			}
+		case strings.HasPrefix(p, "*"):
+			if strings.HasSuffix(value, p[1:]) {
+				return true
+			}
+		case strings.HasSuffix(p, "*"):
+			if strings.HasPrefix(value, p[:len(p)-1]) {
+				return true
+			}
+		case p == value:
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyIPBlock(blocks []string, ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	for _, b := range blocks {
+		if prefix, err := netip.ParsePrefix(b); err == nil {
+			if prefix.Contains(addr) {
+				return true
+			}
+		} else if a, err := netip.ParseAddr(b); err == nil && a == addr {
+			return true
+		}
+	}
+	return false
+}
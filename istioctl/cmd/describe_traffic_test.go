@@ -0,0 +1,217 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/api/security/v1beta1"
+	typev1beta1 "istio.io/api/type/v1beta1"
+	"istio.io/istio/pkg/config"
+)
+
+func TestExplainTraffic(t *testing.T) {
+	src := trafficWorkload{
+		name:      "ratings-v1.bookinfo",
+		namespace: "bookinfo",
+		labels:    klabels.Set{"app": "ratings"},
+		principal: "cluster.local/ns/bookinfo/sa/bookinfo-ratings",
+		ip:        "10.0.0.1",
+		meshed:    true,
+	}
+	dst := trafficWorkload{
+		name:      "productpage-v1.bookinfo",
+		namespace: "bookinfo",
+		labels:    klabels.Set{"app": "productpage"},
+		meshed:    true,
+	}
+	unmeshed := src
+	unmeshed.meshed = false
+	svc := &v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "productpage", Namespace: "bookinfo"}}
+
+	cfg := func(name, namespace string, spec config.Spec) *config.Config {
+		return &config.Config{Meta: config.Meta{Name: name, Namespace: namespace}, Spec: spec}
+	}
+	strict := cfg("default", "istio-system", &v1beta1.PeerAuthentication{
+		Mtls: &v1beta1.PeerAuthentication_MutualTLS{Mode: v1beta1.PeerAuthentication_MutualTLS_STRICT},
+	})
+	allowFrom := func(name string, source *v1beta1.Source, to ...*v1beta1.Rule_To) *config.Config {
+		return cfg(name, "bookinfo", &v1beta1.AuthorizationPolicy{
+			Selector: &typev1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "productpage"}},
+			Rules:    []*v1beta1.Rule{{From: []*v1beta1.Rule_From{{Source: source}}, To: to}},
+		})
+	}
+
+	cases := []struct {
+		name     string
+		src      trafficWorkload
+		dst      trafficWorkload
+		cfgs     trafficConfigs
+		denied   bool
+		explains string
+	}{
+		{
+			name:     "no config",
+			src:      src,
+			dst:      dst,
+			explains: "the source uses auto mTLS",
+		},
+		{
+			name:     "unmeshed destination",
+			src:      unmeshed,
+			dst:      trafficWorkload{name: "productpage-v1.bookinfo", namespace: "bookinfo"},
+			cfgs:     trafficConfigs{peerAuthentications: []*config.Config{strict}},
+			explains: "the destination has no sidecar",
+		},
+		{
+			name:     "strict mTLS from an unmeshed source",
+			src:      unmeshed,
+			dst:      dst,
+			cfgs:     trafficConfigs{rootNamespace: "istio-system", peerAuthentications: []*config.Config{strict}},
+			denied:   true,
+			explains: "the destination requires mTLS (STRICT from PeerAuthentication default.istio-system)",
+		},
+		{
+			name: "strict mTLS with TLS disabled by a DestinationRule",
+			src:  src,
+			dst:  dst,
+			cfgs: trafficConfigs{
+				rootNamespace:       "istio-system",
+				peerAuthentications: []*config.Config{strict},
+				destinationRules: []*config.Config{cfg("productpage", "bookinfo", &v1alpha3.DestinationRule{
+					Host:          "productpage",
+					TrafficPolicy: &v1alpha3.TrafficPolicy{Tls: &v1alpha3.ClientTLSSettings{Mode: v1alpha3.ClientTLSSettings_DISABLE}},
+				})},
+			},
+			denied:   true,
+			explains: "DestinationRule productpage.bookinfo sets the TLS mode DISABLE",
+		},
+		{
+			name: "service out of the Sidecar scope",
+			src:  src,
+			dst:  dst,
+			cfgs: trafficConfigs{
+				registryOnly: true,
+				sidecars: []*config.Config{cfg("default", "bookinfo", &v1alpha3.Sidecar{
+					Egress: []*v1alpha3.IstioEgressListener{{Hosts: []string{"istio-system/*"}}},
+				})},
+			},
+			denied:   true,
+			explains: "Sidecar default.bookinfo does not make productpage.bookinfo.svc.cluster.local visible to the source",
+		},
+		{
+			name: "service in the Sidecar scope",
+			src:  src,
+			dst:  dst,
+			cfgs: trafficConfigs{
+				registryOnly: true,
+				sidecars: []*config.Config{cfg("default", "bookinfo", &v1alpha3.Sidecar{
+					Egress: []*v1alpha3.IstioEgressListener{{Hosts: []string{"./*"}}},
+				})},
+			},
+			explains: "Sidecar default.bookinfo makes productpage.bookinfo.svc.cluster.local visible to the source",
+		},
+		{
+			name: "deny policy",
+			src:  src,
+			dst:  dst,
+			cfgs: trafficConfigs{authorizationPolicies: []*config.Config{cfg("deny-ratings", "bookinfo", &v1beta1.AuthorizationPolicy{
+				Action: v1beta1.AuthorizationPolicy_DENY,
+				Rules: []*v1beta1.Rule{{From: []*v1beta1.Rule_From{{Source: &v1beta1.Source{
+					Principals: []string{"cluster.local/ns/bookinfo/sa/bookinfo-ratings"},
+				}}}}},
+			})}},
+			denied:   true,
+			explains: "DENY AuthorizationPolicy deny-ratings.bookinfo matches the source with rules[0]",
+		},
+		{
+			name: "allow policy of another principal",
+			src:  src,
+			dst:  dst,
+			cfgs: trafficConfigs{authorizationPolicies: []*config.Config{
+				allowFrom("allow-gateway", &v1beta1.Source{Principals: []string{"cluster.local/ns/istio-system/sa/*"}}),
+			}},
+			denied:   true,
+			explains: "none of the ALLOW AuthorizationPolicies allow-gateway.bookinfo matches the source",
+		},
+		{
+			name: "allow policy of the namespace",
+			src:  src,
+			dst:  dst,
+			cfgs: trafficConfigs{authorizationPolicies: []*config.Config{
+				allowFrom("allow-gateway", &v1beta1.Source{Principals: []string{"cluster.local/ns/istio-system/sa/*"}}),
+				allowFrom("allow-bookinfo", &v1beta1.Source{Namespaces: []string{"bookinfo"}}),
+			}},
+			explains: "ALLOW AuthorizationPolicy allow-bookinfo.bookinfo matches the source with rules[0]",
+		},
+		{
+			name: "allow policy without mTLS",
+			src:  unmeshed,
+			dst:  dst,
+			cfgs: trafficConfigs{authorizationPolicies: []*config.Config{
+				allowFrom("allow-bookinfo", &v1beta1.Source{Namespaces: []string{"bookinfo"}}),
+			}},
+			denied:   true,
+			explains: "whose principal and namespace are unknown without mTLS",
+		},
+		{
+			name: "allow policy of some operations",
+			src:  src,
+			dst:  dst,
+			cfgs: trafficConfigs{authorizationPolicies: []*config.Config{
+				allowFrom("allow-get", &v1beta1.Source{IpBlocks: []string{"10.0.0.0/16"}},
+					&v1beta1.Rule_To{Operation: &v1beta1.Operation{Methods: []string{"GET"}}}),
+			}},
+			explains: "ALLOW AuthorizationPolicy allow-get.bookinfo only allows the requests matching rules[0]",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := explainTraffic(c.src, c.dst, svc, c.cfgs)
+			steps := strings.Join(e.steps, "\n")
+			if e.denied != c.denied {
+				t.Fatalf("got denied %v, want %v:\n%s", e.denied, c.denied, steps)
+			}
+			if !strings.Contains(steps, c.explains) {
+				t.Fatalf("expected the explanation to contain %q:\n%s", c.explains, steps)
+			}
+		})
+	}
+}
+
+func TestMatchesAnyString(t *testing.T) {
+	cases := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"cluster.local/ns/bookinfo/sa/ratings", "cluster.local/ns/bookinfo/sa/ratings", true},
+		{"cluster.local/ns/bookinfo/*", "cluster.local/ns/bookinfo/sa/ratings", true},
+		{"*/sa/ratings", "cluster.local/ns/bookinfo/sa/ratings", true},
+		{"*", "", false},
+		{"cluster.local/ns/default/*", "cluster.local/ns/bookinfo/sa/ratings", false},
+	}
+	for _, c := range cases {
+		if got := matchesAnyString([]string{c.pattern}, c.value); got != c.want {
+			t.Errorf("matchesAnyString(%q, %q) = %v, want %v", c.pattern, c.value, got, c.want)
+		}
+	}
+}
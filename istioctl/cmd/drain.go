@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/api/annotation"
+	"istio.io/istio/pkg/kube"
+)
+
+// drainStatsFilter selects the active downstream connection gauges of all listeners.
+const drainStatsFilter = `^listener\..*\.downstream_cx_active$`
+
+func drainCmd() *cobra.Command {
+	var (
+		drainTimeout  time.Duration
+		drainInterval time.Duration
+		inboundOnly   bool
+		cordon        bool
+	)
+	cmd := &cobra.Command{
+		Use:   "drain [<type>/]<name>[.<namespace>]",
+		Short: "Gracefully drain the connections of the sidecar in the specified pod",
+		Long: `Triggers graceful draining of the listeners of the Envoy instance in the specified pod, then reports the number
+of active connections until all of them are closed or the timeout expires.
+
+Draining cannot be undone; once the drain period of Envoy has elapsed, drained listeners stop accepting connections
+until the pod is restarted. With --cordon, Envoy health checks are failed first, so that the pod becomes not ready
+and is removed from the endpoints of its Services before the connections are drained.`,
+		Example: `  # Drain inbound connections of a pod, waiting up to 5 minutes
+  istioctl experimental drain productpage-v1-7d79b4c9f-4zr2k.default
+
+  # Remove a pod from load balancing, then drain all of its connections
+  istioctl experimental drain deployment/productpage-v1 --cordon --inbound-only=false`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("drain requires a pod name")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			podName, podNamespace, err := getPodName(args[0])
+			if err != nil {
+				return err
+			}
+			kubeClient, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create Kubernetes client: %v", err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+			defer cancel()
+			return drainPod(ctx, c, kubeClient, podName, podNamespace, inboundOnly, cordon, drainInterval)
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+	cmd.PersistentFlags().DurationVar(&drainTimeout, "timeout", 5*time.Minute,
+		"The duration to wait for all connections to be closed")
+	cmd.PersistentFlags().DurationVar(&drainInterval, "interval", 2*time.Second,
+		"The interval at which the number of active connections is reported")
+	cmd.PersistentFlags().BoolVar(&inboundOnly, "inbound-only", true,
+		"Only drain the inbound listener of the sidecar, leaving outbound traffic of the workload unaffected. "+
+			"Must be disabled for gateways")
+	cmd.PersistentFlags().BoolVar(&cordon, "cordon", false,
+		"Fail Envoy health checks before draining, so that the pod is removed from load balancing")
+	return cmd
+}
+
+func drainPod(ctx context.Context, c *cobra.Command, kubeClient kube.CLIClient, podName, podNamespace string,
+	inboundOnly, cordon bool, interval time.Duration,
+) error {
+	if cordon {
+		if _, err := kubeClient.EnvoyDo(ctx, podName, podNamespace, "POST", "healthcheck/fail"); err != nil {
+			return fmt.Errorf("failed to cordon %s.%s: %v", podName, podNamespace, err)
+		}
+		c.Printf("Cordoned %s.%s\n", podName, podNamespace)
+	}
+	path := "drain_listeners?graceful"
+	if inboundOnly {
+		path += "&inboundonly"
+	}
+	if _, err := kubeClient.EnvoyDo(ctx, podName, podNamespace, "POST", path); err != nil {
+		return fmt.Errorf("failed to drain %s.%s: %v", podName, podNamespace, err)
+	}
+	c.Printf("Draining %s.%s\n", podName, podNamespace)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		stats, err := kubeClient.EnvoyDo(ctx, podName, podNamespace, "GET", "stats?format=json&filter="+url.QueryEscape(drainStatsFilter))
+		if err != nil {
+			return fmt.Errorf("failed to retrieve connection stats of %s.%s: %v", podName, podNamespace, err)
+		}
+		active, err := activeConnections(stats, inboundOnly)
+		if err != nil {
+			return err
+		}
+		if active == 0 {
+			c.Printf("Drained %s.%s\n", podName, podNamespace)
+			return nil
+		}
+		c.Printf("%d active connections\n", active)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for %s.%s to drain, %d connections still active", podName, podNamespace, active)
+		case <-t.C:
+		}
+	}
+}
+
+// activeConnections returns the number of active downstream connections from the JSON stats of Envoy.
+// Connections to the admin, stats and health check listeners, which are used by this command and by
+// probes, are excluded.
+func activeConnections(stats []byte, inboundOnly bool) (int, error) {
+	var resp struct {
+		Stats []struct {
+			Name  string `json:"name"`
+			Value int    `json:"value"`
+		} `json:"stats"`
+	}
+	if err := json.Unmarshal(stats, &resp); err != nil {
+		return 0, fmt.Errorf("failed to parse stats: %v", err)
+	}
+	total, found := 0, false
+	for _, s := range resp.Stats {
+		if !strings.HasPrefix(s.Name, "listener.") || !strings.HasSuffix(s.Name, ".downstream_cx_active") {
+			continue
+		}
+		if strings.HasPrefix(s.Name, "listener.admin.") ||
+			strings.Contains(s.Name, "_15090.") || strings.Contains(s.Name, "_15021.") {
+			continue
+		}
+		// Inbound traffic of sidecars is captured by the virtualInbound listener.
+		if inboundOnly && !strings.Contains(s.Name, "_15006.") {
+			continue
+		}
+		total += s.Value
+		found = true
+	}
+	if !found {
+		// The stats are only created if they are included by the stats matcher of the proxy.
+		return 0, fmt.Errorf("no active connection stats found; include them with the %s=downstream_cx_active annotation",
+			annotation.SidecarStatsInclusionSuffixes.Name)
+	}
+	return total, nil
+}
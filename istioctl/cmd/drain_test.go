@@ -0,0 +1,53 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+)
+
+func TestActiveConnections(t *testing.T) {
+	stats := []byte(`{"stats":[
+		{"name":"listener.0.0.0.0_15006.downstream_cx_active","value":3},
+		{"name":"listener.0.0.0.0_15001.downstream_cx_active","value":4},
+		{"name":"listener.10.96.0.10_53.downstream_cx_active","value":1},
+		{"name":"listener.0.0.0.0_15090.downstream_cx_active","value":1},
+		{"name":"listener.0.0.0.0_15021.downstream_cx_active","value":2},
+		{"name":"listener.admin.downstream_cx_active","value":1},
+		{"name":"listener.0.0.0.0_15006.downstream_cx_total","value":100}
+	]}`)
+	cases := []struct {
+		inboundOnly bool
+		want        int
+	}{
+		{inboundOnly: true, want: 3},
+		{inboundOnly: false, want: 8},
+	}
+	for _, c := range cases {
+		got, err := activeConnections(stats, c.inboundOnly)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("activeConnections(inboundOnly=%v): got %d, want %d", c.inboundOnly, got, c.want)
+		}
+	}
+	if _, err := activeConnections([]byte("not json"), true); err == nil {
+		t.Errorf("expected error for invalid stats")
+	}
+	if _, err := activeConnections([]byte(`{"stats":[{"name":"listener.admin.downstream_cx_active","value":1}]}`), false); err == nil {
+		t.Errorf("expected error for missing stats")
+	}
+}
@@ -0,0 +1,402 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube"
+)
+
+const (
+	// snapshotConfigDir holds the configs of a snapshot, in the layout read by the istiod --configDir flag.
+	snapshotConfigDir = "config"
+	// snapshotMeshFile holds the mesh config of a snapshot, in the format read by the istiod --meshConfig flag.
+	snapshotMeshFile = "mesh.yaml"
+	// snapshotManifestFile describes the content of a snapshot.
+	snapshotManifestFile = "snapshot.yaml"
+)
+
+// meshSnapshot is the content of a snapshot written by export-mesh-config.
+type meshSnapshot struct {
+	// Istiod is the istiod instance the snapshot was taken from.
+	Istiod string `json:"istiod"`
+	// Configs are the Istio configs of the mesh, as Kubernetes objects.
+	Configs []map[string]any `json:"-"`
+	// Mesh is the mesh config, as JSON.
+	Mesh json.RawMessage `json:"-"`
+	// Services are the services of the registries not backed by configs, and Endpoints their endpoints.
+	Services  json.RawMessage `json:"-"`
+	Endpoints json.RawMessage `json:"-"`
+	// ConfigDumps are the generated xDS of the selected proxies, by proxy ID.
+	ConfigDumps map[string]json.RawMessage `json:"-"`
+	// Proxies are the IDs of the selected proxies.
+	Proxies []string `json:"proxies,omitempty"`
+}
+
+// snapshotService is the part of the istiod /debug/registryz response read by export-mesh-config.
+type snapshotService struct {
+	Hostname       string           `json:"hostname"`
+	DefaultAddress string           `json:"defaultAddress"`
+	Ports          model.PortList   `json:"ports"`
+	Resolution     model.Resolution `json:"Resolution"`
+	Attributes     struct {
+		ServiceRegistry provider.ID
+		Name            string
+		Namespace       string
+		Labels          map[string]string
+	}
+}
+
+// snapshotEndpoints is the part of the istiod /debug/endpointz response read by export-mesh-config.
+type snapshotEndpoints struct {
+	Service   string `json:"svc"`
+	Endpoints []struct {
+		Endpoint *struct {
+			Address         string
+			ServicePortName string
+			EndpointPort    uint32
+			Labels          map[string]string
+			ServiceAccount  string
+			Network         string
+			Locality        struct {
+				Label string
+			}
+		} `json:"endpoint"`
+	} `json:"ep"`
+}
+
+func exportMeshConfigCmd() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var snapshotDir string
+	var proxies []string
+	cmd := &cobra.Command{
+		Use:   "export-mesh-config",
+		Short: "Export the inputs of istiod and the xDS it generates to a snapshot directory",
+		Long: `Exports the inputs of istiod, and the xDS it generates for the selected proxies, to a snapshot directory.
+
+The snapshot holds:
+  config/istio.yaml     the Istio configs of the mesh
+  config/services.yaml  the services of the Kubernetes registry and their endpoints, as ServiceEntries
+  mesh.yaml             the mesh config
+  registry/             the services and endpoints of the registries, as reported by istiod
+  proxies/              the config dump of each selected proxy, as generated by istiod
+  snapshot.yaml         the istiod instance and the proxies of the snapshot
+
+The content is sorted and stripped of the metadata changing on each write, so that the snapshots of the same state
+are identical. A local istiod loads the configs and the mesh config of the snapshot with
+  pilot-discovery discovery --standalone --configDir <dir>/config --meshConfig <dir>/mesh.yaml
+and the config dumps it generates can be compared with the exported ones.`,
+		Example: `  # Export the configs of the mesh and the xDS of two proxies
+  istioctl experimental export-mesh-config --snapshot ./snapshot --proxy productpage-v1-123456-abcde.bookinfo \
+    --proxy istio-ingressgateway-5b7d8c9f-xyz12.istio-system`,
+		Args: cobra.NoArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if snapshotDir == "" {
+				return fmt.Errorf("--snapshot is required")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			ids := make([]string, 0, len(proxies))
+			for _, p := range proxies {
+				podName, ns, err := handlers.InferPodInfoFromTypedResource(p,
+					handlers.HandleNamespace(namespace, defaultNamespace),
+					kubeClient.UtilFactory())
+				if err != nil {
+					return err
+				}
+				ids = append(ids, podName+"."+ns)
+			}
+			snap, err := fetchMeshSnapshot(kubeClient, ids)
+			if err != nil {
+				return err
+			}
+			if err := writeMeshSnapshot(snapshotDir, snap); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(c.OutOrStdout(), "Snapshot of %d configs and %d proxies written to %s\n",
+				len(snap.Configs), len(snap.ConfigDumps), snapshotDir)
+			return nil
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	cmd.PersistentFlags().StringVar(&snapshotDir, "snapshot", "", "Directory the snapshot is written to.")
+	cmd.PersistentFlags().StringSliceVar(&proxies, "proxy", nil,
+		"Pod, as <pod-name>[.<namespace>], of a proxy whose xDS is added to the snapshot. Can be repeated.")
+	return cmd
+}
+
+// fetchMeshSnapshot reads the inputs of istiod, and the config dumps of the proxies, from the debug endpoints of
+// istiod. The inputs are read from a single istiod instance, so that they are consistent.
+func fetchMeshSnapshot(kubeClient kube.CLIClient, proxyIDs []string) (*meshSnapshot, error) {
+	configs, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace, "/debug/configz")
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no istiod instance returned its configs")
+	}
+	istiods := make([]string, 0, len(configs))
+	for istiod := range configs {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	snap := &meshSnapshot{Istiod: istiods[0], ConfigDumps: map[string]json.RawMessage{}}
+	if err := json.Unmarshal(configs[snap.Istiod], &snap.Configs); err != nil {
+		return nil, fmt.Errorf("failed to parse the configs of %s: %v", snap.Istiod, err)
+	}
+
+	for path, out := range map[string]*json.RawMessage{
+		"/debug/mesh":      &snap.Mesh,
+		"/debug/registryz": &snap.Services,
+		"/debug/endpointz": &snap.Endpoints,
+	} {
+		res, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace, path)
+		if err != nil {
+			return nil, err
+		}
+		body, f := res[snap.Istiod]
+		if !f || !json.Valid(body) {
+			return nil, fmt.Errorf("failed to read %s of %s: %s", path, snap.Istiod, string(body))
+		}
+		*out = body
+	}
+
+	for _, id := range proxyIDs {
+		res, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace, "/debug/config_dump?proxyID="+id)
+		if err != nil {
+			return nil, err
+		}
+		// Only the istiod instance the proxy is connected to returns its config dump.
+		for _, body := range res {
+			if json.Valid(body) {
+				snap.ConfigDumps[id] = body
+				break
+			}
+		}
+		if _, f := snap.ConfigDumps[id]; !f {
+			return nil, fmt.Errorf("proxy %s is not connected to any istiod instance", id)
+		}
+		snap.Proxies = append(snap.Proxies, id)
+	}
+	sort.Strings(snap.Proxies)
+	return snap, nil
+}
+
+// writeMeshSnapshot writes the snapshot to the directory.
+func writeMeshSnapshot(dir string, snap *meshSnapshot) error {
+	configs, err := snapshotConfigsYAML(snap.Configs)
+	if err != nil {
+		return err
+	}
+	services, err := snapshotServiceEntries(snap.Services, snap.Endpoints)
+	if err != nil {
+		return err
+	}
+	mesh, err := yaml.JSONToYAML(snap.Mesh)
+	if err != nil {
+		return fmt.Errorf("failed to convert the mesh config: %v", err)
+	}
+	manifest, err := yaml.Marshal(snap)
+	if err != nil {
+		return err
+	}
+	files := map[string][]byte{
+		filepath.Join(snapshotConfigDir, "istio.yaml"):    configs,
+		filepath.Join(snapshotConfigDir, "services.yaml"): services,
+		snapshotMeshFile: mesh,
+		filepath.Join("registry", "services.json"):  indentJSON(snap.Services),
+		filepath.Join("registry", "endpoints.json"): indentJSON(snap.Endpoints),
+		snapshotManifestFile:                        manifest,
+	}
+	for id, dump := range snap.ConfigDumps {
+		files[filepath.Join("proxies", id+".json")] = indentJSON(dump)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, content, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// snapshotConfigsYAML returns the configs as a YAML stream sorted by kind, namespace and name. The metadata set by
+// the API server is removed.
+func snapshotConfigsYAML(configs []map[string]any) ([]byte, error) {
+	key := func(c map[string]any) string {
+		meta, _ := c["metadata"].(map[string]any)
+		return fmt.Sprintf("%v/%v/%v/%v", c["apiVersion"], c["kind"], meta["namespace"], meta["name"])
+	}
+	sort.SliceStable(configs, func(i, j int) bool {
+		return key(configs[i]) < key(configs[j])
+	})
+	docs := make([]string, 0, len(configs))
+	for _, c := range configs {
+		if meta, ok := c["metadata"].(map[string]any); ok {
+			for _, f := range []string{"resourceVersion", "uid", "generation", "creationTimestamp", "managedFields"} {
+				delete(meta, f)
+			}
+		}
+		delete(c, "status")
+		out, err := yaml.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(out))
+	}
+	return []byte(strings.Join(docs, "---\n")), nil
+}
+
+// snapshotServiceEntries converts the services of the registries, other than the ServiceEntries already part of
+// the configs, to ServiceEntries holding their endpoints.
+func snapshotServiceEntries(servicesJSON, endpointsJSON []byte) ([]byte, error) {
+	var services []snapshotService
+	if err := json.Unmarshal(servicesJSON, &services); err != nil {
+		return nil, fmt.Errorf("failed to parse the services: %v", err)
+	}
+	var endpoints []snapshotEndpoints
+	if err := json.Unmarshal(endpointsJSON, &endpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse the endpoints: %v", err)
+	}
+	// The endpoints are keyed by <hostname>:<port name>.
+	byHost := map[string][]*networking.WorkloadEntry{}
+	for _, e := range endpoints {
+		hostname := e.Service
+		if i := strings.LastIndex(hostname, ":"); i >= 0 {
+			hostname = hostname[:i]
+		}
+		for _, ep := range e.Endpoints {
+			if ep.Endpoint == nil {
+				continue
+			}
+			we := &networking.WorkloadEntry{
+				Address:        ep.Endpoint.Address,
+				Labels:         ep.Endpoint.Labels,
+				ServiceAccount: ep.Endpoint.ServiceAccount,
+				Network:        ep.Endpoint.Network,
+				Locality:       ep.Endpoint.Locality.Label,
+				Ports:          map[string]uint32{ep.Endpoint.ServicePortName: ep.Endpoint.EndpointPort},
+			}
+			byHost[hostname] = mergeSnapshotEndpoint(byHost[hostname], we)
+		}
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Hostname < services[j].Hostname
+	})
+	docs := make([]string, 0, len(services))
+	for _, svc := range services {
+		if svc.Attributes.ServiceRegistry == provider.External {
+			continue
+		}
+		se := &networking.ServiceEntry{
+			Hosts:      []string{svc.Hostname},
+			Location:   networking.ServiceEntry_MESH_INTERNAL,
+			Resolution: snapshotResolution(svc.Resolution),
+			Endpoints:  byHost[svc.Hostname],
+		}
+		if svc.DefaultAddress != "" && svc.DefaultAddress != constants.UnspecifiedIP {
+			se.Addresses = []string{svc.DefaultAddress}
+		}
+		for _, p := range svc.Ports {
+			se.Ports = append(se.Ports, &networking.Port{Number: uint32(p.Port), Name: p.Name, Protocol: string(p.Protocol)})
+		}
+		name, ns := svc.Attributes.Name, svc.Attributes.Namespace
+		if name == "" {
+			name = strings.ReplaceAll(svc.Hostname, ".", "-")
+		}
+		obj, err := crd.ConvertConfig(config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.ServiceEntry,
+				Name:             name,
+				Namespace:        ns,
+				Labels:           svc.Attributes.Labels,
+			},
+			Spec: se,
+		})
+		if err != nil {
+			return nil, err
+		}
+		out, err := yaml.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, string(out))
+	}
+	return []byte(strings.Join(docs, "---\n")), nil
+}
+
+// mergeSnapshotEndpoint adds the endpoint to the list, merging its ports into the endpoint of the same address.
+func mergeSnapshotEndpoint(entries []*networking.WorkloadEntry, we *networking.WorkloadEntry) []*networking.WorkloadEntry {
+	for _, e := range entries {
+		if e.Address == we.Address {
+			for name, port := range we.Ports {
+				e.Ports[name] = port
+			}
+			return entries
+		}
+	}
+	return append(entries, we)
+}
+
+func snapshotResolution(r model.Resolution) networking.ServiceEntry_Resolution {
+	switch r {
+	case model.DNSLB:
+		return networking.ServiceEntry_DNS
+	case model.DNSRoundRobinLB:
+		return networking.ServiceEntry_DNS_ROUND_ROBIN
+	case model.Passthrough:
+		return networking.ServiceEntry_NONE
+	default:
+		return networking.ServiceEntry_STATIC
+	}
+}
+
+func indentJSON(in []byte) []byte {
+	var out bytes.Buffer
+	if err := json.Indent(&out, in, "", "  "); err != nil {
+		return in
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
@@ -0,0 +1,84 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteMeshSnapshot(t *testing.T) {
+	var configs []map[string]any
+	if err := json.Unmarshal([]byte(`[
+{"apiVersion":"networking.istio.io/v1alpha3","kind":"VirtualService","metadata":{"name":"reviews","namespace":"default","resourceVersion":"12"},"spec":{"hosts":["reviews"]}},
+{"apiVersion":"networking.istio.io/v1alpha3","kind":"DestinationRule","metadata":{"name":"reviews","namespace":"default","uid":"abc"},"spec":{"host":"reviews"}}
+]`), &configs); err != nil {
+		t.Fatal(err)
+	}
+	snap := &meshSnapshot{
+		Istiod:  "istiod-1",
+		Configs: configs,
+		Mesh:    []byte(`{"rootNamespace":"istio-system"}`),
+		Services: []byte(`[
+{"hostname":"reviews.default.svc.cluster.local","defaultAddress":"10.0.0.1","ports":[{"name":"http","port":9080,"protocol":"HTTP"}],
+ "Attributes":{"ServiceRegistry":"Kubernetes","Name":"reviews","Namespace":"default"}},
+{"hostname":"example.com","ports":[{"name":"https","port":443,"protocol":"TLS"}],"Resolution":1,
+ "Attributes":{"ServiceRegistry":"External","Name":"example.com","Namespace":"default"}}
+]`),
+		Endpoints: []byte(`[
+{"svc":"reviews.default.svc.cluster.local:http","ep":[{"endpoint":{"Address":"10.1.0.1","ServicePortName":"http","EndpointPort":9080}}]}
+]`),
+		ConfigDumps: map[string]json.RawMessage{"reviews-v1.default": []byte(`{"configs":[]}`)},
+		Proxies:     []string{"reviews-v1.default"},
+	}
+	dir := t.TempDir()
+	if err := writeMeshSnapshot(dir, snap); err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	istio := read("config/istio.yaml")
+	if strings.Index(istio, "kind: DestinationRule") > strings.Index(istio, "kind: VirtualService") {
+		t.Errorf("configs are not sorted:\n%s", istio)
+	}
+	if strings.Contains(istio, "resourceVersion") || strings.Contains(istio, "uid") {
+		t.Errorf("configs hold server metadata:\n%s", istio)
+	}
+	services := read("config/services.yaml")
+	for _, want := range []string{"reviews.default.svc.cluster.local", "10.0.0.1", "address: 10.1.0.1", "http: 9080", "resolution: STATIC"} {
+		if !strings.Contains(services, want) {
+			t.Errorf("services do not contain %q:\n%s", want, services)
+		}
+	}
+	if strings.Contains(services, "example.com") {
+		t.Errorf("services contain the ServiceEntries of the configs:\n%s", services)
+	}
+	if got := read("mesh.yaml"); got != "rootNamespace: istio-system\n" {
+		t.Errorf("unexpected mesh config %q", got)
+	}
+	if got := read("snapshot.yaml"); !strings.Contains(got, "reviews-v1.default") {
+		t.Errorf("manifest does not list the proxies:\n%s", got)
+	}
+	read("proxies/reviews-v1.default.json")
+}
@@ -0,0 +1,171 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+// indexSnapshots are the index snapshots of the istiod instances, by istiod instance.
+type indexSnapshots map[string]xds.IndexSnapshot
+
+func indexSnapshotCmd() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	var outputFile string
+	cmd := &cobra.Command{
+		Use:   "index-snapshot",
+		Short: "Summarize the size of the internal indexes of istiod",
+		Long: `Summarizes the number of entries of the internal indexes of each istiod instance: the services, the
+endpoints of each shard, the configs of each kind, the sidecar scopes and the XDS cache entries.
+
+The snapshot can be written to a file, and two snapshots compared with the diff subcommand, to find the index
+growing unboundedly before istiod runs out of memory.`,
+		Example: `  # Print the size of the indexes of istiod
+  istioctl experimental index-snapshot
+
+  # Compare two snapshots taken an hour apart
+  istioctl experimental index-snapshot -o before.json
+  istioctl experimental index-snapshot -o after.json
+  istioctl experimental index-snapshot diff before.json after.json`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			res, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace, "/debug/indexz")
+			if err != nil {
+				return err
+			}
+			snapshots := indexSnapshots{}
+			for istiod, body := range res {
+				var snap xds.IndexSnapshot
+				if err := json.Unmarshal(body, &snap); err != nil {
+					return fmt.Errorf("failed to parse the index snapshot of %s: %v", istiod, err)
+				}
+				snapshots[istiod] = snap
+			}
+			if outputFile == "" {
+				return printIndexSnapshots(c.OutOrStdout(), snapshots)
+			}
+			out, err := json.MarshalIndent(snapshots, "", "  ")
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(outputFile, out, 0o644); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(c.OutOrStdout(), "Index snapshot of %d istiod instances written to %s\n", len(snapshots), outputFile)
+			return nil
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	cmd.PersistentFlags().StringVarP(&outputFile, "output", "o", "", "File the snapshot is written to, as JSON.")
+	cmd.AddCommand(indexSnapshotDiffCmd())
+	return cmd
+}
+
+func indexSnapshotDiffCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <before> <after>",
+		Short: "Compare two index snapshots",
+		Long: `Compares two index snapshots written by index-snapshot, and prints the indexes whose number of entries
+changed on each istiod instance, the largest growths first. Only the istiod instances present in both snapshots
+are compared.`,
+		Example: `  istioctl experimental index-snapshot diff before.json after.json`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(c *cobra.Command, args []string) error {
+			before, err := readIndexSnapshots(args[0])
+			if err != nil {
+				return err
+			}
+			after, err := readIndexSnapshots(args[1])
+			if err != nil {
+				return err
+			}
+			return printIndexSnapshotsDiff(c.OutOrStdout(), before, after)
+		},
+	}
+}
+
+func readIndexSnapshots(file string) (indexSnapshots, error) {
+	by, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := indexSnapshots{}
+	if err := json.Unmarshal(by, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse the index snapshot %s: %v", file, err)
+	}
+	return snapshots, nil
+}
+
+func (s indexSnapshots) istiods() []string {
+	istiods := make([]string, 0, len(s))
+	for istiod := range s {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	return istiods
+}
+
+func printIndexSnapshots(out io.Writer, snapshots indexSnapshots) error {
+	w := new(tabwriter.Writer).Init(out, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ISTIOD\tINDEX\tENTRIES")
+	for _, istiod := range snapshots.istiods() {
+		indexes := snapshots[istiod].Indexes
+		names := make([]string, 0, len(indexes))
+		for name := range indexes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d\n", istiod, name, indexes[name])
+		}
+	}
+	return w.Flush()
+}
+
+func printIndexSnapshotsDiff(out io.Writer, before, after indexSnapshots) error {
+	w := new(tabwriter.Writer).Init(out, 0, 8, 5, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ISTIOD\tINDEX\tBEFORE\tAFTER\tDELTA\tRATE/HOUR")
+	for _, istiod := range after.istiods() {
+		b, f := before[istiod]
+		if !f {
+			continue
+		}
+		a := after[istiod]
+		elapsed := a.Time.Sub(b.Time).Hours()
+		for _, d := range xds.DiffIndexSnapshots(b, a) {
+			rate := "-"
+			if elapsed > 0 {
+				rate = fmt.Sprintf("%+.1f", float64(d.Delta())/elapsed)
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%+d\t%s\n", istiod, d.Index, d.Before, d.After, d.Delta(), rate)
+		}
+	}
+	return w.Flush()
+}
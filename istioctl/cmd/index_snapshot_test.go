@@ -0,0 +1,60 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+func TestPrintIndexSnapshotsDiff(t *testing.T) {
+	now := time.Now()
+	before := indexSnapshots{
+		"istiod-1": {Time: now.Add(-2 * time.Hour), Indexes: map[string]int{"endpoints": 100, "services": 10}},
+		"istiod-2": {Time: now.Add(-2 * time.Hour), Indexes: map[string]int{"endpoints": 100}},
+	}
+	after := indexSnapshots{
+		"istiod-1": {Time: now, Indexes: map[string]int{"endpoints": 300, "services": 10}},
+		"istiod-3": {Time: now, Indexes: map[string]int{"endpoints": 100}},
+	}
+	var out bytes.Buffer
+	if err := printIndexSnapshotsDiff(&out, before, after); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and one diff, got:\n%s", out.String())
+	}
+	if got := strings.Fields(lines[1]); strings.Join(got, " ") != "istiod-1 endpoints 100 300 +200 +100.0" {
+		t.Fatalf("unexpected diff %q", lines[1])
+	}
+}
+
+func TestPrintIndexSnapshots(t *testing.T) {
+	var out bytes.Buffer
+	if err := printIndexSnapshots(&out, indexSnapshots{
+		"istiod-1": xds.IndexSnapshot{Indexes: map[string]int{"services": 10, "endpoints": 100}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "istiod-1") || !strings.Contains(lines[1], "endpoints") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
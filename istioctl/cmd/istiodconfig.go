@@ -34,6 +34,7 @@ import (
 	"istio.io/api/label"
 	"istio.io/istio/istioctl/pkg/clioptions"
 	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/writeapi"
 	"istio.io/pkg/log"
 )
 
@@ -391,6 +392,9 @@ func istiodLogCmd() *cobra.Command {
 
   # Reset levels of all the loggers to default value (info).
   istioctl admin log -r
+
+  # Update levels through istiod, as allowed by its write policy to the oncall service account of the ops namespace.
+  istioctl admin log --level ads:debug --through-istiod --write-identity oncall.ops
 `,
 		Aliases: []string{"l"},
 		Args: func(logCmd *cobra.Command, args []string) error {
@@ -440,6 +444,17 @@ func istiodLogCmd() *cobra.Command {
 				podName, ns = args[0], istioNamespace
 			}
 
+			if throughIstiod {
+				if outputLogLevel == "" || istiodReset || stackTraceLevel != "" {
+					return errors.New("--through-istiod only supports --level")
+				}
+				_, err := writeThroughIstiod(context.TODO(), client, podName, ns, writeapi.Request{
+					Operation: writeapi.LogLevel,
+					LogLevels: strings.Split(outputLogLevel, ","),
+				})
+				return err
+			}
+
 			portForwarder, err := client.NewPortForwarder(podName, ns, bindAddress, 0, controlZport)
 			if err != nil {
 				return fmt.Errorf("could not build port forwarder for ControlZ %s: %v", podName, err)
@@ -477,5 +492,6 @@ func istiodLogCmd() *cobra.Command {
 			"Possible values for <stack-trace-level>: none, error, warn, info, debug")
 	logCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o",
 		outputFormat, "Output format: one of json|short")
+	attachWriteAPIFlags(logCmd)
 	return logCmd
 }
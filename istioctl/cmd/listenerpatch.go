@@ -0,0 +1,209 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
+	"sigs.k8s.io/yaml"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/listenerpatch"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/validation"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func listenerPatchCmd() *cobra.Command {
+	var filename, name string
+	cmd := &cobra.Command{
+		Use:   "listener-patch",
+		Short: "Generate an EnvoyFilter from a typed listener patch",
+		Long: `Generates an EnvoyFilter setting a typed listener patch, covering the most common EnvoyFilter use cases.
+
+A listener patch can insert HTTP filters before or after a named filter, set listener socket options and
+override cluster circuit breaker thresholds. The patch is set with the networking.istio.io/listener-patch
+annotation of the EnvoyFilter, which istiod compiles to EnvoyFilter patches. Unlike raw EnvoyFilters, the patch
+is fully validated, here and by the validation webhook: unknown fields, invalid contexts, filters inserted after
+the router and incomplete options are all rejected.`,
+		Example: `  # Insert a Lua filter before the router in the inbound listeners of the reviews workloads
+  cat <<EOF | istioctl experimental listener-patch --name reviews-lua -f -
+  workloadSelector:
+    app: reviews
+  httpFilters:
+  - context: SIDECAR_INBOUND
+    name: envoy.filters.http.lua
+    before: envoy.filters.http.router
+    typedConfig:
+      "@type": type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua
+      inlineCode: |
+        function envoy_on_request(handle) end
+  circuitBreakers:
+  - service: ratings.default.svc.cluster.local
+    maxConnections: 100
+  EOF`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if filename == "" || name == "" {
+				c.Println(c.UsageString())
+				return fmt.Errorf("--filename and --name must be set")
+			}
+			var in io.Reader = c.InOrStdin()
+			if filename != "-" {
+				f, err := os.Open(filename)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			b, err := io.ReadAll(in)
+			if err != nil {
+				return err
+			}
+			out, err := generateEnvoyFilter(b, name, handlers.HandleNamespace(namespace, defaultNamespace))
+			if err != nil {
+				return err
+			}
+			_, _ = c.OutOrStdout().Write(out)
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&filename, "filename", "f", "", "The listener patch file, or - for stdin")
+	cmd.PersistentFlags().StringVar(&name, "name", "", "The name of the generated EnvoyFilter")
+	cmd.AddCommand(listenerPatchConvertCmd(&filename))
+	return cmd
+}
+
+func listenerPatchConvertCmd(filename *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "convert",
+		Short: "Convert EnvoyFilters to listener patches where possible",
+		Long: `Converts the patches of EnvoyFilters covered by listener patches, to move them off raw EnvoyFilters.
+
+Each EnvoyFilter of the file is converted to a listener patch, followed by a comment for each of its patches that
+cannot be converted, with the reason. Patches matching proxy versions or metadata are never converted: unlike
+EnvoyFilters, listener patches do not depend on the version of the proxies. The EnvoyFilters of which only some
+patches are converted must be kept for the other patches.`,
+		Example: `  # Convert the EnvoyFilters of a namespace
+  kubectl get envoyfilters -n default -o yaml | istioctl experimental listener-patch convert -f -`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if *filename == "" {
+				c.Println(c.UsageString())
+				return fmt.Errorf("--filename must be set")
+			}
+			b, err := readFile(*filename)
+			if err != nil {
+				return err
+			}
+			out, err := convertEnvoyFilters(b)
+			if err != nil {
+				return err
+			}
+			_, _ = c.OutOrStdout().Write(out)
+			return nil
+		},
+	}
+}
+
+// convertEnvoyFilters converts the EnvoyFilters of the YAML documents to listener patches.
+func convertEnvoyFilters(in []byte) ([]byte, error) {
+	// kubectl get -o yaml returns a List of the objects, converted to a stream of JSON objects.
+	if list := (&struct {
+		Kind  string            `json:"kind"`
+		Items []json.RawMessage `json:"items"`
+	}{}); yaml.Unmarshal(in, list) == nil && list.Kind == "List" {
+		docs := make([]string, 0, len(list.Items))
+		for _, item := range list.Items {
+			docs = append(docs, string(item))
+		}
+		in = []byte(strings.Join(docs, "\n"))
+	}
+	cfgs, _, err := crd.ParseInputs(string(in))
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	for _, cfg := range cfgs {
+		if cfg.GroupVersionKind != gvk.EnvoyFilter {
+			continue
+		}
+		if out.Len() > 0 {
+			out.WriteString("---\n")
+		}
+		lp, unconverted := listenerpatch.FromEnvoyFilter(cfg.Spec.(*networking.EnvoyFilter))
+		_, _ = fmt.Fprintf(&out, "# EnvoyFilter %s/%s\n", cfg.Namespace, cfg.Name)
+		if len(lp.HTTPFilters)+len(lp.SocketOptions)+len(lp.CircuitBreakers) > 0 {
+			b, err := yaml.Marshal(lp)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(b)
+		}
+		for _, i := range sets.SortedList(sets.New(maps.Keys(unconverted)...)) {
+			_, _ = fmt.Fprintf(&out, "# configPatches[%d] is not converted: %s\n", i, unconverted[i])
+		}
+	}
+	if out.Len() == 0 {
+		return nil, fmt.Errorf("no EnvoyFilter found")
+	}
+	return out.Bytes(), nil
+}
+
+// generateEnvoyFilter returns the YAML of an EnvoyFilter setting a listener patch, validating both.
+func generateEnvoyFilter(patch []byte, name, ns string) ([]byte, error) {
+	lp := &listenerpatch.ListenerPatch{}
+	if err := yaml.UnmarshalStrict(patch, lp); err != nil {
+		return nil, fmt.Errorf("invalid listener patch: %v", err)
+	}
+	ef, annotations, err := lp.ToEnvoyFilter()
+	if err != nil {
+		return nil, err
+	}
+	return envoyFilterYAML(ef, annotations, name, ns)
+}
+
+// envoyFilterYAML validates a generated EnvoyFilter and returns its YAML.
+func envoyFilterYAML(ef *networking.EnvoyFilter, annotations map[string]string, name, ns string) ([]byte, error) {
+	cfg := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.EnvoyFilter,
+			Name:             name,
+			Namespace:        ns,
+			Annotations:      annotations,
+		},
+		Spec: ef,
+	}
+	// The generated EnvoyFilter is subject to the same validation as any EnvoyFilter.
+	if _, err := validation.ValidateEnvoyFilter(cfg); err != nil {
+		return nil, fmt.Errorf("generated EnvoyFilter is invalid: %v", err)
+	}
+	obj, err := crd.ConvertConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(obj)
+}
@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateEnvoyFilter(t *testing.T) {
+	out, err := generateEnvoyFilter([]byte(`
+workloadSelector:
+  app: reviews
+circuitBreakers:
+- service: ratings.default.svc.cluster.local
+  maxConnections: 100
+`), "reviews", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: EnvoyFilter", "name: reviews", "namespace: default", "networking.istio.io/listener-patch", "maxConnections: 100"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	if _, err := generateEnvoyFilter([]byte(`circuitBreakers:
+- service: ratings.default.svc.cluster.local
+  maxConnection: 100
+`), "reviews", "default"); err == nil || !strings.Contains(err.Error(), "invalid listener patch") {
+		t.Errorf("expected unknown fields to be rejected, got %v", err)
+	}
+}
+
+func TestConvertEnvoyFilters(t *testing.T) {
+	envoyFilter := `apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: ratings
+  namespace: default
+spec:
+  configPatches:
+  - applyTo: CLUSTER
+    match:
+      context: SIDECAR_OUTBOUND
+      cluster:
+        service: ratings.default.svc.cluster.local
+    patch:
+      operation: MERGE
+      value:
+        circuit_breakers:
+          thresholds:
+          - max_connections: 100
+  - applyTo: NETWORK_FILTER
+    match:
+      context: SIDECAR_INBOUND
+    patch:
+      operation: REMOVE
+`
+	for _, in := range []string{envoyFilter, "apiVersion: v1\nkind: List\nitems:\n- " + strings.ReplaceAll(envoyFilter, "\n", "\n  ")} {
+		out, err := convertEnvoyFilters([]byte(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			"# EnvoyFilter default/ratings", "service: ratings.default.svc.cluster.local", "maxConnections: 100",
+			"# configPatches[1] is not converted: NETWORK_FILTER patches are not supported",
+		} {
+			if !strings.Contains(string(out), want) {
+				t.Errorf("expected %q in output:\n%s", want, out)
+			}
+		}
+	}
+
+	if _, err := convertEnvoyFilters([]byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\n")); err == nil {
+		t.Errorf("expected an error without EnvoyFilter")
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -28,23 +30,49 @@ import (
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/exp/maps"
 
 	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/pkg/util/sets"
 	"istio.io/pkg/log"
 )
 
 var (
 	metricsOpts     clioptions.ControlPlaneOptions
 	metricsDuration time.Duration
+
+	metricsPrometheusAddress string
+	metricsBearerTokenFile   string
+	metricsUsername          string
+	metricsPasswordFile      string
+	metricsHeaders           []string
+
+	metricsBy               string
+	metricsSLO              float64
+	metricsCompareRevisions []string
+	metricsCompareOffset    time.Duration
 )
 
 const (
 	destWorkloadLabel          = "destination_workload"
 	destWorkloadNamespaceLabel = "destination_workload_namespace"
+	destRevisionLabel          = "destination_canonical_revision"
 	reqTot                     = "istio_requests_total"
 	reqDur                     = "istio_request_duration_milliseconds"
 )
 
+// metricsBreakdowns are the labels grouping the metrics of a workload, for each value of --by.
+var metricsBreakdowns = map[string][]string{
+	"source":    {"source_workload", "source_workload_namespace"},
+	"revision":  {destRevisionLabel},
+	"code":      {"response_code"},
+	"route":     {"route_name"},
+	"operation": {"request_operation"},
+}
+
+var latencyQuantiles = []float64{0.5, 0.9, 0.99}
+
 func metricsCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "metrics <workload name>...",
@@ -52,16 +80,37 @@ func metricsCmd() *cobra.Command {
 		Long: `
 Prints the metrics for the specified service(s) when running in Kubernetes.
 
-This command finds a Prometheus pod running in the specified istio system
-namespace. It then executes a series of queries per requested workload to
-find the following top-level workload metrics: total requests per second,
-error rate, and request latency at p50, p90, and p99 percentiles. The
-query results are printed to the console, organized by workload name.
+This command queries Prometheus, or any server implementing the Prometheus
+query API such as Thanos, for the following top-level workload metrics: total
+requests per second, error rate, and request latency at p50, p90, and p99
+percentiles. The query results are printed to the console, organized by
+workload name.
+
+By default, this command finds a Prometheus pod running in the specified istio
+system namespace and port-forwards to it. Use --prometheus-address to query
+another server, optionally with a bearer token or basic authentication.
+
+The metrics of a workload can be broken down with --by:
+  source     by source workload
+  revision   by canonical revision of the workload
+  code       by response code
+  route      by route, from the route_name label. The label is not reported by
+             default; add it with a Telemetry tag override set to "xds.route_name".
+  operation  by operation, from the request_operation label set when
+             classifying requests.
+
+With --slo, the error rate is compared to the success rate objective, and the
+remaining error budget over the duration of the query is printed.
+
+With --compare-revisions, the metrics of two canonical revisions of the
+workloads are printed side by side, followed by their difference. With
+--compare-offset, the metrics are compared to the same duration ending at the
+offset in the past.
 
 All metrics returned are from server-side reports. This means that latencies
 and error rates are from the perspective of the service itself and not of an
 individual client (or aggregate set of clients). Rates and latencies are
-calculated over a time interval of 1 minute.
+calculated over a time interval of 1 minute by default.
 `,
 		Example: `  # Retrieve workload metrics for productpage-v1 workload
   istioctl experimental metrics productpage-v1
@@ -70,7 +119,20 @@ calculated over a time interval of 1 minute.
   istioctl experimental metrics productpage-v1 -d 2m
 
   # Retrieve workload metrics for various services in the different namespaces
-  istioctl experimental metrics productpage-v1.foo reviews-v1.bar ratings-v1.baz`,
+  istioctl experimental metrics productpage-v1.foo reviews-v1.bar ratings-v1.baz
+
+  # Retrieve the metrics of the reviews workload by source, from a Thanos query endpoint
+  istioctl experimental metrics reviews --by source --prometheus-address https://thanos.example.com \
+    --prometheus-bearer-token-file /var/run/secrets/token
+
+  # Print the error budget left in the last 30 days for a 99.9% success rate objective
+  istioctl experimental metrics reviews -d 720h --slo 99.9
+
+  # Compare the metrics of two revisions of the reviews workload
+  istioctl experimental metrics reviews --compare-revisions v1,v2
+
+  # Compare the metrics of the last 10 minutes to the same time yesterday
+  istioctl experimental metrics reviews -d 10m --compare-offset 24h`,
 		// nolint: goimports
 		Aliases: []string{"m"},
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -78,165 +140,419 @@ calculated over a time interval of 1 minute.
 				cmd.Println(cmd.UsageString())
 				return fmt.Errorf("metrics requires workload name")
 			}
-			return nil
+			return validateMetricsFlags()
 		},
 		RunE:                  run,
 		DisableFlagsInUseLine: true,
 	}
 
 	cmd.PersistentFlags().DurationVarP(&metricsDuration, "duration", "d", time.Minute, "Duration of query metrics, default value is 1m.")
+	addPrometheusFlags(cmd.PersistentFlags())
+	cmd.PersistentFlags().StringVar(&metricsBy, "by", "",
+		"Breaks down the metrics of each workload, one of source, revision, code, route or operation.")
+	cmd.PersistentFlags().Float64Var(&metricsSLO, "slo", 0,
+		"Success rate objective in percent, for example 99.9. If set, the error budget left over the duration is printed.")
+	cmd.PersistentFlags().StringSliceVar(&metricsCompareRevisions, "compare-revisions", nil,
+		"Compares the metrics of two canonical revisions of the workloads, for example v1,v2.")
+	cmd.PersistentFlags().DurationVar(&metricsCompareOffset, "compare-offset", 0,
+		"Compares the metrics to the metrics of the same duration ending at the offset in the past, for example 24h.")
 
 	return cmd
 }
 
+// addPrometheusFlags adds the flags selecting the Prometheus query API and its credentials.
+func addPrometheusFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&metricsPrometheusAddress, "prometheus-address", "",
+		"Address of the Prometheus or Thanos query API, for example https://thanos.example.com. "+
+			"If not set, a port-forward to the Prometheus pod of the istio system namespace is used.")
+	flags.StringVar(&metricsBearerTokenFile, "prometheus-bearer-token-file", "",
+		"File containing the bearer token sent to the Prometheus query API.")
+	flags.StringVar(&metricsUsername, "prometheus-username", "",
+		"Username of the basic authentication to the Prometheus query API.")
+	flags.StringVar(&metricsPasswordFile, "prometheus-password-file", "",
+		"File containing the password of the basic authentication to the Prometheus query API.")
+	flags.StringSliceVar(&metricsHeaders, "prometheus-header", nil,
+		"Headers sent to the Prometheus query API, as key=value, for example X-Scope-OrgID=tenant.")
+}
+
+// validatePrometheusFlags validates the flags added by addPrometheusFlags.
+func validatePrometheusFlags() error {
+	if metricsPasswordFile != "" && metricsUsername == "" {
+		return errors.New("--prometheus-password-file requires --prometheus-username")
+	}
+	if metricsBearerTokenFile != "" && metricsUsername != "" {
+		return errors.New("--prometheus-bearer-token-file and --prometheus-username are mutually exclusive")
+	}
+	for _, h := range metricsHeaders {
+		if k, _, f := strings.Cut(h, "="); !f || k == "" {
+			return fmt.Errorf("invalid header %q, expected key=value", h)
+		}
+	}
+	return nil
+}
+
+func validateMetricsFlags() error {
+	if _, f := metricsBreakdowns[metricsBy]; metricsBy != "" && !f {
+		return fmt.Errorf("unknown breakdown %q, expected one of source, revision, code, route or operation", metricsBy)
+	}
+	if metricsSLO < 0 || metricsSLO >= 100 {
+		return fmt.Errorf("--slo must be a percentage between 0 and 100, got %v", metricsSLO)
+	}
+	if len(metricsCompareRevisions) > 0 && len(metricsCompareRevisions) != 2 {
+		return fmt.Errorf("--compare-revisions requires two revisions, got %d", len(metricsCompareRevisions))
+	}
+	if len(metricsCompareRevisions) > 0 && metricsCompareOffset != 0 {
+		return errors.New("--compare-revisions and --compare-offset are mutually exclusive")
+	}
+	if metricsCompareOffset < 0 {
+		return fmt.Errorf("--compare-offset must be positive, got %v", metricsCompareOffset)
+	}
+	return validatePrometheusFlags()
+}
+
 type workloadMetrics struct {
-	workload                           string
+	workload string
+	// group identifies the breakdown of the metrics of the workload, if any.
+	group string
+	// compared identifies the revision or the time window of the metrics in compare mode, or "delta" for the
+	// difference between the compared metrics.
+	compared                           string
 	totalRPS, errorRPS                 float64
 	p50Latency, p90Latency, p99Latency time.Duration
 }
 
+// errorRate returns the ratio of the requests that failed.
+func (wm workloadMetrics) errorRate() float64 {
+	if wm.totalRPS == 0 {
+		return 0
+	}
+	return wm.errorRPS / wm.totalRPS
+}
+
+// errorBudgetLeft returns the ratio of the error budget of the success rate objective, in percent, that is left.
+// It is negative once the budget is exhausted.
+func (wm workloadMetrics) errorBudgetLeft(slo float64) float64 {
+	return 1 - wm.errorRate()/(1-slo/100)
+}
+
+// metricsQuery builds the queries of the metrics of a workload.
+type metricsQuery struct {
+	// name is the workload as passed to the command.
+	name      string
+	workload  string
+	namespace string
+	duration  time.Duration
+	// offset moves the end of the queried window in the past.
+	offset time.Duration
+	// revision restricts the metrics to a canonical revision of the workload.
+	revision string
+	// by are the labels breaking down the metrics.
+	by []string
+	// label identifies the query in compare mode.
+	label string
+}
+
+func newMetricsQuery(workload string, duration time.Duration, by []string) metricsQuery {
+	parts := strings.Split(workload, ".")
+	q := metricsQuery{name: workload, workload: parts[0], duration: duration, by: by}
+	if len(parts) > 1 {
+		q.namespace = parts[1]
+	}
+	return q
+}
+
+func (q metricsQuery) rate(metric, extraSelector string) string {
+	selector := fmt.Sprintf(`%s=~"%s.*", %s=~"%s.*",reporter="destination"`,
+		destWorkloadLabel, q.workload, destWorkloadNamespaceLabel, q.namespace)
+	if q.revision != "" {
+		selector += fmt.Sprintf(`,%s="%s"`, destRevisionLabel, q.revision)
+	}
+	r := fmt.Sprintf("rate(%s{%s%s}[%s]", metric, selector, extraSelector, q.duration)
+	if q.offset > 0 {
+		r += fmt.Sprintf(" offset %s", q.offset)
+	}
+	return r + ")"
+}
+
+func (q metricsQuery) sum(expr string, by ...string) string {
+	by = append(by, q.by...)
+	if len(by) == 0 {
+		return fmt.Sprintf("sum(%s)", expr)
+	}
+	return fmt.Sprintf("sum(%s) by (%s)", expr, strings.Join(by, ", "))
+}
+
+func (q metricsQuery) requestRate() string {
+	return q.sum(q.rate(reqTot, ""))
+}
+
+func (q metricsQuery) errorRate() string {
+	return q.sum(q.rate(reqTot, `,response_code=~"[45][0-9]{2}"`))
+}
+
+func (q metricsQuery) latency(quantile float64) string {
+	return fmt.Sprintf("histogram_quantile(%f, %s)", quantile, q.sum(q.rate(reqDur+"_bucket", ""), "le"))
+}
+
 func run(c *cobra.Command, args []string) error {
 	log.Debugf("metrics command invoked for workload(s): %v", args)
 
+	promAPI, closer, err := prometheusQueryAPI()
+	if err != nil {
+		return err
+	}
+	defer closer()
+
+	table := metricsTable{by: metricsBy, slo: metricsSLO, compare: len(metricsCompareRevisions) > 0 || metricsCompareOffset > 0}
+	table.printHeader(c.OutOrStdout())
+
+	for _, workload := range args {
+		q := newMetricsQuery(workload, metricsDuration, metricsBreakdowns[metricsBy])
+		var rows []workloadMetrics
+		if table.compare {
+			rows, err = compare(promAPI, q)
+		} else {
+			rows, err = metrics(promAPI, q)
+		}
+		if err != nil {
+			return fmt.Errorf("could not build metrics for workload '%s': %v", workload, err)
+		}
+
+		for _, wm := range rows {
+			table.printMetrics(c.OutOrStdout(), wm)
+		}
+	}
+	return nil
+}
+
+// prometheusQueryAPI returns the query API of --prometheus-address, or of the Prometheus pod of the istio system
+// namespace through a port-forward if it is not set, with a function closing the port-forward.
+func prometheusQueryAPI() (promv1.API, func(), error) {
+	if metricsPrometheusAddress != "" {
+		promAPI, err := prometheusAPI(metricsPrometheusAddress)
+		return promAPI, func() {}, err
+	}
+
 	client, err := kubeClientWithRevision(kubeconfig, configContext, metricsOpts.Revision)
 	if err != nil {
-		return fmt.Errorf("failed to create k8s client: %v", err)
+		return nil, nil, fmt.Errorf("failed to create k8s client: %v", err)
 	}
 
 	pl, err := client.PodsForSelector(context.TODO(), istioNamespace, "app=prometheus")
 	if err != nil {
-		return fmt.Errorf("not able to locate Prometheus pod: %v", err)
+		return nil, nil, fmt.Errorf("not able to locate Prometheus pod: %v", err)
 	}
 
 	if len(pl.Items) < 1 {
-		return errors.New("no Prometheus pods found")
+		return nil, nil, errors.New("no Prometheus pods found")
 	}
 
 	// only use the first pod in the list
 	promPod := pl.Items[0]
 	fw, err := client.NewPortForwarder(promPod.Name, istioNamespace, "", 0, 9090)
 	if err != nil {
-		return fmt.Errorf("could not build port forwarder for prometheus: %v", err)
+		return nil, nil, fmt.Errorf("could not build port forwarder for prometheus: %v", err)
 	}
 
 	if err = fw.Start(); err != nil {
-		return fmt.Errorf("failure running port forward process: %v", err)
+		return nil, nil, fmt.Errorf("failure running port forward process: %v", err)
 	}
 
 	// Close the forwarder either when we exit or when an this processes is interrupted.
-	defer fw.Close()
 	closePortForwarderOnInterrupt(fw)
 
 	log.Debugf("port-forward to prometheus pod ready")
 
 	promAPI, err := prometheusAPI(fmt.Sprintf("http://%s", fw.Address()))
 	if err != nil {
-		return fmt.Errorf("failure running port forward process: %v", err)
-	}
-
-	printHeader(c.OutOrStdout())
-
-	workloads := args
-	for _, workload := range workloads {
-		sm, err := metrics(promAPI, workload, metricsDuration)
-		if err != nil {
-			return fmt.Errorf("could not build metrics for workload '%s': %v", workload, err)
-		}
-
-		printMetrics(c.OutOrStdout(), sm)
+		fw.Close()
+		return nil, nil, err
 	}
-	return nil
+	return promAPI, fw.Close, nil
 }
 
 func prometheusAPI(address string) (promv1.API, error) {
-	promClient, err := api.NewClient(api.Config{Address: address})
+	rt, err := prometheusRoundTripper()
+	if err != nil {
+		return nil, err
+	}
+	promClient, err := api.NewClient(api.Config{Address: address, RoundTripper: rt})
 	if err != nil {
 		return nil, fmt.Errorf("could not build prometheus client: %v", err)
 	}
 	return promv1.NewAPI(promClient), nil
 }
 
-func metrics(promAPI promv1.API, workload string, duration time.Duration) (workloadMetrics, error) {
-	parts := strings.Split(workload, ".")
-	wname := parts[0]
-	wns := ""
-	if len(parts) > 1 {
-		wns = parts[1]
-	}
-
-	rpsQuery := fmt.Sprintf(`sum(rate(%s{%s=~"%s.*", %s=~"%s.*",reporter="destination"}[%s]))`,
-		reqTot, destWorkloadLabel, wname, destWorkloadNamespaceLabel, wns, duration)
-	errRPSQuery := fmt.Sprintf(`sum(rate(%s{%s=~"%s.*", %s=~"%s.*",reporter="destination",response_code=~"[45][0-9]{2}"}[%s]))`,
-		reqTot, destWorkloadLabel, wname, destWorkloadNamespaceLabel, wns, duration)
+// authRoundTripper adds the credentials and the headers set by the flags to the requests to the query API.
+type authRoundTripper struct {
+	next               http.RoundTripper
+	bearerToken        string
+	username, password string
+	headers            http.Header
+}
 
-	var me *multierror.Error
-	var err error
-	sm := workloadMetrics{workload: workload}
-	sm.totalRPS, err = vectorValue(promAPI, rpsQuery)
-	if err != nil {
-		me = multierror.Append(me, err)
+func (rt authRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range rt.headers {
+		req.Header[k] = v
+	}
+	if rt.bearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+rt.bearerToken)
+	} else if rt.username != "" {
+		req.SetBasicAuth(rt.username, rt.password)
 	}
+	return rt.next.RoundTrip(req)
+}
 
-	sm.errorRPS, err = vectorValue(promAPI, errRPSQuery)
-	if err != nil {
-		me = multierror.Append(me, err)
+func prometheusRoundTripper() (http.RoundTripper, error) {
+	rt := authRoundTripper{next: api.DefaultRoundTripper, username: metricsUsername, headers: http.Header{}}
+	if metricsBearerTokenFile != "" {
+		b, err := os.ReadFile(metricsBearerTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the bearer token: %v", err)
+		}
+		rt.bearerToken = strings.TrimSpace(string(b))
 	}
+	if metricsPasswordFile != "" {
+		b, err := os.ReadFile(metricsPasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read the password: %v", err)
+		}
+		rt.password = strings.TrimSpace(string(b))
+	}
+	for _, h := range metricsHeaders {
+		k, v, _ := strings.Cut(h, "=")
+		rt.headers.Add(k, v)
+	}
+	return rt, nil
+}
 
-	p50Latency, err := getLatency(promAPI, wname, wns, duration, 0.5)
-	if err != nil {
-		me = multierror.Append(me, err)
+// metrics returns the metrics of the workload, one per group if the query breaks them down.
+func metrics(promAPI promv1.API, q metricsQuery) ([]workloadMetrics, error) {
+	var me *multierror.Error
+	values := func(query string) map[string]float64 {
+		v, err := vectorValues(promAPI, query, q.by)
+		if err != nil {
+			me = multierror.Append(me, err)
+		}
+		return v
 	}
-	sm.p50Latency = p50Latency
 
-	p90Latency, err := getLatency(promAPI, wname, wns, duration, 0.9)
-	if err != nil {
-		me = multierror.Append(me, err)
+	totalRPS := values(q.requestRate())
+	errorRPS := values(q.errorRate())
+	latencies := make([]map[string]float64, 0, len(latencyQuantiles))
+	for _, quantile := range latencyQuantiles {
+		latencies = append(latencies, values(q.latency(quantile)))
 	}
-	sm.p90Latency = p90Latency
 
-	p99Latency, err := getLatency(promAPI, wname, wns, duration, 0.99)
-	if err != nil {
-		me = multierror.Append(me, err)
+	groups := sets.New(maps.Keys(totalRPS)...)
+	if len(q.by) == 0 {
+		groups.Insert("")
+	}
+	out := make([]workloadMetrics, 0, len(groups))
+	for _, group := range sets.SortedList(groups) {
+		out = append(out, workloadMetrics{
+			workload:   q.name,
+			group:      group,
+			compared:   q.label,
+			totalRPS:   totalRPS[group],
+			errorRPS:   errorRPS[group],
+			p50Latency: convertLatencyToDuration(latencies[0][group]),
+			p90Latency: convertLatencyToDuration(latencies[1][group]),
+			p99Latency: convertLatencyToDuration(latencies[2][group]),
+		})
 	}
-	sm.p99Latency = p99Latency
 
 	if me.ErrorOrNil() != nil {
-		return sm, fmt.Errorf("error retrieving some metrics: %v", me.Error())
+		return out, fmt.Errorf("error retrieving some metrics: %v", me.Error())
 	}
 
-	return sm, nil
+	return out, nil
 }
 
-func getLatency(promAPI promv1.API, workloadName, workloadNamespace string, duration time.Duration, quantile float64) (time.Duration, error) {
-	latencyQuery := fmt.Sprintf(`histogram_quantile(%f, sum(rate(%s_bucket{%s=~"%s.*", %s=~"%s.*",reporter="destination"}[%s])) by (le))`,
-		quantile, reqDur, destWorkloadLabel, workloadName, destWorkloadNamespaceLabel, workloadNamespace, duration)
-
-	letency, err := vectorValue(promAPI, latencyQuery)
+// compare returns the metrics of the workload for the two compared revisions or time windows, followed by their
+// difference, for each group.
+func compare(promAPI promv1.API, q metricsQuery) ([]workloadMetrics, error) {
+	base, candidate := q, q
+	if len(metricsCompareRevisions) == 2 {
+		base.revision, candidate.revision = metricsCompareRevisions[0], metricsCompareRevisions[1]
+		base.label, candidate.label = base.revision, candidate.revision
+	} else {
+		base.offset = metricsCompareOffset
+		base.label, candidate.label = "-"+metricsCompareOffset.String(), "now"
+	}
+	baseMetrics, err := metrics(promAPI, base)
 	if err != nil {
-		return time.Duration(0), err
+		return nil, err
 	}
+	candidateMetrics, err := metrics(promAPI, candidate)
+	if err != nil {
+		return nil, err
+	}
+	return compareMetrics(q.name, baseMetrics, candidateMetrics, base.label, candidate.label), nil
+}
 
-	return convertLatencyToDuration(letency), nil
+func compareMetrics(workload string, base, candidate []workloadMetrics, baseLabel, candidateLabel string) []workloadMetrics {
+	index := func(rows []workloadMetrics) map[string]workloadMetrics {
+		out := make(map[string]workloadMetrics, len(rows))
+		for _, wm := range rows {
+			out[wm.group] = wm
+		}
+		return out
+	}
+	baseGroups, candidateGroups := index(base), index(candidate)
+	groups := sets.New(maps.Keys(baseGroups)...).InsertAll(maps.Keys(candidateGroups)...)
+
+	out := make([]workloadMetrics, 0, 3*len(groups))
+	for _, group := range sets.SortedList(groups) {
+		b, c := baseGroups[group], candidateGroups[group]
+		b.workload, b.group, b.compared = workload, group, baseLabel
+		c.workload, c.group, c.compared = workload, group, candidateLabel
+		out = append(out, b, c, workloadMetrics{
+			workload:   workload,
+			group:      group,
+			compared:   "delta",
+			totalRPS:   c.totalRPS - b.totalRPS,
+			errorRPS:   c.errorRPS - b.errorRPS,
+			p50Latency: c.p50Latency - b.p50Latency,
+			p90Latency: c.p90Latency - b.p90Latency,
+			p99Latency: c.p99Latency - b.p99Latency,
+		})
+	}
+	return out
 }
 
-func vectorValue(promAPI promv1.API, query string) (float64, error) {
+// vectorValues returns the values of the vector returned by the query, by the values of the labels joined by
+// dots. Without labels, the first value is returned for the empty group.
+func vectorValues(promAPI promv1.API, query string, labels []string) (map[string]float64, error) {
 	val, _, err := promAPI.Query(context.Background(), query, time.Now())
 	if err != nil {
-		return 0, fmt.Errorf("query() failure for '%s': %v", query, err)
+		return nil, fmt.Errorf("query() failure for '%s': %v", query, err)
 	}
 
 	log.Debugf("executing query: %s  result:%s", query, val)
 
 	switch v := val.(type) {
 	case model.Vector:
+		out := map[string]float64{}
 		if v.Len() < 1 {
 			log.Debugf("no values for query: %s", query)
-			return 0, nil
+			return out, nil
 		}
-
-		return float64(v[0].Value), nil
+		if len(labels) == 0 {
+			out[""] = float64(v[0].Value)
+			return out, nil
+		}
+		for _, sample := range v {
+			values := make([]string, 0, len(labels))
+			for _, l := range labels {
+				values = append(values, string(sample.Metric[model.LabelName(l)]))
+			}
+			out[strings.Join(values, ".")] = float64(sample.Value)
+		}
+		return out, nil
 	default:
-		return 0, errors.New("bad metric value type returned for query")
+		return nil, errors.New("bad metric value type returned for query")
 	}
 }
 
@@ -244,19 +560,61 @@ func convertLatencyToDuration(val float64) time.Duration {
 	return time.Duration(val) * time.Millisecond
 }
 
-func printHeader(writer io.Writer) {
+// metricsTable prints the metrics, with the columns enabled by the flags.
+type metricsTable struct {
+	by      string
+	compare bool
+	slo     float64
+}
+
+func (t metricsTable) printHeader(writer io.Writer) {
 	w := tabwriter.NewWriter(writer, 13, 1, 2, ' ', tabwriter.AlignRight)
-	_, _ = fmt.Fprintf(w, "%40s\tTOTAL RPS\tERROR RPS\tP50 LATENCY\tP90 LATENCY\tP99 LATENCY\t\n", "WORKLOAD")
+	columns := []string{fmt.Sprintf("%40s", "WORKLOAD")}
+	if t.by != "" {
+		columns = append(columns, strings.ToUpper(t.by))
+	}
+	if t.compare {
+		columns = append(columns, "COMPARED")
+	}
+	columns = append(columns, "TOTAL RPS", "ERROR RPS", "P50 LATENCY", "P90 LATENCY", "P99 LATENCY")
+	if t.slo > 0 {
+		columns = append(columns, "ERROR RATE", "BUDGET LEFT")
+	}
+	_, _ = fmt.Fprintf(w, "%s\t\n", strings.Join(columns, "\t"))
 	_ = w.Flush()
 }
 
-func printMetrics(writer io.Writer, wm workloadMetrics) {
+func (t metricsTable) printMetrics(writer io.Writer, wm workloadMetrics) {
 	w := tabwriter.NewWriter(writer, 13, 1, 2, ' ', tabwriter.AlignRight)
-	_, _ = fmt.Fprintf(w, "%40s\t", wm.workload)
-	_, _ = fmt.Fprintf(w, "%.3f\t", wm.totalRPS)
-	_, _ = fmt.Fprintf(w, "%.3f\t", wm.errorRPS)
-	_, _ = fmt.Fprintf(w, "%s\t", wm.p50Latency)
-	_, _ = fmt.Fprintf(w, "%s\t", wm.p90Latency)
-	_, _ = fmt.Fprintf(w, "%s\t\n", wm.p99Latency)
+	delta := wm.compared == "delta"
+	rps := func(v float64) string {
+		if delta {
+			return fmt.Sprintf("%+.3f", v)
+		}
+		return fmt.Sprintf("%.3f", v)
+	}
+	latency := func(d time.Duration) string {
+		if delta && d >= 0 {
+			return "+" + d.String()
+		}
+		return d.String()
+	}
+	columns := []string{fmt.Sprintf("%40s", wm.workload)}
+	if t.by != "" {
+		columns = append(columns, wm.group)
+	}
+	if t.compare {
+		columns = append(columns, wm.compared)
+	}
+	columns = append(columns, rps(wm.totalRPS), rps(wm.errorRPS), latency(wm.p50Latency), latency(wm.p90Latency), latency(wm.p99Latency))
+	if t.slo > 0 {
+		if delta {
+			// The ratios of the difference are meaningless.
+			columns = append(columns, "-", "-")
+		} else {
+			columns = append(columns, fmt.Sprintf("%.2f%%", 100*wm.errorRate()), fmt.Sprintf("%.1f%%", 100*wm.errorBudgetLeft(t.slo)))
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%s\t\n", strings.Join(columns, "\t"))
 	_ = w.Flush()
 }
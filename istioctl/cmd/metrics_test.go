@@ -18,6 +18,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
@@ -125,14 +129,16 @@ func TestPrintMetrics(t *testing.T) {
 	}
 	workload := "details"
 
-	sm, err := metrics(mockProm, workload, time.Minute)
+	rows, err := metrics(mockProm, newMetricsQuery(workload, time.Minute, nil))
 	if err != nil {
 		t.Fatalf("Unwanted exception %v", err)
 	}
 
 	var out bytes.Buffer
-	printHeader(&out)
-	printMetrics(&out, sm)
+	metricsTable{}.printHeader(&out)
+	for _, wm := range rows {
+		metricsTable{}.printMetrics(&out, wm)
+	}
 	output := out.String()
 
 	expectedOutput := `                                  WORKLOAD    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY
@@ -143,6 +149,110 @@ func TestPrintMetrics(t *testing.T) {
 	}
 }
 
+func TestMetricsQueries(t *testing.T) {
+	q := newMetricsQuery("reviews.default", time.Minute, metricsBreakdowns["source"])
+	q.revision = "v2"
+	q.offset = time.Hour
+	want := `sum(rate(istio_requests_total{destination_workload=~"reviews.*", destination_workload_namespace=~"default.*",reporter="destination",` +
+		`destination_canonical_revision="v2"}[1m0s] offset 1h0m0s)) by (source_workload, source_workload_namespace)`
+	if got := q.requestRate(); got != want {
+		t.Fatalf("unexpected request rate query; got:\n %s\nwant:\n %s", got, want)
+	}
+	want = `histogram_quantile(0.990000, sum(rate(istio_request_duration_milliseconds_bucket{destination_workload=~"reviews.*", ` +
+		`destination_workload_namespace=~"default.*",reporter="destination",destination_canonical_revision="v2"}[1m0s] offset 1h0m0s)) ` +
+		`by (le, source_workload, source_workload_namespace))`
+	if got := q.latency(0.99); got != want {
+		t.Fatalf("unexpected latency query; got:\n %s\nwant:\n %s", got, want)
+	}
+}
+
+func TestPrintMetricsBreakdown(t *testing.T) {
+	q := newMetricsQuery("details", time.Minute, metricsBreakdowns["code"])
+	code := func(c string, v float64) *prometheus_model.Sample {
+		return &prometheus_model.Sample{Metric: prometheus_model.Metric{"response_code": prometheus_model.LabelValue(c)}, Value: prometheus_model.SampleValue(v)}
+	}
+	mockProm := mockPromAPI{
+		cannedResponse: map[string]prometheus_model.Value{
+			q.requestRate(): prometheus_model.Vector{code("200", 9), code("503", 1)},
+			q.errorRate():   prometheus_model.Vector{code("503", 1)},
+			q.latency(0.5):  prometheus_model.Vector{code("200", 2), code("503", 10)},
+			q.latency(0.9):  prometheus_model.Vector{code("200", 4), code("503", 10)},
+			q.latency(0.99): prometheus_model.Vector{code("200", 8), code("503", 10)},
+		},
+	}
+	rows, err := metrics(mockProm, q)
+	if err != nil {
+		t.Fatalf("Unwanted exception %v", err)
+	}
+
+	table := metricsTable{by: "code", slo: 99}
+	var out bytes.Buffer
+	table.printHeader(&out)
+	for _, wm := range rows {
+		table.printMetrics(&out, wm)
+	}
+
+	expectedOutput := `                                  WORKLOAD         CODE    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY   ERROR RATE  BUDGET LEFT
+                                   details          200        9.000        0.000          2ms          4ms          8ms        0.00%       100.0%
+                                   details          503        1.000        1.000         10ms         10ms         10ms      100.00%     -9900.0%
+`
+	if out.String() != expectedOutput {
+		t.Fatalf("Unexpected output; got:\n %q\nwant:\n %q", out.String(), expectedOutput)
+	}
+}
+
+func TestPrintMetricsCompare(t *testing.T) {
+	base := []workloadMetrics{{totalRPS: 10, errorRPS: 2, p50Latency: 2 * time.Millisecond, p90Latency: 4 * time.Millisecond, p99Latency: 8 * time.Millisecond}}
+	candidate := []workloadMetrics{{totalRPS: 12, errorRPS: 0.5, p50Latency: 3 * time.Millisecond, p90Latency: 4 * time.Millisecond, p99Latency: 6 * time.Millisecond}}
+	rows := compareMetrics("reviews", base, candidate, "v1", "v2")
+
+	table := metricsTable{compare: true, slo: 90}
+	var out bytes.Buffer
+	table.printHeader(&out)
+	for _, wm := range rows {
+		table.printMetrics(&out, wm)
+	}
+
+	expectedOutput := `                                  WORKLOAD     COMPARED    TOTAL RPS    ERROR RPS  P50 LATENCY  P90 LATENCY  P99 LATENCY   ERROR RATE  BUDGET LEFT
+                                   reviews           v1       10.000        2.000          2ms          4ms          8ms       20.00%      -100.0%
+                                   reviews           v2       12.000        0.500          3ms          4ms          6ms        4.17%        58.3%
+                                   reviews        delta       +2.000       -1.500         +1ms          +0s         -2ms            -            -
+`
+	if out.String() != expectedOutput {
+		t.Fatalf("Unexpected output; got:\n %q\nwant:\n %q", out.String(), expectedOutput)
+	}
+}
+
+func TestPrometheusRoundTripper(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer server.Close()
+
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	metricsBearerTokenFile, metricsHeaders = token, []string{"X-Scope-OrgID=tenant"}
+	defer func() {
+		metricsBearerTokenFile, metricsHeaders = "", nil
+	}()
+
+	promAPI, err := prometheusAPI(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := promAPI.Query(context.Background(), "up", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got.Get("Authorization") != "Bearer secret" || got.Get("X-Scope-OrgID") != "tenant" {
+		t.Fatalf("unexpected headers %v", got)
+	}
+}
+
 func (client mockPromAPI) Alerts(ctx context.Context) (promv1.AlertsResult, error) {
 	return promv1.AlertsResult{}, fmt.Errorf("TODO mockPromAPI doesn't mock Alerts")
 }
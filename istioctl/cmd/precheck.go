@@ -57,7 +57,7 @@ import (
 
 func preCheck() *cobra.Command {
 	var opts clioptions.ControlPlaneOptions
-	var skipControlPlane bool
+	var skipControlPlane, ambient bool
 	// cmd represents the upgradeCheck command
 	cmd := &cobra.Command{
 		Use:   "precheck",
@@ -67,13 +67,19 @@ func preCheck() *cobra.Command {
   istioctl x precheck
 
   # Check only a single namespace
-  istioctl x precheck --namespace default`,
+  istioctl x precheck --namespace default
+
+  # Report the blockers for the migration of the mesh to ambient mode, as JSON
+  istioctl x precheck --ambient -o json`,
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			cli, err := kube.NewCLIClient(kube.BuildClientCmd(kubeconfig, configContext), revision)
 			if err != nil {
 				return err
 			}
 
+			if ambient {
+				return runAmbientPrecheck(cmd, cli, namespace)
+			}
 			msgs := diag.Messages{}
 			if !skipControlPlane {
 				msgs, err = checkControlPlane(cli)
@@ -109,10 +115,36 @@ See %s for more information about causes and resolutions.`, url.ConfigAnalysis)
 		},
 	}
 	cmd.PersistentFlags().BoolVar(&skipControlPlane, "skip-controlplane", false, "skip checking the control plane")
+	cmd.PersistentFlags().BoolVar(&ambient, "ambient", false,
+		"check the mesh for the blockers of its migration to ambient mode, instead of the install requirements")
+	cmd.PersistentFlags().StringVarP(&msgOutputFormat, "output", "o", formatting.LogFormat,
+		fmt.Sprintf("Output format: one of %v", formatting.MsgOutputFormatKeys))
 	opts.AttachControlPlaneFlags(cmd)
 	return cmd
 }
 
+// runAmbientPrecheck prints the blockers for the migration of the mesh to ambient mode.
+func runAmbientPrecheck(cmd *cobra.Command, cli kube.CLIClient, namespace string) error {
+	msgs, err := checkAmbient(cli, namespace)
+	if err != nil {
+		return err
+	}
+	msgs = msgs.SortedDedupedCopy()
+	output, err := formatting.Print(msgs, msgOutputFormat, colorize)
+	if err != nil {
+		return err
+	}
+	if len(msgs) == 0 && msgOutputFormat == formatting.LogFormat {
+		fmt.Fprintln(cmd.ErrOrStderr(), color.New(color.FgGreen).Sprint("✔")+" No blockers found for the migration to ambient mode.")
+		return nil
+	}
+	fmt.Fprintln(cmd.OutOrStdout(), output)
+	if len(msgs) > 0 {
+		return fmt.Errorf("blockers found for the migration to ambient mode. See %s for more information", url.ConfigAnalysis)
+	}
+	return nil
+}
+
 func checkControlPlane(cli kube.CLIClient) (diag.Messages, error) {
 	msgs := diag.Messages{}
 
@@ -0,0 +1,197 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+	"istio.io/istio/pkg/config/analysis/diag"
+	"istio.io/istio/pkg/config/analysis/msg"
+	kubeconfig "istio.io/istio/pkg/config/kube"
+	kube3 "istio.io/istio/pkg/config/legacy/source/kube"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/inject"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// crossNetworkPort is the port of the east-west gateways connecting the networks of a multi-network mesh.
+const crossNetworkPort = 15443
+
+// checkAmbient reports the blockers for the migration of a sidecar mode mesh to ambient mode: the features of the
+// sidecars ztunnel and the waypoint proxies do not provide.
+func checkAmbient(cli kube.CLIClient, namespace string) (diag.Messages, error) {
+	msgs := diag.Messages{}
+	for _, check := range []func(kube.CLIClient, string) (diag.Messages, error){
+		checkAmbientEnvoyFilters,
+		checkAmbientSidecarOnlyFields,
+		checkAmbientNetworks,
+		checkAmbientProtocols,
+	} {
+		m, err := check(cli, namespace)
+		if err != nil {
+			return nil, err
+		}
+		msgs.Add(m...)
+	}
+	return msgs, nil
+}
+
+func ambientResource(c collection.Schema, meta metav1.ObjectMeta) *resource.Instance {
+	return &resource.Instance{Origin: &kube3.Origin{
+		Collection: c.Name(),
+		Kind:       c.Resource().Kind(),
+		FullName: resource.FullName{
+			Namespace: resource.Namespace(meta.Namespace),
+			Name:      resource.LocalName(meta.Name),
+		},
+		Version: resource.Version(meta.ResourceVersion),
+	}}
+}
+
+func checkAmbientEnvoyFilters(cli kube.CLIClient, namespace string) (diag.Messages, error) {
+	efs, err := cli.Istio().NetworkingV1alpha3().EnvoyFilters(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	msgs := diag.Messages{}
+	for i := range efs.Items {
+		ef := &efs.Items[i]
+		msgs.Add(msg.NewAmbientEnvoyFilter(ambientResource(collections.IstioNetworkingV1Alpha3Envoyfilters, ef.ObjectMeta)))
+	}
+	return msgs, nil
+}
+
+// checkAmbientSidecarOnlyFields reports the configuration only applied by the sidecars of the clients or servers.
+func checkAmbientSidecarOnlyFields(cli kube.CLIClient, namespace string) (diag.Messages, error) {
+	ctx := context.Background()
+	msgs := diag.Messages{}
+	sidecars, err := cli.Istio().NetworkingV1alpha3().Sidecars(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range sidecars.Items {
+		sc := &sidecars.Items[i]
+		msgs.Add(msg.NewAmbientSidecarOnlyField(ambientResource(collections.IstioNetworkingV1Alpha3Sidecars, sc.ObjectMeta), "The Sidecar resource"))
+	}
+
+	vses, err := cli.Istio().NetworkingV1alpha3().VirtualServices(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for n := range vses.Items {
+		vs := &vses.Items[n]
+		r := ambientResource(collections.IstioNetworkingV1Alpha3Virtualservices, vs.ObjectMeta)
+		for i, h := range vs.Spec.Http {
+			for j, m := range h.Match {
+				if len(m.SourceLabels) > 0 {
+					msgs.Add(msg.NewAmbientSidecarOnlyField(r, fmt.Sprintf("http[%d].match[%d].sourceLabels", i, j)))
+				}
+			}
+		}
+		for i, t := range vs.Spec.Tcp {
+			for j, m := range t.Match {
+				if len(m.SourceLabels) > 0 {
+					msgs.Add(msg.NewAmbientSidecarOnlyField(r, fmt.Sprintf("tcp[%d].match[%d].sourceLabels", i, j)))
+				}
+			}
+		}
+		for i, t := range vs.Spec.Tls {
+			for j, m := range t.Match {
+				if len(m.SourceLabels) > 0 {
+					msgs.Add(msg.NewAmbientSidecarOnlyField(r, fmt.Sprintf("tls[%d].match[%d].sourceLabels", i, j)))
+				}
+			}
+		}
+	}
+
+	pas, err := cli.Istio().SecurityV1beta1().PeerAuthentications(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pas.Items {
+		pa := &pas.Items[i]
+		if len(pa.Spec.PortLevelMtls) > 0 {
+			msgs.Add(msg.NewAmbientSidecarOnlyField(ambientResource(collections.IstioSecurityV1Beta1Peerauthentications, pa.ObjectMeta), "portLevelMtls"))
+		}
+	}
+	return msgs, nil
+}
+
+// checkAmbientNetworks reports multi-network meshes: the namespaces of the cluster are labeled with different
+// networks, or an east-west gateway exposes the workloads to other networks.
+func checkAmbientNetworks(cli kube.CLIClient, _ string) (diag.Messages, error) {
+	ctx := context.Background()
+	nss, err := cli.Kube().CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: label.TopologyNetwork.Name})
+	if err != nil {
+		return nil, err
+	}
+	networks := sets.New[string]()
+	for _, ns := range nss.Items {
+		networks.Insert(ns.Labels[label.TopologyNetwork.Name])
+	}
+	msgs := diag.Messages{}
+	if networks.Len() > 1 {
+		msgs.Add(msg.NewAmbientMultiNetwork(nil, strings.Join(sets.SortedList(networks), ", ")))
+	}
+	// Gateways labeled with their network, exposing the cross-network port.
+	svcs, err := cli.Kube().CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: label.TopologyNetwork.Name})
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range svcs.Items {
+		for _, p := range svc.Spec.Ports {
+			if p.Port == crossNetworkPort {
+				msgs.Add(msg.NewAmbientMultiNetwork(ambientResource(collections.K8SCoreV1Services, svc.ObjectMeta),
+					svc.Labels[label.TopologyNetwork.Name]))
+				break
+			}
+		}
+	}
+	return msgs, nil
+}
+
+// checkAmbientProtocols reports the ports of the Services of the mesh that ztunnel cannot capture.
+func checkAmbientProtocols(cli kube.CLIClient, namespace string) (diag.Messages, error) {
+	svcs, err := cli.Kube().CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	msgs := diag.Messages{}
+	for _, svc := range svcs.Items {
+		if inject.IgnoredNamespaces.Contains(svc.Namespace) || svc.Namespace == istioNamespace {
+			continue
+		}
+		for _, p := range svc.Spec.Ports {
+			proto := string(kubeconfig.ConvertProtocol(p.Port, p.Name, p.Protocol, p.AppProtocol))
+			if p.Protocol == corev1.ProtocolSCTP {
+				proto = string(corev1.ProtocolSCTP)
+			} else if proto != string(protocol.UDP) {
+				continue
+			}
+			msgs.Add(msg.NewAmbientUnsupportedProtocol(ambientResource(collections.K8SCoreV1Services, svc.ObjectMeta), int(p.Port), proto))
+		}
+	}
+	return msgs, nil
+}
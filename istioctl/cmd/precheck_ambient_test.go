@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	security "istio.io/api/security/v1beta1"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	clientsecurity "istio.io/client-go/pkg/apis/security/v1beta1"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/kube"
+)
+
+func TestCheckAmbient(t *testing.T) {
+	cli := kube.NewFakeClient(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "istio-system", Labels: map[string]string{"topology.istio.io/network": "network1"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "dns", Namespace: "default"},
+			Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{
+				{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+				{Name: "dns-tcp", Port: 53, Protocol: corev1.ProtocolTCP},
+			}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-dns", Namespace: "kube-system"},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP}}},
+		},
+		&corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{Name: "istio-eastwestgateway", Namespace: "istio-system", Labels: map[string]string{"topology.istio.io/network": "network1"}},
+			Spec:       corev1.ServiceSpec{Ports: []corev1.ServicePort{{Name: "tls", Port: 15443}}},
+		},
+	)
+	ctx := context.Background()
+	istio := cli.Istio()
+	if _, err := istio.NetworkingV1alpha3().EnvoyFilters("default").Create(ctx, &clientnetworking.EnvoyFilter{
+		ObjectMeta: metav1.ObjectMeta{Name: "lua", Namespace: "default"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := istio.NetworkingV1alpha3().Sidecars("default").Create(ctx, &clientnetworking.Sidecar{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := istio.NetworkingV1alpha3().VirtualServices("default").Create(ctx, &clientnetworking.VirtualService{
+		ObjectMeta: metav1.ObjectMeta{Name: "reviews", Namespace: "default"},
+		Spec: networking.VirtualService{Http: []*networking.HTTPRoute{
+			{Match: []*networking.HTTPMatchRequest{{Uri: &networking.StringMatch{MatchType: &networking.StringMatch_Prefix{Prefix: "/"}}}}},
+			{Match: []*networking.HTTPMatchRequest{{SourceLabels: map[string]string{"app": "productpage"}}}},
+		}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := istio.SecurityV1beta1().PeerAuthentications("default").Create(ctx, &clientsecurity.PeerAuthentication{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"},
+		Spec: security.PeerAuthentication{PortLevelMtls: map[uint32]*security.PeerAuthentication_MutualTLS{
+			8080: {Mode: security.PeerAuthentication_MutualTLS_DISABLE},
+		}},
+	}, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	msgs, err := checkAmbient(cli, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, m := range msgs {
+		got[m.Type.Code()]++
+	}
+	want := map[string]int{
+		msg.AmbientEnvoyFilter.Code():         1,
+		msg.AmbientSidecarOnlyField.Code():    3,
+		msg.AmbientMultiNetwork.Code():        1,
+		msg.AmbientUnsupportedProtocol.Code(): 1,
+	}
+	for code, n := range want {
+		if got[code] != n {
+			t.Errorf("got %d %s messages, want %d: %v", got[code], code, n, msgs)
+		}
+	}
+	if len(msgs) != 6 {
+		t.Errorf("got %d messages, want 6: %v", len(msgs), msgs)
+	}
+}
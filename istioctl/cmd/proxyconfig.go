@@ -16,12 +16,16 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
@@ -29,8 +33,11 @@ import (
 	"istio.io/istio/istioctl/pkg/util/handlers"
 	"istio.io/istio/istioctl/pkg/writer/envoy/clusters"
 	"istio.io/istio/istioctl/pkg/writer/envoy/configdump"
+	istioStatus "istio.io/istio/pilot/cmd/pilot-agent/status"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/filterchainmatch"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/wasm"
 	"istio.io/pkg/log"
 )
 
@@ -150,6 +157,8 @@ var stringToLevel = map[string]Level{
 var (
 	loggerLevelString = ""
 	reset             = false
+	agentLevelString  = ""
+	logLevelDuration  time.Duration
 )
 
 func extractConfigDump(podName, podNamespace string, eds bool) ([]byte, error) {
@@ -591,6 +600,83 @@ func listenerConfigCmd() *cobra.Command {
 	return listenerConfigCmd
 }
 
+func filterChainMatchConfigCmd() *cobra.Command {
+	var podName, podNamespace string
+	var conn filterchainmatch.Connection
+	var inbound bool
+
+	filterChainMatchCmd := &cobra.Command{
+		Use:   "filter-chain-match [<type>/]<name>[.<namespace>]",
+		Short: "Retrieves the filter chain matched by a connection in the Envoy in the specified pod",
+		Long: `Retrieve the listener and the filter chain that a connection would match in the Envoy instance in the specified pod,
+and why the other filter chains of the listener are skipped. The connection is described as seen after the listener filters:
+the server name and the application protocols are the ones detected by the TLS and HTTP inspectors.
+
+Outbound connections are accepted by the listener of their destination address and port, or the virtual outbound
+listener. Inbound connections of sidecars are accepted by the virtual inbound listener.`,
+		Example: `  # Retrieve the filter chain matched by a plaintext HTTP connection to the port 9080 of a pod.
+  istioctl proxy-config filter-chain-match <pod-name[.namespace]> --inbound --destination-ip 10.244.0.12 --destination-port 9080 --alpn http/1.1
+
+  # Retrieve the filter chain matched by a TLS connection to a service, as JSON.
+  istioctl proxy-config filter-chain-match <pod-name[.namespace]> --destination-ip 10.96.12.4 --destination-port 443 --sni api.example.com -o json
+
+  # Retrieve the filter chain matched by a connection without using Kubernetes API
+  ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
+  istioctl proxy-config filter-chain-match --file envoy-config.json --destination-ip 10.96.12.4 --destination-port 80
+`,
+		Aliases: []string{"fcm"},
+		Args: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) != (configDumpFile == "") {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("filter-chain-match requires pod name or --file parameter")
+			}
+			if conn.DestinationIP == "" || conn.DestinationPort == 0 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("filter-chain-match requires --destination-ip and --destination-port")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var configWriter *configdump.ConfigWriter
+			var err error
+			if len(args) == 1 {
+				if podName, podNamespace, err = getPodName(args[0]); err != nil {
+					return err
+				}
+				configWriter, err = setupPodConfigdumpWriter(podName, podNamespace, false, c.OutOrStdout())
+			} else {
+				configWriter, err = setupFileConfigdumpWriter(configDumpFile, c.OutOrStdout())
+			}
+			if err != nil {
+				return err
+			}
+			switch outputFormat {
+			case summaryOutput, jsonOutput, yamlOutput:
+				return configWriter.PrintFilterChainMatch(conn, inbound, outputFormat)
+			default:
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+
+	filterChainMatchCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	filterChainMatchCmd.PersistentFlags().StringVar(&conn.DestinationIP, "destination-ip", "", "Destination IP of the connection")
+	filterChainMatchCmd.PersistentFlags().Uint32Var(&conn.DestinationPort, "destination-port", 0, "Destination port of the connection")
+	filterChainMatchCmd.PersistentFlags().StringVar(&conn.SourceIP, "source-ip", "", "Source IP of the connection")
+	filterChainMatchCmd.PersistentFlags().Uint32Var(&conn.SourcePort, "source-port", 0, "Source port of the connection")
+	filterChainMatchCmd.PersistentFlags().StringVar(&conn.ServerName, "sni", "", "Server name of the TLS connection")
+	filterChainMatchCmd.PersistentFlags().StringSliceVar(&conn.ApplicationProtocols, "alpn", nil,
+		"Application protocols of the connection, such as h2 or http/1.1 for plaintext HTTP and istio-peer-exchange for mTLS")
+	filterChainMatchCmd.PersistentFlags().StringVar(&conn.TransportProtocol, "transport-protocol", "",
+		"Transport protocol of the connection, tls or raw_buffer. Defaults to tls if --sni is set, raw_buffer otherwise")
+	filterChainMatchCmd.PersistentFlags().BoolVar(&inbound, "inbound", false, "Match the connection on the virtual inbound listener of the sidecar")
+	filterChainMatchCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
+		"Envoy config dump JSON file")
+
+	return filterChainMatchCmd
+}
+
 func statsConfigCmd() *cobra.Command {
 	var podName, podNamespace string
 
@@ -680,6 +766,13 @@ func logCmd() *cobra.Command {
 
   # Reset levels of all the loggers to default value (warning).
   istioctl proxy-config log <pod-name[.namespace]> -r
+
+  # Have istiod change the Envoy and agent levels of the selected pods for 10 minutes, without port-forwarding.
+  # The levels are reverted by the proxies once the duration elapsed, including for pods started in the meantime.
+  istioctl proxy-config log --selector app=productpage --level http:debug --agent-level xdsproxy:debug --duration 10m
+
+  # Revert the levels changed through istiod immediately.
+  istioctl proxy-config log --selector app=productpage --reset --duration 0
 `,
 		Aliases: []string{"o"},
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -687,13 +780,20 @@ func logCmd() *cobra.Command {
 				cmd.Println(cmd.UsageString())
 				return fmt.Errorf("log requires pod name or --selector")
 			}
-			if reset && loggerLevelString != "" {
+			if reset && (loggerLevelString != "" || agentLevelString != "") {
 				cmd.Println(cmd.UsageString())
-				return fmt.Errorf("--level cannot be combined with --reset")
+				return fmt.Errorf("--level and --agent-level cannot be combined with --reset")
+			}
+			if !cmd.Flags().Changed("duration") && agentLevelString != "" {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("--agent-level requires --duration")
 			}
 			return nil
 		},
 		RunE: func(c *cobra.Command, args []string) error {
+			if c.Flags().Changed("duration") {
+				return setProxyLogLevelThroughIstiod(c.OutOrStdout(), args)
+			}
 			var err error
 			var loggerNames []string
 			if labelSelector != "" {
@@ -799,10 +899,102 @@ func logCmd() *cobra.Command {
 		fmt.Sprintf("Comma-separated minimum per-logger level of messages to output, in the form of"+
 			" [<logger>:]<level>,[<logger>:]<level>,... where logger can be one of %s and level can be one of %s",
 			s, levelListString))
+	logCmd.PersistentFlags().StringVar(&agentLevelString, "agent-level", agentLevelString,
+		"Comma-separated minimum per-scope level of messages to output by the Istio agent, in the form of"+
+			" <scope>:<level>,<scope>:<level>,... where level can be one of [debug, info, warn, error, none]. Requires --duration")
+	logCmd.PersistentFlags().DurationVar(&logLevelDuration, "duration", logLevelDuration,
+		"Have istiod push the levels to the proxies for the given duration, after which the proxies revert them,"+
+			" instead of port-forwarding to each pod")
 
 	return logCmd
 }
 
+// setProxyLogLevelThroughIstiod has istiod push temporary log levels to the selected proxies.
+func setProxyLogLevelThroughIstiod(w io.Writer, args []string) error {
+	var podName, podNamespace string
+	if labelSelector != "" {
+		podNamespace = handlers.HandleNamespace(namespace, defaultNamespace)
+	} else {
+		var err error
+		if podName, podNamespace, err = getPodName(args[0]); err != nil {
+			return err
+		}
+	}
+	query, err := proxyLogLevelQuery(podName, podNamespace, labelSelector, loggerLevelString, agentLevelString, logLevelDuration, reset)
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubeClient(kubeconfig, configContext)
+	if err != nil {
+		return fmt.Errorf("failed to create k8s client: %v", err)
+	}
+	res, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace, "/debug/proxy_log_level?"+query.Encode())
+	if err != nil {
+		return err
+	}
+	var proxies []string
+	for istiod, body := range res {
+		var ids []string
+		if err := json.Unmarshal(body, &ids); err != nil {
+			return fmt.Errorf("failed to change log levels through %s: %s", istiod, string(body))
+		}
+		proxies = append(proxies, ids...)
+	}
+	sort.Strings(proxies)
+	if len(proxies) == 0 {
+		_, _ = fmt.Fprintln(w, "No connected proxy matched; the levels apply to matching proxies connecting before the duration elapses.")
+		return nil
+	}
+	for _, p := range proxies {
+		_, _ = fmt.Fprintln(w, p)
+	}
+	return nil
+}
+
+// proxyLogLevelQuery builds the query of the istiod proxy log level debug endpoint, validating the levels.
+func proxyLogLevelQuery(podName, podNamespace, selector, envoyLevel, agentLevel string, duration time.Duration,
+	reset bool,
+) (url.Values, error) {
+	query := url.Values{}
+	query.Set("namespace", podNamespace)
+	if podName != "" {
+		query.Set("pod", podName)
+	}
+	if selector != "" {
+		query.Set("selector", selector)
+	}
+	if reset {
+		query.Set("reset", "true")
+		return query, nil
+	}
+	if envoyLevel == "" && agentLevel == "" {
+		return nil, fmt.Errorf("--duration requires --level, --agent-level or --reset")
+	}
+	if duration <= 0 {
+		return nil, fmt.Errorf("--duration must be positive")
+	}
+	var levels []string
+	for _, ol := range strings.Split(envoyLevel, ",") {
+		if ol == "" {
+			continue
+		}
+		loggerLevel := regexp.MustCompile(`[:=]`).Split(ol, 2)
+		level := loggerLevel[len(loggerLevel)-1]
+		if _, ok := stringToLevel[level]; !ok {
+			return nil, fmt.Errorf("unrecognized logging level: %v", level)
+		}
+		levels = append(levels, strings.Join(loggerLevel, ":"))
+	}
+	if len(levels) > 0 {
+		query.Set("level", strings.Join(levels, ","))
+	}
+	if agentLevel != "" {
+		query.Set("agent", agentLevel)
+	}
+	query.Set("duration", duration.String())
+	return query, nil
+}
+
 func routeConfigCmd() *cobra.Command {
 	var podName, podNamespace string
 
@@ -1232,6 +1424,7 @@ func proxyConfig() *cobra.Command {
 	configCmd.AddCommand(clusterConfigCmd())
 	configCmd.AddCommand(allConfigCmd())
 	configCmd.AddCommand(listenerConfigCmd())
+	configCmd.AddCommand(filterChainMatchConfigCmd())
 	configCmd.AddCommand(logCmd())
 	configCmd.AddCommand(routeConfigCmd())
 	configCmd.AddCommand(bootstrapConfigCmd())
@@ -1240,6 +1433,7 @@ func proxyConfig() *cobra.Command {
 	configCmd.AddCommand(secretConfigCmd())
 	configCmd.AddCommand(rootCACompareConfigCmd())
 	configCmd.AddCommand(ecdsConfigCmd())
+	configCmd.AddCommand(wasmConfigCmd())
 
 	return configCmd
 }
@@ -1336,3 +1530,84 @@ func ecdsConfigCmd() *cobra.Command {
 
 	return ecdsConfigCmd
 }
+
+// getWasmModuleStatuses returns the load state of the Wasm modules reported by the agent of the pod.
+func getWasmModuleStatuses(podName, podNamespace string) ([]wasm.ModuleStatus, error) {
+	kubeClient, err := kubeClient(kubeconfig, configContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	result, err := kubeClient.EnvoyDoWithPort(context.TODO(), podName, podNamespace, "GET",
+		strings.TrimPrefix(istioStatus.WasmDebugPath, "/"), 15020)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute command on the agent: %v", err)
+	}
+	var statuses []wasm.ModuleStatus
+	if err := json.Unmarshal(result, &statuses); err != nil {
+		return nil, fmt.Errorf("failed to parse Wasm module statuses: %v", err)
+	}
+	return statuses, nil
+}
+
+func wasmConfigCmd() *cobra.Command {
+	var podName, podNamespace string
+
+	wasmConfigCmd := &cobra.Command{
+		Use:   "wasm [<type>/]<name>[.<namespace>]",
+		Short: "Retrieves the state of the Wasm modules for the Envoy in the specified pod",
+		Long: `Retrieve the Wasm modules of the typed extension configurations for the Envoy instance in the specified pod,
+with their source, checksum and load state. The sources and fetch errors of the modules are reported by
+the Istio agent of the pod, and are not available from a config dump file.`,
+		Example: `  # Retrieve the state of the Wasm modules for a given pod.
+  istioctl proxy-config wasm <pod-name[.namespace]>
+
+  # Retrieve the Wasm modules without using Kubernetes API
+  ssh <user@hostname> 'curl localhost:15000/config_dump' > envoy-config.json
+  istioctl proxy-config wasm --file envoy-config.json
+`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if (len(args) == 1) != (configDumpFile == "") {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("wasm requires pod name or --file parameter")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var configWriter *configdump.ConfigWriter
+			var statuses []wasm.ModuleStatus
+			var err error
+			if len(args) == 1 {
+				if podName, podNamespace, err = getPodName(args[0]); err != nil {
+					return err
+				}
+				configWriter, err = setupPodConfigdumpWriter(podName, podNamespace, true, c.OutOrStdout())
+				if err != nil {
+					return err
+				}
+				// Agents which do not report the state of the modules still show the modules of the config dump.
+				if statuses, err = getWasmModuleStatuses(podName, podNamespace); err != nil {
+					log.Warnf("failed to retrieve the Wasm module statuses from the agent: %v", err)
+				}
+			} else {
+				if configWriter, err = setupFileConfigdumpWriter(configDumpFile, c.OutOrStdout()); err != nil {
+					return err
+				}
+			}
+
+			switch outputFormat {
+			case summaryOutput:
+				return configWriter.PrintWasmSummary(statuses)
+			case jsonOutput, yamlOutput:
+				return configWriter.PrintWasm(statuses, outputFormat)
+			default:
+				return fmt.Errorf("output format %q not supported", outputFormat)
+			}
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+
+	wasmConfigCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", summaryOutput, "Output format: one of json|yaml|short")
+	wasmConfigCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "", "Envoy config dump JSON file")
+
+	return wasmConfigCmd
+}
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	"istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/kube"
@@ -229,3 +230,26 @@ func mockEnvoyClientFactoryGenerator(testResults map[string][]byte) func(kubecon
 
 	return outFactory
 }
+
+func TestProxyLogLevelQuery(t *testing.T) {
+	q, err := proxyLogLevelQuery("", "default", "app=productpage", "debug,http=trace", "xdsproxy:debug", 10*time.Minute, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Encode(), "agent=xdsproxy%3Adebug&duration=10m0s&level=debug%2Chttp%3Atrace&namespace=default&selector=app%3Dproductpage"; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	q, err = proxyLogLevelQuery("productpage-v1-abc", "default", "", "", "", 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := q.Encode(), "namespace=default&pod=productpage-v1-abc&reset=true"; got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, err := proxyLogLevelQuery("", "default", "app=productpage", "verbose", "", time.Minute, false); err == nil {
+		t.Fatalf("expected invalid level to be rejected")
+	}
+	if _, err := proxyLogLevelQuery("", "default", "app=productpage", "", "", time.Minute, false); err == nil {
+		t.Fatalf("expected missing level to be rejected")
+	}
+}
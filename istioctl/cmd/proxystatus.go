@@ -36,6 +36,7 @@ import (
 
 func statusCommand() *cobra.Command {
 	var opts clioptions.ControlPlaneOptions
+	var wide bool
 
 	statusCmd := &cobra.Command{
 		Use:   "proxy-status [<type>/]<name>[.<namespace>]",
@@ -47,6 +48,10 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 		Example: `  # Retrieve sync status for all Envoys in a mesh
   istioctl proxy-status
 
+  # Retrieve sync status for all Envoys in a mesh, along with the size of their config,
+  # the duration of their last push and their last rejected config
+  istioctl proxy-status --wide
+
   # Retrieve sync diff for a single Envoy and Istiod
   istioctl proxy-status istio-egressgateway-59585c5b9c-ndc59.istio-system
 
@@ -104,7 +109,7 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 			if err != nil {
 				return err
 			}
-			sw := pilot.StatusWriter{Writer: c.OutOrStdout()}
+			sw := pilot.StatusWriter{Writer: c.OutOrStdout(), Wide: wide}
 			return sw.PrintAll(statuses)
 		},
 	}
@@ -112,6 +117,8 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 	opts.AttachControlPlaneFlags(statusCmd)
 	statusCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
+	statusCmd.PersistentFlags().BoolVar(&wide, "wide", false,
+		"Print the size of the config of each type, the duration of the last push and the last rejected config of each Envoy")
 
 	return statusCmd
 }
@@ -156,6 +163,7 @@ func newKubeClient(kubeconfig, configContext string) (kube.CLIClient, error) {
 
 func xdsStatusCommand() *cobra.Command {
 	var opts clioptions.ControlPlaneOptions
+	var wide bool
 	var centralOpts clioptions.CentralControlPlaneOptions
 	var multiXdsOpts multixds.Options
 
@@ -236,7 +244,7 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 			if err != nil {
 				return err
 			}
-			sw := pilot.XdsStatusWriter{Writer: c.OutOrStdout()}
+			sw := pilot.XdsStatusWriter{Writer: c.OutOrStdout(), Wide: wide}
 			return sw.PrintAll(xdsResponses)
 		},
 	}
@@ -245,6 +253,8 @@ Retrieves last sent and last acknowledged xDS sync from Istiod to each Envoy in
 	centralOpts.AttachControlPlaneFlags(statusCmd)
 	statusCmd.PersistentFlags().StringVarP(&configDumpFile, "file", "f", "",
 		"Envoy config dump JSON file")
+	statusCmd.PersistentFlags().BoolVar(&wide, "wide", false,
+		"Print the size of the config of each type, the duration of the last push and the last rejected config of each Envoy")
 	statusCmd.PersistentFlags().BoolVar(&multiXdsOpts.XdsViaAgents, "xds-via-agents", false,
 		"Access Istiod via the tap service of each agent")
 	statusCmd.PersistentFlags().IntVar(&multiXdsOpts.XdsViaAgentsLimit, "xds-via-agents-limit", 100,
@@ -0,0 +1,131 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"istio.io/istio/istioctl/pkg/util/handlers"
+)
+
+func pushCmd() *cobra.Command {
+	var (
+		selector   string
+		clearCache bool
+	)
+	cmd := &cobra.Command{
+		Use:   "push [<pod-name>[.<namespace>]]",
+		Short: "Have istiod recompute and push the configuration of selected proxies",
+		Long: `Has istiod recompute the state of the selected proxies, such as their service instances, labels and Sidecar scope,
+and push them their full configuration, without pushing the other proxies of the mesh.
+
+This is meant to recover proxies whose configuration is out of sync with istiod, for example after an incident, without
+restarting istiod or pushing all proxies. The proxies are selected by pod name, or by namespace and label selector.
+
+Only the service account of istiod, the identities of PILOT_DEBUG_ADMIN_IDENTITIES, the identities allowed the proxy.push
+operation on the namespace by the write policy of istiod, or callers connecting through a port-forward as this command
+does, are allowed.`,
+		Example: `  # Push the proxy of a pod
+  istioctl experimental push productpage-v1-7d79b4c9f-4zr2k.default
+
+  # Push the proxies of the reviews workloads of the default namespace
+  istioctl experimental push -n default -l app=reviews
+
+  # Push all the proxies of the default namespace, regenerating cached configuration
+  istioctl experimental push -n default --clear-cache`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("push accepts at most one pod name")
+			}
+			if len(args) == 1 && selector != "" {
+				cmd.Println(cmd.UsageString())
+				return fmt.Errorf("a pod name and a selector cannot be combined")
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, args []string) error {
+			var podName, podNamespace string
+			if len(args) == 1 {
+				var err error
+				if podName, podNamespace, err = getPodName(args[0]); err != nil {
+					return err
+				}
+			} else {
+				podNamespace = handlers.HandleNamespace(namespace, defaultNamespace)
+			}
+			kubeClient, err := kubeClient(kubeconfig, configContext)
+			if err != nil {
+				return fmt.Errorf("failed to create k8s client: %v", err)
+			}
+			res, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace,
+				"/debug/push?"+scopedPushQuery(podName, podNamespace, selector, clearCache).Encode())
+			if err != nil {
+				return err
+			}
+			return printPushedProxies(c.OutOrStdout(), res)
+		},
+		ValidArgsFunction: validPodsNameArgs,
+	}
+	cmd.PersistentFlags().StringVarP(&selector, "selector", "l", "", "Label selector of the proxies of the namespace")
+	cmd.PersistentFlags().BoolVar(&clearCache, "clear-cache", false,
+		"Clear the xDS cache of istiod before the push. The cache is shared by all proxies, and is regenerated as they are pushed")
+	return cmd
+}
+
+// scopedPushQuery builds the query of the istiod scoped push debug endpoint.
+func scopedPushQuery(podName, podNamespace, selector string, clearCache bool) url.Values {
+	query := url.Values{}
+	if podName != "" {
+		query.Set("proxyID", podName+"."+podNamespace)
+	} else {
+		query.Set("namespace", podNamespace)
+		if selector != "" {
+			query.Set("selector", selector)
+		}
+	}
+	if clearCache {
+		query.Set("clearCache", "true")
+	}
+	return query
+}
+
+// printPushedProxies prints the proxies pushed by each istiod instance.
+func printPushedProxies(w io.Writer, res map[string][]byte) error {
+	var proxies []string
+	for istiod, body := range res {
+		var ids []string
+		if err := json.Unmarshal(body, &ids); err != nil {
+			return fmt.Errorf("failed to push through %s: %s", istiod, string(body))
+		}
+		proxies = append(proxies, ids...)
+	}
+	sort.Strings(proxies)
+	if len(proxies) == 0 {
+		_, _ = fmt.Fprintln(w, "No connected proxy matched.")
+		return nil
+	}
+	for _, p := range proxies {
+		_, _ = fmt.Fprintln(w, p)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestScopedPushQuery(t *testing.T) {
+	cases := []struct {
+		podName, podNamespace, selector string
+		clearCache                      bool
+		want                            string
+	}{
+		{podName: "productpage-v1-abc", podNamespace: "default", want: "proxyID=productpage-v1-abc.default"},
+		{podNamespace: "default", selector: "app=reviews", want: "namespace=default&selector=app%3Dreviews"},
+		{podNamespace: "default", clearCache: true, want: "clearCache=true&namespace=default"},
+	}
+	for _, c := range cases {
+		if got := scopedPushQuery(c.podName, c.podNamespace, c.selector, c.clearCache).Encode(); got != c.want {
+			t.Errorf("got %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestPrintPushedProxies(t *testing.T) {
+	var out bytes.Buffer
+	err := printPushedProxies(&out, map[string][]byte{
+		"istiod-1": []byte(`["reviews-v2-abc.default"]`),
+		"istiod-2": []byte(`["reviews-v1-abc.default"]`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "reviews-v1-abc.default\nreviews-v2-abc.default\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := printPushedProxies(&out, map[string][]byte{"istiod-1": []byte(`[]`)}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "No connected proxy matched.\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+
+	if err := printPushedProxies(&out, map[string][]byte{"istiod-1": []byte("namespace or proxyID must be set")}); err == nil {
+		t.Fatal("expected error for a rejected request")
+	}
+}
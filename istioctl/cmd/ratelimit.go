@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pkg/config/ratelimit"
+)
+
+func rateLimitCmd() *cobra.Command {
+	var filename, name string
+	cmd := &cobra.Command{
+		Use:   "rate-limit",
+		Short: "Generate a validated EnvoyFilter from a typed rate limit",
+		Long: `Generates an EnvoyFilter setting a typed rate limit in its networking.istio.io/rate-limit annotation. istiod
+compiles the rate limit to the patches configuring the Envoy local and global rate limit filters.
+
+The limits are enforced by the inbound listeners of the sidecars selected by workloadSelector, or by the
+listeners of the Gateway API Gateway referenced by targetRef. A local limit is a token bucket shared by the
+connections of each proxy. A global limit sends the configured descriptors of each request to a rate limit
+service implementing the Envoy RLS API, reached over gRPC through its outbound cluster.`,
+		Example: `  # Limit the reviews workloads to 100 requests per second each
+  cat <<EOF | istioctl experimental rate-limit --name reviews -f -
+  workloadSelector:
+    app: reviews
+  local:
+    maxTokens: 100
+    fillInterval: 1s
+  EOF
+
+  # Limit the requests of each user and path through the gateway with a rate limit service
+  cat <<EOF | istioctl experimental rate-limit --name gateway -f -
+  targetRef:
+    group: gateway.networking.k8s.io
+    kind: Gateway
+    name: gateway
+  global:
+    domain: gateway
+    service: ratelimit.ratelimit.svc.cluster.local
+    servicePort: 8081
+    descriptors:
+    - entries:
+      - requestHeader: {header: x-user, key: user}
+      - requestHeader: {header: ":path", key: path}
+  EOF`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if filename == "" || name == "" {
+				c.Println(c.UsageString())
+				return fmt.Errorf("--filename and --name must be set")
+			}
+			b, err := readFile(filename)
+			if err != nil {
+				return err
+			}
+			out, err := generateRateLimitEnvoyFilter(b, name, handlers.HandleNamespace(namespace, defaultNamespace))
+			if err != nil {
+				return err
+			}
+			_, _ = c.OutOrStdout().Write(out)
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringVarP(&filename, "filename", "f", "", "The rate limit file, or - for stdin")
+	cmd.PersistentFlags().StringVar(&name, "name", "", "The name of the generated EnvoyFilter")
+	return cmd
+}
+
+// generateRateLimitEnvoyFilter returns the YAML of an EnvoyFilter setting a rate limit, validating both.
+func generateRateLimitEnvoyFilter(in []byte, name, ns string) ([]byte, error) {
+	rl := &ratelimit.RateLimit{}
+	if err := yaml.UnmarshalStrict(in, rl); err != nil {
+		return nil, fmt.Errorf("invalid rate limit: %v", err)
+	}
+	ef, annotations, err := rl.ToEnvoyFilter()
+	if err != nil {
+		return nil, err
+	}
+	return envoyFilterYAML(ef, annotations, name, ns)
+}
@@ -0,0 +1,44 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateRateLimitEnvoyFilter(t *testing.T) {
+	out, err := generateRateLimitEnvoyFilter([]byte(`
+workloadSelector:
+  app: reviews
+local:
+  maxTokens: 100
+  fillInterval: 1s
+`), "reviews", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: EnvoyFilter", "name: reviews", "networking.istio.io/rate-limit", "maxTokens: 100"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	if _, err := generateRateLimitEnvoyFilter([]byte(`local:
+  maxToken: 100
+`), "reviews", "default"); err == nil || !strings.Contains(err.Error(), "invalid rate limit") {
+		t.Errorf("expected unknown fields to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/istioctl/pkg/clioptions"
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/recommendation"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/validation"
+)
+
+var recommendWrite bool
+
+func recommendCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recommend",
+		Short: "Recommend configuration from the observed traffic",
+	}
+	cmd.AddCommand(recommendSidecarCmd())
+	return cmd
+}
+
+func recommendSidecarCmd() *cobra.Command {
+	var opts clioptions.ControlPlaneOptions
+	cmd := &cobra.Command{
+		Use:   "sidecar",
+		Short: "Show the default Sidecar recommended by istiod for a namespace from the traffic of its workloads",
+		Long: `Shows the default Sidecar recommended by istiod for a namespace, restricting the egress of its sidecars to the
+services they call.
+
+Without Sidecar, each sidecar receives the configuration of all the services of the mesh. With
+PILOT_SIDECAR_RECOMMENDATION_PROMETHEUS_ADDRESS set, istiod reads the services called by the sidecars of each
+namespace without Sidecar from the istio_requests_total and istio_tcp_connections_opened_total metrics they report
+over PILOT_SIDECAR_RECOMMENDATION_WINDOW, and recommends a Sidecar with each of them as egress host, along with the
+services of the istio system namespace. With PILOT_SIDECAR_RECOMMENDATION_WRITE set, istiod also writes the
+recommended Sidecars.
+
+With --write, the Sidecar is created in the namespace, unless the namespace already has a default Sidecar that
+was not recommended.`,
+		Example: `  # Show the Sidecar recommended for the bookinfo namespace
+  istioctl experimental recommend sidecar -n bookinfo
+
+  # Write the recommended Sidecar
+  istioctl experimental recommend sidecar -n bookinfo --write`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ns := handlers.HandleNamespace(namespace, defaultNamespace)
+			kubeClient, err := kubeClientWithRevision(kubeconfig, configContext, opts.Revision)
+			if err != nil {
+				return err
+			}
+			res, err := kubeClient.AllDiscoveryDo(context.Background(), istioNamespace, "/debug/sidecar_recommendationz?namespace="+ns)
+			if err != nil {
+				return err
+			}
+			sc, err := recommendedSidecar(res, ns)
+			if err != nil {
+				return err
+			}
+			if !recommendWrite {
+				out, err := sidecarYAML(sc)
+				if err != nil {
+					return err
+				}
+				_, _ = c.OutOrStdout().Write(out)
+				return nil
+			}
+			if err := recommendation.Write(context.Background(), kubeClient.Istio(), sc); err != nil {
+				return err
+			}
+			_, _ = fmt.Fprintf(c.OutOrStdout(), "Sidecar %s/%s written with %d egress hosts\n", sc.Namespace, sc.Name, len(sc.Spec.Egress[0].Hosts))
+			return nil
+		},
+	}
+	opts.AttachControlPlaneFlags(cmd)
+	cmd.PersistentFlags().BoolVar(&recommendWrite, "write", false,
+		"Create or update the default Sidecar of the namespace instead of printing it.")
+	return cmd
+}
+
+// recommendedSidecar returns the Sidecar recommended for the namespace in the responses of the istiod instances.
+// Only the leader istiod recommends Sidecars.
+func recommendedSidecar(responses map[string][]byte, ns string) (*clientnetworking.Sidecar, error) {
+	istiods := make([]string, 0, len(responses))
+	for istiod := range responses {
+		istiods = append(istiods, istiod)
+	}
+	sort.Strings(istiods)
+	var errs []string
+	for _, istiod := range istiods {
+		recommendations := map[string]*clientnetworking.Sidecar{}
+		if err := json.Unmarshal(responses[istiod], &recommendations); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", istiod, strings.TrimSpace(string(responses[istiod]))))
+			continue
+		}
+		if sc := recommendations[ns]; sc != nil {
+			return sc, nil
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("no Sidecar is recommended for namespace %s: %s", ns, strings.Join(errs, "; "))
+	}
+	return nil, fmt.Errorf("no Sidecar is recommended for namespace %s: it has a Sidecar, or no observed traffic", ns)
+}
+
+// sidecarYAML validates the Sidecar, and returns its YAML.
+func sidecarYAML(sc *clientnetworking.Sidecar) ([]byte, error) {
+	cfg := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.Sidecar,
+			Name:             sc.Name,
+			Namespace:        sc.Namespace,
+			Labels:           sc.Labels,
+		},
+		Spec: &sc.Spec,
+	}
+	if _, err := validation.ValidateSidecar(cfg); err != nil {
+		return nil, fmt.Errorf("recommended Sidecar is invalid: %v", err)
+	}
+	obj, err := crd.ConvertConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(obj)
+}
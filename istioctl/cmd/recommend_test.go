@@ -0,0 +1,58 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/recommendation"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func TestRecommendedSidecar(t *testing.T) {
+	sc := recommendation.Sidecar("bookinfo", "istio-system", map[string]sets.String{"db": sets.New("mysql.db.svc.cluster.local")})
+	leader, err := json.Marshal(map[string]any{"bookinfo": sc})
+	if err != nil {
+		t.Fatal(err)
+	}
+	responses := map[string][]byte{
+		"istiod-a": []byte("null"),
+		"istiod-b": leader,
+	}
+	got, err := recommendedSidecar(responses, "bookinfo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := sidecarYAML(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"kind: Sidecar", "name: default", "namespace: bookinfo", "istio.io/recommended: \"true\"",
+		"- istio-system/*", "- db/mysql.db.svc.cluster.local"} {
+		if !strings.Contains(string(out), s) {
+			t.Errorf("expected %q in output:\n%s", s, out)
+		}
+	}
+
+	if _, err := recommendedSidecar(responses, "reviews"); err == nil {
+		t.Fatal("expected an error for a namespace without recommended Sidecar")
+	}
+	disabled := map[string][]byte{"istiod-a": []byte("Sidecar recommendations are not enabled\n")}
+	if _, err := recommendedSidecar(disabled, "bookinfo"); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Fatalf("expected the error of istiod to be returned, got %v", err)
+	}
+}
@@ -235,10 +235,22 @@ debug and diagnose their Istio mesh.
 	experimentalCmd.AddCommand(configCmd())
 	experimentalCmd.AddCommand(workloadCommands())
 	experimentalCmd.AddCommand(revisionCommand())
+	experimentalCmd.AddCommand(upgradeCmd())
 	experimentalCmd.AddCommand(debugCommand())
 	experimentalCmd.AddCommand(preCheck())
 	experimentalCmd.AddCommand(statsConfigCmd())
 	experimentalCmd.AddCommand(checkInjectCommand())
+	experimentalCmd.AddCommand(drainCmd())
+	experimentalCmd.AddCommand(pushCmd())
+	experimentalCmd.AddCommand(connectionPoolCmd())
+	experimentalCmd.AddCommand(listenerPatchCmd())
+	experimentalCmd.AddCommand(rateLimitCmd())
+	experimentalCmd.AddCommand(recommendCmd())
+	experimentalCmd.AddCommand(exportMeshConfigCmd())
+	experimentalCmd.AddCommand(indexSnapshotCmd())
+	experimentalCmd.AddCommand(testCmd())
+	experimentalCmd.AddCommand(simulateCmd())
+	experimentalCmd.AddCommand(deprecationsCmd())
 
 	analyzeCmd := Analyze()
 	hideInheritedFlags(analyzeCmd, FlagIstioNamespace)
@@ -256,7 +268,7 @@ debug and diagnose their Istio mesh.
 	hideInheritedFlags(operatorCmd, FlagNamespace, FlagIstioNamespace, FlagCharts)
 	rootCmd.AddCommand(operatorCmd)
 
-	installCmd := mesh.InstallCmd(loggingOptions)
+	installCmd := installCommand(loggingOptions)
 	hideInheritedFlags(installCmd, FlagNamespace, FlagIstioNamespace, FlagCharts)
 	rootCmd.AddCommand(installCmd)
 
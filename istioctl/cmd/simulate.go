@@ -0,0 +1,152 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pilot/pkg/simulation"
+)
+
+func simulateCmd() *cobra.Command {
+	var filenames []string
+	var proxyFile, meshConfigFile, callsFile string
+	call := simulation.Call{}
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "Simulate which listener, route and cluster of a proxy requests match",
+		Long: `Generates the configuration of a proxy from the given Istio and Kubernetes configuration files, as istiod
+would, and simulates requests sent through the proxy. For each request, the listener, filter chain, route and
+cluster matched are reported.
+
+A single request can be described with flags, or a list of requests with --calls. Each request of the list can
+have the result it is expected to have, in which case the command fails if a request does not match its
+expectation. This allows testing routing and policy changes in CI, without a cluster.`,
+		Example: `  # Simulate an HTTP request sent by a sidecar to the reviews service
+  istioctl experimental simulate -f services.yaml -f routing.yaml --proxy proxy.yaml --port 9080 --host reviews
+
+  # Check the requests of calls.yaml match their expectations
+  istioctl experimental simulate -f services.yaml -f routing.yaml --proxy proxy.yaml --calls calls.yaml
+
+  # Where calls.yaml lists the requests:
+  - name: reviews-v2
+    call:
+      port: 9080
+      host: reviews
+      path: /v2
+    expect:
+      cluster: outbound|9080|v2|reviews.default.svc.cluster.local`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(filenames) == 0 {
+				c.Println(c.UsageString())
+				return fmt.Errorf("at least one --filename must be set")
+			}
+			var cases []simulation.TestCase
+			if callsFile != "" {
+				b, err := os.ReadFile(callsFile)
+				if err != nil {
+					return err
+				}
+				if err := yaml.UnmarshalStrict(b, &cases); err != nil {
+					return fmt.Errorf("invalid calls %s: %v", callsFile, err)
+				}
+			} else {
+				if call.Port == 0 {
+					c.Println(c.UsageString())
+					return fmt.Errorf("--port or --calls must be set")
+				}
+				cases = []simulation.TestCase{{Call: call}}
+			}
+			in, err := readTranslateInput(filenames, proxyFile, meshConfigFile)
+			if err != nil {
+				return err
+			}
+
+			calls := make([]simulation.Call, 0, len(cases))
+			for _, tc := range cases {
+				calls = append(calls, tc.Call)
+			}
+			results, err := simulation.Simulate(in, calls)
+			if err != nil {
+				return err
+			}
+			if failed := printSimulationResults(c.OutOrStdout(), cases, results); failed > 0 {
+				return fmt.Errorf("%d of %d calls did not match their expectations", failed, len(cases))
+			}
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringSliceVarP(&filenames, "filename", "f", nil,
+		"Istio and Kubernetes configuration files, such as VirtualServices and Services")
+	cmd.PersistentFlags().StringVar(&proxyFile, "proxy", "",
+		"YAML file describing the proxy, with its type, id, namespace, ips, labels and istioVersion")
+	cmd.PersistentFlags().StringVar(&meshConfigFile, "meshConfigFile", "", "Mesh configuration file. Defaults to the default mesh configuration")
+	cmd.PersistentFlags().StringVar(&callsFile, "calls", "",
+		"YAML file listing the requests to simulate, with their name, call and expected result")
+	cmd.PersistentFlags().StringVar(&call.Address, "address", "", "Destination address of the request")
+	cmd.PersistentFlags().IntVar(&call.Port, "port", 0, "Destination port of the request")
+	cmd.PersistentFlags().StringVar((*string)(&call.Protocol), "protocol", string(simulation.HTTP),
+		"Protocol of the request: http, http2 or tcp")
+	cmd.PersistentFlags().StringVar((*string)(&call.TLS), "tls", string(simulation.Plaintext),
+		"TLS mode of the request: plaintext, tls or mtls")
+	cmd.PersistentFlags().StringVar(&call.HostHeader, "host", "", "Host header of the request")
+	cmd.PersistentFlags().StringVar(&call.Path, "path", "", "Path of the request")
+	cmd.PersistentFlags().StringVar(&call.Sni, "sni", "", "SNI of the request. Defaults to the host for TLS requests")
+	cmd.PersistentFlags().StringVar((*string)(&call.CallMode), "mode", string(simulation.CallModeOutbound),
+		"How the request reaches the proxy: outbound, inbound or gateway")
+	return cmd
+}
+
+// printSimulationResults writes a table of the results of the calls, and returns the number of calls that
+// did not match their expectations.
+func printSimulationResults(writer io.Writer, cases []simulation.TestCase, results []simulation.Result) int {
+	w := tabwriter.NewWriter(writer, 0, 8, 1, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tLISTENER\tFILTER CHAIN\tROUTE CONFIG\tVIRTUAL HOST\tROUTE\tCLUSTER\tERROR")
+	failed := 0
+	var mismatches []string
+	for i, r := range results {
+		tc := cases[i]
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("call-%d", i)
+		}
+		errMsg := ""
+		if r.Error != nil {
+			errMsg = r.Error.Error()
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", name, r.ListenerMatched, r.FilterChainMatched,
+			r.RouteConfigMatched, r.VirtualHostMatched, r.RouteMatched, r.ClusterMatched, errMsg)
+		if tc.Expect == nil {
+			continue
+		}
+		if diffs := r.Check(*tc.Expect); len(diffs) > 0 {
+			failed++
+			mismatches = append(mismatches, fmt.Sprintf("%s: %s", name, strings.Join(diffs, ", ")))
+		}
+	}
+	_ = w.Flush()
+	for _, m := range mismatches {
+		_, _ = fmt.Fprintln(writer, m)
+	}
+	return failed
+}
@@ -0,0 +1,50 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/simulation"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestPrintSimulationResults(t *testing.T) {
+	cases := []simulation.TestCase{
+		{Name: "match", Expect: &simulation.Expectation{Cluster: "outbound|9080||reviews.default.svc.cluster.local"}},
+		{Name: "mismatch", Expect: &simulation.Expectation{Cluster: "outbound|9080|v2|reviews.default.svc.cluster.local"}},
+		{Name: "no-expectation"},
+		{Name: "unexpected-error", Expect: &simulation.Expectation{Listener: "0.0.0.0_9080"}},
+	}
+	results := []simulation.Result{
+		{ListenerMatched: "0.0.0.0_9080", ClusterMatched: "outbound|9080||reviews.default.svc.cluster.local"},
+		{ListenerMatched: "0.0.0.0_9080", ClusterMatched: "outbound|9080|v1|reviews.default.svc.cluster.local"},
+		{Error: simulation.ErrNoListener},
+		{ListenerMatched: "0.0.0.0_9080", Error: simulation.ErrNoRoute},
+	}
+	out := &bytes.Buffer{}
+	assert.Equal(t, printSimulationResults(out, cases, results), 2)
+	got := out.String()
+	for _, want := range []string{
+		`mismatch: want cluster "outbound|9080|v2|reviews.default.svc.cluster.local" got "outbound|9080|v1|reviews.default.svc.cluster.local"`,
+		`unexpected-error: unexpected error "no route matched"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -16,18 +16,23 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/exp/maps"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
+	"istio.io/api/label"
 	"istio.io/istio/istioctl/pkg/tag"
 	"istio.io/istio/istioctl/pkg/util/formatting"
 	"istio.io/istio/operator/cmd/mesh"
+	"istio.io/istio/pilot/pkg/writeapi"
 	"istio.io/istio/pkg/config/analysis"
 	"istio.io/istio/pkg/config/analysis/analyzers/webhook"
 	"istio.io/istio/pkg/config/analysis/diag"
@@ -47,6 +52,12 @@ overwrite existing revision tags.`
 	tagCreatedStr   = `Revision tag %q created, referencing control plane revision %q. To enable injection using this
 revision tag, use 'kubectl label namespace <NAMESPACE> istio.io/rev=%s'
 `
+	tagInUseStr = `%d namespace(s) with %d injected workload(s) use revision tag %q. Restart their workloads to move them
+to revision %q.
+`
+	forceSetHelpStr = `If true, point the revision tag at the revision even if the pre-flight checks fail, such as when the revision
+has no ready istiod pod.`
+	forceRemoveHelpStr          = "If true, remove the revision tag even if namespaces still use it."
 	webhookNameHelpStr          = "Name to use for a revision tag's mutating webhook configuration."
 	autoInjectNamespacesHelpStr = "If set to true, the sidecars should be automatically injected into all namespaces by default"
 )
@@ -58,6 +69,7 @@ var (
 	manifestsPath        = ""
 	overwrite            = false
 	skipConfirmation     = false
+	force                = false
 	webhookName          = ""
 	autoInjectNamespaces = false
 )
@@ -66,6 +78,7 @@ type tagDescription struct {
 	Tag        string   `json:"tag"`
 	Revision   string   `json:"revision"`
 	Namespaces []string `json:"namespaces"`
+	Workloads  int      `json:"workloads"`
 }
 
 func tagCommand() *cobra.Command {
@@ -124,6 +137,9 @@ injection labels.`,
 
  # Rollout namespace "test-ns" to update workloads to the "1-8-1" revision
  kubectl rollout restart deployments -n test-ns
+
+ # Have istiod move the revision tag, as allowed by its write policy to the release service account of the ops namespace
+ istioctl tag set prod --revision 1-8-1 --overwrite --through-istiod --write-identity release.ops
 `,
 		SuggestFor: []string{"create"},
 		Args: func(cmd *cobra.Command, args []string) error {
@@ -151,6 +167,8 @@ injection labels.`,
 	cmd.PersistentFlags().StringVarP(&revision, "revision", "r", "", revisionHelpStr)
 	cmd.PersistentFlags().StringVarP(&webhookName, "webhook-name", "", "", webhookNameHelpStr)
 	cmd.PersistentFlags().BoolVar(&autoInjectNamespaces, "auto-inject-namespaces", false, autoInjectNamespacesHelpStr)
+	cmd.PersistentFlags().BoolVar(&force, "force", false, forceSetHelpStr)
+	attachWriteAPIFlags(cmd)
 	_ = cmd.MarkPersistentFlagRequired("revision")
 
 	return cmd
@@ -236,7 +254,8 @@ func tagRemoveCommand() *cobra.Command {
 
 Removing a revision tag should be done with care. Removing a revision tag will disrupt sidecar injection in namespaces
 that reference the tag in an "istio.io/rev" label. Verify that there are no remaining namespaces referencing a
-revision tag before removing using the "istioctl tag list" command.
+revision tag before removing using the "istioctl tag list" command. Revision tags still used by namespaces are only
+removed with --force.
 `,
 		Example: ` # Remove the revision tag "prod"
 	istioctl tag remove prod
@@ -257,24 +276,35 @@ revision tag before removing using the "istioctl tag list" command.
 				return fmt.Errorf("failed to create Kubernetes client: %v", err)
 			}
 
-			return removeTag(context.Background(), client.Kube(), args[0], skipConfirmation, cmd.OutOrStdout())
+			if throughIstiod {
+				return removeTagThroughIstiod(context.Background(), client, args[0], skipConfirmation, force, cmd.OutOrStdout())
+			}
+			return removeTag(context.Background(), client.Kube(), args[0], skipConfirmation, force, cmd.OutOrStdout())
 		},
 	}
 
 	cmd.PersistentFlags().BoolVarP(&skipConfirmation, "skip-confirmation", "y", false, skipConfirmationFlagHelpStr)
+	cmd.PersistentFlags().BoolVar(&force, "force", false, forceRemoveHelpStr)
+	attachWriteAPIFlags(cmd)
 	return cmd
 }
 
 // setTag creates or modifies a revision tag.
 func setTag(ctx context.Context, kubeClient kube.CLIClient, tagName, revision, istioNS string, generate bool, w, stderr io.Writer) error {
+	// istiod generates the webhooks of the tag and deactivates the default revision itself when the tag is set
+	// through it, the manifests are only generated to be analyzed.
+	if throughIstiod && webhookName != "" {
+		return errors.New("--webhook-name is not supported with --through-istiod")
+	}
 	opts := &tag.GenerateOptions{
 		Tag:                  tagName,
 		Revision:             revision,
 		WebhookName:          webhookName,
 		ManifestsPath:        manifestsPath,
-		Generate:             generate,
+		Generate:             generate || throughIstiod,
 		Overwrite:            overwrite,
 		AutoInjectNamespaces: autoInjectNamespaces,
+		Staged:               true,
 	}
 	tagWhYAML, err := tag.Generate(ctx, kubeClient, opts, istioNS)
 	if err != nil {
@@ -307,10 +337,29 @@ func setTag(ctx context.Context, kubeClient kube.CLIClient, tagName, revision, i
 		return nil
 	}
 
-	if err := tag.Create(kubeClient, tagWhYAML); err != nil {
+	if !force {
+		if err := tag.CheckRevisionHealthy(ctx, kubeClient.Kube(), istioNS, revision); err != nil {
+			return fmt.Errorf("cannot point revision tag %q at revision %q: %v, pass --force to proceed", tagName, revision, err)
+		}
+	}
+
+	if throughIstiod {
+		if _, err := writeThroughIstiod(ctx, kubeClient, "", "", writeapi.Request{
+			Operation:            writeapi.TagSet,
+			Tag:                  tagName,
+			Revision:             revision,
+			Overwrite:            overwrite,
+			AutoInjectNamespaces: autoInjectNamespaces,
+		}); err != nil {
+			return err
+		}
+	} else if err := tag.Activate(ctx, kubeClient, opts, tagWhYAML); err != nil {
 		return fmt.Errorf("failed to apply tag webhook MutatingWebhookConfiguration to cluster: %v", err)
 	}
 	fmt.Fprintf(w, tagCreatedStr, tagName, revision, tagName)
+	if usage, err := tag.GetTagUsage(ctx, kubeClient.Kube(), tagName); err == nil && usage.InUse() {
+		fmt.Fprintf(w, tagInUseStr, len(usage.Namespaces), usage.Workloads, tagName, revision)
+	}
 	return nil
 }
 
@@ -347,7 +396,7 @@ func analyzeWebhook(name, wh, revision string, config *rest.Config) error {
 }
 
 // removeTag removes an existing revision tag.
-func removeTag(ctx context.Context, kubeClient kubernetes.Interface, tagName string, skipConfirmation bool, w io.Writer) error {
+func removeTag(ctx context.Context, kubeClient kubernetes.Interface, tagName string, skipConfirmation, force bool, w io.Writer) error {
 	webhooks, err := tag.GetWebhooksWithTag(ctx, kubeClient, tagName)
 	if err != nil {
 		return fmt.Errorf("failed to retrieve tag with name %s: %v", tagName, err)
@@ -356,16 +405,8 @@ func removeTag(ctx context.Context, kubeClient kubernetes.Interface, tagName str
 		return fmt.Errorf("cannot remove tag %q: cannot find MutatingWebhookConfiguration for tag", tagName)
 	}
 
-	taggedNamespaces, err := tag.GetNamespacesWithTag(ctx, kubeClient, tagName)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve namespaces dependent on tag %q", tagName)
-	}
-	// warn user if deleting a tag that still has namespaces pointed to it
-	if len(taggedNamespaces) > 0 && !skipConfirmation {
-		if !confirm(buildDeleteTagConfirmation(tagName, taggedNamespaces), w) {
-			fmt.Fprintf(w, "Aborting operation.\n")
-			return nil
-		}
+	if proceed, err := confirmTagRemoval(ctx, kubeClient, tagName, skipConfirmation, force, w); !proceed {
+		return err
 	}
 
 	// proceed with webhook deletion
@@ -378,6 +419,40 @@ func removeTag(ctx context.Context, kubeClient kubernetes.Interface, tagName str
 	return nil
 }
 
+// removeTagThroughIstiod has istiod remove an existing revision tag.
+func removeTagThroughIstiod(ctx context.Context, client kube.CLIClient, tagName string, skipConfirmation, force bool, w io.Writer) error {
+	if proceed, err := confirmTagRemoval(ctx, client.Kube(), tagName, skipConfirmation, force, w); !proceed {
+		return err
+	}
+	if _, err := writeThroughIstiod(ctx, client, "", "", writeapi.Request{Operation: writeapi.TagRemove, Tag: tagName}); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Revision tag %s removed\n", tagName)
+	return nil
+}
+
+// confirmTagRemoval returns true if the revision tag can be removed. Tags still used by namespaces are only
+// removed with force, as their new pods would no longer be injected.
+func confirmTagRemoval(ctx context.Context, kubeClient kubernetes.Interface, tagName string, skipConfirmation, force bool, w io.Writer) (bool, error) {
+	usage, err := tag.GetTagUsage(ctx, kubeClient, tagName)
+	if err != nil {
+		return false, fmt.Errorf("failed to retrieve namespaces dependent on tag %q", tagName)
+	}
+	if !usage.InUse() {
+		return true, nil
+	}
+	if !force {
+		return false, fmt.Errorf("cannot remove tag %q: %d namespace(s) with %d injected workload(s) still use it: %s, pass --force to remove it",
+			tagName, len(usage.Namespaces), usage.Workloads, strings.Join(usage.Namespaces, ", "))
+	}
+	// warn user if deleting a tag that still has namespaces pointed to it
+	if !skipConfirmation && !confirm(buildDeleteTagConfirmation(tagName, usage.Namespaces), w) {
+		fmt.Fprintf(w, "Aborting operation.\n")
+		return false, nil
+	}
+	return true, nil
+}
+
 // listTags lists existing revision.
 func listTags(ctx context.Context, kubeClient kubernetes.Interface, writer io.Writer) error {
 	tagWebhooks, err := tag.GetTagWebhooks(ctx, kubeClient)
@@ -398,14 +473,15 @@ func listTags(ctx context.Context, kubeClient kubernetes.Interface, writer io.Wr
 		if err != nil {
 			return fmt.Errorf("error parsing revision from webhook %q: %v", wh.Name, err)
 		}
-		tagNamespaces, err := tag.GetNamespacesWithTag(ctx, kubeClient, tagName)
+		usage, err := tag.GetTagUsage(ctx, kubeClient, tagName)
 		if err != nil {
 			return fmt.Errorf("error retrieving namespaces for tag %q: %v", tagName, err)
 		}
 		tagDesc := tagDescription{
 			Tag:        tagName,
 			Revision:   tagRevision,
-			Namespaces: tagNamespaces,
+			Namespaces: usage.Namespaces,
+			Workloads:  usage.Workloads,
 		}
 		tags = append(tags, tagDesc)
 	}
@@ -418,12 +494,25 @@ func listTags(ctx context.Context, kubeClient kubernetes.Interface, writer io.Wr
 		return fmt.Errorf("unknown format: %s", revArgs.output)
 	}
 	w := new(tabwriter.Writer).Init(writer, 0, 8, 1, ' ', 0)
-	fmt.Fprintln(w, "TAG\tREVISION\tNAMESPACES")
+	fmt.Fprintln(w, "TAG\tREVISION\tNAMESPACES\tWORKLOADS")
 	for _, t := range tags {
-		fmt.Fprintf(w, "%s\t%s\t%s\n", t.Tag, t.Revision, strings.Join(t.Namespaces, ","))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", t.Tag, t.Revision, strings.Join(t.Namespaces, ","), t.Workloads)
+	}
+	if err := w.Flush(); err != nil {
+		return err
 	}
 
-	return w.Flush()
+	orphaned, err := tag.GetOrphanedNamespaces(ctx, kubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve namespaces using unknown revision tags: %v", err)
+	}
+	revs := maps.Keys(orphaned)
+	sort.Strings(revs)
+	for _, rev := range revs {
+		fmt.Fprintf(writer, "Warning: namespace(s) %s use %s=%s, which is neither a revision nor a revision tag: their new pods are not injected\n",
+			strings.Join(orphaned[rev], ","), label.IoIstioRev.Name, rev)
+	}
+	return nil
 }
 
 // buildDeleteTagConfirmation takes a list of webhooks and creates a message prompting confirmation for their deletion.
@@ -26,6 +26,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
 
+	"istio.io/api/annotation"
 	"istio.io/api/label"
 	"istio.io/istio/istioctl/pkg/tag"
 	"istio.io/istio/operator/pkg/helmreconciler"
@@ -68,6 +69,7 @@ func TestTagList(t *testing.T) {
 		name           string
 		webhooks       admitv1.MutatingWebhookConfigurationList
 		namespaces     corev1.NamespaceList
+		pods           corev1.PodList
 		outputMatches  []string
 		outputExcludes []string
 		error          string
@@ -139,12 +141,58 @@ func TestTagList(t *testing.T) {
 			outputExcludes: []string{},
 			error:          "",
 		},
+		{
+			name: "TestWorkloadsCounted",
+			webhooks: admitv1.MutatingWebhookConfigurationList{
+				Items: []admitv1.MutatingWebhookConfiguration{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "istio-revision-test",
+							Labels: map[string]string{
+								label.IoIstioRev.Name: "revision",
+								tag.IstioTagLabel:     "test",
+							},
+						},
+					},
+				},
+			},
+			namespaces: corev1.NamespaceList{
+				Items: []corev1.Namespace{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "dependent",
+							Labels: map[string]string{label.IoIstioRev.Name: "test"},
+						},
+					},
+				},
+			},
+			pods: corev1.PodList{
+				Items: []corev1.Pod{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:        "injected",
+							Namespace:   "dependent",
+							Annotations: map[string]string{annotation.SidecarStatus.Name: "{}"},
+						},
+					},
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:      "not-injected",
+							Namespace: "dependent",
+						},
+					},
+				},
+			},
+			outputMatches:  []string{`"workloads": 1`},
+			outputExcludes: []string{},
+			error:          "",
+		},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.name, func(t *testing.T) {
 			var out bytes.Buffer
-			client := fake.NewSimpleClientset(tc.webhooks.DeepCopyObject(), tc.namespaces.DeepCopyObject())
+			client := fake.NewSimpleClientset(tc.webhooks.DeepCopyObject(), tc.namespaces.DeepCopyObject(), tc.pods.DeepCopyObject())
 			revArgs.output = jsonFormat
 			err := listTags(context.Background(), client, &out)
 			if tc.error == "" && err != nil {
@@ -183,6 +231,7 @@ func TestRemoveTag(t *testing.T) {
 		namespaces       corev1.NamespaceList
 		outputMatches    []string
 		skipConfirmation bool
+		force            bool
 		error            string
 	}{
 		{
@@ -265,8 +314,75 @@ func TestRemoveTag(t *testing.T) {
 					},
 				},
 			},
+			outputMatches:    []string{},
+			skipConfirmation: false,
+			error:            "cannot remove tag \"match\": 1 namespace(s) with 0 injected workload(s) still use it: dependent, pass --force",
+		},
+		{
+			name: "TestConfirmForcedDeleteTagWithDependentNamespace",
+			tag:  "match",
+			webhooksBefore: admitv1.MutatingWebhookConfigurationList{
+				Items: []admitv1.MutatingWebhookConfiguration{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "istio-revision-tag-match",
+							Labels: map[string]string{tag.IstioTagLabel: "match"},
+						},
+					},
+				},
+			},
+			webhooksAfter: admitv1.MutatingWebhookConfigurationList{
+				Items: []admitv1.MutatingWebhookConfiguration{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "istio-revision-tag-match",
+							Labels: map[string]string{tag.IstioTagLabel: "match"},
+						},
+					},
+				},
+			},
+			namespaces: corev1.NamespaceList{
+				Items: []corev1.Namespace{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "dependent",
+							Labels: map[string]string{label.IoIstioRev.Name: "match"},
+						},
+					},
+				},
+			},
 			outputMatches:    []string{"Caution, found 1 namespace(s) still injected by tag \"match\": dependent"},
 			skipConfirmation: false,
+			force:            true,
+			error:            "",
+		},
+		{
+			name: "TestForceDeleteTagWithDependentNamespace",
+			tag:  "match",
+			webhooksBefore: admitv1.MutatingWebhookConfigurationList{
+				Items: []admitv1.MutatingWebhookConfiguration{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "istio-revision-tag-match",
+							Labels: map[string]string{tag.IstioTagLabel: "match"},
+						},
+					},
+				},
+			},
+			webhooksAfter: admitv1.MutatingWebhookConfigurationList{},
+			namespaces: corev1.NamespaceList{
+				Items: []corev1.Namespace{
+					{
+						ObjectMeta: metav1.ObjectMeta{
+							Name:   "dependent",
+							Labels: map[string]string{label.IoIstioRev.Name: "match"},
+						},
+					},
+				},
+			},
+			outputMatches:    []string{"Revision tag match removed"},
+			skipConfirmation: true,
+			force:            true,
 			error:            "",
 		},
 	}
@@ -275,7 +391,7 @@ func TestRemoveTag(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			var out bytes.Buffer
 			client := fake.NewSimpleClientset(tc.webhooksBefore.DeepCopyObject(), tc.namespaces.DeepCopyObject())
-			err := removeTag(context.Background(), client, tc.tag, tc.skipConfirmation, &out)
+			err := removeTag(context.Background(), client, tc.tag, tc.skipConfirmation, tc.force, &out)
 			if tc.error == "" && err != nil {
 				t.Fatalf("expected no error, got %v", err)
 			}
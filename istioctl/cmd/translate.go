@@ -0,0 +1,153 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pilot/pkg/xds/translate"
+	"istio.io/istio/pkg/config/mesh"
+)
+
+func testCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "test",
+		Short: "Commands to test Istio configuration without a cluster",
+	}
+	cmd.AddCommand(translateCmd())
+	return cmd
+}
+
+func translateCmd() *cobra.Command {
+	var filenames []string
+	var proxyFile, meshConfigFile, outputFile, goldenFile string
+	cmd := &cobra.Command{
+		Use:   "translate",
+		Short: "Generate the xDS configuration of a proxy from configuration files",
+		Long: `Runs the translation of istiod on the given Istio and Kubernetes configuration files, and outputs the
+listeners, clusters, routes and endpoints generated for the described proxy.
+
+The Kubernetes configuration is limited to Services and Pods: the endpoints of a Service are the Pods it
+selects. Gateway API resources are not supported.
+
+The output is stable, so it can be checked in as a golden file and compared in CI with --golden to detect how
+configuration changes or Istio upgrades change the configuration of the proxies.`,
+		Example: `  # Generate the configuration of a sidecar in the default namespace
+  istioctl experimental test translate -f services.yaml -f routing.yaml --proxy proxy.yaml
+
+  # Fail if the generated configuration differs from a golden file
+  istioctl experimental test translate -f services.yaml -f routing.yaml --proxy proxy.yaml --golden proxy.golden.yaml
+
+  # Where proxy.yaml describes the proxy:
+  type: sidecar
+  namespace: default
+  ips: [10.0.0.1]
+  labels:
+    app: reviews`,
+		Args: cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			if len(filenames) == 0 {
+				c.Println(c.UsageString())
+				return fmt.Errorf("at least one --filename must be set")
+			}
+			in, err := readTranslateInput(filenames, proxyFile, meshConfigFile)
+			if err != nil {
+				return err
+			}
+			out, err := translate.Translate(in)
+			if err != nil {
+				return err
+			}
+			b, err := out.YAML()
+			if err != nil {
+				return err
+			}
+			if goldenFile != "" {
+				return compareGolden(c, goldenFile, b)
+			}
+			if outputFile != "" {
+				return os.WriteFile(outputFile, b, 0o644)
+			}
+			_, _ = c.OutOrStdout().Write(b)
+			return nil
+		},
+	}
+	cmd.PersistentFlags().StringSliceVarP(&filenames, "filename", "f", nil,
+		"Istio configuration files and Kubernetes Services and Pods")
+	cmd.PersistentFlags().StringVar(&proxyFile, "proxy", "",
+		"YAML file describing the proxy, with its type, id, namespace, ips, labels and istioVersion")
+	cmd.PersistentFlags().StringVar(&meshConfigFile, "meshConfigFile", "", "Mesh configuration file. Defaults to the default mesh configuration")
+	cmd.PersistentFlags().StringVarP(&outputFile, "output-file", "o", "", "File to write the generated configuration to. Defaults to stdout")
+	cmd.PersistentFlags().StringVar(&goldenFile, "golden", "",
+		"Golden file to compare the generated configuration with. The command fails and prints a diff if they differ")
+	return cmd
+}
+
+// readTranslateInput reads the configuration files, proxy and mesh configuration of the translation.
+func readTranslateInput(filenames []string, proxyFile, meshConfigFile string) (translate.Input, error) {
+	in := translate.Input{}
+	for _, f := range filenames {
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return in, err
+		}
+		in.Config += "\n---\n" + string(b)
+	}
+	if proxyFile != "" {
+		b, err := os.ReadFile(proxyFile)
+		if err != nil {
+			return in, err
+		}
+		if err := yaml.UnmarshalStrict(b, &in.Proxy); err != nil {
+			return in, fmt.Errorf("invalid proxy %s: %v", proxyFile, err)
+		}
+	}
+	if meshConfigFile != "" {
+		m, err := mesh.ReadMeshConfig(meshConfigFile)
+		if err != nil {
+			return in, err
+		}
+		in.MeshConfig = m
+	}
+	return in, nil
+}
+
+func compareGolden(c *cobra.Command, goldenFile string, got []byte) error {
+	want, err := os.ReadFile(goldenFile)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(want, got) {
+		return nil
+	}
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		FromFile: goldenFile,
+		A:        difflib.SplitLines(string(want)),
+		ToFile:   "generated",
+		B:        difflib.SplitLines(string(got)),
+		Context:  3,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.OutOrStdout(), text)
+	return fmt.Errorf("the generated configuration differs from %s", goldenFile)
+}
@@ -56,7 +56,10 @@ func waitCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "wait [flags] <type> <name>[.<namespace>]",
 		Short: "Wait for an Istio resource",
-		Long:  `Waits for the specified condition to be true of an Istio resource.`,
+		Long: `Waits for the specified condition to be true of an Istio resource.
+
+For distribution, waits until the proxies connected to istiod have ACKed the configuration generated from the
+current generation of the resource.`,
 		Example: `  # Wait until the bookinfo virtual service has been distributed to all proxies in the mesh
   istioctl experimental wait --for=distribution virtualservice bookinfo.default
 
@@ -188,8 +191,7 @@ func poll(cmd *cobra.Command,
 	path := fmt.Sprintf("/debug/config_distribution?resource=%s", targetResource)
 	pilotResponses, err := kubeClient.AllDiscoveryDo(context.TODO(), istioNamespace, path)
 	if err != nil {
-		return 0, 0, 0, fmt.Errorf("unable to query pilot for distribution "+
-			"(are you using pilot version >= 1.4 with config distribution tracking on): %s", err)
+		return 0, 0, 0, fmt.Errorf("unable to query pilot for distribution: %s", err)
 	}
 	sdcnum = 0
 	versionCount := make(map[string]int)
@@ -41,6 +41,7 @@ import (
 	"istio.io/istio/operator/pkg/util"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pilot/pkg/writeapi"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/config/validation"
@@ -66,6 +67,7 @@ var (
 	externalIP     string
 	ingressSvc     string
 	autoRegister   bool
+	bootstrapToken bool
 	dnsCapture     bool
 	ports          []string
 	resourceLabels []string
@@ -76,6 +78,8 @@ var (
 const (
 	istioEastWestGatewayServiceName = "istio-eastwestgateway"
 	filePerms                       = os.FileMode(0o744)
+	// vmCertsDir is the directory of the certificates on the VMs, holding the root certificate.
+	vmCertsDir = "/etc/certs"
 )
 
 func workloadCommands() *cobra.Command {
@@ -254,6 +258,12 @@ Configure requires either the WorkloadGroup artifact path or its location on the
 	configureCmd.PersistentFlags().StringVarP(&outputDir, "output", "o", "", "Output directory for generated files")
 	configureCmd.PersistentFlags().StringVar(&clusterID, "clusterID", "", "The ID used to identify the cluster")
 	configureCmd.PersistentFlags().Int64Var(&tokenDuration, "tokenDuration", 3600, "The token duration in seconds (default: 1 hour)")
+	configureCmd.PersistentFlags().BoolVar(&bootstrapToken, "bootstrapToken", false,
+		"Has istiod generate a single-use bootstrap token, valid for --tokenDuration, instead of a service account token. "+
+			"Requires PILOT_ENABLE_VM_BOOTSTRAP_TOKENS to be enabled in istiod, and the --write-identity to be allowed "+
+			"the workload.bootstrap-token operation by the istio-write-policy.")
+	configureCmd.PersistentFlags().StringVar(&writeIdentity, "write-identity", "",
+		"The service account <name>[.<namespace>] identifying the caller to istiod, with --bootstrapToken.")
 	configureCmd.PersistentFlags().StringVar(&ingressSvc, "ingressService", istioEastWestGatewayServiceName, "Name of the Service to be"+
 		" used as the ingress gateway, in the format <service>.<namespace>. If no namespace is provided, the default "+istioNamespace+" namespace will be used.")
 	configureCmd.PersistentFlags().StringVar(&ingressIP, "ingressIP", "", "IP address of the ingress gateway")
@@ -355,6 +365,11 @@ func createClusterEnv(wg *clientv1alpha3.WorkloadGroup, config *meshconfig.Proxy
 	if isRevisioned(revision) {
 		overrides["CA_ADDR"] = IstiodAddr(istioNamespace, revision)
 	}
+	if bootstrapToken {
+		// The bootstrap token is single-use: the certificates are persisted and used to renew the certificates.
+		overrides["PROV_CERT"] = vmCertsDir
+		overrides["OUTPUT_CERTS"] = vmCertsDir
+	}
 	if len(internalIP) > 0 {
 		overrides["ISTIO_SVC_IP"] = internalIP
 	} else if len(externalIP) > 0 {
@@ -389,6 +404,24 @@ func createCertsTokens(kubeClient kube.CLIClient, wg *clientv1alpha3.WorkloadGro
 
 	serviceAccount := wg.Spec.Template.ServiceAccount
 	tokenPath := filepath.Join(dir, "istio-token")
+	if bootstrapToken {
+		// The token is minted by istiod, which authorizes the caller with its write policy and audits the token.
+		resp, err := writeThroughIstiod(context.Background(), kubeClient, "", "", writeapi.Request{
+			Operation:            writeapi.BootstrapTokenMint,
+			Namespace:            wg.Namespace,
+			ServiceAccount:       serviceAccount,
+			TokenDurationSeconds: tokenDuration,
+		})
+		if err != nil {
+			return fmt.Errorf("could not create a bootstrap token for service account %s in namespace %s: %v", serviceAccount, wg.Namespace, err)
+		}
+		if err := os.WriteFile(tokenPath, []byte(resp.BootstrapToken), filePerms); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Warning: a single-use bootstrap token for namespace %q and service account %q, expiring at %s, "+
+			"has been generated by istiod and stored at %q\n", wg.Namespace, serviceAccount, resp.BootstrapTokenExpiration, tokenPath)
+		return nil
+	}
 	jwtPolicy, err := util.DetectSupportedJWTPolicy(kubeClient.Kube())
 	if err != nil {
 		fmt.Fprintf(out, "Failed to determine JWT policy support: %v", err)
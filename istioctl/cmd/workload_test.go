@@ -16,6 +16,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -320,6 +321,52 @@ func TestWorkloadEntryConfigureNilProxyMetadata(t *testing.T) {
 	checkOutputFiles(t, testdir, checkFiles)
 }
 
+func TestWorkloadEntryConfigureBootstrapToken(t *testing.T) {
+	testdir := "testdata/vmconfig/simple"
+	client := fake.NewSimpleClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "bar", Name: "istio-ca-root-cert"},
+			Data:       map[string]string{"root-cert.pem": string(fakeCACert)},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "istio-system", Name: "istio-rev-1"},
+			Data: map[string]string{
+				"mesh": string(util.ReadFile(t, path.Join(testdir, "meshconfig.yaml"))),
+			},
+		},
+	)
+	kubeClientWithRevision = func(_, _, _ string) (kube.CLIClient, error) {
+		return &kube.MockClient{RevisionValue: "rev-1", Interface: client}, nil
+	}
+
+	outputDir := t.TempDir()
+	cmd := []string{
+		"x", "workload", "entry", "configure",
+		"-f", path.Join(testdir, "workloadgroup.yaml"),
+		"--internalIP", "10.10.10.10",
+		"--clusterID", "Kubernetes",
+		"--bootstrapToken",
+		"-o", outputDir,
+	}
+	// The token is minted by istiod, with the identity of the caller.
+	if _, err := runTestCmd(t, cmd); err == nil || !strings.Contains(err.Error(), "--write-identity is required") {
+		t.Fatalf("expected the bootstrap token to require a write identity, got %v", err)
+	}
+	secrets, err := client.CoreV1().Secrets("istio-system").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets.Items) != 0 {
+		t.Fatalf("expected no bootstrap token to be recorded by istioctl, got %v", secrets.Items)
+	}
+	clusterEnv := string(util.ReadFile(t, path.Join(outputDir, "cluster.env")))
+	for _, want := range []string{"PROV_CERT=/etc/certs", "OUTPUT_CERTS=/etc/certs"} {
+		if !strings.Contains(clusterEnv, want) {
+			t.Errorf("expected %s in cluster.env, got %s", want, clusterEnv)
+		}
+	}
+}
+
 func runTestCmd(t *testing.T, args []string) (string, error) {
 	t.Helper()
 	// TODO there is already probably something else that does this
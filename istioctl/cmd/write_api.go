@@ -0,0 +1,200 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/istioctl/pkg/util/handlers"
+	"istio.io/istio/operator/cmd/mesh"
+	iopv1alpha1 "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	"istio.io/istio/pilot/pkg/writeapi"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/kube"
+	"istio.io/pkg/log"
+)
+
+const (
+	throughIstiodHelpStr = `If true, the operation is performed by istiod rather than with the Kubernetes permissions of the caller.
+istiod authorizes it with the istio-write-policy ConfigMap of its namespace, and audits it.`
+	writeIdentityHelpStr = `The service account <name>[.<namespace>] identifying the caller to istiod, with --through-istiod.
+The caller only needs the permission to create tokens for this service account.`
+
+	// writeAPIPort is the istiod webhook port, serving the write API over TLS.
+	writeAPIPort = 15017
+)
+
+// options of the operations performed through istiod
+var (
+	throughIstiod = false
+	writeIdentity = ""
+)
+
+func attachWriteAPIFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&throughIstiod, "through-istiod", false, throughIstiodHelpStr)
+	cmd.PersistentFlags().StringVar(&writeIdentity, "write-identity", "", writeIdentityHelpStr)
+}
+
+// installCommand is `istioctl install`, which is performed by istiod with --through-istiod.
+func installCommand(logOpts *log.Options) *cobra.Command {
+	iArgs := &mesh.InstallArgs{}
+	cmd := mesh.InstallCmdWithArgs(&mesh.RootArgs{}, iArgs, logOpts)
+	attachWriteAPIFlags(cmd)
+	preRun := cmd.PreRunE
+	cmd.PreRunE = func(c *cobra.Command, args []string) error {
+		if throughIstiod {
+			iArgs.ThroughIstiod = installThroughIstiod
+		}
+		return preRun(c, args)
+	}
+	return cmd
+}
+
+// installThroughIstiod has istiod apply the IstioOperator of a revision.
+func installThroughIstiod(client kube.CLIClient, iop *iopv1alpha1.IstioOperator) error {
+	iop.TypeMeta = metav1.TypeMeta{APIVersion: iopv1alpha1.SchemeGroupVersion.String(), Kind: iopv1alpha1.IstioOperatorGVK.Kind}
+	iopYAML, err := yaml.Marshal(iop)
+	if err != nil {
+		return err
+	}
+	revision := iop.Spec.Revision
+	if revision == "" {
+		revision = "default"
+	}
+	_, err = writeThroughIstiod(context.Background(), client, "", "", writeapi.Request{
+		Operation:     writeapi.Install,
+		Revision:      revision,
+		IstioOperator: string(iopYAML),
+	})
+	return err
+}
+
+// parseWriteIdentity parses a <name>[.<namespace>] service account, defaulting to the namespace of the command.
+func parseWriteIdentity(identity, defaultNS string) (string, string, error) {
+	if identity == "" {
+		return "", "", errors.New("--write-identity is required to perform the operation through istiod")
+	}
+	name, ns, found := strings.Cut(identity, ".")
+	if name == "" || (found && ns == "") {
+		return "", "", fmt.Errorf("invalid --write-identity %q, expected <name>[.<namespace>]", identity)
+	}
+	if !found {
+		ns = defaultNS
+	}
+	return name, ns, nil
+}
+
+// writeThroughIstiod has an istiod instance perform the request. If podName is empty, the first running istiod
+// instance of the istiod namespace is used.
+func writeThroughIstiod(ctx context.Context, client kube.CLIClient, podName, podNamespace string, r writeapi.Request) (*writeapi.Response, error) {
+	sa, saNamespace, err := parseWriteIdentity(writeIdentity, handlers.HandleNamespace(namespace, defaultNamespace))
+	if err != nil {
+		return nil, err
+	}
+	token, err := client.Kube().CoreV1().ServiceAccounts(saNamespace).CreateToken(ctx, sa, &authenticationv1.TokenRequest{
+		Spec: authenticationv1.TokenRequestSpec{Audiences: []string{writeapi.Audience}},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a token for service account %s.%s: %v", sa, saNamespace, err)
+	}
+
+	if podName == "" {
+		istiods, err := client.GetIstioPods(ctx, istioNamespace, map[string]string{
+			"labelSelector": "app=istiod",
+			"fieldSelector": kube.RunningStatus,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(istiods) == 0 {
+			return nil, errors.New("unable to find any Istiod instances")
+		}
+		podName, podNamespace = istiods[0].Name, istiods[0].Namespace
+	}
+	tlsConfig, err := istiodTLSConfig(ctx, client, podNamespace)
+	if err != nil {
+		return nil, err
+	}
+	fw, err := client.NewPortForwarder(podName, podNamespace, "", 0, writeAPIPort)
+	if err != nil {
+		return nil, err
+	}
+	if err := fw.Start(); err != nil {
+		return nil, fmt.Errorf("failure running port forward process: %v", err)
+	}
+	defer fw.Close()
+
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("https://%s%s", fw.Address(), writeapi.Path), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.Status.Token)
+	req.Header.Set("Content-Type", "application/json")
+	httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("istiod %s refused %s: %s", podName, r.Operation, strings.TrimSpace(string(out)))
+	}
+	res := &writeapi.Response{}
+	if err := json.Unmarshal(out, res); err != nil {
+		return nil, fmt.Errorf("invalid response from istiod %s: %v", podName, err)
+	}
+	return res, nil
+}
+
+// istiodTLSConfig verifies the certificate of the istiod instances of the namespace with the root certificate
+// published by istiod. The istiod service name is verified since the instance is reached with a port forward.
+func istiodTLSConfig(ctx context.Context, client kube.CLIClient, istiodNamespace string) (*tls.Config, error) {
+	cm, err := client.Kube().CoreV1().ConfigMaps(istiodNamespace).Get(ctx, controller.CACertNamespaceConfigMap, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the istiod root certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(cm.Data[constants.CACertNamespaceConfigMapDataName])) {
+		return nil, fmt.Errorf("invalid istiod root certificate in %s/%s", istiodNamespace, controller.CACertNamespaceConfigMap)
+	}
+	return &tls.Config{
+		RootCAs:    roots,
+		ServerName: fmt.Sprintf("istiod.%s.svc", istiodNamespace),
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
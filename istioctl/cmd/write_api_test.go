@@ -0,0 +1,45 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+)
+
+func TestParseWriteIdentity(t *testing.T) {
+	cases := []struct {
+		identity  string
+		name      string
+		namespace string
+		wantErr   bool
+	}{
+		{identity: "release.ops", name: "release", namespace: "ops"},
+		{identity: "release", name: "release", namespace: "default"},
+		{identity: "", wantErr: true},
+		{identity: ".ops", wantErr: true},
+		{identity: "release.", wantErr: true},
+	}
+	for _, c := range cases {
+		t.Run(c.identity, func(t *testing.T) {
+			name, ns, err := parseWriteIdentity(c.identity, "default")
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, want error %v", err, c.wantErr)
+			}
+			if name != c.name || ns != c.namespace {
+				t.Fatalf("got %s.%s, want %s.%s", name, ns, c.name, c.namespace)
+			}
+		})
+	}
+}
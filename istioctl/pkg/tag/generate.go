@@ -71,6 +71,9 @@ type GenerateOptions struct {
 	Overwrite bool
 	// AutoInjectNamespaces controls, if the sidecars should be injected into all namespaces by default.
 	AutoInjectNamespaces bool
+	// Staged defers the changes to the existing webhooks to Activate, so that they are only made once the
+	// manifests of the revision tag are applied.
+	Staged bool
 }
 
 // Generate generates the manifests for a revision tag pointed the given revision.
@@ -115,16 +118,9 @@ func Generate(ctx context.Context, client kube.CLIClient, opts *GenerateOptions,
 	}
 
 	if opts.Tag == DefaultRevisionName {
-		if !opts.Generate {
-			// deactivate other istio-injection=enabled injectors if using default revisions.
-			err := DeactivateIstioInjectionWebhook(ctx, client.Kube())
-			if err != nil {
-				return "", fmt.Errorf("failed deactivating existing default revision: %w", err)
-			}
-			// delete deprecated validating webhook configuration if it exists.
-			err = DeleteDeprecatedValidator(ctx, client.Kube())
-			if err != nil {
-				return "", fmt.Errorf("failed removing deprecated validating webhook: %w", err)
+		if !opts.Generate && !opts.Staged {
+			if err := deactivatePreviousDefault(ctx, client); err != nil {
+				return "", err
 			}
 		}
 
@@ -156,6 +152,18 @@ func fixWhConfig(whConfig *tagWebhookConfig) *tagWebhookConfig {
 	return whConfig
 }
 
+// deactivatePreviousDefault deactivates the other istio-injection=enabled injectors when setting the default tag.
+func deactivatePreviousDefault(ctx context.Context, client kube.CLIClient) error {
+	if err := DeactivateIstioInjectionWebhook(ctx, client.Kube()); err != nil {
+		return fmt.Errorf("failed deactivating existing default revision: %w", err)
+	}
+	// delete deprecated validating webhook configuration if it exists.
+	if err := DeleteDeprecatedValidator(ctx, client.Kube()); err != nil {
+		return fmt.Errorf("failed removing deprecated validating webhook: %w", err)
+	}
+	return nil
+}
+
 // Create applies the given tag manifests.
 func Create(client kube.CLIClient, manifests string) error {
 	if err := applyYAML(client, manifests, "istio-system"); err != nil {
@@ -164,6 +172,19 @@ func Create(client kube.CLIClient, manifests string) error {
 	return nil
 }
 
+// Activate applies the manifests of a revision tag generated with Staged, and only then deactivates the injector of
+// the previous default revision for the default tag. A failure to apply the manifests leaves the revision tag and
+// the default revision as they were.
+func Activate(ctx context.Context, client kube.CLIClient, opts *GenerateOptions, manifests string) error {
+	if err := Create(client, manifests); err != nil {
+		return err
+	}
+	if opts.Tag == DefaultRevisionName {
+		return deactivatePreviousDefault(ctx, client)
+	}
+	return nil
+}
+
 // generateValidatingWebhook renders a validating webhook configuration from the given tagWebhookConfig.
 func generateValidatingWebhook(config *tagWebhookConfig, chartPath string) (string, error) {
 	r := helm.NewHelmRenderer(chartPath, defaultChart, "Pilot", config.IstioNamespace, nil)
@@ -21,10 +21,12 @@ import (
 
 	"github.com/hashicorp/go-multierror"
 	admitv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 
+	"istio.io/api/annotation"
 	"istio.io/api/label"
 )
 
@@ -77,6 +79,94 @@ func GetNamespacesWithTag(ctx context.Context, client kubernetes.Interface, tag
 	return nsNames, nil
 }
 
+// Usage is the usage of a revision tag.
+type Usage struct {
+	// Namespaces are the namespaces pointed at the tag.
+	Namespaces []string
+	// Workloads is the number of injected pods of these namespaces.
+	Workloads int
+}
+
+// InUse returns true if namespaces are pointed at the tag.
+func (u Usage) InUse() bool {
+	return len(u.Namespaces) > 0
+}
+
+// GetTagUsage retrieves the namespaces pointed at the given tag, and counts their injected pods.
+func GetTagUsage(ctx context.Context, client kubernetes.Interface, tag string) (Usage, error) {
+	namespaces, err := GetNamespacesWithTag(ctx, client, tag)
+	if err != nil {
+		return Usage{}, err
+	}
+	usage := Usage{Namespaces: namespaces}
+	for _, ns := range namespaces {
+		pods, err := client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return Usage{}, err
+		}
+		for _, pod := range pods.Items {
+			if _, injected := pod.Annotations[annotation.SidecarStatus.Name]; injected {
+				usage.Workloads++
+			}
+		}
+	}
+	return usage, nil
+}
+
+// GetOrphanedNamespaces retrieves the namespaces whose istio.io/rev label is neither a revision nor a revision tag,
+// by label value. New pods of these namespaces are not injected.
+func GetOrphanedNamespaces(ctx context.Context, client kubernetes.Interface) (map[string][]string, error) {
+	webhooks, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{
+		LabelSelector: label.IoIstioRev.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{}
+	for _, wh := range webhooks.Items {
+		if tag, ok := wh.Labels[IstioTagLabel]; ok {
+			known[tag] = true
+		} else {
+			known[wh.Labels[label.IoIstioRev.Name]] = true
+		}
+	}
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: label.IoIstioRev.Name,
+	})
+	if err != nil {
+		return nil, err
+	}
+	orphaned := map[string][]string{}
+	for _, ns := range namespaces.Items {
+		if rev := ns.Labels[label.IoIstioRev.Name]; !known[rev] {
+			orphaned[rev] = append(orphaned[rev], ns.Name)
+		}
+	}
+	return orphaned, nil
+}
+
+// CheckRevisionHealthy checks that the revision has a ready istiod pod in the istiod namespace, before pointing a
+// revision tag at it.
+func CheckRevisionHealthy(ctx context.Context, client kubernetes.Interface, istioNS, revision string) error {
+	pods, err := client.CoreV1().Pods(istioNS).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app=istiod,%s=%s", label.IoIstioRev.Name, revision),
+	})
+	if err != nil {
+		return err
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Errorf("revision %q has no istiod pod in namespace %q", revision, istioNS)
+	}
+	for _, pod := range pods.Items {
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("none of the %d istiod pods of revision %q is ready", len(pods.Items), revision)
+}
+
 // GetWebhookTagName extracts tag name from webhook object.
 func GetWebhookTagName(wh admitv1.MutatingWebhookConfiguration) (string, error) {
 	if tagName, ok := wh.ObjectMeta.Labels[IstioTagLabel]; ok {
@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tag
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/api/label"
+)
+
+func istiodPod(name, revision string, ready bool) *corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "istio-system",
+			Labels:    map[string]string{"app": "istiod", label.IoIstioRev.Name: revision},
+		},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}},
+	}
+}
+
+func TestCheckRevisionHealthy(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		istiodPod("istiod-1-17-0", "1-17-0", true),
+		istiodPod("istiod-1-18-0-a", "1-18-0", false),
+		istiodPod("istiod-1-18-0-b", "1-18-0", false),
+	)
+	cases := []struct {
+		revision string
+		error    string
+	}{
+		{revision: "1-17-0"},
+		{revision: "1-18-0", error: "none of the 2 istiod pods of revision \"1-18-0\" is ready"},
+		{revision: "1-19-0", error: "revision \"1-19-0\" has no istiod pod in namespace \"istio-system\""},
+	}
+	for _, c := range cases {
+		t.Run(c.revision, func(t *testing.T) {
+			err := CheckRevisionHealthy(context.Background(), client, "istio-system", c.revision)
+			if c.error == "" && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if c.error != "" && (err == nil || !strings.Contains(err.Error(), c.error)) {
+				t.Fatalf("expected error %q, got %v", c.error, err)
+			}
+		})
+	}
+}
+
+func TestGetOrphanedNamespaces(t *testing.T) {
+	webhook := func(name string, labels map[string]string) *admitv1.MutatingWebhookConfiguration {
+		return &admitv1.MutatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+	}
+	namespace := func(name, rev string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{label.IoIstioRev.Name: rev}}}
+	}
+	client := fake.NewSimpleClientset(
+		webhook("istio-sidecar-injector-1-17-0", map[string]string{label.IoIstioRev.Name: "1-17-0"}),
+		webhook("istio-revision-tag-prod", map[string]string{label.IoIstioRev.Name: "1-17-0", IstioTagLabel: "prod"}),
+		namespace("by-revision", "1-17-0"),
+		namespace("by-tag", "prod"),
+		namespace("orphaned-a", "canary"),
+		namespace("orphaned-b", "canary"),
+	)
+	orphaned, err := GetOrphanedNamespaces(context.Background(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphaned) != 1 || strings.Join(orphaned["canary"], ",") != "orphaned-a,orphaned-b" {
+		t.Fatalf("unexpected orphaned namespaces %v", orphaned)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pilot/pkg/util/filterchainmatch"
+)
+
+// PrintFilterChainMatch prints the listener and the filter chain of the config dump that the connection matches,
+// and why the other filter chains of the listener are skipped.
+func (c *ConfigWriter) PrintFilterChainMatch(conn filterchainmatch.Connection, inbound bool, outputFormat string) error {
+	listeners, err := c.retrieveSortedListenerSlice()
+	if err != nil {
+		return err
+	}
+	l, err := filterchainmatch.SelectListener(listeners, conn, inbound)
+	if err != nil {
+		return err
+	}
+	res, err := filterchainmatch.Match(l, conn)
+	if err != nil {
+		return err
+	}
+	if outputFormat == "json" || outputFormat == "yaml" {
+		out, err := json.MarshalIndent(res, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal filter chain match: %v", err)
+		}
+		if outputFormat == "yaml" {
+			if out, err = yaml.JSONToYAML(out); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(c.Stdout, string(out))
+		return nil
+	}
+	return printFilterChainMatch(c.Stdout, res)
+}
+
+func printFilterChainMatch(out io.Writer, res filterchainmatch.Result) error {
+	w := new(tabwriter.Writer).Init(out, 0, 8, 1, ' ', 0)
+	fmt.Fprintf(w, "LISTENER:\t%s\n", res.Listener)
+	switch {
+	case res.FilterChain == "":
+		fmt.Fprintln(w, "FILTER CHAIN:\tnone, the connection is closed")
+	case res.Default:
+		fmt.Fprintf(w, "FILTER CHAIN:\t%s (default filter chain)\n", res.FilterChain)
+	default:
+		fmt.Fprintf(w, "FILTER CHAIN:\t%s\n", res.FilterChain)
+	}
+	if len(res.Skipped) > 0 {
+		fmt.Fprintln(w, "\nSKIPPED FILTER CHAIN\tREASON")
+		for _, s := range res.Skipped {
+			fmt.Fprintf(w, "%s\t%s\n", s.FilterChain, s.Reason)
+		}
+	}
+	return w.Flush()
+}
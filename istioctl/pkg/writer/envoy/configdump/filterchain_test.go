@@ -0,0 +1,68 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/util/filterchainmatch"
+)
+
+func TestPrintFilterChainMatch(t *testing.T) {
+	cases := []struct {
+		name string
+		res  filterchainmatch.Result
+		want string
+	}{
+		{
+			name: "matched",
+			res: filterchainmatch.Result{
+				Listener:    "virtualInbound",
+				FilterChain: "0.0.0.0_9080",
+				Skipped: []filterchainmatch.SkippedFilterChain{
+					{FilterChain: "0.0.0.0_8080", Reason: `destination port "9080" does not match 8080`},
+				},
+			},
+			want: `LISTENER:     virtualInbound
+FILTER CHAIN: 0.0.0.0_9080
+
+SKIPPED FILTER CHAIN REASON
+0.0.0.0_8080         destination port "9080" does not match 8080
+`,
+		},
+		{
+			name: "default",
+			res:  filterchainmatch.Result{Listener: "virtualOutbound", FilterChain: "PassthroughFilterChain", Default: true},
+			want: "LISTENER:     virtualOutbound\nFILTER CHAIN: PassthroughFilterChain (default filter chain)\n",
+		},
+		{
+			name: "closed",
+			res:  filterchainmatch.Result{Listener: "0.0.0.0_8443"},
+			want: "LISTENER:     0.0.0.0_8443\nFILTER CHAIN: none, the connection is closed\n",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			if err := printFilterChainMatch(out, c.res); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != c.want {
+				t.Fatalf("got:\n%s\nwant:\n%s", out.String(), c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	wasmfilter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/config/xds"
+	"istio.io/istio/pkg/wasm"
+)
+
+// Load states of the Wasm modules.
+const (
+	// WasmLoaded is the state of the modules loaded from a local file.
+	WasmLoaded = "loaded"
+	// WasmRemote is the state of the modules fetched by Envoy itself.
+	WasmRemote = "remote"
+	// WasmStale is the state of the modules whose last update could not be loaded. Envoy uses the previous module.
+	WasmStale = "stale"
+	// WasmFailOpen is the state of the fail open modules that could not be loaded, and are replaced by an allow
+	// all filter.
+	WasmFailOpen = "fail-open"
+	// WasmRejected is the state of the modules that could not be loaded, and were never sent to Envoy.
+	WasmRejected = "rejected"
+)
+
+// WasmModule is the load state of the Wasm module of an extension config.
+type WasmModule struct {
+	Name string `json:"name"`
+	// Source is the remote source of the module, or its local file if the source is not known.
+	Source string `json:"source,omitempty"`
+	// SHA256 is the checksum of the module loaded by Envoy, or the checksum requested for it.
+	SHA256   string `json:"sha256,omitempty"`
+	File     string `json:"file,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	FailOpen bool   `json:"failOpen,omitempty"`
+}
+
+// PrintWasmSummary prints a summary of the Wasm modules of the extension configs. The statuses reported by the
+// agent add the sources and fetch errors of the modules; they are not available from a config dump file.
+func (c *ConfigWriter) PrintWasmSummary(statuses []wasm.ModuleStatus) error {
+	modules, err := c.retrieveWasmModules(statuses)
+	if err != nil {
+		return err
+	}
+	w := new(tabwriter.Writer).Init(c.Stdout, 0, 8, 5, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tSOURCE\tSHA256\tERROR")
+	for _, m := range modules {
+		checksum := m.SHA256
+		if len(checksum) > 12 {
+			checksum = checksum[:12]
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", m.Name, m.Status, m.Source, checksum, m.Error)
+	}
+	return w.Flush()
+}
+
+// PrintWasm prints the Wasm modules of the extension configs in JSON or YAML.
+func (c *ConfigWriter) PrintWasm(statuses []wasm.ModuleStatus, outputFormat string) error {
+	modules, err := c.retrieveWasmModules(statuses)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(modules, "", "    ")
+	if err != nil {
+		return err
+	}
+	if outputFormat == "yaml" {
+		if out, err = yaml.JSONToYAML(out); err != nil {
+			return err
+		}
+	}
+	_, _ = fmt.Fprintln(c.Stdout, string(out))
+	return nil
+}
+
+func (c *ConfigWriter) retrieveWasmModules(statuses []wasm.ModuleStatus) ([]WasmModule, error) {
+	ecds, err := c.retrieveSortedEcds()
+	if err != nil {
+		return nil, err
+	}
+	reported := make(map[string]wasm.ModuleStatus, len(statuses))
+	for _, s := range statuses {
+		reported[s.Name] = s
+	}
+
+	modules := []WasmModule{}
+	for _, ec := range ecds {
+		status, isReported := reported[ec.GetName()]
+		delete(reported, ec.GetName())
+		var m WasmModule
+		switch ec.GetTypedConfig().GetTypeUrl() {
+		case xds.WasmHTTPFilterType:
+			filter := &wasmfilter.Wasm{}
+			if err := ec.GetTypedConfig().UnmarshalTo(filter); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal Wasm filter %s: %v", ec.GetName(), err)
+			}
+			m = WasmModule{Name: ec.GetName(), Status: WasmLoaded, FailOpen: filter.GetConfig().GetFailOpen()}
+			code := filter.GetConfig().GetVmConfig().GetCode()
+			if remote := code.GetRemote(); remote != nil {
+				m.Status = WasmRemote
+				m.Source = remote.GetHttpUri().GetUri()
+				m.SHA256 = remote.GetSha256()
+			} else if file := code.GetLocal().GetFilename(); file != "" {
+				// The agent caches the modules in files named after their checksum.
+				m.File = file
+				m.Source = file
+				m.SHA256 = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			}
+		case xds.RBACHTTPFilterType:
+			// Fail open modules which could not be loaded are replaced by an allow all filter with the same name.
+			if !isReported || status.Loaded() {
+				continue
+			}
+			m = WasmModule{Name: ec.GetName(), Status: WasmFailOpen, FailOpen: true}
+		default:
+			continue
+		}
+		if isReported {
+			mergeModuleStatus(&m, status)
+		}
+		modules = append(modules, m)
+	}
+
+	// The extension configs whose module could not be loaded are not sent to Envoy, unless they are fail open.
+	for _, s := range statuses {
+		if _, f := reported[s.Name]; !f || s.Loaded() {
+			continue
+		}
+		m := WasmModule{Name: s.Name, Status: WasmRejected, FailOpen: s.FailOpen}
+		mergeModuleStatus(&m, s)
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
+
+func mergeModuleStatus(m *WasmModule, s wasm.ModuleStatus) {
+	if s.URL != "" {
+		m.Source = s.URL
+	}
+	if m.SHA256 == "" {
+		m.SHA256 = s.SHA256
+	}
+	if !s.Loaded() {
+		if m.Status == WasmLoaded {
+			m.Status = WasmStale
+		}
+		m.Error = s.Status
+		if s.Error != "" {
+			m.Error += ": " + s.Error
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package configdump
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/wasm"
+)
+
+var wasmStatuses = []wasm.ModuleStatus{
+	{Name: "default.display-metadata", URL: "oci://example.com/display-metadata:1.0", Status: "success"},
+	{
+		Name:     "default.httpbin-rate-limiting",
+		URL:      "https://example.com/rate-limiting.wasm",
+		Status:   "fetch_failure",
+		Error:    "download failure",
+		Rejected: true,
+	},
+	{Name: "default.missing", URL: "oci://example.com/missing:latest", Status: "fetch_failure", Error: "not found", Rejected: true},
+}
+
+func TestPrintWasmSummary(t *testing.T) {
+	gotOut := &bytes.Buffer{}
+	cw := &ConfigWriter{Stdout: gotOut}
+	cd, _ := os.ReadFile("testdata/ecds/configdump.json")
+	cw.Prime(cd)
+	err := cw.PrintWasmSummary(wasmStatuses)
+	assert.NoError(t, err)
+
+	util.CompareContent(t, gotOut.Bytes(), "testdata/wasm/output.txt")
+}
+
+func TestRetrieveWasmModules(t *testing.T) {
+	cw := &ConfigWriter{Stdout: &bytes.Buffer{}}
+	cd, _ := os.ReadFile("testdata/ecds/configdump.json")
+	cw.Prime(cd)
+
+	// Without the statuses of the agent, only the modules of the config dump are known.
+	modules, err := cw.retrieveWasmModules(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, len(modules), 2)
+	for _, m := range modules {
+		assert.Equal(t, m.Status, WasmLoaded)
+		assert.Equal(t, m.Source, m.File)
+	}
+
+	modules, err = cw.retrieveWasmModules(wasmStatuses)
+	assert.NoError(t, err)
+	assert.Equal(t, modules[2], WasmModule{
+		Name:   "default.missing",
+		Source: "oci://example.com/missing:latest",
+		Status: WasmRejected,
+		Error:  "fetch_failure: not found",
+	})
+}
@@ -27,6 +27,7 @@ import (
 
 	"istio.io/istio/istioctl/pkg/multixds"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/xds"
 	xdsresource "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/pkg/log"
@@ -35,6 +36,8 @@ import (
 // StatusWriter enables printing of sync status using multiple []byte Istiod responses
 type StatusWriter struct {
 	Writer io.Writer
+	// Wide prints the config sizes, the last push duration and the last NACK error of each proxy.
+	Wide bool
 }
 
 type writerStatus struct {
@@ -46,6 +49,8 @@ type writerStatus struct {
 type XdsStatusWriter struct {
 	Writer                 io.Writer
 	InternalDebugAllIstiod bool
+	// Wide prints the config sizes, the last push duration and the last NACK error of each proxy.
+	Wide bool
 }
 
 type xdsWriterStatus struct {
@@ -58,6 +63,48 @@ type xdsWriterStatus struct {
 	routeStatus          string
 	endpointStatus       string
 	extensionconfigStaus string
+	configSizes          map[string]int
+	lastPushDuration     string
+	nackErrors           map[string]string
+}
+
+// statusHeader is the header of the sync status table.
+const statusHeader = "NAME\tCLUSTER\tCDS\tLDS\tEDS\tRDS\tECDS\tISTIOD\tVERSION"
+
+// wideStatusHeader is the header of the additional columns of the wide sync status table.
+const wideStatusHeader = "\tCDS SIZE\tLDS SIZE\tEDS SIZE\tRDS SIZE\tLAST PUSH\tLAST NACK"
+
+// maxNackLength is the length of the NACK errors printed in the wide sync status table.
+const maxNackLength = 80
+
+// wideColumns returns the additional columns of the wide sync status table.
+func wideColumns(sizes map[string]int, pushDuration string, nacks map[string]string) string {
+	columns := make([]string, 0, 6)
+	for _, t := range []string{"CDS", "LDS", "EDS", "RDS"} {
+		if size, f := sizes[t]; f {
+			columns = append(columns, util.ByteCount(size))
+		} else {
+			columns = append(columns, "-")
+		}
+	}
+	if pushDuration == "" {
+		pushDuration = "-"
+	}
+	columns = append(columns, pushDuration)
+	nack := "-"
+	types := make([]string, 0, len(nacks))
+	for t := range nacks {
+		types = append(types, t)
+	}
+	if len(types) > 0 {
+		sort.Strings(types)
+		nack = types[0] + ": " + strings.Join(strings.Fields(nacks[types[0]]), " ")
+		if len(nack) > maxNackLength {
+			nack = nack[:maxNackLength] + "..."
+		}
+	}
+	columns = append(columns, nack)
+	return "\t" + strings.Join(columns, "\t")
 }
 
 // PrintAll takes a slice of Pilot syncz responses and outputs them using a tabwriter
@@ -67,7 +114,7 @@ func (s *StatusWriter) PrintAll(statuses map[string][]byte) error {
 		return err
 	}
 	for _, status := range fullStatus {
-		if err := statusPrintln(w, status); err != nil {
+		if err := statusPrintln(w, status, s.Wide); err != nil {
 			return err
 		}
 	}
@@ -82,7 +129,7 @@ func (s *StatusWriter) PrintSingle(statuses map[string][]byte, proxyName string)
 	}
 	for _, status := range fullStatus {
 		if strings.Contains(status.ProxyID, proxyName) {
-			if err := statusPrintln(w, status); err != nil {
+			if err := statusPrintln(w, status, s.Wide); err != nil {
 				return err
 			}
 		}
@@ -92,7 +139,11 @@ func (s *StatusWriter) PrintSingle(statuses map[string][]byte, proxyName string)
 
 func (s *StatusWriter) setupStatusPrint(statuses map[string][]byte) (*tabwriter.Writer, []*writerStatus, error) {
 	w := new(tabwriter.Writer).Init(s.Writer, 0, 9, 5, ' ', 0)
-	_, _ = fmt.Fprintln(w, "NAME\tCLUSTER\tCDS\tLDS\tEDS\tRDS\tECDS\tISTIOD\tVERSION")
+	header := statusHeader
+	if s.Wide {
+		header += wideStatusHeader
+	}
+	_, _ = fmt.Fprintln(w, header)
 	fullStatus := make([]*writerStatus, 0, len(statuses))
 	for pilot, status := range statuses {
 		var ss []*writerStatus
@@ -114,7 +165,7 @@ func (s *StatusWriter) setupStatusPrint(statuses map[string][]byte) (*tabwriter.
 	return w, fullStatus, nil
 }
 
-func statusPrintln(w io.Writer, status *writerStatus) error {
+func statusPrintln(w io.Writer, status *writerStatus, wide bool) error {
 	clusterSynced := xdsStatus(status.ClusterSent, status.ClusterAcked)
 	listenerSynced := xdsStatus(status.ListenerSent, status.ListenerAcked)
 	routeSynced := xdsStatus(status.RouteSent, status.RouteAcked)
@@ -127,10 +178,14 @@ func statusPrintln(w io.Writer, status *writerStatus) error {
 		// but it is better than not providing any information.
 		version = status.ProxyVersion + "*"
 	}
-	_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+	extra := ""
+	if wide {
+		extra = wideColumns(status.ConfigSizes, status.LastPushDuration, status.NackErrors)
+	}
+	_, _ = fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v%v\n",
 		status.ProxyID, status.ClusterID,
 		clusterSynced, listenerSynced, endpointSynced, routeSynced, extensionconfigSynced,
-		status.pilot, version)
+		status.pilot, version, extra)
 	return nil
 }
 
@@ -156,7 +211,7 @@ func (s *XdsStatusWriter) PrintAll(statuses map[string]*discovery.DiscoveryRespo
 		return err
 	}
 	for _, status := range fullStatus {
-		if err := xdsStatusPrintln(w, status); err != nil {
+		if err := xdsStatusPrintln(w, status, s.Wide); err != nil {
 			return err
 		}
 	}
@@ -181,6 +236,7 @@ func (s *XdsStatusWriter) setupStatusPrint(drs map[string]*discovery.DiscoveryRe
 					return nil, nil, fmt.Errorf("could not unmarshal ClientConfig: %w", err)
 				}
 				cds, lds, eds, rds, ecds := getSyncStatus(&clientConfig)
+				sizes, pushDuration := getPushStats(&clientConfig)
 				cp := multixds.CpInfo(dr)
 				meta, err := model.ParseMetadata(clientConfig.GetNode().GetMetadata())
 				if err != nil {
@@ -196,13 +252,20 @@ func (s *XdsStatusWriter) setupStatusPrint(drs map[string]*discovery.DiscoveryRe
 					routeStatus:          rds,
 					endpointStatus:       eds,
 					extensionconfigStaus: ecds,
+					configSizes:          sizes,
+					lastPushDuration:     pushDuration,
+					nackErrors:           getNackErrors(&clientConfig),
 				})
 				if len(fullStatus) == 0 {
 					return nil, nil, fmt.Errorf("no proxies found (checked %d istiods)", len(drs))
 				}
 
 				w = new(tabwriter.Writer).Init(s.Writer, 0, 8, 5, ' ', 0)
-				_, _ = fmt.Fprintln(w, "NAME\tCLUSTER\tCDS\tLDS\tEDS\tRDS\tECDS\tISTIOD\tVERSION")
+				header := statusHeader
+				if s.Wide {
+					header += wideStatusHeader
+				}
+				_, _ = fmt.Fprintln(w, header)
 
 				sort.Slice(fullStatus, func(i, j int) bool {
 					return fullStatus[i].proxyID < fullStatus[j].proxyID
@@ -232,15 +295,46 @@ func (s *XdsStatusWriter) setupStatusPrint(drs map[string]*discovery.DiscoveryRe
 	return w, fullStatus, nil
 }
 
-func xdsStatusPrintln(w io.Writer, status *xdsWriterStatus) error {
-	_, err := fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+func xdsStatusPrintln(w io.Writer, status *xdsWriterStatus, wide bool) error {
+	extra := ""
+	if wide {
+		extra = wideColumns(status.configSizes, status.lastPushDuration, status.nackErrors)
+	}
+	_, err := fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v%v\n",
 		status.proxyID, status.clusterID,
 		status.clusterStatus, status.listenerStatus, status.endpointStatus, status.routeStatus,
 		status.extensionconfigStaus,
-		status.istiodID, status.istiodVersion)
+		status.istiodID, status.istiodVersion, extra)
 	return err
 }
 
+// getPushStats returns the config sizes and the last push duration of the node metadata of the ClientConfig.
+func getPushStats(clientConfig *xdsstatus.ClientConfig) (map[string]int, string) {
+	fields := clientConfig.GetNode().GetMetadata().GetFields()
+	var sizes map[string]int
+	for t, v := range fields[xds.ConfigSizesMetadataKey].GetStructValue().GetFields() {
+		if sizes == nil {
+			sizes = map[string]int{}
+		}
+		sizes[t] = int(v.GetNumberValue())
+	}
+	return sizes, fields[xds.LastPushDurationMetadataKey].GetStringValue()
+}
+
+// getNackErrors returns the last NACK error of each type of the ClientConfig, keyed by the short type.
+func getNackErrors(clientConfig *xdsstatus.ClientConfig) map[string]string {
+	var nacks map[string]string
+	for _, config := range clientConfig.GetGenericXdsConfigs() {
+		if details := config.GetErrorState().GetDetails(); details != "" {
+			if nacks == nil {
+				nacks = map[string]string{}
+			}
+			nacks[xdsresource.GetShortType(config.GetTypeUrl())] = details
+		}
+	}
+	return nacks
+}
+
 func getSyncStatus(clientConfig *xdsstatus.ClientConfig) (cds, lds, eds, rds, ecds string) {
 	configs := handleAndGetXdsConfigs(clientConfig)
 	for _, config := range configs {
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
@@ -25,6 +26,7 @@ import (
 	status "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
 	"github.com/google/uuid"
 	anypb "google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/util/protoconv"
@@ -101,6 +103,25 @@ func TestStatusWriter_PrintAll(t *testing.T) {
 	}
 }
 
+func TestStatusWriter_Wide(t *testing.T) {
+	ss := statusInput1()
+	ss[0].ConfigSizes = map[string]int{"CDS": 1500, "LDS": 800}
+	ss[0].LastPushDuration = "12ms"
+	ss[0].NackErrors = map[string]string{"LDS": "Error adding/updating listener(s) 0.0.0.0_80:\n duplicate filter chain match"}
+	b, _ := json.Marshal(ss)
+
+	got := &bytes.Buffer{}
+	sw := StatusWriter{Writer: got, Wide: true}
+	assert.NoError(t, sw.PrintAll(map[string][]byte{"istiod1": b}))
+	lines := strings.Split(strings.TrimSpace(got.String()), "\n")
+	assert.Equal(t, len(lines), 2)
+	assert.Equal(t, strings.Join(strings.Fields(lines[0]), " "),
+		"NAME CLUSTER CDS LDS EDS RDS ECDS ISTIOD VERSION CDS SIZE LDS SIZE EDS SIZE RDS SIZE LAST PUSH LAST NACK")
+	assert.Equal(t, strings.Join(strings.Fields(lines[1]), " "),
+		"proxy1 cluster1 STALE SYNCED SYNCED NOT SENT NOT SENT istiod1 1.1 1.5kB 800B - - 12ms "+
+			"LDS: Error adding/updating listener(s) 0.0.0.0_80: duplicate filter chain match")
+}
+
 func TestStatusWriter_PrintSingle(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -335,6 +356,31 @@ func TestXdsStatusWriter_PrintAll(t *testing.T) {
 	}
 }
 
+func TestXdsStatusWriter_Wide(t *testing.T) {
+	cc := newXdsClientConfig(clientConfigInput{
+		proxyID:       "proxy1",
+		clusterID:     "cluster1",
+		cdsSyncStatus: status.ConfigStatus_SYNCED,
+		ldsSyncStatus: status.ConfigStatus_STALE,
+	})
+	cc.Node.Metadata.Fields[xds.ConfigSizesMetadataKey] = structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+		"EDS": structpb.NewNumberValue(2_500_000),
+	}})
+	cc.Node.Metadata.Fields[xds.LastPushDurationMetadataKey] = structpb.NewStringValue("1.2s")
+	cc.GenericXdsConfigs[1].ErrorState = &status.UpdateFailureState{Details: strings.Repeat("x", 100)}
+	resp := xdsResponseInput("istiod1", nil)
+	resp.Resources = []*anypb.Any{protoconv.MessageToAny(cc)}
+
+	got := &bytes.Buffer{}
+	sw := XdsStatusWriter{Writer: got, Wide: true}
+	assert.NoError(t, sw.PrintAll(map[string]*discovery.DiscoveryResponse{"istiod1": resp}))
+	lines := strings.Split(strings.TrimSpace(got.String()), "\n")
+	assert.Equal(t, len(lines), 2)
+	nack := ("LDS: " + strings.Repeat("x", 100))[:maxNackLength] + "..."
+	assert.Equal(t, strings.Join(strings.Fields(lines[1]), " "),
+		"proxy1 cluster1 SYNCED STALE UNKNOWN UNKNOWN UNKNOWN istiod1 1.1 - - 2.5MB - 1.2s "+nack)
+}
+
 const clientConfigType = "type.googleapis.com/envoy.service.status.v3.ClientConfig"
 
 type clientConfigInput struct {
@@ -73,6 +73,9 @@ type InstallArgs struct {
 	ManifestsPath string
 	// Revision is the Istio control plane revision the command targets.
 	Revision string
+	// ThroughIstiod, if set, has istiod apply the IstioOperator, reconciled by the in-cluster operator, rather than
+	// installing its manifests with the Kubernetes permissions of the caller.
+	ThroughIstiod func(client kube.CLIClient, iop *v1alpha12.IstioOperator) error
 }
 
 func (a *InstallArgs) String() string {
@@ -193,6 +196,18 @@ func Install(rootArgs *RootArgs, iArgs *InstallArgs, logOpts *log.Options, stdOu
 		return fmt.Errorf("could not configure logs: %s", err)
 	}
 
+	if iArgs.ThroughIstiod != nil {
+		if rootArgs.DryRun {
+			l.LogAndPrint("Installing through istiod is not applicable in dry-run mode")
+			return nil
+		}
+		if err := iArgs.ThroughIstiod(kubeClient, iop); err != nil {
+			return fmt.Errorf("failed to install through istiod: %v", err)
+		}
+		p.Println("The IstioOperator was applied by istiod, and is installed by the in-cluster operator.")
+		return nil
+	}
+
 	// Detect whether previous installation exists prior to performing the installation.
 	exists := revtag.PreviousInstallExists(context.Background(), kubeClient.Kube())
 	rev := iop.Spec.Revision
@@ -18,9 +18,11 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"unicode"
 
+	"golang.org/x/exp/maps"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 	"k8s.io/apimachinery/pkg/util/intstr"
 
@@ -174,6 +176,7 @@ func validateFeatures(values *valuesv1alpha1.Values, spec *v1alpha1.IstioOperato
 	validators := []FeatureValidator{
 		CheckServicePorts,
 		CheckAutoScaleAndReplicaCount,
+		CheckArchImages,
 	}
 
 	for _, validator := range validators {
@@ -185,6 +188,41 @@ func validateFeatures(values *valuesv1alpha1.Values, spec *v1alpha1.IstioOperato
 	return
 }
 
+// CheckArchImages validates values.global.proxy.archImages and warns when pods may be scheduled to an
+// architecture that has no image.
+func CheckArchImages(values *valuesv1alpha1.Values, _ *v1alpha1.IstioOperatorSpec) (errs util.Errors, warnings []string) {
+	images := values.GetGlobal().GetProxy().GetArchImages()
+	if len(images) == 0 {
+		return
+	}
+	weights := map[string]uint32{
+		"amd64":   values.GetGlobal().GetArch().GetAmd64(),
+		"arm64":   values.GetGlobal().GetArch().GetArm64(),
+		"ppc64le": values.GetGlobal().GetArch().GetPpc64Le(),
+		"s390x":   values.GetGlobal().GetArch().GetS390X(),
+	}
+	for _, arch := range sortedKeys(images) {
+		if _, f := weights[arch]; !f {
+			errs = util.AppendErr(errs, fmt.Errorf("values.global.proxy.archImages has unknown architecture %q", arch))
+		} else if images[arch] == "" {
+			errs = util.AppendErr(errs, fmt.Errorf("values.global.proxy.archImages.%s must not be empty", arch))
+		}
+	}
+	for _, arch := range sortedKeys(weights) {
+		if weights[arch] > 0 && images[arch] == "" {
+			warnings = append(warnings, fmt.Sprintf("values.global.arch.%s is set but values.global.proxy.archImages has no image for %s; "+
+				"pods scheduled to %s nodes use the default proxy image", arch, arch, arch))
+		}
+	}
+	return
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := maps.Keys(m)
+	sort.Strings(keys)
+	return keys
+}
+
 // CheckAutoScaleAndReplicaCount warns when autoscaleEnabled is true and k8s replicaCount is set.
 func CheckAutoScaleAndReplicaCount(values *valuesv1alpha1.Values, spec *v1alpha1.IstioOperatorSpec) (errs util.Errors, warnings []string) {
 	if values.GetPilot().GetAutoscaleEnabled().GetValue() && spec.GetComponents().GetPilot().GetK8S().GetReplicaCount() > 1 {
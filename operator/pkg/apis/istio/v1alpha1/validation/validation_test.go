@@ -226,6 +226,22 @@ components.ingressGateways[name=istio-ingressgateway].k8s.replicaCount should no
 components.egressGateways[name=istio-egressgateway].k8s.replicaCount should not be set when values.gateways.istio-egressgateway.autoscaleEnabled is true
 `),
 		},
+		{
+			name: "arch images",
+			values: `
+values:
+  global:
+    arch:
+      amd64: 2
+      arm64: 2
+    proxy:
+      archImages:
+        arm64: docker.io/istio/proxyv2@sha256:0123
+        riscv64: docker.io/istio/proxyv2@sha256:4567
+`,
+			errors:   `values.global.proxy.archImages has unknown architecture "riscv64"`,
+			warnings: `values.global.arch.amd64 is set but values.global.proxy.archImages has no image for amd64; pods scheduled to amd64 nodes use the default proxy image`,
+		},
 		{
 			name: "pilot.k8s.replicaCount is default value set when autoscaleEnabled is true",
 			values: `
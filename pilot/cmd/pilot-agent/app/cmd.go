@@ -166,6 +166,9 @@ func newProxyCommand() *cobra.Command {
 
 			// On SIGINT or SIGTERM, cancel the context, triggering a graceful shutdown
 			go cmd.WaitSignalFunc(cancel)
+			if agentOptions.ExitOnApplicationExit {
+				go istio_agent.WatchApplicationExit(ctx, "/proc", agentOptions.ApplicationStartTimeout, cancel)
+			}
 
 			// Start in process SDS, dns server, xds proxy, and Envoy.
 			wait, err := agent.Run(ctx)
@@ -22,9 +22,11 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/bootstrap/platform"
+	dnsClient "istio.io/istio/pkg/dns/client"
 	istioagent "istio.io/istio/pkg/istio-agent"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/istio/pkg/wasm"
+	"istio.io/istio/security/pkg/rolesanywhere"
 )
 
 // Similar with ISTIO_META_, which is used to customize the node metadata - this customizes extra header.
@@ -47,28 +49,60 @@ func NewAgentOptions(proxy *model.Proxy, cfg *meshconfig.ProxyConfig) *istioagen
 			HTTPRequestTimeout:    wasmHTTPRequestTimeout,
 			HTTPRequestMaxRetries: wasmHTTPRequestMaxRetries,
 		},
-		ProxyIPAddresses:            proxy.IPAddresses,
-		ServiceNode:                 proxy.ServiceNode(),
-		EnvoyStatusPort:             envoyStatusPortEnv,
-		EnvoyPrometheusPort:         envoyPrometheusPortEnv,
-		MinimumDrainDuration:        minimumDrainDurationEnv,
-		ExitOnZeroActiveConnections: exitOnZeroActiveConnectionsEnv,
-		Platform:                    platform.Discover(proxy.SupportsIPv6()),
-		GRPCBootstrapPath:           grpcBootstrapEnv,
-		DisableEnvoy:                disableEnvoyEnv,
-		ProxyXDSDebugViaAgent:       proxyXDSDebugViaAgent,
-		ProxyXDSDebugViaAgentPort:   proxyXDSDebugViaAgentPort,
-		DNSCapture:                  DNSCaptureByAgent.Get(),
-		DNSForwardParallel:          DNSForwardParallel.Get(),
-		DNSAddr:                     DNSCaptureAddr.Get(),
-		ProxyNamespace:              PodNamespaceVar.Get(),
-		ProxyDomain:                 proxy.DNSDomain,
-		IstiodSAN:                   istiodSAN.Get(),
+		ProxyIPAddresses:             proxy.IPAddresses,
+		ServiceNode:                  proxy.ServiceNode(),
+		EnvoyStatusPort:              envoyStatusPortEnv,
+		EnvoyPrometheusPort:          envoyPrometheusPortEnv,
+		MinimumDrainDuration:         minimumDrainDurationEnv,
+		ExitOnZeroActiveConnections:  exitOnZeroActiveConnectionsEnv,
+		NativeSidecar:                nativeSidecarEnv,
+		ProxyTerminationGracePeriod:  proxyTerminationGracePeriodEnv,
+		ExitOnApplicationExit:        exitOnApplicationExitEnv,
+		ApplicationStartTimeout:      applicationStartTimeoutEnv,
+		ConnectionPoolMetrics:        connectionPoolMetricsEnv,
+		PrefetchWorkloadCertificates: prefetchWorkloadCertificatesEnv,
+		Platform:                     platform.Discover(proxy.SupportsIPv6()),
+		GRPCBootstrapPath:            grpcBootstrapEnv,
+		DisableEnvoy:                 disableEnvoyEnv,
+		ProxyXDSDebugViaAgent:        proxyXDSDebugViaAgent,
+		ProxyXDSDebugViaAgentPort:    proxyXDSDebugViaAgentPort,
+		DNSCapture:                   DNSCaptureByAgent.Get(),
+		DNSForwardParallel:           DNSForwardParallel.Get(),
+		DNSAddr:                      DNSCaptureAddr.Get(),
+		ProxyNamespace:               PodNamespaceVar.Get(),
+		ProxyDomain:                  proxy.DNSDomain,
+		IstiodSAN:                    istiodSAN.Get(),
+		IstiodFailoverAddresses:      splitAddresses(istiodFailoverAddresses.Get()),
+		DNSUpstreamPolicy: dnsClient.UpstreamPolicy{
+			ServeStale: DNSServeStale.Get(),
+			MinTTL:     DNSMinTTL.Get(),
+			MaxTTL:     DNSMaxTTL.Get(),
+		},
+	}
+	if awsRolesAnywhereTrustAnchorARNEnv != "" || awsRolesAnywhereProfileARNEnv != "" || awsRolesAnywhereRoleARNEnv != "" {
+		o.AWSRolesAnywhere = &rolesanywhere.Config{
+			TrustAnchorARN:  awsRolesAnywhereTrustAnchorARNEnv,
+			ProfileARN:      awsRolesAnywhereProfileARNEnv,
+			RoleARN:         awsRolesAnywhereRoleARNEnv,
+			SessionDuration: awsRolesAnywhereSessionDurationEnv,
+		}
+		o.AWSCredentialsPort = awsCredentialsPortEnv
 	}
 	extractXDSHeadersFromEnv(o)
 	return o
 }
 
+// splitAddresses splits a comma separated list of addresses, ignoring the empty ones.
+func splitAddresses(s string) []string {
+	var out []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
 // Simplified extraction of gRPC headers from environment.
 // Unlike ISTIO_META, where we need JSON and advanced features - this is just for small string headers.
 func extractXDSHeadersFromEnv(o *istioagent.AgentOptions) {
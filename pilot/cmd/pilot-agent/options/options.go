@@ -82,7 +82,11 @@ var (
 		"Whether to generate PKCS#8 private keys").Get()
 	eccSigAlgEnv        = env.Register("ECC_SIGNATURE_ALGORITHM", "", "The type of ECC signature algorithm to use when generating private keys").Get()
 	fileMountedCertsEnv = env.Register("FILE_MOUNTED_CERTS", false, "").Get()
-	credFetcherTypeEnv  = env.Register("CREDENTIAL_FETCHER_TYPE", security.JWT,
+	nodeProxyEnv        = env.Register("NODE_PROXY", false,
+		"If true, the SDS resources named after a SPIFFE identity are certificates requested on behalf of the host "+
+			"network pods of the node with this identity. The service account of the proxy must be in the "+
+			"CA_TRUSTED_NODE_ACCOUNTS of istiod.").Get()
+	credFetcherTypeEnv = env.Register("CREDENTIAL_FETCHER_TYPE", security.JWT,
 		"The type of the credential fetcher. Currently supported types include GoogleComputeEngine").Get()
 	credIdentityProvider = env.Register("CREDENTIAL_IDENTITY_PROVIDER", "GoogleComputeEngine",
 		"The identity provider for credential. Currently default supported identity provider is GoogleComputeEngine").Get()
@@ -101,6 +105,16 @@ var (
 	DNSForwardParallel = env.Register("DNS_FORWARD_PARALLEL", false,
 		"If set to true, agent will send parallel DNS queries to all upstream nameservers")
 
+	DNSServeStale = env.Register("DNS_SERVE_STALE", time.Duration(0),
+		"If set, the DNS proxy caches the upstream responses, and answers with them for this long past their TTL "+
+			"when all the upstream nameservers fail. Disabled by default")
+
+	DNSMinTTL = env.Register("DNS_MIN_TTL", time.Duration(0),
+		"If set, the TTL of the records of the upstream DNS responses is raised to at least this value")
+
+	DNSMaxTTL = env.Register("DNS_MAX_TTL", time.Duration(0),
+		"If set, the TTL of the records of the upstream DNS responses is lowered to at most this value")
+
 	// Ability of istio-agent to retrieve proxyConfig via XDS for dynamic configuration updates
 	enableProxyConfigXdsEnv = env.Register("PROXY_CONFIG_XDS_AGENT", false,
 		"If set to true, agent retrieves dynamic proxy-config updates via xds channel").Get()
@@ -144,6 +158,11 @@ var (
 		"Override the ServerName used to validate Istiod certificate. "+
 			"Can be used as an alternative to setting /etc/hosts for VMs - discovery address will be an IP:port")
 
+	istiodFailoverAddresses = env.Register("ISTIOD_FAILOVER_ADDRESSES", "",
+		"Comma separated, ordered list of the addresses of the istiod instances the XDS proxy fails over to when the "+
+			"discovery address cannot be reached, such as the external control planes of a remote cluster. The proxy "+
+			"fails back to the discovery address once it can be reached again.")
+
 	minimumDrainDurationEnv = env.Register("MINIMUM_DRAIN_DURATION",
 		5*time.Second,
 		"The minimum duration for which agent waits before it checks for active connections and terminates proxy"+
@@ -152,4 +171,66 @@ var (
 	exitOnZeroActiveConnectionsEnv = env.Register("EXIT_ON_ZERO_ACTIVE_CONNECTIONS",
 		false,
 		"When set to true, terminates proxy when number of active connections become zero during draining").Get()
+
+	nativeSidecarEnv = env.Register("NATIVE_SIDECAR",
+		false,
+		"Set by the injector when the proxy runs as a Kubernetes native sidecar, which is only terminated once the "+
+			"app containers exit").Get()
+
+	proxyTerminationGracePeriodEnv = env.Register("PROXY_TERMINATION_GRACE_PERIOD",
+		time.Duration(0),
+		"The part of the termination grace period of the pod reserved to a native sidecar, for which the proxy drains "+
+			"once the app containers exit instead of the terminationDrainDuration").Get()
+
+	exitOnApplicationExitEnv = env.Register("EXIT_ON_APPLICATION_EXIT",
+		false,
+		"Set by the injector for the exitOnCompletion job policy: the proxy terminates once the processes of the "+
+			"app containers, visible in the process namespace shared with the pod, exit").Get()
+
+	applicationStartTimeoutEnv = env.Register("APPLICATION_START_TIMEOUT",
+		5*time.Minute,
+		"With EXIT_ON_APPLICATION_EXIT, the proxy also terminates if no process of the app containers was seen "+
+			"within this duration, as the app containers may have exited before being seen. Set to 0 to wait forever").Get()
+
+	connectionPoolMetricsEnv = env.Register("ENABLE_CONNECTION_POOL_METRICS",
+		false,
+		"When set to true, the agent exposes the connection pool pressure of each upstream service as "+
+			"istio_upstream_connection_pool_* metrics, labeled with the destination service, port and subset. "+
+			"The Telemetry API disables them with a metrics override of the UPSTREAM_CONNECTION_POOL custom metric "+
+			"for the clients of its Prometheus providers").Get()
+
+	prefetchWorkloadCertificatesEnv = env.Register("PREFETCH_WORKLOAD_CERTIFICATES",
+		false,
+		"When set to true, the agent requests the workload certificates on startup and is not ready until they are "+
+			"issued. Combined with holdApplicationUntilProxyStarts, the application starts once the certificates and the "+
+			"initial configuration are in place, so that its first outbound calls do not race the proxy startup.").Get()
+
+	envoyAdminGatewayPolicyEnv = env.Register("ENVOY_ADMIN_GATEWAY_POLICY",
+		"",
+		"If set, the stats, config_dump and clusters Envoy admin endpoints are served over TLS, with the workload "+
+			"certificate, on ENVOY_ADMIN_GATEWAY_PORT under "+
+			"/admin/, to callers presenting a Kubernetes service account token issued for the istio-envoy-admin audience "+
+			"and allowed by the policy, for example \"stats=monitoring/prometheus;config_dump,clusters=istio-system/*\". "+
+			"The service account of the pod must be allowed to create TokenReviews.").Get()
+
+	envoyAdminGatewayPortEnv = env.Register("ENVOY_ADMIN_GATEWAY_PORT", 15025,
+		"The port of the Envoy admin gateway. It must not be exposed by the Services of the gateways.").Get()
+
+	awsRolesAnywhereTrustAnchorARNEnv = env.Register("AWS_ROLES_ANYWHERE_TRUST_ANCHOR_ARN", "",
+		"If set with AWS_ROLES_ANYWHERE_PROFILE_ARN and AWS_ROLES_ANYWHERE_ROLE_ARN, the agent exchanges the workload "+
+			"certificate for AWS credentials with IAM Roles Anywhere, using this trust anchor of the mesh CA. The credentials "+
+			"are served on localhost:AWS_CREDENTIALS_PORT/credentials, for the AWS SDKs configured with "+
+			"AWS_CONTAINER_CREDENTIALS_FULL_URI.").Get()
+
+	awsRolesAnywhereProfileARNEnv = env.Register("AWS_ROLES_ANYWHERE_PROFILE_ARN", "",
+		"The IAM Roles Anywhere profile used to exchange the workload certificate for AWS credentials.").Get()
+
+	awsRolesAnywhereRoleARNEnv = env.Register("AWS_ROLES_ANYWHERE_ROLE_ARN", "",
+		"The IAM role assumed with the workload certificate.").Get()
+
+	awsRolesAnywhereSessionDurationEnv = env.Register("AWS_ROLES_ANYWHERE_SESSION_DURATION", time.Duration(0),
+		"The duration of the AWS credentials. If unset, the duration of the IAM Roles Anywhere profile is used.").Get()
+
+	awsCredentialsPortEnv = env.Register("AWS_CREDENTIALS_PORT", 15024,
+		"The local port serving the AWS credentials exchanged with IAM Roles Anywhere.").Get()
 )
@@ -17,7 +17,6 @@ package options
 import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
-	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
 	"istio.io/istio/pilot/pkg/model"
 	istioagent "istio.io/istio/pkg/istio-agent"
 )
@@ -30,9 +29,15 @@ func NewStatusServerOptions(proxy *model.Proxy, proxyConfig *meshconfig.ProxyCon
 		StatusPort:     uint16(proxyConfig.StatusPort),
 		KubeAppProbers: kubeAppProberNameVar.Get(),
 		NodeType:       proxy.Type,
-		Probes:         []ready.Prober{agent},
+		Components:     agent.HealthComponents(),
 		NoEnvoy:        agent.EnvoyDisabled(),
 		FetchDNS:       agent.GetDNSTable,
 		GRPCBootstrap:  agent.GRPCBootstrapPath(),
+
+		ConnectionPoolMetrics:   connectionPoolMetricsEnv,
+		EnvoyAdminGatewayPolicy: envoyAdminGatewayPolicyEnv,
+		EnvoyAdminGatewayPort:   uint16(envoyAdminGatewayPortEnv),
+
+		EnvoyAdminGatewayCertificate: agent.WorkloadCertificate,
 	}
 }
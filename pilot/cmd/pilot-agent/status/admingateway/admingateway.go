@@ -0,0 +1,211 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package admingateway exposes a read-only subset of the Envoy admin endpoints on a dedicated agent port, for
+// remote callers authenticated with a Kubernetes service account token and authorized per endpoint class.
+package admingateway
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/security/pkg/k8s/tokenreview"
+	"istio.io/pkg/log"
+)
+
+const (
+	// PathPrefix is the path prefix of the admin endpoints.
+	PathPrefix = "/admin/"
+	// Audience is the audience of the tokens accepted by the gateway. The tokens issued for other audiences, such as
+	// the tokens of the workloads for the CA, cannot be replayed against the proxies.
+	Audience = "istio-envoy-admin"
+)
+
+// Endpoint classes, each authorized separately.
+const (
+	ClassStats      = "stats"
+	ClassConfigDump = "config_dump"
+	ClassClusters   = "clusters"
+)
+
+// endpointClasses maps the exposed Envoy admin endpoints to their class. Any other endpoint is rejected.
+var endpointClasses = map[string]string{
+	"stats":            ClassStats,
+	"stats/prometheus": ClassStats,
+	"config_dump":      ClassConfigDump,
+	"clusters":         ClassClusters,
+}
+
+var auditLog = log.RegisterScope("admingateway", "Audit log of the Envoy admin gateway", 0)
+
+// Authenticator authenticates a bearer token, returning the namespace and name of its service account.
+type Authenticator func(token string) (namespace, serviceAccount string, err error)
+
+// NewTokenReviewAuthenticator returns an Authenticator validating service account tokens issued for the Audience
+// with the Kubernetes TokenReview API. The service account of the pod must be allowed to create TokenReviews.
+func NewTokenReviewAuthenticator(client kubernetes.Interface) Authenticator {
+	return func(token string) (string, string, error) {
+		id, err := tokenreview.ValidateK8sJwt(client, token, []string{Audience})
+		if err != nil {
+			return "", "", err
+		}
+		if len(id) != 2 {
+			return "", "", fmt.Errorf("unexpected service account identity %v", id)
+		}
+		return id[0], id[1], nil
+	}
+}
+
+// Policy maps endpoint classes to the service accounts allowed to call them.
+type Policy map[string][]subject
+
+type subject struct {
+	namespace      string
+	serviceAccount string
+}
+
+func (s subject) matches(namespace, serviceAccount string) bool {
+	return s.namespace == namespace && (s.serviceAccount == "*" || s.serviceAccount == serviceAccount)
+}
+
+// ParsePolicy parses a policy in the form "<class>[,<class>]=<namespace>/<serviceaccount>[,...];...", for example
+// "stats=monitoring/prometheus;config_dump,clusters=istio-system/*". "*" allows all the service accounts of a namespace.
+func ParsePolicy(s string) (Policy, error) {
+	p := Policy{}
+	for _, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		classes, subjects, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rule %q, must be in the form <class>=<namespace>/<serviceaccount>", rule)
+		}
+		var parsed []subject
+		for _, sa := range strings.Split(subjects, ",") {
+			ns, name, ok := strings.Cut(strings.TrimSpace(sa), "/")
+			if !ok || ns == "" || name == "" {
+				return nil, fmt.Errorf("invalid service account %q, must be in the form <namespace>/<serviceaccount>", sa)
+			}
+			parsed = append(parsed, subject{namespace: ns, serviceAccount: name})
+		}
+		for _, class := range strings.Split(classes, ",") {
+			class = strings.TrimSpace(class)
+			switch class {
+			case ClassStats, ClassConfigDump, ClassClusters:
+			default:
+				return nil, fmt.Errorf("invalid endpoint class %q, must be one of %s, %s or %s", class, ClassStats, ClassConfigDump, ClassClusters)
+			}
+			p[class] = append(p[class], parsed...)
+		}
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("policy does not allow any endpoint")
+	}
+	return p, nil
+}
+
+func (p Policy) allowed(class, namespace, serviceAccount string) bool {
+	for _, s := range p[class] {
+		if s.matches(namespace, serviceAccount) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler serves the admin endpoints allowed by the policy, by forwarding the requests to the Envoy admin port.
+type Handler struct {
+	policy       Policy
+	authenticate Authenticator
+	adminURL     string
+	client       *http.Client
+}
+
+// NewHandler creates a Handler forwarding to the Envoy admin port on the given local address.
+func NewHandler(policy Policy, authenticate Authenticator, localHostAddr string, adminPort uint16) *Handler {
+	return &Handler{
+		policy:       policy,
+		authenticate: authenticate,
+		adminURL:     "http://" + net.JoinHostPort(localHostAddr, strconv.Itoa(int(adminPort))) + "/",
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint := strings.TrimPrefix(r.URL.Path, PathPrefix)
+	identity := "-"
+	status := h.serve(w, r, endpoint, &identity)
+	auditLog.Infof("envoy admin request: identity=%s remote=%s method=%s endpoint=%s query=%q status=%d",
+		identity, r.RemoteAddr, r.Method, endpoint, r.URL.RawQuery, status)
+}
+
+// serve handles the request and returns the response status, for the audit log.
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, endpoint string, identity *string) int {
+	class, f := endpointClasses[endpoint]
+	if !f {
+		http.Error(w, fmt.Sprintf("endpoint %q is not exposed", endpoint), http.StatusNotFound)
+		return http.StatusNotFound
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET requests are allowed", http.StatusMethodNotAllowed)
+		return http.StatusMethodNotAllowed
+	}
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth || token == "" {
+		http.Error(w, "a bearer token is required", http.StatusUnauthorized)
+		return http.StatusUnauthorized
+	}
+	ns, sa, err := h.authenticate(token)
+	if err != nil {
+		auditLog.Debugf("authentication failed: %v", err)
+		http.Error(w, "authentication failed", http.StatusUnauthorized)
+		return http.StatusUnauthorized
+	}
+	*identity = ns + "/" + sa
+	if !h.policy.allowed(class, ns, sa) {
+		http.Error(w, fmt.Sprintf("%s is not allowed to access %s endpoints", *identity, class), http.StatusForbidden)
+		return http.StatusForbidden
+	}
+
+	target := h.adminURL + endpoint
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return http.StatusInternalServerError
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to reach envoy: %v", err), http.StatusBadGateway)
+		return http.StatusBadGateway
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+	return resp.StatusCode
+}
@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package admingateway
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	p, err := ParsePolicy("stats=monitoring/prometheus; config_dump,clusters=istio-system/*,default/debugger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		class, ns, sa string
+		want          bool
+	}{
+		{ClassStats, "monitoring", "prometheus", true},
+		{ClassStats, "monitoring", "grafana", false},
+		{ClassConfigDump, "istio-system", "anything", true},
+		{ClassClusters, "default", "debugger", true},
+		{ClassConfigDump, "monitoring", "prometheus", false},
+	}
+	for _, c := range cases {
+		if got := p.allowed(c.class, c.ns, c.sa); got != c.want {
+			t.Errorf("allowed(%s, %s/%s) = %v, want %v", c.class, c.ns, c.sa, got, c.want)
+		}
+	}
+
+	for _, invalid := range []string{"", "stats", "logging=istio-system/*", "stats=prometheus", "stats=/prometheus"} {
+		if _, err := ParsePolicy(invalid); err == nil {
+			t.Errorf("expected policy %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	envoy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, "%s %s?%s", r.Method, r.URL.Path, r.URL.RawQuery)
+	}))
+	defer envoy.Close()
+	host, port, _ := net.SplitHostPort(envoy.Listener.Addr().String())
+	adminPort, _ := strconv.Atoi(port)
+
+	policy, err := ParsePolicy("stats=monitoring/prometheus;config_dump=istio-system/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticate := func(token string) (string, string, error) {
+		switch token {
+		case "prometheus":
+			return "monitoring", "prometheus", nil
+		case "istiod":
+			return "istio-system", "istiod", nil
+		}
+		return "", "", fmt.Errorf("invalid token")
+	}
+	h := NewHandler(policy, authenticate, host, uint16(adminPort))
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		body   string
+	}{
+		{name: "allowed stats", path: "/admin/stats/prometheus?usedonly", token: "prometheus", status: 200, body: "GET /stats/prometheus?usedonly"},
+		{name: "allowed config_dump", path: "/admin/config_dump", token: "istiod", status: 200, body: "GET /config_dump?"},
+		{name: "forbidden class", path: "/admin/config_dump", token: "prometheus", status: 403},
+		{name: "not in policy", path: "/admin/clusters", token: "istiod", status: 403},
+		{name: "not exposed", path: "/admin/quitquitquit", token: "istiod", status: 404},
+		{name: "post", method: "POST", path: "/admin/stats", token: "prometheus", status: 405},
+		{name: "no token", path: "/admin/stats", status: 401},
+		{name: "invalid token", path: "/admin/stats", token: "invalid", status: 401},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+			if tt.body != "" {
+				b, _ := io.ReadAll(rec.Body)
+				if string(b) != tt.body {
+					t.Fatalf("got body %q, want %q", string(b), tt.body)
+				}
+			}
+		})
+	}
+}
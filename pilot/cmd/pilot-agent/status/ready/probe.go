@@ -43,6 +43,21 @@ type Prober interface {
 
 var _ Prober = &Probe{}
 
+// ProberFunc adapts a function to a Prober.
+type ProberFunc func() error
+
+// Check executes the probe function.
+func (f ProberFunc) Check() error {
+	return f()
+}
+
+// Component is the readiness check of a sidecar subsystem, reported on its own in the health status.
+type Component struct {
+	// Name of the subsystem, for example "envoy" or "dns".
+	Name   string
+	Prober Prober
+}
+
 // Check executes the probe and returns an error if the probe fails.
 func (p *Probe) Check() error {
 	// First, check that Envoy has received a configuration update from Pilot.
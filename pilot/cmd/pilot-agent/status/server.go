@@ -46,14 +46,19 @@ import (
 	grpcHealth "google.golang.org/grpc/health/grpc_health_v1"
 	grpcStatus "google.golang.org/grpc/status"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 
 	"istio.io/istio/pilot/cmd/pilot-agent/metrics"
+	"istio.io/istio/pilot/cmd/pilot-agent/status/admingateway"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/grpcready"
 	"istio.io/istio/pilot/cmd/pilot-agent/status/ready"
+	"istio.io/istio/pilot/cmd/pilot-agent/status/util"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 	"istio.io/istio/pkg/kube/apimirror"
+	"istio.io/istio/pkg/wasm"
 	"istio.io/pkg/env"
 	"istio.io/pkg/log"
 )
@@ -61,8 +66,12 @@ import (
 const (
 	// readyPath is for the pilot agent readiness itself.
 	readyPath = "/healthz/ready"
+	// healthStatusPath serves the readiness of each sidecar subsystem as a JSON document.
+	healthStatusPath = "/healthz/status"
 	// quitPath is to notify the pilot agent to quit.
 	quitPath = "/quitquitquit"
+	// WasmDebugPath serves the load state of the Wasm modules as a JSON document.
+	WasmDebugPath = "/debug/wasmz"
 	// KubeAppProberEnvName is the name of the command line flag for pilot agent to pass app prober config.
 	// The json encoded string to pass app HTTP probe information from injector(istioctl or webhook).
 	// For example, ISTIO_KUBE_APP_PROBERS='{"/app-health/httpbin/livez":{"httpGet":{"path": "/hello", "port": 8080}}.
@@ -128,11 +137,24 @@ type Options struct {
 	FetchDNS            func() *dnsProto.NameTable
 	NoEnvoy             bool
 	GRPCBootstrap       string
+	// Components are the readiness checks of the sidecar subsystems, reported on their own in the health status.
+	Components []ready.Component
+	// ConnectionPoolMetrics enables the per upstream service connection pool metrics.
+	ConnectionPoolMetrics bool
+	// EnvoyAdminGatewayPolicy, if set, exposes a subset of the Envoy admin endpoints to the service accounts
+	// allowed by the policy. See admingateway.ParsePolicy for the format.
+	EnvoyAdminGatewayPolicy string
+	// EnvoyAdminGatewayPort is the port of the Envoy admin gateway. Unlike the status port, it is not exposed by the
+	// gateway Services.
+	EnvoyAdminGatewayPort uint16
+	// EnvoyAdminGatewayCertificate returns the certificate of the Envoy admin gateway, which is only served over
+	// TLS so that the bearer tokens of the callers are not sent in cleartext.
+	EnvoyAdminGatewayCertificate func() (*tls.Certificate, error)
 }
 
 // Server provides an endpoint for handling status probes.
 type Server struct {
-	ready                 []ready.Prober
+	ready                 []ready.Component
 	prometheus            *PrometheusScrapeConfiguration
 	mutex                 sync.RWMutex
 	appProbersDestination string
@@ -144,6 +166,7 @@ type Server struct {
 	fetchDNS              func() *dnsProto.NameTable
 	upstreamLocalAddress  *net.TCPAddr
 	config                Options
+	envoyAdminGateway     http.Handler
 }
 
 func init() {
@@ -177,21 +200,24 @@ func NewServer(config Options) (*Server, error) {
 			upstreamLocalAddress = UpstreamLocalAddressIPv6
 		}
 	}
-	probes := make([]ready.Prober, 0)
+	probes := make([]ready.Component, 0)
 	if !config.NoEnvoy {
-		probes = append(probes, &ready.Probe{
+		probes = append(probes, ready.Component{Name: "envoy", Prober: &ready.Probe{
 			LocalHostAddr: localhost,
 			AdminPort:     config.AdminPort,
 			Context:       config.Context,
 			NoEnvoy:       config.NoEnvoy,
-		})
+		}})
 	}
 
 	if config.GRPCBootstrap != "" {
-		probes = append(probes, grpcready.NewProbe(config.GRPCBootstrap))
+		probes = append(probes, ready.Component{Name: "grpc", Prober: grpcready.NewProbe(config.GRPCBootstrap)})
 	}
 
-	probes = append(probes, config.Probes...)
+	for _, p := range config.Probes {
+		probes = append(probes, ready.Component{Name: "agent", Prober: p})
+	}
+	probes = append(probes, config.Components...)
 	s := &Server{
 		statusPort:            config.StatusPort,
 		ready:                 probes,
@@ -205,6 +231,29 @@ func NewServer(config Options) (*Server, error) {
 		s.appProbersDestination = "localhost"
 	}
 
+	if config.EnvoyAdminGatewayPolicy != "" && !config.NoEnvoy {
+		policy, err := admingateway.ParsePolicy(config.EnvoyAdminGatewayPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid envoy admin gateway policy: %v", err)
+		}
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("envoy admin gateway requires the Kubernetes API: %v", err)
+		}
+		client, err := kubernetes.NewForConfig(restConfig)
+		if err != nil {
+			return nil, err
+		}
+		if config.EnvoyAdminGatewayPort == 0 || config.EnvoyAdminGatewayPort == config.StatusPort {
+			return nil, fmt.Errorf("envoy admin gateway requires a port other than the status port")
+		}
+		if config.EnvoyAdminGatewayCertificate == nil {
+			return nil, fmt.Errorf("envoy admin gateway requires a certificate")
+		}
+		s.envoyAdminGateway = admingateway.NewHandler(policy, admingateway.NewTokenReviewAuthenticator(client), localhost, config.AdminPort)
+		log.Infof("Envoy admin gateway enabled on port %d with policy %q", config.EnvoyAdminGatewayPort, config.EnvoyAdminGatewayPolicy)
+	}
+
 	// Enable prometheus server if its configured and a sidecar
 	// Because port 15020 is exposed in the gateway Services, we cannot safely serve this endpoint
 	// If we need to do this in the future, we should use envoy to do routing or have another port to make this internal
@@ -341,6 +390,7 @@ func (s *Server) Run(ctx context.Context) {
 
 	// Add the handler for ready probes.
 	mux.HandleFunc(readyPath, s.handleReadyProbe)
+	mux.HandleFunc(healthStatusPath, s.handleHealthStatus)
 	// Default path for prom
 	mux.HandleFunc(`/metrics`, s.handleStats)
 	// Envoy uses something else - and original agent used the same.
@@ -356,6 +406,7 @@ func (s *Server) Run(ctx context.Context) {
 	mux.HandleFunc("/debug/pprof/symbol", s.handlePprofSymbol)
 	mux.HandleFunc("/debug/pprof/trace", s.handlePprofTrace)
 	mux.HandleFunc("/debug/ndsz", s.handleNdsz)
+	mux.HandleFunc(WasmDebugPath, s.handleWasmz)
 
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.statusPort))
 	if err != nil {
@@ -387,11 +438,43 @@ func (s *Server) Run(ctx context.Context) {
 		}
 	}()
 
+	if s.envoyAdminGateway != nil {
+		go s.runEnvoyAdminGateway(ctx)
+	}
+
 	// Wait for the agent to be shut down.
 	<-ctx.Done()
 	log.Info("Status server has successfully terminated")
 }
 
+// runEnvoyAdminGateway serves the Envoy admin gateway over TLS on its own port, so that it is not reachable through
+// the gateway Services exposing the status port.
+func (s *Server) runEnvoyAdminGateway(ctx context.Context) {
+	mux := http.NewServeMux()
+	mux.Handle(admingateway.PathPrefix, s.envoyAdminGateway)
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.EnvoyAdminGatewayPort))
+	if err != nil {
+		log.Errorf("Error listening on envoy admin gateway port: %v", err)
+		return
+	}
+	server := &http.Server{
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return s.config.EnvoyAdminGatewayCertificate()
+			},
+		},
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close()
+	}()
+	if err := server.ServeTLS(l, "", ""); err != nil && err != http.ErrServerClosed {
+		log.Errorf("Error serving envoy admin gateway: %v", err)
+	}
+}
+
 func (s *Server) handlePprofIndex(w http.ResponseWriter, r *http.Request) {
 	if !isRequestFromLocalhost(r) {
 		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
@@ -442,6 +525,8 @@ func (s *Server) handleReadyProbe(w http.ResponseWriter, _ *http.Request) {
 	s.mutex.Lock()
 	if err != nil {
 		w.WriteHeader(http.StatusServiceUnavailable)
+		// Report the failing subsystems, so that they show up distinctly in the probe output.
+		_, _ = w.Write([]byte(err.Error()))
 
 		log.Warnf("Envoy proxy is NOT ready: %s", err.Error())
 		s.lastProbeSuccessful = false
@@ -457,12 +542,61 @@ func (s *Server) handleReadyProbe(w http.ResponseWriter, _ *http.Request) {
 }
 
 func (s *Server) isReady() error {
-	for _, p := range s.ready {
-		if err := p.Check(); err != nil {
-			return err
+	status := s.healthStatus()
+	if status.Ready {
+		return nil
+	}
+	var failures []string
+	for _, c := range status.Components {
+		if !c.Ready {
+			failures = append(failures, c.Name+": "+c.Message)
 		}
 	}
-	return nil
+	return errors.New(strings.Join(failures, "; "))
+}
+
+// HealthStatus is the health document of the sidecar, with the readiness of each of its subsystems.
+type HealthStatus struct {
+	Ready      bool              `json:"ready"`
+	Components []ComponentStatus `json:"components"`
+}
+
+// ComponentStatus is the readiness of a sidecar subsystem.
+type ComponentStatus struct {
+	Name    string `json:"name"`
+	Ready   bool   `json:"ready"`
+	Message string `json:"message,omitempty"`
+}
+
+// healthStatus runs all the readiness checks, so that every failing subsystem is reported.
+func (s *Server) healthStatus() HealthStatus {
+	status := HealthStatus{Ready: true, Components: make([]ComponentStatus, 0, len(s.ready))}
+	for _, c := range s.ready {
+		cs := ComponentStatus{Name: c.Name, Ready: true}
+		if err := c.Prober.Check(); err != nil {
+			cs.Ready = false
+			cs.Message = err.Error()
+			status.Ready = false
+		}
+		status.Components = append(status.Components, cs)
+	}
+	return status
+}
+
+func (s *Server) handleHealthStatus(w http.ResponseWriter, _ *http.Request) {
+	status := s.healthStatus()
+	b, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if status.Ready {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_, _ = w.Write(b)
 }
 
 func isRequestFromLocalhost(r *http.Request) bool {
@@ -545,6 +679,13 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		metrics.AgentScrapeErrors.Increment()
 	}
 
+	if s.config.ConnectionPoolMetrics && !s.config.NoEnvoy {
+		if err = s.writeConnectionPoolMetrics(w); err != nil {
+			log.Errorf("failed scraping and writing connection pool metrics: %v", err)
+			metrics.EnvoyScrapeErrors.Increment()
+		}
+	}
+
 	if envoy != nil {
 		_, err = io.Copy(w, envoy)
 		if err != nil {
@@ -587,6 +728,23 @@ func scrapeAndWriteAgentMetrics(w io.Writer) error {
 	return errs
 }
 
+// writeConnectionPoolMetrics writes the connection pool pressure of each upstream service. Unlike the raw
+// Envoy circuit breaker stats, these are labeled with the destination service, port and subset.
+func (s *Server) writeConnectionPoolMetrics(w io.Writer) error {
+	stats, err := util.GetConnectionPoolStats("", s.config.AdminPort)
+	if err != nil {
+		return err
+	}
+	enc := expfmt.NewEncoder(w, expfmt.FmtText)
+	var errs error
+	for _, mf := range util.ConnectionPoolMetrics(stats) {
+		if err := enc.Encode(mf); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
 func applyHeaders(into http.Header, from http.Header, keys ...string) {
 	for _, key := range keys {
 		val := from.Get(key)
@@ -853,6 +1011,15 @@ func (s *Server) handleNdsz(w http.ResponseWriter, r *http.Request) {
 	writeJSONProto(w, nametable)
 }
 
+// handleWasmz reports the load state of the Wasm modules of the extension configs.
+func (s *Server) handleWasmz(w http.ResponseWriter, r *http.Request) {
+	if !isRequestFromLocalhost(r) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	writeJSONProto(w, wasm.ModuleStatuses())
+}
+
 // writeJSONProto writes a protobuf to a json payload, handling content type, marshaling, and errors
 func writeJSONProto(w http.ResponseWriter, obj any) {
 	w.Header().Set("Content-Type", "application/json")
@@ -1505,12 +1505,12 @@ func TestAdditionalProbes(t *testing.T) {
 		{
 			name:   "not ready probe",
 			probes: []ready.Prober{urp},
-			err:    errors.New("not ready"),
+			err:    errors.New("agent: not ready"),
 		},
 		{
 			name:   "both probes",
 			probes: []ready.Prober{rp, urp},
-			err:    errors.New("not ready"),
+			err:    errors.New("agent: not ready"),
 		},
 	}
 	testServer := testserver.CreateAndStartServer(liveServerStats)
@@ -1537,6 +1537,53 @@ func TestAdditionalProbes(t *testing.T) {
 	}
 }
 
+func TestHealthStatus(t *testing.T) {
+	testServer := testserver.CreateAndStartServer(liveServerStats)
+	defer testServer.Close()
+	server, err := NewServer(Options{
+		AdminPort: uint16(testServer.Listener.Addr().(*net.TCPAddr).Port),
+		Components: []ready.Component{
+			{Name: "dns", Prober: readyProbe{}},
+			{Name: "sds", Prober: unreadyProbe{}},
+			{Name: "wasm", Prober: ready.ProberFunc(func() error { return errors.New("failed to load Wasm modules: auth") })},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := server.isReady(); err == nil || err.Error() != "sds: not ready; wasm: failed to load Wasm modules: auth" {
+		t.Fatalf("unexpected readiness error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	server.handleHealthStatus(rec, httptest.NewRequest(http.MethodGet, healthStatusPath, nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+	var got HealthStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := HealthStatus{
+		Ready: false,
+		Components: []ComponentStatus{
+			{Name: "envoy", Ready: true},
+			{Name: "dns", Ready: true},
+			{Name: "sds", Ready: false, Message: "not ready"},
+			{Name: "wasm", Ready: false, Message: "failed to load Wasm modules: auth"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected health status: %+v", got)
+	}
+
+	rec = httptest.NewRecorder()
+	server.handleReadyProbe(rec, httptest.NewRequest(http.MethodGet, readyPath, nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "sds: not ready") {
+		t.Fatalf("unexpected readiness response %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
 type readyProbe struct{}
 
 func (s readyProbe) Check() error {
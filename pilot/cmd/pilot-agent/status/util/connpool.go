@@ -0,0 +1,243 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"istio.io/istio/pkg/http"
+)
+
+// ConnectionPoolStatsRegex selects the circuit breaker and overflow stats of all clusters. It must also be
+// included by the stats matcher of the proxy for the stats to be created.
+const ConnectionPoolStatsRegex = `^cluster\..*\.(circuit_breakers\.default\.remaining_.*|upstream_(cx|rq_pending|rq_retry)_overflow)$`
+
+// Resources guarded by the connection pool of a cluster, as reported in the "resource" label.
+const (
+	ResourceConnections     = "connections"
+	ResourcePendingRequests = "pending_requests"
+	ResourceRequests        = "requests"
+	ResourceRetries         = "retries"
+)
+
+const (
+	connectionPoolRemainingMetric = "istio_upstream_connection_pool_remaining"
+	connectionPoolOverflowMetric  = "istio_upstream_connection_pool_overflow_total"
+)
+
+// Stat suffixes of the default priority circuit breaker gauges and overflow counters, per resource.
+var (
+	remainingStats = map[string]string{
+		".circuit_breakers.default.remaining_cx":      ResourceConnections,
+		".circuit_breakers.default.remaining_pending": ResourcePendingRequests,
+		".circuit_breakers.default.remaining_rq":      ResourceRequests,
+		".circuit_breakers.default.remaining_retries": ResourceRetries,
+	}
+	overflowStats = map[string]string{
+		".upstream_cx_overflow":         ResourceConnections,
+		".upstream_rq_pending_overflow": ResourcePendingRequests,
+		".upstream_rq_retry_overflow":   ResourceRetries,
+	}
+)
+
+// ConnectionPoolStats contains the connection pool pressure of an outbound cluster.
+type ConnectionPoolStats struct {
+	Cluster string
+	// Service, Port and Subset are parsed from the name of the cluster.
+	Service string
+	Port    string
+	Subset  string
+	// Remaining is the number of resources that can still be allocated before the circuit breaker opens, by resource.
+	Remaining map[string]uint64
+	// Overflow is the number of times the limit was hit, by resource.
+	Overflow map[string]uint64
+}
+
+// GetConnectionPoolStats returns the connection pool stats of all outbound service clusters of Envoy.
+func GetConnectionPoolStats(localHostAddr string, adminPort uint16) ([]*ConnectionPoolStats, error) {
+	// If the localHostAddr was not set, we use 'localhost' to void empty host in URL.
+	if localHostAddr == "" {
+		localHostAddr = "localhost"
+	}
+
+	hostPort := net.JoinHostPort(localHostAddr, strconv.Itoa(int(adminPort)))
+	stats, err := http.DoHTTPGetWithTimeout(fmt.Sprintf("http://%s/stats?filter=%s", hostPort,
+		url.QueryEscape(ConnectionPoolStatsRegex)), readinessTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConnectionPoolStats(stats)
+}
+
+// ParseConnectionPoolStats parses the connection pool stats of outbound service clusters from the text
+// output of the Envoy stats endpoint. Stats of other clusters are ignored, as are the stats of the clusters which
+// do not track their remaining resources: istiod only tracks them when the connection pool metrics of the proxy
+// are not disabled by Telemetry.
+func ParseConnectionPoolStats(input *bytes.Buffer) ([]*ConnectionPoolStats, error) {
+	clusters := map[string]*ConnectionPoolStats{}
+	for input.Len() > 0 {
+		line, _ := input.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ": ")
+		if !ok || !strings.HasPrefix(name, "cluster.outbound|") {
+			continue
+		}
+		cluster, resource, remaining := splitConnectionPoolStat(name)
+		if cluster == "" {
+			continue
+		}
+		val, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed parsing Envoy stat %s (error: %s) line: %s", name, err.Error(), line)
+		}
+		s := clusters[cluster]
+		if s == nil {
+			s = newConnectionPoolStats(cluster)
+			if s == nil {
+				continue
+			}
+			clusters[cluster] = s
+		}
+		if remaining {
+			s.Remaining[resource] = val
+		} else {
+			s.Overflow[resource] = val
+		}
+	}
+	out := make([]*ConnectionPoolStats, 0, len(clusters))
+	for _, s := range clusters {
+		if len(s.Remaining) == 0 {
+			continue
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Cluster < out[j].Cluster
+	})
+	return out, nil
+}
+
+// splitConnectionPoolStat returns the cluster and resource of a connection pool stat, and whether it is a
+// remaining gauge rather than an overflow counter. The cluster is empty if the stat is not a connection pool stat.
+func splitConnectionPoolStat(name string) (string, string, bool) {
+	name = strings.TrimPrefix(name, "cluster.")
+	for suffix, resource := range remainingStats {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix), resource, true
+		}
+	}
+	for suffix, resource := range overflowStats {
+		if strings.HasSuffix(name, suffix) {
+			return strings.TrimSuffix(name, suffix), resource, false
+		}
+	}
+	return "", "", false
+}
+
+// newConnectionPoolStats parses a cluster name of the form "outbound|port|subset|hostname". It returns nil
+// for clusters which do not belong to a service.
+func newConnectionPoolStats(cluster string) *ConnectionPoolStats {
+	parts := strings.Split(cluster, "|")
+	if len(parts) != 4 || parts[3] == "" {
+		return nil
+	}
+	return &ConnectionPoolStats{
+		Cluster:   cluster,
+		Service:   parts[3],
+		Port:      parts[1],
+		Subset:    parts[2],
+		Remaining: map[string]uint64{},
+		Overflow:  map[string]uint64{},
+	}
+}
+
+// ConnectionPoolMetrics converts connection pool stats to Prometheus metrics, labeled consistently with
+// the standard Istio metrics rather than with the name of the Envoy cluster.
+func ConnectionPoolMetrics(stats []*ConnectionPoolStats) []*dto.MetricFamily {
+	remaining := &dto.MetricFamily{
+		Name: strPtr(connectionPoolRemainingMetric),
+		Help: strPtr("Number of connections or requests which can still be allocated before the connection pool " +
+			"limits of the upstream service are reached."),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	overflow := &dto.MetricFamily{
+		Name: strPtr(connectionPoolOverflowMetric),
+		Help: strPtr("Number of connections or requests rejected because the connection pool limits of the " +
+			"upstream service were reached."),
+		Type: dto.MetricType_COUNTER.Enum(),
+	}
+	for _, s := range stats {
+		for _, resource := range sortedKeys(s.Remaining) {
+			remaining.Metric = append(remaining.Metric, &dto.Metric{
+				Label: s.labels(resource),
+				Gauge: &dto.Gauge{Value: float64Ptr(float64(s.Remaining[resource]))},
+			})
+		}
+		for _, resource := range sortedKeys(s.Overflow) {
+			overflow.Metric = append(overflow.Metric, &dto.Metric{
+				Label:   s.labels(resource),
+				Counter: &dto.Counter{Value: float64Ptr(float64(s.Overflow[resource]))},
+			})
+		}
+	}
+	var out []*dto.MetricFamily
+	for _, mf := range []*dto.MetricFamily{remaining, overflow} {
+		if len(mf.Metric) > 0 {
+			out = append(out, mf)
+		}
+	}
+	return out
+}
+
+func (s *ConnectionPoolStats) labels(resource string) []*dto.LabelPair {
+	subset := s.Subset
+	if subset == "" {
+		subset = "unknown"
+	}
+	return []*dto.LabelPair{
+		{Name: strPtr("destination_service"), Value: strPtr(s.Service)},
+		{Name: strPtr("destination_port"), Value: strPtr(s.Port)},
+		{Name: strPtr("destination_subset"), Value: strPtr(subset)},
+		{Name: strPtr("resource"), Value: strPtr(resource)},
+	}
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
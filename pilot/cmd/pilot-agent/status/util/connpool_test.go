@@ -0,0 +1,88 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/expfmt"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const connectionPoolStats = `cluster.outbound|80|v1|reviews.default.svc.cluster.local.circuit_breakers.default.remaining_cx: 1000
+cluster.outbound|80|v1|reviews.default.svc.cluster.local.circuit_breakers.default.remaining_pending: 12
+cluster.outbound|80|v1|reviews.default.svc.cluster.local.upstream_cx_overflow: 0
+cluster.outbound|80|v1|reviews.default.svc.cluster.local.upstream_rq_pending_overflow: 7
+cluster.outbound|9080||ratings.default.svc.cluster.local.circuit_breakers.default.remaining_rq: 5
+cluster.outbound|9080||ratings.default.svc.cluster.local.upstream_rq_retry_overflow: 3
+cluster.outbound|9080||details.default.svc.cluster.local.upstream_cx_overflow: 2
+cluster.BlackHoleCluster.circuit_breakers.default.remaining_cx: 1024
+cluster.xds-grpc.upstream_cx_overflow: 0
+`
+
+func TestParseConnectionPoolStats(t *testing.T) {
+	stats, err := ParseConnectionPoolStats(bytes.NewBufferString(connectionPoolStats))
+	assert.NoError(t, err)
+	assert.Equal(t, stats, []*ConnectionPoolStats{
+		{
+			Cluster:   "outbound|80|v1|reviews.default.svc.cluster.local",
+			Service:   "reviews.default.svc.cluster.local",
+			Port:      "80",
+			Subset:    "v1",
+			Remaining: map[string]uint64{ResourceConnections: 1000, ResourcePendingRequests: 12},
+			Overflow:  map[string]uint64{ResourceConnections: 0, ResourcePendingRequests: 7},
+		},
+		{
+			Cluster:   "outbound|9080||ratings.default.svc.cluster.local",
+			Service:   "ratings.default.svc.cluster.local",
+			Port:      "9080",
+			Remaining: map[string]uint64{ResourceRequests: 5},
+			Overflow:  map[string]uint64{ResourceRetries: 3},
+		},
+	})
+
+	if _, err := ParseConnectionPoolStats(bytes.NewBufferString(
+		"cluster.outbound|80||a.default.svc.cluster.local.upstream_cx_overflow: x\n")); err == nil {
+		t.Fatal("expected error for invalid stat value")
+	}
+}
+
+func TestConnectionPoolMetrics(t *testing.T) {
+	stats, err := ParseConnectionPoolStats(bytes.NewBufferString(connectionPoolStats))
+	assert.NoError(t, err)
+
+	var out strings.Builder
+	for _, mf := range ConnectionPoolMetrics(stats) {
+		_, err := expfmt.MetricFamilyToText(&out, mf)
+		assert.NoError(t, err)
+	}
+	for _, want := range []string{
+		`# TYPE istio_upstream_connection_pool_remaining gauge`,
+		`istio_upstream_connection_pool_remaining{destination_service="reviews.default.svc.cluster.local",` +
+			`destination_port="80",destination_subset="v1",resource="pending_requests"} 12`,
+		`# TYPE istio_upstream_connection_pool_overflow_total counter`,
+		`istio_upstream_connection_pool_overflow_total{destination_service="ratings.default.svc.cluster.local",` +
+			`destination_port="9080",destination_subset="unknown",resource="retries"} 3`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, out.String())
+		}
+	}
+
+	assert.Equal(t, len(ConnectionPoolMetrics(nil)), 0)
+}
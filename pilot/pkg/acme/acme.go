@@ -0,0 +1,134 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package acme obtains and renews the certificates of the credentialName secrets of Gateways from an ACME
+// certificate authority, such as Let's Encrypt.
+package acme
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/util/sets"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("acme", "ACME certificate management", 0)
+
+const (
+	// Annotation enables ACME certificates for a Gateway when set to "true". The certificate of each
+	// credentialName of its SIMPLE TLS servers is obtained for the hosts of the servers, and written to the secret
+	// in the namespace of the Gateway. It is only honored for the Gateways of the namespaces istiod is allowed to
+	// write the certificates to, and which are deployed in the namespace of the gateway workloads they select,
+	// where the gateways read credentialName secrets from.
+	Annotation = "networking.istio.io/acme"
+	// ManagedLabel labels the secrets written by the controller. Secrets without it are never overwritten.
+	ManagedLabel = "istio.io/acme-managed"
+
+	// HTTP01 and DNS01 are the supported ACME challenges.
+	HTTP01 = "http-01"
+	DNS01  = "dns-01"
+
+	// HTTP01Port is the port istiod serves the HTTP-01 challenge responses on. The gateways must route the
+	// requests to /.well-known/acme-challenge/ on port 80 of the hosts to it.
+	HTTP01Port = 15080
+	// HTTP01Path is the path prefix of the HTTP-01 challenge requests.
+	HTTP01Path = "/.well-known/acme-challenge/"
+)
+
+// Options configure the ACME certificate authority and the challenges solved to prove the control of the hosts.
+type Options struct {
+	// DirectoryURL is the directory of the ACME certificate authority.
+	DirectoryURL string
+	// Email is the contact of the ACME account, if any.
+	Email string
+	// Challenge is the challenge solved for the hosts, HTTP01 or DNS01.
+	Challenge string
+	// Namespace is the istiod namespace, which stores the ACME account key, the HTTP-01 challenge responses and
+	// the TSIG secret of the DNS-01 challenges.
+	Namespace string
+	// Namespaces are the namespaces of the Gateways whose certificates are obtained.
+	Namespaces []string
+	// AcceptTOS accepts the terms of service of the ACME certificate authority when registering the account.
+	AcceptTOS bool
+	// RFC2136 configures the DNS server updated to solve the DNS-01 challenges.
+	RFC2136 RFC2136Options
+}
+
+// RFC2136Options configure the dynamic updates of the DNS-01 challenge records.
+type RFC2136Options struct {
+	// Server is the address of the authoritative DNS server, such as "10.0.0.53:53".
+	Server string
+	// Zone is the DNS zone of the hosts.
+	Zone string
+	// TSIGKeyName, TSIGAlgorithm and the TSIG secret authenticate the updates, if TSIGKeyName is set. The base64
+	// encoded TSIG secret is read from the TSIGSecretKey key of the TSIGSecretName secret of the istiod namespace.
+	TSIGKeyName    string
+	TSIGAlgorithm  string
+	TSIGSecretName string
+}
+
+// TSIGSecretKey is the key of the TSIG secret in the TSIGSecretName secret.
+const TSIGSecretKey = "secret"
+
+// certificates returns the hosts of the certificate of each credentialName secret of the Gateways with ACME
+// certificates, which are honored. Wildcard hosts can only be validated with DNS-01 challenges, so they are
+// ignored otherwise. The secrets are in the namespaces of the Gateways.
+func certificates(gateways []config.Config, wildcards bool,
+	honored func(gw config.Config) (bool, error),
+) (map[types.NamespacedName][]string, error) {
+	hosts := map[types.NamespacedName]sets.String{}
+	for _, gw := range gateways {
+		if gw.Annotations[Annotation] != "true" {
+			continue
+		}
+		ok, err := honored(gw)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		for _, server := range gw.Spec.(*networking.Gateway).GetServers() {
+			tls := server.GetTls()
+			// Gateway API secrets and SDS resources are not Kubernetes secrets of the namespace.
+			if tls.GetMode() != networking.ServerTLSSettings_SIMPLE || tls.GetCredentialName() == "" ||
+				strings.Contains(tls.GetCredentialName(), "://") {
+				continue
+			}
+			for _, h := range server.Hosts {
+				// Hosts may be prefixed with the namespaces of the VirtualServices bound to them.
+				if _, dnsName, f := strings.Cut(h, "/"); f {
+					h = dnsName
+				}
+				if h == "*" || (strings.HasPrefix(h, "*.") && !wildcards) {
+					continue
+				}
+				key := types.NamespacedName{Namespace: gw.Namespace, Name: tls.GetCredentialName()}
+				if hosts[key] == nil {
+					hosts[key] = sets.New[string]()
+				}
+				hosts[key].Insert(h)
+			}
+		}
+	}
+	out := make(map[types.NamespacedName][]string, len(hosts))
+	for key, h := range hosts {
+		out[key] = sets.SortedList(h)
+	}
+	return out, nil
+}
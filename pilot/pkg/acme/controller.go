@@ -0,0 +1,271 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	klabels "k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/workqueue"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// resyncPeriod is how often the certificates are checked for renewal.
+	resyncPeriod = time.Hour
+	// obtainTimeout bounds the validation of the hosts and the issuance of a certificate.
+	obtainTimeout = 5 * time.Minute
+)
+
+// Controller obtains the certificates of the credentialName secrets of the Gateways with the Annotation from an
+// ACME certificate authority, and renews them once two thirds of their lifetime has passed. The secrets are
+// written to the namespaces of the Gateways, whose gateway workloads are found when the Gateways change and at
+// each resync. It only writes secrets it created, which have the ManagedLabel, and does not delete them when the
+// Gateways are.
+type Controller struct {
+	store      model.ConfigStore
+	client     kubernetes.Interface
+	issuer     issuer
+	wildcards  bool
+	namespaces sets.String
+	now        func() time.Time
+
+	mu    sync.Mutex
+	queue *controllers.Queue
+}
+
+// NewController returns a controller of the certificates of the Gateways of the store. It must be created before
+// the store runs, and does not write secrets until it runs.
+func NewController(store model.ConfigStoreController, client kubernetes.Interface, opts Options) *Controller {
+	var s solver
+	if opts.Challenge == DNS01 {
+		s = &rfc2136Solver{opts: opts.RFC2136, secrets: client.CoreV1(), namespace: opts.Namespace}
+	} else {
+		s = &http01Solver{client: client.CoreV1(), namespace: opts.Namespace}
+	}
+	c := &Controller{
+		store:  store,
+		client: client,
+		issuer: &acmeIssuer{
+			directoryURL: opts.DirectoryURL,
+			email:        opts.Email,
+			acceptTOS:    opts.AcceptTOS,
+			secrets:      client.CoreV1(),
+			namespace:    opts.Namespace,
+			solver:       s,
+		},
+		wildcards:  opts.Challenge == DNS01,
+		namespaces: sets.New(opts.Namespaces...),
+		now:        time.Now,
+	}
+	store.RegisterEventHandler(gvk.Gateway, func(old config.Config, cur config.Config, _ model.Event) {
+		c.enqueue(old)
+		c.enqueue(cur)
+	})
+	return c
+}
+
+// Run reconciles the certificates until the stop channel is closed.
+func (c *Controller) Run(stop <-chan struct{}) {
+	// The failed validations count towards the rate limits of the certificate authorities, so the retries are
+	// slow.
+	q := controllers.NewQueue("acme certificates",
+		controllers.WithReconciler(c.reconcile),
+		controllers.WithRateLimiter(workqueue.NewItemExponentialFailureRateLimiter(time.Minute, resyncPeriod)),
+		controllers.WithMaxAttempts(5))
+	c.mu.Lock()
+	c.queue = &q
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		c.queue = nil
+		c.mu.Unlock()
+	}()
+
+	go func() {
+		ticker := time.NewTicker(resyncPeriod)
+		defer ticker.Stop()
+		for {
+			c.enqueueAll()
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	q.Run(stop)
+}
+
+// resyncKey is queued to queue the secrets of all the Gateways. Finding the secrets requires listing the gateway
+// workloads, which is done by the queue rather than by the event handlers.
+var resyncKey = types.NamespacedName{}
+
+// enqueue queues a resync for a Gateway with ACME certificates, if the controller runs.
+func (c *Controller) enqueue(gw config.Config) {
+	if gw.Spec == nil || gw.Annotations[Annotation] != "true" {
+		return
+	}
+	c.enqueueAll()
+}
+
+func (c *Controller) enqueueAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.queue == nil {
+		return
+	}
+	c.queue.Add(resyncKey)
+}
+
+// certificates returns the hosts of the certificate of each secret of the Gateways.
+func (c *Controller) certificates(ctx context.Context) (map[types.NamespacedName][]string, error) {
+	gateways, err := c.store.List(gvk.Gateway, "")
+	if err != nil {
+		return nil, err
+	}
+	return certificates(gateways, c.wildcards, func(gw config.Config) (bool, error) {
+		return c.honored(ctx, gw)
+	})
+}
+
+// honored returns whether the certificates of a Gateway are obtained: the Gateway must be in one of the namespaces
+// of the controller, and in the namespace of the gateway workloads it selects, where the credentialName secrets
+// are read from. Otherwise, the Gateways of a namespace would have istiod write secrets to the namespaces of the
+// gateways of other namespaces. A Gateway selecting no workload yet is honored.
+func (c *Controller) honored(ctx context.Context, gw config.Config) (bool, error) {
+	if !c.namespaces.Contains(gw.Namespace) {
+		log.Debugf("ignoring Gateway %s/%s, not in the ACME namespaces %v", gw.Namespace, gw.Name, sets.SortedList(c.namespaces))
+		return false, nil
+	}
+	selector := gw.Spec.(*networking.Gateway).GetSelector()
+	// The Gateways only select the workloads of their namespace.
+	if len(selector) == 0 || features.ScopeGatewayToNamespace {
+		return true, nil
+	}
+	pods, err := c.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: klabels.SelectorFromSet(selector).String(),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list the workloads of Gateway %s/%s: %v", gw.Namespace, gw.Name, err)
+	}
+	for _, pod := range pods.Items {
+		if pod.Namespace == gw.Namespace {
+			return true, nil
+		}
+	}
+	if len(pods.Items) > 0 {
+		log.Warnf("ignoring Gateway %s/%s, its gateway workloads are in another namespace", gw.Namespace, gw.Name)
+		return false, nil
+	}
+	return true, nil
+}
+
+// reconcile obtains the certificate of a secret if it does not exist yet, is not valid for the hosts of the
+// Gateways anymore, or is to be renewed. The resyncKey queues all the secrets.
+func (c *Controller) reconcile(key types.NamespacedName) error {
+	ctx, cancel := context.WithTimeout(context.Background(), obtainTimeout)
+	defer cancel()
+	certs, err := c.certificates(ctx)
+	if err != nil {
+		return err
+	}
+	if key == resyncKey {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.queue != nil {
+			for k := range certs {
+				c.queue.Add(k)
+			}
+		}
+		return nil
+	}
+	hosts := certs[key]
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	secrets := c.client.CoreV1().Secrets(key.Namespace)
+	secret, err := secrets.Get(ctx, key.Name, metav1.GetOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	exists := err == nil
+	if exists {
+		if secret.Labels[ManagedLabel] != "true" {
+			log.Warnf("secret %v exists and is not managed by the ACME controller, ignoring it", key)
+			return nil
+		}
+		if !c.needsRenewal(secret.Data[v1.TLSCertKey], hosts) {
+			return nil
+		}
+	}
+
+	log.Infof("obtaining the certificate of secret %v for %v", key, hosts)
+	certPEM, keyPEM, err := c.issuer.obtain(ctx, hosts)
+	if err != nil {
+		return fmt.Errorf("failed to obtain the certificate of secret %v: %v", key, err)
+	}
+	data := map[string][]byte{v1.TLSCertKey: certPEM, v1.TLSPrivateKeyKey: keyPEM}
+	if !exists {
+		_, err = secrets.Create(ctx, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace, Labels: map[string]string{ManagedLabel: "true"}},
+			Type:       v1.SecretTypeTLS,
+			Data:       data,
+		}, metav1.CreateOptions{})
+	} else {
+		secret.Data = data
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the certificate to secret %v: %v", key, err)
+	}
+	return nil
+}
+
+// needsRenewal returns whether the certificate is not valid for exactly the hosts, or two thirds of its lifetime
+// has passed.
+func (c *Controller) needsRenewal(certPEM []byte, hosts []string) bool {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return true
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return true
+	}
+	if !sets.New(cert.DNSNames...).Equals(sets.New(hosts...)) {
+		return true
+	}
+	renewAt := cert.NotBefore.Add(cert.NotAfter.Sub(cert.NotBefore) * 2 / 3)
+	return !c.now().Before(renewAt)
+}
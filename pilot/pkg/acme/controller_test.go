@@ -0,0 +1,280 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test"
+)
+
+func gateway(name string, annotated bool, servers ...*networking.Server) config.Config {
+	cfg := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.Gateway, Name: name, Namespace: "istio-ingress"},
+		Spec: &networking.Gateway{Servers: servers},
+	}
+	if annotated {
+		cfg.Annotations = map[string]string{Annotation: "true"}
+	}
+	return cfg
+}
+
+func server(mode networking.ServerTLSSettings_TLSmode, credentialName string, hosts ...string) *networking.Server {
+	return &networking.Server{
+		Port:  &networking.Port{Number: 443, Name: "https", Protocol: "HTTPS"},
+		Hosts: hosts,
+		Tls:   &networking.ServerTLSSettings{Mode: mode, CredentialName: credentialName},
+	}
+}
+
+func TestCertificates(t *testing.T) {
+	gateways := []config.Config{
+		gateway("web", true,
+			server(networking.ServerTLSSettings_SIMPLE, "web-cert", "web.example.com", "bookinfo/www.example.com"),
+			server(networking.ServerTLSSettings_SIMPLE, "wildcard-cert", "*.example.com"),
+			server(networking.ServerTLSSettings_PASSTHROUGH, "", "db.example.com"),
+			server(networking.ServerTLSSettings_MUTUAL, "mtls-cert", "mtls.example.com")),
+		gateway("api", true, server(networking.ServerTLSSettings_SIMPLE, "web-cert", "api.example.com", "*")),
+		gateway("other", false, server(networking.ServerTLSSettings_SIMPLE, "other-cert", "other.example.com")),
+	}
+	webCert := types.NamespacedName{Namespace: "istio-ingress", Name: "web-cert"}
+	wildcardCert := types.NamespacedName{Namespace: "istio-ingress", Name: "wildcard-cert"}
+
+	honored := func(gw config.Config) (bool, error) {
+		return true, nil
+	}
+
+	got, _ := certificates(gateways, false, honored)
+	want := map[types.NamespacedName][]string{webCert: {"api.example.com", "web.example.com", "www.example.com"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	got, _ = certificates(gateways, true, honored)
+	want[wildcardCert] = []string{"*.example.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v with wildcards", got, want)
+	}
+
+	// The certificates of the Gateways which are not honored are not obtained.
+	got, _ = certificates(gateways, false, func(gw config.Config) (bool, error) {
+		return gw.Name != "web", nil
+	})
+	want = map[types.NamespacedName][]string{webCert: {"api.example.com"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v without the Gateways which are not honored", got, want)
+	}
+}
+
+func TestHonored(t *testing.T) {
+	pod := func(name, namespace string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"istio": "ingressgateway"}}}
+	}
+	client := fake.NewSimpleClientset(pod("a", "gateways"), pod("b", "istio-ingress"),
+		&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", Labels: map[string]string{"istio": "appgateway"}}})
+	c := NewController(memory.NewController(memory.Make(collections.Pilot)), client,
+		Options{Challenge: HTTP01, Namespace: "istio-system", Namespaces: []string{"istio-ingress", "tenant"}})
+
+	cases := []struct {
+		name      string
+		namespace string
+		selector  map[string]string
+		scoped    bool
+		want      bool
+	}{
+		{name: "no selector", namespace: "istio-ingress", want: true},
+		{name: "not an ACME namespace", namespace: "gateways", want: false},
+		{name: "workloads of the namespace", namespace: "istio-ingress", selector: map[string]string{"istio": "ingressgateway"}, want: true},
+		{name: "workloads of another namespace", namespace: "tenant", selector: map[string]string{"istio": "appgateway"}, want: false},
+		{name: "no workload", namespace: "tenant", selector: map[string]string{"istio": "egressgateway"}, want: true},
+		{name: "scoped to the namespace", namespace: "tenant", selector: map[string]string{"istio": "appgateway"}, scoped: true, want: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			test.SetForTest(t, &features.ScopeGatewayToNamespace, tt.scoped)
+			gw := gateway("web", true)
+			gw.Namespace = tt.namespace
+			gw.Spec.(*networking.Gateway).Selector = tt.selector
+			got, err := c.honored(context.Background(), gw)
+			if err != nil || got != tt.want {
+				t.Fatalf("got %v %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// fakeIssuer issues self-signed certificates valid for 90 days.
+type fakeIssuer struct {
+	now    time.Time
+	issued [][]string
+}
+
+func (f *fakeIssuer) obtain(_ context.Context, hosts []string) ([]byte, []byte, error) {
+	f.issued = append(f.issued, hosts)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     hosts,
+		NotBefore:    f.now,
+		NotAfter:     f.now.Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+func TestReconcile(t *testing.T) {
+	store := memory.NewController(memory.Make(collections.Pilot))
+	client := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "manual-cert", Namespace: "istio-ingress"},
+		Data:       map[string][]byte{v1.TLSCertKey: []byte("manual")},
+	})
+	now := time.Now()
+	issuer := &fakeIssuer{now: now}
+	c := NewController(store, client, Options{Challenge: HTTP01, Namespace: "istio-system", Namespaces: []string{"istio-ingress"}})
+	c.issuer = issuer
+	c.now = func() time.Time { return now }
+
+	for _, gw := range []config.Config{
+		gateway("web", true, server(networking.ServerTLSSettings_SIMPLE, "web-cert", "web.example.com")),
+		gateway("manual", true, server(networking.ServerTLSSettings_SIMPLE, "manual-cert", "manual.example.com")),
+	} {
+		if _, err := store.Create(gw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	webCert := types.NamespacedName{Namespace: "istio-ingress", Name: "web-cert"}
+	manualCert := types.NamespacedName{Namespace: "istio-ingress", Name: "manual-cert"}
+
+	for _, key := range []types.NamespacedName{webCert, manualCert} {
+		if err := c.reconcile(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(issuer.issued, [][]string{{"web.example.com"}}) {
+		t.Fatalf("expected only the certificate of web-cert to be obtained, got %v", issuer.issued)
+	}
+	secret, err := client.CoreV1().Secrets("istio-ingress").Get(context.Background(), "web-cert", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Type != v1.SecretTypeTLS || secret.Labels[ManagedLabel] != "true" || len(secret.Data[v1.TLSPrivateKeyKey]) == 0 {
+		t.Fatalf("unexpected secret %v", secret)
+	}
+
+	// The certificate is valid until two thirds of its lifetime, and for the hosts of the Gateway.
+	now = now.Add(59 * 24 * time.Hour)
+	if err := c.reconcile(webCert); err != nil {
+		t.Fatal(err)
+	}
+	if len(issuer.issued) != 1 {
+		t.Fatalf("expected the certificate not to be renewed yet, got %v", issuer.issued)
+	}
+	now = now.Add(2 * 24 * time.Hour)
+	if err := c.reconcile(webCert); err != nil {
+		t.Fatal(err)
+	}
+	if len(issuer.issued) != 2 {
+		t.Fatalf("expected the certificate to be renewed, got %v", issuer.issued)
+	}
+	if _, err := store.Update(gateway("web", true, server(networking.ServerTLSSettings_SIMPLE, "web-cert", "web.example.com", "www.example.com"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.reconcile(webCert); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(issuer.issued[len(issuer.issued)-1], []string{"web.example.com", "www.example.com"}) {
+		t.Fatalf("expected the certificate to be obtained for the new hosts, got %v", issuer.issued)
+	}
+}
+
+func TestHTTP01(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s := &http01Solver{client: client.CoreV1(), namespace: "istio-system"}
+	cleanup, err := s.present(context.Background(), "web.example.com", "token", "token.thumbprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(path string) *httptest.ResponseRecorder {
+		cm, err := client.CoreV1().ConfigMaps("istio-system").Get(context.Background(), http01ConfigMap, metav1.GetOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+		if err := indexer.Add(cm); err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		HTTP01Handler(listerv1.NewConfigMapLister(indexer), "istio-system").ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		return rec
+	}
+	if rec := serve(HTTP01Path + "token"); rec.Code != 200 || rec.Body.String() != "token.thumbprint" {
+		t.Fatalf("unexpected challenge response %d %q", rec.Code, rec.Body.String())
+	}
+	if rec := serve(HTTP01Path + "other"); rec.Code != 404 {
+		t.Fatalf("expected unknown tokens not to be found, got %d", rec.Code)
+	}
+	cleanup()
+	if rec := serve(HTTP01Path + "token"); rec.Code != 404 {
+		t.Fatalf("expected the challenge response to be removed, got %d", rec.Code)
+	}
+}
+
+func TestTSIGSecret(t *testing.T) {
+	client := fake.NewSimpleClientset(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "tsig", Namespace: "istio-system"},
+		Data:       map[string][]byte{TSIGSecretKey: []byte("c2VjcmV0")},
+	})
+	s := &rfc2136Solver{opts: RFC2136Options{TSIGSecretName: "tsig"}, secrets: client.CoreV1(), namespace: "istio-system"}
+	if got, err := s.tsigSecret(context.Background()); err != nil || got != "c2VjcmV0" {
+		t.Fatalf("got %q %v", got, err)
+	}
+	s.opts.TSIGSecretName = "missing"
+	if _, err := s.tsigSecret(context.Background()); err == nil {
+		t.Fatalf("expected an error for a missing secret")
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/acme"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// accountSecret stores the key of the ACME account in the istiod namespace, so that the account is kept
+	// across restarts and leader changes.
+	accountSecret = "istio-acme-account"
+	accountKey    = "key.pem"
+)
+
+// issuer obtains certificates from an ACME certificate authority.
+type issuer interface {
+	obtain(ctx context.Context, hosts []string) (certPEM, keyPEM []byte, err error)
+}
+
+// acmeIssuer obtains certificates with the ACME protocol, solving the challenges of the hosts with the solver.
+type acmeIssuer struct {
+	directoryURL string
+	email        string
+	acceptTOS    bool
+	secrets      corev1.SecretsGetter
+	namespace    string
+	solver       solver
+
+	mu sync.Mutex
+	// client is the client of the registered account, once it is.
+	client *acme.Client
+}
+
+var _ issuer = &acmeIssuer{}
+
+// account returns the client of the ACME account, registering the account first if needed.
+func (i *acmeIssuer) account(ctx context.Context) (*acme.Client, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.client != nil {
+		return i.client, nil
+	}
+	key, err := i.accountKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the ACME account key: %v", err)
+	}
+	client := &acme.Client{Key: key, DirectoryURL: i.directoryURL}
+	account := &acme.Account{}
+	if i.email != "" {
+		account.Contact = []string{"mailto:" + i.email}
+	}
+	acceptTOS := func(tosURL string) bool {
+		if !i.acceptTOS {
+			log.Errorf("the terms of service %s of the ACME certificate authority are not accepted", tosURL)
+		}
+		return i.acceptTOS
+	}
+	if _, err := client.Register(ctx, account, acceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("failed to register the ACME account: %v", err)
+	}
+	i.client = client
+	return client, nil
+}
+
+// accountKey returns the stored account key, generating and storing it the first time.
+func (i *acmeIssuer) accountKey(ctx context.Context) (*ecdsa.PrivateKey, error) {
+	secrets := i.secrets.Secrets(i.namespace)
+	secret, err := secrets.Get(ctx, accountSecret, metav1.GetOptions{})
+	if err == nil {
+		block, _ := pem.Decode(secret.Data[accountKey])
+		if block == nil {
+			return nil, fmt.Errorf("no PEM key in secret %s/%s", i.namespace, accountSecret)
+		}
+		return x509.ParseECPrivateKey(block.Bytes)
+	}
+	if !kerrors.IsNotFound(err) {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	secret = &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: accountSecret, Namespace: i.namespace},
+		Data:       map[string][]byte{accountKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})},
+	}
+	if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (i *acmeIssuer) obtain(ctx context.Context, hosts []string) ([]byte, []byte, error) {
+	client, err := i.account(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(hosts...))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create the order: %v", err)
+	}
+	for _, u := range order.AuthzURLs {
+		z, err := client.GetAuthorization(ctx, u)
+		if err != nil {
+			return nil, nil, err
+		}
+		if z.Status == acme.StatusValid {
+			continue
+		}
+		var chal *acme.Challenge
+		for _, c := range z.Challenges {
+			if c.Type == i.solver.challengeType() {
+				chal = c
+			}
+		}
+		if chal == nil {
+			return nil, nil, fmt.Errorf("no %s challenge offered for %s", i.solver.challengeType(), z.Identifier.Value)
+		}
+		var value string
+		if chal.Type == DNS01 {
+			value, err = client.DNS01ChallengeRecord(chal.Token)
+		} else {
+			value, err = client.HTTP01ChallengeResponse(chal.Token)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		cleanup, err := i.solver.present(ctx, z.Identifier.Value, chal.Token, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer cleanup()
+		if _, err := client.Accept(ctx, chal); err != nil {
+			return nil, nil, fmt.Errorf("failed to accept the challenge of %s: %v", z.Identifier.Value, err)
+		}
+		if _, err := client.WaitAuthorization(ctx, z.URI); err != nil {
+			return nil, nil, fmt.Errorf("failed to validate %s: %v", z.Identifier.Value, err)
+		}
+	}
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, nil, fmt.Errorf("failed to validate the order: %v", err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The certificate authority picks the common name, if any, since it cannot exceed 64 characters.
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{DNSNames: hosts}, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to issue the certificate: %v", err)
+	}
+	var certPEM []byte
+	for _, der := range chain {
+		certPEM = append(certPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return certPEM, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
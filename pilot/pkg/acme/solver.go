@@ -0,0 +1,191 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package acme
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+)
+
+// http01ConfigMap stores the pending HTTP-01 challenge responses, by token, so that all the istiod replicas can
+// serve them while only the leader solves the challenges.
+const http01ConfigMap = "istio-acme-http01"
+
+// solver proves the control of a domain for an ACME challenge.
+type solver interface {
+	// challengeType returns the ACME challenge type solved.
+	challengeType() string
+	// present provisions the value of the challenge of the domain, and returns the function removing it once the
+	// challenge is validated.
+	present(ctx context.Context, domain, token, value string) (cleanup func(), err error)
+}
+
+// http01Solver stores the HTTP-01 challenge responses in a ConfigMap, served by the HTTP01Handler of each istiod.
+type http01Solver struct {
+	client    corev1.ConfigMapsGetter
+	namespace string
+
+	// mu serializes the updates of the ConfigMap by the concurrent challenges.
+	mu sync.Mutex
+}
+
+var _ solver = &http01Solver{}
+
+func (s *http01Solver) challengeType() string {
+	return HTTP01
+}
+
+func (s *http01Solver) present(ctx context.Context, _, token, value string) (func(), error) {
+	if err := s.update(ctx, func(data map[string]string) { data[token] = value }); err != nil {
+		return nil, fmt.Errorf("failed to store the HTTP-01 challenge response: %v", err)
+	}
+	return func() {
+		if err := s.update(context.Background(), func(data map[string]string) { delete(data, token) }); err != nil {
+			log.Warnf("failed to remove the HTTP-01 challenge response: %v", err)
+		}
+	}, nil
+}
+
+func (s *http01Solver) update(ctx context.Context, mutate func(data map[string]string)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	configMaps := s.client.ConfigMaps(s.namespace)
+	cm, err := configMaps.Get(ctx, http01ConfigMap, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		cm = &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: http01ConfigMap, Namespace: s.namespace}, Data: map[string]string{}}
+		mutate(cm.Data)
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	mutate(cm.Data)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
+
+// HTTP01Handler serves the HTTP-01 challenge responses stored by the leader istiod in the namespace.
+func HTTP01Handler(configMaps listerv1.ConfigMapLister, namespace string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		token := strings.TrimPrefix(req.URL.Path, HTTP01Path)
+		if req.Method != http.MethodGet || token == req.URL.Path || token == "" {
+			http.NotFound(w, req)
+			return
+		}
+		cm, err := configMaps.ConfigMaps(namespace).Get(http01ConfigMap)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		value, f := cm.Data[token]
+		if !f {
+			http.NotFound(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(value))
+	})
+}
+
+// rfc2136Solver provisions the DNS-01 challenge records with dynamic updates of the authoritative DNS server.
+type rfc2136Solver struct {
+	opts      RFC2136Options
+	secrets   corev1.SecretsGetter
+	namespace string
+}
+
+var _ solver = &rfc2136Solver{}
+
+// dns01RecordTTL is the TTL of the challenge records, which are removed once validated.
+const dns01RecordTTL = 60
+
+func (s *rfc2136Solver) challengeType() string {
+	return DNS01
+}
+
+func (s *rfc2136Solver) present(ctx context.Context, domain, _, value string) (func(), error) {
+	rr := &dns.TXT{
+		Hdr: dns.RR_Header{Name: dns.Fqdn("_acme-challenge." + domain), Rrtype: dns.TypeTXT, Class: dns.ClassINET, Ttl: dns01RecordTTL},
+		Txt: []string{value},
+	}
+	if err := s.update(ctx, rr, false); err != nil {
+		return nil, fmt.Errorf("failed to add the DNS-01 challenge record of %s: %v", domain, err)
+	}
+	return func() {
+		if err := s.update(context.Background(), rr, true); err != nil {
+			log.Warnf("failed to remove the DNS-01 challenge record of %s: %v", domain, err)
+		}
+	}, nil
+}
+
+func (s *rfc2136Solver) update(ctx context.Context, rr dns.RR, remove bool) error {
+	m := new(dns.Msg)
+	m.SetUpdate(dns.Fqdn(s.opts.Zone))
+	if remove {
+		m.Remove([]dns.RR{rr})
+	} else {
+		m.Insert([]dns.RR{rr})
+	}
+	c := &dns.Client{Net: "tcp"}
+	if s.opts.TSIGKeyName != "" {
+		keyName := dns.Fqdn(s.opts.TSIGKeyName)
+		algorithm := dns.HmacSHA256
+		if s.opts.TSIGAlgorithm != "" {
+			algorithm = dns.Fqdn(s.opts.TSIGAlgorithm)
+		}
+		secret, err := s.tsigSecret(ctx)
+		if err != nil {
+			return err
+		}
+		c.TsigSecret = map[string]string{keyName: secret}
+		m.SetTsig(keyName, algorithm, 300, time.Now().Unix())
+	}
+	r, _, err := c.ExchangeContext(ctx, m, s.opts.Server)
+	if err != nil {
+		return err
+	}
+	if r.Rcode != dns.RcodeSuccess {
+		return fmt.Errorf("update rejected by %s: %s", s.opts.Server, dns.RcodeToString[r.Rcode])
+	}
+	return nil
+}
+
+// tsigSecret reads the TSIG secret at each update, so that it can be rotated without restarting istiod.
+func (s *rfc2136Solver) tsigSecret(ctx context.Context) (string, error) {
+	secret, err := s.secrets.Secrets(s.namespace).Get(ctx, s.opts.TSIGSecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the TSIG secret: %v", err)
+	}
+	value := string(secret.Data[TSIGSecretKey])
+	if value == "" {
+		return "", fmt.Errorf("no %s key in secret %s/%s", TSIGSecretKey, s.namespace, s.opts.TSIGSecretName)
+	}
+	return value, nil
+}
@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"istio.io/istio/pilot/pkg/acme"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/pkg/log"
+)
+
+// initACMEController obtains the certificates of the Gateways of the store from an ACME certificate authority. The
+// leader istiod obtains the certificates, and all the istiod replicas serve the HTTP-01 challenge responses.
+func (s *Server) initACMEController(args *PilotArgs, store model.ConfigStoreController) error {
+	opts := acme.Options{
+		DirectoryURL: features.ACMEDirectoryURL,
+		Email:        features.ACMEEmail,
+		Challenge:    features.ACMEChallenge,
+		Namespace:    args.Namespace,
+		Namespaces:   features.ACMENamespaces,
+		AcceptTOS:    features.ACMEAcceptTOS,
+		RFC2136: acme.RFC2136Options{
+			Server:         features.ACMERFC2136Server,
+			Zone:           features.ACMERFC2136Zone,
+			TSIGKeyName:    features.ACMERFC2136TSIGKeyName,
+			TSIGAlgorithm:  features.ACMERFC2136TSIGAlgorithm,
+			TSIGSecretName: features.ACMERFC2136TSIGSecretName,
+		},
+	}
+	if !opts.AcceptTOS {
+		return fmt.Errorf("PILOT_ACME_ACCEPT_TOS must be set to accept the terms of service of the ACME certificate authority")
+	}
+	if len(opts.Namespaces) == 0 {
+		return fmt.Errorf("PILOT_ACME_NAMESPACES is required to obtain ACME certificates")
+	}
+	switch opts.Challenge {
+	case acme.HTTP01:
+	case acme.DNS01:
+		if opts.RFC2136.Server == "" || opts.RFC2136.Zone == "" {
+			return fmt.Errorf("PILOT_ACME_RFC2136_SERVER and PILOT_ACME_RFC2136_ZONE are required for %s challenges", acme.DNS01)
+		}
+		if opts.RFC2136.TSIGKeyName != "" && opts.RFC2136.TSIGSecretName == "" {
+			return fmt.Errorf("PILOT_ACME_RFC2136_TSIG_SECRET_NAME is required with PILOT_ACME_RFC2136_TSIG_KEY_NAME")
+		}
+	default:
+		return fmt.Errorf("unsupported ACME challenge %q, must be %s or %s", opts.Challenge, acme.HTTP01, acme.DNS01)
+	}
+
+	c := acme.NewController(store, s.kubeClient.Kube(), opts)
+	s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+		leaderelection.
+			NewLeaderElection(args.Namespace, args.PodName, leaderelection.ACMEController, args.Revision, s.kubeClient).
+			AddRunFunction(func(leaderStop <-chan struct{}) {
+				log.Infof("Starting ACME controller")
+				c.Run(leaderStop)
+			}).
+			Run(stop)
+		return nil
+	})
+
+	if opts.Challenge == acme.HTTP01 {
+		// The lister is created now so that its informer is started with the other informers.
+		handler := acme.HTTP01Handler(s.kubeClient.KubeInformer().Core().V1().ConfigMaps().Lister(), args.Namespace)
+		mux := http.NewServeMux()
+		mux.Handle(acme.HTTP01Path, handler)
+		server := &http.Server{Addr: ":" + strconv.Itoa(acme.HTTP01Port), Handler: mux}
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			listener, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				log.Infof("starting ACME HTTP-01 challenge server at %s", listener.Addr())
+				if err := server.Serve(listener); isUnexpectedListenerError(err) {
+					log.Errorf("error serving ACME HTTP-01 challenge server: %v", err)
+				}
+			}()
+			go func() {
+				<-stop
+				_ = server.Close()
+			}()
+			return nil
+		})
+	}
+	return nil
+}
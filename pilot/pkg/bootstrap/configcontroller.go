@@ -56,7 +56,8 @@ const (
 
 // initConfigController creates the config controller in the pilotConfig.
 func (s *Server) initConfigController(args *PilotArgs) error {
-	s.initStatusController(args, features.EnableStatus)
+	// The status is written to the Kubernetes resources, and is not available in standalone mode.
+	s.initStatusController(args, features.EnableStatus && s.kubeClient != nil)
 	meshConfig := s.environment.Mesh()
 	if len(meshConfig.ConfigSources) > 0 {
 		// Using MCP for config.
@@ -204,11 +205,21 @@ func (s *Server) initK8SConfigStore(args *PilotArgs) error {
 			})
 		}
 	}
-	if features.EnableAnalysis {
+	if features.EnableAnalysis && s.kubeClient != nil {
 		if err := s.initInprocessAnalysisController(args); err != nil {
 			return err
 		}
 	}
+	if features.SidecarRecommendationPrometheusAddress != "" && s.kubeClient != nil {
+		if err := s.initSidecarRecommendationController(args); err != nil {
+			return err
+		}
+	}
+	if features.EnableACME {
+		if err := s.initACMEController(args, configController); err != nil {
+			return err
+		}
+	}
 	s.RWConfigStore, err = configaggregate.MakeWriteableCache(s.ConfigStores, configController)
 	if err != nil {
 		return err
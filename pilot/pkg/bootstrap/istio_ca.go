@@ -28,10 +28,12 @@ import (
 	"google.golang.org/grpc"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"istio.io/api/security/v1beta1"
 	"istio.io/istio/pilot/pkg/features"
 	securityModel "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/jwt"
 	"istio.io/istio/pkg/security"
@@ -95,6 +97,14 @@ var (
 		cmd.DefaultMaxWorkloadCertTTL,
 		"The max TTL of issued workload certificates.")
 
+	ecdsaOnlyWorkloadCerts = env.Register("ECDSA_ONLY_WORKLOAD_CERTS", false,
+		"If enabled, the CA only signs workload certificates for ECDSA keys. Workloads should be configured to "+
+			"generate ECDSA keys, for example by setting ECC_SIGNATURE_ALGORITHM=ECDSA in the proxy metadata of the mesh config.")
+
+	trustedNodeAccounts = env.Register("CA_TRUSTED_NODE_ACCOUNTS", "",
+		"Comma separated list of the <namespace>/<serviceaccount> of the node proxies, which the CA allows to request "+
+			"certificates for the identities of the host network pods of their node.")
+
 	SelfSignedCACertTTL = env.Register("CITADEL_SELF_SIGNED_CA_CERT_TTL",
 		cmd.DefaultSelfSignedCACertTTL,
 		"The TTL of self-signed CA root certificate.")
@@ -169,6 +179,13 @@ func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts
 	if startErr != nil {
 		log.Fatalf("failed to create istio ca server: %v", startErr)
 	}
+	caServer.RequireECDSAKeys = ecdsaOnlyWorkloadCerts.Get()
+	caServer.TransitionTrustDomains = func() []string {
+		return trustdomain.ActiveTransitions(time.Now())
+	}
+	if accounts := nodeAccounts(trustedNodeAccounts.Get()); len(accounts) > 0 && s.kubeClient != nil {
+		caServer.NodeAuthorizer = caserver.NewNodeAuthorizer(accounts, s.kubeClient.KubeInformer().Core().V1().Pods().Lister())
+	}
 
 	// TODO: if not set, parse Istiod's own token (if present) and get the issuer. The same issuer is used
 	// for all tokens - no need to configure twice. The token may also include cluster info to auto-configure
@@ -192,6 +209,22 @@ func (s *Server) RunCA(grpc *grpc.Server, ca caserver.CertificateAuthority, opts
 	log.Info("Istiod CA has started")
 }
 
+// nodeAccounts parses the comma separated <namespace>/<serviceaccount> of the trusted node proxies.
+func nodeAccounts(s string) []types.NamespacedName {
+	var accounts []types.NamespacedName
+	for _, account := range strings.Split(s, ",") {
+		ns, sa, ok := strings.Cut(strings.TrimSpace(account), "/")
+		if !ok || ns == "" || sa == "" {
+			if account != "" {
+				log.Warnf("ignoring invalid trusted node account %q, expected <namespace>/<serviceaccount>", account)
+			}
+			continue
+		}
+		accounts = append(accounts, types.NamespacedName{Namespace: ns, Name: sa})
+	}
+	return accounts
+}
+
 // detectAuthEnv will use the JWT token that is mounted in istiod to set the default audience
 // and trust domain for Istiod, if not explicitly defined.
 // K8S will use the same kind of tokens for the pods, and the value in istiod's own token is
@@ -22,6 +22,7 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/security"
 	"istio.io/istio/pkg/keepalive"
 	"istio.io/pkg/ctrlz"
 	"istio.io/pkg/env"
@@ -63,6 +64,9 @@ type PilotArgs struct {
 	KeepaliveOptions   *keepalive.Options
 	ShutdownDuration   time.Duration
 	JwtRule            string
+	// Standalone runs istiod from the config directory only, without Kubernetes. The services are the
+	// ServiceEntries of the directory, and the webhooks are served with the local injection config.
+	Standalone bool
 }
 
 // DiscoveryServerOptions contains options for create a new discovery server instance.
@@ -154,6 +158,22 @@ func (p *PilotArgs) Complete() error {
 		return err
 	}
 	p.ServerOptions.TLSOptions.CipherSuits = cipherSuits
+	if p.Standalone {
+		if p.RegistryOptions.FileDir == "" {
+			return fmt.Errorf("standalone mode requires a config directory")
+		}
+		if features.PilotCertProvider == constants.CertProviderKubernetes {
+			return fmt.Errorf("standalone mode cannot use the %s certificate provider", constants.CertProviderKubernetes)
+		}
+		// The Kubernetes registry and config store are never read in standalone mode.
+		p.RegistryOptions.Registries = nil
+		p.RegistryOptions.KubeConfig = ""
+	}
+	if features.TLSProfile != "" {
+		if _, err := security.GetTLSProfile(features.TLSProfile, features.FIPSProxy); err != nil {
+			return fmt.Errorf("invalid PILOT_TLS_PROFILE: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -43,6 +43,7 @@ import (
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
 	"istio.io/istio/pilot/pkg/keycertbundle"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
 	"istio.io/istio/pilot/pkg/server"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
@@ -51,6 +52,7 @@ import (
 	"istio.io/istio/pilot/pkg/status"
 	"istio.io/istio/pilot/pkg/status/distribution"
 	tb "istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pilot/pkg/writeapi"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/cluster"
@@ -72,6 +74,7 @@ import (
 	"istio.io/istio/security/pkg/pki/ca"
 	"istio.io/istio/security/pkg/pki/ra"
 	"istio.io/istio/security/pkg/server/ca/authenticate"
+	"istio.io/istio/security/pkg/server/ca/authenticate/bootstraptoken"
 	"istio.io/istio/security/pkg/server/ca/authenticate/kubeauth"
 	"istio.io/pkg/ctrlz"
 	"istio.io/pkg/filewatcher"
@@ -222,6 +225,14 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	// Initialize workload Trust Bundle before XDS Server
 	e.TrustBundle = s.workloadTrustBundle
 	s.XDSServer = xds.NewDiscoveryServer(e, args.PodName, args.RegistryOptions.KubeOptions.ClusterAliases)
+	shard, err := model.NewProxyShard(features.ProxyShards, features.ProxyShardIndex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy sharding: %v", err)
+	}
+	if shard.Enabled() {
+		log.Infof("serving proxies of shard %d of %d", shard.Index, shard.Count)
+	}
+	s.XDSServer.ProxyShard = shard
 
 	prometheus.EnableHandlingTimeHistogram()
 
@@ -277,6 +288,10 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	}
 
 	s.XDSServer.InitGenerators(e, args.Namespace, s.internalDebugMux)
+	s.XDSServer.IstiodServiceAccount = constants.DefaultConfigServiceAccountName
+	if args.Revision != "" && args.Revision != "default" {
+		s.XDSServer.IstiodServiceAccount += "-" + args.Revision
+	}
 
 	// Initialize workloadTrustBundle after CA has been initialized
 	if err := s.initWorkloadTrustBundle(args); err != nil {
@@ -300,7 +315,7 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	}
 
 	// common https server for webhooks (e.g. injection, validation)
-	if s.kubeClient != nil {
+	if s.kubeClient != nil || args.Standalone {
 		s.initSecureWebhookServer(args)
 		whMu.Lock()
 		wh, err = s.initSidecarInjector(args)
@@ -345,10 +360,45 @@ func NewServer(args *PilotArgs, initFuncs ...func(*Server)) (*Server, error) {
 	if len(features.TrustedGatewayCIDR) > 0 {
 		authenticators = append(authenticators, &authenticate.XfccAuthenticator{})
 	}
+	if len(features.AuthenticatorPlugins) > 0 {
+		pluginOpts := authenticate.PluginOptions{
+			MeshHolder: s.environment.Watcher,
+			ClusterID:  s.clusterID,
+			Namespace:  args.Namespace,
+		}
+		if s.kubeClient != nil {
+			pluginOpts.KubeClient = s.kubeClient.Kube()
+		}
+		plugins, err := authenticate.NewPlugins(features.AuthenticatorPlugins, pluginOpts)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing authenticator plugins: %v", err)
+		}
+		authenticators = append(authenticators, plugins...)
+	}
 	if features.XDSAuth {
 		s.XDSServer.Authenticators = authenticators
 	}
 	caOpts.Authenticators = authenticators
+	if features.EnableVMBootstrapTokens && s.kubeClient != nil {
+		// Bootstrap tokens are consumed when used, so only the CA accepts them: the first certificate of the VM is
+		// issued with the token, and the VM then authenticates to the CA and XDS with its certificate.
+		caOpts.Authenticators = append(append([]security.Authenticator{}, authenticators...),
+			bootstraptoken.NewAuthenticator(s.environment.Watcher, s.kubeClient.Kube(), args.Namespace))
+		s.addStartFunc(func(stop <-chan struct{}) error {
+			go bootstraptoken.RunCleanup(s.kubeClient.Kube(), args.Namespace, time.Hour, stop)
+			return nil
+		})
+	}
+	if features.EnableWriteAPI && s.kubeClient != nil {
+		// The write API is only served over TLS, and only accepts the tokens issued for it.
+		if s.httpsServer == nil {
+			return nil, fmt.Errorf("PILOT_ENABLE_WRITE_API requires the HTTPS webhook server")
+		}
+		writeServer := writeapi.NewServer(s.kubeClient.Kube(), s.kubeClient.Dynamic(), args.Namespace,
+			[]security.Authenticator{writeapi.NewTokenAuthenticator(s.environment.Watcher, s.kubeClient.Kube())})
+		s.httpsMux.Handle(writeapi.Path, writeServer)
+		s.XDSServer.WriteAPI = writeServer
+	}
 
 	// Start CA or RA server. This should be called after CA and Istiod certs have been created.
 	s.startCA(caOpts)
@@ -547,7 +597,12 @@ func (s *Server) initKubeClient(args *PilotArgs) error {
 			return fmt.Errorf("failed creating kube config: %v", err)
 		}
 
-		s.kubeClient, err = kubelib.NewClient(kubelib.NewClientConfigForRestConfig(kubeRestConfig))
+		informerOpts, err := kubelib.ParseInformerOptions(features.InformerResyncPeriods, features.InformerListPageSizes,
+			features.InformerWatchBookmarks)
+		if err != nil {
+			return fmt.Errorf("failed parsing informer options: %v", err)
+		}
+		s.kubeClient, err = kubelib.NewClientWithInformerOptions(kubelib.NewClientConfigForRestConfig(kubeRestConfig), informerOpts)
 		if err != nil {
 			return fmt.Errorf("failed creating kube client: %v", err)
 		}
@@ -928,6 +983,10 @@ func (s *Server) initIstiodCerts(args *PilotArgs, host string) error {
 	var err error
 
 	s.dnsNames = getDNSNames(args, host)
+	if shard := s.XDSServer.ProxyShard; shard.Enabled() {
+		// The proxies of the shard connect to it through the Service of the shard.
+		s.dnsNames = append(s.dnsNames, model.ShardHost(host, shard.Index))
+	}
 	if hasCustomCertArgsOrWellKnown, tlsCertPath, tlsKeyPath, caCertPath := hasCustomTLSCerts(args.ServerOptions.TLSOptions); hasCustomCertArgsOrWellKnown {
 		// Use the DNS certificate provided via args or in well known location.
 		err = s.initCertificateWatches(TLSOptions{
@@ -1106,7 +1165,7 @@ func (s *Server) initControllers(args *PilotArgs) error {
 	if err := s.initCertController(args); err != nil {
 		return fmt.Errorf("error initializing certificate controller: %v", err)
 	}
-	if features.EnableEnhancedResourceScoping {
+	if features.EnableEnhancedResourceScoping && s.multiclusterController != nil {
 		// setup namespace filter
 		args.RegistryOptions.KubeOptions.DiscoveryNamespacesFilter = s.multiclusterController.DiscoveryNamespacesFilter
 	}
@@ -1210,6 +1269,20 @@ func (s *Server) initMeshHandlers() {
 			Reason: []model.TriggerReason{model.GlobalUpdate},
 		})
 	})
+	// When the window of a trust domain transition ends, do a full push to stop accepting the trust domain.
+	for _, t := range trustdomain.Transitions() {
+		if t.Until.IsZero() || !t.Active(time.Now()) {
+			continue
+		}
+		td := t.TrustDomain
+		time.AfterFunc(time.Until(t.Until), func() {
+			log.Infof("transition window of trust domain %s is over", td)
+			s.XDSServer.ConfigUpdate(&model.PushRequest{
+				Full:   true,
+				Reason: []model.TriggerReason{model.GlobalUpdate},
+			})
+		})
+	}
 }
 
 func (s *Server) addIstioCAToTrustBundle(args *PilotArgs) error {
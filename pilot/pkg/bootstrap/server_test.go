@@ -497,6 +497,85 @@ func TestMultiplex(t *testing.T) {
 	})
 }
 
+func TestStandalone(t *testing.T) {
+	configDir := t.TempDir()
+	se := `apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews.default.svc.cluster.local
+  ports:
+  - number: 9080
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 10.1.0.1
+`
+	if err := os.WriteFile(filepath.Join(configDir, "services.yaml"), []byte(se), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := NewPilotArgs(func(p *PilotArgs) {
+		p.Namespace = "istio-system"
+		p.ServerOptions = DiscoveryServerOptions{
+			HTTPAddr:       ":0",
+			MonitoringAddr: ":0",
+			GRPCAddr:       ":0",
+		}
+		p.RegistryOptions = RegistryOptions{
+			FileDir:    configDir,
+			Registries: []string{"Kubernetes"},
+			KubeOptions: kubecontroller.Options{
+				DomainSuffix: constants.DefaultClusterLocalDomain,
+			},
+		}
+		p.ShutdownDuration = 1 * time.Millisecond
+		p.Standalone = true
+	})
+	assert.NoError(t, args.Complete())
+	assert.Equal(t, len(args.RegistryOptions.Registries), 0)
+
+	s, err := NewServer(args)
+	assert.NoError(t, err)
+	stop := make(chan struct{})
+	assert.NoError(t, s.Start(stop))
+	defer func() {
+		close(stop)
+		s.WaitUntilCompletion()
+	}()
+	if s.kubeClient != nil {
+		t.Fatal("standalone istiod created a Kubernetes client")
+	}
+
+	retry.UntilSuccessOrFail(t, func() error {
+		if svc := s.environment.GetService("reviews.default.svc.cluster.local"); svc == nil {
+			return fmt.Errorf("service of the config directory not found")
+		}
+		return nil
+	}, retry.Timeout(time.Second*5))
+
+	c := http.Client{}
+	defer c.CloseIdleConnections()
+	resp, err := c.Get("http://" + s.httpAddr + "/validate")
+	assert.NoError(t, err)
+	// Validate returns 400 on no body; the validation webhook is served without Kubernetes.
+	assert.Equal(t, resp.StatusCode, 400)
+	resp.Body.Close()
+}
+
+func TestStandaloneRequiresConfigDir(t *testing.T) {
+	args := NewPilotArgs(func(p *PilotArgs) {
+		p.Standalone = true
+	})
+	if err := args.Complete(); err == nil {
+		t.Fatal("expected standalone mode without config directory to fail")
+	}
+}
+
 func TestIstiodCipherSuites(t *testing.T) {
 	cases := []struct {
 		name               string
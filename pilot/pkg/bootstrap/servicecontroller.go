@@ -17,10 +17,14 @@ package bootstrap
 import (
 	"fmt"
 
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	kubecontroller "istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/kube/multicluster"
 	"istio.io/pkg/log"
 )
 
@@ -77,6 +81,7 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 	args.RegistryOptions.KubeOptions.MeshServiceController = s.ServiceController()
 	// pass namespace to k8s service registry
 	args.RegistryOptions.KubeOptions.DiscoveryNamespacesFilter = s.multiclusterController.DiscoveryNamespacesFilter
+	args.RegistryOptions.KubeOptions.TrustBundle = s.workloadTrustBundle
 	s.multiclusterController.AddHandler(kubecontroller.NewMulticluster(args.PodName,
 		s.kubeClient.Kube(),
 		args.RegistryOptions.ClusterRegistriesNamespace,
@@ -88,5 +93,16 @@ func (s *Server) initKubeRegistry(args *PilotArgs) (err error) {
 		s.environment.ClusterLocal(),
 		s.server))
 
+	if features.RemoteClusterStaleThreshold > 0 {
+		s.multiclusterController.AddHandler(multicluster.NewHealthMonitor(s.clusterID,
+			features.RemoteClusterHealthCheckInterval,
+			features.RemoteClusterStaleThreshold,
+			func(clusterID cluster.ID, stale bool) {
+				if s.environment.EndpointIndex.SetClusterStale(clusterID, stale) {
+					s.XDSServer.ConfigUpdate(&model.PushRequest{Full: true, Reason: []model.TriggerReason{model.ClusterUpdate}})
+				}
+			}))
+	}
+
 	return
 }
@@ -0,0 +1,48 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstrap
+
+import (
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/leaderelection"
+	"istio.io/istio/pilot/pkg/recommendation"
+	"istio.io/pkg/log"
+)
+
+// initSidecarRecommendationController recommends the default Sidecars of the namespaces from the traffic reported to
+// Prometheus. Only the leader istiod queries Prometheus and writes the Sidecars, and serves the recommendations.
+func (s *Server) initSidecarRecommendationController(args *PilotArgs) error {
+	c, err := recommendation.NewController(s.kubeClient, args.Namespace, recommendation.Options{
+		PrometheusAddress: features.SidecarRecommendationPrometheusAddress,
+		Window:            features.SidecarRecommendationWindow,
+		Interval:          features.SidecarRecommendationInterval,
+		Write:             features.SidecarRecommendationWrite,
+	})
+	if err != nil {
+		return err
+	}
+	s.XDSServer.SidecarRecommendations = c.Recommendations
+	s.addTerminatingStartFunc(func(stop <-chan struct{}) error {
+		leaderelection.
+			NewLeaderElection(args.Namespace, args.PodName, leaderelection.SidecarRecommendationController, args.Revision, s.kubeClient).
+			AddRunFunction(func(leaderStop <-chan struct{}) {
+				log.Infof("Starting Sidecar recommendation controller")
+				c.Run(leaderStop)
+			}).
+			Run(stop)
+		return nil
+	})
+	return nil
+}
@@ -73,13 +73,22 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 		return nil, nil
 	}
 
+	if features.InjectionTemplateOCIReference != "" {
+		var err error
+		watcher, err = inject.NewOCITemplateWatcher(watcher, features.InjectionTemplateOCIReference, features.InjectionTemplateOCIRefreshInterval)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	log.Info("initializing sidecar injector")
 
 	parameters := inject.WebhookParameters{
-		Watcher:  watcher,
-		Env:      s.environment,
-		Mux:      s.httpsMux,
-		Revision: args.Revision,
+		Watcher:    watcher,
+		Env:        s.environment,
+		Mux:        s.httpsMux,
+		Revision:   args.Revision,
+		KubeClient: s.kubeClient,
 	}
 
 	wh, err := inject.NewWebhook(parameters)
@@ -89,7 +98,7 @@ func (s *Server) initSidecarInjector(args *PilotArgs) (*inject.Webhook, error) {
 	// Patch cert if a webhook config name is provided.
 	// This requires RBAC permissions - a low-priv Istiod should not attempt to patch but rely on
 	// operator or CI/CD
-	if features.InjectionWebhookConfigName != "" {
+	if features.InjectionWebhookConfigName != "" && s.kubeClient != nil {
 		s.addStartFunc(func(stop <-chan struct{}) error {
 			// No leader election - different istiod revisions will patch their own cert.
 			// update webhook configuration by watching the cabundle
@@ -23,7 +23,7 @@ import (
 )
 
 func (s *Server) initConfigValidation(args *PilotArgs) error {
-	if s.kubeClient == nil {
+	if s.kubeClient == nil && !args.Standalone {
 		return nil
 	}
 
@@ -34,6 +34,9 @@ func (s *Server) initConfigValidation(args *PilotArgs) error {
 		DomainSuffix: args.RegistryOptions.KubeOptions.DomainSuffix,
 		Mux:          s.httpsMux,
 	}
+	if features.EnableAdmissionCanary {
+		params.Canary = s.XDSServer.ValidateStagedConfig
+	}
 	_, err := server.New(params)
 	if err != nil {
 		return err
@@ -15,6 +15,7 @@
 package crdclient
 
 import (
+	"go.uber.org/atomic"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
@@ -36,6 +37,8 @@ type cacheHandler struct {
 	informer informer.FilteredSharedIndexInformer
 	lister   func(namespace string) cache.GenericNamespaceLister
 	schema   collection.Schema
+	// removed is set once the CRD of the schema is removed, after which informer events are ignored.
+	removed *atomic.Bool
 }
 
 func (h *cacheHandler) onEvent(old any, curr any, event model.Event) error {
@@ -104,6 +107,7 @@ func createCacheHandler(cl *Client, schema collection.Schema, i informers.Generi
 		client:   cl,
 		schema:   schema,
 		informer: informer.NewFilteredSharedIndexInformer(cl.namespacesFilter, i.Informer()),
+		removed:  atomic.NewBool(false),
 	}
 
 	h.lister = func(namespace string) cache.GenericNamespaceLister {
@@ -118,7 +122,7 @@ func createCacheHandler(cl *Client, schema collection.Schema, i informers.Generi
 	h.informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj any) {
 			incrementEvent(kind, "add")
-			if !cl.beginSync.Load() {
+			if !cl.beginSync.Load() || h.removed.Load() {
 				return
 			}
 			cl.queue.Push(func() error {
@@ -127,7 +131,7 @@ func createCacheHandler(cl *Client, schema collection.Schema, i informers.Generi
 		},
 		UpdateFunc: func(old, cur any) {
 			incrementEvent(kind, "update")
-			if !cl.beginSync.Load() {
+			if !cl.beginSync.Load() || h.removed.Load() {
 				return
 			}
 			cl.queue.Push(func() error {
@@ -136,7 +140,7 @@ func createCacheHandler(cl *Client, schema collection.Schema, i informers.Generi
 		},
 		DeleteFunc: func(obj any) {
 			incrementEvent(kind, "delete")
-			if !cl.beginSync.Load() {
+			if !cl.beginSync.Load() || h.removed.Load() {
 				return
 			}
 			cl.queue.Push(func() error {
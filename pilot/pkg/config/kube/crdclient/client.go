@@ -56,6 +56,7 @@ import (
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/queue"
 	"istio.io/pkg/log"
+	"istio.io/pkg/version"
 )
 
 var scope = log.RegisterScope("kube", "Kubernetes client messages", 0)
@@ -73,6 +74,9 @@ type Client struct {
 	// revision for this control plane instance. We will only read configs that match this revision.
 	revision string
 
+	// version of this control plane instance. We will skip the configs pinned to other versions.
+	version string
+
 	// kinds keeps track of all cache handlers for known types
 	kinds   map[config.GroupVersionKind]*cacheHandler
 	kindsMu sync.RWMutex
@@ -104,7 +108,10 @@ type Client struct {
 }
 
 type Option struct {
-	Revision         string
+	Revision string
+	// Version of the control plane, used to skip the configs pinned to other versions. Defaults to the version
+	// of the binary.
+	Version          string
 	DomainSuffix     string
 	Identifier       string
 	NamespacesFilter func(obj interface{}) bool
@@ -136,7 +143,9 @@ func newWaiter() *waiter {
 // indicates the CRD does not exist but the wait failed or was canceled.
 // This is useful to conditionally enable controllers based on CRDs being created.
 func (cl *Client) WaitForCRD(k config.GroupVersionKind, stop <-chan struct{}) bool {
+	cl.kindsMu.RLock()
 	ch, f := cl.crdWatches[k]
+	cl.kindsMu.RUnlock()
 	if !f {
 		log.Warnf("waiting for CRD %s that is not registered", k.String())
 		return false
@@ -156,11 +165,15 @@ func NewForSchemas(client kube.Client, opts Option, schemas collection.Schemas)
 		name := fmt.Sprintf("%s.%s", s.Resource().Plural(), s.Resource().Group())
 		schemasByCRDName[name] = s
 	}
+	if opts.Version == "" {
+		opts.Version = version.Info.Version
+	}
 	out := &Client{
 		domainSuffix:     opts.DomainSuffix,
 		schemas:          schemas,
 		schemasByCRDName: schemasByCRDName,
 		revision:         opts.Revision,
+		version:          opts.Version,
 		queue:            queue.NewQueue(1 * time.Second),
 		kinds:            map[config.GroupVersionKind]*cacheHandler{},
 		handlers:         map[config.GroupVersionKind][]model.EventHandler{},
@@ -254,7 +267,18 @@ func (cl *Client) Run(stop <-chan struct{}) {
 			handleCRDAdd(cl, crd.Name, stop)
 		},
 		UpdateFunc: nil,
-		DeleteFunc: nil,
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			crd, ok := obj.(*metav1.PartialObjectMetadata)
+			if !ok {
+				// Shouldn't happen
+				cl.logger.Errorf("wrong type %T: %v", obj, obj)
+				return
+			}
+			handleCRDRemove(cl, crd.Name)
+		},
 	})
 
 	cl.queue.Run(stop)
@@ -417,7 +441,7 @@ func (cl *Client) List(kind config.GroupVersionKind, namespace string) ([]config
 }
 
 func (cl *Client) objectInRevision(o *config.Config) bool {
-	return config.ObjectInRevision(o, cl.revision)
+	return config.ObjectInRevision(o, cl.revision) && config.ObjectInVersion(o.Annotations, cl.version)
 }
 
 func (cl *Client) allKinds() []*cacheHandler {
@@ -543,6 +567,10 @@ func handleCRDAdd(cl *Client, name string, stop <-chan struct{}) {
 	_ = i.Informer().SetTransform(kube.StripUnusedFields)
 
 	cl.kinds[resourceGVK] = createCacheHandler(cl, s, i)
+	if _, f := collections.Builtin.Find(s.Name().String()); !f {
+		cl.logger.Infof("watching %v, as its CRD %s is present", resourceGVK, name)
+	}
+	setCRDPresent(s.Resource().Kind(), true)
 	if w, f := cl.crdWatches[resourceGVK]; f {
 		cl.logger.Infof("notifying watchers %v was created", resourceGVK)
 		w.once.Do(func() {
@@ -557,6 +585,46 @@ func handleCRDAdd(cl *Client, name string, stop <-chan struct{}) {
 	}
 }
 
+// handleCRDRemove stops handling the events of the resource of the removed CRD, and deletes its objects
+// from the config store. If the CRD is created again, the resource is watched again.
+func handleCRDRemove(cl *Client, name string) {
+	cl.logger.Debugf("removing CRD %q", name)
+	s, f := cl.schemasByCRDName[name]
+	if !f {
+		return
+	}
+	resourceGVK := s.Resource().GroupVersionKind()
+
+	cl.kindsMu.Lock()
+	h, f := cl.kinds[resourceGVK]
+	if !f {
+		cl.kindsMu.Unlock()
+		return
+	}
+	delete(cl.kinds, resourceGVK)
+	if _, f := cl.crdWatches[resourceGVK]; f {
+		// Later waiters wait for the CRD to be created again.
+		cl.crdWatches[resourceGVK] = newWaiter()
+	}
+	cl.kindsMu.Unlock()
+	cl.logger.Infof("stopped watching %v, as its CRD %s was removed", resourceGVK, name)
+	setCRDPresent(s.Resource().Kind(), false)
+
+	// The informer is shared and keeps running so it can be reused if the CRD is created again, so
+	// its handlers are disabled instead.
+	h.removed.Store(true)
+	if !cl.beginSync.Load() {
+		return
+	}
+	// The objects are normally deleted before the CRD, but the deletions may not have been observed yet.
+	for _, obj := range h.informer.GetIndexer().List() {
+		obj := obj
+		cl.queue.Push(func() error {
+			return h.onEvent(nil, obj, model.EventDelete)
+		})
+	}
+}
+
 type starter interface {
 	Start(stopCh <-chan struct{})
 }
@@ -21,6 +21,7 @@ import (
 	"testing"
 	"time"
 
+	"go.uber.org/atomic"
 	"golang.org/x/exp/slices"
 	v1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -150,6 +151,58 @@ func TestClientDelayedCRDs(t *testing.T) {
 	}, retry.Timeout(time.Second*10), retry.Converge(5))
 }
 
+// Ensure that the client stops watching removed CRDs, and watches them again once they are added back
+func TestClientRemovedCRDs(t *testing.T) {
+	schema := collection.NewSchemasBuilder().MustAdd(collections.IstioNetworkingV1Alpha3Virtualservices).Build()
+	store, fake := makeClient(t, schema)
+	r := collections.IstioNetworkingV1Alpha3Virtualservices.Resource()
+	var deletes atomic.Int32
+	store.RegisterEventHandler(r.GroupVersionKind(), func(_, _ config.Config, event model.Event) {
+		if event == model.EventDelete {
+			deletes.Inc()
+		}
+	})
+
+	configMeta := config.Meta{
+		Name:             "name",
+		Namespace:        "ns",
+		GroupVersionKind: r.GroupVersionKind(),
+	}
+	pb, err := r.NewInstance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create(config.Config{
+		Meta: configMeta,
+		Spec: pb,
+	}); err != nil {
+		t.Fatalf("Create => got %v", err)
+	}
+	expectItems := func(n int) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			l, err := store.List(r.GroupVersionKind(), configMeta.Namespace)
+			if err != nil {
+				return fmt.Errorf("expected no error, but got %v", err)
+			}
+			if len(l) != n {
+				return fmt.Errorf("expected %d items, got %d", n, len(l))
+			}
+			return nil
+		}, retry.Timeout(time.Second*10), retry.Converge(5))
+	}
+	expectItems(1)
+
+	deleteCRD(t, fake, r)
+	expectItems(0)
+	retry.UntilOrFail(t, func() bool {
+		return deletes.Load() == 1
+	}, retry.Message("expected the objects of the removed CRD to be deleted"), retry.Timeout(time.Second*5))
+
+	createCRD(t, fake, r)
+	expectItems(1)
+}
+
 // CheckIstioConfigTypes validates that an empty store can do CRUD operators on all given types
 func TestClient(t *testing.T) {
 	store, _ := makeClient(t, collections.PilotGatewayAPI.Union(collections.Kube))
@@ -399,6 +452,45 @@ func TestClientInitialSyncSkipsOtherRevisions(t *testing.T) {
 	assert.Equal(t, expectedCfgs, cfgsAdded)
 }
 
+// TestClientSkipsOtherVersions tests that configs pinned to other versions of the control plane are skipped.
+func TestClientSkipsOtherVersions(t *testing.T) {
+	fake := kube.NewFakeClient()
+	for _, s := range collections.Istio.All() {
+		createCRD(t, fake, s.Resource())
+	}
+
+	annotations := map[string]map[string]string{
+		"unpinned":  nil,
+		"min":       {config.MinVersionAnnotation: "1.17"},
+		"too-old":   {config.MinVersionAnnotation: "1.18"},
+		"too-new":   {config.MaxVersionAnnotation: "1.16"},
+		"in-range":  {config.MinVersionAnnotation: "1.16", config.MaxVersionAnnotation: "1.17"},
+		"max-patch": {config.MaxVersionAnnotation: "1.17.1"},
+	}
+	for name, a := range annotations {
+		obj := &clientnetworkingv1alpha3.ServiceEntry{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "test", Annotations: a},
+			Spec:       v1alpha3.ServiceEntry{},
+		}
+		_, err := fake.Istio().NetworkingV1alpha3().ServiceEntries(obj.Namespace).Create(context.TODO(), obj, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	store, err := New(fake, Option{Version: "1.17.2"})
+	assert.NoError(t, err)
+	var added []string
+	store.RegisterEventHandler(gvk.ServiceEntry, func(old config.Config, curr config.Config, event model.Event) {
+		added = append(added, curr.Name)
+	})
+
+	stop := test.NewStop(t)
+	fake.RunAndWait(stop)
+	store.SyncAll()
+
+	slices.Sort(added)
+	assert.Equal(t, added, []string{"in-range", "min", "unpinned"})
+}
+
 func createCRD(t test.Failer, client kube.Client, r resource.Schema) {
 	t.Helper()
 	crd := &v1.CustomResourceDefinition{
@@ -427,3 +519,21 @@ func createCRD(t test.Failer, client kube.Client, r resource.Schema) {
 		t.Fatal(err)
 	}
 }
+
+func deleteCRD(t test.Failer, client kube.Client, r resource.Schema) {
+	t.Helper()
+	name := fmt.Sprintf("%s.%s", r.Plural(), r.Group())
+	if err := client.Ext().ApiextensionsV1().CustomResourceDefinitions().Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Metadata client fake is not kept in sync, so if using a fake client update that as well
+	fmc, ok := client.Metadata().(*metadatafake.FakeMetadataClient)
+	if !ok {
+		return
+	}
+	fmg := fmc.Resource(collections.K8SApiextensionsK8SIoV1Customresourcedefinitions.Resource().GroupVersionResource())
+	if err := fmg.Delete(context.TODO(), name, metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -27,12 +27,26 @@ var (
 		"Events from k8s config.",
 		monitoring.WithLabels(typeTag, eventTag),
 	)
+
+	crdPresent = monitoring.NewGauge(
+		"pilot_k8s_cfg_crd_present",
+		"Whether the config type is watched, which for custom resources requires their CRD to be installed.",
+		monitoring.WithLabels(typeTag),
+	)
 )
 
 func init() {
-	monitoring.MustRegister(k8sEvents)
+	monitoring.MustRegister(k8sEvents, crdPresent)
 }
 
 func incrementEvent(kind, event string) {
 	k8sEvents.With(typeTag.Value(kind), eventTag.Value(event)).Increment()
 }
+
+func setCRDPresent(kind string, present bool) {
+	v := 0.0
+	if present {
+		v = 1
+	}
+	crdPresent.With(typeTag.Value(kind)).Record(v)
+}
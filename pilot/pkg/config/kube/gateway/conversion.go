@@ -228,9 +228,7 @@ func convertReferencePolicies(r KubernetesResources) AllowedReferences {
 func convertVirtualService(r ConfigContext) []config.Config {
 	result := []config.Config{}
 	for _, obj := range r.TCPRoute {
-		if vsConfig := buildTCPVirtualService(r, obj); vsConfig != nil {
-			result = append(result, *vsConfig)
-		}
+		result = append(result, buildTCPVirtualService(r, obj)...)
 	}
 
 	for _, obj := range r.TLSRoute {
@@ -657,7 +655,7 @@ func extractParentReferenceInfo(gateways map[parentKey]map[k8s.SectionName]*pare
 	return parentRefs
 }
 
-func buildTCPVirtualService(ctx ConfigContext, obj config.Config) *config.Config {
+func buildTCPVirtualService(ctx ConfigContext, obj config.Config) []config.Config {
 	route := obj.Spec.(*k8s.TCPRouteSpec)
 
 	parentRefs := extractParentReferenceInfo(ctx.GatewayReferences, route.ParentRefs, nil, gvk.TCPRoute, obj.Namespace)
@@ -669,50 +667,81 @@ func buildTCPVirtualService(ctx ConfigContext, obj config.Config) *config.Config
 			return rs
 		})
 	}
-	gatewayNames := referencesToInternalNames(parentRefs)
-	if len(gatewayNames) == 0 {
+	gatewayNames, meshHosts := l4Parents(ctx, parentRefs, obj.Namespace)
+	if len(gatewayNames) == 0 && len(meshHosts) == 0 {
 		reportError(nil)
 		return nil
 	}
 
+	var invalidBackendErr *ConfigError
 	routes := []*istio.TCPRoute{}
 	for _, r := range route.Rules {
-		route, err := buildTCPDestination(ctx, r.BackendRefs, obj.Namespace)
+		dest, err := buildTCPDestination(ctx, r.BackendRefs, obj.Namespace, gvk.TCPRoute)
 		if err != nil {
-			reportError(err)
-			return nil
+			if !isInvalidBackend(err) {
+				reportError(err)
+				return nil
+			}
+			invalidBackendErr = err
 		}
-		ir := &istio.TCPRoute{
-			Route: route,
+		if len(dest) == 0 {
+			// No backend can receive connections, they are rejected.
+			continue
 		}
-		routes = append(routes, ir)
+		routes = append(routes, &istio.TCPRoute{
+			Route: dest,
+		})
 	}
 
-	reportError(nil)
-	vsConfig := config.Config{
-		Meta: config.Meta{
-			CreationTimestamp: obj.CreationTimestamp,
-			GroupVersionKind:  gvk.VirtualService,
-			Name:              fmt.Sprintf("%s-tcp-%s", obj.Name, constants.KubernetesGatewayName),
-			Annotations:       routeMeta(obj),
-			Namespace:         obj.Namespace,
-			Domain:            ctx.Domain,
-		},
-		Spec: &istio.VirtualService{
-			// We can use wildcard here since each listener can have at most one route bound to it, so we have
-			// a single VS per Gateway.
-			Hosts:    []string{"*"},
-			Gateways: gatewayNames,
-			Tcp:      routes,
-		},
+	reportError(invalidBackendErr)
+	if len(routes) == 0 {
+		return nil
+	}
+	configs := []config.Config{}
+	if len(gatewayNames) > 0 {
+		configs = append(configs, config.Config{
+			Meta: config.Meta{
+				CreationTimestamp: obj.CreationTimestamp,
+				GroupVersionKind:  gvk.VirtualService,
+				Name:              fmt.Sprintf("%s-tcp-%s", obj.Name, constants.KubernetesGatewayName),
+				Annotations:       routeMeta(obj),
+				Namespace:         obj.Namespace,
+				Domain:            ctx.Domain,
+			},
+			Spec: &istio.VirtualService{
+				// We can use wildcard here since each listener can have at most one route bound to it, so we have
+				// a single VS per Gateway.
+				Hosts:    []string{"*"},
+				Gateways: gatewayNames,
+				Tcp:      routes,
+			},
+		})
 	}
-	return &vsConfig
+	for i, h := range meshHosts {
+		// For mesh routes, the routes apply to the connections to the parent Service only.
+		configs = append(configs, config.Config{
+			Meta: config.Meta{
+				CreationTimestamp: obj.CreationTimestamp,
+				GroupVersionKind:  gvk.VirtualService,
+				Name:              fmt.Sprintf("%s-tcp-mesh-%d-%s", obj.Name, i, constants.KubernetesGatewayName),
+				Annotations:       routeMeta(obj),
+				Namespace:         obj.Namespace,
+				Domain:            ctx.Domain,
+			},
+			Spec: &istio.VirtualService{
+				Hosts:    []string{h},
+				Gateways: []string{constants.IstioMeshGateway},
+				Tcp:      routes,
+			},
+		})
+	}
+	return configs
 }
 
 func buildTLSVirtualService(ctx ConfigContext, obj config.Config) []config.Config {
 	route := obj.Spec.(*k8s.TLSRouteSpec)
 
-	parentRefs := extractParentReferenceInfo(ctx.GatewayReferences, route.ParentRefs, nil, gvk.TLSRoute, obj.Namespace)
+	parentRefs := extractParentReferenceInfo(ctx.GatewayReferences, route.ParentRefs, route.Hostnames, gvk.TLSRoute, obj.Namespace)
 
 	reportError := func(routeErr *ConfigError) {
 		obj.Status.(*kstatus.WrappedStatus).Mutate(func(s config.Status) config.Status {
@@ -722,60 +751,117 @@ func buildTLSVirtualService(ctx ConfigContext, obj config.Config) []config.Confi
 		})
 	}
 
-	routes := []*istio.TLSRoute{}
+	var invalidBackendErr *ConfigError
+	destinations := [][]*istio.RouteDestination{}
 	for _, r := range route.Rules {
-		dest, err := buildTCPDestination(ctx, r.BackendRefs, obj.Namespace)
+		dest, err := buildTCPDestination(ctx, r.BackendRefs, obj.Namespace, gvk.TLSRoute)
 		if err != nil {
-			reportError(err)
-			return nil
+			if !isInvalidBackend(err) {
+				reportError(err)
+				return nil
+			}
+			invalidBackendErr = err
 		}
 		if len(dest) == 0 {
-			return nil
-		}
-		ir := &istio.TLSRoute{
-			Match: buildTLSMatch(route.Hostnames),
-			Route: dest,
+			// No backend can receive connections, they are rejected.
+			continue
 		}
-		routes = append(routes, ir)
+		destinations = append(destinations, dest)
 	}
 
-	reportError(nil)
-	gatewayNames := referencesToInternalNames(parentRefs)
-	if len(gatewayNames) == 0 {
-		// TODO we need to properly return not admitted here
+	reportError(invalidBackendErr)
+	gatewayNames, meshHosts := l4Parents(ctx, parentRefs, obj.Namespace)
+	if len(destinations) == 0 || (len(gatewayNames) == 0 && len(meshHosts) == 0) {
 		return nil
 	}
-	configs := make([]config.Config, 0, len(route.Hostnames))
-	for i, host := range hostnameToStringList(route.Hostnames) {
-		name := fmt.Sprintf("%s-tls-%d-%s", obj.Name, i, constants.KubernetesGatewayName)
-		// Create one VS per hostname with a single hostname.
-		// This ensures we can treat each hostname independently, as the spec requires
-		vsConfig := config.Config{
+	// tlsRoutes matches the routes on the server names.
+	tlsRoutes := func(sniHosts []string) []*istio.TLSRoute {
+		routes := make([]*istio.TLSRoute, 0, len(destinations))
+		for _, dest := range destinations {
+			routes = append(routes, &istio.TLSRoute{
+				Match: []*istio.TLSMatchAttributes{{SniHosts: sniHosts}},
+				Route: dest,
+			})
+		}
+		return routes
+	}
+	configs := []config.Config{}
+	if len(gatewayNames) > 0 {
+		for i, host := range hostnameToStringList(route.Hostnames) {
+			name := fmt.Sprintf("%s-tls-%d-%s", obj.Name, i, constants.KubernetesGatewayName)
+			// Create one VS per hostname with a single hostname.
+			// This ensures we can treat each hostname independently, as the spec requires
+			configs = append(configs, config.Config{
+				Meta: config.Meta{
+					CreationTimestamp: obj.CreationTimestamp,
+					GroupVersionKind:  gvk.VirtualService,
+					Name:              name,
+					Annotations:       routeMeta(obj),
+					Namespace:         obj.Namespace,
+					Domain:            ctx.Domain,
+				},
+				Spec: &istio.VirtualService{
+					Hosts:    []string{host},
+					Gateways: gatewayNames,
+					Tls:      tlsRoutes([]string{host}),
+				},
+			})
+		}
+	}
+	for i, h := range meshHosts {
+		// For mesh routes, the connections to the parent Service are matched on the hostnames of the route, or the
+		// hostname of the Service if the route has none.
+		sniHosts := []string{h}
+		if len(route.Hostnames) > 0 {
+			sniHosts = hostnameToStringList(route.Hostnames)
+		}
+		configs = append(configs, config.Config{
 			Meta: config.Meta{
 				CreationTimestamp: obj.CreationTimestamp,
 				GroupVersionKind:  gvk.VirtualService,
-				Name:              name,
+				Name:              fmt.Sprintf("%s-tls-mesh-%d-%s", obj.Name, i, constants.KubernetesGatewayName),
 				Annotations:       routeMeta(obj),
 				Namespace:         obj.Namespace,
 				Domain:            ctx.Domain,
 			},
 			Spec: &istio.VirtualService{
-				Hosts:    []string{host},
-				Gateways: gatewayNames,
-				Tls:      routes,
+				Hosts:    sets.SortedList(sets.New(sniHosts...).Insert(h)),
+				Gateways: []string{constants.IstioMeshGateway},
+				Tls:      tlsRoutes(sniHosts),
 			},
-		}
-		configs = append(configs, vsConfig)
+		})
 	}
 	return configs
 }
 
-func buildTCPDestination(ctx ConfigContext, forwardTo []k8s.BackendRef, ns string) ([]*istio.RouteDestination, *ConfigError) {
+// l4Parents returns the internal names of the Gateways a TCP or TLS route is bound to, and the hostnames of the
+// Services it is bound to for mesh routing.
+func l4Parents(ctx ConfigContext, parents []routeParentReference, ns string) ([]string, []string) {
+	gateways := []string{}
+	meshHosts := sets.New[string]()
+	for _, p := range filteredReferences(parents) {
+		if p.InternalName == constants.IstioMeshGateway {
+			meshHosts.Insert(fmt.Sprintf("%s.%s.svc.%s",
+				p.OriginalReference.Name, defaultIfNil((*string)(p.OriginalReference.Namespace), ns), ctx.Domain))
+			continue
+		}
+		gateways = append(gateways, p.InternalName)
+	}
+	return gateways, sets.SortedList(meshHosts)
+}
+
+// buildTCPDestination builds the weighted destinations of a TCP or TLS route rule. Backends with a zero weight
+// are skipped. Invalid backends are dropped, and the error is returned along with the valid destinations.
+func buildTCPDestination(
+	ctx ConfigContext,
+	forwardTo []k8s.BackendRef,
+	ns string,
+	kind config.GroupVersionKind,
+) ([]*istio.RouteDestination, *ConfigError) {
 	if forwardTo == nil {
 		return nil, nil
 	}
 
-	refs := ctx.AllowedReferences
 	weights := []int{}
 	action := []k8s.BackendRef{}
 	for i, w := range forwardTo {
@@ -792,44 +878,26 @@ func buildTCPDestination(ctx ConfigContext, forwardTo []k8s.BackendRef, ns strin
 	if len(weights) == 1 {
 		weights = []int{0}
 	}
+	var invalidBackendErr *ConfigError
 	res := []*istio.RouteDestination{}
 	for i, fwd := range action {
-		if toNs := fwd.Namespace; toNs != nil && string(*toNs) != ns {
-			if !refs.BackendAllowed(gvk.HTTPRoute, fwd.Name, *toNs, ns) {
-				return nil, &ConfigError{
-					Reason:  InvalidDestinationPermit,
-					Message: fmt.Sprintf("backendRef %v/%v not accessible to a route in namespace %q (missing a ReferenceGrant?)", fwd.Name, *toNs, ns),
-				}
-			}
-		}
-		dst, err := buildDestination(ctx, fwd, ns)
+		dst, err := buildDestination(ctx, fwd, ns, kind)
 		if err != nil {
-			return nil, err
+			if !isInvalidBackend(err) {
+				return nil, err
+			}
+			invalidBackendErr = err
+			if dst.GetHost() == "" {
+				// The backend is not accessible, so there is no destination to send its share of connections to.
+				continue
+			}
 		}
 		res = append(res, &istio.RouteDestination{
 			Destination: dst,
 			Weight:      int32(weights[i]),
 		})
 	}
-	return res, nil
-}
-
-func buildTLSMatch(hostnames []k8s.Hostname) []*istio.TLSMatchAttributes {
-	// Currently, the spec only supports extensions beyond hostname, which are not currently implemented by Istio.
-	return []*istio.TLSMatchAttributes{{
-		SniHosts: hostnamesToStringListWithWildcard(hostnames),
-	}}
-}
-
-func hostnamesToStringListWithWildcard(h []k8s.Hostname) []string {
-	if len(h) == 0 {
-		return []string{"*"}
-	}
-	res := make([]string, 0, len(h))
-	for _, i := range h {
-		res = append(res, string(i))
-	}
-	return res
+	return res, invalidBackendErr
 }
 
 func buildHTTPDestination(
@@ -863,7 +931,7 @@ func buildHTTPDestination(
 	var invalidBackendErr *ConfigError
 	res := []*istio.HTTPRouteDestination{}
 	for i, fwd := range action {
-		dst, err := buildDestination(ctx, fwd.BackendRef, ns)
+		dst, err := buildDestination(ctx, fwd.BackendRef, ns, gvk.HTTPRoute)
 		if err != nil {
 			if isInvalidBackend(err) {
 				invalidBackendErr = err
@@ -905,11 +973,11 @@ func buildHTTPDestination(
 	return res, invalidBackendErr
 }
 
-func buildDestination(ctx ConfigContext, to k8s.BackendRef, ns string) (*istio.Destination, *ConfigError) {
+func buildDestination(ctx ConfigContext, to k8s.BackendRef, ns string, kind config.GroupVersionKind) (*istio.Destination, *ConfigError) {
 	// check if the reference is allowed
 	refs := ctx.AllowedReferences
 	if toNs := to.Namespace; toNs != nil && string(*toNs) != ns {
-		if !refs.BackendAllowed(gvk.HTTPRoute, to.Name, *toNs, ns) {
+		if !refs.BackendAllowed(kind, to.Name, *toNs, ns) {
 			return &istio.Destination{}, &ConfigError{
 				Reason:  InvalidDestinationPermit,
 				Message: fmt.Sprintf("backendRef %v/%v not accessible to a route in namespace %q (missing a ReferenceGrant?)", to.Name, *toNs, ns),
@@ -1016,7 +1084,7 @@ func createMirrorFilter(ctx ConfigContext, filter *k8s.HTTPRequestMirrorFilter,
 	return buildDestination(ctx, k8s.BackendRef{
 		BackendObjectReference: filter.BackendRef,
 		Weight:                 &weightOne,
-	}, ns)
+	}, ns, gvk.HTTPRoute)
 }
 
 func createRewriteFilter(filter *k8s.HTTPURLRewriteFilter) *istio.HTTPRewrite {
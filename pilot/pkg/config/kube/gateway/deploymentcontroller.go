@@ -37,6 +37,7 @@ import (
 	lister "sigs.k8s.io/gateway-api/pkg/client/listers/apis/v1beta1"
 	"sigs.k8s.io/yaml"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
@@ -73,6 +74,7 @@ type DeploymentController struct {
 	queue              controllers.Queue
 	templates          *template.Template
 	patcher            patcher
+	deleter            deleter
 	gatewayLister      lister.GatewayLister
 	gatewayClassLister lister.GatewayClassLister
 
@@ -89,6 +91,9 @@ type DeploymentController struct {
 // Patcher is a function that abstracts patching logic. This is largely because client-go fakes do not handle patching
 type patcher func(gvr schema.GroupVersionResource, name string, namespace string, data []byte, subresources ...string) error
 
+// deleter is a function that abstracts deletion logic, for the same reasons as patcher.
+type deleter func(gvr schema.GroupVersionResource, name string, namespace string) error
+
 // NewDeploymentController constructs a DeploymentController and registers required informers.
 // The controller will not start until Run() is called.
 func NewDeploymentController(client kube.Client) *DeploymentController {
@@ -106,6 +111,10 @@ func NewDeploymentController(client kube.Client) *DeploymentController {
 			}, subresources...)
 			return err
 		},
+		deleter: func(gvr schema.GroupVersionResource, name string, namespace string) error {
+			err := client.Dynamic().Resource(gvr).Namespace(namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+			return controllers.IgnoreNotFound(err)
+		},
 		gatewayLister:      gw.Lister(),
 		gatewayClassLister: gwc.Lister(),
 	}
@@ -203,18 +212,53 @@ func (d *DeploymentController) configureIstioGateway(log *istiolog.Scope, gw gat
 	}
 	log.Info("reconciling")
 
+	infra, err := extractInfrastructure(gw)
+	if err != nil {
+		return err
+	}
+
 	svc := serviceInput{Gateway: &gw, Ports: extractServicePorts(gw)}
 	if err := d.ApplyTemplate("service.yaml", svc); err != nil {
 		return fmt.Errorf("update service: %v", err)
 	}
 	log.Info("service updated")
 
-	dep := deploymentInput{Gateway: &gw, KubeVersion122: kube.IsAtLeastVersion(d.client, 22)}
+	// The previous Deployment carries the annotations of the previous Gateway. This tells us whether an
+	// autoscaler or disruption budget was rendered before, and must be removed now.
+	previous := d.previousAnnotations(gw)
+
+	dep := deploymentInput{Gateway: &gw, KubeVersion122: kube.IsAtLeastVersion(d.client, 22), Infrastructure: infra}
 	if err := d.ApplyTemplate("deployment.yaml", dep); err != nil {
 		return fmt.Errorf("update deployment: %v", err)
 	}
 	log.Info("deployment updated")
 
+	if infra.Autoscaling != nil {
+		hpa := autoscalerInput{Gateway: &gw, KubeVersion123: kube.IsAtLeastVersion(d.client, 23), Autoscaling: infra.Autoscaling}
+		if err := d.ApplyTemplate("horizontal-pod-autoscaler.yaml", hpa); err != nil {
+			return fmt.Errorf("update horizontal pod autoscaler: %v", err)
+		}
+		log.Info("horizontal pod autoscaler updated")
+	} else if hasAutoscaling(previous) {
+		if err := d.deleter(horizontalPodAutoscalerGVR(d.client), gw.Name, gw.Namespace); err != nil {
+			return fmt.Errorf("delete horizontal pod autoscaler: %v", err)
+		}
+		log.Info("horizontal pod autoscaler deleted")
+	}
+
+	if infra.DisruptionBudget != nil {
+		pdb := disruptionBudgetInput{Gateway: &gw, DisruptionBudget: infra.DisruptionBudget}
+		if err := d.ApplyTemplate("pod-disruption-budget.yaml", pdb); err != nil {
+			return fmt.Errorf("update pod disruption budget: %v", err)
+		}
+		log.Info("pod disruption budget updated")
+	} else if hasDisruptionBudget(previous) {
+		if err := d.deleter(podDisruptionBudgetGVR, gw.Name, gw.Namespace); err != nil {
+			return fmt.Errorf("delete pod disruption budget: %v", err)
+		}
+		log.Info("pod disruption budget deleted")
+	}
+
 	gws := &gateway.Gateway{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       gvk.KubernetesGateway.Kind,
@@ -257,7 +301,7 @@ func (d *DeploymentController) ApplyTemplate(template string, input metav1.Objec
 		return err
 	}
 	us := unstructured.Unstructured{Object: data}
-	gvr, err := controllers.UnstructuredToGVR(us)
+	gvr, err := templateGVR(us)
 	if err != nil {
 		return err
 	}
@@ -308,6 +352,18 @@ type serviceInput struct {
 type deploymentInput struct {
 	*gateway.Gateway
 	KubeVersion122 bool
+	Infrastructure infrastructure
+}
+
+type autoscalerInput struct {
+	*gateway.Gateway
+	KubeVersion123 bool
+	Autoscaling    *autoscaling
+}
+
+type disruptionBudgetInput struct {
+	*gateway.Gateway
+	DisruptionBudget *disruptionBudget
 }
 
 func extractServicePorts(gw gateway.Gateway) []corev1.ServicePort {
@@ -316,6 +372,7 @@ func extractServicePorts(gw gateway.Gateway) []corev1.ServicePort {
 	svcPorts = append(svcPorts, corev1.ServicePort{
 		Name:        "status-port",
 		Port:        int32(15021),
+		Protocol:    corev1.ProtocolTCP,
 		AppProtocol: &tcp,
 	})
 	portNums := map[int32]struct{}{}
@@ -333,8 +390,28 @@ func extractServicePorts(gw gateway.Gateway) []corev1.ServicePort {
 		svcPorts = append(svcPorts, corev1.ServicePort{
 			Name:        name,
 			Port:        int32(l.Port),
+			Protocol:    corev1.ProtocolTCP,
 			AppProtocol: &appProtocol,
 		})
+		if isEligibleForHTTP3(l) {
+			// HTTP/3 is served over QUIC, which requires the same port to be exposed over UDP as well.
+			http3 := "http3"
+			svcPorts = append(svcPorts, corev1.ServicePort{
+				Name:        name + "-quic",
+				Port:        int32(l.Port),
+				Protocol:    corev1.ProtocolUDP,
+				AppProtocol: &http3,
+			})
+		}
 	}
 	return svcPorts
 }
+
+// isEligibleForHTTP3 returns true if a QUIC listener will be generated for the listener, which is the case
+// for HTTPS listeners terminating TLS when PILOT_ENABLE_QUIC_LISTENERS is set.
+func isEligibleForHTTP3(l gateway.Listener) bool {
+	if !features.EnableQUICListeners || l.Protocol != gateway.HTTPSProtocolType {
+		return false
+	}
+	return l.TLS == nil || l.TLS.Mode == nil || *l.TLS.Mode == gateway.TLSModeTerminate
+}
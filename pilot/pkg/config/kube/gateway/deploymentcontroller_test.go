@@ -16,9 +16,11 @@ package gateway
 
 import (
 	"bytes"
+	"fmt"
 	"path/filepath"
 	"testing"
 
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -26,8 +28,11 @@ import (
 	"sigs.k8s.io/gateway-api/apis/v1beta1"
 	"sigs.k8s.io/yaml"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/test/util"
 	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
 	istiolog "istio.io/pkg/log"
 )
 
@@ -95,6 +100,24 @@ func TestConfigureIstioGateway(t *testing.T) {
 				},
 			},
 		},
+		{
+			"infrastructure",
+			v1beta1.Gateway{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "default",
+					Namespace: "default",
+					Annotations: map[string]string{
+						autoscalingMinReplicasAnnotation: "2",
+						autoscalingMaxReplicasAnnotation: "5",
+						pdbMinAvailableAnnotation:        "1",
+						topologySpreadKeysAnnotation:     "topology.kubernetes.io/zone,kubernetes.io/hostname",
+						cpuRequestAnnotation:             "100m",
+						memoryRequestAnnotation:          "128Mi",
+					},
+				},
+				Spec: v1alpha2.GatewaySpec{},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -111,6 +134,10 @@ func TestConfigureIstioGateway(t *testing.T) {
 					buf.Write([]byte("---\n"))
 					return nil
 				},
+				deleter: func(gvr schema.GroupVersionResource, name string, namespace string) error {
+					t.Fatalf("unexpected deletion of %v %v/%v", gvr, namespace, name)
+					return nil
+				},
 			}
 			err := d.configureIstioGateway(istiolog.FindScope(istiolog.DefaultScopeName), tt.gw)
 			if err != nil {
@@ -122,3 +149,135 @@ func TestConfigureIstioGateway(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigureIstioGatewayDeletesInfrastructure(t *testing.T) {
+	client := kube.NewFakeClient()
+	deployments := client.KubeInformer().Apps().V1().Deployments().Informer()
+	// The Deployment rendered for the Gateway with an autoscaler and a disruption budget.
+	_ = deployments.GetStore().Add(&appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "default",
+			Namespace: "default",
+			Annotations: map[string]string{
+				autoscalingMaxReplicasAnnotation: "5",
+				pdbMaxUnavailableAnnotation:      "25%",
+			},
+		},
+	})
+	deleted := []string{}
+	d := &DeploymentController{
+		client:             client,
+		templates:          processTemplates(),
+		deploymentInformer: deployments,
+		patcher: func(gvr schema.GroupVersionResource, name string, namespace string, data []byte, subresources ...string) error {
+			return nil
+		},
+		deleter: func(gvr schema.GroupVersionResource, name string, namespace string) error {
+			deleted = append(deleted, fmt.Sprintf("%s/%s/%s", gvr.Resource, namespace, name))
+			return nil
+		},
+	}
+	gw := v1beta1.Gateway{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "default"}}
+	if err := d.configureIstioGateway(istiolog.FindScope(istiolog.DefaultScopeName), gw); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, deleted, []string{"horizontalpodautoscalers/default/default", "poddisruptionbudgets/default/default"})
+}
+
+func TestExtractInfrastructure(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        infrastructure
+		wantErr     bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:        "default min replicas",
+			annotations: map[string]string{autoscalingMaxReplicasAnnotation: "3"},
+			want:        infrastructure{Autoscaling: &autoscaling{MinReplicas: 1, MaxReplicas: 3}},
+		},
+		{
+			name:        "min replicas without max",
+			annotations: map[string]string{autoscalingMinReplicasAnnotation: "3"},
+			wantErr:     true,
+		},
+		{
+			name:        "min greater than max",
+			annotations: map[string]string{autoscalingMinReplicasAnnotation: "4", autoscalingMaxReplicasAnnotation: "3"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid max replicas",
+			annotations: map[string]string{autoscalingMaxReplicasAnnotation: "0"},
+			wantErr:     true,
+		},
+		{
+			name:        "max unavailable percentage",
+			annotations: map[string]string{pdbMaxUnavailableAnnotation: "25%"},
+			want:        infrastructure{DisruptionBudget: &disruptionBudget{MaxUnavailable: "25%"}},
+		},
+		{
+			name:        "min available and max unavailable",
+			annotations: map[string]string{pdbMinAvailableAnnotation: "1", pdbMaxUnavailableAnnotation: "1"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid min available",
+			annotations: map[string]string{pdbMinAvailableAnnotation: "half"},
+			wantErr:     true,
+		},
+		{
+			name:        "empty topology key",
+			annotations: map[string]string{topologySpreadKeysAnnotation: "topology.kubernetes.io/zone,"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid cpu request",
+			annotations: map[string]string{cpuRequestAnnotation: "lots"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractInfrastructure(v1beta1.Gateway{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
+
+func TestExtractServicePortsHTTP3(t *testing.T) {
+	passthrough := v1beta1.TLSModePassthrough
+	gw := v1beta1.Gateway{
+		Spec: v1beta1.GatewaySpec{
+			Listeners: []v1beta1.Listener{
+				{Name: "https", Port: 443, Protocol: v1beta1.HTTPSProtocolType},
+				{Name: "https-alt", Port: 443, Protocol: v1beta1.HTTPSProtocolType},
+				{Name: "tls", Port: 8443, Protocol: v1beta1.TLSProtocolType, TLS: &v1beta1.GatewayTLSConfig{Mode: &passthrough}},
+			},
+		},
+	}
+	portsOf := func(ports []corev1.ServicePort) []string {
+		res := []string{}
+		for _, p := range ports {
+			res = append(res, fmt.Sprintf("%s/%d/%s", p.Name, p.Port, p.Protocol))
+		}
+		return res
+	}
+
+	assert.Equal(t, portsOf(extractServicePorts(gw)), []string{"status-port/15021/TCP", "https/443/TCP", "tls/8443/TCP"})
+
+	test.SetForTest(t, &features.EnableQUICListeners, true)
+	assert.Equal(t, portsOf(extractServicePorts(gw)), []string{"status-port/15021/TCP", "https/443/TCP", "https-quic/443/UDP", "tls/8443/TCP"})
+}
@@ -0,0 +1,217 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	gateway "sigs.k8s.io/gateway-api/apis/v1beta1"
+
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+)
+
+// Annotations on a Gateway tuning the infrastructure rendered by the deployment controller.
+// TODO: move to API
+const (
+	autoscalingMinReplicasAnnotation = "gateway.istio.io/autoscaling-min-replicas"
+	autoscalingMaxReplicasAnnotation = "gateway.istio.io/autoscaling-max-replicas"
+	pdbMinAvailableAnnotation        = "gateway.istio.io/pdb-min-available"
+	pdbMaxUnavailableAnnotation      = "gateway.istio.io/pdb-max-unavailable"
+	topologySpreadKeysAnnotation     = "gateway.istio.io/topology-spread-keys"
+	cpuRequestAnnotation             = "gateway.istio.io/cpu-request"
+	memoryRequestAnnotation          = "gateway.istio.io/memory-request"
+)
+
+var (
+	podDisruptionBudgetGVR = schema.GroupVersionResource{Group: "policy", Version: "v1", Resource: "poddisruptionbudgets"}
+
+	horizontalPodAutoscalerV2GVR      = schema.GroupVersionResource{Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"}
+	horizontalPodAutoscalerV2beta2GVR = schema.GroupVersionResource{Group: "autoscaling", Version: "v2beta2", Resource: "horizontalpodautoscalers"}
+)
+
+// infrastructure holds the settings of the resources rendered for a Gateway.
+type infrastructure struct {
+	// Autoscaling, if set, renders a HorizontalPodAutoscaler for the Deployment.
+	Autoscaling *autoscaling
+	// DisruptionBudget, if set, renders a PodDisruptionBudget for the pods of the Deployment.
+	DisruptionBudget *disruptionBudget
+	// TopologySpreadKeys are the topology keys the pods are spread over.
+	TopologySpreadKeys []string
+	// CPURequest and MemoryRequest are the resource requests of the proxy container.
+	CPURequest    string
+	MemoryRequest string
+}
+
+type autoscaling struct {
+	MinReplicas int32
+	MaxReplicas int32
+}
+
+// disruptionBudget holds either the minimum number of available pods or the maximum number of unavailable
+// pods, as an integer or a percentage.
+type disruptionBudget struct {
+	MinAvailable   string
+	MaxUnavailable string
+}
+
+// extractInfrastructure reads the infrastructure settings from the annotations of the Gateway.
+func extractInfrastructure(gw gateway.Gateway) (infrastructure, error) {
+	infra := infrastructure{}
+	annotations := gw.Annotations
+
+	if v, f := annotations[autoscalingMaxReplicasAnnotation]; f {
+		max, err := parseReplicas(autoscalingMaxReplicasAnnotation, v)
+		if err != nil {
+			return infra, err
+		}
+		min := int32(1)
+		if v, f := annotations[autoscalingMinReplicasAnnotation]; f {
+			if min, err = parseReplicas(autoscalingMinReplicasAnnotation, v); err != nil {
+				return infra, err
+			}
+		}
+		if min > max {
+			return infra, fmt.Errorf("annotation %v (%d) must not be greater than %v (%d)",
+				autoscalingMinReplicasAnnotation, min, autoscalingMaxReplicasAnnotation, max)
+		}
+		infra.Autoscaling = &autoscaling{MinReplicas: min, MaxReplicas: max}
+	} else if _, f := annotations[autoscalingMinReplicasAnnotation]; f {
+		return infra, fmt.Errorf("annotation %v requires %v", autoscalingMinReplicasAnnotation, autoscalingMaxReplicasAnnotation)
+	}
+
+	minAvailable, hasMin := annotations[pdbMinAvailableAnnotation]
+	maxUnavailable, hasMax := annotations[pdbMaxUnavailableAnnotation]
+	switch {
+	case hasMin && hasMax:
+		return infra, fmt.Errorf("only one of annotations %v and %v may be set", pdbMinAvailableAnnotation, pdbMaxUnavailableAnnotation)
+	case hasMin:
+		if err := validateIntOrPercent(pdbMinAvailableAnnotation, minAvailable); err != nil {
+			return infra, err
+		}
+		infra.DisruptionBudget = &disruptionBudget{MinAvailable: minAvailable}
+	case hasMax:
+		if err := validateIntOrPercent(pdbMaxUnavailableAnnotation, maxUnavailable); err != nil {
+			return infra, err
+		}
+		infra.DisruptionBudget = &disruptionBudget{MaxUnavailable: maxUnavailable}
+	}
+
+	if v, f := annotations[topologySpreadKeysAnnotation]; f {
+		for _, key := range strings.Split(v, ",") {
+			key = strings.TrimSpace(key)
+			if key == "" {
+				return infra, fmt.Errorf("annotation %v has an empty topology key: %q", topologySpreadKeysAnnotation, v)
+			}
+			infra.TopologySpreadKeys = append(infra.TopologySpreadKeys, key)
+		}
+	}
+
+	for _, r := range []struct {
+		annotation string
+		out        *string
+	}{
+		{cpuRequestAnnotation, &infra.CPURequest},
+		{memoryRequestAnnotation, &infra.MemoryRequest},
+	} {
+		v, f := annotations[r.annotation]
+		if !f {
+			continue
+		}
+		if _, err := resource.ParseQuantity(v); err != nil {
+			return infra, fmt.Errorf("annotation %v is not a valid quantity: %v", r.annotation, err)
+		}
+		*r.out = v
+	}
+	return infra, nil
+}
+
+func parseReplicas(annotation, v string) (int32, error) {
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("annotation %v must be a positive integer, got %q", annotation, v)
+	}
+	return int32(n), nil
+}
+
+func validateIntOrPercent(annotation, v string) error {
+	iv := intstr.Parse(v)
+	if _, err := intstr.GetScaledValueFromIntOrPercent(&iv, 100, true); err != nil || strings.HasPrefix(v, "-") {
+		return fmt.Errorf("annotation %v must be a non-negative integer or percentage, got %q", annotation, v)
+	}
+	return nil
+}
+
+func hasAutoscaling(annotations map[string]string) bool {
+	_, f := annotations[autoscalingMaxReplicasAnnotation]
+	return f
+}
+
+func hasDisruptionBudget(annotations map[string]string) bool {
+	_, min := annotations[pdbMinAvailableAnnotation]
+	_, max := annotations[pdbMaxUnavailableAnnotation]
+	return min || max
+}
+
+// previousAnnotations returns the annotations of the Deployment currently rendered for the Gateway, which
+// were copied from the Gateway when it was last reconciled.
+func (d *DeploymentController) previousAnnotations(gw gateway.Gateway) map[string]string {
+	if d.deploymentInformer == nil {
+		return nil
+	}
+	obj, f, _ := d.deploymentInformer.GetStore().GetByKey(gw.Namespace + "/" + gw.Name)
+	if !f {
+		return nil
+	}
+	dep, ok := obj.(*appsv1.Deployment)
+	if !ok {
+		return nil
+	}
+	return dep.Annotations
+}
+
+// horizontalPodAutoscalerGVR returns the HorizontalPodAutoscaler resource served by the cluster;
+// autoscaling/v2 is only available since Kubernetes 1.23.
+func horizontalPodAutoscalerGVR(client kube.Client) schema.GroupVersionResource {
+	if kube.IsAtLeastVersion(client, 23) {
+		return horizontalPodAutoscalerV2GVR
+	}
+	return horizontalPodAutoscalerV2beta2GVR
+}
+
+// templateGVR extracts the GVR of a rendered template. The autoscaler and disruption budget are not
+// part of the Istio schemas, so they are resolved here.
+func templateGVR(us unstructured.Unstructured) (schema.GroupVersionResource, error) {
+	gv, err := schema.ParseGroupVersion(us.GetAPIVersion())
+	if err != nil {
+		return schema.GroupVersionResource{}, err
+	}
+	switch gv.WithKind(us.GetKind()) {
+	case podDisruptionBudgetGVR.GroupVersion().WithKind("PodDisruptionBudget"):
+		return podDisruptionBudgetGVR, nil
+	case horizontalPodAutoscalerV2GVR.GroupVersion().WithKind("HorizontalPodAutoscaler"):
+		return horizontalPodAutoscalerV2GVR, nil
+	case horizontalPodAutoscalerV2beta2GVR.GroupVersion().WithKind("HorizontalPodAutoscaler"):
+		return horizontalPodAutoscalerV2beta2GVR, nil
+	}
+	return controllers.UnstructuredToGVR(us)
+}
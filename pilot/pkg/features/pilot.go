@@ -233,8 +233,9 @@ var (
 	EnableDistributionTracking = env.Register(
 		"PILOT_ENABLE_CONFIG_DISTRIBUTION_TRACKING",
 		false,
-		"If enabled, Pilot will assign meaningful nonces to each Envoy configuration message, and allow "+
-			"users to interrogate which envoy has which config from the debug interface.",
+		"If enabled, Pilot will assign meaningful nonces to each Envoy configuration message, and report "+
+			"the distribution of the config resources in their status. The distribution of each resource is available "+
+			"from the debug interface regardless of this setting.",
 	).Get()
 
 	DistributionHistoryRetention = env.Register(
@@ -409,9 +410,50 @@ var (
 	EnableUnsafeAdminEndpoints = env.Register("UNSAFE_ENABLE_ADMIN_ENDPOINTS", false,
 		"If this is set to true, dangerous admin endpoints will be exposed on the debug interface. Not recommended for production.").Get()
 
+	EnableWriteAPI = env.Register("PILOT_ENABLE_WRITE_API", false,
+		"If enabled, istiod serves the write API on the webhook port, performing the istioctl operations "+
+			"run with --through-istiod when they are allowed by the policy of the istio-write-policy ConfigMap.").Get()
+
+	DebugAdminIdentities = func() sets.String {
+		v := env.Register("PILOT_DEBUG_ADMIN_IDENTITIES", "",
+			"Comma separated list of the SPIFFE identities allowed to use the debug endpoints which change the state of "+
+				"the proxies, besides the service account of istiod.").Get()
+		out := sets.New[string]()
+		for _, id := range strings.Split(v, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				out.Insert(id)
+			}
+		}
+		return out
+	}()
+
+	EnableXDSRecorder = env.Register("PILOT_ENABLE_XDS_RECORDER", false,
+		"If enabled, the /debug/xds_record debug endpoint records the xDS requests and responses of selected proxies "+
+			"to files, with the private keys of the secrets redacted, to be replayed against a test istiod.").Get()
+
+	XDSRecordingDir = env.Register("PILOT_XDS_RECORDING_DIR", "",
+		"The directory of the xDS recordings of the /debug/xds_record debug endpoint. Defaults to the temporary directory.").Get()
+
 	XDSAuth = env.Register("XDS_AUTH", true,
 		"If true, will authenticate XDS clients.").Get()
 
+	AuthenticatorPlugins = func() []string {
+		v := env.Register("PILOT_AUTHENTICATOR_PLUGINS", "",
+			"Comma separated list of the authenticator plugins compiled into istiod to enable, tried in order after the "+
+				"built-in authenticators of the XDS and CA servers.").Get()
+		var out []string
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				out = append(out, name)
+			}
+		}
+		return out
+	}()
+
+	EnableVMBootstrapTokens = env.Register("PILOT_ENABLE_VM_BOOTSTRAP_TOKENS", false,
+		"If enabled, istiod mints single-use bootstrap tokens for `istioctl x workload entry configure --bootstrapToken`, "+
+			"through the write API, and the CA accepts them to issue the first certificate of VM workloads.").Get()
+
 	EnableXDSIdentityCheck = env.Register(
 		"PILOT_ENABLE_XDS_IDENTITY_CHECK",
 		true,
@@ -441,16 +483,70 @@ var (
 		"If enabled, service entries with selectors will select pods from the cluster. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
 
+	ServiceEntrySRVRefreshInterval = env.Register("PILOT_SERVICEENTRY_SRV_REFRESH_INTERVAL", 30*time.Second,
+		"The interval at which istiod resolves the DNS SRV records of service entries annotated with "+
+			"networking.istio.io/dns-srv to update their endpoints.").Get()
+
 	EnableK8SServiceSelectWorkloadEntries = env.RegisterBoolVar("PILOT_ENABLE_K8S_SELECT_WORKLOAD_ENTRIES", true,
 		"If enabled, Kubernetes services with selectors will select workload entries with matching labels. "+
 			"It is safe to disable it if you are quite sure you don't need this feature").Get()
 
+	EnableDynamicNetworks = env.Register("PILOT_ENABLE_DYNAMIC_NETWORKS", false,
+		"If enabled, pods are assigned to the network of their node, from the topology.istio.io/network label of the node "+
+			"or the PILOT_ZONE_NETWORKS of its zone, and the network is updated when the node changes. The network of a node "+
+			"takes precedence over the network of the system namespace and of the proxy metadata, but not over the network "+
+			"label of a pod. This is meant for clusters whose nodes span multiple networks.").Get()
+
+	ZoneNetworks = env.Register("PILOT_ZONE_NETWORKS", "",
+		"Comma separated <zone>=<network> pairs assigning the nodes of the topology.kubernetes.io/zone zones to networks, "+
+			"if PILOT_ENABLE_DYNAMIC_NETWORKS is enabled.").Get()
+
 	InjectionWebhookConfigName = env.Register("INJECTION_WEBHOOK_CONFIG_NAME", "istio-sidecar-injector",
 		"Name of the mutatingwebhookconfiguration to patch, if istioctl is not used.").Get()
 
+	InjectionTemplateOCIReference = env.Register("INJECTION_TEMPLATE_OCI_REFERENCE", "",
+		"If set, injection templates are additionally loaded from the OCI artifact at this reference, replacing local "+
+			"templates with the same name. References pinned by digest are fetched once; others are refreshed periodically.").Get()
+
+	InjectionTemplateOCIRefreshInterval = env.Register("INJECTION_TEMPLATE_OCI_REFRESH_INTERVAL", 5*time.Minute,
+		"The interval at which injection templates are re-fetched from INJECTION_TEMPLATE_OCI_REFERENCE, "+
+			"if it is not pinned by digest. Setting the interval to 0 disables refresh.").Get()
+
+	InjectionPreflight = env.Register("INJECTION_PREFLIGHT", "off",
+		"Controls the check of pods against known incompatibilities with the sidecar at injection, such as ports "+
+			"reserved by the sidecar or containers running as its UID. With 'annotate', the issues are recorded in the "+
+			"sidecar.istio.io/preflightIssues annotation of the pod; with 'reject', the injection and so the pod creation "+
+			"fail; 'off', the default, disables the check. Unknown values are treated as 'off'.").Get()
+
+	EnableNativeSidecars = env.Register("ENABLE_NATIVE_SIDECARS", false,
+		"If enabled, the sidecar is injected as a Kubernetes native sidecar, an init container restarted Always, "+
+			"so that it starts before the app containers and is only terminated once they exit. Can be overridden per "+
+			"pod with the sidecar.istio.io/nativeSidecar annotation. Native sidecars are only injected on Kubernetes "+
+			"1.29 or later, where the SidecarContainers feature is enabled by default; on older clusters the sidecar "+
+			"is injected as a regular container.").Get()
+
+	DefaultJobPolicy = env.Register("PILOT_DEFAULT_JOB_POLICY", "",
+		"The comma separated behaviors of the sidecars injected in the pods of Jobs and CronJobs without the "+
+			"sidecar.istio.io/jobPolicy annotation: holdApplication holds the app until the proxy is ready, and "+
+			"exitOnCompletion terminates the proxy once the app containers exit, so that the pods complete.").Get()
+
 	ValidationWebhookConfigName = env.Register("VALIDATION_WEBHOOK_CONFIG_NAME", "istio-istio-system",
 		"Name of the validatingwebhookconfiguration to patch. Empty will skip using cluster admin to patch.").Get()
 
+	EnableAdmissionCanary = env.Register("PILOT_ENABLE_ADMISSION_CANARY", false,
+		"If enabled, the validation webhook stages the changes of mesh-wide resources, the PeerAuthentications of the "+
+			"root namespace and the VirtualServices with wildcard hosts, and rejects them if the listeners or routes "+
+			"generated for the proxies connected to istiod would be rejected by the proxies while they are accepted "+
+			"with the current configuration.").Get()
+
+	AdmissionCanaryMaxProxies = env.Register("PILOT_ADMISSION_CANARY_MAX_PROXIES", 10,
+		"The maximum number of connected proxies the configuration is generated for when PILOT_ENABLE_ADMISSION_CANARY "+
+			"stages a change. At most one proxy of each type and namespace is picked.").Get()
+
+	AdmissionCanaryTimeout = env.Register("PILOT_ADMISSION_CANARY_TIMEOUT", 3*time.Second,
+		"The maximum time PILOT_ENABLE_ADMISSION_CANARY spends checking a staged change. The change is admitted if the "+
+			"check does not complete in time. It should be lower than the timeout of the validation webhook.").Get()
+
 	SpiffeBundleEndpoints = env.Register("SPIFFE_BUNDLE_ENDPOINTS", "",
 		"The SPIFFE bundle trust domain to endpoint mappings. Istiod retrieves the root certificate from each SPIFFE "+
 			"bundle endpoint and uses it to verify client certifiates from that trust domain. The endpoint must be "+
@@ -497,6 +593,49 @@ var (
 			"Setting the timeout to 0 disables this behavior.",
 	).Get()
 
+	EndpointDrainDuration = env.Register(
+		"PILOT_ENDPOINT_DRAIN_DURATION",
+		0*time.Second,
+		"If set, the endpoints of the pods being deleted are sent with status DRAINING for this duration, capped by the "+
+			"deletion grace period of the pods, before being removed. The client proxies stop sending new requests to them, "+
+			"while the in-flight requests complete. Setting the duration to 0 removes the endpoints as soon as the pods are deleted.",
+	).Get()
+
+	EnableLoadReporting = env.Register(
+		"PILOT_ENABLE_LOAD_REPORTING",
+		false,
+		"If enabled, istiod serves the Envoy load reporting service (LRS) and weights the endpoints sent in EDS by the "+
+			"load the proxies report for them, so that endpoints and localities under load receive less traffic. Only "+
+			"the proxies with the LOAD_REPORTING proxy metadata report their load.",
+	).Get()
+
+	LoadReportingInterval = env.Register(
+		"PILOT_LOAD_REPORTING_INTERVAL",
+		10*time.Second,
+		"The interval at which the proxies report the load of their upstream endpoints, and at which istiod updates "+
+			"the load aware weights of the endpoints. Only used if PILOT_ENABLE_LOAD_REPORTING is enabled.",
+	).Get()
+
+	RemoteClusterStaleThreshold = env.Register(
+		"PILOT_REMOTE_CLUSTER_STALE_THRESHOLD",
+		0*time.Second,
+		"If set, endpoints of a remote cluster whose API server has been unreachable for longer than this threshold are "+
+			"marked as degraded, rather than being served as healthy indefinitely. Setting the threshold to 0 disables this behavior.",
+	).Get()
+
+	RemoteClusterHealthCheckInterval = env.Register(
+		"PILOT_REMOTE_CLUSTER_HEALTH_CHECK_INTERVAL",
+		10*time.Second,
+		"The interval at which the API servers of remote clusters are checked. Only used if PILOT_REMOTE_CLUSTER_STALE_THRESHOLD is set.",
+	).Get()
+
+	DrainStaleRemoteClusterEndpoints = env.Register(
+		"PILOT_DRAIN_STALE_REMOTE_CLUSTER_ENDPOINTS",
+		false,
+		"If true, endpoints of remote clusters considered stale (see PILOT_REMOTE_CLUSTER_STALE_THRESHOLD) are removed "+
+			"from EDS instead of being marked as degraded.",
+	).Get()
+
 	EnableTelemetryLabel = env.Register("PILOT_ENABLE_TELEMETRY_LABEL", true,
 		"If true, pilot will add telemetry related metadata to cluster and endpoint resources, which will be consumed by telemetry filter.",
 	).Get()
@@ -536,6 +675,14 @@ var (
 		"If enabled, Istio agent will intercept ECDS resource update, downloads Wasm module, "+
 			"and replaces Wasm module remote load with downloaded local module file.").Get()
 
+	WasmDefaultFailurePolicy = env.Register(
+		"PILOT_WASM_DEFAULT_FAILURE_POLICY",
+		"FALLBACK_TO_PREVIOUS",
+		"What the proxies do when the module of a WasmPlugin without the extensions.istio.io/failure-policy "+
+			"annotation cannot be fetched or loaded: FAIL_OPEN skips the plugin, FAIL_CLOSE rejects the requests, and "+
+			"FALLBACK_TO_PREVIOUS keeps the previous configuration of the plugin.",
+	).Get()
+
 	PilotJwtPubKeyRefreshInterval = env.Register(
 		"PILOT_JWT_PUB_KEY_REFRESH_INTERVAL",
 		20*time.Minute,
@@ -609,15 +756,30 @@ var (
 	MultiRootMesh = env.Register("ISTIO_MULTIROOT_MESH", false,
 		"If enabled, mesh will support certificates signed by more than one trustAnchor for ISTIO_MUTUAL mTLS").Get()
 
+	EnableTrustBundleDistribution = env.Register("PILOT_ENABLE_TRUST_BUNDLE_DISTRIBUTION", false,
+		"If enabled, the root certificates of the mesh, along with the federated roots of ISTIO_MULTIROOT_MESH, "+
+			"are written to the istio-trust-bundle ConfigMap of each namespace, for the applications terminating TLS "+
+			"themselves to trust the mesh identities.").Get()
+
 	EnableEnvoyFilterMetrics = env.Register("PILOT_ENVOY_FILTER_STATS", false,
 		"If true, Pilot will collect metrics for envoy filter operations.").Get()
 
+	EnableAuthzECDS = env.Register("PILOT_ENABLE_AUTHZ_ECDS", false,
+		"If enabled, the HTTP filters generated from AuthorizationPolicy (RBAC and ext_authz) are sent to proxies with ECDS "+
+			"instead of inline in the listeners, so that policy updates do not drain the listeners. The filters are then "+
+			"named after their extension config, one per action, e.g. 'istio.authz.deny', which EnvoyFilter patches matching them must use.").Get()
+
 	EnableRouteCollapse = env.Register("PILOT_ENABLE_ROUTE_COLLAPSE_OPTIMIZATION", true,
 		"If true, Pilot will merge virtual hosts with the same routes into a single virtual host, as an optimization.").Get()
 
 	MulticlusterHeadlessEnabled = env.Register("ENABLE_MULTICLUSTER_HEADLESS", true,
 		"If true, the DNS name table for a headless service will resolve to same-network endpoints in any cluster.").Get()
 
+	DNSForwardingRules = env.Register("PILOT_DNS_FORWARDING_RULES", "",
+		"Rules sent to the proxies with DNS capture, forwarding the DNS queries of some domains, not resolved by the proxy, "+
+			"to specific upstream servers instead of the nameservers of the proxy. In the form "+
+			"'<suffix>=<server>[,<server>...][;<suffix>=...]', e.g. 'corp.example.com=10.0.0.53,10.0.0.54:5353'.").Get()
+
 	ResolveHostnameGateways = env.Register("RESOLVE_HOSTNAME_GATEWAYS", true,
 		"If true, hostnames in the LoadBalancer addresses of a Service will be resolved at the control plane for use in cross-network gateways.").Get()
 
@@ -692,6 +854,198 @@ var (
 
 	EnableDualStack = env.RegisterBoolVar("ISTIO_DUAL_STACK", false,
 		"If enabled, pilot will configure clusters/listeners/routes for dual stack capability.").Get()
+
+	TLSProfile = env.Register(
+		"PILOT_TLS_PROFILE",
+		"",
+		"If set, the named TLS profile (modern, intermediate or fips) controlling TLS versions, cipher suites and curves "+
+			"is applied mesh-wide to mTLS and gateway TLS. It can be overridden per workload with the "+
+			"security.istio.io/tls-profile annotation. If unset, the default Envoy and Istio TLS settings are used.",
+	).Get()
+
+	EnableDualWorkloadCerts = env.Register(
+		"PILOT_ENABLE_DUAL_WORKLOAD_CERTS",
+		false,
+		"If enabled, gateways serve both an RSA and an ECDSA workload certificate for ISTIO_MUTUAL servers, and Envoy "+
+			"selects the certificate supported by the client. Only applies to gateways whose default workload certificate uses an RSA key.",
+	).Get()
+
+	FIPSProxy = env.Register(
+		"PILOT_PROXY_FIPS_BUILD",
+		false,
+		"If true, proxies are assumed to be FIPS builds of Envoy, and TLS profiles using cipher suites or curves "+
+			"which are not FIPS approved are rejected.",
+	).Get()
+
+	ProxyShards = env.Register(
+		"PILOT_PROXY_SHARDS",
+		0,
+		"If greater than 1, proxies are split between istiod replicas into this many shards, by the hash of their namespace. "+
+			"Each shard is an istiod Deployment with its PILOT_PROXY_SHARD_INDEX, exposed by a Service named after the istiod "+
+			"Service, such as istiod-shard-0 for istiod. The injector points the proxies to the Service of their shard, and "+
+			"each replica rejects the proxies of other shards with a retryable error.",
+	).Get()
+
+	ProxyShardIndex = env.Register(
+		"PILOT_PROXY_SHARD_INDEX",
+		-1,
+		"The shard of the proxies served by this istiod replica, in [0, PILOT_PROXY_SHARDS). Required if PILOT_PROXY_SHARDS is set.",
+	).Get()
+
+	InformerResyncPeriods = env.Register(
+		"PILOT_INFORMER_RESYNC_PERIODS",
+		"",
+		"Comma separated list of resource=duration pairs, such as `*=10m,pods=0s`, setting how often the Kubernetes "+
+			"informers replay their cached objects to their handlers. The `*` resource sets the default. Resyncs do not call "+
+			"the API server. By default, resyncs are disabled.",
+	).Get()
+
+	InformerListPageSizes = env.Register(
+		"PILOT_INFORMER_LIST_PAGE_SIZES",
+		"",
+		"Comma separated list of resource=size pairs, such as `*=500`, setting the page size of the initial lists of "+
+			"the Kubernetes informers. Paginated lists are served by etcd rather than by the API server watch cache, "+
+			"which avoids building very large responses when istiod starts on large clusters. By default, lists are not paginated.",
+	).Get()
+
+	InformerWatchBookmarks = env.Register(
+		"PILOT_INFORMER_WATCH_BOOKMARKS",
+		"",
+		"Comma separated list of resource=bool pairs, such as `endpointslices=false`, enabling or disabling watch "+
+			"bookmarks for the Kubernetes informers. By default, bookmarks are enabled.",
+	).Get()
+
+	EnableXDSEquivalenceCache = env.Register(
+		"PILOT_ENABLE_XDS_EQUIVALENCE_CACHE",
+		false,
+		"If enabled, proxies of the same namespace with the same labels and Sidecar scope share the clusters, listeners "+
+			"and routes generated for them in each push, instead of generating them for each connection.",
+	).Get()
+
+	EnableInboundPassthroughStats = env.Register(
+		"PILOT_ENABLE_INBOUND_PASSTHROUGH_STATS",
+		false,
+		"If enabled, sidecars count the inbound connections to ports not declared by any Service or Sidecar in the "+
+			"tcp.inbound_passthrough_rbac.allowed stat, even if the ports of the inbound passthrough traffic are not restricted.",
+	).Get()
+
+	EnableACME = env.Register(
+		"PILOT_ENABLE_ACME",
+		false,
+		"If enabled, istiod obtains and renews the certificates of the credentialName secrets of the Gateways annotated "+
+			"with networking.istio.io/acme: \"true\" from the ACME certificate authority of PILOT_ACME_DIRECTORY_URL.",
+	).Get()
+
+	ACMEDirectoryURL = env.Register(
+		"PILOT_ACME_DIRECTORY_URL",
+		"https://acme-v02.api.letsencrypt.org/directory",
+		"The directory of the ACME certificate authority issuing the certificates of the Gateways.",
+	).Get()
+
+	ACMEAcceptTOS = env.Register(
+		"PILOT_ACME_ACCEPT_TOS",
+		false,
+		"Accepts the terms of service of the ACME certificate authority of PILOT_ACME_DIRECTORY_URL. It is required to "+
+			"enable PILOT_ENABLE_ACME.",
+	).Get()
+
+	ACMENamespaces = func() []string {
+		v := env.Register(
+			"PILOT_ACME_NAMESPACES",
+			"",
+			"Comma separated list of the namespaces of the Gateways whose certificates are obtained from the ACME "+
+				"certificate authority. istiod is only allowed to write the certificates to these namespaces.",
+		).Get()
+		var out []string
+		for _, ns := range strings.Split(v, ",") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				out = append(out, ns)
+			}
+		}
+		return out
+	}()
+
+	ACMEEmail = env.Register(
+		"PILOT_ACME_EMAIL",
+		"",
+		"The contact email of the ACME account, notified of the certificates about to expire.",
+	).Get()
+
+	ACMEChallenge = env.Register(
+		"PILOT_ACME_CHALLENGE",
+		"http-01",
+		"The ACME challenge proving the control of the hosts of the Gateways, http-01 or dns-01. With http-01, the "+
+			"gateways must route /.well-known/acme-challenge/ on port 80 of the hosts to port 15080 of istiod. With dns-01, "+
+			"the challenge records are added with RFC 2136 dynamic updates, and wildcard hosts are supported.",
+	).Get()
+
+	ACMERFC2136Server = env.Register(
+		"PILOT_ACME_RFC2136_SERVER",
+		"",
+		"The address of the authoritative DNS server updated to solve the dns-01 challenges, such as 10.0.0.53:53.",
+	).Get()
+
+	ACMERFC2136Zone = env.Register(
+		"PILOT_ACME_RFC2136_ZONE",
+		"",
+		"The DNS zone of the hosts of the Gateways, updated to solve the dns-01 challenges.",
+	).Get()
+
+	ACMERFC2136TSIGKeyName = env.Register(
+		"PILOT_ACME_RFC2136_TSIG_KEY_NAME",
+		"",
+		"The name of the TSIG key authenticating the DNS updates, if any.",
+	).Get()
+
+	ACMERFC2136TSIGAlgorithm = env.Register(
+		"PILOT_ACME_RFC2136_TSIG_ALGORITHM",
+		"hmac-sha256.",
+		"The algorithm of the TSIG key authenticating the DNS updates.",
+	).Get()
+
+	ACMERFC2136TSIGSecretName = env.Register(
+		"PILOT_ACME_RFC2136_TSIG_SECRET_NAME",
+		"",
+		"The secret of the istiod namespace holding the base64 encoded secret of the TSIG key authenticating the DNS "+
+			"updates, under the secret key.",
+	).Get()
+
+	SidecarRecommendationPrometheusAddress = env.Register(
+		"PILOT_SIDECAR_RECOMMENDATION_PROMETHEUS_ADDRESS",
+		"",
+		"If set, istiod recommends the default Sidecar of each namespace without Sidecar from the services called by "+
+			"its sidecars, as reported to the query API of this Prometheus address, such as http://prometheus.istio-system:9090. "+
+			"The recommendations are served by the /debug/sidecar_recommendationz debug endpoint.",
+	).Get()
+
+	SidecarRecommendationWindow = env.Register(
+		"PILOT_SIDECAR_RECOMMENDATION_WINDOW",
+		7*24*time.Hour,
+		"The duration of the traffic the Sidecars are recommended from. It must cover the traffic of all the workloads "+
+			"of the namespaces: the calls to the services not observed fail once a recommended Sidecar is written.",
+	).Get()
+
+	SidecarRecommendationInterval = env.Register(
+		"PILOT_SIDECAR_RECOMMENDATION_INTERVAL",
+		time.Hour,
+		"The interval between the recommendations of the Sidecars.",
+	).Get()
+
+	SidecarRecommendationWrite = env.Register(
+		"PILOT_SIDECAR_RECOMMENDATION_WRITE",
+		false,
+		"If enabled, istiod writes the recommended Sidecars to the namespaces without Sidecar, and keeps them up to date. "+
+			"The namespaces with a Sidecar not written by istiod are left untouched.",
+	).Get()
+
+	TrustDomainTransitions = env.Register(
+		"PILOT_TRUST_DOMAIN_TRANSITIONS",
+		"",
+		"Comma separated list of the trust domains the mesh is migrating from, each optionally followed by the end of "+
+			"its transition window as an RFC 3339 time, for example \"old.example.com=2024-01-31T00:00:00Z\". Until the end "+
+			"of the window, the trust domain is treated as an alias of the mesh trust domain in authorization policies and "+
+			"peer certificate validation, and workload certificates signed by istiod carry identities in both trust domains.",
+	).Get()
 )
 
 // EnableEndpointSliceController returns the value of the feature flag and whether it was actually specified.
@@ -19,6 +19,8 @@ import (
 	"sync"
 
 	"go.uber.org/atomic"
+
+	"istio.io/istio/pkg/eventbus"
 )
 
 // KeyCertBundle stores the cert, private key and root cert for istiod.
@@ -34,22 +36,47 @@ type Watcher struct {
 	mutex     sync.Mutex
 	bundle    KeyCertBundle
 	watcherID int32
-	watchers  map[int32]chan struct{}
+	watchers  map[int32]*watcher
+	// rotations notifies the watchers of the key cert rotations, created on first use.
+	rotations *eventbus.Topic[KeyCertBundle]
+}
+
+type watcher struct {
+	ch           chan struct{}
+	subscription *eventbus.Subscription[KeyCertBundle]
 }
 
 func NewWatcher() *Watcher {
 	return &Watcher{
-		watchers: make(map[int32]chan struct{}),
+		watchers: make(map[int32]*watcher),
 	}
 }
 
+// topic returns the topic of the key cert rotations. This must be called with a lock on w.mutex.
+func (w *Watcher) topic() *eventbus.Topic[KeyCertBundle] {
+	if w.rotations == nil {
+		w.rotations = eventbus.NewTopic[KeyCertBundle]("keycertbundle")
+	}
+	return w.rotations
+}
+
 // AddWatcher returns channel to receive the updated items.
 func (w *Watcher) AddWatcher() (int32, chan struct{}) {
 	ch := make(chan struct{}, 1)
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
 	id := w.watcherID
-	w.watchers[id] = ch
+	// The rotations are published with a lock on w.mutex, so the channel is not closed while notified. Pending
+	// notifications are coalesced, the watchers reading the latest bundle.
+	w.watchers[id] = &watcher{
+		ch: ch,
+		subscription: w.topic().Subscribe("keycertbundle-watcher", func(KeyCertBundle) {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}),
+	}
 	w.watcherID++
 
 	return id, ch
@@ -59,9 +86,9 @@ func (w *Watcher) AddWatcher() (int32, chan struct{}) {
 func (w *Watcher) RemoveWatcher(id int32) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	ch := w.watchers[id]
-	if ch != nil {
-		close(ch)
+	if wt := w.watchers[id]; wt != nil {
+		wt.subscription.Unsubscribe()
+		close(wt.ch)
 	}
 	delete(w.watchers, id)
 }
@@ -80,12 +107,7 @@ func (w *Watcher) SetAndNotify(key, cert, caBundle []byte) {
 		w.bundle.CABundle = caBundle
 	}
 	w.initDone.Store(true)
-	for _, ch := range w.watchers {
-		select {
-		case ch <- struct{}{}:
-		default:
-		}
-	}
+	w.topic().Publish(w.bundle)
 }
 
 // SetFromFilesAndNotify sets the key cert and root cert from files and notify the watchers.
@@ -114,12 +136,7 @@ func (w *Watcher) SetFromFilesAndNotify(keyFile, certFile, rootCert string) erro
 		w.bundle.CABundle = caBundle
 	}
 	w.initDone.Store(true)
-	for _, ch := range w.watchers {
-		select {
-		case ch <- struct{}{}:
-		default:
-		}
-	}
+	w.topic().Publish(w.bundle)
 	return nil
 }
 
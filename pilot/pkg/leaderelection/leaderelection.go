@@ -50,6 +50,10 @@ const (
 	GatewayDeploymentController = "istio-gateway-deployment-leader"
 	StatusController            = "istio-status-leader"
 	AnalyzeController           = "istio-analyze-leader"
+	// ACMEController obtains the certificates of Gateways from ACME certificate authorities.
+	ACMEController = "istio-acme-leader"
+	// SidecarRecommendationController recommends the default Sidecars of the namespaces from their traffic.
+	SidecarRecommendationController = "istio-sidecar-recommendation-leader"
 )
 
 // Leader election key prefix for remote istiod managed clusters
@@ -17,9 +17,11 @@ package model
 import (
 	"crypto/md5"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"istio.io/api/annotation"
 	"istio.io/api/security/v1beta1"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/labels"
@@ -80,6 +82,10 @@ type AuthenticationPolicies struct {
 
 	peerAuthentications map[string][]config.Config
 
+	// dryRunPeerAuthentications are the peer authentications with the dry-run annotation. They are not enforced,
+	// but are used to report the connections they would reject.
+	dryRunPeerAuthentications map[string][]config.Config
+
 	// namespaceMutualTLSMode is the MutualTLSMode corresponding to the namespace-level PeerAuthentication.
 	// All namespace-level policies, and only them, are added to this map. If the policy mTLS mode is set
 	// to UNSET, it will be resolved to the value set by mesh policy if exist (i.e not UNKNOWN), or MTLSPermissive
@@ -100,10 +106,11 @@ type AuthenticationPolicies struct {
 // authentication policies in the mesh environment.
 func initAuthenticationPolicies(env *Environment) (*AuthenticationPolicies, error) {
 	policy := &AuthenticationPolicies{
-		requestAuthentications: map[string][]config.Config{},
-		peerAuthentications:    map[string][]config.Config{},
-		globalMutualTLSMode:    MTLSUnknown,
-		rootNamespace:          env.Mesh().GetRootNamespace(),
+		requestAuthentications:    map[string][]config.Config{},
+		peerAuthentications:       map[string][]config.Config{},
+		dryRunPeerAuthentications: map[string][]config.Config{},
+		globalMutualTLSMode:       MTLSUnknown,
+		rootNamespace:             env.Mesh().GetRootNamespace(),
 	}
 
 	if configs, err := env.List(
@@ -141,6 +148,10 @@ func (policy *AuthenticationPolicies) addPeerAuthentication(configs []config.Con
 
 	for _, config := range configs {
 		versions = append(versions, config.UID+"."+config.ResourceVersion)
+		if IsDryRunPeerAuthentication(config) {
+			policy.dryRunPeerAuthentications[config.Namespace] = append(policy.dryRunPeerAuthentications[config.Namespace], config)
+			continue
+		}
 		// Mesh & namespace level policy are those that have empty selector.
 		spec := config.Spec.(*v1beta1.PeerAuthentication)
 		if spec.Selector == nil || len(spec.Selector.MatchLabels) == 0 {
@@ -219,6 +230,19 @@ func (policy *AuthenticationPolicies) GetPeerAuthenticationsForWorkload(namespac
 	return getConfigsForWorkload(policy.peerAuthentications, policy.rootNamespace, namespace, workloadLabels)
 }
 
+// GetDryRunPeerAuthenticationsForWorkload returns a list of dry-run peer authentication policies matching to labels.
+func (policy *AuthenticationPolicies) GetDryRunPeerAuthenticationsForWorkload(namespace string,
+	workloadLabels labels.Instance,
+) []*config.Config {
+	return getConfigsForWorkload(policy.dryRunPeerAuthentications, policy.rootNamespace, namespace, workloadLabels)
+}
+
+// IsDryRunPeerAuthentication returns true if the peer authentication has the dry-run annotation set to true.
+func IsDryRunPeerAuthentication(cfg config.Config) bool {
+	dryRun, _ := strconv.ParseBool(cfg.Annotations[annotation.IoIstioDryRun.Name])
+	return dryRun
+}
+
 // GetRootNamespace return root namespace that is tracked by the policy object.
 func (policy *AuthenticationPolicies) GetRootNamespace() string {
 	return policy.rootNamespace
@@ -689,6 +689,26 @@ func TestGetPoliciesForWorkloadWithJwksResolver(t *testing.T) {
 	}
 }
 
+func TestGetDryRunPeerAuthenticationsForWorkload(t *testing.T) {
+	dryRun := createTestPeerAuthenticationResource("dry-run", "foo", baseTimestamp.Add(-time.Second), nil,
+		securityBeta.PeerAuthentication_MutualTLS_STRICT)
+	dryRun.Annotations = map[string]string{"istio.io/dry-run": "true"}
+	enforced := createTestPeerAuthenticationResource("default", "foo", baseTimestamp, nil,
+		securityBeta.PeerAuthentication_MutualTLS_PERMISSIVE)
+	policies := getTestAuthenticationPolicies([]*config.Config{dryRun, enforced}, t)
+
+	if got := policies.GetPeerAuthenticationsForWorkload("foo", nil); len(got) != 1 || got[0].Name != "default" {
+		t.Fatalf("expected only the enforced policy, got %v", printConfigs(got))
+	}
+	if got := policies.GetDryRunPeerAuthenticationsForWorkload("foo", nil); len(got) != 1 || got[0].Name != "dry-run" {
+		t.Fatalf("expected only the dry-run policy, got %v", printConfigs(got))
+	}
+	// The dry-run policy is older, but is not used for the namespace mode.
+	if got := policies.GetNamespaceMutualTLSMode("foo"); got != MTLSPermissive {
+		t.Fatalf("want %s, but got %s", MTLSPermissive, got)
+	}
+}
+
 func getTestAuthenticationPolicies(configs []*config.Config, t *testing.T) *AuthenticationPolicies {
 	configStore := NewFakeStore()
 	for _, cfg := range configs {
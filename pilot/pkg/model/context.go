@@ -335,6 +335,8 @@ type Proxy struct {
 	// LastPushContext; the XDS cache depends on knowing the time of the PushContext to determine if a
 	// key is stale or not.
 	LastPushTime time.Time
+	// LastPushDuration is the time it took to generate and send all the resources of the last push.
+	LastPushDuration time.Duration
 }
 
 // WatchedResource tracks an active DiscoveryRequest subscription.
@@ -366,6 +368,13 @@ type WatchedResource struct {
 	// LastResources tracks the contents of the last push.
 	// This field is extremely expensive to maintain and is typically disabled
 	LastResources Resources
+
+	// LastSize is the size in bytes of the resources of the last response.
+	LastSize int
+
+	// LastNackError is the error of the last response rejected by the proxy. It is cleared once a later
+	// response is acked.
+	LastNackError string
 }
 
 var istioVersionRegexp = regexp.MustCompile(`^([1-9]+)\.([0-9]+)(\.([0-9]+))?`)
@@ -643,6 +652,13 @@ type NodeMetadata struct {
 	// ExitOnZeroActiveConnections terminates Envoy if there are no active connections if set.
 	ExitOnZeroActiveConnections StringBool `json:"EXIT_ON_ZERO_ACTIVE_CONNECTIONS,omitempty"`
 
+	// ConnectionPoolMetrics includes the circuit breaker stats of outbound clusters if set.
+	ConnectionPoolMetrics StringBool `json:"CONNECTION_POOL_METRICS,omitempty"`
+
+	// LoadReporting makes the proxy report the load of its upstream endpoints to istiod, through the load reporting
+	// service (LRS) of Envoy. Only used if istiod enables PILOT_ENABLE_LOAD_REPORTING.
+	LoadReporting StringBool `json:"LOAD_REPORTING,omitempty"`
+
 	// InboundListenerExactBalance sets connection balance config to use exact_balance for virtualInbound,
 	// as long as QUIC, since it uses UDP, isn't also used.
 	InboundListenerExactBalance StringBool `json:"INBOUND_LISTENER_EXACT_BALANCE,omitempty"`
@@ -18,6 +18,7 @@ import (
 	"sync"
 
 	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/eventbus"
 )
 
 // Controller defines an event controller loop.  Proxy agent registers itself
@@ -65,56 +66,65 @@ type AggregateController interface {
 }
 
 // ControllerHandlers is a utility to help Controller implementations manage their lists of handlers.
+// The handlers are notified in the order they were appended.
 type ControllerHandlers struct {
-	mutex            sync.RWMutex
-	serviceHandlers  []func(*Service, Event)
-	workloadHandlers []func(*WorkloadInstance, Event)
+	mutex sync.Mutex
+	// topics of the service and workload events, created on first use.
+	services  *eventbus.Topic[serviceEvent]
+	workloads *eventbus.Topic[workloadEvent]
 }
 
-func (c *ControllerHandlers) AppendServiceHandler(f func(*Service, Event)) {
-	// Copy on write.
+type serviceEvent struct {
+	service *Service
+	event   Event
+}
+
+type workloadEvent struct {
+	workload *WorkloadInstance
+	event    Event
+}
+
+func (c *ControllerHandlers) serviceTopic() *eventbus.Topic[serviceEvent] {
 	c.mutex.Lock()
-	handlers := make([]func(*Service, Event), 0, len(c.serviceHandlers)+1)
-	handlers = append(handlers, c.serviceHandlers...)
-	handlers = append(handlers, f)
-	c.serviceHandlers = handlers
-	c.mutex.Unlock()
+	defer c.mutex.Unlock()
+	if c.services == nil {
+		c.services = eventbus.NewTopic[serviceEvent]("services")
+	}
+	return c.services
 }
 
-func (c *ControllerHandlers) AppendWorkloadHandler(f func(*WorkloadInstance, Event)) {
-	// Copy on write.
+func (c *ControllerHandlers) workloadTopic() *eventbus.Topic[workloadEvent] {
 	c.mutex.Lock()
-	handlers := make([]func(*WorkloadInstance, Event), 0, len(c.workloadHandlers)+1)
-	handlers = append(handlers, c.workloadHandlers...)
-	handlers = append(handlers, f)
-	c.workloadHandlers = handlers
-	c.mutex.Unlock()
+	defer c.mutex.Unlock()
+	if c.workloads == nil {
+		c.workloads = eventbus.NewTopic[workloadEvent]("workloads")
+	}
+	return c.workloads
 }
 
-func (c *ControllerHandlers) GetServiceHandlers() []func(*Service, Event) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	// Return a shallow copy of the array
-	return c.serviceHandlers
+func (c *ControllerHandlers) AppendServiceHandler(f func(*Service, Event)) {
+	c.serviceTopic().Subscribe("service-handler", func(e serviceEvent) {
+		f(e.service, e.event)
+	})
 }
 
-func (c *ControllerHandlers) GetWorkloadHandlers() []func(*WorkloadInstance, Event) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	// Return a shallow copy of the array
-	return c.workloadHandlers
+func (c *ControllerHandlers) AppendWorkloadHandler(f func(*WorkloadInstance, Event)) {
+	c.workloadTopic().Subscribe("workload-handler", func(e workloadEvent) {
+		f(e.workload, e.event)
+	})
+}
+
+// HasWorkloadHandlers returns true if any workload handler was appended.
+func (c *ControllerHandlers) HasWorkloadHandlers() bool {
+	return c.workloadTopic().Len() > 0
 }
 
 func (c *ControllerHandlers) NotifyServiceHandlers(svc *Service, event Event) {
-	for _, f := range c.GetServiceHandlers() {
-		f(svc, event)
-	}
+	c.serviceTopic().Publish(serviceEvent{service: svc, event: event})
 }
 
 func (c *ControllerHandlers) NotifyWorkloadHandlers(w *WorkloadInstance, event Event) {
-	for _, f := range c.GetWorkloadHandlers() {
-		f(w, event)
-	}
+	c.workloadTopic().Publish(workloadEvent{workload: w, event: event})
 }
 
 // Event represents a registry update event
@@ -24,6 +24,7 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/locality"
 	"istio.io/istio/pkg/config/visibility"
 )
 
@@ -154,10 +155,17 @@ func (ps *PushContext) inheritDestinationRule(parent, child *ConsolidatedDestRul
 	out.rule = &merged
 	out.from = append(out.from, parent.from...)
 	out.from = append(out.from, child.from...)
+	// The annotations are those of the child.
+	out.localityEjectionPolicy = child.localityEjectionPolicy
 	return out
 }
 
 func ConvertConsolidatedDestRule(cfg *config.Config) *ConsolidatedDestRule {
+	ejectionPolicy, err := locality.ParseEjectionPolicy(cfg.Annotations)
+	if err != nil {
+		// The policy is validated by the webhook, which may have been bypassed.
+		log.Debugf("ignoring locality ejection policy of destination rule %s/%s: %v", cfg.Namespace, cfg.Name, err)
+	}
 	return &ConsolidatedDestRule{
 		rule: cfg,
 		from: []types.NamespacedName{
@@ -166,6 +174,7 @@ func ConvertConsolidatedDestRule(cfg *config.Config) *ConsolidatedDestRule {
 				Name:      cfg.Name,
 			},
 		},
+		localityEjectionPolicy: ejectionPolicy,
 	}
 }
 
@@ -197,6 +206,14 @@ func (l *ConsolidatedDestRule) GetRule() *config.Config {
 	return l.rule
 }
 
+// GetLocalityEjectionPolicy returns the locality ejection policy of the rule, if any.
+func (l *ConsolidatedDestRule) GetLocalityEjectionPolicy() *locality.EjectionPolicy {
+	if l == nil {
+		return nil
+	}
+	return l.localityEjectionPolicy
+}
+
 func (l *ConsolidatedDestRule) GetFrom() []types.NamespacedName {
 	if l == nil {
 		return nil
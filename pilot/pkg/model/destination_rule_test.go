@@ -19,6 +19,8 @@ import (
 
 	"k8s.io/apimachinery/pkg/types"
 
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/locality"
 	"istio.io/istio/pkg/test/util/assert"
 )
 
@@ -147,3 +149,22 @@ func TestConsolidatedDestRuleEquals(t *testing.T) {
 		})
 	}
 }
+
+func TestConsolidatedDestRuleLocalityEjectionPolicy(t *testing.T) {
+	cfg := &config.Config{Meta: config.Meta{
+		Name:        "reviews",
+		Namespace:   "default",
+		Annotations: map[string]string{locality.EjectionPolicyAnnotation: "failback: {gradual: true}"},
+	}}
+	got := ConvertConsolidatedDestRule(cfg).GetLocalityEjectionPolicy()
+	assert.Equal(t, got, &locality.EjectionPolicy{Failback: &locality.Failback{Gradual: true}})
+
+	cfg.Annotations[locality.EjectionPolicyAnnotation] = "failback: {unknown: true}"
+	if got := ConvertConsolidatedDestRule(cfg).GetLocalityEjectionPolicy(); got != nil {
+		t.Fatalf("expected an invalid policy to be ignored, got %v", got)
+	}
+	var nilRule *ConsolidatedDestRule
+	if nilRule.GetLocalityEjectionPolicy() != nil {
+		t.Fatalf("expected no policy without a destination rule")
+	}
+}
@@ -98,14 +98,44 @@ type EndpointIndex struct {
 	shardsBySvc map[string]map[string]*EndpointShards
 	// We'll need to clear the cache in-sync with endpoint shards modifications.
 	cache XdsCache
+	// staleClusters holds the clusters whose endpoints may be out of date, because their
+	// API server has been unreachable for too long.
+	staleClusters sets.Set[cluster.ID]
 }
 
 func NewEndpointIndex() *EndpointIndex {
 	return &EndpointIndex{
-		shardsBySvc: make(map[string]map[string]*EndpointShards),
+		shardsBySvc:   make(map[string]map[string]*EndpointShards),
+		staleClusters: sets.New[cluster.ID](),
 	}
 }
 
+// SetClusterStale marks the endpoints of a cluster as stale or fresh. It returns true if the state
+// changed, in which case the cache is cleared and the caller is responsible for triggering a push.
+func (e *EndpointIndex) SetClusterStale(clusterID cluster.ID, stale bool) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.staleClusters.Contains(clusterID) == stale {
+		return false
+	}
+	if stale {
+		e.staleClusters.Insert(clusterID)
+	} else {
+		e.staleClusters.Delete(clusterID)
+	}
+	if e.cache != nil {
+		e.cache.ClearAll()
+	}
+	return true
+}
+
+// StaleClusters returns a copy of the clusters whose endpoints are currently considered stale.
+func (e *EndpointIndex) StaleClusters() sets.Set[cluster.ID] {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.staleClusters.Copy()
+}
+
 func (e *EndpointIndex) SetCache(cache XdsCache) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -23,6 +23,8 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/listenerpatch"
+	"istio.io/istio/pkg/config/ratelimit"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/util/sets"
 )
@@ -50,6 +52,9 @@ type EnvoyFilterConfigPatchWrapper struct {
 	ProxyPrefixMatch string
 	Name             string
 	Namespace        string
+	// MergeCircuitBreakerThresholds is set for the patches compiled from a listenerpatch.Annotation, whose circuit
+	// breaker thresholds are merged into the thresholds of the same priority instead of being appended.
+	MergeCircuitBreakerThresholds bool
 }
 
 // wellKnownVersions defines a mapping of well known regex matches to prefix matches
@@ -84,7 +89,31 @@ func convertToEnvoyFilterWrapper(local *config.Config) *EnvoyFilterWrapper {
 		out.workloadSelector = localEnvoyFilter.WorkloadSelector.Labels
 	}
 	out.Patches = make(map[networking.EnvoyFilter_ApplyTo][]*EnvoyFilterConfigPatchWrapper)
-	for _, cp := range localEnvoyFilter.ConfigPatches {
+	configPatches := localEnvoyFilter.ConfigPatches
+	typed := false
+	if s, f := local.Annotations[listenerpatch.Annotation]; f {
+		// Should be caught by validation, as for the patches below.
+		lp, err := listenerpatch.Parse(s)
+		if err == nil {
+			configPatches, err = lp.ConfigPatches()
+		}
+		if err != nil {
+			log.Errorf("envoyfilter %v/%v discarded due to invalid listener patch: %v", local.Namespace, local.Name, err)
+			return out
+		}
+		typed = true
+	}
+	if s, f := local.Annotations[ratelimit.Annotation]; f {
+		rl, err := ratelimit.Parse(s)
+		if err == nil {
+			configPatches, err = rl.ConfigPatches()
+		}
+		if err != nil {
+			log.Errorf("envoyfilter %v/%v discarded due to invalid rate limit: %v", local.Namespace, local.Name, err)
+			return out
+		}
+	}
+	for _, cp := range configPatches {
 		if cp.Patch == nil {
 			// Should be caught by validation, but sometimes its disabled and we don't want to crash
 			// as a result.
@@ -97,6 +126,8 @@ func convertToEnvoyFilterWrapper(local *config.Config) *EnvoyFilterWrapper {
 			ApplyTo:   cp.ApplyTo,
 			Match:     cp.Match,
 			Operation: cp.Patch.Operation,
+			// Only the thresholds of the typed patches are merged, to keep the semantics of raw patches.
+			MergeCircuitBreakerThresholds: typed,
 		}
 		var err error
 		// Use non-strict building to avoid issues where EnvoyFilter is valid but meant
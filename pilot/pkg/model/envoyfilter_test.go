@@ -20,6 +20,8 @@ import (
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/listenerpatch"
+	"istio.io/istio/pkg/config/ratelimit"
 )
 
 // TestEnvoyFilterMatch tests the matching logic for EnvoyFilter, in particular the regex -> prefix optimization
@@ -135,6 +137,52 @@ func TestConvertEnvoyFilter(t *testing.T) {
 	}
 }
 
+func TestConvertEnvoyFilterListenerPatch(t *testing.T) {
+	cfilter := convertToEnvoyFilterWrapper(&config.Config{
+		Meta: config.Meta{
+			Name:        "test",
+			Namespace:   "testns",
+			Annotations: map[string]string{listenerpatch.Annotation: "circuitBreakers:\n- service: ratings.default.svc.cluster.local\n  maxConnections: 100\n"},
+		},
+		Spec: &networking.EnvoyFilter{},
+	})
+	patches := cfilter.Patches[networking.EnvoyFilter_CLUSTER]
+	if len(patches) != 1 || !patches[0].MergeCircuitBreakerThresholds || patches[0].Value == nil {
+		t.Fatalf("expected the listener patch to be compiled, got %v", cfilter.Patches)
+	}
+
+	cfilter = convertToEnvoyFilterWrapper(&config.Config{
+		Meta: config.Meta{Name: "test", Namespace: "testns", Annotations: map[string]string{listenerpatch.Annotation: "circuitBreakers: []"}},
+		Spec: &networking.EnvoyFilter{},
+	})
+	if len(cfilter.Patches) != 0 {
+		t.Fatalf("expected an invalid listener patch to be discarded, got %v", cfilter.Patches)
+	}
+}
+
+func TestConvertEnvoyFilterRateLimit(t *testing.T) {
+	cfilter := convertToEnvoyFilterWrapper(&config.Config{
+		Meta: config.Meta{
+			Name:        "test",
+			Namespace:   "testns",
+			Annotations: map[string]string{ratelimit.Annotation: "local:\n  maxTokens: 100\n  fillInterval: 1s\n"},
+		},
+		Spec: &networking.EnvoyFilter{},
+	})
+	patches := cfilter.Patches[networking.EnvoyFilter_HTTP_FILTER]
+	if len(patches) != 1 || patches[0].Operation != networking.EnvoyFilter_Patch_INSERT_BEFORE || patches[0].Value == nil {
+		t.Fatalf("expected the rate limit to be compiled, got %v", cfilter.Patches)
+	}
+
+	cfilter = convertToEnvoyFilterWrapper(&config.Config{
+		Meta: config.Meta{Name: "test", Namespace: "testns", Annotations: map[string]string{ratelimit.Annotation: "local: {maxTokens: 0}"}},
+		Spec: &networking.EnvoyFilter{},
+	})
+	if len(cfilter.Patches) != 0 {
+		t.Fatalf("expected an invalid rate limit to be discarded, got %v", cfilter.Patches)
+	}
+}
+
 func TestKeysApplyingTo(t *testing.T) {
 	e := &EnvoyFilterWrapper{
 		Patches: map[networking.EnvoyFilter_ApplyTo][]*EnvoyFilterConfigPatchWrapper{
@@ -28,11 +28,13 @@ import (
 
 	extensions "istio.io/api/extensions/v1alpha1"
 	typeapi "istio.io/api/type/v1beta1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model/credentials"
 	istionetworking "istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/wasmplugin"
 	"istio.io/istio/pkg/util/protomarshal"
 )
 
@@ -47,8 +49,20 @@ const (
 	WasmPolicyEnv = "ISTIO_META_WASM_IMAGE_PULL_POLICY"
 	// name of environment variable at Wasm VM, which will carry the resource version of WasmPlugin.
 	WasmResourceVersionEnv = "ISTIO_META_WASM_PLUGIN_RESOURCE_VERSION"
+	// name of environment variable at Wasm VM, which will carry the failure policy of WasmPlugin.
+	WasmFailurePolicyEnv = "ISTIO_META_WASM_FAILURE_POLICY"
 )
 
+// defaultWasmFailurePolicy is the failure policy of the WasmPlugins without the failure policy annotation.
+var defaultWasmFailurePolicy = func() wasmplugin.FailurePolicy {
+	p, err := wasmplugin.ParseFailurePolicy(features.WasmDefaultFailurePolicy)
+	if err != nil {
+		log.Warnf("PILOT_WASM_DEFAULT_FAILURE_POLICY: %v, using %s", err, wasmplugin.FallbackToPrevious)
+		return wasmplugin.FallbackToPrevious
+	}
+	return p
+}()
+
 func workloadModeForListenerClass(class istionetworking.ListenerClass) typeapi.WorkloadMode {
 	switch class {
 	case istionetworking.ListenerClassGateway:
@@ -70,6 +84,8 @@ type WasmPluginWrapper struct {
 	Name         string
 	Namespace    string
 	ResourceName string
+	// FailurePolicy is what the proxies do when the module cannot be fetched or loaded.
+	FailurePolicy wasmplugin.FailurePolicy
 
 	WasmExtensionConfig *envoyWasmFilterV3.Wasm
 }
@@ -160,12 +176,20 @@ func convertToWasmPluginWrapper(originPlugin config.Config) *WasmPluginWrapper {
 	// Normalize the image pull secret to the full resource name.
 	wasmPlugin.ImagePullSecret = toSecretResourceName(wasmPlugin.ImagePullSecret, plugin.Namespace)
 	datasource := buildDataSource(u, wasmPlugin)
+	failurePolicy, policyErr := wasmplugin.ParseFailurePolicyAnnotation(plugin.Annotations, defaultWasmFailurePolicy)
+	if policyErr != nil {
+		log.Debugf("wasmplugin %v/%v: %v", plugin.Namespace, plugin.Name, policyErr)
+	}
+	vm := buildVMConfig(datasource, plugin.ResourceVersion, wasmPlugin)
+	// The agent applies the failure policy when the module cannot be fetched, and strips the variable.
+	vm.VmConfig.EnvironmentVariables.KeyValues[WasmFailurePolicyEnv] = string(failurePolicy)
 	wasmExtensionConfig := &envoyWasmFilterV3.Wasm{
 		Config: &envoyExtensionsWasmV3.PluginConfig{
 			Name:          plugin.Namespace + "." + plugin.Name,
 			RootId:        wasmPlugin.PluginName,
 			Configuration: cfg,
-			Vm:            buildVMConfig(datasource, plugin.ResourceVersion, wasmPlugin),
+			Vm:            vm,
+			FailOpen:      failurePolicy == wasmplugin.FailOpen,
 		},
 	}
 	if err != nil {
@@ -176,6 +200,7 @@ func convertToWasmPluginWrapper(originPlugin config.Config) *WasmPluginWrapper {
 		Name:                plugin.Name,
 		Namespace:           plugin.Namespace,
 		ResourceName:        plugin.Namespace + "." + plugin.Name,
+		FailurePolicy:       failurePolicy,
 		WasmPlugin:          wasmPlugin,
 		WasmExtensionConfig: wasmExtensionConfig,
 	}
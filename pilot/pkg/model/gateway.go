@@ -22,6 +22,7 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model/credentials"
+	"istio.io/istio/pilot/pkg/util/names"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/gateway"
 	"istio.io/istio/pkg/config/protocol"
@@ -143,6 +144,8 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 	http3AdvertisingRoutes := sets.New[string]()
 	tlsHostsByPort := map[uint32]map[string]string{} // port -> host/bind map
 	autoPassthrough := false
+	// HTTPS route names are built from the port and gateway names, which can contain the separator.
+	httpsRouteNames := names.NewTracker()
 
 	log.Debugf("MergeGateways: merging %d gateways", len(gateways))
 	for _, gwAndInstance := range gateways {
@@ -185,6 +188,14 @@ func MergeGateways(gateways []gatewayWithInstances, proxy *Proxy, ps *PushContex
 			}
 			for _, resolvedPort := range resolvePorts(s.Port.Number, gwAndInstance.instances, gwAndInstance.legacyGatewaySelector) {
 				routeName := gatewayRDSRouteName(s, resolvedPort, gatewayConfig)
+				if strings.HasPrefix(routeName, "https.") {
+					source := fmt.Sprintf("gateway %s port %s", gatewayName, s.Port.Name)
+					if other, collides := httpsRouteNames.Add(routeName, source); collides {
+						msg := fmt.Sprintf("route configuration name %s of %s is also generated for %s", routeName, source, other)
+						log.Warnf("MergeGateways: %s", msg)
+						ps.AddMetric(DuplicatedGeneratedNames, routeName, proxy.ID, msg)
+					}
+				}
 				if s.Tls != nil {
 					// Envoy will reject config that has multiple filter chain matches with the same matching rules.
 					// To avoid this, we need to make sure we don't have duplicated hosts, which will become
@@ -466,8 +477,7 @@ func gatewayRDSRouteName(server *networking.Server, portNumber uint32, cfg confi
 	}
 
 	if p == protocol.HTTPS && server.Tls != nil && !gateway.IsPassThroughServer(server) {
-		return "https" + "." + strconv.Itoa(int(server.Port.Number)) + "." +
-			server.Port.Name + "." + cfg.Name + "." + cfg.Namespace + bind // Format: https.%d.%s.%s.%s.%s
+		return names.GatewayHTTPSRouteName(server.Port.Number, server.Port.Name, cfg.Name, cfg.Namespace, server.Bind)
 	}
 
 	return ""
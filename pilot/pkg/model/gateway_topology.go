@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+)
+
+const (
+	// NumTrustedProxiesAnnotation can be set on the Service fronting a gateway to declare the number of
+	// trusted proxies (load balancers, CDNs) deployed in front of it.
+	// TODO: move to API
+	NumTrustedProxiesAnnotation = "gateway.istio.io/num-trusted-proxies"
+
+	// ClientIPHeaderAnnotation can be set on the Service fronting a gateway to declare a header, set by a
+	// trusted proxy in front of the gateway, carrying the original client address (for example CF-Connecting-IP).
+	// TODO: move to API
+	ClientIPHeaderAnnotation = "gateway.istio.io/client-ip-header"
+)
+
+// ClientAddressSource describes where the client address detection settings of a gateway were derived from.
+type ClientAddressSource string
+
+const (
+	// ClientAddressSourceDefault indicates nothing was configured, and the downstream peer address is used.
+	ClientAddressSourceDefault ClientAddressSource = "default"
+	// ClientAddressSourceProxyConfig indicates the settings come from ProxyConfig.gatewayTopology.
+	ClientAddressSourceProxyConfig ClientAddressSource = "proxyConfig"
+	// ClientAddressSourceService indicates the settings come from annotations on a Service selecting the gateway.
+	ClientAddressSourceService ClientAddressSource = "service"
+)
+
+// ClientAddressDetection describes how a gateway determines the address of the downstream client.
+type ClientAddressDetection struct {
+	// NumTrustedProxies is the number of trusted proxies in front of the gateway. The client address
+	// is taken from the X-Forwarded-For header, skipping this many entries from the right.
+	NumTrustedProxies uint32 `json:"numTrustedProxies"`
+	// ClientIPHeader, if set, is the name of a header carrying the client address. It takes
+	// precedence over X-Forwarded-For.
+	ClientIPHeader string `json:"clientIPHeader,omitempty"`
+	// Source describes where the settings were derived from.
+	Source ClientAddressSource `json:"source"`
+	// Service is the namespace/name of the Service that declared the settings, if Source is "service".
+	Service string `json:"service,omitempty"`
+}
+
+// Describe returns a human readable explanation of how the client address is derived.
+func (c ClientAddressDetection) Describe() string {
+	switch {
+	case c.ClientIPHeader != "":
+		return "client address is read from the " + c.ClientIPHeader + " header"
+	case c.NumTrustedProxies > 0:
+		return "client address is the X-Forwarded-For entry " + strconv.Itoa(int(c.NumTrustedProxies)) +
+			" hop(s) from the right"
+	default:
+		return "client address is the downstream peer address"
+	}
+}
+
+// ParseClientAddressDetection parses the gateway client address annotations of a Service.
+// Nil is returned if the Service declares none.
+func ParseClientAddressDetection(annotations map[string]string) *ClientAddressDetection {
+	hops, hasHops := annotations[NumTrustedProxiesAnnotation]
+	header, hasHeader := annotations[ClientIPHeaderAnnotation]
+	if !hasHops && !hasHeader {
+		return nil
+	}
+	out := &ClientAddressDetection{
+		Source:         ClientAddressSourceService,
+		ClientIPHeader: strings.TrimSpace(header),
+	}
+	if hasHops {
+		n, err := strconv.ParseUint(strings.TrimSpace(hops), 10, 32)
+		if err != nil {
+			log.Warnf("invalid %s annotation value %q: %v", NumTrustedProxiesAnnotation, hops, err)
+		} else {
+			out.NumTrustedProxies = uint32(n)
+		}
+	}
+	return out
+}
+
+// ClientAddressDetection computes how this gateway proxy determines the downstream client address.
+// An explicit ProxyConfig gatewayTopology always takes precedence; otherwise annotations declared on
+// the Services selecting the gateway are used. If multiple Services declare settings, the one with
+// the lowest hostname wins, so the result is deterministic.
+func (node *Proxy) ClientAddressDetection(proxyConfig *meshconfig.ProxyConfig) ClientAddressDetection {
+	if proxyConfig != nil && proxyConfig.GatewayTopology != nil {
+		return ClientAddressDetection{
+			NumTrustedProxies: proxyConfig.GatewayTopology.NumTrustedProxies,
+			Source:            ClientAddressSourceProxyConfig,
+		}
+	}
+	var declared []*Service
+	for _, si := range node.ServiceInstances {
+		if si.Service != nil && si.Service.Attributes.ClientAddressDetection != nil {
+			declared = append(declared, si.Service)
+		}
+	}
+	if len(declared) == 0 {
+		return ClientAddressDetection{Source: ClientAddressSourceDefault}
+	}
+	sort.SliceStable(declared, func(i, j int) bool {
+		return declared[i].Hostname < declared[j].Hostname
+	})
+	svc := declared[0]
+	out := *svc.Attributes.ClientAddressDetection
+	out.Service = svc.Attributes.Namespace + "/" + svc.Attributes.Name
+	return out
+}
@@ -0,0 +1,113 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestParseClientAddressDetection(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        *ClientAddressDetection
+	}{
+		{
+			name: "none",
+			want: nil,
+		},
+		{
+			name:        "hops",
+			annotations: map[string]string{NumTrustedProxiesAnnotation: "2"},
+			want:        &ClientAddressDetection{NumTrustedProxies: 2, Source: ClientAddressSourceService},
+		},
+		{
+			name:        "invalid hops",
+			annotations: map[string]string{NumTrustedProxiesAnnotation: "two"},
+			want:        &ClientAddressDetection{Source: ClientAddressSourceService},
+		},
+		{
+			name:        "header",
+			annotations: map[string]string{ClientIPHeaderAnnotation: "CF-Connecting-IP"},
+			want:        &ClientAddressDetection{ClientIPHeader: "CF-Connecting-IP", Source: ClientAddressSourceService},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, ParseClientAddressDetection(tt.annotations), tt.want)
+		})
+	}
+}
+
+func TestProxyClientAddressDetection(t *testing.T) {
+	svc := func(name string, hops uint32) *ServiceInstance {
+		return &ServiceInstance{Service: &Service{
+			Hostname: host.Name(name + ".istio-system.svc.cluster.local"),
+			Attributes: ServiceAttributes{
+				Name:      name,
+				Namespace: "istio-system",
+				ClientAddressDetection: &ClientAddressDetection{
+					NumTrustedProxies: hops,
+					Source:            ClientAddressSourceService,
+				},
+			},
+		}}
+	}
+	cases := []struct {
+		name        string
+		proxyConfig *meshconfig.ProxyConfig
+		instances   []*ServiceInstance
+		want        ClientAddressDetection
+	}{
+		{
+			name: "default",
+			want: ClientAddressDetection{Source: ClientAddressSourceDefault},
+		},
+		{
+			name:        "proxy config wins",
+			proxyConfig: &meshconfig.ProxyConfig{GatewayTopology: &meshconfig.Topology{NumTrustedProxies: 3}},
+			instances:   []*ServiceInstance{svc("ingress", 1)},
+			want:        ClientAddressDetection{NumTrustedProxies: 3, Source: ClientAddressSourceProxyConfig},
+		},
+		{
+			name:      "service",
+			instances: []*ServiceInstance{{Service: &Service{Hostname: "other"}}, svc("ingress", 1)},
+			want: ClientAddressDetection{
+				NumTrustedProxies: 1,
+				Source:            ClientAddressSourceService,
+				Service:           "istio-system/ingress",
+			},
+		},
+		{
+			name:      "lowest hostname wins",
+			instances: []*ServiceInstance{svc("zeta", 2), svc("alpha", 1)},
+			want: ClientAddressDetection{
+				NumTrustedProxies: 1,
+				Source:            ClientAddressSourceService,
+				Service:           "istio-system/alpha",
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &Proxy{Type: Router, ServiceInstances: tt.instances}
+			assert.Equal(t, node.ClientAddressDetection(tt.proxyConfig), tt.want)
+		})
+	}
+}
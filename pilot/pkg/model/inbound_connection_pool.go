@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strconv"
+	"time"
+)
+
+// Annotations configuring the server side connection management of the inbound listeners of a workload. Unlike
+// the connection pool of DestinationRule, which applies to the connections opened by the clients, they limit the
+// connections accepted by the sidecar of the workload. They can also be set with the proxy metadata of the
+// ProxyConfig of the workload, as ISTIO_META_INBOUND_MAX_CONNECTIONS, ISTIO_META_INBOUND_CONNECTION_BUFFER_LIMIT
+// and ISTIO_META_INBOUND_IDLE_TIMEOUT.
+// TODO: move to API
+const (
+	// InboundMaxConnectionsAnnotation is the maximum number of concurrent connections accepted by each inbound
+	// filter chain of the workload. The connections are counted separately for each port, protocol and TLS mode.
+	// Connections over the limit are closed.
+	InboundMaxConnectionsAnnotation = "networking.istio.io/inbound-max-connections"
+	// InboundConnectionBufferLimitAnnotation is the soft limit, in bytes, of the read and write buffers of each
+	// inbound connection, both downstream and to the application.
+	InboundConnectionBufferLimitAnnotation = "networking.istio.io/inbound-connection-buffer-limit"
+	// InboundIdleTimeoutAnnotation is the idle timeout of the inbound connections, after which connections without
+	// active requests or data are closed.
+	InboundIdleTimeoutAnnotation = "networking.istio.io/inbound-idle-timeout"
+)
+
+var inboundConnectionPoolMetadata = map[string]string{
+	InboundMaxConnectionsAnnotation:        "INBOUND_MAX_CONNECTIONS",
+	InboundConnectionBufferLimitAnnotation: "INBOUND_CONNECTION_BUFFER_LIMIT",
+	InboundIdleTimeoutAnnotation:           "INBOUND_IDLE_TIMEOUT",
+}
+
+// InboundConnectionPool is the server side connection management of the inbound listeners of a proxy.
+type InboundConnectionPool struct {
+	// MaxConnections is the maximum number of concurrent connections per inbound filter chain. Unlimited if 0.
+	MaxConnections uint32
+	// PerConnectionBufferLimitBytes is the buffer limit of each inbound connection. Envoy's default if 0.
+	PerConnectionBufferLimitBytes uint32
+	// IdleTimeout overrides the idle timeout of the inbound connections, if set.
+	IdleTimeout *time.Duration
+}
+
+// InboundConnectionPool returns the inbound connection pool settings of the proxy. Annotations take precedence
+// over the proxy metadata, and invalid settings are ignored.
+func (node *Proxy) InboundConnectionPool() InboundConnectionPool {
+	var p InboundConnectionPool
+	if node.Metadata == nil {
+		return p
+	}
+	if v := node.inboundConnectionPoolSetting(InboundMaxConnectionsAnnotation); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			log.Debugf("ignoring inbound max connections %q of proxy %s", v, node.ID)
+		} else {
+			p.MaxConnections = uint32(n)
+		}
+	}
+	if v := node.inboundConnectionPoolSetting(InboundConnectionBufferLimitAnnotation); v != "" {
+		n, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			log.Debugf("ignoring inbound connection buffer limit %q of proxy %s", v, node.ID)
+		} else {
+			p.PerConnectionBufferLimitBytes = uint32(n)
+		}
+	}
+	if v := node.inboundConnectionPoolSetting(InboundIdleTimeoutAnnotation); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Debugf("ignoring inbound idle timeout %q of proxy %s", v, node.ID)
+		} else {
+			p.IdleTimeout = &d
+		}
+	}
+	return p
+}
+
+func (node *Proxy) inboundConnectionPoolSetting(annotation string) string {
+	return node.workloadSetting(annotation, inboundConnectionPoolMetadata[annotation])
+}
@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInboundConnectionPool(t *testing.T) {
+	timeout := 30 * time.Second
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		raw         map[string]any
+		want        InboundConnectionPool
+	}{
+		{name: "unset"},
+		{
+			name: "annotations",
+			annotations: map[string]string{
+				InboundMaxConnectionsAnnotation:        "100",
+				InboundConnectionBufferLimitAnnotation: "32768",
+				InboundIdleTimeoutAnnotation:           "30s",
+			},
+			want: InboundConnectionPool{MaxConnections: 100, PerConnectionBufferLimitBytes: 32768, IdleTimeout: &timeout},
+		},
+		{
+			name: "proxy metadata",
+			raw:  map[string]any{"INBOUND_MAX_CONNECTIONS": "50"},
+			want: InboundConnectionPool{MaxConnections: 50},
+		},
+		{
+			name:        "annotation overrides proxy metadata",
+			annotations: map[string]string{InboundMaxConnectionsAnnotation: "100"},
+			raw:         map[string]any{"INBOUND_MAX_CONNECTIONS": "50"},
+			want:        InboundConnectionPool{MaxConnections: 100},
+		},
+		{
+			name: "invalid",
+			annotations: map[string]string{
+				InboundMaxConnectionsAnnotation:        "-1",
+				InboundConnectionBufferLimitAnnotation: "32Ki",
+				InboundIdleTimeoutAnnotation:           "-1s",
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &Proxy{Metadata: &NodeMetadata{Annotations: tt.annotations, Raw: tt.raw}}
+			if got := node.InboundConnectionPool(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
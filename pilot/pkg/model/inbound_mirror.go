@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strconv"
+	"strings"
+
+	"istio.io/istio/pkg/config/host"
+)
+
+// Annotations mirroring the inbound HTTP traffic of a workload to a test destination, such as a staging copy of
+// the service. Unlike the mirroring of VirtualService, which is configured on the routes of the clients, the
+// requests are mirrored by the sidecar of the workload, whatever their client. They can also be set with the proxy
+// metadata of the ProxyConfig of the workload, as ISTIO_META_INBOUND_MIRROR and
+// ISTIO_META_INBOUND_MIRROR_PERCENTAGE.
+// TODO: move to API
+const (
+	// InboundMirrorAnnotation is the destination the inbound requests are mirrored to, as "<host>[:<port>]". The
+	// port defaults to the service port the requests are received on. The destination must be visible to the
+	// workload, as for the destinations of its outbound traffic. The responses of the destination are discarded.
+	InboundMirrorAnnotation = "networking.istio.io/inbound-mirror"
+	// InboundMirrorPercentageAnnotation is the percentage of the inbound requests mirrored, from 0 to 100.
+	// Defaults to 100.
+	InboundMirrorPercentageAnnotation = "networking.istio.io/inbound-mirror-percentage"
+)
+
+var inboundMirrorMetadata = map[string]string{
+	InboundMirrorAnnotation:           "INBOUND_MIRROR",
+	InboundMirrorPercentageAnnotation: "INBOUND_MIRROR_PERCENTAGE",
+}
+
+// InboundMirrorPolicy is the mirroring of the inbound HTTP requests of a proxy.
+type InboundMirrorPolicy struct {
+	// Host is the destination of the mirrored requests.
+	Host host.Name
+	// Port is the port of the destination. The service port of the requests if 0.
+	Port int
+	// Percentage is the percentage of the requests mirrored.
+	Percentage float64
+}
+
+// InboundMirrorPolicy returns the mirroring of the inbound requests of the proxy, nil if the requests are not
+// mirrored. Annotations take precedence over the proxy metadata, and invalid settings disable the mirroring.
+func (node *Proxy) InboundMirrorPolicy() *InboundMirrorPolicy {
+	if node.Metadata == nil {
+		return nil
+	}
+	dest := node.workloadSetting(InboundMirrorAnnotation, inboundMirrorMetadata[InboundMirrorAnnotation])
+	if dest == "" {
+		return nil
+	}
+	p := &InboundMirrorPolicy{Percentage: 100}
+	h, port, hasPort := strings.Cut(dest, ":")
+	p.Host = host.Name(h)
+	if hasPort {
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil || n == 0 {
+			log.Debugf("ignoring inbound mirror %q of proxy %s", dest, node.ID)
+			return nil
+		}
+		p.Port = int(n)
+	}
+	if v := node.workloadSetting(InboundMirrorPercentageAnnotation,
+		inboundMirrorMetadata[InboundMirrorPercentageAnnotation]); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f < 0 || f > 100 {
+			log.Debugf("ignoring inbound mirror percentage %q of proxy %s", v, node.ID)
+			return nil
+		}
+		p.Percentage = f
+	}
+	if p.Host == "" || p.Percentage == 0 {
+		return nil
+	}
+	return p
+}
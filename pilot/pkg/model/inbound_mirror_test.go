@@ -0,0 +1,81 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInboundMirrorPolicy(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		raw         map[string]any
+		want        *InboundMirrorPolicy
+	}{
+		{name: "unset"},
+		{
+			name:        "host",
+			annotations: map[string]string{InboundMirrorAnnotation: "reviews-test.default.svc.cluster.local"},
+			want:        &InboundMirrorPolicy{Host: "reviews-test.default.svc.cluster.local", Percentage: 100},
+		},
+		{
+			name: "host, port and percentage",
+			annotations: map[string]string{
+				InboundMirrorAnnotation:           "reviews-test.default.svc.cluster.local:9080",
+				InboundMirrorPercentageAnnotation: "12.5",
+			},
+			want: &InboundMirrorPolicy{Host: "reviews-test.default.svc.cluster.local", Port: 9080, Percentage: 12.5},
+		},
+		{
+			name: "proxy metadata",
+			raw:  map[string]any{"INBOUND_MIRROR": "reviews-test.default.svc.cluster.local", "INBOUND_MIRROR_PERCENTAGE": "10"},
+			want: &InboundMirrorPolicy{Host: "reviews-test.default.svc.cluster.local", Percentage: 10},
+		},
+		{
+			name:        "annotation overrides proxy metadata",
+			annotations: map[string]string{InboundMirrorPercentageAnnotation: "50"},
+			raw:         map[string]any{"INBOUND_MIRROR": "reviews-test.default.svc.cluster.local", "INBOUND_MIRROR_PERCENTAGE": "10"},
+			want:        &InboundMirrorPolicy{Host: "reviews-test.default.svc.cluster.local", Percentage: 50},
+		},
+		{
+			name: "zero percentage",
+			annotations: map[string]string{
+				InboundMirrorAnnotation:           "reviews-test.default.svc.cluster.local",
+				InboundMirrorPercentageAnnotation: "0",
+			},
+		},
+		{
+			name:        "invalid port",
+			annotations: map[string]string{InboundMirrorAnnotation: "reviews-test.default.svc.cluster.local:http"},
+		},
+		{
+			name: "invalid percentage",
+			annotations: map[string]string{
+				InboundMirrorAnnotation:           "reviews-test.default.svc.cluster.local",
+				InboundMirrorPercentageAnnotation: "150",
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &Proxy{Metadata: &NodeMetadata{Annotations: tt.annotations, Raw: tt.raw}}
+			if got := node.InboundMirrorPolicy(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Annotations configuring the inbound passthrough filter chains of a workload, which handle the traffic sent to
+// ports that are not declared by any Service or Sidecar. They can also be set with the proxy metadata of the
+// ProxyConfig of the workload, as ISTIO_META_INBOUND_PASSTHROUGH_PORTS, ISTIO_META_INBOUND_PASSTHROUGH_TIMEOUT
+// and ISTIO_META_INBOUND_PASSTHROUGH_TCP_ONLY.
+// TODO: move to API
+const (
+	// InboundPassthroughPortsAnnotation is a comma separated list of ports and port ranges, such as
+	// "8080,9000-9100", to which undeclared inbound traffic is allowed. Traffic to other undeclared ports is
+	// rejected.
+	InboundPassthroughPortsAnnotation = "networking.istio.io/inbound-passthrough-ports"
+	// InboundPassthroughTimeoutAnnotation is the idle timeout of the undeclared inbound TCP connections.
+	InboundPassthroughTimeoutAnnotation = "networking.istio.io/inbound-passthrough-timeout"
+	// InboundPassthroughTCPOnlyAnnotation disables protocol sniffing for undeclared inbound traffic, which is
+	// then always proxied as TCP.
+	InboundPassthroughTCPOnlyAnnotation = "networking.istio.io/inbound-passthrough-tcp-only"
+)
+
+var inboundPassthroughMetadata = map[string]string{
+	InboundPassthroughPortsAnnotation:   "INBOUND_PASSTHROUGH_PORTS",
+	InboundPassthroughTimeoutAnnotation: "INBOUND_PASSTHROUGH_TIMEOUT",
+	InboundPassthroughTCPOnlyAnnotation: "INBOUND_PASSTHROUGH_TCP_ONLY",
+}
+
+// PortRange is an inclusive range of ports.
+type PortRange struct {
+	Start uint32
+	End   uint32
+}
+
+// InboundPassthroughPolicy is the policy of the inbound passthrough filter chains of a proxy.
+type InboundPassthroughPolicy struct {
+	// AllowedPorts are the undeclared ports to which inbound traffic is allowed. All ports are allowed if nil.
+	AllowedPorts []PortRange
+	// IdleTimeout overrides the idle timeout of the undeclared inbound TCP connections, if set.
+	IdleTimeout *time.Duration
+	// TCPOnly disables protocol sniffing for undeclared inbound traffic.
+	TCPOnly bool
+}
+
+// Restricted returns true if inbound traffic is not allowed to every undeclared port.
+func (p InboundPassthroughPolicy) Restricted() bool {
+	return p.AllowedPorts != nil
+}
+
+// InboundPassthroughPolicy returns the inbound passthrough policy of the proxy. Annotations take precedence over
+// the proxy metadata, and invalid settings are ignored.
+func (node *Proxy) InboundPassthroughPolicy() InboundPassthroughPolicy {
+	var p InboundPassthroughPolicy
+	if node.Metadata == nil {
+		return p
+	}
+	if v := node.inboundPassthroughSetting(InboundPassthroughPortsAnnotation); v != "" {
+		ports, err := ParsePortRanges(v)
+		if err != nil {
+			log.Debugf("ignoring inbound passthrough ports of proxy %s: %v", node.ID, err)
+		} else {
+			p.AllowedPorts = ports
+		}
+	}
+	if v := node.inboundPassthroughSetting(InboundPassthroughTimeoutAnnotation); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			log.Debugf("ignoring inbound passthrough timeout %q of proxy %s", v, node.ID)
+		} else {
+			p.IdleTimeout = &d
+		}
+	}
+	if v := node.inboundPassthroughSetting(InboundPassthroughTCPOnlyAnnotation); v != "" {
+		p.TCPOnly, _ = strconv.ParseBool(v)
+	}
+	return p
+}
+
+// inboundPassthroughSetting returns the value of the annotation, or of the matching proxy metadata.
+func (node *Proxy) inboundPassthroughSetting(annotation string) string {
+	return node.workloadSetting(annotation, inboundPassthroughMetadata[annotation])
+}
+
+// workloadSetting returns the value of the annotation of the workload, or of the proxy metadata key.
+func (node *Proxy) workloadSetting(annotation, metadataKey string) string {
+	if v, f := node.Metadata.Annotations[annotation]; f {
+		return v
+	}
+	if v, ok := node.Metadata.Raw[metadataKey].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// ParsePortRanges parses a comma separated list of ports and inclusive port ranges, such as "8080,9000-9100".
+func ParsePortRanges(s string) ([]PortRange, error) {
+	out := []PortRange{}
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		start, end, isRange := strings.Cut(item, "-")
+		if !isRange {
+			end = start
+		}
+		first, err := parsePort(start)
+		if err != nil {
+			return nil, err
+		}
+		last, err := parsePort(end)
+		if err != nil {
+			return nil, err
+		}
+		if first > last {
+			return nil, fmt.Errorf("invalid port range %q", item)
+		}
+		out = append(out, PortRange{Start: first, End: last})
+	}
+	return out, nil
+}
+
+func parsePort(s string) (uint32, error) {
+	p, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+	if err != nil || p == 0 {
+		return 0, fmt.Errorf("invalid port %q", s)
+	}
+	return uint32(p), nil
+}
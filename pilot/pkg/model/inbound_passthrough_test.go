@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePortRanges(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    []PortRange
+		wantErr bool
+	}{
+		{in: "8080", want: []PortRange{{8080, 8080}}},
+		{in: "8080, 9000-9100", want: []PortRange{{8080, 8080}, {9000, 9100}}},
+		{in: "", want: []PortRange{}},
+		{in: "9100-9000", wantErr: true},
+		{in: "0", wantErr: true},
+		{in: "70000", wantErr: true},
+		{in: "http", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParsePortRanges(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInboundPassthroughPolicy(t *testing.T) {
+	timeout := 10 * time.Second
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		raw         map[string]any
+		want        InboundPassthroughPolicy
+	}{
+		{name: "unset"},
+		{
+			name: "annotations",
+			annotations: map[string]string{
+				InboundPassthroughPortsAnnotation:   "8080",
+				InboundPassthroughTimeoutAnnotation: "10s",
+				InboundPassthroughTCPOnlyAnnotation: "true",
+			},
+			want: InboundPassthroughPolicy{AllowedPorts: []PortRange{{8080, 8080}}, IdleTimeout: &timeout, TCPOnly: true},
+		},
+		{
+			name: "proxy metadata",
+			raw:  map[string]any{"INBOUND_PASSTHROUGH_PORTS": "9000-9100"},
+			want: InboundPassthroughPolicy{AllowedPorts: []PortRange{{9000, 9100}}},
+		},
+		{
+			name:        "annotation overrides proxy metadata",
+			annotations: map[string]string{InboundPassthroughPortsAnnotation: "8080"},
+			raw:         map[string]any{"INBOUND_PASSTHROUGH_PORTS": "9000-9100"},
+			want:        InboundPassthroughPolicy{AllowedPorts: []PortRange{{8080, 8080}}},
+		},
+		{
+			name:        "invalid",
+			annotations: map[string]string{InboundPassthroughPortsAnnotation: "http", InboundPassthroughTimeoutAnnotation: "-1s"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			node := &Proxy{Metadata: &NodeMetadata{Annotations: tt.annotations, Raw: tt.raw}}
+			if got := node.InboundPassthroughPolicy(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
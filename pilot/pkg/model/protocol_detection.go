@@ -0,0 +1,132 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"istio.io/istio/pkg/config/protocol"
+)
+
+// Annotations of a Service tuning the protocol detection of its ports whose protocol is not declared. The
+// global protocol detection timeout delays the first bytes of server-first protocols, such as MySQL, which
+// never send the data the detection waits for.
+// TODO: move to API
+const (
+	// ProtocolDetectionDisabledPortsAnnotation is a comma separated list of service ports, such as "3306,5432",
+	// treated as TCP instead of being detected.
+	ProtocolDetectionDisabledPortsAnnotation = "networking.istio.io/protocol-detection-disabled-ports"
+	// ProtocolDetectionTimeoutAnnotation is a comma separated list of service ports and protocol detection
+	// timeouts, such as "8080=500ms,9000=2s". The timeout applies to the outbound listeners of the port, and to
+	// the inbound listeners bound to the port; the inbound traffic redirected to the virtual inbound listener
+	// keeps the timeout of the mesh, as Envoy has a single timeout per listener.
+	ProtocolDetectionTimeoutAnnotation = "networking.istio.io/protocol-detection-timeout"
+	// ProtocolDetectionFallbackAnnotation is a comma separated list of service ports and the protocol, "tcp" or
+	// "http", the inbound connections whose protocol is not detected are handled as, such as "8080=http".
+	// Defaults to tcp.
+	ProtocolDetectionFallbackAnnotation = "networking.istio.io/protocol-detection-fallback"
+)
+
+// PortProtocolDetection is the protocol detection of a service port.
+type PortProtocolDetection struct {
+	// Disabled is true if the port is treated as TCP instead of being detected.
+	Disabled bool `json:"disabled,omitempty"`
+	// Timeout is the protocol detection timeout of the port, the one of the mesh if 0.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Fallback is the protocol of the connections whose protocol is not detected, TCP if unset.
+	Fallback protocol.Instance `json:"fallback,omitempty"`
+}
+
+// ParseProtocolDetection parses the protocol detection annotations of a Service, keyed by service port.
+// Nil is returned if the Service declares none. Invalid entries are ignored.
+func ParseProtocolDetection(annotations map[string]string) map[int]PortProtocolDetection {
+	var out map[int]PortProtocolDetection
+	set := func(port int, f func(*PortProtocolDetection)) {
+		if out == nil {
+			out = map[int]PortProtocolDetection{}
+		}
+		d := out[port]
+		f(&d)
+		out[port] = d
+	}
+	for _, p := range splitAnnotationList(annotations[ProtocolDetectionDisabledPortsAnnotation]) {
+		port, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			log.Warnf("invalid %s annotation port %q: %v", ProtocolDetectionDisabledPortsAnnotation, p, err)
+			continue
+		}
+		set(int(port), func(d *PortProtocolDetection) { d.Disabled = true })
+	}
+	for _, e := range splitAnnotationList(annotations[ProtocolDetectionTimeoutAnnotation]) {
+		port, v, ok := parsePortEntry(ProtocolDetectionTimeoutAnnotation, e)
+		if !ok {
+			continue
+		}
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			log.Warnf("invalid %s annotation timeout %q", ProtocolDetectionTimeoutAnnotation, e)
+			continue
+		}
+		set(port, func(d *PortProtocolDetection) { d.Timeout = timeout })
+	}
+	for _, e := range splitAnnotationList(annotations[ProtocolDetectionFallbackAnnotation]) {
+		port, v, ok := parsePortEntry(ProtocolDetectionFallbackAnnotation, e)
+		if !ok {
+			continue
+		}
+		fallback := protocol.Parse(v)
+		if fallback != protocol.TCP && fallback != protocol.HTTP {
+			log.Warnf("invalid %s annotation protocol %q, expected tcp or http", ProtocolDetectionFallbackAnnotation, e)
+			continue
+		}
+		set(port, func(d *PortProtocolDetection) { d.Fallback = fallback })
+	}
+	return out
+}
+
+// ProtocolDetection returns the protocol detection of a port of the service.
+func (s *Service) ProtocolDetection(port int) PortProtocolDetection {
+	if s == nil {
+		return PortProtocolDetection{}
+	}
+	return s.Attributes.ProtocolDetection[port]
+}
+
+func splitAnnotationList(v string) []string {
+	var out []string
+	for _, e := range strings.Split(v, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// parsePortEntry parses a "<port>=<value>" entry of an annotation.
+func parsePortEntry(annotation, entry string) (int, string, bool) {
+	p, v, ok := strings.Cut(entry, "=")
+	if !ok {
+		log.Warnf("invalid %s annotation entry %q, expected <port>=<value>", annotation, entry)
+		return 0, "", false
+	}
+	port, err := strconv.ParseUint(strings.TrimSpace(p), 10, 16)
+	if err != nil {
+		log.Warnf("invalid %s annotation port %q: %v", annotation, p, err)
+		return 0, "", false
+	}
+	return int(port), strings.TrimSpace(v), true
+}
@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestParseProtocolDetection(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        map[int]PortProtocolDetection
+	}{
+		{
+			name: "none",
+			want: nil,
+		},
+		{
+			name: "all settings",
+			annotations: map[string]string{
+				ProtocolDetectionDisabledPortsAnnotation: "3306, 5432",
+				ProtocolDetectionTimeoutAnnotation:       "8080=500ms,9000=2s",
+				ProtocolDetectionFallbackAnnotation:      "8080=http,9000=tcp",
+			},
+			want: map[int]PortProtocolDetection{
+				3306: {Disabled: true},
+				5432: {Disabled: true},
+				8080: {Timeout: 500 * time.Millisecond, Fallback: protocol.HTTP},
+				9000: {Timeout: 2 * time.Second, Fallback: protocol.TCP},
+			},
+		},
+		{
+			name: "invalid entries",
+			annotations: map[string]string{
+				ProtocolDetectionDisabledPortsAnnotation: "mysql",
+				ProtocolDetectionTimeoutAnnotation:       "8080,9000=-1s,9001=1s",
+				ProtocolDetectionFallbackAnnotation:      "8080=grpc",
+			},
+			want: map[int]PortProtocolDetection{
+				9001: {Timeout: time.Second},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, ParseProtocolDetection(tt.annotations), tt.want)
+		})
+	}
+}
@@ -15,11 +15,15 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
+
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 
 	"istio.io/api/annotation"
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/api/networking/v1beta1"
+	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -30,10 +34,41 @@ import (
 
 var pclog = istiolog.RegisterScope("proxyconfig", "Istio ProxyConfig", 0)
 
+// ProxyConfigTracingAnnotation is the annotation of a ProxyConfig overriding the trace sampling of the selected
+// workloads. It is a JSON object with the sampler, one of random, client or overall, and the sampling rate in
+// percent, for example {"sampler": "random", "rate": 10}. The random sampler samples the requests at the rate, the
+// client sampler only samples the requests forced by the client with the x-client-trace-id header, at the rate, and
+// the overall sampler caps the sampling of all the requests, forced or not, at the rate.
+// The sampling of the proxy.istio.io/config annotation of a pod takes precedence over it.
+// TODO: move to API
+const ProxyConfigTracingAnnotation = "proxyconfig.istio.io/tracing"
+
+// ProxyConfigStatsMatcherAnnotation is the annotation of a ProxyConfig overriding the Envoy stats matcher of the
+// selected workloads. It is the JSON of the proxyStatsMatcher of the ProxyConfig of the MeshConfig, for example
+// {"inclusionPrefixes": ["cluster.outbound"]}, and replaces the sidecar.istio.io/statsInclusion annotations of the
+// pods. As the stats matcher is part of the bootstrap, it only applies to the workloads injected after the change.
+// TODO: move to API
+const ProxyConfigStatsMatcherAnnotation = "proxyconfig.istio.io/stats-matcher"
+
+// TracingSamplerType is the type of the trace sampler of a ProxyConfigTracingAnnotation.
+type TracingSamplerType string
+
+const (
+	RandomTracingSampler  TracingSamplerType = "random"
+	ClientTracingSampler  TracingSamplerType = "client"
+	OverallTracingSampler TracingSamplerType = "overall"
+)
+
+// TracingSampler is the trace sampling of a ProxyConfigTracingAnnotation.
+type TracingSampler struct {
+	Type TracingSamplerType `json:"sampler"`
+	Rate float64            `json:"rate"`
+}
+
 // ProxyConfigs organizes ProxyConfig configuration by namespace.
 type ProxyConfigs struct {
 	// namespaceToProxyConfigs
-	namespaceToProxyConfigs map[string][]*v1beta1.ProxyConfig
+	namespaceToProxyConfigs map[string][]proxyConfigEntry
 
 	// root namespace
 	rootNamespace string
@@ -62,20 +97,115 @@ func (p *ProxyConfigs) EffectiveProxyConfig(meta *NodeMetadata, mc *meshconfig.M
 
 	// Check for proxy.istio.io/config annotation and merge it with lower priority than the
 	// workload-matching ProxyConfig CRs.
+	var pca *meshconfig.ProxyConfig
 	if v, ok := meta.Annotations[annotation.ProxyConfig.Name]; ok {
-		pca, err := proxyConfigFromAnnotation(v)
+		var err error
+		pca, err = proxyConfigFromAnnotation(v)
 		if err == nil {
 			workloadConfig = mergeWithPrecedence(workloadConfig, pca)
 		}
 	}
 	effectiveProxyConfig = mergeWithPrecedence(workloadConfig, effectiveProxyConfig)
+	// The sampling of the annotation takes precedence over the ProxyConfigTracingAnnotation of the CRs.
+	if sampling := pca.GetTracing().GetSampling(); sampling != 0 {
+		if effectiveProxyConfig.Tracing == nil {
+			effectiveProxyConfig.Tracing = &meshconfig.Tracing{}
+		}
+		effectiveProxyConfig.Tracing.Sampling = sampling
+	}
 
 	return effectiveProxyConfig
 }
 
+// EffectiveTracingSampler returns the trace sampler of the ProxyConfig resources for a given proxy, with the
+// ProxyConfig selecting the workload taking precedence over the one of the namespace, which takes precedence over
+// the one of the root namespace. It returns nil if none of them has a ProxyConfigTracingAnnotation, or if the
+// proxy.istio.io/config annotation of the workload sets the sampling, which takes precedence over the resources.
+func (p *ProxyConfigs) EffectiveTracingSampler(meta *NodeMetadata) *TracingSampler {
+	if p == nil || meta == nil {
+		return nil
+	}
+	if v, ok := meta.Annotations[annotation.ProxyConfig.Name]; ok {
+		if pca, err := proxyConfigFromAnnotation(v); err == nil && pca.GetTracing().GetSampling() != 0 {
+			return nil
+		}
+	}
+	if e := p.workloadEntry(meta.Namespace, meta.Labels); e != nil && e.sampler != nil {
+		return e.sampler
+	}
+	if e := p.namespaceEntry(meta.Namespace); e != nil && e.sampler != nil {
+		return e.sampler
+	}
+	if e := p.namespaceEntry(p.rootNamespace); e != nil && e.sampler != nil {
+		return e.sampler
+	}
+	return nil
+}
+
+// proxyConfigEntry is a ProxyConfig resource, along with its conversion to the ProxyConfig of the MeshConfig
+// and the overrides of its annotations.
+type proxyConfigEntry struct {
+	spec    *v1beta1.ProxyConfig
+	config  *meshconfig.ProxyConfig
+	sampler *TracingSampler
+}
+
+func newProxyConfigEntry(cfg config.Config) proxyConfigEntry {
+	spec := cfg.Spec.(*v1beta1.ProxyConfig)
+	e := proxyConfigEntry{spec: spec, config: toMeshConfigProxyConfig(spec)}
+	if v, f := cfg.Annotations[ProxyConfigTracingAnnotation]; f {
+		sampler, err := ParseTracingSampler(v)
+		if err != nil {
+			pclog.Warnf("ignoring invalid %s annotation of ProxyConfig %s/%s: %v", ProxyConfigTracingAnnotation, cfg.Namespace, cfg.Name, err)
+		} else {
+			e.sampler = sampler
+			e.config.Tracing = &meshconfig.Tracing{Sampling: sampler.Rate}
+		}
+	}
+	if v, f := cfg.Annotations[ProxyConfigStatsMatcherAnnotation]; f {
+		pc, err := ParseStatsMatcher(v)
+		if err != nil {
+			pclog.Warnf("ignoring invalid %s annotation of ProxyConfig %s/%s: %v", ProxyConfigStatsMatcherAnnotation, cfg.Namespace, cfg.Name, err)
+		} else {
+			e.config.ProxyStatsMatcher = pc.ProxyStatsMatcher
+		}
+	}
+	return e
+}
+
+// ParseTracingSampler parses the value of a ProxyConfigTracingAnnotation.
+func ParseTracingSampler(v string) (*TracingSampler, error) {
+	sampler := &TracingSampler{}
+	if err := json.Unmarshal([]byte(v), sampler); err != nil {
+		return nil, err
+	}
+	switch sampler.Type {
+	case RandomTracingSampler, ClientTracingSampler, OverallTracingSampler:
+	default:
+		return nil, fmt.Errorf("unknown sampler %q", sampler.Type)
+	}
+	if sampler.Rate < 0 || sampler.Rate > 100 {
+		return nil, fmt.Errorf("rate %v is not between 0 and 100", sampler.Rate)
+	}
+	return sampler, nil
+}
+
+// ParseStatsMatcher parses the value of a ProxyConfigStatsMatcherAnnotation, returning it as the stats matcher of
+// a ProxyConfig of the MeshConfig.
+func ParseStatsMatcher(v string) (*meshconfig.ProxyConfig, error) {
+	pc := &meshconfig.ProxyConfig{}
+	if err := protomarshal.ApplyJSON(fmt.Sprintf(`{"proxyStatsMatcher": %s}`, v), pc); err != nil {
+		return nil, err
+	}
+	if pc.ProxyStatsMatcher == nil {
+		return nil, fmt.Errorf("no stats matcher")
+	}
+	return pc, nil
+}
+
 func GetProxyConfigs(store ConfigStore, mc *meshconfig.MeshConfig) (*ProxyConfigs, error) {
 	proxyconfigs := &ProxyConfigs{
-		namespaceToProxyConfigs: map[string][]*v1beta1.ProxyConfig{},
+		namespaceToProxyConfigs: map[string][]proxyConfigEntry{},
 		rootNamespace:           mc.GetRootNamespace(),
 	}
 	resources, err := store.List(collections.IstioNetworkingV1Beta1Proxyconfigs.Resource().GroupVersionKind(), NamespaceAll)
@@ -85,7 +215,7 @@ func GetProxyConfigs(store ConfigStore, mc *meshconfig.MeshConfig) (*ProxyConfig
 	sortConfigByCreationTime(resources)
 	ns := proxyconfigs.namespaceToProxyConfigs
 	for _, resource := range resources {
-		ns[resource.Namespace] = append(ns[resource.Namespace], resource.Spec.(*v1beta1.ProxyConfig))
+		ns[resource.Namespace] = append(ns[resource.Namespace], newProxyConfigEntry(resource))
 	}
 	return proxyconfigs, nil
 }
@@ -96,27 +226,41 @@ func (p *ProxyConfigs) mergedGlobalConfig() *meshconfig.ProxyConfig {
 
 // mergedNamespaceConfig merges ProxyConfig resources matching the given namespace.
 func (p *ProxyConfigs) mergedNamespaceConfig(namespace string) *meshconfig.ProxyConfig {
-	for _, pc := range p.namespaceToProxyConfigs[namespace] {
-		if pc.GetSelector() == nil {
+	if e := p.namespaceEntry(namespace); e != nil {
+		return e.config
+	}
+	return nil
+}
+
+// mergedWorkloadConfig merges ProxyConfig resources matching the given namespace and labels.
+func (p *ProxyConfigs) mergedWorkloadConfig(namespace string, l map[string]string) *meshconfig.ProxyConfig {
+	if e := p.workloadEntry(namespace, l); e != nil {
+		return e.config
+	}
+	return nil
+}
+
+func (p *ProxyConfigs) namespaceEntry(namespace string) *proxyConfigEntry {
+	for i, e := range p.namespaceToProxyConfigs[namespace] {
+		if e.spec.GetSelector() == nil {
 			// return the first match. this is consistent since
 			// we sort the resources by creation time beforehand.
-			return toMeshConfigProxyConfig(pc)
+			return &p.namespaceToProxyConfigs[namespace][i]
 		}
 	}
 	return nil
 }
 
-// mergedWorkloadConfig merges ProxyConfig resources matching the given namespace and labels.
-func (p *ProxyConfigs) mergedWorkloadConfig(namespace string, l map[string]string) *meshconfig.ProxyConfig {
-	for _, pc := range p.namespaceToProxyConfigs[namespace] {
-		if len(pc.GetSelector().GetMatchLabels()) == 0 {
+func (p *ProxyConfigs) workloadEntry(namespace string, l map[string]string) *proxyConfigEntry {
+	for i, e := range p.namespaceToProxyConfigs[namespace] {
+		if len(e.spec.GetSelector().GetMatchLabels()) == 0 {
 			continue
 		}
-		selector := labels.Instance(pc.GetSelector().GetMatchLabels())
+		selector := labels.Instance(e.spec.GetSelector().GetMatchLabels())
 		if selector.SubsetOf(l) {
 			// return the first match. this is consistent since
 			// we sort the resources by creation time beforehand.
-			return toMeshConfigProxyConfig(pc)
+			return &p.namespaceToProxyConfigs[namespace][i]
 		}
 	}
 	return nil
@@ -133,6 +277,10 @@ func mergeWithPrecedence(pcs ...*meshconfig.ProxyConfig) *meshconfig.ProxyConfig
 		// TODO(Monkeyanator) some fields seem not to merge when set to the type's default value
 		// such as overriding with a concurrency value 0. Do we need a custom merge similar to what the
 		// telemetry code does with shallowMerge?
+		if pcs[i].GetProxyStatsMatcher() != nil {
+			// the stats matcher is replaced as a whole, rather than appending the inclusions.
+			merged.ProxyStatsMatcher = nil
+		}
 		merge.Merge(merged, pcs[i])
 		if pcs[i].GetConcurrency() != nil {
 			merged.Concurrency = wrappers.Int32(pcs[i].GetConcurrency().GetValue())
@@ -399,6 +399,65 @@ func TestEffectiveProxyConfig(t *testing.T) {
 				"A": "1",
 			}},
 		},
+		{
+			name: "CR annotations override tracing and stats matcher",
+			configs: []config.Config{
+				withAnnotations(newProxyConfig("ns", istioRootNamespace,
+					&v1beta1.ProxyConfig{}), map[string]string{
+					ProxyConfigTracingAnnotation:      `{"sampler": "random", "rate": 1}`,
+					ProxyConfigStatsMatcherAnnotation: `{"inclusionPrefixes": ["cluster.outbound"]}`,
+				}),
+				withAnnotations(newProxyConfig("workload", "test-ns",
+					&v1beta1.ProxyConfig{
+						Selector:    selector(map[string]string{"app": "reviews"}),
+						Concurrency: v(2),
+					}), map[string]string{
+					ProxyConfigTracingAnnotation:      `{"sampler": "client", "rate": 10}`,
+					ProxyConfigStatsMatcherAnnotation: `{"inclusionSuffixes": ["upstream_cx_active"]}`,
+				}),
+			},
+			proxy: newMeta("test-ns", map[string]string{"app": "reviews"}, nil),
+			expected: &meshconfig.ProxyConfig{
+				Concurrency: v(2),
+				Tracing:     &meshconfig.Tracing{Sampling: 10},
+				ProxyStatsMatcher: &meshconfig.ProxyConfig_ProxyStatsMatcher{
+					InclusionSuffixes: []string{"upstream_cx_active"},
+				},
+			},
+		},
+		{
+			name: "pod annotation sampling overrides CR annotation",
+			configs: []config.Config{
+				withAnnotations(newProxyConfig("workload", "test-ns",
+					&v1beta1.ProxyConfig{
+						Selector: selector(map[string]string{"app": "reviews"}),
+					}), map[string]string{
+					ProxyConfigTracingAnnotation: `{"sampler": "random", "rate": 10}`,
+				}),
+			},
+			proxy: newMeta("test-ns", map[string]string{"app": "reviews"}, map[string]string{
+				annotation.ProxyConfig.Name: `{"tracing": {"sampling": 50}}`,
+			}),
+			expected: &meshconfig.ProxyConfig{
+				Tracing: &meshconfig.Tracing{Sampling: 50},
+			},
+		},
+		{
+			name: "invalid CR annotations are ignored",
+			configs: []config.Config{
+				withAnnotations(newProxyConfig("ns", "test-ns",
+					&v1beta1.ProxyConfig{
+						Concurrency: v(3),
+					}), map[string]string{
+					ProxyConfigTracingAnnotation:      `{"sampler": "always", "rate": 10}`,
+					ProxyConfigStatsMatcherAnnotation: `["cluster.outbound"]`,
+				}),
+			},
+			proxy: newMeta("test-ns", nil, nil),
+			expected: &meshconfig.ProxyConfig{
+				Concurrency: v(3),
+			},
+		},
 		{
 			name:  "no configured CR or default config",
 			proxy: newMeta("ns", nil, nil),
@@ -428,6 +487,63 @@ func TestEffectiveProxyConfig(t *testing.T) {
 	}
 }
 
+func TestEffectiveTracingSampler(t *testing.T) {
+	configs := []config.Config{
+		withAnnotations(newProxyConfig("root", istioRootNamespace, &v1beta1.ProxyConfig{}),
+			map[string]string{ProxyConfigTracingAnnotation: `{"sampler": "random", "rate": 1}`}),
+		withAnnotations(newProxyConfig("ns", "test-ns", &v1beta1.ProxyConfig{}),
+			map[string]string{ProxyConfigTracingAnnotation: `{"sampler": "overall", "rate": 5}`}),
+		withAnnotations(newProxyConfig("workload", "test-ns", &v1beta1.ProxyConfig{
+			Selector: selector(map[string]string{"app": "reviews"}),
+		}), map[string]string{ProxyConfigTracingAnnotation: `{"sampler": "client", "rate": 10}`}),
+		newProxyConfig("no-sampler", "other-ns", &v1beta1.ProxyConfig{Concurrency: v(2)}),
+	}
+	pcs, err := GetProxyConfigs(newProxyConfigStore(t, configs), &meshconfig.MeshConfig{RootNamespace: istioRootNamespace})
+	if err != nil {
+		t.Fatalf("failed to list proxyconfigs: %v", err)
+	}
+	cases := []struct {
+		name     string
+		proxy    *NodeMetadata
+		expected *TracingSampler
+	}{
+		{
+			name:     "workload",
+			proxy:    newMeta("test-ns", map[string]string{"app": "reviews"}, nil),
+			expected: &TracingSampler{Type: ClientTracingSampler, Rate: 10},
+		},
+		{
+			name:     "namespace",
+			proxy:    newMeta("test-ns", map[string]string{"app": "ratings"}, nil),
+			expected: &TracingSampler{Type: OverallTracingSampler, Rate: 5},
+		},
+		{
+			name:     "root namespace",
+			proxy:    newMeta("other-ns", nil, nil),
+			expected: &TracingSampler{Type: RandomTracingSampler, Rate: 1},
+		},
+		{
+			name: "pod annotation",
+			proxy: newMeta("test-ns", map[string]string{"app": "reviews"},
+				map[string]string{annotation.ProxyConfig.Name: `{"tracing": {"sampling": 50}}`}),
+			expected: nil,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, pcs.EffectiveTracingSampler(tc.proxy), tc.expected)
+		})
+	}
+}
+
+func TestParseTracingSampler(t *testing.T) {
+	for _, v := range []string{`{"sampler": "always"}`, `{"sampler": "random", "rate": 101}`, `{"sampler": "random", "rate": -1}`, `random`} {
+		if _, err := ParseTracingSampler(v); err == nil {
+			t.Errorf("expected %s to be invalid", v)
+		}
+	}
+}
+
 func newProxyConfig(name, ns string, spec config.Spec) config.Config {
 	return config.Config{
 		Meta: config.Meta{
@@ -450,6 +566,11 @@ func newProxyConfigStore(t *testing.T, configs []config.Config) ConfigStore {
 	return store
 }
 
+func withAnnotations(c config.Config, annotations map[string]string) config.Config {
+	c.Meta.Annotations = annotations
+	return c
+}
+
 func setCreationTimestamp(c config.Config, t time.Time) config.Config {
 	c.Meta.CreationTimestamp = t
 	return c
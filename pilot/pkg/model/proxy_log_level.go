@@ -0,0 +1,36 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"time"
+)
+
+// ProxyLogLevel is a temporary change of the log levels of a proxy, pushed by istiod to the agent.
+// The agent reverts the log levels once the expiration time is reached. An empty ProxyLogLevel
+// reverts the log levels immediately.
+type ProxyLogLevel struct {
+	// Envoy contains the levels of the Envoy loggers, in the form "<level>" or "<logger>:<level>,...".
+	Envoy string `json:"envoy,omitempty"`
+	// Agent contains the levels of the agent logging scopes, in the form "<scope>:<level>,...".
+	Agent string `json:"agent,omitempty"`
+	// Expiration is the time at which the log levels are reverted.
+	Expiration time.Time `json:"expiration,omitempty"`
+}
+
+// IsEmpty returns true if no log level is changed.
+func (l *ProxyLogLevel) IsEmpty() bool {
+	return l == nil || (l.Envoy == "" && l.Agent == "")
+}
@@ -0,0 +1,72 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// ProxyShard is the shard of the proxies served by an istiod replica, when proxies are split between
+// replicas by the hash of their namespace. All the replicas share the same config, but each one only
+// accepts connections, and so only computes pushes, for the proxies of its shard.
+//
+// Each shard is an istiod Deployment exposed by its own Service, named after the istiod Service (see ShardHost).
+// The injector points the proxies to the Service of the shard of their namespace.
+type ProxyShard struct {
+	// Index of the shard of this replica, in [0, Count).
+	Index int
+	// Count is the number of shards. Sharding is disabled if it is 0 or 1.
+	Count int
+}
+
+// NewProxyShard returns the shard of an istiod replica.
+func NewProxyShard(count, index int) (ProxyShard, error) {
+	if count <= 1 {
+		return ProxyShard{}, nil
+	}
+	if index < 0 || index >= count {
+		return ProxyShard{}, fmt.Errorf("shard index %d is not in [0, %d)", index, count)
+	}
+	return ProxyShard{Index: index, Count: count}, nil
+}
+
+// Enabled returns true if proxies are sharded.
+func (sh ProxyShard) Enabled() bool {
+	return sh.Count > 1
+}
+
+// Contains returns true if the proxies of the namespace belong to the shard.
+func (sh ProxyShard) Contains(namespace string) bool {
+	return !sh.Enabled() || ShardForNamespace(namespace, sh.Count) == sh.Index
+}
+
+// ShardForNamespace returns the shard of the proxies of the namespace, out of count shards.
+func ShardForNamespace(namespace string, count int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(namespace))
+	return int(h.Sum32() % uint32(count))
+}
+
+// ShardHost returns the host of the Service of a shard, from the host of the istiod Service: the shards of
+// istiod.istio-system.svc are istiod-shard-<index>.istio-system.svc.
+func ShardHost(istiodHost string, index int) string {
+	name, domain, _ := strings.Cut(istiodHost, ".")
+	if domain == "" {
+		return fmt.Sprintf("%s-shard-%d", name, index)
+	}
+	return fmt.Sprintf("%s-shard-%d.%s", name, index, domain)
+}
@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewProxyShard(t *testing.T) {
+	cases := []struct {
+		count int
+		index int
+		want  ProxyShard
+		err   bool
+	}{
+		{count: 0, index: -1, want: ProxyShard{}},
+		{count: 1, index: 3, want: ProxyShard{}},
+		{count: 3, index: 1, want: ProxyShard{Index: 1, Count: 3}},
+		{count: 3, index: -1, err: true},
+		{count: 3, index: 3, err: true},
+	}
+	for _, tt := range cases {
+		t.Run(fmt.Sprintf("%d/%d", tt.count, tt.index), func(t *testing.T) {
+			got, err := NewProxyShard(tt.count, tt.index)
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyShardContains(t *testing.T) {
+	shards := make([]ProxyShard, 4)
+	for i := range shards {
+		shards[i] = ProxyShard{Index: i, Count: len(shards)}
+	}
+	for _, ns := range []string{"default", "istio-system", "foo", "bar", "baz"} {
+		matches := 0
+		for _, shard := range shards {
+			if shard.Contains(ns) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			t.Errorf("namespace %s is in %d shards, want 1", ns, matches)
+		}
+		if !(ProxyShard{}).Contains(ns) {
+			t.Errorf("namespace %s is not served without sharding", ns)
+		}
+	}
+}
+
+func TestShardHost(t *testing.T) {
+	for host, want := range map[string]string{
+		"istiod.istio-system.svc":        "istiod-shard-2.istio-system.svc",
+		"istiod-canary.istio-system.svc": "istiod-canary-shard-2.istio-system.svc",
+		"istiod":                         "istiod-shard-2",
+	} {
+		if got := ShardHost(host, 2); got != want {
+			t.Errorf("ShardHost(%s) = %s, want %s", host, got, want)
+		}
+	}
+}
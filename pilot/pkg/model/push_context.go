@@ -29,11 +29,13 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/locality"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/schema/kind"
@@ -282,6 +284,8 @@ type ConsolidatedDestRule struct {
 	rule *config.Config
 	// the original dest rules from which above rule is merged.
 	from []types.NamespacedName
+	// localityEjectionPolicy is parsed from the annotations of the rule once, rather than for each cluster built.
+	localityEjectionPolicy *locality.EjectionPolicy
 }
 
 // XDSUpdater is used for direct updates of the xDS model and incremental push.
@@ -407,6 +411,14 @@ const (
 	NamespaceUpdate TriggerReason = "namespace"
 	// ClusterUpdate describes a push triggered by a Cluster change
 	ClusterUpdate TriggerReason = "cluster"
+	// ProxyLogLevelUpdate describes a push triggered by a change of the log levels of proxies
+	ProxyLogLevelUpdate TriggerReason = "proxyloglevel"
+	// ScopedPush describes a full push of selected proxies, triggered for debugging, which recomputes their state
+	// and bypasses the configuration shared between equivalent proxies.
+	ScopedPush TriggerReason = "scopedpush"
+	// LoadReportUpdate describes a push triggered by a change of the load aware weights of endpoints, computed from
+	// the load reported by the proxies.
+	LoadReportUpdate TriggerReason = "loadreport"
 )
 
 // Merge two update requests together
@@ -495,6 +507,19 @@ func (pr *PushRequest) IsRequest() bool {
 	return len(pr.Reason) == 1 && pr.Reason[0] == ProxyRequest
 }
 
+// IsProxyLogLevelUpdate returns true if the push only changes the log levels of proxies.
+func (pr *PushRequest) IsProxyLogLevelUpdate() bool {
+	if pr.Full || len(pr.Reason) == 0 {
+		return false
+	}
+	for _, r := range pr.Reason {
+		if r != ProxyLogLevelUpdate {
+			return false
+		}
+	}
+	return true
+}
+
 func (pr *PushRequest) IsProxyUpdate() bool {
 	for _, r := range pr.Reason {
 		if r == ProxyUpdate {
@@ -504,6 +529,16 @@ func (pr *PushRequest) IsProxyUpdate() bool {
 	return false
 }
 
+// IsScopedPush returns true if the push was triggered for selected proxies through the debug interface.
+func (pr *PushRequest) IsScopedPush() bool {
+	for _, r := range pr.Reason {
+		if r == ScopedPush {
+			return true
+		}
+	}
+	return false
+}
+
 func (pr *PushRequest) PushReason() string {
 	if pr.IsRequest() {
 		return " request"
@@ -623,6 +658,12 @@ var (
 		"Duplicate subsets across destination rules for same host",
 	)
 
+	// DuplicatedGeneratedNames tracks Envoy resource names generated for different configurations.
+	DuplicatedGeneratedNames = monitoring.NewGauge(
+		"pilot_duplicate_generated_names",
+		"Envoy resource names, such as gateway route configurations, generated for different configurations.",
+	)
+
 	// totalVirtualServices tracks the total number of virtual service
 	totalVirtualServices = monitoring.NewGauge(
 		"pilot_virt_services",
@@ -649,6 +690,7 @@ var (
 		ProxyStatusClusterNoInstances,
 		DuplicatedDomains,
 		DuplicatedSubsets,
+		DuplicatedGeneratedNames,
 	}
 )
 
@@ -699,6 +741,29 @@ func (ps *PushContext) StatusJSON() ([]byte, error) {
 	return json.MarshalIndent(ps.ProxyStatus, "", "    ")
 }
 
+// ProxyStatusFor returns the status of the push for the proxies matching the function. The status not related to a
+// proxy in particular is left out.
+func (ps *PushContext) ProxyStatusFor(match func(proxyID string) bool) map[string]map[string]ProxyPushStatus {
+	out := map[string]map[string]ProxyPushStatus{}
+	if ps == nil {
+		return out
+	}
+	ps.proxyStatusMutex.RLock()
+	defer ps.proxyStatusMutex.RUnlock()
+	for metric, statuses := range ps.ProxyStatus {
+		for key, status := range statuses {
+			if status.Proxy == "" || !match(status.Proxy) {
+				continue
+			}
+			if out[metric] == nil {
+				out[metric] = map[string]ProxyPushStatus{}
+			}
+			out[metric][key] = status
+		}
+	}
+	return out
+}
+
 // OnConfigChange is called when a config change is detected.
 func (ps *PushContext) OnConfigChange() {
 	LastPushMutex.Lock()
@@ -942,6 +1007,17 @@ func (ps *PushContext) DelegateVirtualServices(vses []config.Config) []ConfigHas
 	return out
 }
 
+// SidecarScopeCounts returns the number of sidecar scopes of the push context: the scopes of the Sidecar configs,
+// and the scopes derived for the namespaces without their own Sidecar, which are computed lazily.
+func (ps *PushContext) SidecarScopeCounts() (configured int, derived int) {
+	for _, scopes := range ps.sidecarIndex.sidecarsByNamespace {
+		configured += len(scopes)
+	}
+	ps.sidecarIndex.derivedSidecarMutex.RLock()
+	defer ps.sidecarIndex.derivedSidecarMutex.RUnlock()
+	return configured, len(ps.sidecarIndex.meshRootSidecarsByNamespace) + len(ps.sidecarIndex.defaultSidecarsByNamespace)
+}
+
 // getSidecarScope returns a SidecarScope object associated with the
 // proxy. The SidecarScope object is a semi-processed view of the service
 // registry, and config state associated with the sidecar crd. The scope contains
@@ -1477,7 +1553,7 @@ func (ps *PushContext) initServiceAccounts(env *Environment, services []*Service
 				if len(svc.ServiceAccounts) > 0 {
 					accounts = accounts.Copy().InsertAll(svc.ServiceAccounts...)
 				}
-				sa := sets.SortedList(spiffe.ExpandWithTrustDomains(accounts, ps.Mesh.TrustDomainAliases))
+				sa := sets.SortedList(spiffe.ExpandWithTrustDomains(accounts, trustdomain.Aliases(ps.Mesh)))
 				key := serviceAccountKey{
 					hostname:  svc.Hostname,
 					namespace: svc.Attributes.Namespace,
@@ -32,9 +32,12 @@ import (
 	"github.com/mitchellh/copystructure"
 
 	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pilot/pkg/util/names"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/healthcheck"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
@@ -493,6 +496,10 @@ type IstioEndpoint struct {
 
 	// Indicatesthe endpoint health status.
 	HealthStatus HealthStatus
+
+	// DrainDeadline is the time until which the endpoint of a pod being deleted is kept, draining, before being
+	// removed. Zero if the endpoint is not drained before its removal.
+	DrainDeadline time.Time
 }
 
 func (ep *IstioEndpoint) SupportsTunnel(tunnelType string) bool {
@@ -593,6 +600,25 @@ type ServiceAttributes struct {
 
 	// Type holds the value of the corev1.Type of the Kubernetes service
 	Type string
+
+	// ClientAddressDetection holds the client address detection settings declared on the
+	// service, applied to gateways selected by it. Nil if none were declared.
+	ClientAddressDetection *ClientAddressDetection
+
+	// DNSSRV is true if istiod resolves the endpoints of the service, including their ports, from DNS SRV records.
+	DNSSRV bool
+
+	// DynamicForwardProxy is true if the proxies forward the requests to the service to the hosts they are sent to,
+	// resolved by the proxies, rather than to the endpoints of the service. The hosts of the service, which may be
+	// wildcards, are the domains the proxies are allowed to forward to. The telemetry of the requests is that of
+	// the service: the destination_service is the host of the service, not the domain of each request.
+	DynamicForwardProxy bool
+
+	// HealthCheck is the active health checking of the endpoints of the service, if any.
+	HealthCheck *healthcheck.HealthCheck
+
+	// ProtocolDetection holds the protocol detection settings declared on the service, keyed by service port.
+	ProtocolDetection map[int]PortProtocolDetection
 }
 
 // DeepCopy creates a deep copy of ServiceAttributes, but skips internal mutexes.
@@ -624,6 +650,27 @@ func (s *ServiceAttributes) DeepCopy() ServiceAttributes {
 
 	out.ClusterExternalAddresses = s.ClusterExternalAddresses.DeepCopy()
 
+	if s.ClientAddressDetection != nil {
+		cad := *s.ClientAddressDetection
+		out.ClientAddressDetection = &cad
+	}
+
+	if s.ProtocolDetection != nil {
+		out.ProtocolDetection = make(map[int]PortProtocolDetection, len(s.ProtocolDetection))
+		for k, v := range s.ProtocolDetection {
+			out.ProtocolDetection[k] = v
+		}
+	}
+
+	if s.HealthCheck != nil {
+		hc := *s.HealthCheck
+		if hc.HTTP != nil {
+			http := *hc.HTTP
+			hc.HTTP = &http
+		}
+		out.HealthCheck = &hc
+	}
+
 	if s.ClusterExternalPorts != nil {
 		out.ClusterExternalPorts = make(map[cluster.ID]map[uint32]uint32, len(s.ClusterExternalPorts))
 		for k, m := range s.ClusterExternalPorts {
@@ -746,6 +793,12 @@ func (ports PortList) GetByPort(num int) (*Port, bool) {
 	return nil, false
 }
 
+// IsDynamicForwardProxyPort returns true if the requests to the port of the service are forwarded to the hosts they
+// are sent to. Only the hosts of HTTP requests and the SNI of TLS connections can be forwarded to.
+func (s *Service) IsDynamicForwardProxyPort(port *Port) bool {
+	return s != nil && s.Attributes.DynamicForwardProxy && port != nil && (port.Protocol.IsHTTP() || port.Protocol.IsTLS())
+}
+
 // External predicate checks whether the service is external
 func (s *Service) External() bool {
 	return s.MeshExternal
@@ -765,11 +818,19 @@ func BuildInboundSubsetKey(port int) string {
 // BuildDNSSrvSubsetKey generates a unique string referencing service instances for a given service name, a subset and a port.
 // The proxy queries Pilot with this key to obtain the list of instances in a subset.
 // This is used only for the SNI-DNAT router. Do not use for other purposes.
-// The DNS Srv format of the cluster is also used as the default SNI string for Istio mTLS connections
+// The DNS Srv format of the cluster is also used as the default SNI string for Istio mTLS connections, see
+// BuildDNSSrvSubsetSNI.
 func BuildDNSSrvSubsetKey(direction TrafficDirection, subsetName string, hostname host.Name, port int) string {
 	return string(direction) + "_." + strconv.Itoa(port) + "_." + subsetName + "_." + string(hostname)
 }
 
+// BuildDNSSrvSubsetSNI returns the SNI of the Istio mTLS connections to a service subset and port, which is the
+// DNS Srv subset key truncated to the maximum length of SNIs. Unlike the key, it is not parsed, so it must not be
+// used as a cluster name.
+func BuildDNSSrvSubsetSNI(direction TrafficDirection, subsetName string, hostname host.Name, port int) string {
+	return names.Truncate(BuildDNSSrvSubsetKey(direction, subsetName, hostname, port), names.MaxSNILength)
+}
+
 // IsValidSubsetKey checks if a string is valid for subset key parsing.
 func IsValidSubsetKey(s string) bool {
 	return strings.Count(s, "|") == 3
@@ -851,6 +912,8 @@ func (s *Service) GetAddressForProxy(node *Proxy) string {
 
 // GetExtraAddressesForProxy returns a k8s service's extra addresses to the cluster where the node resides.
 // Especially for dual stack k8s service to get other IP family addresses.
+// With dual stack enabled, the auto allocated IPv6 address is also returned to dual stack proxies, along with
+// the auto allocated IPv4 address returned by GetAddressForProxy.
 func (s *Service) GetExtraAddressesForProxy(node *Proxy) []string {
 	if node.Metadata != nil {
 		if node.Metadata.ClusterID != "" {
@@ -858,6 +921,14 @@ func (s *Service) GetExtraAddressesForProxy(node *Proxy) []string {
 			if len(addresses) > 1 {
 				return addresses[1:]
 			}
+			if len(addresses) > 0 {
+				return nil
+			}
+		}
+		if features.EnableDualStack && node.Metadata.DNSCapture && node.Metadata.DNSAutoAllocate &&
+			s.DefaultAddress == constants.UnspecifiedIP && node.SupportsIPv4() && node.SupportsIPv6() &&
+			s.AutoAllocatedIPv4Address != "" && s.AutoAllocatedIPv6Address != "" {
+			return []string{s.AutoAllocatedIPv6Address}
 		}
 	}
 	return nil
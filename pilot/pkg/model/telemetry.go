@@ -15,20 +15,27 @@
 package model
 
 import (
+	"encoding/json"
+	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	udpa "github.com/cncf/xds/go/udpa/type/v1"
 	accesslog "github.com/envoyproxy/go-control-plane/envoy/config/accesslog/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	httpwasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	wasmfilter "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/wasm/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
+	"golang.org/x/exp/maps"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+	"k8s.io/apimachinery/pkg/util/validation"
 
 	sd "istio.io/api/envoy/extensions/stackdriver/config/v1alpha1"
 	"istio.io/api/envoy/extensions/stats"
@@ -38,6 +45,7 @@ import (
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/schema/collections"
+	telemetryconfig "istio.io/istio/pkg/config/telemetry"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/istio/pkg/util/sets"
@@ -46,11 +54,132 @@ import (
 
 var telemetryLog = istiolog.RegisterScope("telemetry", "Istio Telemetry", 0)
 
+// ConnectionPoolMetric is the custom metric selecting, in the metrics overrides of the Telemetries, the connection
+// pool metrics of the upstream services reported by the agent.
+const ConnectionPoolMetric = "UPSTREAM_CONNECTION_POOL"
+
 // Telemetry holds configuration for Telemetry API resources.
 type Telemetry struct {
 	Name      string         `json:"name"`
 	Namespace string         `json:"namespace"`
 	Spec      *tpb.Telemetry `json:"spec"`
+	// MetricExpiry is the duration after which the idle metrics of the proxies are expired, from the
+	// telemetryconfig.MetricExpiryAnnotation.
+	MetricExpiry time.Duration `json:"metricExpiry,omitempty"`
+	// Promotion is the workload metadata promoted to metric dimensions and trace tags, from the
+	// TelemetryPromotedLabelsAnnotation and TelemetryPromotedAnnotationsAnnotation.
+	Promotion *MetadataPromotion `json:"promotion,omitempty"`
+	// RouteLogging is the access logging of specific routes, from the TelemetryRouteAccessLogFiltersAnnotation.
+	RouteLogging *RouteAccessLogging `json:"routeLogging,omitempty"`
+}
+
+// TelemetryPromotedLabelsAnnotation and TelemetryPromotedAnnotationsAnnotation are the annotations of a Telemetry
+// listing, comma separated, the keys of the pod labels and annotations promoted to the source_<key> and
+// destination_<key> dimensions of the standard metrics and tags of the spans, with the characters other than
+// letters, digits and underscores of the key replaced by underscores. Only the listed keys are promoted, so that the
+// cardinality of the metrics stays under the control of the mesh operators.
+//
+// The labels of the peer are read from the metadata exchanged by the proxies, unlike its annotations: the annotations
+// are only promoted for the workload reporting the metric, and the peer dimension is "unknown". Spans are only tagged
+// with the metadata of the workload reporting them. With the Prometheus provider, the dimensions must also be listed
+// in the extraStatTags of the proxy config. Like the other metrics settings, the Telemetry of the workload overrides
+// the one of the namespace, which overrides the one of the root namespace.
+// TODO: move to API
+const (
+	TelemetryPromotedLabelsAnnotation      = "telemetry.istio.io/promoted-labels"
+	TelemetryPromotedAnnotationsAnnotation = "telemetry.istio.io/promoted-annotations"
+)
+
+// MetadataPromotion is the workload metadata promoted to metric dimensions and trace tags.
+type MetadataPromotion struct {
+	Labels      []string `json:"labels,omitempty"`
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// TelemetryRouteAccessLogFiltersAnnotation is the annotation of a Telemetry scoping access log filters to specific
+// routes or hosts, so that the requests of noisy routes such as health checks can be excluded from the access logs
+// without disabling the logging of the workload. It is a JSON list of filters, each with the names of the routes
+// (the names of the HTTP routes of the VirtualServices) and the hosts (the Host headers) it applies to, and the CEL
+// expression the requests of those routes must match to be logged, for example
+// [{"routes": ["healthz"], "expression": "response.code >= 400"}]. The filters are combined with the filter of the
+// access logging, and only apply to HTTP traffic. Like the other access logging settings, the Telemetry of the
+// workload overrides the one of the namespace, which overrides the one of the root namespace.
+// TODO: move to API
+const TelemetryRouteAccessLogFiltersAnnotation = "telemetry.istio.io/route-access-log-filters"
+
+// RouteAccessLogging is the access logging of specific routes.
+type RouteAccessLogging struct {
+	Filters []RouteAccessLogFilter `json:"filters"`
+}
+
+// RouteAccessLogFilter is an access log filter scoped to routes or hosts.
+type RouteAccessLogFilter struct {
+	Routes     []string `json:"routes,omitempty"`
+	Hosts      []string `json:"hosts,omitempty"`
+	Expression string   `json:"expression"`
+}
+
+// parseRouteAccessLogging returns the route access logging of the annotations of a Telemetry, or nil if there is
+// none. The filters are ignored altogether if any of them is invalid, rather than logging more than expected.
+func parseRouteAccessLogging(annotations map[string]string, namespace, name string) *RouteAccessLogging {
+	v, f := annotations[TelemetryRouteAccessLogFiltersAnnotation]
+	if !f {
+		return nil
+	}
+	rl, err := ParseRouteAccessLogFilters(v)
+	if err != nil {
+		log.Warnf("ignoring invalid %s annotation of Telemetry %s/%s: %v", TelemetryRouteAccessLogFiltersAnnotation, namespace, name, err)
+		return nil
+	}
+	return rl
+}
+
+// ParseRouteAccessLogFilters parses the value of a TelemetryRouteAccessLogFiltersAnnotation.
+func ParseRouteAccessLogFilters(v string) (*RouteAccessLogging, error) {
+	rl := &RouteAccessLogging{}
+	if err := json.Unmarshal([]byte(v), &rl.Filters); err != nil {
+		return nil, err
+	}
+	for i, f := range rl.Filters {
+		if len(f.Routes) == 0 && len(f.Hosts) == 0 {
+			return nil, fmt.Errorf("filter %d applies to no route or host", i)
+		}
+		if strings.TrimSpace(f.Expression) == "" {
+			return nil, fmt.Errorf("filter %d has no expression", i)
+		}
+	}
+	if len(rl.Filters) == 0 {
+		return nil, nil
+	}
+	return rl, nil
+}
+
+// Expression returns the CEL expression of the route access logging: the requests of the scoped routes and hosts
+// are only logged if they match the expressions of the filters.
+func (rl *RouteAccessLogging) Expression() string {
+	if rl == nil {
+		return ""
+	}
+	conds := make([]string, 0, len(rl.Filters))
+	for _, f := range rl.Filters {
+		var scopes []string
+		if len(f.Routes) > 0 {
+			scopes = append(scopes, "(has(xds.route_name) && xds.route_name in "+celList(f.Routes)+")")
+		}
+		if len(f.Hosts) > 0 {
+			scopes = append(scopes, "(has(request.host) && request.host in "+celList(f.Hosts)+")")
+		}
+		conds = append(conds, fmt.Sprintf("(!(%s) || (%s))", strings.Join(scopes, " || "), f.Expression))
+	}
+	return strings.Join(conds, " && ")
+}
+
+func celList(values []string) string {
+	quoted := make([]string, 0, len(values))
+	for _, v := range values {
+		quoted = append(quoted, strconv.Quote(v))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
 }
 
 // Telemetries organizes Telemetry configuration by namespace.
@@ -124,16 +253,111 @@ func getTelemetries(env *Environment) (*Telemetries, error) {
 			Namespace: config.Namespace,
 			Spec:      config.Spec.(*tpb.Telemetry),
 		}
+		// An invalid metric expiry is rejected by the validation webhook.
+		telemetry.MetricExpiry, _ = telemetryconfig.ParseMetricExpiry(config.Annotations)
+		telemetry.Promotion = parseMetadataPromotion(config.Annotations, config.Namespace, config.Name)
+		telemetry.RouteLogging = parseRouteAccessLogging(config.Annotations, config.Namespace, config.Name)
 		telemetries.NamespaceToTelemetries[config.Namespace] = append(telemetries.NamespaceToTelemetries[config.Namespace], telemetry)
 	}
 
 	return telemetries, nil
 }
 
+// parseMetadataPromotion returns the metadata promoted by the annotations of a Telemetry, or nil if none is.
+// The keys that are not valid label keys are ignored, as they would not be valid in the CEL expressions either.
+func parseMetadataPromotion(annotations map[string]string, namespace, name string) *MetadataPromotion {
+	parse := func(annotation string) []string {
+		var keys []string
+		for _, k := range strings.Split(annotations[annotation], ",") {
+			k = strings.TrimSpace(k)
+			if k == "" {
+				continue
+			}
+			if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+				log.Warnf("ignoring invalid key %q of the %s annotation of Telemetry %s/%s: %v", k, annotation, namespace, name, errs)
+				continue
+			}
+			keys = append(keys, k)
+		}
+		return keys
+	}
+	p := &MetadataPromotion{
+		Labels:      parse(TelemetryPromotedLabelsAnnotation),
+		Annotations: parse(TelemetryPromotedAnnotationsAnnotation),
+	}
+	if len(p.Labels) == 0 && len(p.Annotations) == 0 {
+		return nil
+	}
+	return p
+}
+
+// promotedDimension returns a metric dimension or trace tag name for a label or annotation key.
+func promotedDimension(side, key string) string {
+	return side + "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+}
+
+// dimensions returns the CEL expressions of the promoted dimensions of the metrics of a class. The proxy reporting
+// the metrics is the source of the outbound traffic and the destination of the inbound traffic.
+func (p *MetadataPromotion) dimensions(class networking.ListenerClass) map[string]string {
+	if p == nil {
+		return nil
+	}
+	localSide, peerSide, peer := "source", "destination", "upstream_peer"
+	if class == networking.ListenerClassSidecarInbound {
+		localSide, peerSide, peer = "destination", "source", "downstream_peer"
+	}
+	out := make(map[string]string, 2*(len(p.Labels)+len(p.Annotations)))
+	for _, k := range p.Labels {
+		out[promotedDimension(localSide, k)] = fmt.Sprintf("node.metadata['LABELS']['%s']", k)
+		out[promotedDimension(peerSide, k)] = fmt.Sprintf("%s.labels['%s'].value", peer, k)
+	}
+	for _, k := range p.Annotations {
+		out[promotedDimension(localSide, k)] = fmt.Sprintf("node.metadata['ANNOTATIONS']['%s']", k)
+		// The annotations are not part of the exchanged metadata.
+		out[promotedDimension(peerSide, k)] = "'unknown'"
+	}
+	return out
+}
+
+// tags returns the trace tags of the metadata of the proxy for a workload mode.
+func (p *MetadataPromotion) tags(proxy *Proxy, mode tpb.WorkloadMode) map[string]*tpb.Tracing_CustomTag {
+	if p == nil {
+		return nil
+	}
+	side := "source"
+	if mode == tpb.WorkloadMode_SERVER {
+		side = "destination"
+	}
+	literal := func(v string) *tpb.Tracing_CustomTag {
+		return &tpb.Tracing_CustomTag{Type: &tpb.Tracing_CustomTag_Literal{Literal: &tpb.Tracing_Literal{Value: v}}}
+	}
+	out := map[string]*tpb.Tracing_CustomTag{}
+	for _, k := range p.Labels {
+		if v, f := proxy.Labels[k]; f {
+			out[promotedDimension(side, k)] = literal(v)
+		}
+	}
+	if proxy.Metadata != nil {
+		for _, k := range p.Annotations {
+			if v, f := proxy.Metadata.Annotations[k]; f {
+				out[promotedDimension(side, k)] = literal(v)
+			}
+		}
+	}
+	return out
+}
+
 type metricsConfig struct {
 	ClientMetrics     []metricsOverride
 	ServerMetrics     []metricsOverride
 	ReportingInterval *durationpb.Duration
+	MetricExpiry      time.Duration
+	Promotion         *MetadataPromotion
 }
 
 type telemetryFilterConfig struct {
@@ -173,9 +397,12 @@ type tagOverride struct {
 // This can include the root namespace, namespace, and workload Telemetries combined
 type computedTelemetries struct {
 	telemetryKey
-	Metrics []*tpb.Metrics
-	Logging []*computedAccessLogging
-	Tracing []*tpb.Tracing
+	Metrics      []*tpb.Metrics
+	MetricExpiry time.Duration
+	Promotion    *MetadataPromotion
+	Logging      []*computedAccessLogging
+	RouteLogging *RouteAccessLogging
+	Tracing      []*tpb.Tracing
 }
 
 // computedAccessLogging contains the various AccessLogging configurations in scope for a given proxy,
@@ -203,6 +430,8 @@ type LoggingConfig struct {
 	AccessLog *accesslog.AccessLog
 	Provider  *meshconfig.MeshConfig_ExtensionProvider
 	Filter    *tpb.AccessLogging_Filter
+	// RouteFilter is the CEL expression of the route access logging, only applying to HTTP traffic.
+	RouteFilter string
 }
 
 func workloadMode(class networking.ListenerClass) tpb.WorkloadMode {
@@ -251,8 +480,9 @@ func (t *Telemetries) AccessLogging(push *PushContext, proxy *Proxy, class netwo
 			continue
 		}
 		cfg := LoggingConfig{
-			Provider: fp,
-			Filter:   f,
+			Provider:    fp,
+			Filter:      f,
+			RouteFilter: ct.RouteLogging.Expression(),
 		}
 
 		al := telemetryAccessLog(push, fp)
@@ -348,6 +578,8 @@ func (t *Telemetries) Tracing(proxy *Proxy) *TracingConfig {
 	if serverSpec.Provider == nil {
 		serverSpec.Disabled = true
 	}
+	clientSpec.CustomTags = withPromotedTags(clientSpec.CustomTags, ct.Promotion.tags(proxy, tpb.WorkloadMode_CLIENT))
+	serverSpec.CustomTags = withPromotedTags(serverSpec.CustomTags, ct.Promotion.tags(proxy, tpb.WorkloadMode_SERVER))
 
 	cfg := TracingConfig{
 		ClientSpec: clientSpec,
@@ -356,6 +588,22 @@ func (t *Telemetries) Tracing(proxy *Proxy) *TracingConfig {
 	return &cfg
 }
 
+// withPromotedTags returns the custom tags with the promoted tags that they do not set. The custom tags of the
+// Telemetry are shared, and never modified.
+func withPromotedTags(custom, promoted map[string]*tpb.Tracing_CustomTag) map[string]*tpb.Tracing_CustomTag {
+	if len(promoted) == 0 {
+		return custom
+	}
+	out := make(map[string]*tpb.Tracing_CustomTag, len(custom)+len(promoted))
+	for k, v := range promoted {
+		out[k] = v
+	}
+	for k, v := range custom {
+		out[k] = v
+	}
+	return out
+}
+
 // HTTPFilters computes the HttpFilter for a given proxy/class
 func (t *Telemetries) HTTPFilters(proxy *Proxy, class networking.ListenerClass) []*hcm.HttpFilter {
 	if res := t.telemetryFilters(proxy, class, networking.ListenerProtocolHTTP); res != nil {
@@ -383,12 +631,22 @@ func (t *Telemetries) applicableTelemetries(proxy *Proxy) computedTelemetries {
 	ms := []*tpb.Metrics{}
 	ls := []*computedAccessLogging{}
 	ts := []*tpb.Tracing{}
+	var expiry time.Duration
+	var promotion *MetadataPromotion
+	var routeLogging *RouteAccessLogging
 	key := telemetryKey{}
 	if t.RootNamespace != "" {
 		telemetry := t.namespaceWideTelemetryConfig(t.RootNamespace)
 		if telemetry != (Telemetry{}) {
 			key.Root = NamespacedName{Name: telemetry.Name, Namespace: telemetry.Namespace}
 			ms = append(ms, telemetry.Spec.GetMetrics()...)
+			expiry = overrideDuration(expiry, telemetry.MetricExpiry)
+			if telemetry.Promotion != nil {
+				promotion = telemetry.Promotion
+			}
+			if telemetry.RouteLogging != nil {
+				routeLogging = telemetry.RouteLogging
+			}
 			if len(telemetry.Spec.GetAccessLogging()) != 0 {
 				ls = append(ls, &computedAccessLogging{
 					telemetryKey: telemetryKey{
@@ -406,6 +664,13 @@ func (t *Telemetries) applicableTelemetries(proxy *Proxy) computedTelemetries {
 		if telemetry != (Telemetry{}) {
 			key.Namespace = NamespacedName{Name: telemetry.Name, Namespace: telemetry.Namespace}
 			ms = append(ms, telemetry.Spec.GetMetrics()...)
+			expiry = overrideDuration(expiry, telemetry.MetricExpiry)
+			if telemetry.Promotion != nil {
+				promotion = telemetry.Promotion
+			}
+			if telemetry.RouteLogging != nil {
+				routeLogging = telemetry.RouteLogging
+			}
 			if len(telemetry.Spec.GetAccessLogging()) != 0 {
 				ls = append(ls, &computedAccessLogging{
 					telemetryKey: telemetryKey{
@@ -427,6 +692,13 @@ func (t *Telemetries) applicableTelemetries(proxy *Proxy) computedTelemetries {
 		if selector.SubsetOf(proxy.Labels) {
 			key.Workload = NamespacedName{Name: telemetry.Name, Namespace: telemetry.Namespace}
 			ms = append(ms, spec.GetMetrics()...)
+			expiry = overrideDuration(expiry, telemetry.MetricExpiry)
+			if telemetry.Promotion != nil {
+				promotion = telemetry.Promotion
+			}
+			if telemetry.RouteLogging != nil {
+				routeLogging = telemetry.RouteLogging
+			}
 			if len(telemetry.Spec.GetAccessLogging()) != 0 {
 				ls = append(ls, &computedAccessLogging{
 					telemetryKey: telemetryKey{
@@ -443,11 +715,22 @@ func (t *Telemetries) applicableTelemetries(proxy *Proxy) computedTelemetries {
 	return computedTelemetries{
 		telemetryKey: key,
 		Metrics:      ms,
+		MetricExpiry: expiry,
+		Promotion:    promotion,
 		Logging:      ls,
+		RouteLogging: routeLogging,
 		Tracing:      ts,
 	}
 }
 
+// overrideDuration returns the override if it is set, the parent otherwise.
+func overrideDuration(parent, override time.Duration) time.Duration {
+	if override > 0 {
+		return override
+	}
+	return parent
+}
+
 // telemetryFilters computes the filters for the given proxy/class and protocol. This computes the
 // set of applicable Telemetries, merges them, then translates to the appropriate filters based on the
 // extension providers in the mesh config. Where possible, the result is cached.
@@ -494,11 +777,15 @@ func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerCl
 			continue
 		}
 		_, logging := tml[k]
-		_, metrics := tmm[k]
+		mc, metrics := tmm[k]
+		if metrics {
+			mc.MetricExpiry = c.MetricExpiry
+			mc.Promotion = c.Promotion
+		}
 
 		cfg := telemetryFilterConfig{
 			Provider:      p,
-			metricsConfig: tmm[k],
+			metricsConfig: mc,
 			AccessLogging: logging,
 			Metrics:       metrics,
 			LogsFilter:    tml[p.Name],
@@ -520,6 +807,34 @@ func (t *Telemetries) telemetryFilters(proxy *Proxy, class networking.ListenerCl
 	return res
 }
 
+// ConnectionPoolMetrics returns true unless the Telemetries of the proxy disable its connection pool metrics.
+// These are reported to Prometheus, so the Telemetries configuring the metrics of the proxy keep them only if one
+// of their Prometheus providers does not disable the ConnectionPoolMetric of the clients.
+func (t *Telemetries) ConnectionPoolMetrics(proxy *Proxy) bool {
+	if t == nil {
+		return true
+	}
+	c := t.applicableTelemetries(proxy)
+	if len(c.Metrics) == 0 {
+		return true
+	}
+	for provider, mc := range mergeMetrics(c.Metrics, t.meshConfig) {
+		if p := t.fetchProvider(provider); p.GetPrometheus() == nil {
+			continue
+		}
+		disabled := false
+		for _, o := range mc.ClientMetrics {
+			if o.Name == ConnectionPoolMetric {
+				disabled = o.Disabled
+			}
+		}
+		if !disabled {
+			return true
+		}
+	}
+	return false
+}
+
 // mergeLogs returns the set of providers for the given logging configuration.
 // The provider names are mapped to any applicable access logging filter that has been applied in provider configuration.
 func mergeLogs(logs []*computedAccessLogging, mesh *meshconfig.MeshConfig, mode tpb.WorkloadMode) map[string]*tpb.AccessLogging_Filter {
@@ -951,6 +1266,16 @@ func generateSDConfig(class networking.ListenerClass, telemetryConfig telemetryF
 	if class == networking.ListenerClassSidecarInbound {
 		metricNameMap = metricToSDServerMetrics
 	}
+	// The promoted dimensions apply to all the metrics, unless overridden.
+	if dimensions := telemetryConfig.Promotion.dimensions(class); len(dimensions) > 0 {
+		cfg.MetricsOverrides = map[string]*sd.MetricsOverride{}
+		for _, metricName := range metricNameMap {
+			if metricName == "" {
+				continue
+			}
+			cfg.MetricsOverrides[metricName] = &sd.MetricsOverride{TagOverrides: maps.Clone(dimensions)}
+		}
+	}
 	for _, override := range telemetryConfig.MetricsForClass(class) {
 		metricName, f := metricNameMap[override.Name]
 		if !f {
@@ -996,6 +1321,9 @@ func generateSDConfig(class networking.ListenerClass, telemetryConfig telemetryF
 	}
 
 	cfg.MetricExpiryDuration = durationpb.New(1 * time.Hour)
+	if telemetryConfig.MetricExpiry > 0 {
+		cfg.MetricExpiryDuration = durationpb.New(telemetryConfig.MetricExpiry)
+	}
 	// In WASM we are not actually processing protobuf at all, so we need to encode this to JSON
 	cfgJSON, _ := protomarshal.MarshalProtoNames(&cfg)
 
@@ -1026,6 +1354,10 @@ func generateStatsConfig(class networking.ListenerClass, metricsCfg telemetryFil
 		DisableHostHeaderFallback: disableHostHeaderFallback(class),
 		TcpReportingDuration:      metricsCfg.ReportingInterval,
 	}
+	// A metric config without name applies to all the metrics, before the overrides of the metrics.
+	if dimensions := metricsCfg.Promotion.dimensions(class); len(dimensions) > 0 {
+		cfg.Metrics = append(cfg.Metrics, &stats.MetricConfig{Dimensions: dimensions})
+	}
 	for _, override := range metricsCfg.MetricsForClass(class) {
 		metricName, f := metricToPrometheusMetric[override.Name]
 		if !f {
@@ -1046,9 +1378,40 @@ func generateStatsConfig(class networking.ListenerClass, metricsCfg telemetryFil
 		}
 		cfg.Metrics = append(cfg.Metrics, mc)
 	}
+	if metricsCfg.MetricExpiry > 0 {
+		if expiring := statsConfigWithExpiry(&cfg, metricsCfg.MetricExpiry); expiring != nil {
+			return expiring
+		}
+	}
 	return protoconv.MessageToAny(&cfg)
 }
 
+// statsRotationIntervalField is the field of the stats filter configuration setting the interval at which the
+// metrics that were not updated since the last rotation are expired.
+const statsRotationIntervalField = "rotationInterval"
+
+// statsConfigWithExpiry returns the configuration of the stats filter expiring the idle metrics after expiry. The
+// stats.PluginConfig of the API does not have the rotation interval yet, so the configuration is sent as a
+// TypedStruct, which the proxy converts to its own PluginConfig. It returns nil if the configuration cannot be
+// converted.
+func statsConfigWithExpiry(cfg *stats.PluginConfig, expiry time.Duration) *anypb.Any {
+	fields, err := protomarshal.ToJSONMap(cfg)
+	if err != nil {
+		telemetryLog.Warnf("failed to convert the stats filter configuration: %v", err)
+		return nil
+	}
+	fields[statsRotationIntervalField] = strconv.FormatFloat(expiry.Seconds(), 'f', -1, 64) + "s"
+	value, err := structpb.NewStruct(fields)
+	if err != nil {
+		telemetryLog.Warnf("failed to convert the stats filter configuration: %v", err)
+		return nil
+	}
+	return protoconv.MessageToAny(&udpa.TypedStruct{
+		TypeUrl: "type.googleapis.com/" + string(cfg.ProtoReflect().Descriptor().FullName()),
+		Value:   value,
+	})
+}
+
 func disableHostHeaderFallback(class networking.ListenerClass) bool {
 	return class == networking.ListenerClassSidecarInbound || class == networking.ListenerClassGateway
 }
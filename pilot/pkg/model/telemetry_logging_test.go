@@ -833,6 +833,36 @@ func TestAccessLoggingWithFilter(t *testing.T) {
 				},
 			},
 		},
+		{
+			"route-filters",
+			[]config.Config{
+				func() config.Config {
+					c := newTelemetry("istio-system", code500filter)
+					c.Annotations = map[string]string{TelemetryRouteAccessLogFiltersAnnotation: `[{"routes": ["metrics"], "expression": "false"}]`}
+					return c
+				}(),
+				func() config.Config {
+					c := newTelemetry("default", code400filter)
+					c.Annotations = map[string]string{TelemetryRouteAccessLogFiltersAnnotation: `[{"routes": ["healthz"], "expression": "response.code >= 500"}]`}
+					return c
+				}(),
+			},
+			sidecar,
+			[]string{"envoy"},
+			[]LoggingConfig{
+				{
+					AccessLog: &accesslog.AccessLog{
+						Name:       wellknown.FileAccessLog,
+						ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: protoconv.MessageToAny(defaultJSONLabelsOut)},
+					},
+					Provider: jsonTextProvider,
+					Filter: &tpb.AccessLogging_Filter{
+						Expression: "response.code >= 400",
+					},
+					RouteFilter: `(!((has(xds.route_name) && xds.route_name in ["healthz"])) || (response.code >= 500))`,
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -844,6 +874,29 @@ func TestAccessLoggingWithFilter(t *testing.T) {
 	}
 }
 
+func TestRouteAccessLogFilters(t *testing.T) {
+	rl, err := ParseRouteAccessLogFilters(`[
+  {"routes": ["healthz", "readyz"], "expression": "response.code >= 400"},
+  {"hosts": ["metrics.default"], "routes": ["stats"], "expression": "false"}
+]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `(!((has(xds.route_name) && xds.route_name in ["healthz", "readyz"])) || (response.code >= 400)) && ` +
+		`(!((has(xds.route_name) && xds.route_name in ["stats"]) || (has(request.host) && request.host in ["metrics.default"])) || (false))`
+	assert.Equal(t, rl.Expression(), want)
+
+	for _, invalid := range []string{
+		`{"routes": ["healthz"]}`,
+		`[{"expression": "false"}]`,
+		`[{"routes": ["healthz"], "expression": " "}]`,
+	} {
+		if _, err := ParseRouteAccessLogFilters(invalid); err == nil {
+			t.Errorf("expected %s to be rejected", invalid)
+		}
+	}
+}
+
 func TestAccessLoggingCache(t *testing.T) {
 	sidecar := &Proxy{ConfigNamespace: "default", Metadata: &NodeMetadata{Labels: map[string]string{"app": "test"}}}
 	otherNamespace := &Proxy{ConfigNamespace: "common", Metadata: &NodeMetadata{Labels: map[string]string{"app": "test"}}}
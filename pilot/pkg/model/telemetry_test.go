@@ -19,6 +19,7 @@ import (
 	"testing"
 	"time"
 
+	udpa "github.com/cncf/xds/go/udpa/type/v1"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	fileaccesslog "github.com/envoyproxy/go-control-plane/envoy/extensions/access_loggers/file/v3"
@@ -38,6 +39,7 @@ import (
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/collections"
+	telemetryconfig "istio.io/istio/pkg/config/telemetry"
 	"istio.io/istio/pkg/test/util/assert"
 	"istio.io/istio/pkg/util/protomarshal"
 )
@@ -468,6 +470,28 @@ func TestTracing(t *testing.T) {
 				},
 			},
 		},
+		{
+			"promoted labels",
+			[]config.Config{func() config.Config {
+				c := newTelemetry("istio-system", envoy)
+				c.Annotations = map[string]string{TelemetryPromotedLabelsAnnotation: "app,team"}
+				return c
+			}()},
+			sidecar,
+			nil,
+			&TracingConfig{
+				ClientSpec: TracingSpec{
+					Provider:                     &meshconfig.MeshConfig_ExtensionProvider{Name: "envoy"},
+					CustomTags:                   map[string]*tpb.Tracing_CustomTag{"source_app": literalTag("test")},
+					UseRequestIDForTraceSampling: true,
+				},
+				ServerSpec: TracingSpec{
+					Provider:                     &meshconfig.MeshConfig_ExtensionProvider{Name: "envoy"},
+					CustomTags:                   map[string]*tpb.Tracing_CustomTag{"destination_app": literalTag("test")},
+					UseRequestIDForTraceSampling: true,
+				},
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -483,6 +507,10 @@ func TestTracing(t *testing.T) {
 	}
 }
 
+func literalTag(v string) *tpb.Tracing_CustomTag {
+	return &tpb.Tracing_CustomTag{Type: &tpb.Tracing_CustomTag_Literal{Literal: &tpb.Tracing_Literal{Value: v}}}
+}
+
 func TestTelemetryFilters(t *testing.T) {
 	overrides := []*tpb.MetricsOverrides{{
 		Match: &tpb.MetricSelector{
@@ -744,6 +772,60 @@ func TestTelemetryFilters(t *testing.T) {
 					`"metric_expiry_duration":"3600s","metrics_overrides":{"client/request_count":{"tag_overrides":{"add":"bar"}}}}`,
 			},
 		},
+		{
+			"stackdriver metric expiry",
+			[]config.Config{
+				func() config.Config {
+					c := newTelemetry("istio-system", emptyStackdriver)
+					c.Annotations = map[string]string{telemetryconfig.MetricExpiryAnnotation: "10m"}
+					return c
+				}(),
+			},
+			sidecar,
+			networking.ListenerClassSidecarOutbound,
+			networking.ListenerProtocolHTTP,
+			nil,
+			map[string]string{
+				"istio.stackdriver": `{"disable_server_access_logging":true,"metric_expiry_duration":"600s"}`,
+			},
+		},
+		{
+			"prometheus promoted labels",
+			[]config.Config{
+				func() config.Config {
+					c := newTelemetry("istio-system", overridesPrometheus)
+					c.Annotations = map[string]string{TelemetryPromotedLabelsAnnotation: "team"}
+					return c
+				}(),
+			},
+			sidecar,
+			networking.ListenerClassSidecarOutbound,
+			networking.ListenerProtocolHTTP,
+			nil,
+			map[string]string{
+				"istio.stats": `{"metrics":[{"dimensions":{"destination_team":"upstream_peer.labels['team'].value",` +
+					`"source_team":"node.metadata['LABELS']['team']"}},` +
+					`{"dimensions":{"add":"bar"},"name":"requests_total","tags_to_remove":["remove"]}]}`,
+			},
+		},
+		{
+			"prometheus promoted annotations inbound",
+			[]config.Config{
+				func() config.Config {
+					c := newTelemetry("istio-system", emptyPrometheus)
+					c.Annotations = map[string]string{TelemetryPromotedAnnotationsAnnotation: "example.com/owner, bad key"}
+					return c
+				}(),
+			},
+			sidecar,
+			networking.ListenerClassSidecarInbound,
+			networking.ListenerProtocolHTTP,
+			nil,
+			map[string]string{
+				"istio.stats": `{"disable_host_header_fallback":true,"metrics":[{"dimensions":` +
+					`{"destination_example_com_owner":"node.metadata['ANNOTATIONS']['example.com/owner']","source_example_com_owner":"'unknown'"}}]}`,
+			},
+		},
 		{
 			"namespace empty merge",
 			[]config.Config{
@@ -905,3 +987,72 @@ func TestTelemetryFilters(t *testing.T) {
 		})
 	}
 }
+
+func TestConnectionPoolMetrics(t *testing.T) {
+	sidecar := &Proxy{
+		ConfigNamespace: "default",
+		Labels:          map[string]string{"app": "test"},
+		Metadata:        &NodeMetadata{Labels: map[string]string{"app": "test"}},
+	}
+	connectionPool := func(disabled bool) *tpb.MetricsOverrides {
+		return &tpb.MetricsOverrides{
+			Match: &tpb.MetricSelector{
+				MetricMatch: &tpb.MetricSelector_CustomMetric{CustomMetric: ConnectionPoolMetric},
+				Mode:        tpb.WorkloadMode_CLIENT,
+			},
+			Disabled: &wrappers.BoolValue{Value: disabled},
+		}
+	}
+	metrics := func(provider string, overrides ...*tpb.MetricsOverrides) *tpb.Telemetry {
+		return &tpb.Telemetry{Metrics: []*tpb.Metrics{{
+			Providers: []*tpb.ProviderRef{{Name: provider}},
+			Overrides: overrides,
+		}}}
+	}
+	cases := []struct {
+		name string
+		cfgs []config.Config
+		want bool
+	}{
+		{"no telemetry", nil, true},
+		{"prometheus", []config.Config{newTelemetry("istio-system", metrics("prometheus"))}, true},
+		{"stackdriver only", []config.Config{newTelemetry("istio-system", metrics("stackdriver"))}, false},
+		{
+			"disabled",
+			[]config.Config{newTelemetry("istio-system", metrics("prometheus", connectionPool(true)))},
+			false,
+		},
+		{
+			"disabled in root, enabled in namespace",
+			[]config.Config{
+				newTelemetry("istio-system", metrics("prometheus", connectionPool(true))),
+				newTelemetry("default", metrics("prometheus", connectionPool(false))),
+			},
+			true,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry, _ := createTestTelemetries(tt.cfgs, t)
+			if got := telemetry.ConnectionPoolMetrics(sidecar); got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatsConfigMetricExpiry(t *testing.T) {
+	cfg := telemetryFilterConfig{metricsConfig: metricsConfig{ReportingInterval: durationpb.New(time.Minute)}}
+	if got := generateStatsConfig(networking.ListenerClassSidecarOutbound, cfg); !strings.HasSuffix(got.GetTypeUrl(), "/stats.PluginConfig") {
+		t.Fatalf("expected the stats configuration without expiry to be a stats.PluginConfig, got %v", got.GetTypeUrl())
+	}
+
+	cfg.MetricExpiry = 10 * time.Minute
+	ts := &udpa.TypedStruct{}
+	if err := generateStatsConfig(networking.ListenerClassSidecarOutbound, cfg).UnmarshalTo(ts); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, ts.TypeUrl, "type.googleapis.com/stats.PluginConfig")
+	assert.Equal(t, ts.Value.Fields[statsRotationIntervalField].GetStringValue(), "600s")
+	assert.Equal(t, ts.Value.Fields["tcpReportingDuration"].GetStringValue(), "60s")
+}
@@ -0,0 +1,52 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/security"
+)
+
+// TLSProfileAnnotation selects the TLS profile of a workload or gateway, overriding PILOT_TLS_PROFILE.
+// TODO: move to API
+const TLSProfileAnnotation = "security.istio.io/tls-profile"
+
+// TLSProfile returns the TLS profile which applies to the proxy, or nil if the default TLS settings
+// should be used. A profile selected by the workload which is not supported by the proxy is ignored
+// in favor of the mesh-wide profile.
+func (node *Proxy) TLSProfile() *security.TLSProfile {
+	if node.Metadata != nil {
+		if name := node.Metadata.Annotations[TLSProfileAnnotation]; name != "" {
+			p, err := security.GetTLSProfile(name, features.FIPSProxy)
+			if err == nil {
+				return p
+			}
+			log.Debugf("ignoring TLS profile of proxy %s: %v", node.ID, err)
+		}
+	}
+	return meshTLSProfile()
+}
+
+func meshTLSProfile() *security.TLSProfile {
+	if features.TLSProfile == "" {
+		return nil
+	}
+	// The mesh-wide profile is validated at startup.
+	p, err := security.GetTLSProfile(features.TLSProfile, features.FIPSProxy)
+	if err != nil {
+		return nil
+	}
+	return p
+}
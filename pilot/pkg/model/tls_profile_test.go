@@ -0,0 +1,56 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/security"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestProxyTLSProfile(t *testing.T) {
+	cases := []struct {
+		name       string
+		mesh       string
+		fips       bool
+		annotation string
+		want       string
+	}{
+		{name: "default"},
+		{name: "mesh", mesh: security.TLSProfileIntermediate, want: security.TLSProfileIntermediate},
+		{name: "override", mesh: security.TLSProfileIntermediate, annotation: security.TLSProfileModern, want: security.TLSProfileModern},
+		{name: "unknown override", mesh: security.TLSProfileIntermediate, annotation: "legacy", want: security.TLSProfileIntermediate},
+		{name: "override without mesh", annotation: security.TLSProfileModern, want: security.TLSProfileModern},
+		{name: "unsupported by FIPS build", mesh: security.TLSProfileFIPS, fips: true, annotation: security.TLSProfileModern, want: security.TLSProfileFIPS},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			test.SetForTest(t, &features.TLSProfile, tt.mesh)
+			test.SetForTest(t, &features.FIPSProxy, tt.fips)
+			node := &Proxy{Metadata: &NodeMetadata{}}
+			if tt.annotation != "" {
+				node.Metadata.Annotations = map[string]string{TLSProfileAnnotation: tt.annotation}
+			}
+			got := ""
+			if p := node.TLSProfile(); p != nil {
+				got = p.Name
+			}
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -94,12 +94,14 @@ func (b *AccessLogBuilder) setTCPAccessLog(push *model.PushContext, proxy *model
 		return
 	}
 
-	if al := buildAccessLogFromTelemetry(cfgs, false); len(al) != 0 {
+	if al := buildAccessLogFromTelemetry(cfgs, false, false); len(al) != 0 {
 		tcp.AccessLog = append(tcp.AccessLog, al...)
 	}
 }
 
-func buildAccessLogFromTelemetry(cfgs []model.LoggingConfig, forListener bool) []*accesslog.AccessLog {
+// buildAccessLogFromTelemetry builds the access logs of the Telemetry logging configs. The route filters only apply to
+// the access logs of the HTTP connection managers, as the route attributes are not set for other traffic.
+func buildAccessLogFromTelemetry(cfgs []model.LoggingConfig, forListener, forHTTP bool) []*accesslog.AccessLog {
 	als := make([]*accesslog.AccessLog, 0, len(cfgs))
 	for _, c := range cfgs {
 		filters := make([]*accesslog.AccessLogFilter, 0, 2)
@@ -110,6 +112,9 @@ func buildAccessLogFromTelemetry(cfgs []model.LoggingConfig, forListener bool) [
 		if telFilter := buildAccessLogFilterFromTelemetry(c); telFilter != nil {
 			filters = append(filters, telFilter)
 		}
+		if forHTTP && c.RouteFilter != "" {
+			filters = append(filters, celAccessLogFilter(c.RouteFilter))
+		}
 
 		al := &accesslog.AccessLog{
 			Name:       c.AccessLog.Name,
@@ -127,8 +132,12 @@ func buildAccessLogFilterFromTelemetry(spec model.LoggingConfig) *accesslog.Acce
 		return nil
 	}
 
+	return celAccessLogFilter(spec.Filter.Expression)
+}
+
+func celAccessLogFilter(expression string) *accesslog.AccessLogFilter {
 	fl := &cel.ExpressionFilter{
-		Expression: spec.Filter.Expression,
+		Expression: expression,
 	}
 
 	return &accesslog.AccessLogFilter{
@@ -159,7 +168,7 @@ func (b *AccessLogBuilder) setHTTPAccessLog(push *model.PushContext, proxy *mode
 		return
 	}
 
-	if al := buildAccessLogFromTelemetry(cfgs, false); len(al) != 0 {
+	if al := buildAccessLogFromTelemetry(cfgs, false, true); len(al) != 0 {
 		connectionManager.AccessLog = append(connectionManager.AccessLog, al...)
 	}
 }
@@ -188,7 +197,7 @@ func (b *AccessLogBuilder) setListenerAccessLog(push *model.PushContext, proxy *
 		return
 	}
 
-	if al := buildAccessLogFromTelemetry(cfgs, true); len(al) != 0 {
+	if al := buildAccessLogFromTelemetry(cfgs, true, false); len(al) != 0 {
 		listener.AccessLog = append(listener.AccessLog, al...)
 	}
 }
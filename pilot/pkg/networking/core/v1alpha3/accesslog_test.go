@@ -439,6 +439,27 @@ func TestSetHttpAccessLog(t *testing.T) {
 	}
 }
 
+func TestBuildAccessLogFromTelemetryRouteFilter(t *testing.T) {
+	cfgs := []model.LoggingConfig{{
+		AccessLog: &accesslog.AccessLog{
+			Name:       wellknown.FileAccessLog,
+			ConfigType: &accesslog.AccessLog_TypedConfig{TypedConfig: protoconv.MessageToAny(defaultJSONLabelsOut)},
+		},
+		Filter:      &tpb.AccessLogging_Filter{Expression: "response.code >= 400"},
+		RouteFilter: `(!((has(xds.route_name) && xds.route_name in ["healthz"])) || (false))`,
+	}}
+
+	httpLogs := buildAccessLogFromTelemetry(cfgs, false, true)
+	assert.Equal(t, httpLogs[0].Filter, buildAccessLogFilter(
+		celAccessLogFilter("response.code >= 400"),
+		celAccessLogFilter(`(!((has(xds.route_name) && xds.route_name in ["healthz"])) || (false))`),
+	))
+
+	// The route attributes are not set for TCP traffic.
+	tcpLogs := buildAccessLogFromTelemetry(cfgs, false, false)
+	assert.Equal(t, tcpLogs[0].Filter, celAccessLogFilter("response.code >= 400"))
+}
+
 func TestSetListenerAccessLog(t *testing.T) {
 	b := newAccessLogBuilder()
 
@@ -37,16 +37,21 @@ import (
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/loadbalancer"
 	"istio.io/istio/pilot/pkg/networking/telemetry"
 	"istio.io/istio/pilot/pkg/networking/util"
 	authn_model "istio.io/istio/pilot/pkg/security/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pilot/pkg/util/trafficpolicy"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	istio_cluster "istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/healthcheck"
+	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
+	configsecurity "istio.io/istio/pkg/config/security"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/pkg/log"
@@ -99,22 +104,25 @@ type metadataCerts struct {
 // ClusterBuilder interface provides an abstraction for building Envoy Clusters.
 type ClusterBuilder struct {
 	// Proxy related information used to build clusters.
-	serviceInstances   []*model.ServiceInstance // Service instances of Proxy.
-	metadataCerts      *metadataCerts           // Client certificates specified in metadata.
-	clusterID          string                   // Cluster in which proxy is running.
-	proxyID            string                   // Identifier that uniquely identifies a proxy.
-	proxyVersion       string                   // Version of Proxy.
-	proxyType          model.NodeType           // Indicates whether the proxy is sidecar or gateway.
-	sidecarScope       *model.SidecarScope      // Computed sidecar for the proxy.
-	passThroughBindIPs []string                 // Passthrough IPs to be used while building clusters.
-	supportsIPv4       bool                     // Whether Proxy IPs has IPv4 address.
-	supportsIPv6       bool                     // Whether Proxy IPs has IPv6 address.
-	hbone              bool                     // Does the proxy support HBONE
-	locality           *core.Locality           // Locality information of proxy.
-	proxyLabels        map[string]string        // Proxy labels.
-	proxyView          model.ProxyView          // Proxy view of endpoints.
-	proxyIPAddresses   []string                 // IP addresses on which proxy is listening on.
-	configNamespace    string                   // Proxy config namespace.
+	serviceInstances   []*model.ServiceInstance   // Service instances of Proxy.
+	metadataCerts      *metadataCerts             // Client certificates specified in metadata.
+	clusterID          string                     // Cluster in which proxy is running.
+	proxyID            string                     // Identifier that uniquely identifies a proxy.
+	proxyVersion       string                     // Version of Proxy.
+	proxyType          model.NodeType             // Indicates whether the proxy is sidecar or gateway.
+	sidecarScope       *model.SidecarScope        // Computed sidecar for the proxy.
+	passThroughBindIPs []string                   // Passthrough IPs to be used while building clusters.
+	supportsIPv4       bool                       // Whether Proxy IPs has IPv4 address.
+	supportsIPv6       bool                       // Whether Proxy IPs has IPv6 address.
+	hbone              bool                       // Does the proxy support HBONE
+	locality           *core.Locality             // Locality information of proxy.
+	proxyLabels        map[string]string          // Proxy labels.
+	proxyView          model.ProxyView            // Proxy view of endpoints.
+	proxyIPAddresses   []string                   // IP addresses on which proxy is listening on.
+	configNamespace    string                     // Proxy config namespace.
+	tlsProfile         *configsecurity.TLSProfile // TLS profile applied to in-mesh mTLS.
+	loadReporting      bool                       // Whether the proxy reports the load of its upstream endpoints.
+	trackRemaining     bool                       // Whether the circuit breakers track the remaining resources.
 	// PushRequest to look for updates.
 	req                   *model.PushRequest
 	cache                 model.XdsCache
@@ -138,9 +146,15 @@ func NewClusterBuilder(proxy *model.Proxy, req *model.PushRequest, cache model.X
 		proxyView:          proxy.GetView(),
 		proxyIPAddresses:   proxy.IPAddresses,
 		configNamespace:    proxy.ConfigNamespace,
+		tlsProfile:         proxy.TLSProfile(),
+		loadReporting:      features.EnableLoadReporting && bool(proxy.Metadata.LoadReporting),
+		trackRemaining:     true,
 		req:                req,
 		cache:              cache,
 	}
+	if req != nil && req.Push != nil {
+		cb.trackRemaining = req.Push.Telemetry.ConnectionPoolMetrics(proxy)
+	}
 	if proxy.Metadata != nil {
 		if proxy.Metadata.TLSClientCertChain != "" {
 			cb.metadataCerts = &metadataCerts{
@@ -181,7 +195,7 @@ func (cb *ClusterBuilder) buildSubsetCluster(opts buildClusterOpts, destRule *co
 	var defaultSni string
 	if opts.clusterMode == DefaultClusterMode {
 		subsetClusterName = model.BuildSubsetKey(model.TrafficDirectionOutbound, subset.Name, service.Hostname, opts.port.Port)
-		defaultSni = model.BuildDNSSrvSubsetKey(model.TrafficDirectionOutbound, subset.Name, service.Hostname, opts.port.Port)
+		defaultSni = model.BuildDNSSrvSubsetSNI(model.TrafficDirectionOutbound, subset.Name, service.Hostname, opts.port.Port)
 	} else {
 		subsetClusterName = model.BuildDNSSrvSubsetKey(model.TrafficDirectionOutbound, subset.Name, service.Hostname, opts.port.Port)
 	}
@@ -220,7 +234,8 @@ func (cb *ClusterBuilder) buildSubsetCluster(opts buildClusterOpts, destRule *co
 	opts.istioMtlsSni = defaultSni
 
 	// If subset has a traffic policy, apply it so that it overrides the destination rule traffic policy.
-	opts.policy = MergeTrafficPolicy(opts.policy, subset.TrafficPolicy, opts.port)
+	opts.policy = MergeSubsetTrafficPolicy(opts.policy, subset.TrafficPolicy, opts.port,
+		destRule != nil && trafficpolicy.Merges(destRule.Annotations))
 
 	if destRule != nil {
 		destinationRule := CastDestinationRule(destRule)
@@ -233,6 +248,7 @@ func (cb *ClusterBuilder) buildSubsetCluster(opts buildClusterOpts, destRule *co
 
 	if cb.proxyType == model.Router || opts.direction == model.TrafficDirectionOutbound {
 		cb.applyMetadataExchange(opts.mutable.cluster)
+		cb.applyLoadReporting(opts.mutable.cluster)
 	}
 
 	// Add the DestinationRule+subsets metadata. Metadata here is generated on a per-cluster
@@ -245,8 +261,9 @@ func (cb *ClusterBuilder) buildSubsetCluster(opts buildClusterOpts, destRule *co
 // applyDestinationRule applies the destination rule if it exists for the Service. It returns the subset clusters if any created as it
 // applies the destination rule.
 func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode ClusterMode, service *model.Service,
-	port *model.Port, proxyView model.ProxyView, destRule *config.Config, serviceAccounts []string,
+	port *model.Port, proxyView model.ProxyView, consolidatedDestRule *model.ConsolidatedDestRule, serviceAccounts []string,
 ) []*cluster.Cluster {
+	destRule := consolidatedDestRule.GetRule()
 	destinationRule := CastDestinationRule(destRule)
 	// merge applicable port level traffic policy settings
 	trafficPolicy := MergeTrafficPolicy(nil, destinationRule.GetTrafficPolicy(), port)
@@ -262,7 +279,7 @@ func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode C
 
 	if clusterMode == DefaultClusterMode {
 		opts.serviceAccounts = serviceAccounts
-		opts.istioMtlsSni = model.BuildDNSSrvSubsetKey(model.TrafficDirectionOutbound, "", service.Hostname, port.Port)
+		opts.istioMtlsSni = model.BuildDNSSrvSubsetSNI(model.TrafficDirectionOutbound, "", service.Hostname, port.Port)
 		opts.meshExternal = service.MeshExternal
 		opts.serviceRegistry = service.Attributes.ServiceRegistry
 		opts.serviceMTLSMode = cb.req.Push.BestEffortInferServiceMTLSMode(destinationRule.GetTrafficPolicy(), service, port)
@@ -270,6 +287,7 @@ func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode C
 
 	if destRule != nil {
 		opts.isDrWithSelector = destinationRule.GetWorkloadSelector() != nil
+		opts.ejectionPolicy = consolidatedDestRule.GetLocalityEjectionPolicy()
 	}
 	// Apply traffic policy for the main default cluster.
 	cb.applyTrafficPolicy(opts)
@@ -280,6 +298,7 @@ func (cb *ClusterBuilder) applyDestinationRule(mc *MutableCluster, clusterMode C
 
 	if cb.proxyType == model.Router || opts.direction == model.TrafficDirectionOutbound {
 		cb.applyMetadataExchange(opts.mutable.cluster)
+		cb.applyLoadReporting(opts.mutable.cluster)
 	}
 
 	if destRule != nil {
@@ -301,8 +320,28 @@ func (cb *ClusterBuilder) applyMetadataExchange(c *cluster.Cluster) {
 	}
 }
 
+// applyLoadReporting makes the proxy report the load of the endpoints of the cluster to istiod, through the load
+// reporting service (LRS). Only EDS clusters are reported, as istiod weights the endpoints it sends by their load.
+func (cb *ClusterBuilder) applyLoadReporting(c *cluster.Cluster) {
+	if !cb.loadReporting || c.GetType() != cluster.Cluster_EDS {
+		return
+	}
+	c.LrsServer = &core.ConfigSource{
+		ConfigSourceSpecifier: &core.ConfigSource_Self{
+			Self: &core.SelfConfigSource{},
+		},
+		ResourceApiVersion: core.ApiVersion_V3,
+	}
+}
+
 // MergeTrafficPolicy returns the merged TrafficPolicy for a destination-level and subset-level policy on a given port.
 func MergeTrafficPolicy(original, subsetPolicy *networking.TrafficPolicy, port *model.Port) *networking.TrafficPolicy {
+	return MergeSubsetTrafficPolicy(original, subsetPolicy, port, false)
+}
+
+// MergeSubsetTrafficPolicy is like MergeTrafficPolicy, but if inherit is set the subset-level settings are merged
+// field by field into the destination-level settings rather than replacing them.
+func MergeSubsetTrafficPolicy(original, subsetPolicy *networking.TrafficPolicy, port *model.Port, inherit bool) *networking.TrafficPolicy {
 	if subsetPolicy == nil {
 		return original
 	}
@@ -321,17 +360,24 @@ func MergeTrafficPolicy(original, subsetPolicy *networking.TrafficPolicy, port *
 	}
 
 	// Override with subset values.
-	if subsetPolicy.ConnectionPool != nil {
-		mergedPolicy.ConnectionPool = subsetPolicy.ConnectionPool
-	}
-	if subsetPolicy.OutlierDetection != nil {
-		mergedPolicy.OutlierDetection = subsetPolicy.OutlierDetection
-	}
-	if subsetPolicy.LoadBalancer != nil {
-		mergedPolicy.LoadBalancer = subsetPolicy.LoadBalancer
-	}
-	if subsetPolicy.Tls != nil {
-		mergedPolicy.Tls = subsetPolicy.Tls
+	if inherit {
+		mergedPolicy.ConnectionPool = trafficpolicy.MergeSettings(mergedPolicy.ConnectionPool, subsetPolicy.ConnectionPool)
+		mergedPolicy.OutlierDetection = trafficpolicy.MergeSettings(mergedPolicy.OutlierDetection, subsetPolicy.OutlierDetection)
+		mergedPolicy.LoadBalancer = trafficpolicy.MergeSettings(mergedPolicy.LoadBalancer, subsetPolicy.LoadBalancer)
+		mergedPolicy.Tls = trafficpolicy.MergeSettings(mergedPolicy.Tls, subsetPolicy.Tls)
+	} else {
+		if subsetPolicy.ConnectionPool != nil {
+			mergedPolicy.ConnectionPool = subsetPolicy.ConnectionPool
+		}
+		if subsetPolicy.OutlierDetection != nil {
+			mergedPolicy.OutlierDetection = subsetPolicy.OutlierDetection
+		}
+		if subsetPolicy.LoadBalancer != nil {
+			mergedPolicy.LoadBalancer = subsetPolicy.LoadBalancer
+		}
+		if subsetPolicy.Tls != nil {
+			mergedPolicy.Tls = subsetPolicy.Tls
+		}
 	}
 
 	// Check if port level overrides exist, if yes override with them.
@@ -366,11 +412,7 @@ func (cb *ClusterBuilder) buildDefaultCluster(name string, discoveryType cluster
 	ec := NewMutableCluster(c)
 	switch discoveryType {
 	case cluster.Cluster_STRICT_DNS, cluster.Cluster_LOGICAL_DNS:
-		if cb.supportsIPv4 {
-			c.DnsLookupFamily = cluster.Cluster_V4_ONLY
-		} else {
-			c.DnsLookupFamily = cluster.Cluster_V6_ONLY
-		}
+		c.DnsLookupFamily = dnsLookupFamily(cb.supportsIPv4, cb.supportsIPv6)
 		dnsRate := cb.req.Push.Mesh.DnsRefreshRate
 		c.DnsRefreshRate = dnsRate
 		c.RespectDnsTtl = true
@@ -412,9 +454,42 @@ func (cb *ClusterBuilder) buildDefaultCluster(name string, discoveryType cluster
 	cb.setUpstreamProtocol(ec, port, direction)
 	addTelemetryMetadata(opts, service, direction, allInstances)
 	addNetworkingMetadata(opts, service, direction)
+	if direction == model.TrafficDirectionOutbound && service != nil {
+		applyHealthCheck(c, service.Attributes.HealthCheck, service.Hostname)
+	}
 	return ec
 }
 
+// applyHealthCheck configures the active health checking of the endpoints of the cluster. The endpoints failing the
+// checks are excluded from the load balancing, and the new endpoints only receive traffic once they pass a check.
+// The HTTP checks are sent to the hostname of the service unless they set a host, as Envoy defaults to the name of
+// the cluster, which is not a valid Host header.
+func applyHealthCheck(c *cluster.Cluster, hc *healthcheck.HealthCheck, hostname host.Name) {
+	if hc == nil {
+		return
+	}
+	check := &core.HealthCheck{
+		Timeout:            durationpb.New(hc.Timeout),
+		Interval:           durationpb.New(hc.Interval),
+		HealthyThreshold:   wrappers.UInt32(hc.HealthyThreshold),
+		UnhealthyThreshold: wrappers.UInt32(hc.UnhealthyThreshold),
+	}
+	if hc.HTTP != nil {
+		h := hc.HTTP.Host
+		if h == "" {
+			h = string(hostname)
+		}
+		check.HealthChecker = &core.HealthCheck_HttpHealthCheck_{HttpHealthCheck: &core.HealthCheck_HttpHealthCheck{
+			Path: hc.HTTP.Path,
+			Host: h,
+		}}
+	} else {
+		check.HealthChecker = &core.HealthCheck_TcpHealthCheck_{TcpHealthCheck: &core.HealthCheck_TcpHealthCheck{}}
+	}
+	c.HealthChecks = []*core.HealthCheck{check}
+	c.CommonLbConfig.IgnoreNewHostsUntilFirstHc = true
+}
+
 // buildInboundClusterForPortOrUDS constructs a single inbound cluster. The cluster will be bound to
 // `inbound|clusterPort||`, and send traffic to <bind>:<instance.Endpoint.EndpointPort>. A workload
 // will have a single inbound cluster per port. In general this works properly, with the exception of
@@ -465,6 +540,9 @@ func (cb *ClusterBuilder) buildInboundClusterForPortOrUDS(clusterPort int, bind
 		}
 	}
 	cb.applyTrafficPolicy(opts)
+	if limit := proxy.InboundConnectionPool().PerConnectionBufferLimitBytes; limit > 0 {
+		localCluster.cluster.PerConnectionBufferLimitBytes = wrappers.UInt32(limit)
+	}
 
 	if bind != LocalhostAddress && bind != LocalhostIPv6Address {
 		// iptables will redirect our own traffic to localhost back to us if we do not use the "magic" upstream bind
@@ -750,6 +828,7 @@ func (cb *ClusterBuilder) applyTrafficPolicy(opts buildClusterOpts) {
 		cb.applyH2Upgrade(opts, connectionPool)
 		applyOutlierDetection(opts.mutable.cluster, outlierDetection)
 		applyLoadBalancer(opts.mutable.cluster, loadBalancer, opts.port, cb.locality, cb.proxyLabels, opts.mesh)
+		loadbalancer.ApplyLocalityEjectionPolicy(opts.mutable.cluster, opts.ejectionPolicy)
 		if opts.clusterMode != SniDnatClusterMode {
 			autoMTLSEnabled := opts.mesh.GetEnableAutoMtls().Value
 			tls, mtlsCtxType := cb.buildAutoMtlsSettings(tls, opts.serviceAccounts, opts.istioMtlsSni,
@@ -859,6 +938,8 @@ func (cb *ClusterBuilder) applyConnectionPool(mesh *meshconfig.MeshConfig, mc *M
 	}
 
 	threshold := getDefaultCircuitBreakerThresholds()
+	// The agent reports the connection pool metrics of the clusters tracking their remaining resources.
+	threshold.TrackRemaining = cb.trackRemaining
 	var idleTimeout *durationpb.Duration
 	var maxRequestsPerConnection uint32
 	var maxConnectionDuration *duration.Duration
@@ -1026,6 +1107,9 @@ func (cb *ClusterBuilder) buildUpstreamClusterTLSContext(opts *buildClusterOpts,
 			CommonTlsContext: defaultUpstreamCommonTLSContext(),
 			Sni:              tls.Sni,
 		}
+		if cb.tlsProfile != nil {
+			tlsContext.CommonTlsContext.TlsParams = authn_model.TLSParametersForProfile(cb.tlsProfile)
+		}
 
 		tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs = append(tlsContext.CommonTlsContext.TlsCertificateSdsSecretConfigs,
 			authn_model.ConstructSdsSecretConfig(authn_model.SDSDefaultResourceName))
@@ -1328,6 +1412,13 @@ func maybeApplyEdsConfig(c *cluster.Cluster) {
 	}
 }
 
+func (cb *ClusterBuilder) tlsProfileName() string {
+	if cb.tlsProfile == nil {
+		return ""
+	}
+	return cb.tlsProfile.Name
+}
+
 func defaultUpstreamCommonTLSContext() *auth.CommonTlsContext {
 	return &auth.CommonTlsContext{
 		TlsParams: &auth.TlsParameters{
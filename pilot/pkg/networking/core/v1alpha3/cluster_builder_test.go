@@ -46,6 +46,7 @@ import (
 	"istio.io/istio/pilot/test/xdstest"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/healthcheck"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
 	"istio.io/istio/pkg/config/protocol"
@@ -520,7 +521,7 @@ func TestApplyDestinationRule(t *testing.T) {
 			tt.cluster.CommonLbConfig = &cluster.Cluster_CommonLbConfig{}
 
 			ec := NewMutableCluster(tt.cluster)
-			destRule := proxy.SidecarScope.DestinationRule(model.TrafficDirectionOutbound, proxy, tt.service.Hostname).GetRule()
+			destRule := proxy.SidecarScope.DestinationRule(model.TrafficDirectionOutbound, proxy, tt.service.Hostname)
 
 			subsetClusters := cb.applyDestinationRule(ec, tt.clusterMode, tt.service, tt.port, tt.proxyView, destRule, nil)
 			if len(subsetClusters) != len(tt.expectedSubsetClusters) {
@@ -854,6 +855,92 @@ func TestMergeTrafficPolicy(t *testing.T) {
 	}
 }
 
+func TestMergeSubsetTrafficPolicyInheritance(t *testing.T) {
+	original := &networking.TrafficPolicy{
+		LoadBalancer: &networking.LoadBalancerSettings{
+			LbPolicy: &networking.LoadBalancerSettings_Simple{
+				Simple: networking.LoadBalancerSettings_LEAST_REQUEST,
+			},
+			LocalityLbSetting: &networking.LocalityLoadBalancerSetting{
+				Enabled: &wrappers.BoolValue{Value: true},
+			},
+		},
+		ConnectionPool: &networking.ConnectionPoolSettings{
+			Http: &networking.ConnectionPoolSettings_HTTPSettings{
+				MaxRetries:              10,
+				Http1MaxPendingRequests: 100,
+			},
+		},
+		Tls: &networking.ClientTLSSettings{
+			Mode: networking.ClientTLSSettings_SIMPLE,
+			Sni:  "reviews.example.com",
+		},
+	}
+	subset := &networking.TrafficPolicy{
+		LoadBalancer: &networking.LoadBalancerSettings{
+			LbPolicy: &networking.LoadBalancerSettings_Simple{
+				Simple: networking.LoadBalancerSettings_ROUND_ROBIN,
+			},
+		},
+		ConnectionPool: &networking.ConnectionPoolSettings{
+			Http: &networking.ConnectionPoolSettings_HTTPSettings{
+				MaxRetries: 3,
+			},
+		},
+		Tls: &networking.ClientTLSSettings{
+			Mode: networking.ClientTLSSettings_DISABLE,
+		},
+	}
+
+	t.Run("replace", func(t *testing.T) {
+		assert.Equal(t, MergeSubsetTrafficPolicy(original, subset, nil, false), &networking.TrafficPolicy{
+			LoadBalancer:   subset.LoadBalancer,
+			ConnectionPool: subset.ConnectionPool,
+			Tls:            subset.Tls,
+		})
+	})
+	t.Run("merge", func(t *testing.T) {
+		assert.Equal(t, MergeSubsetTrafficPolicy(original, subset, nil, true), &networking.TrafficPolicy{
+			LoadBalancer: &networking.LoadBalancerSettings{
+				LbPolicy: &networking.LoadBalancerSettings_Simple{
+					Simple: networking.LoadBalancerSettings_ROUND_ROBIN,
+				},
+				LocalityLbSetting: &networking.LocalityLoadBalancerSetting{
+					Enabled: &wrappers.BoolValue{Value: true},
+				},
+			},
+			ConnectionPool: &networking.ConnectionPoolSettings{
+				Http: &networking.ConnectionPoolSettings_HTTPSettings{
+					MaxRetries:              3,
+					Http1MaxPendingRequests: 100,
+				},
+			},
+			// The TLS mode is always set by the subset, even to its zero value.
+			Tls: &networking.ClientTLSSettings{
+				Mode: networking.ClientTLSSettings_DISABLE,
+				Sni:  "reviews.example.com",
+			},
+		})
+		// The destination-level policy is left untouched.
+		assert.Equal(t, original.ConnectionPool.Http.MaxRetries, int32(10))
+	})
+	t.Run("merge port level settings", func(t *testing.T) {
+		withPort := &networking.TrafficPolicy{
+			Tls: subset.Tls,
+			PortLevelSettings: []*networking.TrafficPolicy_PortTrafficPolicy{{
+				Port: &networking.PortSelector{Number: 8080},
+				OutlierDetection: &networking.OutlierDetection{
+					Consecutive_5XxErrors: &wrappers.UInt32Value{Value: 3},
+				},
+			}},
+		}
+		// Per the docs, port level policies do not inherit.
+		assert.Equal(t, MergeSubsetTrafficPolicy(original, withPort, &model.Port{Port: 8080}, true), &networking.TrafficPolicy{
+			OutlierDetection: withPort.PortLevelSettings[0].OutlierDetection,
+		})
+	})
+}
+
 func TestApplyEdsConfig(t *testing.T) {
 	cases := []struct {
 		name      string
@@ -1092,6 +1179,43 @@ func TestBuildDefaultCluster(t *testing.T) {
 	}
 }
 
+func TestBuildDefaultClusterDNSLookupFamily(t *testing.T) {
+	servicePort := &model.Port{Name: "default", Port: 8080, Protocol: protocol.TCP}
+	service := &model.Service{
+		Ports:      model.PortList{servicePort},
+		Hostname:   "foo.example.com",
+		Resolution: model.DNSLB,
+		Attributes: model.ServiceAttributes{Name: "foo", Namespace: "default"},
+	}
+	endpoints := []*endpoint.LocalityLbEndpoints{{
+		LbEndpoints: []*endpoint.LbEndpoint{{
+			HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{
+				Address: util.BuildAddress("foo.example.com", 8080),
+			}},
+		}},
+	}}
+	cases := []struct {
+		name      string
+		ips       []string
+		dualStack bool
+		want      cluster.Cluster_DnsLookupFamily
+	}{
+		{name: "ipv4", ips: []string{"1.1.1.1"}, want: cluster.Cluster_V4_ONLY},
+		{name: "ipv6", ips: []string{"2001:db8::1"}, want: cluster.Cluster_V6_ONLY},
+		{name: "dual stack disabled", ips: []string{"1.1.1.1", "2001:db8::1"}, want: cluster.Cluster_V4_ONLY},
+		{name: "dual stack", ips: []string{"1.1.1.1", "2001:db8::1"}, dualStack: true, want: cluster.Cluster_ALL},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			test.SetForTest(t, &features.EnableDualStack, tt.dualStack)
+			cg := NewConfigGenTest(t, TestOptions{})
+			cb := NewClusterBuilder(cg.SetupProxy(&model.Proxy{IPAddresses: tt.ips}), &model.PushRequest{Push: cg.PushContext()}, nil)
+			c := cb.buildDefaultCluster("foo", cluster.Cluster_STRICT_DNS, endpoints, model.TrafficDirectionOutbound, servicePort, service, nil)
+			assert.Equal(t, c.cluster.DnsLookupFamily, tt.want)
+		})
+	}
+}
+
 func TestBuildLocalityLbEndpoints(t *testing.T) {
 	proxy := &model.Proxy{
 		Metadata: &model.NodeMetadata{
@@ -3335,7 +3459,7 @@ func TestApplyDestinationRuleOSCACert(t *testing.T) {
 			tt.cluster.CommonLbConfig = &cluster.Cluster_CommonLbConfig{}
 
 			ec := NewMutableCluster(tt.cluster)
-			destRule := proxy.SidecarScope.DestinationRule(model.TrafficDirectionOutbound, proxy, tt.service.Hostname).GetRule()
+			destRule := proxy.SidecarScope.DestinationRule(model.TrafficDirectionOutbound, proxy, tt.service.Hostname)
 
 			// ACT
 			_ = cb.applyDestinationRule(ec, tt.clusterMode, tt.service, tt.port, tt.proxyView, destRule, nil)
@@ -3421,6 +3545,38 @@ func TestApplyTCPKeepalive(t *testing.T) {
 	}
 }
 
+func TestApplyHealthCheck(t *testing.T) {
+	c := &cluster.Cluster{Name: "foo", CommonLbConfig: &cluster.Cluster_CommonLbConfig{}}
+	applyHealthCheck(c, nil, "backend.example.com")
+	if c.HealthChecks != nil || c.CommonLbConfig.IgnoreNewHostsUntilFirstHc {
+		t.Fatalf("unexpected health checks without a health check: %v", c)
+	}
+
+	applyHealthCheck(c, &healthcheck.HealthCheck{
+		HTTP:               &healthcheck.HTTP{Path: "/healthz"},
+		Interval:           5 * time.Second,
+		Timeout:            time.Second,
+		HealthyThreshold:   1,
+		UnhealthyThreshold: 2,
+	})
+	want := []*core.HealthCheck{{
+		Timeout:            durationpb.New(time.Second),
+		Interval:           durationpb.New(5 * time.Second),
+		HealthyThreshold:   wrappers.UInt32(1),
+		UnhealthyThreshold: wrappers.UInt32(2),
+		HealthChecker: &core.HealthCheck_HttpHealthCheck_{HttpHealthCheck: &core.HealthCheck_HttpHealthCheck{
+			Path: "/healthz",
+			Host: "backend.example.com",
+		}},
+	}}
+	if diff := cmp.Diff(want, c.HealthChecks, protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected health checks (-want +got):\n%s", diff)
+	}
+	if !c.CommonLbConfig.IgnoreNewHostsUntilFirstHc {
+		t.Fatalf("expected new hosts to be ignored until the first health check")
+	}
+}
+
 func TestApplyConnectionPool(t *testing.T) {
 	cases := []struct {
 		name                string
@@ -44,11 +44,15 @@ type clusterCache struct {
 	proxySidecar   bool           // identifies if this proxy is a Sidecar
 	proxyView      model.ProxyView
 	metadataCerts  *metadataCerts // metadata certificates of proxy
+	tlsProfile     string         // name of the TLS profile applied to the proxy
+	loadReporting  bool           // whether the proxy reports the load of its upstream endpoints
+	trackRemaining bool           // whether the circuit breakers track the remaining resources
 
 	// service attributes
 	http2          bool // http2 identifies if the cluster is for an http2 service
 	downstreamAuto bool
 	supportsIPv4   bool
+	supportsIPv6   bool
 
 	// Dependent configs
 	service         *model.Service
@@ -78,6 +82,8 @@ func (t *clusterCache) Key() string {
 	hash.Write(Separator)
 	hash.Write([]byte(strconv.FormatBool(t.supportsIPv4)))
 	hash.Write(Separator)
+	hash.Write([]byte(strconv.FormatBool(t.supportsIPv6)))
+	hash.Write(Separator)
 
 	if t.proxyView != nil {
 		hash.Write([]byte(t.proxyView.String()))
@@ -89,6 +95,15 @@ func (t *clusterCache) Key() string {
 	}
 	hash.Write(Separator)
 
+	hash.Write([]byte(t.tlsProfile))
+	hash.Write(Separator)
+
+	hash.Write([]byte(strconv.FormatBool(t.loadReporting)))
+	hash.Write(Separator)
+
+	hash.Write([]byte(strconv.FormatBool(t.trackRemaining)))
+	hash.Write(Separator)
+
 	if t.service != nil {
 		hash.Write([]byte(t.service.Hostname))
 		hash.Write(Slash)
@@ -176,10 +191,14 @@ func buildClusterKey(service *model.Service, port *model.Port, cb *ClusterBuilde
 		http2:           port.Protocol.IsHTTP2(),
 		downstreamAuto:  cb.sidecarProxy() && util.IsProtocolSniffingEnabledForOutboundPort(port),
 		supportsIPv4:    cb.supportsIPv4,
+		supportsIPv6:    cb.supportsIPv6,
 		service:         service,
 		destinationRule: proxy.SidecarScope.DestinationRule(model.TrafficDirectionOutbound, proxy, service.Hostname),
 		envoyFilterKeys: efKeys,
 		metadataCerts:   cb.metadataCerts,
+		tlsProfile:      cb.tlsProfileName(),
+		loadReporting:   cb.loadReporting,
+		trackRemaining:  cb.trackRemaining,
 		peerAuthVersion: cb.req.Push.AuthnPolicies.GetVersion(),
 		serviceAccounts: cb.req.Push.ServiceAccounts(service.Hostname, service.Attributes.Namespace, port.Port),
 	}
@@ -0,0 +1,166 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"fmt"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	dfpcluster "github.com/envoyproxy/go-control-plane/envoy/extensions/clusters/dynamic_forward_proxy/v3"
+	dfpcommon "github.com/envoyproxy/go-control-plane/envoy/extensions/common/dynamic_forward_proxy/v3"
+	dfphttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/dynamic_forward_proxy/v3"
+	lua "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/lua/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	dfpsni "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/sni_dynamic_forward_proxy/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+)
+
+const (
+	dynamicForwardProxyClusterType = "envoy.clusters.dynamic_forward_proxy"
+	dynamicForwardProxyHTTPFilter  = "envoy.filters.http.dynamic_forward_proxy"
+	dynamicForwardProxySNIFilter   = "envoy.filters.network.sni_dynamic_forward_proxy"
+	// dynamicForwardProxyPortFilter is the Lua filter adding the port of the service to the host of the requests
+	// without port, which the dynamic forward proxy would otherwise connect to on port 80. It only runs for the
+	// virtual hosts of the dynamic forward proxy services, which configure it.
+	dynamicForwardProxyPortFilter = "istio.dynamic_forward_proxy_port"
+	// dynamicForwardProxyDNSCacheName is the name of the DNS cache shared by the dynamic forward proxy clusters and
+	// filters of a proxy. Envoy requires the clusters and filters using a cache to configure it identically.
+	dynamicForwardProxyDNSCacheName = "dynamic_forward_proxy_cache_config"
+)
+
+// dnsLookupFamily returns the DNS lookup family of a proxy with the given IP families.
+func dnsLookupFamily(supportsIPv4, supportsIPv6 bool) cluster.Cluster_DnsLookupFamily {
+	if features.EnableDualStack && supportsIPv4 && supportsIPv6 {
+		// Dual stack proxies resolve both the A and AAAA records, so that neither family is dropped.
+		return cluster.Cluster_ALL
+	} else if supportsIPv4 {
+		return cluster.Cluster_V4_ONLY
+	}
+	return cluster.Cluster_V6_ONLY
+}
+
+func dynamicForwardProxyDNSCache(supportsIPv4, supportsIPv6 bool) *dfpcommon.DnsCacheConfig {
+	return &dfpcommon.DnsCacheConfig{
+		Name:            dynamicForwardProxyDNSCacheName,
+		DnsLookupFamily: dnsLookupFamily(supportsIPv4, supportsIPv6),
+	}
+}
+
+// applyDynamicForwardProxy turns an outbound cluster of a dynamic forward proxy service into a dynamic forward proxy
+// cluster, which connects to the host of each request as resolved by the shared DNS cache. It must be applied after
+// the destination rule, as the cluster is no longer of a built-in discovery type.
+func (cb *ClusterBuilder) applyDynamicForwardProxy(c *cluster.Cluster) {
+	c.ClusterDiscoveryType = &cluster.Cluster_ClusterType{ClusterType: &cluster.Cluster_CustomClusterType{
+		Name: dynamicForwardProxyClusterType,
+		TypedConfig: protoconv.MessageToAny(&dfpcluster.ClusterConfig{
+			ClusterImplementationSpecifier: &dfpcluster.ClusterConfig_DnsCacheConfig{
+				DnsCacheConfig: dynamicForwardProxyDNSCache(cb.supportsIPv4, cb.supportsIPv6),
+			},
+		}),
+	}}
+	c.LbPolicy = cluster.Cluster_CLUSTER_PROVIDED
+	c.LbConfig = nil
+	c.LoadAssignment = nil
+	c.EdsClusterConfig = nil
+}
+
+// hasDynamicForwardProxy returns true if any service visible to the proxy is a dynamic forward proxy service.
+func hasDynamicForwardProxy(node *model.Proxy) bool {
+	if node.SidecarScope == nil {
+		return false
+	}
+	for _, svc := range node.SidecarScope.Services() {
+		if svc.Attributes.DynamicForwardProxy {
+			return true
+		}
+	}
+	return false
+}
+
+// buildDynamicForwardProxyHTTPFilter builds the HTTP filter resolving the host of the requests routed to the dynamic
+// forward proxy clusters. The requests routed to other clusters are not affected.
+func buildDynamicForwardProxyHTTPFilter(node *model.Proxy) *hcm.HttpFilter {
+	return &hcm.HttpFilter{
+		Name: dynamicForwardProxyHTTPFilter,
+		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: protoconv.MessageToAny(&dfphttp.FilterConfig{
+			ImplementationSpecifier: &dfphttp.FilterConfig_DnsCacheConfig{
+				DnsCacheConfig: dynamicForwardProxyDNSCache(node.SupportsIPv4(), node.SupportsIPv6()),
+			},
+		})},
+	}
+}
+
+// buildDynamicForwardProxyPortFilter builds the filter adding the port of the service to the host of the requests,
+// which does nothing unless configured by the virtual host of the request.
+func buildDynamicForwardProxyPortFilter() *hcm.HttpFilter {
+	return &hcm.HttpFilter{
+		Name:       dynamicForwardProxyPortFilter,
+		ConfigType: &hcm.HttpFilter_TypedConfig{TypedConfig: protoconv.MessageToAny(&lua.Lua{})},
+	}
+}
+
+// dynamicForwardProxyPortConfig returns the per filter config of the virtual host of a port of a dynamic forward
+// proxy service, or nil if the requests without port are already sent to the port of the service.
+func dynamicForwardProxyPortConfig(service *model.Service, port int) map[string]*anypb.Any {
+	svcPort, f := service.Ports.GetByPort(port)
+	if !f || !svcPort.Protocol.IsHTTP() || !service.IsDynamicForwardProxyPort(svcPort) || port == 80 {
+		return nil
+	}
+	code := fmt.Sprintf(`function envoy_on_request(handle)
+  local authority = handle:headers():get(":authority")
+  if authority ~= nil and string.find(authority, ":%%d+$") == nil then
+    handle:headers():replace(":authority", authority .. ":%d")
+  end
+end
+`, port)
+	return map[string]*anypb.Any{
+		dynamicForwardProxyPortFilter: protoconv.MessageToAny(&lua.LuaPerRoute{
+			Override: &lua.LuaPerRoute_SourceCode{SourceCode: &core.DataSource{
+				Specifier: &core.DataSource_InlineString{InlineString: code},
+			}},
+		}),
+	}
+}
+
+// buildDynamicForwardProxySNIFilter builds the network filter resolving the SNI of the TLS connections forwarded to a
+// dynamic forward proxy cluster, which is connected to on the given upstream port.
+func buildDynamicForwardProxySNIFilter(node *model.Proxy, upstreamPort uint32) *listener.Filter {
+	return &listener.Filter{
+		Name: dynamicForwardProxySNIFilter,
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: protoconv.MessageToAny(&dfpsni.FilterConfig{
+			DnsCacheConfig: dynamicForwardProxyDNSCache(node.SupportsIPv4(), node.SupportsIPv6()),
+			PortSpecifier:  &dfpsni.FilterConfig_PortValue{PortValue: upstreamPort},
+		})},
+	}
+}
+
+// withDynamicForwardProxy prepends the SNI dynamic forward proxy filter to the filters of the TLS connections to the
+// given port of a dynamic forward proxy service.
+func withDynamicForwardProxy(node *model.Proxy, filters []*listener.Filter, service *model.Service, port int) []*listener.Filter {
+	if service == nil {
+		return filters
+	}
+	svcPort, f := service.Ports.GetByPort(port)
+	if !f || !svcPort.Protocol.IsTLS() || !service.IsDynamicForwardProxyPort(svcPort) {
+		return filters
+	}
+	return append([]*listener.Filter{buildDynamicForwardProxySNIFilter(node, uint32(port))}, filters...)
+}
@@ -0,0 +1,118 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const dynamicForwardProxyServiceEntry = `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: egress
+  namespace: default
+  annotations:
+    networking.istio.io/dynamic-forward-proxy: "true"
+spec:
+  hosts:
+  - "*.example.com"
+  location: MESH_EXTERNAL
+  resolution: NONE
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  - number: 443
+    name: tls
+    protocol: TLS
+  - number: 8080
+    name: http-alt
+    protocol: HTTP
+`
+
+func TestDynamicForwardProxy(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{ConfigString: dynamicForwardProxyServiceEntry})
+	proxy := cg.SetupProxy(nil)
+
+	clusters := xdstest.ExtractClusters(cg.Clusters(proxy))
+	for _, name := range []string{"outbound|80||*.example.com", "outbound|443||*.example.com"} {
+		c := clusters[name]
+		if c == nil {
+			t.Fatalf("cluster %s not found", name)
+		}
+		assert.Equal(t, c.GetClusterType().GetName(), dynamicForwardProxyClusterType)
+		assert.Equal(t, c.GetLbPolicy(), cluster.Cluster_CLUSTER_PROVIDED)
+		assert.Equal(t, c.GetLoadAssignment() == nil, true)
+	}
+
+	listeners := cg.Listeners(proxy)
+	var httpFilters []string
+	for _, fc := range xdstest.ExtractListener("0.0.0.0_80", listeners).GetFilterChains() {
+		if _, hf := xdstest.ExtractFilterNames(t, fc); len(hf) > 0 {
+			httpFilters = hf
+		}
+	}
+	assert.Equal(t, httpFilters[len(httpFilters)-3], dynamicForwardProxyPortFilter)
+	assert.Equal(t, httpFilters[len(httpFilters)-2], dynamicForwardProxyHTTPFilter)
+
+	// The requests without port are sent to the port of the service, rather than the default port 80.
+	routes := xdstest.ExtractRouteConfigurations(cg.Routes(proxy))
+	for port, want := range map[string]bool{"80": false, "8080": true} {
+		var vh *route.VirtualHost
+		for _, v := range routes[port].GetVirtualHosts() {
+			if v.GetName() == "*.example.com:"+port {
+				vh = v
+			}
+		}
+		if vh == nil {
+			t.Fatalf("virtual host of port %s not found", port)
+		}
+		_, f := vh.GetTypedPerFilterConfig()[dynamicForwardProxyPortFilter]
+		assert.Equal(t, f, want)
+	}
+
+	var tlsChain *listener.FilterChain
+	for _, fc := range xdstest.ExtractListener("0.0.0.0_443", listeners).GetFilterChains() {
+		if len(fc.GetFilterChainMatch().GetServerNames()) > 0 {
+			tlsChain = fc
+		}
+	}
+	assert.Equal(t, tlsChain.GetFilterChainMatch().GetServerNames(), []string{"*.example.com"})
+	nwFilters, _ := xdstest.ExtractFilterNames(t, tlsChain)
+	assert.Equal(t, nwFilters[0], dynamicForwardProxySNIFilter)
+}
+
+func TestNoDynamicForwardProxy(t *testing.T) {
+	cg := NewConfigGenTest(t, TestOptions{})
+	proxy := cg.SetupProxy(nil)
+	for _, l := range cg.Listeners(proxy) {
+		for _, fc := range l.GetFilterChains() {
+			_, httpFilters := xdstest.ExtractFilterNames(t, fc)
+			for _, f := range httpFilters {
+				if f == dynamicForwardProxyHTTPFilter {
+					t.Fatalf("unexpected dynamic forward proxy filter in listener %s", l.GetName())
+				}
+			}
+		}
+	}
+}
@@ -58,7 +58,11 @@ func ApplyClusterMerge(pctx networking.EnvoyFilter_PatchContext, efw *model.Envo
 			}
 			applied = true
 			if !ret {
-				merge.Merge(c, cp.Value)
+				if cp.MergeCircuitBreakerThresholds {
+					merge.Merge(c, mergeCircuitBreakerThresholds(c, cp.Value))
+				} else {
+					merge.Merge(c, cp.Value)
+				}
 			}
 		}
 		IncrementEnvoyFilterMetric(cp.Key(), Cluster, applied)
@@ -123,6 +127,35 @@ func mergeTransportSocketCluster(c *cluster.Cluster, cp *model.EnvoyFilterConfig
 	return true, nil
 }
 
+// mergeCircuitBreakerThresholds merges the circuit breaker thresholds of the patch into the thresholds of the cluster
+// with the same priority, and returns the rest of the patch to be merged. A plain merge would append the thresholds,
+// and Envoy only uses the first thresholds of each priority, so the patch would have no effect. This only applies to
+// the patches compiled from listener patches: raw EnvoyFilter patches keep the semantics of a proto merge.
+func mergeCircuitBreakerThresholds(c *cluster.Cluster, value proto.Message) proto.Message {
+	patch, ok := value.(*cluster.Cluster)
+	if !ok || len(patch.GetCircuitBreakers().GetThresholds()) == 0 || len(c.GetCircuitBreakers().GetThresholds()) == 0 {
+		return value
+	}
+	// The patch is shared by all the clusters it applies to, do not modify it.
+	patch = proto.Clone(patch).(*cluster.Cluster)
+	var remaining []*cluster.CircuitBreakers_Thresholds
+	for _, pt := range patch.CircuitBreakers.Thresholds {
+		merged := false
+		for _, t := range c.CircuitBreakers.Thresholds {
+			if t.Priority == pt.Priority {
+				merge.Merge(t, pt)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			remaining = append(remaining, pt)
+		}
+	}
+	patch.CircuitBreakers.Thresholds = remaining
+	return patch
+}
+
 // ShouldKeepCluster checks if there is a REMOVE patch on the cluster, returns false if there is one so that it is removed.
 func ShouldKeepCluster(pctx networking.EnvoyFilter_PatchContext, efw *model.EnvoyFilterWrapper, c *cluster.Cluster, hosts []host.Name) bool {
 	if efw == nil {
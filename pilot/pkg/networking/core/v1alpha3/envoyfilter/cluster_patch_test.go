@@ -23,6 +23,8 @@ import (
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
@@ -626,3 +628,52 @@ func TestClusterPatching(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeCircuitBreakerThresholds(t *testing.T) {
+	newCluster := func() *cluster.Cluster {
+		return &cluster.Cluster{CircuitBreakers: &cluster.CircuitBreakers{Thresholds: []*cluster.CircuitBreakers_Thresholds{{
+			MaxConnections: &wrappers.UInt32Value{Value: 1024},
+			MaxRetries:     &wrappers.UInt32Value{Value: 3},
+		}}}}
+	}
+	patch := &cluster.Cluster{
+		ConnectTimeout: &durationpb.Duration{Seconds: 5},
+		CircuitBreakers: &cluster.CircuitBreakers{Thresholds: []*cluster.CircuitBreakers_Thresholds{
+			{MaxConnections: &wrappers.UInt32Value{Value: 10}},
+			{Priority: core.RoutingPriority_HIGH, MaxConnections: &wrappers.UInt32Value{Value: 20}},
+		}},
+	}
+	efw := func(typed bool) *model.EnvoyFilterWrapper {
+		return &model.EnvoyFilterWrapper{Patches: map[networking.EnvoyFilter_ApplyTo][]*model.EnvoyFilterConfigPatchWrapper{
+			networking.EnvoyFilter_CLUSTER: {{
+				ApplyTo:                       networking.EnvoyFilter_CLUSTER,
+				Operation:                     networking.EnvoyFilter_Patch_MERGE,
+				Match:                         &networking.EnvoyFilter_EnvoyConfigObjectMatch{Context: networking.EnvoyFilter_ANY},
+				Value:                         patch,
+				MergeCircuitBreakerThresholds: typed,
+			}},
+		}}
+	}
+
+	// The thresholds of the patches compiled from listener patches are merged by priority.
+	got := ApplyClusterMerge(networking.EnvoyFilter_SIDECAR_OUTBOUND, efw(true), newCluster(), nil)
+	want := &cluster.Cluster{
+		ConnectTimeout: &durationpb.Duration{Seconds: 5},
+		CircuitBreakers: &cluster.CircuitBreakers{Thresholds: []*cluster.CircuitBreakers_Thresholds{
+			{MaxConnections: &wrappers.UInt32Value{Value: 10}, MaxRetries: &wrappers.UInt32Value{Value: 3}},
+			{Priority: core.RoutingPriority_HIGH, MaxConnections: &wrappers.UInt32Value{Value: 20}},
+		}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Fatalf("unexpected cluster (-want +got):\n%s", diff)
+	}
+	if len(patch.CircuitBreakers.Thresholds) != 2 {
+		t.Fatalf("patch should not be modified")
+	}
+
+	// Raw patches keep the semantics of a proto merge, appending the thresholds.
+	got = ApplyClusterMerge(networking.EnvoyFilter_SIDECAR_OUTBOUND, efw(false), newCluster(), nil)
+	if n := len(got.CircuitBreakers.Thresholds); n != 3 {
+		t.Fatalf("expected the thresholds of raw patches to be appended, got %d thresholds", n)
+	}
+}
@@ -16,6 +16,7 @@ package extension
 
 import (
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbac "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	"google.golang.org/protobuf/proto"
@@ -24,6 +25,7 @@ import (
 	extensions "istio.io/api/extensions/v1alpha1"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/config/wasmplugin"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/util/sets"
 	_ "istio.io/istio/pkg/wasm" // include for registering wasm logging scope
@@ -34,8 +36,8 @@ var defaultConfigSource = &core.ConfigSource{
 		Ads: &core.AggregatedConfigSource{},
 	},
 	ResourceApiVersion: core.ApiVersion_V3,
-	// we block proxy init until WasmPlugins are loaded because they might be
-	// critical for security (e.g. authn/authz)
+	// we block proxy init until WasmPlugins and authorization filters are loaded
+	// because they might be critical for security (e.g. authn/authz)
 	InitialFetchTimeout: &durationpb.Duration{Seconds: 0},
 }
 
@@ -52,16 +54,28 @@ func PopAppend(list []*hcm.HttpFilter,
 	return list
 }
 
+// allowAllConfig is the configuration of the fail open plugins until their configuration is loaded.
+var allowAllConfig = protoconv.MessageToAny(&rbac.RBAC{})
+
 func toEnvoyHTTPFilter(wasmPlugin *model.WasmPluginWrapper) *hcm.HttpFilter {
+	filter := DiscoveryHTTPFilter(wasmPlugin.ResourceName, xds.WasmHTTPFilterType, xds.RBACHTTPFilterType)
+	if wasmPlugin.FailurePolicy == wasmplugin.FailOpen {
+		// The listeners of fail open plugins do not wait for their configuration.
+		filter.GetConfigDiscovery().DefaultConfig = allowAllConfig
+		filter.GetConfigDiscovery().ApplyDefaultConfigWithoutWarming = true
+	}
+	return filter
+}
+
+// DiscoveryHTTPFilter returns an HTTP filter whose configuration, of one of the given types, is fetched with ECDS.
+// The name of the filter is the name of the extension config.
+func DiscoveryHTTPFilter(name string, typeURLs ...string) *hcm.HttpFilter {
 	return &hcm.HttpFilter{
-		Name: wasmPlugin.ResourceName,
+		Name: name,
 		ConfigType: &hcm.HttpFilter_ConfigDiscovery{
 			ConfigDiscovery: &core.ExtensionConfigSource{
 				ConfigSource: defaultConfigSource,
-				TypeUrls: []string{
-					xds.WasmHTTPFilterType,
-					xds.RBACHTTPFilterType,
-				},
+				TypeUrls:     typeURLs,
 			},
 		},
 	}
@@ -15,12 +15,21 @@
 package v1alpha3
 
 import (
+	"fmt"
+	"path/filepath"
+	"runtime/debug"
 	"testing"
 
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 
+	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/fuzz"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/env"
 )
 
 func FuzzBuildGatewayListeners(f *testing.F) {
@@ -63,3 +72,81 @@ func FuzzBuildSidecarOutboundListeners(f *testing.F) {
 		NewListenerBuilder(proxy, cg.env.PushContext).buildSidecarOutboundListeners(cg.SetupProxy(proxy), cg.env.PushContext)
 	})
 }
+
+// FuzzTranslateConfigCorpus mutates the real-world configs of tests/fuzz/corpus/config, and checks that their
+// translation for a sidecar and a gateway never panics and is accepted by Envoy. See xdstest.ValidateEnvoyConfig for
+// the validation with an Envoy binary. The failing configs are minimized, and written to FUZZ_ARTIFACT_DIR when set.
+func FuzzTranslateConfigCorpus(f *testing.F) {
+	fuzz.ConfigCorpus(f, filepath.Join(env.IstioSrc, "tests/fuzz/corpus/config"))
+	f.Fuzz(func(t *testing.T, seed []byte, data []byte) {
+		defer fuzz.Finalize()
+		fg := fuzz.New(t, data)
+		in := fuzz.MutateConfig(fg, seed)
+		configs := validCorpusConfigs(in)
+		if len(configs) == 0 {
+			fg.T().Skip("no valid config")
+		}
+		if err := translateCorpusConfigs(configs); err != nil {
+			minimized := fuzz.MinimizeConfig(in, func(in []byte) bool {
+				return translateCorpusConfigs(validCorpusConfigs(in)) != nil
+			})
+			artifact, werr := fuzz.WriteConfigArtifact("translate", minimized)
+			if werr != nil {
+				t.Logf("failed to write the artifact: %v", werr)
+			}
+			t.Fatalf("translation failed: %v\nartifact: %s\nminimized config:\n%s", err, artifact, minimized)
+		}
+	})
+}
+
+// validCorpusConfigs returns the configs of the YAML stream accepted by the validation webhook. The translation
+// only has to handle valid configs.
+func validCorpusConfigs(in []byte) []config.Config {
+	parsed, _, err := crd.ParseInputs(string(in))
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var configs []config.Config
+	for _, c := range parsed {
+		if c.Namespace == "" {
+			c.Namespace = "default"
+		}
+		key := fmt.Sprintf("%v/%s/%s", c.GroupVersionKind, c.Namespace, c.Name)
+		s, ok := collections.PilotGatewayAPI.FindByGroupVersionKind(c.GroupVersionKind)
+		if !ok || seen[key] {
+			continue
+		}
+		if _, err := s.Resource().ValidateConfig(c); err != nil {
+			continue
+		}
+		seen[key] = true
+		configs = append(configs, c)
+	}
+	return configs
+}
+
+// translateCorpusConfigs generates the configuration of a sidecar and a gateway selected by the corpus, returning
+// an error if the generation panics or the configuration is not accepted by Envoy.
+func translateCorpusConfigs(configs []config.Config) error {
+	return test.Wrap(func(t test.Failer) {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("panic: %v\n%s", r, debug.Stack())
+			}
+		}()
+		cg := NewConfigGenTest(t, TestOptions{Configs: configs})
+		sidecarLabels := map[string]string{"app": "reviews", "version": "v1"}
+		gatewayLabels := map[string]string{"istio": "ingressgateway"}
+		for _, p := range []*model.Proxy{
+			{Type: model.SidecarProxy, Labels: sidecarLabels, Metadata: &model.NodeMetadata{Labels: sidecarLabels}},
+			{Type: model.Router, Labels: gatewayLabels, Metadata: &model.NodeMetadata{Labels: gatewayLabels}},
+		} {
+			proxy := cg.SetupProxy(p)
+			listeners := cg.Listeners(proxy)
+			if err := xdstest.ValidateEnvoyConfig(listeners, cg.Clusters(proxy), cg.RoutesFromListeners(proxy, listeners)); err != nil {
+				t.Fatalf("%v: %v", proxy.Type, err)
+			}
+		}
+	})
+}
@@ -27,6 +27,7 @@ import (
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
 	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	customheader "github.com/envoyproxy/go-control-plane/envoy/extensions/http/original_ip_detection/custom_header/v3"
 	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/hashicorp/go-multierror"
 
@@ -39,6 +40,7 @@ import (
 	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/tunnelingconfig"
 	"istio.io/istio/pilot/pkg/networking/telemetry"
 	"istio.io/istio/pilot/pkg/networking/util"
+	"istio.io/istio/pilot/pkg/util/protoconv"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/gateway"
@@ -65,6 +67,12 @@ func (configgen *ConfigGeneratorImpl) buildGatewayListeners(builder *ListenerBui
 	log.Debugf("buildGatewayListeners: gateways after merging: %v", mergedGateway)
 
 	actualWildcard, _ := getActualWildcardAndLocalHost(builder.node)
+	// Dual stack gateways listen on both the IPv4 and IPv6 wildcards.
+	var extraWildcards []string
+	if features.EnableDualStack && builder.node.GetIPMode() == model.Dual {
+		wildcards, _ := getWildcardsAndLocalHost(model.Dual)
+		extraWildcards = wildcards[1:]
+	}
 	errs := istiomultierror.New()
 	// Mutable objects keyed by listener name so that we can build listeners at the end.
 	mutableopts := make(map[string]mutableListenerOpts)
@@ -79,8 +87,10 @@ func (configgen *ConfigGeneratorImpl) buildGatewayListeners(builder *ListenerBui
 			continue
 		}
 		bind := actualWildcard
+		extraBind := extraWildcards
 		if len(port.Bind) > 0 {
 			bind = port.Bind
+			extraBind = nil
 		}
 
 		// NOTE: There is no gating here to check for the value of the QUIC feature flag. However,
@@ -106,6 +116,7 @@ func (configgen *ConfigGeneratorImpl) buildGatewayListeners(builder *ListenerBui
 				push:       builder.push,
 				proxy:      builder.node,
 				bind:       bind,
+				extraBind:  extraBind,
 				port:       &model.Port{Port: int(port.Number)},
 				bindToPort: true,
 				class:      istionetworking.ListenerClassGateway,
@@ -620,11 +631,24 @@ func buildGatewayConnectionManager(proxyConfig *meshconfig.ProxyConfig, node *mo
 	if features.HTTP10 || enableHTTP10(node.Metadata.HTTP10) {
 		httpProtoOpts.AcceptHttp_10 = true
 	}
-	xffNumTrustedHops := uint32(0)
 	forwardClientCertDetails := util.MeshConfigToEnvoyForwardClientCertDetails(meshconfig.Topology_SANITIZE_SET)
 
+	clientAddress := node.ClientAddressDetection(proxyConfig)
+	xffNumTrustedHops := clientAddress.NumTrustedProxies
+	var originalIPDetection []*core.TypedExtensionConfig
+	if clientAddress.ClientIPHeader != "" {
+		// Envoy rejects configuring both xff_num_trusted_hops and original IP detection extensions.
+		xffNumTrustedHops = 0
+		originalIPDetection = []*core.TypedExtensionConfig{{
+			Name: "envoy.http.original_ip_detection.custom_header",
+			TypedConfig: protoconv.MessageToAny(&customheader.CustomHeaderConfig{
+				HeaderName:                          clientAddress.ClientIPHeader,
+				AllowExtensionToSetAddressAsTrusted: true,
+			}),
+		}}
+	}
+
 	if proxyConfig != nil && proxyConfig.GatewayTopology != nil {
-		xffNumTrustedHops = proxyConfig.GatewayTopology.NumTrustedProxies
 		if proxyConfig.GatewayTopology.ForwardClientCertDetails != meshconfig.Topology_UNDEFINED {
 			forwardClientCertDetails = util.MeshConfigToEnvoyForwardClientCertDetails(proxyConfig.GatewayTopology.ForwardClientCertDetails)
 		}
@@ -636,7 +660,8 @@ func buildGatewayConnectionManager(proxyConfig *meshconfig.ProxyConfig, node *mo
 	}
 
 	httpConnManager := &hcm.HttpConnectionManager{
-		XffNumTrustedHops: xffNumTrustedHops,
+		XffNumTrustedHops:             xffNumTrustedHops,
+		OriginalIpDetectionExtensions: originalIPDetection,
 		// Forward client cert if connection is mTLS
 		ForwardClientCertDetails: forwardClientCertDetails,
 		SetCurrentClientCertDetails: &hcm.HttpConnectionManager_SetCurrentClientCertDetails{
@@ -912,7 +937,7 @@ func builtAutoPassthroughFilterChains(push *model.PushContext, proxy *model.Prox
 			// be possible for anyone to access a cluster without mTLS. Note that we cannot actually
 			// check for mTLS here, as we are doing passthrough TLS.
 			filterChains = append(filterChains, &filterChainOpts{
-				sniHosts:   []string{clusterName},
+				sniHosts:   []string{model.BuildDNSSrvSubsetSNI(model.TrafficDirectionOutbound, "", service.Hostname, port.Port)},
 				match:      &listener.FilterChainMatch{ApplicationProtocols: allIstioMtlsALPNs},
 				tlsContext: nil, // NO TLS context because this is passthrough
 				networkFilters: buildOutboundNetworkFiltersWithSingleDestination(
@@ -928,7 +953,7 @@ func builtAutoPassthroughFilterChains(push *model.PushContext, proxy *model.Prox
 					subsetStatPrefix = telemetry.BuildStatPrefix(push.Mesh.OutboundClusterStatName, string(service.Hostname), subset.Name, port, &service.Attributes)
 				}
 				filterChains = append(filterChains, &filterChainOpts{
-					sniHosts:   []string{subsetClusterName},
+					sniHosts:   []string{model.BuildDNSSrvSubsetSNI(model.TrafficDirectionOutbound, subset.Name, service.Hostname, port.Port)},
 					match:      &listener.FilterChainMatch{ApplicationProtocols: allIstioMtlsALPNs},
 					tlsContext: nil, // NO TLS context because this is passthrough
 					networkFilters: buildOutboundNetworkFiltersWithSingleDestination(
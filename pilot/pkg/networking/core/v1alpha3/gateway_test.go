@@ -2853,6 +2853,49 @@ func TestBuildGatewayListenersFilters(t *testing.T) {
 	}
 }
 
+func TestGatewayListenersDualStack(t *testing.T) {
+	test.SetForTest(t, &features.EnableDualStack, true)
+	cg := NewConfigGenTest(t, TestOptions{
+		Configs: []config.Config{{
+			Meta: config.Meta{Name: "gateway", Namespace: "testns", GroupVersionKind: gvk.Gateway},
+			Spec: &networking.Gateway{
+				Servers: []*networking.Server{
+					{
+						Port:  &networking.Port{Name: "http", Number: 80, Protocol: "HTTP"},
+						Hosts: []string{"*.example.com"},
+					},
+					{
+						Port:  &networking.Port{Name: "http-bind", Number: 8080, Protocol: "HTTP"},
+						Hosts: []string{"*.example.com"},
+						Bind:  "10.0.0.1",
+					},
+				},
+			},
+		}},
+	})
+	p := proxyGateway
+	p.IPAddresses = []string{"1.1.1.1", "1111:2222::1"}
+	proxy := cg.SetupProxy(&p)
+	proxy.Metadata = &proxyGatewayMetadata
+
+	builder := cg.ConfigGen.buildGatewayListeners(NewListenerBuilder(proxy, cg.PushContext()))
+	xdstest.ValidateListeners(t, builder.gatewayListeners)
+	wildcard := xdstest.ExtractListener("0.0.0.0_80", builder.gatewayListeners)
+	if wildcard == nil {
+		t.Fatalf("didn't find the wildcard listener")
+	}
+	if len(wildcard.AdditionalAddresses) != 1 || wildcard.AdditionalAddresses[0].GetAddress().GetSocketAddress().GetAddress() != "::" {
+		t.Fatalf("expected the wildcard listener to also listen on ::, got %v", wildcard.AdditionalAddresses)
+	}
+	bound := xdstest.ExtractListener("10.0.0.1_8080", builder.gatewayListeners)
+	if bound == nil {
+		t.Fatalf("didn't find the bound listener")
+	}
+	if len(bound.AdditionalAddresses) != 0 {
+		t.Fatalf("expected the bound listener to only listen on its bind address, got %v", bound.AdditionalAddresses)
+	}
+}
+
 func TestGatewayHCMInternalAddressConfig(t *testing.T) {
 	cg := NewConfigGenTest(t, TestOptions{})
 	proxy := &pilot_model.Proxy{
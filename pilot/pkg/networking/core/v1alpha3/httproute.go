@@ -110,6 +110,13 @@ func (configgen *ConfigGeneratorImpl) BuildHTTPRoutes(
 func buildSidecarInboundHTTPRouteConfig(lb *ListenerBuilder, cc inboundChainConfig) *route.RouteConfiguration {
 	traceOperation := telemetry.TraceOperation(string(cc.telemetryMetadata.InstanceHostname), int(cc.port.Port))
 	defaultRoute := istio_route.BuildDefaultHTTPInboundRoute(cc.clusterName, traceOperation)
+	// Requests to the mirror itself are not mirrored, in case the workload is its own mirror. Passthrough chains
+	// have no service port, the port of the mirror must then be set.
+	if m := lb.inboundMirror; m != nil && m.Host != cc.telemetryMetadata.InstanceHostname && (m.Port != 0 || cc.port.Port != 0) {
+		defaultRoute.GetRoute().RequestMirrorPolicies = []*route.RouteAction_RequestMirrorPolicy{
+			istio_route.BuildInboundMirrorPolicy(m, int(cc.port.Port)),
+		}
+	}
 
 	inboundVHost := &route.VirtualHost{
 		Name:    inboundVirtualHostPrefix + strconv.Itoa(int(cc.port.Port)), // Format: "inbound|http|%d"
@@ -430,12 +437,16 @@ func BuildSidecarOutboundVirtualHosts(node *model.Proxy, push *model.PushContext
 			push.AddMetric(model.DuplicatedDomains, name, node.ID, msg)
 		}
 		if len(domains) > 0 {
-			return &route.VirtualHost{
+			vh := &route.VirtualHost{
 				Name:                       name,
 				Domains:                    domains,
 				Routes:                     vhwrapper.Routes,
 				IncludeRequestAttemptCount: true,
 			}
+			if svc != nil {
+				vh.TypedPerFilterConfig = dynamicForwardProxyPortConfig(svc, vhwrapper.Port)
+			}
+			return vh
 		}
 
 		return nil
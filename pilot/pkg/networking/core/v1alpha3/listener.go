@@ -28,6 +28,7 @@ import (
 	envoyquicv3 "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/quic/v3"
 	auth "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/types/known/durationpb"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 
 	networking "istio.io/api/networking/v1alpha3"
@@ -43,6 +44,7 @@ import (
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/security"
 	"istio.io/istio/pkg/proto"
 	secconst "istio.io/istio/pkg/security"
 	netutil "istio.io/istio/pkg/util/net"
@@ -150,6 +152,9 @@ func BuildListenerTLSContext(serverTLSSettings *networking.ServerTLSSettings,
 			authnmodel.ApplyCredentialSDSToServerCommonTLSContext(ctx.CommonTlsContext, serverTLSSettings, credentialSocketExist)
 		case serverTLSSettings.Mode == networking.ServerTLSSettings_ISTIO_MUTUAL:
 			authnmodel.ApplyToCommonTLSContext(ctx.CommonTlsContext, proxy, serverTLSSettings.SubjectAltNames, []string{}, ctx.RequireClientCertificate.Value)
+			if proxy.Type == model.Router {
+				authnmodel.ApplyDualWorkloadCertificates(ctx.CommonTlsContext, proxy)
+			}
 		default:
 			certProxy := &model.Proxy{}
 			certProxy.IstioVersion = proxy.IstioVersion
@@ -166,6 +171,9 @@ func BuildListenerTLSContext(serverTLSSettings *networking.ServerTLSSettings,
 		switch {
 		case serverTLSSettings.Mode == networking.ServerTLSSettings_ISTIO_MUTUAL:
 			authnmodel.ApplyToCommonTLSContext(ctx.CommonTlsContext, proxy, serverTLSSettings.SubjectAltNames, []string{}, ctx.RequireClientCertificate.Value)
+			if proxy.Type == model.Router {
+				authnmodel.ApplyDualWorkloadCertificates(ctx.CommonTlsContext, proxy)
+			}
 		// If credential name is specified at gateway config, create  SDS config for gateway to fetch key/cert from Istiod.
 		case serverTLSSettings.CredentialName != "":
 			authnmodel.ApplyCredentialSDSToServerCommonTLSContext(ctx.CommonTlsContext, serverTLSSettings, credentialSocketExist)
@@ -183,8 +191,20 @@ func BuildListenerTLSContext(serverTLSSettings *networking.ServerTLSSettings,
 		}
 	}
 
-	// Set TLS parameters if they are non-default
-	if len(serverTLSSettings.CipherSuites) > 0 ||
+	// Set TLS parameters if they are non-default. Settings of the server narrow the TLS profile, but are clamped to
+	// its bounds so that a server cannot weaken it.
+	if p := proxy.TLSProfile(); p != nil {
+		ctx.CommonTlsContext.TlsParams = authnmodel.TLSParametersForProfile(p)
+		if len(serverTLSSettings.CipherSuites) > 0 {
+			ctx.CommonTlsContext.TlsParams.CipherSuites = p.FilterCipherSuites(serverTLSSettings.CipherSuites)
+		}
+		if serverTLSSettings.MinProtocolVersion != networking.ServerTLSSettings_TLS_AUTO {
+			ctx.CommonTlsContext.TlsParams.TlsMinimumProtocolVersion = clampTLSProtocol(p, serverTLSSettings.MinProtocolVersion)
+		}
+		if serverTLSSettings.MaxProtocolVersion != networking.ServerTLSSettings_TLS_AUTO {
+			ctx.CommonTlsContext.TlsParams.TlsMaximumProtocolVersion = clampTLSProtocol(p, serverTLSSettings.MaxProtocolVersion)
+		}
+	} else if len(serverTLSSettings.CipherSuites) > 0 ||
 		serverTLSSettings.MinProtocolVersion != networking.ServerTLSSettings_TLS_AUTO ||
 		serverTLSSettings.MaxProtocolVersion != networking.ServerTLSSettings_TLS_AUTO {
 		ctx.CommonTlsContext.TlsParams = &auth.TlsParameters{
@@ -197,6 +217,11 @@ func BuildListenerTLSContext(serverTLSSettings *networking.ServerTLSSettings,
 	return ctx
 }
 
+// clampTLSProtocol converts a TLS version of a server to the Envoy TLS protocol, bounded by the TLS profile.
+func clampTLSProtocol(p *security.TLSProfile, in networking.ServerTLSSettings_TLSProtocol) auth.TlsParameters_TlsProtocol {
+	return auth.TlsParameters_TlsProtocol(p.ClampVersion(security.TLSVersion(convertTLSProtocol(in))))
+}
+
 // buildSidecarListeners produces a list of listeners for sidecar proxies
 func (configgen *ConfigGeneratorImpl) buildSidecarListeners(builder *ListenerBuilder) *ListenerBuilder {
 	if builder.push.Mesh.ProxyListenPort > 0 {
@@ -988,6 +1013,10 @@ func (lb *ListenerBuilder) buildSidecarOutboundListenerForPortOrUDS(listenerOpts
 		// Listener filters enable inspecting TLS or HTTP. If either filter chain depends on it, our final listener
 		// must also have the inspector.
 		currentListenerEntry.listener.ListenerFilters = mergeListenerFilters(currentListenerEntry.listener.ListenerFilters, mutable.Listener.ListenerFilters)
+		// Services sharing the port may declare different protocol detection timeouts, the longest one is kept
+		// so that the detection succeeds for all of them.
+		currentListenerEntry.listener.ListenerFiltersTimeout = mergeListenerFiltersTimeout(currentListenerEntry.listener.ListenerFiltersTimeout,
+			mutable.Listener.ListenerFiltersTimeout)
 	}
 	switch conflictType {
 	case NoConflict:
@@ -1244,6 +1273,9 @@ func buildListener(opts buildListenerOpts, trafficDirection core.TrafficDirectio
 
 		if opts.proxy.Type != model.Router {
 			res.ListenerFiltersTimeout = opts.push.Mesh.ProtocolDetectionTimeout
+			if d := opts.service.ProtocolDetection(opts.port.Port); d.Timeout > 0 {
+				res.ListenerFiltersTimeout = durationpb.New(d.Timeout)
+			}
 			if res.ListenerFiltersTimeout != nil {
 				res.ContinueOnListenerFiltersTimeout = true
 			}
@@ -1613,6 +1645,22 @@ func isMatchAllFilterChain(fc *listener.FilterChain) bool {
 	return filterChainMatchEmpty(fc.FilterChainMatch)
 }
 
+// mergeListenerFiltersTimeout returns the longest of the listener filters timeouts, 0 disabling the timeout.
+func mergeListenerFiltersTimeout(a, b *durationpb.Duration) *durationpb.Duration {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.AsDuration() == 0 || b.AsDuration() == 0:
+		return durationpb.New(0)
+	case b.AsDuration() > a.AsDuration():
+		return b
+	default:
+		return a
+	}
+}
+
 func removeListenerFilterTimeout(listeners []*listener.Listener) {
 	for _, l := range listeners {
 		// Remove listener filter timeout for
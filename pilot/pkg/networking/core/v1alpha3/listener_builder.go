@@ -69,6 +69,13 @@ type ListenerBuilder struct {
 	authzBuilder *authz.Builder
 	// authzCustomBuilder provides access to CUSTOM authz configuration for the given proxy.
 	authzCustomBuilder *authz.Builder
+
+	// inboundConnectionPool is the server side connection management of the inbound listeners of the proxy.
+	inboundConnectionPool model.InboundConnectionPool
+	// inboundMirror is the mirroring of the inbound HTTP requests of the proxy, nil if they are not mirrored.
+	inboundMirror *model.InboundMirrorPolicy
+	// dynamicForwardProxy is true if the proxy can forward requests to dynamic forward proxy services.
+	dynamicForwardProxy bool
 }
 
 // enabledInspector captures if for a given listener, listener filter inspectors are added
@@ -85,6 +92,9 @@ func NewListenerBuilder(node *model.Proxy, push *model.PushContext) *ListenerBui
 	builder.authnBuilder = authn.NewBuilder(push, node)
 	builder.authzBuilder = authz.NewBuilder(authz.Local, push, node)
 	builder.authzCustomBuilder = authz.NewBuilder(authz.Custom, push, node)
+	builder.inboundConnectionPool = node.InboundConnectionPool()
+	builder.inboundMirror = node.InboundMirrorPolicy()
+	builder.dynamicForwardProxy = hasDynamicForwardProxy(node)
 	return builder
 }
 
@@ -403,6 +413,10 @@ func (lb *ListenerBuilder) buildHTTPConnectionManager(httpOpts *httpListenerOpts
 	// TypedPerFilterConfig in route needs these filters.
 	filters = append(filters, xdsfilters.Fault, xdsfilters.Cors)
 	filters = append(filters, lb.push.Telemetry.HTTPFilters(lb.node, httpOpts.class)...)
+	// The dynamic forward proxy filter resolves the host of the requests just before they are routed.
+	if lb.dynamicForwardProxy && httpOpts.class != istionetworking.ListenerClassSidecarInbound {
+		filters = append(filters, buildDynamicForwardProxyPortFilter(), buildDynamicForwardProxyHTTPFilter(lb.node))
+	}
 	filters = append(filters, xdsfilters.BuildRouterFilter(routerFilterCtx))
 
 	connectionManager.HttpFilters = filters
@@ -40,6 +40,7 @@ import (
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/security/authn"
 	istiomatcher "istio.io/istio/pilot/pkg/security/authz/matcher"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
@@ -68,6 +69,9 @@ type inboundChainConfig struct {
 	// extraBind is string slice and each element is similar with bind address and support multiple addresses for 'virtual' listener
 	extraBind []string
 
+	// passthroughPolicy is the inbound passthrough policy of the proxy, for passthrough chains.
+	passthroughPolicy model.InboundPassthroughPolicy
+
 	// tlsSettings defines the *custom* TLS settings for the chain. mTLS settings are orthogonal; this
 	// only configures TLS overrides.
 	tlsSettings *networking.ServerTLSSettings
@@ -86,6 +90,13 @@ type inboundChainConfig struct {
 
 	// telemetryMetadata defines additional information about the chain for telemetry purposes.
 	telemetryMetadata telemetry.FilterChainMetadata
+
+	// connectionProtocol is the application protocol of the connections matched by a TCP chain, against which
+	// the connection.protocol conditions of the authorization policies are evaluated. Unknown if empty.
+	connectionProtocol string
+
+	// protocolDetection is the protocol detection of the service port of the chain, declared on the service.
+	protocolDetection model.PortProtocolDetection
 }
 
 // StatPrefix returns the stat prefix for the config
@@ -111,6 +122,36 @@ func (cc inboundChainConfig) Name(protocol istionetworking.ListenerProtocol) str
 	return getListenerName(cc.bind, int(cc.port.TargetPort), istionetworking.TransportProtocolTCP)
 }
 
+// listenerProtocolForOpts returns the protocol of the chain built for the options. Connections whose protocol
+// is not detected are handled as HTTP if the service port falls back to HTTP, unless they are TLS connections
+// not terminated by the sidecar.
+func (cc inboundChainConfig) listenerProtocolForOpts(opt FilterChainMatchOptions) istionetworking.ListenerProtocol {
+	if opt.Protocol == istionetworking.ListenerProtocolTCP && cc.port.Protocol.IsUnsupported() &&
+		cc.protocolDetection.Fallback == protocol.HTTP && (opt.TLS || opt.TransportProtocol != xdsfilters.TLSTransportProtocol) {
+		return istionetworking.ListenerProtocolHTTP
+	}
+	return opt.Protocol
+}
+
+// connectionProtocolForOpts returns the application protocol of the connections matched by the TCP chain. TLS is
+// detected by the TLS inspector when the sidecar does not terminate it, otherwise the protocol of the port is used.
+func (cc inboundChainConfig) connectionProtocolForOpts(opt FilterChainMatchOptions) string {
+	if opt.TransportProtocol == xdsfilters.TLSTransportProtocol && !opt.TLS {
+		return authzmodel.ConnectionProtocolTLS
+	}
+	switch cc.port.Protocol {
+	case protocol.TLS, protocol.HTTPS:
+		return authzmodel.ConnectionProtocolTLS
+	case protocol.MySQL:
+		return authzmodel.ConnectionProtocolMySQL
+	case protocol.Mongo:
+		return authzmodel.ConnectionProtocolMongo
+	case protocol.Redis:
+		return authzmodel.ConnectionProtocolRedis
+	}
+	return authzmodel.ConnectionProtocolTCP
+}
+
 var (
 	IPv4PassthroughCIDR = []*core.CidrRange{util.ConvertAddressToCidr("0.0.0.0/0")}
 	IPv6PassthroughCIDR = []*core.CidrRange{util.ConvertAddressToCidr("::/0")}
@@ -323,6 +364,9 @@ func (lb *ListenerBuilder) inboundCustomListener(cc inboundChainConfig, chains [
 		addresses = append(addresses, cc.extraBind...)
 	}
 	ll := lb.buildInboundListener(cc.Name(istionetworking.ListenerProtocolTCP), addresses, cc.port.TargetPort, true, chains)
+	if ll != nil && cc.protocolDetection.Timeout > 0 {
+		ll.ListenerFiltersTimeout = durationpb.New(cc.protocolDetection.Timeout)
+	}
 	return ll
 }
 
@@ -353,6 +397,8 @@ func (lb *ListenerBuilder) buildInboundListener(name string, addresses []string,
 		l.Transparent = proto.BoolTrue
 	}
 
+	applyInboundConnectionBufferLimit(l, lb.inboundConnectionPool)
+
 	accessLogBuilder.setListenerAccessLog(lb.push, lb.node, l, istionetworking.ListenerClassSidecarInbound)
 	l.FilterChains = chains
 	l.ListenerFilters = populateListenerFilters(lb.node, l, bindToPort)
@@ -365,19 +411,30 @@ func (lb *ListenerBuilder) buildInboundListener(name string, addresses []string,
 func (lb *ListenerBuilder) inboundChainForOpts(cc inboundChainConfig, mtls authn.MTLSSettings, opts []FilterChainMatchOptions) []*listener.FilterChain {
 	chains := make([]*listener.FilterChain, 0, len(opts))
 	for _, opt := range opts {
-		switch opt.Protocol {
+		// Connections over the limit are closed before any other filter processes them.
+		filters := buildInboundConnectionLimitFilters(lb.inboundConnectionPool, cc.StatPrefix())
+		if cc.passthrough {
+			filters = append(filters, buildInboundPassthroughPolicyFilters(cc.passthroughPolicy)...)
+		}
+		// Connections accepted without mTLS would be rejected by a dry-run STRICT policy.
+		if !opt.TLS {
+			filters = append(filters, lb.authnBuilder.BuildDryRunStrict(mtls)...)
+		}
+		switch cc.listenerProtocolForOpts(opt) {
 		// Switch on the protocol. Note: we do not need to handle Auto protocol as it will already be split into a TCP and HTTP option.
 		case istionetworking.ListenerProtocolHTTP:
 			chains = append(chains, &listener.FilterChain{
 				FilterChainMatch: cc.ToFilterChainMatch(opt),
-				Filters:          lb.buildInboundNetworkFiltersForHTTP(cc),
+				Filters:          append(filters, lb.buildInboundNetworkFiltersForHTTP(cc)...),
 				TransportSocket:  buildDownstreamTLSTransportSocket(opt.ToTransportSocket(mtls)),
 				Name:             cc.Name(opt.Protocol),
 			})
 		case istionetworking.ListenerProtocolTCP:
+			tcc := cc
+			tcc.connectionProtocol = cc.connectionProtocolForOpts(opt)
 			chains = append(chains, &listener.FilterChain{
 				FilterChainMatch: cc.ToFilterChainMatch(opt),
-				Filters:          lb.buildInboundNetworkFilters(cc),
+				Filters:          append(filters, lb.buildInboundNetworkFilters(tcc)...),
 				TransportSocket:  buildDownstreamTLSTransportSocket(opt.ToTransportSocket(mtls)),
 				Name:             cc.Name(opt.Protocol),
 			})
@@ -425,6 +482,7 @@ func (lb *ListenerBuilder) getFilterChainsByServicePort(chainsByPort map[uint32]
 			clusterName:       model.BuildInboundSubsetKey(int(port.TargetPort)),
 			bind:              actualWildcards[0],
 			bindToPort:        getBindToPort(networking.CaptureMode_DEFAULT, lb.node),
+			protocolDetection: i.Service.ProtocolDetection(i.ServicePort.Port),
 		}
 		// add extra binding addresses
 		if len(actualWildcards) > 1 {
@@ -758,6 +816,11 @@ func buildInboundPassthroughChains(lb *ListenerBuilder) []*listener.FilterChain
 	filterChains := make([]*listener.FilterChain, 0, 1+5*len(ipVersions))
 	filterChains = append(filterChains, buildInboundBlackhole(lb))
 
+	policy := lb.node.InboundPassthroughPolicy()
+	passthroughProtocol := istionetworking.ListenerProtocolAuto
+	if policy.TCPOnly {
+		passthroughProtocol = istionetworking.ListenerProtocolTCP
+	}
 	for _, clusterName := range ipVersions {
 		mtlsOptions := lb.authnBuilder.ForPassthrough()
 		for _, mtls := range mtlsOptions {
@@ -770,10 +833,11 @@ func buildInboundPassthroughChains(lb *ListenerBuilder) []*listener.FilterChain
 					Protocol:   protocol.Unsupported,
 					TargetPort: mtls.Port,
 				},
-				clusterName: clusterName,
-				passthrough: true,
+				clusterName:       clusterName,
+				passthrough:       true,
+				passthroughPolicy: policy,
 			}
-			opts := getFilterChainMatchOptions(mtls, istionetworking.ListenerProtocolAuto)
+			opts := getFilterChainMatchOptions(mtls, passthroughProtocol)
 			filterChains = append(filterChains, lb.inboundChainForOpts(cc, mtls, opts)...)
 		}
 	}
@@ -855,6 +919,12 @@ func (lb *ListenerBuilder) buildInboundNetworkFiltersForHTTP(cc inboundChainConf
 
 	httpOpts := buildSidecarInboundHTTPOpts(lb, cc)
 	hcm := lb.buildHTTPConnectionManager(httpOpts)
+	if lb.inboundConnectionPool.IdleTimeout != nil {
+		if hcm.CommonHttpProtocolOptions == nil {
+			hcm.CommonHttpProtocolOptions = &core.HttpProtocolOptions{}
+		}
+		hcm.CommonHttpProtocolOptions.IdleTimeout = durationpb.New(*lb.inboundConnectionPool.IdleTimeout)
+	}
 	filters = append(filters, &listener.Filter{
 		Name:       wellknown.HTTPConnectionManager,
 		ConfigType: &listener.Filter_TypedConfig{TypedConfig: protoconv.MessageToAny(hcm)},
@@ -877,6 +947,12 @@ func (lb *ListenerBuilder) buildInboundNetworkFilters(fcc inboundChainConfig) []
 	if err == nil {
 		tcpProxy.IdleTimeout = durationpb.New(idleTimeout)
 	}
+	if lb.inboundConnectionPool.IdleTimeout != nil {
+		tcpProxy.IdleTimeout = durationpb.New(*lb.inboundConnectionPool.IdleTimeout)
+	}
+	if fcc.passthrough && fcc.passthroughPolicy.IdleTimeout != nil {
+		tcpProxy.IdleTimeout = durationpb.New(*fcc.passthroughPolicy.IdleTimeout)
+	}
 	tcpFilter := setAccessLogAndBuildTCPFilter(lb.push, lb.node, tcpProxy, istionetworking.ListenerClassSidecarInbound)
 
 	var filters []*listener.Filter
@@ -889,8 +965,8 @@ func (lb *ListenerBuilder) buildInboundNetworkFilters(fcc inboundChainConfig) []
 	} else {
 		filters = append(filters, buildMetadataExchangeNetworkFilters(istionetworking.ListenerClassSidecarInbound)...)
 	}
-	filters = append(filters, lb.authzCustomBuilder.BuildTCP()...)
-	filters = append(filters, lb.authzBuilder.BuildTCP()...)
+	filters = append(filters, lb.authzCustomBuilder.BuildTCPForProtocol(fcc.connectionProtocol)...)
+	filters = append(filters, lb.authzBuilder.BuildTCPForProtocol(fcc.connectionProtocol)...)
 	filters = append(filters, buildMetricsNetworkFilters(lb.push, lb.node, istionetworking.ListenerClassSidecarInbound)...)
 	filters = append(filters, buildNetworkFiltersStack(fcc.port.Protocol, tcpFilter, statPrefix, fcc.clusterName)...)
 
@@ -0,0 +1,50 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	connectionlimit "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/connection_limit/v3"
+	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+)
+
+// ConnectionLimitFilterName is the name of the Envoy filter closing the connections over the inbound max connections.
+const ConnectionLimitFilterName = "envoy.filters.network.connection_limit"
+
+// buildInboundConnectionLimitFilters builds the network filters limiting the concurrent connections of an inbound
+// filter chain. The rejected connections are reported as connection_limit.<stat prefix>.limited_connections.
+func buildInboundConnectionLimitFilters(pool model.InboundConnectionPool, statPrefix string) []*listener.Filter {
+	if pool.MaxConnections == 0 {
+		return nil
+	}
+	limit := &connectionlimit.ConnectionLimit{
+		StatPrefix:     statPrefix,
+		MaxConnections: wrappers.UInt64(uint64(pool.MaxConnections)),
+	}
+	return []*listener.Filter{{
+		Name:       ConnectionLimitFilterName,
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: protoconv.MessageToAny(limit)},
+	}}
+}
+
+// applyInboundConnectionBufferLimit sets the buffer limit of the inbound connections on the listener.
+func applyInboundConnectionBufferLimit(l *listener.Listener, pool model.InboundConnectionPool) {
+	if pool.PerConnectionBufferLimitBytes > 0 {
+		l.PerConnectionBufferLimitBytes = wrappers.UInt32(pool.PerConnectionBufferLimitBytes)
+	}
+}
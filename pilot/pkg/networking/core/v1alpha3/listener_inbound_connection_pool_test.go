@@ -0,0 +1,68 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+	"time"
+
+	connectionlimit "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/connection_limit/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestBuildInboundConnectionLimitFilters(t *testing.T) {
+	if got := buildInboundConnectionLimitFilters(model.InboundConnectionPool{}, "inbound_0.0.0.0_8080"); got != nil {
+		t.Fatalf("expected no filter without max connections, got %v", got)
+	}
+	filters := buildInboundConnectionLimitFilters(model.InboundConnectionPool{MaxConnections: 100}, "inbound_0.0.0.0_8080")
+	if len(filters) != 1 || filters[0].Name != ConnectionLimitFilterName {
+		t.Fatalf("expected one connection limit filter, got %v", filters)
+	}
+	limit := xdstest.UnmarshalAny[connectionlimit.ConnectionLimit](t, filters[0].GetTypedConfig())
+	assert.Equal(t, limit.StatPrefix, "inbound_0.0.0.0_8080")
+	assert.Equal(t, limit.MaxConnections.GetValue(), uint64(100))
+}
+
+func TestInboundConnectionPool(t *testing.T) {
+	proxy := &model.Proxy{Metadata: &model.NodeMetadata{Annotations: map[string]string{
+		model.InboundMaxConnectionsAnnotation:        "100",
+		model.InboundConnectionBufferLimitAnnotation: "32768",
+		model.InboundIdleTimeoutAnnotation:           "30s",
+	}}}
+	listeners := buildListeners(t, TestOptions{Services: testServices}, proxy)
+	l := xdstest.ExtractListener(model.VirtualInboundListenerName, listeners)
+	if l == nil {
+		t.Fatalf("didn't find virtual inbound listener")
+	}
+	assert.Equal(t, l.PerConnectionBufferLimitBytes.GetValue(), uint32(32768))
+	for _, fc := range l.FilterChains {
+		if fc.Name == model.VirtualInboundBlackholeFilterChainName {
+			continue
+		}
+		if len(fc.Filters) == 0 || fc.Filters[0].Name != ConnectionLimitFilterName {
+			t.Fatalf("expected the filter chain %v to start with a connection limit filter", fc.Name)
+		}
+	}
+
+	fc := xdstest.ExtractFilterChain("0.0.0.0_8080", l)
+	if fc == nil {
+		t.Fatalf("didn't find the filter chain of port 8080")
+	}
+	hcm := xdstest.ExtractHTTPConnectionManager(t, fc)
+	assert.Equal(t, hcm.GetCommonHttpProtocolOptions().GetIdleTimeout().AsDuration(), 30*time.Second)
+}
@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestInboundMirror(t *testing.T) {
+	mirrorPolicies := func(annotations map[string]string) []*route.RouteAction_RequestMirrorPolicy {
+		t.Helper()
+		proxy := &model.Proxy{Metadata: &model.NodeMetadata{Annotations: annotations}}
+		listeners := buildListeners(t, TestOptions{Services: testServices}, proxy)
+		l := xdstest.ExtractListener(model.VirtualInboundListenerName, listeners)
+		if l == nil {
+			t.Fatalf("didn't find virtual inbound listener")
+		}
+		fc := xdstest.ExtractFilterChain("0.0.0.0_8080", l)
+		if fc == nil {
+			t.Fatalf("didn't find the filter chain of port 8080")
+		}
+		hcm := xdstest.ExtractHTTPConnectionManager(t, fc)
+		return hcm.GetRouteConfig().GetVirtualHosts()[0].GetRoutes()[0].GetRoute().GetRequestMirrorPolicies()
+	}
+
+	if got := mirrorPolicies(nil); got != nil {
+		t.Fatalf("expected no mirroring without annotation, got %v", got)
+	}
+
+	got := mirrorPolicies(map[string]string{
+		model.InboundMirrorAnnotation:           "mirror.default.svc.cluster.local",
+		model.InboundMirrorPercentageAnnotation: "25",
+	})
+	if len(got) != 1 {
+		t.Fatalf("expected one mirror policy, got %v", got)
+	}
+	assert.Equal(t, got[0].Cluster, "outbound|8080||mirror.default.svc.cluster.local")
+	assert.Equal(t, got[0].RuntimeFraction.GetDefaultValue().GetNumerator(), uint32(250000))
+
+	got = mirrorPolicies(map[string]string{model.InboundMirrorAnnotation: "mirror.default.svc.cluster.local:9080"})
+	if len(got) != 1 {
+		t.Fatalf("expected one mirror policy, got %v", got)
+	}
+	assert.Equal(t, got[0].Cluster, "outbound|9080||mirror.default.svc.cluster.local")
+	assert.Equal(t, got[0].RuntimeFraction.GetDefaultValue().GetNumerator(), uint32(1000000))
+}
@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbactcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	envoytype "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+)
+
+// InboundPassthroughStatPrefix is the prefix of the stats of the inbound connections to undeclared ports. Envoy
+// reports the connections that are allowed as tcp.inbound_passthrough_rbac.allowed, and the connections rejected
+// by the inbound passthrough policy of the workload as tcp.inbound_passthrough_rbac.denied.
+const InboundPassthroughStatPrefix = "tcp.inbound_passthrough_"
+
+// buildInboundPassthroughPolicyFilters builds the network filters enforcing the allowed ports of the inbound
+// passthrough policy, and counting the connections to undeclared ports.
+func buildInboundPassthroughPolicyFilters(policy model.InboundPassthroughPolicy) []*listener.Filter {
+	if !policy.Restricted() && !features.EnableInboundPassthroughStats {
+		return nil
+	}
+	permissions := []*rbacpb.Permission{{Rule: &rbacpb.Permission_Any{Any: true}}}
+	if policy.Restricted() {
+		permissions = make([]*rbacpb.Permission, 0, len(policy.AllowedPorts))
+		for _, r := range policy.AllowedPorts {
+			permissions = append(permissions, &rbacpb.Permission{Rule: &rbacpb.Permission_DestinationPortRange{
+				// Envoy ranges exclude their end.
+				DestinationPortRange: &envoytype.Int32Range{Start: int32(r.Start), End: int32(r.End) + 1},
+			}})
+		}
+	}
+	policies := map[string]*rbacpb.Policy{}
+	// Without any policy, all the connections are denied.
+	if len(permissions) > 0 {
+		policies["inbound-passthrough-ports"] = &rbacpb.Policy{
+			Permissions: permissions,
+			Principals:  []*rbacpb.Principal{{Identifier: &rbacpb.Principal_Any{Any: true}}},
+		}
+	}
+	rbac := &rbactcp.RBAC{
+		StatPrefix: InboundPassthroughStatPrefix,
+		Rules:      &rbacpb.RBAC{Action: rbacpb.RBAC_ALLOW, Policies: policies},
+	}
+	return []*listener.Filter{{
+		Name:       wellknown.RoleBasedAccessControl,
+		ConfigType: &listener.Filter_TypedConfig{TypedConfig: protoconv.MessageToAny(rbac)},
+	}}
+}
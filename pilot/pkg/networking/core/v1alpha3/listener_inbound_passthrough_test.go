@@ -0,0 +1,66 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbactcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/test"
+)
+
+func TestBuildInboundPassthroughPolicyFilters(t *testing.T) {
+	if got := buildInboundPassthroughPolicyFilters(model.InboundPassthroughPolicy{}); got != nil {
+		t.Fatalf("expected no filter without restriction, got %v", got)
+	}
+
+	rules := func(policy model.InboundPassthroughPolicy) *rbacpb.RBAC {
+		t.Helper()
+		filters := buildInboundPassthroughPolicyFilters(policy)
+		if len(filters) != 1 {
+			t.Fatalf("expected one filter, got %v", filters)
+		}
+		rbac := &rbactcp.RBAC{}
+		if err := filters[0].GetTypedConfig().UnmarshalTo(rbac); err != nil {
+			t.Fatal(err)
+		}
+		if rbac.StatPrefix != InboundPassthroughStatPrefix {
+			t.Fatalf("unexpected stat prefix %q", rbac.StatPrefix)
+		}
+		return rbac.Rules
+	}
+
+	r := rules(model.InboundPassthroughPolicy{AllowedPorts: []model.PortRange{{Start: 8080, End: 8080}, {Start: 9000, End: 9100}}})
+	permissions := r.Policies["inbound-passthrough-ports"].GetPermissions()
+	if len(permissions) != 2 {
+		t.Fatalf("expected 2 permissions, got %v", permissions)
+	}
+	if pr := permissions[1].GetDestinationPortRange(); pr.Start != 9000 || pr.End != 9101 {
+		t.Fatalf("unexpected port range %v", pr)
+	}
+
+	if r := rules(model.InboundPassthroughPolicy{AllowedPorts: []model.PortRange{}}); len(r.Policies) != 0 {
+		t.Fatalf("expected all ports to be denied, got %v", r.Policies)
+	}
+
+	test.SetForTest(t, &features.EnableInboundPassthroughStats, true)
+	if r := rules(model.InboundPassthroughPolicy{}); !r.Policies["inbound-passthrough-ports"].GetPermissions()[0].GetAny() {
+		t.Fatalf("expected all ports to be allowed, got %v", r.Policies)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha3
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"istio.io/istio/pilot/pkg/model"
+	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
+	"istio.io/istio/pilot/test/xdstest"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestInboundProtocolDetectionFallback(t *testing.T) {
+	svc := buildService("test.com", wildcardIPv4, protocol.Unsupported, tnow)
+	svc.Attributes.ProtocolDetection = map[int]model.PortProtocolDetection{8080: {Fallback: protocol.HTTP}}
+	listeners := buildListeners(t, TestOptions{Services: []*model.Service{svc}}, nil)
+	l := xdstest.ExtractListener(model.VirtualInboundListenerName, listeners)
+	if l == nil {
+		t.Fatalf("didn't find virtual inbound listener")
+	}
+	chains := 0
+	for _, fc := range l.FilterChains {
+		if fc.Name != "0.0.0.0_8080" {
+			continue
+		}
+		chains++
+		// TLS connections not terminated by the sidecar are still proxied as TCP.
+		tlsPassthrough := fc.GetFilterChainMatch().GetTransportProtocol() == xdsfilters.TLSTransportProtocol && fc.TransportSocket == nil
+		if isHTTPFilterChain(fc) == tlsPassthrough {
+			t.Fatalf("unexpected filters for the filter chain matching %v", fc.FilterChainMatch)
+		}
+	}
+	if chains == 0 {
+		t.Fatalf("didn't find the filter chains of port 8080")
+	}
+}
+
+func TestOutboundProtocolDetectionTimeout(t *testing.T) {
+	svc := buildService("test.com", "1.2.3.4", protocol.Unsupported, tnow)
+	svc.Attributes.ProtocolDetection = map[int]model.PortProtocolDetection{8080: {Timeout: 500 * time.Millisecond}}
+	listeners := buildOutboundListeners(t, getProxy(), nil, nil, svc)
+	l := findListenerByPort(listeners, 8080)
+	if l == nil {
+		t.Fatalf("didn't find the listener of port 8080")
+	}
+	assert.Equal(t, l.ListenerFiltersTimeout.AsDuration(), 500*time.Millisecond)
+	assert.Equal(t, l.ContinueOnListenerFiltersTimeout, true)
+}
+
+func TestMergeListenerFiltersTimeout(t *testing.T) {
+	second := durationpb.New(time.Second)
+	minute := durationpb.New(time.Minute)
+	assert.Equal(t, mergeListenerFiltersTimeout(nil, second), second)
+	assert.Equal(t, mergeListenerFiltersTimeout(second, minute), minute)
+	assert.Equal(t, mergeListenerFiltersTimeout(minute, second), minute)
+	assert.Equal(t, mergeListenerFiltersTimeout(second, durationpb.New(0)).AsDuration(), time.Duration(0))
+}
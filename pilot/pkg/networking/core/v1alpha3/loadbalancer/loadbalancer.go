@@ -19,13 +19,17 @@ import (
 	"math"
 	"sort"
 
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 
 	"istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking/util"
+	localityconfig "istio.io/istio/pkg/config/locality"
 )
 
 func GetLocalityLbSetting(
@@ -195,6 +199,133 @@ func applyLocalityFailover(
 	}
 }
 
+// ApplyLocalityPriorityGroups sets the priority and weight of each LocalityLbEndpoints from the first priority
+// groups matching the proxy locality. It returns false if there are none, in which case nothing is changed.
+func ApplyLocalityPriorityGroups(
+	locality *core.Locality,
+	loadAssignment *endpoint.ClusterLoadAssignment,
+	priorityGroups []localityconfig.PriorityGroups,
+) bool {
+	if loadAssignment == nil {
+		return false
+	}
+	for _, pg := range priorityGroups {
+		if util.LocalityMatch(locality, pg.From) {
+			applyPriorityGroups(loadAssignment, pg.Groups)
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyLocalityEjectionPolicy bounds the ejection time of the endpoints of a cluster with outlier detection.
+func ApplyLocalityEjectionPolicy(c *cluster.Cluster, policy *localityconfig.EjectionPolicy) {
+	if c.OutlierDetection == nil || policy == nil {
+		return
+	}
+	if d := policy.Failback.MaxEjectionDuration(); d > 0 {
+		c.OutlierDetection.MaxEjectionTime = durationpb.New(d)
+	}
+}
+
+// ApplyLocalityFailback makes the traffic of each locality priority follow the share of its endpoints which are not
+// ejected, so that it moves back to a recovering locality as its endpoints are reintroduced, rather than all at once.
+func ApplyLocalityFailback(loadAssignment *endpoint.ClusterLoadAssignment, policy *localityconfig.EjectionPolicy) {
+	if loadAssignment == nil || policy == nil || policy.Failback == nil || !policy.Failback.Gradual {
+		return
+	}
+	// The policy may be shared with other load assignments, as they are shallow copies.
+	p := &endpoint.ClusterLoadAssignment_Policy{}
+	if loadAssignment.Policy != nil {
+		p = proto.Clone(loadAssignment.Policy).(*endpoint.ClusterLoadAssignment_Policy)
+	}
+	// An overprovisioning factor of 100 weights a priority exactly by its healthy endpoints, where the default of
+	// 140 keeps all its traffic until less than 1/1.4 of them are healthy.
+	p.OverprovisioningFactor = &wrappers.UInt32Value{Value: 100}
+	loadAssignment.Policy = p
+}
+
+// set locality loadbalancing priority and weight by priority groups
+func applyPriorityGroups(loadAssignment *endpoint.ClusterLoadAssignment, groups []map[string]uint32) {
+	// key is priority, value is the index of the LocalityLbEndpoints in ClusterLoadAssignment
+	priorityMap := map[int][]int{}
+	// key is the priority and locality of a group, value is the index of the matching LocalityLbEndpoints
+	type groupLocality struct {
+		priority int
+		locality string
+	}
+	matched := map[groupLocality][]int{}
+
+	// 1. assign each LocalityLbEndpoints the priority of the first group with a matching locality.
+	// Endpoints not matching any group get the lowest priority and keep their weight.
+	for i, localityEndpoint := range loadAssignment.Endpoints {
+		priority := len(groups)
+	match:
+		for p, group := range groups {
+			for _, loc := range sortedLocalities(group) {
+				if util.LocalityMatch(localityEndpoint.Locality, loc) {
+					priority = p
+					matched[groupLocality{p, loc}] = append(matched[groupLocality{p, loc}], i)
+					break match
+				}
+			}
+		}
+		loadAssignment.Endpoints[i].Priority = uint32(priority)
+		priorityMap[priority] = append(priorityMap[priority], i)
+	}
+
+	// 2. split the weight of each locality of a group across its matching LocalityLbEndpoints,
+	// the same way as locality weighted load balancing.
+	for key, indexes := range matched {
+		weight := groups[key.priority][key.locality]
+		totalWeight := uint32(0)
+		for _, index := range indexes {
+			totalWeight += localityWeight(loadAssignment.Endpoints[index])
+		}
+		if totalWeight == 0 {
+			continue
+		}
+		for _, index := range indexes {
+			destWeight := float64(localityWeight(loadAssignment.Endpoints[index])*weight) / float64(totalWeight)
+			loadAssignment.Endpoints[index].LoadBalancingWeight = &wrappers.UInt32Value{
+				Value: uint32(math.Ceil(destWeight)),
+			}
+		}
+	}
+
+	// 3. since Priorities should range from 0 (highest) to N (lowest) without skipping,
+	// adjust the priorities of groups without endpoints.
+	priorities := []int{}
+	for priority := range priorityMap {
+		priorities = append(priorities, priority)
+	}
+	sort.Ints(priorities)
+	for i, priority := range priorities {
+		if i != priority {
+			for _, index := range priorityMap[priority] {
+				loadAssignment.Endpoints[index].Priority = uint32(i)
+			}
+		}
+	}
+}
+
+func localityWeight(ep *endpoint.LocalityLbEndpoints) uint32 {
+	if ep.LoadBalancingWeight != nil {
+		return ep.LoadBalancingWeight.Value
+	}
+	return 1
+}
+
+// sortedLocalities returns the localities of a priority group in a stable order.
+func sortedLocalities(group map[string]uint32) []string {
+	localities := make([]string, 0, len(group))
+	for loc := range group {
+		localities = append(localities, loc)
+	}
+	sort.Strings(localities)
+	return localities
+}
+
 // WrappedLocalityLbEndpoints contain an envoy LocalityLbEndpoints
 // and the original IstioEndpoints used to generate it.
 // It is used to do failover priority label match with proxy labels.
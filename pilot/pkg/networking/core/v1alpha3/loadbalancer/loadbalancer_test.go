@@ -31,6 +31,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	memregistry "istio.io/istio/pilot/pkg/serviceregistry/memory"
 	"istio.io/istio/pkg/config"
+	localityconfig "istio.io/istio/pkg/config/locality"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/schema/collections"
@@ -497,6 +498,84 @@ func TestApplyLocalitySetting(t *testing.T) {
 	})
 }
 
+func TestApplyLocalityPriorityGroups(t *testing.T) {
+	newLoadAssignment := func(localities ...string) *endpoint.ClusterLoadAssignment {
+		cla := &endpoint.ClusterLoadAssignment{}
+		for _, l := range localities {
+			region, zone, subzone := model.SplitLocalityLabel(l)
+			cla.Endpoints = append(cla.Endpoints, &endpoint.LocalityLbEndpoints{
+				Locality:            &core.Locality{Region: region, Zone: zone, SubZone: subzone},
+				LoadBalancingWeight: &wrappers.UInt32Value{Value: 2},
+			})
+		}
+		return cla
+	}
+	priorityGroups := []localityconfig.PriorityGroups{
+		{From: "us-west", Groups: []map[string]uint32{{"us-west/*": 100}}},
+		{
+			From: "us-east",
+			Groups: []map[string]uint32{
+				{"us-east/zone1": 100},
+				{"eu-west/*": 100},
+				{"us-central/*": 70, "us-west/*": 30},
+			},
+		},
+	}
+
+	cla := newLoadAssignment("us-east/zone1", "us-east/zone2", "us-central/zone1", "us-central/zone2", "us-west/zone1", "ap-south/zone1")
+	if !ApplyLocalityPriorityGroups(&core.Locality{Region: "us-east", Zone: "zone2"}, cla, priorityGroups) {
+		t.Fatal("expected priority groups to match")
+	}
+	// eu-west has no endpoints so the priorities after it are moved up.
+	wantPriorities := []uint32{0, 2, 1, 1, 1, 2}
+	wantWeights := []uint32{100, 2, 35, 35, 30, 2}
+	for i, ep := range cla.Endpoints {
+		if ep.Priority != wantPriorities[i] || ep.LoadBalancingWeight.GetValue() != wantWeights[i] {
+			t.Errorf("%v: got priority %d weight %d, want priority %d weight %d", ep.Locality,
+				ep.Priority, ep.LoadBalancingWeight.GetValue(), wantPriorities[i], wantWeights[i])
+		}
+	}
+
+	cla = newLoadAssignment("us-east/zone1")
+	if ApplyLocalityPriorityGroups(&core.Locality{Region: "eu-west"}, cla, priorityGroups) {
+		t.Fatal("expected no priority groups to match")
+	}
+	if cla.Endpoints[0].Priority != 0 || cla.Endpoints[0].LoadBalancingWeight.GetValue() != 2 {
+		t.Errorf("endpoints should not be modified, got %v", cla.Endpoints[0])
+	}
+}
+
+func TestApplyLocalityEjectionPolicy(t *testing.T) {
+	policy := &localityconfig.EjectionPolicy{Failback: &localityconfig.Failback{Gradual: true, MaxEjectionTime: "60s"}}
+
+	c := &cluster.Cluster{OutlierDetection: &cluster.OutlierDetection{}}
+	ApplyLocalityEjectionPolicy(c, policy)
+	if c.OutlierDetection.MaxEjectionTime.AsDuration().Seconds() != 60 {
+		t.Errorf("got max ejection time %v, want 60s", c.OutlierDetection.MaxEjectionTime)
+	}
+	// Without outlier detection nothing is ejected, so there is nothing to bound.
+	c = &cluster.Cluster{}
+	ApplyLocalityEjectionPolicy(c, policy)
+	if c.OutlierDetection != nil {
+		t.Errorf("expected the cluster not to be modified, got %v", c)
+	}
+
+	shared := &endpoint.ClusterLoadAssignment_Policy{DropOverloads: []*endpoint.ClusterLoadAssignment_Policy_DropOverload{{Category: "test"}}}
+	cla := &endpoint.ClusterLoadAssignment{Policy: shared}
+	ApplyLocalityFailback(cla, policy)
+	if cla.Policy.GetOverprovisioningFactor().GetValue() != 100 || len(cla.Policy.DropOverloads) != 1 {
+		t.Errorf("unexpected policy %v", cla.Policy)
+	}
+	if shared.OverprovisioningFactor != nil {
+		t.Errorf("the shared policy should not be modified")
+	}
+	cla = &endpoint.ClusterLoadAssignment{}
+	ApplyLocalityFailback(cla, &localityconfig.EjectionPolicy{Failback: &localityconfig.Failback{MaxEjectionTime: "60s"}})
+	if cla.Policy != nil {
+		t.Errorf("expected no failback policy, got %v", cla.Policy)
+	}
+}
+
 func TestGetLocalityLbSetting(t *testing.T) {
 	// dummy config for test
 	failover := []*networking.LocalityLoadBalancerSetting_Failover{nil}
@@ -241,8 +241,13 @@ func buildOutboundNetworkFilters(node *model.Proxy,
 				routes[0].Destination.Subset, port, &service.Attributes)
 		}
 
-		return buildOutboundNetworkFiltersWithSingleDestination(
+		upstreamPort := port.Port
+		if routes[0].Destination.GetPort().GetNumber() != 0 {
+			upstreamPort = int(routes[0].Destination.GetPort().GetNumber())
+		}
+		filters := buildOutboundNetworkFiltersWithSingleDestination(
 			push, node, statPrefix, clusterName, routes[0].Destination.Subset, port, destinationRule, tunnelingconfig.Apply)
+		return withDynamicForwardProxy(node, filters, service, upstreamPort)
 	}
 	return buildOutboundNetworkFiltersWithWeightedClusters(node, routes, push, port, configMeta, destinationRule)
 }
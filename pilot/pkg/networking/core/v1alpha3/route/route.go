@@ -44,6 +44,7 @@ import (
 	"istio.io/istio/pilot/pkg/util/constant"
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/consistenthash"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
@@ -68,7 +69,8 @@ const prefixMatchRegex = `((\/).*)?`
 
 var notimeout = durationpb.New(0)
 
-type DestinationHashMap map[*networking.HTTPRouteDestination]*networking.LoadBalancerSettings_ConsistentHashLB
+// DestinationHashMap holds the hash policies of the route destinations using consistent hash load balancing.
+type DestinationHashMap map[*networking.HTTPRouteDestination][]*route.RouteAction_HashPolicy
 
 // VirtualHostWrapper is a context-dependent virtual host entry with guarded routes.
 // Note: Currently we are not fully utilizing this structure. We could invoke this logic
@@ -124,7 +126,7 @@ func BuildSidecarVirtualHostWrapper(routeCache *Cache, node *model.Proxy, push *
 		for _, port := range svc.Ports {
 			if port.Protocol.IsHTTP() || util.IsProtocolSniffingEnabledForPort(port) {
 				hash, destinationRule := hashForService(push, node, svc, port)
-				if hash != nil {
+				if len(hash) > 0 {
 					dependentDestinationRules = append(dependentDestinationRules, destinationRule)
 				}
 				// append default hosts for the service missing virtual Services.
@@ -247,7 +249,7 @@ func buildSidecarVirtualHostsForVirtualService(
 
 func buildSidecarVirtualHostForService(svc *model.Service,
 	port *model.Port,
-	hash *networking.LoadBalancerSettings_ConsistentHashLB,
+	hash []*route.RouteAction_HashPolicy,
 	mesh *meshconfig.MeshConfig,
 ) VirtualHostWrapper {
 	cluster := model.BuildSubsetKey(model.TrafficDirectionOutbound, "", svc.Hostname, port.Port)
@@ -255,9 +257,8 @@ func buildSidecarVirtualHostForService(svc *model.Service,
 	httpRoute := BuildDefaultHTTPOutboundRoute(cluster, traceOperation, mesh)
 
 	// if this host has no virtualservice, the consistentHash on its destinationRule will be useless
-	hashPolicy := consistentHashToHashPolicy(hash)
-	if hashPolicy != nil {
-		httpRoute.GetRoute().HashPolicy = []*route.RouteAction_HashPolicy{hashPolicy}
+	if len(hash) > 0 {
+		httpRoute.GetRoute().HashPolicy = hash
 	}
 	return VirtualHostWrapper{
 		Port:     port.Port,
@@ -533,11 +534,7 @@ func applyHTTPRouteDestination(
 		}
 
 		weighted = append(weighted, clusterWeight)
-		hash := hashByDestination[dst]
-		hashPolicy := consistentHashToHashPolicy(hash)
-		if hashPolicy != nil {
-			action.HashPolicy = append(action.HashPolicy, hashPolicy)
-		}
+		action.HashPolicy = append(action.HashPolicy, hashByDestination[dst]...)
 	}
 
 	// rewrite to a single cluster if there is only weighted cluster
@@ -1043,6 +1040,25 @@ func BuildDefaultHTTPInboundRoute(clusterName string, operation string) *route.R
 	return out
 }
 
+// BuildInboundMirrorPolicy builds the mirroring of the inbound requests of a proxy, received on the service port.
+// The requests are mirrored to the outbound cluster of the destination of the policy.
+func BuildInboundMirrorPolicy(policy *model.InboundMirrorPolicy, servicePort int) *route.RouteAction_RequestMirrorPolicy {
+	port := policy.Port
+	if port == 0 {
+		port = servicePort
+	}
+	return &route.RouteAction_RequestMirrorPolicy{
+		Cluster: model.BuildSubsetKey(model.TrafficDirectionOutbound, "", policy.Host, port),
+		RuntimeFraction: &core.RuntimeFractionalPercent{
+			DefaultValue: &xdstype.FractionalPercent{
+				Numerator:   uint32(policy.Percentage * 10000),
+				Denominator: xdstype.FractionalPercent_MILLION,
+			},
+		},
+		TraceSampled: &wrappers.BoolValue{Value: false},
+	}
+}
+
 func buildDefaultHTTPRoute(clusterName string, operation string) *route.Route {
 	routeAction := &route.RouteAction{
 		ClusterSpecifier: &route.RouteAction_Cluster{Cluster: clusterName},
@@ -1229,11 +1245,27 @@ func consistentHashToHashPolicy(consistentHash *networking.LoadBalancerSettings_
 	return nil
 }
 
+// hashPolicies returns the hash policies of the consistent hash load balancer, followed by the policies of the
+// additional keys of the DestinationRule.
+func hashPolicies(consistentHash *networking.LoadBalancerSettings_ConsistentHashLB, dr *config.Config) []*route.RouteAction_HashPolicy {
+	policy := consistentHashToHashPolicy(consistentHash)
+	if policy == nil {
+		return nil
+	}
+	out := []*route.RouteAction_HashPolicy{policy}
+	// Invalid keys are rejected by the validation webhook.
+	additional, _ := consistenthash.ParseKeys(dr.Annotations)
+	for _, key := range additional {
+		out = append(out, consistentHashToHashPolicy(key))
+	}
+	return out
+}
+
 func hashForService(push *model.PushContext,
 	node *model.Proxy,
 	svc *model.Service,
 	port *model.Port,
-) (*networking.LoadBalancerSettings_ConsistentHashLB, *model.ConsolidatedDestRule) {
+) ([]*route.RouteAction_HashPolicy, *model.ConsolidatedDestRule) {
 	if push == nil {
 		return nil, nil
 	}
@@ -1255,7 +1287,7 @@ func hashForService(push *model.PushContext,
 		}
 	}
 
-	return consistentHash, mergedDR
+	return hashPolicies(consistentHash, destinationRule), mergedDR
 }
 
 func hashForVirtualService(push *model.PushContext,
@@ -1267,7 +1299,7 @@ func hashForVirtualService(push *model.PushContext,
 	for _, httpRoute := range virtualService.Spec.(*networking.VirtualService).Http {
 		for _, destination := range httpRoute.Route {
 			hash, dr := hashForHTTPDestination(push, node, destination)
-			if hash != nil {
+			if len(hash) > 0 {
 				hashByDestination[destination] = hash
 				destinationRules = append(destinationRules, dr)
 			}
@@ -1284,7 +1316,7 @@ func GetConsistentHashForVirtualService(push *model.PushContext, node *model.Pro
 // hashForHTTPDestination return the ConsistentHashLB and the DestinationRule associated with HTTP route destination.
 func hashForHTTPDestination(push *model.PushContext, node *model.Proxy,
 	dst *networking.HTTPRouteDestination,
-) (*networking.LoadBalancerSettings_ConsistentHashLB, *model.ConsolidatedDestRule) {
+) ([]*route.RouteAction_HashPolicy, *model.ConsolidatedDestRule) {
 	if push == nil {
 		return nil, nil
 	}
@@ -1320,7 +1352,7 @@ func hashForHTTPDestination(push *model.PushContext, node *model.Proxy,
 	case plsHash != nil:
 		consistentHash = plsHash
 	}
-	return consistentHash, mergedDR
+	return hashPolicies(consistentHash, destinationRule), mergedDR
 }
 
 // isCatchAll returns true if HTTPMatchRequest is a catchall match otherwise
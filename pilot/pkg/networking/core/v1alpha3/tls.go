@@ -206,11 +206,12 @@ func buildSidecarOutboundTLSFilterChainOpts(node *model.Proxy, push *model.PushC
 		if destinationCIDR != "" {
 			destinationCIDRs = []string{destinationCIDR}
 		}
+		networkFilters := buildOutboundNetworkFiltersWithSingleDestination(push, node, statPrefix, clusterName, "",
+			listenPort, destinationRule, tunnelingconfig.Apply)
 		out = append(out, &filterChainOpts{
 			sniHosts:         sniHosts,
 			destinationCIDRs: destinationCIDRs,
-			networkFilters: buildOutboundNetworkFiltersWithSingleDestination(push, node, statPrefix, clusterName, "",
-				listenPort, destinationRule, tunnelingconfig.Apply),
+			networkFilters:   withDynamicForwardProxy(node, networkFilters, service, port),
 		})
 	}
 
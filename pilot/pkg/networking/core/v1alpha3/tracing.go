@@ -87,6 +87,7 @@ func configureTracingFromSpec(
 		// use the prior configuration bits of sampling and custom tags
 		h.Tracing = &hcm.HttpConnectionManager_Tracing{}
 		configureSampling(h.Tracing, proxyConfigSamplingValue(proxyCfg))
+		configureProxyConfigSampler(h.Tracing, push, proxy)
 		configureCustomTags(h.Tracing, map[string]*telemetrypb.Tracing_CustomTag{}, proxyCfg, proxy)
 		if proxyCfg.GetTracing().GetMaxPathTagLength() != 0 {
 			h.Tracing.MaxPathTagLength = wrapperspb.UInt32(proxyCfg.GetTracing().MaxPathTagLength)
@@ -123,6 +124,7 @@ func configureTracingFromSpec(
 	// gracefully fallback to MeshConfig configuration. It will act as an implicit
 	// parent configuration during transition period.
 	configureSampling(h.Tracing, spec.RandomSamplingPercentage)
+	configureProxyConfigSampler(h.Tracing, push, proxy)
 	configureCustomTags(h.Tracing, spec.CustomTags, proxyCfg, proxy)
 
 	// if there is configured max tag length somewhere, fallback to it.
@@ -553,6 +555,31 @@ func configureSampling(hcmTracing *hcm.HttpConnectionManager_Tracing, providerPe
 	}
 }
 
+// configureProxyConfigSampler replaces the sampling with the sampler of the ProxyConfig resources of the proxy, if
+// any. It takes precedence over the sampling of the ProxyConfig of the proxy and of the Telemetry resources, as it
+// follows the changes of the resources without a restart.
+func configureProxyConfigSampler(hcmTracing *hcm.HttpConnectionManager_Tracing, push *model.PushContext, proxy *model.Proxy) {
+	if sampler := push.ProxyConfigs.EffectiveTracingSampler(proxy.Metadata); sampler != nil {
+		configureSampler(hcmTracing, sampler)
+	}
+}
+
+// configureSampler configures the sampling of a sampler of the ProxyConfig resources.
+func configureSampler(hcmTracing *hcm.HttpConnectionManager_Tracing, sampler *model.TracingSampler) {
+	switch sampler.Type {
+	case model.ClientTracingSampler:
+		hcmTracing.ClientSampling = &xdstype.Percent{Value: sampler.Rate}
+		hcmTracing.OverallSampling = &xdstype.Percent{Value: 100.0}
+		hcmTracing.RandomSampling = &xdstype.Percent{Value: 0.0}
+	case model.OverallTracingSampler:
+		hcmTracing.ClientSampling = &xdstype.Percent{Value: 100.0}
+		hcmTracing.OverallSampling = &xdstype.Percent{Value: sampler.Rate}
+		hcmTracing.RandomSampling = &xdstype.Percent{Value: 100.0}
+	default:
+		configureSampling(hcmTracing, sampler.Rate)
+	}
+}
+
 func proxyConfigSamplingValue(config *meshconfig.ProxyConfig) float64 {
 	sampling := features.TraceSampling
 
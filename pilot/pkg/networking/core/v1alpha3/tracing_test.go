@@ -27,13 +27,18 @@ import (
 	"google.golang.org/protobuf/types/known/wrapperspb"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1beta1"
 	tpb "istio.io/api/telemetry/v1alpha1"
+	"istio.io/istio/pilot/pkg/config/memory"
 	"istio.io/istio/pilot/pkg/extensionproviders"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/util/protoconv"
 	xdsfilters "istio.io/istio/pilot/pkg/xds/filters"
 	"istio.io/istio/pilot/pkg/xds/requestidextension"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
 )
 
 func TestConfigureTracing(t *testing.T) {
@@ -180,6 +185,67 @@ func TestConfigureTracing(t *testing.T) {
 	}
 }
 
+func TestConfigureSampler(t *testing.T) {
+	cases := []struct {
+		sampler                 *model.TracingSampler
+		client, random, overall float64
+	}{
+		{&model.TracingSampler{Type: model.RandomTracingSampler, Rate: 10}, 100, 10, 100},
+		{&model.TracingSampler{Type: model.ClientTracingSampler, Rate: 10}, 10, 0, 100},
+		{&model.TracingSampler{Type: model.OverallTracingSampler, Rate: 10}, 100, 100, 10},
+	}
+	for _, tc := range cases {
+		t.Run(string(tc.sampler.Type), func(t *testing.T) {
+			got := &hcm.HttpConnectionManager_Tracing{}
+			configureSampler(got, tc.sampler)
+			want := &hcm.HttpConnectionManager_Tracing{
+				ClientSampling:  &xdstype.Percent{Value: tc.client},
+				RandomSampling:  &xdstype.Percent{Value: tc.random},
+				OverallSampling: &xdstype.Percent{Value: tc.overall},
+			}
+			if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+				t.Fatalf("unexpected sampling (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConfigureTracingProxyConfigSampler(t *testing.T) {
+	store := memory.Make(collections.Pilot)
+	if _, err := store.Create(config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.ProxyConfig,
+			Name:             "sampler",
+			Namespace:        "istio-system",
+			Annotations:      map[string]string{model.ProxyConfigTracingAnnotation: `{"sampler": "client", "rate": 10}`},
+		},
+		Spec: &v1beta1.ProxyConfig{},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		spec *model.TracingConfig
+	}{
+		{name: "mesh config"},
+		{name: "telemetry", spec: fakeTracingSpecNoProvider(99.999, false, true)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := fakeOptsMeshAndTelemetryAPI(true)
+			pcs, err := model.GetProxyConfigs(store, &meshconfig.MeshConfig{RootNamespace: "istio-system"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.push.ProxyConfigs = pcs
+			h := &hcm.HttpConnectionManager{}
+			configureTracingFromSpec(tc.spec, opts.push, opts.proxy, h, 0)
+			if got := h.GetTracing().GetClientSampling().GetValue(); got != 10 {
+				t.Fatalf("expected the sampler of the ProxyConfig to be applied, got client sampling %v", got)
+			}
+		})
+	}
+}
+
 func defaultTracingTags() []*tracing.CustomTag {
 	return append(buildOptionalPolicyTags(),
 		&tracing.CustomTag{
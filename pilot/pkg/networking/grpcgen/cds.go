@@ -26,6 +26,7 @@ import (
 	"istio.io/istio/pilot/pkg/model"
 	corexds "istio.io/istio/pilot/pkg/networking/core/v1alpha3"
 	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pilot/pkg/util/trafficpolicy"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/util/sets"
 )
@@ -166,8 +167,9 @@ func (b *clusterBuilder) applyDestinationRule(defaultCluster *cluster.Cluster) (
 	}
 
 	// resolve policy from context
-	destinationRule := corexds.CastDestinationRule(b.node.SidecarScope.DestinationRule(
-		model.TrafficDirectionOutbound, b.node, b.svc.Hostname).GetRule())
+	destRule := b.node.SidecarScope.DestinationRule(model.TrafficDirectionOutbound, b.node, b.svc.Hostname).GetRule()
+	destinationRule := corexds.CastDestinationRule(destRule)
+	inherit := destRule != nil && trafficpolicy.Merges(destRule.Annotations)
 	trafficPolicy := corexds.MergeTrafficPolicy(nil, destinationRule.GetTrafficPolicy(), b.port)
 
 	// setup default cluster
@@ -182,7 +184,7 @@ func (b *clusterBuilder) applyDestinationRule(defaultCluster *cluster.Cluster) (
 				continue
 			}
 			c := edsCluster(subsetKey)
-			trafficPolicy := corexds.MergeTrafficPolicy(trafficPolicy, subset.TrafficPolicy, b.port)
+			trafficPolicy := corexds.MergeSubsetTrafficPolicy(trafficPolicy, subset.TrafficPolicy, b.port, inherit)
 			b.applyTrafficPolicy(c, trafficPolicy)
 			subsetClusters = append(subsetClusters, c)
 		}
@@ -15,17 +15,45 @@
 package authn
 
 import (
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	rbactcp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/rbac/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
 	"istio.io/istio/pilot/pkg/security/authn"
 	"istio.io/istio/pilot/pkg/security/authn/factory"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	"istio.io/istio/pilot/pkg/util/protoconv"
 	"istio.io/pkg/log"
 )
 
 var authnLog = log.RegisterScope("authn", "authn debugging", 0)
 
+// DryRunStrictStatPrefix is the prefix of the stats of the plaintext connections that a dry-run STRICT
+// PeerAuthentication would reject, which Envoy reports as tcp.rbac.istio_dry_run_mtls_strict_shadow_denied.
+const DryRunStrictStatPrefix = "istio_dry_run_mtls_strict_"
+
+// dryRunStrictFilter denies all the connections in shadow mode, so that Envoy counts them without rejecting them.
+var dryRunStrictFilter = &listener.Filter{
+	Name: wellknown.RoleBasedAccessControl,
+	ConfigType: &listener.Filter_TypedConfig{TypedConfig: protoconv.MessageToAny(&rbactcp.RBAC{
+		StatPrefix: authzmodel.RBACTCPFilterStatPrefix,
+		ShadowRules: &rbacpb.RBAC{
+			Action: rbacpb.RBAC_DENY,
+			Policies: map[string]*rbacpb.Policy{
+				"dry-run-mtls-strict": {
+					Permissions: []*rbacpb.Permission{{Rule: &rbacpb.Permission_Any{Any: true}}},
+					Principals:  []*rbacpb.Principal{{Identifier: &rbacpb.Principal_Any{Any: true}}},
+				},
+			},
+		},
+		ShadowRulesStatPrefix: DryRunStrictStatPrefix,
+	})},
+}
+
 type Builder struct {
 	applier      authn.PolicyApplier
 	trustDomains []string
@@ -91,6 +119,15 @@ func (b *Builder) ForPassthrough() []authn.MTLSSettings {
 	return resp
 }
 
+// BuildDryRunStrict returns the network filters to add to the plaintext filter chains of a port, to report the
+// connections that a dry-run STRICT policy would reject.
+func (b *Builder) BuildDryRunStrict(mtls authn.MTLSSettings) []*listener.Filter {
+	if b == nil || !mtls.DryRunStrict {
+		return nil
+	}
+	return []*listener.Filter{dryRunStrictFilter}
+}
+
 func (b *Builder) BuildHTTP(class networking.ListenerClass) []*hcm.HttpFilter {
 	if b == nil {
 		return nil
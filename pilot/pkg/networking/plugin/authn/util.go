@@ -17,6 +17,7 @@ package authn
 import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/security/trustdomain"
 	"istio.io/istio/pkg/util/sets"
 )
 
@@ -25,7 +26,7 @@ func TrustDomainsForValidation(meshConfig *meshconfig.MeshConfig) []string {
 		return nil
 	}
 
-	tds := append([]string{meshConfig.TrustDomain}, meshConfig.TrustDomainAliases...)
+	tds := append([]string{meshConfig.TrustDomain}, trustdomain.Aliases(meshConfig)...)
 	return dedupTrustDomains(tds)
 }
 
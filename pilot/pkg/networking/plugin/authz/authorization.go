@@ -15,14 +15,24 @@
 package authz
 
 import (
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	rbacpb "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
+	rbachttp "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/networking"
+	"istio.io/istio/pilot/pkg/networking/core/v1alpha3/extension"
 	"istio.io/istio/pilot/pkg/networking/util"
 	"istio.io/istio/pilot/pkg/security/authz/builder"
 	"istio.io/istio/pilot/pkg/security/trustdomain"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/util/sets"
 )
 
 type ActionType int
@@ -34,17 +44,47 @@ const (
 	Custom
 )
 
+// extensionConfigPrefix is the prefix of the names of the authorization filters sent with ECDS.
+const extensionConfigPrefix = "istio.authz."
+
+// localExtensionConfigs are the extension configs of the local authorization filters, in the order of the filters.
+// Each is always sent, so that adding or removing a policy never changes the listeners or the type of a name.
+var localExtensionConfigs = []struct {
+	name   string
+	action rbacpb.RBAC_Action
+}{
+	{name: extensionConfigPrefix + "audit", action: rbacpb.RBAC_LOG},
+	{name: extensionConfigPrefix + "deny", action: rbacpb.RBAC_DENY},
+	{name: extensionConfigPrefix + "allow", action: rbacpb.RBAC_ALLOW},
+}
+
+// emptyRBAC is the config of the local authorization filters of an action without policies, which enforces nothing.
+var emptyRBAC = protoconv.MessageToAny(&rbachttp.RBAC{})
+
+// customExtensionConfigName returns the name of the extension config of a filter built for the CUSTOM action. The
+// name depends on the type of the filter, since the ext_authz filter has no config enforcing nothing: the CUSTOM
+// filters are only sent when the proxy has CUSTOM policies.
+func customExtensionConfigName(filterName string) string {
+	if filterName == wellknown.HTTPExternalAuthorization {
+		return extensionConfigPrefix + "custom"
+	}
+	return extensionConfigPrefix + "custom.rbac"
+}
+
 type Builder struct {
 	// Lazy load
 	httpBuilt, tcpBuilt bool
 
 	httpFilters []*hcm.HttpFilter
 	tcpFilters  []*listener.Filter
-	builder     *builder.Builder
+	// tcpFiltersByProtocol caches the TCP filters built for each connection protocol.
+	tcpFiltersByProtocol map[string][]*listener.Filter
+	builder              *builder.Builder
+	actionType           ActionType
 }
 
 func NewBuilder(actionType ActionType, push *model.PushContext, proxy *model.Proxy) *Builder {
-	tdBundle := trustdomain.NewBundle(push.Mesh.TrustDomain, push.Mesh.TrustDomainAliases)
+	tdBundle := trustdomain.NewBundle(push.Mesh.TrustDomain, trustdomain.Aliases(push.Mesh))
 	option := builder.Option{
 		IsCustomBuilder: actionType == Custom,
 	}
@@ -53,7 +93,7 @@ func NewBuilder(actionType ActionType, push *model.PushContext, proxy *model.Pro
 		option.UseAuthenticated = true
 	}
 	b := builder.New(tdBundle, push, policies, option)
-	return &Builder{builder: b}
+	return &Builder{builder: b, actionType: actionType}
 }
 
 func (b *Builder) BuildTCP() []*listener.Filter {
@@ -69,10 +109,33 @@ func (b *Builder) BuildTCP() []*listener.Filter {
 	return b.tcpFilters
 }
 
-func (b *Builder) BuildHTTP(class networking.ListenerClass) []*hcm.HttpFilter {
+// BuildTCPForProtocol returns the TCP filters for a filter chain matching the connections of the given application
+// protocol. BuildTCP is used if the protocol is unknown.
+func (b *Builder) BuildTCPForProtocol(protocol string) []*listener.Filter {
 	if b == nil || b.builder == nil {
 		return nil
 	}
+	if protocol == "" {
+		return b.BuildTCP()
+	}
+	if filters, f := b.tcpFiltersByProtocol[protocol]; f {
+		return filters
+	}
+	if b.tcpFiltersByProtocol == nil {
+		b.tcpFiltersByProtocol = map[string][]*listener.Filter{}
+	}
+	filters := b.builder.BuildTCPForProtocol(protocol)
+	b.tcpFiltersByProtocol[protocol] = filters
+	return filters
+}
+
+func (b *Builder) BuildHTTP(class networking.ListenerClass) []*hcm.HttpFilter {
+	if b == nil {
+		return nil
+	}
+	if b.builder == nil && (!features.EnableAuthzECDS || b.actionType == Custom) {
+		return nil
+	}
 	if class == networking.ListenerClassSidecarOutbound {
 		// Only applies to inbound and gateways
 		return nil
@@ -81,7 +144,63 @@ func (b *Builder) BuildHTTP(class networking.ListenerClass) []*hcm.HttpFilter {
 		return b.httpFilters
 	}
 	b.httpBuilt = true
+	if features.EnableAuthzECDS {
+		for _, f := range b.extensionConfigs() {
+			b.httpFilters = append(b.httpFilters, extension.DiscoveryHTTPFilter(f.Name, f.TypedConfig.GetTypeUrl()))
+		}
+		return b.httpFilters
+	}
 	b.httpFilters = b.builder.BuildHTTP()
 
 	return b.httpFilters
 }
+
+// extensionConfigs returns the HTTP filters of the action type as the extension configs sent with ECDS.
+func (b *Builder) extensionConfigs() []*core.TypedExtensionConfig {
+	var out []*core.TypedExtensionConfig
+	if b.actionType == Custom {
+		if b.builder == nil {
+			return nil
+		}
+		for _, f := range b.builder.BuildHTTP() {
+			out = append(out, &core.TypedExtensionConfig{Name: customExtensionConfigName(f.Name), TypedConfig: f.GetTypedConfig()})
+		}
+		return out
+	}
+	for _, c := range localExtensionConfigs {
+		config := emptyRBAC
+		if b.builder != nil {
+			if filters := b.builder.BuildHTTPForAction(c.action); len(filters) > 0 {
+				config = filters[0].GetTypedConfig()
+			}
+		}
+		out = append(out, &core.TypedExtensionConfig{Name: c.name, TypedConfig: config})
+	}
+	return out
+}
+
+// ExtensionConfigurations returns the HTTP filters built from the authorization policies of the proxy with the
+// requested names, when they are sent with ECDS.
+func ExtensionConfigurations(push *model.PushContext, proxy *model.Proxy, names []string) []*core.TypedExtensionConfig {
+	if !features.EnableAuthzECDS {
+		return nil
+	}
+	requested := sets.New[string]()
+	for _, name := range names {
+		if strings.HasPrefix(name, extensionConfigPrefix) {
+			requested.Insert(name)
+		}
+	}
+	if len(requested) == 0 {
+		return nil
+	}
+	var out []*core.TypedExtensionConfig
+	for _, actionType := range []ActionType{Local, Custom} {
+		for _, c := range NewBuilder(actionType, push, proxy).extensionConfigs() {
+			if requested.Contains(c.Name) {
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
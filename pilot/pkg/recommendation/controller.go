@@ -0,0 +1,160 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommendation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/sets"
+	istiolog "istio.io/pkg/log"
+)
+
+var log = istiolog.RegisterScope("recommendation", "Sidecar recommendations", 0)
+
+// Options are the options of the Controller.
+type Options struct {
+	// PrometheusAddress is the address of the query API of the Prometheus the traffic is read from.
+	PrometheusAddress string
+	// Window is the duration of the traffic the Sidecars are recommended from.
+	Window time.Duration
+	// Interval is the interval between the recommendations.
+	Interval time.Duration
+	// Write writes the recommended Sidecars to the namespaces.
+	Write bool
+}
+
+// Controller recommends the default Sidecars of the namespaces without Sidecar, at each interval, from the traffic
+// of their sidecars reported to Prometheus. The namespaces without observed traffic are not recommended a Sidecar,
+// and the namespaces with a Sidecar which is not a recommended Sidecar have opted in, and are left untouched.
+type Controller struct {
+	client         kube.Client
+	prom           promv1.API
+	istioNamespace string
+	opts           Options
+
+	mu              sync.RWMutex
+	recommendations map[string]*clientnetworking.Sidecar
+}
+
+// NewController returns a controller recommending the Sidecars of the namespaces of the cluster of the client.
+func NewController(client kube.Client, istioNamespace string, opts Options) (*Controller, error) {
+	if opts.Interval <= 0 || opts.Window <= 0 {
+		return nil, fmt.Errorf("the interval %v and the window %v of the recommendations must be positive", opts.Interval, opts.Window)
+	}
+	promClient, err := api.NewClient(api.Config{Address: opts.PrometheusAddress})
+	if err != nil {
+		return nil, fmt.Errorf("invalid Prometheus address %q: %v", opts.PrometheusAddress, err)
+	}
+	return &Controller{
+		client:         client,
+		prom:           promv1.NewAPI(promClient),
+		istioNamespace: istioNamespace,
+		opts:           opts,
+	}, nil
+}
+
+// Run recommends the Sidecars at each interval until the stop channel is closed. The recommendations are then
+// cleared, as they are no longer kept up to date.
+func (c *Controller) Run(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	defer func() {
+		c.mu.Lock()
+		c.recommendations = nil
+		c.mu.Unlock()
+	}()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	for {
+		c.recommend(ctx)
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// recommend recommends the Sidecars of the namespaces, and writes them if enabled.
+func (c *Controller) recommend(ctx context.Context) {
+	namespaces, err := c.client.Kube().CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("failed to list the namespaces: %v", err)
+		return
+	}
+	sidecars, err := c.client.Istio().NetworkingV1alpha3().Sidecars(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Warnf("failed to list the Sidecars: %v", err)
+		return
+	}
+	optedIn := sets.New[string]()
+	for _, sc := range sidecars.Items {
+		if sc.Labels[Label] != "true" {
+			optedIn.Insert(sc.Namespace)
+		}
+	}
+
+	recommendations := map[string]*clientnetworking.Sidecar{}
+	for _, ns := range namespaces.Items {
+		if !recommendable(ns.Name, c.istioNamespace) || optedIn.Contains(ns.Name) {
+			continue
+		}
+		observed, err := ObservedDestinations(ctx, c.prom, ns.Name, c.opts.Window, 0)
+		if err != nil {
+			log.Warnf("failed to read the traffic of namespace %s: %v", ns.Name, err)
+			continue
+		}
+		if len(observed) == 0 {
+			continue
+		}
+		sc := Sidecar(ns.Name, c.istioNamespace, observed)
+		recommendations[ns.Name] = sc
+		if c.opts.Write {
+			if err := Write(ctx, c.client.Istio(), sc); err != nil {
+				log.Warnf("failed to write the recommended Sidecar: %v", err)
+				continue
+			}
+			log.Debugf("wrote the recommended Sidecar of namespace %s with %d egress hosts", ns.Name, len(sc.Spec.Egress[0].Hosts))
+		}
+	}
+
+	c.mu.Lock()
+	c.recommendations = recommendations
+	c.mu.Unlock()
+	log.Infof("recommended the Sidecars of %d namespaces", len(recommendations))
+}
+
+// Recommendations returns the recommended Sidecars by namespace.
+func (c *Controller) Recommendations() map[string]*clientnetworking.Sidecar {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.recommendations
+}
@@ -0,0 +1,148 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recommendation recommends the default Sidecars of the namespaces from the services called by their
+// sidecars, so that the namespaces which never opted in to Sidecars stop receiving the configuration of the whole
+// mesh.
+package recommendation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istioclient "istio.io/client-go/pkg/clientset/versioned"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// SidecarName is the name of the recommended Sidecars.
+	SidecarName = "default"
+	// Label labels the recommended Sidecars, so that they can be told from the Sidecars of the namespaces that
+	// opted in.
+	Label = "istio.io/recommended"
+)
+
+// metrics are the metrics reporting the services called by the sidecars.
+var metrics = []string{"istio_requests_total", "istio_tcp_connections_opened_total"}
+
+// ObservedDestinations returns the hosts of the services called by the sidecars of the namespace over the window,
+// at least minRate times per second, by the namespace of the service.
+func ObservedDestinations(ctx context.Context, promAPI promv1.API, ns string, window time.Duration, minRate float64,
+) (map[string]sets.String, error) {
+	out := map[string]sets.String{}
+	for _, metric := range metrics {
+		query := fmt.Sprintf(`sum(rate(%s{reporter="source",source_workload_namespace=%q}[%s])) by (destination_service_namespace, destination_service)`,
+			metric, ns, model.Duration(window))
+		val, _, err := promAPI.Query(ctx, query, time.Now())
+		if err != nil {
+			return nil, fmt.Errorf("query %q failed: %v", query, err)
+		}
+		vector, ok := val.(model.Vector)
+		if !ok {
+			return nil, errors.New("bad metric value type returned for query")
+		}
+		for _, sample := range vector {
+			if rate := float64(sample.Value); rate <= 0 || rate < minRate {
+				continue
+			}
+			svcNamespace := string(sample.Metric["destination_service_namespace"])
+			hostname := string(sample.Metric["destination_service"])
+			// The traffic not matching a service, such as the traffic to the PassthroughCluster, has no namespace.
+			if svcNamespace == "" || svcNamespace == "unknown" || hostname == "" || hostname == "unknown" {
+				continue
+			}
+			if out[svcNamespace] == nil {
+				out[svcNamespace] = sets.New[string]()
+			}
+			out[svcNamespace].Insert(hostname)
+		}
+	}
+	return out, nil
+}
+
+// Sidecar returns the default Sidecar of the namespace allowing the egress to the observed services and to the
+// services of the istio system namespace.
+func Sidecar(ns, istioNamespace string, observed map[string]sets.String) *clientnetworking.Sidecar {
+	hosts := sets.New(istioNamespace + "/*")
+	for svcNamespace, hostnames := range observed {
+		if svcNamespace == istioNamespace {
+			continue
+		}
+		if svcNamespace == ns {
+			svcNamespace = "."
+		}
+		for hostname := range hostnames {
+			hosts.Insert(svcNamespace + "/" + hostname)
+		}
+	}
+	sc := &clientnetworking.Sidecar{
+		TypeMeta: metav1.TypeMeta{APIVersion: gvk.Sidecar.GroupVersion(), Kind: gvk.Sidecar.Kind},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      SidecarName,
+			Namespace: ns,
+			Labels:    map[string]string{Label: "true"},
+		},
+	}
+	sc.Spec.Egress = []*networking.IstioEgressListener{{Hosts: sets.SortedList(hosts)}}
+	return sc
+}
+
+// Write creates or updates a recommended Sidecar. The default Sidecars of the namespaces that opted in are not
+// overwritten.
+func Write(ctx context.Context, client istioclient.Interface, sc *clientnetworking.Sidecar) error {
+	sidecars := client.NetworkingV1alpha3().Sidecars(sc.Namespace)
+	existing, err := sidecars.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list the Sidecars of namespace %s: %v", sc.Namespace, err)
+	}
+	for _, s := range existing.Items {
+		if s.Spec.WorkloadSelector == nil && s.Name != sc.Name {
+			return fmt.Errorf("namespace %s already has the default Sidecar %s", sc.Namespace, s.Name)
+		}
+	}
+
+	current, err := sidecars.Get(ctx, sc.Name, metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		_, err = sidecars.Create(ctx, sc, metav1.CreateOptions{})
+	case err == nil:
+		if current.Labels[Label] != "true" {
+			return fmt.Errorf("the Sidecar %s/%s is not a recommended Sidecar, and is not overwritten", sc.Namespace, sc.Name)
+		}
+		sc = sc.DeepCopy()
+		sc.ResourceVersion = current.ResourceVersion
+		_, err = sidecars.Update(ctx, sc, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write Sidecar %s/%s: %v", sc.Namespace, sc.Name, err)
+	}
+	return nil
+}
+
+// recommendable returns true if the namespace can be recommended a Sidecar, which excludes the istio system
+// namespace and the namespaces of Kubernetes.
+func recommendable(ns, istioNamespace string) bool {
+	return ns != istioNamespace && !strings.HasPrefix(ns, "kube-")
+}
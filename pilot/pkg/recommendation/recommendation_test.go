@@ -0,0 +1,148 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recommendation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	networking "istio.io/api/networking/v1alpha3"
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
+	istiofake "istio.io/client-go/pkg/clientset/versioned/fake"
+	"istio.io/istio/pkg/kube"
+)
+
+// fakePromAPI answers the queries of the Prometheus query API with canned vectors.
+type fakePromAPI struct {
+	promv1.API
+	vectors map[string]model.Vector
+}
+
+func (p fakePromAPI) Query(_ context.Context, query string, _ time.Time, _ ...promv1.Option) (model.Value, promv1.Warnings, error) {
+	return p.vectors[query], nil, nil
+}
+
+func destination(ns, host string, rate float64) *model.Sample {
+	return &model.Sample{
+		Metric: model.Metric{"destination_service_namespace": model.LabelValue(ns), "destination_service": model.LabelValue(host)},
+		Value:  model.SampleValue(rate),
+	}
+}
+
+func query(metric, ns string) string {
+	return `sum(rate(` + metric + `{reporter="source",source_workload_namespace="` + ns +
+		`"}[1d])) by (destination_service_namespace, destination_service)`
+}
+
+var bookinfoTraffic = fakePromAPI{vectors: map[string]model.Vector{
+	query("istio_requests_total", "bookinfo"): {
+		destination("bookinfo", "reviews.bookinfo.svc.cluster.local", 10),
+		destination("ratings", "ratings.ratings.svc.cluster.local", 1),
+		destination("bookinfo", "details.bookinfo.svc.cluster.local", 0.0001),
+		destination("unknown", "10.0.0.1", 5),
+	},
+	query("istio_tcp_connections_opened_total", "bookinfo"): {
+		destination("db", "mysql.db.svc.cluster.local", 0.5),
+		destination("istio-system", "zipkin.istio-system.svc.cluster.local", 1),
+	},
+}}
+
+func TestRecommendSidecar(t *testing.T) {
+	observed, err := ObservedDestinations(context.Background(), bookinfoTraffic, "bookinfo", 24*time.Hour, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := Sidecar("bookinfo", "istio-system", observed)
+	want := []string{"./reviews.bookinfo.svc.cluster.local", "db/mysql.db.svc.cluster.local", "istio-system/*", "ratings/ratings.ratings.svc.cluster.local"}
+	if got := sc.Spec.Egress[0].Hosts; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got hosts %v, want %v", got, want)
+	}
+	if sc.Name != SidecarName || sc.Labels[Label] != "true" {
+		t.Fatalf("unexpected Sidecar metadata %v", sc.ObjectMeta)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	ctx := context.Background()
+	client := istiofake.NewSimpleClientset()
+	if err := Write(ctx, client, Sidecar("bookinfo", "istio-system", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(ctx, client, Sidecar("bookinfo", "istio-system", nil)); err != nil {
+		t.Fatalf("expected the recommended Sidecar to be updated: %v", err)
+	}
+	if _, err := client.NetworkingV1alpha3().Sidecars("bookinfo").Get(ctx, SidecarName, metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	optedIn := &clientnetworking.Sidecar{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "reviews"}}
+	optedIn.Spec.Egress = []*networking.IstioEgressListener{{Hosts: []string{"./*"}}}
+	other := &clientnetworking.Sidecar{ObjectMeta: metav1.ObjectMeta{Name: "egress", Namespace: "ratings"}}
+	client = istiofake.NewSimpleClientset(optedIn, other)
+	if err := Write(ctx, client, Sidecar("reviews", "istio-system", nil)); err == nil {
+		t.Fatal("expected the Sidecar of a namespace that opted in not to be overwritten")
+	}
+	if err := Write(ctx, client, Sidecar("ratings", "istio-system", nil)); err == nil {
+		t.Fatal("expected a namespace with another default Sidecar to be rejected")
+	}
+}
+
+func TestControllerRecommend(t *testing.T) {
+	ctx := context.Background()
+	namespace := func(name string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	}
+	client := kube.NewFakeClient(namespace("bookinfo"), namespace("reviews"), namespace("quiet"), namespace("istio-system"))
+	optedIn := &clientnetworking.Sidecar{ObjectMeta: metav1.ObjectMeta{Name: "default", Namespace: "reviews"}}
+	if _, err := client.Istio().NetworkingV1alpha3().Sidecars("reviews").Create(ctx, optedIn, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	traffic := fakePromAPI{vectors: map[string]model.Vector{
+		query("istio_requests_total", "bookinfo"): bookinfoTraffic.vectors[query("istio_requests_total", "bookinfo")],
+		query("istio_requests_total", "reviews"):  {destination("ratings", "ratings.ratings.svc.cluster.local", 1)},
+	}}
+	c := &Controller{
+		client:         client,
+		prom:           traffic,
+		istioNamespace: "istio-system",
+		opts:           Options{Window: 24 * time.Hour, Interval: time.Hour, Write: true},
+	}
+
+	c.recommend(ctx)
+	recommendations := c.Recommendations()
+	if len(recommendations) != 1 || recommendations["bookinfo"] == nil {
+		t.Fatalf("expected only the namespace with traffic and without Sidecar to be recommended a Sidecar, got %v", recommendations)
+	}
+	written, err := client.Istio().NetworkingV1alpha3().Sidecars("bookinfo").Get(ctx, SidecarName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(written.Spec.Egress[0].Hosts, recommendations["bookinfo"].Spec.Egress[0].Hosts) {
+		t.Fatalf("expected the recommended Sidecar to be written, got %v", written.Spec.Egress)
+	}
+
+	// The written Sidecar does not opt the namespace in, so that it is kept up to date.
+	c.recommend(ctx)
+	if c.Recommendations()["bookinfo"] == nil {
+		t.Fatalf("expected the namespace with a recommended Sidecar to be recommended a Sidecar")
+	}
+}
@@ -24,8 +24,9 @@ import (
 // NewPolicyApplier returns the appropriate (policy) applier, depends on the versions of the policy exists
 // for the given service instance.
 func NewPolicyApplier(push *model.PushContext, namespace string, labels labels.Instance) authn.PolicyApplier {
-	return v1beta1.NewPolicyApplier(
+	return v1beta1.NewPolicyApplierWithDryRun(
 		push.AuthnPolicies.GetRootNamespace(),
 		push.AuthnPolicies.GetJwtPoliciesForWorkload(namespace, labels),
-		push.AuthnPolicies.GetPeerAuthenticationsForWorkload(namespace, labels), push)
+		push.AuthnPolicies.GetPeerAuthenticationsForWorkload(namespace, labels),
+		push.AuthnPolicies.GetDryRunPeerAuthenticationsForWorkload(namespace, labels), push)
 }
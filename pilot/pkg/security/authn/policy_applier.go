@@ -45,6 +45,10 @@ type PolicyApplier interface {
 	// GetMutualTLSModeForPort gets the mTLS mode for the given port. If there is no port level setting, it
 	// returns the inherited namespace/mesh level setting.
 	GetMutualTLSModeForPort(endpointPort uint32) model.MutualTLSMode
+
+	// GetDryRunMutualTLSModeForPort gets the mTLS mode for the given port if the dry-run policies were enforced.
+	// It returns MTLSUnknown if there are no dry-run policies.
+	GetDryRunMutualTLSModeForPort(endpointPort uint32) model.MutualTLSMode
 }
 
 // MTLSSettings describes the mTLS options for a filter chain
@@ -57,4 +61,7 @@ type MTLSSettings struct {
 	TCP *tlsv3.DownstreamTlsContext
 	// HTTP describes the tls context to use for HTTP filter chains
 	HTTP *tlsv3.DownstreamTlsContext
+	// DryRunStrict is true if the port is in PERMISSIVE mode, but a dry-run policy sets it to STRICT. The
+	// plaintext connections accepted on the port would be rejected if the dry-run policy was enforced.
+	DryRunStrict bool
 }
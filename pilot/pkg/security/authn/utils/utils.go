@@ -65,12 +65,23 @@ func BuildInboundTLS(mTLSMode model.MutualTLSMode, node *model.Proxy,
 		ctx.CommonTlsContext.AlpnProtocols = util.ALPNHttp
 	}
 
-	// Set Minimum TLS version to match the default client version and allowed strong cipher suites for sidecars.
-	ctx.CommonTlsContext.TlsParams = &tls.TlsParameters{
-		CipherSuites: SupportedCiphers,
+	if p := node.TLSProfile(); p != nil {
+		// The mesh minimum version still applies if it is stricter than the profile.
+		ctx.CommonTlsContext.TlsParams = authn_model.TLSParametersForProfile(p)
+		if minTLSVersion > ctx.CommonTlsContext.TlsParams.TlsMinimumProtocolVersion {
+			ctx.CommonTlsContext.TlsParams.TlsMinimumProtocolVersion = minTLSVersion
+			if minTLSVersion == tls.TlsParameters_TLSv1_3 {
+				ctx.CommonTlsContext.TlsParams.CipherSuites = nil
+			}
+		}
+	} else {
+		// Set Minimum TLS version to match the default client version and allowed strong cipher suites for sidecars.
+		ctx.CommonTlsContext.TlsParams = &tls.TlsParameters{
+			CipherSuites: SupportedCiphers,
+		}
+		ctx.CommonTlsContext.TlsParams.TlsMinimumProtocolVersion = minTLSVersion
+		ctx.CommonTlsContext.TlsParams.TlsMaximumProtocolVersion = tls.TlsParameters_TLSv1_3
 	}
-	ctx.CommonTlsContext.TlsParams.TlsMinimumProtocolVersion = minTLSVersion
-	ctx.CommonTlsContext.TlsParams.TlsMaximumProtocolVersion = tls.TlsParameters_TLSv1_3
 	authn_model.ApplyToCommonTLSContext(ctx.CommonTlsContext, node, []string{}, /*subjectAltNames*/
 		trustDomainAliases, ctx.RequireClientCertificate.Value)
 	return ctx
@@ -56,6 +56,10 @@ type v1beta1PolicyApplier struct {
 
 	consolidatedPeerPolicy *v1beta1.PeerAuthentication
 
+	// dryRunPeerPolicy is the effective PeerAuthentication if the dry-run policies were enforced, or nil
+	// if there are no dry-run policies.
+	dryRunPeerPolicy *v1beta1.PeerAuthentication
+
 	push *model.PushContext
 }
 
@@ -165,6 +169,8 @@ func (a *v1beta1PolicyApplier) InboundMTLSSettings(
 	return authn.MTLSSettings{
 		Port: endpointPort,
 		Mode: effectiveMTLSMode,
+		DryRunStrict: modeOverride == model.MTLSUnknown && effectiveMTLSMode == model.MTLSPermissive &&
+			a.GetDryRunMutualTLSModeForPort(endpointPort) == model.MTLSStrict,
 		TCP: authn_utils.BuildInboundTLS(effectiveMTLSMode, node, networking.ListenerProtocolTCP,
 			trustDomainAliases, minTLSVersion),
 		HTTP: authn_utils.BuildInboundTLS(effectiveMTLSMode, node, networking.ListenerProtocolHTTP,
@@ -203,6 +209,23 @@ func NewPolicyApplier(rootNamespace string,
 	}
 }
 
+// NewPolicyApplierWithDryRun returns new applier for v1beta1 authentication policies, which also reports
+// the plaintext connections the dry-run peer authentication policies would reject.
+func NewPolicyApplierWithDryRun(rootNamespace string,
+	jwtPolicies []*config.Config,
+	peerPolicies []*config.Config,
+	dryRunPeerPolicies []*config.Config,
+	push *model.PushContext,
+) authn.PolicyApplier {
+	applier := NewPolicyApplier(rootNamespace, jwtPolicies, peerPolicies, push).(*v1beta1PolicyApplier)
+	if len(dryRunPeerPolicies) > 0 {
+		// The dry-run policies are composed with the enforced ones, as they would be if they were enforced.
+		policies := append(append([]*config.Config{}, peerPolicies...), dryRunPeerPolicies...)
+		applier.dryRunPeerPolicy = ComposePeerAuthentication(rootNamespace, policies)
+	}
+	return applier
+}
+
 // convertToEnvoyJwtConfig converts a list of JWT rules into Envoy JWT filter config to enforce it.
 // Each rule is expected corresponding to one JWT issuer (provider).
 // The behavior of the filter should reject all requests with invalid token. On the other hand,
@@ -369,13 +392,24 @@ func (a *v1beta1PolicyApplier) PortLevelSetting() map[uint32]*v1beta1.PeerAuthen
 }
 
 func (a *v1beta1PolicyApplier) GetMutualTLSModeForPort(endpointPort uint32) model.MutualTLSMode {
-	if a.consolidatedPeerPolicy.PortLevelMtls != nil {
-		if portMtls, ok := a.consolidatedPeerPolicy.PortLevelMtls[endpointPort]; ok {
+	return getMutualTLSModeForPort(a.consolidatedPeerPolicy, endpointPort)
+}
+
+func (a *v1beta1PolicyApplier) GetDryRunMutualTLSModeForPort(endpointPort uint32) model.MutualTLSMode {
+	if a.dryRunPeerPolicy == nil {
+		return model.MTLSUnknown
+	}
+	return getMutualTLSModeForPort(a.dryRunPeerPolicy, endpointPort)
+}
+
+func getMutualTLSModeForPort(policy *v1beta1.PeerAuthentication, endpointPort uint32) model.MutualTLSMode {
+	if policy.PortLevelMtls != nil {
+		if portMtls, ok := policy.PortLevelMtls[endpointPort]; ok {
 			return getMutualTLSMode(portMtls)
 		}
 	}
 
-	return getMutualTLSMode(a.consolidatedPeerPolicy.Mtls)
+	return getMutualTLSMode(policy.Mtls)
 }
 
 // getMutualTLSMode returns the MutualTLSMode enum corresponding peer MutualTLS settings.
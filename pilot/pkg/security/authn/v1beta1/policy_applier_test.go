@@ -2186,3 +2186,46 @@ func TestGetMutualTLSMode(t *testing.T) {
 		})
 	}
 }
+
+func TestDryRunMutualTLSMode(t *testing.T) {
+	now := time.Now()
+	permissive := &config.Config{
+		Meta: config.Meta{Name: "default", Namespace: "foo", CreationTimestamp: now},
+		Spec: &v1beta1.PeerAuthentication{
+			Mtls: &v1beta1.PeerAuthentication_MutualTLS{Mode: v1beta1.PeerAuthentication_MutualTLS_PERMISSIVE},
+		},
+	}
+	dryRunStrict := &config.Config{
+		Meta: config.Meta{Name: "strict", Namespace: "foo", CreationTimestamp: now.Add(-time.Second)},
+		Spec: &v1beta1.PeerAuthentication{
+			Selector: &type_beta.WorkloadSelector{MatchLabels: map[string]string{"app": "foo"}},
+			Mtls:     &v1beta1.PeerAuthentication_MutualTLS{Mode: v1beta1.PeerAuthentication_MutualTLS_STRICT},
+			PortLevelMtls: map[uint32]*v1beta1.PeerAuthentication_MutualTLS{
+				9090: {Mode: v1beta1.PeerAuthentication_MutualTLS_PERMISSIVE},
+			},
+		},
+	}
+	node := &model.Proxy{Labels: map[string]string{"app": "foo"}, Metadata: &model.NodeMetadata{}}
+
+	applier := NewPolicyApplierWithDryRun("root-namespace", nil, []*config.Config{permissive}, nil, &model.PushContext{})
+	if got := applier.GetDryRunMutualTLSModeForPort(8080); got != model.MTLSUnknown {
+		t.Errorf("expected no dry-run mode without dry-run policies, got %v", got)
+	}
+	if applier.InboundMTLSSettings(8080, node, nil, authn.NoOverride).DryRunStrict {
+		t.Errorf("expected no dry-run without dry-run policies")
+	}
+
+	applier = NewPolicyApplierWithDryRun("root-namespace", nil, []*config.Config{permissive}, []*config.Config{dryRunStrict}, &model.PushContext{})
+	for port, want := range map[uint32]bool{8080: true, 9090: false} {
+		mtls := applier.InboundMTLSSettings(port, node, nil, authn.NoOverride)
+		if mtls.Mode != model.MTLSPermissive {
+			t.Errorf("port %d: expected the dry-run policy not to be enforced, got %v", port, mtls.Mode)
+		}
+		if mtls.DryRunStrict != want {
+			t.Errorf("port %d: got dry-run STRICT %v, want %v", port, mtls.DryRunStrict, want)
+		}
+	}
+	if applier.InboundMTLSSettings(8080, node, nil, model.MTLSStrict).DryRunStrict {
+		t.Errorf("expected no dry-run for overridden modes")
+	}
+}
@@ -62,6 +62,9 @@ type Builder struct {
 	allowPolicies []model.AuthorizationPolicy
 	auditPolicies []model.AuthorizationPolicy
 
+	// connectionProtocol is the application protocol of the TCP filter chain the filters are built for, if known.
+	connectionProtocol string
+
 	// logger emits logs about policies
 	logger *AuthzLogger
 }
@@ -122,6 +125,26 @@ func (b Builder) BuildHTTP() []*hcm.HttpFilter {
 	return filters
 }
 
+// BuildHTTPForAction returns the HTTP filters built from the policies of a single ALLOW, DENY or AUDIT (RBAC_LOG)
+// action, or nil if the action has no policies.
+func (b Builder) BuildHTTPForAction(action rbacpb.RBAC_Action) []*hcm.HttpFilter {
+	b.logger = &AuthzLogger{}
+	defer b.logger.Report()
+	var policies []model.AuthorizationPolicy
+	switch action {
+	case rbacpb.RBAC_LOG:
+		policies = b.auditPolicies
+	case rbacpb.RBAC_DENY:
+		policies = b.denyPolicies
+	case rbacpb.RBAC_ALLOW:
+		policies = b.allowPolicies
+	}
+	if configs := b.build(policies, action, false); configs != nil {
+		return configs.http
+	}
+	return nil
+}
+
 // BuildTCP returns the TCP filters built from the authorization policy.
 func (b Builder) BuildTCP() []*listener.Filter {
 	b.logger = &AuthzLogger{}
@@ -150,6 +173,13 @@ func (b Builder) BuildTCP() []*listener.Filter {
 	return filters
 }
 
+// BuildTCPForProtocol returns the TCP filters built from the authorization policy for a filter chain matching the
+// connections of the given application protocol, against which the connection.protocol conditions are evaluated.
+func (b Builder) BuildTCPForProtocol(protocol string) []*listener.Filter {
+	b.connectionProtocol = protocol
+	return b.BuildTCP()
+}
+
 type builtConfigs struct {
 	http []*hcm.HttpFilter
 	tcp  []*listener.Filter
@@ -223,6 +253,7 @@ func (b Builder) build(policies []model.AuthorizationPolicy, action rbacpb.RBAC_
 				continue
 			}
 			m.MigrateTrustDomain(b.trustDomainBundle)
+			m.ResolveConnectionProtocol(b.connectionProtocol)
 			if len(b.trustDomainBundle.TrustDomains) > 1 {
 				b.logger.AppendDebugf("patched source principal with trust domain aliases %v", b.trustDomainBundle.TrustDomains)
 			}
@@ -70,6 +70,29 @@ func (connSNIGenerator) principal(_, _ string, _ bool, _ bool) (*rbacpb.Principa
 	return nil, fmt.Errorf("unimplemented")
 }
 
+type connProtocolGenerator struct {
+	// protocol is the application protocol of the TCP filter chain, unknown if empty.
+	protocol string
+}
+
+func (g connProtocolGenerator) permission(_, value string, forTCP bool) (*rbacpb.Permission, error) {
+	protocol := g.protocol
+	if !forTCP {
+		protocol = ConnectionProtocolHTTP
+	}
+	if protocol == "" {
+		return nil, fmt.Errorf("connection protocol is unknown on this filter chain")
+	}
+	if strings.EqualFold(value, protocol) {
+		return permissionAny(), nil
+	}
+	return permissionNot(permissionAny()), nil
+}
+
+func (connProtocolGenerator) principal(_, _ string, _ bool, _ bool) (*rbacpb.Principal, error) {
+	return nil, fmt.Errorf("unimplemented")
+}
+
 type envoyFilterGenerator struct{}
 
 func (envoyFilterGenerator) permission(key, value string, _ bool) (*rbacpb.Permission, error) {
@@ -58,6 +58,36 @@ func TestGenerator(t *testing.T) {
          requestedServerName:
           exact: exact.com`),
 		},
+		{
+			name:   "connProtocolGenerator-match",
+			g:      connProtocolGenerator{protocol: ConnectionProtocolTLS},
+			value:  "TLS",
+			forTCP: true,
+			want: yamlPermission(t, `
+         any: true`),
+		},
+		{
+			name:   "connProtocolGenerator-mismatch",
+			g:      connProtocolGenerator{protocol: ConnectionProtocolMySQL},
+			value:  "tls",
+			forTCP: true,
+			want: yamlPermission(t, `
+         notRule:
+          any: true`),
+		},
+		{
+			name:  "connProtocolGenerator-http",
+			g:     connProtocolGenerator{},
+			value: "http",
+			want: yamlPermission(t, `
+         any: true`),
+		},
+		{
+			name:   "connProtocolGenerator-unknown",
+			g:      connProtocolGenerator{},
+			value:  "tls",
+			forTCP: true,
+		},
 		{
 			name:  "envoyFilterGenerator-string",
 			g:     envoyFilterGenerator{},
@@ -44,6 +44,7 @@ const (
 	attrDestIP           = "destination.ip"              // supports both single ip and cidr, e.g. "10.1.2.3" or "10.1.0.0/16".
 	attrDestPort         = "destination.port"            // must be in the range [0, 65535].
 	attrConnSNI          = "connection.sni"              // server name indication, e.g. "www.example.com".
+	attrConnProtocol     = "connection.protocol"         // application protocol detected on the connection, e.g. "tls".
 	attrEnvoyFilter      = "experimental.envoy.filters." // an experimental attribute for checking Envoy Metadata directly.
 
 	// Internal names used to generate corresponding Envoy matcher.
@@ -52,6 +53,16 @@ const (
 	hostHeader   = ":authority"
 )
 
+// Application protocols of the connections, matched by the connection.protocol attribute.
+const (
+	ConnectionProtocolTLS   = "tls"
+	ConnectionProtocolHTTP  = "http"
+	ConnectionProtocolMySQL = "mysql"
+	ConnectionProtocolMongo = "mongo"
+	ConnectionProtocolRedis = "redis"
+	ConnectionProtocolTCP   = "tcp"
+)
+
 type rule struct {
 	key       string
 	values    []string
@@ -87,6 +98,8 @@ func New(r *authzpb.Rule) (*Model, error) {
 			basePermission.appendLast(destPortGenerator{}, k, when.Values, when.NotValues)
 		case k == attrConnSNI:
 			basePermission.appendLast(connSNIGenerator{}, k, when.Values, when.NotValues)
+		case k == attrConnProtocol:
+			basePermission.appendLast(connProtocolGenerator{}, k, when.Values, when.NotValues)
 		case strings.HasPrefix(k, attrEnvoyFilter):
 			basePermission.appendLast(envoyFilterGenerator{}, k, when.Values, when.NotValues)
 		case k == attrSrcIP:
@@ -160,6 +173,21 @@ func (m *Model) MigrateTrustDomain(tdBundle trustdomain.Bundle) {
 	}
 }
 
+// ResolveConnectionProtocol sets the application protocol of the connections the model is generated for. Envoy
+// selects the filter chain of a connection by its detected protocol, so the connection.protocol conditions are
+// evaluated when generating the config of each filter chain.
+func (m *Model) ResolveConnectionProtocol(protocol string) {
+	for _, p := range m.permissions {
+		for i, r := range p.rules {
+			if r.key == attrConnProtocol {
+				resolved := *r
+				resolved.g = connProtocolGenerator{protocol: protocol}
+				p.rules[i] = &resolved
+			}
+		}
+	}
+}
+
 // Generate generates the Envoy RBAC config from the model.
 func (m Model) Generate(forTCP bool, useAuthenticated bool, action rbacpb.RBAC_Action) (*rbacpb.Policy, error) {
 	var permissions []*rbacpb.Permission
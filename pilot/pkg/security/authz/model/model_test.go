@@ -283,6 +283,67 @@ when:
 	}
 }
 
+func TestModel_ResolveConnectionProtocol(t *testing.T) {
+	rule := yamlRule(t, `
+to:
+- operation:
+    ports: ["5432"]
+when:
+- key: connection.protocol
+  values: ["tls"]
+- key: connection.sni
+  values: ["*.internal.example"]
+`)
+	cases := []struct {
+		name     string
+		protocol string
+		matched  bool
+	}{
+		{
+			name:     "matched",
+			protocol: ConnectionProtocolTLS,
+			matched:  true,
+		},
+		{
+			name:     "not-matched",
+			protocol: ConnectionProtocolMySQL,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := New(rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.ResolveConnectionProtocol(tc.protocol)
+			p, err := m.Generate(true, false, rbacpb.RBAC_ALLOW)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotYaml, err := protomarshal.ToYAML(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"5432", ".internal.example"} {
+				if !strings.Contains(gotYaml, want) {
+					t.Errorf("got:\n%s but not found %s", gotYaml, want)
+				}
+			}
+			if got := strings.Contains(gotYaml, "notRule"); got == tc.matched {
+				t.Errorf("got:\n%s but want matched %v", gotYaml, tc.matched)
+			}
+		})
+	}
+
+	m, err := New(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Generate(true, false, rbacpb.RBAC_ALLOW); err == nil {
+		t.Errorf("expected an error without the connection protocol")
+	}
+}
+
 func yamlRule(t *testing.T, yaml string) *authzpb.Rule {
 	t.Helper()
 	p := &authzpb.Rule{}
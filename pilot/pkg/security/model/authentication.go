@@ -23,6 +23,7 @@ import (
 	"google.golang.org/protobuf/types/known/durationpb"
 
 	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/model/credentials"
 	"istio.io/istio/pilot/pkg/networking/util"
@@ -249,6 +250,19 @@ func ApplyToCommonTLSContext(tlsContext *tls.CommonTlsContext, proxy *model.Prox
 	}
 }
 
+// ApplyDualWorkloadCertificates adds the ECDSA workload certificate to a server TLS context serving the default
+// workload certificate, if the agent of the proxy issues one. Envoy then serves the certificate supported by the client.
+func ApplyDualWorkloadCertificates(tlsContext *tls.CommonTlsContext, proxy *model.Proxy) {
+	if !features.EnableDualWorkloadCerts || proxy.Metadata == nil || proxy.Metadata.Raw[security.DualWorkloadCertMetaDataName] != "true" {
+		return
+	}
+	if len(tlsContext.TlsCertificateSdsSecretConfigs) != 1 || tlsContext.TlsCertificateSdsSecretConfigs[0].Name != SDSDefaultResourceName {
+		return
+	}
+	tlsContext.TlsCertificateSdsSecretConfigs = append(tlsContext.TlsCertificateSdsSecretConfigs,
+		ConstructSdsSecretConfig(security.WorkloadECDSAKeyCertResourceName))
+}
+
 // ApplyCustomSDSToClientCommonTLSContext applies the customized sds to CommonTlsContext
 // Used for building upstream TLS context for egress gateway's TLS/mTLS origination
 func ApplyCustomSDSToClientCommonTLSContext(tlsContext *tls.CommonTlsContext,
@@ -26,10 +26,12 @@ import (
 	"google.golang.org/protobuf/testing/protocmp"
 	"google.golang.org/protobuf/types/known/durationpb"
 
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/model/credentials"
 	"istio.io/istio/pkg/security"
 	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/test"
 )
 
 func TestConstructSdsSecretConfig(t *testing.T) {
@@ -497,3 +499,59 @@ func TestConstructSdsSecretConfigForCredential(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyDualWorkloadCertificates(t *testing.T) {
+	dual := &model.Proxy{Metadata: &model.NodeMetadata{Raw: map[string]any{security.DualWorkloadCertMetaDataName: "true"}}}
+	cases := []struct {
+		name    string
+		enabled bool
+		proxy   *model.Proxy
+		certs   []string
+		want    []string
+	}{
+		{
+			name:  "disabled",
+			proxy: dual,
+			certs: []string{SDSDefaultResourceName},
+			want:  []string{SDSDefaultResourceName},
+		},
+		{
+			name:    "enabled",
+			enabled: true,
+			proxy:   dual,
+			certs:   []string{SDSDefaultResourceName},
+			want:    []string{SDSDefaultResourceName, security.WorkloadECDSAKeyCertResourceName},
+		},
+		{
+			name:    "agent without ECDSA certificate",
+			enabled: true,
+			proxy:   &model.Proxy{Metadata: &model.NodeMetadata{}},
+			certs:   []string{SDSDefaultResourceName},
+			want:    []string{SDSDefaultResourceName},
+		},
+		{
+			name:    "file mounted certificate",
+			enabled: true,
+			proxy:   dual,
+			certs:   []string{"file-cert:/etc/certs/cert-chain.pem~/etc/certs/key.pem"},
+			want:    []string{"file-cert:/etc/certs/cert-chain.pem~/etc/certs/key.pem"},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			test.SetForTest(t, &features.EnableDualWorkloadCerts, tt.enabled)
+			ctx := &auth.CommonTlsContext{}
+			for _, c := range tt.certs {
+				ctx.TlsCertificateSdsSecretConfigs = append(ctx.TlsCertificateSdsSecretConfigs, ConstructSdsSecretConfig(c))
+			}
+			ApplyDualWorkloadCertificates(ctx, tt.proxy)
+			got := []string{}
+			for _, c := range ctx.TlsCertificateSdsSecretConfigs {
+				got = append(got, c.Name)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Errorf("got certificates %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	tls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+
+	"istio.io/istio/pkg/config/security"
+)
+
+// TLSParametersForProfile returns the Envoy TLS parameters enforcing the given profile.
+func TLSParametersForProfile(p *security.TLSProfile) *tls.TlsParameters {
+	return &tls.TlsParameters{
+		TlsMinimumProtocolVersion: tls.TlsParameters_TlsProtocol(p.MinVersion),
+		TlsMaximumProtocolVersion: tls.TlsParameters_TlsProtocol(p.MaxVersion),
+		CipherSuites:              append([]string(nil), p.CipherSuites...),
+		EcdhCurves:                append([]string(nil), p.EcdhCurves...),
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustdomain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// Transition is a trust domain the mesh is migrating from. Until the end of the transition window, it is treated as
+// an alias of the trust domain of the mesh, and workload certificates carry the identities of both trust domains.
+type Transition struct {
+	TrustDomain string
+	// Until is the end of the transition window. The transition has no end if it is zero.
+	Until time.Time
+}
+
+// Active returns true if the transition window is not over.
+func (t Transition) Active(now time.Time) bool {
+	return t.Until.IsZero() || now.Before(t.Until)
+}
+
+// ParseTransitions parses a comma separated list of <trust domain>[=<RFC 3339 end of the transition window>].
+func ParseTransitions(s string) ([]Transition, error) {
+	var out []Transition
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		td, until, hasUntil := strings.Cut(entry, "=")
+		t := Transition{TrustDomain: td}
+		if hasUntil {
+			end, err := time.Parse(time.RFC3339, until)
+			if err != nil {
+				return nil, fmt.Errorf("invalid end of the transition window of trust domain %q: %v", td, err)
+			}
+			t.Until = end
+		}
+		if t.TrustDomain == "" {
+			return nil, fmt.Errorf("invalid trust domain transition %q: empty trust domain", entry)
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+var transitions = func() []Transition {
+	t, err := ParseTransitions(features.TrustDomainTransitions)
+	if err != nil {
+		authzLog.Errorf("ignoring trust domain transitions: %v", err)
+		return nil
+	}
+	return t
+}()
+
+// Transitions returns the trust domain transitions configured for istiod.
+func Transitions() []Transition {
+	return transitions
+}
+
+// ActiveTransitions returns the trust domains of the transitions whose window is not over.
+func ActiveTransitions(now time.Time) []string {
+	return activeTransitions(transitions, now)
+}
+
+func activeTransitions(transitions []Transition, now time.Time) []string {
+	var out []string
+	for _, t := range transitions {
+		if t.Active(now) {
+			out = append(out, t.TrustDomain)
+		}
+	}
+	return out
+}
+
+// Aliases returns the trust domain aliases of the mesh, including the trust domains in transition.
+func Aliases(mesh *meshconfig.MeshConfig) []string {
+	active := ActiveTransitions(time.Now())
+	if len(active) == 0 {
+		return mesh.GetTrustDomainAliases()
+	}
+	out := append([]string{}, mesh.GetTrustDomainAliases()...)
+	for _, td := range active {
+		if td != mesh.GetTrustDomain() && !isKeyInList(td, out) {
+			out = append(out, td)
+		}
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trustdomain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTransitions(t *testing.T) {
+	end := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in      string
+		want    []Transition
+		wantErr bool
+	}{
+		{in: ""},
+		{in: "old.example.com", want: []Transition{{TrustDomain: "old.example.com"}}},
+		{
+			in:   "old.example.com=2024-01-31T00:00:00Z, older.example.com",
+			want: []Transition{{TrustDomain: "old.example.com", Until: end}, {TrustDomain: "older.example.com"}},
+		},
+		{in: "old.example.com=tomorrow", wantErr: true},
+		{in: "=2024-01-31T00:00:00Z", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseTransitions(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestActiveTransitions(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	transitions := []Transition{
+		{TrustDomain: "ended.example.com", Until: now.Add(-time.Hour)},
+		{TrustDomain: "ending.example.com", Until: now.Add(time.Hour)},
+		{TrustDomain: "open.example.com"},
+	}
+	got := activeTransitions(transitions, now)
+	if want := []string{"ending.example.com", "open.example.com"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
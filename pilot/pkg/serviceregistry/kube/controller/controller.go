@@ -37,6 +37,7 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/serviceregistry/util/workloadinstances"
+	"istio.io/istio/pilot/pkg/trustbundle"
 	"istio.io/istio/pilot/pkg/util/informermetric"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/host"
@@ -144,6 +145,10 @@ type Options struct {
 
 	// If meshConfig.DiscoverySelectors are specified, the DiscoveryNamespacesFilter tracks the namespaces this controller watches.
 	DiscoveryNamespacesFilter filter.DiscoveryNamespacesFilter
+
+	// TrustBundle holds the federated roots distributed to the namespaces when PILOT_ENABLE_TRUST_BUNDLE_DISTRIBUTION
+	// is enabled.
+	TrustBundle *trustbundle.TrustBundle
 }
 
 // DetectEndpointMode determines whether to use Endpoints or EndpointSlice based on the
@@ -197,6 +202,7 @@ type kubernetesNode struct {
 // controllerInterface is a simplified interface for the Controller used for testing.
 type controllerInterface interface {
 	getPodLocality(pod *v1.Pod) string
+	podNetwork(pod *v1.Pod) network.ID
 	Network(endpointIP string, labels labels.Instance) network.ID
 	Cluster() cluster.ID
 }
@@ -478,7 +484,7 @@ func (c *Controller) networkFromSystemNamespace() network.ID {
 }
 
 func (c *Controller) Network(endpointIP string, labels labels.Instance) network.ID {
-	// 1. check the pod/workloadEntry label
+	// 1. check the pod/workloadEntry label, which is set from the node of the pod with dynamic networks
 	if nw := labels[label.TopologyNetwork.Name]; nw != "" {
 		return network.ID(nw)
 	}
@@ -623,6 +629,9 @@ func (c *Controller) onNodeEvent(obj any, event model.Event) error {
 			return nil
 		}
 	}
+	if features.EnableDynamicNetworks {
+		c.updateNodeNetwork(node, event)
+	}
 	var updatedNeeded bool
 	if event == model.EventDelete {
 		updatedNeeded = true
@@ -852,6 +861,9 @@ func (c *Controller) Run(stop <-chan struct{}) {
 	log.Infof("kube controller for %s synced after %v", c.opts.ClusterID, time.Since(st))
 	// after the in-order sync we can start processing the queue
 	c.queue.Run(stop)
+	if esc, ok := c.endpoints.(*endpointSliceController); ok {
+		esc.stopDrainResyncs()
+	}
 	log.Infof("Controller terminated")
 }
 
@@ -910,6 +922,14 @@ func (c *Controller) getPodLocality(pod *v1.Pod) string {
 	return region + "/" + zone + "/" + subzone // Format: "%s/%s/%s"
 }
 
+// podNetwork returns the network of the node of a pod without network label, if dynamic networks are enabled.
+func (c *Controller) podNetwork(pod *v1.Pod) network.ID {
+	if pod.Labels[label.TopologyNetwork.Name] != "" {
+		return ""
+	}
+	return c.networkForNode(pod.Spec.NodeName)
+}
+
 // InstancesByPort implements a service catalog operation
 func (c *Controller) InstancesByPort(svc *model.Service, reqSvcPort int, labels labels.Instance) []*model.ServiceInstance {
 	// First get k8s standard service instances and the workload entry instances
@@ -1371,20 +1391,27 @@ func (c *Controller) getProxyServiceInstancesByPod(pod *v1.Pod,
 func (c *Controller) GetProxyWorkloadLabels(proxy *model.Proxy) labels.Instance {
 	pod := c.pods.getPodByProxy(proxy)
 	if pod != nil {
-		if _, exist := pod.Labels[model.LocalityLabel]; exist {
-			return pod.Labels
+		var locality string
+		if _, exist := pod.Labels[model.LocalityLabel]; !exist {
+			locality = c.getPodLocality(pod)
 		}
-		locality := c.getPodLocality(pod)
-		if locality == "" {
+		nw := c.podNetwork(pod)
+		if locality == "" && nw == "" {
 			return pod.Labels
 		}
-		out := make(map[string]string, len(pod.Labels)+1)
+		out := make(map[string]string, len(pod.Labels)+2)
 		for k, v := range pod.Labels {
 			out[k] = v
 		}
 		// Add locality labels to support locality Load balancing for proxy without service instances.
 		// As this may contain node topology labels, which could not be got from aggregator controller
-		out[model.LocalityLabel] = locality
+		if locality != "" {
+			out[model.LocalityLabel] = locality
+		}
+		// Likewise for the network of the node of the pod, with dynamic networks.
+		if nw != "" {
+			out[label.TopologyNetwork.Name] = nw.String()
+		}
 		return out
 	}
 	return nil
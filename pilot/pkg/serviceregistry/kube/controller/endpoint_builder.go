@@ -33,6 +33,7 @@ type EndpointBuilder struct {
 
 	labels         labels.Instance
 	metaNetwork    network.ID
+	nodeNetwork    network.ID
 	serviceAccount string
 	locality       model.Locality
 	tlsMode        string
@@ -48,9 +49,11 @@ type EndpointBuilder struct {
 
 func NewEndpointBuilder(c controllerInterface, pod *v1.Pod) *EndpointBuilder {
 	locality, sa, namespace, hostname, subdomain, ip := "", "", "", "", "", ""
+	var nodeNetwork network.ID
 	var podLabels labels.Instance
 	if pod != nil {
 		locality = c.getPodLocality(pod)
+		nodeNetwork = c.podNetwork(pod)
 		sa = kube.SecureNamingSAN(pod)
 		podLabels = pod.Labels
 		namespace = pod.Namespace
@@ -71,6 +74,7 @@ func NewEndpointBuilder(c controllerInterface, pod *v1.Pod) *EndpointBuilder {
 			Label:     locality,
 			ClusterID: c.Cluster(),
 		},
+		nodeNetwork:  nodeNetwork,
 		tlsMode:      kube.PodTLSMode(pod),
 		workloadName: dm.Name,
 		namespace:    namespace,
@@ -142,6 +146,10 @@ func (b *EndpointBuilder) endpointNetwork(endpointIP string) network.ID {
 	if b.metaNetwork != "" {
 		return b.metaNetwork
 	}
+	// With dynamic networks, pods are in the network of their node.
+	if b.nodeNetwork != "" {
+		return b.nodeNetwork
+	}
 
 	return b.controller.Network(endpointIP, b.labels)
 }
@@ -236,15 +236,20 @@ func TestNewEndpointBuilderFromMetadataTopologyLabels(t *testing.T) {
 var _ controllerInterface = testController{}
 
 type testController struct {
-	locality string
-	cluster  cluster2.ID
-	network  network.ID
+	locality    string
+	cluster     cluster2.ID
+	network     network.ID
+	nodeNetwork network.ID
 }
 
 func (c testController) getPodLocality(*v1.Pod) string {
 	return c.locality
 }
 
+func (c testController) podNetwork(*v1.Pod) network.ID {
+	return c.nodeNetwork
+}
+
 func (c testController) Network(ip string, instance labels.Instance) network.ID {
 	return c.network
 }
@@ -16,7 +16,9 @@ package controller
 
 import (
 	"sync"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/api/discovery/v1"
 	"k8s.io/api/discovery/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -42,6 +44,18 @@ type endpointSliceController struct {
 	kubeEndpoints
 	endpointCache *endpointSliceCache
 	useV1Resource bool
+
+	// drainResyncs are the times the slices with draining endpoints are processed again to remove them, keyed by
+	// slice. They are no longer scheduled once the controller stops.
+	drainResyncsMu      sync.Mutex
+	drainResyncs        map[string]drainResync
+	drainResyncsStopped bool
+}
+
+// drainResync is the timer processing a slice again at the drain deadline of its endpoints.
+type drainResync struct {
+	deadline time.Time
+	timer    *time.Timer
 }
 
 var _ kubeEndpointsController = &endpointSliceController{}
@@ -73,6 +87,7 @@ func newEndpointSliceController(c *Controller) *endpointSliceController {
 		},
 		useV1Resource: useV1Resource,
 		endpointCache: newEndpointSliceCache(),
+		drainResyncs:  map[string]drainResync{},
 	}
 	c.registerHandlers(filteredInformer, "EndpointSlice", out.onEvent, nil)
 	return out
@@ -242,8 +257,10 @@ func (esc *endpointSliceController) updateEndpointCacheForSlice(hostName host.Na
 			e.Conditions.Serving != nil &&
 			*e.Conditions.Serving &&
 			!*e.Conditions.Ready
+		// The endpoints of the pods being deleted may be drained before their removal.
+		drainable := features.EndpointDrainDuration > 0 && e.Conditions.Ready != nil && !*e.Conditions.Ready
 		if !features.SendUnhealthyEndpoints.Load() {
-			if !draining && e.Conditions.Ready != nil && !*e.Conditions.Ready {
+			if !draining && !drainable && e.Conditions.Ready != nil && !*e.Conditions.Ready {
 				// Ignore not ready endpoints. Draining endpoints are tracked, but not returned
 				// except for persistent-session clusters.
 				continue
@@ -255,6 +272,15 @@ func (esc *endpointSliceController) updateEndpointCacheForSlice(hostName host.Na
 			if pod == nil && expectedPod {
 				continue
 			}
+			var drainDeadline time.Time
+			if drainable {
+				drainDeadline = podDrainDeadline(pod, time.Now())
+				if !drainDeadline.IsZero() {
+					esc.scheduleDrainResync(kube.KeyFunc(slice.Name, slice.Namespace), drainDeadline)
+				} else if !draining && !features.SendUnhealthyEndpoints.Load() {
+					continue
+				}
+			}
 			builder := NewEndpointBuilder(esc.c, pod)
 			// EDS and ServiceEntry use name for service port - ADS will need to map to numbers.
 			for _, port := range slice.Ports() {
@@ -270,6 +296,9 @@ func (esc *endpointSliceController) updateEndpointCacheForSlice(hostName host.Na
 				istioEndpoint := builder.buildIstioEndpoint(a, portNum, portName, discoverabilityPolicy)
 				if ready {
 					istioEndpoint.HealthStatus = model.Healthy
+				} else if !drainDeadline.IsZero() {
+					istioEndpoint.HealthStatus = model.Draining
+					istioEndpoint.DrainDeadline = drainDeadline
 				} else if draining {
 					istioEndpoint.HealthStatus = model.Draining
 				} else {
@@ -282,6 +311,73 @@ func (esc *endpointSliceController) updateEndpointCacheForSlice(hostName host.Na
 	esc.endpointCache.Update(hostName, slice.Name, endpoints)
 }
 
+// podDrainDeadline returns the time until which the endpoints of a pod being deleted are drained, zero if the pod
+// is not being deleted or the deadline has passed. The endpoints are drained for PILOT_ENDPOINT_DRAIN_DURATION
+// after the deletion of the pod, at most until the end of its deletion grace period.
+func podDrainDeadline(pod *corev1.Pod, now time.Time) time.Time {
+	if pod == nil || pod.DeletionTimestamp == nil {
+		return time.Time{}
+	}
+	end := pod.DeletionTimestamp.Time
+	deadline := end
+	if pod.DeletionGracePeriodSeconds != nil {
+		deadline = end.Add(-time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second).Add(features.EndpointDrainDuration)
+		if deadline.After(end) {
+			deadline = end
+		}
+	}
+	if !now.Before(deadline) {
+		return time.Time{}
+	}
+	return deadline
+}
+
+// scheduleDrainResync processes the slice again at the drain deadline of its endpoints, to remove them.
+func (esc *endpointSliceController) scheduleDrainResync(key string, deadline time.Time) {
+	esc.drainResyncsMu.Lock()
+	defer esc.drainResyncsMu.Unlock()
+	if esc.drainResyncsStopped {
+		return
+	}
+	if r, f := esc.drainResyncs[key]; f {
+		if !deadline.Before(r.deadline) {
+			// The slice is processed again before this deadline, which is then scheduled again.
+			return
+		}
+		r.timer.Stop()
+	}
+	timer := time.AfterFunc(time.Until(deadline), func() {
+		esc.drainResyncsMu.Lock()
+		r, f := esc.drainResyncs[key]
+		if !f || !r.deadline.Equal(deadline) {
+			// Stopped, or replaced by an earlier deadline.
+			esc.drainResyncsMu.Unlock()
+			return
+		}
+		delete(esc.drainResyncs, key)
+		esc.drainResyncsMu.Unlock()
+		esc.c.queue.Push(func() error {
+			slice, exists, err := esc.informer.GetIndexer().GetByKey(key)
+			if err != nil || !exists {
+				return err
+			}
+			return esc.onEvent(slice, model.EventUpdate)
+		})
+	})
+	esc.drainResyncs[key] = drainResync{deadline: deadline, timer: timer}
+}
+
+// stopDrainResyncs stops the scheduled drain resyncs, once the controller stopped.
+func (esc *endpointSliceController) stopDrainResyncs() {
+	esc.drainResyncsMu.Lock()
+	defer esc.drainResyncsMu.Unlock()
+	esc.drainResyncsStopped = true
+	for key, r := range esc.drainResyncs {
+		r.timer.Stop()
+		delete(esc.drainResyncs, key)
+	}
+}
+
 func (esc *endpointSliceController) buildIstioEndpointsWithService(name, namespace string, hostName host.Name, updateCache bool) []*model.IstioEndpoint {
 	esLabelSelector := endpointSliceSelectorForService(name)
 	slices, err := esc.listSlices(namespace, esLabelSelector)
@@ -380,14 +476,40 @@ type endpointKey struct {
 	port string
 }
 
+func keyForEndpoint(ep *model.IstioEndpoint) endpointKey {
+	return endpointKey{ep.Address, ep.ServicePortName}
+}
+
+// endpointSliceCache indexes the endpoints of the slices of each service, so that an update of a slice only
+// changes the endpoints of that slice instead of merging the endpoints of all the slices of the service again.
 type endpointSliceCache struct {
-	mu                         sync.RWMutex
-	endpointsByServiceAndSlice map[host.Name]map[string][]*model.IstioEndpoint
+	mu                 sync.Mutex
+	endpointsByService map[host.Name]*serviceEndpoints
+}
+
+// serviceEndpoints are the deduplicated endpoints of the slices of a service.
+type serviceEndpoints struct {
+	// bySlice are the endpoints of each slice.
+	bySlice map[string]map[endpointKey]*model.IstioEndpoint
+	// index locates the endpoint of each key in endpoints.
+	index map[endpointKey]*endpointRef
+	// endpoints are the endpoints of all the slices, without duplicates. They are returned by Get without being
+	// copied, and copied before being changed again once shared.
+	endpoints []*model.IstioEndpoint
+	shared    bool
+}
+
+// endpointRef is the position of an endpoint in the endpoints of a service, and the slice it comes from when
+// several slices have it.
+type endpointRef struct {
+	pos    int
+	slice  string
+	slices int
 }
 
 func newEndpointSliceCache() *endpointSliceCache {
 	out := &endpointSliceCache{
-		endpointsByServiceAndSlice: make(map[host.Name]map[string][]*model.IstioEndpoint),
+		endpointsByService: make(map[host.Name]*serviceEndpoints),
 	}
 	return out
 }
@@ -395,58 +517,140 @@ func newEndpointSliceCache() *endpointSliceCache {
 func (e *endpointSliceCache) Update(hostname host.Name, slice string, endpoints []*model.IstioEndpoint) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	if len(endpoints) == 0 {
-		delete(e.endpointsByServiceAndSlice[hostname], slice)
-	}
-	if _, f := e.endpointsByServiceAndSlice[hostname]; !f {
-		e.endpointsByServiceAndSlice[hostname] = make(map[string][]*model.IstioEndpoint)
+	svc, f := e.endpointsByService[hostname]
+	if !f {
+		svc = &serviceEndpoints{
+			bySlice: make(map[string]map[endpointKey]*model.IstioEndpoint),
+			index:   make(map[endpointKey]*endpointRef),
+		}
+		e.endpointsByService[hostname] = svc
 	}
-	// We will always overwrite. A conflict here means an endpoint is transitioning
-	// from one slice to another See
-	// https://github.com/kubernetes/website/blob/master/content/en/docs/concepts/services-networking/endpoint-slices.md#duplicate-endpoints
-	// In this case, we can always assume and update is fresh, although older slices
-	// we have not gotten updates may be stale; therefor we always take the new
-	// update.
-	e.endpointsByServiceAndSlice[hostname][slice] = endpoints
+	svc.update(slice, endpoints)
 }
 
 func (e *endpointSliceCache) Delete(hostname host.Name, slice string) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
-
-	delete(e.endpointsByServiceAndSlice[hostname], slice)
-	if len(e.endpointsByServiceAndSlice[hostname]) == 0 {
-		delete(e.endpointsByServiceAndSlice, hostname)
+	svc, f := e.endpointsByService[hostname]
+	if !f {
+		return
+	}
+	svc.update(slice, nil)
+	delete(svc.bySlice, slice)
+	if len(svc.bySlice) == 0 {
+		delete(e.endpointsByService, hostname)
 	}
 }
 
+// Get returns the endpoints of the service. They must not be modified by the caller.
 func (e *endpointSliceCache) Get(hostname host.Name) []*model.IstioEndpoint {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-	var endpoints []*model.IstioEndpoint
-	found := map[endpointKey]struct{}{}
-	for _, eps := range e.endpointsByServiceAndSlice[hostname] {
-		for _, ep := range eps {
-			key := endpointKey{ep.Address, ep.ServicePortName}
-			if _, f := found[key]; f {
-				// This a duplicate. Update() already handles conflict resolution, so we don't
-				// need to pick the "right" one here.
-				continue
-			}
-			found[key] = struct{}{}
-			endpoints = append(endpoints, ep)
-		}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	svc, f := e.endpointsByService[hostname]
+	if !f || len(svc.endpoints) == 0 {
+		return nil
 	}
-	return endpoints
+	svc.shared = true
+	// The capacity is limited so that appending to the endpoints does not change the cache.
+	return svc.endpoints[:len(svc.endpoints):len(svc.endpoints)]
 }
 
 func (e *endpointSliceCache) Has(hostname host.Name) bool {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
-	_, found := e.endpointsByServiceAndSlice[hostname]
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, found := e.endpointsByService[hostname]
 	return found
 }
 
+// update replaces the endpoints of a slice, only changing the endpoints of the service it added or removed.
+func (s *serviceEndpoints) update(slice string, endpoints []*model.IstioEndpoint) {
+	old := s.bySlice[slice]
+	cur := make(map[endpointKey]*model.IstioEndpoint, len(endpoints))
+	for _, ep := range endpoints {
+		cur[keyForEndpoint(ep)] = ep
+	}
+	s.bySlice[slice] = cur
+	for key := range old {
+		if _, f := cur[key]; !f {
+			s.release(key, slice)
+		}
+	}
+	for _, ep := range endpoints {
+		key := keyForEndpoint(ep)
+		if cur[key] != ep {
+			// The slice has the endpoint several times, the last one is kept.
+			continue
+		}
+		ref, f := s.index[key]
+		if !f {
+			ref = &endpointRef{pos: len(s.endpoints)}
+			s.index[key] = ref
+		}
+		if _, f := old[key]; !f {
+			ref.slices++
+		}
+		// We will always overwrite. A conflict here means an endpoint is transitioning
+		// from one slice to another See
+		// https://github.com/kubernetes/website/blob/master/content/en/docs/concepts/services-networking/endpoint-slices.md#duplicate-endpoints
+		// In this case, we can always assume and update is fresh, although older slices
+		// we have not gotten updates may be stale; therefor we always take the new
+		// update.
+		ref.slice = slice
+		s.set(ref.pos, ep)
+	}
+}
+
+// release removes the endpoint of a slice, keeping the one of another slice if any.
+func (s *serviceEndpoints) release(key endpointKey, slice string) {
+	ref := s.index[key]
+	ref.slices--
+	if ref.slices == 0 {
+		s.remove(key, ref)
+		return
+	}
+	if ref.slice != slice {
+		return
+	}
+	for name, eps := range s.bySlice {
+		if ep, f := eps[key]; f && name != slice {
+			ref.slice = name
+			s.set(ref.pos, ep)
+			return
+		}
+	}
+}
+
+// set sets the endpoint at a position of the endpoints, appending it at their end.
+func (s *serviceEndpoints) set(pos int, ep *model.IstioEndpoint) {
+	s.unshare()
+	if pos == len(s.endpoints) {
+		s.endpoints = append(s.endpoints, ep)
+		return
+	}
+	s.endpoints[pos] = ep
+}
+
+// remove removes an endpoint, moving the last endpoint in its place.
+func (s *serviceEndpoints) remove(key endpointKey, ref *endpointRef) {
+	s.unshare()
+	last := len(s.endpoints) - 1
+	moved := s.endpoints[last]
+	s.endpoints[ref.pos] = moved
+	s.index[keyForEndpoint(moved)].pos = ref.pos
+	s.endpoints[last] = nil
+	s.endpoints = s.endpoints[:last]
+	delete(s.index, key)
+}
+
+// unshare copies the endpoints if they were returned by Get, so that they are not changed for the caller.
+func (s *serviceEndpoints) unshare() {
+	if !s.shared {
+		return
+	}
+	s.endpoints = append(make([]*model.IstioEndpoint, 0, len(s.endpoints)+1), s.endpoints...)
+	s.shared = false
+}
+
 func endpointSliceSelectorForService(name string) klabels.Selector {
 	return klabels.Set(map[string]string{
 		v1beta1.LabelServiceName: name,
@@ -20,12 +20,15 @@ import (
 	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	mcs "sigs.k8s.io/mcs-api/pkg/apis/v1alpha1"
 
 	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/kube"
 	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/test"
 )
 
 func TestEndpointSliceFromMCSShouldBeIgnored(t *testing.T) {
@@ -146,6 +149,54 @@ func TestEndpointSliceCache(t *testing.T) {
 	}
 }
 
+func TestEndpointSliceCacheIndex(t *testing.T) {
+	cache := newEndpointSliceCache()
+	hostname := host.Name("foo")
+
+	ep1 := &model.IstioEndpoint{Address: "1.2.3.4", ServicePortName: "http"}
+	ep2 := &model.IstioEndpoint{Address: "2.3.4.5", ServicePortName: "http"}
+	cache.Update(hostname, "slice1", []*model.IstioEndpoint{ep1, ep2})
+	got := cache.Get(hostname)
+
+	// The endpoint of the slice updated last is used when an endpoint transitions between slices.
+	moved := &model.IstioEndpoint{Address: "2.3.4.5", ServicePortName: "http", Labels: map[string]string{"version": "v2"}}
+	cache.Update(hostname, "slice2", []*model.IstioEndpoint{moved})
+	if eps := cache.Get(hostname); !testEndpointsEqual(eps, []*model.IstioEndpoint{ep1, moved}) || !containsEndpoint(eps, moved) {
+		t.Fatalf("expected the endpoint of slice2, got %v", eps)
+	}
+	// The endpoint of the other slice is used again when it is removed from the slice.
+	cache.Update(hostname, "slice2", nil)
+	if eps := cache.Get(hostname); !testEndpointsEqual(eps, []*model.IstioEndpoint{ep1, ep2}) || !containsEndpoint(eps, ep2) {
+		t.Fatalf("expected the endpoint of slice1, got %v", eps)
+	}
+	if !cache.Has(hostname) {
+		t.Fatalf("expect to find the host name")
+	}
+	cache.Update(hostname, "slice1", []*model.IstioEndpoint{ep2})
+	if eps := cache.Get(hostname); !testEndpointsEqual(eps, []*model.IstioEndpoint{ep2}) {
+		t.Fatalf("unexpected endpoints %v", eps)
+	}
+
+	// The endpoints returned before are not changed by the updates.
+	if len(got) != 2 || got[0] != ep1 || got[1] != ep2 {
+		t.Fatalf("expected the endpoints returned before not to change, got %v", got)
+	}
+	got = cache.Get(hostname)
+	_ = append(got, ep1)
+	if eps := cache.Get(hostname); !testEndpointsEqual(eps, []*model.IstioEndpoint{ep2}) {
+		t.Fatalf("expected the endpoints not to be changed by the caller, got %v", eps)
+	}
+}
+
+func containsEndpoint(eps []*model.IstioEndpoint, ep *model.IstioEndpoint) bool {
+	for _, e := range eps {
+		if e == ep {
+			return true
+		}
+	}
+	return false
+}
+
 func testEndpointsEqual(a, b []*model.IstioEndpoint) bool {
 	if len(a) != len(b) {
 		return false
@@ -160,3 +211,52 @@ func testEndpointsEqual(a, b []*model.IstioEndpoint) bool {
 	}
 	return reflect.DeepEqual(m1, m2)
 }
+
+func TestPodDrainDeadline(t *testing.T) {
+	test.SetForTest(t, &features.EndpointDrainDuration, 10*time.Second)
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	deleting := func(grace int64) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			DeletionTimestamp:          &metav1.Time{Time: start.Add(time.Duration(grace) * time.Second)},
+			DeletionGracePeriodSeconds: &grace,
+		}}
+	}
+	cases := []struct {
+		name string
+		pod  *corev1.Pod
+		now  time.Time
+		want time.Time
+	}{
+		{name: "no pod", now: start},
+		{name: "pod not deleted", pod: &corev1.Pod{}, now: start},
+		{name: "drain duration", pod: deleting(30), now: start, want: start.Add(10 * time.Second)},
+		{name: "capped by grace period", pod: deleting(5), now: start, want: start.Add(5 * time.Second)},
+		{name: "deadline passed", pod: deleting(30), now: start.Add(10 * time.Second)},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podDrainDeadline(tt.pod, tt.now); !got.Equal(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStopDrainResyncs(t *testing.T) {
+	esc := &endpointSliceController{drainResyncs: map[string]drainResync{}}
+	esc.scheduleDrainResync("ns/slice", time.Now().Add(time.Hour))
+	esc.scheduleDrainResync("ns/slice", time.Now().Add(time.Minute))
+	if len(esc.drainResyncs) != 1 {
+		t.Fatalf("expected a single drain resync, got %v", esc.drainResyncs)
+	}
+	r := esc.drainResyncs["ns/slice"]
+
+	esc.stopDrainResyncs()
+	if r.timer.Stop() {
+		t.Fatalf("expected the drain resync to be stopped")
+	}
+	esc.scheduleDrainResync("ns/other", time.Now().Add(time.Hour))
+	if len(esc.drainResyncs) != 0 {
+		t.Fatalf("expected no drain resync once stopped, got %v", esc.drainResyncs)
+	}
+}
@@ -283,12 +283,19 @@ func (m *Multicluster) initializeCluster(cluster *multicluster.Cluster, kubeCont
 				AddRunFunction(func(leaderStop <-chan struct{}) {
 					log.Infof("starting namespace controller for cluster %s", cluster.ID)
 					nc := NewNamespaceController(client, m.caBundleWatcher, discoveryNamespacesFilter)
+					var tbc *TrustBundleController
+					if features.EnableTrustBundleDistribution {
+						tbc = NewTrustBundleController(client, m.caBundleWatcher, m.opts.TrustBundle, discoveryNamespacesFilter)
+					}
 					// Start informers again. This fixes the case where informers for namespace do not start,
 					// as we create them only after acquiring the leader lock
 					// Note: stop here should be the overall pilot stop, NOT the leader election stop. We are
 					// basically lazy loading the informer, if we stop it when we lose the lock we will never
 					// recreate it again.
 					client.RunAndWait(clusterStopCh)
+					if tbc != nil {
+						go tbc.Run(leaderStop)
+					}
 					nc.Run(leaderStop)
 				})
 			election.Run(clusterStopCh)
@@ -17,10 +17,14 @@ package controller
 import (
 	"net"
 	"strconv"
+	"strings"
 
 	"github.com/yl2chen/cidranger"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/host"
@@ -43,6 +47,11 @@ type multinetwork struct {
 	networkGatewaysBySvc map[host.Name]model.NetworkGatewaySet
 	// implements NetworkGatewaysWatcher; we need to call c.NotifyGatewayHandlers when our gateways change
 	model.NetworkGatewaysHandler
+
+	// zoneNetworks assigns the nodes of zones to networks, if dynamic networks are enabled.
+	zoneNetworks map[string]network.ID
+	// nodeNetworks is the network of each node, if dynamic networks are enabled, to detect the changes.
+	nodeNetworks map[string]network.ID
 }
 
 func initMultinetwork() multinetwork {
@@ -53,7 +62,27 @@ func initMultinetwork() multinetwork {
 		networkForRegistry:          "",
 		registryServiceNameGateways: make(map[host.Name][]model.NetworkGateway),
 		networkGatewaysBySvc:        make(map[host.Name]model.NetworkGatewaySet),
+		zoneNetworks:                parseZoneNetworks(features.ZoneNetworks),
+		nodeNetworks:                make(map[string]network.ID),
+	}
+}
+
+// parseZoneNetworks parses the comma separated <zone>=<network> pairs of PILOT_ZONE_NETWORKS.
+func parseZoneNetworks(s string) map[string]network.ID {
+	out := map[string]network.ID{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		zone, nw, ok := strings.Cut(pair, "=")
+		zone, nw = strings.TrimSpace(zone), strings.TrimSpace(nw)
+		if !ok || zone == "" || nw == "" {
+			log.Warnf("invalid zone network %q, expected <zone>=<network>", pair)
+			continue
+		}
+		out[zone] = network.ID(nw)
 	}
+	return out
 }
 
 // namedRangerEntry for holding network's CIDR and name
@@ -150,6 +179,64 @@ func (c *Controller) reloadMeshNetworks() {
 	c.ranger = ranger
 }
 
+// nodeNetwork returns the network of a node: its network label, or the network of its zone.
+func (c *Controller) nodeNetwork(node metav1.ObjectMeta) network.ID {
+	if nw := node.Labels[label.TopologyNetwork.Name]; nw != "" {
+		return network.ID(nw)
+	}
+	if zone := getLabelValue(node, NodeZoneLabelGA, NodeZoneLabel); zone != "" {
+		return c.zoneNetworks[zone]
+	}
+	return ""
+}
+
+// networkForNode returns the network of the pods scheduled on the node, if dynamic networks are enabled.
+func (c *Controller) networkForNode(nodeName string) network.ID {
+	if !features.EnableDynamicNetworks || nodeName == "" {
+		return ""
+	}
+	c.RLock()
+	defer c.RUnlock()
+	return c.nodeNetworks[nodeName]
+}
+
+// updateNodeNetwork records the network of a node, and updates the pods scheduled on it if it changes.
+func (c *Controller) updateNodeNetwork(node *v1.Node, event model.Event) {
+	var nw network.ID
+	if event != model.EventDelete {
+		nw = c.nodeNetwork(node.ObjectMeta)
+	}
+	c.Lock()
+	old := c.nodeNetworks[node.Name]
+	if nw == "" {
+		delete(c.nodeNetworks, node.Name)
+	} else {
+		c.nodeNetworks[node.Name] = nw
+	}
+	c.Unlock()
+	// Before the initial sync, the networks of the nodes are computed before the pods are processed.
+	if old != nw && event != model.EventDelete && c.HasSynced() {
+		log.Infof("network of node %s changed from %q to %q", node.Name, old, nw)
+		c.onNodeNetworkChange(node.Name)
+	}
+}
+
+// onNodeNetworkChange is fired if the network of a node changes, which changes the network of the pods
+// scheduled on it. The proxies of the pods recompute their network, and their endpoints are rebuilt.
+func (c *Controller) onNodeNetworkChange(nodeName string) {
+	pods, _ := c.pods.informer.List(metav1.NamespaceAll)
+	for _, obj := range pods {
+		pod := obj.(*v1.Pod)
+		if pod.Spec.NodeName == nodeName && pod.Status.PodIP != "" {
+			c.pods.proxyUpdates(pod.Status.PodIP)
+		}
+	}
+	if err := c.syncEndpoints(); err != nil {
+		log.Errorf("one or more errors force-syncing endpoints: %v", err)
+	}
+	c.reloadNetworkGateways()
+}
+
 func (c *Controller) NetworkGateways() []model.NetworkGateway {
 	c.RLock()
 	defer c.RUnlock()
@@ -28,8 +28,11 @@ import (
 
 	"istio.io/api/label"
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/network"
+	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/util/retry"
 )
 
@@ -98,6 +101,58 @@ func TestNetworkUpdateTriggers(t *testing.T) {
 	})
 }
 
+func TestDynamicNetworks(t *testing.T) {
+	test.SetForTest(t, &features.EnableDynamicNetworks, true)
+	c, fx := NewFakeControllerWithOptions(t, FakeControllerOptions{})
+	c.Lock()
+	c.zoneNetworks = parseZoneNetworks("zone-b=network-b, zone-c = network-c,invalid")
+	c.Unlock()
+
+	expectNodeNetwork := func(node string, nw network.ID) {
+		t.Helper()
+		retry.UntilSuccessOrFail(t, func() error {
+			if got := c.networkForNode(node); got != nw {
+				return fmt.Errorf("expected network %q for node %s, got %q", nw, node, got)
+			}
+			return nil
+		}, retry.Timeout(5*time.Second), retry.Delay(10*time.Millisecond))
+	}
+	addNodes(t, c,
+		generateNode("node-a", map[string]string{label.TopologyNetwork.Name: "network-a"}),
+		generateNode("node-b", map[string]string{NodeZoneLabelGA: "zone-b"}),
+		generateNode("node-d", nil))
+	expectNodeNetwork("node-a", "network-a")
+	expectNodeNetwork("node-b", "network-b")
+	expectNodeNetwork("node-d", "")
+
+	pod := generatePod("128.0.0.1", "pod1", "nsA", "", "node-d", map[string]string{"app": "prod-app"}, nil)
+	labeled := generatePod("128.0.0.2", "pod2", "nsA", "", "node-d",
+		map[string]string{"app": "prod-app", label.TopologyNetwork.Name: "network-pod"}, nil)
+	addPods(t, c, fx, pod, labeled)
+	expectEndpointNetwork := func(pod *corev1.Pod, nw network.ID) {
+		t.Helper()
+		ep := NewEndpointBuilder(c, pod).buildIstioEndpoint(pod.Status.PodIP, 80, "http", model.AlwaysDiscoverable)
+		if ep.Network != nw {
+			t.Fatalf("expected network %q for pod %s, got %q", nw, pod.Name, ep.Network)
+		}
+	}
+	expectEndpointNetwork(pod, "")
+	expectEndpointNetwork(labeled, "network-pod")
+
+	// Moving the node to a zone changes the network of its pods.
+	fx.Clear()
+	addNodes(t, c, generateNode("node-d", map[string]string{NodeZoneLabelGA: "zone-c"}))
+	expectNodeNetwork("node-d", "network-c")
+	fx.WaitOrFail(t, "proxy")
+	expectEndpointNetwork(pod, "network-c")
+	expectEndpointNetwork(labeled, "network-pod")
+
+	proxy := &model.Proxy{ID: "pod1.nsA", IPAddresses: []string{"128.0.0.1"}, Metadata: &model.NodeMetadata{Namespace: "nsA"}}
+	if nw := c.GetProxyWorkloadLabels(proxy)[label.TopologyNetwork.Name]; nw != "network-c" {
+		t.Fatalf("expected the network of the node in the workload labels, got %q", nw)
+	}
+}
+
 func addLabeledServiceGateway(t *testing.T, c *FakeController, nw string) {
 	ctx := context.TODO()
 
@@ -197,7 +197,7 @@ func (pc *PodCache) onEvent(curr any, ev model.Event) error {
 // notifyWorkloadHandlers fire workloadInstance handlers for pod
 func (pc *PodCache) notifyWorkloadHandlers(pod *v1.Pod, ev model.Event) {
 	// if no workload handler registered, skip building WorkloadInstance
-	if len(pc.c.handlers.GetWorkloadHandlers()) == 0 {
+	if !pc.c.handlers.HasWorkloadHandlers() {
 		return
 	}
 	// fire instance handles for workload
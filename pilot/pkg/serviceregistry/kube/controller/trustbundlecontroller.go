@@ -0,0 +1,249 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"reflect"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	"istio.io/istio/pilot/pkg/keycertbundle"
+	"istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/kube/controllers"
+	"istio.io/istio/pkg/kube/inject"
+	filter "istio.io/istio/pkg/kube/namespace"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// TrustBundleConfigMap is the name of the ConfigMap in each namespace storing the trust bundle of the mesh, for
+	// the applications terminating TLS themselves.
+	TrustBundleConfigMap = "istio-trust-bundle"
+	// TrustBundleDataName is the data name of the TrustBundleConfigMap storing all the trusted roots: the roots of
+	// the mesh and the federated roots.
+	TrustBundleDataName = "ca.crt"
+	// MeshRootsDataName is the data name of the TrustBundleConfigMap storing the roots of the mesh CA.
+	MeshRootsDataName = "mesh-roots.pem"
+	// FederatedRootsDataName is the data name of the TrustBundleConfigMap storing the federated roots, configured in
+	// the caCertificates of MeshConfig or fetched from SPIFFE bundle endpoints.
+	FederatedRootsDataName = "federated-roots.pem"
+	// TrustBundleHashAnnotation is the SHA-256 of the TrustBundleDataName data, which changes on each rotation.
+	TrustBundleHashAnnotation = "security.istio.io/trust-bundle-hash"
+)
+
+// TrustBundleController writes the trust bundle of the mesh to a ConfigMap in each namespace. Unlike the
+// NamespaceController, it also distributes the federated roots, and each rotation replaces all the data of the
+// ConfigMap in a single update, so that the projected files of the ConfigMap volumes are swapped at once.
+type TrustBundleController struct {
+	client          corev1.CoreV1Interface
+	caBundleWatcher *keycertbundle.Watcher
+	trustBundle     *trustbundle.TrustBundle
+	// trustBundleCh is notified of the updates of the trust bundle. The handler notifying it is registered once, as
+	// the controller runs each time the leadership is acquired.
+	trustBundleCh chan struct{}
+
+	queue              controllers.Queue
+	namespacesInformer cache.SharedInformer
+	configMapInformer  cache.SharedInformer
+	namespaceLister    listerv1.NamespaceLister
+	configmapLister    listerv1.ConfigMapLister
+
+	cmHandle cache.ResourceEventHandlerRegistration
+	nsHandle cache.ResourceEventHandlerRegistration
+
+	// if meshConfig.DiscoverySelectors specified, DiscoveryNamespacesFilter tracks the namespaces to be watched by this controller.
+	DiscoveryNamespacesFilter filter.DiscoveryNamespacesFilter
+}
+
+// NewTrustBundleController returns a pointer to a newly constructed TrustBundleController instance. The trust
+// bundle holds the federated roots, and may be nil.
+func NewTrustBundleController(kubeClient kube.Client, caBundleWatcher *keycertbundle.Watcher, trustBundle *trustbundle.TrustBundle,
+	discoveryNamespacesFilter filter.DiscoveryNamespacesFilter,
+) *TrustBundleController {
+	c := &TrustBundleController{
+		client:                    kubeClient.Kube().CoreV1(),
+		caBundleWatcher:           caBundleWatcher,
+		trustBundle:               trustBundle,
+		trustBundleCh:             make(chan struct{}, 1),
+		DiscoveryNamespacesFilter: discoveryNamespacesFilter,
+	}
+	if trustBundle != nil {
+		trustBundle.AddUpdateHandler(func() {
+			select {
+			case c.trustBundleCh <- struct{}{}:
+			default:
+			}
+		})
+	}
+	c.queue = controllers.NewQueue("trust bundle controller", controllers.WithReconciler(c.reconcile))
+
+	c.configMapInformer = kubeClient.KubeInformer().Core().V1().ConfigMaps().Informer()
+	c.configmapLister = kubeClient.KubeInformer().Core().V1().ConfigMaps().Lister()
+	c.namespacesInformer = kubeClient.KubeInformer().Core().V1().Namespaces().Informer()
+	c.namespaceLister = kubeClient.KubeInformer().Core().V1().Namespaces().Lister()
+
+	c.cmHandle, _ = c.configMapInformer.AddEventHandler(controllers.FilteredObjectSpecHandler(c.queue.AddObject, func(o controllers.Object) bool {
+		if o.GetName() != TrustBundleConfigMap || inject.IgnoredNamespaces.Contains(o.GetNamespace()) {
+			return false
+		}
+		return c.DiscoveryNamespacesFilter == nil || c.DiscoveryNamespacesFilter.Filter(o)
+	}))
+	c.nsHandle, _ = c.namespacesInformer.AddEventHandler(controllers.FilteredObjectSpecHandler(c.queue.AddObject, func(o controllers.Object) bool {
+		if inject.IgnoredNamespaces.Contains(o.GetName()) {
+			return false
+		}
+		return c.DiscoveryNamespacesFilter == nil || c.DiscoveryNamespacesFilter.FilterNamespace(o.(*v1.Namespace).ObjectMeta)
+	}))
+
+	return c
+}
+
+// Run starts the TrustBundleController until a value is sent to stopCh.
+func (c *TrustBundleController) Run(stopCh <-chan struct{}) {
+	if !kube.WaitForCacheSync(stopCh, c.namespacesInformer.HasSynced, c.configMapInformer.HasSynced) {
+		log.Error("Failed to sync trust bundle controller cache")
+		return
+	}
+	go c.startBundleWatcher(stopCh)
+	c.queue.Run(stopCh)
+	_ = c.configMapInformer.RemoveEventHandler(c.cmHandle)
+	_ = c.namespacesInformer.RemoveEventHandler(c.nsHandle)
+}
+
+// startBundleWatcher listens for updates to the CA bundle and the federated roots, and updates the ConfigMap in
+// each namespace.
+func (c *TrustBundleController) startBundleWatcher(stop <-chan struct{}) {
+	id, watchCh := c.caBundleWatcher.AddWatcher()
+	defer c.caBundleWatcher.RemoveWatcher(id)
+	for {
+		select {
+		case <-watchCh:
+		case <-c.trustBundleCh:
+		case <-stop:
+			return
+		}
+		namespaceList, _ := c.namespaceLister.List(labels.Everything())
+		for _, ns := range namespaceList {
+			if ns.Status.Phase == v1.NamespaceTerminating || inject.IgnoredNamespaces.Contains(ns.Name) {
+				continue
+			}
+			if c.DiscoveryNamespacesFilter != nil && !c.DiscoveryNamespacesFilter.FilterNamespace(ns.ObjectMeta) {
+				continue
+			}
+			c.queue.Add(types.NamespacedName{Name: ns.Name})
+		}
+	}
+}
+
+// reconcile creates or updates the trust bundle ConfigMap of the namespace. All the data of the ConfigMap is
+// replaced at once, so that applications never observe a partially rotated bundle.
+func (c *TrustBundleController) reconcile(o types.NamespacedName) error {
+	ns := o.Namespace
+	if ns == "" {
+		// For Namespace object, it will not have o.Namespace field set
+		ns = o.Name
+	}
+	data := c.bundleData()
+	hash := sha256.Sum256([]byte(data[TrustBundleDataName]))
+	annotations := map[string]string{TrustBundleHashAnnotation: hex.EncodeToString(hash[:])}
+
+	cm, err := c.configmapLister.ConfigMaps(ns).Get(TrustBundleConfigMap)
+	if errors.IsNotFound(err) {
+		cm = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        TrustBundleConfigMap,
+				Namespace:   ns,
+				Labels:      configMapLabel,
+				Annotations: annotations,
+			},
+			Data: data,
+		}
+		if _, err := c.client.ConfigMaps(ns).Create(context.TODO(), cm, metav1.CreateOptions{}); err != nil {
+			// The namespace may be terminating, or the ConfigMap created concurrently. Do not retry.
+			if errors.IsAlreadyExists(err) || errors.HasStatusCause(err, v1.NamespaceTerminatingCause) {
+				return nil
+			}
+			return fmt.Errorf("error when creating configmap %v: %v", TrustBundleConfigMap, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error when getting configmap %v: %v", TrustBundleConfigMap, err)
+	}
+	if reflect.DeepEqual(cm.Data, data) && cm.Annotations[TrustBundleHashAnnotation] == annotations[TrustBundleHashAnnotation] {
+		return nil
+	}
+	newCm := cm.DeepCopy()
+	newCm.Data = data
+	if newCm.Annotations == nil {
+		newCm.Annotations = map[string]string{}
+	}
+	newCm.Annotations[TrustBundleHashAnnotation] = annotations[TrustBundleHashAnnotation]
+	if _, err := c.client.ConfigMaps(ns).Update(context.TODO(), newCm, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("error when updating configmap %v: %v", TrustBundleConfigMap, err)
+	}
+	return nil
+}
+
+// bundleData returns the data of the trust bundle ConfigMap. The federated roots exclude the roots of the mesh.
+func (c *TrustBundleController) bundleData() map[string]string {
+	meshRoots := splitPEM(string(c.caBundleWatcher.GetCABundle()))
+	seen := sets.New(meshRoots...)
+	var federatedRoots []string
+	if c.trustBundle != nil {
+		for _, cert := range c.trustBundle.GetTrustBundle() {
+			for _, root := range splitPEM(cert) {
+				if !seen.InsertContains(root) {
+					federatedRoots = append(federatedRoots, root)
+				}
+			}
+		}
+	}
+	return map[string]string{
+		TrustBundleDataName:    strings.Join(append(append([]string{}, meshRoots...), federatedRoots...), ""),
+		MeshRootsDataName:      strings.Join(meshRoots, ""),
+		FederatedRootsDataName: strings.Join(federatedRoots, ""),
+	}
+}
+
+// splitPEM returns the PEM encoded certificates of the bundle, normalized so that identical certificates are equal.
+func splitPEM(bundle string) []string {
+	var certs []string
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return certs
+		}
+		if block.Type == "CERTIFICATE" {
+			certs = append(certs, string(pem.EncodeToMemory(block)))
+		}
+	}
+}
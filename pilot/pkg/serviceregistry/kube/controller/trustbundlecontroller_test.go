@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/keycertbundle"
+	"istio.io/istio/pilot/pkg/trustbundle"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func readTestCert(t *testing.T, name string) string {
+	t.Helper()
+	by, err := os.ReadFile(filepath.Join(env.IstioSrc, "security/pkg/pki/testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(by)
+}
+
+func TestTrustBundleController(t *testing.T) {
+	client := kube.NewFakeClient()
+	t.Cleanup(client.Shutdown)
+	meshRoot := readTestCert(t, "root-cert-10y.pem")
+	federatedRoot := readTestCert(t, "spiffe-root-cert-1.pem")
+
+	watcher := keycertbundle.NewWatcher()
+	watcher.SetAndNotify(nil, nil, []byte(meshRoot))
+	tb := trustbundle.NewTrustBundle(nil)
+	// The mesh root is also part of the trust bundle, and must not be reported as federated.
+	if err := tb.UpdateTrustAnchor(&trustbundle.TrustAnchorUpdate{
+		TrustAnchorConfig: trustbundle.TrustAnchorConfig{Certs: []string{meshRoot}},
+		Source:            trustbundle.SourceIstioCA,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewTrustBundleController(client, watcher, tb, nil)
+	stop := test.NewStop(t)
+	client.RunAndWait(stop)
+	go c.Run(stop)
+	retry.UntilOrFail(t, c.queue.HasSynced)
+
+	createNamespace(t, client.Kube(), "foo", nil)
+	expectConfigMap(t, c.configmapLister, TrustBundleConfigMap, "foo", map[string]string{
+		TrustBundleDataName:    meshRoot,
+		MeshRootsDataName:      meshRoot,
+		FederatedRootsDataName: "",
+	})
+
+	if err := tb.UpdateTrustAnchor(&trustbundle.TrustAnchorUpdate{
+		TrustAnchorConfig: trustbundle.TrustAnchorConfig{Certs: []string{federatedRoot}},
+		Source:            trustbundle.SourceMeshConfig,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expectConfigMap(t, c.configmapLister, TrustBundleConfigMap, "foo", map[string]string{
+		TrustBundleDataName:    meshRoot + federatedRoot,
+		MeshRootsDataName:      meshRoot,
+		FederatedRootsDataName: federatedRoot,
+	})
+	hash := sha256.Sum256([]byte(meshRoot + federatedRoot))
+	retry.UntilSuccessOrFail(t, func() error {
+		cm, err := c.configmapLister.ConfigMaps("foo").Get(TrustBundleConfigMap)
+		if err != nil {
+			return err
+		}
+		if got := cm.Annotations[TrustBundleHashAnnotation]; got != hex.EncodeToString(hash[:]) {
+			return fmt.Errorf("unexpected hash %v", got)
+		}
+		return nil
+	}, retry.Timeout(time.Second*10))
+}
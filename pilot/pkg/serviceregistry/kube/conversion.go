@@ -29,6 +29,7 @@ import (
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/kube"
+	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/config/visibility"
 	"istio.io/istio/pkg/spiffe"
 )
@@ -78,9 +79,15 @@ func ConvertService(svc corev1.Service, domainSuffix string, clusterID cluster.I
 		}
 	}
 
+	protocolDetection := model.ParseProtocolDetection(svc.Annotations)
 	ports := make([]*model.Port, 0, len(svc.Spec.Ports))
 	for _, port := range svc.Spec.Ports {
-		ports = append(ports, convertPort(port))
+		p := convertPort(port)
+		// Ports whose protocol detection is disabled are plain TCP.
+		if p.Protocol.IsUnsupported() && protocolDetection[p.Port].Disabled {
+			p.Protocol = protocol.TCP
+		}
+		ports = append(ports, p)
 	}
 
 	var exportTo map[visibility.Instance]bool
@@ -122,6 +129,9 @@ func ConvertService(svc corev1.Service, domainSuffix string, clusterID cluster.I
 			Labels:          svc.Labels,
 			ExportTo:        exportTo,
 			LabelSelectors:  svc.Spec.Selector,
+
+			ClientAddressDetection: model.ParseClientAddressDetection(svc.Annotations),
+			ProtocolDetection:      protocolDetection,
 		},
 	}
 
@@ -25,10 +25,12 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"istio.io/api/annotation"
+	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/kube"
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/test/util/assert"
 )
 
 var (
@@ -268,6 +270,33 @@ func TestServiceConversionWithEmptyServiceAccountsAnnotation(t *testing.T) {
 	}
 }
 
+func TestServiceConversionWithProtocolDetectionAnnotations(t *testing.T) {
+	localSvc := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "mysql",
+			Namespace: "default",
+			Annotations: map[string]string{
+				model.ProtocolDetectionDisabledPortsAnnotation: "3306,8080",
+				model.ProtocolDetectionTimeoutAnnotation:       "9000=1s",
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: "10.0.0.1",
+			Ports: []corev1.ServicePort{
+				{Port: 3306, Protocol: corev1.ProtocolTCP},
+				{Name: "http", Port: 8080, Protocol: corev1.ProtocolTCP},
+				{Port: 9000, Protocol: corev1.ProtocolTCP},
+			},
+		},
+	}
+
+	service := ConvertService(localSvc, domainSuffix, clusterID)
+	// Ports whose protocol is declared are not affected.
+	assert.Equal(t, []protocol.Instance{service.Ports[0].Protocol, service.Ports[1].Protocol, service.Ports[2].Protocol},
+		[]protocol.Instance{protocol.TCP, protocol.HTTP, protocol.Unsupported})
+	assert.Equal(t, service.ProtocolDetection(9000).Timeout, time.Second)
+}
+
 func TestExternalServiceConversion(t *testing.T) {
 	serviceName := "service1"
 	namespace := "default"
@@ -157,7 +157,5 @@ func (c *Controller) Run(<-chan struct{}) {}
 func (c *Controller) HasSynced() bool { return true }
 
 func (c *Controller) OnServiceEvent(s *model.Service, e model.Event) {
-	for _, h := range c.serviceHandler.GetServiceHandlers() {
-		h(s, e)
-	}
+	c.serviceHandler.NotifyServiceHandlers(s, e)
 }
@@ -105,6 +105,9 @@ type Controller struct {
 
 	workloadHandlers []func(*model.WorkloadInstance, model.Event)
 
+	// srv resolves the endpoints of the service entries annotated with DNSSRVAnnotation.
+	srv *srvResolver
+
 	// callback function used to get the networkID according to workload ip and labels.
 	networkIDCallback func(IP string, labels labels.Instance) network.ID
 
@@ -175,12 +178,58 @@ func newController(store model.ConfigStore, xdsUpdater model.XDSUpdater, options
 		},
 		edsQueue: queue.NewQueue(time.Second),
 	}
+	s.srv = newSRVResolver(features.ServiceEntrySRVRefreshInterval, s.srvTargetsChanged)
 	for _, o := range options {
 		o(s)
 	}
 	return s
 }
 
+// srvTargetsChanged queues the update of the instances of a service entry after its SRV records were resolved
+// again. It is called by the SRV resolver, so the update is queued on the edsQueue to be ordered with the EDS
+// updates of the service entry events.
+func (s *Controller) srvTargetsChanged(key types.NamespacedName) {
+	s.edsQueue.Push(func() error {
+		s.updateSRVInstances(key)
+		return nil
+	})
+}
+
+// updateSRVInstances rebuilds the instances of a service entry from its resolved SRV targets, and pushes them with
+// EDS. It runs on the edsQueue, so it updates the endpoints directly rather than queueing the update.
+func (s *Controller) updateSRVInstances(key types.NamespacedName) {
+	if s.store == nil {
+		return
+	}
+	cfg := s.store.Get(gvk.ServiceEntry, key.Name, key.Namespace)
+	if cfg == nil || !isDNSSRV(*cfg) {
+		return
+	}
+	s.mutex.Lock()
+	// The services are those of the last handled event; if the service entry was not handled yet, its handler
+	// builds the instances from the resolved targets.
+	cs := s.services.getServices(key)
+	if len(cs) == 0 {
+		s.mutex.Unlock()
+		return
+	}
+	serviceInstancesByConfig, _ := s.buildServiceInstances(*cfg, cs)
+	for configKey, old := range s.serviceInstances.getServiceEntryInstances(key) {
+		s.serviceInstances.deleteInstances(configKey, old)
+	}
+	for ckey, value := range serviceInstancesByConfig {
+		s.serviceInstances.addInstances(ckey, value)
+	}
+	s.serviceInstances.updateServiceEntryInstances(key, serviceInstancesByConfig)
+	s.mutex.Unlock()
+
+	keys := map[instancesKey]struct{}{}
+	for _, svc := range cs {
+		keys[instancesKey{hostname: svc.Hostname, namespace: key.Namespace}] = struct{}{}
+	}
+	s.doEdsUpdate(keys)
+}
+
 // convertWorkloadEntry convert wle from Config.Spec and populate the metadata labels into it.
 func convertWorkloadEntry(cfg config.Config) *networking.WorkloadEntry {
 	wle := cfg.Spec.(*networking.WorkloadEntry)
@@ -349,8 +398,16 @@ func (s *Controller) serviceEntryHandler(_, curr config.Config, event model.Even
 	cs := convertServices(curr)
 	configsUpdated := sets.New[model.ConfigKey]()
 	key := types.NamespacedName{Namespace: curr.Namespace, Name: curr.Name}
+	dnsSRV := isDNSSRV(curr)
 
 	s.mutex.Lock()
+	// The SRV names are watched under the lock, so that the instances resolved from them are only updated once
+	// the services of the event are stored.
+	if event == model.EventDelete || !dnsSRV {
+		s.srv.watch(key, nil)
+	} else {
+		s.srv.watch(key, srvNames(currentServiceEntry, cs))
+	}
 	// If it is add/delete event we should always do a full push. If it is update event, we should do full push,
 	// only when services have changed - otherwise, just push endpoint updates.
 	var addedSvcs, deletedSvcs, updatedSvcs, unchangedSvcs []*model.Service
@@ -410,7 +467,8 @@ func (s *Controller) serviceEntryHandler(_, curr config.Config, event model.Even
 	// If this service entry had endpoints with IPs (i.e. resolution STATIC), then we do EDS update.
 	// If the service entry had endpoints with FQDNs (i.e. resolution DNS), then we need to do
 	// full push (as fqdn endpoints go via strict_dns clusters in cds).
-	if len(unchangedSvcs) > 0 {
+	// Endpoints resolved from SRV records are sent with EDS, so they only need an EDS update.
+	if len(unchangedSvcs) > 0 && !dnsSRV {
 		if currentServiceEntry.Resolution == networking.ServiceEntry_DNS || currentServiceEntry.Resolution == networking.ServiceEntry_DNS_ROUND_ROBIN {
 			for _, svc := range unchangedSvcs {
 				configsUpdated[makeConfigKey(svc)] = struct{}{}
@@ -587,6 +645,9 @@ func (s *Controller) AppendWorkloadHandler(h func(*model.WorkloadInstance, model
 
 // Run is used by some controllers to execute background jobs after init is done.
 func (s *Controller) Run(stopCh <-chan struct{}) {
+	if !s.workloadEntryController {
+		go s.srv.run(stopCh)
+	}
 	s.edsQueue.Run(stopCh)
 }
 
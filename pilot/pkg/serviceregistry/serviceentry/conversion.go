@@ -19,6 +19,8 @@ import (
 	"strings"
 	"time"
 
+	"google.golang.org/protobuf/proto"
+
 	"istio.io/api/label"
 	networking "istio.io/api/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/features"
@@ -162,6 +164,11 @@ func convertServices(cfg config.Config) []*model.Service {
 	case networking.ServiceEntry_STATIC:
 		resolution = model.ClientSideLB
 	}
+	dnsSRV := isDNSSRV(cfg)
+	if dnsSRV {
+		// istiod resolves the endpoints, so the proxies get them with EDS.
+		resolution = model.ClientSideLB
+	}
 
 	svcPorts := make(model.PortList, 0, len(serviceEntry.Ports))
 	for _, port := range serviceEntry.Ports {
@@ -201,8 +208,16 @@ func convertServices(cfg config.Config) []*model.Service {
 		}
 	}
 
-	return buildServices(hostAddresses, cfg.Name, cfg.Namespace, svcPorts, serviceEntry.Location, resolution,
+	services := buildServices(hostAddresses, cfg.Name, cfg.Namespace, svcPorts, serviceEntry.Location, resolution,
 		exportTo, labelSelectors, serviceEntry.SubjectAltNames, creationTime, cfg.Labels)
+	hc := healthCheck(cfg)
+	dfp := isDynamicForwardProxy(cfg)
+	for _, svc := range services {
+		svc.Attributes.DNSSRV = dnsSRV
+		svc.Attributes.HealthCheck = hc
+		svc.Attributes.DynamicForwardProxy = dfp
+	}
+	return services
 }
 
 func buildServices(hostAddresses []*HostAddress, name, namespace string, ports model.PortList, location networking.ServiceEntry_Location,
@@ -322,6 +337,9 @@ func (s *Controller) convertServiceEntryToInstances(cfg config.Config, services
 	if services == nil {
 		services = convertServices(cfg)
 	}
+	if isDNSSRV(cfg) {
+		return s.convertSRVTargetsToInstances(serviceEntry, services)
+	}
 	for _, service := range services {
 		for _, serviceEntryPort := range serviceEntry.Ports {
 			if len(serviceEntry.Endpoints) == 0 && serviceEntry.WorkloadSelector == nil &&
@@ -356,6 +374,26 @@ func (s *Controller) convertServiceEntryToInstances(cfg config.Config, services
 	return out
 }
 
+// convertSRVTargetsToInstances translates the resolved SRV targets of a ServiceEntry into ServiceInstances.
+// Each target keeps the metadata of the endpoint it was resolved from, with the port and weight of the SRV record
+// as the target port of the single port of the ServiceEntry.
+func (s *Controller) convertSRVTargetsToInstances(serviceEntry *networking.ServiceEntry, services []*model.Service) []*model.ServiceInstance {
+	out := make([]*model.ServiceInstance, 0)
+	for _, service := range services {
+		for _, endpoint := range srvEndpoints(serviceEntry, service) {
+			for _, target := range s.srv.lookup(endpoint.Address) {
+				wle := proto.Clone(endpoint).(*networking.WorkloadEntry)
+				wle.Address = target.address
+				wle.Weight = target.weight
+				serviceEntryPort := serviceEntry.Ports[0]
+				wle.Ports = map[string]uint32{serviceEntryPort.Name: target.port}
+				out = append(out, s.convertEndpoint(service, serviceEntryPort, wle, &configKey{}, s.clusterID))
+			}
+		}
+	}
+	return out
+}
+
 func getTLSModeFromWorkloadEntry(wle *networking.WorkloadEntry) string {
 	// * Use security.istio.io/tlsMode if its present
 	// * If not, set TLS mode if ServiceAccount is specified
@@ -0,0 +1,35 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+)
+
+// DynamicForwardProxyAnnotation makes the proxies forward the HTTP requests and TLS connections to the hosts of a
+// `resolution: NONE` ServiceEntry through the Envoy dynamic forward proxy: the proxies resolve the host of each
+// request, or the SNI of each connection, and connect to it. The hosts of the ServiceEntry, which may be wildcards,
+// are the domains the proxies are allowed to forward to; exportTo and the Sidecar egress hosts restrict which
+// proxies can use it. Plain TCP ports are not forwarded, as their destination host is unknown.
+// TODO: move to API
+const DynamicForwardProxyAnnotation = "networking.istio.io/dynamic-forward-proxy"
+
+// isDynamicForwardProxy returns true if the traffic to the ServiceEntry is forwarded by the dynamic forward proxy.
+func isDynamicForwardProxy(cfg config.Config) bool {
+	se := cfg.Spec.(*networking.ServiceEntry)
+	return cfg.Annotations[DynamicForwardProxyAnnotation] == "true" && se.WorkloadSelector == nil &&
+		se.Resolution == networking.ServiceEntry_NONE
+}
@@ -0,0 +1,58 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestDynamicForwardProxyServiceEntry(t *testing.T) {
+	se := func(resolution networking.ServiceEntry_Resolution, annotation string) config.Config {
+		return config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.ServiceEntry,
+				Name:             "egress",
+				Namespace:        "default",
+				Annotations:      map[string]string{DynamicForwardProxyAnnotation: annotation},
+			},
+			Spec: &networking.ServiceEntry{
+				Hosts:      []string{"*.example.com", "api.example.org"},
+				Ports:      []*networking.Port{{Number: 443, Name: "tls", Protocol: "TLS"}},
+				Location:   networking.ServiceEntry_MESH_EXTERNAL,
+				Resolution: resolution,
+			},
+		}
+	}
+
+	services := convertServices(se(networking.ServiceEntry_NONE, "true"))
+	if len(services) != 2 {
+		t.Fatalf("expected a service per host, got %d", len(services))
+	}
+	for _, svc := range services {
+		if !svc.Attributes.DynamicForwardProxy || !svc.IsDynamicForwardProxyPort(svc.Ports[0]) {
+			t.Fatalf("expected %s to be a dynamic forward proxy service", svc.Hostname)
+		}
+	}
+	if convertServices(se(networking.ServiceEntry_NONE, "false"))[0].Attributes.DynamicForwardProxy {
+		t.Fatalf("expected the annotation to be disabled")
+	}
+	if convertServices(se(networking.ServiceEntry_DNS, "true"))[0].Attributes.DynamicForwardProxy {
+		t.Fatalf("expected the annotation of a DNS ServiceEntry to be ignored")
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/healthcheck"
+)
+
+// healthCheck returns the health check of the endpoints of the ServiceEntry, or nil if there is none.
+func healthCheck(cfg config.Config) *healthcheck.HealthCheck {
+	v, f := cfg.Annotations[healthcheck.Annotation]
+	if !f || cfg.Spec.(*networking.ServiceEntry).Resolution == networking.ServiceEntry_NONE {
+		return nil
+	}
+	hc, err := healthcheck.Parse(v)
+	if err != nil {
+		// Rejected by the validation, unless the ServiceEntry predates it.
+		log.Warnf("ignoring invalid %s annotation of ServiceEntry %s/%s: %v", healthcheck.Annotation, cfg.Namespace, cfg.Name, err)
+		return nil
+	}
+	return hc
+}
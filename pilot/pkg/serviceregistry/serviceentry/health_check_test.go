@@ -0,0 +1,54 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/healthcheck"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestHealthCheckServiceEntry(t *testing.T) {
+	se := func(resolution networking.ServiceEntry_Resolution, hc string) config.Config {
+		return config.Config{
+			Meta: config.Meta{
+				GroupVersionKind: gvk.ServiceEntry,
+				Name:             "backend",
+				Namespace:        "default",
+				Annotations:      map[string]string{healthcheck.Annotation: hc},
+			},
+			Spec: &networking.ServiceEntry{
+				Hosts:      []string{"backend.example.com"},
+				Ports:      []*networking.Port{{Number: 80, Name: "http", Protocol: "HTTP"}},
+				Endpoints:  []*networking.WorkloadEntry{{Address: "10.0.0.1"}, {Address: "10.0.0.2"}},
+				Resolution: resolution,
+			},
+		}
+	}
+
+	services := convertServices(se(networking.ServiceEntry_STATIC, `{"http": {"path": "/healthz"}}`))
+	if len(services) != 1 || services[0].Attributes.HealthCheck == nil || services[0].Attributes.HealthCheck.HTTP.Path != "/healthz" {
+		t.Fatalf("expected the health check of the ServiceEntry, got %+v", services[0].Attributes)
+	}
+	if hc := convertServices(se(networking.ServiceEntry_STATIC, `{"http": {}}`))[0].Attributes.HealthCheck; hc != nil {
+		t.Fatalf("expected an invalid health check to be ignored, got %+v", hc)
+	}
+	if hc := convertServices(se(networking.ServiceEntry_NONE, `{"tcp": {}}`))[0].Attributes.HealthCheck; hc != nil {
+		t.Fatalf("expected the health check of a passthrough ServiceEntry to be ignored, got %+v", hc)
+	}
+}
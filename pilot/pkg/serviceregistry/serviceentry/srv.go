@@ -0,0 +1,238 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// DNSSRVAnnotation makes istiod resolve the endpoints of a `resolution: DNS` ServiceEntry from DNS SRV records,
+// so that both the address and the port of each endpoint come from DNS. The SRV names are the endpoint addresses,
+// or the hosts if there are no endpoints. The resolved targets are sent to the proxies with EDS.
+// As an SRV record has a single port, only ServiceEntries with a single port are resolved from SRV records.
+const DNSSRVAnnotation = constants.DNSSRVAnnotation
+
+const srvLookupTimeout = 5 * time.Second
+
+// isDNSSRV returns true if the endpoints of the ServiceEntry are resolved from SRV records.
+func isDNSSRV(cfg config.Config) bool {
+	se := cfg.Spec.(*networking.ServiceEntry)
+	return cfg.Annotations[DNSSRVAnnotation] == "true" && se.WorkloadSelector == nil && len(se.Ports) == 1 &&
+		(se.Resolution == networking.ServiceEntry_DNS || se.Resolution == networking.ServiceEntry_DNS_ROUND_ROBIN)
+}
+
+// srvTarget is an endpoint resolved from an SRV record.
+type srvTarget struct {
+	address string
+	port    uint32
+	weight  uint32
+}
+
+// srvResolver periodically resolves the SRV names of the ServiceEntries and calls onChange for the
+// ServiceEntries whose targets changed.
+type srvResolver struct {
+	mu sync.RWMutex
+	// names are the SRV names of each ServiceEntry.
+	names map[types.NamespacedName][]string
+	// targets are the resolved targets of each SRV name.
+	targets map[string][]srvTarget
+
+	refresh  time.Duration
+	trigger  chan struct{}
+	onChange func(types.NamespacedName)
+
+	lookupSRV func(ctx context.Context, name string) ([]*net.SRV, error)
+	lookupIP  func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+func newSRVResolver(refresh time.Duration, onChange func(types.NamespacedName)) *srvResolver {
+	return &srvResolver{
+		names:    map[types.NamespacedName][]string{},
+		targets:  map[string][]srvTarget{},
+		refresh:  refresh,
+		trigger:  make(chan struct{}, 1),
+		onChange: onChange,
+		lookupSRV: func(ctx context.Context, name string) ([]*net.SRV, error) {
+			_, srvs, err := net.DefaultResolver.LookupSRV(ctx, "", "", name)
+			return srvs, err
+		},
+		lookupIP: net.DefaultResolver.LookupIPAddr,
+	}
+}
+
+// watch sets the SRV names of the ServiceEntry, or deletes them if there are none. New names are resolved
+// in the background, and onChange is called once they are.
+func (r *srvResolver) watch(key types.NamespacedName, names []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(names) == 0 {
+		delete(r.names, key)
+		return
+	}
+	r.names[key] = names
+	for _, name := range names {
+		if _, f := r.targets[name]; !f {
+			select {
+			case r.trigger <- struct{}{}:
+			default:
+			}
+			return
+		}
+	}
+}
+
+// lookup returns the last resolved targets of the SRV name.
+func (r *srvResolver) lookup(name string) []srvTarget {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.targets[name]
+}
+
+func (r *srvResolver) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(r.refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-r.trigger:
+		}
+		r.resolveAll()
+	}
+}
+
+// resolveAll resolves all the SRV names, and calls onChange for the ServiceEntries whose targets changed.
+func (r *srvResolver) resolveAll() {
+	r.mu.RLock()
+	names := sets.New[string]()
+	for _, n := range r.names {
+		names.InsertAll(n...)
+	}
+	r.mu.RUnlock()
+
+	resolved := make(map[string][]srvTarget, len(names))
+	for name := range names {
+		targets, err := r.resolve(name)
+		if err != nil {
+			log.Warnf("failed to resolve SRV records of %s: %v", name, err)
+			// Keep using the last resolved targets.
+			if old, f := r.lookupTargets(name); f {
+				resolved[name] = old
+			}
+			continue
+		}
+		resolved[name] = targets
+	}
+
+	r.mu.Lock()
+	changed := sets.New[string]()
+	for name, targets := range resolved {
+		if old, f := r.targets[name]; !f || !reflect.DeepEqual(old, targets) {
+			changed.Insert(name)
+		}
+	}
+	r.targets = resolved
+	var keys []types.NamespacedName
+	for key, n := range r.names {
+		for _, name := range n {
+			if changed.Contains(name) {
+				keys = append(keys, key)
+				break
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	for _, key := range keys {
+		r.onChange(key)
+	}
+}
+
+func (r *srvResolver) lookupTargets(name string) ([]srvTarget, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	targets, f := r.targets[name]
+	return targets, f
+}
+
+// resolve returns the targets of the SRV records with the lowest priority, as records with higher
+// priorities are only meant to be used when none of those are reachable.
+func (r *srvResolver) resolve(name string) ([]srvTarget, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), srvLookupTimeout)
+	defer cancel()
+	srvs, err := r.lookupSRV(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	var targets []srvTarget
+	for _, srv := range srvs {
+		if srv.Priority != srvs[0].Priority {
+			// LookupSRV sorts the records by priority.
+			break
+		}
+		addrs, err := r.lookupIP(ctx, strings.TrimSuffix(srv.Target, "."))
+		if err != nil {
+			return nil, err
+		}
+		weight := uint32(srv.Weight)
+		if weight == 0 {
+			weight = 1
+		}
+		for _, addr := range addrs {
+			targets = append(targets, srvTarget{address: addr.IP.String(), port: uint32(srv.Port), weight: weight})
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].address != targets[j].address {
+			return targets[i].address < targets[j].address
+		}
+		return targets[i].port < targets[j].port
+	})
+	return targets, nil
+}
+
+// srvEndpoints returns the endpoints of the service whose addresses are the SRV names to resolve.
+func srvEndpoints(se *networking.ServiceEntry, service *model.Service) []*networking.WorkloadEntry {
+	if len(se.Endpoints) == 0 {
+		return []*networking.WorkloadEntry{{Address: string(service.Hostname)}}
+	}
+	return se.Endpoints
+}
+
+// srvNames returns the SRV names of the services of a ServiceEntry.
+func srvNames(se *networking.ServiceEntry, services []*model.Service) []string {
+	names := sets.New[string]()
+	for _, service := range services {
+		for _, endpoint := range srvEndpoints(se, service) {
+			names.Insert(endpoint.Address)
+		}
+	}
+	return sets.SortedList(names)
+}
@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+func TestSRVServiceEntry(t *testing.T) {
+	se := config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.ServiceEntry,
+			Name:             "consul",
+			Namespace:        "default",
+			Annotations:      map[string]string{DNSSRVAnnotation: "true"},
+		},
+		Spec: &networking.ServiceEntry{
+			Hosts:      []string{"web.service.consul"},
+			Ports:      []*networking.Port{{Number: 80, Name: "http", Protocol: "HTTP"}},
+			Resolution: networking.ServiceEntry_DNS,
+		},
+	}
+	srvs := map[string][]*net.SRV{
+		"web.service.consul": {
+			{Target: "node1.node.consul.", Port: 21000, Weight: 10},
+			{Target: "node2.node.consul.", Port: 21001},
+			{Target: "backup.node.consul.", Port: 22000, Priority: 1},
+		},
+	}
+	ips := map[string][]net.IPAddr{
+		"node1.node.consul":  {{IP: net.ParseIP("10.0.0.1")}},
+		"node2.node.consul":  {{IP: net.ParseIP("10.0.0.2")}},
+		"backup.node.consul": {{IP: net.ParseIP("10.0.0.3")}},
+	}
+
+	s := newController(nil, nil)
+	var changed []types.NamespacedName
+	s.srv.onChange = func(key types.NamespacedName) { changed = append(changed, key) }
+	s.srv.lookupSRV = func(_ context.Context, name string) ([]*net.SRV, error) {
+		if r, f := srvs[name]; f {
+			return r, nil
+		}
+		return nil, fmt.Errorf("no such host %s", name)
+	}
+	s.srv.lookupIP = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		return ips[host], nil
+	}
+
+	services := convertServices(se)
+	if len(services) != 1 || services[0].Resolution != model.ClientSideLB || !services[0].Attributes.DNSSRV {
+		t.Fatalf("expected an EDS service resolved from SRV records, got %v", services[0])
+	}
+	key := types.NamespacedName{Namespace: "default", Name: "consul"}
+	s.srv.watch(key, srvNames(se.Spec.(*networking.ServiceEntry), services))
+	if instances := s.convertServiceEntryToInstances(se, services); len(instances) != 0 {
+		t.Fatalf("expected no instances before SRV records are resolved, got %v", instances)
+	}
+
+	s.srv.resolveAll()
+	if want := []types.NamespacedName{key}; !reflect.DeepEqual(changed, want) {
+		t.Fatalf("got changes %v, want %v", changed, want)
+	}
+	var got []string
+	for _, instance := range s.convertServiceEntryToInstances(se, services) {
+		got = append(got, fmt.Sprintf("%s:%d/%d weight %d", instance.Endpoint.Address, instance.Endpoint.EndpointPort,
+			instance.ServicePort.Port, instance.Endpoint.LbWeight))
+	}
+	// The backup target has a lower priority so it is not used.
+	if want := []string{"10.0.0.1:21000/80 weight 10", "10.0.0.2:21001/80 weight 1"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got instances %v, want %v", got, want)
+	}
+
+	// Failures keep the previous targets, and unchanged targets do not trigger updates.
+	changed = nil
+	delete(srvs, "web.service.consul")
+	s.srv.resolveAll()
+	if len(changed) != 0 || len(s.srv.lookup("web.service.consul")) != 2 {
+		t.Fatalf("expected previous targets to be kept, got changes %v", changed)
+	}
+}
+
+func TestSRVTargetsChanged(t *testing.T) {
+	se := &config.Config{
+		Meta: config.Meta{
+			GroupVersionKind: gvk.ServiceEntry,
+			Name:             "consul",
+			Namespace:        "default",
+			Annotations:      map[string]string{DNSSRVAnnotation: "true"},
+		},
+		Spec: &networking.ServiceEntry{
+			Hosts:      []string{"web.service.consul"},
+			Ports:      []*networking.Port{{Number: 80, Name: "http", Protocol: "HTTP"}},
+			Resolution: networking.ServiceEntry_DNS,
+		},
+	}
+	store, _, events := initServiceDiscoveryWithOpts(t, false, func(s *Controller) {
+		s.srv.lookupSRV = func(_ context.Context, name string) ([]*net.SRV, error) {
+			return []*net.SRV{{Target: "node1.node.consul.", Port: 21000}, {Target: "node2.node.consul.", Port: 21001}}, nil
+		}
+		s.srv.lookupIP = func(_ context.Context, host string) ([]net.IPAddr, error) {
+			return map[string][]net.IPAddr{
+				"node1.node.consul": {{IP: net.ParseIP("10.0.0.1")}},
+				"node2.node.consul": {{IP: net.ParseIP("10.0.0.2")}},
+			}[host], nil
+		}
+	})
+	createConfigs([]*config.Config{se}, store, t)
+	// The resolved targets are pushed with EDS from the edsQueue.
+	waitUntilEvent(t, events, Event{kind: "eds", host: "web.service.consul", namespace: "default", endpoints: 2})
+}
+
+func TestSRVServiceEntryMultiplePorts(t *testing.T) {
+	se := config.Config{
+		Meta: config.Meta{Annotations: map[string]string{DNSSRVAnnotation: "true"}},
+		Spec: &networking.ServiceEntry{
+			Hosts: []string{"web.service.consul"},
+			Ports: []*networking.Port{
+				{Number: 80, Name: "http", Protocol: "HTTP"},
+				{Number: 443, Name: "https", Protocol: "HTTPS"},
+			},
+			Resolution: networking.ServiceEntry_DNS,
+		},
+	}
+	if isDNSSRV(se) {
+		t.Fatalf("expected service entries with multiple ports not to be resolved from SRV records")
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulation
+
+import (
+	"fmt"
+
+	"istio.io/istio/pilot/pkg/xds/translate"
+	"istio.io/istio/pkg/test"
+)
+
+// TestCase is a call to simulate, with the result it is expected to have. It is the format of the calls of
+// istioctl experimental simulate.
+type TestCase struct {
+	// Name of the test case, used in the output.
+	Name string `json:"name,omitempty"`
+	// Call is the request sent by the proxy.
+	Call Call `json:"call"`
+	// Expect is the expected result of the call. The result is only reported if unset.
+	Expect *Expectation `json:"expect,omitempty"`
+}
+
+// Expectation is the expected result of a call. Unset fields are not checked.
+type Expectation struct {
+	// Error is the expected error of the call, such as "no route matched".
+	Error       string `json:"error,omitempty"`
+	Listener    string `json:"listener,omitempty"`
+	FilterChain string `json:"filterChain,omitempty"`
+	RouteConfig string `json:"routeConfig,omitempty"`
+	VirtualHost string `json:"virtualHost,omitempty"`
+	Route       string `json:"route,omitempty"`
+	Cluster     string `json:"cluster,omitempty"`
+}
+
+// Check returns the differences between the result and the expectation, empty if the result matches.
+func (r Result) Check(want Expectation) []string {
+	var errs []string
+	check := func(field, want, got string) {
+		if want != "" && want != got {
+			errs = append(errs, fmt.Sprintf("want %s %q got %q", field, want, got))
+		}
+	}
+	gotErr := ""
+	if r.Error != nil {
+		gotErr = r.Error.Error()
+	}
+	check("error", want.Error, gotErr)
+	check("listener", want.Listener, r.ListenerMatched)
+	check("filter chain", want.FilterChain, r.FilterChainMatched)
+	check("route config", want.RouteConfig, r.RouteConfigMatched)
+	check("virtual host", want.VirtualHost, r.VirtualHostMatched)
+	check("route", want.Route, r.RouteMatched)
+	check("cluster", want.Cluster, r.ClusterMatched)
+	if want.Error == "" && r.Error != nil {
+		errs = append(errs, fmt.Sprintf("unexpected error %q", gotErr))
+	}
+	return errs
+}
+
+// Simulate generates the configuration of the proxy of the input, as istiod would, and returns the listener,
+// filter chain, route and cluster each call matches. Unlike the other functions of the package, it does not
+// need a test and can be used to test configuration changes in CI.
+func Simulate(in translate.Input, calls []Call) ([]Result, error) {
+	out, err := translate.Translate(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate calls: %v", err)
+	}
+	var results []Result
+	// The matching reports malformed configuration through a test.Failer, which is turned into an error.
+	err = test.Wrap(func(t test.Failer) {
+		sim := &Simulation{t: t, Listeners: out.Listeners, Clusters: out.Clusters, Routes: out.Routes}
+		for _, c := range calls {
+			r := sim.Run(c)
+			r.t = nil
+			results = append(results, r)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate calls: %v", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package simulation
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/xds/translate"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+const config = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  ports:
+  - name: http
+    port: 9080
+  clusterIP: 10.0.0.10
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews
+  http:
+  - match:
+    - uri:
+        prefix: /v2
+    route:
+    - destination:
+        host: reviews
+        subset: v2
+  - route:
+    - destination:
+        host: reviews
+        subset: v1
+`
+
+func TestSimulate(t *testing.T) {
+	results, err := Simulate(translate.Input{Config: config, Proxy: translate.Proxy{Namespace: "default"}}, []Call{
+		{Port: 9080, HostHeader: "reviews", Path: "/v2/ratings", Protocol: HTTP},
+		{Port: 9080, HostHeader: "reviews", Protocol: HTTP},
+		{Port: 9081, HostHeader: "reviews", Protocol: HTTP},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(results), 3)
+	assert.Equal(t, results[0].Check(Expectation{
+		RouteConfig: "9080",
+		Cluster:     "outbound|9080|v2|reviews.default.svc.cluster.local",
+	}), nil)
+	assert.Equal(t, results[1].Check(Expectation{Cluster: "outbound|9080|v1|reviews.default.svc.cluster.local"}), nil)
+	assert.Equal(t, results[2].Check(Expectation{Cluster: "PassthroughCluster"}), nil)
+}
+
+func TestSimulateInvalidProxy(t *testing.T) {
+	if _, err := Simulate(translate.Input{Config: config, Proxy: translate.Proxy{Type: "gateway"}}, nil); err == nil {
+		t.Fatal("expected an error for an invalid proxy type")
+	}
+}
@@ -100,29 +100,29 @@ var (
 )
 
 type Call struct {
-	Address string
-	Port    int
-	Path    string
+	Address string `json:"address,omitempty"`
+	Port    int    `json:"port,omitempty"`
+	Path    string `json:"path,omitempty"`
 
 	// Protocol describes the protocol type. TLS encapsulation is separate
-	Protocol Protocol
+	Protocol Protocol `json:"protocol,omitempty"`
 	// TLS describes the connection tls parameters
 	// TODO: currently this does not verify TLS vs mTLS
-	TLS  TLSMode
-	Alpn string
+	TLS  TLSMode `json:"tls,omitempty"`
+	Alpn string  `json:"alpn,omitempty"`
 
 	// HostHeader is a convenience field for Headers
-	HostHeader string
-	Headers    http.Header
+	HostHeader string      `json:"host,omitempty"`
+	Headers    http.Header `json:"headers,omitempty"`
 
-	Sni string
+	Sni string `json:"sni,omitempty"`
 
 	// CallMode describes the type of call to make.
-	CallMode CallMode
+	CallMode CallMode `json:"mode,omitempty"`
 
-	CustomListenerValidations []CustomFilterChainValidation
+	CustomListenerValidations []CustomFilterChainValidation `json:"-"`
 
-	MtlsSecretConfigName string
+	MtlsSecretConfigName string `json:"-"`
 }
 
 func (c Call) FillDefaults() Call {
@@ -227,13 +227,13 @@ func (r Result) Matches(t *testing.T, want Result) {
 }
 
 type Simulation struct {
-	t         *testing.T
+	t         test.Failer
 	Listeners []*listener.Listener
 	Clusters  []*cluster.Cluster
 	Routes    []*route.RouteConfiguration
 }
 
-func NewSimulationFromConfigGen(t *testing.T, s *v1alpha3.ConfigGenTest, proxy *model.Proxy) *Simulation {
+func NewSimulationFromConfigGen(t test.Failer, s *v1alpha3.ConfigGenTest, proxy *model.Proxy) *Simulation {
 	l := s.Listeners(proxy)
 	sim := &Simulation{
 		t:         t,
@@ -244,7 +244,7 @@ func NewSimulationFromConfigGen(t *testing.T, s *v1alpha3.ConfigGenTest, proxy *
 	return sim
 }
 
-func NewSimulation(t *testing.T, s *xds.FakeDiscoveryServer, proxy *model.Proxy) *Simulation {
+func NewSimulation(t test.Failer, s *xds.FakeDiscoveryServer, proxy *model.Proxy) *Simulation {
 	return NewSimulationFromConfigGen(t, s.ConfigGenTest, proxy)
 }
 
@@ -255,9 +255,16 @@ func (sim *Simulation) withT(t *testing.T) *Simulation {
 	return &cpy
 }
 
+// RunExpectations runs the expectations as sub tests of the test the simulation was created with, which must be a
+// *testing.T.
 func (sim *Simulation) RunExpectations(es []Expect) {
+	t, ok := sim.t.(*testing.T)
+	if !ok {
+		sim.t.Fatalf("expectations can only be run as sub tests of a *testing.T, got %T", sim.t)
+		return
+	}
 	for _, e := range es {
-		sim.t.Run(e.Name, func(t *testing.T) {
+		t.Run(e.Name, func(t *testing.T) {
 			sim.withT(t).Run(e.Call).Matches(t, e.Result)
 		})
 	}
@@ -46,6 +46,8 @@ type TrustBundle struct {
 	mutex              sync.RWMutex
 	mergedCerts        []string
 	updatecb           func()
+	handlersMutex      sync.RWMutex
+	handlers           []func()
 	endpointMutex      sync.RWMutex
 	endpoints          []string
 	endpointUpdateChan chan struct{}
@@ -108,6 +110,14 @@ func (tb *TrustBundle) UpdateCb(updatecb func()) {
 	tb.updatecb = updatecb
 }
 
+// AddUpdateHandler registers a handler called after each change of the trust anchors, in addition to the update
+// callback.
+func (tb *TrustBundle) AddUpdateHandler(h func()) {
+	tb.handlersMutex.Lock()
+	defer tb.handlersMutex.Unlock()
+	tb.handlers = append(tb.handlers, h)
+}
+
 // GetTrustBundle : Retrieves all the trustAnchors for current Spiffee Trust Domain
 func (tb *TrustBundle) GetTrustBundle() []string {
 	tb.mutex.RLock()
@@ -186,6 +196,12 @@ func (tb *TrustBundle) UpdateTrustAnchor(anchorConfig *TrustAnchorUpdate) error
 	if tb.updatecb != nil {
 		tb.updatecb()
 	}
+	tb.handlersMutex.RLock()
+	handlers := tb.handlers
+	tb.handlersMutex.RUnlock()
+	for _, h := range handlers {
+		h()
+	}
 	return nil
 }
 
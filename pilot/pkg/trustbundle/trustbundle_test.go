@@ -160,6 +160,8 @@ func TestUpdateTrustAnchor(t *testing.T) {
 	cbCounter := 0
 	tb := NewTrustBundle(nil)
 	tb.UpdateCb(func() { cbCounter++ })
+	handlerCounter := 0
+	tb.AddUpdateHandler(func() { handlerCounter++ })
 
 	var trustedCerts []string
 	var err error
@@ -253,6 +255,9 @@ func TestUpdateTrustAnchor(t *testing.T) {
 	if !isEqSliceStr(trustedCerts, []string{}) || cbCounter != 5 {
 		t.Errorf("cert removal update failed. Callback value is %v", cbCounter)
 	}
+	if handlerCounter != cbCounter {
+		t.Errorf("expected the update handler to be called %v times, got %v", cbCounter, handlerCounter)
+	}
 }
 
 func expectTbCount(t *testing.T, tb *TrustBundle, expAnchorCount int, ti time.Duration, strPrefix string) {
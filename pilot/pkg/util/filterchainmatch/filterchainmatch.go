@@ -0,0 +1,388 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filterchainmatch reports which filter chain of an Envoy listener a connection would match, following
+// the filter chain match order of Envoy, and why the other filter chains are skipped.
+package filterchainmatch
+
+import (
+	"fmt"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// Connection describes a synthetic connection, as seen by the listener after its listener filters: the server
+// name and the application protocols are detected by the TLS and HTTP inspectors.
+type Connection struct {
+	DestinationIP        string   `json:"destinationIP"`
+	DestinationPort      uint32   `json:"destinationPort"`
+	SourceIP             string   `json:"sourceIP,omitempty"`
+	SourcePort           uint32   `json:"sourcePort,omitempty"`
+	ServerName           string   `json:"serverName,omitempty"`
+	TransportProtocol    string   `json:"transportProtocol,omitempty"`
+	ApplicationProtocols []string `json:"applicationProtocols,omitempty"`
+}
+
+// Result is the filter chain a connection matches on a listener.
+type Result struct {
+	Listener string `json:"listener"`
+	// FilterChain is the matched filter chain. It is empty if no filter chain matches, in which case Envoy
+	// closes the connection.
+	FilterChain string `json:"filterChain,omitempty"`
+	// Default is true if no filter chain matches and the connection uses the default filter chain.
+	Default bool `json:"default,omitempty"`
+	// Skipped are the filter chains that do not match, in the order of the listener.
+	Skipped []SkippedFilterChain `json:"skipped,omitempty"`
+}
+
+// SkippedFilterChain is a filter chain that does not match the connection, and the reason why.
+type SkippedFilterChain struct {
+	FilterChain string `json:"filterChain"`
+	Reason      string `json:"reason"`
+}
+
+// connection is the parsed Connection.
+type connection struct {
+	Connection
+	destination netip.Addr
+	source      netip.Addr
+}
+
+func parse(c Connection) (connection, error) {
+	conn := connection{Connection: c}
+	var err error
+	if conn.destination, err = netip.ParseAddr(c.DestinationIP); err != nil {
+		return conn, fmt.Errorf("invalid destination IP %q", c.DestinationIP)
+	}
+	if c.SourceIP != "" {
+		if conn.source, err = netip.ParseAddr(c.SourceIP); err != nil {
+			return conn, fmt.Errorf("invalid source IP %q", c.SourceIP)
+		}
+	}
+	if conn.TransportProtocol == "" {
+		conn.TransportProtocol = "raw_buffer"
+		if c.ServerName != "" {
+			conn.TransportProtocol = "tls"
+		}
+	}
+	return conn, nil
+}
+
+// criterion is a field of the filter chain match. score returns -1 if the filter chain does not match the
+// connection, 0 if the field is not set, and a higher value for more specific matches.
+type criterion struct {
+	name  string
+	value func(c connection) string
+	field func(m *listener.FilterChainMatch) string
+	score func(m *listener.FilterChainMatch, c connection) int
+}
+
+// criteria are the fields of the filter chain match, in the order Envoy matches them. At each step, only the
+// filter chains with the most specific match are kept; Envoy does not fall back to less specific filter chains
+// when they fail to match a later field.
+var criteria = []criterion{
+	{
+		name:  "destination port",
+		value: func(c connection) string { return strconv.Itoa(int(c.DestinationPort)) },
+		field: func(m *listener.FilterChainMatch) string { return strconv.Itoa(int(m.GetDestinationPort().GetValue())) },
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			if m.GetDestinationPort() == nil {
+				return 0
+			}
+			if m.GetDestinationPort().GetValue() == c.DestinationPort {
+				return 1
+			}
+			return -1
+		},
+	},
+	{
+		name:  "destination IP",
+		value: func(c connection) string { return c.DestinationIP },
+		field: func(m *listener.FilterChainMatch) string { return cidrs(m.GetPrefixRanges()) },
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			return cidrScore(m.GetPrefixRanges(), c.destination)
+		},
+	},
+	{
+		name:  "server name",
+		value: func(c connection) string { return c.ServerName },
+		field: func(m *listener.FilterChainMatch) string { return strings.Join(m.GetServerNames(), ",") },
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			return serverNameScore(m.GetServerNames(), c.ServerName)
+		},
+	},
+	{
+		name:  "transport protocol",
+		value: func(c connection) string { return c.TransportProtocol },
+		field: func(m *listener.FilterChainMatch) string { return m.GetTransportProtocol() },
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			if m.GetTransportProtocol() == "" {
+				return 0
+			}
+			if m.GetTransportProtocol() == c.TransportProtocol {
+				return 1
+			}
+			return -1
+		},
+	},
+	{
+		name:  "application protocols",
+		value: func(c connection) string { return strings.Join(c.ApplicationProtocols, ",") },
+		field: func(m *listener.FilterChainMatch) string { return strings.Join(m.GetApplicationProtocols(), ",") },
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			if len(m.GetApplicationProtocols()) == 0 {
+				return 0
+			}
+			for _, want := range m.GetApplicationProtocols() {
+				for _, got := range c.ApplicationProtocols {
+					if want == got {
+						return 1
+					}
+				}
+			}
+			return -1
+		},
+	},
+	{
+		name:  "direct source IP",
+		value: func(c connection) string { return c.SourceIP },
+		field: func(m *listener.FilterChainMatch) string { return cidrs(m.GetDirectSourcePrefixRanges()) },
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			return cidrScore(m.GetDirectSourcePrefixRanges(), c.source)
+		},
+	},
+	{
+		name:  "source type",
+		value: func(c connection) string { return c.SourceIP },
+		field: func(m *listener.FilterChainMatch) string { return m.GetSourceType().String() },
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			local := c.source.IsValid() && (c.source.IsLoopback() || c.source == c.destination)
+			switch m.GetSourceType() {
+			case listener.FilterChainMatch_ANY:
+				return 0
+			case listener.FilterChainMatch_SAME_IP_OR_LOOPBACK:
+				if local {
+					return 1
+				}
+			case listener.FilterChainMatch_EXTERNAL:
+				if !local {
+					return 1
+				}
+			}
+			return -1
+		},
+	},
+	{
+		name:  "source IP",
+		value: func(c connection) string { return c.SourceIP },
+		field: func(m *listener.FilterChainMatch) string { return cidrs(m.GetSourcePrefixRanges()) },
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			return cidrScore(m.GetSourcePrefixRanges(), c.source)
+		},
+	},
+	{
+		name:  "source port",
+		value: func(c connection) string { return strconv.Itoa(int(c.SourcePort)) },
+		field: func(m *listener.FilterChainMatch) string {
+			ports := make([]string, 0, len(m.GetSourcePorts()))
+			for _, p := range m.GetSourcePorts() {
+				ports = append(ports, strconv.Itoa(int(p)))
+			}
+			return strings.Join(ports, ",")
+		},
+		score: func(m *listener.FilterChainMatch, c connection) int {
+			if len(m.GetSourcePorts()) == 0 {
+				return 0
+			}
+			for _, p := range m.GetSourcePorts() {
+				if p == c.SourcePort {
+					return 1
+				}
+			}
+			return -1
+		},
+	},
+}
+
+// cidrScore scores the most specific range containing the address by its prefix length.
+func cidrScore(ranges []*core.CidrRange, addr netip.Addr) int {
+	if len(ranges) == 0 {
+		return 0
+	}
+	best := -1
+	for _, r := range ranges {
+		ip, err := netip.ParseAddr(r.GetAddressPrefix())
+		if err != nil || !addr.IsValid() {
+			continue
+		}
+		// Envoy matches all addresses when the prefix length is not set.
+		bits := int(r.GetPrefixLen().GetValue())
+		prefix, err := ip.Prefix(bits)
+		if err != nil || !prefix.Contains(addr) {
+			continue
+		}
+		if bits+1 > best {
+			best = bits + 1
+		}
+	}
+	return best
+}
+
+// serverNameScore prefers exact server names, then the longest wildcard suffix.
+func serverNameScore(names []string, serverName string) int {
+	if len(names) == 0 {
+		return 0
+	}
+	best := -1
+	for _, n := range names {
+		if n == serverName {
+			return 1 << 16
+		}
+		if suffix := strings.TrimPrefix(n, "*"); suffix != n && serverName != "" &&
+			strings.HasSuffix(serverName, suffix) && len(serverName) > len(suffix) && len(suffix) > best {
+			best = len(suffix)
+		}
+	}
+	return best
+}
+
+func cidrs(ranges []*core.CidrRange) string {
+	out := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		out = append(out, r.GetAddressPrefix()+"/"+strconv.Itoa(int(r.GetPrefixLen().GetValue())))
+	}
+	return strings.Join(out, ",")
+}
+
+// Match returns the filter chain of the listener matching the connection.
+func Match(l *listener.Listener, c Connection) (Result, error) {
+	conn, err := parse(c)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{Listener: l.GetName()}
+	type candidate struct {
+		name  string
+		match *listener.FilterChainMatch
+	}
+	candidates := make([]candidate, 0, len(l.GetFilterChains()))
+	for i, fc := range l.GetFilterChains() {
+		candidates = append(candidates, candidate{name: filterChainName(fc, i), match: fc.GetFilterChainMatch()})
+	}
+	skipped := map[string]string{}
+	for _, cr := range criteria {
+		scores := make([]int, len(candidates))
+		best := -1
+		for i, cand := range candidates {
+			scores[i] = cr.score(cand.match, conn)
+			if scores[i] > best {
+				best = scores[i]
+			}
+		}
+		kept := candidates[:0]
+		for i, cand := range candidates {
+			switch {
+			case scores[i] < 0:
+				skipped[cand.name] = fmt.Sprintf("%s %q does not match %s", cr.name, cr.value(conn), cr.field(cand.match))
+			case scores[i] < best:
+				skipped[cand.name] = fmt.Sprintf("another filter chain matches the %s %q more specifically", cr.name, cr.value(conn))
+			default:
+				kept = append(kept, cand)
+			}
+		}
+		candidates = kept
+	}
+
+	for i, fc := range l.GetFilterChains() {
+		name := filterChainName(fc, i)
+		if reason, f := skipped[name]; f {
+			res.Skipped = append(res.Skipped, SkippedFilterChain{FilterChain: name, Reason: reason})
+		}
+	}
+	switch {
+	case len(candidates) > 0:
+		// Envoy rejects listeners with several filter chains with the same match, so there is a single one.
+		res.FilterChain = candidates[0].name
+	case l.GetDefaultFilterChain() != nil:
+		res.FilterChain = filterChainName(l.GetDefaultFilterChain(), -1)
+		res.Default = true
+	}
+	return res, nil
+}
+
+func filterChainName(fc *listener.FilterChain, i int) string {
+	if fc.GetName() != "" {
+		return fc.GetName()
+	}
+	if i < 0 {
+		return "default"
+	}
+	return "#" + strconv.Itoa(i)
+}
+
+// SelectListener returns the listener accepting the connection. Inbound connections of sidecars are redirected
+// to the virtual inbound listener. Other connections are accepted by the listener bound to the destination
+// address, then the listener bound to the wildcard address of the destination port; connections redirected to
+// the virtual outbound listener of sidecars are handed off in the same way.
+func SelectListener(listeners []*listener.Listener, c Connection, inbound bool) (*listener.Listener, error) {
+	conn, err := parse(c)
+	if err != nil {
+		return nil, err
+	}
+	byName := func(name string) *listener.Listener {
+		for _, l := range listeners {
+			if l.GetName() == name {
+				return l
+			}
+		}
+		return nil
+	}
+	if inbound {
+		if l := byName(model.VirtualInboundListenerName); l != nil {
+			return l, nil
+		}
+		return nil, fmt.Errorf("no %s listener", model.VirtualInboundListenerName)
+	}
+	wildcard := "0.0.0.0"
+	if conn.destination.Is6() {
+		wildcard = "::"
+	}
+	var wildcardListener *listener.Listener
+	for _, l := range listeners {
+		addr := l.GetAddress().GetSocketAddress()
+		if addr.GetPortValue() != c.DestinationPort {
+			continue
+		}
+		if ip, err := netip.ParseAddr(addr.GetAddress()); err == nil && ip == conn.destination {
+			return l, nil
+		}
+		if addr.GetAddress() == wildcard && wildcardListener == nil {
+			wildcardListener = l
+		}
+	}
+	if wildcardListener != nil {
+		return wildcardListener, nil
+	}
+	if l := byName(model.VirtualOutboundListenerName); l != nil {
+		return l, nil
+	}
+	return nil, fmt.Errorf("no listener accepts connections to %s",
+		netip.AddrPortFrom(conn.destination, uint16(c.DestinationPort)))
+}
@@ -0,0 +1,180 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filterchainmatch
+
+import (
+	"reflect"
+	"testing"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func virtualInbound() *listener.Listener {
+	cidr := func(ip string, bits uint32) []*core.CidrRange {
+		return []*core.CidrRange{{AddressPrefix: ip, PrefixLen: wrapperspb.UInt32(bits)}}
+	}
+	return &listener.Listener{
+		Name: "virtualInbound",
+		FilterChains: []*listener.FilterChain{
+			{
+				Name:             "0.0.0.0_9080",
+				FilterChainMatch: &listener.FilterChainMatch{DestinationPort: wrapperspb.UInt32(9080), TransportProtocol: "tls"},
+			},
+			{
+				Name: "0.0.0.0_9080_plaintext",
+				FilterChainMatch: &listener.FilterChainMatch{
+					DestinationPort:      wrapperspb.UInt32(9080),
+					TransportProtocol:    "raw_buffer",
+					ApplicationProtocols: []string{"http/1.1", "h2c"},
+				},
+			},
+			{
+				Name:             "10.0.0.0_8443",
+				FilterChainMatch: &listener.FilterChainMatch{DestinationPort: wrapperspb.UInt32(8443), PrefixRanges: cidr("10.0.0.0", 8)},
+			},
+			{
+				Name: "10.1.0.0_8443_sni",
+				FilterChainMatch: &listener.FilterChainMatch{
+					DestinationPort: wrapperspb.UInt32(8443),
+					PrefixRanges:    cidr("10.1.0.0", 16),
+					ServerNames:     []string{"*.example.com"},
+				},
+			},
+			{Name: "passthrough", FilterChainMatch: &listener.FilterChainMatch{PrefixRanges: cidr("0.0.0.0", 0)}},
+		},
+		DefaultFilterChain: &listener.FilterChain{Name: "blackhole"},
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		name        string
+		conn        Connection
+		filterChain string
+		isDefault   bool
+		skipped     []SkippedFilterChain
+	}{
+		{
+			name:        "mtls",
+			conn:        Connection{DestinationIP: "10.1.2.3", DestinationPort: 9080, ServerName: "outbound_.9080_._.reviews.default.svc.cluster.local"},
+			filterChain: "0.0.0.0_9080",
+			skipped: []SkippedFilterChain{
+				{FilterChain: "0.0.0.0_9080_plaintext", Reason: `transport protocol "tls" does not match raw_buffer`},
+				{FilterChain: "10.0.0.0_8443", Reason: `destination port "9080" does not match 8443`},
+				{FilterChain: "10.1.0.0_8443_sni", Reason: `destination port "9080" does not match 8443`},
+				{FilterChain: "passthrough", Reason: `another filter chain matches the destination port "9080" more specifically`},
+			},
+		},
+		{
+			name:        "plaintext without http",
+			conn:        Connection{DestinationIP: "10.1.2.3", DestinationPort: 9080},
+			filterChain: "blackhole",
+			isDefault:   true,
+			skipped: []SkippedFilterChain{
+				{FilterChain: "0.0.0.0_9080", Reason: `transport protocol "raw_buffer" does not match tls`},
+				{FilterChain: "0.0.0.0_9080_plaintext", Reason: `application protocols "" does not match http/1.1,h2c`},
+				{FilterChain: "10.0.0.0_8443", Reason: `destination port "9080" does not match 8443`},
+				{FilterChain: "10.1.0.0_8443_sni", Reason: `destination port "9080" does not match 8443`},
+				{FilterChain: "passthrough", Reason: `another filter chain matches the destination port "9080" more specifically`},
+			},
+		},
+		{
+			name:        "most specific destination IP",
+			conn:        Connection{DestinationIP: "10.1.2.3", DestinationPort: 8443, ServerName: "a.example.com"},
+			filterChain: "10.1.0.0_8443_sni",
+		},
+		{
+			name:        "no fallback to less specific destination IP",
+			conn:        Connection{DestinationIP: "10.1.2.3", DestinationPort: 8443, ServerName: "a.example.org"},
+			filterChain: "blackhole",
+			isDefault:   true,
+		},
+		{
+			name:        "less specific destination IP",
+			conn:        Connection{DestinationIP: "10.2.2.3", DestinationPort: 8443, ServerName: "a.example.org"},
+			filterChain: "10.0.0.0_8443",
+		},
+		{
+			name:        "passthrough",
+			conn:        Connection{DestinationIP: "10.2.2.3", DestinationPort: 3306},
+			filterChain: "passthrough",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			res, err := Match(virtualInbound(), c.conn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.FilterChain != c.filterChain || res.Default != c.isDefault {
+				t.Fatalf("matched %q (default %v), want %q (default %v): %+v", res.FilterChain, res.Default, c.filterChain, c.isDefault, res.Skipped)
+			}
+			if c.skipped != nil && !reflect.DeepEqual(res.Skipped, c.skipped) {
+				t.Fatalf("skipped %+v, want %+v", res.Skipped, c.skipped)
+			}
+		})
+	}
+
+	if _, err := Match(virtualInbound(), Connection{DestinationIP: "not-an-ip"}); err == nil {
+		t.Fatal("expected an error for an invalid IP")
+	}
+}
+
+func TestServerNameScore(t *testing.T) {
+	if serverNameScore([]string{"*.example.com"}, "example.com") >= 0 {
+		t.Fatal("wildcard must not match the domain itself")
+	}
+	if serverNameScore([]string{"*.com"}, "a.example.com") >= serverNameScore([]string{"*.example.com"}, "a.example.com") {
+		t.Fatal("expected longer wildcard suffixes to be more specific")
+	}
+	if serverNameScore([]string{"*.example.com"}, "a.example.com") >= serverNameScore([]string{"a.example.com"}, "a.example.com") {
+		t.Fatal("expected exact server names to be more specific")
+	}
+}
+
+func TestSelectListener(t *testing.T) {
+	socket := func(name, ip string, port uint32) *listener.Listener {
+		return &listener.Listener{Name: name, Address: &core.Address{Address: &core.Address_SocketAddress{
+			SocketAddress: &core.SocketAddress{Address: ip, PortSpecifier: &core.SocketAddress_PortValue{PortValue: port}},
+		}}}
+	}
+	listeners := []*listener.Listener{
+		socket("virtualOutbound", "0.0.0.0", 15001),
+		socket("virtualInbound", "0.0.0.0", 15006),
+		socket("0.0.0.0_9080", "0.0.0.0", 9080),
+		socket("10.96.0.10_9080", "10.96.0.10", 9080),
+	}
+	cases := []struct {
+		conn    Connection
+		inbound bool
+		want    string
+	}{
+		{conn: Connection{DestinationIP: "10.96.0.10", DestinationPort: 9080}, want: "10.96.0.10_9080"},
+		{conn: Connection{DestinationIP: "10.96.0.11", DestinationPort: 9080}, want: "0.0.0.0_9080"},
+		{conn: Connection{DestinationIP: "10.96.0.11", DestinationPort: 3306}, want: "virtualOutbound"},
+		{conn: Connection{DestinationIP: "10.96.0.10", DestinationPort: 9080}, inbound: true, want: "virtualInbound"},
+	}
+	for _, c := range cases {
+		l, err := SelectListener(listeners, c.conn, c.inbound)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.GetName() != c.want {
+			t.Errorf("%+v: got listener %q, want %q", c.conn, l.GetName(), c.want)
+		}
+	}
+}
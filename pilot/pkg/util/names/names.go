@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package names builds the names of the generated Envoy resources that are derived from user supplied names,
+// and detects when different configurations would generate the same name. Such collisions are silent in Envoy:
+// resources with the same name are merged or replaced, which can send traffic to the wrong upstream.
+package names
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// MaxSNILength is the maximum length of the SNI set by Envoy clusters.
+const MaxSNILength = 255
+
+// hashSuffixLength is the length of the suffix added to truncated names.
+const hashSuffixLength = 9
+
+// Truncate returns the name if it is not longer than max. Longer names are truncated, and suffixed with a hash
+// of the full name so that truncated names stay distinct.
+func Truncate(name string, max int) string {
+	if len(name) <= max || max <= hashSuffixLength {
+		return name
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return fmt.Sprintf("%s-%08x", name[:max-hashSuffixLength], h.Sum32())
+}
+
+// Ambiguous returns true if the name joining the parts with the separator is ambiguous, that is if any part
+// contains the separator. Ambiguous names can be generated from different parts.
+func Ambiguous(sep string, parts ...string) bool {
+	for _, p := range parts {
+		if strings.Contains(p, sep) {
+			return true
+		}
+	}
+	return false
+}
+
+// GatewayHTTPSRouteName returns the name of the route configuration of an HTTPS server of a gateway.
+// Format: https.<port number>.<port name>.<gateway name>.<gateway namespace>[.<bind>]
+func GatewayHTTPSRouteName(portNumber uint32, portName, gatewayName, namespace, bind string) string {
+	name := "https." + strconv.Itoa(int(portNumber)) + "." + portName + "." + gatewayName + "." + namespace
+	if bind != "" {
+		name += "." + bind
+	}
+	return name
+}
+
+// AmbiguousGatewayHTTPSRouteName returns true if the route name of the HTTPS server can also be generated for
+// another server, as the port name or the gateway name contain dots.
+func AmbiguousGatewayHTTPSRouteName(portName, gatewayName string) bool {
+	return Ambiguous(".", portName, gatewayName)
+}
+
+// Tracker records the source of each generated name to detect when different sources generate the same name.
+// It is not safe for concurrent use.
+type Tracker struct {
+	sources map[string]string
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{sources: map[string]string{}}
+}
+
+// Add records that the name is generated from the source. If the name was already generated from a different
+// source, it returns that source and true.
+func (t *Tracker) Add(name, source string) (string, bool) {
+	if existing, f := t.sources[name]; f {
+		return existing, existing != source
+	}
+	t.sources[name] = source
+	return "", false
+}
@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package names
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("outbound_.80_._.example.com", MaxSNILength); got != "outbound_.80_._.example.com" {
+		t.Fatalf("short names must not change, got %q", got)
+	}
+	long1 := strings.Repeat("a", 300) + ".example.com"
+	long2 := strings.Repeat("a", 300) + ".example.org"
+	got1, got2 := Truncate(long1, MaxSNILength), Truncate(long2, MaxSNILength)
+	if len(got1) != MaxSNILength || len(got2) != MaxSNILength {
+		t.Fatalf("expected truncated names of %d characters, got %d and %d", MaxSNILength, len(got1), len(got2))
+	}
+	if got1 == got2 {
+		t.Fatalf("expected names with the same prefix to stay distinct, got %q", got1)
+	}
+	if Truncate(long1, MaxSNILength) != got1 {
+		t.Fatalf("expected truncation to be stable")
+	}
+}
+
+func TestGatewayHTTPSRouteName(t *testing.T) {
+	a := GatewayHTTPSRouteName(443, "https.a", "gw", "default", "")
+	b := GatewayHTTPSRouteName(443, "https", "a.gw", "default", "")
+	if a != b || a != "https.443.https.a.gw.default" {
+		t.Fatalf("expected ambiguous names, got %q and %q", a, b)
+	}
+	if !AmbiguousGatewayHTTPSRouteName("https.a", "gw") || AmbiguousGatewayHTTPSRouteName("https", "gw") {
+		t.Fatalf("unexpected ambiguity")
+	}
+	if got := GatewayHTTPSRouteName(443, "https", "gw", "default", "10.0.0.1"); got != "https.443.https.gw.default.10.0.0.1" {
+		t.Fatalf("unexpected name with bind %q", got)
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker()
+	if _, collides := tr.Add("https.443.https.a.gw.default", "default/gw https.a"); collides {
+		t.Fatalf("unexpected collision")
+	}
+	if _, collides := tr.Add("https.443.https.a.gw.default", "default/gw https.a"); collides {
+		t.Fatalf("the same source must not collide")
+	}
+	if other, collides := tr.Add("https.443.https.a.gw.default", "default/a.gw https"); !collides || other != "default/gw https.a" {
+		t.Fatalf("expected a collision with default/gw https.a, got %q %v", other, collides)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package trafficpolicy implements the inheritance of the DestinationRule traffic policy by its subsets.
+//
+// By default, each of the connection pool, load balancer, outlier detection and TLS settings of a subset
+// replaces the one of the DestinationRule as a whole: a subset setting only the TLS mode drops the SNI and
+// certificates of the parent policy. DestinationRules with the SubsetPolicyInheritanceAnnotation set to
+// InheritanceMerge instead merge the subset settings into the parent settings field by field.
+package trafficpolicy
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+// SubsetPolicyInheritanceAnnotation selects how the subsets of a DestinationRule inherit its traffic policy.
+// TODO: move to API
+const SubsetPolicyInheritanceAnnotation = "networking.istio.io/subset-policy-inheritance"
+
+const (
+	// InheritanceReplace replaces each parent setting configured by the subset. This is the default.
+	InheritanceReplace = "replace"
+	// InheritanceMerge merges each setting configured by the subset into the parent setting, field by field.
+	InheritanceMerge = "merge"
+)
+
+// atomicFields are always taken from the subset when it configures their parent message, as their zero
+// value is meaningful and cannot be told apart from an unset field.
+var atomicFields = map[protoreflect.FullName]bool{
+	"istio.networking.v1alpha3.ClientTLSSettings.mode": true,
+}
+
+// Merges returns true if the annotations of a DestinationRule select the field-level inheritance of its
+// traffic policy by its subsets.
+func Merges(annotations map[string]string) bool {
+	return annotations[SubsetPolicyInheritanceAnnotation] == InheritanceMerge
+}
+
+// MergeSettings returns the settings of the parent with the fields configured by the subset overridden.
+// Nested messages are merged recursively, while scalars, lists, maps, well known types and oneof members of
+// a different kind replace the parent value. The inputs are not modified.
+func MergeSettings[T proto.Message](parent, subset T) T {
+	if !parent.ProtoReflect().IsValid() {
+		return subset
+	}
+	if !subset.ProtoReflect().IsValid() {
+		return parent
+	}
+	merged := proto.Clone(parent).(T)
+	mergeFields(merged.ProtoReflect(), proto.Clone(subset).ProtoReflect())
+	return merged
+}
+
+func mergeFields(dst, src protoreflect.Message) {
+	src.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if mergeable(fd) && dst.Has(fd) {
+			mergeFields(dst.Mutable(fd).Message(), v.Message())
+		} else {
+			dst.Set(fd, v)
+		}
+		return true
+	})
+	// Range skips the zero values of the subset, which still override the atomic fields.
+	fields := src.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); atomicFields[fd.FullName()] && !src.Has(fd) {
+			dst.Clear(fd)
+		}
+	}
+}
+
+func mergeable(fd protoreflect.FieldDescriptor) bool {
+	return fd.Kind() == protoreflect.MessageKind && !fd.IsList() && !fd.IsMap() &&
+		!strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf.")
+}
+
+// DroppedFields returns the paths of the fields configured in the parent settings that are dropped when the
+// subset settings replace them, or nil if the subset does not configure the settings.
+func DroppedFields(parent, subset proto.Message) []string {
+	if !parent.ProtoReflect().IsValid() || !subset.ProtoReflect().IsValid() {
+		return nil
+	}
+	var dropped []string
+	droppedFields(parent.ProtoReflect(), subset.ProtoReflect(), "", &dropped)
+	sort.Strings(dropped)
+	return dropped
+}
+
+func droppedFields(parent, subset protoreflect.Message, prefix string, dropped *[]string) {
+	parent.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		path := prefix + fd.JSONName()
+		switch {
+		case atomicFields[fd.FullName()]:
+		case fd.ContainingOneof() != nil && subset.WhichOneof(fd.ContainingOneof()) != nil:
+			// The subset explicitly selects another member of the oneof.
+		case !subset.Has(fd):
+			*dropped = append(*dropped, path)
+		case mergeable(fd):
+			droppedFields(v.Message(), subset.Get(fd).Message(), path+".", dropped)
+		}
+		return true
+	})
+}
+
+// DroppedSettings returns the paths of the fields of the parent traffic policy dropped by the settings of the
+// subset traffic policy when they replace the parent settings.
+func DroppedSettings(parent, subset *networking.TrafficPolicy) []string {
+	if parent == nil || subset == nil {
+		return nil
+	}
+	var dropped []string
+	for _, s := range []struct {
+		name           string
+		parent, subset proto.Message
+	}{
+		{"connectionPool", parent.ConnectionPool, subset.ConnectionPool},
+		{"loadBalancer", parent.LoadBalancer, subset.LoadBalancer},
+		{"outlierDetection", parent.OutlierDetection, subset.OutlierDetection},
+		{"tls", parent.Tls, subset.Tls},
+	} {
+		for _, f := range DroppedFields(s.parent, s.subset) {
+			dropped = append(dropped, s.name+"."+f)
+		}
+	}
+	return dropped
+}
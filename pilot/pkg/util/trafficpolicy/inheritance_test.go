@@ -0,0 +1,114 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trafficpolicy
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestMergeSettings(t *testing.T) {
+	parent := &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+			ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+				HashKey:         &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: "x-user"},
+				MinimumRingSize: 1024,
+			},
+		},
+	}
+
+	// The nested consistent hash is merged.
+	got := MergeSettings(parent, &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+			ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{MinimumRingSize: 2048},
+		},
+	})
+	assert.Equal(t, got, &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+			ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+				HashKey:         &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: "x-user"},
+				MinimumRingSize: 2048,
+			},
+		},
+	})
+	assert.Equal(t, parent.GetConsistentHash().GetMinimumRingSize(), uint64(1024))
+
+	// Another member of the oneof replaces the parent one.
+	got = MergeSettings(parent, &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_ROUND_ROBIN},
+	})
+	assert.Equal(t, got, &networking.LoadBalancerSettings{
+		LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_ROUND_ROBIN},
+	})
+
+	// Well known types are replaced as a whole.
+	outlier := MergeSettings(
+		&networking.OutlierDetection{Interval: &durationpb.Duration{Seconds: 10, Nanos: 500}, MinHealthPercent: 20},
+		&networking.OutlierDetection{Interval: &durationpb.Duration{Seconds: 5}},
+	)
+	assert.Equal(t, outlier, &networking.OutlierDetection{Interval: &durationpb.Duration{Seconds: 5}, MinHealthPercent: 20})
+
+	var unset *networking.LoadBalancerSettings
+	assert.Equal(t, MergeSettings(unset, parent), parent)
+	assert.Equal(t, MergeSettings(parent, unset), parent)
+}
+
+func TestDroppedSettings(t *testing.T) {
+	parent := &networking.TrafficPolicy{
+		LoadBalancer: &networking.LoadBalancerSettings{
+			LbPolicy: &networking.LoadBalancerSettings_Simple{Simple: networking.LoadBalancerSettings_LEAST_REQUEST},
+			LocalityLbSetting: &networking.LocalityLoadBalancerSetting{
+				Distribute: []*networking.LocalityLoadBalancerSetting_Distribute{{From: "a", To: map[string]uint32{"b": 100}}},
+			},
+		},
+		Tls: &networking.ClientTLSSettings{
+			Mode:           networking.ClientTLSSettings_SIMPLE,
+			Sni:            "reviews.example.com",
+			CaCertificates: "/etc/certs/ca.pem",
+		},
+		OutlierDetection: &networking.OutlierDetection{
+			Interval: durationpb.New(10_000_000_000),
+		},
+	}
+	subset := &networking.TrafficPolicy{
+		LoadBalancer: &networking.LoadBalancerSettings{
+			LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+				ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+					HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true},
+				},
+			},
+		},
+		Tls: &networking.ClientTLSSettings{
+			Mode: networking.ClientTLSSettings_SIMPLE,
+			Sni:  "reviews-v2.example.com",
+		},
+	}
+	assert.Equal(t, DroppedSettings(parent, subset), []string{
+		"loadBalancer.localityLbSetting",
+		"tls.caCertificates",
+	})
+	assert.Equal(t, DroppedSettings(parent, &networking.TrafficPolicy{}), nil)
+	assert.Equal(t, DroppedSettings(nil, subset), nil)
+}
+
+func TestMerges(t *testing.T) {
+	assert.Equal(t, Merges(nil), false)
+	assert.Equal(t, Merges(map[string]string{SubsetPolicyInheritanceAnnotation: InheritanceReplace}), false)
+	assert.Equal(t, Merges(map[string]string{SubsetPolicyInheritanceAnnotation: InheritanceMerge}), true)
+}
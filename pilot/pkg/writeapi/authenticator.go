@@ -0,0 +1,62 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writeapi
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/k8s/tokenreview"
+	"istio.io/istio/security/pkg/server/ca/authenticate"
+)
+
+// TokenAuthenticator authenticates the callers with service account tokens issued for the Audience only.
+type TokenAuthenticator struct {
+	meshHolder mesh.Holder
+	client     kubernetes.Interface
+}
+
+var _ security.Authenticator = &TokenAuthenticator{}
+
+// NewTokenAuthenticator creates a TokenAuthenticator reviewing the tokens with the API server of the client.
+func NewTokenAuthenticator(meshHolder mesh.Holder, client kubernetes.Interface) *TokenAuthenticator {
+	return &TokenAuthenticator{meshHolder: meshHolder, client: client}
+}
+
+func (a *TokenAuthenticator) AuthenticatorType() string {
+	return "WriteAPITokenAuthenticator"
+}
+
+// Authenticate returns the SPIFFE identity of the service account of the token.
+func (a *TokenAuthenticator) Authenticate(ctx security.AuthContext) (*security.Caller, error) {
+	if ctx.Request == nil {
+		return nil, nil
+	}
+	token, err := security.ExtractRequestToken(ctx.Request)
+	if err != nil {
+		return nil, fmt.Errorf("target JWT extraction error: %v", err)
+	}
+	id, err := tokenreview.ValidateK8sJwt(a.client, token, []string{Audience})
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate the JWT: %v", err)
+	}
+	return &security.Caller{
+		AuthSource: security.AuthSourceIDToken,
+		Identities: []string{fmt.Sprintf(authenticate.IdentityTemplate, a.meshHolder.Mesh().GetTrustDomain(), id[0], id[1])},
+	}, nil
+}
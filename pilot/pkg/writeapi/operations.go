@@ -0,0 +1,412 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/api/label"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/security/pkg/server/ca/authenticate/bootstraptoken"
+	"istio.io/pkg/log"
+)
+
+const (
+	// tagLabel is the label of the webhook configurations of a revision tag.
+	tagLabel = "istio.io/tag"
+	// defaultTag is the tag of the default revision, which also validates the config.
+	defaultTag = "default"
+
+	injectionWebhookSuffix = "sidecar-injector.istio.io"
+
+	// deactivatedSelectorsAnnotation records the selectors of the webhooks of the default revision deactivated by
+	// the default tag, so that they are restored when the default tag is removed.
+	deactivatedSelectorsAnnotation = "istio.io/deactivated-selectors"
+)
+
+// istioOperatorGVR is the resource of the IstioOperators applied by Install.
+var istioOperatorGVR = schema.GroupVersionResource{Group: "install.istio.io", Version: "v1alpha1", Resource: "istiooperators"}
+
+// neverMatch deactivates the webhooks of the default revision when the default tag is set, as istioctl does.
+var neverMatch = &metav1.LabelSelector{
+	MatchLabels: map[string]string{
+		"istio.io/deactivated": "never-match",
+	},
+}
+
+// setTag applies the webhook configurations of a revision tag. They are generated by istiod from the injector of
+// the canonical webhook of the revision, so that the callers allowed to set a tag cannot change other webhook
+// configurations, nor what the webhooks of the tag select.
+func (s *Server) setTag(ctx context.Context, r Request) ([]string, error) {
+	if r.Tag == "" || r.Revision == "" {
+		return nil, invalidRequest("the tag and the revision are required")
+	}
+	if errs := validation.IsDNS1123Label(r.Tag); len(errs) > 0 {
+		return nil, invalidRequest("invalid tag %q: %v", r.Tag, errs)
+	}
+	admit := s.client.AdmissionregistrationV1()
+	canonical, err := admit.MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,!%s", label.IoIstioRev.Name, r.Revision, tagLabel),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(canonical.Items) != 1 {
+		return nil, invalidRequest("expected a single MutatingWebhookConfiguration for revision %q, found %d", r.Revision, len(canonical.Items))
+	}
+	injector, err := injectorClientConfig(canonical.Items[0])
+	if err != nil {
+		return nil, err
+	}
+	if r.Tag != defaultTag {
+		revisions, err := admit.MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{
+			LabelSelector: fmt.Sprintf("%s=%s,!%s", label.IoIstioRev.Name, r.Tag, tagLabel),
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(revisions.Items) > 0 {
+			return nil, invalidRequest("cannot create revision tag %q: found existing control plane revision with same name", r.Tag)
+		}
+	}
+	if !r.Overwrite {
+		existing, err := admit.MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{LabelSelector: tagLabel + "=" + r.Tag})
+		if err != nil {
+			return nil, err
+		}
+		if len(existing.Items) > 0 {
+			return nil, invalidRequest("revision tag %q already exists, and overwrite is false", r.Tag)
+		}
+	}
+
+	mutating := tagMutatingWebhook(r.Tag, r.Revision, s.namespace, injector, r.AutoInjectNamespaces)
+	current, err := admit.MutatingWebhookConfigurations().Get(ctx, mutating.Name, metav1.GetOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+	if exists && current.Labels[tagLabel] != r.Tag {
+		return nil, invalidRequest("MutatingWebhookConfiguration %s exists and is not the webhook of revision tag %q", mutating.Name, r.Tag)
+	}
+
+	var changed []string
+	if r.Tag == defaultTag {
+		if changed, err = s.deactivateDefaultRevision(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if exists {
+		mutating.ResourceVersion = current.ResourceVersion
+		_, err = admit.MutatingWebhookConfigurations().Update(ctx, mutating, metav1.UpdateOptions{})
+	} else {
+		_, err = admit.MutatingWebhookConfigurations().Create(ctx, mutating, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return changed, err
+	}
+	changed = append(changed, "MutatingWebhookConfiguration/"+mutating.Name)
+
+	if r.Tag == defaultTag {
+		validating := defaultValidatingWebhook(r.Revision, injector)
+		current, err := admit.ValidatingWebhookConfigurations().Get(ctx, validating.Name, metav1.GetOptions{})
+		switch {
+		case kerrors.IsNotFound(err):
+			_, err = admit.ValidatingWebhookConfigurations().Create(ctx, validating, metav1.CreateOptions{})
+		case err == nil:
+			validating.ResourceVersion = current.ResourceVersion
+			_, err = admit.ValidatingWebhookConfigurations().Update(ctx, validating, metav1.UpdateOptions{})
+		}
+		if err != nil {
+			return changed, err
+		}
+		changed = append(changed, "ValidatingWebhookConfiguration/"+validating.Name)
+	}
+	return changed, nil
+}
+
+// webhookSelectors are the selectors of a webhook of the default revision, saved when it is deactivated.
+type webhookSelectors struct {
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	ObjectSelector    *metav1.LabelSelector `json:"objectSelector,omitempty"`
+}
+
+// defaultRevisionWebhook returns the canonical webhook configuration of the default revision, or nil if there is
+// none.
+func (s *Server) defaultRevisionWebhook(ctx context.Context) (*admitv1.MutatingWebhookConfiguration, error) {
+	whs, err := s.client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,!%s", label.IoIstioRev.Name, defaultTag, tagLabel),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(whs.Items) != 1 {
+		return nil, nil
+	}
+	return &whs.Items[0], nil
+}
+
+// deactivateDefaultRevision deactivates the injector of the default revision, which would otherwise inject the
+// pods selected by the default tag as well. It is kept so that the default tag can be pointed back to it, and its
+// selectors are saved so that removeTag reactivates it.
+func (s *Server) deactivateDefaultRevision(ctx context.Context) ([]string, error) {
+	webhook, err := s.defaultRevisionWebhook(ctx)
+	if webhook == nil || err != nil {
+		return nil, err
+	}
+	if _, f := webhook.Annotations[deactivatedSelectorsAnnotation]; !f {
+		saved := make(map[string]webhookSelectors, len(webhook.Webhooks))
+		for _, w := range webhook.Webhooks {
+			saved[w.Name] = webhookSelectors{NamespaceSelector: w.NamespaceSelector, ObjectSelector: w.ObjectSelector}
+		}
+		b, err := json.Marshal(saved)
+		if err != nil {
+			return nil, err
+		}
+		if webhook.Annotations == nil {
+			webhook.Annotations = map[string]string{}
+		}
+		webhook.Annotations[deactivatedSelectorsAnnotation] = string(b)
+	}
+	for i := range webhook.Webhooks {
+		webhook.Webhooks[i].NamespaceSelector = neverMatch
+		webhook.Webhooks[i].ObjectSelector = neverMatch
+	}
+	if _, err := s.client.AdmissionregistrationV1().MutatingWebhookConfigurations().Update(ctx, webhook, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	return []string{"MutatingWebhookConfiguration/" + webhook.Name}, nil
+}
+
+// reactivateDefaultRevision restores the selectors of the injector of the default revision deactivated by the
+// default tag. Without saved selectors, the webhooks get the selectors of the default tag, as istioctl does.
+func (s *Server) reactivateDefaultRevision(ctx context.Context) ([]string, error) {
+	webhook, err := s.defaultRevisionWebhook(ctx)
+	if webhook == nil || err != nil {
+		return nil, err
+	}
+	saved := map[string]webhookSelectors{}
+	if a, f := webhook.Annotations[deactivatedSelectorsAnnotation]; f {
+		if err := json.Unmarshal([]byte(a), &saved); err != nil {
+			return nil, fmt.Errorf("invalid %s annotation of %s: %v", deactivatedSelectorsAnnotation, webhook.Name, err)
+		}
+	} else {
+		injector, err := injectorClientConfig(*webhook)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range tagMutatingWebhook(defaultTag, defaultTag, s.namespace, injector, false).Webhooks {
+			saved[w.Name] = webhookSelectors{NamespaceSelector: w.NamespaceSelector, ObjectSelector: w.ObjectSelector}
+		}
+	}
+	changed := false
+	for i, w := range webhook.Webhooks {
+		sel, f := saved[w.Name]
+		if !f || !reflect.DeepEqual(w.NamespaceSelector, neverMatch) {
+			continue
+		}
+		webhook.Webhooks[i].NamespaceSelector = sel.NamespaceSelector
+		webhook.Webhooks[i].ObjectSelector = sel.ObjectSelector
+		changed = true
+	}
+	if !changed {
+		return nil, nil
+	}
+	delete(webhook.Annotations, deactivatedSelectorsAnnotation)
+	if _, err := s.client.AdmissionregistrationV1().MutatingWebhookConfigurations().Update(ctx, webhook, metav1.UpdateOptions{}); err != nil {
+		return nil, err
+	}
+	return []string{"MutatingWebhookConfiguration/" + webhook.Name}, nil
+}
+
+// removeTag deletes the webhook configurations of a revision tag. Removing the default tag also deletes the
+// default validator, and reactivates the injector of the default revision.
+func (s *Server) removeTag(ctx context.Context, r Request) ([]string, error) {
+	if r.Tag == "" {
+		return nil, invalidRequest("the tag is required")
+	}
+	admit := s.client.AdmissionregistrationV1()
+	selector := metav1.ListOptions{LabelSelector: tagLabel + "=" + r.Tag}
+	whs, err := admit.MutatingWebhookConfigurations().List(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(whs.Items) == 0 {
+		return nil, invalidRequest("cannot find MutatingWebhookConfiguration for tag %q", r.Tag)
+	}
+	vwhs, err := admit.ValidatingWebhookConfigurations().List(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0, len(whs.Items)+len(vwhs.Items))
+	for _, wh := range whs.Items {
+		if err := admit.MutatingWebhookConfigurations().Delete(ctx, wh.Name, metav1.DeleteOptions{}); err != nil {
+			return changed, err
+		}
+		changed = append(changed, "MutatingWebhookConfiguration/"+wh.Name)
+	}
+	for _, wh := range vwhs.Items {
+		if err := admit.ValidatingWebhookConfigurations().Delete(ctx, wh.Name, metav1.DeleteOptions{}); err != nil {
+			return changed, err
+		}
+		changed = append(changed, "ValidatingWebhookConfiguration/"+wh.Name)
+	}
+	if r.Tag == defaultTag {
+		reactivated, err := s.reactivateDefaultRevision(ctx)
+		if err != nil {
+			return changed, err
+		}
+		changed = append(changed, reactivated...)
+	}
+	return changed, nil
+}
+
+// install applies the spec of an IstioOperator in the istiod namespace, where the in-cluster operator reconciles
+// it. The name of the IstioOperator is set by istiod from its revision, so that the callers allowed to install a
+// revision cannot change the IstioOperators of the other revisions.
+func (s *Server) install(ctx context.Context, r Request) ([]string, error) {
+	if r.IstioOperator == "" {
+		return nil, invalidRequest("the IstioOperator is required")
+	}
+	iop := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(r.IstioOperator), &iop.Object); err != nil {
+		return nil, invalidRequest("invalid IstioOperator: %v", err)
+	}
+	if gvk := iop.GroupVersionKind(); gvk.Group != istioOperatorGVR.Group || gvk.Kind != "IstioOperator" {
+		return nil, invalidRequest("expected an IstioOperator, got %v", gvk)
+	}
+	spec, _, err := unstructured.NestedMap(iop.Object, "spec")
+	if err != nil {
+		return nil, invalidRequest("invalid IstioOperator spec: %v", err)
+	}
+	revision, _, err := unstructured.NestedString(spec, "revision")
+	if err != nil {
+		return nil, invalidRequest("invalid IstioOperator revision: %v", err)
+	}
+	if revision == "" {
+		revision = defaultTag
+	}
+	if errs := validation.IsDNS1123Label(revision); len(errs) > 0 {
+		return nil, invalidRequest("invalid revision %q: %s", revision, strings.Join(errs, ", "))
+	}
+	if revision != r.Revision {
+		return nil, invalidRequest("the IstioOperator installs revision %q, not %q", revision, r.Revision)
+	}
+
+	applied := &unstructured.Unstructured{Object: map[string]any{"spec": spec}}
+	applied.SetGroupVersionKind(istioOperatorGVR.GroupVersion().WithKind("IstioOperator"))
+	applied.SetName("write-api-" + revision)
+	applied.SetNamespace(s.namespace)
+	iops := s.dynamic.Resource(istioOperatorGVR).Namespace(s.namespace)
+	current, err := iops.Get(ctx, applied.GetName(), metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		_, err = iops.Create(ctx, applied, metav1.CreateOptions{})
+	case err == nil:
+		applied.SetResourceVersion(current.GetResourceVersion())
+		_, err = iops.Update(ctx, applied, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []string{"IstioOperator/" + applied.GetName()}, nil
+}
+
+// injectorClientConfig returns the client config of the injector of the canonical webhook of a revision.
+func injectorClientConfig(wh admitv1.MutatingWebhookConfiguration) (admitv1.WebhookClientConfig, error) {
+	for _, w := range wh.Webhooks {
+		if strings.HasSuffix(w.Name, injectionWebhookSuffix) {
+			return w.ClientConfig, nil
+		}
+	}
+	return admitv1.WebhookClientConfig{}, fmt.Errorf("could not find sidecar-injector webhook in canonical webhook %q", wh.Name)
+}
+
+var logLevels = map[string]log.Level{
+	"none":  log.NoneLevel,
+	"error": log.ErrorLevel,
+	"warn":  log.WarnLevel,
+	"info":  log.InfoLevel,
+	"debug": log.DebugLevel,
+}
+
+// setLogLevels sets the output levels of the istiod scopes.
+func setLogLevels(levels []string) ([]string, error) {
+	type scopeLevel struct {
+		scope *log.Scope
+		level log.Level
+	}
+	parsed := make([]scopeLevel, 0, len(levels))
+	for _, sl := range levels {
+		name, lvl, ok := strings.Cut(sl, ":")
+		if !ok {
+			return nil, invalidRequest("invalid log level %q, expected <scope>:<level>", sl)
+		}
+		scope := log.FindScope(name)
+		if scope == nil {
+			return nil, invalidRequest("unknown log scope %q", name)
+		}
+		level, f := logLevels[lvl]
+		if !f {
+			return nil, invalidRequest("unknown log level %q", lvl)
+		}
+		parsed = append(parsed, scopeLevel{scope, level})
+	}
+	changed := make([]string, 0, len(parsed))
+	for _, p := range parsed {
+		p.scope.SetOutputLevel(p.level)
+		changed = append(changed, "scope/"+p.scope.Name())
+	}
+	return changed, nil
+}
+
+// mintBootstrapToken mints a bootstrap token, so that the caller does not need the permissions on the Secrets of
+// the istiod namespace recording the tokens.
+func (s *Server) mintBootstrapToken(ctx context.Context, r Request) (*Response, error) {
+	if !features.EnableVMBootstrapTokens {
+		return nil, invalidRequest("bootstrap tokens are not enabled, see PILOT_ENABLE_VM_BOOTSTRAP_TOKENS")
+	}
+	if errs := validation.IsDNS1123Label(r.Namespace); len(errs) > 0 {
+		return nil, invalidRequest("invalid namespace %q: %s", r.Namespace, strings.Join(errs, ", "))
+	}
+	if r.Namespace == s.namespace {
+		// The service accounts of istiod are allowed to manage the mesh.
+		return nil, invalidRequest("bootstrap tokens cannot be minted for the istiod namespace %s", s.namespace)
+	}
+	if errs := validation.IsDNS1123Subdomain(r.ServiceAccount); len(errs) > 0 {
+		return nil, invalidRequest("invalid service account %q: %s", r.ServiceAccount, strings.Join(errs, ", "))
+	}
+	ttl := time.Duration(r.TokenDurationSeconds) * time.Second
+	if ttl <= 0 || ttl > bootstraptoken.MaxTTL {
+		return nil, invalidRequest("invalid token duration %v, must be positive and at most %v", ttl, bootstraptoken.MaxTTL)
+	}
+	token, expiration, err := bootstraptoken.Mint(ctx, s.client, s.namespace, r.Namespace, r.ServiceAccount, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{BootstrapToken: token, BootstrapTokenExpiration: expiration.Format(time.RFC3339)}, nil
+}
@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writeapi
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+// Operation is a state-changing istioctl operation performed by istiod.
+type Operation string
+
+const (
+	// TagSet creates or moves a revision tag. Rolling back a revision tag is a TagSet to the previous revision.
+	TagSet Operation = "tag.set"
+	// TagRemove removes a revision tag.
+	TagRemove Operation = "tag.remove"
+	// LogLevel changes the log levels of istiod.
+	LogLevel Operation = "admin.log"
+	// BootstrapTokenMint mints a single-use bootstrap token onboarding a VM workload with a service account.
+	BootstrapTokenMint Operation = "workload.bootstrap-token"
+	// ProxyPush pushes the proxies of a namespace with the /debug/push debug endpoint of istiod.
+	ProxyPush Operation = "proxy.push"
+	// Install installs or reconfigures a revision of the control plane, by applying an IstioOperator reconciled by
+	// the in-cluster operator.
+	Install Operation = "install"
+
+	// anyOperation allows all the operations in a Rule.
+	anyOperation Operation = "*"
+)
+
+var operations = sets.New(TagSet, TagRemove, LogLevel, BootstrapTokenMint, ProxyPush, Install, anyOperation)
+
+// Policy is the server-side policy of the write API: an operation is allowed if a rule allows it.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule allows identities to perform operations.
+type Rule struct {
+	// Identities are the SPIFFE identities allowed by the rule. A trailing "*" matches any suffix, so that
+	// "spiffe://cluster.local/ns/ops/sa/*" allows all the service accounts of the ops namespace.
+	Identities []string `json:"identities"`
+	// Operations are the operations allowed by the rule, "*" allows all of them.
+	Operations []Operation `json:"operations"`
+	// Tags, if set, restricts the tag operations to these revision tags.
+	Tags []string `json:"tags,omitempty"`
+	// Revisions, if set, restricts the revisions that the revision tags can be set to, and that can be installed.
+	Revisions []string `json:"revisions,omitempty"`
+	// Namespaces are the namespaces of the service accounts of the workload operations, and of the proxies of the
+	// proxy operations. They are required by these operations: a rule without namespaces allows none of them, and
+	// "*" allows all the namespaces. Bootstrap tokens are never minted for the istiod namespace.
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// ParsePolicy parses and validates a YAML policy.
+func ParsePolicy(data string) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.UnmarshalStrict([]byte(data), p); err != nil {
+		return nil, fmt.Errorf("invalid write policy: %v", err)
+	}
+	for i, r := range p.Rules {
+		if len(r.Identities) == 0 || len(r.Operations) == 0 {
+			return nil, fmt.Errorf("invalid write policy: rule %d must have identities and operations", i)
+		}
+		for _, op := range r.Operations {
+			if !operations.Contains(op) {
+				return nil, fmt.Errorf("invalid write policy: rule %d has unknown operation %q", i, op)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Allowed returns true if one of the identities is allowed to perform the request.
+func (p *Policy) Allowed(identities []string, r Request) bool {
+	for _, rule := range p.Rules {
+		if rule.allows(identities, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) allows(identities []string, req Request) bool {
+	if !matchesAny(r.Identities, identities) {
+		return false
+	}
+	op := false
+	for _, o := range r.Operations {
+		if o == anyOperation || o == req.Operation {
+			op = true
+			break
+		}
+	}
+	if !op {
+		return false
+	}
+	switch req.Operation {
+	case TagSet:
+		return contains(r.Tags, req.Tag) && contains(r.Revisions, req.Revision)
+	case TagRemove:
+		return contains(r.Tags, req.Tag)
+	case Install:
+		return contains(r.Revisions, req.Revision)
+	case BootstrapTokenMint, ProxyPush:
+		for _, ns := range r.Namespaces {
+			if ns == "*" || ns == req.Namespace {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// contains returns true if the restriction is unset, or contains the value.
+func contains(restriction []string, value string) bool {
+	if len(restriction) == 0 {
+		return true
+	}
+	for _, v := range restriction {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(patterns []string, identities []string) bool {
+	for _, p := range patterns {
+		for _, id := range identities {
+			if p == id || (strings.HasSuffix(p, "*") && strings.HasPrefix(id, strings.TrimSuffix(p, "*"))) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writeapi
+
+import (
+	"testing"
+)
+
+const testPolicy = `
+rules:
+- identities: ["spiffe://cluster.local/ns/ops/sa/release"]
+  operations: ["tag.set", "tag.remove", "install"]
+  tags: ["prod"]
+  revisions: ["1-17-0", "1-17-1"]
+- identities: ["spiffe://cluster.local/ns/ops/sa/*"]
+  operations: ["admin.log"]
+- identities: ["spiffe://cluster.local/ns/istio-system/sa/admin"]
+  operations: ["*"]
+- identities: ["spiffe://cluster.local/ns/ops/sa/onboarding"]
+  operations: ["workload.bootstrap-token"]
+  namespaces: ["vms"]
+- identities: ["spiffe://cluster.local/ns/ops/sa/pusher"]
+  operations: ["proxy.push"]
+  namespaces: ["*"]
+`
+
+func TestPolicy(t *testing.T) {
+	p, err := ParsePolicy(testPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := []string{"spiffe://cluster.local/ns/ops/sa/release"}
+	oncall := []string{"spiffe://cluster.local/ns/ops/sa/oncall"}
+	admin := []string{"spiffe://cluster.local/ns/istio-system/sa/admin"}
+	onboarding := []string{"spiffe://cluster.local/ns/ops/sa/onboarding"}
+	pusher := []string{"spiffe://cluster.local/ns/ops/sa/pusher"}
+	cases := []struct {
+		name       string
+		identities []string
+		request    Request
+		allowed    bool
+	}{
+		{"allowed tag and revision", release, Request{Operation: TagSet, Tag: "prod", Revision: "1-17-1"}, true},
+		{"other revision", release, Request{Operation: TagSet, Tag: "prod", Revision: "1-18-0"}, false},
+		{"other tag", release, Request{Operation: TagSet, Tag: "canary", Revision: "1-17-1"}, false},
+		{"install allowed revision", release, Request{Operation: Install, Revision: "1-17-1"}, true},
+		{"install other revision", release, Request{Operation: Install, Revision: "1-18-0"}, false},
+		{"remove allowed tag", release, Request{Operation: TagRemove, Tag: "prod"}, true},
+		{"operation allowed by another rule", release, Request{Operation: LogLevel}, true},
+		{"wildcard identity", oncall, Request{Operation: LogLevel}, true},
+		{"wildcard identity, other operation", oncall, Request{Operation: TagRemove, Tag: "prod"}, false},
+		{"any operation", admin, Request{Operation: TagSet, Tag: "default", Revision: "1-18-0"}, true},
+		{"bootstrap token in allowed namespace", onboarding, Request{Operation: BootstrapTokenMint, Namespace: "vms"}, true},
+		{"bootstrap token in other namespace", onboarding, Request{Operation: BootstrapTokenMint, Namespace: "default"}, false},
+		{"bootstrap token without namespaces", admin, Request{Operation: BootstrapTokenMint, Namespace: "vms"}, false},
+		{"proxy push without namespaces", admin, Request{Operation: ProxyPush, Namespace: "default"}, false},
+		{"proxy push in any namespace", pusher, Request{Operation: ProxyPush, Namespace: "default"}, true},
+		{"unknown identity", []string{"spiffe://cluster.local/ns/default/sa/app"}, Request{Operation: LogLevel}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := p.Allowed(c.identities, c.request); got != c.allowed {
+				t.Fatalf("got %v, want %v", got, c.allowed)
+			}
+		})
+	}
+}
+
+func TestParsePolicyErrors(t *testing.T) {
+	for _, policy := range []string{
+		`rules: [{identities: ["a"], operations: ["uninstall"]}]`,
+		`rules: [{operations: ["tag.set"]}]`,
+		`rules: [{identities: ["a"], operations: ["tag.set"], clusters: ["b"]}]`,
+	} {
+		if _, err := ParsePolicy(policy); err == nil {
+			t.Errorf("expected an error for %s", policy)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package writeapi implements the istiod API performing the state-changing istioctl operations. Unlike
+// operations performed with the Kubernetes permissions of the caller, each request is authorized by a
+// server-side policy and audited, so that operators can be granted individual operations on shared meshes.
+package writeapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/log"
+)
+
+const (
+	// Path is the path of the write API on the istiod webhook port.
+	Path = "/write"
+	// Audience is the audience of the tokens authenticating the callers. It is dedicated to the write API, so
+	// that the tokens of the workloads, which are issued for the CA, are not accepted.
+	Audience = "istio-write-api"
+	// PolicyConfigMap is the ConfigMap of the istiod namespace holding the write policy, under the PolicyKey key.
+	PolicyConfigMap = "istio-write-policy"
+	PolicyKey       = "policy"
+)
+
+var auditLog = log.RegisterScope("writeapi", "istiod write API audit log", 0)
+
+// Request is a request of the write API.
+type Request struct {
+	Operation Operation `json:"operation"`
+	// Tag and Revision are the revision tag and the revision of the tag operations. Revision is also the revision
+	// installed by Install, "default" for the IstioOperator without revision.
+	Tag      string `json:"tag,omitempty"`
+	Revision string `json:"revision,omitempty"`
+	// AutoInjectNamespaces makes the default tag inject the pods of the namespaces without injection label, for
+	// TagSet.
+	AutoInjectNamespaces bool `json:"autoInjectNamespaces,omitempty"`
+	// Overwrite allows TagSet to move an existing revision tag.
+	Overwrite bool `json:"overwrite,omitempty"`
+	// LogLevels are the <scope>:<level> output levels set by LogLevel.
+	LogLevels []string `json:"logLevels,omitempty"`
+	// Namespace and ServiceAccount are the identity granted by the token minted by BootstrapTokenMint, valid for
+	// TokenDurationSeconds.
+	Namespace            string `json:"namespace,omitempty"`
+	ServiceAccount       string `json:"serviceAccount,omitempty"`
+	TokenDurationSeconds int64  `json:"tokenDurationSeconds,omitempty"`
+	// IstioOperator is the YAML IstioOperator applied by Install. Only its spec is applied.
+	IstioOperator string `json:"istioOperator,omitempty"`
+}
+
+// Response is the response of the write API.
+type Response struct {
+	// Identities are the authenticated identities of the caller.
+	Identities []string `json:"identities"`
+	// Changed are the resources changed by the operation, as <kind>/<name>.
+	Changed []string `json:"changed"`
+	// BootstrapToken is the token minted by BootstrapTokenMint, expiring at BootstrapTokenExpiration (RFC 3339).
+	BootstrapToken           string `json:"bootstrapToken,omitempty"`
+	BootstrapTokenExpiration string `json:"bootstrapTokenExpiration,omitempty"`
+}
+
+// Server serves the write API.
+type Server struct {
+	client         kubernetes.Interface
+	dynamic        dynamic.Interface
+	namespace      string
+	authenticators []security.Authenticator
+}
+
+// NewServer creates a Server changing the cluster of the clients, and reading the policy from the namespace. The
+// dynamic client applies the IstioOperators of Install.
+func NewServer(client kubernetes.Interface, dynamicClient dynamic.Interface, namespace string,
+	authenticators []security.Authenticator,
+) *Server {
+	return &Server{client: client, dynamic: dynamicClient, namespace: namespace, authenticators: authenticators}
+}
+
+// invalidRequestError is returned for the requests that cannot be performed, as opposed to the failures to
+// perform them.
+type invalidRequestError struct {
+	error
+}
+
+func invalidRequest(format string, a ...any) error {
+	return invalidRequestError{fmt.Errorf(format, a...)}
+}
+
+// ServeHTTP authenticates and authorizes the request, and performs the operation. Requests from localhost are
+// authenticated as well: istioctl reaches istiod with a port forward.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	ids := s.authenticate(req)
+	if ids == nil {
+		http.Error(w, "authentication failure", http.StatusUnauthorized)
+		return
+	}
+	var r Request
+	if err := json.NewDecoder(req.Body).Decode(&r); err != nil {
+		http.Error(w, fmt.Sprintf("invalid request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	allowed, err := s.Authorize(req.Context(), ids, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("%v are not allowed to perform %s by the write policy", ids, r.Operation), http.StatusForbidden)
+		return
+	}
+
+	resp, err := s.perform(req.Context(), r)
+	if err != nil {
+		audit("failed", ids, r, err)
+		code := http.StatusInternalServerError
+		if errors.As(err, &invalidRequestError{}) {
+			code = http.StatusBadRequest
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	audit("allowed", ids, r, nil)
+	resp.Identities = ids
+	sort.Strings(resp.Changed)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// Authorize returns true if the write policy allows the identities to perform the request. The requests which are
+// not allowed are audited, the allowed ones are audited once performed. It is used by the istiod endpoints
+// performing the operations outside of the write API.
+func (s *Server) Authorize(ctx context.Context, ids []string, r Request) (bool, error) {
+	policy, err := s.policy(ctx)
+	if err != nil {
+		audit("failed", ids, r, err)
+		return false, err
+	}
+	if !policy.Allowed(ids, r) {
+		audit("denied", ids, r, nil)
+		return false, nil
+	}
+	return true, nil
+}
+
+// Audit records a request performed outside of the write API, once authorized by Authorize.
+func Audit(ids []string, r Request, err error) {
+	if err != nil {
+		audit("failed", ids, r, err)
+		return
+	}
+	audit("allowed", ids, r, nil)
+}
+
+func audit(result string, ids []string, r Request, err error) {
+	if err != nil {
+		auditLog.Warnf("%s: operation=%s tag=%q revision=%q logLevels=%v serviceAccount=%q identities=%v: %v",
+			result, r.Operation, r.Tag, r.Revision, r.LogLevels, r.Namespace+"/"+r.ServiceAccount, ids, err)
+		return
+	}
+	auditLog.Infof("%s: operation=%s tag=%q revision=%q logLevels=%v serviceAccount=%q identities=%v",
+		result, r.Operation, r.Tag, r.Revision, r.LogLevels, r.Namespace+"/"+r.ServiceAccount, ids)
+}
+
+// authenticate returns the identities of the caller, or nil if the request is not authenticated.
+func (s *Server) authenticate(req *http.Request) []string {
+	authRequest := security.AuthContext{Request: req}
+	for _, authn := range s.authenticators {
+		u, err := authn.Authenticate(authRequest)
+		if u != nil && u.Identities != nil && err == nil {
+			return u.Identities
+		}
+		auditLog.Debugf("authenticator %s: %v", authn.AuthenticatorType(), err)
+	}
+	return nil
+}
+
+// policy reads the write policy. Without policy, all the requests are denied.
+func (s *Server) policy(ctx context.Context) (*Policy, error) {
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, PolicyConfigMap, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return &Policy{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the write policy: %v", err)
+	}
+	return ParsePolicy(cm.Data[PolicyKey])
+}
+
+func (s *Server) perform(ctx context.Context, r Request) (*Response, error) {
+	var changed []string
+	var err error
+	switch r.Operation {
+	case TagSet:
+		changed, err = s.setTag(ctx, r)
+	case TagRemove:
+		changed, err = s.removeTag(ctx, r)
+	case LogLevel:
+		changed, err = setLogLevels(r.LogLevels)
+	case BootstrapTokenMint:
+		return s.mintBootstrapToken(ctx, r)
+	case Install:
+		changed, err = s.install(ctx, r)
+	default:
+		return nil, invalidRequest("unknown operation %q", r.Operation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Changed: changed}, nil
+}
@@ -0,0 +1,347 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writeapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	ktesting "k8s.io/client-go/testing"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/security/pkg/server/ca/authenticate/bootstraptoken"
+	"istio.io/pkg/log"
+)
+
+const identityHeader = "x-test-identity"
+
+type headerAuthenticator struct{}
+
+func (headerAuthenticator) Authenticate(ctx security.AuthContext) (*security.Caller, error) {
+	if id := ctx.Request.Header.Get(identityHeader); id != "" {
+		return &security.Caller{Identities: []string{id}}, nil
+	}
+	return nil, errors.New("no identity")
+}
+
+func (headerAuthenticator) AuthenticatorType() string {
+	return "header"
+}
+
+func injectorWebhook(name string, labels map[string]string, service string) *admitv1.MutatingWebhookConfiguration {
+	path := "/inject"
+	return &admitv1.MutatingWebhookConfiguration{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "admissionregistration.k8s.io/v1", Kind: "MutatingWebhookConfiguration"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+		Webhooks: []admitv1.MutatingWebhook{{
+			Name: "rev.namespace.sidecar-injector.istio.io",
+			ClientConfig: admitv1.WebhookClientConfig{
+				Service: &admitv1.ServiceReference{Name: service, Namespace: "istio-system", Path: &path},
+			},
+		}},
+	}
+}
+
+func TestServer(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		injectorWebhook("istio-sidecar-injector-1-17-1", map[string]string{"istio.io/rev": "1-17-1"}, "istiod-1-17-1"),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: PolicyConfigMap, Namespace: "istio-system"},
+			Data:       map[string]string{PolicyKey: testPolicy},
+		},
+	)
+	s := NewServer(client, nil, "istio-system", []security.Authenticator{headerAuthenticator{}})
+
+	do := func(identity string, r Request) (int, Response) {
+		t.Helper()
+		body, _ := json.Marshal(r)
+		req := httptest.NewRequest(http.MethodPost, Path, bytes.NewReader(body))
+		if identity != "" {
+			req.Header.Set(identityHeader, identity)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		var resp Response
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, resp
+	}
+	release := "spiffe://cluster.local/ns/ops/sa/release"
+	setProd := Request{Operation: TagSet, Tag: "prod", Revision: "1-17-1"}
+
+	if code, _ := do("", setProd); code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthenticated requests to be rejected, got %d", code)
+	}
+	if code, _ := do("spiffe://cluster.local/ns/default/sa/app", setProd); code != http.StatusForbidden {
+		t.Fatalf("expected requests not allowed by the policy to be denied, got %d", code)
+	}
+
+	code, resp := do(release, setProd)
+	if code != http.StatusOK || len(resp.Changed) != 1 || resp.Changed[0] != "MutatingWebhookConfiguration/istio-revision-tag-prod" {
+		t.Fatalf("unexpected response %d %+v", code, resp)
+	}
+	wh, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(context.Background(),
+		"istio-revision-tag-prod", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range wh.Webhooks {
+		if w.ClientConfig.Service.Name != "istiod-1-17-1" || *w.ClientConfig.Service.Path != "/inject" {
+			t.Fatalf("expected webhook %s to point to the injector of the revision, got %v", w.Name, w.ClientConfig.Service)
+		}
+		if *w.FailurePolicy != admitv1.Fail || !selects(w.NamespaceSelector, "istio.io/rev", "prod") &&
+			!selects(w.ObjectSelector, "istio.io/rev", "prod") {
+			t.Fatalf("expected webhook %s to only select the pods of the tag, got %v %v", w.Name, w.NamespaceSelector, w.ObjectSelector)
+		}
+	}
+	if code, _ := do(release, setProd); code != http.StatusBadRequest {
+		t.Fatalf("expected an existing tag not to be overwritten, got %d", code)
+	}
+	setProd.Overwrite = true
+	if code, _ := do(release, setProd); code != http.StatusOK {
+		t.Fatalf("expected the tag to be overwritten, got %d", code)
+	}
+
+	if code, _ := do(release, Request{Operation: TagRemove, Tag: "prod"}); code != http.StatusOK {
+		t.Fatalf("expected the tag to be removed, got %d", code)
+	}
+	whs, _ := client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.Background(), metav1.ListOptions{})
+	if len(whs.Items) != 1 {
+		t.Fatalf("expected only the revision webhook to remain, got %d", len(whs.Items))
+	}
+
+	defer auditLog.SetOutputLevel(auditLog.GetOutputLevel())
+	if code, _ := do("spiffe://cluster.local/ns/ops/sa/oncall", Request{Operation: LogLevel, LogLevels: []string{"writeapi:debug"}}); code != http.StatusOK {
+		t.Fatalf("expected the log level to be set, got %d", code)
+	}
+	if auditLog.GetOutputLevel() != log.DebugLevel {
+		t.Fatalf("expected the writeapi scope at debug level, got %v", auditLog.GetOutputLevel())
+	}
+	if code, _ := do("spiffe://cluster.local/ns/ops/sa/oncall", Request{Operation: LogLevel, LogLevels: []string{"writeapi:loud"}}); code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid log level to be rejected, got %d", code)
+	}
+}
+
+func selects(s *metav1.LabelSelector, key, value string) bool {
+	for _, r := range s.MatchExpressions {
+		if r.Key == key && r.Operator == metav1.LabelSelectorOpIn && len(r.Values) == 1 && r.Values[0] == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSetTagWebhookConfigurations(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		injectorWebhook("istio-sidecar-injector", map[string]string{"istio.io/rev": "default"}, "istiod"),
+		injectorWebhook("istio-sidecar-injector-1-17-1", map[string]string{"istio.io/rev": "1-17-1"}, "istiod-1-17-1"),
+		injectorWebhook("istio-revision-tag-other", nil, "other"),
+	)
+	s := NewServer(client, nil, "istio-system", nil)
+	ctx := context.Background()
+
+	if _, err := s.setTag(ctx, Request{Tag: "1-17-1", Revision: "default"}); err == nil {
+		t.Fatalf("expected a tag named after a revision to be rejected")
+	}
+	if _, err := s.setTag(ctx, Request{Tag: "other", Revision: "1-17-1", Overwrite: true}); err == nil {
+		t.Fatalf("expected a webhook configuration that is not of the tag not to be overwritten")
+	}
+	if _, err := s.setTag(ctx, Request{Tag: "Prod!", Revision: "1-17-1"}); err == nil {
+		t.Fatalf("expected an invalid tag to be rejected")
+	}
+
+	changed, err := s.setTag(ctx, Request{Tag: "default", Revision: "1-17-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"MutatingWebhookConfiguration/istio-sidecar-injector",
+		"MutatingWebhookConfiguration/istio-revision-tag-default",
+		"ValidatingWebhookConfiguration/istiod-default-validator",
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("got changes %v, want %v", changed, want)
+	}
+	vwh, err := client.AdmissionregistrationV1().ValidatingWebhookConfigurations().Get(ctx, "istiod-default-validator", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if svc := vwh.Webhooks[0].ClientConfig.Service; svc.Name != "istiod-1-17-1" || *svc.Path != "/validate" {
+		t.Fatalf("expected the validator to point to the revision, got %v", svc)
+	}
+	def, err := client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "istio-sidecar-injector", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(def.Webhooks[0].NamespaceSelector, neverMatch) {
+		t.Fatalf("expected the default revision to be deactivated, got %v", def.Webhooks[0].NamespaceSelector)
+	}
+
+	changed, err = s.removeTag(ctx, Request{Tag: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		"MutatingWebhookConfiguration/istio-revision-tag-default",
+		"ValidatingWebhookConfiguration/istiod-default-validator",
+		"MutatingWebhookConfiguration/istio-sidecar-injector",
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("got changes %v, want %v", changed, want)
+	}
+	def, err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().Get(ctx, "istio-sidecar-injector", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Webhooks[0].NamespaceSelector != nil || def.Webhooks[0].ObjectSelector != nil || len(def.Annotations) != 0 {
+		t.Fatalf("expected the default revision to be reactivated, got %v %v", def.Webhooks[0].NamespaceSelector, def.Annotations)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{istioOperatorGVR: "IstioOperatorList"})
+	s := NewServer(fake.NewSimpleClientset(), dynamicClient, "istio-system", nil)
+	ctx := context.Background()
+	iop := `
+apiVersion: install.istio.io/v1alpha1
+kind: IstioOperator
+metadata:
+  name: other
+  namespace: other
+spec:
+  profile: minimal
+  revision: 1-17-1
+`
+
+	for name, r := range map[string]Request{
+		"other revision": {Revision: "1-18-0", IstioOperator: iop},
+		"other kind":     {Revision: "default", IstioOperator: "apiVersion: v1\nkind: ConfigMap"},
+		"no operator":    {Revision: "1-17-1"},
+	} {
+		if _, err := s.install(ctx, r); err == nil {
+			t.Fatalf("%s: expected the request to be rejected", name)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		changed, err := s.install(ctx, Request{Revision: "1-17-1", IstioOperator: iop})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(changed, []string{"IstioOperator/write-api-1-17-1"}) {
+			t.Fatalf("unexpected changes %v", changed)
+		}
+	}
+	applied, err := dynamicClient.Resource(istioOperatorGVR).Namespace("istio-system").Get(ctx, "write-api-1-17-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile, _, _ := unstructured.NestedString(applied.Object, "spec", "profile"); profile != "minimal" {
+		t.Fatalf("expected the spec to be applied, got %v", applied.Object)
+	}
+}
+
+func TestTokenAuthenticator(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action ktesting.Action) (bool, runtime.Object, error) {
+		review := action.(ktesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		if !reflect.DeepEqual(review.Spec.Audiences, []string{Audience}) || review.Spec.Token != "write-token" {
+			return true, &authenticationv1.TokenReview{}, nil
+		}
+		return true, &authenticationv1.TokenReview{Status: authenticationv1.TokenReviewStatus{
+			Authenticated: true,
+			Audiences:     []string{Audience},
+			User: authenticationv1.UserInfo{
+				Username: "system:serviceaccount:ops:release",
+				Groups:   []string{"system:serviceaccounts"},
+			},
+		}}, nil
+	})
+	a := NewTokenAuthenticator(mesh.NewFixedWatcher(mesh.DefaultMeshConfig()), client)
+
+	for token, want := range map[string]string{"write-token": "spiffe://cluster.local/ns/ops/sa/release", "istio-ca-token": ""} {
+		req := httptest.NewRequest(http.MethodPost, Path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		caller, err := a.Authenticate(security.AuthContext{Request: req})
+		if want == "" {
+			if err == nil {
+				t.Fatalf("expected %s to be rejected, got %v", token, caller)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(caller.Identities, []string{want}) {
+			t.Fatalf("got %v %v, want %s", caller, err, want)
+		}
+	}
+}
+
+func TestMintBootstrapToken(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	s := NewServer(client, nil, "istio-system", nil)
+	ctx := context.Background()
+	r := Request{Operation: BootstrapTokenMint, Namespace: "vms", ServiceAccount: "vm", TokenDurationSeconds: 600}
+
+	if _, err := s.mintBootstrapToken(ctx, r); err == nil {
+		t.Fatalf("expected bootstrap tokens to be disabled by default")
+	}
+	test.SetForTest(t, &features.EnableVMBootstrapTokens, true)
+	for _, invalid := range []Request{
+		{Namespace: "vms", ServiceAccount: "vm"},
+		{Namespace: "vms", ServiceAccount: "vm", TokenDurationSeconds: int64(bootstraptoken.MaxTTL.Seconds()) + 1},
+		{Namespace: "Vms!", ServiceAccount: "vm", TokenDurationSeconds: 600},
+		{Namespace: "istio-system", ServiceAccount: "istiod", TokenDurationSeconds: 600},
+	} {
+		if _, err := s.mintBootstrapToken(ctx, invalid); !errors.As(err, &invalidRequestError{}) {
+			t.Fatalf("expected %+v to be rejected, got %v", invalid, err)
+		}
+	}
+
+	resp, err := s.mintBootstrapToken(ctx, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(resp.BootstrapToken, bootstraptoken.TokenPrefix) || resp.BootstrapTokenExpiration == "" {
+		t.Fatalf("expected a bootstrap token, got %+v", resp)
+	}
+	secrets, err := client.CoreV1().Secrets("istio-system").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets.Items) != 1 || secrets.Items[0].Type != bootstraptoken.SecretType {
+		t.Fatalf("expected the bootstrap token to be recorded in the istiod namespace, got %v", secrets.Items)
+	}
+}
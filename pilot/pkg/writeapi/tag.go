@@ -0,0 +1,165 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package writeapi
+
+import (
+	"fmt"
+	"net/url"
+
+	admitv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/api/label"
+)
+
+// The webhook configurations of the revision tags are generated as the revision-tags.yaml and the validating
+// webhook templates of the charts, which istioctl renders, so that the revision tags set through istiod and with
+// istioctl are the same. Only the injector of the canonical webhook of the revision is taken from the cluster.
+
+// tagWebhookName returns the name of the MutatingWebhookConfiguration of a revision tag.
+func tagWebhookName(tag, istioNamespace string) string {
+	if istioNamespace == "istio-system" {
+		return "istio-revision-tag-" + tag
+	}
+	return fmt.Sprintf("istio-revision-tag-%s-%s", tag, istioNamespace)
+}
+
+// defaultValidatorName is the name of the ValidatingWebhookConfiguration of the default tag.
+const defaultValidatorName = "istiod-default-validator"
+
+var (
+	sideEffectsNone         = admitv1.SideEffectClassNone
+	failurePolicyFail       = admitv1.Fail
+	failurePolicyIgnore     = admitv1.Ignore
+	injectionRules          = []admitv1.RuleWithOperations{rule([]string{""}, []string{"v1"}, []string{"pods"}, admitv1.Create)}
+	validationRules         = []admitv1.RuleWithOperations{rule(validatedGroups, []string{"*"}, []string{"*"}, admitv1.Create, admitv1.Update)}
+	validatedGroups         = []string{"security.istio.io", "networking.istio.io", "telemetry.istio.io", "extensions.istio.io"}
+	admissionReviewVersions = []string{"v1beta1", "v1"}
+)
+
+func rule(groups, versions, resources []string, ops ...admitv1.OperationType) admitv1.RuleWithOperations {
+	return admitv1.RuleWithOperations{
+		Operations: ops,
+		Rule:       admitv1.Rule{APIGroups: groups, APIVersions: versions, Resources: resources},
+	}
+}
+
+func selector(requirements ...metav1.LabelSelectorRequirement) *metav1.LabelSelector {
+	return &metav1.LabelSelector{MatchExpressions: requirements}
+}
+
+func in(key string, values ...string) metav1.LabelSelectorRequirement {
+	return metav1.LabelSelectorRequirement{Key: key, Operator: metav1.LabelSelectorOpIn, Values: values}
+}
+
+func notIn(key string, values ...string) metav1.LabelSelectorRequirement {
+	return metav1.LabelSelectorRequirement{Key: key, Operator: metav1.LabelSelectorOpNotIn, Values: values}
+}
+
+func doesNotExist(key string) metav1.LabelSelectorRequirement {
+	return metav1.LabelSelectorRequirement{Key: key, Operator: metav1.LabelSelectorOpDoesNotExist}
+}
+
+// tagMutatingWebhook returns the MutatingWebhookConfiguration of a revision tag pointing to the injector.
+func tagMutatingWebhook(tag, revision, istioNamespace string, injector admitv1.WebhookClientConfig,
+	autoInjectNamespaces bool,
+) *admitv1.MutatingWebhookConfiguration {
+	webhook := func(prefix string, namespaceSelector, objectSelector *metav1.LabelSelector) admitv1.MutatingWebhook {
+		return admitv1.MutatingWebhook{
+			Name:                    prefix + injectionWebhookSuffix,
+			ClientConfig:            *injector.DeepCopy(),
+			SideEffects:             &sideEffectsNone,
+			Rules:                   injectionRules,
+			FailurePolicy:           &failurePolicyFail,
+			AdmissionReviewVersions: admissionReviewVersions,
+			NamespaceSelector:       namespaceSelector,
+			ObjectSelector:          objectSelector,
+		}
+	}
+	notDisabled := notIn("sidecar.istio.io/inject", "false")
+	webhooks := []admitv1.MutatingWebhook{
+		webhook("rev.namespace.",
+			selector(in(label.IoIstioRev.Name, tag), doesNotExist("istio-injection")),
+			selector(notDisabled)),
+		webhook("rev.object.",
+			selector(doesNotExist(label.IoIstioRev.Name), doesNotExist("istio-injection")),
+			selector(notDisabled, in(label.IoIstioRev.Name, tag))),
+	}
+	if tag == defaultTag {
+		webhooks = append(webhooks,
+			webhook("namespace.",
+				selector(in("istio-injection", "enabled")),
+				selector(notDisabled)),
+			webhook("object.",
+				selector(doesNotExist("istio-injection"), doesNotExist(label.IoIstioRev.Name)),
+				selector(in("sidecar.istio.io/inject", "true"), doesNotExist(label.IoIstioRev.Name))))
+		if autoInjectNamespaces {
+			webhooks = append(webhooks, webhook("auto.",
+				selector(doesNotExist("istio-injection"), doesNotExist(label.IoIstioRev.Name),
+					notIn("kubernetes.io/metadata.name", "kube-system", "kube-public", "kube-node-lease", "local-path-storage")),
+				selector(doesNotExist("sidecar.istio.io/inject"), doesNotExist(label.IoIstioRev.Name))))
+		}
+	}
+	return &admitv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: tagWebhookName(tag, istioNamespace),
+			Labels: map[string]string{
+				tagLabel:                      tag,
+				label.IoIstioRev.Name:         revision,
+				"operator.istio.io/component": "Pilot",
+				"app":                         "sidecar-injector",
+			},
+		},
+		Webhooks: webhooks,
+	}
+}
+
+// defaultValidatingWebhook returns the ValidatingWebhookConfiguration of the default tag, validating the config
+// with the revision of the injector.
+func defaultValidatingWebhook(revision string, injector admitv1.WebhookClientConfig) *admitv1.ValidatingWebhookConfiguration {
+	clientConfig := *injector.DeepCopy()
+	validatePath := "/validate"
+	if clientConfig.URL != nil {
+		if u, err := url.Parse(*clientConfig.URL); err == nil {
+			u.Path = validatePath
+			validationURL := u.String()
+			clientConfig.URL = &validationURL
+		}
+	}
+	if clientConfig.Service != nil {
+		clientConfig.Service.Path = &validatePath
+	}
+	return &admitv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: defaultValidatorName,
+			Labels: map[string]string{
+				"app":                         "istiod",
+				"istio":                       "istiod",
+				label.IoIstioRev.Name:         revision,
+				tagLabel:                      defaultTag,
+				"operator.istio.io/component": "Pilot",
+			},
+		},
+		Webhooks: []admitv1.ValidatingWebhook{{
+			Name:                    "validation.istio.io",
+			ClientConfig:            clientConfig,
+			Rules:                   validationRules,
+			FailurePolicy:           &failurePolicyIgnore,
+			SideEffects:             &sideEffectsNone,
+			AdmissionReviewVersions: admissionReviewVersions,
+			ObjectSelector:          selector(doesNotExist(label.IoIstioRev.Name)),
+		}},
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"time"
+
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	"github.com/hashicorp/go-multierror"
+	"google.golang.org/protobuf/proto"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking/core"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// stagedConfigStore overlays a staged config on the config store, as if the change was committed.
+type stagedConfigStore struct {
+	model.ConfigStore
+	staged config.Config
+}
+
+func (s stagedConfigStore) isStaged(typ config.GroupVersionKind, name, namespace string) bool {
+	return typ == s.staged.GroupVersionKind && name == s.staged.Name && namespace == s.staged.Namespace
+}
+
+func (s stagedConfigStore) Get(typ config.GroupVersionKind, name, namespace string) *config.Config {
+	if s.isStaged(typ, name, namespace) {
+		return &s.staged
+	}
+	return s.ConfigStore.Get(typ, name, namespace)
+}
+
+func (s stagedConfigStore) List(typ config.GroupVersionKind, namespace string) ([]config.Config, error) {
+	configs, err := s.ConfigStore.List(typ, namespace)
+	if err != nil || typ != s.staged.GroupVersionKind || (namespace != "" && namespace != s.staged.Namespace) {
+		return configs, err
+	}
+	out := make([]config.Config, 0, len(configs)+1)
+	for _, c := range configs {
+		if !s.isStaged(c.GroupVersionKind, c.Name, c.Namespace) {
+			out = append(out, c)
+		}
+	}
+	return append(out, s.staged), nil
+}
+
+// isHighRiskConfig returns true for the configs applying to the whole mesh, which are staged before being admitted.
+func isHighRiskConfig(cfg config.Config, rootNamespace string) bool {
+	switch cfg.GroupVersionKind {
+	case gvk.PeerAuthentication:
+		pa, ok := cfg.Spec.(*v1beta1.PeerAuthentication)
+		return ok && cfg.Namespace == rootNamespace && pa.GetSelector() == nil
+	case gvk.VirtualService:
+		vs, ok := cfg.Spec.(*networking.VirtualService)
+		if !ok {
+			return false
+		}
+		for _, h := range vs.Hosts {
+			if host.Name(h).IsWildCarded() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateStagedConfig is the admission canary of the high-risk configs. The config is staged on top of the
+// current configs, and the listeners and routes are generated for a sample of the connected proxies with an
+// incremental update of the current push context. An error is returned if any of them would be rejected by the
+// proxies while they are accepted with the current configs: existing problems do not block unrelated changes.
+// Other configs are not checked, and the config is admitted if the check does not complete within
+// PILOT_ADMISSION_CANARY_TIMEOUT.
+func (s *DiscoveryServer) ValidateStagedConfig(cfg config.Config) error {
+	if !isHighRiskConfig(cfg, s.Env.Mesh().GetRootNamespace()) {
+		return nil
+	}
+	// Buffered, so that the check can complete after the timeout.
+	result := make(chan error, 1)
+	go func() {
+		result <- s.validateStagedConfig(cfg)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(features.AdmissionCanaryTimeout):
+		log.Warnf("admission canary of %s %s/%s did not complete within %v, admitting it",
+			cfg.GroupVersionKind.Kind, cfg.Namespace, cfg.Name, features.AdmissionCanaryTimeout)
+		return nil
+	}
+}
+
+func (s *DiscoveryServer) validateStagedConfig(cfg config.Config) error {
+	current := s.globalPushContext()
+	env := *s.Env
+	env.ConfigStore = stagedConfigStore{ConfigStore: s.Env.ConfigStore, staged: cfg}
+	// The Gateway API controller is not reconciled with the staged push, its last state is listed by the config store.
+	env.GatewayAPIController = nil
+	push := model.NewPushContext()
+	// Only the indexes of the kind of the config are rebuilt, as on a push of istiod.
+	req := &model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.FromGvk(cfg.GroupVersionKind), Name: cfg.Name, Namespace: cfg.Namespace}),
+		Reason:         []model.TriggerReason{model.ConfigUpdate},
+	}
+	if err := push.InitContext(&env, current, req); err != nil {
+		return fmt.Errorf("failed to initialize the push context: %v", err)
+	}
+	// Generated configs are not cached, as the cache is keyed by config name rather than content.
+	configgen := core.NewConfigGenerator(&model.DisabledCache{})
+
+	var errs *multierror.Error
+	for _, con := range canaryConnections(s.Clients(), features.AdmissionCanaryMaxProxies) {
+		routes := con.Routes()
+		existing := sets.New(proxyConfigErrors(configgen, canaryProxy(con.proxy, current), current, routes)...)
+		for _, err := range proxyConfigErrors(configgen, canaryProxy(con.proxy, push), push, routes) {
+			if !existing.Contains(err) {
+				errs = multierror.Append(errs, fmt.Errorf("%s: %s", con.proxy.ID, err))
+			}
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// proxyConfigErrors returns the problems of the listeners and routes generated for the proxy, which would be
+// rejected by Envoy.
+func proxyConfigErrors(configgen core.ConfigGenerator, proxy *model.Proxy, push *model.PushContext, routeNames []string) []string {
+	return append(listenerErrors(configgen, proxy, push), routeErrors(configgen, proxy, push, routeNames)...)
+}
+
+// canaryConnections picks at most limit Envoy proxies, one of each type and namespace.
+func canaryConnections(connections []*Connection, limit int) []*Connection {
+	var out []*Connection
+	seen := sets.New[string]()
+	for _, con := range connections {
+		if len(out) >= limit {
+			break
+		}
+		proxy := con.proxy
+		if (proxy.Type != model.SidecarProxy && proxy.Type != model.Router) || proxy.IsProxylessGrpc() {
+			continue
+		}
+		if seen.InsertContains(string(proxy.Type) + "/" + proxy.ConfigNamespace) {
+			continue
+		}
+		out = append(out, con)
+	}
+	return out
+}
+
+// canaryProxy returns a copy of the proxy with the sidecar scope and gateways of the staged push, leaving the
+// state of the connected proxy untouched.
+func canaryProxy(proxy *model.Proxy, push *model.PushContext) *model.Proxy {
+	proxy.RLock()
+	p := &model.Proxy{
+		Type:             proxy.Type,
+		IPAddresses:      proxy.IPAddresses,
+		ID:               proxy.ID,
+		Locality:         proxy.Locality,
+		DNSDomain:        proxy.DNSDomain,
+		ConfigNamespace:  proxy.ConfigNamespace,
+		Labels:           proxy.Labels,
+		Metadata:         proxy.Metadata,
+		ServiceInstances: proxy.ServiceInstances,
+		IstioVersion:     proxy.IstioVersion,
+		VerifiedIdentity: proxy.VerifiedIdentity,
+		XdsNode:          proxy.XdsNode,
+	}
+	proxy.RUnlock()
+	p.SetSidecarScope(push)
+	p.SetGatewaysForProxy(push)
+	p.DiscoverIPMode()
+	return p
+}
+
+// listenerErrors checks the generated listeners against the constraints enforced by Envoy.
+func listenerErrors(configgen core.ConfigGenerator, proxy *model.Proxy, push *model.PushContext) []string {
+	var errs []string
+	names := sets.New[string]()
+	for _, l := range configgen.BuildListeners(proxy, push) {
+		if names.InsertContains(l.Name) {
+			errs = append(errs, fmt.Sprintf("duplicate listener %s", l.Name))
+		}
+		if err := l.Validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("listener %s: %v", l.Name, err))
+		}
+		for i, fc := range l.FilterChains {
+			for _, prev := range l.FilterChains[:i] {
+				if proto.Equal(fc.GetFilterChainMatch(), prev.GetFilterChainMatch()) {
+					errs = append(errs, fmt.Sprintf("listener %s: filter chains %q and %q have the same match",
+						l.Name, prev.Name, fc.Name))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// routeErrors checks the generated routes watched by the proxy against the constraints enforced by Envoy.
+func routeErrors(configgen core.ConfigGenerator, proxy *model.Proxy, push *model.PushContext, routeNames []string) []string {
+	if len(routeNames) == 0 {
+		return nil
+	}
+	resources, _ := configgen.BuildHTTPRoutes(proxy, &model.PushRequest{Full: true, Push: push}, routeNames)
+	var errs []string
+	for _, r := range resources {
+		rc := &route.RouteConfiguration{}
+		if err := r.Resource.UnmarshalTo(rc); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err := rc.Validate(); err != nil {
+			errs = append(errs, fmt.Sprintf("route %s: %v", rc.Name, err))
+		}
+		domains := sets.New[string]()
+		for _, vh := range rc.VirtualHosts {
+			for _, d := range vh.Domains {
+				if domains.InsertContains(d) {
+					errs = append(errs, fmt.Sprintf("route %s: duplicate domain %s", rc.Name, d))
+				}
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+	"time"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/api/security/v1beta1"
+	typev1beta1 "istio.io/api/type/v1beta1"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestIsHighRiskConfig(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  config.Config
+		want bool
+	}{
+		{
+			name: "mesh-wide peer authentication",
+			cfg: config.Config{
+				Meta: config.Meta{GroupVersionKind: gvk.PeerAuthentication, Name: "default", Namespace: "istio-system"},
+				Spec: &v1beta1.PeerAuthentication{},
+			},
+			want: true,
+		},
+		{
+			name: "namespace peer authentication",
+			cfg: config.Config{
+				Meta: config.Meta{GroupVersionKind: gvk.PeerAuthentication, Name: "default", Namespace: "foo"},
+				Spec: &v1beta1.PeerAuthentication{},
+			},
+		},
+		{
+			name: "workload peer authentication in the root namespace",
+			cfg: config.Config{
+				Meta: config.Meta{GroupVersionKind: gvk.PeerAuthentication, Name: "default", Namespace: "istio-system"},
+				Spec: &v1beta1.PeerAuthentication{Selector: &typev1beta1.WorkloadSelector{MatchLabels: map[string]string{"app": "a"}}},
+			},
+		},
+		{
+			name: "wildcard virtual service",
+			cfg: config.Config{
+				Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "vs", Namespace: "foo"},
+				Spec: &networking.VirtualService{Hosts: []string{"a.example.com", "*.example.com"}},
+			},
+			want: true,
+		},
+		{
+			name: "virtual service",
+			cfg: config.Config{
+				Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "vs", Namespace: "foo"},
+				Spec: &networking.VirtualService{Hosts: []string{"a.example.com"}},
+			},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, isHighRiskConfig(tt.cfg, "istio-system"), tt.want)
+		})
+	}
+}
+
+func TestStagedConfigStore(t *testing.T) {
+	store := memory.Make(collections.Pilot)
+	vs := func(name, host string) config.Config {
+		return config.Config{
+			Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: name, Namespace: "foo"},
+			Spec: &networking.VirtualService{Hosts: []string{host}},
+		}
+	}
+	for _, c := range []config.Config{vs("a", "a.example.com"), vs("b", "b.example.com")} {
+		if _, err := store.Create(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	staged := stagedConfigStore{ConfigStore: store, staged: vs("b", "*.example.com")}
+
+	assert.Equal(t, staged.Get(gvk.VirtualService, "b", "foo").Spec.(*networking.VirtualService).Hosts, []string{"*.example.com"})
+	assert.Equal(t, staged.Get(gvk.VirtualService, "a", "foo").Spec.(*networking.VirtualService).Hosts, []string{"a.example.com"})
+	for _, ns := range []string{"", "foo"} {
+		configs, err := staged.List(gvk.VirtualService, ns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hosts := map[string]string{}
+		for _, c := range configs {
+			hosts[c.Name] = c.Spec.(*networking.VirtualService).Hosts[0]
+		}
+		assert.Equal(t, hosts, map[string]string{"a": "a.example.com", "b": "*.example.com"})
+	}
+	configs, err := staged.List(gvk.VirtualService, "bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(configs), 0)
+}
+
+func TestValidateStagedConfig(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	s.ConnectADS().RequestResponseAck(t, nil)
+	assert.Equal(t, len(canaryConnections(s.Discovery.Clients(), 10)), 1)
+
+	cfg := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "vs", Namespace: "default"},
+		Spec: &networking.VirtualService{
+			Hosts: []string{"*.example.com"},
+			Http: []*networking.HTTPRoute{{
+				Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "a.example.com"}}},
+			}},
+		},
+	}
+	if err := s.Discovery.ValidateStagedConfig(cfg); err != nil {
+		t.Fatalf("expected the config to be admitted, got %v", err)
+	}
+}
+
+func TestValidateStagedConfigTimeout(t *testing.T) {
+	test.SetForTest(t, &features.AdmissionCanaryTimeout, time.Duration(0))
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	s.ConnectADS().RequestResponseAck(t, nil)
+
+	cfg := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "vs", Namespace: "default"},
+		Spec: &networking.VirtualService{Hosts: []string{"*.example.com"}},
+	}
+	if err := s.Discovery.ValidateStagedConfig(cfg); err != nil {
+		t.Fatalf("expected the config to be admitted when the check times out, got %v", err)
+	}
+}
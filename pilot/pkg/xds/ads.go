@@ -27,6 +27,7 @@ import (
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
+	"istio.io/api/label"
 	"istio.io/istio/pilot/pkg/autoregistration"
 	"istio.io/istio/pilot/pkg/features"
 	istiogrpc "istio.io/istio/pilot/pkg/grpc"
@@ -36,6 +37,7 @@ import (
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/network"
 	"istio.io/istio/pkg/util/sets"
 	"istio.io/pkg/env"
 	istiolog "istio.io/pkg/log"
@@ -106,6 +108,9 @@ type Connection struct {
 
 	// errorChan is used to process error during discovery request processing.
 	errorChan chan error
+
+	// distribution tracks the pushes applied by the proxy, for the distribution status of the config.
+	distribution *connectionDistribution
 }
 
 // Event represents a config or registry event that results in a push.
@@ -119,14 +124,15 @@ type Event struct {
 
 func newConnection(peerAddr string, stream DiscoveryStream) *Connection {
 	return &Connection{
-		pushChannel: make(chan *Event),
-		initialized: make(chan struct{}),
-		stop:        make(chan struct{}),
-		reqChan:     make(chan *discovery.DiscoveryRequest, 1),
-		errorChan:   make(chan error, 1),
-		peerAddr:    peerAddr,
-		connectedAt: time.Now(),
-		stream:      stream,
+		pushChannel:  make(chan *Event),
+		initialized:  make(chan struct{}),
+		stop:         make(chan struct{}),
+		reqChan:      make(chan *discovery.DiscoveryRequest, 1),
+		errorChan:    make(chan error, 1),
+		peerAddr:     peerAddr,
+		connectedAt:  time.Now(),
+		stream:       stream,
+		distribution: newConnectionDistribution(),
 	}
 }
 
@@ -188,6 +194,7 @@ func (s *DiscoveryServer) receive(con *Connection, identities []string) {
 // handles 'push' requests and close - the code will eventually call the 'push' code, and it needs more mutex
 // protection. Original code avoided the mutexes by doing both 'push' and 'process requests' in same thread.
 func (s *DiscoveryServer) processRequest(req *discovery.DiscoveryRequest, con *Connection) error {
+	s.xdsRecorders.recordRequest(con, req)
 	stype := v3.GetShortType(req.TypeUrl)
 	log.Debugf("ADS:%s: REQ %s resources:%d nonce:%s version:%s ", stype,
 		con.conID, len(req.ResourceNames), req.ResponseNonce, req.VersionInfo)
@@ -362,6 +369,7 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 		errCode := codes.Code(request.ErrorDetail.Code)
 		log.Warnf("ADS:%s: ACK ERROR %s %s:%s", stype, con.conID, errCode.String(), request.ErrorDetail.GetMessage())
 		incrementXDSRejects(request.TypeUrl, con.proxy.ID, errCode.String())
+		con.recordNack(request.TypeUrl, request.ErrorDetail.GetMessage())
 		if s.StatusGen != nil {
 			s.StatusGen.OnNack(con.proxy, request)
 		}
@@ -427,10 +435,12 @@ func (s *DiscoveryServer) shouldRespond(con *Connection, request *discovery.Disc
 	con.proxy.Lock()
 	previousResources := con.proxy.WatchedResources[request.TypeUrl].ResourceNames
 	con.proxy.WatchedResources[request.TypeUrl].NonceAcked = request.ResponseNonce
+	con.proxy.WatchedResources[request.TypeUrl].LastNackError = ""
 	con.proxy.WatchedResources[request.TypeUrl].ResourceNames = request.ResourceNames
 	alwaysRespond := previousInfo.AlwaysRespond
 	previousInfo.AlwaysRespond = false
 	con.proxy.Unlock()
+	con.distribution.acked(request.TypeUrl, request.ResponseNonce)
 
 	// Envoy can send two DiscoveryRequests with same version and nonce.
 	// when it detects a new resource. We should respond if they change.
@@ -542,6 +552,9 @@ func (s *DiscoveryServer) initConnection(node *core.Node, con *Connection, ident
 	if err := s.authorize(con, identities); err != nil {
 		return err
 	}
+	if err := s.checkShard(proxy); err != nil {
+		return err
+	}
 
 	// Register the connection. this allows pushes to be triggered for the proxy. Note: the timing of
 	// this and initializeProxy important. While registering for pushes *after* initialization is complete seems like
@@ -628,11 +641,28 @@ func setTopologyLabels(proxy *model.Proxy) {
 		}
 	}
 
+	if features.EnableDynamicNetworks {
+		// The network assigned by the registry from the node of the proxy takes precedence over the network
+		// of its metadata, which was set at injection time, before the pod was scheduled.
+		if nw := registryNetwork(proxy); nw != "" && nw != proxy.Metadata.Network {
+			log.Debugf("assigning %s to network %s instead of %q", proxy.ID, nw, proxy.Metadata.Network)
+			proxy.Metadata.Network = nw
+		}
+	}
+
 	locality := util.LocalityToString(proxy.Locality)
 	// add topology labels to proxy labels
 	proxy.Labels = labelutil.AugmentLabels(proxy.Labels, proxy.Metadata.ClusterID, locality, proxy.Metadata.Network)
 }
 
+// registryNetwork returns the network of the proxy in the registry, from its service instances or its workload labels.
+func registryNetwork(proxy *model.Proxy) network.ID {
+	if len(proxy.ServiceInstances) > 0 && proxy.ServiceInstances[0].Endpoint.Network != "" {
+		return proxy.ServiceInstances[0].Endpoint.Network
+	}
+	return network.ID(proxy.Labels[label.TopologyNetwork.Name])
+}
+
 // initializeProxy completes the initialization of a proxy. It is expected to be called only after
 // initProxyMetadata.
 func (s *DiscoveryServer) initializeProxy(con *Connection) error {
@@ -660,7 +690,8 @@ func (s *DiscoveryServer) computeProxyState(proxy *model.Proxy, request *model.P
 	// only recompute workload labels when
 	// 1. stream established and proxy first time initialization
 	// 2. proxy update
-	recomputeLabels := request == nil || request.IsProxyUpdate()
+	// 3. scoped push, which recomputes the whole state of the proxy
+	recomputeLabels := request == nil || request.IsProxyUpdate() || request.IsScopedPush()
 	if recomputeLabels {
 		proxy.SetWorkloadLabels(s.Env)
 		setTopologyLabels(proxy)
@@ -735,6 +766,15 @@ func (s *DiscoveryServer) DeltaAggregatedResources(stream discovery.AggregatedDi
 // Compute and send the new configuration for a connection.
 func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 	pushRequest := pushEv.pushRequest
+	start := time.Now()
+
+	if pushRequest.IsProxyLogLevelUpdate() {
+		// Log level changes only concern the proxy itself, avoid pushing the other types.
+		if w := con.Watched(v3.ProxyLogLevelType); w != nil {
+			return s.pushXds(con, w, pushRequest)
+		}
+		return nil
+	}
 
 	if pushRequest.Full {
 		// Update Proxy with current information.
@@ -746,6 +786,7 @@ func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 		if pushRequest.Full {
 			// Only report for full versions, incremental pushes do not have a new version.
 			reportAllEvents(s.StatusReporter, con.conID, pushRequest.Push.LedgerVersion, nil)
+			con.distribution.skippedAll(pushRequest.Push.PushVersion, nil)
 		}
 		return nil
 	}
@@ -758,9 +799,11 @@ func (s *DiscoveryServer) pushConnection(con *Connection, pushEv *Event) error {
 			return err
 		}
 	}
+	con.recordPushDuration(time.Since(start))
 	if pushRequest.Full {
 		// Report all events for unwatched resources. Watched resources will be reported in pushXds or on ack.
 		reportAllEvents(s.StatusReporter, con.conID, pushRequest.Push.LedgerVersion, ignoreEvents)
+		con.distribution.skippedAll(pushRequest.Push.PushVersion, ignoreEvents)
 	}
 
 	proxiesConvergeDelay.Record(time.Since(pushRequest.Start).Seconds())
@@ -919,6 +962,67 @@ func (conn *Connection) send(res *discovery.DiscoveryResponse) error {
 	return err
 }
 
+// maxNackErrorLength is the length of the NACK errors kept for the debug endpoints.
+const maxNackErrorLength = 512
+
+// recordNack keeps the error of a response rejected by the proxy, for the debug endpoints.
+func (conn *Connection) recordNack(typeURL string, message string) {
+	if len(message) > maxNackErrorLength {
+		message = message[:maxNackErrorLength] + "..."
+	}
+	conn.proxy.Lock()
+	defer conn.proxy.Unlock()
+	if wr := conn.proxy.WatchedResources[typeURL]; wr != nil {
+		wr.LastNackError = message
+	}
+}
+
+// recordResponseSize keeps the size of the last response of the type, for the debug endpoints.
+func (conn *Connection) recordResponseSize(typeURL string, size int) {
+	conn.proxy.Lock()
+	defer conn.proxy.Unlock()
+	if wr := conn.proxy.WatchedResources[typeURL]; wr != nil {
+		wr.LastSize = size
+	}
+}
+
+// recordPushDuration keeps the duration of the last push, for the debug endpoints.
+func (conn *Connection) recordPushDuration(d time.Duration) {
+	conn.proxy.Lock()
+	defer conn.proxy.Unlock()
+	conn.proxy.LastPushDuration = d
+}
+
+// pushStats returns the size of the last response and the last NACK error of each type, keyed by the short type,
+// along with the duration of the last push.
+func (conn *Connection) pushStats() (sizes map[string]int, nacks map[string]string, pushDuration string) {
+	conn.proxy.RLock()
+	defer conn.proxy.RUnlock()
+	return proxyPushStats(conn.proxy)
+}
+
+// proxyPushStats is pushStats for a proxy whose lock is held by the caller.
+func proxyPushStats(proxy *model.Proxy) (sizes map[string]int, nacks map[string]string, pushDuration string) {
+	for typeURL, wr := range proxy.WatchedResources {
+		if wr.LastSize > 0 {
+			if sizes == nil {
+				sizes = map[string]int{}
+			}
+			sizes[v3.GetShortType(typeURL)] = wr.LastSize
+		}
+		if wr.LastNackError != "" {
+			if nacks == nil {
+				nacks = map[string]string{}
+			}
+			nacks[v3.GetShortType(typeURL)] = wr.LastNackError
+		}
+	}
+	if proxy.LastPushDuration > 0 {
+		pushDuration = proxy.LastPushDuration.String()
+	}
+	return
+}
+
 // nolint
 func (conn *Connection) NonceAcked(typeUrl string) string {
 	conn.proxy.RLock()
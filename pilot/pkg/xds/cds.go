@@ -34,7 +34,6 @@ var skippedCdsConfigs = map[kind.Kind]struct{}{
 	kind.AuthorizationPolicy:   {},
 	kind.RequestAuthentication: {},
 	kind.Secret:                {},
-	kind.Telemetry:             {},
 	kind.WasmPlugin:            {},
 	kind.ProxyConfig:           {},
 }
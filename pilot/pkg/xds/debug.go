@@ -23,6 +23,7 @@ import (
 	"net/http/pprof"
 	"net/netip"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -35,6 +36,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	anypb "google.golang.org/protobuf/types/known/anypb"
 
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/config/kube/crd"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
@@ -44,12 +46,14 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pilot/pkg/writeapi"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/network"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
 	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/istio/pkg/util/sets"
 	istiolog "istio.io/pkg/log"
@@ -132,6 +136,12 @@ type SyncStatus struct {
 	EndpointAcked        string `json:"endpoint_acked,omitempty"`
 	ExtensionConfigSent  string `json:"extensionconfig_sent,omitempty"`
 	ExtensionConfigAcked string `json:"extensionconfig_acked,omitempty"`
+	// ConfigSizes is the size in bytes of the last response of each type, keyed by the short type, such as CDS.
+	ConfigSizes map[string]int `json:"config_sizes,omitempty"`
+	// LastPushDuration is the time it took to generate and send the last push.
+	LastPushDuration string `json:"last_push_duration,omitempty"`
+	// NackErrors is the error of the last rejected response of each type, until a later response is acked.
+	NackErrors map[string]string `json:"nack_errors,omitempty"`
 }
 
 // SyncedVersions shows what resourceVersion of a given resource has been acked by Envoy.
@@ -191,6 +201,8 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/ndsz", "Status and debug interface for NDS", s.ndsz)
 	s.addDebugHandler(mux, internalMux, "/debug/adsz", "Status and debug interface for ADS", s.adsz)
 	s.addDebugHandler(mux, internalMux, "/debug/adsz?push=true", "Initiates push of the current state to all connected endpoints", s.adsz)
+	s.addAdminDebugHandler(mux, internalMux, "/debug/push",
+		"Recomputes the state of the proxies of a namespace, workload selector or proxy ID, and pushes them", s.scopedPushz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/syncz", "Synchronization status of all Envoys connected to this Pilot instance", s.Syncz)
 	s.addDebugHandler(mux, internalMux, "/debug/config_distribution", "Version status of all Envoys connected to this Pilot instance", s.distributedVersions)
@@ -201,21 +213,40 @@ func (s *DiscoveryServer) AddDebugHandlers(mux, internalMux *http.ServeMux, enab
 	s.addDebugHandler(mux, internalMux, "/debug/cachez", "Info about the internal XDS caches", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez?sizes=true", "Info about the size of the internal XDS caches", s.cachez)
 	s.addDebugHandler(mux, internalMux, "/debug/cachez?clear=true", "Clear the XDS caches", s.cachez)
+	s.addDebugHandler(mux, internalMux, "/debug/indexz", "Number of entries of the internal indexes of istiod", s.indexz)
 	s.addDebugHandler(mux, internalMux, "/debug/configz", "Debug support for config", s.configz)
+	s.addDebugHandler(mux, internalMux, "/debug/archive",
+		"Archive of the config, push events and proxy status of a namespace or proxy, for bug reports", s.debugArchivez)
 	s.addDebugHandler(mux, internalMux, "/debug/sidecarz", "Debug sidecar scope for a proxy", s.sidecarz)
+	s.addDebugHandler(mux, internalMux, "/debug/clientaddressz", "Debug how a gateway proxy derives the client address", s.clientAddressz)
 	s.addDebugHandler(mux, internalMux, "/debug/resourcesz", "Debug support for watched resources", s.resourcez)
 	s.addDebugHandler(mux, internalMux, "/debug/instancesz", "Debug support for service instances", s.instancesz)
 
 	s.addDebugHandler(mux, internalMux, "/debug/authorizationz", "Internal authorization policies", s.authorizationz)
+	s.addDebugHandler(mux, internalMux, "/debug/mtls_dry_runz",
+		"Inbound ports of a proxy on which dry-run PeerAuthentication policies would reject plaintext connections", s.mtlsDryRunz)
+	s.addDebugHandler(mux, internalMux, "/debug/hashz",
+		"Endpoint of a ring hash cluster selected by consistent hash keys on a proxy", s.hashz)
+	s.addDebugHandler(mux, internalMux, "/debug/trafficpolicyz",
+		"Effective outbound traffic policy of the services of a proxy, and the DestinationRules it comes from", s.trafficPolicyz)
 	s.addDebugHandler(mux, internalMux, "/debug/telemetryz", "Debug Telemetry configuration", s.telemetryz)
 	s.addDebugHandler(mux, internalMux, "/debug/config_dump", "ConfigDump in the form of the Envoy admin config dump API for passed in proxyID", s.ConfigDump)
+	s.addDebugHandler(mux, internalMux, "/debug/filter_chain_match",
+		"Filter chain of the listeners of a proxy matched by a synthetic connection, and why the others are skipped", s.filterChainMatchz)
 	s.addDebugHandler(mux, internalMux, "/debug/push_status", "Last PushContext Details", s.pushStatusHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/pushcontext", "Debug support for current push context", s.pushContextHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/connections", "Info about the connected XDS clients", s.connectionsHandler)
+	s.addDebugHandler(mux, internalMux, "/debug/proxy_log_level", "Temporarily change the log levels of selected proxies", s.proxyLogLevelz)
+	if features.EnableXDSRecorder {
+		s.addAdminDebugHandler(mux, internalMux, "/debug/xds_record",
+			"Records the xDS requests and responses of a proxy, with the secrets redacted, to replay them", s.xdsRecordz)
+	}
 
 	s.addDebugHandler(mux, internalMux, "/debug/inject", "Active inject template", s.injectTemplateHandler(webhook))
 	s.addDebugHandler(mux, internalMux, "/debug/mesh", "Active mesh config", s.meshHandler)
 	s.addDebugHandler(mux, internalMux, "/debug/clusterz", "List remote clusters where istiod reads endpoints", s.clusterz)
+	s.addDebugHandler(mux, internalMux, "/debug/sidecar_recommendationz",
+		"List the Sidecars recommended from the observed traffic, or the Sidecar of ?namespace=", s.sidecarRecommendationz)
 	s.addDebugHandler(mux, internalMux, "/debug/networkz", "List cross-network gateways", s.networkz)
 	s.addDebugHandler(mux, internalMux, "/debug/mcsz", "List information about Kubernetes MCS services", s.mcsz)
 
@@ -234,6 +265,24 @@ func (s *DiscoveryServer) addDebugHandler(mux *http.ServeMux, internalMux *http.
 	mux.HandleFunc(path, s.allowAuthenticatedOrLocalhost(http.HandlerFunc(handler)))
 }
 
+// writeDebugOperations are the admin debug handlers also allowed by the write policy, as the write API operation
+// on the namespace of the request.
+var writeDebugOperations = map[string]writeapi.Operation{
+	"/debug/push": writeapi.ProxyPush,
+}
+
+// addAdminDebugHandler adds a debug handler which changes the state of the proxies. Unlike the other debug
+// handlers, it is only allowed to the admin identities, besides the requests from localhost.
+func (s *DiscoveryServer) addAdminDebugHandler(mux *http.ServeMux, internalMux *http.ServeMux,
+	path string, help string, handler func(http.ResponseWriter, *http.Request),
+) {
+	s.debugHandlers[path] = help
+	if internalMux != nil {
+		internalMux.HandleFunc(path, handler)
+	}
+	mux.HandleFunc(path, s.allowAdminOrLocalhost(http.HandlerFunc(handler)))
+}
+
 func (s *DiscoveryServer) allowAuthenticatedOrLocalhost(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		// Request is from localhost, no need to authenticate
@@ -241,21 +290,7 @@ func (s *DiscoveryServer) allowAuthenticatedOrLocalhost(next http.Handler) http.
 			next.ServeHTTP(w, req)
 			return
 		}
-		// Authenticate request with the same method as XDS
-		authFailMsgs := make([]string, 0)
-		var ids []string
-		authRequest := security.AuthContext{Request: req}
-		for _, authn := range s.Authenticators {
-			u, err := authn.Authenticate(authRequest)
-			// If one authenticator passes, return
-			if u != nil && u.Identities != nil && err == nil {
-				ids = u.Identities
-				break
-			}
-			authFailMsgs = append(authFailMsgs, fmt.Sprintf("Authenticator %s: %v", authn.AuthenticatorType(), err))
-		}
-		if ids == nil {
-			istiolog.Errorf("Failed to authenticate %s %v", req.URL, authFailMsgs)
+		if ids := s.authenticateDebugRequest(req); ids == nil {
 			// Not including detailed info in the response, XDS doesn't either (returns a generic "authentication failure).
 			w.WriteHeader(http.StatusUnauthorized)
 			return
@@ -266,6 +301,83 @@ func (s *DiscoveryServer) allowAuthenticatedOrLocalhost(next http.Handler) http.
 	}
 }
 
+func (s *DiscoveryServer) allowAdminOrLocalhost(next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if isRequestFromLocalhost(req) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		ids := s.authenticateDebugRequest(req)
+		if ids == nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if !s.isAdmin(ids) && !s.allowedByWritePolicy(req, ids) {
+			istiolog.Warnf("Denied %s to %v, not an admin identity", req.URL.Path, ids)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, req)
+	}
+}
+
+// allowedByWritePolicy returns true if the write policy allows the identities to perform the operation of the
+// request on its namespace.
+func (s *DiscoveryServer) allowedByWritePolicy(req *http.Request, ids []string) bool {
+	op, f := writeDebugOperations[req.URL.Path]
+	if !f || s.WriteAPI == nil {
+		return false
+	}
+	r := writeapi.Request{Operation: op, Namespace: debugNamespace(req.URL.Query())}
+	if r.Namespace == "" {
+		return false
+	}
+	allowed, err := s.WriteAPI.Authorize(req.Context(), ids, r)
+	if err != nil {
+		istiolog.Warnf("Failed to authorize %s to %v with the write policy: %v", req.URL.Path, ids, err)
+		return false
+	}
+	if allowed {
+		writeapi.Audit(ids, r, nil)
+	}
+	return allowed
+}
+
+// authenticateDebugRequest authenticates the request with the same method as XDS, and returns the identities
+// of the caller, or nil if the request is not authenticated.
+func (s *DiscoveryServer) authenticateDebugRequest(req *http.Request) []string {
+	authFailMsgs := make([]string, 0)
+	authRequest := security.AuthContext{Request: req}
+	for _, authn := range s.Authenticators {
+		u, err := authn.Authenticate(authRequest)
+		// If one authenticator passes, return
+		if u != nil && u.Identities != nil && err == nil {
+			return u.Identities
+		}
+		authFailMsgs = append(authFailMsgs, fmt.Sprintf("Authenticator %s: %v", authn.AuthenticatorType(), err))
+	}
+	istiolog.Errorf("Failed to authenticate %s %v", req.URL, authFailMsgs)
+	return nil
+}
+
+// isAdmin returns true if one of the identities is the one of the service account of istiod, or is listed by
+// PILOT_DEBUG_ADMIN_IDENTITIES.
+func (s *DiscoveryServer) isAdmin(ids []string) bool {
+	for _, id := range ids {
+		if features.DebugAdminIdentities.Contains(id) {
+			return true
+		}
+		if s.systemNamespace == "" || s.IstiodServiceAccount == "" {
+			continue
+		}
+		if parsed, err := spiffe.ParseIdentity(id); err == nil &&
+			parsed.Namespace == s.systemNamespace && parsed.ServiceAccount == s.IstiodServiceAccount {
+			return true
+		}
+	}
+	return false
+}
+
 func isRequestFromLocalhost(r *http.Request) bool {
 	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
@@ -280,28 +392,37 @@ func isRequestFromLocalhost(r *http.Request) bool {
 func (s *DiscoveryServer) Syncz(w http.ResponseWriter, req *http.Request) {
 	syncz := make([]SyncStatus, 0)
 	for _, con := range s.Clients() {
-		node := con.proxy
-		if node != nil {
-			syncz = append(syncz, SyncStatus{
-				ProxyID:              node.ID,
-				ClusterID:            node.Metadata.ClusterID.String(),
-				IstioVersion:         node.Metadata.IstioVersion,
-				ClusterSent:          con.NonceSent(v3.ClusterType),
-				ClusterAcked:         con.NonceAcked(v3.ClusterType),
-				ListenerSent:         con.NonceSent(v3.ListenerType),
-				ListenerAcked:        con.NonceAcked(v3.ListenerType),
-				RouteSent:            con.NonceSent(v3.RouteType),
-				RouteAcked:           con.NonceAcked(v3.RouteType),
-				EndpointSent:         con.NonceSent(v3.EndpointType),
-				EndpointAcked:        con.NonceAcked(v3.EndpointType),
-				ExtensionConfigSent:  con.NonceSent(v3.ExtensionConfigurationType),
-				ExtensionConfigAcked: con.NonceAcked(v3.ExtensionConfigurationType),
-			})
+		if con.proxy != nil {
+			syncz = append(syncz, syncStatus(con))
 		}
 	}
 	writeJSON(w, syncz, req)
 }
 
+// syncStatus returns the synchronization status of a connected proxy.
+func syncStatus(con *Connection) SyncStatus {
+	node := con.proxy
+	sizes, nacks, pushDuration := con.pushStats()
+	return SyncStatus{
+		ProxyID:              node.ID,
+		ClusterID:            node.Metadata.ClusterID.String(),
+		IstioVersion:         node.Metadata.IstioVersion,
+		ClusterSent:          con.NonceSent(v3.ClusterType),
+		ClusterAcked:         con.NonceAcked(v3.ClusterType),
+		ListenerSent:         con.NonceSent(v3.ListenerType),
+		ListenerAcked:        con.NonceAcked(v3.ListenerType),
+		RouteSent:            con.NonceSent(v3.RouteType),
+		RouteAcked:           con.NonceAcked(v3.RouteType),
+		EndpointSent:         con.NonceSent(v3.EndpointType),
+		EndpointAcked:        con.NonceAcked(v3.EndpointType),
+		ExtensionConfigSent:  con.NonceSent(v3.ExtensionConfigurationType),
+		ExtensionConfigAcked: con.NonceAcked(v3.ExtensionConfigurationType),
+		ConfigSizes:          sizes,
+		LastPushDuration:     pushDuration,
+		NackErrors:           nacks,
+	}
+}
+
 // registryz providees debug support for registry - adding and listing model items.
 // Can be combined with the push debug interface to reproduce changes.
 func (s *DiscoveryServer) registryz(w http.ResponseWriter, req *http.Request) {
@@ -393,40 +514,38 @@ func (s *DiscoveryServer) endpointz(w http.ResponseWriter, req *http.Request) {
 }
 
 func (s *DiscoveryServer) distributedVersions(w http.ResponseWriter, req *http.Request) {
-	if !features.EnableDistributionTracking {
-		w.WriteHeader(http.StatusConflict)
-		_, _ = fmt.Fprint(w, "Pilot Version tracking is disabled.  Please set the "+
-			"PILOT_ENABLE_CONFIG_DISTRIBUTION_TRACKING environment variable to true to enable.")
+	resourceID := req.URL.Query().Get("resource")
+	if resourceID == "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_, _ = fmt.Fprintf(w, "querystring parameter 'resource' is required\n")
 		return
 	}
-	if resourceID := req.URL.Query().Get("resource"); resourceID != "" {
-		proxyNamespace := req.URL.Query().Get("proxy_namespace")
-		knownVersions := make(map[string]string)
-		var results []SyncedVersions
-		for _, con := range s.Clients() {
-			// wrap this in independent scope so that panic's don't bypass Unlock...
-			con.proxy.RLock()
-
-			if con.proxy != nil && (proxyNamespace == "" || proxyNamespace == con.proxy.ConfigNamespace) {
-				// read nonces from our statusreporter to allow for skipped nonces, etc.
-				results = append(results, SyncedVersions{
-					ProxyID: con.proxy.ID,
-					ClusterVersion: s.getResourceVersion(s.StatusReporter.QueryLastNonce(con.conID, v3.ClusterType),
-						resourceID, knownVersions),
-					ListenerVersion: s.getResourceVersion(s.StatusReporter.QueryLastNonce(con.conID, v3.ListenerType),
-						resourceID, knownVersions),
-					RouteVersion: s.getResourceVersion(s.StatusReporter.QueryLastNonce(con.conID, v3.RouteType),
-						resourceID, knownVersions),
-				})
-			}
-			con.proxy.RUnlock()
+	key, gvk, err := parseDistributionResource(resourceID)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+	current := ""
+	if s.Env.ConfigStore != nil {
+		if cfg := s.Env.ConfigStore.Get(gvk, key.Name, key.Namespace); cfg != nil {
+			current = strconv.FormatInt(cfg.Generation, 10)
 		}
-
-		writeJSON(w, results, req)
-	} else {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		_, _ = fmt.Fprintf(w, "querystring parameter 'resource' is required\n")
 	}
+	proxyNamespace := req.URL.Query().Get("proxy_namespace")
+	results := []SyncedVersions{}
+	for _, con := range s.Clients() {
+		if con.proxy == nil || (proxyNamespace != "" && proxyNamespace != con.proxy.ConfigNamespace) {
+			continue
+		}
+		results = append(results, SyncedVersions{
+			ProxyID:         con.proxy.ID,
+			ClusterVersion:  s.distribution.ackedGeneration(con.distribution, v3.ClusterType, key, current),
+			ListenerVersion: s.distribution.ackedGeneration(con.distribution, v3.ListenerType, key, current),
+			RouteVersion:    s.distribution.ackedGeneration(con.distribution, v3.RouteType, key, current),
+		})
+	}
+	writeJSON(w, results, req)
 }
 
 // VersionLen is the Config Version and is only used as the nonce prefix, but we can reconstruct
@@ -434,26 +553,6 @@ func (s *DiscoveryServer) distributedVersions(w http.ResponseWriter, req *http.R
 // len = ceil(bitlength/(2^6))+1
 const VersionLen = 12
 
-func (s *DiscoveryServer) getResourceVersion(nonce, key string, cache map[string]string) string {
-	if len(nonce) < VersionLen {
-		return ""
-	}
-	configVersion := nonce[:VersionLen]
-	result, ok := cache[configVersion]
-	if !ok {
-		lookupResult, err := s.Env.GetLedger().GetPreviousValue(configVersion, key)
-		if err != nil {
-			istiolog.Errorf("Unable to retrieve resource %s at version %s: %v", key, configVersion, err)
-			lookupResult = ""
-		}
-		// update the cache even on an error, because errors will not resolve themselves, and we don't want to
-		// repeat the same error for many s.adsClients.
-		cache[configVersion] = lookupResult
-		return lookupResult
-	}
-	return result
-}
-
 // kubernetesConfig wraps a config.Config with a custom marshaling method that matches a Kubernetes
 // object structure.
 type kubernetesConfig struct {
@@ -494,6 +593,29 @@ func (s *DiscoveryServer) sidecarz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, con.proxy.SidecarScope, req)
 }
 
+// ClientAddressDebug explains how a gateway proxy derives the downstream client address.
+type ClientAddressDebug struct {
+	model.ClientAddressDetection
+	Description string `json:"description"`
+}
+
+// clientAddressz dumps the client address detection settings computed for a gateway proxy.
+func (s *DiscoveryServer) clientAddressz(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	if con.proxy.Type != model.Router {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Client address detection only applies to gateway proxies\n"))
+		return
+	}
+	proxyConfig := con.proxy.Metadata.ProxyConfigOrDefault(s.globalPushContext().Mesh.DefaultConfig)
+	detection := con.proxy.ClientAddressDetection(proxyConfig)
+	writeJSON(w, ClientAddressDebug{ClientAddressDetection: detection, Description: detection.Describe()}, req)
+}
+
 // Resource debugging.
 func (s *DiscoveryServer) resourcez(w http.ResponseWriter, req *http.Request) {
 	schemas := make([]config.GroupVersionKind, 0)
@@ -1128,6 +1250,25 @@ func (s *DiscoveryServer) clusterz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, s.ListRemoteClusters(), req)
 }
 
+// sidecarRecommendationz lists the recommended Sidecars by namespace, or the Sidecar recommended for ?namespace=.
+// Only the istiod which is the leader of the recommendations has recommended Sidecars.
+func (s *DiscoveryServer) sidecarRecommendationz(w http.ResponseWriter, req *http.Request) {
+	if s.SidecarRecommendations == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("Sidecar recommendations are not enabled, see PILOT_SIDECAR_RECOMMENDATION_PROMETHEUS_ADDRESS\n"))
+		return
+	}
+	recommendations := s.SidecarRecommendations()
+	if ns := req.URL.Query().Get("namespace"); ns != "" {
+		filtered := map[string]*clientnetworking.Sidecar{}
+		if sc, f := recommendations[ns]; f {
+			filtered[ns] = sc
+		}
+		recommendations = filtered
+	}
+	writeJSON(w, recommendations, req)
+}
+
 // handlePushRequest handles a ?push=true query param and triggers a push.
 // A boolean response is returned to indicate if the caller should continue
 func (s *DiscoveryServer) handlePushRequest(w http.ResponseWriter, req *http.Request) bool {
@@ -0,0 +1,211 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// maxPushEvents is the number of pushes kept by the push event log.
+const maxPushEvents = 100
+
+// pushEvent is a push of istiod, and the configs that triggered it.
+type pushEvent struct {
+	Time           time.Time             `json:"time"`
+	Version        string                `json:"version"`
+	Full           bool                  `json:"full"`
+	Reasons        []model.TriggerReason `json:"reasons,omitempty"`
+	ConfigsUpdated []string              `json:"configsUpdated,omitempty"`
+
+	configs []model.ConfigKey
+}
+
+// pushEventLog keeps the last pushes, oldest first.
+type pushEventLog struct {
+	mu     sync.Mutex
+	events []pushEvent
+}
+
+func newPushEventLog() *pushEventLog {
+	return &pushEventLog{}
+}
+
+func (l *pushEventLog) record(version string, req *model.PushRequest) {
+	if l == nil {
+		return
+	}
+	ev := pushEvent{Time: time.Now(), Version: version, Full: req.Full, Reasons: req.Reason}
+	for key := range req.ConfigsUpdated {
+		ev.configs = append(ev.configs, key)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, ev)
+	if len(l.events) > maxPushEvents {
+		l.events = l.events[len(l.events)-maxPushEvents:]
+	}
+}
+
+// forNamespaces returns the pushes triggered by the configs of the namespaces, or by no config in particular. The
+// configs of the other namespaces that triggered the same pushes are left out.
+func (l *pushEventLog) forNamespaces(namespaces ...string) []pushEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	want := sets.New(namespaces...)
+	out := []pushEvent{}
+	for _, ev := range l.events {
+		if len(ev.configs) == 0 {
+			out = append(out, ev)
+			continue
+		}
+		var updated []string
+		for _, key := range ev.configs {
+			if want.Contains(key.Namespace) {
+				updated = append(updated, key.String())
+			}
+		}
+		if len(updated) == 0 {
+			continue
+		}
+		sort.Strings(updated)
+		ev.ConfigsUpdated = updated
+		out = append(out, ev)
+	}
+	return out
+}
+
+// debugArchivez returns a gzipped tar archive of the state of istiod relevant to the proxies of a namespace, or of
+// a single proxy, so that the state can be collected without access to the cluster. The archive contains:
+//
+//	config.json, the configs of the namespace and the root namespace seen by istiod.
+//	mesh.json, the mesh config.
+//	push_status.json, the status of the last push for the proxies of the namespace.
+//	events.json, the last pushes triggered by the configs of the namespace and the root namespace.
+//	proxies.json, the synchronization status of the selected proxies connected to this instance.
+//
+// The proxies are selected like /debug/push:
+//
+//	GET /debug/archive?proxyID=pod.ns archives the namespace of a single proxy.
+//	GET /debug/archive?namespace=ns&selector=app=foo archives a namespace, with the proxies matching the selector.
+func (s *DiscoveryServer) debugArchivez(w http.ResponseWriter, req *http.Request) {
+	p, err := parseScopedPush(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	// The namespace is the one of the proxy ID rather than of the proxy, which is not verified, since the debug
+	// generator only allows the identities of that namespace to request the archive.
+	namespace := debugNamespace(req.URL.Query())
+	proxies := make([]SyncStatus, 0)
+	for _, con := range s.Clients() {
+		if p.matches(con.proxy) && con.proxy.ConfigNamespace == namespace {
+			proxies = append(proxies, syncStatus(con))
+		}
+	}
+	if p.proxyID != "" && len(proxies) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(fmt.Sprintf("proxy %s is not connected to this instance", p.proxyID)))
+		return
+	}
+	mesh := s.Env.Mesh()
+	namespaces := []string{namespace}
+	if mesh.GetRootNamespace() != "" && mesh.GetRootNamespace() != namespace {
+		namespaces = append(namespaces, mesh.GetRootNamespace())
+	}
+
+	configs := make([]kubernetesConfig, 0)
+	if s.Env.ConfigStore != nil {
+		s.Env.ConfigStore.Schemas().ForEach(func(schema collection.Schema) bool {
+			for _, ns := range namespaces {
+				cfg, _ := s.Env.ConfigStore.List(schema.Resource().GroupVersionKind(), ns)
+				for _, c := range cfg {
+					configs = append(configs, kubernetesConfig{c})
+				}
+			}
+			return false
+		})
+	}
+	model.LastPushMutex.Lock()
+	pushStatus := model.LastPushStatus.ProxyStatusFor(func(proxyID string) bool {
+		return proxyIDNamespace(proxyID) == namespace
+	})
+	model.LastPushMutex.Unlock()
+	files := map[string]any{
+		"config.json":      configs,
+		"mesh.json":        mesh,
+		"push_status.json": pushStatus,
+		"events.json":      s.pushEvents.forNamespaces(namespaces...),
+		"proxies.json":     proxies,
+	}
+	archive, err := writeDebugArchive(files)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=istiod-%s-%s.tar.gz", namespace, time.Now().UTC().Format("20060102-150405")))
+	_, _ = w.Write(archive)
+}
+
+// writeDebugArchive returns a gzipped tar archive of the files, marshaled as JSON.
+func writeDebugArchive(files map[string]any) ([]byte, error) {
+	contents := map[string][]byte{}
+	for name, obj := range files {
+		b, err := config.ToPrettyJSON(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %v", name, err)
+		}
+		contents[name] = b
+	}
+
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, name := range names {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(contents[name])), ModTime: now}); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(contents[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,219 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const archiveConfigs = `
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  hosts:
+  - reviews
+  http:
+  - route:
+    - destination:
+        host: reviews
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: ratings
+  namespace: other
+spec:
+  hosts:
+  - ratings
+  http:
+  - route:
+    - destination:
+        host: ratings
+`
+
+func readDebugArchive(t *testing.T, body []byte) map[string][]byte {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[hdr.Name] = b
+	}
+}
+
+func TestDebugArchive(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: archiveConfigs})
+	ads := s.ConnectADS()
+	ads.RequestResponseAck(t, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+
+	s.Discovery.pushEvents.record("1", &model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.VirtualService, Name: "reviews", Namespace: "default"}),
+		Reason:         []model.TriggerReason{model.ConfigUpdate},
+	})
+	s.Discovery.pushEvents.record("2", &model.PushRequest{
+		Full:           true,
+		ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.VirtualService, Name: "ratings", Namespace: "other"}),
+		Reason:         []model.TriggerReason{model.ConfigUpdate},
+	})
+	s.Discovery.pushEvents.record("3", &model.PushRequest{
+		Full: true,
+		ConfigsUpdated: sets.New(
+			model.ConfigKey{Kind: kind.VirtualService, Name: "reviews", Namespace: "default"},
+			model.ConfigKey{Kind: kind.VirtualService, Name: "ratings", Namespace: "other"}),
+		Reason: []model.TriggerReason{model.ConfigUpdate},
+	})
+
+	pushStatus := model.NewPushContext()
+	pushStatus.AddMetric(model.ProxyStatusConflictInboundListener, "reviews", "test.default", "conflict")
+	pushStatus.AddMetric(model.ProxyStatusConflictInboundListener, "ratings", "test.other", "conflict")
+	pushStatus.AddMetric(model.DuplicatedClusters, "outbound|80||ratings.other.svc.cluster.local", "", "duplicated")
+	model.LastPushMutex.Lock()
+	lastPushStatus := model.LastPushStatus
+	model.LastPushStatus = pushStatus
+	model.LastPushMutex.Unlock()
+	t.Cleanup(func() {
+		model.LastPushMutex.Lock()
+		model.LastPushStatus = lastPushStatus
+		model.LastPushMutex.Unlock()
+	})
+
+	for _, query := range []string{"namespace=default", "proxyID=test.default"} {
+		t.Run(query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.Discovery.debugArchivez(rec, httptest.NewRequest("GET", "/debug/archive?"+query, nil))
+			if rec.Code != 200 {
+				t.Fatalf("got status %d: %s", rec.Code, rec.Body.String())
+			}
+			files := readDebugArchive(t, rec.Body.Bytes())
+			for _, name := range []string{"config.json", "mesh.json", "push_status.json", "events.json", "proxies.json"} {
+				if _, f := files[name]; !f {
+					t.Fatalf("expected %s in the archive, got %v", name, files)
+				}
+			}
+
+			var configs []struct {
+				Metadata struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"metadata"`
+			}
+			if err := json.Unmarshal(files["config.json"], &configs); err != nil {
+				t.Fatal(err)
+			}
+			if len(configs) != 1 || configs[0].Metadata.Name != "reviews" {
+				t.Fatalf("expected only the configs of the namespace, got %s", files["config.json"])
+			}
+
+			var events []pushEvent
+			if err := json.Unmarshal(files["events.json"], &events); err != nil {
+				t.Fatal(err)
+			}
+			versions := sets.New[string]()
+			for _, ev := range events {
+				versions.Insert(ev.Version)
+				for _, key := range ev.ConfigsUpdated {
+					if key != "VirtualService/default/reviews" {
+						t.Fatalf("expected only the configs of the namespace, got %s", files["events.json"])
+					}
+				}
+			}
+			if !versions.Contains("1") || versions.Contains("2") || !versions.Contains("3") {
+				t.Fatalf("expected only the pushes of the namespace, got %s", files["events.json"])
+			}
+
+			var status map[string]map[string]model.ProxyPushStatus
+			if err := json.Unmarshal(files["push_status.json"], &status); err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]map[string]model.ProxyPushStatus{
+				model.ProxyStatusConflictInboundListener.Name(): {"reviews": {Proxy: "test.default", Message: "conflict"}},
+			}
+			if !reflect.DeepEqual(status, want) {
+				t.Fatalf("expected only the push status of the proxies of the namespace, got %s", files["push_status.json"])
+			}
+
+			var proxies []SyncStatus
+			if err := json.Unmarshal(files["proxies.json"], &proxies); err != nil {
+				t.Fatal(err)
+			}
+			if len(proxies) != 1 || proxies[0].ProxyID != "test.default" || proxies[0].ClusterAcked == "" {
+				t.Fatalf("expected the status of the proxy, got %s", files["proxies.json"])
+			}
+		})
+	}
+
+	for query, want := range map[string]int{"": 400, "proxyID=missing.default": 404} {
+		rec := httptest.NewRecorder()
+		s.Discovery.debugArchivez(rec, httptest.NewRequest("GET", "/debug/archive?"+query, nil))
+		if rec.Code != want {
+			t.Fatalf("%q: got status %d, want %d", query, rec.Code, want)
+		}
+	}
+}
+
+func TestPushEventLog(t *testing.T) {
+	l := newPushEventLog()
+	for i := 0; i < maxPushEvents+10; i++ {
+		l.record("v", &model.PushRequest{Full: true})
+	}
+	if got := len(l.forNamespaces("default")); got != maxPushEvents {
+		t.Fatalf("expected %d events, got %d", maxPushEvents, got)
+	}
+}
+
+func TestDebugNamespace(t *testing.T) {
+	for query, want := range map[string]string{
+		"namespace=default":                  "default",
+		"proxyID=productpage-v1-abc.default": "default",
+		"proxyID=productpage":                "",
+		"":                                   "",
+	} {
+		if got := debugNamespace(httptest.NewRequest("GET", "/debug/archive?"+query, nil).URL.Query()); got != want {
+			t.Fatalf("%q: got namespace %q, want %q", query, got, want)
+		}
+	}
+}
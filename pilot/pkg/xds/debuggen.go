@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	anypb "google.golang.org/protobuf/types/known/anypb"
@@ -35,6 +36,28 @@ var activeNamespaceDebuggers = map[string]struct{}{
 	"edsz":        {},
 }
 
+// ownNamespaceDebuggers are the debug types allowed to the identities of other namespaces than the system namespace,
+// only for the proxies of their namespace.
+var ownNamespaceDebuggers = map[string]struct{}{
+	"archive": {},
+}
+
+// debugNamespace returns the namespace of the proxies selected by a debug request, from its namespace or proxyID.
+func debugNamespace(q url.Values) string {
+	if id := q.Get("proxyID"); id != "" {
+		return proxyIDNamespace(id)
+	}
+	return q.Get("namespace")
+}
+
+// proxyIDNamespace returns the namespace of a proxy ID of the form pod.namespace.
+func proxyIDNamespace(id string) string {
+	if i := strings.LastIndex(id, "."); i >= 0 {
+		return id[i+1:]
+	}
+	return ""
+}
+
 // DebugGen is a Generator for istio debug info
 type DebugGen struct {
 	Server          *DiscoveryServer
@@ -102,6 +125,8 @@ func (dg *DebugGen) Generate(proxy *model.Proxy, w *model.WatchedResource, req *
 		shouldAllow := false
 		if _, ok := activeNamespaceDebuggers[debugType]; ok {
 			shouldAllow = true
+		} else if _, ok := ownNamespaceDebuggers[debugType]; ok {
+			shouldAllow = debugNamespace(u.Query()) == identity.Namespace
 		}
 		if !shouldAllow {
 			return res, model.DefaultXdsLogDetails, fmt.Errorf("the debug info is not available for current identity: %q", identity)
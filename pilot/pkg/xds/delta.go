@@ -152,6 +152,15 @@ func (s *DiscoveryServer) StreamDeltas(stream DeltaDiscoveryStream) error {
 // for large configs. The method will hold a lock on con.pushMutex.
 func (s *DiscoveryServer) pushConnectionDelta(con *Connection, pushEv *Event) error {
 	pushRequest := pushEv.pushRequest
+	start := time.Now()
+
+	if pushRequest.IsProxyLogLevelUpdate() {
+		// Log level changes only concern the proxy itself, avoid pushing the other types.
+		if w := con.Watched(v3.ProxyLogLevelType); w != nil {
+			return s.pushDeltaXds(con, w, pushRequest)
+		}
+		return nil
+	}
 
 	if pushRequest.Full {
 		// Update Proxy with current information.
@@ -163,6 +172,7 @@ func (s *DiscoveryServer) pushConnectionDelta(con *Connection, pushEv *Event) er
 		if pushRequest.Full {
 			// Only report for full versions, incremental pushes do not have a new version
 			reportAllEvents(s.StatusReporter, con.conID, pushRequest.Push.LedgerVersion, nil)
+			con.distribution.skippedAll(pushRequest.Push.PushVersion, nil)
 		}
 		return nil
 	}
@@ -175,9 +185,11 @@ func (s *DiscoveryServer) pushConnectionDelta(con *Connection, pushEv *Event) er
 			return err
 		}
 	}
+	con.recordPushDuration(time.Since(start))
 	if pushRequest.Full {
 		// Report all events for unwatched resources. Watched resources will be reported in pushXds or on ack.
 		reportAllEvents(s.StatusReporter, con.conID, pushRequest.Push.LedgerVersion, ignoreEvents)
+		con.distribution.skippedAll(pushRequest.Push.PushVersion, ignoreEvents)
 	}
 
 	proxiesConvergeDelay.Record(time.Since(pushRequest.Start).Seconds())
@@ -314,6 +326,7 @@ func (s *DiscoveryServer) shouldRespondDelta(con *Connection, request *discovery
 		errCode := codes.Code(request.ErrorDetail.Code)
 		deltaLog.Warnf("ADS:%s: ACK ERROR %s %s:%s", stype, con.conID, errCode.String(), request.ErrorDetail.GetMessage())
 		incrementXDSRejects(request.TypeUrl, con.proxy.ID, errCode.String())
+		con.recordNack(request.TypeUrl, request.ErrorDetail.GetMessage())
 		if s.StatusGen != nil {
 			s.StatusGen.OnNack(con.proxy, deltaToSotwRequest(request))
 		}
@@ -376,10 +389,12 @@ func (s *DiscoveryServer) shouldRespondDelta(con *Connection, request *discovery
 	previousResources := con.proxy.WatchedResources[request.TypeUrl].ResourceNames
 	deltaResources := deltaWatchedResources(previousResources, request)
 	con.proxy.WatchedResources[request.TypeUrl].NonceAcked = request.ResponseNonce
+	con.proxy.WatchedResources[request.TypeUrl].LastNackError = ""
 	con.proxy.WatchedResources[request.TypeUrl].ResourceNames = deltaResources
 	alwaysRespond := previousInfo.AlwaysRespond
 	previousInfo.AlwaysRespond = false
 	con.proxy.Unlock()
+	con.distribution.acked(request.TypeUrl, request.ResponseNonce)
 
 	oldAck := listEqualUnordered(previousResources, deltaResources)
 	// Spontaneous DeltaDiscoveryRequests from the client.
@@ -461,6 +476,9 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection,
 		if s.StatusReporter != nil {
 			s.StatusReporter.RegisterEvent(con.conID, w.TypeUrl, req.Push.LedgerVersion)
 		}
+		if req.Full {
+			con.distribution.skipped(w.TypeUrl, req.Push.PushVersion)
+		}
 		return err
 	}
 	defer func() { recordPushTime(w.TypeUrl, time.Since(t0)) }()
@@ -514,6 +532,8 @@ func (s *DiscoveryServer) pushDeltaXds(con *Connection,
 		}
 		return err
 	}
+	con.distribution.sent(w.TypeUrl, resp.Nonce, resp.SystemVersionInfo)
+	con.recordResponseSize(w.TypeUrl, configSize)
 
 	switch {
 	case !req.Full:
@@ -544,6 +564,7 @@ func newDeltaConnection(peerAddr string, stream DeltaDiscoveryStream) *Connectio
 		deltaStream:  stream,
 		deltaReqChan: make(chan *discovery.DeltaDiscoveryRequest, 1),
 		errorChan:    make(chan error, 1),
+		distribution: newConnectionDistribution(),
 	}
 }
 
@@ -23,11 +23,13 @@ import (
 	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
 	"github.com/google/uuid"
 	"go.uber.org/atomic"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 
+	clientnetworking "istio.io/client-go/pkg/apis/networking/v1alpha3"
 	"istio.io/istio/pilot/pkg/autoregistration"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
@@ -39,6 +41,7 @@ import (
 	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
 	"istio.io/istio/pilot/pkg/serviceregistry/memory"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pilot/pkg/writeapi"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/cluster"
 	"istio.io/istio/pkg/security"
@@ -151,9 +154,42 @@ type DiscoveryServer struct {
 	// ListRemoteClusters collects debug information about other clusters this istiod reads from.
 	ListRemoteClusters func() []cluster.DebugInfo
 
+	// SidecarRecommendations returns the Sidecars recommended by this istiod by namespace, if it recommends them.
+	SidecarRecommendations func() map[string]*clientnetworking.Sidecar
+
 	// ClusterAliases are aliase names for cluster. When a proxy connects with a cluster ID
 	// and if it has a different alias we should use that a cluster ID for proxy.
 	ClusterAliases map[cluster.ID]cluster.ID
+
+	// proxyLogLevels holds the temporary log levels pushed to proxies through the debug interface.
+	proxyLogLevels *proxyLogLevels
+
+	// distribution tracks the config resource generations applied by the connected proxies.
+	distribution *distributionTracker
+
+	// xdsRecorders holds the recordings of the xDS streams of proxies, if enabled.
+	xdsRecorders *xdsRecorders
+
+	// ProxyShard is the shard of the proxies served by this replica, if proxies are sharded between replicas.
+	ProxyShard model.ProxyShard
+
+	// systemNamespace and IstiodServiceAccount are the namespace and service account of istiod. The identity of
+	// this service account is allowed to use the debug endpoints which change the state of the proxies.
+	systemNamespace      string
+	IstiodServiceAccount string
+
+	// WriteAPI, if set, authorizes the identities which are not admin to use some of the debug endpoints which
+	// change the state of the proxies, with the write policy.
+	WriteAPI *writeapi.Server
+
+	// generationCache shares the configuration generated for equivalent proxies, if enabled.
+	generationCache *generationCache
+
+	// loadReports holds the load of the endpoints reported by the proxies, used to weight the endpoints.
+	loadReports *loadReports
+
+	// pushEvents holds the last pushes, included in the debug archives.
+	pushEvents *pushEventLog
 }
 
 // NewDiscoveryServer creates DiscoveryServer that sources data from Pilot's internal mesh data structures
@@ -170,6 +206,10 @@ func NewDiscoveryServer(env *model.Environment, instanceID string, clusterAliase
 		pushQueue:           NewPushQueue(),
 		debugHandlers:       map[string]string{},
 		adsClients:          map[string]*Connection{},
+		proxyLogLevels:      &proxyLogLevels{},
+		distribution:        newDistributionTracker(),
+		loadReports:         newLoadReports(),
+		pushEvents:          newPushEventLog(),
 		debounceOptions: debounceOptions{
 			debounceAfter:     features.DebounceAfter,
 			debounceMax:       features.DebounceMax,
@@ -192,6 +232,13 @@ func NewDiscoveryServer(env *model.Environment, instanceID string, clusterAliase
 		out.Env.EndpointIndex.SetCache(out.Cache)
 	}
 
+	if features.EnableXDSEquivalenceCache {
+		out.generationCache = newGenerationCache()
+	}
+
+	if features.EnableXDSRecorder {
+		out.xdsRecorders = newXDSRecorders(features.XDSRecordingDir)
+	}
 	out.ConfigGenerator = core.NewConfigGenerator(out.Cache)
 
 	return out
@@ -224,6 +271,9 @@ func (s *DiscoveryServer) closeJwksResolver() {
 func (s *DiscoveryServer) Register(rpcs *grpc.Server) {
 	// Register v3 server
 	discovery.RegisterAggregatedDiscoveryServiceServer(rpcs, s)
+	if features.EnableLoadReporting {
+		lrs.RegisterLoadReportingServiceServer(rpcs, s)
+	}
 }
 
 var processStartTime = time.Now()
@@ -243,6 +293,9 @@ func (s *DiscoveryServer) Start(stopCh <-chan struct{}) {
 	go s.handleUpdates(stopCh)
 	go s.periodicRefreshMetrics(stopCh)
 	go s.sendPushes(stopCh)
+	if features.EnableLoadReporting {
+		go s.updateLoadWeights(stopCh)
+	}
 }
 
 func (s *DiscoveryServer) getNonK8sRegistries() []serviceregistry.Instance {
@@ -307,6 +360,7 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 	if !req.Full {
 		req.Push = s.globalPushContext()
 		s.dropCacheForRequest(req)
+		s.pushEvents.record(versionInfo(), req)
 		s.AdsPushAll(versionInfo(), req)
 		return
 	}
@@ -326,6 +380,8 @@ func (s *DiscoveryServer) Push(req *model.PushRequest) {
 	if err != nil {
 		return
 	}
+	s.distribution.recordPush(versionLocal, req.ConfigsUpdated, s.Env.ConfigStore)
+	s.pushEvents.record(versionLocal, req)
 	initContextTime := time.Since(t0)
 	log.Debugf("InitContext %v for push took %s", versionLocal, initContextTime)
 	pushContextInitTime.Record(initContextTime.Seconds())
@@ -563,6 +619,7 @@ func (s *DiscoveryServer) sendPushes(stopCh <-chan struct{}) {
 
 // InitGenerators initializes generators to be used by XdsServer.
 func (s *DiscoveryServer) InitGenerators(env *model.Environment, systemNameSpace string, internalDebugMux *http.ServeMux) {
+	s.systemNamespace = systemNameSpace
 	edsGen := &EdsGenerator{Server: s}
 	s.StatusGen = NewStatusGen(s)
 	s.Generators[v3.ClusterType] = &CdsGenerator{Server: s}
@@ -587,6 +644,7 @@ func (s *DiscoveryServer) InitGenerators(env *model.Environment, systemNameSpace
 	s.Generators["event"] = s.StatusGen
 	s.Generators[v3.DebugType] = NewDebugGen(s, systemNameSpace, internalDebugMux)
 	s.Generators[v3.BootstrapType] = &BootstrapGenerator{Server: s}
+	s.Generators[v3.ProxyLogLevelType] = &ProxyLogLevelGenerator{Server: s}
 }
 
 // Shutdown shuts down DiscoveryServer components.
@@ -0,0 +1,248 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// maxTrackedGenerations is the number of generations of a resource kept by the distribution tracker.
+const maxTrackedGenerations = 10
+
+// distributionTracker records which generation of each config resource the connected proxies have applied.
+// For every resource generation, it keeps the first push that included it; for every connection and xDS type,
+// the connectionDistribution of the connection keeps the last push ACKed by the proxy (or skipped, as it did not
+// change the proxy config). A proxy has applied a generation once it has ACKed a push at least as recent as the
+// one that introduced it. The state of the connections is kept per connection, so that sending and ACKing
+// responses does not contend with the other connections.
+// Unlike the ledger based status reporter, this does not need to keep the history of the whole configuration.
+type distributionTracker struct {
+	mu sync.RWMutex
+	// resources holds the last generations of each resource updated since istiod started, oldest first.
+	resources map[model.ConfigKey][]generationPush
+}
+
+type generationPush struct {
+	generation int64
+	push       uint64
+}
+
+// connectionDistribution holds the distribution state of a connection, by type URL.
+type connectionDistribution struct {
+	mu    sync.Mutex
+	types map[string]*typeDistribution
+}
+
+type typeDistribution struct {
+	// acked is the last push applied by the proxy.
+	acked uint64
+	// pendingNonce is the nonce of the last response sent and not yet ACKed, generated for the pending push.
+	pendingNonce string
+	pending      uint64
+}
+
+func newDistributionTracker() *distributionTracker {
+	return &distributionTracker{
+		resources: map[model.ConfigKey][]generationPush{},
+	}
+}
+
+func newConnectionDistribution() *connectionDistribution {
+	return &connectionDistribution{
+		types: map[string]*typeDistribution{},
+	}
+}
+
+// pushSequence returns the sequence number of a push version, in the form "<timestamp>/<sequence>".
+func pushSequence(version string) uint64 {
+	seq, err := strconv.ParseUint(version[strings.LastIndex(version, "/")+1:], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return seq
+}
+
+var pilotKinds = func() map[kind.Kind]config.GroupVersionKind {
+	kinds := map[kind.Kind]config.GroupVersionKind{}
+	for _, s := range collections.Pilot.All() {
+		gvk := s.Resource().GroupVersionKind()
+		kinds[kind.FromGvk(gvk)] = gvk
+	}
+	return kinds
+}()
+
+// recordPush records the generations of the configs updated by the push with the given version.
+func (t *distributionTracker) recordPush(version string, configs sets.Set[model.ConfigKey], store model.ConfigStore) {
+	seq := pushSequence(version)
+	if seq == 0 || store == nil || len(configs) == 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for key := range configs {
+		gvk, f := pilotKinds[key.Kind]
+		if !f {
+			continue
+		}
+		cfg := store.Get(gvk, key.Name, key.Namespace)
+		if cfg == nil {
+			delete(t.resources, key)
+			continue
+		}
+		gens := t.resources[key]
+		if len(gens) > 0 && gens[len(gens)-1].generation == cfg.Generation {
+			continue
+		}
+		gens = append(gens, generationPush{generation: cfg.Generation, push: seq})
+		if len(gens) > maxTrackedGenerations {
+			gens = gens[len(gens)-maxTrackedGenerations:]
+		}
+		t.resources[key] = gens
+	}
+}
+
+func (c *connectionDistribution) typeLocked(typeURL string) *typeDistribution {
+	d := c.types[typeURL]
+	if d == nil {
+		d = &typeDistribution{}
+		c.types[typeURL] = d
+	}
+	return d
+}
+
+// sent records a response sent to the connection for the push with the given version.
+func (c *connectionDistribution) sent(typeURL, nonce, version string) {
+	if _, f := AllEventTypes[typeURL]; !f {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.typeLocked(typeURL)
+	d.pendingNonce = nonce
+	d.pending = pushSequence(version)
+}
+
+// acked records the ACK of a response by the connection.
+func (c *connectionDistribution) acked(typeURL, nonce string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.types[typeURL]
+	if d == nil || d.pendingNonce == "" || d.pendingNonce != nonce {
+		return
+	}
+	if d.pending > d.acked {
+		d.acked = d.pending
+	}
+	d.pendingNonce = ""
+}
+
+// skipped records that the push with the given version did not change the config of the type for the connection.
+func (c *connectionDistribution) skipped(typeURL, version string) {
+	if _, f := AllEventTypes[typeURL]; !f {
+		return
+	}
+	seq := pushSequence(version)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.typeLocked(typeURL)
+	if d.pendingNonce != "" {
+		// The proxy will have applied this push once it ACKs the pending response.
+		if seq > d.pending {
+			d.pending = seq
+		}
+	} else if seq > d.acked {
+		d.acked = seq
+	}
+}
+
+// skippedAll records a push skipped for all the types, except the ignored ones, for the connection.
+func (c *connectionDistribution) skippedAll(version string, ignored sets.String) {
+	for _, typeURL := range AllEventTypesList {
+		if !ignored.Contains(typeURL) {
+			c.skipped(typeURL, version)
+		}
+	}
+}
+
+// ackedPush returns the last push applied by the connection for a type, or 0 if it has not applied any.
+func (c *connectionDistribution) ackedPush(typeURL string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d := c.types[typeURL]; d != nil {
+		return d.acked
+	}
+	// The type was never pushed to the proxy, so it is as recent as the least recent type pushed.
+	var acked uint64
+	for _, d := range c.types {
+		if acked == 0 || d.acked < acked {
+			acked = d.acked
+		}
+	}
+	return acked
+}
+
+// ackedGeneration returns the generation of a resource applied by a connection for a type, or an empty string if
+// the proxy does not have a known generation yet. current is the generation of the resource in the config store,
+// which all the proxies have if the resource was not updated since istiod started.
+func (t *distributionTracker) ackedGeneration(con *connectionDistribution, typeURL string, key model.ConfigKey, current string) string {
+	acked := con.ackedPush(typeURL)
+	if acked == 0 {
+		return ""
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	gens := t.resources[key]
+	if len(gens) == 0 {
+		return current
+	}
+	for i := len(gens) - 1; i >= 0; i-- {
+		if gens[i].push <= acked {
+			return strconv.FormatInt(gens[i].generation, 10)
+		}
+	}
+	return ""
+}
+
+// parseDistributionResource parses a resource, as "<group>/<version>/<kind>/<namespace>/<name>" or
+// "<kind>/<namespace>/<name>".
+func parseDistributionResource(resource string) (model.ConfigKey, config.GroupVersionKind, error) {
+	parts := strings.Split(resource, "/")
+	var k, ns, name string
+	switch len(parts) {
+	case 5:
+		k, ns, name = parts[2], parts[3], parts[4]
+	case 3:
+		k, ns, name = parts[0], parts[1], parts[2]
+	default:
+		return model.ConfigKey{}, config.GroupVersionKind{}, fmt.Errorf("invalid resource %q, must be in the form "+
+			"<group>/<version>/<kind>/<namespace>/<name>", resource)
+	}
+	for ck, gvk := range pilotKinds {
+		// The version is ignored, all the versions of a type share the same generations.
+		if gvk.Kind == k && (len(parts) == 3 || gvk.Group == parts[0]) {
+			return model.ConfigKey{Kind: ck, Name: name, Namespace: ns}, gvk, nil
+		}
+	}
+	return model.ConfigKey{}, config.GroupVersionKind{}, fmt.Errorf("unknown resource type %q", k)
+}
@@ -0,0 +1,112 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/gvk"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func TestDistributionTracker(t *testing.T) {
+	store := memory.MakeSkipValidation(collections.Pilot)
+	vs := config.Config{
+		Meta: config.Meta{GroupVersionKind: gvk.VirtualService, Name: "reviews", Namespace: "default", Generation: 1},
+		Spec: &networking.VirtualService{},
+	}
+	if _, err := store.Create(vs); err != nil {
+		t.Fatal(err)
+	}
+	key := model.ConfigKey{Kind: kind.VirtualService, Name: "reviews", Namespace: "default"}
+	tracker := newDistributionTracker()
+	a, b := newConnectionDistribution(), newConnectionDistribution()
+
+	// Proxies connected before any update have the current generation once they applied a push.
+	a.sent(v3.ListenerType, "n1", "2023-01-01T00:00:00Z/1")
+	if got := tracker.ackedGeneration(a, v3.ListenerType, key, "1"); got != "" {
+		t.Fatalf("expected no generation before the ACK, got %q", got)
+	}
+	a.acked(v3.ListenerType, "n1")
+	if got := tracker.ackedGeneration(a, v3.ListenerType, key, "1"); got != "1" {
+		t.Fatalf("expected current generation, got %q", got)
+	}
+	// Types never pushed follow the other types.
+	if got := tracker.ackedGeneration(a, v3.RouteType, key, "1"); got != "1" {
+		t.Fatalf("expected current generation for unwatched type, got %q", got)
+	}
+
+	vs.Generation = 2
+	if _, err := store.Update(vs); err != nil {
+		t.Fatal(err)
+	}
+	tracker.recordPush("2023-01-01T00:00:01Z/2", sets.New(key), store)
+	if got := tracker.ackedGeneration(a, v3.ListenerType, key, "2"); got != "1" {
+		t.Fatalf("expected previous generation before the push, got %q", got)
+	}
+
+	// A stale nonce is not an ACK of the pending push.
+	a.sent(v3.ListenerType, "n2", "2023-01-01T00:00:01Z/2")
+	a.acked(v3.ListenerType, "n1")
+	if got := tracker.ackedGeneration(a, v3.ListenerType, key, "2"); got != "1" {
+		t.Fatalf("expected previous generation on stale nonce, got %q", got)
+	}
+	a.acked(v3.ListenerType, "n2")
+	if got := tracker.ackedGeneration(a, v3.ListenerType, key, "2"); got != "2" {
+		t.Fatalf("expected new generation after the ACK, got %q", got)
+	}
+
+	// Skipped pushes count as applied, once the pending response is ACKed.
+	b.sent(v3.ClusterType, "n3", "2023-01-01T00:00:00Z/1")
+	b.skippedAll("2023-01-01T00:00:01Z/2", nil)
+	if got := tracker.ackedGeneration(b, v3.ClusterType, key, "2"); got != "" {
+		t.Fatalf("expected no generation with a pending response, got %q", got)
+	}
+	if got := tracker.ackedGeneration(b, v3.RouteType, key, "2"); got != "2" {
+		t.Fatalf("expected skipped push to be applied, got %q", got)
+	}
+	b.acked(v3.ClusterType, "n3")
+	if got := tracker.ackedGeneration(b, v3.ClusterType, key, "2"); got != "2" {
+		t.Fatalf("expected skipped push to be applied after the ACK, got %q", got)
+	}
+}
+
+func TestParseDistributionResource(t *testing.T) {
+	for _, resource := range []string{
+		"networking.istio.io/v1alpha3/VirtualService/default/reviews",
+		"networking.istio.io/v1beta1/VirtualService/default/reviews",
+		"VirtualService/default/reviews",
+	} {
+		key, _, err := parseDistributionResource(resource)
+		if err != nil {
+			t.Fatalf("%s: %v", resource, err)
+		}
+		if key != (model.ConfigKey{Kind: kind.VirtualService, Name: "reviews", Namespace: "default"}) {
+			t.Fatalf("%s: unexpected key %v", resource, key)
+		}
+	}
+	for _, resource := range []string{"reviews", "Unknown/default/reviews", "security.istio.io/v1beta1/VirtualService/default/reviews"} {
+		if _, _, err := parseDistributionResource(resource); err == nil {
+			t.Fatalf("expected %q to be rejected", resource)
+		}
+	}
+}
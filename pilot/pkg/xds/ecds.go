@@ -21,6 +21,7 @@ import (
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 
 	credscontroller "istio.io/istio/pilot/pkg/credentials"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/model/credentials"
 	"istio.io/istio/pilot/pkg/util/protoconv"
@@ -45,7 +46,8 @@ func ecdsNeedsPush(req *model.PushRequest) bool {
 	if len(req.ConfigsUpdated) == 0 {
 		return true
 	}
-	// Only push if config updates is triggered by EnvoyFilter, WasmPlugin, or Secret.
+	// Only push if config updates is triggered by EnvoyFilter, WasmPlugin, or Secret, or by AuthorizationPolicy
+	// when the authorization filters are sent with ECDS.
 	for config := range req.ConfigsUpdated {
 		switch config.Kind {
 		case kind.EnvoyFilter:
@@ -54,6 +56,10 @@ func ecdsNeedsPush(req *model.PushRequest) bool {
 			return true
 		case kind.Secret:
 			return true
+		case kind.AuthorizationPolicy:
+			if features.EnableAuthzECDS {
+				return true
+			}
 		}
 	}
 	return false
@@ -70,6 +76,10 @@ func onlyReferencedConfigsUpdated(req *model.PushRequest) bool {
 			return false
 		case kind.WasmPlugin:
 			return false
+		case kind.AuthorizationPolicy:
+			if features.EnableAuthzECDS {
+				return false
+			}
 		case kind.Secret:
 			dependentUpdated = true
 		}
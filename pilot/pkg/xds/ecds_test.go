@@ -20,19 +20,25 @@ import (
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	extensions "istio.io/api/extensions/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/networking"
+	"istio.io/istio/pilot/pkg/networking/plugin/authz"
 	"istio.io/istio/pilot/pkg/xds"
 	v3 "istio.io/istio/pilot/pkg/xds/v3"
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/gvk"
 	"istio.io/istio/pkg/config/schema/kind"
+	xdsfilters "istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/util/sets"
 )
 
@@ -296,3 +302,110 @@ func TestECDSGenerate(t *testing.T) {
 		})
 	}
 }
+
+func TestECDSAuthorizationPolicy(t *testing.T) {
+	test.SetForTest(t, &features.EnableAuthzECDS, true)
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: denyAdminPolicy})
+	proxy := s.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{ClusterID: "Kubernetes"}})
+
+	// The listener only references the filters, so that policy updates do not change it.
+	filters := authz.NewBuilder(authz.Local, s.PushContext(), proxy).BuildHTTP(networking.ListenerClassSidecarInbound)
+	if got := filterNames(filters); !reflect.DeepEqual(got, localAuthzNames) {
+		t.Fatalf("got filters %v, want %v", got, localAuthzNames)
+	}
+	for _, f := range filters {
+		if got := f.GetConfigDiscovery().GetTypeUrls(); !reflect.DeepEqual(got, []string{xdsfilters.RBACHTTPFilterType}) {
+			t.Fatalf("unexpected type URLs %v of %s", got, f.Name)
+		}
+	}
+
+	gen := s.Discovery.Generators[v3.ExtensionConfigurationType]
+	req := &model.PushRequest{
+		Full:           true,
+		Push:           s.PushContext(),
+		Start:          time.Now(),
+		ConfigsUpdated: sets.New(model.ConfigKey{Kind: kind.AuthorizationPolicy, Name: "deny-admin", Namespace: "default"}),
+	}
+	resources, _, _ := gen.Generate(proxy, &model.WatchedResource{ResourceNames: []string{"istio.authz.deny"}}, req)
+	if len(resources) != 1 || resources[0].Name != "istio.authz.deny" {
+		t.Fatalf("expected the RBAC extension config, got %v", resources)
+	}
+	ec := &core.TypedExtensionConfig{}
+	if err := resources[0].Resource.UnmarshalTo(ec); err != nil {
+		t.Fatal(err)
+	}
+	if ec.TypedConfig.TypeUrl != xdsfilters.RBACHTTPFilterType {
+		t.Fatalf("unexpected extension config type %v", ec.TypedConfig.TypeUrl)
+	}
+}
+
+// The names of the authorization filters sent with ECDS must not depend on the policies, so that a name never points
+// to the config of another action, or of another filter type.
+func TestECDSAuthorizationPolicyStableNames(t *testing.T) {
+	test.SetForTest(t, &features.EnableAuthzECDS, true)
+	allowGet := `
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: allow-get
+  namespace: default
+spec:
+  action: ALLOW
+  rules:
+  - to:
+    - operation:
+        methods: ["GET"]
+`
+	for _, tt := range []struct {
+		name   string
+		config string
+	}{
+		{name: "no policies"},
+		{name: "deny", config: denyAdminPolicy},
+		{name: "policy added", config: denyAdminPolicy + "---\n" + allowGet},
+		{name: "policy removed", config: allowGet},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: tt.config})
+			proxy := s.SetupProxy(&model.Proxy{Metadata: &model.NodeMetadata{ClusterID: "Kubernetes"}})
+			filters := authz.NewBuilder(authz.Local, s.PushContext(), proxy).BuildHTTP(networking.ListenerClassSidecarInbound)
+			if got := filterNames(filters); !reflect.DeepEqual(got, localAuthzNames) {
+				t.Fatalf("got filters %v, want %v", got, localAuthzNames)
+			}
+			gen := s.Discovery.Generators[v3.ExtensionConfigurationType]
+			req := &model.PushRequest{Full: true, Push: s.PushContext(), Start: time.Now()}
+			resources, _, _ := gen.Generate(proxy, &model.WatchedResource{ResourceNames: localAuthzNames}, req)
+			got := make([]string, 0, len(resources))
+			for _, r := range resources {
+				got = append(got, r.Name)
+			}
+			if !reflect.DeepEqual(got, localAuthzNames) {
+				t.Fatalf("got extension configs %v, want %v", got, localAuthzNames)
+			}
+		})
+	}
+}
+
+var localAuthzNames = []string{"istio.authz.audit", "istio.authz.deny", "istio.authz.allow"}
+
+const denyAdminPolicy = `
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: deny-admin
+  namespace: default
+spec:
+  action: DENY
+  rules:
+  - to:
+    - operation:
+        paths: ["/admin"]
+`
+
+func filterNames(filters []*hcm.HttpFilter) []string {
+	names := make([]string, 0, len(filters))
+	for _, f := range filters {
+		names = append(names, f.Name)
+	}
+	return names
+}
@@ -295,6 +295,8 @@ func (s *DiscoveryServer) localityEndpointsForCluster(b EndpointBuilder) ([]*Loc
 		return nil, nil
 	}
 
+	b.staleClusters = s.Env.EndpointIndex.StaleClusters()
+	b.loadFactors = s.loadReports.endpointFactors(b.hostname, b.port)
 	return b.buildLocalityLbEndpointsFromShards(epShards, svcPort), nil
 }
 
@@ -331,6 +333,15 @@ func (s *DiscoveryServer) generateEndpoints(b EndpointBuilder) *endpoint.Cluster
 				LocalityLbEndpoints: l.Endpoints[i],
 			}
 		}
+		// The traffic only fails over, and back, with outlier detection.
+		if enableFailover {
+			loadbalancer.ApplyLocalityFailback(l, b.localityEjectionPolicy())
+		}
+		// Priority groups replace the failover settings, and like them need outlier detection to ever fail over.
+		if enableFailover && lbSetting.GetDistribute() == nil &&
+			loadbalancer.ApplyLocalityPriorityGroups(b.locality, l, b.localityPriorityGroups()) {
+			return l
+		}
 		loadbalancer.ApplyLocalityLBSetting(l, wrappedLocalityLbEndpoints, b.locality, b.proxy.Labels, lbSetting, enableFailover)
 	}
 	return l
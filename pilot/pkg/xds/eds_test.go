@@ -304,6 +304,69 @@ func TestEdsWeightedServiceEntry(t *testing.T) {
 	}
 }
 
+// The clusters of AUTO_PASSTHROUGH gateways are named after the DNS Srv subset key, which must round-trip through
+// EDS even when it is longer than the truncated SNI.
+func TestEdsLongHostnameSniDnat(t *testing.T) {
+	hostname := strings.Join([]string{strings.Repeat("a", 60), strings.Repeat("b", 60), strings.Repeat("c", 60),
+		strings.Repeat("d", 60), "com"}, ".")
+	s := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{ConfigString: fmt.Sprintf(`
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: long
+  namespace: default
+spec:
+  hosts: [%s]
+  ports:
+  - number: 80
+    name: tls
+    protocol: TLS
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+    labels:
+      security.istio.io/tlsMode: istio
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: passthrough
+  namespace: istio-system
+spec:
+  selector:
+    istio: ingressgateway
+  servers:
+  - port:
+      number: 15443
+      name: tls
+      protocol: TLS
+    tls:
+      mode: AUTO_PASSTHROUGH
+    hosts: ["*"]
+`, hostname)})
+	proxy := s.SetupProxy(&model.Proxy{
+		Type:            model.Router,
+		ConfigNamespace: "istio-system",
+		Labels:          map[string]string{"istio": "ingressgateway"},
+		Metadata:        &model.NodeMetadata{ClusterID: "Kubernetes"},
+	})
+
+	clusterName := model.BuildDNSSrvSubsetKey(model.TrafficDirectionOutbound, "", host.Name(hostname), 80)
+	if sni := model.BuildDNSSrvSubsetSNI(model.TrafficDirectionOutbound, "", host.Name(hostname), 80); sni == clusterName {
+		t.Fatalf("expected the SNI of %s to be truncated", clusterName)
+	}
+	for _, cla := range s.Endpoints(proxy) {
+		if cla.ClusterName != clusterName {
+			continue
+		}
+		if len(cla.Endpoints) == 0 || len(cla.Endpoints[0].LbEndpoints) == 0 {
+			t.Fatalf("expected endpoints for %s, got %v", clusterName, cla)
+		}
+		return
+	}
+	t.Fatalf("no load assignment for cluster %s", clusterName)
+}
+
 var (
 	watchEds = []string{v3.ClusterType, v3.EndpointType}
 	watchAll = []string{v3.ClusterType, v3.EndpointType, v3.ListenerType, v3.RouteType}
@@ -23,6 +23,7 @@ import (
 
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
 
@@ -36,8 +37,10 @@ import (
 	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/locality"
 	"istio.io/istio/pkg/config/schema/kind"
 	"istio.io/istio/pkg/network"
+	"istio.io/istio/pkg/util/sets"
 )
 
 var (
@@ -65,6 +68,14 @@ type EndpointBuilder struct {
 	proxy      *model.Proxy
 
 	mtlsChecker *mtlsChecker
+
+	// staleClusters are the clusters whose endpoints are considered stale, because their
+	// API server has been unreachable for too long.
+	staleClusters sets.Set[cluster.ID]
+
+	// loadFactors are the load factors of the endpoints of the service, by endpoint address, if the proxies
+	// report the load of the service.
+	loadFactors map[string]uint32
 }
 
 func NewEndpointBuilder(clusterName string, proxy *model.Proxy, push *model.PushContext) EndpointBuilder {
@@ -109,6 +120,26 @@ func (b EndpointBuilder) DestinationRule() *networkingapi.DestinationRule {
 	return nil
 }
 
+// localityPriorityGroups returns the locality priority groups configured on the DestinationRule, if any.
+func (b EndpointBuilder) localityPriorityGroups() []locality.PriorityGroups {
+	dr := b.destinationRule.GetRule()
+	if dr == nil {
+		return nil
+	}
+	priorityGroups, err := locality.ParsePriorityGroups(dr.Annotations)
+	if err != nil {
+		// Rejected by validation, so this can only happen for configurations written without the webhook.
+		log.Debugf("ignoring locality priority groups of destination rule %s/%s: %v", dr.Namespace, dr.Name, err)
+		return nil
+	}
+	return priorityGroups
+}
+
+// localityEjectionPolicy returns the locality ejection policy configured on the DestinationRule, if any.
+func (b EndpointBuilder) localityEjectionPolicy() *locality.EjectionPolicy {
+	return b.destinationRule.GetLocalityEjectionPolicy()
+}
+
 // Key provides the eds cache key and should include any information that could change the way endpoints are generated.
 func (b EndpointBuilder) Key() string {
 	// nolint: gosec
@@ -259,6 +290,10 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 		if isClusterLocal && (shardKey.Cluster != b.clusterID) {
 			continue
 		}
+		stale := b.staleClusters.Contains(shardKey.Cluster)
+		if stale && features.DrainStaleRemoteClusterEndpoints {
+			continue
+		}
 		for _, ep := range endpoints {
 			// TODO(nmittler): Consider merging discoverability policy with cluster-local
 			if !ep.IsDiscoverableFromProxy(b.proxy) {
@@ -271,8 +306,9 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 			if !subsetLabels.SubsetOf(ep.Labels) {
 				continue
 			}
-			// Draining endpoints are only sent to 'persistent session' clusters.
-			draining := ep.HealthStatus == model.Draining ||
+			// Draining endpoints are only sent to 'persistent session' clusters, unless their pods are being deleted and
+			// the client proxies must stop sending new requests to them before their removal.
+			draining := ep.HealthStatus == model.Draining && ep.DrainDeadline.IsZero() ||
 				features.DrainingLabel != "" && ep.Labels[features.DrainingLabel] != ""
 			if draining {
 				persistentSession := b.service.Attributes.Labels[features.PersistentSessionLabel] != ""
@@ -311,7 +347,16 @@ func (b *EndpointBuilder) buildLocalityLbEndpointsFromShards(
 					}
 				}
 			}
-			locLbEps.append(ep, ep.EnvoyEndpoint)
+			lbEp := ep.EnvoyEndpoint
+			if stale {
+				// The endpoint may be out of date; prefer endpoints from other clusters but keep it as a fallback.
+				lbEp = proto.Clone(lbEp).(*endpoint.LbEndpoint)
+				lbEp.HealthStatus = core.HealthStatus_DEGRADED
+			}
+			if b.loadFactors != nil {
+				lbEp = withLoadFactor(lbEp, ep, b.loadFactors)
+			}
+			locLbEps.append(ep, lbEp)
 		}
 	}
 	shards.Unlock()
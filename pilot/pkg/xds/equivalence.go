@@ -0,0 +1,233 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+
+	xxhashv2 "github.com/cespare/xxhash/v2"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	resultTag = monitoring.MustCreateLabel("result")
+
+	equivalenceCacheReads = monitoring.NewSum(
+		"pilot_xds_equivalence_cache_reads",
+		"Total number of xDS generations served by the equivalence class cache, by result (hit or miss).",
+		monitoring.WithLabels(typeTag, resultTag),
+	)
+
+	equivalenceClassSize = monitoring.NewDistribution(
+		"pilot_xds_equivalence_class_size",
+		"Number of proxies sharing the configuration generated for an equivalence class in a push.",
+		[]float64{1, 2, 5, 10, 20, 50, 100, 500, 1000},
+		monitoring.WithLabels(typeTag),
+	)
+
+	equivalenceClasses = monitoring.NewGauge(
+		"pilot_xds_equivalence_classes",
+		"Number of equivalence classes of the previous push.",
+	)
+)
+
+func init() {
+	monitoring.MustRegister(equivalenceCacheReads, equivalenceClassSize, equivalenceClasses)
+}
+
+// generationCache shares the clusters, listeners and routes generated in a push between equivalent proxies,
+// which are the proxies of the same namespace with the same labels, metadata, service instances and Sidecar
+// scope. Deployments with many replicas then generate their configuration once per push rather than once
+// per connection. The cache only holds the generations of the current push context.
+type generationCache struct {
+	mu      sync.Mutex
+	push    *model.PushContext
+	entries map[uint64]*generationEntry
+}
+
+type generationEntry struct {
+	once    sync.Once
+	typeURL string
+	proxies int
+
+	res     model.Resources
+	logdata model.XdsLogDetails
+	err     error
+}
+
+func newGenerationCache() *generationCache {
+	return &generationCache{entries: map[uint64]*generationEntry{}}
+}
+
+// entry returns the entry of the equivalence class in the push, and whether it already existed.
+// Entries of previous pushes are dropped once a new push starts.
+func (c *generationCache) entry(push *model.PushContext, typeURL string, key uint64) (*generationEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.push != push {
+		c.recordClasses()
+		c.push = push
+		c.entries = map[uint64]*generationEntry{}
+	}
+	e, f := c.entries[key]
+	if !f {
+		e = &generationEntry{typeURL: typeURL}
+		c.entries[key] = e
+	}
+	e.proxies++
+	return e, f
+}
+
+// recordClasses records the sizes of the equivalence classes of the push being dropped.
+func (c *generationCache) recordClasses() {
+	if c.push == nil {
+		return
+	}
+	equivalenceClasses.Record(float64(len(c.entries)))
+	for _, e := range c.entries {
+		equivalenceClassSize.With(typeTag.Value(v3.GetMetricType(e.typeURL))).Record(float64(e.proxies))
+	}
+}
+
+// generate generates the resources watched by the proxy. When the equivalence cache is enabled, the
+// resources generated for an equivalent proxy in the same push are reused.
+func (s *DiscoveryServer) generate(gen model.XdsResourceGenerator, con *Connection, w *model.WatchedResource,
+	req *model.PushRequest,
+) (model.Resources, model.XdsLogDetails, error) {
+	if s.generationCache == nil || !equivalenceCacheable(con.proxy, w, req) || req.Push != s.globalPushContext() {
+		return gen.Generate(con.proxy, w, req)
+	}
+	e, hit := s.generationCache.entry(req.Push, w.TypeUrl, equivalenceKey(con.proxy, w, req))
+	e.once.Do(func() {
+		e.res, e.logdata, e.err = gen.Generate(con.proxy, w, req)
+	})
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	equivalenceCacheReads.With(typeTag.Value(v3.GetMetricType(w.TypeUrl)), resultTag.Value(result)).Increment()
+	return e.res, e.logdata, e.err
+}
+
+// equivalenceCacheable returns true if the generation can be shared between equivalent proxies. Only full
+// pushes of the default generators for Envoy are shared; incremental and delta generations depend on the
+// state of each connection, and scoped pushes must regenerate the configuration of each proxy.
+func equivalenceCacheable(proxy *model.Proxy, w *model.WatchedResource, req *model.PushRequest) bool {
+	switch w.TypeUrl {
+	case v3.ClusterType, v3.ListenerType, v3.RouteType:
+	default:
+		return false
+	}
+	if proxy.Metadata == nil || proxy.Metadata.Generator != "" || proxy.XdsResourceGenerator != nil || proxy.IsProxylessGrpc() {
+		return false
+	}
+	return req.Full && req.Delta.IsEmpty() && !req.IsScopedPush()
+}
+
+// equivalenceKey returns the key of the equivalence class of the proxy for the watched resource. It covers
+// everything the generators read from the proxy, except its ID, name and IPs. The IPs are only part of the
+// key when the generated configuration uses them.
+func equivalenceKey(proxy *model.Proxy, w *model.WatchedResource, req *model.PushRequest) uint64 {
+	h := xxhashv2.New()
+	write := func(values ...string) {
+		for _, v := range values {
+			_, _ = h.WriteString(v)
+			// Add separator to avoid collisions.
+			_, _ = h.WriteString("/")
+		}
+	}
+
+	write(w.TypeUrl, string(proxy.Type), proxy.ConfigNamespace, proxy.DNSDomain, strconv.Itoa(int(proxy.GetIPMode())))
+	write(sortedStrings(w.ResourceNames)...)
+	write(strconv.FormatBool(req.IsRequest()))
+	configs := make([]string, 0, len(req.ConfigsUpdated))
+	for k := range req.ConfigsUpdated {
+		configs = append(configs, k.String())
+	}
+	write(sortedStrings(configs)...)
+
+	if sc := proxy.SidecarScope; sc != nil {
+		write(sc.Namespace, sc.Name)
+	}
+	if l := proxy.Locality; l != nil {
+		write(l.Region, l.Zone, l.SubZone)
+	}
+	write(labelStrings(proxy.Labels)...)
+
+	// Instance fields are specific to each proxy and are not used to generate configuration.
+	meta := *proxy.Metadata
+	meta.InstanceName = ""
+	meta.InstanceIPs = nil
+	if b, err := json.Marshal(meta); err == nil {
+		_, _ = h.Write(b)
+	}
+
+	instances := make([]string, 0, len(proxy.ServiceInstances))
+	for _, si := range proxy.ServiceInstances {
+		instances = append(instances, si.Service.Attributes.Namespace+"/"+string(si.Service.Hostname)+"/"+
+			si.ServicePort.Name+"/"+strconv.Itoa(si.ServicePort.Port)+"/"+string(si.ServicePort.Protocol)+"/"+
+			strconv.Itoa(int(si.Endpoint.EndpointPort))+"/"+si.Endpoint.ServiceAccount+"/"+si.Endpoint.TLSMode)
+	}
+	write(sortedStrings(instances)...)
+
+	if usesProxyIPs(proxy, w.TypeUrl) {
+		write(proxy.IPAddresses...)
+	}
+	return h.Sum64()
+}
+
+// usesProxyIPs returns true if the configuration generated for the type depends on the IPs of the proxy:
+// inbound listeners of Sidecar ingress listeners bind to the IP of the proxy, their clusters can forward to
+// it, and the listeners of headless services skip the IP of the proxy.
+func usesProxyIPs(proxy *model.Proxy, typeURL string) bool {
+	if proxy.Type != model.SidecarProxy {
+		return false
+	}
+	switch typeURL {
+	case v3.ClusterType:
+		return proxy.SidecarScope != nil && proxy.SidecarScope.HasIngressListener()
+	case v3.ListenerType:
+		if proxy.SidecarScope != nil && proxy.SidecarScope.HasIngressListener() {
+			return true
+		}
+		for _, si := range proxy.ServiceInstances {
+			if si.Service.Resolution == model.Passthrough {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedStrings(s []string) []string {
+	out := append([]string(nil), s...)
+	sort.Strings(out)
+	return out
+}
+
+func labelStrings(labels map[string]string) []string {
+	out := make([]string, 0, len(labels))
+	for k, v := range labels {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,112 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+type countingGenerator struct {
+	calls int
+}
+
+func (g *countingGenerator) Generate(*model.Proxy, *model.WatchedResource, *model.PushRequest) (model.Resources, model.XdsLogDetails, error) {
+	g.calls++
+	return model.Resources{&discovery.Resource{Name: "resource"}}, model.DefaultXdsLogDetails, nil
+}
+
+func TestEquivalenceCache(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	s.Discovery.generationCache = newGenerationCache()
+	proxy := func(id, ip, namespace string, labels map[string]string) *Connection {
+		return &Connection{proxy: s.SetupProxy(&model.Proxy{
+			ID:              id,
+			IPAddresses:     []string{ip},
+			ConfigNamespace: namespace,
+			Labels:          labels,
+			Metadata:        &model.NodeMetadata{Labels: labels, InstanceName: id, InstanceIPs: []string{ip}},
+		})}
+	}
+	app := map[string]string{"app": "a"}
+	a1 := proxy("a-1.default", "10.0.0.1", "default", app)
+	a2 := proxy("a-2.default", "10.0.0.2", "default", app)
+	b := proxy("b-1.default", "10.0.0.3", "default", map[string]string{"app": "b"})
+	other := proxy("a-1.other", "10.0.0.4", "other", app)
+
+	gen := &countingGenerator{}
+	push := func(con *Connection, req *model.PushRequest) {
+		t.Helper()
+		res, _, err := s.Discovery.generate(gen, con, &model.WatchedResource{TypeUrl: v3.ClusterType}, req)
+		if err != nil || len(res) != 1 {
+			t.Fatalf("unexpected generation: %v %v", res, err)
+		}
+	}
+	req := &model.PushRequest{Full: true, Push: s.PushContext()}
+	for _, con := range []*Connection{a1, a2, b, other} {
+		push(con, req)
+	}
+	// The two replicas of a share their clusters.
+	if gen.calls != 3 {
+		t.Fatalf("expected 3 generations, got %d", gen.calls)
+	}
+
+	// Incremental pushes are not shared.
+	gen.calls = 0
+	push(a1, &model.PushRequest{Full: false, Push: s.PushContext()})
+	push(a2, &model.PushRequest{Full: false, Push: s.PushContext()})
+	if gen.calls != 2 {
+		t.Fatalf("expected 2 generations, got %d", gen.calls)
+	}
+
+	// A new push context drops the previous generations.
+	s.Discovery.updateMutex.Lock()
+	s.Env().PushContext = model.NewPushContext()
+	s.Discovery.updateMutex.Unlock()
+	gen.calls = 0
+	push(a1, &model.PushRequest{Full: true, Push: s.PushContext()})
+	if gen.calls != 1 {
+		t.Fatalf("expected 1 generation, got %d", gen.calls)
+	}
+}
+
+func TestEquivalenceKey(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{})
+	req := &model.PushRequest{Full: true, Push: s.PushContext()}
+	key := func(typeURL, ip string, labels map[string]string) uint64 {
+		p := s.SetupProxy(&model.Proxy{
+			IPAddresses: []string{ip},
+			Labels:      labels,
+			Metadata:    &model.NodeMetadata{Labels: labels, InstanceIPs: []string{ip}},
+		})
+		return equivalenceKey(p, &model.WatchedResource{TypeUrl: typeURL}, req)
+	}
+	app := map[string]string{"app": "a"}
+	for _, typeURL := range []string{v3.ClusterType, v3.ListenerType, v3.RouteType} {
+		if key(typeURL, "10.0.0.1", app) != key(typeURL, "10.0.0.2", app) {
+			t.Errorf("%s: expected proxies with different IPs to be equivalent", typeURL)
+		}
+		if key(typeURL, "10.0.0.1", app) == key(typeURL, "10.0.0.1", map[string]string{"app": "b"}) {
+			t.Errorf("%s: expected proxies with different labels not to be equivalent", typeURL)
+		}
+	}
+	if key(v3.ClusterType, "10.0.0.1", app) == key(v3.ListenerType, "10.0.0.1", app) {
+		t.Errorf("expected types to have different keys")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/filterchainmatch"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+// filterChainMatchQuery parses the connection of a /debug/filter_chain_match request.
+func filterChainMatchQuery(req *http.Request) (filterchainmatch.Connection, bool, error) {
+	q := req.URL.Query()
+	conn := filterchainmatch.Connection{
+		DestinationIP:     q.Get("destinationIP"),
+		SourceIP:          q.Get("sourceIP"),
+		ServerName:        q.Get("sni"),
+		TransportProtocol: q.Get("transportProtocol"),
+	}
+	if alpn := q.Get("alpn"); alpn != "" {
+		conn.ApplicationProtocols = strings.Split(alpn, ",")
+	}
+	port, err := strconv.ParseUint(q.Get("destinationPort"), 10, 16)
+	if err != nil {
+		return conn, false, fmt.Errorf("invalid destinationPort %q", q.Get("destinationPort"))
+	}
+	conn.DestinationPort = uint32(port)
+	if sp := q.Get("sourcePort"); sp != "" {
+		port, err := strconv.ParseUint(sp, 10, 16)
+		if err != nil {
+			return conn, false, fmt.Errorf("invalid sourcePort %q", sp)
+		}
+		conn.SourcePort = uint32(port)
+	}
+	return conn, q.Get("direction") == "inbound", nil
+}
+
+// filterChainMatchz reports the filter chain of the listeners generated for a proxy that a synthetic connection
+// would match, and why the other filter chains of the listener are skipped.
+//
+//	GET /debug/filter_chain_match?proxyID=pod.ns&destinationIP=10.0.0.1&destinationPort=9080
+//	&sourceIP=, &sourcePort=, &sni=, &alpn=h2,http/1.1 and &transportProtocol=tls describe the connection.
+//	&direction=inbound matches the connection on the inbound listener of the sidecar.
+func (s *DiscoveryServer) filterChainMatchz(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	conn, inbound, err := filterChainMatchQuery(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	listeners, err := s.generateListeners(con)
+	if err != nil {
+		handleHTTPError(w, err)
+		return
+	}
+	l, err := filterchainmatch.SelectListener(listeners, conn, inbound)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	res, err := filterchainmatch.Match(l, conn)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	writeJSON(w, res, req)
+}
+
+// generateListeners generates the listeners of the proxy, as in its config dump.
+func (s *DiscoveryServer) generateListeners(con *Connection) ([]*listener.Listener, error) {
+	w := con.Watched(v3.ListenerType)
+	gen := s.findGenerator(v3.ListenerType, con)
+	if w == nil || gen == nil {
+		return nil, fmt.Errorf("proxy %s does not watch listeners", con.proxy.ID)
+	}
+	req := &model.PushRequest{Push: con.proxy.LastPushContext, Start: time.Now(), Full: true}
+	resources, _, err := gen.Generate(con.proxy, w, req)
+	if err != nil {
+		return nil, err
+	}
+	listeners := make([]*listener.Listener, 0, len(resources))
+	for _, r := range resources {
+		l := &listener.Listener{}
+		if err := r.Resource.UnmarshalTo(l); err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
@@ -0,0 +1,48 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pilot/pkg/util/filterchainmatch"
+)
+
+func TestFilterChainMatchQuery(t *testing.T) {
+	req := httptest.NewRequest("GET",
+		"/debug/filter_chain_match?proxyID=a.default&destinationIP=10.0.0.1&destinationPort=9080&sourcePort=45000&sni=a.example.com&alpn=h2,http/1.1&direction=inbound", nil) // nolint: lll
+	conn, inbound, err := filterChainMatchQuery(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filterchainmatch.Connection{
+		DestinationIP:        "10.0.0.1",
+		DestinationPort:      9080,
+		SourcePort:           45000,
+		ServerName:           "a.example.com",
+		ApplicationProtocols: []string{"h2", "http/1.1"},
+	}
+	if !reflect.DeepEqual(conn, want) || !inbound {
+		t.Fatalf("got %+v (inbound %v), want %+v", conn, inbound, want)
+	}
+
+	for _, query := range []string{"destinationIP=10.0.0.1", "destinationIP=10.0.0.1&destinationPort=70000", "destinationPort=80&sourcePort=x"} {
+		if _, _, err := filterChainMatchQuery(httptest.NewRequest("GET", "/debug/filter_chain_match?"+query, nil)); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+
+	xxhashv2 "github.com/cespare/xxhash/v2"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+
+	networkingapi "istio.io/api/networking/v1alpha3"
+)
+
+const (
+	// Defaults of Envoy for the ring hash load balancer.
+	defaultMinRingSize = 1024
+	defaultMaxRingSize = 8 * 1024 * 1024
+)
+
+// HashDebug is the endpoint a hash key selects in a cluster using consistent hash load balancing.
+type HashDebug struct {
+	Cluster string `json:"cluster"`
+	// LbPolicy is RING_HASH.
+	LbPolicy string `json:"lbPolicy"`
+	// Hash is the hash of the request, combining the hashes of the keys as Envoy combines the hash policies of a route.
+	Hash uint64 `json:"hash"`
+	// Endpoint is the address of the selected endpoint, empty if the cluster has no healthy endpoint.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Endpoints is the number of endpoints the key was hashed on.
+	Endpoints int `json:"endpoints"`
+}
+
+// hashz reports which endpoint of a ring hash cluster a hash key selects on a proxy. The key query parameter holds
+// the values of the hash keys of the request, in the order of the hash policies of the route; a source IP key is the
+// IP address of the client. Only the healthy endpoints of the highest priority are considered, in the order they
+// are sent to the proxy. Maglev clusters are not supported, as their table is built with a seeded hash.
+func (s *DiscoveryServer) hashz(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	clusterName := req.URL.Query().Get("cluster")
+	keys := req.URL.Query()["key"]
+	if clusterName == "" || len(keys) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("You must provide a cluster and at least one key in the query string\n"))
+		return
+	}
+	b := NewEndpointBuilder(clusterName, con.proxy, s.globalPushContext())
+	_, lb := getOutlierDetectionAndLoadBalancerSettings(b.DestinationRule(), b.port, b.subsetName)
+	out, err := hashEndpoint(clusterName, lb.GetConsistentHash(), s.generateEndpoints(b), keys)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	writeJSON(w, out, req)
+}
+
+func hashEndpoint(clusterName string, hash *networkingapi.LoadBalancerSettings_ConsistentHashLB,
+	cla *endpoint.ClusterLoadAssignment, keys []string,
+) (HashDebug, error) {
+	if hash == nil {
+		return HashDebug{}, fmt.Errorf("cluster %s does not use consistent hash load balancing", clusterName)
+	}
+	if hash.GetMaglev() != nil {
+		return HashDebug{}, fmt.Errorf("cluster %s uses Maglev load balancing, only ring hash is supported", clusterName)
+	}
+	out := HashDebug{Cluster: clusterName, LbPolicy: "RING_HASH", Hash: combineHashes(keys)}
+	hosts := hashHosts(cla)
+	out.Endpoints = len(hosts)
+	if len(hosts) == 0 {
+		return out, nil
+	}
+	minRingSize := hash.GetRingHash().GetMinimumRingSize()
+	if minRingSize == 0 {
+		minRingSize = hash.GetMinimumRingSize() // nolint: staticcheck
+	}
+	if minRingSize == 0 {
+		minRingSize = defaultMinRingSize
+	}
+	out.Endpoint = hosts[chooseRingHost(ringHash(hosts, minRingSize, defaultMaxRingSize), out.Hash)].address
+	return out, nil
+}
+
+// combineHashes combines the hashes of the keys, as Envoy does for the hash policies of a route.
+func combineHashes(keys []string) uint64 {
+	var hash uint64
+	for i, key := range keys {
+		h := xxhashv2.Sum64String(key)
+		if i > 0 {
+			// Envoy rotates the previous hash, so that identical keys do not cancel each other out.
+			hash = bits.RotateLeft64(hash, 1)
+		}
+		hash ^= h
+	}
+	return hash
+}
+
+type hashHost struct {
+	address string
+	weight  float64
+}
+
+// hashHosts returns the healthy endpoints of the highest priority with their normalized weights.
+func hashHosts(cla *endpoint.ClusterLoadAssignment) []hashHost {
+	priority := uint32(math.MaxUint32)
+	for _, llb := range cla.GetEndpoints() {
+		if llb.GetPriority() < priority {
+			priority = llb.GetPriority()
+		}
+	}
+	var hosts []hashHost
+	var total float64
+	for _, llb := range cla.GetEndpoints() {
+		if llb.GetPriority() != priority {
+			continue
+		}
+		for _, lbe := range llb.GetLbEndpoints() {
+			switch lbe.GetHealthStatus() {
+			case core.HealthStatus_UNHEALTHY, core.HealthStatus_DRAINING, core.HealthStatus_TIMEOUT:
+				continue
+			}
+			sa := lbe.GetEndpoint().GetAddress().GetSocketAddress()
+			if sa == nil {
+				continue
+			}
+			weight := float64(1)
+			if w := lbe.GetLoadBalancingWeight(); w != nil && w.GetValue() > 0 {
+				weight = float64(w.GetValue())
+			}
+			total += weight
+			hosts = append(hosts, hashHost{
+				address: net.JoinHostPort(sa.GetAddress(), strconv.Itoa(int(sa.GetPortValue()))),
+				weight:  weight,
+			})
+		}
+	}
+	for i := range hosts {
+		hosts[i].weight /= total
+	}
+	return hosts
+}
+
+type ringEntry struct {
+	hash uint64
+	host int
+}
+
+// ringHash builds the ring of the ring hash load balancer of Envoy.
+func ringHash(hosts []hashHost, minRingSize, maxRingSize uint64) []ringEntry {
+	minWeight := 1.0
+	for _, h := range hosts {
+		minWeight = math.Min(minWeight, h.weight)
+	}
+	scale := math.Min(math.Ceil(minWeight*float64(minRingSize))/minWeight, float64(maxRingSize))
+	ring := make([]ringEntry, 0, int(math.Ceil(scale)))
+	currentHashes, targetHashes := 0.0, 0.0
+	for i, h := range hosts {
+		targetHashes += scale * h.weight
+		for n := 0; currentHashes < targetHashes; n++ {
+			ring = append(ring, ringEntry{hash: xxhashv2.Sum64String(h.address + "_" + strconv.Itoa(n)), host: i})
+			currentHashes++
+		}
+	}
+	sort.SliceStable(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+	return ring
+}
+
+// chooseRingHost returns the host of the first entry of the ring with a hash not lower than the hash of the request.
+func chooseRingHost(ring []ringEntry, hash uint64) int {
+	i := sort.Search(len(ring), func(i int) bool {
+		return ring[i].hash >= hash
+	})
+	if i == len(ring) {
+		i = 0
+	}
+	return ring[i].host
+}
@@ -0,0 +1,92 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	networkingapi "istio.io/api/networking/v1alpha3"
+)
+
+// The expected values are those of the hash and ring hash load balancer tests of Envoy.
+
+func TestCombineHashes(t *testing.T) {
+	for key, want := range map[string]uint64{
+		"foo":  3728699739546630719,
+		"bar":  5234164152756840025,
+		"lyft": 4400747396090729504,
+		"":     17241709254077376921,
+	} {
+		if got := combineHashes([]string{key}); got != want {
+			t.Fatalf("%q: got %d, want %d", key, got, want)
+		}
+	}
+	if combineHashes([]string{"a", "a"}) == 0 {
+		t.Fatalf("expected identical keys not to cancel each other out")
+	}
+}
+
+func TestRingHash(t *testing.T) {
+	var hosts []hashHost
+	for port := 90; port <= 95; port++ {
+		hosts = append(hosts, hashHost{address: fmt.Sprintf("127.0.0.1:%d", port), weight: 1.0 / 6})
+	}
+	ring := ringHash(hosts, 12, 12)
+	want := []ringEntry{
+		{833437586790550860, 4},
+		{928266305478181108, 2},
+		{1033482794131418490, 0},
+		{3551244743356806947, 5},
+		{3851675632748031481, 3},
+		{5583722120771150861, 1},
+		{6311230543546372928, 1},
+		{7700377290971790572, 3},
+		{13144177310400110813, 5},
+		{13444792449719432967, 2},
+		{15516499411664133160, 4},
+		{16117243373044804889, 0},
+	}
+	if len(ring) != len(want) {
+		t.Fatalf("got a ring of %d entries, want %d", len(ring), len(want))
+	}
+	for i := range want {
+		if ring[i] != want[i] {
+			t.Fatalf("entry %d: got %v, want %v", i, ring[i], want[i])
+		}
+	}
+	for hash, host := range map[uint64]int{
+		0:                   4,
+		math.MaxUint64:      4,
+		3551244743356806947: 5,
+		3551244743356806948: 3,
+	} {
+		if got := chooseRingHost(ring, hash); got != host {
+			t.Fatalf("hash %d: got host %d, want %d", hash, got, host)
+		}
+	}
+}
+
+func TestHashEndpointMaglev(t *testing.T) {
+	maglev := &networkingapi.LoadBalancerSettings_ConsistentHashLB{
+		HashAlgorithm: &networkingapi.LoadBalancerSettings_ConsistentHashLB_Maglev{
+			Maglev: &networkingapi.LoadBalancerSettings_ConsistentHashLB_MagLev{},
+		},
+	}
+	if _, err := hashEndpoint("outbound|80||foo", maglev, nil, []string{"user"}); err == nil {
+		t.Fatalf("expected Maglev clusters to be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// IndexSnapshot summarizes the cardinality of the internal indexes of istiod, as the number of entries of each
+// index. Index names are hierarchical: "services/<namespace>" counts the services of a namespace, and
+// "endpoints/<provider>/<cluster>" the endpoints of a shard. Snapshots taken over time are compared with
+// DiffIndexSnapshots to find the indexes growing unboundedly.
+type IndexSnapshot struct {
+	Time    time.Time      `json:"time"`
+	Indexes map[string]int `json:"indexes"`
+}
+
+// IndexDiff is the change of the number of entries of an index between two snapshots.
+type IndexDiff struct {
+	Index  string `json:"index"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+}
+
+// Delta returns the growth of the index, negative if it shrank.
+func (d IndexDiff) Delta() int {
+	return d.After - d.Before
+}
+
+// DiffIndexSnapshots returns the indexes whose number of entries changed between the snapshots, the largest
+// growths first. Indexes missing from a snapshot are considered empty.
+func DiffIndexSnapshots(before, after IndexSnapshot) []IndexDiff {
+	var diffs []IndexDiff
+	for index, n := range after.Indexes {
+		if n != before.Indexes[index] {
+			diffs = append(diffs, IndexDiff{Index: index, Before: before.Indexes[index], After: n})
+		}
+	}
+	for index, n := range before.Indexes {
+		if _, f := after.Indexes[index]; !f && n != 0 {
+			diffs = append(diffs, IndexDiff{Index: index, Before: n})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Delta() != diffs[j].Delta() {
+			return diffs[i].Delta() > diffs[j].Delta()
+		}
+		return diffs[i].Index < diffs[j].Index
+	})
+	return diffs
+}
+
+// indexSnapshot counts the entries of the indexes of the current push context, the endpoint index, the config
+// store and the XDS cache.
+func (s *DiscoveryServer) indexSnapshot() IndexSnapshot {
+	indexes := map[string]int{}
+	push := s.globalPushContext()
+
+	services := push.GetAllServices()
+	indexes["services"] = len(services)
+	for _, svc := range services {
+		indexes["services/"+svc.Attributes.Namespace]++
+	}
+
+	for _, byNamespace := range s.Env.EndpointIndex.Shardz() {
+		for _, shards := range byNamespace {
+			indexes["endpointShards"]++
+			shards.RLock()
+			for key, endpoints := range shards.Shards {
+				indexes["endpoints"] += len(endpoints)
+				indexes["endpoints/"+key.String()] += len(endpoints)
+			}
+			shards.RUnlock()
+		}
+	}
+
+	if s.Env.ConfigStore != nil {
+		for _, schema := range s.Env.ConfigStore.Schemas().All() {
+			configs, err := s.Env.ConfigStore.List(schema.Resource().GroupVersionKind(), "")
+			if err != nil {
+				continue
+			}
+			indexes["configs/"+schema.Resource().Kind()] = len(configs)
+		}
+	}
+
+	configured, derived := push.SidecarScopeCounts()
+	indexes["sidecarScopes"] = configured + derived
+	indexes["sidecarScopes/configured"] = configured
+	indexes["sidecarScopes/derived"] = derived
+
+	indexes["xdsCache"] = len(s.Cache.Keys())
+	indexes["connections"] = len(s.AllClients())
+
+	return IndexSnapshot{Time: time.Now(), Indexes: indexes}
+}
+
+// indexz reports the number of entries of the internal indexes of istiod.
+func (s *DiscoveryServer) indexz(w http.ResponseWriter, req *http.Request) {
+	writeJSON(w, s.indexSnapshot(), req)
+}
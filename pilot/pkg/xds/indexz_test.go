@@ -0,0 +1,57 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestDiffIndexSnapshots(t *testing.T) {
+	before := IndexSnapshot{Indexes: map[string]int{"services": 10, "endpoints": 100, "configs/Sidecar": 2, "xdsCache": 5}}
+	after := IndexSnapshot{Indexes: map[string]int{"services": 10, "endpoints": 400, "sidecarScopes": 3, "xdsCache": 4}}
+	assert.Equal(t, DiffIndexSnapshots(before, after), []IndexDiff{
+		{Index: "endpoints", Before: 100, After: 400},
+		{Index: "sidecarScopes", After: 3},
+		{Index: "xdsCache", Before: 5, After: 4},
+		{Index: "configs/Sidecar", Before: 2},
+	})
+}
+
+func TestIndexSnapshot(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: se
+  namespace: default
+spec:
+  hosts:
+  - example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: STATIC
+  endpoints:
+  - address: 1.1.1.1
+  - address: 2.2.2.2
+`})
+	snap := s.Discovery.indexSnapshot()
+	assert.Equal(t, snap.Indexes["services/default"], 1)
+	assert.Equal(t, snap.Indexes["configs/ServiceEntry"], 1)
+	assert.Equal(t, snap.Indexes["endpoints"], 2)
+}
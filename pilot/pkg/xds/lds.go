@@ -35,14 +35,12 @@ var skippedLdsConfigs = map[model.NodeType]map[kind.Kind]struct{}{
 		kind.WorkloadGroup: {},
 		kind.WorkloadEntry: {},
 		kind.Secret:        {},
-		kind.ProxyConfig:   {},
 	},
 	model.SidecarProxy: {
 		kind.Gateway:       {},
 		kind.WorkloadGroup: {},
 		kind.WorkloadEntry: {},
 		kind.Secret:        {},
-		kind.ProxyConfig:   {},
 	},
 }
 
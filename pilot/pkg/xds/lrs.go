@@ -0,0 +1,359 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	wrappers "google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pilot/pkg/features"
+	istiogrpc "istio.io/istio/pilot/pkg/grpc"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/schema/kind"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// loadWeightScale is the load factor of an endpoint under the average load of its service. The weights of the
+	// endpoints of a service with reported load are multiplied by their load factor.
+	loadWeightScale = 100
+	// maxLoadFactor bounds the ratio between the weight of an endpoint and its weight under the average load, so
+	// that an endpoint under load keeps receiving some traffic, and an idle endpoint is not flooded.
+	maxLoadFactor = 10
+	// loadWeightChangeThreshold is the relative change of the load factor of an endpoint that triggers a push of
+	// the endpoints of its service. Smaller changes are ignored, to avoid pushing endpoints at every report.
+	loadWeightChangeThreshold = 0.2
+	// loadReportStaleIntervals is the number of reporting intervals after which the last report of a proxy is
+	// ignored, if the proxy stopped reporting without closing its stream.
+	loadReportStaleIntervals = 3
+	// maxProxyRequestsInProgress caps the requests in progress reported by a proxy for a service port, which is the
+	// default circuit breaker limit of the requests of a cluster. Larger reports are scaled down, so that a single
+	// proxy cannot skew the weights of the endpoints beyond the load a proxy normally sends.
+	maxProxyRequestsInProgress = 1024
+)
+
+// loadKey identifies the endpoints of a service port.
+type loadKey struct {
+	hostname host.Name
+	port     int
+}
+
+// proxyLoadReport is the last load report of a proxy.
+type proxyLoadReport struct {
+	received time.Time
+	// inProgress holds the requests in progress to each endpoint, by service port then endpoint address.
+	inProgress map[loadKey]map[string]uint64
+}
+
+// loadReports aggregates the load of the upstream endpoints reported by the proxies through the load reporting
+// service (LRS) of Envoy, and derives load aware weights for the endpoints sent in EDS.
+// The load of an endpoint is the number of requests in progress to it, summed over all the proxies. Weighting the
+// endpoints inversely to their load balances the requests in progress between them, which accounts both for the
+// rate of requests and for their latency: a slow endpoint, or a locality whose endpoints are overloaded, gets
+// fewer requests. As the weights of a locality are the sum of the weights of its endpoints, this also applies to
+// the locality weighted load balancing.
+// The reports are not shared between the istiod replicas: each replica weights the endpoints from the load
+// reported by the proxies connected to it, which is a sample of the load of the whole mesh. The proxies connected
+// to a replica get the same weights, but the weights of different replicas may differ.
+type loadReports struct {
+	mu sync.RWMutex
+	// reports holds the last load report of each LRS stream.
+	reports map[string]*proxyLoadReport
+	// factors holds the published load factors of the endpoints, by service port then endpoint address.
+	// The maps are replaced rather than updated, so the callers can keep them.
+	factors map[loadKey]map[string]uint32
+}
+
+func newLoadReports() *loadReports {
+	return &loadReports{
+		reports: map[string]*proxyLoadReport{},
+		factors: map[loadKey]map[string]uint32{},
+	}
+}
+
+// record stores the load report of a stream, replacing its previous report. Only the load of the services visible
+// to the proxy is recorded, and the load of each service port is capped to maxProxyRequestsInProgress.
+func (r *loadReports) record(id string, req *lrs.LoadStatsRequest, now time.Time, visible func(host.Name) bool) {
+	report := &proxyLoadReport{received: now, inProgress: map[loadKey]map[string]uint64{}}
+	for _, cs := range req.GetClusterStats() {
+		// The stats of the subset clusters of a service are merged, as they share the endpoints of the service.
+		dir, _, hostname, port := model.ParseSubsetKey(cs.GetClusterName())
+		if dir != model.TrafficDirectionOutbound || !visible(hostname) {
+			continue
+		}
+		key := loadKey{hostname: hostname, port: port}
+		for _, ls := range cs.GetUpstreamLocalityStats() {
+			for _, es := range ls.GetUpstreamEndpointStats() {
+				sa := es.GetAddress().GetSocketAddress()
+				if sa == nil {
+					continue
+				}
+				eps := report.inProgress[key]
+				if eps == nil {
+					eps = map[string]uint64{}
+					report.inProgress[key] = eps
+				}
+				eps[endpointLoadAddress(sa.GetAddress(), sa.GetPortValue())] += es.GetTotalRequestsInProgress()
+			}
+		}
+	}
+	for _, eps := range report.inProgress {
+		capRequestsInProgress(eps)
+	}
+	r.mu.Lock()
+	r.reports[id] = report
+	r.mu.Unlock()
+}
+
+// capRequestsInProgress scales down the requests in progress to the endpoints of a service port reported by a proxy,
+// if they exceed maxProxyRequestsInProgress.
+func capRequestsInProgress(eps map[string]uint64) {
+	var sum uint64
+	for _, l := range eps {
+		sum += l
+	}
+	if sum <= maxProxyRequestsInProgress {
+		return
+	}
+	for addr, l := range eps {
+		eps[addr] = uint64(float64(l) * maxProxyRequestsInProgress / float64(sum))
+	}
+}
+
+// remove drops the report of a closed stream.
+func (r *loadReports) remove(id string) {
+	r.mu.Lock()
+	delete(r.reports, id)
+	r.mu.Unlock()
+}
+
+// endpointFactors returns the load factors of the endpoints of a service port, by endpoint address, or nil if no
+// load is reported for the service port.
+func (r *loadReports) endpointFactors(hostname host.Name, port int) map[string]uint32 {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.factors[loadKey{hostname: hostname, port: port}]
+}
+
+// update computes the load factors of the endpoints from the reports received since staleAfter, and publishes
+// the ones that changed significantly. It returns the service ports whose load factors were published.
+func (r *loadReports) update(now time.Time, staleAfter time.Duration) []loadKey {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := map[loadKey]map[string]uint64{}
+	for _, report := range r.reports {
+		if now.Sub(report.received) > staleAfter {
+			continue
+		}
+		for key, eps := range report.inProgress {
+			t := total[key]
+			if t == nil {
+				t = map[string]uint64{}
+				total[key] = t
+			}
+			for addr, l := range eps {
+				t[addr] += l
+			}
+		}
+	}
+
+	var changed []loadKey
+	for key, eps := range total {
+		computed := loadFactors(eps)
+		published := r.factors[key]
+		if !loadFactorsChanged(published, computed) {
+			continue
+		}
+		// Move halfway to the new factors, to dampen the oscillations caused by the weights changing the load.
+		for addr, f := range computed {
+			if p, ok := published[addr]; ok {
+				computed[addr] = (p + f) / 2
+			}
+		}
+		r.factors[key] = computed
+		changed = append(changed, key)
+	}
+	for key := range r.factors {
+		if _, f := total[key]; !f {
+			delete(r.factors, key)
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// loadFactors computes the load factors of endpoints from their requests in progress.
+func loadFactors(inProgress map[string]uint64) map[string]uint32 {
+	var sum uint64
+	for _, l := range inProgress {
+		sum += l
+	}
+	mean := float64(sum) / float64(len(inProgress))
+	factors := make(map[string]uint32, len(inProgress))
+	for addr, l := range inProgress {
+		// Add one request to the loads, so that idle endpoints and services get finite and equal factors.
+		f := (mean + 1) / (float64(l) + 1)
+		f = math.Max(1.0/maxLoadFactor, math.Min(maxLoadFactor, f))
+		factors[addr] = uint32(math.Round(f * loadWeightScale))
+	}
+	return factors
+}
+
+func loadFactorsChanged(published, computed map[string]uint32) bool {
+	if len(published) != len(computed) {
+		return true
+	}
+	for addr, c := range computed {
+		p, f := published[addr]
+		if !f {
+			return true
+		}
+		if math.Abs(float64(c)-float64(p)) > loadWeightChangeThreshold*float64(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func endpointLoadAddress(address string, port uint32) string {
+	return net.JoinHostPort(address, strconv.Itoa(int(port)))
+}
+
+// withLoadFactor returns the endpoint with its weight multiplied by its load factor. The endpoints without
+// reported load are weighted as under the average load.
+func withLoadFactor(lbEp *endpoint.LbEndpoint, ep *model.IstioEndpoint, factors map[string]uint32) *endpoint.LbEndpoint {
+	f, ok := factors[endpointLoadAddress(ep.Address, ep.EndpointPort)]
+	if !ok {
+		f = loadWeightScale
+	}
+	lbEp = proto.Clone(lbEp).(*endpoint.LbEndpoint)
+	lbEp.LoadBalancingWeight = &wrappers.UInt32Value{Value: ep.GetLoadBalancingWeight() * f}
+	return lbEp
+}
+
+// StreamLoadStats implements the load reporting service of Envoy. The proxies with the LOAD_REPORTING metadata
+// report the load of the endpoints of their outbound EDS clusters, at the interval set by istiod. A proxy must
+// be connected to the XDS server of the same istiod with the same identity, and only reports the load of the
+// services visible to it.
+func (s *DiscoveryServer) StreamLoadStats(stream lrs.LoadReportingService_StreamLoadStatsServer) error {
+	ids, err := s.authenticate(stream.Context())
+	if err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	req, err := stream.Recv()
+	if err != nil {
+		if istiogrpc.IsExpectedGRPCError(err) {
+			return nil
+		}
+		return err
+	}
+	con := s.loadReportingConnection(req.GetNode().GetId())
+	if con == nil {
+		return status.Errorf(codes.FailedPrecondition, "no XDS connection of %s", req.GetNode().GetId())
+	}
+	if features.EnableXDSIdentityCheck && ids != nil {
+		if _, err := checkConnectionIdentity(con.proxy, ids); err != nil {
+			log.Warnf("Unauthorized load reports of %s with identity %v: %v", req.GetNode().GetId(), ids, err)
+			return status.Errorf(codes.PermissionDenied, "authorization failed: %v", err)
+		}
+	}
+	visible := func(hostname host.Name) bool {
+		con.proxy.RLock()
+		defer con.proxy.RUnlock()
+		return con.proxy.SidecarScope.GetService(hostname) != nil
+	}
+	id := connectionID(req.GetNode().GetId())
+	defer s.loadReports.remove(id)
+	if err := stream.Send(&lrs.LoadStatsResponse{
+		SendAllClusters:           true,
+		LoadReportingInterval:     durationpb.New(features.LoadReportingInterval),
+		ReportEndpointGranularity: true,
+	}); err != nil {
+		return err
+	}
+	for {
+		s.loadReports.record(id, req, time.Now(), visible)
+		req, err = stream.Recv()
+		if err != nil {
+			if istiogrpc.IsExpectedGRPCError(err) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// loadReportingConnection returns the XDS connection of the proxy of a load reporting stream, whose services are
+// the ones it can report the load of.
+func (s *DiscoveryServer) loadReportingConnection(nodeID string) *Connection {
+	for _, con := range s.Clients() {
+		if con.node.GetId() == nodeID && con.proxy != nil {
+			return con
+		}
+	}
+	return nil
+}
+
+// updateLoadWeights periodically updates the load factors of the endpoints, and pushes the endpoints of the
+// services whose load factors changed.
+func (s *DiscoveryServer) updateLoadWeights(stopCh <-chan struct{}) {
+	ticker := time.NewTicker(features.LoadReportingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			changed := s.loadReports.update(time.Now(), loadReportStaleIntervals*features.LoadReportingInterval)
+			s.pushLoadWeights(changed)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (s *DiscoveryServer) pushLoadWeights(changed []loadKey) {
+	if len(changed) == 0 {
+		return
+	}
+	push := s.globalPushContext()
+	updates := sets.New[model.ConfigKey]()
+	for _, key := range changed {
+		for ns := range push.ServiceIndex.HostnameAndNamespace[key.hostname] {
+			updates.Insert(model.ConfigKey{Kind: kind.ServiceEntry, Name: string(key.hostname), Namespace: ns})
+		}
+	}
+	if len(updates) == 0 {
+		return
+	}
+	s.ConfigUpdate(&model.PushRequest{
+		Full:           false,
+		ConfigsUpdated: updates,
+		Reason:         []model.TriggerReason{model.LoadReportUpdate},
+	})
+}
@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func loadStatsRequest(cluster string, inProgress map[string]uint64) *lrs.LoadStatsRequest {
+	ls := &endpoint.UpstreamLocalityStats{}
+	for addr, l := range inProgress {
+		ls.UpstreamEndpointStats = append(ls.UpstreamEndpointStats, &endpoint.UpstreamEndpointStats{
+			Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+				Address:       addr,
+				PortSpecifier: &core.SocketAddress_PortValue{PortValue: 8080},
+			}}},
+			TotalRequestsInProgress: l,
+		})
+	}
+	return &lrs.LoadStatsRequest{ClusterStats: []*endpoint.ClusterStats{{
+		ClusterName:           cluster,
+		UpstreamLocalityStats: []*endpoint.UpstreamLocalityStats{ls},
+	}}}
+}
+
+func allVisible(host.Name) bool {
+	return true
+}
+
+func TestLoadReports(t *testing.T) {
+	now := time.Now()
+	key := loadKey{hostname: "reviews.default.svc.cluster.local", port: 9080}
+	r := newLoadReports()
+
+	// The load reported by different proxies, and for different subsets, is summed.
+	r.record("a-1", loadStatsRequest("outbound|9080||reviews.default.svc.cluster.local",
+		map[string]uint64{"10.0.0.1": 6, "10.0.0.2": 1}), now, allVisible)
+	r.record("b-2", loadStatsRequest("outbound|9080|v1|reviews.default.svc.cluster.local",
+		map[string]uint64{"10.0.0.1": 2, "10.0.0.2": 0}), now, allVisible)
+	r.record("c-3", loadStatsRequest("inbound|9080||", map[string]uint64{"10.0.0.3": 10}), now, allVisible)
+	assert.Equal(t, r.update(now, time.Minute), []loadKey{key})
+	// Mean load 4.5: factor (4.5+1)/(8+1) and (4.5+1)/(1+1).
+	assert.Equal(t, r.endpointFactors(key.hostname, key.port), map[string]uint32{"10.0.0.1:8080": 61, "10.0.0.2:8080": 275})
+
+	// Small changes are not published.
+	r.record("b-2", loadStatsRequest("outbound|9080|v1|reviews.default.svc.cluster.local",
+		map[string]uint64{"10.0.0.1": 3, "10.0.0.2": 0}), now, allVisible)
+	assert.Equal(t, len(r.update(now, time.Minute)), 0)
+
+	// Larger changes move the factors halfway.
+	r.record("a-1", loadStatsRequest("outbound|9080||reviews.default.svc.cluster.local",
+		map[string]uint64{"10.0.0.1": 0, "10.0.0.2": 1}), now, allVisible)
+	r.remove("b-2")
+	assert.Equal(t, r.update(now, time.Minute), []loadKey{key})
+	assert.Equal(t, r.endpointFactors(key.hostname, key.port), map[string]uint32{"10.0.0.1:8080": 105, "10.0.0.2:8080": 175})
+
+	// Stale reports are ignored, and the factors of services without reports are dropped.
+	assert.Equal(t, r.update(now.Add(2*time.Minute), time.Minute), []loadKey{key})
+	assert.Equal(t, r.endpointFactors(key.hostname, key.port), nil)
+}
+
+func TestLoadReportsRestricted(t *testing.T) {
+	now := time.Now()
+	key := loadKey{hostname: "reviews.default.svc.cluster.local", port: 9080}
+	r := newLoadReports()
+
+	// The load of the services not visible to the proxy is ignored.
+	r.record("a-1", loadStatsRequest("outbound|9080||reviews.default.svc.cluster.local",
+		map[string]uint64{"10.0.0.1": 6, "10.0.0.2": 1}), now, func(host.Name) bool { return false })
+	assert.Equal(t, len(r.update(now, time.Minute)), 0)
+
+	// The load reported by a proxy is scaled down to maxProxyRequestsInProgress.
+	r.record("a-1", loadStatsRequest("outbound|9080||reviews.default.svc.cluster.local",
+		map[string]uint64{"10.0.0.1": 3 * maxProxyRequestsInProgress, "10.0.0.2": maxProxyRequestsInProgress}), now, allVisible)
+	r.mu.RLock()
+	assert.Equal(t, r.reports["a-1"].inProgress[key], map[string]uint64{"10.0.0.1:8080": 768, "10.0.0.2:8080": 256})
+	r.mu.RUnlock()
+}
+
+func TestLoadFactorsBounds(t *testing.T) {
+	loads := map[string]uint64{"hot": 10000}
+	for i := 0; i < 19; i++ {
+		loads[fmt.Sprint(i)] = 0
+	}
+	factors := loadFactors(loads)
+	assert.Equal(t, factors["hot"], uint32(10))
+	assert.Equal(t, factors["0"], uint32(1000))
+	assert.Equal(t, loadFactors(map[string]uint64{"a": 0, "b": 0}), map[string]uint32{"a": 100, "b": 100})
+}
+
+func TestWithLoadFactor(t *testing.T) {
+	ep := &model.IstioEndpoint{Address: "10.0.0.1", EndpointPort: 8080, LbWeight: 2}
+	lbEp := buildEnvoyLbEndpoint(false, ep)
+	factors := map[string]uint32{"10.0.0.1:8080": 50}
+
+	got := withLoadFactor(lbEp, ep, factors)
+	assert.Equal(t, got.GetLoadBalancingWeight().GetValue(), uint32(100))
+	// The shared endpoint is not modified.
+	assert.Equal(t, lbEp.GetLoadBalancingWeight().GetValue(), uint32(2))
+
+	other := &model.IstioEndpoint{Address: "10.0.0.2", EndpointPort: 8080}
+	got = withLoadFactor(buildEnvoyLbEndpoint(false, other), other, factors)
+	assert.Equal(t, got.GetLoadBalancingWeight().GetValue(), uint32(loadWeightScale))
+}
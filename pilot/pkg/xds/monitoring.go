@@ -193,18 +193,21 @@ func recordXDSClients(version string, delta float64) {
 
 // triggerMetric is a precomputed monitoring.Metric for each trigger type. This saves on a lot of allocations
 var triggerMetric = map[model.TriggerReason]monitoring.Metric{
-	model.EndpointUpdate:  pushTriggers.With(typeTag.Value(string(model.EndpointUpdate))),
-	model.ConfigUpdate:    pushTriggers.With(typeTag.Value(string(model.ConfigUpdate))),
-	model.ServiceUpdate:   pushTriggers.With(typeTag.Value(string(model.ServiceUpdate))),
-	model.ProxyUpdate:     pushTriggers.With(typeTag.Value(string(model.ProxyUpdate))),
-	model.GlobalUpdate:    pushTriggers.With(typeTag.Value(string(model.GlobalUpdate))),
-	model.UnknownTrigger:  pushTriggers.With(typeTag.Value(string(model.UnknownTrigger))),
-	model.DebugTrigger:    pushTriggers.With(typeTag.Value(string(model.DebugTrigger))),
-	model.SecretTrigger:   pushTriggers.With(typeTag.Value(string(model.SecretTrigger))),
-	model.NetworksTrigger: pushTriggers.With(typeTag.Value(string(model.NetworksTrigger))),
-	model.ProxyRequest:    pushTriggers.With(typeTag.Value(string(model.ProxyRequest))),
-	model.NamespaceUpdate: pushTriggers.With(typeTag.Value(string(model.NamespaceUpdate))),
-	model.ClusterUpdate:   pushTriggers.With(typeTag.Value(string(model.ClusterUpdate))),
+	model.EndpointUpdate:      pushTriggers.With(typeTag.Value(string(model.EndpointUpdate))),
+	model.ConfigUpdate:        pushTriggers.With(typeTag.Value(string(model.ConfigUpdate))),
+	model.ServiceUpdate:       pushTriggers.With(typeTag.Value(string(model.ServiceUpdate))),
+	model.ProxyUpdate:         pushTriggers.With(typeTag.Value(string(model.ProxyUpdate))),
+	model.GlobalUpdate:        pushTriggers.With(typeTag.Value(string(model.GlobalUpdate))),
+	model.UnknownTrigger:      pushTriggers.With(typeTag.Value(string(model.UnknownTrigger))),
+	model.DebugTrigger:        pushTriggers.With(typeTag.Value(string(model.DebugTrigger))),
+	model.SecretTrigger:       pushTriggers.With(typeTag.Value(string(model.SecretTrigger))),
+	model.NetworksTrigger:     pushTriggers.With(typeTag.Value(string(model.NetworksTrigger))),
+	model.ProxyRequest:        pushTriggers.With(typeTag.Value(string(model.ProxyRequest))),
+	model.NamespaceUpdate:     pushTriggers.With(typeTag.Value(string(model.NamespaceUpdate))),
+	model.ClusterUpdate:       pushTriggers.With(typeTag.Value(string(model.ClusterUpdate))),
+	model.ProxyLogLevelUpdate: pushTriggers.With(typeTag.Value(string(model.ProxyLogLevelUpdate))),
+	model.ScopedPush:          pushTriggers.With(typeTag.Value(string(model.ScopedPush))),
+	model.LoadReportUpdate:    pushTriggers.With(typeTag.Value(string(model.LoadReportUpdate))),
 }
 
 func recordPushTriggers(reasons ...model.TriggerReason) {
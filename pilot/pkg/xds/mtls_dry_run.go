@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+	"sort"
+
+	"istio.io/api/security/v1beta1"
+	"istio.io/istio/pilot/pkg/model"
+	authnplugin "istio.io/istio/pilot/pkg/networking/plugin/authn"
+	"istio.io/istio/pilot/pkg/security/authn"
+	"istio.io/istio/pilot/pkg/security/authn/factory"
+	authzmodel "istio.io/istio/pilot/pkg/security/authz/model"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// MTLSDryRunDebug describes the dry-run PeerAuthentication policies applying to a proxy, and the inbound ports
+// on which they would reject the plaintext connections that are currently accepted.
+type MTLSDryRunDebug struct {
+	// Policies are the dry-run policies applying to the proxy, as namespace/name.
+	Policies []string `json:"policies"`
+	// Ports are the mTLS modes of the inbound ports of the proxy. Port 0 is the mode of the ports without
+	// their own setting.
+	Ports []MTLSDryRunPort `json:"ports"`
+	// Stat is the Envoy stat counting the plaintext connections that the dry-run policies would reject.
+	Stat string `json:"stat"`
+}
+
+// MTLSDryRunPort is the mTLS mode of an inbound port, with and without the dry-run policies.
+type MTLSDryRunPort struct {
+	Port       uint32 `json:"port"`
+	Mode       string `json:"mode"`
+	DryRunMode string `json:"dryRunMode"`
+	// WouldRejectPlaintext is true if plaintext connections are accepted on the port, but would be rejected if
+	// the dry-run policies were enforced.
+	WouldRejectPlaintext bool `json:"wouldRejectPlaintext"`
+}
+
+// mtlsDryRunz reports the ports of a proxy on which the dry-run PeerAuthentication policies would reject connections.
+func (s *DiscoveryServer) mtlsDryRunz(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	writeJSON(w, mtlsDryRun(s.globalPushContext(), con.proxy), req)
+}
+
+func mtlsDryRun(push *model.PushContext, proxy *model.Proxy) MTLSDryRunDebug {
+	namespace := proxy.Metadata.Namespace
+	policies := push.AuthnPolicies.GetDryRunPeerAuthenticationsForWorkload(namespace, proxy.Labels)
+	applier := factory.NewPolicyApplier(push, namespace, proxy.Labels)
+
+	out := MTLSDryRunDebug{
+		Policies: make([]string, 0, len(policies)),
+		Ports:    []MTLSDryRunPort{},
+		Stat:     authzmodel.RBACTCPFilterStatPrefix + "rbac." + authnplugin.DryRunStrictStatPrefix + "shadow_denied",
+	}
+	ports := sets.New[uint32](0)
+	for _, si := range proxy.ServiceInstances {
+		ports.Insert(si.Endpoint.EndpointPort)
+	}
+	for port := range applier.PortLevelSetting() {
+		ports.Insert(port)
+	}
+	for _, cfg := range policies {
+		out.Policies = append(out.Policies, cfg.Namespace+"/"+cfg.Name)
+		for port := range cfg.Spec.(*v1beta1.PeerAuthentication).PortLevelMtls {
+			ports.Insert(port)
+		}
+	}
+	sort.Strings(out.Policies)
+
+	for _, port := range sets.SortedList(ports) {
+		mtls := applier.InboundMTLSSettings(port, proxy, nil, authn.NoOverride)
+		out.Ports = append(out.Ports, MTLSDryRunPort{
+			Port:                 port,
+			Mode:                 mtls.Mode.String(),
+			DryRunMode:           applier.GetDryRunMutualTLSModeForPort(port).String(),
+			WouldRejectPlaintext: mtls.DryRunStrict,
+		})
+	}
+	return out
+}
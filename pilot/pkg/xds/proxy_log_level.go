@@ -0,0 +1,242 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/config/labels"
+)
+
+// maxProxyLogLevelDuration bounds the duration of log level changes, so that a forgotten change does not
+// keep proxies logging at debug level.
+const maxProxyLogLevelDuration = 24 * time.Hour
+
+// ProxyLogLevelOverride is a temporary change of the log levels of the proxies selected by a namespace,
+// labels and optionally a pod name.
+type ProxyLogLevelOverride struct {
+	Namespace string          `json:"namespace"`
+	Selector  labels.Instance `json:"selector,omitempty"`
+	Pod       string          `json:"pod,omitempty"`
+	model.ProxyLogLevel
+}
+
+func (o *ProxyLogLevelOverride) sameTarget(other *ProxyLogLevelOverride) bool {
+	return o.Namespace == other.Namespace && o.Pod == other.Pod && o.Selector.Equals(other.Selector)
+}
+
+func (o *ProxyLogLevelOverride) matches(proxy *model.Proxy) bool {
+	if proxy.ConfigNamespace != o.Namespace {
+		return false
+	}
+	if o.Pod != "" && proxy.ID != o.Pod+"."+o.Namespace {
+		return false
+	}
+	return o.Selector.SubsetOf(proxy.Labels)
+}
+
+// proxyLogLevels holds the active log level overrides. Overrides are kept in memory only; since proxies
+// revert the log levels on expiration by themselves, losing them on restart only shortens their duration.
+type proxyLogLevels struct {
+	mu        sync.RWMutex
+	overrides []*ProxyLogLevelOverride
+}
+
+// set adds an override, replacing the previous override of the same proxies if any.
+func (l *proxyLogLevels) set(o *ProxyLogLevelOverride) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.removeLocked(o)
+	l.overrides = append(l.overrides, o)
+}
+
+// remove deletes the override of the same proxies as o and returns it, or nil if there is none.
+func (l *proxyLogLevels) remove(o *ProxyLogLevelOverride) *ProxyLogLevelOverride {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.removeLocked(o)
+}
+
+func (l *proxyLogLevels) removeLocked(o *ProxyLogLevelOverride) *ProxyLogLevelOverride {
+	for i, existing := range l.overrides {
+		if existing.sameTarget(o) {
+			l.overrides = append(l.overrides[:i], l.overrides[i+1:]...)
+			return existing
+		}
+	}
+	return nil
+}
+
+// forProxy returns the most recent unexpired override selecting the proxy, or nil if there is none.
+func (l *proxyLogLevels) forProxy(proxy *model.Proxy, now time.Time) *model.ProxyLogLevel {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for i := len(l.overrides) - 1; i >= 0; i-- {
+		o := l.overrides[i]
+		if now.Before(o.Expiration) && o.matches(proxy) {
+			return &o.ProxyLogLevel
+		}
+	}
+	return nil
+}
+
+// list returns the unexpired overrides, pruning the expired ones.
+func (l *proxyLogLevels) list(now time.Time) []*ProxyLogLevelOverride {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	active := l.overrides[:0]
+	for _, o := range l.overrides {
+		if now.Before(o.Expiration) {
+			active = append(active, o)
+		}
+	}
+	l.overrides = active
+	return append([]*ProxyLogLevelOverride{}, active...)
+}
+
+// ProxyLogLevelGenerator generates the temporary log levels of a proxy. An empty log level is sent when
+// no override selects the proxy, so that the agent reverts the log levels of an override which was removed.
+type ProxyLogLevelGenerator struct {
+	Server *DiscoveryServer
+}
+
+var _ model.XdsResourceGenerator = &ProxyLogLevelGenerator{}
+
+func proxyLogLevelNeedsPush(req *model.PushRequest) bool {
+	if req == nil || req.IsRequest() {
+		return true
+	}
+	for _, reason := range req.Reason {
+		if reason == model.ProxyLogLevelUpdate {
+			return true
+		}
+	}
+	return false
+}
+
+// Generate returns the log levels of the given proxy, as JSON.
+func (g *ProxyLogLevelGenerator) Generate(proxy *model.Proxy, _ *model.WatchedResource, req *model.PushRequest) (model.Resources,
+	model.XdsLogDetails, error,
+) {
+	if !proxyLogLevelNeedsPush(req) {
+		return nil, model.DefaultXdsLogDetails, nil
+	}
+	level := g.Server.proxyLogLevels.forProxy(proxy, time.Now())
+	if level == nil {
+		level = &model.ProxyLogLevel{}
+	}
+	b, err := json.Marshal(level)
+	if err != nil {
+		return nil, model.DefaultXdsLogDetails, err
+	}
+	return model.Resources{&discovery.Resource{
+		Resource: protoconv.MessageToAny(wrapperspb.String(string(b))),
+	}}, model.DefaultXdsLogDetails, nil
+}
+
+// proxyLogLevelz temporarily changes the log levels of the proxies connected to this instance.
+//
+//	GET /debug/proxy_log_level lists the active changes.
+//	GET /debug/proxy_log_level?namespace=ns&selector=app=foo&level=http:debug&agent=xdsproxy:debug&duration=10m
+//	  changes the Envoy and agent log levels of the selected proxies for the given duration.
+//	GET /debug/proxy_log_level?namespace=ns&selector=app=foo&reset reverts the change immediately.
+func (s *DiscoveryServer) proxyLogLevelz(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	if !q.Has("namespace") {
+		writeJSON(w, s.proxyLogLevels.list(time.Now()), req)
+		return
+	}
+	o, err := parseProxyLogLevelOverride(req, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if q.Has("reset") {
+		if removed := s.proxyLogLevels.remove(o); removed == nil {
+			// Nothing to revert on this instance.
+			writeJSON(w, []string{}, req)
+			return
+		}
+	} else {
+		s.proxyLogLevels.set(o)
+	}
+
+	proxies := []string{}
+	for _, con := range s.Clients() {
+		if !o.matches(con.proxy) {
+			continue
+		}
+		proxies = append(proxies, con.proxy.ID)
+		s.pushQueue.Enqueue(con, &model.PushRequest{
+			Push:   s.globalPushContext(),
+			Start:  time.Now(),
+			Reason: []model.TriggerReason{model.ProxyLogLevelUpdate},
+		})
+	}
+	writeJSON(w, proxies, req)
+}
+
+func parseProxyLogLevelOverride(req *http.Request, now time.Time) (*ProxyLogLevelOverride, error) {
+	q := req.URL.Query()
+	o := &ProxyLogLevelOverride{
+		Namespace: q.Get("namespace"),
+		Pod:       q.Get("pod"),
+	}
+	if o.Namespace == "" {
+		return nil, fmt.Errorf("namespace must be set")
+	}
+	selector, err := klabels.ConvertSelectorToLabelsMap(q.Get("selector"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+	if len(selector) > 0 {
+		o.Selector = labels.Instance(selector)
+	}
+	if q.Has("reset") {
+		return o, nil
+	}
+
+	o.Envoy = q.Get("level")
+	o.Agent = q.Get("agent")
+	if o.IsEmpty() {
+		return nil, fmt.Errorf("level or agent must be set")
+	}
+	for _, l := range strings.Split(o.Agent, ",") {
+		if l != "" && !strings.Contains(l, ":") {
+			return nil, fmt.Errorf("invalid agent log level %q, must be in the form <scope>:<level>", l)
+		}
+	}
+	duration, err := time.ParseDuration(q.Get("duration"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid duration: %v", err)
+	}
+	if duration <= 0 || duration > maxProxyLogLevelDuration {
+		return nil, fmt.Errorf("duration must be positive and at most %v", maxProxyLogLevelDuration)
+	}
+	o.Expiration = now.Add(duration)
+	return o, nil
+}
@@ -0,0 +1,126 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestProxyLogLevels(t *testing.T) {
+	now := time.Now()
+	productpage := &model.Proxy{ID: "productpage-v1-abc.default", ConfigNamespace: "default", Labels: map[string]string{"app": "productpage"}}
+	reviews := &model.Proxy{ID: "reviews-v1-abc.default", ConfigNamespace: "default", Labels: map[string]string{"app": "reviews"}}
+	other := &model.Proxy{ID: "productpage-v1-abc.other", ConfigNamespace: "other", Labels: map[string]string{"app": "productpage"}}
+
+	l := &proxyLogLevels{}
+	namespaceWide := &ProxyLogLevelOverride{
+		Namespace:     "default",
+		ProxyLogLevel: model.ProxyLogLevel{Envoy: "info", Expiration: now.Add(time.Minute)},
+	}
+	selected := &ProxyLogLevelOverride{
+		Namespace:     "default",
+		Selector:      map[string]string{"app": "productpage"},
+		ProxyLogLevel: model.ProxyLogLevel{Envoy: "http:debug", Expiration: now.Add(time.Minute)},
+	}
+	l.set(namespaceWide)
+	l.set(selected)
+
+	if got := l.forProxy(productpage, now); got == nil || got.Envoy != "http:debug" {
+		t.Fatalf("expected most recent matching override, got %+v", got)
+	}
+	if got := l.forProxy(reviews, now); got == nil || got.Envoy != "info" {
+		t.Fatalf("expected namespace wide override, got %+v", got)
+	}
+	if got := l.forProxy(other, now); got != nil {
+		t.Fatalf("expected no override in other namespace, got %+v", got)
+	}
+
+	// Setting the same target again replaces the previous override.
+	l.set(&ProxyLogLevelOverride{
+		Namespace:     "default",
+		Selector:      map[string]string{"app": "productpage"},
+		ProxyLogLevel: model.ProxyLogLevel{Envoy: "http:trace", Expiration: now.Add(time.Minute)},
+	})
+	if got := len(l.list(now)); got != 2 {
+		t.Fatalf("expected 2 overrides, got %d", got)
+	}
+	if got := l.forProxy(productpage, now); got == nil || got.Envoy != "http:trace" {
+		t.Fatalf("expected replaced override, got %+v", got)
+	}
+
+	if removed := l.remove(&ProxyLogLevelOverride{Namespace: "default", Selector: map[string]string{"app": "productpage"}}); removed == nil {
+		t.Fatalf("expected override to be removed")
+	}
+	if got := l.forProxy(productpage, now.Add(2*time.Minute)); got != nil {
+		t.Fatalf("expected expired override to be ignored, got %+v", got)
+	}
+	if got := len(l.list(now.Add(2 * time.Minute))); got != 0 {
+		t.Fatalf("expected expired overrides to be pruned, got %d", got)
+	}
+}
+
+func TestParseProxyLogLevelOverride(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name    string
+		query   string
+		want    *ProxyLogLevelOverride
+		wantErr bool
+	}{
+		{
+			name:  "selector",
+			query: "namespace=default&selector=app%3Dproductpage&level=http:debug&agent=xdsproxy:debug&duration=10m",
+			want: &ProxyLogLevelOverride{
+				Namespace: "default",
+				Selector:  map[string]string{"app": "productpage"},
+				ProxyLogLevel: model.ProxyLogLevel{
+					Envoy: "http:debug", Agent: "xdsproxy:debug", Expiration: now.Add(10 * time.Minute),
+				},
+			},
+		},
+		{
+			name:  "reset",
+			query: "namespace=default&pod=productpage-v1-abc&reset=true",
+			want:  &ProxyLogLevelOverride{Namespace: "default", Pod: "productpage-v1-abc"},
+		},
+		{name: "missing namespace", query: "level=debug&duration=10m", wantErr: true},
+		{name: "missing level", query: "namespace=default&duration=10m", wantErr: true},
+		{name: "missing duration", query: "namespace=default&level=debug", wantErr: true},
+		{name: "duration too long", query: "namespace=default&level=debug&duration=48h", wantErr: true},
+		{name: "invalid agent level", query: "namespace=default&agent=debug&duration=10m", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProxyLogLevelOverride(httptest.NewRequest("GET", "/debug/proxy_log_level?"+tt.query, nil), now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.sameTarget(tt.want) || got.Envoy != tt.want.Envoy || got.Agent != tt.want.Agent ||
+				!got.Expiration.Equal(tt.want.Expiration) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
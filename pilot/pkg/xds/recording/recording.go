@@ -0,0 +1,344 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package recording records the discovery requests and responses of a proxy, and replays them against
+// another istiod. Recordings are JSON lines files, one Record per line, so that they can be inspected and
+// edited; the types of the resources must be registered to read them.
+package recording
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoytls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// redacted replaces the secrets of the recorded responses.
+const redacted = "[redacted]"
+
+// writeQueueSize is the number of records waiting to be written, beyond which the records are dropped rather
+// than delaying the pushes to the recorded proxy.
+const writeQueueSize = 1000
+
+// Record is a recorded event of an xDS stream: exactly one of Node, Request and Response is set.
+type Record struct {
+	Time time.Time
+	// Node is the node of the proxy, sent on the first request of its streams.
+	Node     *core.Node
+	Request  *discovery.DiscoveryRequest
+	Response *discovery.DiscoveryResponse
+}
+
+type entry struct {
+	Time     time.Time       `json:"time"`
+	Node     json.RawMessage `json:"node,omitempty"`
+	Request  json.RawMessage `json:"request,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// Writer writes the records of a proxy. It is safe for concurrent use. The records are redacted, marshaled and
+// written in the background, so that recording does not delay the xDS streams: the requests and responses must
+// not be modified once written.
+type Writer struct {
+	mu      sync.Mutex
+	w       io.WriteCloser
+	queue   chan Record
+	done    chan struct{}
+	records int
+	dropped int
+	hasNode bool
+	closed  bool
+	err     error
+}
+
+// NewWriter creates a Writer writing the records to w, which is closed by Close.
+func NewWriter(w io.WriteCloser) *Writer {
+	wr := &Writer{w: w, queue: make(chan Record, writeQueueSize), done: make(chan struct{})}
+	go wr.run()
+	return wr
+}
+
+// WriteNode records the node of the proxy. Later calls are ignored, the node of a proxy does not change.
+func (w *Writer) WriteNode(node *core.Node) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.hasNode || node == nil {
+		return w.err
+	}
+	w.hasNode = true
+	return w.enqueueLocked(Record{Node: node})
+}
+
+// HasNode returns true if the node of the proxy was recorded.
+func (w *Writer) HasNode() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.hasNode
+}
+
+// WriteRequest records a request of the proxy. The node of the request is recorded by WriteNode, if needed.
+func (w *Writer) WriteRequest(req *discovery.DiscoveryRequest) error {
+	if req.Node != nil {
+		if err := w.WriteNode(req.Node); err != nil {
+			return err
+		}
+		req = proto.Clone(req).(*discovery.DiscoveryRequest)
+		req.Node = nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enqueueLocked(Record{Request: req})
+}
+
+// WriteResponse records a response sent to the proxy, with its secrets redacted.
+func (w *Writer) WriteResponse(res *discovery.DiscoveryResponse) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.enqueueLocked(Record{Response: res})
+}
+
+func (w *Writer) enqueueLocked(rec Record) error {
+	if w.err != nil {
+		return w.err
+	}
+	if w.closed {
+		return errors.New("recording closed")
+	}
+	rec.Time = time.Now()
+	select {
+	case w.queue <- rec:
+		w.records++
+		return nil
+	default:
+		w.dropped++
+		return errors.New("too many records waiting to be written, record dropped")
+	}
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	for rec := range w.queue {
+		b, err := marshal(rec)
+		if err != nil {
+			// A record which cannot be redacted is dropped, rather than written with its secrets.
+			w.mu.Lock()
+			w.records--
+			w.dropped++
+			w.mu.Unlock()
+			continue
+		}
+		if _, err := w.w.Write(append(b, '\n')); err != nil {
+			w.mu.Lock()
+			if w.err == nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+func marshal(rec Record) ([]byte, error) {
+	e := &entry{Time: rec.Time}
+	var err error
+	switch {
+	case rec.Node != nil:
+		e.Node, err = protomarshal.Marshal(rec.Node)
+	case rec.Request != nil:
+		e.Request, err = protomarshal.Marshal(rec.Request)
+	case rec.Response != nil:
+		var res *discovery.DiscoveryResponse
+		if res, err = Redact(rec.Response); err == nil {
+			e.Response, err = protomarshal.Marshal(res)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(e)
+}
+
+// Records returns the number of records written, or waiting to be written.
+func (w *Writer) Records() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.records
+}
+
+// Dropped returns the number of records dropped, as too many records were waiting to be written or their secrets
+// could not be redacted.
+func (w *Writer) Dropped() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.dropped
+}
+
+// Close writes the records waiting to be written, and closes the underlying writer. Later records fail to be
+// written. It returns the first error writing the records, if any.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return errors.New("recording closed")
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.err
+	if cerr := w.w.Close(); err == nil {
+		err = cerr
+	}
+	w.err = errors.New("recording closed")
+	return err
+}
+
+// Read reads the records of a recording.
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		b, err := br.ReadBytes('\n')
+		if len(b) > 0 {
+			rec, perr := parse(b)
+			if perr != nil {
+				return nil, fmt.Errorf("line %d: %v", line, perr)
+			}
+			records = append(records, rec)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func parse(b []byte) (Record, error) {
+	e := entry{}
+	if err := json.Unmarshal(b, &e); err != nil {
+		return Record{}, err
+	}
+	rec := Record{Time: e.Time}
+	switch {
+	case e.Node != nil:
+		rec.Node = &core.Node{}
+		return rec, protomarshal.Unmarshal(e.Node, rec.Node)
+	case e.Request != nil:
+		rec.Request = &discovery.DiscoveryRequest{}
+		return rec, protomarshal.Unmarshal(e.Request, rec.Request)
+	case e.Response != nil:
+		rec.Response = &discovery.DiscoveryResponse{}
+		return rec, protomarshal.Unmarshal(e.Response, rec.Response)
+	}
+	return rec, errors.New("empty record")
+}
+
+// Redact returns the response with the secrets of its resources replaced: the private keys and the other secrets
+// of the SDS resources, and of the TLS contexts and the Wasm extensions inlined in the other resources. The
+// resources, and the resources they embed, are walked through the registered types, so that a resource of an
+// unregistered type fails to be redacted.
+func Redact(res *discovery.DiscoveryResponse) (*discovery.DiscoveryResponse, error) {
+	out := proto.Clone(res).(*discovery.DiscoveryResponse)
+	for _, r := range out.Resources {
+		if err := redactAny(r); err != nil {
+			return nil, fmt.Errorf("failed to redact %s: %v", r.TypeUrl, err)
+		}
+	}
+	return out, nil
+}
+
+func redactAny(a *anypb.Any) error {
+	m, err := a.UnmarshalNew()
+	if err != nil {
+		return err
+	}
+	if err := redactMessage(m.ProtoReflect()); err != nil {
+		return err
+	}
+	a.Value, err = proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	return err
+}
+
+func redactMessage(m protoreflect.Message) error {
+	switch msg := m.Interface().(type) {
+	case *anypb.Any:
+		return redactAny(msg)
+	case *envoytls.TlsCertificate:
+		if msg.PrivateKey != nil {
+			msg.PrivateKey = redactedDataSource()
+		}
+		if msg.Password != nil {
+			msg.Password = redactedDataSource()
+		}
+		msg.PrivateKeyProvider = nil
+		return nil
+	case *envoytls.GenericSecret:
+		msg.Secret = redactedDataSource()
+		return nil
+	case *envoytls.TlsSessionTicketKeys:
+		msg.Keys = nil
+		return nil
+	case *wasm.EnvironmentVariables:
+		if _, f := msg.KeyValues[model.WasmSecretEnv]; f {
+			msg.KeyValues[model.WasmSecretEnv] = redacted
+		}
+		return nil
+	}
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		switch {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				return true
+			}
+			v.Map().Range(func(_ protoreflect.MapKey, mv protoreflect.Value) bool {
+				err = redactMessage(mv.Message())
+				return err == nil
+			})
+		case fd.IsList():
+			if fd.Message() == nil {
+				return true
+			}
+			for i := 0; i < v.List().Len() && err == nil; i++ {
+				err = redactMessage(v.List().Get(i).Message())
+			}
+		case fd.Message() != nil:
+			err = redactMessage(v.Message())
+		}
+		return err == nil
+	})
+	return err
+}
+
+func redactedDataSource() *core.DataSource {
+	return &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: redacted}}
+}
@@ -0,0 +1,239 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recording
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	envoytls "github.com/envoyproxy/go-control-plane/envoy/extensions/transport_sockets/tls/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+type buffer struct {
+	bytes.Buffer
+}
+
+func (buffer) Close() error {
+	return nil
+}
+
+func mustAny(t *testing.T, m *envoytls.Secret) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestWriteRead(t *testing.T) {
+	b := &buffer{}
+	w := NewWriter(b)
+	secret := &envoytls.Secret{
+		Name: "default",
+		Type: &envoytls.Secret_TlsCertificate{TlsCertificate: &envoytls.TlsCertificate{
+			CertificateChain: &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: "chain"}},
+			PrivateKey:       &core.DataSource{Specifier: &core.DataSource_InlineBytes{InlineBytes: []byte("key")}},
+		}},
+	}
+	node := &core.Node{Id: "sidecar~10.0.0.1~productpage-v1-abc.default~default.svc.cluster.local"}
+	if err := w.WriteRequest(&discovery.DiscoveryRequest{Node: node, TypeUrl: v3.SecretType, ResourceNames: []string{"default"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteResponse(&discovery.DiscoveryResponse{
+		TypeUrl: v3.SecretType, Nonce: "a", Resources: []*anypb.Any{mustAny(t, secret)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Records() != 3 {
+		t.Fatalf("expected the node, the request and the response to be recorded, got %d records", w.Records())
+	}
+	if bytes.Contains(b.Bytes(), []byte("a2V5")) {
+		t.Fatalf("expected the private key to be redacted: %s", b.String())
+	}
+
+	records, err := Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0].Node.GetId() != node.Id || records[1].Request.GetNode() != nil || records[2].Response == nil {
+		t.Fatalf("unexpected records %v", records)
+	}
+	got := &envoytls.Secret{}
+	if err := records[2].Response.Resources[0].UnmarshalTo(got); err != nil {
+		t.Fatal(err)
+	}
+	if key := got.GetTlsCertificate().GetPrivateKey().GetInlineString(); key != redacted {
+		t.Fatalf("expected a redacted private key, got %q", key)
+	}
+	if chain := got.GetTlsCertificate().GetCertificateChain().GetInlineString(); chain != "chain" {
+		t.Fatalf("expected the certificate chain to be kept, got %q", chain)
+	}
+	if _, err := Read(bytes.NewBufferString("{}\n")); err == nil {
+		t.Fatalf("expected an error for an empty record")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tlsContext, err := anypb.New(&envoytls.UpstreamTlsContext{CommonTlsContext: &envoytls.CommonTlsContext{
+		TlsCertificates: []*envoytls.TlsCertificate{{
+			CertificateChain: &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: "chain"}},
+			PrivateKey:       &core.DataSource{Specifier: &core.DataSource_InlineString{InlineString: "key"}},
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := anypb.New(&cluster.Cluster{
+		Name: "outbound|443||external.example.com",
+		TransportSocket: &core.TransportSocket{
+			Name:       "envoy.transport_sockets.tls",
+			ConfigType: &core.TransportSocket_TypedConfig{TypedConfig: tlsContext},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := append([]byte(nil), c.Value...)
+	res := &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, Resources: []*anypb.Any{c}}
+	out, err := Redact(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &cluster.Cluster{}
+	if err := out.Resources[0].UnmarshalTo(got); err != nil {
+		t.Fatal(err)
+	}
+	redactedContext := &envoytls.UpstreamTlsContext{}
+	if err := got.GetTransportSocket().GetTypedConfig().UnmarshalTo(redactedContext); err != nil {
+		t.Fatal(err)
+	}
+	cert := redactedContext.GetCommonTlsContext().GetTlsCertificates()[0]
+	if cert.GetPrivateKey().GetInlineString() != redacted || cert.GetCertificateChain().GetInlineString() != "chain" {
+		t.Fatalf("expected only the inlined private key to be redacted, got %v", cert)
+	}
+	if !bytes.Equal(c.Value, original) {
+		t.Fatalf("expected the response not to be modified")
+	}
+
+	unknown := &discovery.DiscoveryResponse{Resources: []*anypb.Any{{TypeUrl: "type.googleapis.com/unknown.Type"}}}
+	if _, err := Redact(unknown); err == nil {
+		t.Fatalf("expected a resource of an unregistered type to fail to be redacted")
+	}
+}
+
+// fakeStream responds to the initial requests of each type with a response with a new nonce.
+type fakeStream struct {
+	sent      []*discovery.DiscoveryRequest
+	responses chan *discovery.DiscoveryResponse
+	clusters  []string
+}
+
+func (f *fakeStream) Send(req *discovery.DiscoveryRequest) error {
+	f.sent = append(f.sent, req)
+	if req.ResponseNonce != "" {
+		return nil
+	}
+	res := &discovery.DiscoveryResponse{TypeUrl: req.TypeUrl, Nonce: fmt.Sprintf("replayed-%d", len(f.sent)), VersionInfo: "v2"}
+	if req.TypeUrl == v3.ClusterType {
+		for _, c := range f.clusters {
+			a, _ := anypb.New(&cluster.Cluster{Name: c})
+			res.Resources = append(res.Resources, a)
+		}
+	}
+	f.responses <- res
+	return nil
+}
+
+func (f *fakeStream) Recv() (*discovery.DiscoveryResponse, error) {
+	res, ok := <-f.responses
+	if !ok {
+		return nil, io.EOF
+	}
+	return res, nil
+}
+
+type endedStream struct{}
+
+func (endedStream) Send(*discovery.DiscoveryRequest) error {
+	return nil
+}
+
+func (endedStream) Recv() (*discovery.DiscoveryResponse, error) {
+	return nil, io.EOF
+}
+
+func TestReplay(t *testing.T) {
+	recordedCluster, _ := anypb.New(&cluster.Cluster{Name: "outbound|80||reviews.default.svc.cluster.local"})
+	removedCluster, _ := anypb.New(&cluster.Cluster{Name: "outbound|80||ratings.default.svc.cluster.local"})
+	records := []Record{
+		{Node: &core.Node{Id: "proxy"}},
+		{Request: &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType}},
+		{Request: &discovery.DiscoveryRequest{TypeUrl: v3.ListenerType}},
+		{Response: &discovery.DiscoveryResponse{TypeUrl: v3.ListenerType, Nonce: "l1", VersionInfo: "v1"}},
+		{Response: &discovery.DiscoveryResponse{
+			TypeUrl: v3.ClusterType, Nonce: "c1", VersionInfo: "v1",
+			Resources: []*anypb.Any{recordedCluster, removedCluster},
+		}},
+		{Request: &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "c1", VersionInfo: "v1"}},
+		// The recording started after this response was sent.
+		{Request: &discovery.DiscoveryRequest{TypeUrl: v3.ListenerType, ResponseNonce: "l0", VersionInfo: "v0"}},
+	}
+	stream := &fakeStream{
+		responses: make(chan *discovery.DiscoveryResponse, 10),
+		clusters:  []string{"outbound|80||reviews.default.svc.cluster.local", "outbound|80||details.default.svc.cluster.local"},
+	}
+	replayed, err := Replay(stream, records, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed) != 2 {
+		t.Fatalf("expected 2 replayed responses, got %d", len(replayed))
+	}
+	if stream.sent[0].Node.GetId() != "proxy" || stream.sent[1].Node != nil {
+		t.Fatalf("expected the node to be sent on the first request only")
+	}
+	if ack := stream.sent[2]; ack.ResponseNonce != "replayed-1" || ack.VersionInfo != "v2" {
+		t.Fatalf("expected the ACK to refer to the replayed response, got %v", ack)
+	}
+	if ack := stream.sent[3]; ack.ResponseNonce != "replayed-2" {
+		t.Fatalf("expected the ACK of an unrecorded response to refer to the last replayed response, got %v", ack)
+	}
+
+	diff, err := replayed[1].Diff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Added) != 1 || diff.Added[0] != "outbound|80||details.default.svc.cluster.local" ||
+		len(diff.Removed) != 1 || diff.Removed[0] != "outbound|80||ratings.default.svc.cluster.local" || len(diff.Changed) != 0 {
+		t.Fatalf("unexpected diff %+v", diff)
+	}
+
+	if _, err := Replay(endedStream{}, records[:4], time.Second); err == nil {
+		t.Fatalf("expected an error when the stream ends")
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package recording
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Stream is the client side of an ADS stream.
+type Stream interface {
+	Send(*discovery.DiscoveryRequest) error
+	Recv() (*discovery.DiscoveryResponse, error)
+}
+
+// Replayed is a recorded response, and the response of the replayed istiod to the same requests.
+type Replayed struct {
+	Recorded *discovery.DiscoveryResponse
+	Replayed *discovery.DiscoveryResponse
+}
+
+type received struct {
+	res *discovery.DiscoveryResponse
+	err error
+}
+
+// Replay sends the recorded requests on the stream, and waits for a response of the same type for each
+// recorded response. The nonces and versions of the recorded requests are rewritten to those of the
+// replayed responses, so that the requests ACK or NACK them as they did on the recorded stream. The
+// responses replayed so far are returned together with the error if the replay does not complete.
+func Replay(stream Stream, records []Record, timeout time.Duration) ([]Replayed, error) {
+	recv := make(chan received)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			res, err := stream.Recv()
+			select {
+			case recv <- received{res, err}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	var node *core.Node
+	sentNode := false
+	// nonces and versions map the recorded nonces and versions to the replayed ones. Requests may refer to
+	// responses which were not recorded, when the recording started on a connected proxy: these use the last
+	// replayed response of their type.
+	nonces := map[string]string{}
+	versions := map[string]string{}
+	lastNonce := map[string]string{}
+	lastVersion := map[string]string{}
+	pending := map[string][]*discovery.DiscoveryResponse{}
+	var out []Replayed
+	for _, rec := range records {
+		switch {
+		case rec.Node != nil:
+			node = rec.Node
+		case rec.Request != nil:
+			req := proto.Clone(rec.Request).(*discovery.DiscoveryRequest)
+			if !sentNode {
+				if node == nil {
+					return out, errors.New("the recording has no node")
+				}
+				req.Node = node
+				sentNode = true
+			}
+			if req.ResponseNonce != "" {
+				req.ResponseNonce = replayedValue(nonces, lastNonce, req.ResponseNonce, req.TypeUrl)
+			}
+			if req.VersionInfo != "" {
+				req.VersionInfo = replayedValue(versions, lastVersion, req.VersionInfo, req.TypeUrl)
+			}
+			if err := stream.Send(req); err != nil {
+				return out, fmt.Errorf("failed to send request: %v", err)
+			}
+		case rec.Response != nil:
+			typeURL := rec.Response.TypeUrl
+			res, err := nextResponse(recv, pending, typeURL, timeout)
+			if err != nil {
+				return out, err
+			}
+			nonces[rec.Response.Nonce] = res.Nonce
+			versions[rec.Response.VersionInfo] = res.VersionInfo
+			lastNonce[typeURL] = res.Nonce
+			lastVersion[typeURL] = res.VersionInfo
+			out = append(out, Replayed{Recorded: rec.Response, Replayed: res})
+		}
+	}
+	return out, nil
+}
+
+func replayedValue(replayed, last map[string]string, recorded, typeURL string) string {
+	if v, f := replayed[recorded]; f {
+		return v
+	}
+	return last[typeURL]
+}
+
+// nextResponse returns the next response of the type, buffering the responses of the other types.
+func nextResponse(recv <-chan received, pending map[string][]*discovery.DiscoveryResponse, typeURL string,
+	timeout time.Duration,
+) (*discovery.DiscoveryResponse, error) {
+	if p := pending[typeURL]; len(p) > 0 {
+		pending[typeURL] = p[1:]
+		return p[0], nil
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case r := <-recv:
+			if r.err != nil {
+				return nil, fmt.Errorf("failed to receive %s response: %v", typeURL, r.err)
+			}
+			if r.res.TypeUrl == typeURL {
+				return r.res, nil
+			}
+			pending[r.res.TypeUrl] = append(pending[r.res.TypeUrl], r.res)
+		case <-timer.C:
+			return nil, fmt.Errorf("timed out waiting for %s response", typeURL)
+		}
+	}
+}
+
+// ResourceDiff is the difference between the resources of a recorded and a replayed response.
+type ResourceDiff struct {
+	Added   []string
+	Removed []string
+	Changed []string
+}
+
+// Empty returns true if the responses have the same resources.
+func (d ResourceDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff compares the resources of the replayed response to the recorded ones, by name.
+func (r Replayed) Diff() (ResourceDiff, error) {
+	recorded, err := resourcesByName(r.Recorded)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+	replayed, err := resourcesByName(r.Replayed)
+	if err != nil {
+		return ResourceDiff{}, err
+	}
+	d := ResourceDiff{}
+	for name, res := range replayed {
+		old, f := recorded[name]
+		switch {
+		case !f:
+			d.Added = append(d.Added, name)
+		case !proto.Equal(old, res):
+			d.Changed = append(d.Changed, name)
+		}
+	}
+	for name := range recorded {
+		if _, f := replayed[name]; !f {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d, nil
+}
+
+func resourcesByName(res *discovery.DiscoveryResponse) (map[string]proto.Message, error) {
+	out := make(map[string]proto.Message, len(res.Resources))
+	for i, a := range res.Resources {
+		m, err := anypb.UnmarshalNew(a, proto.UnmarshalOptions{})
+		if err != nil {
+			return nil, err
+		}
+		out[resourceName(m, i)] = m
+	}
+	return out, nil
+}
+
+// resourceName returns the name of a resource, or its index for the unnamed resources.
+func resourceName(m proto.Message, index int) string {
+	switch r := m.(type) {
+	case interface{ GetName() string }:
+		return r.GetName()
+	case interface{ GetClusterName() string }:
+		return r.GetClusterName()
+	}
+	return fmt.Sprintf("#%d", index)
+}
@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/config/labels"
+)
+
+// scopedPush selects the proxies pushed by /debug/push: a single proxy, or the proxies of a namespace optionally
+// selected by their labels.
+type scopedPush struct {
+	proxyID   string
+	namespace string
+	selector  labels.Instance
+}
+
+func (p scopedPush) matches(proxy *model.Proxy) bool {
+	if p.proxyID != "" {
+		// The namespace of the proxy ID is the one authorized, the proxy must be of that namespace.
+		return proxy.ID == p.proxyID && proxy.ConfigNamespace == proxyIDNamespace(p.proxyID)
+	}
+	return proxy.ConfigNamespace == p.namespace && p.selector.SubsetOf(proxy.Labels)
+}
+
+func parseScopedPush(req *http.Request) (scopedPush, error) {
+	q := req.URL.Query()
+	p := scopedPush{proxyID: q.Get("proxyID"), namespace: q.Get("namespace")}
+	if p.proxyID != "" {
+		if p.namespace != "" || q.Get("selector") != "" {
+			return p, fmt.Errorf("proxyID cannot be combined with namespace or selector")
+		}
+		return p, nil
+	}
+	if p.namespace == "" {
+		return p, fmt.Errorf("namespace or proxyID must be set, use /debug/adsz?push=true to push all proxies")
+	}
+	selector, err := klabels.ConvertSelectorToLabelsMap(q.Get("selector"))
+	if err != nil {
+		return p, fmt.Errorf("invalid selector: %v", err)
+	}
+	if len(selector) > 0 {
+		p.selector = labels.Instance(selector)
+	}
+	return p, nil
+}
+
+// scopedPushz recomputes the state of the selected proxies connected to this instance, such as their service
+// instances, labels and Sidecar scope, and pushes them their full configuration, without recomputing the push
+// context or pushing the other proxies. It is meant to recover proxies whose configuration is out of sync.
+//
+//	GET /debug/push?proxyID=pod.ns pushes a single proxy.
+//	GET /debug/push?namespace=ns&selector=app=foo pushes the proxies of the namespace matching the selector.
+//	&clearCache=true also clears the xDS cache, shared by all proxies, before the push.
+//
+// The IDs of the pushed proxies are returned.
+func (s *DiscoveryServer) scopedPushz(w http.ResponseWriter, req *http.Request) {
+	p, err := parseScopedPush(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+	if req.URL.Query().Get("clearCache") == "true" {
+		s.Cache.ClearAll()
+	}
+
+	proxies := []string{}
+	push := s.globalPushContext()
+	for _, con := range s.Clients() {
+		if !p.matches(con.proxy) {
+			continue
+		}
+		proxies = append(proxies, con.proxy.ID)
+		s.pushQueue.Enqueue(con, &model.PushRequest{
+			Full:   true,
+			Push:   push,
+			Start:  time.Now(),
+			Reason: []model.TriggerReason{model.ScopedPush},
+		})
+	}
+	log.Infof("Scoped push of %d proxies requested by %s", len(proxies), req.RemoteAddr)
+	writeJSON(w, proxies, req)
+}
@@ -0,0 +1,163 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/writeapi"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/util/sets"
+)
+
+func TestParseScopedPush(t *testing.T) {
+	productpage := &model.Proxy{ID: "productpage-v1-abc.default", ConfigNamespace: "default", Labels: map[string]string{"app": "productpage"}}
+	reviews := &model.Proxy{ID: "reviews-v1-abc.default", ConfigNamespace: "default", Labels: map[string]string{"app": "reviews"}}
+	other := &model.Proxy{ID: "productpage-v1-abc.other", ConfigNamespace: "other", Labels: map[string]string{"app": "productpage"}}
+	spoofed := &model.Proxy{ID: "spoofed.default", ConfigNamespace: "other"}
+
+	cases := []struct {
+		name    string
+		query   string
+		want    []*model.Proxy
+		wantErr bool
+	}{
+		{name: "proxy", query: "proxyID=productpage-v1-abc.default", want: []*model.Proxy{productpage}},
+		{name: "proxy of another namespace", query: "proxyID=spoofed.default"},
+		{name: "namespace", query: "namespace=default", want: []*model.Proxy{productpage, reviews}},
+		{name: "selector", query: "namespace=default&selector=app%3Dproductpage", want: []*model.Proxy{productpage}},
+		{name: "missing namespace", query: "selector=app%3Dproductpage", wantErr: true},
+		{name: "proxy and namespace", query: "proxyID=productpage-v1-abc.default&namespace=default", wantErr: true},
+		{name: "invalid selector", query: "namespace=default&selector=app%3D%3D%3D", wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseScopedPush(httptest.NewRequest("GET", "/debug/push?"+tt.query, nil))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []*model.Proxy
+			for _, proxy := range []*model.Proxy{productpage, reviews, other, spoofed} {
+				if p.matches(proxy) {
+					got = append(got, proxy)
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d proxies, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got proxy %s, want %s", got[i].ID, tt.want[i].ID)
+				}
+			}
+		})
+	}
+}
+
+type fakeDebugAuthenticator struct {
+	identities []string
+}
+
+func (a fakeDebugAuthenticator) Authenticate(security.AuthContext) (*security.Caller, error) {
+	return &security.Caller{Identities: a.identities}, nil
+}
+
+func (a fakeDebugAuthenticator) AuthenticatorType() string {
+	return "fake"
+}
+
+func TestAllowAdminOrLocalhost(t *testing.T) {
+	test.SetForTest(t, &features.DebugAdminIdentities, sets.New("spiffe://cluster.local/ns/ops/sa/oncall"))
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: writeapi.PolicyConfigMap, Namespace: "istio-system"},
+		Data: map[string]string{writeapi.PolicyKey: `
+rules:
+- identities: ["spiffe://cluster.local/ns/default/sa/deployer"]
+  operations: ["proxy.push"]
+  namespaces: ["default"]
+`},
+	})
+	cases := []struct {
+		name       string
+		remoteAddr string
+		query      string
+		identities []string
+		want       int
+	}{
+		{name: "localhost", remoteAddr: "127.0.0.1:1234", want: http.StatusOK},
+		{name: "istiod", remoteAddr: "10.0.0.1:1234", identities: []string{"spiffe://cluster.local/ns/istio-system/sa/istiod"}, want: http.StatusOK},
+		{
+			name: "istiod namespace", remoteAddr: "10.0.0.1:1234",
+			identities: []string{"spiffe://cluster.local/ns/istio-system/sa/istioctl"}, want: http.StatusForbidden,
+		},
+		{name: "admin identity", remoteAddr: "10.0.0.1:1234", identities: []string{"spiffe://cluster.local/ns/ops/sa/oncall"}, want: http.StatusOK},
+		{name: "other namespace", remoteAddr: "10.0.0.1:1234", identities: []string{"spiffe://cluster.local/ns/default/sa/default"}, want: http.StatusForbidden},
+		{
+			name: "write policy", remoteAddr: "10.0.0.1:1234",
+			identities: []string{"spiffe://cluster.local/ns/default/sa/deployer"}, want: http.StatusOK,
+		},
+		{
+			name: "write policy proxy", remoteAddr: "10.0.0.1:1234", query: "proxyID=productpage-v1-abc.default",
+			identities: []string{"spiffe://cluster.local/ns/default/sa/deployer"}, want: http.StatusOK,
+		},
+		{
+			name: "write policy other namespace", remoteAddr: "10.0.0.1:1234", query: "namespace=other",
+			identities: []string{"spiffe://cluster.local/ns/default/sa/deployer"}, want: http.StatusForbidden,
+		},
+		{name: "not spiffe", remoteAddr: "10.0.0.1:1234", identities: []string{"istio-system"}, want: http.StatusForbidden},
+		{name: "unauthenticated", remoteAddr: "10.0.0.1:1234", want: http.StatusUnauthorized},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &DiscoveryServer{
+				systemNamespace:      "istio-system",
+				IstiodServiceAccount: "istiod",
+				WriteAPI:             writeapi.NewServer(client, nil, "istio-system", nil),
+			}
+			if tt.identities != nil {
+				s.Authenticators = []security.Authenticator{fakeDebugAuthenticator{identities: tt.identities}}
+			}
+			handler := s.allowAdminOrLocalhost(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			query := tt.query
+			if query == "" {
+				query = "namespace=default"
+			}
+			req := httptest.NewRequest("GET", "/debug/push?"+query, nil)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("got status %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
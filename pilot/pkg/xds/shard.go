@@ -0,0 +1,32 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// checkShard rejects proxies from other shards. The injector points the proxies to the Service of their shard,
+// so only proxies with a custom discovery address are rejected, with a retryable error.
+func (s *DiscoveryServer) checkShard(proxy *model.Proxy) error {
+	if s.ProxyShard.Contains(proxy.ConfigNamespace) {
+		return nil
+	}
+	return status.Errorf(codes.Unavailable, "proxies in namespace %s are served by shard %d, not %d",
+		proxy.ConfigNamespace, model.ShardForNamespace(proxy.ConfigNamespace, s.ProxyShard.Count), s.ProxyShard.Index)
+}
@@ -22,6 +22,7 @@ import (
 	status "github.com/envoyproxy/go-control-plane/envoy/service/status/v3"
 	"google.golang.org/protobuf/proto"
 	anypb "google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/util/protoconv"
@@ -51,6 +52,16 @@ const (
 	// TODO: TypeURLReady - readiness events for endpoints, agent can propagate
 )
 
+const (
+	// ConfigSizesMetadataKey is the node metadata of the syncz ClientConfigs holding the size in bytes of the
+	// last response of each type, keyed by the short type, such as CDS.
+	ConfigSizesMetadataKey = "CONFIG_SIZES"
+
+	// LastPushDurationMetadataKey is the node metadata of the syncz ClientConfigs holding the time it took to
+	// generate and send the last push.
+	LastPushDurationMetadataKey = "LAST_PUSH_DURATION"
+)
+
 // StatusGen is a Generator for XDS status: connections, syncz, configdump
 type StatusGen struct {
 	Server *DiscoveryServer
@@ -126,6 +137,9 @@ func (sg *StatusGen) debugSyncz() model.Resources {
 				pxc := &status.ClientConfig_GenericXdsConfig{}
 				if watchedResource, ok := con.proxy.WatchedResources[stype]; ok {
 					pxc.ConfigStatus = debugSyncStatus(watchedResource)
+					if watchedResource.LastNackError != "" {
+						pxc.ErrorState = &status.UpdateFailureState{Details: watchedResource.LastNackError}
+					}
 				} else {
 					pxc.ConfigStatus = status.ConfigStatus_NOT_SENT
 				}
@@ -136,10 +150,8 @@ func (sg *StatusGen) debugSyncz() model.Resources {
 			}
 			clientConfig := &status.ClientConfig{
 				Node: &core.Node{
-					Id: con.proxy.ID,
-					Metadata: model.NodeMetadata{
-						ClusterID: con.proxy.Metadata.ClusterID,
-					}.ToStruct(),
+					Id:       con.proxy.ID,
+					Metadata: syncMetadata(con.proxy),
 				},
 				GenericXdsConfigs: xdsConfigs,
 			}
@@ -154,6 +166,32 @@ func (sg *StatusGen) debugSyncz() model.Resources {
 	return res
 }
 
+// syncMetadata returns the node metadata of the ClientConfig of a proxy, along with its push stats, as the
+// ClientConfig has no field for them.
+func syncMetadata(proxy *model.Proxy) *structpb.Struct {
+	meta := model.NodeMetadata{
+		ClusterID: proxy.Metadata.ClusterID,
+	}.ToStruct()
+	if meta == nil {
+		meta = &structpb.Struct{}
+	}
+	if meta.Fields == nil {
+		meta.Fields = map[string]*structpb.Value{}
+	}
+	sizes, _, pushDuration := proxyPushStats(proxy)
+	if len(sizes) > 0 {
+		fields := make(map[string]*structpb.Value, len(sizes))
+		for t, size := range sizes {
+			fields[t] = structpb.NewNumberValue(float64(size))
+		}
+		meta.Fields[ConfigSizesMetadataKey] = structpb.NewStructValue(&structpb.Struct{Fields: fields})
+	}
+	if pushDuration != "" {
+		meta.Fields[LastPushDurationMetadataKey] = structpb.NewStringValue(pushDuration)
+	}
+	return meta
+}
+
 func debugSyncStatus(wr *model.WatchedResource) status.ConfigStatus {
 	if wr.NonceSent == "" {
 		return status.ConfigStatus_NOT_SENT
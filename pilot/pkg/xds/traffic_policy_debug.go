@@ -0,0 +1,108 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"net/http"
+	"sort"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+)
+
+// Scopes of the DestinationRules applying to a proxy, in precedence order. Rules of the proxy namespace take
+// precedence over the rules of the service namespace, which take precedence over the rules of the root namespace.
+// The rules of the root namespace are the mesh-wide defaults, applied when no other rule matches; with destination
+// rule inheritance, their traffic policy is also the base of the rules of the other namespaces.
+const (
+	TrafficPolicyScopeProxyNamespace   = "proxy-namespace"
+	TrafficPolicyScopeServiceNamespace = "service-namespace"
+	TrafficPolicyScopeMesh             = "mesh"
+)
+
+// TrafficPolicyDebug is the effective traffic policy of a service for a proxy.
+type TrafficPolicyDebug struct {
+	Host      string `json:"host"`
+	Namespace string `json:"namespace"`
+	// DestinationRules are the rules merged into the effective policy, lowest precedence first. It is empty when
+	// no rule applies.
+	DestinationRules []TrafficPolicySource     `json:"destinationRules"`
+	TrafficPolicy    *networking.TrafficPolicy `json:"trafficPolicy,omitempty"`
+}
+
+// TrafficPolicySource is a DestinationRule contributing to an effective traffic policy.
+type TrafficPolicySource struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Scope     string `json:"scope"`
+}
+
+// trafficPolicyz reports the effective outbound traffic policy of the services of a proxy, and the DestinationRules
+// it comes from. The host query parameter selects a single service.
+func (s *DiscoveryServer) trafficPolicyz(w http.ResponseWriter, req *http.Request) {
+	proxyID, con := s.getDebugConnection(req)
+	if con == nil {
+		s.errorHandler(w, proxyID, con)
+		return
+	}
+	writeJSON(w, effectiveTrafficPolicies(s.globalPushContext(), con.proxy, req.URL.Query().Get("host")), req)
+}
+
+func effectiveTrafficPolicies(push *model.PushContext, proxy *model.Proxy, hostname string) []TrafficPolicyDebug {
+	out := []TrafficPolicyDebug{}
+	if proxy.SidecarScope == nil {
+		return out
+	}
+	for _, svc := range proxy.SidecarScope.Services() {
+		if hostname != "" && string(svc.Hostname) != hostname {
+			continue
+		}
+		tp := TrafficPolicyDebug{
+			Host:             string(svc.Hostname),
+			Namespace:        svc.Attributes.Namespace,
+			DestinationRules: []TrafficPolicySource{},
+		}
+		if dr := proxy.SidecarScope.DestinationRule(model.TrafficDirectionOutbound, proxy, svc.Hostname); dr != nil {
+			tp.TrafficPolicy = dr.GetRule().Spec.(*networking.DestinationRule).GetTrafficPolicy()
+			for _, from := range dr.GetFrom() {
+				tp.DestinationRules = append(tp.DestinationRules, TrafficPolicySource{
+					Name:      from.Name,
+					Namespace: from.Namespace,
+					Scope:     trafficPolicyScope(push, proxy, svc, from.Namespace),
+				})
+			}
+		}
+		out = append(out, tp)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Host != out[j].Host {
+			return out[i].Host < out[j].Host
+		}
+		return out[i].Namespace < out[j].Namespace
+	})
+	return out
+}
+
+func trafficPolicyScope(push *model.PushContext, proxy *model.Proxy, svc *model.Service, namespace string) string {
+	switch namespace {
+	case push.Mesh.GetRootNamespace():
+		return TrafficPolicyScopeMesh
+	case proxy.ConfigNamespace:
+		return TrafficPolicyScopeProxyNamespace
+	case svc.Attributes.Namespace:
+		return TrafficPolicyScopeServiceNamespace
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"testing"
+
+	"istio.io/istio/pilot/pkg/model"
+)
+
+func TestEffectiveTrafficPolicies(t *testing.T) {
+	s := NewFakeDiscoveryServer(t, FakeOptions{ConfigString: `
+apiVersion: networking.istio.io/v1alpha3
+kind: ServiceEntry
+metadata:
+  name: services
+  namespace: default
+spec:
+  hosts:
+  - a.example.com
+  - b.example.com
+  ports:
+  - number: 80
+    name: http
+    protocol: HTTP
+  resolution: DNS
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: mesh-defaults
+  namespace: istio-system
+spec:
+  host: "*"
+  trafficPolicy:
+    connectionPool:
+      tcp:
+        maxConnections: 100
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: b
+  namespace: default
+spec:
+  host: b.example.com
+  trafficPolicy:
+    connectionPool:
+      tcp:
+        maxConnections: 10
+`})
+	proxy := s.SetupProxy(&model.Proxy{ConfigNamespace: "app"})
+
+	got := effectiveTrafficPolicies(s.PushContext(), proxy, "")
+	if len(got) != 2 || got[0].Host != "a.example.com" || got[1].Host != "b.example.com" {
+		t.Fatalf("expected the policies of a.example.com and b.example.com, got %+v", got)
+	}
+	expect := func(tp TrafficPolicyDebug, name, scope string, maxConnections int32) {
+		t.Helper()
+		if len(tp.DestinationRules) != 1 || tp.DestinationRules[0].Name != name || tp.DestinationRules[0].Scope != scope {
+			t.Fatalf("%s: expected %s from the %s scope, got %+v", tp.Host, name, scope, tp.DestinationRules)
+		}
+		if got := tp.TrafficPolicy.GetConnectionPool().GetTcp().GetMaxConnections(); got != maxConnections {
+			t.Fatalf("%s: expected %d max connections, got %d", tp.Host, maxConnections, got)
+		}
+	}
+	// The mesh defaults apply when no other rule matches.
+	expect(got[0], "mesh-defaults", TrafficPolicyScopeMesh, 100)
+	expect(got[1], "b", TrafficPolicyScopeServiceNamespace, 10)
+
+	if got := effectiveTrafficPolicies(s.PushContext(), proxy, "b.example.com"); len(got) != 1 || got[0].Host != "b.example.com" {
+		t.Fatalf("expected only the policy of b.example.com, got %+v", got)
+	}
+}
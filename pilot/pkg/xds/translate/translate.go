@@ -0,0 +1,414 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package translate generates the xDS configuration of a proxy from a set of Istio and Kubernetes
+// configuration files, without a cluster. It runs the config generator of istiod over in-memory registries,
+// so the output can be checked into golden files to detect how an Istio upgrade changes the configuration
+// of the proxies.
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"google.golang.org/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pilot/pkg/config/memory"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/serviceregistry"
+	"istio.io/istio/pilot/pkg/serviceregistry/aggregate"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube"
+	"istio.io/istio/pilot/pkg/serviceregistry/kube/controller"
+	memregistry "istio.io/istio/pilot/pkg/serviceregistry/memory"
+	"istio.io/istio/pilot/pkg/serviceregistry/provider"
+	"istio.io/istio/pilot/pkg/serviceregistry/serviceentry"
+	"istio.io/istio/pilot/pkg/xds"
+	clusterid "istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/config/schema/collections"
+	"istio.io/istio/pkg/config/schema/resource"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// Proxy describes the proxy to generate the configuration for. Unset fields get the same defaults as in
+// the Istio translation tests.
+type Proxy struct {
+	// Type is the type of the proxy, sidecar or router. Defaults to sidecar.
+	Type string `json:"type,omitempty"`
+	// ID of the proxy, usually <pod name>.<namespace>. Defaults to app.test.
+	ID string `json:"id,omitempty"`
+	// Namespace of the proxy. Defaults to default.
+	Namespace string `json:"namespace,omitempty"`
+	// IPs of the proxy. Defaults to 1.1.1.1.
+	IPs []string `json:"ips,omitempty"`
+	// Labels of the workload of the proxy.
+	Labels map[string]string `json:"labels,omitempty"`
+	// IstioVersion is the version of the proxy, which changes the generated configuration for older proxies.
+	// Defaults to 1.17.0.
+	IstioVersion string `json:"istioVersion,omitempty"`
+}
+
+// Input is the configuration to translate.
+type Input struct {
+	// Config is a YAML stream of Istio resources, and of the Kubernetes Services and Pods the proxy depends
+	// on. The endpoints of a Service are the Pods it selects. Gateway API resources are not supported, as
+	// they are translated by the Kubernetes controllers of istiod.
+	Config string
+	// MeshConfig is the mesh configuration. Defaults to the default mesh configuration.
+	MeshConfig *meshconfig.MeshConfig
+	// Proxy is the proxy to generate the configuration for.
+	Proxy Proxy
+}
+
+// Output is the xDS configuration of the proxy.
+type Output struct {
+	Listeners []*listener.Listener
+	Clusters  []*cluster.Cluster
+	Routes    []*route.RouteConfiguration
+	Endpoints []*endpoint.ClusterLoadAssignment
+}
+
+// registryClusterID is the cluster of the Kubernetes Services of the input.
+const registryClusterID = clusterid.ID(provider.Kubernetes)
+
+// Translate generates the xDS configuration of the proxy. The resources of the output are sorted by name,
+// so that the output is stable.
+func Translate(in Input) (*Output, error) {
+	configs, others, err := crd.ParseInputs(in.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	services, pods, err := parseKubernetesObjects(others)
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := in.Proxy.toModel()
+	if err != nil {
+		return nil, err
+	}
+	m := in.MeshConfig
+	if m == nil {
+		m = mesh.DefaultMeshConfig()
+	}
+
+	configController := memory.NewSyncController(memory.Make(collections.Pilot))
+	env := model.NewEnvironment()
+	env.Watcher = mesh.NewFixedWatcher(m)
+	env.NetworksWatcher = mesh.NewFixedNetworksWatcher(nil)
+	env.ConfigStore = configController
+	registries := aggregate.NewController(aggregate.Options{})
+	env.ServiceDiscovery = registries
+	env.Init()
+
+	// The server is never started: it only holds the endpoint shards and the generators.
+	s := xds.NewDiscoveryServer(env, "translate", nil)
+	defer s.Shutdown()
+	updater := cacheUpdater{s}
+
+	se := serviceentry.NewController(configController, updater)
+	registries.AddRegistry(se)
+	kubeRegistry := memregistry.NewServiceDiscovery()
+	kubeRegistry.ClusterID = registryClusterID
+	kubeRegistry.XdsUpdater = updater
+	registries.AddRegistry(serviceregistry.Simple{
+		ClusterID:        registryClusterID,
+		ProviderID:       provider.Kubernetes,
+		ServiceDiscovery: kubeRegistry,
+		Controller:       kubeRegistry.Controller,
+	})
+	if err := env.InitNetworksManager(updater); err != nil {
+		return nil, err
+	}
+
+	for _, cfg := range configs {
+		if _, err := configController.Create(cfg); err != nil {
+			return nil, fmt.Errorf("failed to create %s %s/%s: %v", cfg.GroupVersionKind.Kind, cfg.Namespace, cfg.Name, err)
+		}
+	}
+	se.ResyncEDS()
+	for _, svc := range services {
+		addService(kubeRegistry, svc, pods, env.DomainSuffix)
+	}
+
+	push := env.PushContext
+	if err := push.InitContext(env, nil, nil); err != nil {
+		return nil, fmt.Errorf("failed to initialize push context: %v", err)
+	}
+	proxy.SetSidecarScope(push)
+	proxy.SetServiceInstances(env.ServiceDiscovery)
+	proxy.SetGatewaysForProxy(push)
+	proxy.DiscoverIPMode()
+
+	out, err := generate(s, proxy, push)
+	if err != nil {
+		return nil, fmt.Errorf("failed to translate config: %v", err)
+	}
+	out.sort()
+	return out, nil
+}
+
+// generate builds the listeners, clusters, routes and endpoints of the proxy, as they are sent on a full push.
+func generate(s *xds.DiscoveryServer, proxy *model.Proxy, push *model.PushContext) (*Output, error) {
+	req := &model.PushRequest{Full: true, Push: push}
+	out := &Output{Listeners: s.ConfigGenerator.BuildListeners(proxy, push)}
+
+	clusters, _ := s.ConfigGenerator.BuildClusters(proxy, req)
+	for _, r := range clusters {
+		c := &cluster.Cluster{}
+		if err := r.Resource.UnmarshalTo(c); err != nil {
+			return nil, err
+		}
+		out.Clusters = append(out.Clusters, c)
+	}
+
+	routes, _ := s.ConfigGenerator.BuildHTTPRoutes(proxy, req, routeNames(out.Listeners))
+	for _, r := range routes {
+		rc := &route.RouteConfiguration{}
+		if err := r.Resource.UnmarshalTo(rc); err != nil {
+			return nil, err
+		}
+		out.Routes = append(out.Routes, rc)
+	}
+
+	eds := &xds.EdsGenerator{Server: s}
+	endpoints, _, err := eds.Generate(proxy, &model.WatchedResource{ResourceNames: edsClusterNames(out.Clusters)}, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range endpoints {
+		cla := &endpoint.ClusterLoadAssignment{}
+		if err := r.Resource.UnmarshalTo(cla); err != nil {
+			return nil, err
+		}
+		out.Endpoints = append(out.Endpoints, cla)
+	}
+	return out, nil
+}
+
+// cacheUpdater updates the endpoint shards of the server without requesting pushes, as no proxy is connected.
+type cacheUpdater struct {
+	*xds.DiscoveryServer
+}
+
+var _ model.XDSUpdater = cacheUpdater{}
+
+func (u cacheUpdater) EDSUpdate(shard model.ShardKey, hostname string, namespace string, eps []*model.IstioEndpoint) {
+	u.EDSCacheUpdate(shard, hostname, namespace, eps)
+}
+
+func (u cacheUpdater) ConfigUpdate(*model.PushRequest) {}
+
+func (u cacheUpdater) ProxyUpdate(clusterid.ID, string) {}
+
+// parseKubernetesObjects decodes the Services and Pods of the input. Any other resource is rejected, rather
+// than silently ignored.
+func parseKubernetesObjects(objs []crd.IstioKind) ([]*corev1.Service, []*corev1.Pod, error) {
+	var services []*corev1.Service
+	var pods []*corev1.Pod
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if _, f := collections.PilotGatewayAPI.FindByGroupVersionAliasesKind(resource.FromKubernetesGVK(&gvk)); f {
+			return nil, nil, fmt.Errorf("%s %s/%s: Gateway API resources are not supported", obj.Kind, obj.Namespace, obj.Name)
+		}
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case gvk.Group == "" && gvk.Kind == "Service":
+			svc := &corev1.Service{}
+			if err := json.Unmarshal(b, svc); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse Service %s/%s: %v", obj.Namespace, obj.Name, err)
+			}
+			services = append(services, svc)
+		case gvk.Group == "" && gvk.Kind == "Pod":
+			pod := &corev1.Pod{}
+			if err := json.Unmarshal(b, pod); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse Pod %s/%s: %v", obj.Namespace, obj.Name, err)
+			}
+			pods = append(pods, pod)
+		default:
+			return nil, nil, fmt.Errorf("%s %s/%s: only Istio resources, Services and Pods are supported", obj.Kind, obj.Namespace, obj.Name)
+		}
+	}
+	return services, pods, nil
+}
+
+// addService adds the Service to the registry, with an endpoint for each port of each Pod it selects.
+func addService(registry *memregistry.ServiceDiscovery, svc *corev1.Service, pods []*corev1.Pod, domainSuffix string) {
+	if svc.Namespace == "" {
+		svc.Namespace = constants.IstioDefaultConfigNamespace
+	}
+	service := kube.ConvertService(*svc, domainSuffix, registryClusterID)
+	registry.AddService(service)
+	var eps []*model.IstioEndpoint
+	for _, pod := range pods {
+		if pod.Namespace == "" {
+			pod.Namespace = constants.IstioDefaultConfigNamespace
+		}
+		if pod.Namespace != svc.Namespace || pod.Status.PodIP == "" || len(svc.Spec.Selector) == 0 ||
+			!labels.Instance(svc.Spec.Selector).SubsetOf(pod.Labels) {
+			continue
+		}
+		registry.AddWorkload(pod.Status.PodIP, pod.Labels)
+		for i := range svc.Spec.Ports {
+			port := &svc.Spec.Ports[i]
+			targetPort := int(port.Port)
+			if port.TargetPort.IntValue() != 0 || port.TargetPort.StrVal != "" {
+				p, err := controller.FindPort(pod, port)
+				if err != nil {
+					continue
+				}
+				targetPort = p
+			}
+			eps = append(eps, &model.IstioEndpoint{
+				Address:         pod.Status.PodIP,
+				EndpointPort:    uint32(targetPort),
+				ServicePortName: port.Name,
+				Labels:          pod.Labels,
+				ServiceAccount:  kube.SecureNamingSAN(pod),
+				Namespace:       pod.Namespace,
+				WorkloadName:    pod.Name,
+				TLSMode:         kube.PodTLSMode(pod),
+			})
+		}
+	}
+	registry.SetEndpoints(string(service.Hostname), service.Attributes.Namespace, eps)
+}
+
+// routeNames returns the names of the route configurations the HTTP connection managers of the listeners
+// fetch with RDS.
+func routeNames(listeners []*listener.Listener) []string {
+	var names []string
+	for _, l := range listeners {
+		for _, fc := range l.FilterChains {
+			for _, filter := range fc.Filters {
+				if filter.Name != wellknown.HTTPConnectionManager {
+					continue
+				}
+				h := &hcm.HttpConnectionManager{}
+				if err := filter.GetTypedConfig().UnmarshalTo(h); err != nil {
+					continue
+				}
+				if rds := h.GetRds(); rds != nil {
+					names = append(names, rds.RouteConfigName)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// edsClusterNames returns the names of the clusters whose endpoints are fetched with EDS.
+func edsClusterNames(clusters []*cluster.Cluster) []string {
+	var names []string
+	for _, c := range clusters {
+		if c.GetType() == cluster.Cluster_EDS {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func (p Proxy) toModel() (*model.Proxy, error) {
+	if p.ID == "" {
+		p.ID = "app.test"
+	}
+	if p.Namespace == "" {
+		p.Namespace = constants.IstioDefaultConfigNamespace
+	}
+	if len(p.IPs) == 0 {
+		p.IPs = []string{"1.1.1.1"}
+	}
+	if p.IstioVersion == "" {
+		p.IstioVersion = "1.17.0"
+	}
+	proxy := &model.Proxy{
+		ID:              p.ID,
+		ConfigNamespace: p.Namespace,
+		DNSDomain:       p.Namespace + ".svc." + constants.DefaultClusterLocalDomain,
+		IPAddresses:     p.IPs,
+		Labels:          p.Labels,
+		IstioVersion:    model.ParseIstioVersion(p.IstioVersion),
+		Metadata: &model.NodeMetadata{
+			Namespace:    p.Namespace,
+			Labels:       p.Labels,
+			IstioVersion: p.IstioVersion,
+		},
+	}
+	switch model.NodeType(p.Type) {
+	case "", model.SidecarProxy:
+		proxy.Type = model.SidecarProxy
+	case model.Router:
+		proxy.Type = model.Router
+	default:
+		return nil, fmt.Errorf("invalid proxy type %q, must be %s or %s", p.Type, model.SidecarProxy, model.Router)
+	}
+	return proxy, nil
+}
+
+func (o *Output) sort() {
+	sort.Slice(o.Listeners, func(i, j int) bool { return o.Listeners[i].Name < o.Listeners[j].Name })
+	sort.Slice(o.Clusters, func(i, j int) bool { return o.Clusters[i].Name < o.Clusters[j].Name })
+	sort.Slice(o.Routes, func(i, j int) bool { return o.Routes[i].Name < o.Routes[j].Name })
+	sort.Slice(o.Endpoints, func(i, j int) bool { return o.Endpoints[i].ClusterName < o.Endpoints[j].ClusterName })
+}
+
+// YAML returns the output as a YAML document with the listeners, clusters, routes and endpoints.
+func (o *Output) YAML() ([]byte, error) {
+	doc := map[string][]map[string]any{}
+	add := func(key string, msg proto.Message) error {
+		m, err := protomarshal.ToJSONMap(msg)
+		if err != nil {
+			return err
+		}
+		doc[key] = append(doc[key], m)
+		return nil
+	}
+	for _, l := range o.Listeners {
+		if err := add("listeners", l); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range o.Clusters {
+		if err := add("clusters", c); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range o.Routes {
+		if err := add("routes", r); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range o.Endpoints {
+		if err := add("endpoints", e); err != nil {
+			return nil, err
+		}
+	}
+	return yaml.Marshal(doc)
+}
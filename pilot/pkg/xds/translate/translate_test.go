@@ -0,0 +1,134 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package translate
+
+import (
+	"strings"
+	"testing"
+
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	"golang.org/x/exp/slices"
+
+	"istio.io/istio/pilot/test/xdstest"
+)
+
+const config = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  ports:
+  - name: http
+    port: 9080
+  clusterIP: 10.0.0.10
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews
+  namespace: default
+spec:
+  host: reviews
+  subsets:
+  - name: v1
+    labels:
+      version: v1
+`
+
+func TestTranslate(t *testing.T) {
+	out, err := Translate(Input{Config: config, Proxy: Proxy{Namespace: "default"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clusters := xdstest.MapKeys(xdstest.ExtractClusters(out.Clusters))
+	want := "outbound|9080|v1|reviews.default.svc.cluster.local"
+	if !slices.Contains(clusters, want) {
+		t.Fatalf("cluster %s not found in %v", want, clusters)
+	}
+
+	b, err := out.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := Translate(Input{Config: config, Proxy: Proxy{Namespace: "default"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := again.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != string(b2) || !strings.Contains(string(b), want) {
+		t.Fatalf("expected a stable output containing %s", want)
+	}
+}
+
+func TestTranslateInvalidProxy(t *testing.T) {
+	if _, err := Translate(Input{Config: config, Proxy: Proxy{Type: "gateway"}}); err == nil {
+		t.Fatal("expected an error for an invalid proxy type")
+	}
+}
+
+func TestTranslateEndpoints(t *testing.T) {
+	pod := `
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: reviews-v1
+  namespace: default
+  labels:
+    app: reviews
+    version: v1
+spec:
+  containers:
+  - name: reviews
+status:
+  podIP: 10.1.0.1
+`
+	withSelector := strings.Replace(config, "  clusterIP: 10.0.0.10", "  clusterIP: 10.0.0.10\n  selector:\n    app: reviews", 1)
+	out, err := Translate(Input{Config: withSelector + pod, Proxy: Proxy{Namespace: "default"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "outbound|9080|v1|reviews.default.svc.cluster.local"
+	for _, cla := range out.Endpoints {
+		if cla.ClusterName != want {
+			continue
+		}
+		addrs := xdstest.ExtractLoadAssignments([]*endpoint.ClusterLoadAssignment{cla})[want]
+		if !slices.Equal(addrs, []string{"10.1.0.1:9080"}) {
+			t.Fatalf("expected the endpoint of the pod, got %v", addrs)
+		}
+		return
+	}
+	t.Fatalf("endpoints of %s not found", want)
+}
+
+func TestTranslateUnsupportedKind(t *testing.T) {
+	deployment := `
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: reviews
+  namespace: default
+`
+	if _, err := Translate(Input{Config: config + deployment}); err == nil {
+		t.Fatal("expected an error for an unsupported kind")
+	}
+}
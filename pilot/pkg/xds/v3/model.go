@@ -37,6 +37,9 @@ const (
 	DebugType     = "istio.io/debug"
 	BootstrapType = resource.APITypePrefix + "envoy.config.bootstrap.v3.Bootstrap"
 
+	// ProxyLogLevelType carries the temporary log levels of a proxy to the agent, as JSON in a StringValue.
+	ProxyLogLevelType = resource.APITypePrefix + "istio.v1.ProxyLogLevel"
+
 	// nolint
 	HttpProtocolOptionsType = "envoy.extensions.upstreams.http.v3.HttpProtocolOptions"
 )
@@ -0,0 +1,269 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/xds/recording"
+	"istio.io/istio/pkg/util/sets"
+)
+
+const (
+	// maxXDSRecordingDuration bounds the duration of the recordings, which grow with each push to the proxy.
+	maxXDSRecordingDuration     = time.Hour
+	defaultXDSRecordingDuration = 10 * time.Minute
+)
+
+// XDSRecording is an active recording of the xDS streams of a proxy.
+type XDSRecording struct {
+	ProxyID    string    `json:"proxyID"`
+	File       string    `json:"file"`
+	Expiration time.Time `json:"expiration"`
+	Records    int       `json:"records"`
+	// Dropped is the number of records dropped, as they could not be written in time or redacted.
+	Dropped int `json:"dropped,omitempty"`
+
+	writer *recording.Writer
+	timer  *time.Timer
+}
+
+// xdsRecorders holds the active recordings, by proxy ID. Only the SotW streams are recorded: the
+// requests and responses of delta xDS are not.
+type xdsRecorders struct {
+	mu         sync.RWMutex
+	dir        string
+	recordings map[string]*XDSRecording
+	// files are the recordings written by this instance, which are the only files served.
+	files sets.String
+}
+
+func newXDSRecorders(dir string) *xdsRecorders {
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return &xdsRecorders{dir: dir, recordings: map[string]*XDSRecording{}, files: sets.New[string]()}
+}
+
+func (r *xdsRecorders) forProxy(con *Connection) *XDSRecording {
+	if r == nil || con.proxy == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.recordings[con.proxy.ID]
+}
+
+// recordRequest records a request of the proxy of the connection, if it is recorded.
+func (r *xdsRecorders) recordRequest(con *Connection, req *discovery.DiscoveryRequest) {
+	rec := r.forProxy(con)
+	if rec == nil {
+		return
+	}
+	if err := rec.writer.WriteNode(con.node); err != nil {
+		log.Warnf("failed to record the node of %s: %v", con.proxy.ID, err)
+		return
+	}
+	if err := rec.writer.WriteRequest(req); err != nil {
+		log.Warnf("failed to record the %s request of %s: %v", req.TypeUrl, con.proxy.ID, err)
+	}
+}
+
+// recordResponse records a response sent to the proxy of the connection, if it is recorded.
+func (r *xdsRecorders) recordResponse(con *Connection, res *discovery.DiscoveryResponse) {
+	rec := r.forProxy(con)
+	if rec == nil {
+		return
+	}
+	if err := rec.writer.WriteResponse(res); err != nil {
+		log.Warnf("failed to record the %s response of %s: %v", res.TypeUrl, con.proxy.ID, err)
+	}
+}
+
+// start starts recording the proxy. If the proxy is connected, its node and the resources it watches are
+// recorded first, as the initial requests of a stream, so that the recording can be replayed.
+func (r *xdsRecorders) start(proxyID string, con *Connection, duration time.Duration, now time.Time) (*XDSRecording, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, f := r.recordings[proxyID]; f {
+		return nil, fmt.Errorf("%s is already recorded", proxyID)
+	}
+	name := fmt.Sprintf("%s-%s.jsonl", proxyID, now.UTC().Format("20060102T150405Z"))
+	f, err := os.OpenFile(filepath.Join(r.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	rec := &XDSRecording{
+		ProxyID:    proxyID,
+		File:       name,
+		Expiration: now.Add(duration),
+		writer:     recording.NewWriter(f),
+	}
+	if con != nil {
+		if err := rec.writer.WriteNode(con.node); err != nil {
+			_ = rec.writer.Close()
+			return nil, err
+		}
+		for _, typeURL := range watchedTypes(con) {
+			req := &discovery.DiscoveryRequest{TypeUrl: typeURL}
+			if w := con.Watched(typeURL); w != nil {
+				req.ResourceNames = w.ResourceNames
+			}
+			if err := rec.writer.WriteRequest(req); err != nil {
+				_ = rec.writer.Close()
+				return nil, err
+			}
+		}
+	}
+	rec.timer = time.AfterFunc(duration, func() {
+		r.stop(proxyID)
+	})
+	r.recordings[proxyID] = rec
+	r.files.Insert(name)
+	return rec, nil
+}
+
+// watchedTypes returns the types watched by the connection, in push order.
+func watchedTypes(con *Connection) []string {
+	con.proxy.RLock()
+	watched := make([]string, 0, len(con.proxy.WatchedResources))
+	for typeURL := range con.proxy.WatchedResources {
+		watched = append(watched, typeURL)
+	}
+	con.proxy.RUnlock()
+	order := func(typeURL string) int {
+		for i, t := range PushOrder {
+			if t == typeURL {
+				return i
+			}
+		}
+		return len(PushOrder)
+	}
+	sort.Slice(watched, func(i, j int) bool {
+		if oi, oj := order(watched[i]), order(watched[j]); oi != oj {
+			return oi < oj
+		}
+		return watched[i] < watched[j]
+	})
+	return watched
+}
+
+// stop stops recording the proxy, and returns the recording or nil if the proxy is not recorded.
+func (r *xdsRecorders) stop(proxyID string) *XDSRecording {
+	r.mu.Lock()
+	rec := r.recordings[proxyID]
+	delete(r.recordings, proxyID)
+	r.mu.Unlock()
+	if rec == nil {
+		return nil
+	}
+	rec.timer.Stop()
+	if err := rec.writer.Close(); err != nil {
+		log.Warnf("failed to close the xDS recording of %s: %v", proxyID, err)
+	}
+	rec.Records, rec.Dropped = rec.writer.Records(), rec.writer.Dropped()
+	return rec
+}
+
+func (r *xdsRecorders) list() []*XDSRecording {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*XDSRecording, 0, len(r.recordings))
+	for _, rec := range r.recordings {
+		c := *rec
+		c.Records, c.Dropped = rec.writer.Records(), rec.writer.Dropped()
+		out = append(out, &c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ProxyID < out[j].ProxyID
+	})
+	return out
+}
+
+// recorded returns true if the file is a recording written by this instance.
+func (r *xdsRecorders) recorded(file string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.files.Contains(file)
+}
+
+// xdsRecordz records the xDS streams of a proxy connected to this instance, with the secrets redacted. The
+// recordings can be replayed against another istiod with tools/xds-replay.
+//
+//	GET /debug/xds_record lists the active recordings.
+//	GET /debug/xds_record?proxyID=pod.ns&duration=10m starts recording the proxy for the duration, if set.
+//	GET /debug/xds_record?proxyID=pod.ns&stop=true stops recording the proxy.
+//	GET /debug/xds_record?file=name downloads a recording written by this instance.
+func (s *DiscoveryServer) xdsRecordz(w http.ResponseWriter, req *http.Request) {
+	q := req.URL.Query()
+	if file := q.Get("file"); file != "" {
+		if !s.xdsRecorders.recorded(file) {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Recording not found on this Pilot instance.\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/jsonl")
+		http.ServeFile(w, req, filepath.Join(s.xdsRecorders.dir, file))
+		return
+	}
+	proxyID := q.Get("proxyID")
+	if proxyID == "" {
+		writeJSON(w, s.xdsRecorders.list(), req)
+		return
+	}
+	if q.Get("stop") == "true" {
+		rec := s.xdsRecorders.stop(proxyID)
+		if rec == nil {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Proxy is not recorded by this Pilot instance.\n"))
+			return
+		}
+		writeJSON(w, rec, req)
+		return
+	}
+
+	duration := defaultXDSRecordingDuration
+	if d := q.Get("duration"); d != "" {
+		var err error
+		if duration, err = time.ParseDuration(d); err != nil || duration <= 0 || duration > maxXDSRecordingDuration {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprintf(w, "duration must be positive and at most %v\n", maxXDSRecordingDuration)
+			return
+		}
+	}
+	var con *Connection
+	for _, c := range s.Clients() {
+		if c.proxy.ID == proxyID {
+			con = c
+			break
+		}
+	}
+	rec, err := s.xdsRecorders.start(proxyID, con, duration, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error() + "\n"))
+		return
+	}
+	log.Infof("recording the xDS streams of %s to %s until %v", proxyID, rec.File, rec.Expiration)
+	writeJSON(w, rec, req)
+}
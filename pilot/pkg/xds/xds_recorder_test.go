@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xds
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds/recording"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+)
+
+func TestXDSRecorders(t *testing.T) {
+	dir := t.TempDir()
+	r := newXDSRecorders(dir)
+	con := &Connection{
+		node: &core.Node{Id: "sidecar~10.0.0.1~productpage-v1-abc.default~default.svc.cluster.local"},
+		proxy: &model.Proxy{
+			ID: "productpage-v1-abc.default",
+			WatchedResources: map[string]*model.WatchedResource{
+				v3.ListenerType: {TypeUrl: v3.ListenerType},
+				v3.ClusterType:  {TypeUrl: v3.ClusterType},
+				v3.RouteType:    {TypeUrl: v3.RouteType, ResourceNames: []string{"80"}},
+			},
+		},
+	}
+	other := &Connection{proxy: &model.Proxy{ID: "reviews-v1-abc.default"}}
+
+	rec, err := r.start(con.proxy.ID, con, time.Minute, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.start(con.proxy.ID, con, time.Minute, time.Now()); err == nil {
+		t.Fatalf("expected an error when the proxy is already recorded")
+	}
+	r.recordResponse(con, &discovery.DiscoveryResponse{TypeUrl: v3.ClusterType, Nonce: "a"})
+	r.recordRequest(con, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType, ResponseNonce: "a"})
+	r.recordRequest(other, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+	if got := r.list(); len(got) != 1 || got[0].Records != 6 {
+		t.Fatalf("unexpected recordings %+v", got)
+	}
+	if stopped := r.stop(con.proxy.ID); stopped == nil || stopped.File != rec.File {
+		t.Fatalf("expected the recording to be stopped, got %+v", stopped)
+	}
+	if r.stop(con.proxy.ID) != nil {
+		t.Fatalf("expected nothing to stop")
+	}
+	if !r.recorded(rec.File) || r.recorded("other.jsonl") {
+		t.Fatalf("expected only the recordings of this instance to be served")
+	}
+	r.recordRequest(con, &discovery.DiscoveryRequest{TypeUrl: v3.ListenerType})
+
+	f, err := os.Open(filepath.Join(dir, rec.File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := recording.Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 || records[0].Node.GetId() != con.node.Id {
+		t.Fatalf("unexpected records %v", records)
+	}
+	// The resources watched before the recording started are recorded as initial requests, in push order.
+	for i, typeURL := range []string{v3.ClusterType, v3.ListenerType, v3.RouteType} {
+		if got := records[i+1].Request.GetTypeUrl(); got != typeURL {
+			t.Fatalf("expected a %s request, got %s", typeURL, got)
+		}
+	}
+	if records[5].Request.GetResponseNonce() != "a" {
+		t.Fatalf("expected the ACK to be recorded last, got %v", records[5])
+	}
+
+	var disabled *xdsRecorders
+	disabled.recordRequest(con, &discovery.DiscoveryRequest{TypeUrl: v3.ClusterType})
+}
@@ -113,7 +113,7 @@ func (s *DiscoveryServer) pushXds(con *Connection, w *model.WatchedResource, req
 			ResourceNames: req.Delta.Subscribed.UnsortedList(),
 		}
 	}
-	res, logdata, err := gen.Generate(con.proxy, w, req)
+	res, logdata, err := s.generate(gen, con, w, req)
 	info := ""
 	if len(logdata.AdditionalInfo) > 0 {
 		info = " " + logdata.AdditionalInfo
@@ -126,6 +126,9 @@ func (s *DiscoveryServer) pushXds(con *Connection, w *model.WatchedResource, req
 		if s.StatusReporter != nil {
 			s.StatusReporter.RegisterEvent(con.conID, w.TypeUrl, req.Push.LedgerVersion)
 		}
+		if req.Full {
+			con.distribution.skipped(w.TypeUrl, req.Push.PushVersion)
+		}
 		if log.DebugEnabled() {
 			log.Debugf("%s: SKIP%s for node:%s%s", v3.GetShortType(w.TypeUrl), req.PushReason(), con.proxy.ID, info)
 		}
@@ -164,6 +167,9 @@ func (s *DiscoveryServer) pushXds(con *Connection, w *model.WatchedResource, req
 		}
 		return err
 	}
+	con.distribution.sent(w.TypeUrl, resp.Nonce, resp.VersionInfo)
+	s.xdsRecorders.recordResponse(con, resp)
+	con.recordResponseSize(w.TypeUrl, configSize)
 
 	switch {
 	case !req.Full:
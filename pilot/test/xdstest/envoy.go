@@ -0,0 +1,135 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package xdstest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	bootstrap "github.com/envoyproxy/go-control-plane/envoy/config/bootstrap/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	endpoint "github.com/envoyproxy/go-control-plane/envoy/config/endpoint/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	route "github.com/envoyproxy/go-control-plane/envoy/config/route/v3"
+	http "github.com/envoyproxy/go-control-plane/envoy/extensions/upstreams/http/v3"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	"istio.io/istio/pilot/pkg/util/protoconv"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	"istio.io/istio/pkg/util/protomarshal"
+)
+
+// EnvoyValidateBinary is the environment variable holding the path of the Envoy binary run by ValidateEnvoyConfig.
+const EnvoyValidateBinary = "ENVOY_VALIDATE_BINARY"
+
+// xdsClusterName is the ADS cluster of the validation bootstrap. It is never connected to.
+const xdsClusterName = "xds-grpc"
+
+// ValidateEnvoyConfig checks that the generated configuration is accepted by Envoy. The listeners, clusters and
+// routes are always checked against the constraints of the Envoy API. When ENVOY_VALIDATE_BINARY is set, the
+// listeners and clusters are also loaded by that binary with --mode validate, through a bootstrap declaring them as
+// static resources, and an ADS server for the resources they reference, such as routes, endpoints and secrets.
+// Unlike the other validations of this package, it returns an error, so that the callers can minimize the
+// configurations failing it.
+func ValidateEnvoyConfig(ls []*listener.Listener, cs []*cluster.Cluster, rs []*route.RouteConfiguration) error {
+	for _, l := range ls {
+		if err := l.Validate(); err != nil {
+			return fmt.Errorf("listener %v is invalid: %v", l.Name, err)
+		}
+	}
+	for _, c := range cs {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("cluster %v is invalid: %v", c.Name, err)
+		}
+	}
+	for _, r := range rs {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("route configuration %v is invalid: %v", r.Name, err)
+		}
+	}
+
+	binary := os.Getenv(EnvoyValidateBinary)
+	if binary == "" {
+		return nil
+	}
+	bs, err := protomarshal.Marshal(validationBootstrap(ls, cs))
+	if err != nil {
+		return fmt.Errorf("failed to marshal the validation bootstrap: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "envoy-validate")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bootstrap.json")
+	if err := os.WriteFile(path, bs, 0o644); err != nil {
+		return err
+	}
+	out, err := exec.Command(binary, "--mode", "validate", "-c", path, "--log-level", "error").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("envoy rejected the configuration: %v: %s", err, out)
+	}
+	return nil
+}
+
+func validationBootstrap(ls []*listener.Listener, cs []*cluster.Cluster) *bootstrap.Bootstrap {
+	xdsCluster := &cluster.Cluster{
+		Name:                 xdsClusterName,
+		ClusterDiscoveryType: &cluster.Cluster_Type{Type: cluster.Cluster_STATIC},
+		TypedExtensionProtocolOptions: map[string]*anypb.Any{
+			v3.HttpProtocolOptionsType: protoconv.MessageToAny(&http.HttpProtocolOptions{
+				UpstreamProtocolOptions: &http.HttpProtocolOptions_ExplicitHttpConfig_{
+					ExplicitHttpConfig: &http.HttpProtocolOptions_ExplicitHttpConfig{
+						ProtocolConfig: &http.HttpProtocolOptions_ExplicitHttpConfig_Http2ProtocolOptions{
+							Http2ProtocolOptions: &core.Http2ProtocolOptions{},
+						},
+					},
+				},
+			}),
+		},
+		LoadAssignment: &endpoint.ClusterLoadAssignment{
+			ClusterName: xdsClusterName,
+			Endpoints: []*endpoint.LocalityLbEndpoints{{
+				LbEndpoints: []*endpoint.LbEndpoint{{
+					HostIdentifier: &endpoint.LbEndpoint_Endpoint{Endpoint: &endpoint.Endpoint{
+						Address: &core.Address{Address: &core.Address_SocketAddress{SocketAddress: &core.SocketAddress{
+							Address:       "127.0.0.1",
+							PortSpecifier: &core.SocketAddress_PortValue{PortValue: 15010},
+						}}},
+					}},
+				}},
+			}},
+		},
+	}
+	return &bootstrap.Bootstrap{
+		Node: &core.Node{Id: "sidecar~1.1.1.1~validate.default~default.svc.cluster.local", Cluster: "validate"},
+		StaticResources: &bootstrap.Bootstrap_StaticResources{
+			Listeners: ls,
+			Clusters:  append([]*cluster.Cluster{xdsCluster}, cs...),
+		},
+		DynamicResources: &bootstrap.Bootstrap_DynamicResources{
+			AdsConfig: &core.ApiConfigSource{
+				ApiType:             core.ApiConfigSource_GRPC,
+				TransportApiVersion: core.ApiVersion_V3,
+				GrpcServices: []*core.GrpcService{{
+					TargetSpecifier: &core.GrpcService_EnvoyGrpc_{EnvoyGrpc: &core.GrpcService_EnvoyGrpc{ClusterName: xdsClusterName}},
+				}},
+			},
+		},
+	}
+}
@@ -63,6 +63,9 @@ const (
 	// required for metrics based on stat_prefix in virtual service.
 	requiredEnvoyStatsMatcherInclusionRegexes = `vhost\.*\.route\.*`
 
+	// Circuit breaker and overflow stats of clusters, needed for the connection pool metrics of the agent.
+	connectionPoolStatsMatcherInclusionRegex = `cluster\..*\.(circuit_breakers\.default\.remaining_.*|upstream_(cx|rq_pending|rq_retry)_overflow)`
+
 	// Prefixes of V2 metrics.
 	// "reporter" prefix is for istio standard metrics.
 	// "component" suffix is for istio_build metric.
@@ -91,6 +94,7 @@ func (cfg Config) toTemplateParams() (map[string]any, error) {
 		option.NodeType(cfg.ID),
 		option.PilotSubjectAltName(cfg.Metadata.PilotSubjectAltName),
 		option.OutlierLogPath(cfg.Metadata.OutlierLogPath),
+		option.LoadReporting(bool(cfg.Metadata.LoadReporting)),
 		option.DiscoveryHost(discHost),
 		option.Metadata(cfg.Metadata),
 		option.XdsType(xdsType))
@@ -247,13 +251,17 @@ func getStatsOptions(meta *model.BootstrapNodeMetadata) []option.Instance {
 	if meta.ExitOnZeroActiveConnections {
 		inclusionSuffixes = requiredEnvoyStatsMatcherInclusionSuffixes
 	}
+	inclusionRegexes := requiredEnvoyStatsMatcherInclusionRegexes
+	if meta.ConnectionPoolMetrics {
+		inclusionRegexes += "," + connectionPoolStatsMatcherInclusionRegex
+	}
 
 	return []option.Instance{
 		option.EnvoyStatsMatcherInclusionPrefix(parseOption(prefixAnno,
 			requiredEnvoyStatsMatcherInclusionPrefixes, proxyConfigPrefixes)),
 		option.EnvoyStatsMatcherInclusionSuffix(parseOption(suffixAnno,
 			inclusionSuffixes, proxyConfigSuffixes)),
-		option.EnvoyStatsMatcherInclusionRegexp(parseOption(RegexAnno, requiredEnvoyStatsMatcherInclusionRegexes, proxyConfigRegexps)),
+		option.EnvoyStatsMatcherInclusionRegexp(parseOption(RegexAnno, inclusionRegexes, proxyConfigRegexps)),
 		option.EnvoyExtraStatTags(extraStatTags),
 	}
 }
@@ -512,12 +520,14 @@ type MetadataOptions struct {
 	ProxyConfig                 *meshAPI.ProxyConfig
 	PilotSubjectAltName         []string
 	CredentialSocketExists      bool
+	DualWorkloadCerts           bool
 	XDSRootCert                 string
 	OutlierLogPath              string
 	annotationFilePath          string
 	EnvoyStatusPort             int
 	EnvoyPrometheusPort         int
 	ExitOnZeroActiveConnections bool
+	ConnectionPoolMetrics       bool
 }
 
 const (
@@ -571,6 +581,7 @@ func GetNodeMetaData(options MetadataOptions) (*model.Node, error) {
 	meta.EnvoyStatusPort = options.EnvoyStatusPort
 	meta.EnvoyPrometheusPort = options.EnvoyPrometheusPort
 	meta.ExitOnZeroActiveConnections = model.StringBool(options.ExitOnZeroActiveConnections)
+	meta.ConnectionPoolMetrics = model.StringBool(options.ConnectionPoolMetrics)
 
 	meta.ProxyConfig = (*model.NodeMetaProxyConfig)(options.ProxyConfig)
 
@@ -634,6 +645,9 @@ func GetNodeMetaData(options MetadataOptions) (*model.Node, error) {
 	if options.CredentialSocketExists {
 		untypedMeta[security.CredentialMetaDataName] = "true"
 	}
+	if options.DualWorkloadCerts {
+		untypedMeta[security.DualWorkloadCertMetaDataName] = "true"
+	}
 
 	return &model.Node{
 		ID:          options.ID,
@@ -47,6 +47,7 @@ func All() []analysis.Analyzer {
 		&gateway.CertificateAnalyzer{},
 		&gateway.SecretAnalyzer{},
 		&gateway.ConflictingGatewayAnalyzer{},
+		&gateway.RouteNameAnalyzer{},
 		&injection.Analyzer{},
 		&injection.ImageAnalyzer{},
 		&injection.ImageAutoAnalyzer{},
@@ -61,7 +62,10 @@ func All() []analysis.Analyzer {
 		&virtualservice.JWTClaimRouteAnalyzer{},
 		&virtualservice.RegexAnalyzer{},
 		&destinationrule.CaCertificateAnalyzer{},
+		&destinationrule.ConflictingAnalyzer{},
+		&destinationrule.SubsetInheritanceAnalyzer{},
 		&serviceentry.ProtocolAddressesAnalyzer{},
+		&serviceentry.SNIAnalyzer{},
 		&webhook.Analyzer{},
 		&envoyfilter.EnvoyPatchAnalyzer{},
 		&telemetry.ProdiverAnalyzer{},
@@ -411,6 +411,23 @@ var testGrid = []testCase{
 			{msg.ReferencedResourceNotFound, "AuthorizationPolicy httpbin/httpbin-bogus-not-ns"},
 		},
 	},
+	{
+		name:       "destinationrule conflicting across namespaces",
+		inputFiles: []string{"testdata/destinationrule-conflicting.yaml"},
+		analyzer:   &destinationrule.ConflictingAnalyzer{},
+		expected: []message{
+			{msg.ConflictingDestinationRules, "DestinationRule team-a/reviews"},
+			{msg.ConflictingDestinationRules, "DestinationRule team-c/reviews-team-c"},
+		},
+	},
+	{
+		name:       "destinationrule subset dropping traffic policy settings",
+		inputFiles: []string{"testdata/destinationrule-subset-inheritance.yaml"},
+		analyzer:   &destinationrule.SubsetInheritanceAnalyzer{},
+		expected: []message{
+			{msg.SubsetTrafficPolicyDropsSettings, "DestinationRule default/reviews"},
+		},
+	},
 	{
 		name: "destinationrule with no cacert, simple at destinationlevel",
 		inputFiles: []string{
@@ -739,6 +756,24 @@ var testGrid = []testCase{
 			{msg.ConflictingGateways, "Gateway beta-l"},
 		},
 	},
+	{
+		name:       "gateway route names",
+		inputFiles: []string{"testdata/gateway-route-names.yaml"},
+		analyzer:   &gateway.RouteNameAnalyzer{},
+		expected: []message{
+			{msg.GeneratedNameConflict, "Gateway default/gw"},
+			{msg.GeneratedNameConflict, "Gateway default/a.gw"},
+		},
+	},
+	{
+		name:       "serviceentry long SNI",
+		inputFiles: []string{"testdata/serviceentry-long-sni.yaml"},
+		analyzer:   &serviceentry.SNIAnalyzer{},
+		expected: []message{
+			{msg.GeneratedNameTruncated, "ServiceEntry default/long-host"},
+			{msg.GeneratedNameTruncated, "DestinationRule default/long-subset"},
+		},
+	},
 	{
 		name:       "Analyze invalid telemetry",
 		inputFiles: []string{"testdata/telemetry-invalid-provider.yaml"},
@@ -0,0 +1,169 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// ConflictingAnalyzer checks for DestinationRules in different namespaces that target the same host
+// with different TLS modes or subsets. Clients use the DestinationRule of their own namespace first, then
+// the one of the service namespace and then the one of the root namespace, so these DestinationRules
+// apply differently depending on the namespace of the client.
+type ConflictingAnalyzer struct{}
+
+var _ analysis.Analyzer = &ConflictingAnalyzer{}
+
+func (c *ConflictingAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "destinationrule.ConflictingAnalyzer",
+		Description: "Checks for DestinationRules in different namespaces with conflicting settings for the same host",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+			collections.IstioMeshV1Alpha1MeshConfig.Name(),
+		},
+	}
+}
+
+func (c *ConflictingAnalyzer) Analyze(ctx analysis.Context) {
+	rootNamespace := constants.IstioSystemNamespace
+	ctx.ForEach(collections.IstioMeshV1Alpha1MeshConfig.Name(), func(r *resource.Instance) bool {
+		if ns := r.Message.(*meshconfig.MeshConfig).GetRootNamespace(); ns != "" {
+			rootNamespace = ns
+		}
+		return r.Metadata.FullName.Name != util.MeshConfigName
+	})
+
+	byHost := map[string][]*resource.Instance{}
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		dr := r.Message.(*v1alpha3.DestinationRule)
+		// DestinationRules with a workload selector only apply to the selected workloads, and wildcard
+		// hosts are resolved per service, so both are left out.
+		if dr.GetWorkloadSelector() != nil || strings.HasPrefix(dr.GetHost(), "*") {
+			return true
+		}
+		host := util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, dr.GetHost())
+		byHost[host] = append(byHost[host], r)
+		return true
+	})
+
+	hosts := make([]string, 0, len(byHost))
+	for host := range byHost {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		c.analyzeHost(ctx, host, byHost[host], rootNamespace)
+	}
+}
+
+func (c *ConflictingAnalyzer) analyzeHost(ctx analysis.Context, host string, drs []*resource.Instance, rootNamespace string) {
+	fallback := fallbackDestinationRule(host, drs, rootNamespace)
+	fallbackName := "no DestinationRule"
+	if fallback != nil {
+		fallbackName = fallback.Metadata.FullName.String()
+	}
+	for _, r := range drs {
+		if r == fallback {
+			continue
+		}
+		ns := r.Metadata.FullName.Namespace
+		// Other namespaces use the fallback, so that is the one to compare with if there is one.
+		others := drs
+		if fallback != nil {
+			others = []*resource.Instance{fallback}
+		}
+		for _, other := range others {
+			if other.Metadata.FullName.Namespace == ns {
+				continue
+			}
+			conflicts := conflictingSettings(r.Message.(*v1alpha3.DestinationRule), other.Message.(*v1alpha3.DestinationRule))
+			if len(conflicts) == 0 {
+				continue
+			}
+			ctx.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+				msg.NewConflictingDestinationRules(r, host, other.Metadata.FullName.String(),
+					strings.Join(conflicts, ", "), ns.String(), fallbackName))
+			break
+		}
+	}
+}
+
+// fallbackDestinationRule returns the DestinationRule used for the host by clients in namespaces that do not
+// define their own, which is the one exported from the service namespace or else from the root namespace.
+func fallbackDestinationRule(host string, drs []*resource.Instance, rootNamespace string) *resource.Instance {
+	var root *resource.Instance
+	serviceNamespace := util.GetFullNameFromFQDN(host).Namespace
+	for _, r := range drs {
+		if !util.IsExportToAllNamespaces(r.Message.(*v1alpha3.DestinationRule).GetExportTo()) {
+			continue
+		}
+		ns := r.Metadata.FullName.Namespace
+		if serviceNamespace != "" && ns == serviceNamespace {
+			return r
+		}
+		if root == nil && ns.String() == rootNamespace {
+			root = r
+		}
+	}
+	return root
+}
+
+// conflictingSettings returns a description of the settings that differ between the two DestinationRules.
+func conflictingSettings(a, b *v1alpha3.DestinationRule) []string {
+	var conflicts []string
+	if modeA, modeB := a.GetTrafficPolicy().GetTls().GetMode(), b.GetTrafficPolicy().GetTls().GetMode(); modeA != modeB {
+		conflicts = append(conflicts, fmt.Sprintf("TLS mode %s and %s", modeA, modeB))
+	}
+
+	subsetsA, subsetsB := subsetLabels(a), subsetLabels(b)
+	var subsets []string
+	for name, l := range subsetsA {
+		if other, f := subsetsB[name]; !f || len(l) != len(other) || !l.SubsetOf(other) {
+			subsets = append(subsets, name)
+		}
+	}
+	for name := range subsetsB {
+		if _, f := subsetsA[name]; !f {
+			subsets = append(subsets, name)
+		}
+	}
+	if len(subsets) > 0 {
+		sort.Strings(subsets)
+		conflicts = append(conflicts, fmt.Sprintf("subsets %s", strings.Join(subsets, ", ")))
+	}
+	return conflicts
+}
+
+func subsetLabels(dr *v1alpha3.DestinationRule) map[string]labels.Instance {
+	subsets := make(map[string]labels.Instance, len(dr.GetSubsets()))
+	for _, s := range dr.GetSubsets() {
+		subsets[s.GetName()] = s.GetLabels()
+	}
+	return subsets
+}
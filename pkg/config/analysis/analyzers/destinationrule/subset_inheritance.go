@@ -0,0 +1,69 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package destinationrule
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/util/trafficpolicy"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// SubsetInheritanceAnalyzer checks for subsets whose traffic policy replaces settings of the DestinationRule
+// traffic policy, dropping fields of the DestinationRule settings that the subset does not configure. For
+// example, a subset only setting the TLS mode drops the SNI and certificates of the DestinationRule.
+type SubsetInheritanceAnalyzer struct{}
+
+var _ analysis.Analyzer = &SubsetInheritanceAnalyzer{}
+
+func (s *SubsetInheritanceAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "destinationrule.SubsetInheritanceAnalyzer",
+		Description: "Checks for subset traffic policies dropping settings of the DestinationRule traffic policy",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+		},
+	}
+}
+
+func (s *SubsetInheritanceAnalyzer) Analyze(ctx analysis.Context) {
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		if trafficpolicy.Merges(r.Metadata.Annotations) {
+			// The subsets inherit the fields they do not configure.
+			return true
+		}
+		dr := r.Message.(*v1alpha3.DestinationRule)
+		for i, subset := range dr.GetSubsets() {
+			dropped := trafficpolicy.DroppedSettings(dr.GetTrafficPolicy(), subset.GetTrafficPolicy())
+			if len(dropped) == 0 {
+				continue
+			}
+			m := msg.NewSubsetTrafficPolicyDropsSettings(r, subset.GetName(), strings.Join(dropped, ", "),
+				trafficpolicy.SubsetPolicyInheritanceAnnotation, trafficpolicy.InheritanceMerge)
+			if line, ok := util.ErrorLine(r, fmt.Sprintf(util.DestinationRuleSubsetTrafficPolicy, i)); ok {
+				m.Line = line
+			}
+			ctx.Report(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), m)
+		}
+		return true
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gateway
+
+import (
+	"sort"
+	"strings"
+
+	klabels "k8s.io/apimachinery/pkg/labels"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/util/names"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// RouteNameAnalyzer checks that the route configurations generated for the HTTPS servers of gateways selecting
+// the same workloads have distinct names. The names are built from the port and gateway names, so dots in
+// them can make different servers generate the same name.
+type RouteNameAnalyzer struct{}
+
+// (compile-time check that we implement the interface)
+var _ analysis.Analyzer = &RouteNameAnalyzer{}
+
+// Metadata implements analysis.Analyzer
+func (*RouteNameAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "gateway.RouteNameAnalyzer",
+		Description: "Checks the route configuration names generated for the HTTPS servers of gateways",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Gateways.Name(),
+		},
+	}
+}
+
+type httpsRoute struct {
+	r      *resource.Instance
+	name   string
+	source string
+}
+
+// Analyze implements analysis.Analyzer
+func (*RouteNameAnalyzer) Analyze(c analysis.Context) {
+	routesBySelector := map[string][]httpsRoute{}
+	c.ForEach(collections.IstioNetworkingV1Alpha3Gateways.Name(), func(r *resource.Instance) bool {
+		gw := r.Message.(*v1alpha3.Gateway)
+		selector := klabels.SelectorFromSet(gw.Selector).String()
+		for _, server := range gw.Servers {
+			if server.Port == nil || protocol.Parse(server.Port.Protocol) != protocol.HTTPS ||
+				server.Tls == nil || gateway.IsPassThroughServer(server) {
+				continue
+			}
+			routesBySelector[selector] = append(routesBySelector[selector], httpsRoute{
+				r: r,
+				name: names.GatewayHTTPSRouteName(server.Port.Number, server.Port.Name,
+					r.Metadata.FullName.Name.String(), r.Metadata.FullName.Namespace.String(), server.Bind),
+				source: "gateway " + r.Metadata.FullName.String() + " port " + server.Port.Name,
+			})
+		}
+		return true
+	})
+
+	for _, routes := range routesBySelector {
+		byName := map[string][]httpsRoute{}
+		for _, route := range routes {
+			byName[route.name] = append(byName[route.name], route)
+		}
+		for name, routes := range byName {
+			for _, route := range routes {
+				var others []string
+				for _, other := range routes {
+					if other.source != route.source {
+						others = append(others, other.source)
+					}
+				}
+				if len(others) == 0 {
+					continue
+				}
+				sort.Strings(others)
+				m := msg.NewGeneratedNameConflict(route.r, "route configuration", name, route.source, strings.Join(others, ", "))
+				c.Report(collections.IstioNetworkingV1Alpha3Gateways.Name(), m)
+			}
+		}
+	}
+}
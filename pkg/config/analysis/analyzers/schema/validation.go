@@ -106,6 +106,9 @@ func morePreciseMessage(r *resource.Instance, err error, isError bool) diag.Mess
 			return msg.NewVirtualServiceIneffectiveMatch(r, aae.Parameters[0].(string), aae.Parameters[1].(string), aae.Parameters[2].(string))
 		}
 	}
+	if d, ok := err.(*validation.Deprecation); ok {
+		return msg.NewDeprecated(r, d.Error())
+	}
 	if !isError {
 		return msg.NewSchemaWarning(r, err)
 	}
@@ -63,6 +63,7 @@ func TestSchemaValidationWrapper(t *testing.T) {
 	m1 := &v1alpha3.VirtualService{}
 	m2 := &v1alpha3.VirtualService{}
 	m3 := &v1alpha3.VirtualService{}
+	m4 := &v1alpha3.VirtualService{}
 
 	testSchema := schemaWithValidateFn(func(cfg config.Config) (warnings validation.Warning, errs error) {
 		if cfg.Spec == m1 {
@@ -74,6 +75,9 @@ func TestSchemaValidationWrapper(t *testing.T) {
 		if cfg.Spec == m3 {
 			return nil, multierror.Append(fmt.Errorf(""), fmt.Errorf(""))
 		}
+		if cfg.Spec == m4 {
+			return multierror.Append(validation.Deprecations[0], fmt.Errorf("")), nil
+		}
 		return nil, nil
 	})
 
@@ -128,6 +132,22 @@ func TestSchemaValidationWrapper(t *testing.T) {
 		g.Expect(ctx.Reports[0].Type).To(Equal(msg.SchemaValidationError))
 		g.Expect(ctx.Reports[1].Type).To(Equal(msg.SchemaValidationError))
 	})
+
+	t.Run("Deprecation", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx := &fixtures.Context{
+			Resources: []*resource.Instance{
+				{
+					Message: m4,
+					Origin:  fakeOrigin{},
+				},
+			},
+		}
+		a.Analyze(ctx)
+		g.Expect(ctx.Reports).To(HaveLen(2))
+		g.Expect(ctx.Reports[0].Type).To(Equal(msg.Deprecated))
+		g.Expect(ctx.Reports[1].Type).To(Equal(msg.SchemaWarning))
+	})
 }
 
 func schemaWithValidateFn(validateFn func(cfg config.Config) (validation.Warning, error)) collection.Schema {
@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serviceentry
+
+import (
+	"fmt"
+
+	"istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/util/names"
+	"istio.io/istio/pkg/config/analysis"
+	"istio.io/istio/pkg/config/analysis/analyzers/util"
+	"istio.io/istio/pkg/config/analysis/msg"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/resource"
+	"istio.io/istio/pkg/config/schema/collection"
+	"istio.io/istio/pkg/config/schema/collections"
+)
+
+// SNIAnalyzer checks the length of the SNIs of the ServiceEntry hosts and of their DestinationRule subsets.
+// The SNIs longer than the maximum length accepted by Envoy are truncated.
+type SNIAnalyzer struct{}
+
+var _ analysis.Analyzer = &SNIAnalyzer{}
+
+func (*SNIAnalyzer) Metadata() analysis.Metadata {
+	return analysis.Metadata{
+		Name:        "serviceentry.SNIAnalyzer",
+		Description: "Checks the length of the SNIs of ServiceEntry hosts",
+		Inputs: collection.Names{
+			collections.IstioNetworkingV1Alpha3Serviceentries.Name(),
+			collections.IstioNetworkingV1Alpha3Destinationrules.Name(),
+		},
+	}
+}
+
+func (a *SNIAnalyzer) Analyze(ctx analysis.Context) {
+	portsByHost := map[string][]int{}
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Serviceentries.Name(), func(r *resource.Instance) bool {
+		se := r.Message.(*v1alpha3.ServiceEntry)
+		for _, h := range se.Hosts {
+			for _, port := range se.Ports {
+				portsByHost[h] = append(portsByHost[h], int(port.Number))
+				a.analyzeSNI(ctx, collections.IstioNetworkingV1Alpha3Serviceentries.Name(), r, h, "", int(port.Number))
+			}
+		}
+		return true
+	})
+
+	ctx.ForEach(collections.IstioNetworkingV1Alpha3Destinationrules.Name(), func(r *resource.Instance) bool {
+		dr := r.Message.(*v1alpha3.DestinationRule)
+		h := util.ConvertHostToFQDN(r.Metadata.FullName.Namespace, dr.Host)
+		for _, subset := range dr.Subsets {
+			for _, port := range portsByHost[h] {
+				a.analyzeSNI(ctx, collections.IstioNetworkingV1Alpha3Destinationrules.Name(), r, h, subset.Name, port)
+			}
+		}
+		return true
+	})
+}
+
+func (*SNIAnalyzer) analyzeSNI(ctx analysis.Context, col collection.Name, r *resource.Instance, h, subset string, port int) {
+	sni := model.BuildDNSSrvSubsetSNI(model.TrafficDirectionOutbound, subset, host.Name(h), port)
+	full := model.BuildDNSSrvSubsetKey(model.TrafficDirectionOutbound, subset, host.Name(h), port)
+	if sni == full {
+		return
+	}
+	source := fmt.Sprintf("host %s port %d", h, port)
+	if subset != "" {
+		source = fmt.Sprintf("subset %s of %s", subset, source)
+	}
+	ctx.Report(col, msg.NewGeneratedNameTruncated(r, "SNI", full, source, names.MaxSNILength, sni))
+}
@@ -102,6 +102,10 @@ const (
 	// Required parameters: portLevelSettings index.
 	DestinationRuleTLSPortLevelCert = "{.spec.trafficPolicy.portLevelSettings[%d].tls.caCertificates}"
 
+	// Path for DestinationRule subset traffic policy.
+	// Required parameters: subset index.
+	DestinationRuleSubsetTrafficPolicy = "{.spec.subsets[%d].trafficPolicy}"
+
 	// Path for ConfigPatch in envoyFilter
 	// Required parameters: envoyFilter config patch index
 	EnvoyFilterConfigPath = "{.spec.configPatches[%d].patch.value}"
@@ -224,6 +224,38 @@ var (
 	// InvalidTelemetryProvider defines a diag.MessageType for message "InvalidTelemetryProvider".
 	// Description: The Telemetry with empty providers will be ignored
 	InvalidTelemetryProvider = diag.NewMessageType(diag.Warning, "IST0157", "The Telemetry %v in namespace %q with empty providers will be ignored.")
+
+	// ConflictingDestinationRules defines a diag.MessageType for message "ConflictingDestinationRules".
+	// Description: DestinationRules in different namespaces define conflicting settings for the same host
+	ConflictingDestinationRules = diag.NewMessageType(diag.Warning, "IST0158", "This DestinationRule for host %s conflicts with DestinationRule %s on %s. Workloads in namespace %s use this DestinationRule, while other namespaces use %s.")
+
+	// GeneratedNameConflict defines a diag.MessageType for message "GeneratedNameConflict".
+	// Description: Different configurations generate the same Envoy resource name
+	GeneratedNameConflict = diag.NewMessageType(diag.Warning, "IST0159", "The %s name %s generated for %s is also generated for %s. Only one of them will be used.")
+
+	// GeneratedNameTruncated defines a diag.MessageType for message "GeneratedNameTruncated".
+	// Description: A generated Envoy resource name exceeds the maximum length and is truncated
+	GeneratedNameTruncated = diag.NewMessageType(diag.Info, "IST0160", "The %s %s generated for %s is longer than %d characters and is truncated to %s.")
+
+	// SubsetTrafficPolicyDropsSettings defines a diag.MessageType for message "SubsetTrafficPolicyDropsSettings".
+	// Description: A subset traffic policy replaces settings of the DestinationRule traffic policy and drops some of their fields
+	SubsetTrafficPolicyDropsSettings = diag.NewMessageType(diag.Warning, "IST0161", "The traffic policy of subset %s replaces the DestinationRule traffic policy settings it configures, dropping %s. Set the %s annotation to %q to inherit them.")
+
+	// AmbientEnvoyFilter defines a diag.MessageType for message "AmbientEnvoyFilter".
+	// Description: EnvoyFilters are not supported by ambient mode
+	AmbientEnvoyFilter = diag.NewMessageType(diag.Warning, "IST0162", "EnvoyFilters are not supported by ambient mode: the EnvoyFilter must be replaced before migrating the workloads it applies to.")
+
+	// AmbientSidecarOnlyField defines a diag.MessageType for message "AmbientSidecarOnlyField".
+	// Description: A field is only supported by sidecars and has no effect in ambient mode
+	AmbientSidecarOnlyField = diag.NewMessageType(diag.Warning, "IST0163", "%s is only supported by sidecars, and has no effect on the workloads migrated to ambient mode.")
+
+	// AmbientMultiNetwork defines a diag.MessageType for message "AmbientMultiNetwork".
+	// Description: Multi-network meshes are not supported by ambient mode
+	AmbientMultiNetwork = diag.NewMessageType(diag.Warning, "IST0164", "The mesh spans multiple networks (%s), which ambient mode does not support.")
+
+	// AmbientUnsupportedProtocol defines a diag.MessageType for message "AmbientUnsupportedProtocol".
+	// Description: A Service port uses a protocol not supported by ambient mode
+	AmbientUnsupportedProtocol = diag.NewMessageType(diag.Warning, "IST0165", "Port %v of the Service uses protocol %s, which is not supported by ambient mode.")
 )
 
 // All returns a list of all known message types.
@@ -283,6 +315,14 @@ func All() []*diag.MessageType {
 		EnvoyFilterUsesRelativeOperationWithProxyVersion,
 		UnsupportedGatewayAPIVersion,
 		InvalidTelemetryProvider,
+		ConflictingDestinationRules,
+		GeneratedNameConflict,
+		GeneratedNameTruncated,
+		SubsetTrafficPolicyDropsSettings,
+		AmbientEnvoyFilter,
+		AmbientSidecarOnlyField,
+		AmbientMultiNetwork,
+		AmbientUnsupportedProtocol,
 	}
 }
 
@@ -815,3 +855,89 @@ func NewInvalidTelemetryProvider(r *resource.Instance, name string, namespace st
 		namespace,
 	)
 }
+
+// NewConflictingDestinationRules returns a new diag.Message based on ConflictingDestinationRules.
+func NewConflictingDestinationRules(r *resource.Instance, host string, conflictingDestinationRule string, conflicts string, namespace string, fallbackDestinationRule string) diag.Message {
+	return diag.NewMessage(
+		ConflictingDestinationRules,
+		r,
+		host,
+		conflictingDestinationRule,
+		conflicts,
+		namespace,
+		fallbackDestinationRule,
+	)
+}
+
+// NewGeneratedNameConflict returns a new diag.Message based on GeneratedNameConflict.
+func NewGeneratedNameConflict(r *resource.Instance, kind string, generatedName string, source string, conflictingSource string) diag.Message {
+	return diag.NewMessage(
+		GeneratedNameConflict,
+		r,
+		kind,
+		generatedName,
+		source,
+		conflictingSource,
+	)
+}
+
+// NewGeneratedNameTruncated returns a new diag.Message based on GeneratedNameTruncated.
+func NewGeneratedNameTruncated(r *resource.Instance, kind string, generatedName string, source string, maxLength int, truncatedName string) diag.Message {
+	return diag.NewMessage(
+		GeneratedNameTruncated,
+		r,
+		kind,
+		generatedName,
+		source,
+		maxLength,
+		truncatedName,
+	)
+}
+
+// NewSubsetTrafficPolicyDropsSettings returns a new diag.Message based on SubsetTrafficPolicyDropsSettings.
+func NewSubsetTrafficPolicyDropsSettings(r *resource.Instance, subset string, droppedFields string, annotation string, mode string) diag.Message {
+	return diag.NewMessage(
+		SubsetTrafficPolicyDropsSettings,
+		r,
+		subset,
+		droppedFields,
+		annotation,
+		mode,
+	)
+}
+
+// NewAmbientEnvoyFilter returns a new diag.Message based on AmbientEnvoyFilter.
+func NewAmbientEnvoyFilter(r *resource.Instance) diag.Message {
+	return diag.NewMessage(
+		AmbientEnvoyFilter,
+		r,
+	)
+}
+
+// NewAmbientSidecarOnlyField returns a new diag.Message based on AmbientSidecarOnlyField.
+func NewAmbientSidecarOnlyField(r *resource.Instance, field string) diag.Message {
+	return diag.NewMessage(
+		AmbientSidecarOnlyField,
+		r,
+		field,
+	)
+}
+
+// NewAmbientMultiNetwork returns a new diag.Message based on AmbientMultiNetwork.
+func NewAmbientMultiNetwork(r *resource.Instance, networks string) diag.Message {
+	return diag.NewMessage(
+		AmbientMultiNetwork,
+		r,
+		networks,
+	)
+}
+
+// NewAmbientUnsupportedProtocol returns a new diag.Message based on AmbientUnsupportedProtocol.
+func NewAmbientUnsupportedProtocol(r *resource.Instance, port int, protocol string) diag.Message {
+	return diag.NewMessage(
+		AmbientUnsupportedProtocol,
+		r,
+		port,
+		protocol,
+	)
+}
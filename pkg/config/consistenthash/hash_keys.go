@@ -0,0 +1,63 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package consistenthash holds the consistent hash load balancer settings configured with annotations.
+package consistenthash
+
+import (
+	"fmt"
+	"strings"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+// KeysAnnotation lists the hash keys of a DestinationRule combined with the hash key of its consistent hash load
+// balancer, as comma separated header:<name>, cookie:<name>, query:<name> or source-ip, for example:
+//
+//	networking.istio.io/consistent-hash-keys: cookie:session, source-ip
+//
+// The hash of a request combines the hashes of the keys present in the request, in order.
+// TODO: move to API
+const KeysAnnotation = "networking.istio.io/consistent-hash-keys"
+
+// ParseKeys returns the hash keys of the KeysAnnotation, or nil if there is none.
+func ParseKeys(annotations map[string]string) ([]*networking.LoadBalancerSettings_ConsistentHashLB, error) {
+	value, f := annotations[KeysAnnotation]
+	if !f {
+		return nil, nil
+	}
+	var out []*networking.LoadBalancerSettings_ConsistentHashLB
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		kind, name, _ := strings.Cut(key, ":")
+		hash := &networking.LoadBalancerSettings_ConsistentHashLB{}
+		switch {
+		case kind == "source-ip" && name == "":
+			hash.HashKey = &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true}
+		case kind == "header" && name != "":
+			hash.HashKey = &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: name}
+		case kind == "cookie" && name != "":
+			hash.HashKey = &networking.LoadBalancerSettings_ConsistentHashLB_HttpCookie{
+				HttpCookie: &networking.LoadBalancerSettings_ConsistentHashLB_HTTPCookie{Name: name},
+			}
+		case kind == "query" && name != "":
+			hash.HashKey = &networking.LoadBalancerSettings_ConsistentHashLB_HttpQueryParameterName{HttpQueryParameterName: name}
+		default:
+			return nil, fmt.Errorf("invalid %s annotation: invalid hash key %q, must be header:<name>, cookie:<name>, "+
+				"query:<name> or source-ip", KeysAnnotation, key)
+		}
+		out = append(out, hash)
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consistenthash
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+func TestParseKeys(t *testing.T) {
+	got, err := ParseKeys(map[string]string{KeysAnnotation: "header:x-user, cookie:session,query:id, source-ip"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*networking.LoadBalancerSettings_ConsistentHashLB{
+		{HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpHeaderName{HttpHeaderName: "x-user"}},
+		{HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpCookie{
+			HttpCookie: &networking.LoadBalancerSettings_ConsistentHashLB_HTTPCookie{Name: "session"},
+		}},
+		{HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_HttpQueryParameterName{HttpQueryParameterName: "id"}},
+		{HashKey: &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if !proto.Equal(got[i], want[i]) {
+			t.Fatalf("key %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if got, err := ParseKeys(nil); got != nil || err != nil {
+		t.Fatalf("expected no hash keys, got %v %v", got, err)
+	}
+	for _, invalid := range []string{"", "header", "cookie:", "source-ip:x", "header:x-user,,source-ip", "path:/"} {
+		if _, err := ParseKeys(map[string]string{KeysAnnotation: invalid}); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -136,6 +136,10 @@ const (
 	RouteSemanticsIngress  = "ingress"
 	RouteSemanticsGateway  = "gateway"
 
+	// DNSSRVAnnotation makes istiod resolve the endpoints of a `resolution: DNS` ServiceEntry from DNS SRV records.
+	// The ServiceEntry must have a single port, as the SRV records carry a single port for each target.
+	DNSSRVAnnotation = "networking.istio.io/dns-srv"
+
 	// TrustworthyJWTPath is the default 3P token to authenticate with third party services
 	TrustworthyJWTPath = "./var/run/secrets/tokens/istio-token"
 
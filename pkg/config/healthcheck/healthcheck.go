@@ -0,0 +1,111 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package healthcheck provides the active health checking of the endpoints of ServiceEntries, set with the
+// Annotation. The proxies probe each endpoint, and stop sending traffic to the endpoints failing the probes.
+package healthcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Annotation makes the proxies actively health check the endpoints of a ServiceEntry. It is the JSON of the health
+// check, see Parse. It is ignored for the `resolution: NONE` ServiceEntries, as their traffic is not load balanced.
+// TODO: move to API
+const Annotation = "networking.istio.io/health-check"
+
+const (
+	defaultHealthCheckInterval           = 10 * time.Second
+	defaultHealthCheckTimeout            = time.Second
+	defaultHealthCheckHealthyThreshold   = 1
+	defaultHealthCheckUnhealthyThreshold = 3
+)
+
+// HealthCheck is the active health checking of the endpoints of a service. The proxies probe each endpoint,
+// and stop sending traffic to the endpoints failing the probes until they pass them again.
+type HealthCheck struct {
+	// HTTP probes the endpoints with HTTP requests, which must return a 200 status.
+	HTTP *HTTP
+	// TCP probes the endpoints by opening a connection, if HTTP is not set.
+	TCP bool
+	// Interval is the time between two probes of an endpoint.
+	Interval time.Duration
+	// Timeout is the time to wait for a probe to succeed.
+	Timeout time.Duration
+	// HealthyThreshold is the number of successful probes for an unhealthy endpoint to be healthy again.
+	HealthyThreshold uint32
+	// UnhealthyThreshold is the number of failed probes for a healthy endpoint to be unhealthy.
+	UnhealthyThreshold uint32
+}
+
+// HTTP is the HTTP request of a HealthCheck.
+type HTTP struct {
+	Path string `json:"path"`
+	// Host is the Host header of the request. It defaults to the hostname of the service.
+	Host string `json:"host,omitempty"`
+}
+
+type healthCheckJSON struct {
+	HTTP               *HTTP     `json:"http,omitempty"`
+	TCP                *struct{} `json:"tcp,omitempty"`
+	Interval           string    `json:"interval,omitempty"`
+	Timeout            string    `json:"timeout,omitempty"`
+	HealthyThreshold   uint32    `json:"healthyThreshold,omitempty"`
+	UnhealthyThreshold uint32    `json:"unhealthyThreshold,omitempty"`
+}
+
+// Parse parses a health check in JSON, for example
+// {"http": {"path": "/healthz"}, "interval": "5s", "timeout": "1s", "unhealthyThreshold": 2}. Exactly one of http
+// and tcp must be set. The interval, timeout and thresholds default to 10s, 1s, 1 and 3.
+func Parse(v string) (*HealthCheck, error) {
+	in := healthCheckJSON{}
+	if err := json.Unmarshal([]byte(v), &in); err != nil {
+		return nil, err
+	}
+	if (in.HTTP == nil) == (in.TCP == nil) {
+		return nil, fmt.Errorf("exactly one of http and tcp must be set")
+	}
+	if in.HTTP != nil && !strings.HasPrefix(in.HTTP.Path, "/") {
+		return nil, fmt.Errorf("http path %q must start with /", in.HTTP.Path)
+	}
+	hc := &HealthCheck{
+		HTTP:               in.HTTP,
+		TCP:                in.TCP != nil,
+		Interval:           defaultHealthCheckInterval,
+		Timeout:            defaultHealthCheckTimeout,
+		HealthyThreshold:   defaultHealthCheckHealthyThreshold,
+		UnhealthyThreshold: defaultHealthCheckUnhealthyThreshold,
+	}
+	var err error
+	if in.Interval != "" {
+		if hc.Interval, err = time.ParseDuration(in.Interval); err != nil || hc.Interval <= 0 {
+			return nil, fmt.Errorf("invalid interval %q", in.Interval)
+		}
+	}
+	if in.Timeout != "" {
+		if hc.Timeout, err = time.ParseDuration(in.Timeout); err != nil || hc.Timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q", in.Timeout)
+		}
+	}
+	if in.HealthyThreshold > 0 {
+		hc.HealthyThreshold = in.HealthyThreshold
+	}
+	if in.UnhealthyThreshold > 0 {
+		hc.UnhealthyThreshold = in.UnhealthyThreshold
+	}
+	return hc, nil
+}
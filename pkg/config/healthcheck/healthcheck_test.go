@@ -0,0 +1,74 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package healthcheck
+
+import (
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name  string
+		value string
+		want  *HealthCheck
+	}{
+		{
+			name:  "http",
+			value: `{"http": {"path": "/healthz", "host": "example.com"}, "interval": "5s", "unhealthyThreshold": 2}`,
+			want: &HealthCheck{
+				HTTP:               &HTTP{Path: "/healthz", Host: "example.com"},
+				Interval:           5 * time.Second,
+				Timeout:            time.Second,
+				HealthyThreshold:   1,
+				UnhealthyThreshold: 2,
+			},
+		},
+		{
+			name:  "tcp",
+			value: `{"tcp": {}, "timeout": "2s", "healthyThreshold": 2}`,
+			want: &HealthCheck{
+				TCP:                true,
+				Interval:           10 * time.Second,
+				Timeout:            2 * time.Second,
+				HealthyThreshold:   2,
+				UnhealthyThreshold: 3,
+			},
+		},
+		{name: "no probe", value: `{"interval": "5s"}`},
+		{name: "both probes", value: `{"http": {"path": "/healthz"}, "tcp": {}}`},
+		{name: "relative path", value: `{"http": {"path": "healthz"}}`},
+		{name: "invalid interval", value: `{"tcp": {}, "interval": "5"}`},
+		{name: "negative timeout", value: `{"tcp": {}, "timeout": "-1s"}`},
+		{name: "not json", value: `tcp`},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.value)
+			if tt.want == nil {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, got, tt.want)
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package listenerpatch
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+// FromEnvoyFilter converts the patches of an EnvoyFilter covered by a ListenerPatch. The reasons why the other
+// patches cannot be converted are returned by index, so that the EnvoyFilter can be kept for them. Patches matching
+// proxy versions or metadata are never converted, as a ListenerPatch does not depend on the proxy version.
+func FromEnvoyFilter(ef *networking.EnvoyFilter) (*ListenerPatch, map[int]string) {
+	p := &ListenerPatch{
+		WorkloadSelector: ef.GetWorkloadSelector().GetLabels(),
+		Priority:         ef.GetPriority(),
+	}
+	unconverted := map[int]string{}
+	for i, cp := range ef.GetConfigPatches() {
+		if err := p.convert(cp); err != nil {
+			unconverted[i] = err.Error()
+		}
+	}
+	return p, unconverted
+}
+
+func (p *ListenerPatch) convert(cp *networking.EnvoyFilter_EnvoyConfigObjectPatch) error {
+	match := cp.GetMatch()
+	if match.GetProxy() != nil {
+		return fmt.Errorf("proxy matches are not supported")
+	}
+	ctx, err := contextOf(match.GetContext())
+	if err != nil {
+		return err
+	}
+	value := cp.GetPatch().GetValue().AsMap()
+	switch cp.GetApplyTo() {
+	case networking.EnvoyFilter_HTTP_FILTER:
+		f, err := httpFilterOf(ctx, match.GetListener(), cp.GetPatch().GetOperation(), value)
+		if err != nil {
+			return err
+		}
+		p.HTTPFilters = append(p.HTTPFilters, f)
+	case networking.EnvoyFilter_LISTENER:
+		options, err := socketOptionsOf(ctx, match.GetListener(), cp.GetPatch().GetOperation(), value)
+		if err != nil {
+			return err
+		}
+		p.SocketOptions = append(p.SocketOptions, options...)
+	case networking.EnvoyFilter_CLUSTER:
+		cbs, err := circuitBreakersOf(ctx, match.GetCluster(), cp.GetPatch().GetOperation(), value)
+		if err != nil {
+			return err
+		}
+		p.CircuitBreakers = append(p.CircuitBreakers, cbs...)
+	default:
+		return fmt.Errorf("%s patches are not supported", cp.GetApplyTo())
+	}
+	return nil
+}
+
+func contextOf(c networking.EnvoyFilter_PatchContext) (Context, error) {
+	for ctx, pc := range contexts {
+		if pc == c {
+			return ctx, nil
+		}
+	}
+	return "", fmt.Errorf("context %s is not supported", c)
+}
+
+// onlyKeys returns an error if the value has other keys than the allowed ones.
+func onlyKeys(what string, value map[string]any, allowed ...string) error {
+	extra := []string{}
+	for k := range value {
+		found := false
+		for _, a := range allowed {
+			if k == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			extra = append(extra, k)
+		}
+	}
+	if len(extra) > 0 {
+		sort.Strings(extra)
+		return fmt.Errorf("%s fields %v are not supported", what, extra)
+	}
+	return nil
+}
+
+func httpFilterOf(ctx Context, l *networking.EnvoyFilter_ListenerMatch, op networking.EnvoyFilter_Patch_Operation,
+	value map[string]any,
+) (HTTPFilter, error) {
+	f := HTTPFilter{Context: ctx, Port: l.GetPortNumber()}
+	if l.GetName() != "" || l.GetListenerFilter() != "" {
+		return f, fmt.Errorf("listener names and listener filters are not supported")
+	}
+	chain := l.GetFilterChain()
+	if chain.GetName() != "" || chain.GetSni() != "" || chain.GetTransportProtocol() != "" ||
+		chain.GetApplicationProtocols() != "" || chain.GetDestinationPort() != 0 {
+		return f, fmt.Errorf("filter chain matches other than the filter are not supported")
+	}
+	if name := chain.GetFilter().GetName(); name != "" && name != wellknown.HTTPConnectionManager {
+		return f, fmt.Errorf("HTTP filters can only be inserted in %s", wellknown.HTTPConnectionManager)
+	}
+	sub := chain.GetFilter().GetSubFilter().GetName()
+	switch op {
+	case networking.EnvoyFilter_Patch_INSERT_FIRST:
+		if sub != "" {
+			return f, fmt.Errorf("%s with a sub filter is not supported", op)
+		}
+	case networking.EnvoyFilter_Patch_INSERT_BEFORE:
+		f.Before = sub
+	case networking.EnvoyFilter_Patch_INSERT_AFTER:
+		f.After = sub
+	default:
+		return f, fmt.Errorf("operation %s is not supported for HTTP filters", op)
+	}
+	if (op == networking.EnvoyFilter_Patch_INSERT_BEFORE || op == networking.EnvoyFilter_Patch_INSERT_AFTER) && sub == "" {
+		return f, fmt.Errorf("%s without a sub filter is not supported", op)
+	}
+	if err := onlyKeys("filter", value, "name", "typed_config"); err != nil {
+		return f, err
+	}
+	f.Name, _ = value["name"].(string)
+	f.TypedConfig, _ = value["typed_config"].(map[string]any)
+	return f, f.validate()
+}
+
+func socketOptionsOf(ctx Context, l *networking.EnvoyFilter_ListenerMatch, op networking.EnvoyFilter_Patch_Operation,
+	value map[string]any,
+) ([]SocketOption, error) {
+	if op != networking.EnvoyFilter_Patch_MERGE {
+		return nil, fmt.Errorf("operation %s is not supported for listeners", op)
+	}
+	if l.GetName() != "" || l.GetListenerFilter() != "" || l.GetFilterChain() != nil {
+		return nil, fmt.Errorf("listener matches other than the port are not supported")
+	}
+	if err := onlyKeys("listener", value, "socket_options"); err != nil {
+		return nil, err
+	}
+	raw, ok := value["socket_options"].([]any)
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("socket_options must be a non empty list")
+	}
+	options := make([]SocketOption, 0, len(raw))
+	for i, r := range raw {
+		m, ok := r.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("socket_options[%d] must be an object", i)
+		}
+		if err := onlyKeys(fmt.Sprintf("socket_options[%d]", i), m, "level", "name", "int_value", "buf_value", "state"); err != nil {
+			return nil, err
+		}
+		o := SocketOption{Context: ctx, Port: l.GetPortNumber()}
+		o.Level, _ = integer(m["level"])
+		o.Name, _ = integer(m["name"])
+		if v, ok := integer(m["int_value"]); ok {
+			o.IntValue = &v
+		}
+		o.BufValue, _ = m["buf_value"].(string)
+		state, _ := m["state"].(string)
+		o.State = SocketState(state)
+		if err := o.validate(); err != nil {
+			return nil, fmt.Errorf("socket_options[%d]: %v", i, err)
+		}
+		options = append(options, o)
+	}
+	return options, nil
+}
+
+var thresholdFields = []string{"priority", "max_connections", "max_pending_requests", "max_requests", "max_retries"}
+
+func circuitBreakersOf(ctx Context, c *networking.EnvoyFilter_ClusterMatch, op networking.EnvoyFilter_Patch_Operation,
+	value map[string]any,
+) ([]CircuitBreaker, error) {
+	if op != networking.EnvoyFilter_Patch_MERGE {
+		return nil, fmt.Errorf("operation %s is not supported for clusters", op)
+	}
+	if c.GetName() != "" || c.GetService() == "" {
+		return nil, fmt.Errorf("only clusters matched by service are supported")
+	}
+	if err := onlyKeys("cluster", value, "circuit_breakers"); err != nil {
+		return nil, err
+	}
+	breakers, _ := value["circuit_breakers"].(map[string]any)
+	if err := onlyKeys("circuit_breakers", breakers, "thresholds"); err != nil {
+		return nil, err
+	}
+	raw, ok := breakers["thresholds"].([]any)
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("circuit_breakers.thresholds must be a non empty list")
+	}
+	cbs := make([]CircuitBreaker, 0, len(raw))
+	for i, r := range raw {
+		m, ok := r.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("thresholds[%d] must be an object", i)
+		}
+		if err := onlyKeys(fmt.Sprintf("thresholds[%d]", i), m, thresholdFields...); err != nil {
+			return nil, err
+		}
+		cb := CircuitBreaker{Service: c.GetService(), Port: c.GetPortNumber(), Subset: c.GetSubset()}
+		if ctx != SidecarOutbound {
+			cb.Context = ctx
+		}
+		cb.Priority, _ = m["priority"].(string)
+		for field, t := range map[string]**uint32{
+			"max_connections":      &cb.MaxConnections,
+			"max_pending_requests": &cb.MaxPendingRequests,
+			"max_requests":         &cb.MaxRequests,
+			"max_retries":          &cb.MaxRetries,
+		} {
+			v, f := m[field]
+			if !f {
+				continue
+			}
+			n, ok := integer(v)
+			if !ok || n < 0 || n > int64(^uint32(0)) {
+				return nil, fmt.Errorf("thresholds[%d]: invalid %s %v", i, field, v)
+			}
+			u := uint32(n)
+			*t = &u
+		}
+		if err := cb.validate(); err != nil {
+			return nil, fmt.Errorf("thresholds[%d]: %v", i, err)
+		}
+		cbs = append(cbs, cb)
+	}
+	return cbs, nil
+}
+
+// integer returns the value of an integer of a Struct, which are numbers, or strings for the 64 bits integers.
+func integer(v any) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), float64(int64(n)) == n
+	case string:
+		var i int64
+		_, err := fmt.Sscan(n, &i)
+		return i, err == nil
+	}
+	return 0, false
+}
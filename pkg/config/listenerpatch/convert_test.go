@@ -0,0 +1,90 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package listenerpatch
+
+import (
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestFromEnvoyFilter(t *testing.T) {
+	p := &ListenerPatch{
+		WorkloadSelector: map[string]string{"app": "reviews"},
+		Priority:         10,
+		HTTPFilters: []HTTPFilter{
+			{Context: SidecarInbound, Port: 9080, Name: "envoy.filters.http.lua", Before: "envoy.filters.http.router", TypedConfig: luaConfig},
+			{Context: Gateway, Name: "envoy.filters.http.lua", TypedConfig: luaConfig},
+		},
+		SocketOptions: []SocketOption{{Context: Gateway, Level: 1, Name: 9, IntValue: int64Ptr(1), State: StateListening}},
+		CircuitBreakers: []CircuitBreaker{{
+			Service: "ratings.default.svc.cluster.local", Port: 9080, Priority: "DEFAULT", MaxConnections: uint32Ptr(100), MaxRetries: uint32Ptr(0),
+		}},
+	}
+	patches, err := p.ConfigPatches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ef := &networking.EnvoyFilter{
+		WorkloadSelector: &networking.WorkloadSelector{Labels: p.WorkloadSelector},
+		Priority:         p.Priority,
+		ConfigPatches:    patches,
+	}
+	unsupported := []*networking.EnvoyFilter_EnvoyConfigObjectPatch{
+		{
+			ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+			Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context: networking.EnvoyFilter_SIDECAR_INBOUND,
+				Proxy:   &networking.EnvoyFilter_ProxyMatch{ProxyVersion: "^1\\.16.*"},
+			},
+			Patch: ef.ConfigPatches[0].Patch,
+		},
+		{
+			ApplyTo: networking.EnvoyFilter_ROUTE_CONFIGURATION,
+			Match:   &networking.EnvoyFilter_EnvoyConfigObjectMatch{Context: networking.EnvoyFilter_SIDECAR_OUTBOUND},
+			Patch:   &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_MERGE},
+		},
+		{
+			ApplyTo: networking.EnvoyFilter_CLUSTER,
+			Match:   ef.ConfigPatches[len(ef.ConfigPatches)-1].Match,
+			Patch:   &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_REMOVE},
+		},
+		{
+			ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+			Match:   &networking.EnvoyFilter_EnvoyConfigObjectMatch{Context: networking.EnvoyFilter_ANY},
+			Patch:   ef.ConfigPatches[0].Patch,
+		},
+	}
+	ef.ConfigPatches = append(ef.ConfigPatches, unsupported...)
+
+	converted, unconverted := FromEnvoyFilter(ef)
+	assert.Equal(t, converted, p)
+	reasons := map[int]string{
+		4: "proxy matches are not supported",
+		5: "ROUTE_CONFIGURATION patches are not supported",
+		6: "operation REMOVE is not supported for clusters",
+		7: "context ANY is not supported",
+	}
+	if len(unconverted) != len(reasons) {
+		t.Fatalf("expected %d unconverted patches, got %v", len(reasons), unconverted)
+	}
+	for i, reason := range reasons {
+		if !strings.Contains(unconverted[i], reason) {
+			t.Errorf("patch %d: expected %q, got %q", i, reason, unconverted[i])
+		}
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package listenerpatch provides a typed API for the most common EnvoyFilter use cases: inserting HTTP filters
+// relative to a named filter, setting listener socket options and tuning cluster circuit breakers. Unlike raw
+// EnvoyFilter patches, a ListenerPatch is fully validated, by the validation webhook when set on an EnvoyFilter
+// with the Annotation, before being compiled to EnvoyFilter patches.
+package listenerpatch
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/hashicorp/go-multierror"
+	"google.golang.org/protobuf/types/known/structpb"
+	"sigs.k8s.io/yaml"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/labels"
+)
+
+// Annotation sets a ListenerPatch, in YAML or JSON, on an EnvoyFilter. istiod compiles it to the patches of the
+// EnvoyFilter, which must have no configPatches of its own; the workloadSelector and priority of the EnvoyFilter
+// are used, so they must not be set in the ListenerPatch. The circuit breaker thresholds of the patch are merged
+// into the thresholds of the same priority generated from the DestinationRules.
+const Annotation = "networking.istio.io/listener-patch"
+
+// Parse parses and validates the ListenerPatch of an Annotation, rejecting unknown fields.
+func Parse(s string) (*ListenerPatch, error) {
+	p := &ListenerPatch{}
+	if err := yaml.UnmarshalStrict([]byte(s), p); err != nil {
+		return nil, fmt.Errorf("invalid listener patch: %v", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ListenerPatch is a typed set of changes to the configuration generated for the selected workloads.
+type ListenerPatch struct {
+	// WorkloadSelector selects the workloads the patch applies to. All workloads of the namespace if empty.
+	WorkloadSelector map[string]string `json:"workloadSelector,omitempty"`
+	// Priority is the priority of the generated EnvoyFilter.
+	Priority int32 `json:"priority,omitempty"`
+
+	HTTPFilters     []HTTPFilter     `json:"httpFilters,omitempty"`
+	SocketOptions   []SocketOption   `json:"socketOptions,omitempty"`
+	CircuitBreakers []CircuitBreaker `json:"circuitBreakers,omitempty"`
+}
+
+// Context is the traffic direction of the listeners or clusters a patch applies to.
+type Context string
+
+const (
+	SidecarInbound  Context = "SIDECAR_INBOUND"
+	SidecarOutbound Context = "SIDECAR_OUTBOUND"
+	Gateway         Context = "GATEWAY"
+)
+
+var contexts = map[Context]networking.EnvoyFilter_PatchContext{
+	SidecarInbound:  networking.EnvoyFilter_SIDECAR_INBOUND,
+	SidecarOutbound: networking.EnvoyFilter_SIDECAR_OUTBOUND,
+	Gateway:         networking.EnvoyFilter_GATEWAY,
+}
+
+// HTTPFilter inserts an HTTP filter in the HTTP connection manager of the matching listeners.
+// The filter is inserted before or after the named filter, or first if neither is set.
+type HTTPFilter struct {
+	Context Context `json:"context"`
+	// Port restricts the patch to the listeners of the port. All listeners if unset.
+	Port uint32 `json:"port,omitempty"`
+	// Name is the name of the inserted filter, e.g. envoy.filters.http.ext_authz.
+	Name   string `json:"name"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	// TypedConfig is the configuration of the filter, in the JSON form of google.protobuf.Any.
+	TypedConfig map[string]any `json:"typedConfig"`
+}
+
+// SocketState is the state of the listener socket at which a socket option is set.
+type SocketState string
+
+const (
+	StatePrebind   SocketState = "STATE_PREBIND"
+	StateBound     SocketState = "STATE_BOUND"
+	StateListening SocketState = "STATE_LISTENING"
+)
+
+// SocketOption sets a socket option on the matching listeners. Exactly one of IntValue and BufValue must be set.
+type SocketOption struct {
+	Context  Context     `json:"context"`
+	Port     uint32      `json:"port,omitempty"`
+	Level    int64       `json:"level"`
+	Name     int64       `json:"name"`
+	IntValue *int64      `json:"intValue,omitempty"`
+	BufValue string      `json:"bufValue,omitempty"`
+	State    SocketState `json:"state,omitempty"`
+}
+
+// CircuitBreaker overrides circuit breaker thresholds of the clusters of a service. Unset thresholds keep the
+// values generated from the DestinationRules.
+type CircuitBreaker struct {
+	// Context defaults to SIDECAR_OUTBOUND.
+	Context Context `json:"context,omitempty"`
+	Service string  `json:"service"`
+	Port    uint32  `json:"port,omitempty"`
+	Subset  string  `json:"subset,omitempty"`
+	// Priority is the routing priority of the thresholds, DEFAULT or HIGH. Defaults to DEFAULT.
+	Priority           string  `json:"priority,omitempty"`
+	MaxConnections     *uint32 `json:"maxConnections,omitempty"`
+	MaxPendingRequests *uint32 `json:"maxPendingRequests,omitempty"`
+	MaxRequests        *uint32 `json:"maxRequests,omitempty"`
+	MaxRetries         *uint32 `json:"maxRetries,omitempty"`
+}
+
+// Validate checks the patch, returning all the errors found.
+func (p *ListenerPatch) Validate() error {
+	var errs error
+	if len(p.HTTPFilters)+len(p.SocketOptions)+len(p.CircuitBreakers) == 0 {
+		errs = multierror.Append(errs, fmt.Errorf("listener patch: at least one of httpFilters, socketOptions or circuitBreakers must be set"))
+	}
+	if err := labels.Instance(p.WorkloadSelector).Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("listener patch: invalid workloadSelector: %v", err))
+	}
+	for i, f := range p.HTTPFilters {
+		if err := f.validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("httpFilters[%d]: %v", i, err))
+		}
+	}
+	for i, o := range p.SocketOptions {
+		if err := o.validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("socketOptions[%d]: %v", i, err))
+		}
+	}
+	for i, cb := range p.CircuitBreakers {
+		if err := cb.validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("circuitBreakers[%d]: %v", i, err))
+		}
+	}
+	return errs
+}
+
+func validateContext(c Context) error {
+	if _, f := contexts[c]; !f {
+		return fmt.Errorf("invalid context %q, must be one of %s, %s or %s", c, SidecarInbound, SidecarOutbound, Gateway)
+	}
+	return nil
+}
+
+func (f HTTPFilter) validate() error {
+	if err := validateContext(f.Context); err != nil {
+		return err
+	}
+	if f.Name == "" {
+		return fmt.Errorf("name must be set")
+	}
+	if f.Name == wellknown.Router {
+		return fmt.Errorf("the router filter cannot be inserted")
+	}
+	if f.Before != "" && f.After != "" {
+		return fmt.Errorf("only one of before and after can be set")
+	}
+	if f.After == wellknown.Router {
+		return fmt.Errorf("filters cannot be inserted after the router filter, which must be the last one")
+	}
+	if f.Before == f.Name || f.After == f.Name {
+		return fmt.Errorf("filter %q cannot be inserted relative to itself", f.Name)
+	}
+	if t, ok := f.TypedConfig["@type"].(string); !ok || t == "" {
+		return fmt.Errorf("typedConfig must be set and contain @type")
+	}
+	return nil
+}
+
+func (o SocketOption) validate() error {
+	if err := validateContext(o.Context); err != nil {
+		return err
+	}
+	if (o.IntValue == nil) == (o.BufValue == "") {
+		return fmt.Errorf("exactly one of intValue and bufValue must be set")
+	}
+	switch o.State {
+	case "", StatePrebind, StateBound, StateListening:
+	default:
+		return fmt.Errorf("invalid state %q, must be one of %s, %s or %s", o.State, StatePrebind, StateBound, StateListening)
+	}
+	if o.Level < 0 || o.Name < 0 {
+		return fmt.Errorf("level and name must not be negative")
+	}
+	return nil
+}
+
+func (cb CircuitBreaker) validate() error {
+	if cb.Context != "" {
+		if err := validateContext(cb.Context); err != nil {
+			return err
+		}
+		if cb.Context == SidecarInbound {
+			return fmt.Errorf("circuit breakers can only be set on %s or %s clusters", SidecarOutbound, Gateway)
+		}
+	}
+	if cb.Service == "" {
+		return fmt.Errorf("service must be set")
+	}
+	switch cb.Priority {
+	case "", "DEFAULT", "HIGH":
+	default:
+		return fmt.Errorf("invalid priority %q, must be DEFAULT or HIGH", cb.Priority)
+	}
+	if cb.MaxConnections == nil && cb.MaxPendingRequests == nil && cb.MaxRequests == nil && cb.MaxRetries == nil {
+		return fmt.Errorf("at least one threshold must be set")
+	}
+	return nil
+}
+
+// ToEnvoyFilter compiles the patch to an EnvoyFilter, with the patch in its Annotation. The patch must be valid.
+func (p *ListenerPatch) ToEnvoyFilter() (*networking.EnvoyFilter, map[string]string, error) {
+	if err := p.Validate(); err != nil {
+		return nil, nil, err
+	}
+	ef := &networking.EnvoyFilter{Priority: p.Priority}
+	if len(p.WorkloadSelector) > 0 {
+		ef.WorkloadSelector = &networking.WorkloadSelector{Labels: p.WorkloadSelector}
+	}
+	annotated := *p
+	annotated.WorkloadSelector, annotated.Priority = nil, 0
+	b, err := yaml.Marshal(annotated)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ef, map[string]string{Annotation: string(b)}, nil
+}
+
+// ConfigPatches compiles the patch to EnvoyFilter patches. The patch must be valid.
+func (p *ListenerPatch) ConfigPatches() ([]*networking.EnvoyFilter_EnvoyConfigObjectPatch, error) {
+	var patches []*networking.EnvoyFilter_EnvoyConfigObjectPatch
+	for _, f := range p.HTTPFilters {
+		value, err := ToStruct(map[string]any{"name": f.Name, "typed_config": f.TypedConfig})
+		if err != nil {
+			return nil, err
+		}
+		filter := &networking.EnvoyFilter_ListenerMatch_FilterMatch{Name: wellknown.HTTPConnectionManager}
+		op := networking.EnvoyFilter_Patch_INSERT_FIRST
+		switch {
+		case f.Before != "":
+			filter.SubFilter = &networking.EnvoyFilter_ListenerMatch_SubFilterMatch{Name: f.Before}
+			op = networking.EnvoyFilter_Patch_INSERT_BEFORE
+		case f.After != "":
+			filter.SubFilter = &networking.EnvoyFilter_ListenerMatch_SubFilterMatch{Name: f.After}
+			op = networking.EnvoyFilter_Patch_INSERT_AFTER
+		}
+		patches = append(patches, &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+			Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context: contexts[f.Context],
+				ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+					Listener: &networking.EnvoyFilter_ListenerMatch{
+						PortNumber:  f.Port,
+						FilterChain: &networking.EnvoyFilter_ListenerMatch_FilterChainMatch{Filter: filter},
+					},
+				},
+			},
+			Patch: &networking.EnvoyFilter_Patch{Operation: op, Value: value},
+		})
+	}
+	for _, o := range p.SocketOptions {
+		option := map[string]any{"level": o.Level, "name": o.Name}
+		if o.IntValue != nil {
+			option["int_value"] = *o.IntValue
+		} else {
+			option["buf_value"] = o.BufValue
+		}
+		if o.State != "" {
+			option["state"] = o.State
+		}
+		value, err := ToStruct(map[string]any{"socket_options": []any{option}})
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_LISTENER,
+			Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context: contexts[o.Context],
+				ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+					Listener: &networking.EnvoyFilter_ListenerMatch{PortNumber: o.Port},
+				},
+			},
+			Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_MERGE, Value: value},
+		})
+	}
+	for _, cb := range p.CircuitBreakers {
+		ctx := cb.Context
+		if ctx == "" {
+			ctx = SidecarOutbound
+		}
+		priority := cb.Priority
+		if priority == "" {
+			priority = "DEFAULT"
+		}
+		thresholds := map[string]any{"priority": priority}
+		for name, v := range map[string]*uint32{
+			"max_connections":      cb.MaxConnections,
+			"max_pending_requests": cb.MaxPendingRequests,
+			"max_requests":         cb.MaxRequests,
+			"max_retries":          cb.MaxRetries,
+		} {
+			if v != nil {
+				thresholds[name] = *v
+			}
+		}
+		value, err := ToStruct(map[string]any{"circuit_breakers": map[string]any{"thresholds": []any{thresholds}}})
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_CLUSTER,
+			Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context: contexts[ctx],
+				ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Cluster{
+					Cluster: &networking.EnvoyFilter_ClusterMatch{Service: cb.Service, PortNumber: cb.Port, Subset: cb.Subset},
+				},
+			},
+			Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_MERGE, Value: value},
+		})
+	}
+	return patches, nil
+}
+
+// ToStruct converts a value to a Struct through JSON, so that integers of any type are accepted. It is shared with
+// the other typed APIs compiled to EnvoyFilter patches.
+func ToStruct(v map[string]any) (*structpb.Struct, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
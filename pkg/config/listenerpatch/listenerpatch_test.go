@@ -0,0 +1,198 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package listenerpatch
+
+import (
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+var luaConfig = map[string]any{"@type": "type.googleapis.com/envoy.extensions.filters.http.lua.v3.Lua"}
+
+func uint32Ptr(v uint32) *uint32 { return &v }
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name  string
+		patch ListenerPatch
+		err   string
+	}{
+		{
+			name: "valid",
+			patch: ListenerPatch{
+				WorkloadSelector: map[string]string{"app": "reviews"},
+				HTTPFilters: []HTTPFilter{{
+					Context: SidecarInbound, Name: "envoy.filters.http.lua", Before: "envoy.filters.http.router", TypedConfig: luaConfig,
+				}},
+				SocketOptions:   []SocketOption{{Context: Gateway, Port: 8443, Level: 1, Name: 9, IntValue: int64Ptr(1)}},
+				CircuitBreakers: []CircuitBreaker{{Service: "ratings.default.svc.cluster.local", MaxConnections: uint32Ptr(100)}},
+			},
+		},
+		{name: "empty", patch: ListenerPatch{}, err: "at least one of"},
+		{
+			name: "after router",
+			patch: ListenerPatch{HTTPFilters: []HTTPFilter{{
+				Context: SidecarInbound, Name: "envoy.filters.http.lua", After: "envoy.filters.http.router", TypedConfig: luaConfig,
+			}}},
+			err: "httpFilters[0]: filters cannot be inserted after the router filter",
+		},
+		{
+			name: "before and after",
+			patch: ListenerPatch{HTTPFilters: []HTTPFilter{{
+				Context: SidecarInbound, Name: "envoy.filters.http.lua", Before: "a", After: "b", TypedConfig: luaConfig,
+			}}},
+			err: "only one of before and after",
+		},
+		{
+			name:  "missing type",
+			patch: ListenerPatch{HTTPFilters: []HTTPFilter{{Context: SidecarInbound, Name: "envoy.filters.http.lua"}}},
+			err:   "typedConfig must be set",
+		},
+		{
+			name:  "invalid context",
+			patch: ListenerPatch{HTTPFilters: []HTTPFilter{{Context: "ANY", Name: "envoy.filters.http.lua", TypedConfig: luaConfig}}},
+			err:   `invalid context "ANY"`,
+		},
+		{
+			name:  "socket option without value",
+			patch: ListenerPatch{SocketOptions: []SocketOption{{Context: Gateway, Level: 1, Name: 9}}},
+			err:   "socketOptions[0]: exactly one of intValue and bufValue",
+		},
+		{
+			name:  "circuit breaker without thresholds",
+			patch: ListenerPatch{CircuitBreakers: []CircuitBreaker{{Service: "ratings.default.svc.cluster.local"}}},
+			err:   "circuitBreakers[0]: at least one threshold",
+		},
+		{
+			name: "inbound circuit breaker",
+			patch: ListenerPatch{CircuitBreakers: []CircuitBreaker{{
+				Context: SidecarInbound, Service: "ratings.default.svc.cluster.local", MaxRetries: uint32Ptr(3),
+			}}},
+			err: "circuit breakers can only be set",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.patch.Validate()
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("expected error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestConfigPatches(t *testing.T) {
+	p := &ListenerPatch{
+		WorkloadSelector: map[string]string{"app": "reviews"},
+		HTTPFilters: []HTTPFilter{{
+			Context: SidecarInbound, Port: 9080, Name: "envoy.filters.http.lua", Before: "envoy.filters.http.router", TypedConfig: luaConfig,
+		}},
+		SocketOptions:   []SocketOption{{Context: Gateway, Level: 1, Name: 9, IntValue: int64Ptr(1), State: StateListening}},
+		CircuitBreakers: []CircuitBreaker{{Service: "ratings.default.svc.cluster.local", Port: 9080, MaxConnections: uint32Ptr(100)}},
+	}
+	patches, err := p.ConfigPatches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patches) != 3 {
+		t.Fatalf("expected 3 patches, got %d", len(patches))
+	}
+
+	filter := patches[0]
+	if filter.ApplyTo != networking.EnvoyFilter_HTTP_FILTER || filter.Patch.Operation != networking.EnvoyFilter_Patch_INSERT_BEFORE ||
+		filter.Match.Context != networking.EnvoyFilter_SIDECAR_INBOUND {
+		t.Fatalf("unexpected http filter patch: %v", filter)
+	}
+	lm := filter.Match.GetListener()
+	if lm.PortNumber != 9080 || lm.FilterChain.Filter.Name != "envoy.filters.network.http_connection_manager" ||
+		lm.FilterChain.Filter.SubFilter.Name != "envoy.filters.http.router" {
+		t.Fatalf("unexpected http filter match: %v", lm)
+	}
+	if got := filter.Patch.Value.Fields["name"].GetStringValue(); got != "envoy.filters.http.lua" {
+		t.Fatalf("unexpected filter name %q", got)
+	}
+
+	socket := patches[1]
+	if socket.ApplyTo != networking.EnvoyFilter_LISTENER || socket.Patch.Operation != networking.EnvoyFilter_Patch_MERGE {
+		t.Fatalf("unexpected socket option patch: %v", socket)
+	}
+	option := socket.Patch.Value.Fields["socket_options"].GetListValue().GetValues()[0].GetStructValue()
+	if option.Fields["int_value"].GetNumberValue() != 1 || option.Fields["state"].GetStringValue() != "STATE_LISTENING" {
+		t.Fatalf("unexpected socket option: %v", option)
+	}
+
+	cb := patches[2]
+	if cb.ApplyTo != networking.EnvoyFilter_CLUSTER || cb.Match.Context != networking.EnvoyFilter_SIDECAR_OUTBOUND ||
+		cb.Match.GetCluster().Service != "ratings.default.svc.cluster.local" || cb.Match.GetCluster().PortNumber != 9080 {
+		t.Fatalf("unexpected circuit breaker patch: %v", cb)
+	}
+	thresholds := cb.Patch.Value.Fields["circuit_breakers"].GetStructValue().Fields["thresholds"].GetListValue().GetValues()[0].GetStructValue()
+	if thresholds.Fields["max_connections"].GetNumberValue() != 100 || thresholds.Fields["priority"].GetStringValue() != "DEFAULT" {
+		t.Fatalf("unexpected thresholds: %v", thresholds)
+	}
+	if _, f := thresholds.Fields["max_retries"]; f {
+		t.Fatalf("unset thresholds should not be patched: %v", thresholds)
+	}
+}
+
+func TestToEnvoyFilter(t *testing.T) {
+	p := &ListenerPatch{
+		WorkloadSelector: map[string]string{"app": "reviews"},
+		Priority:         10,
+		CircuitBreakers:  []CircuitBreaker{{Service: "ratings.default.svc.cluster.local", MaxConnections: uint32Ptr(100)}},
+	}
+	ef, annotations, err := p.ToEnvoyFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ef.GetWorkloadSelector().GetLabels()["app"] != "reviews" || ef.Priority != 10 || len(ef.ConfigPatches) != 0 {
+		t.Fatalf("unexpected EnvoyFilter: %v", ef)
+	}
+	parsed, err := Parse(annotations[Annotation])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.WorkloadSelector != nil || parsed.Priority != 0 || len(parsed.CircuitBreakers) != 1 {
+		t.Fatalf("expected the annotation to hold the patches only, got %v", annotations[Annotation])
+	}
+
+	if _, _, err := (&ListenerPatch{}).ToEnvoyFilter(); err == nil {
+		t.Fatalf("expected invalid patch to be rejected")
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse(`circuitBreakers:
+- service: ratings.default.svc.cluster.local
+  maxConnection: 100
+`); err == nil || !strings.Contains(err.Error(), "invalid listener patch") {
+		t.Fatalf("expected unknown fields to be rejected, got %v", err)
+	}
+	if _, err := Parse(`circuitBreakers:
+- service: ratings.default.svc.cluster.local
+`); err == nil || !strings.Contains(err.Error(), "at least one threshold") {
+		t.Fatalf("expected invalid patch to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locality
+
+import (
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// EjectionPolicyAnnotation controls how traffic fails back to a locality once its endpoints are reintroduced, for a
+// DestinationRule with outlier detection. The value is a YAML EjectionPolicy, for example:
+//
+//	networking.istio.io/locality-ejection-policy: |
+//	  failback:
+//	    gradual: true
+//	    maxEjectionTime: 60s
+//
+// The traffic moves back to a recovering locality as its endpoints are reintroduced, and endpoints failing
+// repeatedly stay ejected for at most a minute.
+//
+// The share of the endpoints of a locality that can be ejected cannot be capped: Envoy only caps the ejected
+// endpoints of the whole cluster, with the maxEjectionPercent of the outlier detection. The minHealthPercent of the
+// outlier detection does not cap the ejections either: it is the panic threshold of each priority, below which the
+// traffic of the priority is balanced over all its endpoints, ejected or not. A priority groups the localities at
+// the same distance from the proxy, not a single locality.
+const EjectionPolicyAnnotation = "networking.istio.io/locality-ejection-policy"
+
+// EjectionPolicy is the outlier ejection policy of the localities of a service.
+type EjectionPolicy struct {
+	// Failback controls how the traffic moves back to a locality after its endpoints were ejected.
+	Failback *Failback `json:"failback,omitempty"`
+}
+
+// Failback controls how the traffic moves back to a locality after its endpoints were ejected.
+type Failback struct {
+	// Gradual moves the traffic of a locality back in proportion to its endpoints reintroduced, rather than all
+	// at once when most of them are. Without it, a locality keeps all its traffic until about 30% of its
+	// endpoints are ejected, and takes it all back when they return.
+	Gradual bool `json:"gradual,omitempty"`
+	// MaxEjectionTime bounds the ejection time of the endpoints, which grows each time they are ejected again. It
+	// is a duration such as "60s".
+	MaxEjectionTime string `json:"maxEjectionTime,omitempty"`
+}
+
+// MaxEjectionDuration returns the maximum ejection time of the endpoints, or zero if it is not bounded.
+func (f *Failback) MaxEjectionDuration() time.Duration {
+	if f == nil || f.MaxEjectionTime == "" {
+		return 0
+	}
+	d, _ := time.ParseDuration(f.MaxEjectionTime)
+	return d
+}
+
+// ParseEjectionPolicy returns the locality ejection policy configured in the annotations, if any.
+func ParseEjectionPolicy(annotations map[string]string) (*EjectionPolicy, error) {
+	value, f := annotations[EjectionPolicyAnnotation]
+	if !f {
+		return nil, nil
+	}
+	policy := &EjectionPolicy{}
+	if err := yaml.UnmarshalStrict([]byte(value), policy); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", EjectionPolicyAnnotation, err)
+	}
+	if policy.Failback != nil && policy.Failback.MaxEjectionTime != "" {
+		if d, err := time.ParseDuration(policy.Failback.MaxEjectionTime); err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid %s annotation: maxEjectionTime must be a positive duration", EjectionPolicyAnnotation)
+		}
+	}
+	return policy, nil
+}
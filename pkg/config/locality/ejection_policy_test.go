@@ -0,0 +1,53 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locality
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEjectionPolicy(t *testing.T) {
+	got, err := ParseEjectionPolicy(map[string]string{EjectionPolicyAnnotation: `
+failback:
+  gradual: true
+  maxEjectionTime: 60s
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &EjectionPolicy{Failback: &Failback{Gradual: true, MaxEjectionTime: "60s"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if d := got.Failback.MaxEjectionDuration(); d != time.Minute {
+		t.Fatalf("got max ejection time %v, want 1m", d)
+	}
+
+	if got, err := ParseEjectionPolicy(nil); got != nil || err != nil {
+		t.Fatalf("expected no ejection policy, got %v %v", got, err)
+	}
+	for _, invalid := range []string{
+		// The share of the endpoints ejected per locality is limited by minHealthPercent.
+		"maxEjectionPercentPerLocality: 50",
+		"failback: {maxEjectionTime: 1m30}",
+		"failback: {maxEjectionTime: -1s}",
+	} {
+		if _, err := ParseEjectionPolicy(map[string]string{EjectionPolicyAnnotation: invalid}); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
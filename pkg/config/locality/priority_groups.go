@@ -0,0 +1,59 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locality
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// PriorityGroupsAnnotation configures tiered locality failover for a DestinationRule. The value is a YAML
+// list of PriorityGroups, for example:
+//
+//	networking.istio.io/locality-priority-groups: |
+//	  - from: us-east
+//	    groups:
+//	    - us-east/*: 100
+//	    - us-central/*: 70
+//	      us-west/*: 30
+//	    - "*": 100
+//
+// Clients in us-east send all traffic to us-east while it is healthy, then fail over to us-central and us-west
+// with a 70/30 split, and to any other locality last.
+const PriorityGroupsAnnotation = "networking.istio.io/locality-priority-groups"
+
+// PriorityGroups is the failover order of the localities of a service for clients in the From locality.
+type PriorityGroups struct {
+	// From is the locality of the clients, in the same format as the `from` field of locality distribute settings.
+	From string `json:"from"`
+	// Groups are ordered from the highest to the lowest priority. Each group maps localities to the weight of
+	// the traffic they receive while the group is the active priority. Endpoints in localities that do not match
+	// any group get the lowest priority.
+	Groups []map[string]uint32 `json:"groups"`
+}
+
+// ParsePriorityGroups returns the priority groups configured in the annotations, if any.
+func ParsePriorityGroups(annotations map[string]string) ([]PriorityGroups, error) {
+	value, f := annotations[PriorityGroupsAnnotation]
+	if !f {
+		return nil, nil
+	}
+	var groups []PriorityGroups
+	if err := yaml.UnmarshalStrict([]byte(value), &groups); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %v", PriorityGroupsAnnotation, err)
+	}
+	return groups, nil
+}
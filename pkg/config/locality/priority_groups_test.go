@@ -0,0 +1,49 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package locality
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePriorityGroups(t *testing.T) {
+	got, err := ParsePriorityGroups(map[string]string{PriorityGroupsAnnotation: `
+- from: us-east
+  groups:
+  - us-east/*: 100
+  - us-central/*: 70
+    us-west/*: 30
+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []PriorityGroups{{
+		From:   "us-east",
+		Groups: []map[string]uint32{{"us-east/*": 100}, {"us-central/*": 70, "us-west/*": 30}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if got, err := ParsePriorityGroups(nil); got != nil || err != nil {
+		t.Fatalf("expected no priority groups, got %v %v", got, err)
+	}
+	for _, invalid := range []string{"from: us-east", "- form: us-east", "- groups: [{us-east: -1}]"} {
+		if _, err := ParsePriorityGroups(map[string]string{PriorityGroupsAnnotation: invalid}); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"unsafe"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/eventbus"
 	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/pkg/filewatcher"
 	"istio.io/pkg/log"
@@ -59,8 +60,11 @@ func NewMultiWatcher(config *meshconfig.MeshConfig) *MultiWatcher {
 var _ Watcher = &internalWatcher{}
 
 type internalWatcher struct {
-	mutex    sync.Mutex
-	handlers []func()
+	mutex sync.Mutex
+	// handlers are notified of the mesh config changes, created on first use.
+	handlers *eventbus.Topic[*meshconfig.MeshConfig]
+	// handlerCount is the number of handlers added, ordering the handlers.
+	handlerCount int
 	// Current merged mesh config
 	MeshConfig *meshconfig.MeshConfig
 
@@ -125,7 +129,20 @@ func (w *internalWatcher) Mesh() *meshconfig.MeshConfig {
 func (w *internalWatcher) AddMeshHandler(h func()) {
 	w.mutex.Lock()
 	defer w.mutex.Unlock()
-	w.handlers = append(w.handlers, h)
+	// The first handler added is the ConfigPush, other handlers affect what will be pushed, so the handlers are
+	// notified in the reverse order they were added.
+	w.handlerCount++
+	w.topic().Subscribe("mesh-handler", func(*meshconfig.MeshConfig) {
+		h()
+	}, eventbus.WithPriority(-w.handlerCount))
+}
+
+// topic returns the topic of the mesh config changes. This must be called with a lock on w.mutex.
+func (w *internalWatcher) topic() *eventbus.Topic[*meshconfig.MeshConfig] {
+	if w.handlers == nil {
+		w.handlers = eventbus.NewTopic[*meshconfig.MeshConfig]("mesh")
+	}
+	return w.handlers
 }
 
 // HandleMeshConfigData keeps track of the standard mesh config. These are merged with the user
@@ -182,23 +199,17 @@ func (w *internalWatcher) HandleMeshConfig(meshConfig *meshconfig.MeshConfig) {
 
 // handleMeshConfigInternal behaves the same as HandleMeshConfig but must be called under a lock
 func (w *internalWatcher) handleMeshConfigInternal(meshConfig *meshconfig.MeshConfig) {
-	var handlers []func()
-
-	if !reflect.DeepEqual(meshConfig, w.MeshConfig) {
-		log.Infof("mesh configuration updated to: %s", PrettyFormatOfMeshConfig(meshConfig))
-		if !reflect.DeepEqual(meshConfig.ConfigSources, w.MeshConfig.ConfigSources) {
-			log.Info("mesh configuration sources have changed")
-			// TODO Need to recreate or reload initConfigController()
-		}
-
-		atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&w.MeshConfig)), unsafe.Pointer(meshConfig))
-		handlers = append(handlers, w.handlers...)
+	if reflect.DeepEqual(meshConfig, w.MeshConfig) {
+		return
 	}
-
-	// TODO hack: the first handler added is the ConfigPush, other handlers affect what will be pushed, so reversing iteration
-	for i := len(handlers) - 1; i >= 0; i-- {
-		handlers[i]()
+	log.Infof("mesh configuration updated to: %s", PrettyFormatOfMeshConfig(meshConfig))
+	if !reflect.DeepEqual(meshConfig.ConfigSources, w.MeshConfig.ConfigSources) {
+		log.Info("mesh configuration sources have changed")
+		// TODO Need to recreate or reload initConfigController()
 	}
+
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(&w.MeshConfig)), unsafe.Pointer(meshConfig))
+	w.topic().Publish(meshConfig)
 }
 
 // Add to the FileWatcher the provided file and execute the provided function
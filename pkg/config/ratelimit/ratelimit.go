@@ -0,0 +1,427 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit provides a typed API for rate limiting, replacing the EnvoyFilter recipes configuring the
+// Envoy local_ratelimit and ratelimit filters. A RateLimit is set on an EnvoyFilter with the Annotation, and is
+// fully validated before being compiled to the patches of the EnvoyFilter.
+package ratelimit
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/envoyproxy/go-control-plane/pkg/wellknown"
+	"github.com/hashicorp/go-multierror"
+	"sigs.k8s.io/yaml"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config/host"
+	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/listenerpatch"
+)
+
+const (
+	localRateLimitFilter = "envoy.filters.http.local_ratelimit"
+	rateLimitFilter      = "envoy.filters.http.ratelimit"
+
+	gatewayGroup = "gateway.networking.k8s.io"
+	gatewayKind  = "Gateway"
+	// gatewayNameLabel is the label of the pods of the gateways deployed for Gateway API Gateways.
+	gatewayNameLabel = "istio.io/gateway-name"
+)
+
+// Annotation sets a RateLimit, in YAML or JSON, on an EnvoyFilter. istiod compiles it to the patches of the
+// EnvoyFilter, which must have no configPatches of its own. The workloadSelector of the EnvoyFilter is used, so it
+// must not be set in the RateLimit; a targetRef may be set, as it selects the gateway listeners, but the EnvoyFilter
+// must then select the pods of the Gateway.
+const Annotation = "networking.istio.io/rate-limit"
+
+// Parse parses and validates the RateLimit of an Annotation, rejecting unknown fields.
+func Parse(s string) (*RateLimit, error) {
+	r := &RateLimit{}
+	if err := yaml.UnmarshalStrict([]byte(s), r); err != nil {
+		return nil, fmt.Errorf("invalid rate limit: %v", err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// RateLimit limits the rate of the requests received by the targeted workloads. The limits are enforced by the
+// inbound listeners of sidecars, or by the listeners of gateways.
+type RateLimit struct {
+	// WorkloadSelector selects the sidecars enforcing the limits. All workloads of the namespace if empty.
+	WorkloadSelector map[string]string `json:"workloadSelector,omitempty"`
+	// TargetRef selects a Gateway API Gateway enforcing the limits, instead of sidecars.
+	TargetRef *TargetRef `json:"targetRef,omitempty"`
+	// Port restricts the limits to the listeners of the port. All listeners if unset.
+	Port uint32 `json:"port,omitempty"`
+
+	Local  *Local  `json:"local,omitempty"`
+	Global *Global `json:"global,omitempty"`
+}
+
+// TargetRef references the Gateway API Gateway enforcing the limits.
+type TargetRef struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+}
+
+// Local is a token bucket shared by the connections of each proxy.
+type Local struct {
+	// MaxTokens is the capacity of the bucket.
+	MaxTokens uint32 `json:"maxTokens"`
+	// TokensPerFill are the tokens added to the bucket every FillInterval. Defaults to MaxTokens.
+	TokensPerFill uint32 `json:"tokensPerFill,omitempty"`
+	// FillInterval is the interval at which tokens are added, e.g. 1s. It must be at least 50ms.
+	FillInterval string `json:"fillInterval"`
+}
+
+// Global delegates the limits to a rate limit service implementing the Envoy RLS API.
+type Global struct {
+	// Domain is the domain of the rate limit service configuration.
+	Domain string `json:"domain"`
+	// Service is the hostname of the rate limit service, e.g. ratelimit.ratelimit.svc.cluster.local.
+	Service string `json:"service"`
+	// ServicePort is the gRPC port of the rate limit service.
+	ServicePort uint32 `json:"servicePort"`
+	// Timeout of the requests to the rate limit service. Defaults to 20ms.
+	Timeout string `json:"timeout,omitempty"`
+	// FailureModeDeny rejects the requests if the rate limit service cannot be reached, instead of allowing them.
+	FailureModeDeny bool `json:"failureModeDeny,omitempty"`
+	// Descriptors are the descriptors sent to the rate limit service for each request.
+	Descriptors []Descriptor `json:"descriptors"`
+}
+
+// Descriptor is a list of entries sent together to the rate limit service.
+type Descriptor struct {
+	Entries []DescriptorEntry `json:"entries"`
+}
+
+// DescriptorEntry is an entry of a descriptor. Exactly one field must be set.
+type DescriptorEntry struct {
+	// RequestHeader sends the value of a request header. Requests without the header are not limited by the
+	// descriptor.
+	RequestHeader *RequestHeader `json:"requestHeader,omitempty"`
+	// GenericKey sends a fixed key and value.
+	GenericKey *GenericKey `json:"genericKey,omitempty"`
+	// RemoteAddress sends the address of the client, as the remote_address key.
+	RemoteAddress bool `json:"remoteAddress,omitempty"`
+}
+
+type RequestHeader struct {
+	Header string `json:"header"`
+	Key    string `json:"key"`
+}
+
+type GenericKey struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+// Validate checks the rate limit, returning all the errors found.
+func (r *RateLimit) Validate() error {
+	var errs error
+	if r.Local == nil && r.Global == nil {
+		errs = multierror.Append(errs, fmt.Errorf("rate limit: at least one of local or global must be set"))
+	}
+	if r.TargetRef != nil {
+		if len(r.WorkloadSelector) > 0 {
+			errs = multierror.Append(errs, fmt.Errorf("rate limit: only one of workloadSelector and targetRef can be set"))
+		}
+		if r.TargetRef.Group != gatewayGroup || r.TargetRef.Kind != gatewayKind || r.TargetRef.Name == "" {
+			errs = multierror.Append(errs, fmt.Errorf("rate limit: targetRef must reference a %s %s by name", gatewayGroup, gatewayKind))
+		}
+	}
+	if err := labels.Instance(r.WorkloadSelector).Validate(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("rate limit: invalid workloadSelector: %v", err))
+	}
+	if r.Port > 65535 {
+		errs = multierror.Append(errs, fmt.Errorf("rate limit: invalid port %d", r.Port))
+	}
+	if r.Local != nil {
+		if err := r.Local.validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("local: %v", err))
+		}
+	}
+	if r.Global != nil {
+		if err := r.Global.validate(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("global: %v", err))
+		}
+	}
+	return errs
+}
+
+func (l *Local) validate() error {
+	if l.MaxTokens == 0 {
+		return fmt.Errorf("maxTokens must be positive")
+	}
+	d, err := time.ParseDuration(l.FillInterval)
+	if err != nil {
+		return fmt.Errorf("invalid fillInterval: %v", err)
+	}
+	if d < 50*time.Millisecond {
+		return fmt.Errorf("fillInterval must be at least 50ms")
+	}
+	return nil
+}
+
+func (g *Global) validate() error {
+	var errs error
+	if g.Domain == "" {
+		errs = multierror.Append(errs, fmt.Errorf("domain must be set"))
+	}
+	if g.Service == "" || host.Name(g.Service).IsWildCarded() {
+		errs = multierror.Append(errs, fmt.Errorf("service must be set to the hostname of the rate limit service"))
+	}
+	if g.ServicePort == 0 || g.ServicePort > 65535 {
+		errs = multierror.Append(errs, fmt.Errorf("invalid servicePort %d", g.ServicePort))
+	}
+	if g.Timeout != "" {
+		if d, err := time.ParseDuration(g.Timeout); err != nil || d <= 0 {
+			errs = multierror.Append(errs, fmt.Errorf("invalid timeout %q", g.Timeout))
+		}
+	}
+	if len(g.Descriptors) == 0 {
+		errs = multierror.Append(errs, fmt.Errorf("at least one descriptor must be set"))
+	}
+	for i, d := range g.Descriptors {
+		if len(d.Entries) == 0 {
+			errs = multierror.Append(errs, fmt.Errorf("descriptors[%d]: at least one entry must be set", i))
+		}
+		for j, e := range d.Entries {
+			if err := e.validate(); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("descriptors[%d].entries[%d]: %v", i, j, err))
+			}
+		}
+	}
+	return errs
+}
+
+func (e DescriptorEntry) validate() error {
+	set := 0
+	if e.RequestHeader != nil {
+		set++
+		if e.RequestHeader.Header == "" || e.RequestHeader.Key == "" {
+			return fmt.Errorf("requestHeader must have a header and a key")
+		}
+	}
+	if e.GenericKey != nil {
+		set++
+		if e.GenericKey.Value == "" {
+			return fmt.Errorf("genericKey must have a value")
+		}
+	}
+	if e.RemoteAddress {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of requestHeader, genericKey and remoteAddress must be set")
+	}
+	return nil
+}
+
+// ToEnvoyFilter compiles the rate limit to an EnvoyFilter, with the rate limit in its Annotation. The rate limit
+// must be valid.
+func (r *RateLimit) ToEnvoyFilter() (*networking.EnvoyFilter, map[string]string, error) {
+	if err := r.Validate(); err != nil {
+		return nil, nil, err
+	}
+	ef := &networking.EnvoyFilter{}
+	if selector := r.Selector(); len(selector) > 0 {
+		ef.WorkloadSelector = &networking.WorkloadSelector{Labels: selector}
+	}
+	annotated := *r
+	annotated.WorkloadSelector = nil
+	b, err := yaml.Marshal(annotated)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ef, map[string]string{Annotation: string(b)}, nil
+}
+
+// Selector returns the labels of the pods enforcing the limits: the pods of the Gateway of the targetRef if set.
+func (r *RateLimit) Selector() map[string]string {
+	if r.TargetRef != nil {
+		return map[string]string{gatewayNameLabel: r.TargetRef.Name}
+	}
+	return r.WorkloadSelector
+}
+
+// MatchesSelector returns whether the workload selector of an EnvoyFilter selects the pods enforcing the limits.
+func (r *RateLimit) MatchesSelector(ws *networking.WorkloadSelector) bool {
+	if r.TargetRef == nil {
+		return true
+	}
+	return reflect.DeepEqual(ws.GetLabels(), r.Selector())
+}
+
+// ConfigPatches compiles the rate limit to EnvoyFilter patches. The rate limit must be valid.
+func (r *RateLimit) ConfigPatches() ([]*networking.EnvoyFilter_EnvoyConfigObjectPatch, error) {
+	var patches []*networking.EnvoyFilter_EnvoyConfigObjectPatch
+	ctx := networking.EnvoyFilter_SIDECAR_INBOUND
+	if r.TargetRef != nil {
+		ctx = networking.EnvoyFilter_GATEWAY
+	}
+	// The filters are inserted before the router, so that the limits apply after authentication and authorization.
+	insertFilter := func(name string, typedConfig map[string]any) error {
+		value, err := listenerpatch.ToStruct(map[string]any{"name": name, "typed_config": typedConfig})
+		if err != nil {
+			return err
+		}
+		patches = append(patches, &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_HTTP_FILTER,
+			Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context: ctx,
+				ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Listener{
+					Listener: &networking.EnvoyFilter_ListenerMatch{
+						PortNumber: r.Port,
+						FilterChain: &networking.EnvoyFilter_ListenerMatch_FilterChainMatch{
+							Filter: &networking.EnvoyFilter_ListenerMatch_FilterMatch{
+								Name:      wellknown.HTTPConnectionManager,
+								SubFilter: &networking.EnvoyFilter_ListenerMatch_SubFilterMatch{Name: wellknown.Router},
+							},
+						},
+					},
+				},
+			},
+			Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_INSERT_BEFORE, Value: value},
+		})
+		return nil
+	}
+
+	if l := r.Local; l != nil {
+		tokensPerFill := l.TokensPerFill
+		if tokensPerFill == 0 {
+			tokensPerFill = l.MaxTokens
+		}
+		enabled := map[string]any{"default_value": map[string]any{"numerator": 100, "denominator": "HUNDRED"}}
+		if err := insertFilter(localRateLimitFilter, map[string]any{
+			"@type":       "type.googleapis.com/envoy.extensions.filters.http.local_ratelimit.v3.LocalRateLimit",
+			"stat_prefix": "http_local_rate_limiter",
+			"token_bucket": map[string]any{
+				"max_tokens":      l.MaxTokens,
+				"tokens_per_fill": tokensPerFill,
+				"fill_interval":   l.FillInterval,
+			},
+			"filter_enabled":  withRuntimeKey(enabled, "local_rate_limit_enabled"),
+			"filter_enforced": withRuntimeKey(enabled, "local_rate_limit_enforced"),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if g := r.Global; g != nil {
+		cluster := fmt.Sprintf("outbound|%d||%s", g.ServicePort, g.Service)
+		filter := map[string]any{
+			"@type":             "type.googleapis.com/envoy.extensions.filters.http.ratelimit.v3.RateLimit",
+			"domain":            g.Domain,
+			"failure_mode_deny": g.FailureModeDeny,
+			"rate_limit_service": map[string]any{
+				"grpc_service":          map[string]any{"envoy_grpc": map[string]any{"cluster_name": cluster, "authority": g.Service}},
+				"transport_api_version": "V3",
+			},
+		}
+		if g.Timeout != "" {
+			filter["timeout"] = g.Timeout
+		}
+		if err := insertFilter(rateLimitFilter, filter); err != nil {
+			return nil, err
+		}
+
+		rateLimits := make([]any, 0, len(g.Descriptors))
+		for _, d := range g.Descriptors {
+			actions := make([]any, 0, len(d.Entries))
+			for _, e := range d.Entries {
+				actions = append(actions, e.action())
+			}
+			rateLimits = append(rateLimits, map[string]any{"actions": actions})
+		}
+		value, err := listenerpatch.ToStruct(map[string]any{"rate_limits": rateLimits})
+		if err != nil {
+			return nil, err
+		}
+		routeConfig := &networking.EnvoyFilter_RouteConfigurationMatch{}
+		if r.Port != 0 {
+			if ctx == networking.EnvoyFilter_GATEWAY {
+				routeConfig.PortNumber = r.Port
+			} else {
+				routeConfig.Vhost = &networking.EnvoyFilter_RouteConfigurationMatch_VirtualHostMatch{Name: fmt.Sprintf("inbound|http|%d", r.Port)}
+			}
+		}
+		patches = append(patches, &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_VIRTUAL_HOST,
+			Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context:     ctx,
+				ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_RouteConfiguration{RouteConfiguration: routeConfig},
+			},
+			Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_MERGE, Value: value},
+		})
+
+		// The rate limit service is called with gRPC, which requires HTTP/2 whatever the name of its port.
+		value, err = listenerpatch.ToStruct(map[string]any{"typed_extension_protocol_options": map[string]any{
+			"envoy.extensions.upstreams.http.v3.HttpProtocolOptions": map[string]any{
+				"@type":                "type.googleapis.com/envoy.extensions.upstreams.http.v3.HttpProtocolOptions",
+				"explicit_http_config": map[string]any{"http2_protocol_options": map[string]any{}},
+			},
+		}})
+		if err != nil {
+			return nil, err
+		}
+		clusterCtx := networking.EnvoyFilter_SIDECAR_OUTBOUND
+		if ctx == networking.EnvoyFilter_GATEWAY {
+			clusterCtx = networking.EnvoyFilter_GATEWAY
+		}
+		patches = append(patches, &networking.EnvoyFilter_EnvoyConfigObjectPatch{
+			ApplyTo: networking.EnvoyFilter_CLUSTER,
+			Match: &networking.EnvoyFilter_EnvoyConfigObjectMatch{
+				Context: clusterCtx,
+				ObjectTypes: &networking.EnvoyFilter_EnvoyConfigObjectMatch_Cluster{
+					Cluster: &networking.EnvoyFilter_ClusterMatch{Service: g.Service, PortNumber: g.ServicePort},
+				},
+			},
+			Patch: &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_MERGE, Value: value},
+		})
+	}
+	return patches, nil
+}
+
+func withRuntimeKey(v map[string]any, key string) map[string]any {
+	out := map[string]any{"runtime_key": key}
+	for k, val := range v {
+		out[k] = val
+	}
+	return out
+}
+
+func (e DescriptorEntry) action() map[string]any {
+	switch {
+	case e.RequestHeader != nil:
+		return map[string]any{"request_headers": map[string]any{
+			"header_name":    e.RequestHeader.Header,
+			"descriptor_key": e.RequestHeader.Key,
+		}}
+	case e.GenericKey != nil:
+		k := map[string]any{"descriptor_value": e.GenericKey.Value}
+		if e.GenericKey.Key != "" {
+			k["descriptor_key"] = e.GenericKey.Key
+		}
+		return map[string]any{"generic_key": k}
+	default:
+		return map[string]any{"remote_address": map[string]any{}}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"strings"
+	"testing"
+
+	networking "istio.io/api/networking/v1alpha3"
+)
+
+var local = &Local{MaxTokens: 100, FillInterval: "1s"}
+
+func global(entries ...DescriptorEntry) *Global {
+	return &Global{
+		Domain:      "gateway",
+		Service:     "ratelimit.ratelimit.svc.cluster.local",
+		ServicePort: 8081,
+		Descriptors: []Descriptor{{Entries: entries}},
+	}
+}
+
+var userHeader = DescriptorEntry{RequestHeader: &RequestHeader{Header: "x-user", Key: "user"}}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name string
+		rl   RateLimit
+		err  string
+	}{
+		{name: "local", rl: RateLimit{WorkloadSelector: map[string]string{"app": "reviews"}, Local: local}},
+		{
+			name: "global on gateway",
+			rl: RateLimit{
+				TargetRef: &TargetRef{Group: "gateway.networking.k8s.io", Kind: "Gateway", Name: "gateway"},
+				Global:    global(userHeader, DescriptorEntry{RemoteAddress: true}),
+			},
+		},
+		{name: "empty", rl: RateLimit{}, err: "at least one of local or global"},
+		{
+			name: "selector and target",
+			rl: RateLimit{
+				WorkloadSelector: map[string]string{"app": "reviews"},
+				TargetRef:        &TargetRef{Group: "gateway.networking.k8s.io", Kind: "Gateway", Name: "gateway"},
+				Local:            local,
+			},
+			err: "only one of workloadSelector and targetRef",
+		},
+		{
+			name: "target kind",
+			rl:   RateLimit{TargetRef: &TargetRef{Kind: "Service", Name: "reviews"}, Local: local},
+			err:  "targetRef must reference a gateway.networking.k8s.io Gateway",
+		},
+		{name: "fill interval", rl: RateLimit{Local: &Local{MaxTokens: 10, FillInterval: "10ms"}}, err: "at least 50ms"},
+		{name: "no tokens", rl: RateLimit{Local: &Local{FillInterval: "1s"}}, err: "maxTokens must be positive"},
+		{
+			name: "entry with two fields",
+			rl:   RateLimit{Global: global(DescriptorEntry{GenericKey: &GenericKey{Value: "a"}, RemoteAddress: true})},
+			err:  "descriptors[0].entries[0]: exactly one of",
+		},
+		{name: "no service", rl: RateLimit{Global: &Global{Domain: "a", ServicePort: 8081}}, err: "service must be set"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.rl.Validate()
+			if c.err == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("expected error containing %q, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestToEnvoyFilter(t *testing.T) {
+	rl := RateLimit{WorkloadSelector: map[string]string{"app": "reviews"}, Port: 9080, Local: local, Global: global(userHeader)}
+	ef, annotations, err := rl.ToEnvoyFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ef.WorkloadSelector.GetLabels()["app"] != "reviews" || len(ef.ConfigPatches) > 0 {
+		t.Fatalf("unexpected EnvoyFilter %v", ef)
+	}
+	parsed, err := Parse(annotations[Annotation])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.WorkloadSelector) > 0 {
+		t.Fatalf("expected the selector to be left to the EnvoyFilter, got %v", parsed.WorkloadSelector)
+	}
+	ef.ConfigPatches, err = parsed.ConfigPatches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyTo := []networking.EnvoyFilter_ApplyTo{
+		networking.EnvoyFilter_HTTP_FILTER, networking.EnvoyFilter_HTTP_FILTER, networking.EnvoyFilter_VIRTUAL_HOST, networking.EnvoyFilter_CLUSTER,
+	}
+	if len(ef.ConfigPatches) != len(applyTo) {
+		t.Fatalf("expected %d patches, got %d", len(applyTo), len(ef.ConfigPatches))
+	}
+	for i, p := range ef.ConfigPatches {
+		if p.ApplyTo != applyTo[i] {
+			t.Fatalf("patch %d: expected %v, got %v", i, applyTo[i], p.ApplyTo)
+		}
+	}
+	localFilter := ef.ConfigPatches[0]
+	if localFilter.Match.Context != networking.EnvoyFilter_SIDECAR_INBOUND || localFilter.Match.GetListener().GetPortNumber() != 9080 ||
+		localFilter.Patch.Operation != networking.EnvoyFilter_Patch_INSERT_BEFORE {
+		t.Fatalf("unexpected local rate limit patch %v", localFilter)
+	}
+	bucket := localFilter.Patch.Value.Fields["typed_config"].GetStructValue().Fields["token_bucket"].GetStructValue()
+	if bucket.Fields["tokens_per_fill"].GetNumberValue() != 100 {
+		t.Fatalf("expected tokens_per_fill to default to max_tokens, got %v", bucket)
+	}
+	if vh := ef.ConfigPatches[2].Match.GetRouteConfiguration().GetVhost().GetName(); vh != "inbound|http|9080" {
+		t.Fatalf("expected the inbound virtual host of the port, got %q", vh)
+	}
+	service := ef.ConfigPatches[1].Patch.Value.Fields["typed_config"].GetStructValue().Fields["rate_limit_service"].GetStructValue()
+	cluster := service.Fields["grpc_service"].GetStructValue().Fields["envoy_grpc"].GetStructValue().Fields["cluster_name"].GetStringValue()
+	if cluster != "outbound|8081||ratelimit.ratelimit.svc.cluster.local" {
+		t.Fatalf("unexpected rate limit service cluster %q", cluster)
+	}
+	if c := ef.ConfigPatches[3].Match; c.Context != networking.EnvoyFilter_SIDECAR_OUTBOUND ||
+		c.GetCluster().GetService() != "ratelimit.ratelimit.svc.cluster.local" {
+		t.Fatalf("unexpected cluster patch match %v", c)
+	}
+
+	gw := RateLimit{TargetRef: &TargetRef{Group: "gateway.networking.k8s.io", Kind: "Gateway", Name: "gateway"}, Local: local}
+	ef, annotations, err = gw.ToEnvoyFilter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ef.WorkloadSelector.GetLabels()[gatewayNameLabel] != "gateway" {
+		t.Fatalf("expected the gateway pods to be selected, got %v", ef)
+	}
+	parsed, err = Parse(annotations[Annotation])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.MatchesSelector(ef.WorkloadSelector) || parsed.MatchesSelector(&networking.WorkloadSelector{Labels: map[string]string{"app": "gateway"}}) {
+		t.Fatalf("expected only the selector of the gateway pods to match")
+	}
+	patches, err := parsed.ConfigPatches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if patches[0].Match.Context != networking.EnvoyFilter_GATEWAY {
+		t.Fatalf("expected the gateway listeners to be patched, got %v", patches[0].Match)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse("local: {maxTokens: 10, fillInterval: 1s}"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Parse("local: {maxTokens: 10, fillInterval: 1s, unknown: true}"); err == nil {
+		t.Fatal("expected unknown fields to be rejected")
+	}
+	if _, err := Parse("local: {maxTokens: 10, fillInterval: 1ms}"); err == nil {
+		t.Fatal("expected invalid rate limits to be rejected")
+	}
+}
@@ -54,6 +54,7 @@ const (
 	attrDestNamespace    = "destination.namespace"  // e.g. "default".
 	attrDestUser         = "destination.user"       // service account, e.g. "bookinfo-productpage".
 	attrConnSNI          = "connection.sni"         // server name indication, e.g. "www.example.com".
+	attrConnProtocol     = "connection.protocol"    // application protocol detected on the connection, e.g. "tls".
 	attrExperimental     = "experimental.envoy.filters."
 )
 
@@ -123,6 +124,8 @@ func ValidateAttribute(key string, values []string) error {
 	case isEqual(key, attrDestPort):
 		return ValidatePorts(values)
 	case isEqual(key, attrConnSNI):
+	case isEqual(key, attrConnProtocol):
+		return validateConnectionProtocols(values)
 	case hasPrefix(key, attrExperimental):
 		return validateMapKey(key)
 	case isEqual(key, attrDestNamespace):
@@ -177,6 +180,19 @@ func ValidatePorts(ports []string) error {
 	return errs.ErrorOrNil()
 }
 
+// connectionProtocols are the application protocols matched by the connection.protocol attribute.
+var connectionProtocols = sets.New("tls", "http", "mysql", "mongo", "redis", "tcp")
+
+func validateConnectionProtocols(values []string) error {
+	var errs *multierror.Error
+	for _, v := range values {
+		if !connectionProtocols.Contains(strings.ToLower(v)) {
+			errs = multierror.Append(errs, fmt.Errorf("bad connection protocol (%s): must be one of %v", v, sets.SortedList(connectionProtocols)))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
 func validateMapKey(key string) error {
 	open := strings.Index(key, "[")
 	if strings.HasSuffix(key, "]") && open > 0 && open < len(key)-2 {
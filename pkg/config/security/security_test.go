@@ -201,6 +201,15 @@ func TestValidateCondition(t *testing.T) {
 			key:    "connection.sni",
 			values: []string{"value"},
 		},
+		{
+			key:    "connection.protocol",
+			values: []string{"tls", "MySQL"},
+		},
+		{
+			key:       "connection.protocol",
+			values:    []string{"postgres"},
+			wantError: true,
+		},
 		{
 			key:    "experimental.envoy.filters.a.b[c]",
 			values: []string{"value"},
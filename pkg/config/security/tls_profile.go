@@ -0,0 +1,216 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+// TLSVersion is a TLS protocol version. Values match the Envoy TlsParameters.TlsProtocol enum.
+type TLSVersion int32
+
+const (
+	TLSAuto TLSVersion = iota
+	TLSv1_0
+	TLSv1_1
+	TLSv1_2
+	TLSv1_3
+)
+
+func (v TLSVersion) String() string {
+	switch v {
+	case TLSv1_0:
+		return "TLSv1_0"
+	case TLSv1_1:
+		return "TLSv1_1"
+	case TLSv1_2:
+		return "TLSv1_2"
+	case TLSv1_3:
+		return "TLSv1_3"
+	default:
+		return "TLS_AUTO"
+	}
+}
+
+// Names of the built-in TLS profiles.
+const (
+	// TLSProfileModern only allows TLS 1.3.
+	TLSProfileModern = "modern"
+	// TLSProfileIntermediate allows TLS 1.2 and 1.3 with forward secret AEAD ciphers.
+	TLSProfileIntermediate = "intermediate"
+	// TLSProfileFIPS only allows FIPS 140-2 approved ciphers and curves.
+	TLSProfileFIPS = "fips"
+)
+
+// TLSProfile is a named set of TLS parameters applied to both mTLS and gateway TLS.
+type TLSProfile struct {
+	Name       string
+	MinVersion TLSVersion
+	MaxVersion TLSVersion
+	// CipherSuites only apply to TLS 1.2 and below; BoringSSL does not allow TLS 1.3 suites to be configured.
+	CipherSuites []string
+	EcdhCurves   []string
+}
+
+// TLSProfiles contains the built-in TLS profiles, keyed by name.
+var TLSProfiles = map[string]*TLSProfile{
+	TLSProfileModern: {
+		Name:       TLSProfileModern,
+		MinVersion: TLSv1_3,
+		MaxVersion: TLSv1_3,
+		EcdhCurves: []string{"X25519", "P-256", "P-384"},
+	},
+	TLSProfileIntermediate: {
+		Name:       TLSProfileIntermediate,
+		MinVersion: TLSv1_2,
+		MaxVersion: TLSv1_3,
+		CipherSuites: []string{
+			"ECDHE-ECDSA-AES128-GCM-SHA256",
+			"ECDHE-RSA-AES128-GCM-SHA256",
+			"ECDHE-ECDSA-AES256-GCM-SHA384",
+			"ECDHE-RSA-AES256-GCM-SHA384",
+			"ECDHE-ECDSA-CHACHA20-POLY1305",
+			"ECDHE-RSA-CHACHA20-POLY1305",
+		},
+		EcdhCurves: []string{"X25519", "P-256", "P-384"},
+	},
+	TLSProfileFIPS: {
+		Name:       TLSProfileFIPS,
+		MinVersion: TLSv1_2,
+		MaxVersion: TLSv1_3,
+		CipherSuites: []string{
+			"ECDHE-ECDSA-AES128-GCM-SHA256",
+			"ECDHE-RSA-AES128-GCM-SHA256",
+			"ECDHE-ECDSA-AES256-GCM-SHA384",
+			"ECDHE-RSA-AES256-GCM-SHA384",
+		},
+		EcdhCurves: []string{"P-256", "P-384"},
+	},
+}
+
+// ValidEcdhCurves contains the curves supported by BoringSSL.
+var ValidEcdhCurves = sets.New(
+	"X25519",
+	"P-256",
+	"P-384",
+	"P-521",
+)
+
+// FIPSCipherSuites contains the cipher suites allowed by FIPS builds of Envoy.
+var FIPSCipherSuites = sets.New(
+	"ECDHE-ECDSA-AES128-GCM-SHA256",
+	"ECDHE-RSA-AES128-GCM-SHA256",
+	"ECDHE-ECDSA-AES256-GCM-SHA384",
+	"ECDHE-RSA-AES256-GCM-SHA384",
+)
+
+// FIPSEcdhCurves contains the curves allowed by FIPS builds of Envoy.
+var FIPSEcdhCurves = sets.New(
+	"P-256",
+	"P-384",
+)
+
+// GetTLSProfile returns the built-in profile with the given name, validated against the capabilities
+// of the proxy. If fips is set, the profile must be usable by a FIPS build of Envoy.
+func GetTLSProfile(name string, fips bool) (*TLSProfile, error) {
+	p, f := TLSProfiles[name]
+	if !f {
+		return nil, fmt.Errorf("unknown TLS profile %q, must be one of: %s", name, strings.Join(sortedProfileNames(), ", "))
+	}
+	if err := p.Validate(fips); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks that the profile can be applied by Envoy. If fips is set, the profile is also validated
+// against the restrictions of FIPS builds.
+func (p *TLSProfile) Validate(fips bool) error {
+	var errs *multierror.Error
+	if p.MinVersion != TLSAuto && p.MaxVersion != TLSAuto && p.MinVersion > p.MaxVersion {
+		errs = multierror.Append(errs, fmt.Errorf("TLS profile %q: minimum version %v is greater than maximum version %v",
+			p.Name, p.MinVersion, p.MaxVersion))
+	}
+	if p.MinVersion == TLSv1_3 && len(p.CipherSuites) > 0 {
+		errs = multierror.Append(errs, fmt.Errorf("TLS profile %q: cipher suites cannot be configured for TLS 1.3", p.Name))
+	}
+	if fips && p.MinVersion < TLSv1_2 {
+		errs = multierror.Append(errs, fmt.Errorf("TLS profile %q: FIPS builds require a minimum version of TLSv1_2", p.Name))
+	}
+	for _, c := range p.CipherSuites {
+		if !ValidCipherSuites.Contains(c) {
+			errs = multierror.Append(errs, fmt.Errorf("TLS profile %q: unsupported cipher suite %q", p.Name, c))
+		} else if fips && !FIPSCipherSuites.Contains(c) {
+			errs = multierror.Append(errs, fmt.Errorf("TLS profile %q: cipher suite %q is not supported by FIPS builds", p.Name, c))
+		}
+	}
+	for _, c := range p.EcdhCurves {
+		if !ValidEcdhCurves.Contains(c) {
+			errs = multierror.Append(errs, fmt.Errorf("TLS profile %q: unsupported curve %q", p.Name, c))
+		} else if fips && !FIPSEcdhCurves.Contains(c) {
+			errs = multierror.Append(errs, fmt.Errorf("TLS profile %q: curve %q is not supported by FIPS builds", p.Name, c))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// ClampVersion bounds a TLS version set by a server to the versions of the profile, so that the server cannot
+// weaken the profile. TLSAuto is returned as is, since it stands for the version of the profile.
+func (p *TLSProfile) ClampVersion(v TLSVersion) TLSVersion {
+	switch {
+	case v == TLSAuto:
+		return v
+	case p.MinVersion != TLSAuto && v < p.MinVersion:
+		return p.MinVersion
+	case p.MaxVersion != TLSAuto && v > p.MaxVersion:
+		return p.MaxVersion
+	}
+	return v
+}
+
+// FilterCipherSuites returns the cipher suites set by a server that are allowed by the profile. If the profile does
+// not restrict the cipher suites, they are returned as is; if none of them is allowed, the cipher suites of the
+// profile are returned.
+func (p *TLSProfile) FilterCipherSuites(suites []string) []string {
+	if len(p.CipherSuites) == 0 {
+		return suites
+	}
+	allowed := sets.New(p.CipherSuites...)
+	var out []string
+	for _, c := range suites {
+		if allowed.Contains(c) {
+			out = append(out, c)
+		}
+	}
+	if len(out) == 0 {
+		return append([]string(nil), p.CipherSuites...)
+	}
+	return out
+}
+
+func sortedProfileNames() []string {
+	names := make([]string, 0, len(TLSProfiles))
+	for n := range TLSProfiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
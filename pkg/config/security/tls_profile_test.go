@@ -0,0 +1,138 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security_test
+
+import (
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pkg/config/security"
+)
+
+func TestGetTLSProfile(t *testing.T) {
+	cases := []struct {
+		name    string
+		fips    bool
+		wantErr bool
+	}{
+		{name: security.TLSProfileModern},
+		{name: security.TLSProfileIntermediate},
+		{name: security.TLSProfileFIPS},
+		{name: security.TLSProfileFIPS, fips: true},
+		// CHACHA20 and X25519 are not available in FIPS builds.
+		{name: security.TLSProfileModern, fips: true, wantErr: true},
+		{name: security.TLSProfileIntermediate, fips: true, wantErr: true},
+		{name: "legacy", wantErr: true},
+	}
+	for _, c := range cases {
+		p, err := security.GetTLSProfile(c.name, c.fips)
+		if gotErr := err != nil; gotErr != c.wantErr {
+			t.Errorf("GetTLSProfile(%q, fips=%v): got error %v, want error %v", c.name, c.fips, err, c.wantErr)
+			continue
+		}
+		if err == nil && p.Name != c.name {
+			t.Errorf("GetTLSProfile(%q): got profile %q", c.name, p.Name)
+		}
+	}
+}
+
+func TestTLSProfileValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		profile security.TLSProfile
+		fips    bool
+		wantErr bool
+	}{
+		{
+			name:    "valid",
+			profile: security.TLSProfile{MinVersion: security.TLSv1_2, CipherSuites: []string{"AES128-SHA"}, EcdhCurves: []string{"P-521"}},
+		},
+		{
+			name:    "inverted versions",
+			profile: security.TLSProfile{MinVersion: security.TLSv1_3, MaxVersion: security.TLSv1_2},
+			wantErr: true,
+		},
+		{
+			name:    "ciphers with TLS 1.3",
+			profile: security.TLSProfile{MinVersion: security.TLSv1_3, CipherSuites: []string{"ECDHE-RSA-AES128-GCM-SHA256"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown cipher",
+			profile: security.TLSProfile{CipherSuites: []string{"RC4-MD5"}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown curve",
+			profile: security.TLSProfile{EcdhCurves: []string{"secp256k1"}},
+			wantErr: true,
+		},
+		{
+			name:    "non FIPS cipher",
+			profile: security.TLSProfile{MinVersion: security.TLSv1_2, CipherSuites: []string{"AES128-SHA"}},
+			fips:    true,
+			wantErr: true,
+		},
+		{
+			name:    "FIPS minimum version",
+			profile: security.TLSProfile{MinVersion: security.TLSv1_1},
+			fips:    true,
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.profile.Validate(c.fips)
+			if gotErr := err != nil; gotErr != c.wantErr {
+				t.Fatalf("got error %v, want error %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSProfileClamp(t *testing.T) {
+	fips := security.TLSProfiles[security.TLSProfileFIPS]
+	versions := []struct {
+		in, want security.TLSVersion
+	}{
+		{in: security.TLSAuto, want: security.TLSAuto},
+		{in: security.TLSv1_0, want: security.TLSv1_2},
+		{in: security.TLSv1_2, want: security.TLSv1_2},
+		{in: security.TLSv1_3, want: security.TLSv1_3},
+	}
+	for _, c := range versions {
+		if got := fips.ClampVersion(c.in); got != c.want {
+			t.Errorf("ClampVersion(%v): got %v, want %v", c.in, got, c.want)
+		}
+	}
+	if got := security.TLSProfiles[security.TLSProfileModern].ClampVersion(security.TLSv1_2); got != security.TLSv1_3 {
+		t.Errorf("ClampVersion(TLSv1_2) with the modern profile: got %v, want TLSv1_3", got)
+	}
+
+	ciphers := []struct {
+		name string
+		in   []string
+		want []string
+	}{
+		{name: "allowed", in: []string{"ECDHE-RSA-AES128-GCM-SHA256"}, want: []string{"ECDHE-RSA-AES128-GCM-SHA256"}},
+		{name: "mixed", in: []string{"AES128-SHA", "ECDHE-RSA-AES128-GCM-SHA256"}, want: []string{"ECDHE-RSA-AES128-GCM-SHA256"}},
+		{name: "none allowed", in: []string{"AES128-SHA"}, want: fips.CipherSuites},
+	}
+	for _, c := range ciphers {
+		if got := fips.FilterCipherSuites(c.in); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("FilterCipherSuites %s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package telemetry holds the Telemetry settings configured with annotations.
+package telemetry
+
+import (
+	"fmt"
+	"time"
+)
+
+// MetricExpiryAnnotation is the annotation of a Telemetry setting the duration after which the label sets of the
+// standard metrics that were not updated are expired by the stats extension of the proxies, to bound the cardinality
+// of the metrics of proxies talking to many ephemeral peers, for example:
+//
+//	telemetry.istio.io/metric-expiry: 10m
+//
+// It applies to the Prometheus and Stackdriver providers. The metrics of the Stackdriver provider expire after one
+// hour by default, while the metrics of the Prometheus provider do not expire by default. Like the other metrics
+// settings, the Telemetry of the workload overrides the one of the
+// namespace, which overrides the one of the root namespace.
+// TODO: move to API
+const MetricExpiryAnnotation = "telemetry.istio.io/metric-expiry"
+
+// ParseMetricExpiry returns the metric expiry of the MetricExpiryAnnotation, or 0 if there is none.
+func ParseMetricExpiry(annotations map[string]string) (time.Duration, error) {
+	value, f := annotations[MetricExpiryAnnotation]
+	if !f {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s annotation: %v", MetricExpiryAnnotation, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s annotation: %v must be positive", MetricExpiryAnnotation, d)
+	}
+	return d, nil
+}
@@ -0,0 +1,39 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package telemetry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMetricExpiry(t *testing.T) {
+	got, err := ParseMetricExpiry(map[string]string{MetricExpiryAnnotation: "10m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 10*time.Minute {
+		t.Fatalf("got %v, want 10m", got)
+	}
+
+	if got, err := ParseMetricExpiry(nil); got != 0 || err != nil {
+		t.Fatalf("expected no metric expiry, got %v %v", got, err)
+	}
+	for _, invalid := range []string{"", "10", "0s", "-1m"} {
+		if _, err := ParseMetricExpiry(map[string]string{MetricExpiryAnnotation: invalid}); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"errors"
+
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/schema/gvk"
+)
+
+// Deprecation is a field or behavior of the config APIs which is scheduled for removal. The validation of a config
+// using it returns the deprecation as a warning, so that the webhook, the analyzers and istioctl can report it.
+type Deprecation struct {
+	// ID identifies the deprecation in metrics and reports.
+	ID string
+	// Kind is the kind of the configs the deprecation applies to.
+	Kind config.GroupVersionKind
+	// Field is the path of the deprecated field, without indexes.
+	Field string
+	// Replacement is the path of the field replacing the deprecated one, if any.
+	Replacement string
+	// RemovedIn is the Istio release the field is removed in, once it is planned.
+	RemovedIn string
+	// Message is the warning returned for the configs using the field.
+	Message string
+}
+
+var _ error = &Deprecation{}
+
+func (d *Deprecation) Error() string {
+	if d.RemovedIn == "" {
+		return d.Message
+	}
+	return d.Message + "; it will be removed in Istio " + d.RemovedIn
+}
+
+var (
+	deprecatedMirrorPercent = &Deprecation{
+		ID:          "virtualservice-mirror-percent",
+		Kind:        gvk.VirtualService,
+		Field:       "spec.http.mirrorPercent",
+		Replacement: "spec.http.mirrorPercentage",
+		Message:     `using deprecated setting "mirrorPercent", use "mirrorPercentage" instead`,
+	}
+	deprecatedConsecutiveErrors = &Deprecation{
+		ID:          "destinationrule-outlier-consecutive-errors",
+		Kind:        gvk.DestinationRule,
+		Field:       "spec.trafficPolicy.outlierDetection.consecutiveErrors",
+		Replacement: "spec.trafficPolicy.outlierDetection.consecutive5xxErrors",
+		Message:     "outlier detection consecutive errors is deprecated, use consecutiveGatewayErrors or consecutive5xxErrors instead",
+	}
+	deprecatedMinimumRingSize = &Deprecation{
+		ID:          "destinationrule-consistent-hash-minimum-ring-size",
+		Kind:        gvk.DestinationRule,
+		Field:       "spec.trafficPolicy.loadBalancer.consistentHash.minimumRingSize",
+		Replacement: "spec.trafficPolicy.loadBalancer.consistentHash.ringHash.minimumRingSize",
+		Message:     "consistent hash MinimumRingSize is deprecated, use ConsistentHashLB's RingHash configuration instead",
+	}
+)
+
+// Deprecations are the deprecated fields of the config APIs.
+var Deprecations = []*Deprecation{
+	deprecatedMirrorPercent,
+	deprecatedConsecutiveErrors,
+	deprecatedMinimumRingSize,
+}
+
+// UsedDeprecations returns the deprecations in the warnings of the validation of a config, once each.
+func UsedDeprecations(warning Warning) []*Deprecation {
+	var out []*Deprecation
+	var walk func(err error)
+	walk = func(err error) {
+		if me, ok := err.(*multierror.Error); ok {
+			for _, e := range me.Errors {
+				walk(e)
+			}
+			return
+		}
+		var d *Deprecation
+		if !errors.As(err, &d) {
+			return
+		}
+		for _, seen := range out {
+			if seen == d {
+				return
+			}
+		}
+		out = append(out, d)
+	}
+	walk(warning)
+	return out
+}
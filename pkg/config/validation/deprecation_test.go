@@ -0,0 +1,64 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestUsedDeprecations(t *testing.T) {
+	policy := &networking.TrafficPolicy{
+		OutlierDetection: &networking.OutlierDetection{ConsecutiveErrors: 5},
+		LoadBalancer: &networking.LoadBalancerSettings{
+			LbPolicy: &networking.LoadBalancerSettings_ConsistentHash{
+				ConsistentHash: &networking.LoadBalancerSettings_ConsistentHashLB{
+					HashKey:         &networking.LoadBalancerSettings_ConsistentHashLB_UseSourceIp{UseSourceIp: true},
+					MinimumRingSize: 1024,
+				},
+			},
+		},
+	}
+	warnings, err := ValidateDestinationRule(config.Config{
+		Meta: config.Meta{Name: "reviews", Namespace: "default"},
+		Spec: &networking.DestinationRule{
+			Host:          "reviews",
+			TrafficPolicy: policy,
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, UsedDeprecations(warnings), []*Deprecation{deprecatedConsecutiveErrors, deprecatedMinimumRingSize})
+	assert.Equal(t, UsedDeprecations(nil), nil)
+
+	warnings, err = ValidateVirtualService(config.Config{
+		Meta: config.Meta{Name: "reviews", Namespace: "default"},
+		Spec: &networking.VirtualService{
+			Hosts: []string{"reviews"},
+			Http: []*networking.HTTPRoute{
+				{MirrorPercent: &wrapperspb.UInt32Value{Value: 5}, Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews"}}}},
+				{MirrorPercent: &wrapperspb.UInt32Value{Value: 5}, Route: []*networking.HTTPRouteDestination{{Destination: &networking.Destination{Host: "reviews"}}}},
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, UsedDeprecations(warnings), []*Deprecation{deprecatedMirrorPercent})
+	assert.Equal(t, (&Deprecation{Message: "field is deprecated", RemovedIn: "1.20"}).Error(),
+		"field is deprecated; it will be removed in Istio 1.20")
+}
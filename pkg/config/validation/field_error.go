@@ -0,0 +1,136 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+const networkingReference = "https://istio.io/latest/docs/reference/config/networking/"
+
+// FieldError is a validation error located at a field of a config, which tooling can process. Its message is the
+// message of the underlying error, so that locating an error does not change how it reads.
+type FieldError struct {
+	// Field is the path of the invalid field, for example `spec.http[0].route[1].weight`. It is empty if the error
+	// is not located yet.
+	Field string
+	// Value is the provided value of the field, if known.
+	Value string
+	// Allowed are the allowed values of the field, if they can be listed.
+	Allowed []string
+	// Rule links to the reference documentation of the rule the field failed.
+	Rule string
+	Err  error
+}
+
+var _ error = &FieldError{}
+
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Explain returns the message of the error, followed by the field, provided value, allowed values and rule it
+// is known for.
+func (e *FieldError) Explain() string {
+	var details []string
+	if e.Field != "" {
+		details = append(details, "field: "+e.Field)
+	}
+	if e.Value != "" {
+		details = append(details, fmt.Sprintf("value: %q", e.Value))
+	}
+	if len(e.Allowed) > 0 {
+		details = append(details, "allowed: "+strings.Join(e.Allowed, ", "))
+	}
+	if e.Rule != "" {
+		details = append(details, "see "+e.Rule)
+	}
+	if len(details) == 0 {
+		return e.Error()
+	}
+	return fmt.Sprintf("%v (%s)", e.Err, strings.Join(details, "; "))
+}
+
+// invalidValue returns an error for a value, which is located by the callers of the validation with atField.
+func invalidValue(value any, allowed []string, err error) *FieldError {
+	return &FieldError{Value: fmt.Sprint(value), Allowed: allowed, Err: err}
+}
+
+// networkingRule returns the reference documentation of a message of a networking API.
+func networkingRule(page, message string) string {
+	return networkingReference + page + "/#" + message
+}
+
+// atField locates the errors of a field: the paths of the errors already located within the field are prefixed
+// with the path of the field, and the other errors are located at the field. The rule is set on the errors which
+// do not have a more precise one. Warnings are left as is.
+func atField(field, rule string, err error) error {
+	switch t := err.(type) {
+	case nil:
+		return nil
+	case Validation:
+		t.Err = atField(field, rule, t.Err)
+		return t
+	case *multierror.Error:
+		out := &multierror.Error{ErrorFormat: t.ErrorFormat}
+		for _, e := range t.Errors {
+			out.Errors = append(out.Errors, atField(field, rule, e))
+		}
+		return out
+	case *FieldError:
+		located := *t
+		switch {
+		case located.Field == "":
+			located.Field = field
+		case strings.HasPrefix(located.Field, "["):
+			located.Field = field + located.Field
+		default:
+			located.Field = field + "." + located.Field
+		}
+		if located.Rule == "" {
+			located.Rule = rule
+		}
+		return &located
+	default:
+		return &FieldError{Field: field, Rule: rule, Err: err}
+	}
+}
+
+// FieldErrors flattens the validation errors of a config. The errors which are not located have an empty field.
+func FieldErrors(err error) []*FieldError {
+	switch t := err.(type) {
+	case nil:
+		return nil
+	case Validation:
+		return FieldErrors(t.Err)
+	case *multierror.Error:
+		var out []*FieldError
+		for _, e := range t.Errors {
+			out = append(out, FieldErrors(e)...)
+		}
+		return out
+	case *FieldError:
+		return []*FieldError{t}
+	default:
+		return []*FieldError{{Err: err}}
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-multierror"
+
+	networking "istio.io/api/networking/v1alpha3"
+	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestAtField(t *testing.T) {
+	inner := atField("route[1]", "", multierror.Append(invalidValue(-1, nil, errors.New("weight must be positive")),
+		errors.New("destination is required")))
+	err := atField("spec.http[0]", "rule", appendValidation(Validation{}, inner, WrapWarning(errors.New("warning"))))
+
+	fieldErrs := FieldErrors(err)
+	assert.Equal(t, len(fieldErrs), 2)
+	assert.Equal(t, fieldErrs[0].Field, "spec.http[0].route[1]")
+	assert.Equal(t, fieldErrs[0].Value, "-1")
+	assert.Equal(t, fieldErrs[0].Rule, "rule")
+	assert.Equal(t, fieldErrs[0].Error(), "weight must be positive")
+	assert.Equal(t, fieldErrs[1].Field, "spec.http[0].route[1]")
+	assert.Equal(t, err.(Validation).Warning.Error(), "warning")
+	assert.Equal(t, atField("spec", "rule", nil), nil)
+}
+
+func TestFieldErrorExplain(t *testing.T) {
+	fe := &FieldError{
+		Field:   "spec.ports[0].protocol",
+		Value:   "QUIC",
+		Allowed: []string{"HTTP", "TCP"},
+		Rule:    networkingRule("service-entry", "ServiceEntry"),
+		Err:     errors.New("unsupported protocol: QUIC"),
+	}
+	assert.Equal(t, fe.Explain(), `unsupported protocol: QUIC (field: spec.ports[0].protocol; value: "QUIC"; allowed: HTTP, TCP; `+
+		`see https://istio.io/latest/docs/reference/config/networking/service-entry/#ServiceEntry)`)
+	assert.Equal(t, (&FieldError{Err: errors.New("invalid")}).Explain(), "invalid")
+}
+
+func TestServiceEntryFieldErrors(t *testing.T) {
+	_, err := ValidateServiceEntry(config.Config{
+		Meta: config.Meta{Name: "se", Namespace: "default"},
+		Spec: &networking.ServiceEntry{
+			Hosts:      []string{"*"},
+			Ports:      []*networking.Port{{Number: 80, Name: "http", Protocol: "QUIC"}},
+			Resolution: networking.ServiceEntry_NONE,
+		},
+	})
+	fieldErrs := FieldErrors(err)
+	assert.Equal(t, len(fieldErrs), 2)
+	assert.Equal(t, fieldErrs[0].Field, "spec.hosts[0]")
+	assert.Equal(t, fieldErrs[0].Value, "*")
+	assert.Equal(t, fieldErrs[1].Field, "spec.ports[0].protocol")
+	assert.Equal(t, fieldErrs[1].Allowed, supportedProtocols)
+	assert.Equal(t, fieldErrs[1].Error(), "unsupported protocol: QUIC")
+}
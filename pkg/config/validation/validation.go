@@ -50,13 +50,20 @@ import (
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/util/constant"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/consistenthash"
 	"istio.io/istio/pkg/config/constants"
 	"istio.io/istio/pkg/config/gateway"
+	"istio.io/istio/pkg/config/healthcheck"
 	"istio.io/istio/pkg/config/host"
 	"istio.io/istio/pkg/config/labels"
+	"istio.io/istio/pkg/config/listenerpatch"
+	"istio.io/istio/pkg/config/locality"
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/config/ratelimit"
 	"istio.io/istio/pkg/config/security"
+	telemetryconfig "istio.io/istio/pkg/config/telemetry"
 	"istio.io/istio/pkg/config/visibility"
+	"istio.io/istio/pkg/config/wasmplugin"
 	"istio.io/istio/pkg/config/xds"
 	"istio.io/istio/pkg/kube/apimirror"
 	"istio.io/istio/pkg/util/grpc"
@@ -220,8 +227,9 @@ func registerValidateFunc(name string, f ValidateFunc) ValidateFunc {
 func validateAnnotationDryRun(f ValidateFunc) ValidateFunc {
 	return func(config config.Config) (Warning, error) {
 		_, isAuthz := config.Spec.(*security_beta.AuthorizationPolicy)
-		// Only the AuthorizationPolicy supports the annotation "istio.io/dry-run".
-		if err := checkDryRunAnnotation(config, isAuthz); err != nil {
+		_, isPeerAuthn := config.Spec.(*security_beta.PeerAuthentication)
+		// Only the AuthorizationPolicy and PeerAuthentication support the annotation "istio.io/dry-run".
+		if err := checkDryRunAnnotation(config, isAuthz || isPeerAuthn); err != nil {
 			return nil, err
 		}
 		return f(config)
@@ -233,7 +241,12 @@ func checkDryRunAnnotation(cfg config.Config, allowed bool) error {
 		if !allowed {
 			return fmt.Errorf("%s/%s has unsupported annotation %s, please remove the annotation", cfg.Namespace, cfg.Name, annotation.IoIstioDryRun.Name)
 		}
-		if spec, ok := cfg.Spec.(*security_beta.AuthorizationPolicy); ok {
+		switch spec := cfg.Spec.(type) {
+		case *security_beta.PeerAuthentication:
+			if _, err := strconv.ParseBool(val); err != nil {
+				return fmt.Errorf("%s/%s has annotation %s with invalid value (%s): %v", cfg.Namespace, cfg.Name, annotation.IoIstioDryRun.Name, val, err)
+			}
+		case *security_beta.AuthorizationPolicy:
 			switch spec.Action {
 			case security_beta.AuthorizationPolicy_ALLOW, security_beta.AuthorizationPolicy_DENY:
 				if _, err := strconv.ParseBool(val); err != nil {
@@ -253,7 +266,7 @@ func ValidatePort(port int) error {
 	if 1 <= port && port <= 65535 {
 		return nil
 	}
-	return fmt.Errorf("port number %d must be in the range 1..65535", port)
+	return invalidValue(port, []string{"1-65535"}, fmt.Errorf("port number %d must be in the range 1..65535", port))
 }
 
 // ValidateFQDN checks a fully-qualified domain name
@@ -472,8 +485,9 @@ var ValidateGateway = registerValidateFunc("ValidateGateway",
 		if len(value.Servers) == 0 {
 			v = appendValidation(v, fmt.Errorf("gateway must have at least one server"))
 		} else {
-			for _, server := range value.Servers {
-				v = appendValidation(v, validateServer(server))
+			for i, server := range value.Servers {
+				v = appendValidation(v, atField(fmt.Sprintf("spec.servers[%d]", i), networkingRule("gateway", "Server"),
+					validateServer(server)))
 			}
 		}
 
@@ -659,6 +673,7 @@ var ValidateDestinationRule = registerValidateFunc("ValidateDestinationRule",
 		if !ok {
 			return nil, fmt.Errorf("cannot cast to destination rule")
 		}
+		drRule := networkingRule("destination-rule", "DestinationRule")
 		v := Validation{}
 		if features.EnableDestinationRuleInheritance {
 			if rule.Host == "" {
@@ -679,29 +694,101 @@ var ValidateDestinationRule = registerValidateFunc("ValidateDestinationRule",
 						fmt.Errorf("mesh/namespace destination rule cannot have portLevelSettings configured"))
 				}
 			} else {
-				v = appendValidation(v, ValidateWildcardDomain(rule.Host))
+				v = appendValidation(v, atField("spec.host", drRule, ValidateWildcardDomain(rule.Host)))
 			}
 		} else {
-			v = appendValidation(v, ValidateWildcardDomain(rule.Host))
+			v = appendValidation(v, atField("spec.host", drRule, ValidateWildcardDomain(rule.Host)))
 		}
 
-		v = appendValidation(v, validateTrafficPolicy(rule.TrafficPolicy))
+		v = appendValidation(v, atField("spec.trafficPolicy", networkingRule("destination-rule", "TrafficPolicy"),
+			validateTrafficPolicy(rule.TrafficPolicy)))
 
-		for _, subset := range rule.Subsets {
+		for i, subset := range rule.Subsets {
+			subsetField := fmt.Sprintf("spec.subsets[%d]", i)
 			if subset == nil {
-				v = appendValidation(v, errors.New("subset may not be null"))
+				v = appendValidation(v, atField(subsetField, drRule, errors.New("subset may not be null")))
 				continue
 			}
-			v = appendValidation(v, validateSubset(subset))
+			v = appendValidation(v, atField(subsetField, networkingRule("destination-rule", "Subset"), validateSubset(subset)))
 		}
-		v = appendValidation(v,
-			validateExportTo(cfg.Namespace, rule.ExportTo, false, rule.GetWorkloadSelector() != nil))
+		v = appendValidation(v, atField("spec.exportTo", drRule,
+			validateExportTo(cfg.Namespace, rule.ExportTo, false, rule.GetWorkloadSelector() != nil)))
+
+		v = appendValidation(v, atField("spec.workloadSelector", drRule, validateWorkloadSelector(rule.GetWorkloadSelector())))
 
-		v = appendValidation(v, validateWorkloadSelector(rule.GetWorkloadSelector()))
+		v = appendValidation(v, validateLocalityPriorityGroups(cfg.Annotations, rule.TrafficPolicy))
+		v = appendValidation(v, validateLocalityEjectionPolicy(cfg.Annotations, rule.TrafficPolicy))
+		v = appendValidation(v, validateConsistentHashKeys(cfg.Annotations))
 
 		return v.Unwrap()
 	})
 
+// validateLocalityEjectionPolicy validates the locality ejection policy annotation of a DestinationRule.
+func validateLocalityEjectionPolicy(annotations map[string]string, policy *networking.TrafficPolicy) (errs Validation) {
+	ejectionPolicy, err := locality.ParseEjectionPolicy(annotations)
+	if err != nil {
+		return appendValidation(errs, err)
+	}
+	if ejectionPolicy == nil {
+		return
+	}
+	if policy.GetOutlierDetection() == nil {
+		errs = appendValidation(errs, WrapWarning(fmt.Errorf("outlier detection policy must be provided for the locality ejection policy")))
+	}
+	return
+}
+
+// validateConsistentHashKeys validates the hash keys annotation of a DestinationRule.
+func validateConsistentHashKeys(annotations map[string]string) error {
+	_, err := consistenthash.ParseKeys(annotations)
+	return err
+}
+
+// validateLocalityPriorityGroups validates the locality priority groups annotation of a DestinationRule.
+func validateLocalityPriorityGroups(annotations map[string]string, policy *networking.TrafficPolicy) (errs Validation) {
+	priorityGroups, err := locality.ParsePriorityGroups(annotations)
+	if err != nil {
+		return appendValidation(errs, err)
+	}
+	if len(priorityGroups) == 0 {
+		return
+	}
+
+	srcLocalities := make([]string, 0, len(priorityGroups))
+	for _, pg := range priorityGroups {
+		srcLocalities = append(srcLocalities, pg.From)
+		if len(pg.Groups) == 0 {
+			errs = appendValidation(errs, fmt.Errorf("locality priority groups for %q must have at least one group", pg.From))
+		}
+		for i, group := range pg.Groups {
+			var totalWeight uint32
+			destLocalities := make([]string, 0, len(group))
+			for loc, weight := range group {
+				destLocalities = append(destLocalities, loc)
+				if weight <= 0 || weight > 100 {
+					errs = appendValidation(errs, fmt.Errorf("locality weight must be in range [1, 100]"))
+					return
+				}
+				totalWeight += weight
+			}
+			if totalWeight != 100 {
+				errs = appendValidation(errs, fmt.Errorf("total locality weight of priority group %d for %q is %v != 100", i, pg.From, totalWeight))
+				return
+			}
+			errs = appendValidation(errs, validateLocalities(destLocalities))
+		}
+	}
+	errs = appendValidation(errs, validateLocalities(srcLocalities))
+
+	if policy.GetOutlierDetection() == nil {
+		errs = appendValidation(errs, WrapWarning(fmt.Errorf("outlier detection policy must be provided for locality priority groups")))
+	}
+	if lb := policy.GetLoadBalancer().GetLocalityLbSetting(); len(lb.GetDistribute()) > 0 {
+		errs = appendValidation(errs, WrapWarning(fmt.Errorf("locality priority groups are ignored when 'distribute' is set")))
+	}
+	return
+}
+
 func validateExportTo(namespace string, exportTo []string, isServiceEntry bool, isDestinationRuleWithSelector bool) (errs error) {
 	if len(exportTo) > 0 {
 		// Make sure there are no duplicates
@@ -809,6 +896,13 @@ var ValidateEnvoyFilter = registerValidateFunc("ValidateEnvoyFilter",
 			errs = appendValidation(errs, WrapWarning(fmt.Errorf("Envoy filter: %s, will be applied to all services in namespace", warning))) // nolint: stylecheck
 		}
 
+		if s, f := cfg.Annotations[listenerpatch.Annotation]; f {
+			errs = appendValidation(errs, validateListenerPatchAnnotation(s, rule))
+		}
+		if s, f := cfg.Annotations[ratelimit.Annotation]; f {
+			errs = appendValidation(errs, validateRateLimitAnnotation(s, rule, cfg.Annotations))
+		}
+
 		for _, cp := range rule.ConfigPatches {
 			if cp == nil {
 				errs = appendValidation(errs, fmt.Errorf("Envoy filter: null config patch")) // nolint: stylecheck
@@ -922,6 +1016,67 @@ var ValidateEnvoyFilter = registerValidateFunc("ValidateEnvoyFilter",
 		return errs.Unwrap()
 	})
 
+// validateListenerPatchAnnotation validates the listener patch of an EnvoyFilter, which is compiled to the patches
+// of the EnvoyFilter and so cannot be combined with its own patches. Unlike raw patches, the compiled patches are
+// built strictly: the typed configs of the patch are rejected rather than warned about if they are not valid.
+func validateListenerPatchAnnotation(s string, ef *networking.EnvoyFilter) error {
+	lp, err := listenerpatch.Parse(s)
+	if err != nil {
+		return fmt.Errorf("Envoy filter: invalid %s annotation: %v", listenerpatch.Annotation, err) // nolint: stylecheck
+	}
+	if len(ef.ConfigPatches) > 0 {
+		return fmt.Errorf("Envoy filter: configPatches cannot be set with the %s annotation", listenerpatch.Annotation) // nolint: stylecheck
+	}
+	if len(lp.WorkloadSelector) > 0 || lp.Priority != 0 {
+		return fmt.Errorf("Envoy filter: the %s annotation cannot set workloadSelector or priority, "+ // nolint: stylecheck
+			"which are those of the EnvoyFilter", listenerpatch.Annotation)
+	}
+	patches, err := lp.ConfigPatches()
+	if err != nil {
+		return fmt.Errorf("Envoy filter: invalid %s annotation: %v", listenerpatch.Annotation, err) // nolint: stylecheck
+	}
+	for _, cp := range patches {
+		if _, err := xds.BuildXDSObjectFromStruct(cp.ApplyTo, cp.Patch.Value, true); err != nil {
+			return fmt.Errorf("Envoy filter: invalid %s annotation: %v: %v", listenerpatch.Annotation, cp.ApplyTo, err) // nolint: stylecheck
+		}
+	}
+	return nil
+}
+
+// validateRateLimitAnnotation validates the rate limit of an EnvoyFilter, which is compiled to the patches of the
+// EnvoyFilter like a listener patch, and so cannot be combined with its own patches or with a listener patch.
+func validateRateLimitAnnotation(s string, ef *networking.EnvoyFilter, annotations map[string]string) error {
+	rl, err := ratelimit.Parse(s)
+	if err != nil {
+		return fmt.Errorf("Envoy filter: invalid %s annotation: %v", ratelimit.Annotation, err) // nolint: stylecheck
+	}
+	if len(ef.ConfigPatches) > 0 {
+		return fmt.Errorf("Envoy filter: configPatches cannot be set with the %s annotation", ratelimit.Annotation) // nolint: stylecheck
+	}
+	if _, f := annotations[listenerpatch.Annotation]; f {
+		return fmt.Errorf("Envoy filter: the %s and %s annotations cannot be set together", // nolint: stylecheck
+			listenerpatch.Annotation, ratelimit.Annotation)
+	}
+	if len(rl.WorkloadSelector) > 0 {
+		return fmt.Errorf("Envoy filter: the %s annotation cannot set workloadSelector, "+ // nolint: stylecheck
+			"which is that of the EnvoyFilter", ratelimit.Annotation)
+	}
+	if !rl.MatchesSelector(ef.WorkloadSelector) {
+		return fmt.Errorf("Envoy filter: the workloadSelector must be %v to select the pods of the targetRef "+ // nolint: stylecheck
+			"of the %s annotation", rl.Selector(), ratelimit.Annotation)
+	}
+	patches, err := rl.ConfigPatches()
+	if err != nil {
+		return fmt.Errorf("Envoy filter: invalid %s annotation: %v", ratelimit.Annotation, err) // nolint: stylecheck
+	}
+	for _, cp := range patches {
+		if _, err := xds.BuildXDSObjectFromStruct(cp.ApplyTo, cp.Patch.Value, true); err != nil {
+			return fmt.Errorf("Envoy filter: invalid %s annotation: %v: %v", ratelimit.Annotation, cp.ApplyTo, err) // nolint: stylecheck
+		}
+	}
+	return nil
+}
+
 func validateListenerMatchName(name string) error {
 	if newName, f := xds.ReverseDeprecatedFilterNames[name]; f {
 		return WrapWarning(fmt.Errorf("using deprecated filter name %q; use %q instead", name, newName))
@@ -1390,9 +1545,8 @@ func validateOutlierDetection(outlier *networking.OutlierDetection) (errs Valida
 	}
 	// nolint: staticcheck
 	if outlier.ConsecutiveErrors != 0 {
-		warn := "outlier detection consecutive errors is deprecated, use consecutiveGatewayErrors or consecutive5xxErrors instead"
-		scope.Warnf(warn)
-		errs = appendValidation(errs, WrapWarning(errors.New(warn)))
+		scope.Warnf("%v", deprecatedConsecutiveErrors)
+		errs = appendValidation(errs, WrapWarning(deprecatedConsecutiveErrors))
 	}
 	if !outlier.SplitExternalLocalOriginErrors && outlier.ConsecutiveLocalOriginFailures.GetValue() > 0 {
 		err := "outlier detection consecutive local origin failures is specified, but split external local origin errors is set to false"
@@ -1468,9 +1622,8 @@ func validateLoadBalancer(settings *networking.LoadBalancerSettings, outlier *ne
 			}
 		}
 		if consistentHash.MinimumRingSize != 0 { // nolint: staticcheck
-			warn := "consistent hash MinimumRingSize is deprecated, use ConsistentHashLB's RingHash configuration instead"
-			scope.Warnf(warn)
-			errs = appendValidation(errs, WrapWarning(errors.New(warn)))
+			scope.Warnf("%v", deprecatedMinimumRingSize)
+			errs = appendValidation(errs, WrapWarning(deprecatedMinimumRingSize))
 		}
 		// nolint: staticcheck
 		if consistentHash.MinimumRingSize != 0 && consistentHash.GetHashAlgorithm() != nil {
@@ -2211,6 +2364,7 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 		if !ok {
 			return nil, errors.New("cannot cast to virtual service")
 		}
+		vsRule := networkingRule("virtual-service", "VirtualService")
 		errs := Validation{}
 		if len(virtualService.Hosts) == 0 {
 			// This must be delegate - enforce delegate validations.
@@ -2233,7 +2387,7 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 		if len(virtualService.Gateways) == 0 {
 			appliesToMesh = true
 		} else {
-			errs = appendValidation(errs, validateGatewayNames(virtualService.Gateways))
+			errs = appendValidation(errs, atField("spec.gateways", vsRule, validateGatewayNames(virtualService.Gateways)))
 			for _, gatewayName := range virtualService.Gateways {
 				if gatewayName == constants.IstioMeshGateway {
 					appliesToMesh = true
@@ -2261,14 +2415,16 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 		}
 
 		allHostsValid := true
-		for _, virtualHost := range virtualService.Hosts {
+		for i, virtualHost := range virtualService.Hosts {
+			hostField := fmt.Sprintf("spec.hosts[%d]", i)
 			if err := ValidateWildcardDomain(virtualHost); err != nil {
 				if !netutil.IsValidIPAddress(virtualHost) {
-					errs = appendValidation(errs, err)
+					errs = appendValidation(errs, atField(hostField, vsRule, invalidValue(virtualHost, nil, err)))
 					allHostsValid = false
 				}
 			} else if appliesToMesh && virtualHost == "*" {
-				errs = appendValidation(errs, fmt.Errorf("wildcard host * is not allowed for virtual services bound to the mesh gateway"))
+				errs = appendValidation(errs, atField(hostField, vsRule, invalidValue(virtualHost, nil,
+					fmt.Errorf("wildcard host * is not allowed for virtual services bound to the mesh gateway"))))
 				allHostsValid = false
 			}
 		}
@@ -2291,19 +2447,24 @@ var ValidateVirtualService = registerValidateFunc("ValidateVirtualService",
 		if len(virtualService.Http) == 0 && len(virtualService.Tcp) == 0 && len(virtualService.Tls) == 0 {
 			errs = appendValidation(errs, errors.New("http, tcp or tls must be provided in virtual service"))
 		}
-		for _, httpRoute := range virtualService.Http {
+		httpRule := networkingRule("virtual-service", "HTTPRoute")
+		for i, httpRoute := range virtualService.Http {
+			httpField := fmt.Sprintf("spec.http[%d]", i)
 			if httpRoute == nil {
-				errs = appendValidation(errs, errors.New("http route may not be null"))
+				errs = appendValidation(errs, atField(httpField, httpRule, errors.New("http route may not be null")))
 				continue
 			}
 			gatewaySemantics := cfg.Annotations[constants.InternalRouteSemantics] == constants.RouteSemanticsGateway
-			errs = appendValidation(errs, validateHTTPRoute(httpRoute, len(virtualService.Hosts) == 0, gatewaySemantics))
+			errs = appendValidation(errs, atField(httpField, httpRule,
+				validateHTTPRoute(httpRoute, len(virtualService.Hosts) == 0, gatewaySemantics)))
 		}
-		for _, tlsRoute := range virtualService.Tls {
-			errs = appendValidation(errs, validateTLSRoute(tlsRoute, virtualService))
+		for i, tlsRoute := range virtualService.Tls {
+			errs = appendValidation(errs, atField(fmt.Sprintf("spec.tls[%d]", i), networkingRule("virtual-service", "TLSRoute"),
+				validateTLSRoute(tlsRoute, virtualService)))
 		}
-		for _, tcpRoute := range virtualService.Tcp {
-			errs = appendValidation(errs, validateTCPRoute(tcpRoute))
+		for i, tcpRoute := range virtualService.Tcp {
+			errs = appendValidation(errs, atField(fmt.Sprintf("spec.tcp[%d]", i), networkingRule("virtual-service", "TCPRoute"),
+				validateTCPRoute(tcpRoute)))
 		}
 
 		errs = appendValidation(errs, validateExportTo(cfg.Namespace, virtualService.ExportTo, false, false))
@@ -3268,6 +3429,7 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 			return nil, fmt.Errorf("cannot cast to service entry")
 		}
 
+		seRule := networkingRule("service-entry", "ServiceEntry")
 		errs := Validation{}
 
 		warning, err := validateAlphaWorkloadSelector(serviceEntry.WorkloadSelector)
@@ -3289,19 +3451,22 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 		if len(serviceEntry.Hosts) == 0 {
 			errs = appendValidation(errs, fmt.Errorf("service entry must have at least one host"))
 		}
-		for _, hostname := range serviceEntry.Hosts {
+		for i, hostname := range serviceEntry.Hosts {
+			hostField := fmt.Sprintf("spec.hosts[%d]", i)
 			// Full wildcard is not allowed in the service entry.
 			if hostname == "*" {
-				errs = appendValidation(errs, fmt.Errorf("invalid host %s", hostname))
-			} else {
-				errs = appendValidation(errs, ValidateWildcardDomain(hostname))
+				errs = appendValidation(errs, atField(hostField, seRule, invalidValue(hostname, nil, fmt.Errorf("invalid host %s", hostname))))
+			} else if err := ValidateWildcardDomain(hostname); err != nil {
+				errs = appendValidation(errs, atField(hostField, seRule, invalidValue(hostname, nil, err)))
 			}
 		}
 
 		cidrFound := false
-		for _, address := range serviceEntry.Addresses {
+		for i, address := range serviceEntry.Addresses {
 			cidrFound = cidrFound || strings.Contains(address, "/")
-			errs = appendValidation(errs, ValidateIPSubnet(address))
+			if err := ValidateIPSubnet(address); err != nil {
+				errs = appendValidation(errs, atField(fmt.Sprintf("spec.addresses[%d]", i), seRule, invalidValue(address, nil, err)))
+			}
 		}
 
 		if cidrFound {
@@ -3312,9 +3477,10 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 
 		servicePortNumbers := make(map[uint32]bool)
 		servicePorts := make(map[string]bool, len(serviceEntry.Ports))
-		for _, port := range serviceEntry.Ports {
+		for i, port := range serviceEntry.Ports {
+			portField := fmt.Sprintf("spec.ports[%d]", i)
 			if port == nil {
-				errs = appendValidation(errs, fmt.Errorf("service entry port may not be null"))
+				errs = appendValidation(errs, atField(portField, seRule, fmt.Errorf("service entry port may not be null")))
 				continue
 			}
 			if servicePorts[port.Name] {
@@ -3326,7 +3492,7 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 			}
 			servicePortNumbers[port.Number] = true
 			if port.TargetPort != 0 {
-				errs = appendValidation(errs, ValidatePort(int(port.TargetPort)))
+				errs = appendValidation(errs, atField(portField+".targetPort", seRule, ValidatePort(int(port.TargetPort))))
 			}
 			if len(serviceEntry.Addresses) == 0 {
 				if port.Protocol == "" || port.Protocol == "TCP" {
@@ -3334,9 +3500,9 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 				}
 			}
 			errs = appendValidation(errs,
-				ValidatePortName(port.Name),
-				ValidateProtocol(port.Protocol),
-				ValidatePort(int(port.Number)))
+				atField(portField+".name", seRule, ValidatePortName(port.Name)),
+				atField(portField+".protocol", seRule, ValidateProtocol(port.Protocol)),
+				atField(portField+".number", seRule, ValidatePort(int(port.Number))))
 		}
 
 		switch serviceEntry.Resolution {
@@ -3380,6 +3546,9 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 					}
 				}
 			}
+			if cfg.Annotations[constants.DNSSRVAnnotation] == "true" && len(serviceEntry.Ports) != 1 {
+				errs = appendValidation(errs, fmt.Errorf("exactly 1 service port required for endpoints resolved from SRV records"))
+			}
 			if serviceEntry.Resolution == networking.ServiceEntry_DNS_ROUND_ROBIN && len(serviceEntry.Endpoints) > 1 {
 				errs = appendValidation(errs,
 					fmt.Errorf("there must only be 0 or 1 endpoint for resolution mode %s", serviceEntry.Resolution))
@@ -3417,8 +3586,9 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 				}
 			}
 		default:
-			errs = appendValidation(errs, fmt.Errorf("unsupported resolution type %s",
-				networking.ServiceEntry_Resolution_name[int32(serviceEntry.Resolution)]))
+			resolution := networking.ServiceEntry_Resolution_name[int32(serviceEntry.Resolution)]
+			errs = appendValidation(errs, atField("spec.resolution", seRule, invalidValue(resolution,
+				[]string{"NONE", "STATIC", "DNS", "DNS_ROUND_ROBIN"}, fmt.Errorf("unsupported resolution type %s", resolution))))
 		}
 
 		// multiple hosts and TCP is invalid unless the resolution type is NONE.
@@ -3438,7 +3608,13 @@ var ValidateServiceEntry = registerValidateFunc("ValidateServiceEntry",
 			}
 		}
 
-		errs = appendValidation(errs, validateExportTo(cfg.Namespace, serviceEntry.ExportTo, true, false))
+		if v, f := cfg.Annotations[healthcheck.Annotation]; f {
+			if _, err := healthcheck.Parse(v); err != nil {
+				errs = appendValidation(errs, fmt.Errorf("invalid %s annotation: %v", healthcheck.Annotation, err))
+			}
+		}
+
+		errs = appendValidation(errs, atField("spec.exportTo", seRule, validateExportTo(cfg.Namespace, serviceEntry.ExportTo, true, false)))
 		return errs.Unwrap()
 	})
 
@@ -3450,11 +3626,18 @@ func ValidatePortName(name string) error {
 	return nil
 }
 
+// supportedProtocols are the protocols of the ports, which are matched case insensitively.
+var supportedProtocols = []string{
+	string(protocol.GRPC), string(protocol.GRPCWeb), string(protocol.HTTP), string(protocol.HTTP2), string(protocol.HTTPS),
+	string(protocol.TCP), string(protocol.TLS), string(protocol.UDP), string(protocol.Mongo), string(protocol.Redis),
+	string(protocol.MySQL),
+}
+
 // ValidateProtocol validates a portocol name is known
 func ValidateProtocol(protocolStr string) error {
 	// Empty string is used for protocol sniffing.
 	if protocolStr != "" && protocol.Parse(protocolStr) == protocol.Unsupported {
-		return fmt.Errorf("unsupported protocol: %s", protocolStr)
+		return invalidValue(protocolStr, supportedProtocols, fmt.Errorf("unsupported protocol: %s", protocolStr))
 	}
 	return nil
 }
@@ -3739,10 +3922,19 @@ var ValidateTelemetry = registerValidateFunc("ValidateTelemetry",
 			validateTelemetryMetrics(spec.Metrics),
 			validateTelemetryTracing(spec.Tracing),
 			validateTelemetryAccessLogging(spec.AccessLogging),
+			validateTelemetryMetricExpiry(cfg.Annotations),
 		)
 		return errs.Unwrap()
 	})
 
+// validateTelemetryMetricExpiry validates the metric expiry annotation of a Telemetry.
+func validateTelemetryMetricExpiry(annotations map[string]string) (v Validation) {
+	if _, err := telemetryconfig.ParseMetricExpiry(annotations); err != nil {
+		return appendValidation(v, err)
+	}
+	return
+}
+
 func validateTelemetryAccessLogging(logging []*telemetry.AccessLogging) (v Validation) {
 	for _, l := range logging {
 		if l == nil {
@@ -3865,10 +4057,16 @@ var ValidateWasmPlugin = registerValidateFunc("ValidateWasmPlugin",
 			validateWasmPluginSHA(spec),
 			validateWasmPluginVMConfig(spec.VmConfig),
 			validateWasmPluginMatch(spec.Match),
+			validateWasmPluginFailurePolicy(cfg.Annotations),
 		)
 		return errs.Unwrap()
 	})
 
+func validateWasmPluginFailurePolicy(annotations map[string]string) error {
+	_, err := wasmplugin.ParseFailurePolicyAnnotation(annotations, wasmplugin.FallbackToPrevious)
+	return err
+}
+
 func validateWasmPluginURL(pluginURL string) error {
 	if pluginURL == "" {
 		return fmt.Errorf("url field needs to be set")
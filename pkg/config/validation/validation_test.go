@@ -37,7 +37,14 @@ import (
 	api "istio.io/api/type/v1beta1"
 	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config"
+	"istio.io/istio/pkg/config/consistenthash"
 	"istio.io/istio/pkg/config/constants"
+	"istio.io/istio/pkg/config/healthcheck"
+	"istio.io/istio/pkg/config/listenerpatch"
+	"istio.io/istio/pkg/config/locality"
+	"istio.io/istio/pkg/config/ratelimit"
+	telemetryconfig "istio.io/istio/pkg/config/telemetry"
+	"istio.io/istio/pkg/config/wasmplugin"
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/util/assert"
 )
@@ -3406,6 +3413,21 @@ func TestValidateWorkloadGroup(t *testing.T) {
 			},
 			valid: true,
 		},
+		{
+			name: "probe grpc scheme",
+			in: &networking.WorkloadGroup{
+				Template: &networking.WorkloadEntry{},
+				Probe: &networking.ReadinessProbe{
+					HealthCheckMethod: &networking.ReadinessProbe_HttpGet{
+						HttpGet: &networking.HTTPHealthCheckConfig{
+							Port:   5,
+							Scheme: "GRPC",
+						},
+					},
+				},
+			},
+			valid: false,
+		},
 		{
 			name: "probe tcp invalid",
 			in: &networking.WorkloadGroup{
@@ -4451,6 +4473,96 @@ func TestValidateEnvoyFilter(t *testing.T) {
 	}
 }
 
+func TestValidateEnvoyFilterListenerPatch(t *testing.T) {
+	circuitBreaker := "circuitBreakers:\n- service: ratings.default.svc.cluster.local\n  maxConnections: 100\n"
+	httpFilter := func(typedConfig string) string {
+		return "httpFilters:\n- context: SIDECAR_INBOUND\n  name: envoy.filters.http.ext_authz\n  typedConfig: " + typedConfig + "\n"
+	}
+	tests := []struct {
+		name  string
+		patch string
+		in    *networking.EnvoyFilter
+		error string
+	}{
+		{name: "valid", patch: circuitBreaker, in: &networking.EnvoyFilter{Priority: 10}},
+		{name: "invalid", patch: "circuitBreakers:\n- service: ratings.default.svc.cluster.local\n", in: &networking.EnvoyFilter{},
+			error: "at least one threshold must be set"},
+		{name: "unknown field", patch: "circuitBreaker: []", in: &networking.EnvoyFilter{}, error: "invalid listener patch"},
+		{name: "config patches", patch: circuitBreaker, in: &networking.EnvoyFilter{
+			ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{{
+				ApplyTo: networking.EnvoyFilter_CLUSTER,
+				Patch:   &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_REMOVE},
+			}},
+		}, error: "configPatches cannot be set"},
+		{name: "workload selector", patch: "workloadSelector:\n  app: reviews\n" + circuitBreaker, in: &networking.EnvoyFilter{},
+			error: "cannot set workloadSelector or priority"},
+		{name: "valid typed config", patch: httpFilter(`{"@type": "type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthz",
+      "failureModeAllow": true}`), in: &networking.EnvoyFilter{}},
+		{name: "unknown typed config field", patch: httpFilter(`{"@type": "type.googleapis.com/envoy.extensions.filters.http.ext_authz.v3.ExtAuthz",
+      "failureModeAlow": true}`), in: &networking.EnvoyFilter{}, error: "HTTP_FILTER"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warn, err := ValidateEnvoyFilter(config.Config{
+				Meta: config.Meta{
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: map[string]string{listenerpatch.Annotation: tt.patch},
+				},
+				Spec: tt.in,
+			})
+			checkValidationMessage(t, warn, err, "", tt.error)
+		})
+	}
+}
+
+func TestValidateEnvoyFilterRateLimit(t *testing.T) {
+	local := "local:\n  maxTokens: 100\n  fillInterval: 1s\n"
+	targetRef := "targetRef:\n  group: gateway.networking.k8s.io\n  kind: Gateway\n  name: gateway\n"
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		in          *networking.EnvoyFilter
+		error       string
+	}{
+		{name: "valid", annotations: map[string]string{ratelimit.Annotation: local}, in: &networking.EnvoyFilter{}},
+		{name: "invalid", annotations: map[string]string{ratelimit.Annotation: "local:\n  maxTokens: 100\n  fillInterval: 1ms\n"},
+			in: &networking.EnvoyFilter{}, error: "fillInterval must be at least 50ms"},
+		{name: "unknown field", annotations: map[string]string{ratelimit.Annotation: "locals: {}"}, in: &networking.EnvoyFilter{},
+			error: "invalid rate limit"},
+		{name: "config patches", annotations: map[string]string{ratelimit.Annotation: local}, in: &networking.EnvoyFilter{
+			ConfigPatches: []*networking.EnvoyFilter_EnvoyConfigObjectPatch{{
+				ApplyTo: networking.EnvoyFilter_CLUSTER,
+				Patch:   &networking.EnvoyFilter_Patch{Operation: networking.EnvoyFilter_Patch_REMOVE},
+			}},
+		}, error: "configPatches cannot be set"},
+		{name: "listener patch", annotations: map[string]string{
+			ratelimit.Annotation:     local,
+			listenerpatch.Annotation: "circuitBreakers:\n- service: ratings.default.svc.cluster.local\n  maxConnections: 100\n",
+		}, in: &networking.EnvoyFilter{}, error: "cannot be set together"},
+		{name: "workload selector", annotations: map[string]string{ratelimit.Annotation: "workloadSelector:\n  app: reviews\n" + local},
+			in: &networking.EnvoyFilter{}, error: "cannot set workloadSelector"},
+		{name: "target ref", annotations: map[string]string{ratelimit.Annotation: targetRef + local}, in: &networking.EnvoyFilter{
+			WorkloadSelector: &networking.WorkloadSelector{Labels: map[string]string{"istio.io/gateway-name": "gateway"}},
+		}},
+		{name: "target ref without its selector", annotations: map[string]string{ratelimit.Annotation: targetRef + local},
+			in: &networking.EnvoyFilter{}, error: "to select the pods of the targetRef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warn, err := ValidateEnvoyFilter(config.Config{
+				Meta: config.Meta{
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: tt.annotations,
+				},
+				Spec: tt.in,
+			})
+			checkValidationMessage(t, warn, err, "", tt.error)
+		})
+	}
+}
+
 func TestValidateServiceEntries(t *testing.T) {
 	cases := []struct {
 		name    string
@@ -5106,6 +5218,55 @@ func TestValidateServiceEntries(t *testing.T) {
 	}
 }
 
+func TestValidateServiceEntryDNSSRV(t *testing.T) {
+	for _, ports := range [][]*networking.Port{
+		{{Number: 80, Name: "http", Protocol: "HTTP"}},
+		{{Number: 80, Name: "http", Protocol: "HTTP"}, {Number: 443, Name: "https", Protocol: "HTTPS"}},
+	} {
+		_, err := ValidateServiceEntry(config.Config{
+			Meta: config.Meta{
+				Name:        someName,
+				Namespace:   someNamespace,
+				Annotations: map[string]string{constants.DNSSRVAnnotation: "true"},
+			},
+			Spec: &networking.ServiceEntry{
+				Hosts:      []string{"web.service.consul"},
+				Ports:      ports,
+				Resolution: networking.ServiceEntry_DNS,
+			},
+		})
+		if valid := len(ports) == 1; (err == nil) != valid {
+			t.Errorf("%d ports: got valid=%v but wanted valid=%v: %v", len(ports), err == nil, valid, err)
+		}
+	}
+}
+
+func TestValidateServiceEntryHealthCheck(t *testing.T) {
+	for hc, valid := range map[string]bool{
+		`{"http": {"path": "/healthz"}, "interval": "5s"}`: true,
+		`{"tcp": {}}`:                   true,
+		`{"http": {"path": "healthz"}}`: false,
+		`{"tcp": {}, "interval": "5"}`:  false,
+	} {
+		_, err := ValidateServiceEntry(config.Config{
+			Meta: config.Meta{
+				Name:        someName,
+				Namespace:   someNamespace,
+				Annotations: map[string]string{healthcheck.Annotation: hc},
+			},
+			Spec: &networking.ServiceEntry{
+				Hosts:      []string{"backend.example.com"},
+				Ports:      []*networking.Port{{Number: 80, Name: "http", Protocol: "HTTP"}},
+				Endpoints:  []*networking.WorkloadEntry{{Address: "10.0.0.1"}},
+				Resolution: networking.ServiceEntry_STATIC,
+			},
+		})
+		if (err == nil) != valid {
+			t.Errorf("%s: got valid=%v but wanted valid=%v: %v", hc, err == nil, valid, err)
+		}
+	}
+}
+
 func TestValidateAuthorizationPolicy(t *testing.T) {
 	cases := []struct {
 		name        string
@@ -7095,6 +7256,94 @@ func TestValidateLocalityLbSetting(t *testing.T) {
 	}
 }
 
+func TestValidateLocalityPriorityGroups(t *testing.T) {
+	outlier := &networking.TrafficPolicy{OutlierDetection: &networking.OutlierDetection{}}
+	cases := []struct {
+		name   string
+		value  string
+		policy *networking.TrafficPolicy
+		err    bool
+		warn   bool
+	}{
+		{
+			name: "valid",
+			value: `
+- from: us-east
+  groups:
+  - us-east/*: 100
+  - us-central/*: 70
+    us-west/*: 30
+`,
+			policy: outlier,
+		},
+		{
+			name:  "missing outlier detection",
+			value: `[{from: us-east, groups: [{us-east/*: 100}]}]`,
+			warn:  true,
+		},
+		{
+			name:   "invalid yaml",
+			value:  `[{from: us-east, groups: us-east}]`,
+			policy: outlier,
+			err:    true,
+		},
+		{
+			name:   "no groups",
+			value:  `[{from: us-east}]`,
+			policy: outlier,
+			err:    true,
+		},
+		{
+			name:   "weights not adding up to 100",
+			value:  `[{from: us-east, groups: [{us-east/*: 50, us-west/*: 30}]}]`,
+			policy: outlier,
+			err:    true,
+		},
+		{
+			name:   "overlapping localities",
+			value:  `[{from: us-east, groups: [{us-east/*: 50, us-east/zone1: 50}]}]`,
+			policy: outlier,
+			err:    true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := validateLocalityPriorityGroups(map[string]string{locality.PriorityGroupsAnnotation: c.value}, c.policy)
+			warn, err := v.Unwrap()
+			if (err != nil) != c.err {
+				t.Errorf("got err=%v but wanted err=%v", err, c.err)
+			}
+			if (warn != nil) != c.warn {
+				t.Errorf("got warn=%v but wanted warn=%v", warn, c.warn)
+			}
+		})
+	}
+}
+
+func TestValidateDestinationRuleConsistentHashKeys(t *testing.T) {
+	for value, valid := range map[string]bool{
+		"cookie:session, source-ip": true,
+		"header:x-user,query:id":    true,
+		"cookie:session, invalid":   false,
+		"header:":                   false,
+	} {
+		t.Run(value, func(t *testing.T) {
+			_, err := ValidateDestinationRule(config.Config{
+				Meta: config.Meta{
+					Name:        "reviews",
+					Namespace:   "default",
+					Annotations: map[string]string{consistenthash.KeysAnnotation: value},
+				},
+				Spec: &networking.DestinationRule{Host: "reviews"},
+			})
+			if (err == nil) != valid {
+				t.Fatalf("got err=%v but wanted valid=%v", err, valid)
+			}
+		})
+	}
+}
+
 func TestValidateLocalities(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -7556,10 +7805,11 @@ func TestValidateRequestAuthentication(t *testing.T) {
 
 func TestValidatePeerAuthentication(t *testing.T) {
 	cases := []struct {
-		name       string
-		configName string
-		in         proto.Message
-		valid      bool
+		name        string
+		configName  string
+		annotations map[string]string
+		in          proto.Message
+		valid       bool
 	}{
 		{
 			name:       "empty spec",
@@ -7657,14 +7907,33 @@ func TestValidatePeerAuthentication(t *testing.T) {
 			},
 			valid: true,
 		},
+		{
+			name:        "dry run",
+			configName:  someName,
+			annotations: map[string]string{"istio.io/dry-run": "true"},
+			in: &security_beta.PeerAuthentication{
+				Mtls: &security_beta.PeerAuthentication_MutualTLS{
+					Mode: security_beta.PeerAuthentication_MutualTLS_STRICT,
+				},
+			},
+			valid: true,
+		},
+		{
+			name:        "dry run invalid value",
+			configName:  someName,
+			annotations: map[string]string{"istio.io/dry-run": "foo"},
+			in:          &security_beta.PeerAuthentication{},
+			valid:       false,
+		},
 	}
 
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			if _, got := ValidatePeerAuthentication(config.Config{
 				Meta: config.Meta{
-					Name:      c.configName,
-					Namespace: someNamespace,
+					Name:        c.configName,
+					Namespace:   someNamespace,
+					Annotations: c.annotations,
 				},
 				Spec: c.in,
 			}); (got == nil) != c.valid {
@@ -8119,6 +8388,40 @@ func TestValidateTelemetry(t *testing.T) {
 	}
 }
 
+func TestValidateTelemetryMetricExpiry(t *testing.T) {
+	stackdriver := []*telemetry.Metrics{{Providers: []*telemetry.ProviderRef{{Name: "stackdriver"}}}}
+	tests := []struct {
+		name    string
+		expiry  string
+		metrics []*telemetry.Metrics
+		err     string
+		warning string
+	}{
+		{"stackdriver", "10m", stackdriver, "", ""},
+		{"invalid", "10", stackdriver, "invalid telemetry.istio.io/metric-expiry annotation", ""},
+		{"negative", "-1m", stackdriver, "must be positive", ""},
+		{"default provider", "10m", []*telemetry.Metrics{{}}, "", ""},
+		{
+			"prometheus", "10m",
+			[]*telemetry.Metrics{{Providers: []*telemetry.ProviderRef{{Name: "stackdriver"}, {Name: "prometheus"}}}},
+			"", "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warn, err := ValidateTelemetry(config.Config{
+				Meta: config.Meta{
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: map[string]string{telemetryconfig.MetricExpiryAnnotation: tt.expiry},
+				},
+				Spec: &telemetry.Telemetry{Metrics: tt.metrics},
+			})
+			checkValidationMessage(t, warn, err, tt.warning, tt.err)
+		})
+	}
+}
+
 func TestValidateProxyConfig(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -8310,6 +8613,27 @@ func TestValidateWasmPlugin(t *testing.T) {
 	}
 }
 
+func TestValidateWasmPluginFailurePolicy(t *testing.T) {
+	for policy, out := range map[string]string{
+		"FAIL_OPEN":            "",
+		"FAIL_CLOSE":           "",
+		"FALLBACK_TO_PREVIOUS": "",
+		"fail_open":            "invalid failure policy",
+	} {
+		t.Run(policy, func(t *testing.T) {
+			warn, err := ValidateWasmPlugin(config.Config{
+				Meta: config.Meta{
+					Name:        someName,
+					Namespace:   someNamespace,
+					Annotations: map[string]string{wasmplugin.FailurePolicyAnnotation: policy},
+				},
+				Spec: &extensions.WasmPlugin{Url: "http://test.com/test"},
+			})
+			checkValidationMessage(t, warn, err, "", out)
+		})
+	}
+}
+
 func TestRecurseMissingTypedConfig(t *testing.T) {
 	good := &listener.Filter{
 		Name:       wellknown.TCPProxy,
@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+const (
+	// MinVersionAnnotation restricts a config to the control planes of at least the given Istio version, such as
+	// "1.17" or "1.17.2". Older control planes ignore the config, so that a config using fields they do not
+	// support can be applied during a canary upgrade.
+	MinVersionAnnotation = "istio.io/min-version"
+	// MaxVersionAnnotation restricts a config to the control planes of at most the given Istio version. Newer
+	// control planes ignore the config.
+	MaxVersionAnnotation = "istio.io/max-version"
+)
+
+var versionRegexp = regexp.MustCompile(`^(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// parseVersion returns the major, minor and, if set, patch components of the version.
+func parseVersion(v string) []int {
+	m := versionRegexp.FindStringSubmatch(v)
+	if m == nil {
+		return nil
+	}
+	out := make([]int, 0, 3)
+	for _, c := range m[1:] {
+		if c == "" {
+			break
+		}
+		n, _ := strconv.Atoi(c)
+		out = append(out, n)
+	}
+	return out
+}
+
+// compareVersions compares the version to the bound, up to the components of the bound: 1.16.3 is equal
+// to the bound 1.16.
+func compareVersions(v, bound []int) int {
+	for i := range bound {
+		c := 0
+		if i < len(v) {
+			c = v[i]
+		}
+		if c != bound[i] {
+			if c < bound[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// ValidateVersionAnnotations returns an error if the version pinning annotations cannot be parsed.
+func ValidateVersionAnnotations(annotations map[string]string) error {
+	for _, a := range []string{MinVersionAnnotation, MaxVersionAnnotation} {
+		if v, f := annotations[a]; f && parseVersion(v) == nil {
+			return fmt.Errorf("invalid %s annotation %q, expected a version such as 1.17 or 1.17.2", a, v)
+		}
+	}
+	return nil
+}
+
+// ObjectInVersion returns true if a control plane of the version applies the config with the annotations, per
+// its MinVersionAnnotation and MaxVersionAnnotation. Control plane versions that cannot be parsed, such as
+// unknown builds, and invalid annotations do not restrict the config.
+func ObjectInVersion(annotations map[string]string, version string) bool {
+	v := parseVersion(version)
+	if v == nil {
+		return true
+	}
+	if min := parseVersion(annotations[MinVersionAnnotation]); min != nil && compareVersions(v, min) < 0 {
+		return false
+	}
+	if max := parseVersion(annotations[MaxVersionAnnotation]); max != nil && compareVersions(v, max) > 0 {
+		return false
+	}
+	return true
+}
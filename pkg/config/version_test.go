@@ -0,0 +1,59 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import "testing"
+
+func TestObjectInVersion(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		version     string
+		want        bool
+	}{
+		{name: "no annotations", version: "1.17.0", want: true},
+		{name: "min", annotations: map[string]string{MinVersionAnnotation: "1.17"}, version: "1.17.0", want: true},
+		{name: "below min", annotations: map[string]string{MinVersionAnnotation: "1.17"}, version: "1.16.5", want: false},
+		{name: "below min patch", annotations: map[string]string{MinVersionAnnotation: "1.17.2"}, version: "1.17.1", want: false},
+		{name: "max includes patches", annotations: map[string]string{MaxVersionAnnotation: "1.16"}, version: "1.16.9", want: true},
+		{name: "above max", annotations: map[string]string{MaxVersionAnnotation: "1.16"}, version: "1.17.0", want: false},
+		{name: "dev build", annotations: map[string]string{MinVersionAnnotation: "1.17"}, version: "1.17-dev", want: true},
+		{name: "pre-release", annotations: map[string]string{MaxVersionAnnotation: "1.16"}, version: "1.17.0-beta.1", want: false},
+		{
+			name:        "range",
+			annotations: map[string]string{MinVersionAnnotation: "1.16", MaxVersionAnnotation: "1.17"},
+			version:     "1.17.3",
+			want:        true,
+		},
+		{name: "unknown version", annotations: map[string]string{MinVersionAnnotation: "1.17"}, version: "unknown", want: true},
+		{name: "invalid annotation", annotations: map[string]string{MinVersionAnnotation: "latest"}, version: "1.16.0", want: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := ObjectInVersion(c.annotations, c.version); got != c.want {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestValidateVersionAnnotations(t *testing.T) {
+	if err := ValidateVersionAnnotations(map[string]string{MinVersionAnnotation: "1.17", MaxVersionAnnotation: "1.18.1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateVersionAnnotations(map[string]string{MaxVersionAnnotation: "v1"}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wasmplugin holds the WasmPlugin settings configured with annotations.
+package wasmplugin
+
+import (
+	"fmt"
+)
+
+// FailurePolicyAnnotation sets what a proxy does when the module of a WasmPlugin cannot be fetched or loaded, for
+// example:
+//
+//	extensions.istio.io/failure-policy: FAIL_CLOSE
+//
+// Without it, the policy is the one of PILOT_WASM_DEFAULT_FAILURE_POLICY.
+const FailurePolicyAnnotation = "extensions.istio.io/failure-policy"
+
+// FailurePolicy is what a proxy does when the module of a WasmPlugin cannot be fetched or loaded.
+type FailurePolicy string
+
+const (
+	// FailOpen skips the plugin: the requests are processed as if it was not configured. The plugin is also
+	// skipped if its VM fails at runtime.
+	FailOpen FailurePolicy = "FAIL_OPEN"
+	// FailClose rejects all the requests of the filter chains of the plugin.
+	FailClose FailurePolicy = "FAIL_CLOSE"
+	// FallbackToPrevious rejects the new configuration of the plugin, so that the proxy keeps the previous one.
+	// The listeners of the plugin are not ready until a configuration of the plugin is loaded.
+	FallbackToPrevious FailurePolicy = "FALLBACK_TO_PREVIOUS"
+)
+
+// ParseFailurePolicy parses a failure policy.
+func ParseFailurePolicy(value string) (FailurePolicy, error) {
+	switch p := FailurePolicy(value); p {
+	case FailOpen, FailClose, FallbackToPrevious:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid failure policy %q, must be one of %s, %s or %s", value, FailOpen, FailClose, FallbackToPrevious)
+}
+
+// ParseFailurePolicyAnnotation returns the failure policy configured in the annotations, or the default policy
+// if there is none.
+func ParseFailurePolicyAnnotation(annotations map[string]string, def FailurePolicy) (FailurePolicy, error) {
+	value, f := annotations[FailurePolicyAnnotation]
+	if !f {
+		return def, nil
+	}
+	p, err := ParseFailurePolicy(value)
+	if err != nil {
+		return def, fmt.Errorf("invalid %s annotation: %v", FailurePolicyAnnotation, err)
+	}
+	return p, nil
+}
@@ -0,0 +1,42 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasmplugin
+
+import "testing"
+
+func TestParseFailurePolicyAnnotation(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+		want        FailurePolicy
+		wantErr     bool
+	}{
+		{name: "default", want: FallbackToPrevious},
+		{name: "fail open", annotations: map[string]string{FailurePolicyAnnotation: "FAIL_OPEN"}, want: FailOpen},
+		{name: "fail close", annotations: map[string]string{FailurePolicyAnnotation: "FAIL_CLOSE"}, want: FailClose},
+		{name: "invalid", annotations: map[string]string{FailurePolicyAnnotation: "fail_open"}, want: FallbackToPrevious, wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFailurePolicyAnnotation(tt.annotations, FallbackToPrevious)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -45,6 +45,9 @@ type LocalDNSServer struct {
 	// nameTable holds the original NameTable, for debugging
 	nameTable atomic.Value
 
+	// forwardingRules holds the forwarding rules of the NameTable, as []forwardingRule
+	forwardingRules atomic.Value
+
 	dnsProxies []*dnsProxy
 
 	resolvConfServers []string
@@ -58,6 +61,10 @@ type LocalDNSServer struct {
 
 	respondBeforeSync         bool
 	forwardToUpstreamParallel bool
+
+	upstreamPolicy UpstreamPolicy
+	// cache holds the upstream responses, if they can be served stale
+	cache *responseCache
 }
 
 // LookupTable is borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hostsfile.go
@@ -77,6 +84,8 @@ type LookupTable struct {
 	// The cname records here (comprised of different variants of the hosts above,
 	// expanded by the search namespaces) pointing to the actual host.
 	cname map[string][]dns.RR
+	// The SRV records of the hosts with ports in the name table, targeting the host itself.
+	srv map[string][]dns.RR
 }
 
 const (
@@ -86,10 +95,16 @@ const (
 	defaultTTLInSeconds = 30
 )
 
-func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardToUpstreamParallel bool) (*LocalDNSServer, error) {
+func NewLocalDNSServer(proxyNamespace, proxyDomain string, addr string, forwardToUpstreamParallel bool,
+	upstreamPolicy UpstreamPolicy,
+) (*LocalDNSServer, error) {
 	h := &LocalDNSServer{
 		proxyNamespace:            proxyNamespace,
 		forwardToUpstreamParallel: forwardToUpstreamParallel,
+		upstreamPolicy:            upstreamPolicy,
+	}
+	if upstreamPolicy.ServeStale > 0 {
+		h.cache = newResponseCache()
 	}
 
 	registerStats()
@@ -192,10 +207,13 @@ func (h *LocalDNSServer) UpdateLookupTable(nt *dnsProto.NameTable) {
 		name4:    map[string][]dns.RR{},
 		name6:    map[string][]dns.RR{},
 		cname:    map[string][]dns.RR{},
+		srv:      map[string][]dns.RR{},
 	}
 	h.BuildAlternateHosts(nt, lookupTable.buildDNSAnswers)
+	lookupTable.buildSRVAnswers(nt)
 	h.lookupTable.Store(lookupTable)
 	h.nameTable.Store(nt)
+	h.forwardingRules.Store(newForwardingRules(nt.ForwardingRules))
 	log.Debugf("updated lookup table with %d hosts", len(lookupTable.allHosts))
 }
 
@@ -233,12 +251,38 @@ func (h *LocalDNSServer) upstream(proxy *dnsProxy, req *dns.Msg, hostname string
 	start := time.Now()
 	// We did not find the host in our internal cache. Query upstream and return the response as is.
 	log.Debugf("response for hostname %q not found in dns proxy, querying upstream", hostname)
-	response := h.queryUpstream(proxy.upstreamClient, req, log)
+	response := h.queryUpstream(proxy.upstreamClient, req, h.upstreamServers(hostname), log)
 	requestDuration.Record(time.Since(start).Seconds())
 	log.Debugf("upstream response for hostname %q : %v", hostname, response)
+	if response.Rcode == dns.RcodeServerFailure {
+		failures.Increment()
+		if h.cache != nil {
+			if stale := h.cache.stale(req, h.upstreamPolicy.ServeStale); stale != nil {
+				staleResponses.Increment()
+				log.Debugf("upstream failed for hostname %q, serving stale response", hostname)
+				return stale
+			}
+		}
+		return response
+	}
+	h.upstreamPolicy.clampTTL(response)
+	if h.cache != nil {
+		h.cache.store(req, response)
+	}
 	return response
 }
 
+// upstreamServers returns the servers to forward the queries for the hostname to: the servers of the matching
+// forwarding rule if any, or the servers of resolv.conf.
+func (h *LocalDNSServer) upstreamServers(hostname string) []string {
+	if rules, ok := h.forwardingRules.Load().([]forwardingRule); ok {
+		if servers := matchForwardingRule(rules, hostname); servers != nil {
+			return servers
+		}
+	}
+	return h.resolvConfServers
+}
+
 // ServeDNS is the implementation of DNS interface
 func (h *LocalDNSServer) ServeDNS(proxy *dnsProxy, w dns.ResponseWriter, req *dns.Msg) {
 	requests.Increment()
@@ -390,14 +434,14 @@ func (h *LocalDNSServer) Close() {
 	}
 }
 
-func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
+func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg, servers []string, scope *istiolog.Scope) *dns.Msg {
 	if h.forwardToUpstreamParallel {
-		return h.queryUpstreamParallel(upstreamClient, req, scope)
+		return h.queryUpstreamParallel(upstreamClient, req, servers, scope)
 	}
 
 	var response *dns.Msg
 
-	for _, upstream := range h.resolvConfServers {
+	for _, upstream := range servers {
 		cResponse, _, err := upstreamClient.Exchange(req, upstream)
 		if err == nil {
 			response = cResponse
@@ -422,7 +466,7 @@ func (h *LocalDNSServer) queryUpstream(upstreamClient *dns.Client, req *dns.Msg,
 //     response—or defer to the operating system, which we have no control over.
 //   - systemd-resolved: which is used as a default resolver in many Linux distributions nowadays also performs parallel
 //     lookups for multiple DNS servers and returns the first successful response.
-func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *dns.Msg, scope *istiolog.Scope) *dns.Msg {
+func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *dns.Msg, servers []string, scope *istiolog.Scope) *dns.Msg {
 	// Guarantee that the ctx we use below is done when this function returns.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -448,7 +492,7 @@ func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *
 		}
 	}
 
-	for _, upstream := range h.resolvConfServers {
+	for _, upstream := range servers {
 		go queryOne(upstream)
 	}
 
@@ -461,7 +505,7 @@ func (h *LocalDNSServer) queryUpstreamParallel(upstreamClient *dns.Client, req *
 		case <-errCh:
 			errorsCount++
 			// All servers returned error - return failure.
-			if errorsCount == len(h.resolvConfServers) {
+			if errorsCount == len(servers) {
 				scope.Infof("all upstream failed")
 				return serverFailure(req)
 			}
@@ -549,6 +593,12 @@ func (table *LookupTable) lookupHost(qtype uint16, hostname string) ([]dns.RR, b
 		ipAnswers = table.name4[hostname]
 	case dns.TypeAAAA:
 		ipAnswers = table.name6[hostname]
+	case dns.TypeSRV:
+		ipAnswers = table.srv[hostname]
+		if len(ipAnswers) == 0 {
+			// Only hosts with ports have SRV records, others are resolved upstream as before.
+			return nil, false
+		}
 	default:
 		// TODO: handle PTR records for reverse dns lookups
 		return nil, false
@@ -626,6 +676,24 @@ func (table *LookupTable) buildDNSAnswers(altHosts map[string]struct{}, ipv4 []n
 	}
 }
 
+// buildSRVAnswers stores the SRV records of the hosts with ports. The records target the host itself, so that
+// clients discovering the port with SRV still send the traffic to the service address.
+func (table *LookupTable) buildSRVAnswers(nt *dnsProto.NameTable) {
+	for hostname, ni := range nt.Table {
+		if len(ni.Ports) == 0 {
+			continue
+		}
+		h := strings.ToLower(hostname)
+		if !strings.HasSuffix(h, ".") {
+			h += "."
+		}
+		if _, f := table.allHosts[h]; !f {
+			continue
+		}
+		table.srv[h] = srv(h, ni.Ports)
+	}
+}
+
 // Borrowed from https://github.com/coredns/coredns/blob/master/plugin/hosts/hosts.go
 // a takes a slice of ip string and returns a slice of A RRs.
 func a(host string, ips []netip.Addr) []dns.RR {
@@ -651,6 +719,20 @@ func aaaa(host string, ips []netip.Addr) []dns.RR {
 	return answers
 }
 
+// srv takes a slice of ports and returns a slice of SRV RRs targeting the host.
+func srv(host string, ports []uint32) []dns.RR {
+	answers := make([]dns.RR, len(ports))
+	for i, port := range ports {
+		r := new(dns.SRV)
+		r.Hdr = dns.RR_Header{Name: host, Rrtype: dns.TypeSRV, Class: dns.ClassINET, Ttl: defaultTTLInSeconds}
+		r.Port = uint16(port)
+		r.Weight = 1
+		r.Target = host
+		answers[i] = r
+	}
+	return answers
+}
+
 func cname(host string, targetHost string) []dns.RR {
 	answer := new(dns.CNAME)
 	answer.Hdr = dns.RR_Header{
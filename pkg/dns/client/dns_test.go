@@ -495,9 +495,64 @@ func makeUpstream(t test.Failer, responses map[string]string) string {
 	return server.Addr
 }
 
+func TestSRV(t *testing.T) {
+	d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", false, UpstreamPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.resolvConfServers = []string{makeUpstream(t, map[string]string{})}
+	d.searchNamespaces = []string{"ns1.svc.cluster.local"}
+	d.StartDNS()
+	t.Cleanup(d.Close)
+	d.UpdateLookupTable(&dnsProto.NameTable{
+		Table: map[string]*dnsProto.NameTable_NameInfo{
+			"web.service.consul": {
+				Ips:      []string{"240.240.0.1"},
+				Registry: "External",
+				Ports:    []uint32{80, 443},
+			},
+			"www.google.com": {
+				Ips:      []string{"1.1.1.1"},
+				Registry: "External",
+			},
+		},
+	})
+
+	client := dns.Client{Timeout: 3 * time.Second, Net: "udp"}
+	query := func(host string) *dns.Msg {
+		t.Helper()
+		res, _, err := client.Exchange(new(dns.Msg).SetQuestion(host, dns.TypeSRV), d.dnsProxies[0].Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := query("web.service.consul.")
+	if res.Rcode != dns.RcodeSuccess || !res.Authoritative {
+		t.Fatalf("expected an authoritative response, got %v", res)
+	}
+	ports := map[uint16]bool{}
+	for _, rr := range res.Answer {
+		srv, ok := rr.(*dns.SRV)
+		if !ok || srv.Target != "web.service.consul." {
+			t.Fatalf("unexpected answer %v", rr)
+		}
+		ports[srv.Port] = true
+	}
+	if !reflect.DeepEqual(ports, map[uint16]bool{80: true, 443: true}) {
+		t.Fatalf("got ports %v", ports)
+	}
+
+	// Hosts without ports are resolved upstream.
+	if res := query("www.google.com."); res.Authoritative {
+		t.Fatalf("expected the query to be forwarded, got %v", res)
+	}
+}
+
 func initDNS(t test.Failer, forwardToUpstreamParallel bool) *LocalDNSServer {
 	srv := makeUpstream(t, map[string]string{"www.bing.com.": "1.1.1.1"})
-	testAgentDNS, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", forwardToUpstreamParallel)
+	testAgentDNS, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", forwardToUpstreamParallel, UpstreamPolicy{})
 	if err != nil {
 		t.Fatal(err)
 	}
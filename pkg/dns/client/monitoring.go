@@ -34,6 +34,11 @@ var (
 		"Total number of DNS requests forwarded to upstream.",
 	)
 
+	staleResponses = monitoring.NewSum(
+		"dns_upstream_stale_responses_total",
+		"Total number of DNS requests answered with a stale cached response, as upstream failed.",
+	)
+
 	requestDuration = monitoring.NewDistribution(
 		"dns_upstream_request_duration_seconds",
 		"Total time in seconds Istio takes to get DNS response from upstream.",
@@ -45,5 +50,6 @@ func registerStats() {
 	monitoring.MustRegister(requests)
 	monitoring.MustRegister(upstreamRequests)
 	monitoring.MustRegister(failures)
+	monitoring.MustRegister(staleResponses)
 	monitoring.MustRegister(requestDuration)
 }
@@ -0,0 +1,183 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/miekg/dns"
+
+	dnsProto "istio.io/istio/pkg/dns/proto"
+)
+
+// maxCachedResponses is the number of upstream responses kept to serve stale.
+const maxCachedResponses = 10000
+
+// UpstreamPolicy configures the handling of the queries forwarded to the upstream servers.
+type UpstreamPolicy struct {
+	// ServeStale is how long past their TTL the upstream responses can be served, when the upstream servers fail.
+	// Zero disables serving stale responses.
+	ServeStale time.Duration
+	// MinTTL and MaxTTL bound the TTL of the records of the upstream responses, if set.
+	MinTTL time.Duration
+	MaxTTL time.Duration
+}
+
+// clampTTL bounds the TTL of the records of the response according to the policy.
+func (p UpstreamPolicy) clampTTL(response *dns.Msg) {
+	if p.MinTTL <= 0 && p.MaxTTL <= 0 {
+		return
+	}
+	minTTL, maxTTL := uint32(p.MinTTL.Seconds()), uint32(p.MaxTTL.Seconds())
+	forEachRecord(response, func(rr dns.RR) {
+		h := rr.Header()
+		if h.Ttl < minTTL {
+			h.Ttl = minTTL
+		}
+		if maxTTL > 0 && h.Ttl > maxTTL {
+			h.Ttl = maxTTL
+		}
+	})
+}
+
+// forEachRecord calls f with all the records of the message, except the OPT pseudo record whose TTL holds flags.
+func forEachRecord(msg *dns.Msg, f func(dns.RR)) {
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype != dns.TypeOPT {
+				f(rr)
+			}
+		}
+	}
+}
+
+type cacheKey struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+type cachedResponse struct {
+	response *dns.Msg
+	expires  time.Time
+}
+
+// responseCache keeps the last upstream responses, to answer with them when the upstream servers fail.
+type responseCache struct {
+	mu        sync.Mutex
+	responses *simplelru.LRU
+	now       func() time.Time
+}
+
+func newResponseCache() *responseCache {
+	responses, _ := simplelru.NewLRU(maxCachedResponses, nil)
+	return &responseCache{responses: responses, now: time.Now}
+}
+
+func newCacheKey(req *dns.Msg) cacheKey {
+	q := req.Question[0]
+	return cacheKey{name: strings.ToLower(q.Name), qtype: q.Qtype, qclass: q.Qclass}
+}
+
+// store records a successful upstream response, until its lowest TTL expires.
+func (c *responseCache) store(req, response *dns.Msg) {
+	if response.Truncated || (response.Rcode != dns.RcodeSuccess && response.Rcode != dns.RcodeNameError) {
+		return
+	}
+	ttl := -1
+	forEachRecord(response, func(rr dns.RR) {
+		if ttl < 0 || int(rr.Header().Ttl) < ttl {
+			ttl = int(rr.Header().Ttl)
+		}
+	})
+	if ttl < 0 {
+		// Without any record, we do not know how long the response is valid.
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses.Add(newCacheKey(req), cachedResponse{
+		response: response.Copy(),
+		expires:  c.now().Add(time.Duration(ttl) * time.Second),
+	})
+}
+
+// stale returns the cached response to the request, if it expired less than maxStale ago. As recommended by
+// RFC 8767, the TTL of its records is reset to a low value, so that clients retry soon.
+func (c *responseCache) stale(req *dns.Msg, maxStale time.Duration) *dns.Msg {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := newCacheKey(req)
+	v, f := c.responses.Get(key)
+	if !f {
+		return nil
+	}
+	cached := v.(cachedResponse)
+	if c.now().After(cached.expires.Add(maxStale)) {
+		c.responses.Remove(key)
+		return nil
+	}
+	response := cached.response.Copy()
+	response.Id = req.Id
+	response.Question = req.Question
+	forEachRecord(response, func(rr dns.RR) {
+		rr.Header().Ttl = defaultTTLInSeconds
+	})
+	return response
+}
+
+type forwardingRule struct {
+	// suffix is the lower case domain suffix matched, with a trailing dot.
+	suffix  string
+	servers []string
+}
+
+// newForwardingRules returns the rules to use for the NDS forwarding rules, longest suffix first.
+func newForwardingRules(rules []*dnsProto.NameTable_ForwardingRule) []forwardingRule {
+	out := make([]forwardingRule, 0, len(rules))
+	for _, r := range rules {
+		suffix := strings.ToLower(strings.Trim(r.Suffix, "."))
+		if suffix == "" || len(r.Servers) == 0 {
+			continue
+		}
+		servers := make([]string, 0, len(r.Servers))
+		for _, s := range r.Servers {
+			if _, _, err := net.SplitHostPort(s); err != nil {
+				s = net.JoinHostPort(strings.Trim(s, "[]"), "53")
+			}
+			servers = append(servers, s)
+		}
+		out = append(out, forwardingRule{suffix: suffix + ".", servers: servers})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return len(out[i].suffix) > len(out[j].suffix)
+	})
+	return out
+}
+
+// matchForwardingRule returns the servers of the rule matching the hostname, or nil if none does.
+func matchForwardingRule(rules []forwardingRule, hostname string) []string {
+	for _, r := range rules {
+		if hostname == r.suffix || strings.HasSuffix(hostname, "."+r.suffix) {
+			return r.servers
+		}
+	}
+	return nil
+}
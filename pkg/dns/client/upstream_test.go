@@ -0,0 +1,155 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	dnsProto "istio.io/istio/pkg/dns/proto"
+)
+
+func TestClampTTL(t *testing.T) {
+	response := &dns.Msg{Answer: []dns.RR{
+		&dns.A{Hdr: dns.RR_Header{Name: "a.", Rrtype: dns.TypeA, Ttl: 5}},
+		&dns.A{Hdr: dns.RR_Header{Name: "b.", Rrtype: dns.TypeA, Ttl: 60}},
+		&dns.A{Hdr: dns.RR_Header{Name: "c.", Rrtype: dns.TypeA, Ttl: 7200}},
+	}}
+	response.SetEdns0(4096, true)
+	opt := response.IsEdns0().Hdr.Ttl
+
+	UpstreamPolicy{MinTTL: 10 * time.Second, MaxTTL: time.Hour}.clampTTL(response)
+	var got []uint32
+	for _, rr := range response.Answer {
+		got = append(got, rr.Header().Ttl)
+	}
+	if want := []uint32{10, 60, 3600}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got TTLs %v, want %v", got, want)
+	}
+	if response.IsEdns0().Hdr.Ttl != opt {
+		t.Fatalf("OPT record should not be modified")
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := newResponseCache()
+	cache.now = func() time.Time { return now }
+
+	req := new(dns.Msg).SetQuestion("www.example.com.", dns.TypeA)
+	response := new(dns.Msg).SetReply(req)
+	response.Answer = a("www.example.com.", []netip.Addr{netip.MustParseAddr("1.1.1.1")})
+	response.Answer[0].Header().Ttl = 300
+	cache.store(req, response)
+
+	failed := serverFailure(req)
+	cache.store(req, failed)
+
+	retry := new(dns.Msg).SetQuestion("WWW.example.com.", dns.TypeA)
+	now = now.Add(310 * time.Second)
+	stale := cache.stale(retry, time.Minute)
+	if stale == nil {
+		t.Fatal("expected a stale response")
+	}
+	if stale.Id != retry.Id || stale.Question[0].Name != "WWW.example.com." {
+		t.Fatalf("stale response does not match the request: %v", stale)
+	}
+	if ttl := stale.Answer[0].Header().Ttl; ttl != defaultTTLInSeconds {
+		t.Fatalf("expected stale TTL %d, got %d", defaultTTLInSeconds, ttl)
+	}
+	if response.Answer[0].Header().Ttl != 300 {
+		t.Fatalf("cached response should not be modified")
+	}
+	if cache.stale(new(dns.Msg).SetQuestion("www.example.com.", dns.TypeAAAA), time.Minute) != nil {
+		t.Fatal("unexpected stale response for another type")
+	}
+
+	now = now.Add(time.Minute)
+	if cache.stale(retry, time.Minute) != nil {
+		t.Fatal("expected response to be too stale")
+	}
+}
+
+func TestMatchForwardingRule(t *testing.T) {
+	rules := newForwardingRules([]*dnsProto.NameTable_ForwardingRule{
+		{Suffix: "example.com", Servers: []string{"10.0.0.1"}},
+		{Suffix: "Corp.Example.com.", Servers: []string{"10.0.0.2:5353", "[fd00::2]:53"}},
+		{Suffix: "empty.com"},
+	})
+	cases := map[string][]string{
+		"www.example.com.":     {"10.0.0.1:53"},
+		"example.com.":         {"10.0.0.1:53"},
+		"db.corp.example.com.": {"10.0.0.2:5353", "[fd00::2]:53"},
+		"notexample.com.":      nil,
+		"x.empty.com.":         nil,
+	}
+	for host, want := range cases {
+		if got := matchForwardingRule(rules, host); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestUpstreamPolicy(t *testing.T) {
+	d, err := NewLocalDNSServer("ns1", "ns1.svc.cluster.local", "localhost:0", false,
+		UpstreamPolicy{ServeStale: time.Hour, MaxTTL: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.resolvConfServers = []string{makeUpstream(t, map[string]string{"www.bing.com.": "1.1.1.1"})}
+	d.StartDNS()
+	t.Cleanup(d.Close)
+	corp := makeUpstream(t, map[string]string{"db.corp.example.com.": "5.5.5.5"})
+	forward := func(suffix, server string) {
+		d.UpdateLookupTable(&dnsProto.NameTable{
+			ForwardingRules: []*dnsProto.NameTable_ForwardingRule{{Suffix: suffix, Servers: []string{server}}},
+		})
+	}
+
+	client := dns.Client{Timeout: 3 * time.Second, Net: "udp"}
+	query := func(host string) *dns.Msg {
+		t.Helper()
+		res, _, err := client.Exchange(new(dns.Msg).SetQuestion(host, dns.TypeA), d.dnsProxies[0].Address())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	assertAnswer := func(res *dns.Msg, want string, ttl uint32) {
+		t.Helper()
+		if res.Rcode != dns.RcodeSuccess || len(res.Answer) != 1 {
+			t.Fatalf("unexpected response: %v", res)
+		}
+		if got := res.Answer[0].(*dns.A); got.A.String() != want || got.Hdr.Ttl != ttl {
+			t.Fatalf("got %v, want %s with TTL %d", got, want, ttl)
+		}
+	}
+
+	// The default upstream does not know this host, so it must have been forwarded by the rule.
+	forward("corp.example.com", corp)
+	assertAnswer(query("db.corp.example.com."), "5.5.5.5", 10)
+	assertAnswer(query("www.bing.com."), "1.1.1.1", 10)
+
+	// Once upstream fails, the cached response is served.
+	forward("bing.com", "127.0.0.1:1")
+	assertAnswer(query("www.bing.com."), "1.1.1.1", defaultTTLInSeconds)
+	if res := query("other.bing.com."); res.Rcode != dns.RcodeServerFailure {
+		t.Fatalf("expected server failure for uncached host, got %v", res)
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	dnsProto "istio.io/istio/pkg/dns/proto"
+)
+
+// ParseForwardingRules parses DNS forwarding rules, in the form "<suffix>=<server>[,<server>...][;<suffix>=...]".
+// Servers without a port use the port 53.
+func ParseForwardingRules(rules string) ([]*dnsProto.NameTable_ForwardingRule, error) {
+	var out []*dnsProto.NameTable_ForwardingRule
+	for _, rule := range strings.Split(rules, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		suffix, servers, found := strings.Cut(rule, "=")
+		suffix = strings.Trim(strings.TrimSpace(suffix), ".")
+		if !found || suffix == "" {
+			return nil, fmt.Errorf("invalid forwarding rule %q, must be in the form <suffix>=<server>[,<server>...]", rule)
+		}
+		fr := &dnsProto.NameTable_ForwardingRule{Suffix: strings.ToLower(suffix)}
+		for _, server := range strings.Split(servers, ",") {
+			server = strings.TrimSpace(server)
+			if server == "" {
+				continue
+			}
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+			}
+			fr.Servers = append(fr.Servers, server)
+		}
+		if len(fr.Servers) == 0 {
+			return nil, fmt.Errorf("invalid forwarding rule %q, no server set", rule)
+		}
+		out = append(out, fr)
+	}
+	return out, nil
+}
@@ -0,0 +1,51 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	dnsProto "istio.io/istio/pkg/dns/proto"
+)
+
+func TestParseForwardingRules(t *testing.T) {
+	rules, err := ParseForwardingRules(" Corp.Example.com.=10.0.0.53, 10.0.0.54:5353; other.com=fd00::53;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*dnsProto.NameTable_ForwardingRule{
+		{Suffix: "corp.example.com", Servers: []string{"10.0.0.53:53", "10.0.0.54:5353"}},
+		{Suffix: "other.com", Servers: []string{"[fd00::53]:53"}},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("got %v, want %v", rules, want)
+	}
+	for i := range want {
+		if !proto.Equal(rules[i], want[i]) {
+			t.Fatalf("got %v, want %v", rules[i], want[i])
+		}
+	}
+
+	if rules, err := ParseForwardingRules(""); err != nil || len(rules) != 0 {
+		t.Fatalf("expected no rules, got %v %v", rules, err)
+	}
+	for _, invalid := range []string{"corp.example.com", "=10.0.0.53", "corp.example.com="} {
+		if _, err := ParseForwardingRules(invalid); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -17,6 +17,9 @@ package server
 import (
 	"strings"
 
+	"golang.org/x/exp/slices"
+
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config/constants"
@@ -32,6 +35,9 @@ type Config struct {
 	// MulticlusterHeadlessEnabled if true, the DNS name table for a headless service will resolve to
 	// same-network endpoints in any cluster.
 	MulticlusterHeadlessEnabled bool
+
+	// ForwardingRules are the DNS forwarding rules sent with the name table.
+	ForwardingRules []*dnsProto.NameTable_ForwardingRule
 }
 
 // BuildNameTable produces a table of hostnames and their associated IPs that can then
@@ -44,7 +50,8 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 	}
 
 	out := &dnsProto.NameTable{
-		Table: make(map[string]*dnsProto.NameTable_NameInfo),
+		Table:           make(map[string]*dnsProto.NameTable_NameInfo),
+		ForwardingRules: cfg.ForwardingRules,
 	}
 	for _, svc := range cfg.Node.SidecarScope.Services() {
 		svcAddress := svc.GetAddressForProxy(cfg.Node)
@@ -57,6 +64,13 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 				continue
 			}
 			addressList = append(addressList, svcAddress)
+			if features.EnableDualStack {
+				for _, address := range svc.GetExtraAddressesForProxy(cfg.Node) {
+					if netutil.IsValidIPAddress(address) {
+						addressList = append(addressList, address)
+					}
+				}
+			}
 		} else {
 			// The IP will be unspecified here if its headless service or if the auto
 			// IP allocation logic for service entry was unable to allocate an IP.
@@ -118,6 +132,13 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 			Ips:      addressList,
 			Registry: string(svc.Attributes.ServiceRegistry),
 		}
+		if svc.Attributes.DNSSRV {
+			// The ports of the endpoints are resolved by istiod, so clients discovering the service
+			// with SRV queries use the service ports.
+			for _, port := range svc.Ports {
+				nameInfo.Ports = append(nameInfo.Ports, uint32(port.Port))
+			}
+		}
 		if svc.Attributes.ServiceRegistry == provider.Kubernetes &&
 			!strings.HasSuffix(hostName.String(), "."+constants.DefaultClusterSetLocalDomain) {
 			// The agent will take care of resolving a, a.ns, a.ns.svc, etc.
@@ -128,7 +149,24 @@ func BuildNameTable(cfg Config) *dnsProto.NameTable {
 			nameInfo.Namespace = svc.Attributes.Namespace
 			nameInfo.Shortname = svc.Attributes.Name
 		}
+		if existing, f := out.Table[hostName.String()]; f && features.EnableDualStack && svcAddress != constants.UnspecifiedIP &&
+			existing.Registry == string(provider.External) && nameInfo.Registry == string(provider.External) {
+			// A ServiceEntry has a Service for each of its addresses, typically an IPv4 and an IPv6 one. Both must be
+			// resolved, for the clients to get the A and AAAA records.
+			nameInfo.Ips = mergeAddresses(existing.Ips, nameInfo.Ips)
+		}
 		out.Table[hostName.String()] = nameInfo
 	}
 	return out
 }
+
+// mergeAddresses appends the addresses missing from the existing ones.
+func mergeAddresses(existing, addresses []string) []string {
+	out := append([]string{}, existing...)
+	for _, a := range addresses {
+		if !slices.Contains(out, a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -21,6 +21,7 @@ import (
 	"google.golang.org/protobuf/testing/protocmp"
 
 	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/serviceregistry/provider"
 	"istio.io/istio/pkg/config/constants"
@@ -28,6 +29,7 @@ import (
 	"istio.io/istio/pkg/config/protocol"
 	dnsProto "istio.io/istio/pkg/dns/proto"
 	dnsServer "istio.io/istio/pkg/dns/server"
+	"istio.io/istio/pkg/test"
 )
 
 // nolint
@@ -435,6 +437,65 @@ func TestNameTable(t *testing.T) {
 	}
 }
 
+func TestNameTableDualStack(t *testing.T) {
+	test.SetForTest(t, &features.EnableDualStack, true)
+	proxy := &model.Proxy{
+		IPAddresses: []string{"9.9.9.9", "2001:db8::9"},
+		Metadata:    &model.NodeMetadata{DNSCapture: true, DNSAutoAllocate: true},
+		Type:        model.SidecarProxy,
+		DNSDomain:   "testns.svc.cluster.local",
+	}
+	proxy.DiscoverIPMode()
+
+	serviceEntryService := func(address string) *model.Service {
+		return &model.Service{
+			Hostname:       host.Name("dual.bar.com"),
+			DefaultAddress: address,
+			Ports:          model.PortList{&model.Port{Name: "tcp-port", Port: 9000, Protocol: protocol.TCP}},
+			Resolution:     model.ClientSideLB,
+			Attributes: model.ServiceAttributes{
+				Name:            "dual.bar.com",
+				Namespace:       "testns",
+				ServiceRegistry: provider.External,
+			},
+		}
+	}
+	autoAllocatedService := &model.Service{
+		Hostname:                 host.Name("auto.bar.com"),
+		DefaultAddress:           constants.UnspecifiedIP,
+		AutoAllocatedIPv4Address: "240.240.0.1",
+		AutoAllocatedIPv6Address: "2001:2::f0f0:1",
+		Ports:                    model.PortList{&model.Port{Name: "tcp-port", Port: 9000, Protocol: protocol.TCP}},
+		Resolution:               model.DNSLB,
+		Attributes: model.ServiceAttributes{
+			Name:            "auto.bar.com",
+			Namespace:       "testns",
+			ServiceRegistry: provider.External,
+		},
+	}
+
+	push := model.NewPushContext()
+	push.Mesh = &meshconfig.MeshConfig{RootNamespace: "istio-system"}
+	push.AddPublicServices([]*model.Service{serviceEntryService("10.0.0.1"), serviceEntryService("2001:db8::1"), autoAllocatedService})
+	proxy.SidecarScope = model.ConvertToSidecarScope(push, nil, "default")
+
+	want := &dnsProto.NameTable{
+		Table: map[string]*dnsProto.NameTable_NameInfo{
+			"dual.bar.com": {
+				Ips:      []string{"10.0.0.1", "2001:db8::1"},
+				Registry: "External",
+			},
+			"auto.bar.com": {
+				Ips:      []string{"240.240.0.1", "2001:2::f0f0:1"},
+				Registry: "External",
+			},
+		},
+	}
+	if diff := cmp.Diff(dnsServer.BuildNameTable(dnsServer.Config{Node: proxy, Push: push}), want, protocmp.Transform()); diff != "" {
+		t.Fatalf("got diff: %v", diff)
+	}
+}
+
 func makeInstances(proxy *model.Proxy, svc *model.Service, servicePort int, targetPort int) []*model.ServiceInstance {
 	ret := make([]*model.ServiceInstance, 0)
 	for _, p := range svc.Ports {
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	admin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
@@ -48,6 +49,24 @@ func DrainListeners(adminPort uint32, inboundonly bool) error {
 	return err
 }
 
+// SetLogLevel changes the log levels of Envoy. The level is in the same form as the --log-level flag of the agent,
+// "<level>" to change all loggers and/or "<logger>:<level>" to change a single logger, separated by commas.
+func SetLogLevel(adminPort uint32, level string) error {
+	logLevel, componentLogs := splitComponentLog(level)
+	if logLevel != "" {
+		if _, err := doEnvoyPost("logging?level="+url.QueryEscape(logLevel), "", "", adminPort); err != nil {
+			return err
+		}
+	}
+	for _, cl := range componentLogs {
+		logger, l, _ := strings.Cut(cl, ":")
+		if _, err := doEnvoyPost("logging?"+url.QueryEscape(logger)+"="+url.QueryEscape(l), "", "", adminPort); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetServerInfo returns a structure representing a call to /server_info
 func GetServerInfo(adminPort uint32) (*admin.ServerInfo, error) {
 	buffer, err := doEnvoyGet("server_info", adminPort)
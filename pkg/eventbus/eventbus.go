@@ -0,0 +1,213 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eventbus provides a typed publish/subscribe event bus for the notifications between the components of
+// istiod, such as service updates, certificate rotations and mesh config changes.
+//
+// A Topic delivers the events of a single type to its subscribers, in a well-defined order: subscribers are
+// notified by ascending priority, and in the order they subscribed for the same priority. Subscribers are
+// synchronous by default, the handler running on the goroutine publishing the event. Asynchronous subscribers
+// have a bounded queue drained by a dedicated goroutine; publishing blocks while the queue is full, applying
+// backpressure to the publisher instead of dropping events or growing unboundedly.
+package eventbus
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"istio.io/pkg/monitoring"
+)
+
+// Topic delivers the events of type T to its subscribers. A Topic is safe for concurrent use.
+type Topic[T any] struct {
+	name string
+	// The metrics of the topic, labeled once rather than on each event.
+	publishedEvents  monitoring.Metric
+	subscribersGauge monitoring.Metric
+
+	mu sync.RWMutex
+	// subscribers sorted by delivery order, copied on write.
+	subscribers []*Subscription[T]
+	seq         int
+}
+
+// NewTopic creates a topic. The name identifies the topic in the metrics.
+func NewTopic[T any](name string) *Topic[T] {
+	return &Topic[T]{
+		name:             name,
+		publishedEvents:  publishedEvents.With(topicTag.Value(name)),
+		subscribersGauge: subscribersGauge.With(topicTag.Value(name)),
+	}
+}
+
+// Name returns the name of the topic.
+func (t *Topic[T]) Name() string {
+	return t.name
+}
+
+// Subscription is the registration of a handler to a topic.
+type Subscription[T any] struct {
+	topic    *Topic[T]
+	name     string
+	handler  func(T)
+	priority int
+	seq      int
+	// The metrics of the subscriber, labeled once rather than on each event.
+	handlerDuration monitoring.Metric
+	backpressure    monitoring.Metric
+
+	// queue and stop are set for asynchronous subscribers only.
+	queue    chan T
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// SubscribeOption configures a subscription.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	priority  int
+	queueSize int
+}
+
+// WithPriority sets the priority of the subscriber. Subscribers with a lower priority are notified first; the
+// default priority is 0.
+func WithPriority(priority int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.priority = priority
+	}
+}
+
+// WithQueue makes the subscriber asynchronous, with a queue of the given size. Publish blocks while the queue of
+// an asynchronous subscriber is full.
+func WithQueue(size int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.queueSize = size
+	}
+}
+
+// Subscribe registers the handler for the events published to the topic. The name identifies the subscriber in
+// the metrics.
+func (t *Topic[T]) Subscribe(name string, handler func(T), opts ...SubscribeOption) *Subscription[T] {
+	o := subscribeOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &Subscription[T]{
+		topic:    t,
+		name:     name,
+		handler:  handler,
+		priority: o.priority,
+
+		handlerDuration: handlerDuration.With(topicTag.Value(t.name), subscriberTag.Value(name)),
+		backpressure:    backpressure.With(topicTag.Value(t.name), subscriberTag.Value(name)),
+	}
+	if o.queueSize > 0 {
+		s.queue = make(chan T, o.queueSize)
+		s.stop = make(chan struct{})
+		go s.run()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s.seq = t.seq
+	t.seq++
+	subscribers := make([]*Subscription[T], 0, len(t.subscribers)+1)
+	subscribers = append(subscribers, t.subscribers...)
+	subscribers = append(subscribers, s)
+	sort.SliceStable(subscribers, func(i, j int) bool {
+		if subscribers[i].priority != subscribers[j].priority {
+			return subscribers[i].priority < subscribers[j].priority
+		}
+		return subscribers[i].seq < subscribers[j].seq
+	})
+	t.subscribers = subscribers
+	// Recorded under the lock, so that the gauge is the number of subscribers after the last change.
+	t.subscribersGauge.Record(float64(len(subscribers)))
+	return s
+}
+
+// Unsubscribe removes the subscriber from the topic. Events queued for an asynchronous subscriber and not yet
+// handled are dropped.
+func (s *Subscription[T]) Unsubscribe() {
+	t := s.topic
+	t.mu.Lock()
+	for i, sub := range t.subscribers {
+		if sub == s {
+			subscribers := make([]*Subscription[T], 0, len(t.subscribers)-1)
+			subscribers = append(subscribers, t.subscribers[:i]...)
+			t.subscribers = append(subscribers, t.subscribers[i+1:]...)
+			t.subscribersGauge.Record(float64(len(t.subscribers)))
+			break
+		}
+	}
+	t.mu.Unlock()
+	if s.stop != nil {
+		s.stopOnce.Do(func() {
+			close(s.stop)
+		})
+	}
+}
+
+// Len returns the number of subscribers of the topic.
+func (t *Topic[T]) Len() int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.subscribers)
+}
+
+// Publish delivers the event to the subscribers of the topic, in order. Publish returns once the synchronous
+// subscribers handled the event and the event is queued for the asynchronous subscribers.
+func (t *Topic[T]) Publish(event T) {
+	t.mu.RLock()
+	subscribers := t.subscribers
+	t.mu.RUnlock()
+
+	t.publishedEvents.Increment()
+	for _, s := range subscribers {
+		if s.queue == nil {
+			s.handle(event)
+			continue
+		}
+		select {
+		case s.queue <- event:
+			continue
+		default:
+		}
+		// The queue is full: block the publisher until the subscriber catches up.
+		s.backpressure.Increment()
+		select {
+		case s.queue <- event:
+		case <-s.stop:
+		}
+	}
+}
+
+func (s *Subscription[T]) handle(event T) {
+	start := time.Now()
+	s.handler(event)
+	s.handlerDuration.Record(time.Since(start).Seconds())
+}
+
+func (s *Subscription[T]) run() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		case event := <-s.queue:
+			s.handle(event)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/util/assert"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+func TestOrder(t *testing.T) {
+	topic := NewTopic[int]("test")
+	var mu sync.Mutex
+	var got []string
+	record := func(name string) func(int) {
+		return func(e int) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, fmt.Sprintf("%s:%d", name, e))
+		}
+	}
+	topic.Subscribe("a", record("a"))
+	topic.Subscribe("b", record("b"), WithPriority(-1))
+	c := topic.Subscribe("c", record("c"))
+	topic.Subscribe("d", record("d"), WithPriority(1))
+	topic.Subscribe("e", record("e"))
+	assert.Equal(t, topic.Len(), 5)
+
+	topic.Publish(1)
+	assert.Equal(t, got, []string{"b:1", "a:1", "c:1", "e:1", "d:1"})
+
+	got = nil
+	c.Unsubscribe()
+	c.Unsubscribe()
+	assert.Equal(t, topic.Len(), 4)
+	topic.Publish(2)
+	assert.Equal(t, got, []string{"b:2", "a:2", "e:2", "d:2"})
+}
+
+func TestAsync(t *testing.T) {
+	topic := NewTopic[int]("test")
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var got []int
+	sub := topic.Subscribe("slow", func(e int) {
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, e)
+	}, WithQueue(1))
+
+	// The first event is being handled, the second one is queued.
+	topic.Publish(1)
+	<-started
+	topic.Publish(2)
+	published := make(chan struct{})
+	go func() {
+		topic.Publish(3)
+		close(published)
+	}()
+	select {
+	case <-published:
+		t.Fatal("expected the publisher to be blocked by the full queue")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	<-published
+	retry.UntilOrFail(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(got) == 3
+	}, retry.Timeout(time.Second*5))
+	assert.Equal(t, got, []int{1, 2, 3})
+
+	sub.Unsubscribe()
+	assert.Equal(t, topic.Len(), 0)
+}
+
+func TestUnsubscribeUnblocksPublisher(t *testing.T) {
+	topic := NewTopic[int]("test")
+	started := make(chan struct{}, 1)
+	block := make(chan struct{})
+	defer close(block)
+	sub := topic.Subscribe("stuck", func(int) {
+		started <- struct{}{}
+		<-block
+	}, WithQueue(1))
+	topic.Publish(1)
+	<-started
+	topic.Publish(2)
+	published := make(chan struct{})
+	go func() {
+		topic.Publish(3)
+		close(published)
+	}()
+	sub.Unsubscribe()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the publisher to be unblocked")
+	}
+}
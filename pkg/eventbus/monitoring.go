@@ -0,0 +1,58 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	topicTag      = monitoring.MustCreateLabel("topic")
+	subscriberTag = monitoring.MustCreateLabel("subscriber")
+
+	publishedEvents = monitoring.NewSum(
+		"eventbus_published_events_total",
+		"Total number of events published, by topic.",
+		monitoring.WithLabels(topicTag),
+	)
+
+	subscribersGauge = monitoring.NewGauge(
+		"eventbus_subscribers",
+		"Number of subscribers, by topic.",
+		monitoring.WithLabels(topicTag),
+	)
+
+	handlerDuration = monitoring.NewDistribution(
+		"eventbus_handler_duration_seconds",
+		"Time in seconds a subscriber takes to handle an event, by topic and subscriber.",
+		[]float64{.001, .01, .1, 1, 5, 10},
+		monitoring.WithLabels(topicTag, subscriberTag),
+	)
+
+	backpressure = monitoring.NewSum(
+		"eventbus_backpressure_total",
+		"Total number of times a publisher was blocked by the full queue of an asynchronous subscriber.",
+		monitoring.WithLabels(topicTag, subscriberTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(
+		publishedEvents,
+		subscribersGauge,
+		handlerDuration,
+		backpressure,
+	)
+}
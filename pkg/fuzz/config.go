@@ -0,0 +1,266 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/test"
+)
+
+// maxMutations is the maximum number of mutations applied to a config corpus entry by a single fuzz case.
+const maxMutations = 8
+
+// interestingValues are the values substituted to the fields of the configs. They are chosen to reach the edge
+// cases of the translation: empty and wildcard values, out of range numbers, and unusual addresses.
+var interestingValues = []any{
+	"", "*", "*.", ".", "-", "0", "0.0.0.0/0", "::/0", "::1", "[::1]:80", "1.1.1.1/33", "a..b", "*.*.com",
+	"%", "\x00", "ü", strings.Repeat("a", 256), "/", "//", "^(.*$", "Host", ":authority", "UNSPECIFIED",
+	0, -1, 1, 65535, 65536, 4294967296, 0.5, true, false, nil,
+	map[string]any{}, []any{},
+}
+
+// ConfigCorpus adds each YAML file of the directory as a seed of the fuzzer, along with empty mutation data.
+// The fuzz function takes the config seed and the mutation data: f.Fuzz(func(t *testing.T, seed, data []byte)).
+// A missing directory adds no seed, so that fuzzers built away from the source tree still run.
+func ConfigCorpus(f test.Fuzzer, dir string) {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.yaml"))
+	for _, file := range files {
+		by, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		f.Add(by, []byte{})
+	}
+}
+
+// slot is a field of a config, or an element of a list, that can be read, replaced or removed.
+type slot struct {
+	get    func() any
+	set    func(any)
+	remove func()
+}
+
+// MutateConfig applies a series of structural mutations, driven by the fuzz data, to a YAML stream of configs.
+// Fields are removed, replaced with interesting or fuzzed values, and list elements are duplicated, so that the
+// mutated configs stay close to the real-world configs of the corpus. The apiVersion and kind of the configs are
+// kept, so that they are still parsed as the same types.
+func MutateConfig(h Helper, in []byte) []byte {
+	docs := parseConfigs(in)
+	if len(docs) == 0 {
+		return in
+	}
+	count, err := h.cf.GetInt()
+	if err != nil {
+		return in
+	}
+	for i := 0; i < pick(count, maxMutations)+1; i++ {
+		slots := collectSlots(docs)
+		if len(slots) == 0 {
+			break
+		}
+		n, err := h.cf.GetInt()
+		if err != nil {
+			break
+		}
+		s := slots[pick(n, len(slots))]
+		op, err := h.cf.GetInt()
+		if err != nil {
+			break
+		}
+		switch pick(op, 5) {
+		case 0:
+			s.remove()
+		case 1:
+			v, err := h.cf.GetInt()
+			if err != nil {
+				return renderConfigs(docs)
+			}
+			s.set(interestingValues[pick(v, len(interestingValues))])
+		case 2:
+			v, err := h.cf.GetString()
+			if err != nil {
+				return renderConfigs(docs)
+			}
+			s.set(v)
+		case 3:
+			// Duplicate the value in a list, to reach the handling of duplicated hosts, ports and rules.
+			s.set([]any{s.get(), s.get()})
+		case 4:
+			if l, ok := s.get().([]any); ok && len(l) > 0 {
+				s.set(append(l, l[0]))
+			} else {
+				s.remove()
+			}
+		}
+	}
+	return renderConfigs(docs)
+}
+
+// MinimizeConfig reduces a YAML stream of configs failing the check, by removing the configs, fields and list
+// elements which are not needed for the check to fail. It returns the input if the input does not fail.
+func MinimizeConfig(in []byte, fails func([]byte) bool) []byte {
+	if !fails(in) {
+		return in
+	}
+	docs := parseConfigs(in)
+	for changed := true; changed; {
+		changed = false
+		// Drop whole configs first, as they are the largest reduction.
+		for i := 0; i < len(docs); i++ {
+			candidate := append(append([]map[string]any{}, docs[:i]...), docs[i+1:]...)
+			if len(candidate) > 0 && fails(renderConfigs(candidate)) {
+				docs = candidate
+				changed = true
+				i--
+			}
+		}
+		// Each removal is tried on a copy, as it shifts the elements of the lists holding the removed slot.
+		for i := 0; ; i++ {
+			candidate := parseConfigs(renderConfigs(docs))
+			slots := collectSlots(candidate)
+			if i >= len(slots) {
+				break
+			}
+			slots[i].remove()
+			if fails(renderConfigs(candidate)) {
+				docs = candidate
+				changed = true
+				i--
+			}
+		}
+	}
+	return renderConfigs(docs)
+}
+
+func parseConfigs(in []byte) []map[string]any {
+	var docs []map[string]any
+	for _, chunk := range strings.Split(string(in), "\n---") {
+		var doc map[string]any
+		if err := yaml.Unmarshal([]byte(chunk), &doc); err != nil || len(doc) == 0 {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs
+}
+
+func renderConfigs(docs []map[string]any) []byte {
+	out := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		by, err := yaml.Marshal(doc)
+		if err != nil {
+			continue
+		}
+		out = append(out, string(by))
+	}
+	return []byte(strings.Join(out, "---\n"))
+}
+
+// collectSlots returns the slots of the configs, in a stable order. The apiVersion and kind are not mutated.
+func collectSlots(docs []map[string]any) []slot {
+	var slots []slot
+	for _, doc := range docs {
+		for _, k := range sortedKeys(doc) {
+			if k == "apiVersion" || k == "kind" {
+				continue
+			}
+			slots = appendSlots(slots, doc, k)
+		}
+	}
+	return slots
+}
+
+func appendSlots(slots []slot, parent map[string]any, key string) []slot {
+	slots = append(slots, slot{
+		get:    func() any { return parent[key] },
+		set:    func(v any) { parent[key] = v },
+		remove: func() { delete(parent, key) },
+	})
+	switch v := parent[key].(type) {
+	case map[string]any:
+		for _, k := range sortedKeys(v) {
+			slots = appendSlots(slots, v, k)
+		}
+	case []any:
+		for i := range v {
+			i := i
+			slots = append(slots, slot{
+				get: func() any {
+					l, _ := parent[key].([]any)
+					if i < len(l) {
+						return l[i]
+					}
+					return nil
+				},
+				set: func(nv any) {
+					if l, ok := parent[key].([]any); ok && i < len(l) {
+						l[i] = nv
+					}
+				},
+				remove: func() {
+					if l, ok := parent[key].([]any); ok && i < len(l) {
+						parent[key] = append(append([]any{}, l[:i]...), l[i+1:]...)
+					}
+				},
+			})
+			if m, ok := v[i].(map[string]any); ok {
+				for _, k := range sortedKeys(m) {
+					slots = appendSlots(slots, m, k)
+				}
+			}
+		}
+	}
+	return slots
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pick returns the index selected by the fuzzed integer in a list of length l.
+func pick(n, l int) int {
+	return int(uint(n) % uint(l))
+}
+
+// ArtifactDir is the environment variable holding the directory the failing configs are written to by
+// WriteConfigArtifact, so that continuous runs keep them after the fuzzer exits.
+const ArtifactDir = "FUZZ_ARTIFACT_DIR"
+
+// WriteConfigArtifact writes the failing config to the artifact directory, named after its content. It returns the
+// path of the artifact, or an empty path if no artifact directory is set.
+func WriteConfigArtifact(name string, in []byte) (string, error) {
+	dir := os.Getenv(ArtifactDir)
+	if dir == "" {
+		return "", nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%x.yaml", name, sha256.Sum256(in)))
+	return path, os.WriteFile(path, in, 0o644)
+}
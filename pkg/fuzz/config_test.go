@@ -0,0 +1,79 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"strings"
+	"testing"
+)
+
+const testConfigs = `apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: reviews
+spec:
+  hosts:
+  - reviews
+  - ratings
+  http:
+  - route:
+    - destination:
+        host: reviews
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: DestinationRule
+metadata:
+  name: reviews
+spec:
+  host: reviews
+`
+
+func TestMutateConfig(t *testing.T) {
+	for _, data := range [][]byte{{}, []byte("abcdefghijklmnopqrstuvwxyz0123456789"), []byte(strings.Repeat("\xff", 100))} {
+		out := MutateConfig(New(t, data), []byte(testConfigs))
+		docs := parseConfigs(out)
+		if len(docs) != 2 {
+			t.Fatalf("expected the configs to be kept, got:\n%s", out)
+		}
+		for _, doc := range docs {
+			if doc["apiVersion"] != "networking.istio.io/v1alpha3" || doc["kind"] == nil {
+				t.Fatalf("expected the types to be kept, got:\n%s", out)
+			}
+		}
+		if again := MutateConfig(New(t, data), []byte(testConfigs)); string(again) != string(out) {
+			t.Fatalf("mutation is not deterministic:\n%s\n%s", out, again)
+		}
+	}
+}
+
+func TestMinimizeConfig(t *testing.T) {
+	fails := func(in []byte) bool {
+		return strings.Contains(string(in), "ratings")
+	}
+	got := string(MinimizeConfig([]byte(testConfigs), fails))
+	want := `apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+spec:
+  hosts:
+  - ratings
+`
+	if got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if got := MinimizeConfig([]byte(testConfigs), func([]byte) bool { return false }); string(got) != testConfigs {
+		t.Fatalf("expected passing configs to be returned as is, got:\n%s", got)
+	}
+}
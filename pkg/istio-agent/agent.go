@@ -16,6 +16,7 @@ package istioagent
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"net/netip"
@@ -51,6 +52,7 @@ import (
 	gca "istio.io/istio/security/pkg/nodeagent/caclient/providers/google"
 	cas "istio.io/istio/security/pkg/nodeagent/caclient/providers/google-cas"
 	"istio.io/istio/security/pkg/nodeagent/sds"
+	"istio.io/istio/security/pkg/rolesanywhere"
 	"istio.io/pkg/filewatcher"
 	"istio.io/pkg/log"
 )
@@ -98,6 +100,9 @@ type Agent struct {
 	sdsServer   *sds.Server
 	secretCache *cache.SecretManagerClient
 
+	// awsCredentialsServer serves the AWS credentials exchanged for the workload certificate.
+	awsCredentialsServer *rolesanywhere.Server
+
 	// Used when proxying envoy xds via istio-agent is enabled.
 	xdsProxy    *XdsProxy
 	fileWatcher filewatcher.FileWatcher
@@ -125,6 +130,8 @@ type AgentOptions struct {
 	DNSAddr string
 	// DNSForwardParallel indicates whether the agent should send parallel DNS queries to all upstream nameservers.
 	DNSForwardParallel bool
+	// DNSUpstreamPolicy configures the handling of the DNS queries forwarded to the upstream nameservers.
+	DNSUpstreamPolicy dnsClient.UpstreamPolicy
 	// ProxyType is the type of proxy we are configured to handle
 	ProxyType model.NodeType
 	// ProxyNamespace to use for local dns resolution
@@ -173,6 +180,25 @@ type AgentOptions struct {
 
 	ExitOnZeroActiveConnections bool
 
+	// NativeSidecar is set when the proxy runs as a Kubernetes native sidecar, only terminated once the app
+	// containers exit. The proxy then drains for the ProxyTerminationGracePeriod reserved to it, if set.
+	NativeSidecar               bool
+	ProxyTerminationGracePeriod time.Duration
+
+	// ExitOnApplicationExit terminates the agent once the processes of the app containers exit, so that the pods
+	// of jobs complete. It requires the process namespace to be shared with the pod. As the processes of short lived
+	// app containers may exit before being seen, the agent also terminates if none was seen within the
+	// ApplicationStartTimeout.
+	ExitOnApplicationExit   bool
+	ApplicationStartTimeout time.Duration
+
+	// ConnectionPoolMetrics includes the circuit breaker stats of outbound clusters in the stats of Envoy.
+	ConnectionPoolMetrics bool
+
+	// PrefetchWorkloadCertificates requests the workload certificates on startup, and holds the readiness of the
+	// agent until they are issued, so that the application is only started once the proxy can serve mTLS traffic.
+	PrefetchWorkloadCertificates bool
+
 	// Cloud platform
 	Platform platform.Environment
 
@@ -184,8 +210,16 @@ type AgentOptions struct {
 	DownstreamGrpcOptions []grpc.ServerOption
 
 	IstiodSAN string
+	// IstiodFailoverAddresses are the addresses of the istiod instances the XDS proxy fails over to, in order,
+	// when the discovery address cannot be reached.
+	IstiodFailoverAddresses []string
 
 	WASMOptions wasm.Options
+
+	// AWSRolesAnywhere, if set, exchanges the workload certificate for AWS credentials with IAM Roles Anywhere,
+	// and serves them to the containers of the pod on AWSCredentialsPort.
+	AWSRolesAnywhere   *rolesanywhere.Config
+	AWSCredentialsPort int
 }
 
 // NewAgent hosts the functionality for local SDS and XDS. This consists of the local SDS server and
@@ -226,6 +260,10 @@ func (a *Agent) generateNodeMetadata() (*model.Node, error) {
 		log.Info("Credential SDS socket found")
 	}
 
+	// An ECDSA certificate can be issued on demand, unless the default certificate already uses an ECDSA
+	// key or is mounted from files.
+	dualWorkloadCerts := a.secOpts.ECCSigAlg == "" && !a.secOpts.FileMountedCerts
+
 	return bootstrap.GetNodeMetaData(bootstrap.MetadataOptions{
 		ID:                          a.cfg.ServiceNode,
 		Envs:                        os.Environ(),
@@ -235,10 +273,12 @@ func (a *Agent) generateNodeMetadata() (*model.Node, error) {
 		ProxyConfig:                 a.proxyConfig,
 		PilotSubjectAltName:         pilotSAN,
 		CredentialSocketExists:      credentialSocketExists,
+		DualWorkloadCerts:           dualWorkloadCerts,
 		OutlierLogPath:              a.envoyOpts.OutlierLogPath,
 		EnvoyPrometheusPort:         a.cfg.EnvoyPrometheusPort,
 		EnvoyStatusPort:             a.cfg.EnvoyStatusPort,
 		ExitOnZeroActiveConnections: a.cfg.ExitOnZeroActiveConnections,
+		ConnectionPoolMetrics:       a.cfg.ConnectionPoolMetrics,
 		XDSRootCert:                 a.cfg.XDSRootCerts,
 	})
 }
@@ -285,6 +325,9 @@ func (a *Agent) initializeEnvoyAgent(ctx context.Context) error {
 	envoyProxy := envoy.NewProxy(a.envoyOpts)
 
 	drainDuration := a.proxyConfig.TerminationDrainDuration.AsDuration()
+	if a.cfg.NativeSidecar && a.cfg.ProxyTerminationGracePeriod > 0 {
+		drainDuration = a.cfg.ProxyTerminationGracePeriod
+	}
 	localHostAddr := localHostIPv4
 	if a.cfg.IsIPv6 {
 		localHostAddr = localHostIPv6
@@ -345,6 +388,11 @@ func (a *Agent) Run(ctx context.Context) (func(), error) {
 			return nil, fmt.Errorf("failed to start SDS server: %v", err)
 		}
 	}
+	if a.cfg.AWSRolesAnywhere != nil {
+		if err := a.initAWSCredentialsServer(); err != nil {
+			return nil, fmt.Errorf("failed to start AWS credentials server: %v", err)
+		}
+	}
 	a.xdsProxy, err = initXdsProxy(a)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start xds proxy: %v", err)
@@ -431,11 +479,35 @@ func (a *Agent) initSdsServer() error {
 		pkpConf := a.proxyConfig.GetPrivateKeyProvider()
 		a.sdsServer = sds.NewServer(a.secOpts, a.secretCache, pkpConf)
 		a.secretCache.RegisterSecretHandler(a.sdsServer.OnSecretUpdate)
+		if a.cfg.PrefetchWorkloadCertificates {
+			// The certificates are cached, and served to Envoy once it requests them over SDS.
+			go func() {
+				_, _ = a.getWorkloadCerts(a.secretCache)
+			}()
+		}
 	}
 
 	return nil
 }
 
+// initAWSCredentialsServer serves the AWS credentials of the workload on the local host. The credentials are
+// exchanged for the workload certificate, so the secret manager of the agent is required.
+func (a *Agent) initAWSCredentialsServer() error {
+	if a.secretCache == nil {
+		return errors.New("AWS IAM Roles Anywhere requires the workload certificates issued by the agent")
+	}
+	client, err := rolesanywhere.NewClient(*a.cfg.AWSRolesAnywhere)
+	if err != nil {
+		return err
+	}
+	localHostAddr := localHostIPv4
+	if a.cfg.IsIPv6 {
+		localHostAddr = localHostIPv6
+	}
+	a.awsCredentialsServer, err = rolesanywhere.NewServer(localHostAddr, a.cfg.AWSCredentialsPort, client, a.secretCache)
+	return err
+}
+
 // getWorkloadCerts will attempt to get a cert, with infinite exponential backoff
 // It will not return until both workload cert and root cert are generated.
 //
@@ -491,7 +563,7 @@ func (a *Agent) initLocalDNSServer() (err error) {
 	// we don't need dns server on gateways
 	if a.cfg.DNSCapture && a.cfg.ProxyType == model.SidecarProxy {
 		if a.localDNSServer, err = dnsClient.NewLocalDNSServer(a.cfg.ProxyNamespace, a.cfg.ProxyDomain, a.cfg.DNSAddr,
-			a.cfg.DNSForwardParallel); err != nil {
+			a.cfg.DNSForwardParallel, a.cfg.DNSUpstreamPolicy); err != nil {
 			return err
 		}
 		a.localDNSServer.StartDNS()
@@ -537,6 +609,56 @@ func (a *Agent) Check() (err error) {
 	return nil
 }
 
+// HealthComponents returns the readiness checks of the agent subsystems, reported on their own by the status server:
+// the DNS proxy, the workload certificate served over SDS and the Wasm modules fetched for Envoy.
+func (a *Agent) HealthComponents() []ready.Component {
+	components := []ready.Component{
+		{Name: "dns", Prober: a},
+		{Name: "sds", Prober: ready.ProberFunc(a.checkWorkloadCertificate)},
+	}
+	if !a.cfg.DisableEnvoy {
+		components = append(components, ready.Component{Name: "wasm", Prober: ready.ProberFunc(wasm.CheckFetchStatus)})
+	}
+	return components
+}
+
+// checkWorkloadCertificate checks that the workload certificate served over SDS has not expired. A certificate that
+// was not requested yet is not an error: Envoy does not become ready until the certificates it needs are served.
+// When the certificates are prefetched, the agent is not ready until they are issued.
+func (a *Agent) checkWorkloadCertificate() error {
+	if a.secretCache == nil {
+		return nil
+	}
+	exp, ok := a.secretCache.WorkloadCertificateExpiration()
+	if !ok {
+		if a.cfg.PrefetchWorkloadCertificates {
+			return errors.New("workload certificate not issued yet")
+		}
+		return nil
+	}
+	if time.Now().After(exp) {
+		return fmt.Errorf("workload certificate expired at %s", exp.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// WorkloadCertificate returns the workload certificate issued to the agent, for the servers of the agent which
+// require TLS.
+func (a *Agent) WorkloadCertificate() (*tls.Certificate, error) {
+	if a.secretCache == nil {
+		return nil, errors.New("workload certificates are not issued by the agent")
+	}
+	secret, err := a.secretCache.GenerateSecret(security.WorkloadKeyCertResourceName)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(secret.CertificateChain, secret.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
 // GetDNSTable builds DNS table used in debugging interface.
 func (a *Agent) GetDNSTable() *dnsProto.NameTable {
 	if a.localDNSServer != nil && a.localDNSServer.NameTable() != nil {
@@ -574,6 +696,9 @@ func (a *Agent) close() {
 	if a.sdsServer != nil {
 		a.sdsServer.Stop()
 	}
+	if a.awsCredentialsServer != nil {
+		a.awsCredentialsServer.Stop()
+	}
 	if a.secretCache != nil {
 		a.secretCache.Close()
 	}
@@ -0,0 +1,104 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"istio.io/pkg/log"
+)
+
+// applicationExitCheckInterval is the interval at which the processes of the app containers are listed. It is
+// short, so that the processes of short lived jobs are seen.
+var applicationExitCheckInterval = 500 * time.Millisecond
+
+// WatchApplicationExit calls onExit once the processes of the other containers of the pod, visible in the process
+// namespace shared with the pod, were seen and all exited. The pause process, PID 1 of the shared namespace, is
+// ignored, and so are the processes of the proxy container, which belong to the same cgroup as the agent.
+// An application exiting between two checks is never seen running: if no application process was seen within
+// startTimeout, the application is considered exited as well. A zero startTimeout waits for the application forever.
+func WatchApplicationExit(ctx context.Context, proc string, startTimeout time.Duration, onExit func()) {
+	self, err := os.ReadFile(filepath.Join(proc, "self", "cgroup"))
+	if err != nil {
+		log.Warnf("cannot watch the application processes: %v", err)
+		return
+	}
+	t := time.NewTicker(applicationExitCheckInterval)
+	defer t.Stop()
+	var started <-chan time.Time
+	if startTimeout > 0 {
+		st := time.NewTimer(startTimeout)
+		defer st.Stop()
+		started = st.C
+	}
+	seen := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-started:
+			if !seen {
+				log.Infof("no application process seen within %v, terminating the proxy", startTimeout)
+				onExit()
+				return
+			}
+			continue
+		case <-t.C:
+		}
+		n, err := applicationProcesses(proc, self)
+		if err != nil {
+			log.Warnf("failed to list the application processes: %v", err)
+			continue
+		}
+		switch {
+		case n > 0 && !seen:
+			log.Infof("watching %d application processes, the proxy terminates once they exit", n)
+			seen = true
+		case n == 0 && seen:
+			log.Infof("application processes exited, terminating the proxy")
+			onExit()
+			return
+		}
+	}
+}
+
+// applicationProcesses returns the number of processes of the other containers of the pod.
+func applicationProcesses(proc string, self []byte) (int, error) {
+	entries, err := os.ReadDir(proc)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, e := range entries {
+		pid, err := strconv.Atoi(e.Name())
+		if err != nil || pid == 1 {
+			continue
+		}
+		cgroup, err := os.ReadFile(filepath.Join(proc, e.Name(), "cgroup"))
+		if err != nil {
+			// The process exited.
+			continue
+		}
+		if !bytes.Equal(cgroup, self) {
+			n++
+		}
+	}
+	return n, nil
+}
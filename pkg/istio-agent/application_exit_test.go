@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test"
+)
+
+func TestWatchApplicationExit(t *testing.T) {
+	test.SetForTest(t, &applicationExitCheckInterval, 10*time.Millisecond)
+	proc := t.TempDir()
+	process := func(pid, cgroup string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(proc, pid), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(proc, pid, "cgroup"), []byte(cgroup), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	process("self", "0::/proxy\n")
+	process("1", "0::/pause\n")
+	process("7", "0::/proxy\n")
+
+	exited := make(chan struct{})
+	go WatchApplicationExit(context.Background(), proc, 0, func() { close(exited) })
+
+	// Processes of the pause and proxy containers only: the application has not started yet.
+	select {
+	case <-exited:
+		t.Fatal("expected the proxy to wait for the application to start")
+	case <-time.After(100 * time.Millisecond):
+	}
+	process("12", "0::/app\n")
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-exited:
+		t.Fatal("expected the proxy to run while the application runs")
+	default:
+	}
+	if err := os.RemoveAll(filepath.Join(proc, "12")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the proxy to terminate once the application exited")
+	}
+}
+
+func TestWatchApplicationExitStartTimeout(t *testing.T) {
+	test.SetForTest(t, &applicationExitCheckInterval, 10*time.Millisecond)
+	proc := t.TempDir()
+	for pid, cgroup := range map[string]string{"self": "0::/proxy\n", "1": "0::/pause\n"} {
+		if err := os.MkdirAll(filepath.Join(proc, pid), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(proc, pid, "cgroup"), []byte(cgroup), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The application exited before being seen.
+	exited := make(chan struct{})
+	go WatchApplicationExit(context.Background(), proc, 100*time.Millisecond, func() { close(exited) })
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the proxy to terminate once no application process was seen within the start timeout")
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/pkg/log"
+)
+
+var stringToLogLevel = map[string]log.Level{
+	"debug": log.DebugLevel,
+	"info":  log.InfoLevel,
+	"warn":  log.WarnLevel,
+	"error": log.ErrorLevel,
+	"fatal": log.FatalLevel,
+	"none":  log.NoneLevel,
+}
+
+// logLevelController applies the temporary log levels pushed by istiod, and reverts them when they expire
+// or when istiod removes them.
+type logLevelController struct {
+	mu sync.Mutex
+	// envoyDefault is the configured Envoy log level, restored on revert.
+	envoyDefault string
+	// setEnvoyLogLevel changes the Envoy log level, nil if Envoy is not managed by the agent.
+	setEnvoyLogLevel func(level string) error
+	envoyChanged     bool
+	// agentDefaults holds the levels of the agent scopes before they were changed.
+	agentDefaults map[string]log.Level
+	current       *model.ProxyLogLevel
+	timer         *time.Timer
+}
+
+func newLogLevelController(envoyDefault string, setEnvoyLogLevel func(level string) error) *logLevelController {
+	return &logLevelController{
+		envoyDefault:     envoyDefault,
+		setEnvoyLogLevel: setEnvoyLogLevel,
+		agentDefaults:    map[string]log.Level{},
+	}
+}
+
+// handleResponse is the handler of the ProxyLogLevel type.
+func (c *logLevelController) handleResponse(resp *anypb.Any) error {
+	sv := &wrapperspb.StringValue{}
+	if err := resp.UnmarshalTo(sv); err != nil {
+		log.Errorf("failed to unmarshal proxy log level: %v", err)
+		return err
+	}
+	level := &model.ProxyLogLevel{}
+	if err := json.Unmarshal([]byte(sv.GetValue()), level); err != nil {
+		log.Errorf("failed to unmarshal proxy log level: %v", err)
+		return err
+	}
+	return c.apply(level, time.Now())
+}
+
+// apply changes the log levels until the expiration of the given level. Any previous change is reverted first,
+// so that loggers which are not part of the new change are restored.
+func (c *logLevelController) apply(level *model.ProxyLogLevel, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.current != nil && c.current.Envoy == level.Envoy && c.current.Agent == level.Agent &&
+		c.current.Expiration.Equal(level.Expiration) {
+		// istiod sends the current levels on every reconnection, do not restart the change.
+		return nil
+	}
+	agentLevels, err := parseAgentLogLevels(level.Agent)
+	if err != nil {
+		return err
+	}
+	err = c.revertLocked()
+	if level.IsEmpty() || !now.Before(level.Expiration) {
+		return err
+	}
+
+	log.Infof("changing log levels until %v: envoy %q, agent %q", level.Expiration.Format(time.RFC3339), level.Envoy, level.Agent)
+	c.current = level
+	if level.Envoy != "" && c.setEnvoyLogLevel != nil {
+		c.envoyChanged = true
+		if envoyErr := c.setEnvoyLogLevel(level.Envoy); envoyErr != nil {
+			err = fmt.Errorf("failed to change envoy log level: %v", envoyErr)
+		}
+	}
+	for scope, l := range agentLevels {
+		s := log.FindScope(scope)
+		if s == nil {
+			log.Warnf("ignoring log level of unknown scope %q", scope)
+			continue
+		}
+		c.agentDefaults[scope] = s.GetOutputLevel()
+		s.SetOutputLevel(l)
+	}
+	c.timer = time.AfterFunc(level.Expiration.Sub(now), func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.current != level {
+			// Replaced by a newer change in the meantime.
+			return
+		}
+		log.Infof("log level change expired, reverting log levels")
+		if err := c.revertLocked(); err != nil {
+			log.Warn(err)
+		}
+	})
+	return err
+}
+
+func (c *logLevelController) revertLocked() error {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.current = nil
+	for scope, l := range c.agentDefaults {
+		if s := log.FindScope(scope); s != nil {
+			s.SetOutputLevel(l)
+		}
+		delete(c.agentDefaults, scope)
+	}
+	if c.envoyChanged {
+		c.envoyChanged = false
+		if err := c.setEnvoyLogLevel(c.envoyDefault); err != nil {
+			return fmt.Errorf("failed to revert envoy log level: %v", err)
+		}
+	}
+	return nil
+}
+
+// parseAgentLogLevels parses agent log levels in the form "<scope>:<level>,...".
+func parseAgentLogLevels(levels string) (map[string]log.Level, error) {
+	out := map[string]log.Level{}
+	for _, sl := range strings.Split(levels, ",") {
+		if sl == "" {
+			continue
+		}
+		scope, level, ok := strings.Cut(sl, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid agent log level %q", sl)
+		}
+		l, ok := stringToLogLevel[level]
+		if !ok {
+			return nil, fmt.Errorf("invalid agent log level %q", sl)
+		}
+		out[scope] = l
+	}
+	return out, nil
+}
+
+// envoyDefaultLogLevel returns the Envoy log level configured at startup, in the form accepted by envoy.SetLogLevel.
+func envoyDefaultLogLevel(logLevel, componentLogLevel string) string {
+	if logLevel == "" {
+		logLevel = "warning"
+	}
+	if !strings.Contains(logLevel, ":") && componentLogLevel != "" {
+		return logLevel + "," + componentLogLevel
+	}
+	return logLevel
+}
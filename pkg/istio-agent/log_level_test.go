@@ -0,0 +1,80 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/pkg/log"
+)
+
+func TestLogLevelController(t *testing.T) {
+	var envoyLevels []string
+	c := newLogLevelController(envoyDefaultLogLevel("warning", "misc:error"), func(level string) error {
+		envoyLevels = append(envoyLevels, level)
+		return nil
+	})
+	scope := log.RegisterScope("loglevel-test", "", 0)
+	scope.SetOutputLevel(log.InfoLevel)
+
+	now := time.Now()
+	level := &model.ProxyLogLevel{Envoy: "http:debug", Agent: "loglevel-test:debug", Expiration: now.Add(time.Hour)}
+	if err := c.apply(level, now); err != nil {
+		t.Fatal(err)
+	}
+	if scope.GetOutputLevel() != log.DebugLevel {
+		t.Fatalf("expected agent scope level to be changed, got %v", scope.GetOutputLevel())
+	}
+	// Receiving the same levels again, for instance on reconnection, is a no-op.
+	if err := c.apply(&model.ProxyLogLevel{Envoy: "http:debug", Agent: "loglevel-test:debug", Expiration: level.Expiration}, now); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(envoyLevels, []string{"http:debug"}) {
+		t.Fatalf("unexpected envoy levels: %v", envoyLevels)
+	}
+
+	// An empty level reverts the change.
+	if err := c.apply(&model.ProxyLogLevel{}, now); err != nil {
+		t.Fatal(err)
+	}
+	if scope.GetOutputLevel() != log.InfoLevel {
+		t.Fatalf("expected agent scope level to be reverted, got %v", scope.GetOutputLevel())
+	}
+	if !reflect.DeepEqual(envoyLevels, []string{"http:debug", "warning,misc:error"}) {
+		t.Fatalf("unexpected envoy levels: %v", envoyLevels)
+	}
+
+	// Changes are reverted on expiration.
+	if err := c.apply(&model.ProxyLogLevel{Agent: "loglevel-test:error", Expiration: time.Now().Add(10 * time.Millisecond)}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if scope.GetOutputLevel() != log.ErrorLevel {
+		t.Fatalf("expected agent scope level to be changed, got %v", scope.GetOutputLevel())
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for scope.GetOutputLevel() != log.InfoLevel {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected agent scope level to be reverted on expiration, got %v", scope.GetOutputLevel())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err := c.apply(&model.ProxyLogLevel{Agent: "loglevel-test", Expiration: now.Add(time.Hour)}, now); err == nil {
+		t.Fatalf("expected invalid agent level to be rejected")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
+	"google.golang.org/grpc/metadata"
+
+	istiogrpc "istio.io/istio/pilot/pkg/grpc"
+)
+
+// StreamLoadStats proxies the load reporting service (LRS) stream of Envoy to istiod. Envoy reports its load to
+// the xds-grpc cluster, which is the agent, so each LRS stream of Envoy is forwarded on its own upstream stream.
+// Unlike ADS, the stream carries no state the agent needs: the messages are forwarded as they are, and the
+// streams are closed together.
+func (p *XdsProxy) StreamLoadStats(downstream lrs.LoadReportingService_StreamLoadStatsServer) error {
+	upstreamConn, _, err := p.dialUpstream()
+	if err != nil {
+		proxyLog.Errorf("failed to connect to upstream %s for load reporting: %v", p.istiodAddress, err)
+		return err
+	}
+	defer upstreamConn.Close()
+
+	ctx, cancel := context.WithCancel(downstream.Context())
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "ClusterID", p.clusterID)
+	for k, v := range p.xdsHeaders {
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	upstream, err := lrs.NewLoadReportingServiceClient(upstreamConn).StreamLoadStats(ctx)
+	if err != nil {
+		proxyLog.Debugf("failed to create upstream load reporting stream: %v", err)
+		return err
+	}
+
+	// Both directions report their first error, so the channel never blocks.
+	errCh := make(chan error, 2)
+	go func() {
+		for {
+			resp, err := upstream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := downstream.Send(resp); err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}()
+	go func() {
+		for {
+			req, err := downstream.Recv()
+			if err != nil {
+				errCh <- err
+				return
+			}
+			if err := upstream.Send(req); err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}()
+
+	err = <-errCh
+	if istiogrpc.IsExpectedGRPCError(err) {
+		proxyLog.Debugf("load reporting stream terminated with status %v", err)
+		return nil
+	}
+	proxyLog.Warnf("load reporting stream terminated with unexpected error %v", err)
+	return err
+}
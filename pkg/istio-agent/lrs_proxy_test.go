@@ -0,0 +1,55 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"testing"
+	"time"
+
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/xds"
+	"istio.io/istio/pkg/test"
+)
+
+// Validates that the load reports of Envoy reach istiod through the agent.
+func TestLoadReportingProxy(t *testing.T) {
+	test.SetForTest(t, &features.EnableLoadReporting, true)
+	test.SetForTest(t, &features.LoadReportingInterval, 5*time.Second)
+	proxy := setupXdsProxy(t)
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+	setDialOptions(proxy, f.BufListener)
+	conn := setupDownstreamConnection(t, proxy)
+
+	downstream, err := lrs.NewLoadReportingServiceClient(conn).StreamLoadStats(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := downstream.Send(&lrs.LoadStatsRequest{Node: &core.Node{Id: "sidecar~1.1.1.1~debug~cluster.local"}}); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := downstream.Recv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.GetSendAllClusters() || resp.GetLoadReportingInterval().AsDuration() != 5*time.Second {
+		t.Fatalf("expected the load reporting settings of istiod, got %v", resp)
+	}
+	if err := downstream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+}
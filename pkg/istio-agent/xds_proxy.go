@@ -30,6 +30,7 @@ import (
 	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	lrs "github.com/envoyproxy/go-control-plane/envoy/service/load_stats/v3"
 	"go.uber.org/atomic"
 	"golang.org/x/net/http2"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
@@ -51,6 +52,7 @@ import (
 	"istio.io/istio/pkg/channels"
 	"istio.io/istio/pkg/config/constants"
 	dnsProto "istio.io/istio/pkg/dns/proto"
+	"istio.io/istio/pkg/envoy"
 	"istio.io/istio/pkg/h2c"
 	"istio.io/istio/pkg/istio-agent/health"
 	"istio.io/istio/pkg/istio-agent/metrics"
@@ -89,14 +91,16 @@ type XdsProxy struct {
 	downstreamListener   net.Listener
 	downstreamGrpcServer *grpc.Server
 	istiodAddress        string
-	istiodDialOptions    []grpc.DialOption
-	optsMutex            sync.RWMutex
-	handlers             map[string]ResponseHandler
-	healthChecker        *health.WorkloadHealthChecker
-	xdsHeaders           map[string]string
-	xdsUdsPath           string
-	proxyAddresses       []string
-	ia                   *Agent
+	// istiodFailoverAddresses are the addresses tried in order when istiodAddress cannot be reached.
+	istiodFailoverAddresses []string
+	istiodDialOptions       []grpc.DialOption
+	optsMutex               sync.RWMutex
+	handlers                map[string]ResponseHandler
+	healthChecker           *health.WorkloadHealthChecker
+	xdsHeaders              map[string]string
+	xdsUdsPath              string
+	proxyAddresses          []string
+	ia                      *Agent
 
 	httpTapServer      *http.Server
 	tapMutex           sync.RWMutex
@@ -146,18 +150,19 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 
 	cache := wasm.NewLocalFileCache(constants.IstioDataDir, ia.cfg.WASMOptions)
 	proxy := &XdsProxy{
-		istiodAddress:         ia.proxyConfig.DiscoveryAddress,
-		istiodSAN:             ia.cfg.IstiodSAN,
-		clusterID:             ia.secOpts.ClusterID,
-		handlers:              map[string]ResponseHandler{},
-		stopChan:              make(chan struct{}),
-		healthChecker:         health.NewWorkloadHealthChecker(ia.proxyConfig.ReadinessProbe, envoyProbe, ia.cfg.ProxyIPAddresses, ia.cfg.IsIPv6),
-		xdsHeaders:            ia.cfg.XDSHeaders,
-		xdsUdsPath:            ia.cfg.XdsUdsPath,
-		wasmCache:             cache,
-		proxyAddresses:        ia.cfg.ProxyIPAddresses,
-		ia:                    ia,
-		downstreamGrpcOptions: ia.cfg.DownstreamGrpcOptions,
+		istiodAddress:           ia.proxyConfig.DiscoveryAddress,
+		istiodSAN:               ia.cfg.IstiodSAN,
+		istiodFailoverAddresses: ia.cfg.IstiodFailoverAddresses,
+		clusterID:               ia.secOpts.ClusterID,
+		handlers:                map[string]ResponseHandler{},
+		stopChan:                make(chan struct{}),
+		healthChecker:           health.NewWorkloadHealthChecker(ia.proxyConfig.ReadinessProbe, envoyProbe, ia.cfg.ProxyIPAddresses, ia.cfg.IsIPv6),
+		xdsHeaders:              ia.cfg.XDSHeaders,
+		xdsUdsPath:              ia.cfg.XdsUdsPath,
+		wasmCache:               cache,
+		proxyAddresses:          ia.cfg.ProxyIPAddresses,
+		ia:                      ia,
+		downstreamGrpcOptions:   ia.cfg.DownstreamGrpcOptions,
 	}
 
 	if ia.localDNSServer != nil {
@@ -188,6 +193,15 @@ func initXdsProxy(ia *Agent) (*XdsProxy, error) {
 		}
 	}
 
+	var setEnvoyLogLevel func(level string) error
+	if !ia.EnvoyDisabled() {
+		setEnvoyLogLevel = func(level string) error {
+			return envoy.SetLogLevel(uint32(ia.proxyConfig.ProxyAdminPort), level)
+		}
+	}
+	logLevels := newLogLevelController(envoyDefaultLogLevel(ia.envoyOpts.LogLevel, ia.envoyOpts.ComponentLogLevel), setEnvoyLogLevel)
+	proxy.handlers[v3.ProxyLogLevelType] = logLevels.handleResponse
+
 	proxyLog.Infof("Initializing with upstream address %q and cluster %q", proxy.istiodAddress, proxy.clusterID)
 
 	if err = proxy.initDownstreamServer(); err != nil {
@@ -338,10 +352,7 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 	p.registerStream(con)
 	defer p.unregisterStream(con)
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-
-	upstreamConn, err := p.buildUpstreamConn(ctx)
+	upstreamConn, address, err := p.dialUpstream()
 	if err != nil {
 		proxyLog.Errorf("failed to connect to upstream %s: %v", p.istiodAddress, err)
 		metrics.IstiodConnectionFailures.Increment()
@@ -349,8 +360,12 @@ func (p *XdsProxy) handleStream(downstream adsStream) error {
 	}
 	defer upstreamConn.Close()
 
+	upstreamCtx, cancelUpstream := context.WithCancel(context.Background())
+	defer cancelUpstream()
+	p.failbackWhenRecovered(upstreamCtx, address, cancelUpstream)
+
 	xds := discovery.NewAggregatedDiscoveryServiceClient(upstreamConn)
-	ctx = metadata.AppendToOutgoingContext(context.Background(), "ClusterID", p.clusterID)
+	ctx := metadata.AppendToOutgoingContext(upstreamCtx, "ClusterID", p.clusterID)
 	for k, v := range p.xdsHeaders {
 		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
 	}
@@ -463,6 +478,12 @@ func (p *XdsProxy) handleUpstreamRequest(con *ProxyConnection) {
 						TypeUrl: v3.ProxyConfigType,
 					})
 				}
+				// fire off an initial request for the temporary log levels
+				if _, f := p.handlers[v3.ProxyLogLevelType]; f {
+					con.sendRequest(&discovery.DiscoveryRequest{
+						TypeUrl: v3.ProxyLogLevelType,
+					})
+				}
 				// set flag before sending the initial request to prevent race.
 				initialRequestsSent.Store(true)
 				// Fire of a configured initial request, if there is one
@@ -645,6 +666,7 @@ func (p *XdsProxy) initDownstreamServer() error {
 	opts = append(opts, istiogrpc.ServerOptions(istiokeepalive.DefaultOption())...)
 	grpcs := grpc.NewServer(opts...)
 	discovery.RegisterAggregatedDiscoveryServiceServer(grpcs, p)
+	lrs.RegisterLoadReportingServiceServer(grpcs, p)
 	reflection.Register(grpcs)
 	p.downstreamGrpcServer = grpcs
 	p.downstreamListener = l
@@ -17,7 +17,6 @@ package istioagent
 import (
 	"context"
 	"fmt"
-	"time"
 
 	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
 	google_rpc "google.golang.org/genproto/googleapis/rpc/status"
@@ -94,6 +93,12 @@ func (p *XdsProxy) DeltaAggregatedResources(downstream xds.DeltaDiscoveryStream)
 						TypeUrl: v3.ProxyConfigType,
 					})
 				}
+				// fire off an initial request for the temporary log levels
+				if _, f := p.handlers[v3.ProxyLogLevelType]; f {
+					con.sendDeltaRequest(&discovery.DeltaDiscoveryRequest{
+						TypeUrl: v3.ProxyLogLevelType,
+					})
+				}
 				// Fire of a configured initial request, if there is one
 				if initialRequest != nil {
 					con.sendDeltaRequest(initialRequest)
@@ -103,9 +108,7 @@ func (p *XdsProxy) DeltaAggregatedResources(downstream xds.DeltaDiscoveryStream)
 		}
 	}()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-	upstreamConn, err := p.buildUpstreamConn(ctx)
+	upstreamConn, address, err := p.dialUpstream()
 	if err != nil {
 		proxyLog.Errorf("failed to connect to upstream %s: %v", p.istiodAddress, err)
 		metrics.IstiodConnectionFailures.Increment()
@@ -113,8 +116,12 @@ func (p *XdsProxy) DeltaAggregatedResources(downstream xds.DeltaDiscoveryStream)
 	}
 	defer upstreamConn.Close()
 
+	upstreamCtx, cancelUpstream := context.WithCancel(context.Background())
+	defer cancelUpstream()
+	p.failbackWhenRecovered(upstreamCtx, address, cancelUpstream)
+
 	xds := discovery.NewAggregatedDiscoveryServiceClient(upstreamConn)
-	ctx = metadata.AppendToOutgoingContext(context.Background(), "ClusterID", p.clusterID)
+	ctx := metadata.AppendToOutgoingContext(upstreamCtx, "ClusterID", p.clusterID)
 	for k, v := range p.xdsHeaders {
 		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
 	}
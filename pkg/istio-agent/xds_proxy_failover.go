@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"google.golang.org/grpc"
+)
+
+var (
+	// upstreamDialTimeout bounds the connection to an istiod instance.
+	upstreamDialTimeout = 5 * time.Second
+	// failbackInterval is the interval at which the XDS proxy connected to a failover address checks whether the
+	// discovery address can be reached again.
+	failbackInterval = 30 * time.Second
+)
+
+// dialUpstream connects to the discovery address or, if it cannot be reached, to the first failover address that
+// can, returning the address connected to. Without failover addresses, the connection is established as before.
+func (p *XdsProxy) dialUpstream() (*grpc.ClientConn, string, error) {
+	if len(p.istiodFailoverAddresses) == 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), upstreamDialTimeout)
+		defer cancel()
+		conn, err := p.buildUpstreamConn(ctx)
+		return conn, p.istiodAddress, err
+	}
+	var errs error
+	for _, address := range append([]string{p.istiodAddress}, p.istiodFailoverAddresses...) {
+		conn, err := p.probeUpstream(address)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%s: %v", address, err))
+			continue
+		}
+		if address != p.istiodAddress {
+			proxyLog.Warnf("failed over to upstream %s: %v", address, errs)
+		}
+		return conn, address, nil
+	}
+	return nil, "", errs
+}
+
+// probeUpstream connects to an istiod instance, blocking until the connection, including the TLS handshake, is
+// established, so that the failover addresses are only used when the instances before them cannot be reached.
+func (p *XdsProxy) probeUpstream(address string) (*grpc.ClientConn, error) {
+	p.optsMutex.RLock()
+	opts := make([]grpc.DialOption, 0, len(p.istiodDialOptions)+2)
+	opts = append(opts, p.istiodDialOptions...)
+	p.optsMutex.RUnlock()
+	opts = append(opts, grpc.WithBlock(), grpc.WithReturnConnectionError())
+
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamDialTimeout)
+	defer cancel()
+	return grpc.DialContext(ctx, address, opts...)
+}
+
+// failbackWhenRecovered cancels the upstream stream connected to a failover address once the discovery address can
+// be reached again. The termination is propagated to Envoy, which reconnects through the discovery address.
+func (p *XdsProxy) failbackWhenRecovered(ctx context.Context, address string, cancel context.CancelFunc) {
+	if address == p.istiodAddress {
+		return
+	}
+	go func() {
+		t := time.NewTicker(failbackInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.stopChan:
+				return
+			case <-t.C:
+			}
+			conn, err := p.probeUpstream(p.istiodAddress)
+			if err != nil {
+				proxyLog.Debugf("upstream %s still cannot be reached: %v", p.istiodAddress, err)
+				continue
+			}
+			_ = conn.Close()
+			proxyLog.Infof("upstream %s can be reached again, failing back from %s", p.istiodAddress, address)
+			cancel()
+			return
+		}
+	}()
+}
@@ -0,0 +1,93 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istioagent
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pilot/pkg/xds"
+)
+
+func serveDiscovery(t *testing.T, f *xds.FakeDiscoveryServer, listener net.Listener) *grpc.Server {
+	grpcServer := grpc.NewServer()
+	t.Cleanup(grpcServer.Stop)
+	f.Discovery.Register(grpcServer)
+	go grpcServer.Serve(listener)
+	return grpcServer
+}
+
+func TestXdsProxyFailover(t *testing.T) {
+	dialTimeout, interval := upstreamDialTimeout, failbackInterval
+	upstreamDialTimeout, failbackInterval = 200*time.Millisecond, 50*time.Millisecond
+	t.Cleanup(func() {
+		upstreamDialTimeout, failbackInterval = dialTimeout, interval
+	})
+
+	proxy := setupXdsProxy(t)
+	f := xds.NewFakeDiscoveryServer(t, xds.FakeOptions{})
+
+	// Reserve the discovery address, which cannot be reached until istiod is started on it.
+	primary, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary.Close()
+	unreachable, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unreachable.Close()
+	failoverListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	failover := serveDiscovery(t, f, failoverListener)
+
+	proxy.istiodAddress = primary.Addr().String()
+	proxy.istiodFailoverAddresses = []string{unreachable.Addr().String(), failoverListener.Addr().String()}
+	proxy.istiodDialOptions = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+
+	// The discovery address and the first failover address cannot be reached, so the second one is used.
+	conn := setupDownstreamConnection(t, proxy)
+	downstream := stream(t, conn)
+	sendDownstreamWithNode(t, downstream, model.NodeMetadata{
+		Namespace:   "default",
+		InstanceIPs: []string{"1.1.1.1"},
+	})
+
+	// Once the discovery address can be reached again, the stream is terminated to fail back to it.
+	listener, err := net.Listen("tcp", proxy.istiodAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveDiscovery(t, f, listener)
+	if _, err := downstream.Recv(); err == nil {
+		t.Fatal("expected the stream connected to the failover address to be terminated")
+	}
+
+	// The new stream is connected to the discovery address, as the failover address is stopped.
+	failover.Stop()
+	downstream = stream(t, conn)
+	sendDownstreamWithNode(t, downstream, model.NodeMetadata{
+		Namespace:   "default",
+		InstanceIPs: []string{"1.1.1.1"},
+	})
+}
@@ -370,7 +370,7 @@ type client struct {
 }
 
 // newClientInternal creates a Kubernetes client from the given factory.
-func newClientInternal(clientFactory *clientFactory, revision string) (*client, error) {
+func newClientInternal(clientFactory *clientFactory, revision string, opts InformerOptions) (*client, error) {
 	var c client
 	var err error
 
@@ -380,6 +380,9 @@ func newClientInternal(clientFactory *clientFactory, revision string) (*client,
 	if err != nil {
 		return nil, err
 	}
+	opts.wrapTransport(c.config)
+	resync := opts.defaultResync()
+	customResync := opts.customResync()
 
 	c.revision = revision
 
@@ -404,37 +407,42 @@ func newClientInternal(clientFactory *clientFactory, revision string) (*client,
 	if err != nil {
 		return nil, err
 	}
-	c.kubeInformer = informers.NewSharedInformerFactory(c.kube, resyncInterval)
+	c.kubeInformer = informers.NewSharedInformerFactoryWithOptions(c.kube, resync,
+		informers.WithCustomResyncConfig(customResync))
 
 	c.metadata, err = metadata.NewForConfig(c.config)
 	if err != nil {
 		return nil, err
 	}
-	c.metadataInformer = metadatainformer.NewSharedInformerFactory(c.metadata, resyncInterval)
+	// The metadata and dynamic informer factories do not support resync periods per resource.
+	c.metadataInformer = metadatainformer.NewSharedInformerFactory(c.metadata, resync)
 
 	c.dynamic, err = dynamic.NewForConfig(c.config)
 	if err != nil {
 		return nil, err
 	}
-	c.dynamicInformer = dynamicinformer.NewDynamicSharedInformerFactory(c.dynamic, resyncInterval)
+	c.dynamicInformer = dynamicinformer.NewDynamicSharedInformerFactory(c.dynamic, resync)
 
 	c.istio, err = istioclient.NewForConfig(c.config)
 	if err != nil {
 		return nil, err
 	}
-	c.istioInformer = istioinformer.NewSharedInformerFactory(c.istio, resyncInterval)
+	c.istioInformer = istioinformer.NewSharedInformerFactoryWithOptions(c.istio, resync,
+		istioinformer.WithCustomResyncConfig(customResync))
 
 	c.gatewayapi, err = gatewayapiclient.NewForConfig(c.config)
 	if err != nil {
 		return nil, err
 	}
-	c.gatewayapiInformer = gatewayapiinformer.NewSharedInformerFactory(c.gatewayapi, resyncInterval)
+	c.gatewayapiInformer = gatewayapiinformer.NewSharedInformerFactoryWithOptions(c.gatewayapi, resync,
+		gatewayapiinformer.WithCustomResyncConfig(customResync))
 
 	c.extSet, err = kubeExtClient.NewForConfig(c.config)
 	if err != nil {
 		return nil, err
 	}
-	c.extInformer = kubeExtInformers.NewSharedInformerFactory(c.extSet, resyncInterval)
+	c.extInformer = kubeExtInformers.NewSharedInformerFactoryWithOptions(c.extSet, resync,
+		kubeExtInformers.WithCustomResyncConfig(customResync))
 
 	c.portManager = defaultAvailablePort
 
@@ -464,12 +472,18 @@ func NewDefaultClient() (Client, error) {
 // This is appropriate for use in CLI libraries because it exposes functionality unsafe for in-cluster controllers,
 // and uses standard CLI (kubectl) caching.
 func NewCLIClient(clientConfig clientcmd.ClientConfig, revision string) (CLIClient, error) {
-	return newClientInternal(newClientFactory(clientConfig, true), revision)
+	return newClientInternal(newClientFactory(clientConfig, true), revision, InformerOptions{})
 }
 
 // NewClient creates a Kubernetes client from the given rest config.
 func NewClient(clientConfig clientcmd.ClientConfig) (Client, error) {
-	return newClientInternal(newClientFactory(clientConfig, false), "")
+	return newClientInternal(newClientFactory(clientConfig, false), "", InformerOptions{})
+}
+
+// NewClientWithInformerOptions creates a Kubernetes client from the given rest config, whose informers
+// are tuned with the given options.
+func NewClientWithInformerOptions(clientConfig clientcmd.ClientConfig, opts InformerOptions) (Client, error) {
+	return newClientInternal(newClientFactory(clientConfig, false), "", opts)
 }
 
 func (c *client) RESTConfig() *rest.Config {
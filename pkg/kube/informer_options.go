@@ -0,0 +1,235 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+
+	"istio.io/pkg/monitoring"
+)
+
+// AllResources is the key of InformerOptions settings applying to the resources without their own setting.
+const AllResources = "*"
+
+// InformerOptions tunes how the informers of a Client list and watch resources, to reduce the load on the
+// API server when all the informers start at once on large clusters. All the settings are keyed by resource,
+// such as "pods" or "endpointslices", or by AllResources.
+type InformerOptions struct {
+	// Resync is the period at which informers replay their cached objects to their handlers. Resyncs do not
+	// call the API server. A period of 0, the default, disables resyncs.
+	Resync map[string]time.Duration
+	// ListPageSize is the number of objects returned by each page of the initial lists. By default, lists are
+	// served by the API server watch cache in a single response, which can be very large. Paginated lists are
+	// served by etcd, and are usually slower, but do not require the API server to build the whole response.
+	ListPageSize map[string]int64
+	// WatchBookmarks enables watch bookmarks, which allow restarted watches to resume from a recent resource
+	// version instead of relisting. They are enabled by default.
+	WatchBookmarks map[string]bool
+}
+
+// ParseInformerOptions parses the settings of InformerOptions, each of which is a comma separated list of
+// resource=value pairs such as "*=10m,pods=0s".
+func ParseInformerOptions(resync, listPageSize, watchBookmarks string) (InformerOptions, error) {
+	var opts InformerOptions
+	var err error
+	if opts.Resync, err = parseResourceSettings(resync, time.ParseDuration); err != nil {
+		return opts, fmt.Errorf("invalid resync periods: %v", err)
+	}
+	if opts.ListPageSize, err = parseResourceSettings(listPageSize, func(s string) (int64, error) {
+		size, err := strconv.ParseInt(s, 10, 64)
+		if err == nil && size < 0 {
+			err = fmt.Errorf("negative page size %d", size)
+		}
+		return size, err
+	}); err != nil {
+		return opts, fmt.Errorf("invalid list page sizes: %v", err)
+	}
+	if opts.WatchBookmarks, err = parseResourceSettings(watchBookmarks, strconv.ParseBool); err != nil {
+		return opts, fmt.Errorf("invalid watch bookmarks: %v", err)
+	}
+	return opts, nil
+}
+
+func parseResourceSettings[T any](s string, parse func(string) (T, error)) (map[string]T, error) {
+	if s == "" {
+		return nil, nil
+	}
+	settings := map[string]T{}
+	for _, setting := range strings.Split(s, ",") {
+		resource, value, f := strings.Cut(strings.TrimSpace(setting), "=")
+		if !f || resource == "" {
+			return nil, fmt.Errorf("%q is not a resource=value pair", setting)
+		}
+		v, err := parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", resource, err)
+		}
+		settings[strings.ToLower(resource)] = v
+	}
+	return settings, nil
+}
+
+// resourceSetting returns the setting of the resource, or else the one of AllResources.
+func resourceSetting[T any](settings map[string]T, resource string) (T, bool) {
+	if v, f := settings[resource]; f {
+		return v, true
+	}
+	v, f := settings[AllResources]
+	return v, f
+}
+
+// defaultResync returns the resync period of the resources without their own setting.
+func (o InformerOptions) defaultResync() time.Duration {
+	return o.Resync[AllResources]
+}
+
+// customResync returns the resync periods of the resources with their own setting, keyed by an object of
+// the resource type as expected by the informer factories.
+func (o InformerOptions) customResync() map[metav1.Object]time.Duration {
+	custom := map[metav1.Object]time.Duration{}
+	for gvk, t := range IstioScheme.AllKnownTypes() {
+		resource, _ := meta.UnsafeGuessKindToResource(gvk)
+		period, f := o.Resync[resource.Resource]
+		if !f {
+			continue
+		}
+		if obj, ok := reflect.New(t).Interface().(metav1.Object); ok {
+			custom[obj] = period
+		}
+	}
+	return custom
+}
+
+// wrapTransport applies the list and watch settings to the requests of the clients.
+func (o InformerOptions) wrapTransport(config *rest.Config) {
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return &informerTransport{opts: o, next: rt}
+	})
+}
+
+var (
+	resourceTag = monitoring.MustCreateLabel("resource")
+
+	listResponseBytes = monitoring.NewDistribution(
+		"pilot_k8s_list_response_bytes",
+		"Size in bytes of the responses to list requests sent to the Kubernetes API server, which are mostly "+
+			"made when informers start or relist.",
+		[]float64{1e3, 1e4, 1e5, 1e6, 1e7, 5e7, 1e8, 5e8},
+		monitoring.WithLabels(resourceTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(listResponseBytes)
+}
+
+// informerTransport sets the page size of list requests and the watch bookmarks of watch requests, and
+// records the size of list responses.
+type informerTransport struct {
+	opts InformerOptions
+	next http.RoundTripper
+}
+
+func (t *informerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource, list := listedResource(req)
+	if !list {
+		return t.next.RoundTrip(req)
+	}
+	query := req.URL.Query()
+	if watch, _ := strconv.ParseBool(query.Get("watch")); watch {
+		if bookmarks, f := resourceSetting(t.opts.WatchBookmarks, resource); f {
+			query.Set("allowWatchBookmarks", strconv.FormatBool(bookmarks))
+			req = withQuery(req, query)
+		}
+		return t.next.RoundTrip(req)
+	}
+
+	// Only paginated lists are changed, as the pager of the informers falls back to full lists when
+	// continuing a list fails.
+	if size, _ := resourceSetting(t.opts.ListPageSize, resource); size > 0 && query.Get("limit") != "" {
+		query.Set("limit", strconv.FormatInt(size, 10))
+		if query.Get("resourceVersion") == "0" {
+			// The watch cache ignores the limit of lists at any resource version.
+			query.Del("resourceVersion")
+		}
+		req = withQuery(req, query)
+	}
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	resp.Body = &countingBody{ReadCloser: resp.Body, resource: resource}
+	return resp, nil
+}
+
+func withQuery(req *http.Request, query url.Values) *http.Request {
+	req = req.Clone(req.Context())
+	req.URL.RawQuery = query.Encode()
+	return req
+}
+
+// listedResource returns the resource of list and watch requests, whose paths are
+// /api/<version>[/namespaces/<namespace>]/<resource> or /apis/<group>/<version>[/namespaces/<namespace>]/<resource>.
+func listedResource(req *http.Request) (string, bool) {
+	if req.Method != http.MethodGet {
+		return "", false
+	}
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case parts[0] == "api" && len(parts) >= 2:
+		parts = parts[2:]
+	case parts[0] == "apis" && len(parts) >= 3:
+		parts = parts[3:]
+	default:
+		return "", false
+	}
+	switch {
+	case len(parts) == 1:
+		return parts[0], true
+	case len(parts) == 3 && parts[0] == "namespaces":
+		return parts[2], true
+	default:
+		return "", false
+	}
+}
+
+// countingBody records the size of a list response once it is read.
+type countingBody struct {
+	io.ReadCloser
+	resource string
+	bytes    int
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.bytes += n
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	listResponseBytes.With(resourceTag.Value(b.resource)).Record(float64(b.bytes))
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,117 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInformerOptions(t *testing.T) {
+	opts, err := ParseInformerOptions("*=10m, Pods=0s", "*=500", "endpointslices=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := InformerOptions{
+		Resync:         map[string]time.Duration{AllResources: 10 * time.Minute, "pods": 0},
+		ListPageSize:   map[string]int64{AllResources: 500},
+		WatchBookmarks: map[string]bool{"endpointslices": false},
+	}
+	if !reflect.DeepEqual(opts, want) {
+		t.Fatalf("got %+v, want %+v", opts, want)
+	}
+
+	for _, invalid := range [][3]string{{"pods", "", ""}, {"pods=1", "", ""}, {"", "*=-1", ""}, {"", "", "pods=maybe"}} {
+		if _, err := ParseInformerOptions(invalid[0], invalid[1], invalid[2]); err == nil {
+			t.Errorf("expected %v to be invalid", invalid)
+		}
+	}
+}
+
+type recordingTransport struct {
+	requests []*http.Request
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+}
+
+func TestInformerTransport(t *testing.T) {
+	opts := InformerOptions{
+		ListPageSize:   map[string]int64{AllResources: 100, "pods": 1000},
+		WatchBookmarks: map[string]bool{"endpointslices": false},
+	}
+	cases := []struct {
+		name string
+		url  string
+		want url.Values
+	}{
+		{
+			name: "paginated list",
+			url:  "/api/v1/namespaces/default/pods?limit=500&resourceVersion=0",
+			want: url.Values{"limit": {"1000"}},
+		},
+		{
+			name: "default page size",
+			url:  "/apis/networking.istio.io/v1alpha3/serviceentries?limit=500",
+			want: url.Values{"limit": {"100"}},
+		},
+		{
+			name: "full list",
+			url:  "/api/v1/pods?resourceVersion=10",
+			want: url.Values{"resourceVersion": {"10"}},
+		},
+		{
+			name: "get",
+			url:  "/api/v1/namespaces/default?limit=500",
+			want: url.Values{"limit": {"500"}},
+		},
+		{
+			name: "watch without bookmarks",
+			url:  "/apis/discovery.k8s.io/v1/endpointslices?watch=true&allowWatchBookmarks=true",
+			want: url.Values{"watch": {"true"}, "allowWatchBookmarks": {"false"}},
+		},
+		{
+			name: "watch with bookmarks",
+			url:  "/api/v1/services?watch=true&allowWatchBookmarks=true",
+			want: url.Values{"watch": {"true"}, "allowWatchBookmarks": {"true"}},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &recordingTransport{}
+			transport := &informerTransport{opts: opts, next: next}
+			req, err := http.NewRequest(http.MethodGet, "https://kubernetes"+tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _ = io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			if got := next.requests[0].URL.Query(); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got query %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
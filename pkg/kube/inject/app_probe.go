@@ -19,6 +19,7 @@ package inject
 import (
 	"encoding/json"
 	"strconv"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -26,9 +27,15 @@ import (
 	"istio.io/api/annotation"
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
 	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/util/sets"
 	"istio.io/pkg/log"
 )
 
+// RewriteAppProbersExcludeContainersAnnotation is a comma separated list of the containers of a pod whose probes
+// are not rewritten, even if probes are rewritten for the pod.
+// TODO: move to API
+const RewriteAppProbersExcludeContainersAnnotation = "sidecar.istio.io/rewriteAppProbersExcludeContainers"
+
 // ShouldRewriteAppHTTPProbers returns if we should rewrite apps' probers config.
 func ShouldRewriteAppHTTPProbers(annotations map[string]string, specSetting bool) bool {
 	if annotations != nil {
@@ -123,62 +130,129 @@ type Prober struct {
 	TimeoutSeconds int32                   `json:"timeoutSeconds,omitempty"`
 }
 
-// DumpAppProbers returns a json encoded string as `status.KubeAppProbers`.
-// Also update the probers so that all usages of named port will be resolved to integer.
-func DumpAppProbers(podSpec *corev1.PodSpec, targetPort int32) string {
-	out := KubeAppProbers{}
-	updateNamedPort := func(p *Prober, portMap map[string]int32) *Prober {
-		if p == nil {
-			return nil
+// probeRewrite decides which probes of a pod are rewritten. The probes run by the agent and the probes
+// patched in the pod are both selected by it, so that the agent serves every rewritten probe.
+type probeRewrite struct {
+	statusPort         int32
+	excludedContainers sets.String
+	// includedPorts are the only inbound ports redirected to the sidecar, if not nil.
+	includedPorts sets.Set[int32]
+	excludedPorts sets.Set[int32]
+}
+
+func newProbeRewrite(annotations map[string]string, statusPort int32) probeRewrite {
+	r := probeRewrite{statusPort: statusPort, excludedContainers: sets.New[string](), excludedPorts: sets.New[int32]()}
+	for _, c := range strings.Split(annotations[RewriteAppProbersExcludeContainersAnnotation], ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			r.excludedContainers.Insert(c)
 		}
-		if p.GRPC != nil {
-			// don't need to update for gRPC probe port as it only supports integer
-			return p
+	}
+	if v, f := annotations[annotation.SidecarTrafficIncludeInboundPorts.Name]; f && strings.TrimSpace(v) != "*" {
+		ports, err := parsePorts(v)
+		if err == nil {
+			r.includedPorts = sets.New[int32]()
+			for _, p := range ports {
+				r.includedPorts.Insert(int32(p))
+			}
+		}
+	}
+	if ports, err := parsePorts(annotations[annotation.SidecarTrafficExcludeInboundPorts.Name]); err == nil {
+		for _, p := range ports {
+			r.excludedPorts.Insert(int32(p))
 		}
-		if p.HTTPGet == nil && p.TCPSocket == nil {
+	}
+	return r
+}
+
+// redirected returns true if the inbound traffic to the port is redirected to the sidecar. Probes to ports
+// which are not redirected reach the application directly, and do not need to be rewritten.
+func (r probeRewrite) redirected(port int32) bool {
+	if r.excludedPorts.Contains(port) {
+		return false
+	}
+	return r.includedPorts == nil || r.includedPorts.Contains(port)
+}
+
+// prober returns the prober run by the agent for the probe of the container, with named ports resolved to
+// integers, or nil if the probe is not rewritten.
+func (r probeRewrite) prober(c *corev1.Container, probe *corev1.Probe) *Prober {
+	if c.Name == ProxyContainerName || r.excludedContainers.Contains(c.Name) {
+		return nil
+	}
+	p := kubeProbeToInternalProber(probe)
+	if p == nil {
+		return nil
+	}
+	if p.GRPC != nil {
+		// gRPC probe ports are always integers.
+		if p.GRPC.Port == r.statusPort || !r.redirected(p.GRPC.Port) {
 			return nil
 		}
+		return p
+	}
 
-		var probePort *intstr.IntOrString
-		if p.HTTPGet != nil {
-			probePort = &p.HTTPGet.Port
-		} else {
-			probePort = &p.TCPSocket.Port
+	var probePort *intstr.IntOrString
+	if p.HTTPGet != nil {
+		if p.HTTPGet.Host != "" {
+			// Kubelet probes another host, which would receive the rewritten probe on its own status port.
+			return nil
 		}
+		p.HTTPGet = p.HTTPGet.DeepCopy()
+		probePort = &p.HTTPGet.Port
+	} else {
+		if p.TCPSocket.Host != "" {
+			return nil
+		}
+		p.TCPSocket = p.TCPSocket.DeepCopy()
+		probePort = &p.TCPSocket.Port
+	}
 
-		if probePort.Type == intstr.String {
-			port, exists := portMap[probePort.StrVal]
-			if !exists {
-				return nil
-			}
-			*probePort = intstr.FromInt(int(port))
-		} else if probePort.IntVal == targetPort {
-			// Already is rewritten
+	if probePort.Type == intstr.String {
+		port, exists := containerPort(c, probePort.StrVal)
+		if !exists {
 			return nil
 		}
-		return p
+		*probePort = intstr.FromInt(int(port))
 	}
-	for _, c := range podSpec.Containers {
-		if c.Name == ProxyContainerName {
-			continue
+	if int32(probePort.IntValue()) == r.statusPort {
+		// Already is rewritten
+		return nil
+	}
+	if !r.redirected(int32(probePort.IntValue())) {
+		return nil
+	}
+	return p
+}
+
+func containerPort(c *corev1.Container, name string) (int32, bool) {
+	for _, p := range c.Ports {
+		if p.Name != "" && p.Name == name {
+			return p.ContainerPort, true
 		}
+	}
+	return 0, false
+}
+
+// DumpAppProbers returns a json encoded string as `status.KubeAppProbers`.
+// Also update the probers so that all usages of named port will be resolved to integer.
+func DumpAppProbers(podSpec *corev1.PodSpec, targetPort int32) string {
+	return dumpAppProbers(podSpec, newProbeRewrite(nil, targetPort))
+}
+
+func dumpAppProbers(podSpec *corev1.PodSpec, rewrite probeRewrite) string {
+	out := KubeAppProbers{}
+	for i := range podSpec.Containers {
+		c := &podSpec.Containers[i]
 		readyz, livez, startupz := status.FormatProberURL(c.Name)
-		portMap := map[string]int32{}
-		for _, p := range c.Ports {
-			if p.Name != "" {
-				portMap[p.Name] = p.ContainerPort
-			}
-		}
-		if h := updateNamedPort(kubeProbeToInternalProber(c.ReadinessProbe), portMap); h != nil {
+		if h := rewrite.prober(c, c.ReadinessProbe); h != nil {
 			out[readyz] = h
 		}
-		if h := updateNamedPort(kubeProbeToInternalProber(c.LivenessProbe), portMap); h != nil {
+		if h := rewrite.prober(c, c.LivenessProbe); h != nil {
 			out[livez] = h
 		}
-		if h := updateNamedPort(kubeProbeToInternalProber(c.StartupProbe), portMap); h != nil {
+		if h := rewrite.prober(c, c.StartupProbe); h != nil {
 			out[startupz] = h
 		}
-
 	}
 	// prevent generate '{}'
 	if len(out) == 0 {
@@ -192,30 +266,37 @@ func DumpAppProbers(podSpec *corev1.PodSpec, targetPort int32) string {
 	return string(b)
 }
 
-// patchRewriteProbe generates the patch for webhook.
-func patchRewriteProbe(annotations map[string]string, pod *corev1.Pod, defaultPort int32) {
-	statusPort := int(defaultPort)
+// rewriteStatusPort returns the status port of the agent serving rewritten probes.
+func rewriteStatusPort(annotations map[string]string, defaultPort int32) int32 {
 	if v, f := annotations[annotation.SidecarStatusPort.Name]; f {
 		p, err := strconv.Atoi(v)
 		if err != nil {
 			log.Errorf("Invalid annotation %v=%v: %v", annotation.SidecarStatusPort.Name, v, err)
+			return defaultPort
 		}
-		statusPort = p
+		return int32(p)
 	}
+	return defaultPort
+}
+
+// patchRewriteProbe generates the patch for webhook.
+func patchRewriteProbe(annotations map[string]string, pod *corev1.Pod, defaultPort int32) {
+	statusPort := rewriteStatusPort(annotations, defaultPort)
+	rewrite := newProbeRewrite(annotations, statusPort)
 	for i, c := range pod.Spec.Containers {
 		// Skip sidecar container.
 		if c.Name == ProxyContainerName {
 			continue
 		}
 		readyz, livez, startupz := status.FormatProberURL(c.Name)
-		if probePatch := convertAppProber(c.ReadinessProbe, readyz, statusPort); probePatch != nil {
-			c.ReadinessProbe = probePatch
+		if rewrite.prober(&c, c.ReadinessProbe) != nil {
+			c.ReadinessProbe = convertAppProber(c.ReadinessProbe, readyz, int(statusPort))
 		}
-		if probePatch := convertAppProber(c.LivenessProbe, livez, statusPort); probePatch != nil {
-			c.LivenessProbe = probePatch
+		if rewrite.prober(&c, c.LivenessProbe) != nil {
+			c.LivenessProbe = convertAppProber(c.LivenessProbe, livez, int(statusPort))
 		}
-		if probePatch := convertAppProber(c.StartupProbe, startupz, statusPort); probePatch != nil {
-			c.StartupProbe = probePatch
+		if rewrite.prober(&c, c.StartupProbe) != nil {
+			c.StartupProbe = convertAppProber(c.StartupProbe, startupz, int(statusPort))
 		}
 		pod.Spec.Containers[i] = c
 	}
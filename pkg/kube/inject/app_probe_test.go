@@ -364,3 +364,77 @@ func TestPatchRewriteProbe(t *testing.T) {
 		}
 	}
 }
+
+func TestProbeRewriteSkipped(t *testing.T) {
+	httpProbe := func(host string, port intstr.IntOrString) *corev1.Probe {
+		return &corev1.Probe{ProbeHandler: corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Host: host, Path: "/ready", Port: port}}}
+	}
+	for _, tc := range []struct {
+		name        string
+		annotations map[string]string
+		container   corev1.Container
+		rewritten   bool
+	}{
+		{
+			name:      "rewritten",
+			container: corev1.Container{Name: "app", ReadinessProbe: httpProbe("", intstr.FromInt(8080))},
+			rewritten: true,
+		},
+		{
+			name:        "container opted out",
+			annotations: map[string]string{RewriteAppProbersExcludeContainersAnnotation: "other, app"},
+			container:   corev1.Container{Name: "app", ReadinessProbe: httpProbe("", intstr.FromInt(8080))},
+		},
+		{
+			name:        "port excluded from redirection",
+			annotations: map[string]string{annotation.SidecarTrafficExcludeInboundPorts.Name: "9090,8080"},
+			container:   corev1.Container{Name: "app", ReadinessProbe: httpProbe("", intstr.FromInt(8080))},
+		},
+		{
+			name:        "port not included in redirection",
+			annotations: map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: "9090"},
+			container:   corev1.Container{Name: "app", ReadinessProbe: httpProbe("", intstr.FromInt(8080))},
+		},
+		{
+			name:        "all ports included in redirection",
+			annotations: map[string]string{annotation.SidecarTrafficIncludeInboundPorts.Name: "*"},
+			container:   corev1.Container{Name: "app", ReadinessProbe: httpProbe("", intstr.FromInt(8080))},
+			rewritten:   true,
+		},
+		{
+			name:      "probe of another host",
+			container: corev1.Container{Name: "app", ReadinessProbe: httpProbe("example.com", intstr.FromInt(8080))},
+		},
+		{
+			name:      "unknown named port",
+			container: corev1.Container{Name: "app", ReadinessProbe: httpProbe("", intstr.FromString("http"))},
+		},
+		{
+			name:        "named port excluded from redirection",
+			annotations: map[string]string{annotation.SidecarTrafficExcludeInboundPorts.Name: "8080"},
+			container: corev1.Container{
+				Name:           "app",
+				Ports:          []corev1.ContainerPort{{Name: "http", ContainerPort: 8080}},
+				ReadinessProbe: httpProbe("", intstr.FromString("http")),
+			},
+		},
+		{
+			name:        "gRPC port excluded from redirection",
+			annotations: map[string]string{annotation.SidecarTrafficExcludeInboundPorts.Name: "1234"},
+			container: corev1.Container{
+				Name:           "app",
+				ReadinessProbe: &corev1.Probe{ProbeHandler: corev1.ProbeHandler{GRPC: &corev1.GRPCAction{Port: 1234}}},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{tc.container}}}
+			probers := dumpAppProbers(&pod.Spec, newProbeRewrite(tc.annotations, 15020))
+			patchRewriteProbe(tc.annotations, pod, 15020)
+			patched := !reflect.DeepEqual(pod.Spec.Containers[0].ReadinessProbe, tc.container.ReadinessProbe)
+			if (probers != "") != tc.rewritten || patched != tc.rewritten {
+				t.Fatalf("expected rewritten=%v, got probers %q and patched=%v", tc.rewritten, probers, patched)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 
 	"github.com/Masterminds/sprig/v3"
 	jsonpatch "github.com/evanphx/json-patch/v5"
+	"google.golang.org/protobuf/proto"
 	appsv1 "k8s.io/api/apps/v1"
 	batch "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -45,7 +46,9 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	proxyConfig "istio.io/api/networking/v1beta1"
 	opconfig "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/util/protomarshal"
 	"istio.io/pkg/log"
 )
 
@@ -89,6 +92,8 @@ const (
 	ImageTypeDebug = "debug"
 	// ImageTypeDistroless is the suffix of the distroless image.
 	ImageTypeDistroless = "distroless"
+	// ImageTypeWindows is the suffix of the Windows image.
+	ImageTypeWindows = "windows"
 	// ImageTypeDefault is the type name of the default image, sufix is elided.
 	ImageTypeDefault = "default"
 )
@@ -106,6 +111,8 @@ type SidecarTemplateData struct {
 	Revision             string
 	EstimatedConcurrency int
 	ProxyImage           string
+	// IsWindows is true if the pod is scheduled on Windows nodes.
+	IsWindows bool
 }
 
 type (
@@ -336,7 +343,7 @@ func imageURL(hub, imageName, tag, imageType string) string {
 }
 
 // KnownImageTypes are image types that istio pubishes.
-var KnownImageTypes = []string{ImageTypeDistroless, ImageTypeDebug}
+var KnownImageTypes = []string{ImageTypeDistroless, ImageTypeDebug, ImageTypeWindows}
 
 func updateImageTypeIfPresent(tag string, imageType string) string {
 	if imageType == "" {
@@ -368,6 +375,19 @@ func RunTemplate(params InjectionParameters) (mergedPod *corev1.Pod, templatePod
 		return nil, nil, err
 	}
 
+	proxyCfg := params.proxyConfig
+	windows := isWindowsPod(params.pod)
+	if windows {
+		// Windows nodes cannot redirect traffic with iptables, so the proxy runs in explicit mode and its listeners
+		// bind to the ports directly.
+		if mode, f := metadata.Annotations[annotation.SidecarInterceptionMode.Name]; f && mode != meshconfig.ProxyConfig_NONE.String() {
+			return nil, nil, fmt.Errorf("interception mode %s is not supported by Windows pods, only %s is",
+				mode, meshconfig.ProxyConfig_NONE)
+		}
+		proxyCfg = proto.Clone(proxyCfg).(*meshconfig.ProxyConfig)
+		proxyCfg.InterceptionMode = meshconfig.ProxyConfig_NONE
+	}
+
 	cluster := params.valuesConfig.asStruct.GetGlobal().GetMultiCluster().GetClusterName()
 	// TODO allow overriding the values.global network in injection with the system namespace label
 	network := params.valuesConfig.asStruct.GetGlobal().GetNetwork()
@@ -382,6 +402,11 @@ func RunTemplate(params InjectionParameters) (mergedPod *corev1.Pod, templatePod
 	if n, ok := metadata.Labels[label.TopologyNetwork.Name]; ok {
 		network = n
 	}
+	if _, ok := metadata.Labels[label.TopologyNetwork.Name]; !ok && features.EnableDynamicNetworks {
+		// The network is assigned by istiod from the node of the pod, which is not known yet.
+		network = ""
+		delete(params.proxyEnvs, "ISTIO_META_NETWORK")
+	}
 
 	// use network in values for template, and proxy env variables
 	if cluster != "" {
@@ -396,17 +421,31 @@ func RunTemplate(params InjectionParameters) (mergedPod *corev1.Pod, templatePod
 		return nil, nil, err
 	}
 
+	proxyImage := ProxyImage(params.valuesConfig.asStruct, params.proxyConfig.Image, strippedPod.Annotations)
+	if windows {
+		// The distroless and debug variants are only published for Linux, so the Windows variant is used unless the
+		// pod sets its own image type.
+		proxyImage = ProxyImage(params.valuesConfig.asStruct, &proxyConfig.ProxyImage{ImageType: ImageTypeWindows}, strippedPod.Annotations)
+	} else if arch := podArchitecture(params.pod); arch != "" && !podSetsImageType(strippedPod.Annotations) {
+		// Per-architecture images are full references, typically pinned by digest, so they are used as is. They do
+		// not override the image type the pod sets.
+		if image := params.valuesConfig.asStruct.GetGlobal().GetProxy().GetArchImages()[arch]; image != "" {
+			proxyImage = image
+		}
+	}
+
 	data := SidecarTemplateData{
 		TypeMeta:             params.typeMeta,
 		DeploymentMeta:       params.deployMeta,
 		ObjectMeta:           strippedPod.ObjectMeta,
 		Spec:                 strippedPod.Spec,
-		ProxyConfig:          params.proxyConfig,
+		ProxyConfig:          proxyCfg,
 		MeshConfig:           meshConfig,
 		Values:               params.valuesConfig.asMap,
 		Revision:             params.revision,
 		EstimatedConcurrency: estimateConcurrency(params.proxyConfig, metadata.Annotations, params.valuesConfig.asStruct),
-		ProxyImage:           ProxyImage(params.valuesConfig.asStruct, params.proxyConfig.Image, strippedPod.Annotations),
+		ProxyImage:           proxyImage,
+		IsWindows:            windows,
 	}
 
 	mergedPod = params.pod
@@ -435,6 +474,61 @@ func RunTemplate(params InjectionParameters) (mergedPod *corev1.Pod, templatePod
 	return mergedPod, templatePod, nil
 }
 
+// isWindowsPod returns true if the pod can only be scheduled on Windows nodes.
+func isWindowsPod(pod *corev1.Pod) bool {
+	if pod.Spec.OS != nil {
+		return pod.Spec.OS.Name == corev1.Windows
+	}
+	return pod.Spec.NodeSelector[corev1.LabelOSStable] == string(corev1.Windows)
+}
+
+// podSetsImageType returns true if the pod sets its own proxy image type, either with the proxyImageType annotation
+// or with the image of its proxy config annotation.
+func podSetsImageType(annotations map[string]string) bool {
+	if _, ok := annotations[annotation.SidecarProxyImageType.Name]; ok {
+		return true
+	}
+	pca, ok := annotations[annotation.ProxyConfig.Name]
+	if !ok {
+		return false
+	}
+	pc := &meshconfig.ProxyConfig{}
+	if err := protomarshal.ApplyYAML(pca, pc); err != nil {
+		return false
+	}
+	return pc.GetImage().GetImageType() != ""
+}
+
+// podArchitecture returns the node architecture the pod can only be scheduled on, from either its node selector
+// or its required node affinity. It returns an empty string if the pod may run on more than one architecture.
+func podArchitecture(pod *corev1.Pod) string {
+	if arch := pod.Spec.NodeSelector[corev1.LabelArchStable]; arch != "" {
+		return arch
+	}
+	if pod.Spec.Affinity == nil || pod.Spec.Affinity.NodeAffinity == nil {
+		return ""
+	}
+	required := pod.Spec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		return ""
+	}
+	arch := ""
+	// Terms are ORed, so each of them has to restrict the pod to the same architecture.
+	for _, term := range required.NodeSelectorTerms {
+		termArch := ""
+		for _, expr := range term.MatchExpressions {
+			if expr.Key == corev1.LabelArchStable && expr.Operator == corev1.NodeSelectorOpIn && len(expr.Values) == 1 {
+				termArch = expr.Values[0]
+			}
+		}
+		if termArch == "" || (arch != "" && termArch != arch) {
+			return ""
+		}
+		arch = termArch
+	}
+	return arch
+}
+
 func knownTemplates(t Templates) []string {
 	keys := make([]string, 0, len(t))
 	for k := range t {
@@ -773,6 +867,10 @@ func IntoObject(injector Injector, sidecarTemplate Templates, valuesConfig Value
 		return nil, err
 	}
 	patchedPod := patchedObject.(*corev1.Pod)
+	if FindSidecar(patchedPod.Spec.InitContainers) != nil {
+		return nil, fmt.Errorf("%q is injected with a native sidecar, which is not supported by kube-inject: "+
+			"set the %s annotation to false", fullName, NativeSidecarAnnotation)
+	}
 	*metadata = patchedPod.ObjectMeta
 	*podSpec = patchedPod.Spec
 	return out, nil
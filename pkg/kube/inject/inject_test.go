@@ -29,6 +29,7 @@ import (
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"istio.io/api/annotation"
@@ -1089,3 +1090,211 @@ func TestProxyImage(t *testing.T) {
 		})
 	}
 }
+
+func TestWindowsInjection(t *testing.T) {
+	templates, err := ParseTemplates(map[string]string{
+		"sidecar": `
+spec:
+  containers:
+  - name: istio-proxy
+    image: {{ .ProxyImage }}
+    env:
+    - name: INTERCEPTION_MODE
+      value: {{ .ProxyConfig.InterceptionMode.String }}
+    {{- if not .IsWindows }}
+    securityContext:
+      runAsUser: 1337
+    {{- end }}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := NewValuesConfig(`global: {hub: docker.io/istio, tag: "1.17-distroless"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyCfg := mesh.DefaultProxyConfig()
+
+	for _, tt := range []struct {
+		desc        string
+		annotations map[string]string
+		spec        corev1.PodSpec
+		image       string
+		mode        string
+		windows     bool
+		err         string
+	}{
+		{
+			desc:  "linux",
+			image: "docker.io/istio/proxyv2:1.17-distroless",
+			mode:  "REDIRECT",
+		},
+		{
+			desc:    "node selector",
+			spec:    corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelOSStable: "windows"}},
+			image:   "docker.io/istio/proxyv2:1.17-windows",
+			mode:    "NONE",
+			windows: true,
+		},
+		{
+			desc:    "pod os",
+			spec:    corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}},
+			image:   "docker.io/istio/proxyv2:1.17-windows",
+			mode:    "NONE",
+			windows: true,
+		},
+		{
+			desc:        "interception mode annotation",
+			annotations: map[string]string{annotation.SidecarInterceptionMode.Name: "NONE"},
+			spec:        corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}},
+			image:       "docker.io/istio/proxyv2:1.17-windows",
+			mode:        "NONE",
+			windows:     true,
+		},
+		{
+			desc:        "unsupported interception mode annotation",
+			annotations: map[string]string{annotation.SidecarInterceptionMode.Name: "REDIRECT"},
+			spec:        corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}},
+			err:         "not supported by Windows pods",
+		},
+		{
+			desc:        "image type annotation",
+			annotations: map[string]string{annotation.SidecarProxyImageType.Name: "windows-debug"},
+			spec:        corev1.PodSpec{OS: &corev1.PodOS{Name: corev1.Windows}},
+			image:       "docker.io/istio/proxyv2:1.17-windows-debug",
+			mode:        "NONE",
+			windows:     true,
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			tt.spec.Containers = []corev1.Container{{Name: "hello", Image: "fake.docker.io/google-samples/hello-go-gke:1.0"}}
+			_, injected, err := RunTemplate(InjectionParameters{
+				pod:             &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "hello", Annotations: tt.annotations}, Spec: tt.spec},
+				templates:       templates,
+				defaultTemplate: []string{"sidecar"},
+				meshConfig:      mesh.DefaultMeshConfig(),
+				proxyConfig:     proxyCfg,
+				valuesConfig:    values,
+				proxyEnvs:       map[string]string{},
+			})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			proxy := injected.Spec.Containers[0]
+			if proxy.Image != tt.image {
+				t.Errorf("got image %q, want %q", proxy.Image, tt.image)
+			}
+			if got := proxy.Env[0].Value; got != tt.mode {
+				t.Errorf("got interception mode %q, want %q", got, tt.mode)
+			}
+			if (proxy.SecurityContext == nil) != tt.windows {
+				t.Errorf("unexpected security context %v", proxy.SecurityContext)
+			}
+		})
+	}
+	if proxyCfg.InterceptionMode != meshapi.ProxyConfig_REDIRECT {
+		t.Fatalf("proxy config should not be modified, got %v", proxyCfg.InterceptionMode)
+	}
+}
+
+func TestArchImageInjection(t *testing.T) {
+	templates, err := ParseTemplates(map[string]string{
+		"sidecar": `
+spec:
+  containers:
+  - name: istio-proxy
+    image: {{ .ProxyImage }}
+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := NewValuesConfig(`
+global:
+  hub: docker.io/istio
+  tag: "1.17"
+  proxy:
+    archImages:
+      arm64: docker.io/istio/proxyv2@sha256:0123
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archAffinity := func(archs ...string) *corev1.Affinity {
+		terms := []corev1.NodeSelectorTerm{}
+		for _, arch := range archs {
+			terms = append(terms, corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{{
+				Key:      corev1.LabelArchStable,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{arch},
+			}}})
+		}
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+		}}
+	}
+
+	for _, tt := range []struct {
+		desc        string
+		spec        corev1.PodSpec
+		annotations map[string]string
+		image       string
+	}{
+		{
+			desc:  "no architecture",
+			image: "docker.io/istio/proxyv2:1.17",
+		},
+		{
+			desc:  "node selector",
+			spec:  corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelArchStable: "arm64"}},
+			image: "docker.io/istio/proxyv2@sha256:0123",
+		},
+		{
+			desc:  "node affinity",
+			spec:  corev1.PodSpec{Affinity: archAffinity("arm64", "arm64")},
+			image: "docker.io/istio/proxyv2@sha256:0123",
+		},
+		{
+			desc:  "multiple architectures",
+			spec:  corev1.PodSpec{Affinity: archAffinity("arm64", "amd64")},
+			image: "docker.io/istio/proxyv2:1.17",
+		},
+		{
+			desc:  "architecture without image",
+			spec:  corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelArchStable: "amd64"}},
+			image: "docker.io/istio/proxyv2:1.17",
+		},
+		{
+			desc:        "image type annotation",
+			spec:        corev1.PodSpec{NodeSelector: map[string]string{corev1.LabelArchStable: "arm64"}},
+			annotations: map[string]string{annotation.SidecarProxyImageType.Name: "debug"},
+			image:       "docker.io/istio/proxyv2:1.17-debug",
+		},
+	} {
+		t.Run(tt.desc, func(t *testing.T) {
+			tt.spec.Containers = []corev1.Container{{Name: "hello", Image: "fake.docker.io/google-samples/hello-go-gke:1.0"}}
+			_, injected, err := RunTemplate(InjectionParameters{
+				pod:             &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "hello", Annotations: tt.annotations}, Spec: tt.spec},
+				templates:       templates,
+				defaultTemplate: []string{"sidecar"},
+				meshConfig:      mesh.DefaultMeshConfig(),
+				proxyConfig:     mesh.DefaultProxyConfig(),
+				valuesConfig:    values,
+				proxyEnvs:       map[string]string{},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := injected.Spec.Containers[0].Image; got != tt.image {
+				t.Errorf("got image %q, want %q", got, tt.image)
+			}
+		})
+	}
+}
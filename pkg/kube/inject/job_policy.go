@@ -0,0 +1,115 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// JobPolicyAnnotation is the comma separated list of the behaviors of the sidecar of a batch workload, overriding
+// the PILOT_DEFAULT_JOB_POLICY applied to the pods of Jobs and CronJobs. The destinations the workload reaches
+// before its proxy is ready or after it exits, such as the Kubernetes API, are declared with the
+// traffic.sidecar.istio.io/excludeOutboundIPRanges and excludeOutboundPorts annotations, which bypass the
+// redirection. The init containers of the workload run before the redirection is set up.
+const JobPolicyAnnotation = "sidecar.istio.io/jobPolicy"
+
+// The behaviors of JobPolicyAnnotation.
+const (
+	// JobPolicyHoldApplication holds the start of the app containers until the proxy is ready, as with
+	// holdApplicationUntilProxyStarts.
+	JobPolicyHoldApplication = "holdApplication"
+	// JobPolicyExitOnCompletion terminates the proxy once the app containers exit, so that the pod completes.
+	JobPolicyExitOnCompletion = "exitOnCompletion"
+)
+
+// ExitOnApplicationExitEnv tells the agent to terminate once the processes of the app containers, visible in the
+// process namespace shared with the pod, exit.
+const ExitOnApplicationExitEnv = "EXIT_ON_APPLICATION_EXIT"
+
+// jobPolicy is the parsed JobPolicyAnnotation of a pod.
+type jobPolicy struct {
+	holdApplication  bool
+	exitOnCompletion bool
+}
+
+func parseJobPolicy(value string) (jobPolicy, error) {
+	var p jobPolicy
+	for _, b := range strings.Split(value, ",") {
+		switch strings.TrimSpace(b) {
+		case JobPolicyHoldApplication:
+			p.holdApplication = true
+		case JobPolicyExitOnCompletion:
+			p.exitOnCompletion = true
+		case "":
+		default:
+			return jobPolicy{}, fmt.Errorf("unknown job policy %q, expected %s or %s", b, JobPolicyHoldApplication, JobPolicyExitOnCompletion)
+		}
+	}
+	return p, nil
+}
+
+func validateJobPolicy(value string) error {
+	_, err := parseJobPolicy(value)
+	return err
+}
+
+// jobPolicyFor returns the job policy of a pod: its JobPolicyAnnotation or, for the pods of Jobs and CronJobs, the
+// PILOT_DEFAULT_JOB_POLICY.
+func jobPolicyFor(pod *corev1.Pod, kind string) (jobPolicy, error) {
+	if v, f := pod.Annotations[JobPolicyAnnotation]; f {
+		return parseJobPolicy(v)
+	}
+	if kind == "Job" || kind == "CronJob" {
+		return parseJobPolicy(features.DefaultJobPolicy)
+	}
+	return jobPolicy{}, nil
+}
+
+// applyJobPolicy applies the job policy of the pod to its proxy. Native sidecars are already terminated once the
+// app containers exit. Otherwise, the process namespace of the pod is shared, for the agent to watch the processes
+// of the app containers.
+func applyJobPolicy(pod *corev1.Pod, req InjectionParameters) error {
+	policy, err := jobPolicyFor(req.pod, req.typeMeta.Kind)
+	if err != nil {
+		return err
+	}
+	sidecar := FindSidecar(pod.Spec.Containers)
+	if sidecar == nil {
+		return nil
+	}
+	if policy.holdApplication {
+		if sidecar.Lifecycle == nil {
+			sidecar.Lifecycle = &corev1.Lifecycle{}
+		}
+		if sidecar.Lifecycle.PostStart == nil {
+			sidecar.Lifecycle.PostStart = &corev1.LifecycleHandler{
+				Exec: &corev1.ExecAction{Command: []string{"pilot-agent", "wait"}},
+			}
+		}
+		pod.Spec.Containers = modifyContainers(pod.Spec.Containers, ProxyContainerName, MoveFirst)
+		sidecar = FindSidecar(pod.Spec.Containers)
+	}
+	if policy.exitOnCompletion && !req.nativeSidecar {
+		share := true
+		pod.Spec.ShareProcessNamespace = &share
+		sidecar.Env = append(sidecar.Env, corev1.EnvVar{Name: ExitOnApplicationExitEnv, Value: "true"})
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/test"
+)
+
+func TestParseJobPolicy(t *testing.T) {
+	p, err := parseJobPolicy("holdApplication, exitOnCompletion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.holdApplication || !p.exitOnCompletion {
+		t.Fatalf("unexpected policy %+v", p)
+	}
+	if _, err := parseJobPolicy("holdApplication,neverExit"); err == nil {
+		t.Fatal("expected an unknown behavior to be rejected")
+	}
+}
+
+func TestApplyJobPolicy(t *testing.T) {
+	test.SetForTest(t, &features.DefaultJobPolicy, JobPolicyExitOnCompletion)
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Annotations: annotations},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}, {Name: ProxyContainerName}}},
+		}
+	}
+	hasExitEnv := func(c *corev1.Container) bool {
+		for _, e := range c.Env {
+			if e.Name == ExitOnApplicationExitEnv {
+				return true
+			}
+		}
+		return false
+	}
+
+	cases := []struct {
+		name          string
+		kind          string
+		annotations   map[string]string
+		nativeSidecar bool
+		hold          bool
+		exit          bool
+	}{
+		{name: "deployment", kind: "Deployment"},
+		{name: "job default", kind: "Job", exit: true},
+		{name: "cronjob default", kind: "CronJob", exit: true},
+		{name: "annotation", kind: "Pod", annotations: map[string]string{JobPolicyAnnotation: "holdApplication,exitOnCompletion"}, hold: true, exit: true},
+		{name: "annotation overrides default", kind: "Job", annotations: map[string]string{JobPolicyAnnotation: "holdApplication"}, hold: true},
+		{name: "native sidecar", kind: "Job", nativeSidecar: true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pod := newPod(c.annotations)
+			req := InjectionParameters{pod: pod, typeMeta: metav1.TypeMeta{Kind: c.kind}, nativeSidecar: c.nativeSidecar}
+			if err := applyJobPolicy(pod, req); err != nil {
+				t.Fatal(err)
+			}
+			sidecar := FindSidecar(pod.Spec.Containers)
+			hold := pod.Spec.Containers[0].Name == ProxyContainerName && sidecar.Lifecycle != nil && sidecar.Lifecycle.PostStart != nil
+			if hold != c.hold {
+				t.Fatalf("got hold %v, want %v", hold, c.hold)
+			}
+			exit := pod.Spec.ShareProcessNamespace != nil && *pod.Spec.ShareProcessNamespace && hasExitEnv(sidecar)
+			if exit != c.exit {
+				t.Fatalf("got exit %v, want %v", exit, c.exit)
+			}
+		})
+	}
+
+	pod := newPod(map[string]string{JobPolicyAnnotation: "never"})
+	if err := applyJobPolicy(pod, InjectionParameters{pod: pod}); err == nil {
+		t.Fatal("expected an invalid job policy to be rejected")
+	}
+}
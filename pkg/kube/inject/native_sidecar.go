@@ -0,0 +1,150 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/kube"
+	"istio.io/pkg/log"
+)
+
+const (
+	// NativeSidecarAnnotation overrides ENABLE_NATIVE_SIDECARS for a pod.
+	NativeSidecarAnnotation = "sidecar.istio.io/nativeSidecar"
+	// ProxyTerminationGracePeriodAnnotation is the part of the termination grace period of a pod with a native sidecar
+	// reserved to the proxy: the grace period of the pod is extended by it, and the proxy drains for it once the app
+	// containers exit. Without it, the proxy drains for the terminationDrainDuration, within what the app containers
+	// left of the grace period.
+	ProxyTerminationGracePeriodAnnotation = "sidecar.istio.io/proxyTerminationGracePeriod"
+
+	// NativeSidecarEnv and ProxyTerminationGracePeriodEnv tell the agent it runs as a native sidecar, and for how long
+	// it drains once the app containers exit.
+	NativeSidecarEnv               = "NATIVE_SIDECAR"
+	ProxyTerminationGracePeriodEnv = "PROXY_TERMINATION_GRACE_PERIOD"
+
+	// defaultTerminationGracePeriodSeconds is the Kubernetes default termination grace period of a pod.
+	defaultTerminationGracePeriodSeconds = 30
+
+	// nativeSidecarMinorVersion is the first Kubernetes minor version enabling the SidecarContainers feature by default.
+	nativeSidecarMinorVersion = 29
+)
+
+// nativeSidecarEnabled returns true if the proxy of the pod is injected as a native sidecar. Native sidecars are
+// only injected if the cluster supports them: an older API server drops the restart policy of the proxy, which
+// would then block the app containers as a regular init container. The proxy is injected as a regular sidecar
+// instead, whether native sidecars are enabled by ENABLE_NATIVE_SIDECARS or by the annotation of the pod.
+func nativeSidecarEnabled(annotations map[string]string, client kube.Client) bool {
+	enabled := features.EnableNativeSidecars
+	if v, f := annotations[NativeSidecarAnnotation]; f {
+		b, err := strconv.ParseBool(v)
+		enabled = err == nil && b
+	}
+	if !enabled {
+		return false
+	}
+	if !nativeSidecarsSupported(client) {
+		log.Debugf("native sidecars are not supported by the cluster, injecting a regular sidecar")
+		return false
+	}
+	return true
+}
+
+// nativeSidecarsSupported returns true if the API server of the client supports native sidecars. The version is
+// cached by the client, so it is only requested once.
+func nativeSidecarsSupported(client kube.Client) bool {
+	if client == nil {
+		return false
+	}
+	v, err := client.GetKubernetesVersion()
+	if err != nil {
+		return false
+	}
+	return kube.IsKubeAtLeastOrLessThanVersion(v, nativeSidecarMinorVersion, true)
+}
+
+// applyNativeSidecar moves the proxy container of the pod to its init containers, to be restarted Always as a
+// native sidecar. It is inserted right after the Istio init containers, which are moved first, so that the init
+// containers of the app run with the proxy and their traffic is redirected to it. Kubernetes only terminates native
+// sidecars once all the app containers exit, so that the proxy and its DNS proxy keep serving the preStop hooks of
+// the app.
+func applyNativeSidecar(pod *corev1.Pod) error {
+	sidecar := FindSidecar(pod.Spec.Containers)
+	if sidecar == nil {
+		return nil
+	}
+	proxy := *sidecar
+	proxy.Env = append(proxy.Env, corev1.EnvVar{Name: NativeSidecarEnv, Value: "true"})
+	if v, f := pod.Annotations[ProxyTerminationGracePeriodAnnotation]; f {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid %s annotation: %v", ProxyTerminationGracePeriodAnnotation, err)
+		}
+		grace := int64(defaultTerminationGracePeriodSeconds)
+		if pod.Spec.TerminationGracePeriodSeconds != nil {
+			grace = *pod.Spec.TerminationGracePeriodSeconds
+		}
+		grace += int64(math.Ceil(d.Seconds()))
+		pod.Spec.TerminationGracePeriodSeconds = &grace
+		proxy.Env = append(proxy.Env, corev1.EnvVar{Name: ProxyTerminationGracePeriodEnv, Value: d.String()})
+	}
+
+	containers := make([]corev1.Container, 0, len(pod.Spec.Containers)-1)
+	for _, c := range pod.Spec.Containers {
+		if c.Name != ProxyContainerName {
+			containers = append(containers, c)
+		}
+	}
+	pod.Spec.Containers = containers
+	// A pod injected again may already have the proxy in its init containers.
+	initContainers := make([]corev1.Container, 0, len(pod.Spec.InitContainers)+1)
+	for _, name := range []string{ValidationContainerName, InitContainerName} {
+		if c := FindContainer(name, pod.Spec.InitContainers); c != nil {
+			initContainers = append(initContainers, *c)
+		}
+	}
+	initContainers = append(initContainers, proxy)
+	for _, c := range pod.Spec.InitContainers {
+		if c.Name != ProxyContainerName && c.Name != ValidationContainerName && c.Name != InitContainerName {
+			initContainers = append(initContainers, c)
+		}
+	}
+	pod.Spec.InitContainers = initContainers
+	return nil
+}
+
+// setNativeSidecarRestartPolicy sets the Always restart policy of the proxy init container in the JSON of a pod.
+// The field is not part of the Kubernetes API version Istio is built with, so it cannot be set on the pod itself.
+func setNativeSidecarRestartPolicy(podJSON []byte) ([]byte, error) {
+	pod := map[string]any{}
+	if err := json.Unmarshal(podJSON, &pod); err != nil {
+		return nil, err
+	}
+	spec, _ := pod["spec"].(map[string]any)
+	initContainers, _ := spec["initContainers"].([]any)
+	for _, c := range initContainers {
+		if c, ok := c.(map[string]any); ok && c["name"] == ProxyContainerName {
+			c["restartPolicy"] = string(corev1.RestartPolicyAlways)
+		}
+	}
+	return json.Marshal(pod)
+}
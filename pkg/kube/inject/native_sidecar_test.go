@@ -0,0 +1,119 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/test"
+)
+
+func TestNativeSidecarEnabled(t *testing.T) {
+	client := kube.NewFakeClientWithVersion("29")
+	if nativeSidecarEnabled(nil, client) {
+		t.Fatal("expected native sidecars to be disabled by default")
+	}
+	if !nativeSidecarEnabled(map[string]string{NativeSidecarAnnotation: "true"}, client) {
+		t.Fatal("expected the annotation to enable native sidecars")
+	}
+	test.SetForTest(t, &features.EnableNativeSidecars, true)
+	if !nativeSidecarEnabled(nil, client) {
+		t.Fatal("expected ENABLE_NATIVE_SIDECARS to enable native sidecars")
+	}
+	if nativeSidecarEnabled(map[string]string{NativeSidecarAnnotation: "false"}, client) {
+		t.Fatal("expected the annotation to disable native sidecars")
+	}
+
+	// Clusters not supporting native sidecars get regular sidecars, even when requested by the pod.
+	for _, client := range []kube.Client{nil, kube.NewFakeClientWithVersion("28")} {
+		if nativeSidecarEnabled(nil, client) || nativeSidecarEnabled(map[string]string{NativeSidecarAnnotation: "true"}, client) {
+			t.Fatal("expected a regular sidecar on clusters not supporting native sidecars")
+		}
+	}
+}
+
+func TestApplyNativeSidecar(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{ProxyTerminationGracePeriodAnnotation: "10s"}},
+		Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: ValidationContainerName}, {Name: "app-init"}, {Name: InitContainerName}},
+			Containers:     []corev1.Container{{Name: "app"}, {Name: ProxyContainerName}},
+		},
+	}
+	if err := applyNativeSidecar(pod); err != nil {
+		t.Fatal(err)
+	}
+	var initContainers []string
+	for _, c := range pod.Spec.InitContainers {
+		initContainers = append(initContainers, c.Name)
+	}
+	if got := strings.Join(initContainers, ","); got != "istio-validation,istio-init,istio-proxy,app-init" {
+		t.Fatalf("unexpected init containers %v", got)
+	}
+	if len(pod.Spec.Containers) != 1 || pod.Spec.Containers[0].Name != "app" {
+		t.Fatalf("expected the proxy to be removed from the containers, got %v", pod.Spec.Containers)
+	}
+	if got := *pod.Spec.TerminationGracePeriodSeconds; got != 40 {
+		t.Fatalf("expected the grace period to be extended by the proxy part, got %d", got)
+	}
+	env := map[string]string{}
+	for _, e := range pod.Spec.InitContainers[2].Env {
+		env[e.Name] = e.Value
+	}
+	if env[NativeSidecarEnv] != "true" || env[ProxyTerminationGracePeriodEnv] != "10s" {
+		t.Fatalf("unexpected proxy env %v", env)
+	}
+
+	// Injecting the pod again keeps a single proxy.
+	pod.Spec.Containers = append(pod.Spec.Containers, corev1.Container{Name: ProxyContainerName})
+	delete(pod.Annotations, ProxyTerminationGracePeriodAnnotation)
+	if err := applyNativeSidecar(pod); err != nil {
+		t.Fatal(err)
+	}
+	if len(pod.Spec.InitContainers) != 4 || len(pod.Spec.Containers) != 1 {
+		t.Fatalf("expected a single proxy, got %v and %v", pod.Spec.InitContainers, pod.Spec.Containers)
+	}
+}
+
+func TestCreatePatchNativeSidecar(t *testing.T) {
+	original, err := json.Marshal(&corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{Spec: corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: ProxyContainerName}},
+		Containers:     []corev1.Container{{Name: "app"}},
+	}}
+	patch, err := createPatch(pod, original, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(patch), `"restartPolicy":"Always"`) {
+		t.Fatalf("expected the restart policy of the proxy to be set, got %s", patch)
+	}
+	if patch, err = createPatch(pod, original, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(patch), "restartPolicy") {
+		t.Fatalf("expected no restart policy without native sidecars, got %s", patch)
+	}
+}
@@ -0,0 +1,277 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+
+	"istio.io/pkg/log"
+)
+
+// maxTemplateArtifactSize limits the total uncompressed size of templates read from an OCI artifact.
+const maxTemplateArtifactSize = 16 * 1024 * 1024
+
+// templateFetchRetryInterval is the interval at which a failed fetch of an artifact that is not
+// polled is retried.
+const templateFetchRetryInterval = 30 * time.Second
+
+var _ Watcher = &ociTemplateWatcher{}
+
+// ociTemplateWatcher layers injection templates distributed as an OCI artifact on top of the
+// configuration provided by another Watcher.
+//
+// The artifact is an image whose layers contain template files; each file named <name>.yaml or
+// <name>.tmpl defines (or replaces) the template <name>. If the reference is pinned by digest, the
+// artifact is fetched once and its digest verified; otherwise it is polled every refresh interval and
+// the handler is invoked whenever the digest changes.
+//
+// The artifact is only fetched by Run, so that an unreachable registry never blocks the injector:
+// until the artifact is fetched, the local templates are used.
+type ociTemplateWatcher struct {
+	base    Watcher
+	ref     name.Reference
+	pinned  bool
+	refresh time.Duration
+	fetch   func(name.Reference) (v1.Image, error)
+
+	mu        sync.Mutex
+	digest    string
+	templates map[string]string
+	handler   func(*Config, string) error
+}
+
+// NewOCITemplateWatcher creates a Watcher which overlays the injection templates found in the OCI
+// artifact at reference on top of the configuration watched by base.
+func NewOCITemplateWatcher(base Watcher, reference string, refresh time.Duration) (Watcher, error) {
+	ref, err := name.ParseReference(reference)
+	if err != nil {
+		return nil, fmt.Errorf("invalid injection template reference %q: %v", reference, err)
+	}
+	_, pinned := ref.(name.Digest)
+	return &ociTemplateWatcher{
+		base:    base,
+		ref:     ref,
+		pinned:  pinned,
+		refresh: refresh,
+		fetch: func(ref name.Reference) (v1.Image, error) {
+			return remote.Image(ref, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+		},
+	}, nil
+}
+
+func (w *ociTemplateWatcher) SetHandler(handler func(*Config, string) error) {
+	w.mu.Lock()
+	w.handler = handler
+	w.mu.Unlock()
+	w.base.SetHandler(func(c *Config, values string) error {
+		merged, err := w.overlay(c)
+		if err != nil {
+			return err
+		}
+		return handler(merged, values)
+	})
+}
+
+func (w *ociTemplateWatcher) Run(stop <-chan struct{}) {
+	go w.base.Run(stop)
+	for {
+		interval := w.refresh
+		if err := w.refreshTemplates(); err != nil {
+			log.Warnf("failed to fetch injection templates from %s: %v", w.ref, err)
+			if w.pinned || w.refresh <= 0 {
+				interval = templateFetchRetryInterval
+			}
+		} else if w.pinned || w.refresh <= 0 {
+			return
+		}
+		select {
+		case <-time.After(interval):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// refreshTemplates fetches the artifact and, if its content changed, invokes the handler with the
+// new configuration.
+func (w *ociTemplateWatcher) refreshTemplates() error {
+	changed, err := w.update()
+	if err != nil || !changed {
+		return err
+	}
+	sidecarConfig, valuesConfig, err := w.Get()
+	if err != nil {
+		log.Errorf("update error: %v", err)
+		return nil
+	}
+	w.mu.Lock()
+	handler := w.handler
+	w.mu.Unlock()
+	if handler != nil {
+		if err := handler(sidecarConfig, valuesConfig); err != nil {
+			log.Errorf("update error: %v", err)
+		}
+	}
+	return nil
+}
+
+func (w *ociTemplateWatcher) Get() (*Config, string, error) {
+	c, values, err := w.base.Get()
+	if err != nil {
+		return nil, "", err
+	}
+	merged, err := w.overlay(c)
+	if err != nil {
+		return nil, "", err
+	}
+	return merged, values, nil
+}
+
+// overlay returns a copy of c with the artifact templates added. If the artifact has not been
+// fetched yet, c is returned as is.
+func (w *ociTemplateWatcher) overlay(c *Config) (*Config, error) {
+	w.mu.Lock()
+	templates := w.templates
+	w.mu.Unlock()
+	if templates == nil {
+		return c, nil
+	}
+
+	out := *c
+	out.RawTemplates = make(RawTemplates, len(c.RawTemplates)+len(templates))
+	for k, v := range c.RawTemplates {
+		out.RawTemplates[k] = v
+	}
+	for k, v := range templates {
+		if _, f := c.RawTemplates[k]; f {
+			log.Debugf("injection template %q from %s replaces the local template", k, w.ref)
+		}
+		out.RawTemplates[k] = v
+	}
+	parsed, err := ParseTemplates(out.RawTemplates)
+	if err != nil {
+		return nil, err
+	}
+	out.Templates = parsed
+	return &out, nil
+}
+
+// update fetches the artifact, returning true if its content changed since the last fetch.
+func (w *ociTemplateWatcher) update() (bool, error) {
+	img, err := w.fetch(w.ref)
+	if err != nil {
+		return false, fmt.Errorf("could not fetch image: %v", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		return false, fmt.Errorf("could not compute image digest: %v", err)
+	}
+	if d, ok := w.ref.(name.Digest); ok && d.DigestStr() != digest.String() {
+		return false, fmt.Errorf("digest mismatch: expected %s, got %s", d.DigestStr(), digest)
+	}
+
+	w.mu.Lock()
+	unchanged := w.digest == digest.String()
+	w.mu.Unlock()
+	if unchanged {
+		return false, nil
+	}
+
+	templates, err := extractTemplates(img)
+	if err != nil {
+		return false, err
+	}
+	// Invalid templates are rejected before they replace the current ones, keeping the current digest so that
+	// the artifact is fetched again.
+	if _, err := ParseTemplates(templates); err != nil {
+		return false, fmt.Errorf("invalid injection templates in %s@%s: %v", w.ref.Context(), digest, err)
+	}
+	log.Infof("loaded %d injection templates from %s@%s", len(templates), w.ref.Context(), digest)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.digest = digest.String()
+	w.templates = templates
+	return true, nil
+}
+
+// extractTemplates reads all template files from the layers of img. Later layers take precedence.
+func extractTemplates(img v1.Image) (map[string]string, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch layers: %v", err)
+	}
+	if len(layers) == 0 {
+		return nil, errors.New("number of layers must be greater than zero")
+	}
+	templates := map[string]string{}
+	remaining := int64(maxTemplateArtifactSize)
+	for _, layer := range layers {
+		r, err := layer.Uncompressed()
+		if err != nil {
+			return nil, fmt.Errorf("could not get layer content: %v", err)
+		}
+		err = readTemplates(tar.NewReader(r), templates, &remaining)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(templates) == 0 {
+		return nil, errors.New("no injection templates found in image")
+	}
+	return templates, nil
+}
+
+func readTemplates(tr *tar.Reader, templates map[string]string, remaining *int64) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read layer: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		base := path.Base(hdr.Name)
+		ext := path.Ext(base)
+		if ext != ".yaml" && ext != ".tmpl" {
+			continue
+		}
+		if hdr.Size > *remaining {
+			return fmt.Errorf("injection templates exceed the maximum size of %d bytes", maxTemplateArtifactSize)
+		}
+		*remaining -= hdr.Size
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", hdr.Name, err)
+		}
+		templates[strings.TrimSuffix(base, ext)] = string(b)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+type staticWatcher struct {
+	config *Config
+	values string
+}
+
+func (w *staticWatcher) SetHandler(func(*Config, string) error) {}
+
+func (w *staticWatcher) Run(<-chan struct{}) {}
+
+func (w *staticWatcher) Get() (*Config, string, error) {
+	return w.config, w.values, nil
+}
+
+func templateImage(t *testing.T, files map[string]string) v1.Image {
+	t.Helper()
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for n, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: n, Size: int64(len(content)), Typeflag: tar.TypeReg, Mode: 0o644}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	img, err := mutate.Append(empty.Image, mutate.Addendum{Layer: static.NewLayer(b.Bytes(), types.OCIUncompressedLayer)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func TestOCITemplateWatcher(t *testing.T) {
+	base := &staticWatcher{
+		config: &Config{
+			DefaultTemplates: []string{SidecarTemplateName},
+			RawTemplates: RawTemplates{
+				SidecarTemplateName: "spec: {}",
+				"gateway":           "spec: {}",
+			},
+		},
+		values: "{}",
+	}
+	img := templateImage(t, map[string]string{
+		"templates/sidecar.yaml": "metadata: {}",
+		"templates/custom.tmpl":  "spec: {}",
+		"templates/README.md":    "ignored",
+	})
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name    string
+		ref     string
+		wantErr bool
+	}{
+		{name: "tag", ref: "example.com/templates:latest"},
+		{name: "pinned", ref: "example.com/templates@" + digest.String()},
+		{name: "digest mismatch", ref: "example.com/templates@sha256:" + string(bytes.Repeat([]byte("0"), 64)), wantErr: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewOCITemplateWatcher(base, tt.ref, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fetches := 0
+			w.(*ociTemplateWatcher).fetch = func(name.Reference) (v1.Image, error) {
+				fetches++
+				return img, nil
+			}
+			var handled *Config
+			w.SetHandler(func(c *Config, _ string) error {
+				handled = c
+				return nil
+			})
+
+			// Until the artifact is fetched, the local templates are used.
+			c, values, err := w.Get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fetches != 0 {
+				t.Fatalf("expected Get to not fetch the artifact, got %d fetches", fetches)
+			}
+			if c.RawTemplates[SidecarTemplateName] != "spec: {}" {
+				t.Fatalf("expected local templates before the artifact is fetched, got %v", c.RawTemplates)
+			}
+
+			err = w.(*ociTemplateWatcher).refreshTemplates()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				if c, _, err := w.Get(); err != nil || c.RawTemplates[SidecarTemplateName] != "spec: {}" {
+					t.Fatalf("expected local templates after a failed fetch, got %v, %v", c, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if handled == nil {
+				t.Fatalf("expected the handler to be invoked with the artifact templates")
+			}
+			c, values, err = w.Get()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values != "{}" {
+				t.Fatalf("unexpected values %q", values)
+			}
+			want := RawTemplates{
+				SidecarTemplateName: "metadata: {}",
+				"gateway":           "spec: {}",
+				"custom":            "spec: {}",
+			}
+			if len(c.RawTemplates) != len(want) {
+				t.Fatalf("got templates %v, want %v", c.RawTemplates, want)
+			}
+			for k, v := range want {
+				if c.RawTemplates[k] != v {
+					t.Fatalf("template %q: got %q, want %q", k, c.RawTemplates[k], v)
+				}
+				if c.Templates[k] == nil {
+					t.Fatalf("template %q not parsed", k)
+				}
+				if handled.RawTemplates[k] != v {
+					t.Fatalf("handled template %q: got %q, want %q", k, handled.RawTemplates[k], v)
+				}
+			}
+			if base.config.RawTemplates[SidecarTemplateName] != "spec: {}" {
+				t.Fatalf("base config was modified")
+			}
+			if _, _, err := w.Get(); err != nil {
+				t.Fatal(err)
+			}
+			if fetches != 1 {
+				t.Fatalf("expected artifact to be fetched once, got %d", fetches)
+			}
+			changed, err := w.(*ociTemplateWatcher).update()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if changed {
+				t.Fatalf("expected unchanged digest to not trigger an update")
+			}
+		})
+	}
+}
+
+func TestOCITemplateWatcherInvalidTemplates(t *testing.T) {
+	base := &staticWatcher{
+		config: &Config{RawTemplates: RawTemplates{SidecarTemplateName: "spec: {}"}},
+		values: "{}",
+	}
+	valid := templateImage(t, map[string]string{"templates/custom.yaml": "spec: {}"})
+	invalid := templateImage(t, map[string]string{"templates/custom.yaml": "spec: {{ .Unclosed"})
+	w, err := NewOCITemplateWatcher(base, "example.com/templates:latest", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := valid
+	w.(*ociTemplateWatcher).fetch = func(name.Reference) (v1.Image, error) {
+		return img, nil
+	}
+	if err := w.(*ociTemplateWatcher).refreshTemplates(); err != nil {
+		t.Fatal(err)
+	}
+
+	// An artifact with invalid templates is rejected, the previous templates are kept and the artifact is fetched
+	// again.
+	img = invalid
+	for i := 0; i < 2; i++ {
+		if err := w.(*ociTemplateWatcher).refreshTemplates(); err == nil {
+			t.Fatalf("expected invalid templates to be rejected")
+		}
+	}
+	c, _, err := w.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RawTemplates["custom"] != "spec: {}" || c.Templates["custom"] == nil {
+		t.Fatalf("expected the previous templates to be kept, got %v", c.RawTemplates)
+	}
+	digest, _ := valid.Digest()
+	if w.(*ociTemplateWatcher).digest != digest.String() {
+		t.Fatalf("expected the digest of the previous artifact to be kept")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+)
+
+// PreflightAnnotation records the mesh compatibility issues found on a pod at injection.
+const PreflightAnnotation = "sidecar.istio.io/preflightIssues"
+
+// Modes of the injection preflight check, set with INJECTION_PREFLIGHT.
+const (
+	// PreflightAnnotate records the issues in the PreflightAnnotation of the pod.
+	PreflightAnnotate = "annotate"
+	// PreflightReject fails the injection, and so the creation of the pod.
+	PreflightReject = "reject"
+	// PreflightOff disables the check. It is the default.
+	PreflightOff = "off"
+)
+
+// proxyUID is the UID and GID of the sidecar, whose traffic is not redirected.
+const proxyUID = 1337
+
+// reservedPorts are the ports used by the sidecar in the pod network namespace.
+var reservedPorts = map[int32]string{
+	15000: "Envoy admin",
+	15001: "outbound capture",
+	15004: "debug",
+	15006: "inbound capture",
+	15008: "HBONE tunnel",
+	15009: "HBONE",
+	15020: "merged Prometheus telemetry",
+	15021: "health check",
+	15053: "DNS proxy",
+	15090: "Envoy Prometheus telemetry",
+}
+
+// sidecarContainers are the containers added by injection, which are not checked.
+var sidecarContainers = map[string]struct{}{
+	ProxyContainerName:      {},
+	InitContainerName:       {},
+	ValidationContainerName: {},
+	EnableCoreDumpName:      {},
+}
+
+// preflightCheck validates the pod against the known incompatibilities with the sidecar, which would otherwise
+// break its traffic once it runs. It returns a description of each issue found.
+func preflightCheck(pod *corev1.Pod) []string {
+	var issues []string
+	containers := make([]corev1.Container, 0, len(pod.Spec.InitContainers)+len(pod.Spec.Containers))
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, c := range containers {
+		if _, f := sidecarContainers[c.Name]; f {
+			continue
+		}
+		if hasCapability(c, "NET_ADMIN") {
+			issues = append(issues, fmt.Sprintf("container %q adds the NET_ADMIN capability, "+
+				"its network changes may conflict with the sidecar traffic redirection", c.Name))
+		}
+		for _, p := range c.Ports {
+			if use, f := reservedPorts[p.ContainerPort]; f {
+				issues = append(issues, fmt.Sprintf("container %q port %d conflicts with the sidecar %s port", c.Name, p.ContainerPort, use))
+			}
+		}
+		if uid, gid := runAs(pod, c); uid == proxyUID || gid == proxyUID {
+			issues = append(issues, fmt.Sprintf("container %q runs as UID or GID %d, reserved for the sidecar: "+
+				"its traffic would bypass the sidecar", c.Name, proxyUID))
+		}
+	}
+	return issues
+}
+
+func hasCapability(c corev1.Container, capability corev1.Capability) bool {
+	if c.SecurityContext == nil || c.SecurityContext.Capabilities == nil {
+		return false
+	}
+	for _, added := range c.SecurityContext.Capabilities.Add {
+		if strings.TrimPrefix(string(added), "CAP_") == string(capability) {
+			return true
+		}
+	}
+	return false
+}
+
+// runAs returns the effective UID and GID of a container, or -1 if not set.
+func runAs(pod *corev1.Pod, c corev1.Container) (uid, gid int64) {
+	uid, gid = -1, -1
+	if psc := pod.Spec.SecurityContext; psc != nil {
+		if psc.RunAsUser != nil {
+			uid = *psc.RunAsUser
+		}
+		if psc.RunAsGroup != nil {
+			gid = *psc.RunAsGroup
+		}
+	}
+	if sc := c.SecurityContext; sc != nil {
+		if sc.RunAsUser != nil {
+			uid = *sc.RunAsUser
+		}
+		if sc.RunAsGroup != nil {
+			gid = *sc.RunAsGroup
+		}
+	}
+	return uid, gid
+}
+
+// applyPreflight runs the preflight check on the pod before injection and, depending on INJECTION_PREFLIGHT,
+// either returns the issues found as an error or records them in the PreflightAnnotation of the injected pod.
+func applyPreflight(original, injected *corev1.Pod) error {
+	mode := features.InjectionPreflight
+	switch mode {
+	case PreflightAnnotate, PreflightReject:
+	case PreflightOff:
+		return nil
+	default:
+		log.Warnf("unknown INJECTION_PREFLIGHT mode %q, falling back to %q", mode, PreflightOff)
+		return nil
+	}
+	issues := preflightCheck(original)
+	if len(issues) == 0 {
+		delete(injected.Annotations, PreflightAnnotation)
+		return nil
+	}
+	if mode == PreflightReject {
+		return fmt.Errorf("pod is not compatible with the sidecar: %s", strings.Join(issues, "; "))
+	}
+	log.Warnf("%s/%s has mesh compatibility issues: %s", original.Namespace, potentialPodName(original.ObjectMeta),
+		strings.Join(issues, "; "))
+	if injected.Annotations == nil {
+		injected.Annotations = map[string]string{}
+	}
+	injected.Annotations[PreflightAnnotation] = strings.Join(issues, "; ")
+	return nil
+}
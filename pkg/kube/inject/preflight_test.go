@@ -0,0 +1,143 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pkg/test"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestPreflightCheck(t *testing.T) {
+	cases := []struct {
+		name string
+		pod  corev1.Pod
+		want []string
+	}{
+		{
+			name: "compatible",
+			pod: corev1.Pod{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: 8080}}}},
+			}},
+		},
+		{
+			name: "sidecar containers are ignored",
+			pod: corev1.Pod{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: InitContainerName, SecurityContext: &corev1.SecurityContext{
+					Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"NET_ADMIN"}},
+				}}},
+				Containers: []corev1.Container{{
+					Name:            ProxyContainerName,
+					Ports:           []corev1.ContainerPort{{ContainerPort: 15090}},
+					SecurityContext: &corev1.SecurityContext{RunAsUser: int64Ptr(1337)},
+				}},
+			}},
+		},
+		{
+			name: "net admin",
+			pod: corev1.Pod{Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "other-mesh-init", SecurityContext: &corev1.SecurityContext{
+					Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"CAP_NET_ADMIN"}},
+				}}},
+			}},
+			want: []string{`container "other-mesh-init" adds the NET_ADMIN capability, ` +
+				"its network changes may conflict with the sidecar traffic redirection"},
+		},
+		{
+			name: "reserved port",
+			pod: corev1.Pod{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: 15001}}}},
+			}},
+			want: []string{`container "app" port 15001 conflicts with the sidecar outbound capture port`},
+		},
+		{
+			name: "proxy UID from pod",
+			pod: corev1.Pod{Spec: corev1.PodSpec{
+				SecurityContext: &corev1.PodSecurityContext{RunAsUser: int64Ptr(1337)},
+				Containers: []corev1.Container{
+					{Name: "app"},
+					{Name: "other", SecurityContext: &corev1.SecurityContext{RunAsUser: int64Ptr(1000)}},
+				},
+			}},
+			want: []string{`container "app" runs as UID or GID 1337, reserved for the sidecar: its traffic would bypass the sidecar`},
+		},
+		{
+			name: "proxy GID",
+			pod: corev1.Pod{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", SecurityContext: &corev1.SecurityContext{RunAsGroup: int64Ptr(1337)}}},
+			}},
+			want: []string{`container "app" runs as UID or GID 1337, reserved for the sidecar: its traffic would bypass the sidecar`},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := preflightCheck(&tt.pod); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPreflight(t *testing.T) {
+	incompatible := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: 15021}}}},
+		},
+	}
+	want := `container "app" port 15021 conflicts with the sidecar health check port`
+
+	// The check is disabled by default.
+	injected := &corev1.Pod{}
+	if err := applyPreflight(incompatible, injected); err != nil || len(injected.Annotations) != 0 {
+		t.Fatalf("expected check to be disabled, got %v %v", err, injected.Annotations)
+	}
+
+	test.SetForTest(t, &features.InjectionPreflight, PreflightAnnotate)
+	if err := applyPreflight(incompatible, injected); err != nil {
+		t.Fatal(err)
+	}
+	if got := injected.Annotations[PreflightAnnotation]; got != want {
+		t.Fatalf("got annotation %q, want %q", got, want)
+	}
+
+	// Issues fixed in a later injection clear the annotation.
+	if err := applyPreflight(&corev1.Pod{}, injected); err != nil {
+		t.Fatal(err)
+	}
+	if _, f := injected.Annotations[PreflightAnnotation]; f {
+		t.Fatalf("expected annotation to be removed: %v", injected.Annotations)
+	}
+
+	test.SetForTest(t, &features.InjectionPreflight, PreflightReject)
+	if err := applyPreflight(incompatible, &corev1.Pod{}); err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected rejection with %q, got %v", want, err)
+	}
+
+	// An unknown mode falls back to the default, which disables the check.
+	test.SetForTest(t, &features.InjectionPreflight, "Reject")
+	injected = &corev1.Pod{}
+	if err := applyPreflight(incompatible, injected); err != nil || len(injected.Annotations) != 0 {
+		t.Fatalf("expected check to be disabled, got %v %v", err, injected.Annotations)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"net"
+
+	"google.golang.org/protobuf/proto"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/pkg/log"
+)
+
+// shardDiscoveryAddress points the proxies of a namespace to the Service of their istiod shard, when proxies are
+// sharded between istiod replicas with PILOT_PROXY_SHARDS. Proxies with a custom discovery address are left
+// unchanged.
+func shardDiscoveryAddress(pc *meshconfig.ProxyConfig, defaultAddress, namespace string) *meshconfig.ProxyConfig {
+	if features.ProxyShards <= 1 || pc.GetDiscoveryAddress() != defaultAddress {
+		return pc
+	}
+	host, port, err := net.SplitHostPort(defaultAddress)
+	if err != nil {
+		log.Warnf("cannot shard the invalid discovery address %q: %v", defaultAddress, err)
+		return pc
+	}
+	pc = proto.Clone(pc).(*meshconfig.ProxyConfig)
+	pc.DiscoveryAddress = net.JoinHostPort(model.ShardHost(host, model.ShardForNamespace(namespace, features.ProxyShards)), port)
+	return pc
+}
@@ -0,0 +1,49 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package inject
+
+import (
+	"fmt"
+	"testing"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pilot/pkg/features"
+	"istio.io/istio/pilot/pkg/model"
+	"istio.io/istio/pkg/test"
+)
+
+func TestShardDiscoveryAddress(t *testing.T) {
+	const defaultAddress = "istiod.istio-system.svc:15012"
+	pc := &meshconfig.ProxyConfig{DiscoveryAddress: defaultAddress}
+
+	if got := shardDiscoveryAddress(pc, defaultAddress, "foo"); got.DiscoveryAddress != defaultAddress {
+		t.Fatalf("expected the discovery address to be unchanged without sharding, got %s", got.DiscoveryAddress)
+	}
+
+	test.SetForTest(t, &features.ProxyShards, 4)
+	got := shardDiscoveryAddress(pc, defaultAddress, "foo")
+	want := fmt.Sprintf("istiod-shard-%d.istio-system.svc:15012", model.ShardForNamespace("foo", 4))
+	if got.DiscoveryAddress != want {
+		t.Fatalf("got %s, want %s", got.DiscoveryAddress, want)
+	}
+	if pc.DiscoveryAddress != defaultAddress {
+		t.Fatalf("expected the shared proxy config not to be modified, got %s", pc.DiscoveryAddress)
+	}
+
+	custom := &meshconfig.ProxyConfig{DiscoveryAddress: "istiod-custom.istio-system.svc:15012"}
+	if got := shardDiscoveryAddress(custom, defaultAddress, "foo"); got.DiscoveryAddress != custom.DiscoveryAddress {
+		t.Fatalf("expected a custom discovery address to be unchanged, got %s", got.DiscoveryAddress)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"net/netip"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 
@@ -28,10 +29,18 @@ import (
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/config/validation"
 	"istio.io/istio/pkg/util/protomarshal"
+	iptablesconfig "istio.io/istio/tools/istio-iptables/pkg/config"
 )
 
 type annotationValidationFunc func(value string) error
 
+// ExcludeOutboundUIDsAnnotation is a comma separated list of the UIDs, or ranges of them such as 1000-1999, of the
+// processes of the pod whose outbound traffic bypasses the sidecar, so that agents running in the pod can reach their
+// backends without excluding the ports or ranges of their destinations. Groups are excluded with the
+// ISTIO_OUTBOUND_OWNER_GROUPS_EXCLUDE proxy environment variable.
+// TODO: move to API
+const ExcludeOutboundUIDsAnnotation = "traffic.sidecar.istio.io/excludeOutboundUIDs"
+
 // per-sidecar policy and status
 var (
 	AnnotationValidation = map[string]annotationValidationFunc{
@@ -48,6 +57,10 @@ var (
 		annotation.SidecarTrafficExcludeOutboundPorts.Name:        ValidateExcludeOutboundPorts,
 		annotation.PrometheusMergeMetrics.Name:                    validateBool,
 		annotation.ProxyConfig.Name:                               validateProxyConfig,
+		NativeSidecarAnnotation:                                   validateBool,
+		ProxyTerminationGracePeriodAnnotation:                     validateDuration,
+		JobPolicyAnnotation:                                       validateJobPolicy,
+		ExcludeOutboundUIDsAnnotation:                             ValidateExcludeOutboundOwnerIDs,
 	}
 )
 
@@ -125,6 +138,11 @@ func ValidateExcludeOutboundPorts(ports string) error {
 	return validatePortList("excludeOutboundPorts", ports)
 }
 
+// ValidateExcludeOutboundOwnerIDs validates the excludeOutboundUIDs parameter
+func ValidateExcludeOutboundOwnerIDs(ids string) error {
+	return iptablesconfig.ValidateOwnerIDs(ids)
+}
+
 // validateStatusPort validates the statusPort parameter
 func validateStatusPort(port string) error {
 	if _, e := parsePort(port); e != nil {
@@ -145,6 +163,18 @@ func validateBool(value string) error {
 	return err
 }
 
+// validateDuration validates that the given annotation value is a non-negative duration.
+func validateDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if d < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	return nil
+}
+
 func validateCIDRList(cidrs string) error {
 	if len(cidrs) > 0 {
 		for _, cidr := range strings.Split(cidrs, ",") {
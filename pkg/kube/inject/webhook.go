@@ -45,6 +45,7 @@ import (
 	meshconfig "istio.io/api/mesh/v1alpha1"
 	opconfig "istio.io/istio/operator/pkg/apis/istio/v1alpha1"
 	"istio.io/istio/pilot/cmd/pilot-agent/status"
+	"istio.io/istio/pilot/pkg/features"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pkg/config/mesh"
 	"istio.io/istio/pkg/kube"
@@ -100,6 +101,8 @@ type Webhook struct {
 
 	env      *model.Environment
 	revision string
+	// kubeClient detects whether the cluster supports native sidecars. Native sidecars are never injected without it.
+	kubeClient kube.Client
 }
 
 // ParsedContainers holds the unmarshalled containers and initContainers
@@ -158,6 +161,9 @@ type WebhookParameters struct {
 
 	// The istio.io/rev this injector is responsible for
 	Revision string
+
+	// KubeClient is the client of the cluster the pods are injected for, used to detect the features it supports.
+	KubeClient kube.Client
 }
 
 // NewWebhook creates a new instance of a mutating webhook for automatic sidecar injection.
@@ -171,6 +177,11 @@ func NewWebhook(p WebhookParameters) (*Webhook, error) {
 		meshConfig: p.Env.Mesh(),
 		env:        p.Env,
 		revision:   p.Revision,
+		kubeClient: p.KubeClient,
+	}
+	if features.EnableNativeSidecars && !nativeSidecarsSupported(p.KubeClient) {
+		log.Warnf("ENABLE_NATIVE_SIDECARS is set but the cluster does not support native sidecars: " +
+			"the sidecars are injected as regular containers")
 	}
 
 	p.Watcher.SetHandler(wh.updateConfig)
@@ -315,6 +326,9 @@ type InjectionParameters struct {
 	revision            string
 	proxyEnvs           map[string]string
 	injectedAnnotations map[string]string
+	// nativeSidecar injects the proxy as a native sidecar. It is only set by the webhook: the pods injected by
+	// kube-inject are decoded with the Kubernetes API Istio is built with, which drops the restart policy.
+	nativeSidecar bool
 }
 
 func checkPreconditions(params InjectionParameters) {
@@ -400,7 +414,17 @@ func injectPod(req InjectionParameters) ([]byte, error) {
 		return nil, fmt.Errorf("failed to process pod: %v", err)
 	}
 
-	patch, err := createPatch(mergedPod, originalPodSpec)
+	if req.nativeSidecar {
+		if err := applyNativeSidecar(mergedPod); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyPreflight(req.pod, mergedPod); err != nil {
+		return nil, err
+	}
+
+	patch, err := createPatch(mergedPod, originalPodSpec, req.nativeSidecar)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create patch: %v", err)
 	}
@@ -558,11 +582,16 @@ func reinsertOverrides(pod *corev1.Pod) (*corev1.Pod, error) {
 	return pod, nil
 }
 
-func createPatch(pod *corev1.Pod, original []byte) ([]byte, error) {
+func createPatch(pod *corev1.Pod, original []byte, nativeSidecar bool) ([]byte, error) {
 	reinjected, err := json.Marshal(pod)
 	if err != nil {
 		return nil, err
 	}
+	if nativeSidecar {
+		if reinjected, err = setNativeSidecarRestartPolicy(reinjected); err != nil {
+			return nil, err
+		}
+	}
 	p, err := jsonpatch.CreatePatch(original, reinjected)
 	if err != nil {
 		return nil, err
@@ -596,6 +625,10 @@ func postProcessPod(pod *corev1.Pod, injectedPod corev1.Pod, req InjectionParame
 		return err
 	}
 
+	if err := applyJobPolicy(pod, req); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -612,6 +645,10 @@ func applyMetadata(pod *corev1.Pod, injectedPodData corev1.Pod, req InjectionPar
 	}
 }
 
+// PrefetchWorkloadCertificatesMetadata is the proxy metadata making the agent prefetch the workload certificates.
+// The application of pods setting it is held until the proxy is ready, as with holdApplicationUntilProxyStarts.
+const PrefetchWorkloadCertificatesMetadata = "PREFETCH_WORKLOAD_CERTIFICATES"
+
 // reorderPod ensures containers are properly ordered after merging
 func reorderPod(pod *corev1.Pod, req InjectionParameters) error {
 	var merr error
@@ -626,7 +663,8 @@ func reorderPod(pod *corev1.Pod, req InjectionParameters) error {
 
 	// nolint: staticcheck
 	holdPod := mc.GetDefaultConfig().GetHoldApplicationUntilProxyStarts().GetValue() ||
-		req.valuesConfig.asStruct.GetGlobal().GetProxy().GetHoldApplicationUntilProxyStarts().GetValue()
+		req.valuesConfig.asStruct.GetGlobal().GetProxy().GetHoldApplicationUntilProxyStarts().GetValue() ||
+		mc.GetDefaultConfig().GetProxyMetadata()[PrefetchWorkloadCertificatesMetadata] == "true"
 
 	proxyLocation := MoveLast
 	// If HoldApplicationUntilProxyStarts is set, reorder the proxy location
@@ -655,7 +693,8 @@ func applyRewrite(pod *corev1.Pod, req InjectionParameters) error {
 	rewrite := ShouldRewriteAppHTTPProbers(pod.Annotations, req.valuesConfig.asStruct.GetSidecarInjectorWebhook().GetRewriteAppHTTPProbe().GetValue())
 	// We don't have to escape json encoding here when using golang libraries.
 	if rewrite {
-		if prober := DumpAppProbers(&pod.Spec, req.meshConfig.GetDefaultConfig().GetStatusPort()); prober != "" {
+		statusPort := rewriteStatusPort(pod.Annotations, req.meshConfig.GetDefaultConfig().GetStatusPort())
+		if prober := dumpAppProbers(&pod.Spec, newProbeRewrite(pod.Annotations, statusPort)); prober != "" {
 			// If sidecar.istio.io/status is not present then append instead of merge.
 			_, previouslyInjected := pod.Annotations[annotation.SidecarStatus.Name]
 			sidecar.Env = mergeOrAppendProbers(previouslyInjected, sidecar.Env, prober)
@@ -966,6 +1005,7 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 			proxyConfig = generatedProxyConfig
 		}
 	}
+	proxyConfig = shardDiscoveryAddress(proxyConfig, wh.meshConfig.GetDefaultConfig().GetDiscoveryAddress(), pod.Namespace)
 	deploy, typeMeta := kube.GetDeployMetaFromPod(&pod)
 	params := InjectionParameters{
 		pod:                 &pod,
@@ -980,6 +1020,7 @@ func (wh *Webhook) inject(ar *kube.AdmissionReview, path string) *kube.Admission
 		revision:            wh.revision,
 		injectedAnnotations: wh.Config.InjectedAnnotations,
 		proxyEnvs:           parseInjectEnvs(path),
+		nativeSidecar:       nativeSidecarEnabled(pod.Annotations, wh.kubeClient),
 	}
 	wh.mu.RUnlock()
 
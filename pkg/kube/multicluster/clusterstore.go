@@ -27,6 +27,8 @@ type ClusterStore struct {
 	// keyed by secret key(ns/name)->clusterID
 	remoteClusters map[string]map[cluster.ID]*Cluster
 	clusters       sets.String
+	// entries are the secret key/clusterID of the remote clusters, monitored by the gauge of the remote clusters.
+	entries *sets.Monitored[string]
 }
 
 // newClustersStore initializes data struct to store clusters information
@@ -34,6 +36,7 @@ func newClustersStore() *ClusterStore {
 	return &ClusterStore{
 		remoteClusters: make(map[string]map[cluster.ID]*Cluster),
 		clusters:       sets.New[string](),
+		entries:        sets.NewMonitored[string](clustersCount, clusterType.Value("remote")),
 	}
 }
 
@@ -45,6 +48,7 @@ func (c *ClusterStore) Store(secretKey string, clusterID cluster.ID, value *Clus
 	}
 	c.remoteClusters[secretKey][clusterID] = value
 	c.clusters.Insert(string(clusterID))
+	c.entries.Insert(secretKey + "/" + string(clusterID))
 }
 
 func (c *ClusterStore) Delete(secretKey string, clusterID cluster.ID) {
@@ -52,6 +56,7 @@ func (c *ClusterStore) Delete(secretKey string, clusterID cluster.ID) {
 	defer c.Unlock()
 	delete(c.remoteClusters[secretKey], clusterID)
 	c.clusters.Delete(string(clusterID))
+	c.entries.Delete(secretKey + "/" + string(clusterID))
 	if len(c.remoteClusters[secretKey]) == 0 {
 		delete(c.remoteClusters, secretKey)
 	}
@@ -0,0 +1,130 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"time"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/pkg/monitoring"
+)
+
+func init() {
+	monitoring.MustRegister(staleClusters)
+}
+
+var (
+	clusterLabel = monitoring.MustCreateLabel("cluster")
+
+	staleClusters = monitoring.NewGauge(
+		"remote_cluster_stale",
+		"Whether the API server of a remote cluster has been unreachable for longer than the stale threshold.",
+		monitoring.WithLabels(clusterLabel),
+	)
+)
+
+var _ ClusterHandler = &HealthMonitor{}
+
+// HealthMonitor is a ClusterHandler which periodically checks the API server of each remote cluster.
+// Clusters which have been unreachable for longer than the threshold are reported as stale, and reported
+// as fresh again once they are reachable.
+type HealthMonitor struct {
+	configClusterID cluster.ID
+	interval        time.Duration
+	threshold       time.Duration
+	onChange        func(clusterID cluster.ID, stale bool)
+
+	// check reports whether the API server of the cluster is reachable; overridden in tests.
+	check func(c *Cluster) error
+}
+
+// NewHealthMonitor creates a HealthMonitor. onChange is called whenever a remote cluster becomes stale or
+// recovers; it is not called for the config cluster, whose API server istiod cannot run without.
+func NewHealthMonitor(configClusterID cluster.ID, interval, threshold time.Duration,
+	onChange func(clusterID cluster.ID, stale bool),
+) *HealthMonitor {
+	return &HealthMonitor{
+		configClusterID: configClusterID,
+		interval:        interval,
+		threshold:       threshold,
+		onChange:        onChange,
+		check: func(c *Cluster) error {
+			_, err := c.Client.Kube().Discovery().ServerVersion()
+			return err
+		},
+	}
+}
+
+func (h *HealthMonitor) ClusterAdded(c *Cluster, stop <-chan struct{}) error {
+	if c.ID != h.configClusterID {
+		go h.monitor(c, stop)
+	}
+	return nil
+}
+
+func (h *HealthMonitor) ClusterUpdated(c *Cluster, stop <-chan struct{}) error {
+	// The previous monitor exits once the stop channel of the previous cluster is closed. The new monitor starts
+	// with a fresh cluster, so the stale state of the previous one is cleared, typically after the kubeconfig
+	// was rotated to fix the connectivity. The cluster is marked stale again if it stays unreachable.
+	if c.ID != h.configClusterID {
+		staleClusters.With(clusterLabel.Value(string(c.ID))).Record(0)
+		h.onChange(c.ID, false)
+	}
+	return h.ClusterAdded(c, stop)
+}
+
+func (h *HealthMonitor) ClusterDeleted(clusterID cluster.ID) error {
+	staleClusters.With(clusterLabel.Value(string(clusterID))).Record(0)
+	h.onChange(clusterID, false)
+	return nil
+}
+
+func (h *HealthMonitor) monitor(c *Cluster, stop <-chan struct{}) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	lastHealthy := time.Now()
+	stale := false
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			stale = h.probe(c, now, &lastHealthy, stale)
+		}
+	}
+}
+
+// probe checks the cluster once, returning the new stale state.
+func (h *HealthMonitor) probe(c *Cluster, now time.Time, lastHealthy *time.Time, stale bool) bool {
+	err := h.check(c)
+	if err == nil {
+		*lastHealthy = now
+		if stale {
+			log.Infof("remote cluster %s is reachable again", c.ID)
+			staleClusters.With(clusterLabel.Value(string(c.ID))).Record(0)
+			h.onChange(c.ID, false)
+		}
+		return false
+	}
+	log.Debugf("remote cluster %s health check failed: %v", c.ID, err)
+	if !stale && now.Sub(*lastHealthy) > h.threshold {
+		log.Warnf("remote cluster %s has been unreachable since %v, marking its endpoints stale: %v",
+			c.ID, lastHealthy.Format(time.RFC3339), err)
+		staleClusters.With(clusterLabel.Value(string(c.ID))).Record(1)
+		h.onChange(c.ID, true)
+		return true
+	}
+	return stale
+}
@@ -0,0 +1,75 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package multicluster
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestHealthMonitorProbe(t *testing.T) {
+	var changes []bool
+	h := NewHealthMonitor("config", time.Second, time.Minute, func(id cluster.ID, stale bool) {
+		assert.Equal(t, id, cluster.ID("remote"))
+		changes = append(changes, stale)
+	})
+	var checkErr error
+	h.check = func(*Cluster) error { return checkErr }
+
+	c := &Cluster{ID: "remote"}
+	start := time.Now()
+	lastHealthy := start
+	stale := false
+
+	// Unreachable, but not for long enough.
+	checkErr = errors.New("connection refused")
+	stale = h.probe(c, start.Add(30*time.Second), &lastHealthy, stale)
+	assert.Equal(t, stale, false)
+
+	// Unreachable beyond the threshold.
+	stale = h.probe(c, start.Add(2*time.Minute), &lastHealthy, stale)
+	assert.Equal(t, stale, true)
+	stale = h.probe(c, start.Add(3*time.Minute), &lastHealthy, stale)
+	assert.Equal(t, stale, true)
+
+	// Recovered.
+	checkErr = nil
+	stale = h.probe(c, start.Add(4*time.Minute), &lastHealthy, stale)
+	assert.Equal(t, stale, false)
+	assert.Equal(t, lastHealthy, start.Add(4*time.Minute))
+
+	// Each transition is reported exactly once.
+	assert.Equal(t, changes, []bool{true, false})
+}
+
+func TestHealthMonitorClusterUpdated(t *testing.T) {
+	var changes []bool
+	h := NewHealthMonitor("config", time.Hour, time.Minute, func(id cluster.ID, stale bool) {
+		assert.Equal(t, id, cluster.ID("remote"))
+		changes = append(changes, stale)
+	})
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// The stale state of a cluster is cleared when its kubeconfig is updated, but not when it is added.
+	assert.NoError(t, h.ClusterAdded(&Cluster{ID: "remote"}, stop))
+	assert.NoError(t, h.ClusterUpdated(&Cluster{ID: "remote"}, stop))
+	assert.NoError(t, h.ClusterUpdated(&Cluster{ID: "config"}, stop))
+	assert.Equal(t, changes, []bool{false})
+}
@@ -75,8 +75,7 @@ var (
 		monitoring.WithLabels(clusterType),
 	)
 
-	localClusters  = clustersCount.With(clusterType.Value("local"))
-	remoteClusters = clustersCount.With(clusterType.Value("remote"))
+	localClusters = clustersCount.With(clusterType.Value("local"))
 )
 
 type ClusterHandler interface {
@@ -138,9 +137,8 @@ func NewController(kubeclientset kube.Client, namespace string, clusterID cluste
 	)
 	_ = secretsInformer.SetTransform(kube.StripUnusedFields)
 
-	// init gauges
+	// init gauges, the gauge of the remote clusters is recorded by the cluster store.
 	localClusters.Record(1.0)
-	remoteClusters.Record(0.0)
 
 	controller := &Controller{
 		namespace:           namespace,
@@ -233,7 +231,6 @@ func (c *Controller) processItem(key types.NamespacedName) error {
 		log.Debugf("secret %s does not exist in informer cache, deleting it", key)
 		c.deleteSecret(key.String())
 	}
-	remoteClusters.Record(float64(c.cs.Len()))
 
 	return nil
 }
@@ -258,7 +255,12 @@ var BuildClientsFromConfig = func(kubeConfig []byte) (kube.Client, error) {
 
 	clientConfig := clientcmd.NewDefaultClientConfig(*rawConfig, &clientcmd.ConfigOverrides{})
 
-	clients, err := kube.NewClient(clientConfig)
+	informerOpts, err := kube.ParseInformerOptions(features.InformerResyncPeriods, features.InformerListPageSizes,
+		features.InformerWatchBookmarks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid informer options: %v", err)
+	}
+	clients, err := kube.NewClientWithInformerOptions(clientConfig, informerOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kube clients: %v", err)
 	}
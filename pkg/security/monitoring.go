@@ -0,0 +1,44 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package security
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	authenticatorTag = monitoring.MustCreateLabel("authenticator")
+	resultTag        = monitoring.MustCreateLabel("result")
+
+	authenticationAttempts = monitoring.NewSum(
+		"authenticator_attempts_total",
+		"Total number of requests authenticated by each authenticator of the XDS and CA servers, by result.",
+		monitoring.WithLabels(authenticatorTag, resultTag),
+	)
+
+	authenticationDuration = monitoring.NewDistribution(
+		"authenticator_duration_seconds",
+		"Time in seconds each authenticator of the XDS and CA servers takes to authenticate a request.",
+		[]float64{.001, .01, .1, .5, 1, 5},
+		monitoring.WithLabels(authenticatorTag),
+	)
+)
+
+func init() {
+	monitoring.MustRegister(
+		authenticationAttempts,
+		authenticationDuration,
+	)
+}
@@ -54,6 +54,10 @@ const (
 	// CredentialMetaDataName is the name in node meta data.
 	CredentialMetaDataName = "credential"
 
+	// DualWorkloadCertMetaDataName is the name in node meta data indicating that the agent serves an ECDSA
+	// workload certificate under WorkloadECDSAKeyCertResourceName, in addition to the RSA default certificate.
+	DualWorkloadCertMetaDataName = "dualWorkloadCert"
+
 	// SDSExternalClusterName is the name of the cluster for external SDS connections which is defined via CredentialNameSocketPath
 	SDSExternalClusterName = "sds-external"
 
@@ -94,6 +98,10 @@ const (
 	// TODO: change all the pilot one reference definition here instead.
 	WorkloadKeyCertResourceName = "default"
 
+	// WorkloadECDSAKeyCertResourceName is the resource name of the discovery request for an ECDSA workload
+	// identity certificate, served alongside the default certificate when it uses an RSA key.
+	WorkloadECDSAKeyCertResourceName = "default-ecdsa"
+
 	// GCE is Credential fetcher type of Google plugin
 	GCE = "GoogleComputeEngine"
 
@@ -140,6 +148,10 @@ const (
 
 	// CertSigner info
 	CertSigner = "CertSigner"
+
+	// ImpersonatedIdentity is the CSR metadata of the identity a node proxy requests a certificate for, on behalf
+	// of a host network pod of its node.
+	ImpersonatedIdentity = "ImpersonatedIdentity"
 )
 
 // Options provides all of the configuration parameters for secret discovery service
@@ -236,6 +248,10 @@ type Options struct {
 	// Cert signer info
 	CertSigner string
 
+	// NodeProxy has the SDS resources named after a SPIFFE identity be certificates requested on behalf of this
+	// identity, with the ImpersonatedIdentity metadata, for a proxy serving the host network pods of its node.
+	NodeProxy bool
+
 	// Delay in reading certificates from file after the change is detected. This is useful in cases
 	// where the write operation of key and cert take longer.
 	FileDebounceDuration time.Duration
@@ -304,6 +320,13 @@ type Client interface {
 	GetRootCertBundle() ([]string, error)
 }
 
+// ImpersonatingClient is a Client able to request certificates on behalf of other identities, which the CA only
+// allows for the node proxies.
+type ImpersonatingClient interface {
+	// CSRSignImpersonated signs a CSR for the identity, with the ImpersonatedIdentity metadata.
+	CSRSignImpersonated(csrPEM []byte, certValidTTLInSec int64, identity string) ([]string, error)
+}
+
 // SecretManager defines secrets management interface which is used by SDS.
 type SecretManager interface {
 	// GenerateSecret generates new secret for the given resource.
@@ -393,6 +416,23 @@ func (ac *AuthContext) Header(header string) []string {
 type Caller struct {
 	AuthSource AuthSource
 	Identities []string
+
+	// KubernetesInfo is the pod the Kubernetes token of the caller is bound to, if any.
+	KubernetesInfo KubernetesInfo
+
+	// Consume, if set, consumes the single-use credential of the caller. It is called once the request of the
+	// caller succeeded, and fails if the credential was already consumed, in which case the result of the
+	// request must not be returned.
+	Consume func() error
+}
+
+// KubernetesInfo is the pod a Kubernetes service account token is bound to. The pod name and UID are only set for
+// bound tokens.
+type KubernetesInfo struct {
+	PodName           string
+	PodNamespace      string
+	PodUID            string
+	PodServiceAccount string
 }
 
 // Authenticator determines the caller identity based on request context.
@@ -409,14 +449,20 @@ type AuthenticationManager struct {
 }
 
 // Authenticate loops through all the configured Authenticators and returns if one of the authenticator succeeds.
+// The result and duration of each attempt are recorded by authenticator.
 func (am *AuthenticationManager) Authenticate(ctx context.Context) *Caller {
 	req := AuthContext{GrpcContext: ctx}
 	for _, authn := range am.Authenticators {
+		start := time.Now()
 		u, err := authn.Authenticate(req)
+		authenticator := authenticatorTag.Value(authn.AuthenticatorType())
+		authenticationDuration.With(authenticator).Record(time.Since(start).Seconds())
 		if u != nil && len(u.Identities) > 0 && err == nil {
+			authenticationAttempts.With(authenticator, resultTag.Value("success")).Increment()
 			securityLog.Debugf("Authentication successful through auth source %v", u.AuthSource)
 			return u
 		}
+		authenticationAttempts.With(authenticator, resultTag.Value("failure")).Increment()
 		am.authFailMsgs = append(am.authFailMsgs, fmt.Sprintf("Authenticator %s: %v", authn.AuthenticatorType(), err))
 	}
 	return nil
@@ -0,0 +1,291 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gatewayconformance records the results of the Gateway API conformance suite, and reports them in the
+// ConformanceReport format of the Gateway API project, with the support level of each conformance profile.
+package gatewayconformance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/gateway-api/conformance/utils/suite"
+	"sigs.k8s.io/yaml"
+
+	"istio.io/istio/pkg/util/sets"
+)
+
+// ReportPathEnv is the environment variable holding the path the conformance report is written to.
+const ReportPathEnv = "GATEWAY_CONFORMANCE_REPORT"
+
+// Profile is a conformance profile, the set of conformance tests an implementation of a use case of the Gateway
+// API must pass.
+type Profile struct {
+	Name string
+	// Matches returns true if the test is part of the profile.
+	Matches func(test suite.ConformanceTest) bool
+}
+
+var (
+	// MeshProfile is the profile of the implementations routing the traffic of the mesh, with routes attached to
+	// services. Its tests are the Mesh tests of the suite; a suite without them reports the profile as skipped.
+	MeshProfile = Profile{Name: "mesh", Matches: isMeshTest}
+	// IngressProfile is the profile of the implementations routing the traffic entering the cluster, with routes
+	// attached to gateways.
+	IngressProfile = Profile{Name: "ingress", Matches: func(test suite.ConformanceTest) bool { return !isMeshTest(test) }}
+
+	// Profiles are the profiles Istio reports the conformance of.
+	Profiles = []Profile{MeshProfile, IngressProfile}
+)
+
+func isMeshTest(test suite.ConformanceTest) bool {
+	return strings.HasPrefix(test.ShortName, "Mesh")
+}
+
+// Result is the result of a conformance test.
+type Result string
+
+const (
+	Passed  Result = "Passed"
+	Failed  Result = "Failed"
+	Skipped Result = "Skipped"
+)
+
+// ResultOf returns the result of a completed test.
+func ResultOf(t testing.TB) Result {
+	switch {
+	case t.Failed():
+		return Failed
+	case t.Skipped():
+		return Skipped
+	default:
+		return Passed
+	}
+}
+
+// Recorder records the results of the conformance tests, per profile. It is safe for concurrent use, so that the
+// parallel tests of the suite can record their results.
+type Recorder struct {
+	mu        sync.Mutex
+	supported map[suite.SupportedFeature]bool
+	results   map[string]map[string]result
+}
+
+type result struct {
+	test   suite.ConformanceTest
+	result Result
+}
+
+// NewRecorder returns a recorder for a suite supporting the given features.
+func NewRecorder(supported map[suite.SupportedFeature]bool) *Recorder {
+	return &Recorder{supported: supported, results: map[string]map[string]result{}}
+}
+
+// Record records the result of a test of a profile.
+func (r *Recorder) Record(profile Profile, test suite.ConformanceTest, res Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.results[profile.Name] == nil {
+		r.results[profile.Name] = map[string]result{}
+	}
+	r.results[profile.Name][test.ShortName] = result{test: test, result: res}
+}
+
+// Report is a conformance report, in the ConformanceReport format of the Gateway API project.
+type Report struct {
+	APIVersion        string          `json:"apiVersion"`
+	Kind              string          `json:"kind"`
+	Date              string          `json:"date"`
+	Implementation    Implementation  `json:"implementation"`
+	GatewayAPIVersion string          `json:"gatewayAPIVersion"`
+	Profiles          []ProfileReport `json:"profiles"`
+}
+
+// Implementation describes the implementation the conformance is reported of.
+type Implementation struct {
+	Organization string   `json:"organization"`
+	Project      string   `json:"project"`
+	URL          string   `json:"url"`
+	Version      string   `json:"version"`
+	Contact      []string `json:"contact"`
+}
+
+// ProfileReport is the conformance of a profile. The core tests exercise the features every implementation must
+// support, and the extended tests the optional features.
+type ProfileReport struct {
+	Name     string          `json:"name"`
+	Core     Status          `json:"core"`
+	Extended *ExtendedStatus `json:"extended,omitempty"`
+}
+
+// Status is the results of the tests of a support level.
+type Status struct {
+	// Result is success if all the tests passed, partial if some were skipped, and failure if any failed.
+	Result       string     `json:"result"`
+	Summary      string     `json:"summary,omitempty"`
+	Statistics   Statistics `json:"statistics"`
+	SkippedTests []string   `json:"skippedTests,omitempty"`
+	FailedTests  []string   `json:"failedTests,omitempty"`
+}
+
+// ExtendedStatus is the results of the extended tests, along with the extended features.
+type ExtendedStatus struct {
+	Status
+	SupportedFeatures   []string `json:"supportedFeatures,omitempty"`
+	UnsupportedFeatures []string `json:"unsupportedFeatures,omitempty"`
+}
+
+// Statistics counts the results of the tests.
+type Statistics struct {
+	Passed  int `json:"Passed"`
+	Skipped int `json:"Skipped"`
+	Failed  int `json:"Failed"`
+}
+
+// Report returns the conformance report of the recorded results.
+func (r *Recorder) Report(impl Implementation, profiles []Profile, date time.Time) *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := &Report{
+		APIVersion:        "gateway.networking.k8s.io/v1alpha1",
+		Kind:              "ConformanceReport",
+		Date:              date.UTC().Format(time.RFC3339),
+		Implementation:    impl,
+		GatewayAPIVersion: GatewayAPIVersion(),
+	}
+	for _, profile := range profiles {
+		pr := ProfileReport{Name: profile.Name}
+		extended := &ExtendedStatus{}
+		supported, unsupported := sets.New[string](), sets.New[string]()
+		for _, name := range sortedTests(r.results[profile.Name]) {
+			res := r.results[profile.Name][name]
+			if !isExtended(res.test) {
+				pr.Core.add(name, res.result)
+				continue
+			}
+			extended.add(name, res.result)
+			for _, f := range res.test.Features {
+				if suite.StandardCoreFeatures[f] {
+					continue
+				}
+				if r.supported[f] {
+					supported.Insert(string(f))
+				} else {
+					unsupported.Insert(string(f))
+				}
+			}
+		}
+		pr.Core.complete()
+		if total := extended.Statistics; total.Passed+total.Skipped+total.Failed > 0 {
+			extended.complete()
+			extended.SupportedFeatures = sets.SortedList(supported)
+			extended.UnsupportedFeatures = sets.SortedList(unsupported)
+			pr.Extended = extended
+		}
+		report.Profiles = append(report.Profiles, pr)
+	}
+	return report
+}
+
+// isExtended returns true if the test exercises extended features, which are not required for conformance.
+func isExtended(test suite.ConformanceTest) bool {
+	for _, f := range test.Features {
+		if !suite.StandardCoreFeatures[f] {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Status) add(name string, res Result) {
+	switch res {
+	case Passed:
+		s.Statistics.Passed++
+	case Skipped:
+		s.Statistics.Skipped++
+		s.SkippedTests = append(s.SkippedTests, name)
+	case Failed:
+		s.Statistics.Failed++
+		s.FailedTests = append(s.FailedTests, name)
+	}
+}
+
+func (s *Status) complete() {
+	switch {
+	case s.Statistics.Failed > 0:
+		s.Result = "failure"
+	case s.Statistics.Passed == 0:
+		s.Result = "skipped"
+		s.Summary = "no test of this profile was run"
+	case s.Statistics.Skipped > 0:
+		s.Result = "partial"
+	default:
+		s.Result = "success"
+	}
+}
+
+// Write writes the report in YAML to the path.
+func (r *Report) Write(path string) error {
+	by, err := yaml.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, by, 0o644)
+}
+
+// GatewayAPIVersion returns the version of the Gateway API module the suite is built with.
+func GatewayAPIVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == "sigs.k8s.io/gateway-api" {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+func sortedTests(m map[string]result) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String returns a one line summary of the report, for the test logs.
+func (r *Report) String() string {
+	parts := make([]string, 0, len(r.Profiles))
+	for _, p := range r.Profiles {
+		s := fmt.Sprintf("%s: core %s", p.Name, p.Core.Result)
+		if p.Extended != nil {
+			s += fmt.Sprintf(", extended %s", p.Extended.Result)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gatewayconformance
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/gateway-api/conformance/utils/suite"
+
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+func TestReport(t *testing.T) {
+	r := NewRecorder(map[suite.SupportedFeature]bool{
+		suite.SupportReferenceGrant: true,
+		suite.SupportTLSRoute:       true,
+	})
+	r.Record(IngressProfile, suite.ConformanceTest{ShortName: "HTTPRouteSimpleSameNamespace"}, Passed)
+	r.Record(IngressProfile, suite.ConformanceTest{
+		ShortName: "HTTPRouteReferenceGrant",
+		Features:  []suite.SupportedFeature{suite.SupportReferenceGrant},
+	}, Failed)
+	r.Record(IngressProfile, suite.ConformanceTest{
+		ShortName: "TLSRouteSimpleSameNamespace",
+		Features:  []suite.SupportedFeature{suite.SupportTLSRoute},
+	}, Passed)
+	r.Record(IngressProfile, suite.ConformanceTest{
+		ShortName: "HTTPRouteDestinationPortMatching",
+		Features:  []suite.SupportedFeature{suite.SupportRouteDestinationPortMatching},
+	}, Skipped)
+
+	date := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	report := r.Report(Implementation{Organization: "istio", Project: "istio"}, Profiles, date)
+	assert.Equal(t, report.Date, "2023-01-02T03:04:05Z")
+	assert.Equal(t, report.Profiles, []ProfileReport{
+		{
+			Name: "mesh",
+			Core: Status{Result: "skipped", Summary: "no test of this profile was run"},
+		},
+		{
+			Name: "ingress",
+			Core: Status{
+				Result:      "failure",
+				Statistics:  Statistics{Passed: 1, Failed: 1},
+				FailedTests: []string{"HTTPRouteReferenceGrant"},
+			},
+			Extended: &ExtendedStatus{
+				Status: Status{
+					Result:       "partial",
+					Statistics:   Statistics{Passed: 1, Skipped: 1},
+					SkippedTests: []string{"HTTPRouteDestinationPortMatching"},
+				},
+				SupportedFeatures:   []string{"TLSRoute"},
+				UnsupportedFeatures: []string{"RouteDestinationPortMatching"},
+			},
+		},
+	})
+	assert.Equal(t, report.String(), "mesh: core skipped; ingress: core failure, extended partial")
+
+	path := filepath.Join(t.TempDir(), "report", "conformance.yaml")
+	if err := report.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	by, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"kind: ConformanceReport", "- TLSRoute", "Passed: 1"} {
+		if !strings.Contains(string(by), want) {
+			t.Errorf("report does not contain %q:\n%s", want, by)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sets
+
+import (
+	"istio.io/pkg/monitoring"
+)
+
+// Monitored is a Set whose size is recorded to a gauge on each mutation, so that the size does not need to be
+// recorded periodically. Like Set, it is not safe for concurrent use.
+type Monitored[T comparable] struct {
+	set   Set[T]
+	gauge monitoring.Metric
+}
+
+// NewMonitored returns a Monitored set with the given items, recording its size to the gauge with the labels.
+func NewMonitored[T comparable](gauge monitoring.Metric, labels ...monitoring.LabelValue) *Monitored[T] {
+	if len(labels) > 0 {
+		gauge = gauge.With(labels...)
+	}
+	m := &Monitored[T]{set: New[T](), gauge: gauge}
+	m.record()
+	return m
+}
+
+// Insert a single item to this set.
+func (m *Monitored[T]) Insert(item T) *Monitored[T] {
+	m.set.Insert(item)
+	m.record()
+	return m
+}
+
+// InsertAll adds the items to this set.
+func (m *Monitored[T]) InsertAll(items ...T) *Monitored[T] {
+	m.set.InsertAll(items...)
+	m.record()
+	return m
+}
+
+// Delete removes an item from the set.
+func (m *Monitored[T]) Delete(item T) *Monitored[T] {
+	m.set.Delete(item)
+	m.record()
+	return m
+}
+
+// DeleteAll removes items from the set.
+func (m *Monitored[T]) DeleteAll(items ...T) *Monitored[T] {
+	m.set.DeleteAll(items...)
+	m.record()
+	return m
+}
+
+// Clear removes all the items from the set.
+func (m *Monitored[T]) Clear() *Monitored[T] {
+	m.set = New[T]()
+	m.record()
+	return m
+}
+
+// Contains returns whether the given item is in the set.
+func (m *Monitored[T]) Contains(item T) bool {
+	return m.set.Contains(item)
+}
+
+// Len returns the number of elements.
+func (m *Monitored[T]) Len() int {
+	return m.set.Len()
+}
+
+// IsEmpty indicates whether the set is the empty set.
+func (m *Monitored[T]) IsEmpty() bool {
+	return m.set.IsEmpty()
+}
+
+// UnsortedList returns the slice with contents in random order.
+func (m *Monitored[T]) UnsortedList() []T {
+	return m.set.UnsortedList()
+}
+
+// Copy returns a copy of the set, which is not monitored.
+func (m *Monitored[T]) Copy() Set[T] {
+	return m.set.Copy()
+}
+
+func (m *Monitored[T]) record() {
+	m.gauge.Record(float64(m.set.Len()))
+}
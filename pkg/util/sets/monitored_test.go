@@ -0,0 +1,81 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sets
+
+import (
+	"testing"
+
+	"go.opencensus.io/stats/view"
+
+	"istio.io/pkg/monitoring"
+)
+
+var (
+	testLabel = monitoring.MustCreateLabel("set")
+	testGauge = monitoring.NewGauge("test_monitored_set_size", "Size of the test sets", monitoring.WithLabels(testLabel))
+)
+
+func init() {
+	monitoring.MustRegister(testGauge)
+}
+
+func gaugeValue(t *testing.T, label string) float64 {
+	t.Helper()
+	rows, err := view.RetrieveData(testGauge.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		for _, tag := range row.Tags {
+			if tag.Value == label {
+				return row.Data.(*view.LastValueData).Value
+			}
+		}
+	}
+	t.Fatalf("no value recorded for set %s", label)
+	return 0
+}
+
+func TestMonitored(t *testing.T) {
+	a := NewMonitored[string](testGauge, testLabel.Value("a"))
+	b := NewMonitored[string](testGauge, testLabel.Value("b"))
+	if got := gaugeValue(t, "a"); got != 0 {
+		t.Fatalf("expected the size of an empty set to be recorded, got %v", got)
+	}
+
+	a.InsertAll("x", "y", "z").Insert("x")
+	b.Insert("x")
+	if got := gaugeValue(t, "a"); got != 3 {
+		t.Fatalf("expected size 3, got %v", got)
+	}
+	if got := gaugeValue(t, "b"); got != 1 {
+		t.Fatalf("expected the sets to be recorded with their labels, got %v", got)
+	}
+
+	a.Delete("x").DeleteAll("y", "missing")
+	if got := gaugeValue(t, "a"); got != 1 || a.Len() != 1 || !a.Contains("z") {
+		t.Fatalf("expected size 1, got %v for %v", got, a.UnsortedList())
+	}
+
+	a.Copy().Insert("copy")
+	if a.Contains("copy") {
+		t.Fatalf("expected the copy not to share the set")
+	}
+
+	a.Clear()
+	if got := gaugeValue(t, "a"); got != 0 || !a.IsEmpty() {
+		t.Fatalf("expected the set to be cleared, got %v", got)
+	}
+}
@@ -80,6 +80,8 @@ type checksumEntry struct {
 	checksum string
 	// Keeps the resource version per each resource for dealing with multiple resources which pointing the same image.
 	resourceVersionByResource map[string]string
+	// Caching directives of the most recent response, for modules downloaded over HTTP/HTTPS.
+	httpCacheInfo *httpCacheInfo
 }
 
 type moduleKey struct {
@@ -98,6 +100,8 @@ type cacheKey struct {
 	// Resource version of WasmPlugin resource. Even though PullPolicy is Always,
 	// if there is no change of resource state, a cached entry is used instead of pulling newly.
 	resourceVersion string
+	// Caching directives of the HTTP/HTTPS response the module was downloaded or revalidated with.
+	httpCacheInfo *httpCacheInfo
 }
 
 // cacheEntry contains information about a Wasm module cache entry.
@@ -239,12 +243,34 @@ func (c *LocalFileCache) Get(
 	defer cancel()
 	switch u.Scheme {
 	case "http", "https":
+		// If a module which is not pinned by a checksum was previously downloaded from this URL, and its
+		// freshness expired, revalidate it instead of downloading it again.
+		var cached *httpCacheInfo
+		var cachedChecksum string
+		if key.checksum == "" {
+			cachedChecksum, cached = c.getHTTPCacheInfo(downloadURL)
+		}
 		// Download the Wasm module with http fetcher.
-		b, err = c.httpFetcher.Fetch(ctx, downloadURL, insecure)
+		b, key.httpCacheInfo, err = c.httpFetcher.fetch(ctx, downloadURL, insecure, cached)
 		if err != nil {
 			wasmRemoteFetchCount.With(resultTag.Value(downloadFailure)).Increment()
 			return "", err
 		}
+		if b == nil {
+			// Not modified, the cached module is still valid.
+			key.checksum = cachedChecksum
+			if modulePath, _ := c.getEntry(key, true); modulePath != "" {
+				c.touchEntry(key)
+				return modulePath, nil
+			}
+			// The module was purged in the meantime.
+			key.checksum = ""
+			b, key.httpCacheInfo, err = c.httpFetcher.fetch(ctx, downloadURL, insecure, nil)
+			if err != nil {
+				wasmRemoteFetchCount.With(resultTag.Value(downloadFailure)).Increment()
+				return "", err
+			}
+		}
 
 		// Get sha256 checksum and check if it is the same as provided one.
 		sha := sha256.Sum256(b)
@@ -324,10 +350,25 @@ func (c *LocalFileCache) updateChecksum(key cacheKey) bool {
 		}
 		ce.checksum = key.checksum
 		ce.resourceVersionByResource[key.resourceName] = key.resourceVersion
+		if key.httpCacheInfo != nil {
+			ce.httpCacheInfo = key.httpCacheInfo
+		}
 	}
 	return needChecksumUpdate
 }
 
+// getHTTPCacheInfo returns the checksum and the caching directives of the module most recently downloaded
+// from the given URL. The caching directives are nil if the module cannot be revalidated.
+func (c *LocalFileCache) getHTTPCacheInfo(downloadURL string) (string, *httpCacheInfo) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	ce, found := c.checksums[downloadURL]
+	if !found || !ce.httpCacheInfo.conditional() {
+		return "", nil
+	}
+	return ce.checksum, ce.httpCacheInfo
+}
+
 func (c *LocalFileCache) touchEntry(key cacheKey) {
 	c.mux.Lock()
 	defer c.mux.Unlock()
@@ -390,7 +431,9 @@ func (c *LocalFileCache) getEntry(key cacheKey, ignoreResourceVersion bool) (str
 		// If no checksum, try the checksum cache.
 		// If the image was pulled before, there should be a checksum of the most recently pulled image.
 		if ce, found := c.checksums[key.downloadURL]; found {
-			if ignoreResourceVersion || key.resourceVersion == ce.resourceVersionByResource[key.resourceName] {
+			// A module downloaded over HTTP/HTTPS is not used once stale, so that it is revalidated.
+			if (ignoreResourceVersion || key.resourceVersion == ce.resourceVersionByResource[key.resourceName]) &&
+				!ce.httpCacheInfo.stale(time.Now()) {
 				// update checksum
 				key.checksum = ce.checksum
 			}
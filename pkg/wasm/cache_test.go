@@ -854,6 +854,65 @@ func TestWasmCachePolicyChangesUsingHTTP(t *testing.T) {
 	testWasmGet(url2, extensions.PullPolicy_Always, "4", wantFilePath2, 3)
 }
 
+func TestWasmCacheControlUsingHTTP(t *testing.T) {
+	tmpDir := t.TempDir()
+	cache := NewLocalFileCache(tmpDir, defaultOptions())
+	defer close(cache.stopChan)
+
+	gotNumRequest, gotNumDownload := 0, 0
+	binary1 := append(wasmHeader, 1)
+	binary2 := append(wasmHeader, 2)
+	etag := `"1"`
+	body := binary1
+	maxAge := "max-age=0"
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotNumRequest++
+		w.Header().Set("Cache-Control", maxAge)
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		gotNumDownload++
+		w.Write(body)
+	}))
+	defer ts.Close()
+	wantFilePath1 := generateModulePath(t, tmpDir, ts.URL, fmt.Sprintf("%x.wasm", sha256.Sum256(binary1)))
+	wantFilePath2 := generateModulePath(t, tmpDir, ts.URL, fmt.Sprintf("%x.wasm", sha256.Sum256(binary2)))
+	var defaultPullPolicy extensions.PullPolicy
+
+	testWasmGet := func(checksum, wantFilePath string, wantNumRequest, wantNumDownload int) {
+		t.Helper()
+		gotFilePath, err := cache.Get(ts.URL, checksum, "namespace.resource", "1", time.Second*10, []byte{}, defaultPullPolicy)
+		if err != nil {
+			t.Fatalf("failed to download Wasm module: %v", err)
+		}
+		if gotFilePath != wantFilePath {
+			t.Fatalf("wasm download path got %v want %v", gotFilePath, wantFilePath)
+		}
+		if gotNumRequest != wantNumRequest || gotNumDownload != wantNumDownload {
+			t.Fatalf("wasm requests got %v (%v downloads) want %v (%v downloads)",
+				gotNumRequest, gotNumDownload, wantNumRequest, wantNumDownload)
+		}
+	}
+
+	// 1st time: Initially load the binary1.
+	testWasmGet("", wantFilePath1, 1, 1)
+	// 2nd time: The module is stale, it is revalidated but not downloaded again.
+	testWasmGet("", wantFilePath1, 2, 1)
+	// 3rd time: The module changed on the server, the new one is downloaded.
+	etag, body = `"2"`, binary2
+	testWasmGet("", wantFilePath2, 3, 2)
+	// 4th time: The module is fresh, the server is not contacted.
+	maxAge = "max-age=3600"
+	etag = `"3"`
+	testWasmGet("", wantFilePath2, 4, 3)
+	testWasmGet("", wantFilePath2, 4, 3)
+	// 5th time: A module pinned by its checksum is never refreshed.
+	testWasmGet(fmt.Sprintf("%x", sha256.Sum256(binary1)), wantFilePath1, 4, 3)
+}
+
 func TestAllInsecureServer(t *testing.T) {
 	tmpDir := t.TempDir()
 	options := defaultOptions()
@@ -20,6 +20,7 @@ import (
 
 	udpa "github.com/cncf/xds/go/udpa/type/v1"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
 	rbac "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	"github.com/envoyproxy/go-control-plane/pkg/conversion"
@@ -29,10 +30,15 @@ import (
 	extensions "istio.io/api/extensions/v1alpha1"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/config/wasmplugin"
 	"istio.io/istio/pkg/config/xds"
 )
 
-var allowTypedConfig = protoconv.MessageToAny(&rbac.RBAC{})
+var (
+	allowTypedConfig = protoconv.MessageToAny(&rbac.RBAC{})
+	// denyTypedConfig has no policy allowing requests, so it denies them all.
+	denyTypedConfig = protoconv.MessageToAny(&rbac.RBAC{Rules: &rbacv3.RBAC{Action: rbacv3.RBAC_ALLOW}})
+)
 
 func createAllowAllFilter(name string) (*anypb.Any, error) {
 	ec := &core.TypedExtensionConfig{
@@ -42,6 +48,14 @@ func createAllowAllFilter(name string) (*anypb.Any, error) {
 	return anypb.New(ec)
 }
 
+func createDenyAllFilter(name string) (*anypb.Any, error) {
+	ec := &core.TypedExtensionConfig{
+		Name:        name,
+		TypedConfig: denyTypedConfig,
+	}
+	return anypb.New(ec)
+}
+
 // MaybeConvertWasmExtensionConfig converts any presence of module remote download to local file.
 // It downloads the Wasm module and stores the module locally in the file system.
 func MaybeConvertWasmExtensionConfig(resources []*anypb.Any, cache Cache) bool {
@@ -76,6 +90,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	newExtensionConfig = resource
 	sendNack = false
 	status := noRemoteLoad
+	module := ModuleStatus{}
 	defer func() {
 		wasmConfigConversionCount.
 			With(resultTag.Value(status)).
@@ -83,13 +98,21 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 
 		if newExtensionConfig == resource && !sendNack && status != noRemoteLoad {
 			var err error
-			newExtensionConfig, err = createAllowAllFilter(ec.GetName())
+			if module.FailurePolicy == string(wasmplugin.FailClose) {
+				newExtensionConfig, err = createDenyAllFilter(ec.GetName())
+			} else {
+				newExtensionConfig, err = createAllowAllFilter(ec.GetName())
+			}
 			if err != nil {
-				// If the fallback is failing, send the Nack regardless of fail_open.
-				wasmLog.Infof("failed to create allow-all filter as a fallback of %s Wasm Module.", ec.GetName())
+				// If the fallback is failing, send the Nack regardless of the failure policy.
+				wasmLog.Infof("failed to create the fallback filter of %s Wasm Module.", ec.GetName())
 				sendNack = true
 			}
 		}
+		module.Name = ec.GetName()
+		module.Status = status
+		module.Rejected = sendNack
+		recordConversion(module)
 	}()
 	if err := resource.UnmarshalTo(ec); err != nil {
 		wasmLog.Debugf("failed to unmarshal extension config resource: %v", err)
@@ -131,14 +154,26 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 		return
 	}
 
+	vm := wasmHTTPFilterConfig.Config.GetVmConfig()
+	envs := vm.GetEnvironmentVariables()
+
 	// Wasm plugin configuration has remote load. From this point, any failure should result as a Nack,
-	// unless the plugin is marked as fail open.
-	failOpen := wasmHTTPFilterConfig.Config.GetFailOpen()
-	sendNack = !failOpen
+	// unless the failure policy of the plugin replaces it with an allow all or deny all filter. Without a
+	// failure policy, set by older versions of istiod, the plugin fails open if marked as fail open.
+	failurePolicy := wasmplugin.FailurePolicy(envs.GetKeyValues()[model.WasmFailurePolicyEnv])
+	var failOpen bool
+	switch failurePolicy {
+	case wasmplugin.FailOpen:
+		failOpen = true
+	case wasmplugin.FailClose, wasmplugin.FallbackToPrevious:
+	default:
+		failOpen = wasmHTTPFilterConfig.Config.GetFailOpen()
+	}
+	sendNack = !failOpen && failurePolicy != wasmplugin.FailClose
+	module.FailOpen = failOpen
+	module.FailurePolicy = string(failurePolicy)
 	status = conversionSuccess
 
-	vm := wasmHTTPFilterConfig.Config.GetVmConfig()
-	envs := vm.GetEnvironmentVariables()
 	var pullSecret []byte
 	pullPolicy := extensions.PullPolicy_UNSPECIFIED_POLICY
 	resourceVersion := ""
@@ -146,6 +181,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 		if sec, found := envs.KeyValues[model.WasmSecretEnv]; found {
 			if sec == "" {
 				status = fetchFailure
+				module.Error = "missing image pulling secret"
 				wasmLog.Errorf("cannot fetch Wasm module %v: missing image pulling secret", wasmHTTPFilterConfig.Config.Name)
 				return
 			}
@@ -155,6 +191,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 		// These env variables are added by Istio control plane and meant to be consumed by the agent for image pulling control,
 		// thus should not be leaked to Envoy or the Wasm extension runtime.
 		delete(envs.KeyValues, model.WasmSecretEnv)
+		delete(envs.KeyValues, model.WasmFailurePolicyEnv)
 		if len(envs.KeyValues) == 0 {
 			if len(envs.HostEnvKeys) == 0 {
 				vm.EnvironmentVariables = nil
@@ -175,6 +212,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	httpURI := remote.GetHttpUri()
 	if httpURI == nil {
 		status = missRemoteFetchHint
+		module.Error = "no httpUri specified"
 		wasmLog.Errorf("wasm remote fetch %+v does not have httpUri specified", remote)
 		return
 	}
@@ -182,6 +220,8 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	if remote.Sha256 == "nil" {
 		remote.Sha256 = ""
 	}
+	module.URL = httpURI.GetUri()
+	module.SHA256 = remote.Sha256
 	// Default timeout. Without this if user does not specify a timeout in the config, it fails with deadline exceeded
 	// while building transport in go container.
 	timeout := time.Second * 5
@@ -191,9 +231,11 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	f, err := cache.Get(httpURI.GetUri(), remote.Sha256, wasmHTTPFilterConfig.Config.Name, resourceVersion, timeout, pullSecret, pullPolicy)
 	if err != nil {
 		status = fetchFailure
+		module.Error = err.Error()
 		wasmLog.Errorf("cannot fetch Wasm module %v: %v", remote.GetHttpUri().GetUri(), err)
 		return
 	}
+	module.File = f
 
 	// Rewrite remote fetch to local file.
 	vm.Code = &core.AsyncDataSource{
@@ -209,6 +251,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	wasmTypedConfig, err := anypb.New(wasmHTTPFilterConfig)
 	if err != nil {
 		status = marshalFailure
+		module.Error = err.Error()
 		wasmLog.Errorf("failed to marshal new wasm HTTP filter %+v to protobuf Any: %v", wasmHTTPFilterConfig, err)
 		return
 	}
@@ -218,6 +261,7 @@ func convert(resource *anypb.Any, cache Cache) (newExtensionConfig *anypb.Any, s
 	nec, err := anypb.New(ec)
 	if err != nil {
 		status = marshalFailure
+		module.Error = err.Error()
 		wasmLog.Errorf("failed to marshal new extension config resource: %v", err)
 		return
 	}
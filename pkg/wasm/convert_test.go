@@ -24,6 +24,7 @@ import (
 
 	udpa "github.com/cncf/xds/go/udpa/type/v1"
 	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	rbacv3 "github.com/envoyproxy/go-control-plane/envoy/config/rbac/v3"
 	rbac "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/rbac/v3"
 	wasm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/http/wasm/v3"
 	v3 "github.com/envoyproxy/go-control-plane/envoy/extensions/wasm/v3"
@@ -36,6 +37,7 @@ import (
 	extensions "istio.io/api/extensions/v1alpha1"
 	"istio.io/istio/pilot/pkg/model"
 	"istio.io/istio/pilot/pkg/util/protoconv"
+	"istio.io/istio/pkg/config/wasmplugin"
 	"istio.io/istio/pkg/config/xds"
 )
 
@@ -117,6 +119,26 @@ func TestWasmConvert(t *testing.T) {
 			},
 			wantNack: false,
 		},
+		{
+			name: "remote load fail close",
+			input: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-fail-close"],
+			},
+			wantOutput: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-deny"],
+			},
+			wantNack: false,
+		},
+		{
+			name: "remote load fallback to previous",
+			input: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-fail-fallback"],
+			},
+			wantOutput: []*core.TypedExtensionConfig{
+				extensionConfigMap["remote-load-fail-fallback"],
+			},
+			wantNack: true,
+		},
 		{
 			name: "no typed struct",
 			input: []*core.TypedExtensionConfig{
@@ -217,6 +239,31 @@ func buildAnyExtensionConfig(name string, msg proto.Message) *core.TypedExtensio
 	}
 }
 
+// buildFailurePolicyExtensionConfig returns a fail open extension config, whose failure policy overrides fail open.
+func buildFailurePolicyExtensionConfig(policy wasmplugin.FailurePolicy) *core.TypedExtensionConfig {
+	return buildTypedStructExtensionConfig("remote-load-fail", &wasm.Wasm{
+		Config: &v3.PluginConfig{
+			Vm: &v3.PluginConfig_VmConfig{
+				VmConfig: &v3.VmConfig{
+					Code: &core.AsyncDataSource{Specifier: &core.AsyncDataSource_Remote{
+						Remote: &core.RemoteDataSource{
+							HttpUri: &core.HttpUri{
+								Uri: "http://test?module=test.wasm&error=download-error",
+							},
+						},
+					}},
+					EnvironmentVariables: &v3.EnvironmentVariables{
+						KeyValues: map[string]string{
+							model.WasmFailurePolicyEnv: string(policy),
+						},
+					},
+				},
+			},
+			FailOpen: true,
+		},
+	})
+}
+
 var extensionConfigMap = map[string]*core.TypedExtensionConfig{
 	"empty": {
 		Name: "empty",
@@ -318,7 +365,12 @@ var extensionConfigMap = map[string]*core.TypedExtensionConfig{
 			FailOpen: true,
 		},
 	}),
-	"remote-load-allow": buildAnyExtensionConfig("remote-load-fail", &rbac.RBAC{}),
+	"remote-load-allow":         buildAnyExtensionConfig("remote-load-fail", &rbac.RBAC{}),
+	"remote-load-fail-close":    buildFailurePolicyExtensionConfig(wasmplugin.FailClose),
+	"remote-load-fail-fallback": buildFailurePolicyExtensionConfig(wasmplugin.FallbackToPrevious),
+	"remote-load-deny": buildAnyExtensionConfig("remote-load-fail", &rbac.RBAC{
+		Rules: &rbacv3.RBAC{Action: rbacv3.RBAC_ALLOW},
+	}),
 	"remote-load-secret": buildTypedStructExtensionConfig("remote-load-success", &wasm.Wasm{
 		Config: &v3.PluginConfig{
 			Vm: &v3.PluginConfig_VmConfig{
@@ -0,0 +1,99 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// httpCacheInfo contains the caching directives of a Wasm module downloaded over HTTP/HTTPS.
+// It is only used for modules which are not pinned by a checksum, since pinned modules never change.
+type httpCacheInfo struct {
+	// etag and lastModified are the validators used to revalidate the module once it is stale.
+	etag         string
+	lastModified string
+	// expires is the time after which the module must be revalidated. If zero, the server did not
+	// send any freshness information and the module is used until the pull policy requires a new pull.
+	expires time.Time
+}
+
+// stale returns true if the cached module must be revalidated with the server.
+func (i *httpCacheInfo) stale(now time.Time) bool {
+	return i != nil && !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// conditional returns true if a conditional request can be made to revalidate the module.
+func (i *httpCacheInfo) conditional() bool {
+	return i != nil && (i.etag != "" || i.lastModified != "")
+}
+
+func (i *httpCacheInfo) setConditionalHeaders(h http.Header) {
+	if i.etag != "" {
+		h.Set("If-None-Match", i.etag)
+	}
+	if i.lastModified != "" {
+		h.Set("If-Modified-Since", i.lastModified)
+	}
+}
+
+// parseHTTPCacheInfo extracts the caching directives from the headers of a response. For a
+// "304 Not Modified" response, previous holds the validators of the cached module, which are kept
+// unless the server sent new ones.
+func parseHTTPCacheInfo(h http.Header, previous *httpCacheInfo, now time.Time) *httpCacheInfo {
+	info := &httpCacheInfo{
+		etag:         h.Get("ETag"),
+		lastModified: h.Get("Last-Modified"),
+	}
+	if previous != nil {
+		if info.etag == "" {
+			info.etag = previous.etag
+		}
+		if info.lastModified == "" {
+			info.lastModified = previous.lastModified
+		}
+	}
+
+	// max-age takes precedence over Expires, as per RFC 9111.
+	maxAge := -1
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache" || directive == "no-store":
+			// The module still needs to be stored for Envoy to load it, but it is revalidated on every use.
+			info.expires = now
+			return info
+		case strings.HasPrefix(directive, "max-age="):
+			if v, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(directive, "max-age="), `"`)); err == nil && v >= 0 {
+				maxAge = v
+			}
+		}
+	}
+	if maxAge >= 0 {
+		info.expires = now.Add(time.Duration(maxAge) * time.Second)
+		return info
+	}
+	if expires := h.Get("Expires"); expires != "" {
+		t, err := http.ParseTime(expires)
+		if err != nil || t.Before(now) {
+			// Invalid dates, such as "0", mean the response is already expired.
+			t = now
+		}
+		info.expires = t
+	}
+	return info
+}
@@ -0,0 +1,89 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseHTTPCacheInfo(t *testing.T) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name     string
+		header   map[string]string
+		previous *httpCacheInfo
+		want     httpCacheInfo
+	}{
+		{
+			name:   "no directives",
+			header: map[string]string{"ETag": `"abc"`},
+			want:   httpCacheInfo{etag: `"abc"`},
+		},
+		{
+			name:   "max-age",
+			header: map[string]string{"Cache-Control": "public, max-age=60", "Expires": "Mon, 02 Jan 2023 00:00:00 GMT"},
+			want:   httpCacheInfo{expires: now.Add(time.Minute)},
+		},
+		{
+			name:   "no-cache",
+			header: map[string]string{"Cache-Control": "no-cache, max-age=60", "Last-Modified": "Sat, 31 Dec 2022 00:00:00 GMT"},
+			want:   httpCacheInfo{lastModified: "Sat, 31 Dec 2022 00:00:00 GMT", expires: now},
+		},
+		{
+			name:   "expires",
+			header: map[string]string{"Expires": "Mon, 02 Jan 2023 00:00:00 GMT"},
+			want:   httpCacheInfo{expires: now.Add(24 * time.Hour)},
+		},
+		{
+			name:   "invalid expires",
+			header: map[string]string{"Expires": "0"},
+			want:   httpCacheInfo{expires: now},
+		},
+		{
+			name:     "not modified keeps validators",
+			header:   map[string]string{"Cache-Control": "max-age=10"},
+			previous: &httpCacheInfo{etag: `"abc"`, lastModified: "Sat, 31 Dec 2022 00:00:00 GMT"},
+			want:     httpCacheInfo{etag: `"abc"`, lastModified: "Sat, 31 Dec 2022 00:00:00 GMT", expires: now.Add(10 * time.Second)},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range c.header {
+				h.Set(k, v)
+			}
+			got := parseHTTPCacheInfo(h, c.previous, now)
+			if got.etag != c.want.etag || got.lastModified != c.want.lastModified || !got.expires.Equal(c.want.expires) {
+				t.Fatalf("got %+v, want %+v", *got, c.want)
+			}
+		})
+	}
+}
+
+func TestHTTPCacheInfoStale(t *testing.T) {
+	now := time.Now()
+	var nilInfo *httpCacheInfo
+	if nilInfo.stale(now) || (&httpCacheInfo{}).stale(now) {
+		t.Errorf("modules without freshness information should not be stale")
+	}
+	if !(&httpCacheInfo{expires: now}).stale(now) {
+		t.Errorf("expired module should be stale")
+	}
+	if (&httpCacheInfo{expires: now.Add(time.Second)}).stale(now) {
+		t.Errorf("fresh module should not be stale")
+	}
+}
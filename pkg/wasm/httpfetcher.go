@@ -69,6 +69,13 @@ func NewHTTPFetcher(requestTimeout time.Duration, requestMaxRetry int) *HTTPFetc
 
 // Fetch downloads a wasm module with HTTP get.
 func (f *HTTPFetcher) Fetch(ctx context.Context, url string, allowInsecure bool) ([]byte, error) {
+	b, _, err := f.fetch(ctx, url, allowInsecure, nil)
+	return b, err
+}
+
+// fetch downloads a wasm module with HTTP get, and returns it along with the caching directives of the response.
+// If cached has validators, the request is conditional and a nil module is returned if it was not modified.
+func (f *HTTPFetcher) fetch(ctx context.Context, url string, allowInsecure bool, cached *httpCacheInfo) ([]byte, *httpCacheInfo, error) {
 	c := f.client
 	if allowInsecure {
 		c = f.insecureClient
@@ -83,7 +90,10 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, url string, allowInsecure bool)
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if err != nil {
 			wasmLog.Debugf("wasm module download request failed: %v", err)
-			return nil, err
+			return nil, nil, err
+		}
+		if cached.conditional() {
+			cached.setConditionalHeaders(req.Header)
 		}
 		resp, err := c.Do(req)
 		if err != nil {
@@ -91,29 +101,36 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, url string, allowInsecure bool)
 			wasmLog.Debugf("wasm module download request failed: %v", err)
 			if ctx.Err() != nil {
 				// If there is context timeout, exit this loop.
-				return nil, fmt.Errorf("wasm module download failed after %v attempts, last error: %v", attempts, lastError)
+				return nil, nil, fmt.Errorf("wasm module download failed after %v attempts, last error: %v", attempts, lastError)
 			}
 			time.Sleep(b.NextBackOff())
 			continue
 		}
+		if resp.StatusCode == http.StatusNotModified && cached.conditional() {
+			err = resp.Body.Close()
+			if err != nil {
+				wasmLog.Infof("wasm server connection is not closed: %v", err)
+			}
+			return nil, parseHTTPCacheInfo(resp.Header, cached, time.Now()), nil
+		}
 		if resp.StatusCode == http.StatusOK {
 			// Limit wasm module to 256mb; in reality it must be much smaller
 			body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*256))
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			err = resp.Body.Close()
 			if err != nil {
 				wasmLog.Infof("wasm server connection is not closed: %v", err)
 			}
-			return unboxIfPossible(body), err
+			return unboxIfPossible(body), parseHTTPCacheInfo(resp.Header, nil, time.Now()), err
 		}
 		lastError = fmt.Errorf("wasm module download request failed: status code %v", resp.StatusCode)
 		if retryable(resp.StatusCode) {
 			// Limit wasm module to 256mb; in reality it must be much smaller
 			body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024*256))
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 			wasmLog.Debugf("wasm module download failed: status code %v, body %v", resp.StatusCode, string(body))
 			err = resp.Body.Close()
@@ -129,7 +146,7 @@ func (f *HTTPFetcher) Fetch(ctx context.Context, url string, allowInsecure bool)
 		}
 		break
 	}
-	return nil, fmt.Errorf("wasm module download failed after %v attempts, last error: %v", attempts, lastError)
+	return nil, nil, fmt.Errorf("wasm module download failed after %v attempts, last error: %v", attempts, lastError)
 }
 
 func retryable(code int) bool {
@@ -0,0 +1,100 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// modules holds the load state of the Wasm modules of the extension configs with a remote load, by extension
+// config name. Like the conversion metrics, it is shared by all the conversions of the process.
+var modules = struct {
+	sync.Mutex
+	statuses map[string]ModuleStatus
+}{statuses: map[string]ModuleStatus{}}
+
+// ModuleStatus is the load state of the Wasm module of an extension config, as of its last conversion.
+type ModuleStatus struct {
+	// Name is the name of the extension config.
+	Name string `json:"name"`
+	// URL is the remote source of the module.
+	URL string `json:"url,omitempty"`
+	// SHA256 is the checksum of the module requested by the extension config, if any.
+	SHA256 string `json:"sha256,omitempty"`
+	// File is the local file of the module in the cache, once it is loaded.
+	File string `json:"file,omitempty"`
+	// Status is the result of the conversion, as reported by the wasm_config_conversion_count metric.
+	Status string `json:"status"`
+	// Error is the error with which the module could not be loaded.
+	Error string `json:"error,omitempty"`
+	// FailOpen is set when the extension config is replaced by an allow all filter if its module cannot be loaded.
+	FailOpen bool `json:"failOpen,omitempty"`
+	// FailurePolicy is the failure policy of the WasmPlugin of the extension config, if set by istiod.
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+	// Rejected is set when the extension config was rejected, which blocks the listeners using it.
+	Rejected bool `json:"rejected,omitempty"`
+}
+
+// Loaded returns true if the module was loaded.
+func (m ModuleStatus) Loaded() bool {
+	return m.Status == conversionSuccess
+}
+
+// recordConversion records the result of the conversion of an extension config.
+func recordConversion(m ModuleStatus) {
+	if m.Name == "" {
+		return
+	}
+	modules.Lock()
+	defer modules.Unlock()
+	if m.Status == noRemoteLoad {
+		delete(modules.statuses, m.Name)
+		return
+	}
+	modules.statuses[m.Name] = m
+}
+
+// ModuleStatuses returns the load state of the Wasm modules of the extension configs with a remote load,
+// sorted by extension config name.
+func ModuleStatuses() []ModuleStatus {
+	modules.Lock()
+	defer modules.Unlock()
+	out := make([]ModuleStatus, 0, len(modules.statuses))
+	for _, m := range modules.statuses {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// CheckFetchStatus returns an error if the Wasm module of an extension config that is not fail open could not be
+// loaded by its last conversion.
+func CheckFetchStatus() error {
+	var failed []string
+	for _, m := range ModuleStatuses() {
+		if !m.Loaded() && m.Rejected {
+			failed = append(failed, m.Name+" ("+m.Status+")")
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("failed to load Wasm modules: %s", strings.Join(failed, ", "))
+}
@@ -0,0 +1,59 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wasm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func resetModuleStatuses(t *testing.T) {
+	t.Cleanup(func() {
+		modules.Lock()
+		modules.statuses = map[string]ModuleStatus{}
+		modules.Unlock()
+	})
+}
+
+func TestCheckFetchStatus(t *testing.T) {
+	resetModuleStatuses(t)
+	recordConversion(ModuleStatus{Name: "open", Status: fetchFailure, FailOpen: true})
+	if err := CheckFetchStatus(); err != nil {
+		t.Fatalf("fail open modules should not fail the check: %v", err)
+	}
+	recordConversion(ModuleStatus{Name: "closed", Status: fetchFailure, Rejected: true})
+	recordConversion(ModuleStatus{Name: "other", Status: missRemoteFetchHint, Rejected: true})
+	want := "failed to load Wasm modules: closed (fetch_failure), other (miss_remote_fetch_hint)"
+	if err := CheckFetchStatus(); err == nil || err.Error() != want {
+		t.Fatalf("got %v, want %s", err, want)
+	}
+	recordConversion(ModuleStatus{Name: "closed", Status: conversionSuccess})
+	recordConversion(ModuleStatus{Name: "other", Status: noRemoteLoad})
+	if err := CheckFetchStatus(); err != nil {
+		t.Fatalf("expected failures to be cleared: %v", err)
+	}
+}
+
+func TestModuleStatuses(t *testing.T) {
+	resetModuleStatuses(t)
+	loaded := ModuleStatus{Name: "b", URL: "https://example.com/b.wasm", File: "/cache/b.wasm", Status: conversionSuccess}
+	failed := ModuleStatus{Name: "a", URL: "oci://example.com/a", Status: fetchFailure, Error: "not found", FailOpen: true}
+	recordConversion(loaded)
+	recordConversion(failed)
+	recordConversion(ModuleStatus{Name: "c", Status: noRemoteLoad})
+	if got := ModuleStatuses(); !reflect.DeepEqual(got, []ModuleStatus{failed, loaded}) {
+		t.Fatalf("unexpected statuses %+v", got)
+	}
+}
@@ -27,6 +27,7 @@ const (
 	resourceTag = "resource"
 	reason      = "reason"
 	status      = "status"
+	deprecation = "deprecation"
 )
 
 var (
@@ -44,6 +45,9 @@ var (
 
 	// StatusTag holds the error code for the context.
 	StatusTag = monitoring.MustCreateLabel(status)
+
+	// DeprecationTag holds the ID of the deprecated field for the context.
+	DeprecationTag = monitoring.MustCreateLabel(deprecation)
 )
 
 var (
@@ -62,6 +66,11 @@ var (
 		"Resource validation http serve errors",
 		monitoring.WithLabels(StatusTag),
 	)
+	metricValidationDeprecated = monitoring.NewSum(
+		"galley/validation/deprecated",
+		"Valid resource using a deprecated field",
+		monitoring.WithLabels(GroupTag, VersionTag, ResourceTag, DeprecationTag),
+	)
 )
 
 func init() {
@@ -69,6 +78,7 @@ func init() {
 		metricValidationPassed,
 		metricValidationFailed,
 		metricValidationHTTPError,
+		metricValidationDeprecated,
 	)
 }
 
@@ -89,6 +99,15 @@ func reportValidationPass(request *kube.AdmissionRequest) {
 		Increment()
 }
 
+func reportValidationDeprecated(request *kube.AdmissionRequest, id string) {
+	metricValidationDeprecated.
+		With(GroupTag.Value(request.Resource.Group)).
+		With(VersionTag.Value(request.Resource.Version)).
+		With(ResourceTag.Value(request.Resource.Resource)).
+		With(DeprecationTag.Value(id)).
+		Increment()
+}
+
 func reportValidationHTTPError(status int) {
 	metricValidationHTTPError.
 		With(StatusTag.Value(strconv.Itoa(status))).
@@ -101,4 +120,5 @@ const (
 	reasonUnknownType          = "unknown_type"
 	reasonCRDConversionError   = "crd_conversion_error"
 	reasonInvalidConfig        = "invalid_resource"
+	reasonCanaryRejected       = "canary_rejected"
 )
@@ -20,20 +20,25 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	multierror "github.com/hashicorp/go-multierror"
 	admissionv1 "k8s.io/api/admission/v1"
 	kubeApiAdmissionv1beta1 "k8s.io/api/admission/v1beta1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 
 	"istio.io/istio/pilot/pkg/config/kube/crd"
+	"istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collection"
 	"istio.io/istio/pkg/config/schema/resource"
 	"istio.io/istio/pkg/config/validation"
 	"istio.io/istio/pkg/kube"
+	"istio.io/istio/pkg/util/sets"
 	"istio.io/pkg/log"
+	"istio.io/pkg/version"
 )
 
 var scope = log.RegisterScope("validationServer", "validation webhook server", 0)
@@ -44,13 +49,7 @@ var (
 	deserializer  = codecs.UniversalDeserializer()
 
 	// Expect AdmissionRequest to only include these top-level field names
-	validFields = map[string]bool{
-		"apiVersion": true,
-		"kind":       true,
-		"metadata":   true,
-		"spec":       true,
-		"status":     true,
-	}
+	validFields = sets.New("apiVersion", "kind", "metadata", "spec", "status")
 )
 
 func init() {
@@ -75,6 +74,10 @@ type Options struct {
 
 	// Use an existing mux instead of creating our own.
 	Mux *http.ServeMux
+
+	// Canary, if set, is called with the valid configurations before admitting them. It stages the high-risk
+	// configurations and returns an error if the configuration generated for the proxies would be rejected.
+	Canary func(config.Config) error
 }
 
 // String produces a stringified version of the arguments for debugging.
@@ -99,6 +102,9 @@ type Webhook struct {
 	// pilot
 	schemas      collection.Schemas
 	domainSuffix string
+	// version is the version of the control plane, configs pinned to other versions are not validated.
+	version string
+	canary  func(config.Config) error
 }
 
 // New creates a new instance of the admission webhook server.
@@ -110,6 +116,8 @@ func New(o Options) (*Webhook, error) {
 	wh := &Webhook{
 		schemas:      o.Schemas,
 		domainSuffix: o.DomainSuffix,
+		version:      version.Info.Version,
+		canary:       o.Canary,
 	}
 
 	o.Mux.HandleFunc("/validate", wh.serveValidate)
@@ -122,6 +130,41 @@ func toAdmissionResponse(err error) *kube.AdmissionResponse {
 	return &kube.AdmissionResponse{Result: &metav1.Status{Message: err.Error()}}
 }
 
+// toInvalidResponse rejects an invalid config. The message explains each error with its field, provided value,
+// allowed values and rule when they are known, and the causes hold the same explanations in a machine-readable form.
+func toInvalidResponse(gvk schema.GroupVersionKind, name string, prefix string, err error) *kube.AdmissionResponse {
+	fieldErrs := validation.FieldErrors(err)
+	causes := make([]metav1.StatusCause, 0, len(fieldErrs))
+	explanations := make([]string, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		causeType := metav1.CauseTypeFieldValueInvalid
+		if len(fe.Allowed) > 0 {
+			causeType = metav1.CauseTypeFieldValueNotSupported
+		}
+		explanation := fe.Explain()
+		causes = append(causes, metav1.StatusCause{Type: causeType, Message: explanation, Field: fe.Field})
+		explanations = append(explanations, explanation)
+	}
+	message := prefix
+	if len(explanations) == 1 {
+		message += explanations[0]
+	} else {
+		message += fmt.Sprintf("%d errors occurred:\n\t* %s\n\n", len(explanations), strings.Join(explanations, "\n\t* "))
+	}
+	return &kube.AdmissionResponse{Result: &metav1.Status{
+		Status:  metav1.StatusFailure,
+		Message: message,
+		Reason:  metav1.StatusReasonInvalid,
+		Code:    http.StatusUnprocessableEntity,
+		Details: &metav1.StatusDetails{
+			Name:   name,
+			Group:  gvk.Group,
+			Kind:   gvk.Kind,
+			Causes: causes,
+		},
+	}}
+}
+
 type admitFunc func(*kube.AdmissionRequest) *kube.AdmissionResponse
 
 func serve(w http.ResponseWriter, r *http.Request, admit admitFunc) {
@@ -208,6 +251,15 @@ func (wh *Webhook) validate(request *kube.AdmissionRequest) *kube.AdmissionRespo
 		return toAdmissionResponse(fmt.Errorf("cannot decode configuration: %v", err))
 	}
 
+	if err := config.ValidateVersionAnnotations(obj.Annotations); err != nil {
+		scope.Infof("configuration is invalid: %v", err)
+		reportValidationFailed(request, reasonInvalidConfig)
+		return toAdmissionResponse(fmt.Errorf("configuration is invalid: %v", err))
+	}
+	// A config pinned to other versions of the control plane may use fields this one does not know. It is validated
+	// as well as this version can, and only the errors caused by unknown fields are skipped.
+	pinned := !config.ObjectInVersion(obj.Annotations, wh.version)
+
 	gvk := obj.GroupVersionKind()
 
 	s, exists := wh.schemas.FindByGroupVersionAliasesKind(resource.FromKubernetesGVK(&gvk))
@@ -218,6 +270,13 @@ func (wh *Webhook) validate(request *kube.AdmissionRequest) *kube.AdmissionRespo
 	}
 
 	out, err := crd.ConvertObject(s, &obj, wh.domainSuffix)
+	if err != nil && pinned && isUnknownFieldError(err) {
+		reportValidationPass(request)
+		return &kube.AdmissionResponse{Allowed: true, Warnings: []string{
+			fmt.Sprintf("configuration is not fully validated: it does not apply to the Istio %s control plane, which does not support it: %v",
+				wh.version, err),
+		}}
+	}
 	if err != nil {
 		scope.Infof("error decoding configuration: %v", err)
 		reportValidationFailed(request, reasonCRDConversionError)
@@ -228,15 +287,33 @@ func (wh *Webhook) validate(request *kube.AdmissionRequest) *kube.AdmissionRespo
 	if err != nil {
 		scope.Infof("configuration is invalid: %v", err)
 		reportValidationFailed(request, reasonInvalidConfig)
-		return toAdmissionResponse(fmt.Errorf("configuration is invalid: %v", err))
+		return toInvalidResponse(gvk, obj.Name, "configuration is invalid: ", err)
 	}
 
 	if reason, err := checkFields(request.Object.Raw, request.Kind.Kind, request.Namespace, obj.Name); err != nil {
 		reportValidationFailed(request, reason)
+		if reason == reasonInvalidConfig {
+			return toInvalidResponse(gvk, obj.Name, "", err)
+		}
 		return toAdmissionResponse(err)
 	}
 
+	// The canary pushes to the proxies of this control plane, which ignores pinned configs.
+	if wh.canary != nil && !pinned {
+		if out.Namespace == "" {
+			out.Namespace = request.Namespace
+		}
+		if err := wh.canary(*out); err != nil {
+			scope.Infof("configuration is rejected by the canary: %v", err)
+			reportValidationFailed(request, reasonCanaryRejected)
+			return toAdmissionResponse(fmt.Errorf("configuration would be rejected by the proxies: %v", err))
+		}
+	}
+
 	reportValidationPass(request)
+	for _, d := range validation.UsedDeprecations(warnings) {
+		reportValidationDeprecated(request, d.ID)
+	}
 	return &kube.AdmissionResponse{Allowed: true, Warnings: toKubeWarnings(warnings)}
 }
 
@@ -255,6 +332,12 @@ func toKubeWarnings(warn validation.Warning) []string {
 	return []string{warn.Error()}
 }
 
+// isUnknownFieldError returns true if the error decoding a config is caused by fields or enum values unknown to
+// this control plane.
+func isUnknownFieldError(err error) bool {
+	return strings.Contains(err.Error(), "unknown field") || strings.Contains(err.Error(), "unknown value")
+}
+
 func checkFields(raw []byte, kind string, namespace string, name string) (string, error) {
 	trial := make(map[string]json.RawMessage)
 	if err := json.Unmarshal(raw, &trial); err != nil {
@@ -263,11 +346,14 @@ func checkFields(raw []byte, kind string, namespace string, name string) (string
 	}
 
 	for key := range trial {
-		if _, ok := validFields[key]; !ok {
+		if !validFields.Contains(key) {
 			scope.Infof("unknown field %q on %s resource %s/%s",
 				key, kind, namespace, name)
-			return reasonInvalidConfig, fmt.Errorf("unknown field %q on %s resource %s/%s",
-				key, kind, namespace, name)
+			return reasonInvalidConfig, &validation.FieldError{
+				Field:   key,
+				Allowed: sets.SortedList(validFields),
+				Err:     fmt.Errorf("unknown field %q on %s resource %s/%s", key, kind, namespace, name),
+			}
 		}
 	}
 
@@ -17,6 +17,7 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -33,6 +34,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
+	istioconfig "istio.io/istio/pkg/config"
 	"istio.io/istio/pkg/config/schema/collections"
 	"istio.io/istio/pkg/kube"
 	"istio.io/istio/pkg/test/config"
@@ -213,6 +215,170 @@ func TestAdmitPilot(t *testing.T) {
 	}
 }
 
+func TestAdmitPinnedVersion(t *testing.T) {
+	wh := createTestWebhook(t)
+	wh.version = "1.17.0"
+
+	pinned := func(valid bool, annotations map[string]string, newFields ...string) []byte {
+		var un unstructured.Unstructured
+		if err := json.Unmarshal(makePilotConfig(t, 0, valid, false), &un); err != nil {
+			t.Fatal(err)
+		}
+		un.SetAnnotations(annotations)
+		for _, f := range newFields {
+			if err := unstructured.SetNestedField(un.Object, "value", "spec", f); err != nil {
+				t.Fatal(err)
+			}
+		}
+		raw, err := json.Marshal(&un)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+
+	cases := []struct {
+		name     string
+		in       []byte
+		allowed  bool
+		warnings int
+	}{
+		{"invalid config for this version", pinned(false, map[string]string{"istio.io/min-version": "1.17"}), false, 0},
+		{"invalid config for a newer version", pinned(false, map[string]string{"istio.io/min-version": "1.18"}), false, 0},
+		{"invalid config for an older version", pinned(false, map[string]string{"istio.io/max-version": "1.16"}), false, 0},
+		{"valid config with new fields for a newer version", pinned(true, map[string]string{"istio.io/min-version": "1.18"}, "newField"), true, 0},
+		{"valid config for this version", pinned(true, map[string]string{"istio.io/max-version": "1.17"}), true, 0},
+		{"invalid annotation", pinned(true, map[string]string{"istio.io/min-version": "latest"}), false, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := wh.validate(&kube.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Kind: collections.Mock.Resource().Kind()},
+				Object:    runtime.RawExtension{Raw: c.in},
+				Operation: kube.Create,
+			})
+			if got.Allowed != c.allowed {
+				t.Fatalf("got %v want %v", got.Allowed, c.allowed)
+			}
+			if len(got.Warnings) != c.warnings {
+				t.Fatalf("got warnings %v want %d", got.Warnings, c.warnings)
+			}
+		})
+	}
+}
+
+func TestAdmitCanary(t *testing.T) {
+	wh := createTestWebhook(t)
+	var staged []istioconfig.Config
+	wh.canary = func(cfg istioconfig.Config) error {
+		staged = append(staged, cfg)
+		if cfg.Name == "mock-config1" {
+			return fmt.Errorf("duplicate domain")
+		}
+		return nil
+	}
+
+	for i, allowed := range []bool{true, false} {
+		got := wh.validate(&kube.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Kind: collections.Mock.Resource().Kind()},
+			Object:    runtime.RawExtension{Raw: makePilotConfig(t, i, true, false)},
+			Namespace: "istio-system",
+			Operation: kube.Create,
+		})
+		if got.Allowed != allowed {
+			t.Fatalf("config %d: got %v want %v", i, got.Allowed, allowed)
+		}
+	}
+	if len(staged) != 2 || staged[0].Namespace != "istio-system" {
+		t.Fatalf("unexpected staged configs %v", staged)
+	}
+
+	// Invalid configs are rejected before being staged.
+	got := wh.validate(&kube.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: collections.Mock.Resource().Kind()},
+		Object:    runtime.RawExtension{Raw: makePilotConfig(t, 2, false, false)},
+		Operation: kube.Create,
+	})
+	if got.Allowed || len(staged) != 2 {
+		t.Fatalf("expected the invalid config to be rejected before being staged")
+	}
+}
+
+func TestAdmitInvalidCauses(t *testing.T) {
+	wh, err := New(Options{
+		DomainSuffix: testDomainSuffix,
+		Schemas:      collections.Pilot,
+		Mux:          http.NewServeMux(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(map[string]any{
+		"apiVersion": "networking.istio.io/v1alpha3",
+		"kind":       "ServiceEntry",
+		"metadata":   map[string]any{"name": "egress", "namespace": "default"},
+		"spec": map[string]any{
+			"hosts":      []string{"*"},
+			"ports":      []map[string]any{{"number": 80, "name": "http", "protocol": "QUIC"}},
+			"resolution": "NONE",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := wh.validate(&kube.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Group: "networking.istio.io", Version: "v1alpha3", Kind: "ServiceEntry"},
+		Object:    runtime.RawExtension{Raw: raw},
+		Operation: kube.Create,
+	})
+	if got.Allowed {
+		t.Fatal("expected the config to be rejected")
+	}
+	if got.Result.Reason != metav1.StatusReasonInvalid || got.Result.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("unexpected status %+v", got.Result)
+	}
+	details := got.Result.Details
+	if details == nil || details.Kind != "ServiceEntry" || details.Name != "egress" || len(details.Causes) != 2 {
+		t.Fatalf("unexpected details %+v", details)
+	}
+	protocolCause := details.Causes[1]
+	if protocolCause.Field != "spec.ports[0].protocol" || protocolCause.Type != metav1.CauseTypeFieldValueNotSupported ||
+		!strings.Contains(protocolCause.Message, `value: "QUIC"`) || !strings.Contains(protocolCause.Message, "allowed: GRPC") ||
+		!strings.Contains(protocolCause.Message, "service-entry/#ServiceEntry") {
+		t.Fatalf("unexpected cause %+v", protocolCause)
+	}
+	if !strings.Contains(got.Result.Message, "2 errors occurred") || !strings.Contains(got.Result.Message, "field: spec.hosts[0]") {
+		t.Fatalf("unexpected message %q", got.Result.Message)
+	}
+
+	// The unknown top-level fields are reported as a cause too.
+	got = createTestWebhook(t).validate(&kube.AdmissionRequest{
+		Kind:      metav1.GroupVersionKind{Kind: collections.Mock.Resource().Kind()},
+		Object:    runtime.RawExtension{Raw: makePilotConfig(t, 0, true, true)},
+		Operation: kube.Create,
+	})
+	if got.Allowed || len(got.Result.Details.Causes) != 1 || got.Result.Details.Causes[0].Field != "unexpected_key" {
+		t.Fatalf("unexpected response %+v", got.Result)
+	}
+}
+
+func TestIsUnknownFieldError(t *testing.T) {
+	cases := []struct {
+		err     error
+		unknown bool
+	}{
+		{errors.New(`unknown field "newField" in istio.networking.v1alpha3.VirtualService`), true},
+		{errors.New(`unknown value "NEW" for enum istio.networking.v1alpha3.ClientTLSSettings.TLSmode`), true},
+		{errors.New(`invalid value for string type: 1`), false},
+	}
+	for _, c := range cases {
+		if got := isUnknownFieldError(c.err); got != c.unknown {
+			t.Errorf("isUnknownFieldError(%v): got %v want %v", c.err, got, c.unknown)
+		}
+	}
+}
+
 func makeTestReview(t *testing.T, valid bool, apiVersion string) []byte {
 	t.Helper()
 	review := admissionv1.AdmissionReview{
@@ -22,6 +22,8 @@ import (
 	k8sauth "k8s.io/api/authentication/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/pkg/security"
 )
 
 // ValidateK8sJwt validates a k8s JWT at API server.
@@ -30,20 +32,48 @@ import (
 // targetToken: the JWT of the K8s service account to be reviewed
 // aud: list of audiences to check. If empty 1st party tokens will be checked.
 func ValidateK8sJwt(kubeClient kubernetes.Interface, targetToken string, aud []string) ([]string, error) {
+	info, err := ReviewK8sJwt(kubeClient, targetToken, aud)
+	if err != nil {
+		return nil, err
+	}
+	return []string{info.PodNamespace, info.PodServiceAccount}, nil
+}
+
+// ReviewK8sJwt validates a k8s JWT at API server, as ValidateK8sJwt, and returns the pod the token is bound to.
+func ReviewK8sJwt(kubeClient kubernetes.Interface, targetToken string, aud []string) (security.KubernetesInfo, error) {
 	tokenReview := &k8sauth.TokenReview{
 		Spec: k8sauth.TokenReviewSpec{
-			Token: targetToken,
+			Token:     targetToken,
+			Audiences: aud,
 		},
 	}
-	if aud != nil {
-		tokenReview.Spec.Audiences = aud
-	}
 	reviewRes, err := kubeClient.AuthenticationV1().TokenReviews().Create(context.TODO(), tokenReview, metav1.CreateOptions{})
 	if err != nil {
-		return nil, err
+		return security.KubernetesInfo{}, err
+	}
+	id, err := getTokenReviewResult(reviewRes)
+	if err != nil {
+		return security.KubernetesInfo{}, err
 	}
+	return security.KubernetesInfo{
+		PodName:           extra(reviewRes, podNameExtra),
+		PodNamespace:      id[0],
+		PodUID:            extra(reviewRes, podUIDExtra),
+		PodServiceAccount: id[1],
+	}, nil
+}
 
-	return getTokenReviewResult(reviewRes)
+// The extras of the users of the tokens bound to pods.
+const (
+	podNameExtra = "authentication.kubernetes.io/pod-name"
+	podUIDExtra  = "authentication.kubernetes.io/pod-uid"
+)
+
+func extra(tokenReview *k8sauth.TokenReview, key string) string {
+	if v := tokenReview.Status.User.Extra[key]; len(v) == 1 {
+		return v[0]
+	}
+	return ""
 }
 
 func getTokenReviewResult(tokenReview *k8sauth.TokenReview) ([]string, error) {
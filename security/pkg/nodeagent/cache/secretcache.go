@@ -28,6 +28,7 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"go.uber.org/atomic"
 
 	"istio.io/istio/pkg/backoff"
 	"istio.io/istio/pkg/file"
@@ -108,6 +109,9 @@ type SecretManagerClient struct {
 	// outputMutex protects writes of certificates to disk
 	outputMutex sync.Mutex
 
+	// workloadExpiration is the expiration time of the last workload certificate generated, zero if none.
+	workloadExpiration atomic.Time
+
 	// Dynamically configured Trust Bundle Mutex
 	configTrustBundleMutex sync.RWMutex
 	// Dynamically configured Trust Bundle
@@ -123,7 +127,11 @@ type SecretManagerClient struct {
 type secretCache struct {
 	mu       sync.RWMutex
 	workload *security.SecretItem
-	certRoot []byte
+	// ecdsaWorkload is the ECDSA workload certificate, served alongside an RSA workload certificate.
+	ecdsaWorkload *security.SecretItem
+	// impersonated are the certificates of the identities of the host network pods served by a node proxy.
+	impersonated map[string]*security.SecretItem
+	certRoot     []byte
 }
 
 // GetRoot returns cached root cert and cert expiration time. This method is thread safe.
@@ -155,6 +163,37 @@ func (s *secretCache) SetWorkload(value *security.SecretItem) {
 	s.workload = value
 }
 
+func (s *secretCache) GetECDSAWorkload() *security.SecretItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ecdsaWorkload
+}
+
+func (s *secretCache) SetECDSAWorkload(value *security.SecretItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ecdsaWorkload = value
+}
+
+func (s *secretCache) GetImpersonated(identity string) *security.SecretItem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.impersonated[identity]
+}
+
+func (s *secretCache) SetImpersonated(identity string, value *security.SecretItem) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == nil {
+		delete(s.impersonated, identity)
+		return
+	}
+	if s.impersonated == nil {
+		s.impersonated = map[string]*security.SecretItem{}
+	}
+	s.impersonated[identity] = value
+}
+
 var _ security.SecretManager = &SecretManagerClient{}
 
 // FileCert stores a reference to a certificate on disk
@@ -212,11 +251,51 @@ func (sc *SecretManagerClient) OnSecretUpdate(resourceName string) {
 	}
 }
 
+// WorkloadCertificateExpiration returns the expiration time of the last workload certificate generated,
+// from a CA or from files. It returns false if no workload certificate was generated yet.
+func (sc *SecretManagerClient) WorkloadCertificateExpiration() (time.Time, bool) {
+	exp := sc.workloadExpiration.Load()
+	return exp, !exp.IsZero()
+}
+
+// impersonates returns true if the resource is the certificate of an identity impersonated by a node proxy.
+func (sc *SecretManagerClient) impersonates(resourceName string) bool {
+	return sc.configOptions.NodeProxy && strings.HasPrefix(resourceName, spiffe.URIPrefix)
+}
+
 // getCachedSecret: retrieve cached Secret Item (workload-certificate/workload-root) from secretManager client
 func (sc *SecretManagerClient) getCachedSecret(resourceName string) (secret *security.SecretItem) {
 	var rootCertBundle []byte
 	var ns *security.SecretItem
 
+	if resourceName == security.WorkloadECDSAKeyCertResourceName {
+		if c := sc.cache.GetECDSAWorkload(); c != nil {
+			cacheLog.WithLabels("ttl", time.Until(c.ExpireTime)).Info("returned ECDSA workload certificate from cache")
+			return &security.SecretItem{
+				ResourceName:     resourceName,
+				CertificateChain: c.CertificateChain,
+				PrivateKey:       c.PrivateKey,
+				ExpireTime:       c.ExpireTime,
+				CreatedTime:      c.CreatedTime,
+			}
+		}
+		return nil
+	}
+
+	if sc.impersonates(resourceName) {
+		if c := sc.cache.GetImpersonated(resourceName); c != nil {
+			cacheLog.WithLabels("ttl", time.Until(c.ExpireTime)).Info("returned impersonated certificate from cache")
+			return &security.SecretItem{
+				ResourceName:     resourceName,
+				CertificateChain: c.CertificateChain,
+				PrivateKey:       c.PrivateKey,
+				ExpireTime:       c.ExpireTime,
+				CreatedTime:      c.CreatedTime,
+			}
+		}
+		return nil
+	}
+
 	if c := sc.cache.GetWorkload(); c != nil {
 		if resourceName == security.RootCertReqResourceName {
 			rootCertBundle = sc.mergeTrustAnchorBytes(c.RootCert)
@@ -250,6 +329,9 @@ func (sc *SecretManagerClient) GenerateSecret(resourceName string) (secret *secu
 		if secret == nil || err != nil {
 			return
 		}
+		if resourceName == security.WorkloadKeyCertResourceName {
+			sc.workloadExpiration.Store(secret.ExpireTime)
+		}
 		// We need to hold a mutex here, otherwise if two threads are writing the same certificate,
 		// we may permanently end up with a mismatch key/cert pair. We still make end up temporarily
 		// with mismatched key/cert pair since we cannot atomically write multiple files. It may be
@@ -574,6 +656,22 @@ func (sc *SecretManagerClient) generateNewSecret(resourceName string) (*security
 		ServiceAccount: sc.configOptions.ServiceAccount,
 	}
 
+	sign := sc.caClient.CSRSign
+	if sc.impersonates(resourceName) {
+		identity, err := spiffe.ParseIdentity(resourceName)
+		if err != nil {
+			return nil, err
+		}
+		impersonating, ok := sc.caClient.(security.ImpersonatingClient)
+		if !ok {
+			return nil, fmt.Errorf("the CA client cannot request the certificate of %s on behalf of a host network pod", resourceName)
+		}
+		csrHostName = &identity
+		sign = func(csrPEM []byte, certValidTTLInSec int64) ([]string, error) {
+			return impersonating.CSRSignImpersonated(csrPEM, certValidTTLInSec, resourceName)
+		}
+	}
+
 	cacheLog.Debugf("constructed host name for CSR: %s", csrHostName.String())
 	options := pkiutil.CertOptions{
 		Host:       csrHostName.String(),
@@ -581,6 +679,9 @@ func (sc *SecretManagerClient) generateNewSecret(resourceName string) (*security
 		PKCS8Key:   sc.configOptions.Pkcs8Keys,
 		ECSigAlg:   pkiutil.SupportedECSignatureAlgorithms(sc.configOptions.ECCSigAlg),
 	}
+	if resourceName == security.WorkloadECDSAKeyCertResourceName {
+		options.ECSigAlg = pkiutil.EcdsaSigAlg
+	}
 
 	// Generate the cert/key, send CSR to CA.
 	csrPEM, keyPEM, err := pkiutil.GenCSR(options)
@@ -591,7 +692,7 @@ func (sc *SecretManagerClient) generateNewSecret(resourceName string) (*security
 
 	numOutgoingRequests.With(RequestType.Value(monitoring.CSR)).Increment()
 	timeBeforeCSR := time.Now()
-	certChainPEM, err := sc.caClient.CSRSign(csrPEM, int64(sc.configOptions.SecretTTL.Seconds()))
+	certChainPEM, err := sign(csrPEM, int64(sc.configOptions.SecretTTL.Seconds()))
 	if err == nil {
 		trustBundlePEM, err = sc.caClient.GetRootCertBundle()
 	}
@@ -646,18 +747,28 @@ func (sc *SecretManagerClient) rotateTime(secret security.SecretItem) time.Durat
 func (sc *SecretManagerClient) registerSecret(item security.SecretItem) {
 	delay := sc.rotateTime(item)
 	certExpirySeconds.ValueFrom(func() float64 { return time.Until(item.ExpireTime).Seconds() }, item.ResourceName)
-	item.ResourceName = security.WorkloadKeyCertResourceName
+	get, set := sc.cache.GetWorkload, sc.cache.SetWorkload
+	switch {
+	case item.ResourceName == security.WorkloadECDSAKeyCertResourceName:
+		get, set = sc.cache.GetECDSAWorkload, sc.cache.SetECDSAWorkload
+	case sc.impersonates(item.ResourceName):
+		identity := item.ResourceName
+		get = func() *security.SecretItem { return sc.cache.GetImpersonated(identity) }
+		set = func(value *security.SecretItem) { sc.cache.SetImpersonated(identity, value) }
+	default:
+		item.ResourceName = security.WorkloadKeyCertResourceName
+	}
 	// In case there are two calls to GenerateSecret at once, we don't want both to be concurrently registered
-	if sc.cache.GetWorkload() != nil {
+	if get() != nil {
 		resourceLog(item.ResourceName).Infof("skip scheduling certificate rotation, already scheduled")
 		return
 	}
-	sc.cache.SetWorkload(&item)
+	set(&item)
 	resourceLog(item.ResourceName).Debugf("scheduled certificate for rotation in %v", delay)
 	sc.queue.PushDelayed(func() error {
 		resourceLog(item.ResourceName).Debugf("rotating certificate")
 		// Clear the cache so the next call generates a fresh certificate
-		sc.cache.SetWorkload(nil)
+		set(nil)
 
 		sc.OnSecretUpdate(item.ResourceName)
 		return nil
@@ -70,10 +70,16 @@ func testWorkloadAgentGenerateSecret(t *testing.T, isUsingPluginProvider bool) {
 	}
 
 	sc := createCache(t, fakeCACli, func(resourceName string) {}, security.Options{WorkloadRSAKeySize: 2048})
+	if _, ok := sc.WorkloadCertificateExpiration(); ok {
+		t.Fatalf("unexpected workload certificate expiration before generation")
+	}
 	gotSecret, err := sc.GenerateSecret(security.WorkloadKeyCertResourceName)
 	if err != nil {
 		t.Fatalf("Failed to get secrets: %v", err)
 	}
+	if exp, ok := sc.WorkloadCertificateExpiration(); !ok || !exp.Equal(gotSecret.ExpireTime) {
+		t.Errorf("Got unexpected workload certificate expiration %v, want %v", exp, gotSecret.ExpireTime)
+	}
 
 	if got, want := gotSecret.CertificateChain, []byte(strings.Join(fakeCACli.GeneratedCerts[0], "")); !bytes.Equal(got, want) {
 		t.Errorf("Got unexpected certificate chain #1. Got: %v, want: %v", string(got), string(want))
@@ -106,6 +112,85 @@ func testWorkloadAgentGenerateSecret(t *testing.T, isUsingPluginProvider bool) {
 	}
 }
 
+func TestWorkloadAgentGenerateECDSASecret(t *testing.T) {
+	fakeCACli, err := mock.NewMockCAClient(time.Hour, true)
+	if err != nil {
+		t.Fatalf("Error creating Mock CA client: %v", err)
+	}
+	sc := createCache(t, fakeCACli, func(resourceName string) {}, security.Options{WorkloadRSAKeySize: 2048})
+
+	rsaSecret, err := sc.GenerateSecret(security.WorkloadKeyCertResourceName)
+	if err != nil {
+		t.Fatalf("Failed to get secrets: %v", err)
+	}
+	ecdsaSecret, err := sc.GenerateSecret(security.WorkloadECDSAKeyCertResourceName)
+	if err != nil {
+		t.Fatalf("Failed to get secrets: %v", err)
+	}
+	if len(fakeCACli.GeneratedCerts) != 2 {
+		t.Fatalf("expected two certificates to be issued, got %d", len(fakeCACli.GeneratedCerts))
+	}
+	if bytes.Equal(rsaSecret.PrivateKey, ecdsaSecret.PrivateKey) {
+		t.Fatalf("expected distinct keys for the RSA and ECDSA certificates")
+	}
+	if !bytes.Contains(ecdsaSecret.PrivateKey, []byte("EC PRIVATE KEY")) {
+		t.Errorf("expected an ECDSA private key, got %s", ecdsaSecret.PrivateKey)
+	}
+	if !bytes.Contains(rsaSecret.PrivateKey, []byte("RSA PRIVATE KEY")) {
+		t.Errorf("expected an RSA private key, got %s", rsaSecret.PrivateKey)
+	}
+
+	// Both certificates are cached independently.
+	cached, err := sc.GenerateSecret(security.WorkloadECDSAKeyCertResourceName)
+	if err != nil {
+		t.Fatalf("Failed to get secrets: %v", err)
+	}
+	if !bytes.Equal(cached.PrivateKey, ecdsaSecret.PrivateKey) || len(fakeCACli.GeneratedCerts) != 2 {
+		t.Errorf("expected ECDSA certificate to be served from cache")
+	}
+	if cached.ResourceName != security.WorkloadECDSAKeyCertResourceName {
+		t.Errorf("unexpected resource name %q", cached.ResourceName)
+	}
+}
+
+func TestWorkloadAgentGenerateImpersonatedSecret(t *testing.T) {
+	fakeCACli, err := mock.NewMockCAClient(time.Hour, true)
+	if err != nil {
+		t.Fatalf("Error creating Mock CA client: %v", err)
+	}
+	sc := createCache(t, fakeCACli, func(resourceName string) {}, security.Options{WorkloadRSAKeySize: 2048, NodeProxy: true})
+
+	identity := "spiffe://cluster.local/ns/host/sa/node-exporter"
+	secret, err := sc.GenerateSecret(identity)
+	if err != nil {
+		t.Fatalf("Failed to get secrets: %v", err)
+	}
+	if !reflect.DeepEqual(fakeCACli.ImpersonatedIdentities, []string{identity}) {
+		t.Fatalf("expected the certificate to be requested on behalf of %s, got %v", identity, fakeCACli.ImpersonatedIdentities)
+	}
+	if secret.ResourceName != identity {
+		t.Errorf("unexpected resource name %q", secret.ResourceName)
+	}
+	if _, err := sc.GenerateSecret(security.WorkloadKeyCertResourceName); err != nil {
+		t.Fatalf("Failed to get secrets: %v", err)
+	}
+	if len(fakeCACli.ImpersonatedIdentities) != 1 {
+		t.Errorf("expected the workload certificate of the node proxy not to be impersonated")
+	}
+
+	// The certificates of the impersonated identities are cached independently.
+	cached, err := sc.GenerateSecret(identity)
+	if err != nil {
+		t.Fatalf("Failed to get secrets: %v", err)
+	}
+	if !bytes.Equal(cached.PrivateKey, secret.PrivateKey) || len(fakeCACli.GeneratedCerts) != 2 {
+		t.Errorf("expected impersonated certificate to be served from cache")
+	}
+	if _, err := sc.GenerateSecret("spiffe://cluster.local/ns/host"); err == nil {
+		t.Errorf("expected an invalid identity to be rejected")
+	}
+}
+
 type UpdateTracker struct {
 	t    *testing.T
 	hits map[string]int
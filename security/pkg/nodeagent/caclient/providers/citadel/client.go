@@ -87,6 +87,17 @@ func (c *CitadelClient) Close() {
 
 // CSRSign calls Citadel to sign a CSR.
 func (c *CitadelClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) ([]string, error) {
+	return c.createCertificate(csrPEM, certValidTTLInSec, nil)
+}
+
+// CSRSignImpersonated calls Citadel to sign a CSR on behalf of the identity.
+func (c *CitadelClient) CSRSignImpersonated(csrPEM []byte, certValidTTLInSec int64, identity string) ([]string, error) {
+	return c.createCertificate(csrPEM, certValidTTLInSec, map[string]*structpb.Value{
+		security.ImpersonatedIdentity: {Kind: &structpb.Value_StringValue{StringValue: identity}},
+	})
+}
+
+func (c *CitadelClient) createCertificate(csrPEM []byte, certValidTTLInSec int64, meta map[string]*structpb.Value) ([]string, error) {
 	crMetaStruct := &structpb.Struct{
 		Fields: map[string]*structpb.Value{
 			security.CertSigner: {
@@ -94,6 +105,9 @@ func (c *CitadelClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) ([]strin
 			},
 		},
 	}
+	for k, v := range meta {
+		crMetaStruct.Fields[k] = v
+	}
 	req := &pb.IstioCertificateRequest{
 		Csr:              string(csrPEM),
 		ValidityDuration: certValidTTLInSec,
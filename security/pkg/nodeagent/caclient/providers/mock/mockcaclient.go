@@ -39,7 +39,9 @@ type CAClient struct {
 	bundle          *util.KeyCertBundle
 	certLifetime    time.Duration
 	GeneratedCerts  [][]string // Cache the generated certificates for verification purpose.
-	mockTrustAnchor bool
+	// ImpersonatedIdentities are the identities of the certificates requested on behalf of other identities.
+	ImpersonatedIdentities []string
+	mockTrustAnchor        bool
 }
 
 // NewMockCAClient creates an instance of CAClient. errors is used to specify the number of errors
@@ -87,6 +89,12 @@ func (c *CAClient) CSRSign(csrPEM []byte, certValidTTLInSec int64) ([]string, er
 	return ret, nil
 }
 
+// CSRSignImpersonated records the identity and returns the certificate or errors depending on the settings.
+func (c *CAClient) CSRSignImpersonated(csrPEM []byte, certValidTTLInSec int64, identity string) ([]string, error) {
+	c.ImpersonatedIdentities = append(c.ImpersonatedIdentities, identity)
+	return c.CSRSign(csrPEM, certValidTTLInSec)
+}
+
 func (c *CAClient) GetRootCertBundle() ([]string, error) {
 	if c.mockTrustAnchor {
 		rootCertBytes := c.bundle.GetRootCertPem()
@@ -0,0 +1,247 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rolesanywhere exchanges the workload certificate for temporary AWS credentials with IAM Roles Anywhere,
+// and serves them to the containers of the pod in the format of the AWS container credentials provider.
+// See https://docs.aws.amazon.com/rolesanywhere/latest/userguide/authentication-sign-process.html.
+package rolesanywhere
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+const (
+	service        = "rolesanywhere"
+	sessionsPath   = "/sessions"
+	amzDateFormat  = "20060102T150405Z"
+	scopeFormat    = "20060102"
+	defaultTimeout = 10 * time.Second
+)
+
+// Config configures the exchange of the workload certificate for AWS credentials.
+type Config struct {
+	// TrustAnchorARN is the ARN of the Roles Anywhere trust anchor of the mesh CA.
+	TrustAnchorARN string
+	// ProfileARN is the ARN of the Roles Anywhere profile.
+	ProfileARN string
+	// RoleARN is the ARN of the IAM role to assume.
+	RoleARN string
+	// Region is the AWS region of the trust anchor. It defaults to the region of TrustAnchorARN.
+	Region string
+	// Endpoint is the Roles Anywhere endpoint. It defaults to the public endpoint of the region.
+	Endpoint string
+	// SessionDuration is the duration of the credentials. If zero, the duration of the profile is used.
+	SessionDuration time.Duration
+}
+
+// Validate returns an error if the configuration is incomplete, and fills the defaults.
+func (c *Config) Validate() error {
+	if c.TrustAnchorARN == "" || c.ProfileARN == "" || c.RoleARN == "" {
+		return fmt.Errorf("the trust anchor, profile and role ARNs are required")
+	}
+	if c.Region == "" {
+		// arn:partition:rolesanywhere:region:account:trust-anchor/id
+		parts := strings.Split(c.TrustAnchorARN, ":")
+		if len(parts) < 6 || parts[3] == "" {
+			return fmt.Errorf("cannot find the region of trust anchor %q", c.TrustAnchorARN)
+		}
+		c.Region = parts[3]
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, c.Region)
+	}
+	return nil
+}
+
+// Credentials are temporary AWS credentials.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// Client creates Roles Anywhere sessions.
+type Client struct {
+	config Config
+	client *http.Client
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// NewClient returns a Client for the configuration.
+func NewClient(config Config) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Client{
+		config: config,
+		client: &http.Client{Timeout: defaultTimeout},
+		now:    time.Now,
+	}, nil
+}
+
+type createSessionRequest struct {
+	DurationSeconds int    `json:"durationSeconds,omitempty"`
+	ProfileArn      string `json:"profileArn"`
+	RoleArn         string `json:"roleArn"`
+	TrustAnchorArn  string `json:"trustAnchorArn"`
+}
+
+type createSessionResponse struct {
+	CredentialSet []struct {
+		Credentials struct {
+			AccessKeyID     string    `json:"accessKeyId"`
+			SecretAccessKey string    `json:"secretAccessKey"`
+			SessionToken    string    `json:"sessionToken"`
+			Expiration      time.Time `json:"expiration"`
+		} `json:"credentials"`
+	} `json:"credentialSet"`
+}
+
+// CreateSession exchanges the PEM encoded certificate chain and private key for AWS credentials.
+func (c *Client) CreateSession(ctx context.Context, certChain, privateKey []byte) (*Credentials, error) {
+	certs, _, err := util.ParsePemEncodedCertificateChain(certChain)
+	if err != nil {
+		return nil, err
+	}
+	key, err := util.ParsePemEncodedKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(createSessionRequest{
+		DurationSeconds: int(c.config.SessionDuration.Seconds()),
+		ProfileArn:      c.config.ProfileARN,
+		RoleArn:         c.config.RoleARN,
+		TrustAnchorArn:  c.config.TrustAnchorARN,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.config.Endpoint, "/")+sessionsPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if err := sign(req, body, certs, key, c.config.Region, c.now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a Roles Anywhere session: %v", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to create a Roles Anywhere session: status %d: %s", resp.StatusCode, respBody)
+	}
+	var out createSessionResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, fmt.Errorf("failed to decode the Roles Anywhere session: %v", err)
+	}
+	if len(out.CredentialSet) == 0 {
+		return nil, fmt.Errorf("the Roles Anywhere session has no credentials")
+	}
+	creds := out.CredentialSet[0].Credentials
+	return &Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Expiration:      creds.Expiration,
+	}, nil
+}
+
+// sign signs the request with the private key of the leaf certificate, per the Signature Version 4 process
+// for X.509 certificates.
+func sign(req *http.Request, body []byte, certs []*x509.Certificate, key crypto.PrivateKey, region string, now time.Time) error {
+	var algorithm string
+	switch key.(type) {
+	case *ecdsa.PrivateKey:
+		algorithm = "AWS4-X509-ECDSA-SHA256"
+	case *rsa.PrivateKey:
+		algorithm = "AWS4-X509-RSA-SHA256"
+	default:
+		return fmt.Errorf("unsupported private key type %T", key)
+	}
+
+	now = now.UTC()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(amzDateFormat))
+	req.Header.Set("X-Amz-X509", base64.StdEncoding.EncodeToString(certs[0].Raw))
+	signed := []string{"content-type", "host", "x-amz-date", "x-amz-x509"}
+	if len(certs) > 1 {
+		chain := make([]string, 0, len(certs)-1)
+		for _, c := range certs[1:] {
+			chain = append(chain, base64.StdEncoding.EncodeToString(c.Raw))
+		}
+		req.Header.Set("X-Amz-X509-Chain", strings.Join(chain, ","))
+		signed = append(signed, "x-amz-x509-chain")
+	}
+	signedHeaders := strings.Join(signed, ";")
+
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(req.Header.Get(h)) + "\n")
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := strings.Join([]string{now.Format(scopeFormat), region, service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{algorithm, now.Format(amzDateFormat), scope, hex.EncodeToString(requestHash[:])}, "\n")
+	digest := sha256.Sum256([]byte(stringToSign))
+
+	var signature []byte
+	var err error
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		signature, err = ecdsa.SignASN1(rand.Reader, k, digest[:])
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sign the Roles Anywhere request: %v", err)
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, certs[0].SerialNumber.String(), scope, signedHeaders, hex.EncodeToString(signature)))
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rolesanywhere
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+var testTime = time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+func genCert(t *testing.T) ([]byte, []byte) {
+	t.Helper()
+	cert, key, err := util.GenCertKeyFromOptions(util.CertOptions{
+		Host:         "spiffe://cluster.local/ns/default/sa/app",
+		NotBefore:    time.Now(),
+		TTL:          time.Hour,
+		IsSelfSigned: true,
+		ECSigAlg:     util.EcdsaSigAlg,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+// fakeRolesAnywhere verifies the signature of the CreateSession requests, and counts them.
+type fakeRolesAnywhere struct {
+	t     *testing.T
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeRolesAnywhere) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	if err := verify(req, body); err != nil {
+		f.t.Errorf("invalid signature: %v", err)
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	var in createSessionRequest
+	if err := json.Unmarshal(body, &in); err != nil || in.RoleArn != "arn:aws:iam::123456789012:role/app" {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	_, _ = fmt.Fprintf(w, `{"credentialSet":[{"credentials":{"accessKeyId":"AKID","secretAccessKey":"secret",`+
+		`"sessionToken":"token","expiration":%q}}]}`, testTime.Add(time.Hour).Format(time.RFC3339))
+}
+
+func (f *fakeRolesAnywhere) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func verify(req *http.Request, body []byte) error {
+	der, err := base64.StdEncoding.DecodeString(req.Header.Get("X-Amz-X509"))
+	if err != nil {
+		return err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+	auth := req.Header.Get("Authorization")
+	prefix := "AWS4-X509-ECDSA-SHA256 Credential=" + cert.SerialNumber.String() + "/20230102/us-west-2/rolesanywhere/aws4_request, " +
+		"SignedHeaders=content-type;host;x-amz-date;x-amz-x509, Signature="
+	if !strings.HasPrefix(auth, prefix) {
+		return fmt.Errorf("unexpected authorization %q", auth)
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(auth, prefix))
+	if err != nil {
+		return err
+	}
+	date := req.Header.Get("X-Amz-Date")
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := "POST\n/sessions\n\n" +
+		"content-type:application/json\nhost:" + req.Host + "\nx-amz-date:" + date + "\nx-amz-x509:" + req.Header.Get("X-Amz-X509") + "\n\n" +
+		"content-type;host;x-amz-date;x-amz-x509\n" + hex.EncodeToString(payloadHash[:])
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	digest := sha256.Sum256([]byte("AWS4-X509-ECDSA-SHA256\n" + date + "\n20230102/us-west-2/rolesanywhere/aws4_request\n" +
+		hex.EncodeToString(requestHash[:])))
+	if !ecdsa.VerifyASN1(cert.PublicKey.(*ecdsa.PublicKey), digest[:], signature) {
+		return fmt.Errorf("signature does not match")
+	}
+	return nil
+}
+
+func newTestClient(t *testing.T, f *fakeRolesAnywhere) *Client {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	c, err := NewClient(Config{
+		TrustAnchorARN: "arn:aws:rolesanywhere:us-west-2:123456789012:trust-anchor/mesh",
+		ProfileARN:     "arn:aws:rolesanywhere:us-west-2:123456789012:profile/app",
+		RoleARN:        "arn:aws:iam::123456789012:role/app",
+		Endpoint:       srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.now = func() time.Time { return testTime }
+	return c
+}
+
+func TestConfigValidate(t *testing.T) {
+	c := Config{
+		TrustAnchorARN: "arn:aws:rolesanywhere:eu-west-1:123456789012:trust-anchor/mesh",
+		ProfileARN:     "arn:aws:rolesanywhere:eu-west-1:123456789012:profile/app",
+		RoleARN:        "arn:aws:iam::123456789012:role/app",
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if c.Region != "eu-west-1" || c.Endpoint != "https://rolesanywhere.eu-west-1.amazonaws.com" {
+		t.Fatalf("unexpected defaults %q %q", c.Region, c.Endpoint)
+	}
+	if err := (&Config{TrustAnchorARN: "mesh", ProfileARN: "p", RoleARN: "r"}).Validate(); err == nil {
+		t.Fatalf("expected an error for a trust anchor without region")
+	}
+	if err := (&Config{TrustAnchorARN: c.TrustAnchorARN}).Validate(); err == nil {
+		t.Fatalf("expected an error for missing ARNs")
+	}
+}
+
+func TestCreateSession(t *testing.T) {
+	f := &fakeRolesAnywhere{t: t}
+	c := newTestClient(t, f)
+	cert, key := genCert(t)
+	creds, err := c.CreateSession(context.Background(), cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token", Expiration: testTime.Add(time.Hour)}
+	if *creds != want {
+		t.Fatalf("got %+v, want %+v", *creds, want)
+	}
+}
+
+type fakeSecrets struct {
+	cert, key []byte
+}
+
+func (f *fakeSecrets) GenerateSecret(string) (*security.SecretItem, error) {
+	return &security.SecretItem{CertificateChain: f.cert, PrivateKey: f.key}, nil
+}
+
+func TestServer(t *testing.T) {
+	f := &fakeRolesAnywhere{t: t}
+	c := newTestClient(t, f)
+	cert, key := genCert(t)
+	secrets := &fakeSecrets{cert: cert, key: key}
+	s, err := NewServer("127.0.0.1", 0, c, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+
+	get := func() credentialsResponse {
+		t.Helper()
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", s.Port, CredentialsPath))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected status %d", resp.StatusCode)
+		}
+		var out credentialsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	want := credentialsResponse{AccessKeyID: "AKID", SecretAccessKey: "secret", Token: "token", Expiration: "2023-01-02T04:04:05Z"}
+	if got := get(); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	get()
+	if f.count() != 1 {
+		t.Fatalf("expected the credentials to be cached, got %d sessions", f.count())
+	}
+
+	// A rotated certificate creates a new session.
+	secrets.cert, secrets.key = genCert(t)
+	get()
+	if f.count() != 2 {
+		t.Fatalf("expected a new session after the certificate rotation, got %d sessions", f.count())
+	}
+
+	// Credentials about to expire are renewed.
+	c.now = func() time.Time { return testTime.Add(time.Hour - time.Minute) }
+	get()
+	if f.count() != 3 {
+		t.Fatalf("expected a new session before the expiration, got %d sessions", f.count())
+	}
+
+	// The credentials are only served to the containers of the pod.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, CredentialsPath, nil)
+	req.RemoteAddr = "10.0.0.1:12345"
+	s.ServeCredentials(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a remote request to be forbidden, got status %d", rec.Code)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rolesanywhere
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/pkg/log"
+)
+
+// CredentialsPath is the path serving the credentials. The containers of the pod use them by setting
+// AWS_CONTAINER_CREDENTIALS_FULL_URI to http://localhost:<port>/credentials.
+const CredentialsPath = "/credentials"
+
+// refreshBefore is how long before their expiration the credentials are renewed.
+const refreshBefore = 5 * time.Minute
+
+var serverLog = log.RegisterScope("rolesanywhere", "AWS IAM Roles Anywhere credentials debugging", 0)
+
+// Server serves the AWS credentials of the workload on the local host. The credentials are renewed when they
+// are about to expire, or when the workload certificate is rotated.
+type Server struct {
+	client  *Client
+	secrets security.SecretManager
+	server  *http.Server
+	// Port number that server listens on.
+	Port int
+
+	mu        sync.Mutex
+	creds     *Credentials
+	certChain []byte
+}
+
+// credentialsResponse is the format of the AWS container credentials provider.
+type credentialsResponse struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+	Expiration      string `json:"Expiration"`
+}
+
+// NewServer starts serving the credentials exchanged for the workload certificate of the secret manager.
+func NewServer(localHostAddr string, localPort int, client *Client, secrets security.SecretManager) (*Server, error) {
+	s := &Server{
+		client:  client,
+		secrets: secrets,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(CredentialsPath, s.ServeCredentials)
+	hostPort := net.JoinHostPort(localHostAddr, strconv.Itoa(localPort))
+	s.server = &http.Server{
+		Addr:        hostPort,
+		Handler:     mux,
+		IdleTimeout: 90 * time.Second,
+		ReadTimeout: 30 * time.Second,
+	}
+	ln, err := net.Listen("tcp", hostPort)
+	if err != nil {
+		return nil, err
+	}
+	// If passed in port is 0, get the actual chosen port.
+	s.Port = ln.Addr().(*net.TCPAddr).Port
+	go func() {
+		serverLog.Infof("Start serving AWS credentials on %s:%d", localHostAddr, s.Port)
+		// Serve always returns a non-nil error.
+		serverLog.Info(s.server.Serve(ln))
+	}()
+	return s, nil
+}
+
+// ServeCredentials serves the AWS credentials of the workload, only to the containers of the pod.
+func (s *Server) ServeCredentials(w http.ResponseWriter, req *http.Request) {
+	if !isRequestFromLocalhost(req) {
+		http.Error(w, "Only requests from localhost are allowed", http.StatusForbidden)
+		return
+	}
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	creds, err := s.credentials(req.Context())
+	if err != nil {
+		serverLog.Warnf("failed to get AWS credentials: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(credentialsResponse{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		Token:           creds.SessionToken,
+		Expiration:      creds.Expiration.UTC().Format(time.RFC3339),
+	})
+}
+
+func (s *Server) credentials(ctx context.Context) (*Credentials, error) {
+	secret, err := s.secrets.GenerateSecret(security.WorkloadKeyCertResourceName)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.creds != nil && bytes.Equal(s.certChain, secret.CertificateChain) &&
+		s.client.now().Add(refreshBefore).Before(s.creds.Expiration) {
+		return s.creds, nil
+	}
+	creds, err := s.client.CreateSession(ctx, secret.CertificateChain, secret.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	serverLog.Debugf("created Roles Anywhere session expiring at %s", creds.Expiration.Format(time.RFC3339))
+	s.creds = creds
+	s.certChain = secret.CertificateChain
+	return creds, nil
+}
+
+func isRequestFromLocalhost(r *http.Request) bool {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+
+	userIP := net.ParseIP(ip)
+	return userIP.IsLoopback()
+}
+
+// Stop stops the server.
+func (s *Server) Stop() {
+	if err := s.server.Shutdown(context.Background()); err != nil {
+		serverLog.Errorf("failed to shut down the AWS credentials server: %v", err)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bootstraptoken implements the short-lived, single-use tokens used to onboard VM workloads. A token
+// authenticates a single certificate signing request of the workload to the CA of istiod, after which the
+// workload authenticates with its certificate.
+//
+// A token has the form "istio-bootstrap.<id>.<secret>". The token is recorded in a Secret of the istiod
+// namespace, named after the id of the token and holding the hash of the secret, the identity it grants and its
+// expiration. The Secret is deleted once a certificate is issued with the token, so that a token is used at most
+// once across the replicas of istiod, or once the token expired.
+package bootstraptoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/security/pkg/server/ca/authenticate"
+	"istio.io/pkg/log"
+)
+
+const (
+	AuthenticatorType = "BootstrapTokenAuthenticator"
+
+	// TokenPrefix is the prefix of the bootstrap tokens, distinguishing them from the JWTs.
+	TokenPrefix = "istio-bootstrap."
+
+	// SecretType is the type of the Secrets recording the bootstrap tokens.
+	SecretType corev1.SecretType = "istio.io/bootstrap-token"
+
+	// MaxTTL is the longest validity of a bootstrap token, which is meant to be used right away.
+	MaxTTL = 24 * time.Hour
+
+	secretNamePrefix = "istio-bootstrap-token-"
+
+	secretHashKey     = "secret-hash"
+	namespaceKey      = "namespace"
+	serviceAccountKey = "service-account"
+	expirationKey     = "expiration"
+)
+
+// auditLog records the bootstrap tokens minted, used and rejected.
+var auditLog = log.RegisterScope("bootstraptoken", "VM bootstrap token audit log", 0)
+
+func secretName(id string) string {
+	return secretNamePrefix + id
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int, encode func([]byte) string) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+// parse splits a bootstrap token into its id and secret.
+func parse(token string) (id, secret string, err error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return "", "", fmt.Errorf("not a bootstrap token")
+	}
+	parts := strings.Split(strings.TrimPrefix(token, TokenPrefix), ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("malformed bootstrap token")
+	}
+	return parts[0], parts[1], nil
+}
+
+// Mint creates a bootstrap token granting the identity of the service account, valid for the given duration.
+// The token is recorded in the istiod namespace, and returned with its expiration. Tokens are minted by istiod,
+// for the callers allowed by the write policy.
+func Mint(ctx context.Context, client kubernetes.Interface, istiodNamespace, namespace, serviceAccount string,
+	ttl time.Duration,
+) (string, time.Time, error) {
+	if ttl <= 0 || ttl > MaxTTL {
+		return "", time.Time{}, fmt.Errorf("invalid bootstrap token duration %v, must be positive and at most %v", ttl, MaxTTL)
+	}
+	id, err := randomString(8, hex.EncodeToString)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	secret, err := randomString(32, base64.RawURLEncoding.EncodeToString)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiration := time.Now().Add(ttl).UTC().Truncate(time.Second)
+	s := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretName(id),
+			Namespace: istiodNamespace,
+		},
+		Type: SecretType,
+		Data: map[string][]byte{
+			secretHashKey:     []byte(hashSecret(secret)),
+			namespaceKey:      []byte(namespace),
+			serviceAccountKey: []byte(serviceAccount),
+			expirationKey:     []byte(expiration.Format(time.RFC3339)),
+		},
+	}
+	if _, err := client.CoreV1().Secrets(istiodNamespace).Create(ctx, s, metav1.CreateOptions{}); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to record the bootstrap token: %v", err)
+	}
+	auditLog.Infof("bootstrap token %s minted for %s/%s, expiring at %s", id, namespace, serviceAccount,
+		expiration.Format(time.RFC3339))
+	return TokenPrefix + id + "." + secret, expiration, nil
+}
+
+// CleanupExpired deletes the Secrets of the expired bootstrap tokens of the istiod namespace. The tokens which are
+// never used are otherwise kept forever.
+func CleanupExpired(ctx context.Context, client kubernetes.Interface, istiodNamespace string, now time.Time) error {
+	secrets, err := client.CoreV1().Secrets(istiodNamespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("type", string(SecretType)).String(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the bootstrap tokens: %v", err)
+	}
+	for _, s := range secrets.Items {
+		if s.Type != SecretType || !strings.HasPrefix(s.Name, secretNamePrefix) {
+			continue
+		}
+		expiration, err := time.Parse(time.RFC3339, string(s.Data[expirationKey]))
+		if err == nil && !now.After(expiration) {
+			continue
+		}
+		uid := s.UID
+		err = client.CoreV1().Secrets(istiodNamespace).Delete(ctx, s.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &uid},
+		})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete the expired bootstrap token %s: %v", s.Name, err)
+		}
+		auditLog.Infof("expired bootstrap token %s deleted", strings.TrimPrefix(s.Name, secretNamePrefix))
+	}
+	return nil
+}
+
+// RunCleanup runs CleanupExpired at the given interval until stop is closed.
+func RunCleanup(client kubernetes.Interface, istiodNamespace string, interval time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+		if err := CleanupExpired(context.Background(), client, istiodNamespace, time.Now()); err != nil {
+			auditLog.Warnf("%v", err)
+		}
+	}
+}
+
+// Authenticator authenticates the bootstrap tokens. The tokens are consumed by the Consume function of the
+// returned Caller, once the request succeeded.
+type Authenticator struct {
+	// holder of a mesh configuration for dynamically updating trust domain
+	meshHolder mesh.Holder
+	client     kubernetes.Interface
+	// namespace of istiod, where the tokens are recorded
+	namespace string
+
+	now func() time.Time
+}
+
+var _ security.Authenticator = &Authenticator{}
+
+// NewAuthenticator creates an authenticator of the bootstrap tokens recorded in the istiod namespace.
+func NewAuthenticator(meshHolder mesh.Holder, client kubernetes.Interface, istiodNamespace string) *Authenticator {
+	return &Authenticator{
+		meshHolder: meshHolder,
+		client:     client,
+		namespace:  istiodNamespace,
+		now:        time.Now,
+	}
+}
+
+func (a *Authenticator) AuthenticatorType() string {
+	return AuthenticatorType
+}
+
+// Authenticate authenticates the call using the bootstrap token from the context.
+// The returned Caller.Identities is in SPIFFE format.
+func (a *Authenticator) Authenticate(authRequest security.AuthContext) (*security.Caller, error) {
+	if authRequest.GrpcContext != nil {
+		token, err := security.ExtractBearerToken(authRequest.GrpcContext)
+		if err != nil {
+			return nil, fmt.Errorf("bootstrap token extraction error: %v", err)
+		}
+		return a.authenticate(authRequest.GrpcContext, token, security.GetConnectionAddress(authRequest.GrpcContext))
+	}
+	if authRequest.Request != nil {
+		token, err := security.ExtractRequestToken(authRequest.Request)
+		if err != nil {
+			return nil, fmt.Errorf("bootstrap token extraction error: %v", err)
+		}
+		return a.authenticate(authRequest.Request.Context(), token, remoteAddr(authRequest.Request))
+	}
+	return nil, nil
+}
+
+func remoteAddr(req *http.Request) string {
+	if req.RemoteAddr == "" {
+		return "unknown"
+	}
+	return req.RemoteAddr
+}
+
+func (a *Authenticator) authenticate(ctx context.Context, token, peer string) (*security.Caller, error) {
+	id, secret, err := parse(token)
+	if err != nil {
+		// Other tokens are handled by the other authenticators, and are not audited.
+		return nil, err
+	}
+	reject := func(format string, args ...any) (*security.Caller, error) {
+		err := fmt.Errorf(format, args...)
+		auditLog.Warnf("bootstrap token %s rejected from %s: %v", id, peer, err)
+		return nil, err
+	}
+
+	s, err := a.client.CoreV1().Secrets(a.namespace).Get(ctx, secretName(id), metav1.GetOptions{})
+	if err != nil {
+		return reject("unknown or already used bootstrap token: %v", err)
+	}
+	if s.Type != SecretType {
+		return reject("unknown bootstrap token")
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), s.Data[secretHashKey]) != 1 {
+		return reject("invalid bootstrap token secret")
+	}
+	expiration, err := time.Parse(time.RFC3339, string(s.Data[expirationKey]))
+	if err != nil {
+		return reject("invalid bootstrap token expiration: %v", err)
+	}
+	ns, sa := string(s.Data[namespaceKey]), string(s.Data[serviceAccountKey])
+	if ns == "" || sa == "" {
+		return reject("bootstrap token without identity")
+	}
+
+	consume := func() error {
+		// The preconditions ensure that a single request consumes the token when presented to several istiod
+		// replicas concurrently.
+		return a.client.CoreV1().Secrets(a.namespace).Delete(ctx, s.Name, metav1.DeleteOptions{
+			Preconditions: &metav1.Preconditions{UID: &s.UID, ResourceVersion: &s.ResourceVersion},
+		})
+	}
+	if a.now().After(expiration) {
+		// An expired token can no longer be used, so it is dropped.
+		_ = consume()
+		return reject("bootstrap token for %s/%s expired at %s", ns, sa, expiration.Format(time.RFC3339))
+	}
+
+	return &security.Caller{
+		AuthSource: security.AuthSourceIDToken,
+		Identities: []string{fmt.Sprintf(authenticate.IdentityTemplate, a.meshHolder.Mesh().GetTrustDomain(), ns, sa)},
+		// The token is consumed once the certificate is issued, so that the VM can retry if the issuance fails.
+		Consume: func() error {
+			if err := consume(); err != nil {
+				auditLog.Warnf("bootstrap token %s rejected from %s: already used: %v", id, peer, err)
+				return fmt.Errorf("already used bootstrap token: %v", err)
+			}
+			auditLog.Infof("bootstrap token %s used from %s for %s/%s", id, peer, ns, sa)
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,171 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bootstraptoken
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+type mockMeshConfigHolder struct {
+	trustDomain string
+}
+
+func (mh mockMeshConfigHolder) Mesh() *meshconfig.MeshConfig {
+	return &meshconfig.MeshConfig{
+		TrustDomain: mh.trustDomain,
+	}
+}
+
+func authContext(token string) security.AuthContext {
+	md := metadata.MD{"authorization": []string{security.BearerTokenPrefix + token}}
+	return security.AuthContext{GrpcContext: metadata.NewIncomingContext(context.Background(), md)}
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		token  string
+		id     string
+		secret string
+		err    bool
+	}{
+		{token: "istio-bootstrap.abc.def", id: "abc", secret: "def"},
+		{token: "eyJhbGciOiJSUzI1NiJ9.e30.sig", err: true},
+		{token: "istio-bootstrap.abc", err: true},
+		{token: "istio-bootstrap..def", err: true},
+		{token: "istio-bootstrap.a.b.c", err: true},
+	}
+	for _, tt := range cases {
+		t.Run(tt.token, func(t *testing.T) {
+			id, secret, err := parse(tt.token)
+			assert.Equal(t, err != nil, tt.err)
+			assert.Equal(t, id, tt.id)
+			assert.Equal(t, secret, tt.secret)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	a := NewAuthenticator(mockMeshConfigHolder{"cluster.local"}, client, "istio-system")
+
+	token, expiration, err := Mint(context.Background(), client, "istio-system", "bar", "vm", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, TokenPrefix) {
+		t.Fatalf("unexpected token %v", token)
+	}
+	assert.Equal(t, expiration.After(time.Now()), true)
+
+	id, _, _ := parse(token)
+	if _, err := a.Authenticate(authContext(TokenPrefix + id + ".wrong")); err == nil {
+		t.Fatal("expected a token with a wrong secret to be rejected")
+	}
+
+	// The token is only consumed once the request succeeded, so that a failed request can be retried.
+	for i := 0; i < 2; i++ {
+		caller, err := a.Authenticate(authContext(token))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, caller.AuthSource, security.AuthSourceIDToken)
+		assert.Equal(t, caller.Identities, []string{"spiffe://cluster.local/ns/bar/sa/vm"})
+	}
+	caller, err := a.Authenticate(authContext(token))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := caller.Consume(); err != nil {
+		t.Fatal(err)
+	}
+	if err := caller.Consume(); err == nil {
+		t.Fatal("expected the token to be consumed once")
+	}
+
+	if _, err := a.Authenticate(authContext(token)); err == nil {
+		t.Fatal("expected the token to be single-use")
+	}
+	secrets, err := client.CoreV1().Secrets("istio-system").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(secrets.Items), 0)
+}
+
+func TestAuthenticateExpired(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	a := NewAuthenticator(mockMeshConfigHolder{"cluster.local"}, client, "istio-system")
+	token, expiration, err := Mint(context.Background(), client, "istio-system", "bar", "vm", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.now = func() time.Time {
+		return expiration.Add(time.Second)
+	}
+	if _, err := a.Authenticate(authContext(token)); err == nil {
+		t.Fatal("expected the expired token to be rejected")
+	}
+	secrets, err := client.CoreV1().Secrets("istio-system").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(secrets.Items), 0)
+}
+
+func TestCleanupExpired(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	_, expiration, err := Mint(context.Background(), client, "istio-system", "bar", "vm", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := Mint(context.Background(), client, "istio-system", "bar", "vm", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := CleanupExpired(context.Background(), client, "istio-system", expiration.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	secrets, err := client.CoreV1().Secrets("istio-system").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(secrets.Items), 1)
+	expiration, err = time.Parse(time.RFC3339, string(secrets.Items[0].Data[expirationKey]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, expiration.After(time.Now().Add(30*time.Minute)), true)
+}
+
+func TestAuthenticateOtherTokens(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	a := NewAuthenticator(mockMeshConfigHolder{"cluster.local"}, client, "istio-system")
+	if _, err := a.Authenticate(authContext("eyJhbGciOiJSUzI1NiJ9.e30.sig")); err == nil {
+		t.Fatal("expected a JWT to be rejected")
+	}
+	if len(client.Actions()) != 0 {
+		t.Fatalf("expected no API call for a JWT, got %v", client.Actions())
+	}
+}
@@ -150,18 +150,15 @@ func (a *KubeJWTAuthenticator) authenticate(targetJWT string, clusterID cluster.
 		// is unbound and the setting to require bound tokens is off
 		aud = nil
 	}
-	id, err := tokenreview.ValidateK8sJwt(kubeClient, targetJWT, aud)
+	info, err := tokenreview.ReviewK8sJwt(kubeClient, targetJWT, aud)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate the JWT from cluster %q: %v", clusterID, err)
 	}
-	if len(id) != 2 {
-		return nil, fmt.Errorf("failed to parse the JWT. Validation result length is not 2, but %d", len(id))
-	}
-	callerNamespace := id[0]
-	callerServiceAccount := id[1]
 	return &security.Caller{
 		AuthSource: security.AuthSourceIDToken,
-		Identities: []string{fmt.Sprintf(authenticate.IdentityTemplate, a.meshHolder.Mesh().GetTrustDomain(), callerNamespace, callerServiceAccount)},
+		Identities: []string{fmt.Sprintf(authenticate.IdentityTemplate, a.meshHolder.Mesh().GetTrustDomain(),
+			info.PodNamespace, info.PodServiceAccount)},
+		KubernetesInfo: info,
 	}, nil
 }
 
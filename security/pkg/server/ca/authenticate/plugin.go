@@ -0,0 +1,96 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+
+	"istio.io/istio/pkg/cluster"
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/security"
+)
+
+// PluginOptions are the options passed to the authenticator plugins.
+type PluginOptions struct {
+	// MeshHolder holds the mesh config, for the trust domain.
+	MeshHolder mesh.Holder
+	// KubeClient is the client of the primary cluster, nil if istiod does not run on Kubernetes.
+	KubeClient kubernetes.Interface
+	// ClusterID is the ID of the primary cluster.
+	ClusterID cluster.ID
+	// Namespace is the namespace of istiod.
+	Namespace string
+}
+
+// PluginFactory creates an authenticator plugin.
+type PluginFactory func(opts PluginOptions) (security.Authenticator, error)
+
+var (
+	pluginsMu sync.RWMutex
+	plugins   = map[string]PluginFactory{}
+)
+
+// RegisterPlugin registers an authenticator plugin, to be enabled by name in istiod. Plugins are compiled into
+// istiod, registering themselves from an init function of their package, imported by the main package:
+// authenticators verifying cloud IAM signatures or custom tokens are added without changing the authentication
+// of istiod. RegisterPlugin panics if a plugin with the same name is already registered.
+func RegisterPlugin(name string, factory PluginFactory) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+	if _, f := plugins[name]; f {
+		panic(fmt.Sprintf("authenticator plugin %q registered twice", name))
+	}
+	plugins[name] = factory
+}
+
+// RegisteredPlugins returns the names of the registered authenticator plugins, sorted.
+func RegisteredPlugins() []string {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	return registeredPluginsLocked()
+}
+
+func registeredPluginsLocked() []string {
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewPlugins creates the authenticator plugins with the given names, in order. An error is returned if a plugin
+// is not registered or fails to be created.
+func NewPlugins(names []string, opts PluginOptions) ([]security.Authenticator, error) {
+	pluginsMu.RLock()
+	defer pluginsMu.RUnlock()
+	authenticators := make([]security.Authenticator, 0, len(names))
+	for _, name := range names {
+		factory, f := plugins[name]
+		if !f {
+			return nil, fmt.Errorf("unknown authenticator plugin %q, registered plugins are %v", name, registeredPluginsLocked())
+		}
+		a, err := factory(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the authenticator plugin %q: %v", name, err)
+		}
+		authenticators = append(authenticators, a)
+	}
+	return authenticators, nil
+}
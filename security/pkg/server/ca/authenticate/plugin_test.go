@@ -0,0 +1,85 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authenticate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/metadata"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/test/util/assert"
+)
+
+// headerAuthenticator authenticates the requests with an "x-identity" header, as a custom token verifier would.
+type headerAuthenticator struct {
+	namespace string
+}
+
+func (a headerAuthenticator) AuthenticatorType() string {
+	return "HeaderAuthenticator"
+}
+
+func (a headerAuthenticator) Authenticate(ctx security.AuthContext) (*security.Caller, error) {
+	md, _ := metadata.FromIncomingContext(ctx.GrpcContext)
+	if ids := md.Get("x-identity"); len(ids) == 1 {
+		return &security.Caller{Identities: []string{fmt.Sprintf(IdentityTemplate, "cluster.local", a.namespace, ids[0])}}, nil
+	}
+	return nil, fmt.Errorf("no identity header")
+}
+
+func TestPlugins(t *testing.T) {
+	RegisterPlugin("test-header", func(opts PluginOptions) (security.Authenticator, error) {
+		return headerAuthenticator{namespace: opts.Namespace}, nil
+	})
+	RegisterPlugin("test-broken", func(PluginOptions) (security.Authenticator, error) {
+		return nil, fmt.Errorf("missing credentials")
+	})
+
+	if _, err := NewPlugins([]string{"test-unknown"}, PluginOptions{}); err == nil {
+		t.Fatal("expected an error for an unknown plugin")
+	}
+	if _, err := NewPlugins([]string{"test-header", "test-broken"}, PluginOptions{}); err == nil {
+		t.Fatal("expected an error for a plugin failing to be created")
+	}
+
+	plugins, err := NewPlugins([]string{"test-header"}, PluginOptions{Namespace: "istio-system"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The plugins are chained after the built-in authenticators.
+	am := security.AuthenticationManager{Authenticators: append([]security.Authenticator{&ClientCertAuthenticator{}}, plugins...)}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{"x-identity": []string{"vm"}})
+	caller := am.Authenticate(ctx)
+	if caller == nil {
+		t.Fatalf("expected the request to be authenticated: %v", am.FailedMessages())
+	}
+	assert.Equal(t, caller.Identities, []string{"spiffe://cluster.local/ns/istio-system/sa/vm"})
+}
+
+func TestRegisterPluginTwice(t *testing.T) {
+	factory := func(PluginOptions) (security.Authenticator, error) {
+		return headerAuthenticator{}, nil
+	}
+	RegisterPlugin("test-twice", factory)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected registering a plugin twice to panic")
+		}
+	}()
+	RegisterPlugin("test-twice", factory)
+}
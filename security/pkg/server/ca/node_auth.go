@@ -0,0 +1,97 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+
+	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/util/sets"
+)
+
+// NodeAuthorizer authorizes the node proxies to request certificates on behalf of the host network pods of their
+// node. Host network pods cannot run a sidecar capturing their traffic, so a single proxy per node, running with
+// a trusted service account, holds their identities instead.
+type NodeAuthorizer struct {
+	trustedNodeAccounts sets.Set[types.NamespacedName]
+	pods                listerv1.PodLister
+}
+
+// NewNodeAuthorizer creates a NodeAuthorizer trusting the node proxies running with the service accounts.
+func NewNodeAuthorizer(trustedNodeAccounts []types.NamespacedName, pods listerv1.PodLister) *NodeAuthorizer {
+	return &NodeAuthorizer{
+		trustedNodeAccounts: sets.New(trustedNodeAccounts...),
+		pods:                pods,
+	}
+}
+
+// authorizeImpersonation returns an error unless the caller is a node proxy running with a trusted service
+// account, and a host network pod running with the impersonated identity is scheduled on its node.
+func (na *NodeAuthorizer) authorizeImpersonation(c *security.Caller, impersonated string) error {
+	caller := c.KubernetesInfo
+	account := types.NamespacedName{Namespace: caller.PodNamespace, Name: caller.PodServiceAccount}
+	if !na.trustedNodeAccounts.Contains(account) {
+		return fmt.Errorf("service account %s is not trusted to impersonate identities", account)
+	}
+	if caller.PodName == "" || caller.PodUID == "" {
+		return fmt.Errorf("the token of %s is not bound to a pod", account)
+	}
+	callerPod, err := na.pods.Pods(caller.PodNamespace).Get(caller.PodName)
+	if err != nil {
+		return fmt.Errorf("failed to find the pod of the caller: %v", err)
+	}
+	if string(callerPod.UID) != caller.PodUID {
+		return fmt.Errorf("the pod the token of %s is bound to no longer exists", account)
+	}
+	node := callerPod.Spec.NodeName
+	if node == "" {
+		return fmt.Errorf("pod %s/%s is not scheduled", callerPod.Namespace, callerPod.Name)
+	}
+
+	id, err := spiffe.ParseIdentity(impersonated)
+	if err != nil {
+		return fmt.Errorf("invalid impersonated identity %q: %v", impersonated, err)
+	}
+	if len(c.Identities) == 0 {
+		return fmt.Errorf("the caller has no identity")
+	}
+	if callerID, err := spiffe.ParseIdentity(c.Identities[0]); err != nil || callerID.TrustDomain != id.TrustDomain {
+		return fmt.Errorf("impersonated identity %q is not in the trust domain of the caller", impersonated)
+	}
+	pods, err := na.pods.Pods(id.Namespace).List(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if pod.Spec.HostNetwork && pod.Spec.NodeName == node && serviceAccount(pod) == id.ServiceAccount &&
+			pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			return nil
+		}
+	}
+	return fmt.Errorf("no host network pod of node %s runs as %s", node, impersonated)
+}
+
+func serviceAccount(pod *v1.Pod) string {
+	if pod.Spec.ServiceAccountName == "" {
+		return "default"
+	}
+	return pod.Spec.ServiceAccountName
+}
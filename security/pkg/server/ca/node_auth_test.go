@@ -0,0 +1,153 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ca
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	listerv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+
+	pb "istio.io/api/security/v1alpha1"
+	"istio.io/istio/pkg/security"
+	mockca "istio.io/istio/security/pkg/pki/ca/mock"
+	"istio.io/istio/security/pkg/pki/util"
+)
+
+func podLister(t *testing.T, pods ...*v1.Pod) listerv1.PodLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, pod := range pods {
+		if err := indexer.Add(pod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return listerv1.NewPodLister(indexer)
+}
+
+func testPod(ns, name, node, sa string, hostNetwork bool) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, UID: types.UID(name + "-uid")},
+		Spec:       v1.PodSpec{NodeName: node, ServiceAccountName: sa, HostNetwork: hostNetwork},
+		Status:     v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+func nodeProxyCaller(pod string) *security.Caller {
+	return &security.Caller{
+		Identities: []string{"spiffe://cluster.local/ns/istio-system/sa/istio-node-proxy"},
+		KubernetesInfo: security.KubernetesInfo{
+			PodName:           pod,
+			PodNamespace:      "istio-system",
+			PodUID:            pod + "-uid",
+			PodServiceAccount: "istio-node-proxy",
+		},
+	}
+}
+
+func TestNodeAuthorizer(t *testing.T) {
+	na := NewNodeAuthorizer([]types.NamespacedName{{Namespace: "istio-system", Name: "istio-node-proxy"}}, podLister(t,
+		testPod("istio-system", "node-proxy-a", "node-a", "istio-node-proxy", true),
+		testPod("istio-system", "node-proxy-b", "node-b", "istio-node-proxy", true),
+		testPod("monitoring", "node-exporter-a", "node-a", "node-exporter", true),
+		testPod("default", "app-a", "node-a", "app", false),
+	))
+	untrusted := nodeProxyCaller("node-proxy-a")
+	untrusted.KubernetesInfo.PodServiceAccount = "other"
+	unbound := nodeProxyCaller("node-proxy-a")
+	unbound.KubernetesInfo.PodName = ""
+	stale := nodeProxyCaller("node-proxy-a")
+	stale.KubernetesInfo.PodUID = "old-uid"
+
+	cases := []struct {
+		name         string
+		caller       *security.Caller
+		impersonated string
+		allowed      bool
+	}{
+		{"host network pod of the node", nodeProxyCaller("node-proxy-a"), "spiffe://cluster.local/ns/monitoring/sa/node-exporter", true},
+		{"host network pod of another node", nodeProxyCaller("node-proxy-b"), "spiffe://cluster.local/ns/monitoring/sa/node-exporter", false},
+		{"pod network pod", nodeProxyCaller("node-proxy-a"), "spiffe://cluster.local/ns/default/sa/app", false},
+		{"other trust domain", nodeProxyCaller("node-proxy-a"), "spiffe://example.com/ns/monitoring/sa/node-exporter", false},
+		{"untrusted account", untrusted, "spiffe://cluster.local/ns/monitoring/sa/node-exporter", false},
+		{"unbound token", unbound, "spiffe://cluster.local/ns/monitoring/sa/node-exporter", false},
+		{"deleted caller pod", stale, "spiffe://cluster.local/ns/monitoring/sa/node-exporter", false},
+		{"invalid identity", nodeProxyCaller("node-proxy-a"), "node-exporter", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := na.authorizeImpersonation(c.caller, c.impersonated)
+			if c.allowed && err != nil {
+				t.Fatalf("expected the impersonation to be allowed: %v", err)
+			}
+			if !c.allowed && err == nil {
+				t.Fatal("expected the impersonation to be denied")
+			}
+		})
+	}
+}
+
+type callerAuthenticator struct {
+	caller *security.Caller
+}
+
+func (a callerAuthenticator) AuthenticatorType() string {
+	return "callerAuthenticator"
+}
+
+func (a callerAuthenticator) Authenticate(security.AuthContext) (*security.Caller, error) {
+	return a.caller, nil
+}
+
+func TestCreateCertificateImpersonation(t *testing.T) {
+	impersonated := "spiffe://cluster.local/ns/monitoring/sa/node-exporter"
+	request := &pb.IstioCertificateRequest{
+		Csr: "dumb CSR",
+		Metadata: &structpb.Struct{Fields: map[string]*structpb.Value{
+			security.ImpersonatedIdentity: structpb.NewStringValue(impersonated),
+		}},
+	}
+	fakeCA := &mockca.FakeCA{
+		SignedCert:    []byte("cert"),
+		KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte("cert_chain"), []byte("root_cert")),
+	}
+	server := &Server{
+		ca:             fakeCA,
+		Authenticators: []security.Authenticator{callerAuthenticator{nodeProxyCaller("node-proxy-a")}},
+		monitoring:     newMonitoringMetrics(),
+	}
+	_, err := server.CreateCertificate(context.Background(), request)
+	if s, _ := status.FromError(err); s.Code() != codes.PermissionDenied {
+		t.Fatalf("expected the impersonation to be denied without node authorizer, got %v", err)
+	}
+
+	server.NodeAuthorizer = NewNodeAuthorizer([]types.NamespacedName{{Namespace: "istio-system", Name: "istio-node-proxy"}}, podLister(t,
+		testPod("istio-system", "node-proxy-a", "node-a", "istio-node-proxy", true),
+		testPod("monitoring", "node-exporter-a", "node-a", "node-exporter", true),
+	))
+	if _, err := server.CreateCertificate(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fakeCA.ReceivedIDs, []string{impersonated}) {
+		t.Fatalf("expected a certificate for the impersonated identity, got %v", fakeCA.ReceivedIDs)
+	}
+}
@@ -15,6 +15,8 @@
 package ca
 
 import (
+	"crypto/x509"
+	"fmt"
 	"time"
 
 	"golang.org/x/net/context"
@@ -24,6 +26,8 @@ import (
 
 	pb "istio.io/api/security/v1alpha1"
 	"istio.io/istio/pkg/security"
+	"istio.io/istio/pkg/spiffe"
+	"istio.io/istio/pkg/util/sets"
 	"istio.io/istio/security/pkg/pki/ca"
 	caerror "istio.io/istio/security/pkg/pki/error"
 	"istio.io/istio/security/pkg/pki/util"
@@ -50,6 +54,17 @@ type Server struct {
 	Authenticators []security.Authenticator
 	ca             CertificateAuthority
 	serverCertTTL  time.Duration
+
+	// RequireECDSAKeys rejects CSRs for keys other than ECDSA keys, enforcing ECDSA-only workload identity.
+	RequireECDSAKeys bool
+
+	// TransitionTrustDomains returns the trust domains the mesh is migrating from. Workload certificates also carry
+	// the identities of the callers in these trust domains, so that peers still expecting them accept the certificates.
+	TransitionTrustDomains func() []string
+
+	// NodeAuthorizer authorizes the node proxies to request certificates for the host network pods of their node.
+	// Without it, certificates are only issued for the identities of the callers.
+	NodeAuthorizer *NodeAuthorizer
 }
 
 // CreateCertificate handles an incoming certificate signing request (CSR). It does
@@ -72,9 +87,28 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	crMetadata := request.Metadata.GetFields()
 	certSigner := crMetadata[security.CertSigner].GetStringValue()
 	log.Debugf("cert signer from workload %s", certSigner)
+	if s.RequireECDSAKeys {
+		if err := checkECDSAKey(request.Csr); err != nil {
+			s.monitoring.CSRError.Increment()
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	identities := caller.Identities
+	if impersonated := crMetadata[security.ImpersonatedIdentity].GetStringValue(); impersonated != "" {
+		if s.NodeAuthorizer == nil {
+			s.monitoring.AuthnError.Increment()
+			return nil, status.Error(codes.PermissionDenied, "impersonation is not allowed")
+		}
+		if err := s.NodeAuthorizer.authorizeImpersonation(caller, impersonated); err != nil {
+			serverCaLog.Warnf("impersonation of %s by %v denied: %v", impersonated, caller.Identities, err)
+			s.monitoring.AuthnError.Increment()
+			return nil, status.Error(codes.PermissionDenied, "impersonation is not allowed")
+		}
+		identities = []string{impersonated}
+	}
 	_, _, certChainBytes, rootCertBytes := s.ca.GetCAKeyCertBundle().GetAll()
 	certOpts := ca.CertOpts{
-		SubjectIDs: caller.Identities,
+		SubjectIDs: s.subjectIDs(identities),
 		TTL:        time.Duration(request.ValidityDuration) * time.Second,
 		ForCA:      false,
 		CertSigner: certSigner,
@@ -101,6 +135,15 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	if len(rootCertBytes) != 0 {
 		respCertChain = append(respCertChain, string(rootCertBytes))
 	}
+	// Single-use credentials are only consumed once the certificate is issued, so that a failure to issue it
+	// does not lose the credential, and only one of the concurrent requests with the credential gets a certificate.
+	if caller.Consume != nil {
+		if err := caller.Consume(); err != nil {
+			serverCaLog.Warnf("credential of %v already used: %v", caller.Identities, err)
+			s.monitoring.AuthnError.Increment()
+			return nil, status.Error(codes.Unauthenticated, "request authenticate failure")
+		}
+	}
 	response := &pb.IstioCertificateResponse{
 		CertChain: respCertChain,
 	}
@@ -109,6 +152,45 @@ func (s *Server) CreateCertificate(ctx context.Context, request *pb.IstioCertifi
 	return response, nil
 }
 
+// subjectIDs returns the identities of the caller, followed by their SPIFFE identities in the trust domains in
+// transition.
+func (s *Server) subjectIDs(identities []string) []string {
+	if s.TransitionTrustDomains == nil {
+		return identities
+	}
+	trustDomains := s.TransitionTrustDomains()
+	if len(trustDomains) == 0 {
+		return identities
+	}
+	out := append([]string{}, identities...)
+	known := sets.New(identities...)
+	for _, id := range identities {
+		parsed, err := spiffe.ParseIdentity(id)
+		if err != nil {
+			continue
+		}
+		for _, td := range trustDomains {
+			parsed.TrustDomain = td
+			if alias := parsed.String(); !known.InsertContains(alias) {
+				out = append(out, alias)
+			}
+		}
+	}
+	return out
+}
+
+// checkECDSAKey returns an error if the CSR is not for an ECDSA key.
+func checkECDSAKey(csrPEM string) error {
+	csr, err := util.ParsePemEncodedCSR([]byte(csrPEM))
+	if err != nil {
+		return fmt.Errorf("failed to parse CSR: %v", err)
+	}
+	if csr.PublicKeyAlgorithm != x509.ECDSA {
+		return fmt.Errorf("only ECDSA workload certificates are allowed, got a CSR for a %v key", csr.PublicKeyAlgorithm)
+	}
+	return nil
+}
+
 func recordCertsExpiry(keyCertBundle *util.KeyCertBundle) {
 	rootCertExpiry, err := keyCertBundle.ExtractRootCertExpiryTimestamp()
 	if err != nil {
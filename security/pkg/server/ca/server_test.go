@@ -20,6 +20,7 @@ import (
 	"crypto/x509/pkix"
 	"fmt"
 	"net"
+	"reflect"
 	"testing"
 
 	"golang.org/x/net/context"
@@ -40,6 +41,7 @@ type mockAuthenticator struct {
 	authSource security.AuthSource
 	identities []string
 	errMsg     string
+	consume    func() error
 }
 
 func (authn *mockAuthenticator) AuthenticatorType() string {
@@ -54,6 +56,7 @@ func (authn *mockAuthenticator) Authenticate(_ security.AuthContext) (*security.
 	return &security.Caller{
 		AuthSource: authn.authSource,
 		Identities: authn.identities,
+		Consume:    authn.consume,
 	}, nil
 }
 
@@ -258,3 +261,88 @@ func TestCreateCertificate(t *testing.T) {
 		}
 	}
 }
+
+func TestCreateCertificateConsumesCredential(t *testing.T) {
+	consumed := 0
+	authn := &mockAuthenticator{identities: []string{"test-identity"}, consume: func() error {
+		consumed++
+		if consumed > 1 {
+			return fmt.Errorf("already used")
+		}
+		return nil
+	}}
+	server := &Server{
+		ca:             &mockca.FakeCA{SignErr: caerror.NewError(caerror.CANotReady, fmt.Errorf("cannot sign"))},
+		Authenticators: []security.Authenticator{authn},
+		monitoring:     newMonitoringMetrics(),
+	}
+	request := &pb.IstioCertificateRequest{Csr: "dumb CSR"}
+	if _, err := server.CreateCertificate(context.Background(), request); err == nil || consumed != 0 {
+		t.Fatalf("expected the credential not to be consumed when the signing fails, got %v, consumed %d", err, consumed)
+	}
+
+	server.ca = &mockca.FakeCA{
+		SignedCert:    []byte("cert"),
+		KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte("cert_chain"), []byte("root_cert")),
+	}
+	if _, err := server.CreateCertificate(context.Background(), request); err != nil || consumed != 1 {
+		t.Fatalf("expected the credential to be consumed, got %v, consumed %d", err, consumed)
+	}
+	_, err := server.CreateCertificate(context.Background(), request)
+	if s, _ := status.FromError(err); s.Code() != codes.Unauthenticated {
+		t.Fatalf("expected a consumed credential to be rejected, got %v", err)
+	}
+}
+
+func TestCreateCertificateECDSAOnly(t *testing.T) {
+	rsaCSR, _, err := util.GenCSR(util.CertOptions{Host: "spiffe://cluster.local/ns/default/sa/default", RSAKeySize: 2048})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaCSR, _, err := util.GenCSR(util.CertOptions{Host: "spiffe://cluster.local/ns/default/sa/default", ECSigAlg: util.EcdsaSigAlg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := map[string]struct {
+		csr  []byte
+		code codes.Code
+	}{
+		"RSA key":   {csr: rsaCSR, code: codes.InvalidArgument},
+		"ECDSA key": {csr: ecdsaCSR, code: codes.OK},
+	}
+	for id, c := range testCases {
+		server := &Server{
+			ca: &mockca.FakeCA{
+				SignedCert:    []byte("cert"),
+				KeyCertBundle: util.NewKeyCertBundleFromPem(nil, nil, []byte("cert_chain"), []byte("root_cert")),
+			},
+			Authenticators:   []security.Authenticator{&mockAuthenticator{identities: []string{"test-identity"}}},
+			monitoring:       newMonitoringMetrics(),
+			RequireECDSAKeys: true,
+		}
+		_, err := server.CreateCertificate(context.Background(), &pb.IstioCertificateRequest{Csr: string(c.csr)})
+		s, _ := status.FromError(err)
+		if code := s.Code(); code != c.code {
+			t.Errorf("Case %s: expecting code to be (%d) but got (%d): %s", id, c.code, code, s.Message())
+		}
+	}
+}
+
+func TestSubjectIDsWithTransitionTrustDomains(t *testing.T) {
+	identities := []string{"spiffe://new.example.com/ns/default/sa/default", "test-identity"}
+	server := &Server{}
+	if got := server.subjectIDs(identities); !reflect.DeepEqual(got, identities) {
+		t.Fatalf("expected the caller identities without transitions, got %v", got)
+	}
+	server.TransitionTrustDomains = func() []string {
+		return []string{"old.example.com", "new.example.com"}
+	}
+	want := []string{
+		"spiffe://new.example.com/ns/default/sa/default",
+		"test-identity",
+		"spiffe://old.example.com/ns/default/sa/default",
+	}
+	if got := server.subjectIDs(identities); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
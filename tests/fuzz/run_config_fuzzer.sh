@@ -0,0 +1,35 @@
+#!/bin/bash
+
+# Copyright Istio Authors
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#    http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Runs the config corpus fuzzer continuously, in rounds of FUZZ_TIME. The minimized failing configs are written to
+# FUZZ_ARTIFACT_DIR, and the inputs found by the Go fuzzer are kept in its testdata directory.
+# Set ENVOY_VALIDATE_BINARY to the path of an Envoy binary to validate the generated configs with it.
+
+set -o nounset
+set -o pipefail
+
+ROOT="$(cd -P "$(dirname "${BASH_SOURCE[0]}")/../.." && pwd)"
+FUZZ_TIME="${FUZZ_TIME:-10m}"
+FUZZ_ROUNDS="${FUZZ_ROUNDS:-0}"
+export FUZZ_ARTIFACT_DIR="${FUZZ_ARTIFACT_DIR:-${ROOT}/out/fuzz-artifacts}"
+
+round=0
+while [[ "${FUZZ_ROUNDS}" == 0 || "${round}" -lt "${FUZZ_ROUNDS}" ]]; do
+  round=$((round + 1))
+  echo "config fuzzing round ${round}, artifacts in ${FUZZ_ARTIFACT_DIR}"
+  (cd "${ROOT}" && go test ./pilot/pkg/networking/core/v1alpha3/ -run '^$' \
+    -fuzz '^FuzzTranslateConfigCorpus$' -fuzztime "${FUZZ_TIME}") || echo "round ${round} found a failure"
+done
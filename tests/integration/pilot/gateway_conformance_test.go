@@ -34,8 +34,11 @@ package pilot
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	apiextensions "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -47,6 +50,7 @@ import (
 	"istio.io/istio/pkg/test/framework"
 	"istio.io/istio/pkg/test/framework/components/namespace"
 	"istio.io/istio/pkg/test/framework/resource/config/apply"
+	"istio.io/istio/pkg/test/gatewayconformance"
 	"istio.io/istio/pkg/test/scopes"
 	"istio.io/istio/pkg/test/util/retry"
 )
@@ -131,17 +135,51 @@ func TestGatewayConformance(t *testing.T) {
 			csuite := suite.New(opts)
 			csuite.Setup(t)
 
-			for _, ct := range tests.ConformanceTests {
-				t.Run(ct.ShortName, func(t *testing.T) {
-					if reason, f := skippedTests[ct.ShortName]; f {
-						t.Skip(reason)
+			recorder := gatewayconformance.NewRecorder(opts.SupportedFeatures)
+			for _, profile := range gatewayconformance.Profiles {
+				profile := profile
+				t.Run(profile.Name, func(t *testing.T) {
+					for _, ct := range tests.ConformanceTests {
+						if !profile.Matches(ct) {
+							continue
+						}
+						ct := ct
+						t.Run(ct.ShortName, func(t *testing.T) {
+							defer func() {
+								recorder.Record(profile, ct, gatewayconformance.ResultOf(t))
+							}()
+							if reason, f := skippedTests[ct.ShortName]; f {
+								t.Skip(reason)
+							}
+							ct.Run(t, csuite)
+						})
 					}
-					ct.Run(t, csuite)
 				})
 			}
+			writeConformanceReport(ctx, recorder)
 		})
 }
 
+// writeConformanceReport writes the conformance report to the path of GATEWAY_CONFORMANCE_REPORT, or to the work
+// directory of the test, so that the CI keeps it with the other artifacts.
+func writeConformanceReport(ctx framework.TestContext, recorder *gatewayconformance.Recorder) {
+	report := recorder.Report(gatewayconformance.Implementation{
+		Organization: "istio",
+		Project:      "istio",
+		URL:          "https://istio.io/",
+		Version:      ctx.Settings().Image.Tag,
+		Contact:      []string{"@istio/maintainers"},
+	}, gatewayconformance.Profiles, time.Now())
+	path := os.Getenv(gatewayconformance.ReportPathEnv)
+	if path == "" {
+		path = filepath.Join(ctx.CreateDirectoryOrFail("gateway-conformance"), "report.yaml")
+	}
+	if err := report.Write(path); err != nil {
+		ctx.Fatalf("failed to write the conformance report: %v", err)
+	}
+	scopes.Framework.Infof("gateway conformance (%s) written to %s", report, path)
+}
+
 func DeployGatewayAPICRD(ctx framework.TestContext) {
 	if !supportsGatewayAPI(ctx) {
 		ctx.Skip("Not supported; requires CRDv1 support.")
@@ -104,6 +104,15 @@ test.integration-fuzz.%.kube: | $(JUNIT_REPORT) check-go-tag
 	${_INTEGRATION_TEST_FLAGS} ${_INTEGRATION_TEST_SELECT_FLAGS} \
 	2>&1 | tee >($(JUNIT_REPORT) > $(JUNIT_OUT))
 
+# Runs the Gateway API conformance suite, and writes the conformance report of each profile to
+# $(GATEWAY_CONFORMANCE_REPORT), or to the work directory of the test if it is not set.
+.PHONY: test.integration.gateway-conformance
+test.integration.gateway-conformance: | $(JUNIT_REPORT) check-go-tag
+	GATEWAY_CONFORMANCE_REPORT=$(GATEWAY_CONFORMANCE_REPORT) $(RUN_TEST) ./tests/integration/pilot -timeout 30m \
+	${_INTEGRATION_TEST_FLAGS} \
+	--test.run="TestGatewayConformance" \
+	2>&1 | tee >($(JUNIT_REPORT) > $(JUNIT_OUT))
+
 # Generate presubmit integration test targets for each component in kubernetes environment
 test.integration.%.kube.presubmit:
 	@make test.integration.$*.kube
@@ -15,6 +15,7 @@
 package cmd
 
 import (
+	"bytes"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -134,6 +135,11 @@ func (s *DependenciesStub) RunQuietlyAndIgnore(cmd string, args ...string) {
 	s.execute(true /*quietly*/, cmd, args...)
 }
 
+func (s *DependenciesStub) RunWithOutput(cmd string, args ...string) (*bytes.Buffer, error) {
+	s.execute(false /*quietly*/, cmd, args...)
+	return &bytes.Buffer{}, nil
+}
+
 func (s *DependenciesStub) execute(quietly bool, cmd string, args ...string) {
 	cmdline := strings.Join(append([]string{cmd}, args...), " ")
 	s.ExecutedAll = append(s.ExecutedAll, cmdline)
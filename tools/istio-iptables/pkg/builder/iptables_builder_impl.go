@@ -16,6 +16,7 @@ package builder
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"istio.io/istio/pkg/util/sets"
@@ -205,7 +206,9 @@ func (rb *IptablesBuilder) buildRestore(rules []*Rule) string {
 		if !chainTableLookupMap.Contains(chainTable) {
 			// Ignore chain creation for built-in chains for iptables
 			if _, present := constants.BuiltInChainsMap[r.chain]; !present {
-				tableRulesMap[r.table] = append(tableRulesMap[r.table], fmt.Sprintf("-N %s", r.chain))
+				// Declaring the chain creates it, or flushes it if it already exists, so that applying
+				// the same ruleset again replaces any rules that were modified outside of Istio.
+				tableRulesMap[r.table] = append(tableRulesMap[r.table], fmt.Sprintf(":%s - [0:0]", r.chain))
 				chainTableLookupMap.Insert(chainTable)
 			}
 		}
@@ -225,6 +228,97 @@ func (rb *IptablesBuilder) BuildV6Restore() string {
 	return rb.buildRestore(rb.rules.rulesv6)
 }
 
+// ruleArgs returns the rule specification of r, without the operation, chain and position.
+func ruleArgs(r *Rule) []string {
+	args := r.params[2:]
+	if r.params[0] == "-I" {
+		args = args[1:]
+	}
+	return args
+}
+
+func (rb *IptablesBuilder) buildCheck(command string, rules []*Rule) [][]string {
+	output := make([][]string, 0, len(rules))
+	for _, r := range rules {
+		output = append(output, append([]string{command, "-t", r.table, "-C", r.chain}, ruleArgs(r)...))
+	}
+	return output
+}
+
+// BuildV4Check returns a command checking the presence of each IPv4 rule.
+func (rb *IptablesBuilder) BuildV4Check() [][]string {
+	return rb.buildCheck(constants.IPTABLES, rb.rules.rulesv4)
+}
+
+// BuildV6Check returns a command checking the presence of each IPv6 rule.
+func (rb *IptablesBuilder) BuildV6Check() [][]string {
+	return rb.buildCheck(constants.IP6TABLES, rb.rules.rulesv6)
+}
+
+// ruleTarget returns the target of the rule specification args, or an empty string if it has none.
+func ruleTarget(args []string) string {
+	if idx := indexOf("-j", args); idx >= 0 && idx+1 < len(args) {
+		return args[idx+1]
+	}
+	return ""
+}
+
+// buildTargets returns the targets of the rules of each chain of each table, in the order the rules are applied:
+// appended rules are added at the end of the chain and inserted rules at their position.
+func (rb *IptablesBuilder) buildTargets(rules []*Rule) map[string]map[string][]string {
+	output := map[string]map[string][]string{}
+	for _, r := range rules {
+		if output[r.table] == nil {
+			output[r.table] = map[string][]string{}
+		}
+		targets := output[r.table][r.chain]
+		pos := len(targets)
+		if r.params[0] == "-I" {
+			if p, err := strconv.Atoi(r.params[2]); err == nil && p >= 1 && p-1 < pos {
+				pos = p - 1
+			}
+		}
+		targets = append(targets, "")
+		copy(targets[pos+1:], targets[pos:])
+		targets[pos] = ruleTarget(ruleArgs(r))
+		output[r.table][r.chain] = targets
+	}
+	return output
+}
+
+// BuildV4Targets returns the targets of the IPv4 rules of each chain of each table, in the order they are applied.
+func (rb *IptablesBuilder) BuildV4Targets() map[string]map[string][]string {
+	return rb.buildTargets(rb.rules.rulesv4)
+}
+
+// BuildV6Targets returns the targets of the IPv6 rules of each chain of each table, in the order they are applied.
+func (rb *IptablesBuilder) BuildV6Targets() map[string]map[string][]string {
+	return rb.buildTargets(rb.rules.rulesv6)
+}
+
+func (rb *IptablesBuilder) buildDelete(command string, rules []*Rule) [][]string {
+	output := make([][]string, 0)
+	for _, r := range rules {
+		if _, present := constants.BuiltInChainsMap[r.chain]; !present {
+			continue
+		}
+		output = append(output, append([]string{command, "-t", r.table, "-D", r.chain}, ruleArgs(r)...))
+	}
+	return output
+}
+
+// BuildV4Delete returns a command deleting each IPv4 rule added to a built-in chain.
+// Rules in Istio chains are not included, as restoring the chain replaces them.
+func (rb *IptablesBuilder) BuildV4Delete() [][]string {
+	return rb.buildDelete(constants.IPTABLES, rb.rules.rulesv4)
+}
+
+// BuildV6Delete returns a command deleting each IPv6 rule added to a built-in chain.
+// Rules in Istio chains are not included, as restoring the chain replaces them.
+func (rb *IptablesBuilder) BuildV6Delete() [][]string {
+	return rb.buildDelete(constants.IP6TABLES, rb.rules.rulesv6)
+}
+
 // AppendVersionedRule is a wrapper around AppendRule that substitutes an ipv4/ipv6 specific value
 // in place in the params. This allows appending a dual-stack rule that has an IP value in it.
 func (rb *IptablesBuilder) AppendVersionedRule(ipv4 string, ipv6 string, command log.Command, chain string, table string, params ...string) {
@@ -312,3 +312,58 @@ func TestBuildV4V6MultipleRulesWithNewChain(t *testing.T) {
 		t.Errorf("Actual and expected output mismatch; but instead got Actual: %#v ; Expected: %#v", actualV6, expectedV6)
 	}
 }
+
+func TestBuildV4RestoreDeclaresChains(t *testing.T) {
+	iptables := NewIptablesBuilder(nil)
+	iptables.AppendRuleV4(iptableslog.UndefinedCommand, "chain", constants.NAT, "-f", "foo", "-b", "bar")
+	iptables.AppendRuleV4(iptableslog.UndefinedCommand, constants.PREROUTING, constants.NAT, "-j", "chain")
+	expected := "* nat\n:chain - [0:0]\n-A chain -f foo -b bar\n-A PREROUTING -j chain\nCOMMIT\n"
+	if actual := iptables.BuildV4Restore(); actual != expected {
+		t.Errorf("Output didn't match: Got: %s, Expected: %s", actual, expected)
+	}
+}
+
+func TestBuildV4CheckAndDelete(t *testing.T) {
+	iptables := NewIptablesBuilder(nil)
+	iptables.AppendRuleV4(iptableslog.UndefinedCommand, "chain", "table", "-f", "foo", "-b", "bar")
+	iptables.InsertRuleV4(iptableslog.UndefinedCommand, "chain", "table", 2, "-f", "foo", "-b", "baz")
+	iptables.InsertRuleV4(iptableslog.UndefinedCommand, constants.PREROUTING, constants.NAT, 1, "-j", "chain")
+	expectedCheck := [][]string{
+		{"iptables", "-t", "table", "-C", "chain", "-f", "foo", "-b", "bar"},
+		{"iptables", "-t", "table", "-C", "chain", "-f", "foo", "-b", "baz"},
+		{"iptables", "-t", "nat", "-C", "PREROUTING", "-j", "chain"},
+	}
+	if actual := iptables.BuildV4Check(); !reflect.DeepEqual(actual, expectedCheck) {
+		t.Errorf("Actual and expected output mismatch; but instead got Actual: %#v ; Expected: %#v", actual, expectedCheck)
+	}
+	expectedDelete := [][]string{
+		{"iptables", "-t", "nat", "-D", "PREROUTING", "-j", "chain"},
+	}
+	if actual := iptables.BuildV4Delete(); !reflect.DeepEqual(actual, expectedDelete) {
+		t.Errorf("Actual and expected output mismatch; but instead got Actual: %#v ; Expected: %#v", actual, expectedDelete)
+	}
+	expectedTargets := map[string]map[string][]string{
+		"table":       {"chain": {"", ""}},
+		constants.NAT: {constants.PREROUTING: {"chain"}},
+	}
+	if actual := iptables.BuildV4Targets(); !reflect.DeepEqual(actual, expectedTargets) {
+		t.Errorf("Actual and expected output mismatch; but instead got Actual: %#v ; Expected: %#v", actual, expectedTargets)
+	}
+	if actual := iptables.BuildV6Check(); len(actual) != 0 {
+		t.Errorf("Expected V6 rules to be empty; but instead got Actual: %#v", actual)
+	}
+}
+
+func TestBuildV4TargetsOrder(t *testing.T) {
+	iptables := NewIptablesBuilder(nil)
+	iptables.AppendRuleV4(iptableslog.UndefinedCommand, "chain", constants.NAT, "-p", "tcp", "-j", "first")
+	iptables.AppendRuleV4(iptableslog.UndefinedCommand, "chain", constants.NAT, "-p", "tcp", "-j", "last")
+	iptables.InsertRuleV4(iptableslog.UndefinedCommand, "chain", constants.NAT, 2, "-p", "tcp", "-j", "second")
+	iptables.InsertRuleV4(iptableslog.UndefinedCommand, "chain", constants.NAT, 1, "-p", "tcp", "-j", "zeroth")
+	expected := map[string]map[string][]string{
+		constants.NAT: {"chain": {"zeroth", "first", "second", "last"}},
+	}
+	if actual := iptables.BuildV4Targets(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Actual and expected output mismatch; but instead got Actual: %#v ; Expected: %#v", actual, expected)
+	}
+}
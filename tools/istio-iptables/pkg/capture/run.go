@@ -19,10 +19,13 @@ import (
 	"net"
 	"net/netip"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/vishvananda/netlink"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 
 	"istio.io/istio/tools/istio-iptables/pkg/builder"
 	"istio.io/istio/tools/istio-iptables/pkg/config"
@@ -289,6 +292,88 @@ func (cfg *IptablesConfigurator) Run() {
 		}
 	}()
 
+	cfg.buildRules()
+	cfg.executeCommands()
+}
+
+// Check builds the desired rules and verifies that they are applied, without modifying them. Each rule is checked
+// with `iptables -C`, and the order of the rules, as reported by iptables-save, is compared with the desired order.
+// It returns the drift found, which means rules were removed, modified or added outside of Istio.
+func (cfg *IptablesConfigurator) Check() []string {
+	cfg.buildRules()
+	var drift []string
+	for _, cmd := range append(cfg.iptables.BuildV4Check(), cfg.iptables.BuildV6Check()...) {
+		if err := cfg.ext.Run(cmd[0], cmd[1:]...); err != nil {
+			drift = append(drift, "missing or modified rule: "+strings.Join(cmd, " "))
+		}
+	}
+	drift = append(drift, cfg.checkOrder(constants.IPTABLESSAVE, cfg.iptables.BuildV4Targets())...)
+	drift = append(drift, cfg.checkOrder(constants.IP6TABLESSAVE, cfg.iptables.BuildV6Targets())...)
+	return drift
+}
+
+// checkOrder compares the rules applied, as reported by the save command, with the desired targets of each chain.
+// The rules are compared by target, as iptables-save normalizes the matches, which the `iptables -C` checks cover.
+// The Istio chains must contain exactly the desired rules, while the built-in chains must start with them, so that
+// no rule added before the jumps to the Istio chains bypasses them.
+func (cfg *IptablesConfigurator) checkOrder(save string, desired map[string]map[string][]string) []string {
+	if len(desired) == 0 {
+		return nil
+	}
+	out, err := cfg.ext.RunWithOutput(save)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to run %s: %v", save, err)}
+	}
+	applied := parseSavedTargets(out.String())
+	var drift []string
+	tables := maps.Keys(desired)
+	sort.Strings(tables)
+	for _, table := range tables {
+		chains := maps.Keys(desired[table])
+		sort.Strings(chains)
+		for _, chain := range chains {
+			want, got := desired[table][chain], applied[table][chain]
+			if _, builtin := constants.BuiltInChainsMap[chain]; builtin {
+				if len(got) < len(want) || !slices.Equal(got[:len(want)], want) {
+					drift = append(drift, fmt.Sprintf("chain %s of table %s does not start with the Istio rules: want targets %v, got %v",
+						chain, table, want, got))
+				}
+			} else if !slices.Equal(got, want) {
+				drift = append(drift, fmt.Sprintf("chain %s of table %s differs: want targets %v, got %v", chain, table, want, got))
+			}
+		}
+	}
+	return drift
+}
+
+// parseSavedTargets returns the targets of the rules of each chain of each table in the output of iptables-save.
+func parseSavedTargets(output string) map[string]map[string][]string {
+	targets := map[string]map[string][]string{}
+	table := ""
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(fields[0], "*"):
+			table = strings.TrimPrefix(fields[0], "*")
+			targets[table] = map[string][]string{}
+		case fields[0] == "-A" && len(fields) > 1 && table != "":
+			target := ""
+			for i := 2; i < len(fields)-1; i++ {
+				if fields[i] == "-j" {
+					target = fields[i+1]
+					break
+				}
+			}
+			targets[table][fields[1]] = append(targets[table][fields[1]], target)
+		}
+	}
+	return targets
+}
+
+func (cfg *IptablesConfigurator) buildRules() {
 	// Since OUTBOUND_IP_RANGES_EXCLUDE could carry ipv4 and ipv6 ranges
 	// need to split them in different arrays one for ipv4 and one for ipv6
 	// in order to not to fail
@@ -434,6 +519,7 @@ func (cfg *IptablesConfigurator) Run() {
 	ownerGroupsFilter := config.ParseInterceptFilter(cfg.cfg.OwnerGroupsInclude, cfg.cfg.OwnerGroupsExclude)
 
 	cfg.handleCaptureByOwnerGroup(ownerGroupsFilter)
+	cfg.handleExcludedOwners()
 
 	if redirectDNS {
 		if cfg.cfg.CaptureAllDNS {
@@ -536,7 +622,6 @@ func (cfg *IptablesConfigurator) Run() {
 		cfg.iptables.InsertRule(iptableslog.UndefinedCommand, constants.ISTIOINBOUND, constants.MANGLE, 3,
 			"-p", constants.TCP, "-i", "lo", "-m", "mark", "!", "--mark", outboundMark, "-j", constants.RETURN)
 	}
-	cfg.executeCommands()
 }
 
 type UDPRuleApplier struct {
@@ -721,6 +806,16 @@ func (cfg *IptablesConfigurator) handleCaptureByOwnerGroup(filter config.Interce
 	}
 }
 
+// handleExcludedOwners excludes the outbound traffic of the processes of the excluded UIDs from redirection, so that
+// agents running in the pod can bypass Envoy without excluding their destination ports. Groups are excluded by
+// handleCaptureByOwnerGroup.
+func (cfg *IptablesConfigurator) handleExcludedOwners() {
+	for _, uid := range split(cfg.cfg.OwnerUIDsExclude) {
+		cfg.iptables.AppendRule(iptableslog.UndefinedCommand, constants.ISTIOOUTPUT, constants.NAT,
+			"-m", "owner", "--uid-owner", uid, "-j", constants.RETURN)
+	}
+}
+
 func (cfg *IptablesConfigurator) createRulesFile(f *os.File, contents string) error {
 	defer f.Close()
 	log.Infof("Writing following contents to rules file: %v\n%v", f.Name(), strings.TrimSpace(contents))
@@ -778,8 +873,17 @@ func (cfg *IptablesConfigurator) executeIptablesRestoreCommand(isIpv4 bool) erro
 	return nil
 }
 
+// removeBuiltinChainRules deletes the rules Istio adds to built-in chains, if they are present, so that
+// applying the rules again does not duplicate them. Istio chains are flushed by iptables-restore instead.
+func (cfg *IptablesConfigurator) removeBuiltinChainRules(commands [][]string) {
+	for _, cmd := range commands {
+		cfg.ext.RunQuietlyAndIgnore(cmd[0], cmd[1:]...)
+	}
+}
+
 func (cfg *IptablesConfigurator) executeCommands() {
 	if cfg.cfg.RestoreFormat {
+		cfg.removeBuiltinChainRules(cfg.iptables.BuildV4Delete())
 		// Execute iptables-restore
 		err := cfg.executeIptablesRestoreCommand(true)
 		if err != nil {
@@ -787,6 +891,7 @@ func (cfg *IptablesConfigurator) executeCommands() {
 			os.Exit(1)
 		}
 		// Execute ip6tables-restore
+		cfg.removeBuiltinChainRules(cfg.iptables.BuildV6Delete())
 		err = cfg.executeIptablesRestoreCommand(false)
 		if err != nil {
 			log.Errorf("Failed to execute iptables-restore command: %v", err)
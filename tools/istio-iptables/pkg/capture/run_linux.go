@@ -125,3 +125,88 @@ func ConfigureRoutes(cfg *config.Config, ext dep.Dependencies) error {
 	}
 	return nil
 }
+
+// CheckRoutes verifies, in the current network namespace and without modifying them, that the routes configured
+// by ConfigureRoutes are still applied, and returns the drift found.
+func CheckRoutes(cfg *config.Config) []string {
+	if cfg.DryRun {
+		return nil
+	}
+	var drift []string
+	if cfg.EnableInboundIPv6 {
+		drift = append(drift, checkIPv6Addresses()...)
+	}
+	if cfg.InboundPortsInclude != "" && cfg.InboundInterceptionMode == constants.TPROXY {
+		drift = append(drift, checkTProxyRoutes(cfg)...)
+	}
+	return drift
+}
+
+// checkIPv6Addresses verifies that the address added by configureIPv6Addresses is still set on the local interface.
+func checkIPv6Addresses() []string {
+	link, err := netlink.LinkByName("lo")
+	if err != nil {
+		return []string{fmt.Sprintf("failed to find 'lo' link: %v", err)}
+	}
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_V6)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to list the IPv6 addresses: %v", err)}
+	}
+	for _, addr := range addrs {
+		if addr.IP.Equal(net.ParseIP("::6")) {
+			return nil
+		}
+	}
+	return []string{"missing address: ::6/128 dev lo"}
+}
+
+// checkTProxyRoutes verifies that the rules and routes added by configureTProxyRoutes are still applied.
+func checkTProxyRoutes(cfg *config.Config) []string {
+	tproxyTable, err := strconv.Atoi(cfg.InboundTProxyRouteTable)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to parse InboundTProxyRouteTable: %v", err)}
+	}
+	tproxyMark, err := strconv.Atoi(cfg.InboundTProxyMark)
+	if err != nil {
+		return []string{fmt.Sprintf("failed to parse InboundTProxyMark: %v", err)}
+	}
+	families := []int{unix.AF_INET}
+	if cfg.EnableInboundIPv6 {
+		families = append(families, unix.AF_INET6)
+	}
+	var drift []string
+	for _, family := range families {
+		rules, err := netlink.RuleListFiltered(family, &netlink.Rule{Table: tproxyTable, Mark: tproxyMark},
+			netlink.RT_FILTER_TABLE|netlink.RT_FILTER_MARK)
+		if err != nil {
+			drift = append(drift, fmt.Sprintf("failed to list the netlink rules: %v", err))
+		} else if len(rules) == 0 {
+			drift = append(drift, fmt.Sprintf("missing rule: fwmark %d lookup %d (family %d)", tproxyMark, tproxyTable, family))
+		}
+		routes, err := netlink.RouteListFiltered(family, &netlink.Route{Table: tproxyTable, Type: unix.RTN_LOCAL},
+			netlink.RT_FILTER_TABLE|netlink.RT_FILTER_TYPE)
+		if err != nil {
+			drift = append(drift, fmt.Sprintf("failed to list the routes: %v", err))
+			continue
+		}
+		found := false
+		for _, r := range routes {
+			if ones, _ := maskSize(r.Dst); ones == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			drift = append(drift, fmt.Sprintf("missing route: local default dev lo table %d (family %d)", tproxyTable, family))
+		}
+	}
+	return drift
+}
+
+// maskSize returns the prefix length of the destination of a route, a nil destination being the default route.
+func maskSize(dst *net.IPNet) (int, int) {
+	if dst == nil {
+		return 0, 0
+	}
+	return dst.Mask.Size()
+}
@@ -15,6 +15,9 @@
 package capture
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"net/netip"
 	"path/filepath"
 	"reflect"
@@ -211,6 +214,13 @@ func TestIptables(t *testing.T) {
 				cfg.OwnerGroupsExclude = "888,ftp"
 			},
 		},
+		{
+			"outbound-owner-ids-exclude",
+			func(cfg *config.Config) {
+				cfg.OwnerUIDsExclude = "0,1000-1999"
+				cfg.OwnerGroupsExclude = "65534"
+			},
+		},
 		{
 			"ipv6-dns-outbound-owner-groups",
 			func(cfg *config.Config) {
@@ -332,6 +342,149 @@ func TestSeparateV4V6(t *testing.T) {
 	}
 }
 
+// recordingDependencies records the commands run and fails the ones that were configured to fail.
+type recordingDependencies struct {
+	dep.StdoutStubDependencies
+	failing  map[string]bool
+	saved    map[string]string
+	commands []string
+}
+
+func (r *recordingDependencies) Run(cmd string, args ...string) error {
+	command := strings.Join(append([]string{cmd}, args...), " ")
+	r.commands = append(r.commands, command)
+	if r.failing[command] {
+		return errors.New("rule does not exist")
+	}
+	return nil
+}
+
+func (r *recordingDependencies) RunQuietlyAndIgnore(cmd string, args ...string) {
+	_ = r.Run(cmd, args...)
+}
+
+func (r *recordingDependencies) RunWithOutput(cmd string, args ...string) (*bytes.Buffer, error) {
+	r.commands = append(r.commands, strings.Join(append([]string{cmd}, args...), " "))
+	return bytes.NewBufferString(r.saved[cmd]), nil
+}
+
+// save returns the iptables-save output of rules with the given targets, adding the extra rules first.
+func save(targets map[string]map[string][]string, extra map[string]map[string][]string) string {
+	var b strings.Builder
+	for table, chains := range targets {
+		b.WriteString("*" + table + "\n")
+		for chain, chainTargets := range chains {
+			for _, target := range append(append([]string{}, extra[table][chain]...), chainTargets...) {
+				b.WriteString("-A " + chain + " -m comment --comment test -j " + target + "\n")
+			}
+		}
+		b.WriteString("COMMIT\n")
+	}
+	return b.String()
+}
+
+func TestCheck(t *testing.T) {
+	cfg := constructTestConfig()
+	cfg.InboundPortsInclude = "*"
+	desired := NewIptablesConfigurator(cfg, &dep.StdoutStubDependencies{})
+	desired.buildRules()
+	targets := desired.iptables.BuildV4Targets()
+	inbound := targets[constants.NAT][constants.ISTIOINBOUND]
+	saved := func(extra map[string]map[string][]string) map[string]string {
+		return map[string]string{
+			constants.IPTABLESSAVE:  save(targets, extra),
+			constants.IP6TABLESSAVE: save(desired.iptables.BuildV6Targets(), nil),
+		}
+	}
+
+	drifted := "iptables -t nat -C PREROUTING -p tcp -j ISTIO_INBOUND"
+	cases := []struct {
+		name   string
+		ext    *recordingDependencies
+		expect []string
+	}{
+		{
+			name:   "applied",
+			ext:    &recordingDependencies{saved: saved(nil)},
+			expect: nil,
+		},
+		{
+			name:   "modified rule",
+			ext:    &recordingDependencies{failing: map[string]bool{drifted: true}, saved: saved(nil)},
+			expect: []string{"missing or modified rule: " + drifted},
+		},
+		{
+			name: "rule before the Istio jump",
+			ext: &recordingDependencies{saved: saved(map[string]map[string][]string{
+				constants.NAT: {constants.PREROUTING: {"ACCEPT"}},
+			})},
+			expect: []string{fmt.Sprintf("chain PREROUTING of table nat does not start with the Istio rules: want targets %v, got %v",
+				targets[constants.NAT][constants.PREROUTING], append([]string{"ACCEPT"}, targets[constants.NAT][constants.PREROUTING]...))},
+		},
+		{
+			name: "rule added to an Istio chain",
+			ext: &recordingDependencies{saved: saved(map[string]map[string][]string{
+				constants.NAT: {constants.ISTIOINBOUND: {"RETURN"}},
+			})},
+			expect: []string{fmt.Sprintf("chain ISTIO_INBOUND of table nat differs: want targets %v, got %v",
+				inbound, append([]string{"RETURN"}, inbound...))},
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			drift := NewIptablesConfigurator(cfg, tt.ext).Check()
+			if !reflect.DeepEqual(drift, tt.expect) {
+				t.Fatalf("expected drift %v, got %v", tt.expect, drift)
+			}
+			for _, cmd := range tt.ext.commands {
+				if !strings.Contains(cmd, " -C ") && cmd != constants.IPTABLESSAVE && cmd != constants.IP6TABLESSAVE {
+					t.Fatalf("check should not modify rules, but ran %q", cmd)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRoutes(t *testing.T) {
+	cfg := constructTestConfig()
+	cfg.InboundPortsInclude = "*"
+	if drift := CheckRoutes(cfg); drift != nil {
+		t.Fatalf("expected no routes to be checked in REDIRECT mode, got drift %v", drift)
+	}
+	cfg.InboundInterceptionMode = constants.TPROXY
+	cfg.DryRun = true
+	if drift := CheckRoutes(cfg); drift != nil {
+		t.Fatalf("expected no routes to be checked in dry run mode, got drift %v", drift)
+	}
+}
+
+func TestRunRemovesBuiltinChainRules(t *testing.T) {
+	ext := &recordingDependencies{}
+	cfg := constructTestConfig()
+	cfg.InboundPortsInclude = "*"
+	cfg.OutputPath = filepath.Join(t.TempDir(), "rules.txt")
+	NewIptablesConfigurator(cfg, ext).Run()
+	deleted := map[string]bool{}
+	for _, cmd := range ext.commands {
+		if strings.Contains(cmd, " -D ") {
+			deleted[cmd] = true
+		}
+	}
+	for _, want := range []string{
+		"iptables -t nat -D PREROUTING -p tcp -j ISTIO_INBOUND",
+		"iptables -t nat -D OUTPUT -p tcp -j ISTIO_OUTPUT",
+	} {
+		if !deleted[want] {
+			t.Errorf("expected %q to be run before applying the rules, got %v", want, ext.commands)
+		}
+	}
+	for cmd := range deleted {
+		if strings.Contains(cmd, "-D ISTIO_") {
+			t.Errorf("rules in Istio chains should not be deleted one by one, got %q", cmd)
+		}
+	}
+}
+
 func compareToGolden(t *testing.T, name string, actual []string) {
 	t.Helper()
 	gotBytes := []byte(strings.Join(actual, "\n"))
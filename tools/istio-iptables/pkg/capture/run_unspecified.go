@@ -35,3 +35,9 @@ func configureTProxyRoutes(cfg *config.Config) error {
 func ConfigureRoutes(cfg *config.Config, ext dep.Dependencies) error {
 	return ErrNotImplemented
 }
+
+// CheckRoutes verifies that the routes configured by ConfigureRoutes are still applied. Routes are not configured
+// on this platform, so that there is no drift.
+func CheckRoutes(cfg *config.Config) []string {
+	return nil
+}
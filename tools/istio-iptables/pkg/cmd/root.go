@@ -62,18 +62,15 @@ var rootCmd = &cobra.Command{
 		if err := cfg.Validate(); err != nil {
 			handleErrorWithCode(err, 1)
 		}
-		var ext dep.Dependencies
-		if cfg.DryRun {
-			ext = &dep.StdoutStubDependencies{}
-		} else {
-			ext = &dep.RealDependencies{
-				CNIMode:          cfg.CNIMode,
-				HostNSEnterExec:  cfg.HostNSEnterExec,
-				NetworkNamespace: cfg.NetworkNamespace,
+		ext := newDependencies(cfg)
+		iptConfigurator := capture.NewIptablesConfigurator(cfg, ext)
+		if cfg.CheckRules {
+			if drift := append(iptConfigurator.Check(), capture.CheckRoutes(cfg)...); len(drift) > 0 {
+				handleErrorWithCode(fmt.Errorf("iptables rules or routes have drifted:\n%s", strings.Join(drift, "\n")),
+					constants.DriftErrorCode)
 			}
+			return
 		}
-
-		iptConfigurator := capture.NewIptablesConfigurator(cfg, ext)
 		if !cfg.SkipRuleApply {
 			iptConfigurator.Run()
 			if err := capture.ConfigureRoutes(cfg, ext); err != nil {
@@ -120,11 +117,23 @@ var configureRoutesCommand = &cobra.Command{
 	},
 }
 
+func newDependencies(cfg *config.Config) dep.Dependencies {
+	if cfg.DryRun {
+		return &dep.StdoutStubDependencies{}
+	}
+	return &dep.RealDependencies{
+		CNIMode:          cfg.CNIMode,
+		HostNSEnterExec:  cfg.HostNSEnterExec,
+		NetworkNamespace: cfg.NetworkNamespace,
+	}
+}
+
 func constructConfig() *config.Config {
 	cfg := &config.Config{
 		DryRun:                  viper.GetBool(constants.DryRun),
 		TraceLogging:            viper.GetBool(constants.TraceLogging),
 		RestoreFormat:           viper.GetBool(constants.RestoreFormat),
+		CheckRules:              viper.GetBool(constants.CheckRules),
 		ProxyPort:               viper.GetString(constants.EnvoyPort),
 		InboundCapturePort:      viper.GetString(constants.InboundCapturePort),
 		InboundTunnelPort:       viper.GetString(constants.InboundTunnelPort),
@@ -137,6 +146,7 @@ func constructConfig() *config.Config {
 		InboundPortsExclude:     viper.GetString(constants.LocalExcludePorts),
 		OwnerGroupsInclude:      viper.GetString(constants.OwnerGroupsInclude.Name),
 		OwnerGroupsExclude:      viper.GetString(constants.OwnerGroupsExclude.Name),
+		OwnerUIDsExclude:        viper.GetString(constants.OutboundOwnerUIDsExclude),
 		OutboundPortsInclude:    viper.GetString(constants.OutboundPorts),
 		OutboundPortsExclude:    viper.GetString(constants.LocalOutboundPortsExclude),
 		OutboundIPRangesInclude: viper.GetString(constants.ServiceCidr),
@@ -290,6 +300,11 @@ func bindFlags(cmd *cobra.Command, args []string) {
 	}
 	viper.SetDefault(constants.ExcludeInterfaces, "")
 
+	if err := viper.BindPFlag(constants.OutboundOwnerUIDsExclude, cmd.Flags().Lookup(constants.OutboundOwnerUIDsExclude)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.OutboundOwnerUIDsExclude, "")
+
 	if err := viper.BindPFlag(constants.ServiceCidr, cmd.Flags().Lookup(constants.ServiceCidr)); err != nil {
 		handleError(err)
 	}
@@ -350,6 +365,11 @@ func bindFlags(cmd *cobra.Command, args []string) {
 	}
 	viper.SetDefault(constants.RestoreFormat, true)
 
+	if err := viper.BindPFlag(constants.CheckRules, cmd.Flags().Lookup(constants.CheckRules)); err != nil {
+		handleError(err)
+	}
+	viper.SetDefault(constants.CheckRules, false)
+
 	if err := viper.BindPFlag(constants.IptablesProbePort, cmd.Flags().Lookup(constants.IptablesProbePort)); err != nil {
 		handleError(err)
 	}
@@ -443,6 +463,10 @@ func bindCmdlineFlags(rootCmd *cobra.Command) {
 	rootCmd.Flags().StringP(constants.ExcludeInterfaces, "c", "",
 		"Comma separated list of NIC (optional). Neither inbound nor outbound traffic will be captured")
 
+	rootCmd.Flags().String(constants.OutboundOwnerUIDsExclude, "",
+		"Comma separated list of UIDs, or ranges of UIDs such as 1000-1999, of the processes whose outbound traffic "+
+			"is excluded from redirection to Envoy (optional)")
+
 	rootCmd.Flags().StringP(constants.ServiceCidr, "i", "",
 		"Comma separated list of IP ranges in CIDR form to redirect to envoy (optional). "+
 			"The wildcard character \"*\" can be used to redirect all outbound traffic. An empty list will disable all outbound")
@@ -470,6 +494,9 @@ func bindCmdlineFlags(rootCmd *cobra.Command) {
 
 	rootCmd.Flags().BoolP(constants.RestoreFormat, "f", true, "Print iptables rules in iptables-restore interpretable format")
 
+	rootCmd.Flags().Bool(constants.CheckRules, false,
+		"Check that the iptables rules and routes are applied instead of applying them, exiting with an error if they have drifted")
+
 	rootCmd.Flags().String(constants.IptablesProbePort, constants.DefaultIptablesProbePort, "set listen port for failure detection")
 
 	rootCmd.Flags().Duration(constants.ProbeTimeout, constants.DefaultProbeTimeout, "failure detection timeout")
@@ -494,6 +521,19 @@ func bindCmdlineFlags(rootCmd *cobra.Command) {
 	rootCmd.Flags().Bool(constants.HostNSEnterExec, false, "Instead of using the internal go netns, use the nsenter command for switching network namespaces.")
 }
 
+// CheckRules checks the rules and routes configured through viper without modifying them, as the --check flag does,
+// and returns the drift found. Unlike the command, it does not exit, so that the CNI plugin can report the drift.
+// The routes are checked in the current network namespace.
+func CheckRules() ([]string, error) {
+	bindFlags(rootCmd, nil)
+	cfg := constructConfig()
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	drift := capture.NewIptablesConfigurator(cfg, newDependencies(cfg)).Check()
+	return append(drift, capture.CheckRoutes(cfg)...), nil
+}
+
 func GetCommand() *cobra.Command {
 	return rootCmd
 }
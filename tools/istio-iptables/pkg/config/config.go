@@ -39,6 +39,7 @@ type Config struct {
 	InboundPortsExclude     string        `json:"INBOUND_PORTS_EXCLUDE"`
 	OwnerGroupsInclude      string        `json:"OUTBOUND_OWNER_GROUPS_INCLUDE"`
 	OwnerGroupsExclude      string        `json:"OUTBOUND_OWNER_GROUPS_EXCLUDE"`
+	OwnerUIDsExclude        string        `json:"OUTBOUND_OWNER_UIDS_EXCLUDE"`
 	OutboundPortsInclude    string        `json:"OUTBOUND_PORTS_INCLUDE"`
 	OutboundPortsExclude    string        `json:"OUTBOUND_PORTS_EXCLUDE"`
 	OutboundIPRangesInclude string        `json:"OUTBOUND_IPRANGES_INCLUDE"`
@@ -49,6 +50,7 @@ type Config struct {
 	ProbeTimeout            time.Duration `json:"PROBE_TIMEOUT"`
 	DryRun                  bool          `json:"DRY_RUN"`
 	RestoreFormat           bool          `json:"RESTORE_FORMAT"`
+	CheckRules              bool          `json:"CHECK_RULES"`
 	SkipRuleApply           bool          `json:"SKIP_RULE_APPLY"`
 	RunValidation           bool          `json:"RUN_VALIDATION"`
 	RedirectDNS             bool          `json:"REDIRECT_DNS"`
@@ -86,6 +88,7 @@ func (c *Config) Print() {
 	b.WriteString(fmt.Sprintf("INBOUND_PORTS_EXCLUDE=%s\n", c.InboundPortsExclude))
 	b.WriteString(fmt.Sprintf("OUTBOUND_OWNER_GROUPS_INCLUDE=%s\n", c.OwnerGroupsInclude))
 	b.WriteString(fmt.Sprintf("OUTBOUND_OWNER_GROUPS_EXCLUDE=%s\n", c.OwnerGroupsExclude))
+	b.WriteString(fmt.Sprintf("OUTBOUND_OWNER_UIDS_EXCLUDE=%s\n", c.OwnerUIDsExclude))
 	b.WriteString(fmt.Sprintf("OUTBOUND_IP_RANGES_INCLUDE=%s\n", c.OutboundIPRangesInclude))
 	b.WriteString(fmt.Sprintf("OUTBOUND_IP_RANGES_EXCLUDE=%s\n", c.OutboundIPRangesExclude))
 	b.WriteString(fmt.Sprintf("OUTBOUND_PORTS_INCLUDE=%s\n", c.OutboundPortsInclude))
@@ -105,5 +108,8 @@ func (c *Config) Print() {
 }
 
 func (c *Config) Validate() error {
+	if err := ValidateOwnerIDs(c.OwnerUIDsExclude); err != nil {
+		return fmt.Errorf("invalid excluded owner UIDs: %v", err)
+	}
 	return ValidateOwnerGroups(c.OwnerGroupsInclude, c.OwnerGroupsExclude)
 }
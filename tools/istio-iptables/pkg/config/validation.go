@@ -16,6 +16,8 @@ package config
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -41,3 +43,23 @@ func ValidateOwnerGroups(include, exclude string) error {
 	}
 	return nil
 }
+
+// ValidateOwnerIDs validates a comma separated list of UIDs or GIDs, or ranges of them such as 1000-1999, as
+// matched by the --uid-owner and --gid-owner options of the owner module of iptables.
+func ValidateOwnerIDs(ids string) error {
+	for _, id := range Split(ids) {
+		lo, hi, isRange := strings.Cut(id, "-")
+		from, err := strconv.ParseUint(lo, 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid ID %q", id)
+		}
+		if !isRange {
+			continue
+		}
+		to, err := strconv.ParseUint(hi, 10, 32)
+		if err != nil || to < from {
+			return fmt.Errorf("invalid ID range %q", id)
+		}
+	}
+	return nil
+}
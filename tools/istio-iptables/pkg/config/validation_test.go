@@ -91,3 +91,12 @@ func TestValidateOwnerGroups_Invalid(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateOwnerIDs(t *testing.T) {
+	for _, ids := range []string{"", "0", "1000,2000-2999", "0-4294967295"} {
+		assert.NoError(t, ValidateOwnerIDs(ids))
+	}
+	for _, ids := range []string{"root", "-1", "2000-1000", "1000-", "4294967296"} {
+		assert.Error(t, ValidateOwnerIDs(ids))
+	}
+}
@@ -98,6 +98,7 @@ const (
 	TraceLogging              = "iptables-trace-logging"
 	Clean                     = "clean"
 	RestoreFormat             = "restore-format"
+	CheckRules                = "check"
 	SkipRuleApply             = "skip-rule-apply"
 	RunValidation             = "run-validation"
 	IptablesProbePort         = "iptables-probe-port"
@@ -109,6 +110,7 @@ const (
 	NetworkNamespace          = "network-namespace"
 	CNIMode                   = "cni-mode"
 	HostNSEnterExec           = "host-nsenter-exec"
+	OutboundOwnerUIDsExclude  = "istio-outbound-owner-uids-exclude"
 )
 
 // Environment variables that deliberately have no equivalent command-line flags.
@@ -171,6 +173,9 @@ const (
 	ValidationErrorCode     = 126
 )
 
+// DriftErrorCode is the exit code used by the check mode when the applied rules differ from the desired ones.
+const DriftErrorCode = 125
+
 // DNS ports
 const (
 	IstioAgentDNSListenerPort = "15053"
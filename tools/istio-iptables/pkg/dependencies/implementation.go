@@ -130,9 +130,9 @@ func exitCode(err error) (int, bool) {
 func (r *RealDependencies) RunOrFail(cmd string, args ...string) {
 	var err error
 	if XTablesCmds.Contains(cmd) {
-		err = r.executeXTables(cmd, false, args...)
+		_, err = r.executeXTables(cmd, false, args...)
 	} else {
-		err = r.execute(cmd, false, args...)
+		_, err = r.execute(cmd, false, args...)
 	}
 	if err != nil {
 		log.Errorf("Failed to execute: %s %s, %v", cmd, strings.Join(args, " "), err)
@@ -143,9 +143,9 @@ func (r *RealDependencies) RunOrFail(cmd string, args ...string) {
 // Run runs a command
 func (r *RealDependencies) Run(cmd string, args ...string) (err error) {
 	if XTablesCmds.Contains(cmd) {
-		err = r.executeXTables(cmd, false, args...)
+		_, err = r.executeXTables(cmd, false, args...)
 	} else {
-		err = r.execute(cmd, false, args...)
+		_, err = r.execute(cmd, false, args...)
 	}
 	return err
 }
@@ -153,8 +153,16 @@ func (r *RealDependencies) Run(cmd string, args ...string) (err error) {
 // RunQuietlyAndIgnore runs a command quietly and ignores errors
 func (r *RealDependencies) RunQuietlyAndIgnore(cmd string, args ...string) {
 	if XTablesCmds.Contains(cmd) {
-		_ = r.executeXTables(cmd, true, args...)
+		_, _ = r.executeXTables(cmd, true, args...)
 	} else {
-		_ = r.execute(cmd, true, args...)
+		_, _ = r.execute(cmd, true, args...)
 	}
 }
+
+// RunWithOutput runs a command and returns its standard output
+func (r *RealDependencies) RunWithOutput(cmd string, args ...string) (*bytes.Buffer, error) {
+	if XTablesCmds.Contains(cmd) {
+		return r.executeXTables(cmd, false, args...)
+	}
+	return r.execute(cmd, false, args...)
+}
@@ -30,7 +30,7 @@ import (
 	"istio.io/pkg/log"
 )
 
-func (r *RealDependencies) execute(cmd string, ignoreErrors bool, args ...string) error {
+func (r *RealDependencies) execute(cmd string, ignoreErrors bool, args ...string) (*bytes.Buffer, error) {
 	if r.CNIMode && r.HostNSEnterExec {
 		originalCmd := cmd
 		cmd = constants.NSENTER
@@ -58,7 +58,7 @@ func (r *RealDependencies) execute(cmd string, ignoreErrors bool, args ...string
 	if r.CNIMode && !r.HostNSEnterExec {
 		nsContainer, err = ns.GetNS(r.NetworkNamespace)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		err = nsContainer.Do(func(ns.NetNS) error {
@@ -77,10 +77,10 @@ func (r *RealDependencies) execute(cmd string, ignoreErrors bool, args ...string
 		log.Errorf("Command error output: \n%v", stderr.String())
 	}
 
-	return err
+	return stdout, err
 }
 
-func (r *RealDependencies) executeXTables(cmd string, ignoreErrors bool, args ...string) error {
+func (r *RealDependencies) executeXTables(cmd string, ignoreErrors bool, args ...string) (*bytes.Buffer, error) {
 	if r.CNIMode && r.HostNSEnterExec {
 		originalCmd := cmd
 		cmd = constants.NSENTER
@@ -95,7 +95,7 @@ func (r *RealDependencies) executeXTables(cmd string, ignoreErrors bool, args ..
 	if r.CNIMode && !r.HostNSEnterExec {
 		nsContainer, err = ns.GetNS(r.NetworkNamespace)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defer nsContainer.Close()
 	}
@@ -137,7 +137,7 @@ func (r *RealDependencies) executeXTables(cmd string, ignoreErrors bool, args ..
 		return err
 	})
 	if backoffError != nil {
-		return fmt.Errorf("timed out trying to acquire XTables lock: %v", err)
+		return nil, fmt.Errorf("timed out trying to acquire XTables lock: %v", err)
 	}
 
 	if len(stdout.String()) != 0 {
@@ -156,5 +156,5 @@ func (r *RealDependencies) executeXTables(cmd string, ignoreErrors bool, args ..
 		log.Errorf("Command error output: %v", stderrStr)
 	}
 
-	return err
+	return stdout, err
 }
@@ -17,15 +17,18 @@
 
 package dependencies
 
-import "errors"
+import (
+	"bytes"
+	"errors"
+)
 
 // ErrNotImplemented is returned when a requested feature is not implemented.
 var ErrNotImplemented = errors.New("not implemented")
 
-func (r *RealDependencies) execute(cmd string, ignoreErrors bool, args ...string) error {
-	return ErrNotImplemented
+func (r *RealDependencies) execute(cmd string, ignoreErrors bool, args ...string) (*bytes.Buffer, error) {
+	return nil, ErrNotImplemented
 }
 
-func (r *RealDependencies) executeXTables(cmd string, ignoreErrors bool, args ...string) error {
-	return ErrNotImplemented
+func (r *RealDependencies) executeXTables(cmd string, ignoreErrors bool, args ...string) (*bytes.Buffer, error) {
+	return nil, ErrNotImplemented
 }
@@ -0,0 +1,101 @@
+// Copyright Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// xds-replay replays the xDS streams of a proxy recorded by the /debug/xds_record debug endpoint of istiod
+// against another istiod, typically a local istiod with the config of the recorded cluster, and reports the
+// responses which differ from the recorded ones.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	discovery "github.com/envoyproxy/go-control-plane/envoy/service/discovery/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"istio.io/istio/pilot/pkg/xds/recording"
+	v3 "istio.io/istio/pilot/pkg/xds/v3"
+	_ "istio.io/istio/pkg/config/xds" // register the types of the recorded resources
+)
+
+var (
+	recordingFile = flag.String("recording", "", "The xDS recording to replay, downloaded from /debug/xds_record?file=<name>")
+	xdsAddress    = flag.String("xds-address", "localhost:15010", "The plaintext xDS address of the replayed istiod")
+	timeout       = flag.Duration("timeout", 10*time.Second, "How long to wait for each response")
+)
+
+func main() {
+	flag.Parse()
+	if err := replay(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func replay() error {
+	if *recordingFile == "" {
+		return fmt.Errorf("--recording must be set")
+	}
+	f, err := os.Open(*recordingFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	records, err := recording.Read(f)
+	if err != nil {
+		return fmt.Errorf("invalid recording: %v", err)
+	}
+
+	conn, err := grpc.Dial(*xdsAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := discovery.NewAggregatedDiscoveryServiceClient(conn).StreamAggregatedResources(ctx)
+	if err != nil {
+		return err
+	}
+
+	replayed, err := recording.Replay(stream, records, *timeout)
+	different := 0
+	for _, r := range replayed {
+		diff, derr := r.Diff()
+		if derr != nil {
+			return derr
+		}
+		if diff.Empty() {
+			continue
+		}
+		different++
+		fmt.Printf("%s response (recorded nonce %s):\n", v3.GetShortType(r.Recorded.TypeUrl), r.Recorded.Nonce)
+		printNames("added", diff.Added)
+		printNames("removed", diff.Removed)
+		printNames("changed", diff.Changed)
+	}
+	fmt.Printf("%d of %d responses differ\n", different, len(replayed))
+	return err
+}
+
+func printNames(change string, names []string) {
+	if len(names) > 0 {
+		fmt.Printf("  %s: %s\n", change, strings.Join(names, ", "))
+	}
+}